@@ -3,14 +3,14 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
+	"github.com/rtcdance/streamgate/pkg/core"
 	"github.com/rtcdance/streamgate/pkg/core/config"
 	"github.com/rtcdance/streamgate/pkg/core/logger"
 	"github.com/rtcdance/streamgate/pkg/gateway"
@@ -19,6 +19,9 @@ import (
 )
 
 func main() {
+	config.BindFlags(flag.CommandLine)
+	flag.Parse()
+
 	log := logger.NewDevelopmentLogger("streamgate-api-gateway")
 	defer func() { _ = log.Sync() }()
 
@@ -55,7 +58,6 @@ func main() {
 	if err != nil {
 		log.Fatal("Failed to setup router", zap.Error(err))
 	}
-	defer func() { _ = resources.Close() }()
 
 	httpServer := &http.Server{
 		Addr:              fmt.Sprintf(":%d", cfg.Server.Port),
@@ -81,38 +83,41 @@ func main() {
 		SegmentStorage: resources.SegmentStorage,
 		UploadService:  resources.UploadService,
 		TranscodingSvc: resources.TranscodingSvc,
+		LiveSvc:        resources.LiveSvc,
 	}
 	grpcServer := gateway.SetupGRPCServer(context.Background(), cfg, log, grpcServices)
 
-	go func() {
-		log.Info("Starting HTTP server", zap.Int("port", cfg.Server.Port))
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Error("HTTP server error", zap.Error(err))
-		}
-	}()
-
+	runner := core.NewRunner(log, cfg.Server.GetDrainDelay(), cfg.Server.GetShutdownTimeout())
+	runner.ServeHTTP("http", httpServer)
 	go func() {
 		log.Info("Starting gRPC server", zap.Int("port", grpcPort))
 		if err := grpcServer.Serve(grpcListener); err != nil {
-			log.Error("gRPC server error", zap.Error(err))
+			runner.Fail(fmt.Errorf("grpc server: %w", err))
 		}
 	}()
+	runner.OnShutdown("grpc", func(ctx context.Context) error {
+		done := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			grpcServer.Stop()
+		}
+		return nil
+	})
+	runner.OnShutdown("transcoding", resources.Drain)
+	runner.OnShutdown("resources", func(context.Context) error { return resources.Close() })
 
 	log.Info("StreamGate API Gateway Service started successfully",
 		zap.Int("http_port", cfg.Server.Port),
 		zap.Int("grpc_port", grpcPort))
 
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	sig := <-sigChan
-	log.Info("Received shutdown signal", zap.String("signal", sig.String()))
-
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	if err := httpServer.Shutdown(shutdownCtx); err != nil {
-		log.Error("Error shutting down HTTP server", zap.Error(err))
+	if err := runner.Wait(context.Background()); err != nil {
+		log.Error("Error during shutdown", zap.Error(err))
+		os.Exit(1)
 	}
-	grpcServer.GracefulStop()
 	log.Info("StreamGate API Gateway Service stopped gracefully")
 }
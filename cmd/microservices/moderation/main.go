@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+
+	"go.uber.org/zap"
+
+	"github.com/rtcdance/streamgate/pkg/core"
+	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/rtcdance/streamgate/pkg/core/logger"
+	"github.com/rtcdance/streamgate/pkg/plugins/moderation"
+)
+
+func main() {
+	config.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	// Initialize logger
+	log := logger.NewDevelopmentLogger("streamgate-moderation")
+	defer func() { _ = log.Sync() }()
+
+	log.Info("Starting StreamGate Moderation Service...")
+
+	// Load configuration
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatal("Failed to load configuration", zap.Error(err))
+	}
+
+	// Force microservice mode
+	cfg.Mode = "microservice"
+	cfg.ServiceName = "moderation"
+	if err := cfg.ValidateProduction(log); err != nil {
+		var ve *config.ValidationError
+		if errors.As(err, &ve) && ve.HasCritical() {
+			log.Fatal("Critical security config validation failed (cannot be bypassed)", zap.Strings("errors", ve.Critical))
+		}
+		if cfg.Debug {
+			log.Warn("Production config validation failed (debug mode, continuing anyway)", zap.Error(err))
+		} else {
+			log.Fatal("Config validation failed", zap.Error(err))
+		}
+	}
+	log.Info("Configuration loaded",
+		zap.String("mode", cfg.Mode),
+		zap.String("service", cfg.ServiceName),
+		zap.Int("port", cfg.Server.Port))
+
+	// Initialize microkernel
+	kernel, err := core.NewMicrokernel(cfg, log)
+	if err != nil {
+		log.Fatal("Failed to initialize microkernel", zap.Error(err))
+	}
+
+	// Register moderation plugin
+	if err := kernel.RegisterPlugin(moderation.NewModerationPlugin(cfg, log)); err != nil {
+		log.Fatal("Failed to register moderation plugin", zap.Error(err))
+	}
+
+	// Start microkernel
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := kernel.Start(ctx); err != nil {
+		log.Fatal("Failed to start microkernel", zap.Error(err))
+	}
+
+	log.Info("StreamGate Moderation Service started successfully", zap.Int("port", cfg.Server.Port))
+
+	runner := core.NewRunner(log, cfg.Server.GetDrainDelay(), cfg.Server.GetShutdownTimeout())
+	runner.OnShutdown("kernel", kernel.Shutdown)
+	if err := runner.Wait(ctx); err != nil {
+		log.Error("Error during shutdown", zap.Error(err))
+		os.Exit(1)
+	}
+
+	log.Info("StreamGate Moderation Service stopped gracefully")
+}
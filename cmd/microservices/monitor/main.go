@@ -3,10 +3,8 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
 
 	"go.uber.org/zap"
 
@@ -17,6 +15,9 @@ import (
 )
 
 func main() {
+	config.BindFlags(flag.CommandLine)
+	flag.Parse()
+
 	// Initialize logger
 	log := logger.NewDevelopmentLogger("streamgate-monitor")
 	defer func() { _ = log.Sync() }()
@@ -69,18 +70,9 @@ func main() {
 
 	log.Info("StreamGate Monitor Service started successfully", zap.Int("port", cfg.Server.Port))
 
-	// Wait for shutdown signal
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
-	sig := <-sigChan
-	log.Info("Received shutdown signal", zap.String("signal", sig.String()))
-
-	// Graceful shutdown with timeout
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	if err := kernel.Shutdown(shutdownCtx); err != nil {
+	runner := core.NewRunner(log, cfg.Server.GetDrainDelay(), cfg.Server.GetShutdownTimeout())
+	runner.OnShutdown("kernel", kernel.Shutdown)
+	if err := runner.Wait(ctx); err != nil {
 		log.Error("Error during shutdown", zap.Error(err))
 		os.Exit(1)
 	}
@@ -3,9 +3,8 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
 	"go.uber.org/zap"
@@ -17,6 +16,9 @@ import (
 )
 
 func main() {
+	config.BindFlags(flag.CommandLine)
+	flag.Parse()
+
 	// Initialize logger
 	log := logger.NewDevelopmentLogger("streamgate-transcoder")
 	defer func() { _ = log.Sync() }()
@@ -69,18 +71,14 @@ func main() {
 
 	log.Info("StreamGate Transcoder Service started successfully", zap.Int("port", cfg.Server.Port))
 
-	// Wait for shutdown signal
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
-	sig := <-sigChan
-	log.Info("Received shutdown signal", zap.String("signal", sig.String()))
-
-	// Graceful shutdown with timeout
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
-
-	if err := kernel.Shutdown(shutdownCtx); err != nil {
+	// Leave running transcodes time to finish before they are checkpointed.
+	shutdownTimeout := cfg.Server.GetShutdownTimeout()
+	if cfg.Server.ShutdownTimeout == "" {
+		shutdownTimeout = 60 * time.Second
+	}
+	runner := core.NewRunner(log, cfg.Server.GetDrainDelay(), shutdownTimeout)
+	runner.OnShutdown("kernel", kernel.Shutdown)
+	if err := runner.Wait(ctx); err != nil {
 		log.Error("Error during shutdown", zap.Error(err))
 		os.Exit(1)
 	}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+)
+
+const configUsage = "usage: streamgate [-set key=value]... config validate"
+
+// runConfigCommand runs "streamgate config validate": it loads the config
+// the way startup does, with the same files, environment and -set flags,
+// and reports every problem startup would fail on. It returns the exit
+// code.
+func runConfigCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "validate" {
+		fmt.Fprintln(stderr, configUsage)
+		return 2
+	}
+	fs := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprintln(stderr, configUsage) }
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if err := cfg.ValidateProduction(nil); err != nil {
+		var ve *config.ValidationError
+		fatal := !cfg.Debug || (errors.As(err, &ve) && ve.HasCritical())
+		fmt.Fprintln(stderr, err)
+		if fatal {
+			return 1
+		}
+	}
+	fmt.Fprintln(stdout, "config is valid")
+	return 0
+}
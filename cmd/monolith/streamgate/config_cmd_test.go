@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validConfig = `
+app:
+  debug: true
+server:
+  port: 8080
+database:
+  host: db
+auth:
+  jwt_secret: 0123456789abcdef0123456789abcdef
+`
+
+func TestRunConfigCommand(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		config     string
+		wantCode   int
+		wantStdout string
+		wantStderr []string
+	}{
+		{
+			name:       "valid",
+			args:       []string{"validate"},
+			config:     validConfig,
+			wantStdout: "config is valid",
+		},
+		{
+			name:       "schema problems",
+			args:       []string{"validate"},
+			config:     validConfig + "grpc:\n  port: 0\ntranscoding:\n  hardware: gpu\n",
+			wantCode:   1,
+			wantStderr: []string{"invalid config: 2 problem(s)", "invalid gRPC port: 0", "transcoding.hardware"},
+		},
+		{
+			name:       "critical production problem",
+			args:       []string{"validate"},
+			config:     "server:\n  port: 8080\ndatabase:\n  host: db\napp:\n  debug: true\n",
+			wantCode:   1,
+			wantStderr: []string{"CRITICAL: auth.jwt_secret is empty"},
+		},
+		{
+			name:       "unknown subcommand",
+			args:       []string{"show"},
+			wantCode:   2,
+			wantStderr: []string{"usage: streamgate"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Chdir(dir)
+			t.Cleanup(viper.Reset)
+			require.NoError(t, os.Mkdir(filepath.Join(dir, "config"), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "config", "config.yaml"), []byte(tt.config), 0o644))
+
+			var stdout, stderr bytes.Buffer
+			code := runConfigCommand(tt.args, &stdout, &stderr)
+			assert.Equal(t, tt.wantCode, code, stderr.String())
+			assert.Contains(t, stdout.String(), tt.wantStdout)
+			for _, want := range tt.wantStderr {
+				assert.Contains(t, stderr.String(), want)
+			}
+		})
+	}
+}
@@ -4,23 +4,25 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"flag"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/rtcdance/streamgate/migrations"
 	"github.com/rtcdance/streamgate/pkg/core"
 	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/rtcdance/streamgate/pkg/core/extplugin"
 	"github.com/rtcdance/streamgate/pkg/core/logger"
+	"github.com/rtcdance/streamgate/pkg/storage"
 	migrate "github.com/rtcdance/streamgate/pkg/storage/migrate"
 
 	_ "github.com/rtcdance/streamgate/pkg/plugins/api"
 	_ "github.com/rtcdance/streamgate/pkg/plugins/auth"
 	_ "github.com/rtcdance/streamgate/pkg/plugins/cache"
+	_ "github.com/rtcdance/streamgate/pkg/plugins/keys"
 	_ "github.com/rtcdance/streamgate/pkg/plugins/metadata"
+	_ "github.com/rtcdance/streamgate/pkg/plugins/moderation"
 	_ "github.com/rtcdance/streamgate/pkg/plugins/monitor"
 	_ "github.com/rtcdance/streamgate/pkg/plugins/streaming"
 	_ "github.com/rtcdance/streamgate/pkg/plugins/transcoder"
@@ -29,6 +31,12 @@ import (
 )
 
 func main() {
+	config.BindFlags(flag.CommandLine)
+	flag.Parse()
+	if flag.Arg(0) == "config" {
+		os.Exit(runConfigCommand(flag.Args()[1:], os.Stdout, os.Stderr))
+	}
+
 	// Initialize logger
 	log := logger.NewDevelopmentLogger("streamgate-monolith")
 	defer func() { _ = log.Sync() }()
@@ -56,6 +64,8 @@ func main() {
 	}
 	log.Info("Configuration loaded", zap.String("mode", cfg.Mode), zap.Int("port", cfg.Server.Port))
 
+	// The connection is kept to audit plugin lifecycle when audit is enabled.
+	var auditLogger *storage.PostgresAuditLogger
 	dsn := cfg.Database.GetDSN()
 	if dsn != "" {
 		db, err := sql.Open("postgres", dsn)
@@ -68,7 +78,16 @@ func main() {
 			} else {
 				log.Info("Auto-migration completed")
 			}
-			_ = db.Close()
+			if cfg.Audit.Enabled {
+				auditLogger = storage.NewPostgresAuditLogger(storage.NewPostgresDBFromDB(db), log.Named("audit"))
+				auditLogger.Start()
+				defer func() {
+					_ = auditLogger.Close()
+					_ = db.Close()
+				}()
+			} else {
+				_ = db.Close()
+			}
 		}
 	}
 
@@ -77,6 +96,9 @@ func main() {
 	if err != nil {
 		log.Fatal("Failed to initialize microkernel", zap.Error(err))
 	}
+	if auditLogger != nil {
+		kernel.SetAuditLogger(auditLogger)
+	}
 
 	// Register all plugins discovered via init() auto-registration
 	// Each plugin package's init() calls core.RegisterPluginFactory()
@@ -87,6 +109,11 @@ func main() {
 	log.Info("All registered plugins loaded",
 		zap.Strings("plugins", core.RegisteredPluginNames()))
 
+	// Out-of-process plugins from plugins.external
+	if err := extplugin.Register(context.Background(), kernel, log); err != nil {
+		log.Fatal("Failed to load external plugins", zap.Error(err))
+	}
+
 	// Start microkernel
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -95,23 +122,16 @@ func main() {
 		log.Fatal("Failed to start microkernel", zap.Error(err))
 	}
 
-	log.Info("StreamGate Monolithic Mode started successfully")
-
-	// Wait for shutdown signal
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
-	sig := <-sigChan
-	log.Info("Received shutdown signal", zap.String("signal", sig.String()))
-
-	core.SetDraining()
-	log.Info("Drain state activated, rejecting new requests")
+	if len(cfg.Plugins.External) > 0 {
+		// Acts only while plugins.hot_reload is on.
+		go extplugin.Watch(ctx, kernel, cfg.Plugins.GetWatchInterval(), log)
+	}
 
-	// Graceful shutdown with timeout
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	log.Info("StreamGate Monolithic Mode started successfully")
 
-	if err := kernel.Shutdown(shutdownCtx); err != nil {
+	runner := core.NewRunner(log, cfg.Server.GetDrainDelay(), cfg.Server.GetShutdownTimeout())
+	runner.OnShutdown("kernel", kernel.Shutdown)
+	if err := runner.Wait(ctx); err != nil {
 		log.Error("Error during shutdown", zap.Error(err))
 		os.Exit(1)
 	}
@@ -0,0 +1 @@
+ALTER TABLE uploads ADD COLUMN IF NOT EXISTS total_chunks INTEGER;
@@ -0,0 +1 @@
+ALTER TABLE uploads ADD COLUMN IF NOT EXISTS multipart_id VARCHAR(1024);
@@ -0,0 +1,2 @@
+ALTER TABLE uploads DROP COLUMN IF EXISTS scan_signature;
+ALTER TABLE uploads DROP COLUMN IF EXISTS scan_status;
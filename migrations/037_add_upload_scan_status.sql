@@ -0,0 +1,2 @@
+ALTER TABLE uploads ADD COLUMN IF NOT EXISTS scan_status VARCHAR(32);
+ALTER TABLE uploads ADD COLUMN IF NOT EXISTS scan_signature VARCHAR(255);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_contents_moderation_pending;
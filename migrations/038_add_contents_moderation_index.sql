@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_contents_moderation_pending ON contents (updated_at) WHERE metadata->>'moderation_status' = 'pending_review';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_contents_tags;
+DROP INDEX IF EXISTS idx_contents_search_vector;
+ALTER TABLE contents DROP COLUMN IF EXISTS search_vector;
+DROP FUNCTION IF EXISTS contents_search_tags(TEXT[]);
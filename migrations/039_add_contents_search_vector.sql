@@ -0,0 +1,14 @@
+-- array_to_string is only STABLE, so generated columns need an IMMUTABLE wrapper.
+CREATE OR REPLACE FUNCTION contents_search_tags(tags TEXT[]) RETURNS TEXT
+    LANGUAGE sql IMMUTABLE PARALLEL SAFE
+    AS $$ SELECT COALESCE(array_to_string(tags, ' '), '') $$;
+
+ALTER TABLE contents ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', COALESCE(title, '')), 'A') ||
+        setweight(to_tsvector('simple', contents_search_tags(tags)), 'B') ||
+        setweight(to_tsvector('simple', COALESCE(description, '')), 'C')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_contents_search_vector ON contents USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_contents_tags ON contents USING GIN (tags);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS event_outbox (
+    id VARCHAR(128) PRIMARY KEY,
+    event_type VARCHAR(128) NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    available_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_outbox_due ON event_outbox(available_at) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_event_outbox_published ON event_outbox(published_at) WHERE published_at IS NOT NULL;
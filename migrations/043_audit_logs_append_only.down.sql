@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS audit_logs_no_truncate ON audit_logs;
+DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs;
+DROP FUNCTION IF EXISTS audit_logs_append_only();
+
+ALTER TABLE audit_logs
+    DROP COLUMN IF EXISTS trace_id,
+    DROP COLUMN IF EXISTS ip;
@@ -0,0 +1 @@
+ALTER TABLE uploads ADD COLUMN IF NOT EXISTS chunk_size BIGINT;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS transcode_budget_reservations;
+DROP TABLE IF EXISTS transcode_budgets;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS transcode_budgets (
+    wallet_address VARCHAR(128) NOT NULL,
+    period CHAR(7) NOT NULL,
+    consumed DOUBLE PRECISION NOT NULL DEFAULT 0,
+    reserved DOUBLE PRECISION NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (wallet_address, period)
+);
+
+CREATE TABLE IF NOT EXISTS transcode_budget_reservations (
+    task_id VARCHAR(128) PRIMARY KEY,
+    wallet_address VARCHAR(128) NOT NULL,
+    period CHAR(7) NOT NULL,
+    cost DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
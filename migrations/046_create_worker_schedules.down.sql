@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS worker_schedule_lease;
+DROP TABLE IF EXISTS worker_schedules;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS worker_schedules (
+    id VARCHAR(255) PRIMARY KEY,
+    schedule JSONB NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS worker_schedule_lease (
+    id SMALLINT PRIMARY KEY CHECK (id = 1),
+    holder VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
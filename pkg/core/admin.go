@@ -0,0 +1,302 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrPluginNotFound is returned for a name no plugin is registered
+	// under.
+	ErrPluginNotFound = errors.New("plugin not found")
+	// ErrPluginConflict is returned when a plugin's state or that of the
+	// plugins it depends on, or that depend on it, rules out an operation.
+	ErrPluginConflict = errors.New("plugin operation not allowed")
+)
+
+const (
+	// adminOpTimeout bounds a start, stop or reload run by the admin API.
+	adminOpTimeout = 60 * time.Second
+	// maxConfigPatchSize bounds the body of PUT /admin/config.
+	maxConfigPatchSize = 1 << 20
+)
+
+// PluginInfo describes a registered plugin and its lifecycle status.
+type PluginInfo struct {
+	Name      string      `json:"name"`
+	Version   string      `json:"version"`
+	DependsOn []string    `json:"depends_on"`
+	State     PluginState `json:"state"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// Plugins lists the registered plugins by name.
+func (m *Microkernel) Plugins() []PluginInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	infos := make([]PluginInfo, 0, len(m.plugins))
+	for name, plugin := range m.plugins {
+		status := m.status[name]
+		infos = append(infos, PluginInfo{
+			Name:      name,
+			Version:   plugin.Version(),
+			DependsOn: plugin.DependsOn(),
+			State:     status.State,
+			Error:     status.Error,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// StartPlugin initializes and starts a stopped or failed plugin again
+// while the kernel runs. The plugins it depends on must be running.
+func (m *Microkernel) StartPlugin(ctx context.Context, name string) error {
+	m.admin.Lock()
+	defer m.admin.Unlock()
+
+	plugin, err := m.managedPlugin(name)
+	if err != nil {
+		return err
+	}
+	if state := m.pluginState(name); state == StateRunning {
+		return fmt.Errorf("%w: plugin %s is already running", ErrPluginConflict, name)
+	}
+	return m.restartPlugin(ctx, plugin)
+}
+
+// StopPlugin stops a running plugin while the kernel runs. It is refused
+// while a running plugin depends on it.
+func (m *Microkernel) StopPlugin(ctx context.Context, name string) error {
+	m.admin.Lock()
+	defer m.admin.Unlock()
+
+	plugin, err := m.managedPlugin(name)
+	if err != nil {
+		return err
+	}
+	if state := m.pluginState(name); state != StateRunning {
+		return fmt.Errorf("%w: plugin %s is %s, not running", ErrPluginConflict, name, state)
+	}
+	for _, info := range m.Plugins() {
+		if info.State == StateRunning && slices.Contains(info.DependsOn, name) {
+			return fmt.Errorf("%w: plugin %s is required by running plugin %s", ErrPluginConflict, name, info.Name)
+		}
+	}
+	if err := m.stopPlugin(ctx, plugin); err != nil {
+		return err
+	}
+	m.logger.Info("Plugin stopped by admin", zap.String("name", name))
+	return nil
+}
+
+// ReloadPlugin stops a plugin if it runs, then initializes and starts it
+// again, so it picks up the current configuration. Plugins depending on
+// it keep running.
+func (m *Microkernel) ReloadPlugin(ctx context.Context, name string) error {
+	m.admin.Lock()
+	defer m.admin.Unlock()
+
+	plugin, err := m.managedPlugin(name)
+	if err != nil {
+		return err
+	}
+	if m.pluginState(name) == StateRunning {
+		if err := m.stopPlugin(ctx, plugin); err != nil {
+			return fmt.Errorf("failed to stop plugin %s: %w", name, err)
+		}
+	}
+	return m.restartPlugin(ctx, plugin)
+}
+
+// managedPlugin returns the plugin the admin operations may act on: a
+// registered one, once the kernel has started, that runs on its own
+// rather than being served by the api-gateway.
+func (m *Microkernel) managedPlugin(name string) (Plugin, error) {
+	m.mu.RLock()
+	plugin, ok := m.plugins[name]
+	started := m.started
+	m.mu.RUnlock()
+
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("%w: %s", ErrPluginNotFound, name)
+	case !started:
+		return nil, fmt.Errorf("%w: microkernel not started", ErrPluginConflict)
+	case m.servedByGateway(plugin):
+		return nil, fmt.Errorf("%w: plugin %s is served by the api-gateway in %s mode", ErrPluginConflict, name, m.config.Mode)
+	}
+	return plugin, nil
+}
+
+// restartPlugin initializes and starts plugin once its dependencies run.
+// Init comes first because plugins set up their servers there and do not
+// expect Start again after Stop.
+func (m *Microkernel) restartPlugin(ctx context.Context, plugin Plugin) error {
+	for _, dep := range plugin.DependsOn() {
+		depPlugin, err := m.GetPlugin(dep)
+		if err != nil {
+			return fmt.Errorf("%w: plugin %s depends on %s which is not registered", ErrPluginConflict, plugin.Name(), dep)
+		}
+		if m.pluginState(dep) != StateRunning && !m.servedByGateway(depPlugin) {
+			return fmt.Errorf("%w: plugin %s depends on %s which is not running", ErrPluginConflict, plugin.Name(), dep)
+		}
+	}
+	if err := m.initPlugin(ctx, plugin); err != nil {
+		return fmt.Errorf("failed to initialize plugin %s: %w", plugin.Name(), err)
+	}
+	if err := m.startPlugin(ctx, plugin); err != nil {
+		return fmt.Errorf("failed to start plugin %s: %w", plugin.Name(), err)
+	}
+	m.logger.Info("Plugin started by admin", zap.String("name", plugin.Name()))
+	return nil
+}
+
+func (m *Microkernel) pluginState(name string) PluginState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status[name].State
+}
+
+// adminWalletKey carries the authenticated caller of AdminHandler.
+type adminWalletKey struct{}
+
+// WithAdminWallet returns ctx carrying wallet, the caller of AdminHandler.
+// The caller must have authenticated the wallet; AdminHandler trusts it.
+func WithAdminWallet(ctx context.Context, wallet string) context.Context {
+	return context.WithValue(ctx, adminWalletKey{}, wallet)
+}
+
+// AdminHandler serves the plugin admin API to callers whose wallet, as
+// set by WithAdminWallet, is in auth.admin_wallets:
+//
+//	GET  /admin/plugins                  every plugin and its state
+//	POST /admin/plugins/{name}/start     also stop and reload
+//	PUT  /admin/config                   apply a YAML or JSON config patch
+//
+// Start, stop and reload answer 202 and run in the background, as the
+// plugin may be the one serving the request; GET /admin/plugins shows
+// the outcome. Only the api-gateway serves it, behind its JWT auth; no
+// request header can grant access.
+func (m *Microkernel) AdminHandler() http.Handler {
+	if m == nil {
+		return http.NotFoundHandler()
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/plugins", func(w http.ResponseWriter, r *http.Request) {
+		writeAdminJSON(w, http.StatusOK, map[string]interface{}{"plugins": m.Plugins()})
+	})
+	mux.HandleFunc("POST /admin/plugins/{name}/{action}", m.handlePluginAction)
+	mux.HandleFunc("PUT /admin/config", m.handleConfigPatch)
+	return m.requireAdmin(mux)
+}
+
+// requireAdmin checks the caller against the current auth.admin_wallets,
+// so a config patch can change who the admins are.
+func (m *Microkernel) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wallet, _ := r.Context().Value(adminWalletKey{}).(string)
+		allowed := false
+		for _, admin := range m.configs.Get().Auth.AdminWallets {
+			if wallet != "" && strings.EqualFold(admin, wallet) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			writeAdminError(w, http.StatusForbidden, "admin access required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (m *Microkernel) handlePluginAction(w http.ResponseWriter, r *http.Request) {
+	name, action := r.PathValue("name"), r.PathValue("action")
+	var op func(context.Context, string) error
+	switch action {
+	case "start":
+		op = m.StartPlugin
+	case "stop":
+		op = m.StopPlugin
+	case "reload":
+		op = m.ReloadPlugin
+	default:
+		writeAdminError(w, http.StatusNotFound, "unknown plugin action: want start, stop or reload")
+		return
+	}
+	// Fail fast on what can be told now; the operation checks again.
+	if _, err := m.managedPlugin(name); err != nil {
+		writeAdminError(w, adminStatus(err), err.Error())
+		return
+	}
+
+	m.logger.Info("Plugin admin request",
+		zap.String("name", name),
+		zap.String("action", action),
+		zap.String("wallet", r.Header.Get("X-Wallet-Address")))
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), adminOpTimeout)
+		defer cancel()
+		if err := op(ctx, name); err != nil {
+			m.logger.Error("Plugin admin request failed",
+				zap.String("name", name),
+				zap.String("action", action),
+				zap.Error(err))
+		}
+	}()
+	writeAdminJSON(w, http.StatusAccepted, map[string]string{"name": name, "action": action, "status": "accepted"})
+}
+
+func (m *Microkernel) handleConfigPatch(w http.ResponseWriter, r *http.Request) {
+	patch, err := io.ReadAll(io.LimitReader(r.Body, maxConfigPatchSize+1))
+	if err != nil || len(patch) > maxConfigPatchSize {
+		writeAdminError(w, http.StatusBadRequest, "config patch missing or too large")
+		return
+	}
+	old := m.configs.Get()
+	if err := m.configs.Apply(patch); err != nil {
+		writeAdminError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	changed := config.ChangedSections(old, m.configs.Get())
+	m.logger.Info("Configuration patched by admin",
+		zap.Strings("sections", changed),
+		zap.String("wallet", r.Header.Get("X-Wallet-Address")))
+	writeAdminJSON(w, http.StatusOK, map[string]interface{}{"changed": changed})
+}
+
+// adminStatus maps an admin operation error to its HTTP status.
+func adminStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrPluginNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrPluginConflict):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeAdminError(w http.ResponseWriter, status int, message string) {
+	writeAdminJSON(w, status, map[string]string{"error": message})
+}
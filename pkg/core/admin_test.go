@@ -0,0 +1,153 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newAdminTestKernel returns a started kernel with auth depending on db,
+// in a mode where every plugin runs on its own.
+func newAdminTestKernel(t *testing.T) *Microkernel {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.EventBus.Driver = "memory"
+	cfg.Server.Port = 8080
+	cfg.Database.Host = "localhost"
+	cfg.Auth.AdminWallets = []string{"0xAdmin"}
+	kernel, err := NewMicrokernel(cfg, zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, kernel.RegisterPlugin(&mockPlugin{name: "db", version: "1.0.0"}))
+	require.NoError(t, kernel.RegisterPlugin(&mockPlugin{name: "auth", version: "2.0.0", deps: []string{"db"}}))
+	require.NoError(t, kernel.Start(context.Background()))
+	t.Cleanup(func() { _ = kernel.Shutdown(context.Background()) })
+	return kernel
+}
+
+func TestMicrokernel_PluginOperations(t *testing.T) {
+	ctx := context.Background()
+	kernel := newAdminTestKernel(t)
+
+	assert.Equal(t, []PluginInfo{
+		{Name: "auth", Version: "2.0.0", DependsOn: []string{"db"}, State: StateRunning},
+		{Name: "db", Version: "1.0.0", State: StateRunning},
+	}, kernel.Plugins())
+
+	assert.ErrorIs(t, kernel.StartPlugin(ctx, "auth"), ErrPluginConflict, "already running")
+	assert.ErrorIs(t, kernel.StopPlugin(ctx, "db"), ErrPluginConflict, "auth depends on it")
+	assert.ErrorIs(t, kernel.ReloadPlugin(ctx, "nope"), ErrPluginNotFound)
+
+	require.NoError(t, kernel.StopPlugin(ctx, "auth"))
+	require.NoError(t, kernel.StopPlugin(ctx, "db"))
+	assert.ErrorIs(t, kernel.StopPlugin(ctx, "db"), ErrPluginConflict, "not running")
+	assert.ErrorIs(t, kernel.StartPlugin(ctx, "auth"), ErrPluginConflict, "db is not running")
+
+	require.NoError(t, kernel.StartPlugin(ctx, "db"))
+	require.NoError(t, kernel.StartPlugin(ctx, "auth"))
+	require.NoError(t, kernel.ReloadPlugin(ctx, "db"), "dependents do not block a reload")
+	for _, info := range kernel.Plugins() {
+		assert.Equal(t, StateRunning, info.State, info.Name)
+	}
+
+	p, err := kernel.GetPlugin("auth")
+	require.NoError(t, err)
+	p.(*mockPlugin).startErr = assert.AnError
+	assert.ErrorIs(t, kernel.ReloadPlugin(ctx, "auth"), assert.AnError)
+	status, err := kernel.GetPluginStatus("auth")
+	require.NoError(t, err)
+	assert.Equal(t, StateError, status.State)
+}
+
+func TestMicrokernel_PluginOperations_Monolith(t *testing.T) {
+	kernel := newTestKernel(t)
+	require.NoError(t, kernel.RegisterPlugin(&mockPlugin{name: "cache", version: "1.0.0"}))
+	assert.ErrorIs(t, kernel.ReloadPlugin(context.Background(), "cache"), ErrPluginConflict, "kernel not started")
+
+	require.NoError(t, kernel.Start(context.Background()))
+	defer func() { _ = kernel.Shutdown(context.Background()) }()
+	err := kernel.ReloadPlugin(context.Background(), "cache")
+	assert.ErrorIs(t, err, ErrPluginConflict)
+	assert.Contains(t, err.Error(), "served by the api-gateway")
+}
+
+func TestMicrokernel_AdminHandler(t *testing.T) {
+	kernel := newAdminTestKernel(t)
+	handler := kernel.AdminHandler()
+	serve := func(method, path, wallet, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if wallet != "" {
+			req = req.WithContext(WithAdminWallet(req.Context(), wallet))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		wallet   string
+		body     string
+		wantCode int
+		want     string
+	}{
+		{"no wallet", http.MethodGet, "/admin/plugins", "", "", http.StatusForbidden, "admin access required"},
+		{"not an admin", http.MethodGet, "/admin/plugins", "0xother", "", http.StatusForbidden, "admin access required"},
+		{"list", http.MethodGet, "/admin/plugins", "0xadmin", "", http.StatusOK, `"name":"auth"`},
+		{"unknown plugin", http.MethodPost, "/admin/plugins/nope/reload", "0xAdmin", "", http.StatusNotFound, "plugin not found"},
+		{"unknown action", http.MethodPost, "/admin/plugins/auth/pause", "0xAdmin", "", http.StatusNotFound, "want start, stop or reload"},
+		{"wrong method", http.MethodGet, "/admin/plugins/auth/reload", "0xAdmin", "", http.StatusMethodNotAllowed, ""},
+		{"reload", http.MethodPost, "/admin/plugins/auth/reload", "0xAdmin", "", http.StatusAccepted, `"status":"accepted"`},
+		{"bad patch", http.MethodPut, "/admin/config", "0xAdmin", "nosuchkey: 1\n", http.StatusUnprocessableEntity, "invalid config patch"},
+		{"patch", http.MethodPut, "/admin/config", "0xAdmin", "appname: patched\n", http.StatusOK, `"changed":["AppName"]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(tt.method, tt.path, tt.wallet, tt.body)
+			assert.Equal(t, tt.wantCode, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), tt.want)
+		})
+	}
+	assert.Equal(t, "patched", kernel.GetConfigManager().Get().AppName)
+
+	// The reload ran in the background; auth is back to running.
+	assert.Eventually(t, func() bool {
+		w := serve(http.MethodGet, "/admin/plugins", "0xAdmin", "")
+		var resp struct{ Plugins []PluginInfo }
+		return json.Unmarshal(w.Body.Bytes(), &resp) == nil &&
+			len(resp.Plugins) == 2 && resp.Plugins[0].State == StateRunning
+	}, time.Second, 10*time.Millisecond)
+
+	t.Run("admins follow the config", func(t *testing.T) {
+		w := serve(http.MethodPut, "/admin/config", "0xAdmin", "auth:\n  adminwallets: [\"0xNew\"]\n")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/admin/plugins", "0xAdmin", "").Code)
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/admin/plugins", "0xnew", "").Code)
+	})
+
+	t.Run("header is not trusted", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin/plugins", http.NoBody)
+		req.Header.Set("X-Wallet-Address", "0xNew")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("nil kernel", func(t *testing.T) {
+		var nilKernel *Microkernel
+		w := httptest.NewRecorder()
+		nilKernel.AdminHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/plugins", http.NoBody))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -3,17 +3,23 @@ package config
 import (
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/rtcdance/streamgate/pkg/core/event"
+	"github.com/rtcdance/streamgate/pkg/monitoring"
+	"github.com/rtcdance/streamgate/pkg/web3/signature"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
-	"go.yaml.in/yaml/v3"
 )
 
 var envVarPattern = regexp.MustCompile(`\$\{([^}]+)\}`)
@@ -33,7 +39,10 @@ func expandEnvWithDefaults(s string) string {
 	})
 }
 
-func readConfigWithExpansion(configName string, merge bool, configPaths ...string) error {
+// readConfigWithExpansion reads the first configName.yaml found in
+// configPaths into viper, merging it over what was read before when merge
+// is set. It returns the file's path and the keys it sets.
+func readConfigWithExpansion(configName string, merge bool, configPaths ...string) (string, []string, error) {
 	for _, cp := range configPaths {
 		path := filepath.Join(cp, configName+".yaml")
 		data, err := os.ReadFile(path)
@@ -41,20 +50,36 @@ func readConfigWithExpansion(configName string, merge bool, configPaths ...strin
 			if os.IsNotExist(err) {
 				continue
 			}
-			return fmt.Errorf("error reading config file %s: %w", path, err)
+			return "", nil, fmt.Errorf("error reading config file %s: %w", path, err)
+		}
+		// Migrate before expanding so a saved migration keeps ${VAR}
+		// references rather than their values.
+		data, err = migrateConfigFile(path, data)
+		if err != nil {
+			return "", nil, err
 		}
 		expanded := expandEnvWithDefaults(string(data))
+		keys, err := fileKeys(expanded)
+		if err != nil {
+			return "", nil, fmt.Errorf("error parsing config file %s: %w", path, err)
+		}
 		viper.SetConfigType("yaml")
 		if merge {
-			return viper.MergeConfig(strings.NewReader(expanded))
+			err = viper.MergeConfig(strings.NewReader(expanded))
+		} else {
+			err = viper.ReadConfig(strings.NewReader(expanded))
 		}
-		return viper.ReadConfig(strings.NewReader(expanded))
+		return path, keys, err
 	}
-	return viper.ConfigFileNotFoundError{}
+	return "", nil, viper.ConfigFileNotFoundError{}
 }
 
 // Config holds the application configuration
 type Config struct {
+	// Version is the config schema version; older files are migrated to
+	// CurrentVersion on load.
+	Version string
+
 	// Application
 	AppName     string
 	Mode        string // "monolith" or "microservice"
@@ -80,15 +105,48 @@ type Config struct {
 	// NATS (for microservices mode)
 	NATS NATSConfig
 
+	// EventBus selects the event bus backend
+	EventBus EventBusConfig
+
 	// Consul (for service discovery)
 	Consul ConsulConfig
 
+	// Discovery selects the service discovery backend
+	Discovery DiscoveryConfig
+
+	// Remote is the central config document layered over the local files
+	Remote RemoteConfig
+
 	// Transcoding
 	Transcoding TranscodingConfig
 
 	// Streaming
 	Streaming StreamingConfig
 
+	// Live ingest
+	Live LiveConfig
+
+	// Content moderation
+	Moderation ModerationConfig
+
+	// Archival of original uploads
+	Archive ArchiveConfig
+
+	// Storage tiering and retention
+	Lifecycle LifecycleConfig
+
+	// Metadata search
+	Search SearchConfig
+
+	// Outbound webhooks for platform events
+	Webhooks WebhooksConfig
+
+	// Audit log of security-relevant actions
+	Audit AuditConfig
+
+	// Content encryption
+	Encryption EncryptionConfig
+
 	// Web3
 	Web3 Web3Config
 
@@ -101,6 +159,9 @@ type Config struct {
 	// Circuit Breaker
 	CircuitBreaker CircuitBreakerConfig
 
+	// Retry budget
+	RetryBudget RetryBudgetConfig
+
 	// Monitoring
 	Monitoring MonitoringConfig
 
@@ -121,6 +182,61 @@ type Config struct {
 
 	// Plugins (for monolithic mode)
 	Plugins PluginsConfig
+
+	// Analytics
+	Analytics AnalyticsConfig
+
+	// Gateway dispatch to standalone microservices
+	Gateway GatewayConfig
+
+	// Background job worker
+	Worker WorkerConfig
+}
+
+// GatewayConfig holds api-gateway dispatch configuration
+type GatewayConfig struct {
+	// Upstreams forwards matching /api/v1 paths to standalone
+	// microservices instead of the in-process handlers. Empty serves
+	// everything in process.
+	Upstreams []UpstreamConfig
+}
+
+// UpstreamConfig is one microservice the api-gateway forwards to.
+type UpstreamConfig struct {
+	Name string `mapstructure:"name" yaml:"name"`
+	// URL is the service base URL, e.g. "http://metadata:8080".
+	URL string `mapstructure:"url" yaml:"url"`
+	// Service, used when URL is empty, resolves instances by name through
+	// the discovery backend.
+	Service string `mapstructure:"service" yaml:"service"`
+	// Prefixes are the request paths routed to this service, e.g.
+	// "/api/v1/metadata". Paths are forwarded unchanged.
+	Prefixes []string `mapstructure:"prefixes" yaml:"prefixes"`
+	// Timeout bounds each attempt (e.g. "10s").
+	Timeout string `mapstructure:"timeout" yaml:"timeout"`
+	// Retries is how many times idempotent requests are retried after a
+	// connection error or 502/503/504.
+	Retries int `mapstructure:"retries" yaml:"retries"`
+}
+
+// AnalyticsConfig holds content access analytics configuration
+type AnalyticsConfig struct {
+	// BucketSize is the granularity of access counters (e.g. "1h").
+	BucketSize string
+	// Retention is how long access counters are kept (e.g. "168h").
+	Retention string
+}
+
+// WorkerConfig holds background job worker configuration
+type WorkerConfig struct {
+	// SchedulesStore is where the recurring job schedules are kept: "file"
+	// (the default) uses SchedulesFile on this node, so run one worker
+	// replica; "postgres" shares them between replicas and only one of
+	// them runs the schedules.
+	SchedulesStore string `yaml:"schedules_store"`
+	// SchedulesFile persists the recurring job schedules across restarts.
+	// Empty keeps them in memory only.
+	SchedulesFile string `yaml:"schedules_file"`
 }
 
 type UploadConfig struct {
@@ -128,6 +244,19 @@ type UploadConfig struct {
 	StorageQuota   int64    `yaml:"storage_quota"`
 	AllowedFormats []string `yaml:"allowed_formats"`
 	MaxChunks      int      `yaml:"max_chunks"`
+	MinChunkSize   int64    `yaml:"min_chunk_size"`
+	MaxChunkSize   int64    `yaml:"max_chunk_size"`
+	Scan           UploadScanConfig
+}
+
+// UploadScanConfig selects the malware scanner run on completed uploads
+// before they are processed. Backend is "clamav" (clamd at ClamAVAddr),
+// "http" (an external scanner at HTTPURL) or empty to skip scanning.
+type UploadScanConfig struct {
+	Backend    string
+	ClamAVAddr string
+	HTTPURL    string
+	Timeout    string
 }
 
 type TranscodeConfig struct {
@@ -139,6 +268,38 @@ type ServerConfig struct {
 	Port         int
 	ReadTimeout  int
 	WriteTimeout int
+	// DrainDelay is how long readiness fails before the server stops
+	// accepting requests on shutdown, so load balancers stop routing to
+	// it first, e.g. "5s".
+	DrainDelay string `yaml:"drain_delay"`
+	// ShutdownTimeout bounds the shutdown after the drain delay, e.g.
+	// "30s". In-flight transcodes not done by then are checkpointed.
+	ShutdownTimeout string `yaml:"shutdown_timeout"`
+}
+
+// Defaults used when server.drain_delay or server.shutdown_timeout is
+// unset or invalid.
+const (
+	DefaultDrainDelay      = 5 * time.Second
+	DefaultShutdownTimeout = 30 * time.Second
+)
+
+// GetDrainDelay returns DrainDelay parsed as a duration, falling back to
+// DefaultDrainDelay. "0s" disables the delay.
+func (c *ServerConfig) GetDrainDelay() time.Duration {
+	if d, err := time.ParseDuration(c.DrainDelay); err == nil && d >= 0 {
+		return d
+	}
+	return DefaultDrainDelay
+}
+
+// GetShutdownTimeout returns ShutdownTimeout parsed as a duration, falling
+// back to DefaultShutdownTimeout.
+func (c *ServerConfig) GetShutdownTimeout() time.Duration {
+	if d, err := time.ParseDuration(c.ShutdownTimeout); err == nil && d > 0 {
+		return d
+	}
+	return DefaultShutdownTimeout
 }
 
 // GRPCConfig holds gRPC configuration
@@ -147,6 +308,22 @@ type GRPCConfig struct {
 	TLSEnabled bool   `yaml:"tls_enabled"`
 	TLSCert    string `yaml:"tls_cert"`
 	TLSKey     string `yaml:"tls_key"`
+	// UnaryTimeout bounds unary calls that arrive without a deadline,
+	// e.g. "30s".
+	UnaryTimeout string `yaml:"unary_timeout"`
+}
+
+// DefaultGRPCUnaryTimeout is used when grpc.unary_timeout is unset or
+// invalid.
+const DefaultGRPCUnaryTimeout = 30 * time.Second
+
+// GetUnaryTimeout returns UnaryTimeout parsed as a duration, falling back
+// to DefaultGRPCUnaryTimeout.
+func (c *GRPCConfig) GetUnaryTimeout() time.Duration {
+	if d, err := time.ParseDuration(c.UnaryTimeout); err == nil && d > 0 {
+		return d
+	}
+	return DefaultGRPCUnaryTimeout
 }
 
 // ConsulConfig holds Consul configuration
@@ -155,9 +332,129 @@ type ConsulConfig struct {
 	Port    int
 }
 
+// DiscoveryConfig holds service discovery configuration for microservice mode
+type DiscoveryConfig struct {
+	// Backend is "consul" (default), "etcd" or "dns".
+	Backend string
+	Etcd    EtcdDiscoveryConfig
+	DNS     DNSDiscoveryConfig
+}
+
+// EtcdDiscoveryConfig holds etcd discovery configuration
+type EtcdDiscoveryConfig struct {
+	// Endpoints are etcd client URLs, e.g. "http://etcd:2379".
+	Endpoints []string
+	// Prefix is the key prefix services are registered under.
+	Prefix string
+	// TTL is the registration lease; it is renewed at a third of it.
+	TTL string
+}
+
+// DNSDiscoveryConfig holds static DNS discovery configuration. Services
+// are looked up as SRV records _<name>._tcp.<domain>, falling back to
+// A/AAAA records for <name>.<domain> on Port.
+type DNSDiscoveryConfig struct {
+	Domain string
+	Port   int
+}
+
+// RemoteConfig names a config document in etcd or Consul KV that every
+// service reads over its local config files, so settings can be changed
+// centrally.
+type RemoteConfig struct {
+	// Source is a URI understood by OpenRemoteSource, e.g.
+	// "etcd://etcd:2379/streamgate/config.yaml". Empty disables it.
+	Source string
+	// PollInterval is how often running services check the document for
+	// changes.
+	PollInterval string
+}
+
+// DefaultRemotePollInterval is used when RemoteConfig.PollInterval is unset
+// or invalid.
+const DefaultRemotePollInterval = 30 * time.Second
+
+// GetPollInterval returns PollInterval parsed as a duration, falling back
+// to DefaultRemotePollInterval.
+func (c *RemoteConfig) GetPollInterval() time.Duration {
+	if d, err := time.ParseDuration(c.PollInterval); err == nil && d > 0 {
+		return d
+	}
+	return DefaultRemotePollInterval
+}
+
 // PluginsConfig holds plugin configuration
 type PluginsConfig struct {
 	Enabled []string
+	// External are plugin binaries run as separate processes and talked
+	// to over gRPC; see package extplugin.
+	External []ExternalPluginConfig
+	// HotReload restarts an external plugin on its new binary when the
+	// file changes. It seeds ConfigManager.SetHotReload.
+	HotReload bool
+	// WatchInterval is how often external plugin binaries are checked for
+	// changes while HotReload is on.
+	WatchInterval string
+	// TrustedKeys are minisign public keys. An external plugin binary runs
+	// only with a signature by one of them.
+	TrustedKeys []string
+	// AllowUnsigned lists plugin paths, as in External, that may run
+	// without a valid signature; "*" allows all. For development only.
+	AllowUnsigned []string
+	// Limits bounds each plugin's resources, by plugin name.
+	Limits map[string]PluginLimits
+	// UsageInterval is how often plugin resource usage is sampled and the
+	// hard limits enforced.
+	UsageInterval string
+}
+
+// PluginLimits bounds one plugin's resources. Zero means unlimited.
+type PluginLimits struct {
+	// MaxConcurrentJobs bounds the jobs the plugin runs at once; further
+	// jobs wait for a slot.
+	MaxConcurrentJobs int `mapstructure:"max_concurrent_jobs" yaml:"max_concurrent_jobs" json:"max_concurrent_jobs"`
+	// MaxMemoryMB and MaxGoroutines are hard limits: a plugin over one is
+	// restarted. They apply to plugins that report their own usage, i.e.
+	// external plugins.
+	MaxMemoryMB   int `mapstructure:"max_memory_mb" yaml:"max_memory_mb" json:"max_memory_mb"`
+	MaxGoroutines int `mapstructure:"max_goroutines" yaml:"max_goroutines" json:"max_goroutines"`
+}
+
+// DefaultPluginUsageInterval is used when PluginsConfig.UsageInterval is
+// unset or invalid.
+const DefaultPluginUsageInterval = 15 * time.Second
+
+// GetUsageInterval returns UsageInterval parsed as a duration, falling
+// back to DefaultPluginUsageInterval.
+func (c *PluginsConfig) GetUsageInterval() time.Duration {
+	if d, err := time.ParseDuration(c.UsageInterval); err == nil && d > 0 {
+		return d
+	}
+	return DefaultPluginUsageInterval
+}
+
+// DefaultPluginWatchInterval is used when PluginsConfig.WatchInterval is
+// unset or invalid.
+const DefaultPluginWatchInterval = 5 * time.Second
+
+// GetWatchInterval returns WatchInterval parsed as a duration, falling
+// back to DefaultPluginWatchInterval.
+func (c *PluginsConfig) GetWatchInterval() time.Duration {
+	if d, err := time.ParseDuration(c.WatchInterval); err == nil && d > 0 {
+		return d
+	}
+	return DefaultPluginWatchInterval
+}
+
+// ExternalPluginConfig is one out-of-process plugin binary.
+type ExternalPluginConfig struct {
+	Path string   `mapstructure:"path" yaml:"path" json:"path"`
+	Args []string `mapstructure:"args" yaml:"args" json:"args"`
+	// Signature is the binary's minisign signature file, Path+".minisig"
+	// by default.
+	Signature string `mapstructure:"signature" yaml:"signature" json:"signature"`
+	// Settings are passed to the plugin's Init.
+	Settings map[string]string `mapstructure:"settings" yaml:"settings" json:"settings"`
 }
 
 // DatabaseConfig holds database configuration
@@ -171,6 +468,12 @@ type DatabaseConfig struct {
 	MaxConns        int
 	MaxIdleConns    int
 	ConnMaxLifetime string
+	// ReplicaDSNs are read-replica connection strings. Plain reads are
+	// spread across them; writes and transactions stay on the primary.
+	ReplicaDSNs []string
+	// ReplicaRetryAfter is how long a replica that failed a read is skipped
+	// (e.g. "30s").
+	ReplicaRetryAfter string
 }
 
 // RedisConfig holds Redis configuration
@@ -182,15 +485,40 @@ type RedisConfig struct {
 	PoolSize int
 }
 
-// StorageConfig holds storage configuration
+// defaultStorageEndpoint is the local MinIO used in development.
+const defaultStorageEndpoint = "localhost:9000"
+
+// StorageConfig holds storage configuration. AccessKey and SecretKey are
+// the HMAC key pair for "gcs" and the account name and key for "azure".
 type StorageConfig struct {
-	Type      string // "s3" or "minio"
+	Type      string // "minio" (default), "s3", "gcs", "azure", "local" or "ipfs"
 	Endpoint  string
 	AccessKey string
 	SecretKey string
 	Bucket    string
 	Region    string
 	UseSSL    bool
+	// Path is the root directory of "local" storage.
+	Path string
+	// IPFS configures "ipfs" storage.
+	IPFS StorageIPFSConfig
+}
+
+// StorageIPFSConfig configures the IPFS storage driver.
+type StorageIPFSConfig struct {
+	// APIURL is the Kubo RPC API objects are written through.
+	APIURL string
+	// Root is the MFS directory buckets are kept under.
+	Root string
+	// Gateways are the HTTP gateways presigned URLs point at (the first)
+	// and the streaming plugin fetches segments from, in order.
+	Gateways []string
+	// PinningServiceURL, when set, is an IPFS Pinning Service API endpoint
+	// (Pinata, web3.storage, ...) every upload is also pinned to.
+	PinningServiceURL string
+	PinningToken      string
+	// CacheSizeMB bounds the streaming plugin's cache of gateway fetches.
+	CacheSizeMB int
 }
 
 // TranscodingConfig holds transcoding configuration
@@ -199,6 +527,115 @@ type TranscodingConfig struct {
 	MaxWorkers    int
 	QueueSize     int
 	OutputFormats []string
+	// Qualities is the default profile ladder, highest rung first, used
+	// when a submission does not specify profiles.
+	Qualities []QualityConfig
+	Budget    TranscodeBudgetConfig
+	// PartDuration is the LL-HLS part length for low-latency rungs.
+	PartDuration string
+	// QueueMaxWait is how long a queued task may wait before it runs
+	// ahead of higher priority tasks.
+	QueueMaxWait string
+	// QueueStore is where the task queue is kept: "memory", lost on
+	// restart, or "redis", which survives restarts and shares tasks
+	// between transcoders.
+	QueueStore string
+	// VisibilityTimeout is how long a stored task stays leased to a
+	// transcoder that stopped renewing it before another takes it over.
+	VisibilityTimeout string
+	// Distributed lets several transcoders share the redis queue store,
+	// each claiming tasks while it has idle workers.
+	Distributed bool
+	// AllowPartialVariants publishes the rungs of a ladder that succeeded
+	// instead of failing the task when some rungs fail.
+	AllowPartialVariants bool
+	// PackagerPath is the shaka-packager binary DRM rungs are packaged
+	// with. Empty packages CENC rungs with FFmpeg; CBCS needs the packager.
+	PackagerPath string
+	// Hardware selects the hardware encoder: "none", "auto" (probe nvenc,
+	// qsv, then vaapi), or one of "nvenc", "qsv", "vaapi". Rungs fall back
+	// to software encoding when it is unavailable.
+	Hardware string
+	// VAAPIDevice is the DRM render node VAAPI encodes on.
+	VAAPIDevice string
+	// Codecs lists the codecs every ladder is transcoded in: "h264" uses
+	// Qualities, "hevc" and "av1" their ladder in CodecLadders.
+	Codecs []string
+	// CodecLadders holds the ladder presets of HEVC and AV1, keyed by
+	// codec. Codecs without one use the built-in preset.
+	CodecLadders map[string][]QualityConfig
+	PerTitle     PerTitleConfig
+	Storyboard   StoryboardConfig
+	Loudness     LoudnessConfig
+}
+
+// LoudnessConfig normalises every audio track to EBU R128 with a two-pass
+// loudnorm: Integrated in LUFS, TruePeak in dBTP and Range in LU.
+type LoudnessConfig struct {
+	Enabled    bool
+	Integrated float64
+	TruePeak   float64
+	Range      float64
+}
+
+// StoryboardConfig controls the scrub preview storyboard generated for
+// each content: a Width-pixel thumbnail every Interval, tiled Columns ×
+// Rows per JPEG sprite sheet, indexed by a WebVTT file.
+type StoryboardConfig struct {
+	Enabled  bool
+	Interval string
+	Columns  int
+	Rows     int
+	Width    int
+}
+
+// PerTitleConfig derives the ladder of each submission without profiles
+// from an analysis of its source: rungs above the source are dropped and
+// each rung's bitrate is sized to reach TargetVMAF on sampled clips.
+type PerTitleConfig struct {
+	Enabled    bool
+	TargetVMAF float64
+	// MinBitrate and MaxBitrate bound derived rungs, in bits per second.
+	MinBitrate int
+	MaxBitrate int
+	// MinRungs and MaxRungs bound the rungs kept per codec.
+	MinRungs int
+	MaxRungs int
+	// Samples clips of SampleDuration are scored per rung.
+	Samples        int
+	SampleDuration string
+}
+
+// TranscodeBudgetConfig prices transcode jobs and caps each wallet's
+// monthly spend. Cost per rung is (PerRungSecond + PerMegapixelSecond ×
+// output megapixels) for each second of source.
+type TranscodeBudgetConfig struct {
+	// MonthlyLimit is the cost units each wallet may spend per UTC month.
+	// Zero disables enforcement.
+	MonthlyLimit       float64
+	PerRungSecond      float64
+	PerMegapixelSecond float64
+	// DefaultDuration is charged when a source's duration is unknown.
+	DefaultDuration string
+}
+
+// QualityConfig is one rung of the default transcoding ladder.
+type QualityConfig struct {
+	Name    string `mapstructure:"name" yaml:"name" json:"name"`
+	Width   int    `mapstructure:"width" yaml:"width" json:"width"`
+	Height  int    `mapstructure:"height" yaml:"height" json:"height"`
+	Bitrate int    `mapstructure:"bitrate" yaml:"bitrate" json:"bitrate"` // bits per second
+	// LowLatency transcodes this rung as LL-HLS partial segments.
+	LowLatency bool `mapstructure:"low_latency" yaml:"low_latency" json:"low_latency"`
+	// DRM packages this rung as DRM-protected fMP4: "cenc" (Widevine) or
+	// "cbcs" (FairPlay and Widevine). Empty leaves the rung unprotected.
+	DRM string `mapstructure:"drm" yaml:"drm" json:"drm,omitempty"`
+	// Codec overrides the rung's video codec: a family ("h264", "hevc"),
+	// encoded in hardware when available, or an FFmpeg encoder name.
+	Codec string `mapstructure:"codec" yaml:"codec" json:"codec,omitempty"`
+	// BurnSubtitles renders the content's subtitles in this language into
+	// the rung's picture.
+	BurnSubtitles string `mapstructure:"burn_subtitles" yaml:"burn_subtitles" json:"burn_subtitles,omitempty"`
 }
 
 // StreamingConfig holds streaming configuration
@@ -208,6 +645,248 @@ type StreamingConfig struct {
 	CacheEnabled         bool
 	CacheTTL             string
 	MaxConcurrentStreams int
+	// MaxManifestSegments caps segments listed per VOD media playlist.
+	MaxManifestSegments int
+	// LiveWindowSegments is the sliding window size of live playlists.
+	LiveWindowSegments int
+	// Egress routes segment URLs to regional edges; empty serves from origin.
+	Egress EgressConfig
+	// DRM configures license acquisition for DRM-protected renditions.
+	DRM DRMConfig
+}
+
+// DRMConfig configures the license proxy for DRM-protected content.
+type DRMConfig struct {
+	// LicenseServers are the operator's license servers, one per DRM
+	// system. Playback of a system without one is refused.
+	LicenseServers []LicenseServerConfig
+	// LicenseTimeout bounds each license server request.
+	LicenseTimeout string
+}
+
+// LicenseServerConfig is the license server of one DRM system.
+type LicenseServerConfig struct {
+	// System is "widevine" or "fairplay".
+	System string `mapstructure:"system" yaml:"system" json:"system"`
+	// URL receives license challenges as POST bodies.
+	URL string `mapstructure:"url" yaml:"url" json:"url"`
+	// CertificateURL serves the FairPlay application certificate.
+	CertificateURL string `mapstructure:"certificate_url" yaml:"certificate_url" json:"certificate_url,omitempty"`
+}
+
+// LiveConfig holds live ingest configuration
+type LiveConfig struct {
+	// Enabled starts the ingest listeners and live stream routes.
+	Enabled bool
+	// RTMPPort is the port encoders publish to.
+	RTMPPort int
+	// SRTPort is the UDP port SRT callers publish to; 0 disables SRT.
+	SRTPort int
+	// WorkDir holds the HLS output of live streams.
+	WorkDir    string
+	FFmpegPath string
+	// SegmentDuration is the live HLS segment length in seconds.
+	SegmentDuration int
+}
+
+// ModerationConfig configures review of new uploads. Frames sampled with
+// ffmpeg are sent to the provider at ProviderURL, a self-hosted model or an
+// external API speaking the same JSON protocol, and the content is held in
+// pending_review until a moderator approves it.
+type ModerationConfig struct {
+	Enabled     bool
+	ProviderURL string
+	// APIKey is sent as a bearer token to the provider.
+	APIKey     string
+	FFmpegPath string
+	// FrameInterval is the time between sampled frames, e.g. "10s".
+	FrameInterval string
+	MaxFrames     int
+	// Threshold is the label score, 0-1, at or above which content is
+	// flagged for a moderator.
+	Threshold float64
+	// AutoApprove approves content the provider does not flag; otherwise
+	// every upload waits for a moderator.
+	AutoApprove bool
+	Timeout     string
+	// WebhookURLs are notified when content needs review and when it is
+	// decided, signed with WebhookSecret when set.
+	WebhookURLs   []string
+	WebhookSecret string
+}
+
+// ArchiveConfig configures the permanent archival tier. Once an upload is
+// transcoded its original is pushed to Arweave through an ANS-104 bundler
+// and can later be restored from it. Archived data is public and can never
+// be deleted, so only enable it for content that may be published.
+type ArchiveConfig struct {
+	Enabled bool
+	Arweave ArweaveArchiveConfig
+	// Timeout bounds archiving or restoring one original, e.g. "1h".
+	Timeout string
+}
+
+// ArweaveArchiveConfig locates the Arweave bundler, gateway and wallet.
+type ArweaveArchiveConfig struct {
+	// BundlerURL is where signed data items are posted.
+	BundlerURL string
+	// GatewayURL serves archived originals for restores.
+	GatewayURL string
+	// WalletPath is the JWK keyfile of the wallet that signs uploads; the
+	// bundler must be funded for it.
+	WalletPath string
+}
+
+// LifecycleConfig defines storage tiering and retention policies, applied
+// on a schedule and through the admin lifecycle API. A zero threshold
+// disables its policy.
+type LifecycleConfig struct {
+	Enabled bool
+	// Interval between scheduled runs, e.g. "24h". Empty leaves runs to
+	// the admin API.
+	Interval string
+	// DryRun makes scheduled runs only log what they would change.
+	DryRun bool
+	// ColdBucket receives originals older than ColdAfterDays, e.g. a bucket
+	// with an archive storage class.
+	ColdBucket    string
+	ColdAfterDays int
+	// PurgeRenditionsAfterDays deletes the renditions of content nobody has
+	// played for this many days, except those named in KeepRenditions.
+	// Purged renditions are rebuilt by transcoding the original again.
+	PurgeRenditionsAfterDays int
+	KeepRenditions           []string
+	// OrphanChunkAge is how long an upload must go untouched before its
+	// chunks are deleted, e.g. "72h".
+	OrphanChunkAge string
+	// BatchSize caps the items each policy handles per run.
+	BatchSize int
+}
+
+// SearchConfig selects the backend of the metadata search API. Postgres
+// full-text search needs nothing beyond the database; the elasticsearch
+// driver, which also works with OpenSearch, keeps an index fed from it.
+type SearchConfig struct {
+	Enabled bool
+	// Driver is "postgres" or "elasticsearch".
+	Driver        string
+	Elasticsearch ElasticsearchSearchConfig
+	// ReindexInterval between full rebuilds of the elasticsearch index,
+	// e.g. "1h". Moderation and gating changes reach the index this way;
+	// new content is indexed once transcoded.
+	ReindexInterval string
+}
+
+// ElasticsearchSearchConfig locates the Elasticsearch or OpenSearch
+// cluster. APIKey takes precedence over Username and Password.
+type ElasticsearchSearchConfig struct {
+	URL      string
+	Index    string
+	APIKey   string
+	Username string
+	Password string
+	Timeout  string
+}
+
+// WebhooksConfig tunes delivery of platform events to the webhook
+// endpoints operators register through the admin API. Deliveries that
+// still fail after MaxAttempts are kept as dead letters for redelivery.
+type WebhooksConfig struct {
+	Enabled     bool
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, e.g. "30s"; it
+	// doubles with every failed attempt up to MaxBackoff.
+	InitialBackoff string
+	MaxBackoff     string
+	// Timeout bounds each delivery request.
+	Timeout string
+	// PollInterval is how often due retries are looked for.
+	PollInterval string
+}
+
+// AuditConfig controls the audit log of logins, NFT gate decisions,
+// content changes and plugin lifecycle, kept in the audit_logs table.
+type AuditConfig struct {
+	Enabled bool
+	// Retention is how long entries are kept, e.g. "2160h"; "0" keeps
+	// them forever.
+	Retention string
+	// Export ships a copy of every entry to an external system.
+	Export AuditExportConfig
+}
+
+// AuditExportConfig configures shipping audit entries to a file, an
+// object store bucket or a SIEM collector over HTTP.
+type AuditExportConfig struct {
+	Enabled bool
+	// Sink is "file", "object" or "http".
+	Sink string
+	// Format is "jsonl" or "cef".
+	Format string
+	// Path is the file the file sink appends to, rotated once it would
+	// exceed MaxBytes; zero never rotates.
+	Path     string
+	MaxBytes int64
+	// Bucket and Prefix are where the object sink writes one object per
+	// batch; Bucket defaults to storage.bucket.
+	Bucket string
+	Prefix string
+	// URL is the collector the http sink posts batches to, with Headers,
+	// e.g. an Authorization token, on every request.
+	URL     string
+	Headers map[string]string
+	// BatchSize and FlushInterval bound how long an entry waits to ship.
+	BatchSize     int
+	FlushInterval string
+}
+
+// GetFlushInterval returns FlushInterval parsed as a duration; zero uses
+// the exporter default.
+func (c *AuditExportConfig) GetFlushInterval() time.Duration {
+	d, err := time.ParseDuration(c.FlushInterval)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// GetRetention returns Retention parsed as a duration; zero means entries
+// are never purged.
+func (c *AuditConfig) GetRetention() time.Duration {
+	d, err := time.ParseDuration(c.Retention)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// EncryptionConfig configures AES-128 encryption of HLS segments. Keys are
+// generated per content and kept in KeyDir, sealed with MasterKey, so the
+// transcoder and the streaming service must share both.
+type EncryptionConfig struct {
+	Enabled bool
+	// KeyDir holds the sealed content keys.
+	KeyDir string
+	// MasterKey is the hex-encoded 256-bit key that seals content keys.
+	MasterKey string
+}
+
+// EgressConfig maps clients to regional segment edges by country.
+type EgressConfig struct {
+	// CountryHeader carries the client's ISO country code, as set by the
+	// CDN or load balancer in front of the gateway.
+	CountryHeader string
+	// DefaultRegion serves clients whose country is unmapped. Empty means
+	// origin.
+	DefaultRegion string
+	Regions       []EgressRegionConfig
+}
+
+// EgressRegionConfig is one regional edge for segment delivery.
+type EgressRegionConfig struct {
+	Name      string   `mapstructure:"name" yaml:"name" json:"name"`
+	BaseURL   string   `mapstructure:"base_url" yaml:"base_url" json:"base_url"`
+	Countries []string `mapstructure:"countries" yaml:"countries" json:"countries"`
 }
 
 // RateLimitingConfig holds rate limiting configuration
@@ -245,6 +924,36 @@ type NATSConfig struct {
 	URL string
 }
 
+// EventBusConfig selects the backend that carries plugin and platform
+// events. Driver is "memory", "nats" (core NATS, at-most-once),
+// "jetstream" or "kafka"; empty uses memory in monolith mode and nats
+// otherwise. JetStream and Kafka deliver at least once to consumer groups.
+type EventBusConfig struct {
+	Driver string
+	// Group is the consumer group of subscriptions that name none,
+	// usually one per service.
+	Group string
+	// JetStream uses nats.url.
+	JetStream JetStreamBusConfig
+	Kafka     KafkaBusConfig
+}
+
+// JetStreamBusConfig configures the JetStream event bus.
+type JetStreamBusConfig struct {
+	Stream string
+	// AckWait is how long a handler may run before its event is
+	// redelivered, e.g. "30s".
+	AckWait    string
+	MaxDeliver int
+}
+
+// KafkaBusConfig configures the Kafka event bus, which talks to the
+// brokers through a Kafka REST Proxy.
+type KafkaBusConfig struct {
+	RESTURL     string
+	TopicPrefix string
+}
+
 // ChainConfigEntry defines a single blockchain network configuration.
 type ChainConfigEntry struct {
 	ID          int64    `mapstructure:"id" yaml:"id" json:"id"`
@@ -254,20 +963,51 @@ type ChainConfigEntry struct {
 	ExplorerURL string   `mapstructure:"explorer_url" yaml:"explorer_url" json:"explorer_url"`
 	Currency    string   `mapstructure:"currency" yaml:"currency" json:"currency"`
 	IsTestnet   bool     `mapstructure:"testnet" yaml:"testnet" json:"testnet"`
+	Disabled    bool     `mapstructure:"disabled" yaml:"disabled" json:"disabled"`
+	// Slug is the short name clients use to select the chain, e.g.
+	// "polygon". Entries under web3.networks take it from their key.
+	Slug string `mapstructure:"slug" yaml:"slug,omitempty" json:"slug,omitempty"`
+	// Confirmations overrides the chain's default finality depth.
+	Confirmations uint64 `mapstructure:"confirmations" yaml:"confirmations,omitempty" json:"confirmations,omitempty"`
 }
 
 // Web3Config holds Web3 configuration
 type Web3Config struct {
-	EthereumRPC        string
-	EthereumWSURL      string // WebSocket URL for real-time event subscriptions
-	SolanaRPC          string
-	ChainID            int64
-	BlockTag           string // "safe" (default), "finalized", or "latest"
-	Chains             []ChainConfigEntry
-	Transaction        TransactionConfig
-	RateLimit          RPCRateLimitConfig
-	AnvilDemoContract  string
-	AnvilDeployerKey   string
+	EthereumRPC       string
+	EthereumRPCs      []string // extra RPC endpoints for ChainID, used for failover
+	EthereumWSURL     string   // WebSocket URL for real-time event subscriptions
+	SolanaRPC         string
+	ChainID           int64
+	BlockTag          string // "safe" (default), "finalized", or "latest"
+	Chains            []ChainConfigEntry
+	Networks          map[string]ChainConfigEntry // named EVM chains keyed by slug, e.g. "polygon"
+	Transaction       TransactionConfig
+	RateLimit         RPCRateLimitConfig
+	RPCHealthInterval string // how often every RPC endpoint is probed, e.g. "30s"; "0" disables
+	NFTCacheTTL       string // how long NFT ownership results are cached, e.g. "60s"
+	AnvilDemoContract string
+	AnvilDeployerKey  string
+}
+
+// ChainEntries returns Chains followed by Networks sorted by slug, with
+// each network's slug set from its key.
+func (w Web3Config) ChainEntries() []ChainConfigEntry {
+	entries := make([]ChainConfigEntry, 0, len(w.Chains)+len(w.Networks))
+	entries = append(entries, w.Chains...)
+	slugs := make([]string, 0, len(w.Networks))
+	for slug := range w.Networks {
+		slugs = append(slugs, slug)
+	}
+	sort.Strings(slugs)
+	for _, slug := range slugs {
+		entry := w.Networks[slug]
+		entry.Slug = slug
+		if entry.Name == "" {
+			entry.Name = slug
+		}
+		entries = append(entries, entry)
+	}
+	return entries
 }
 
 // RPCRateLimitConfig holds RPC rate limiting configuration
@@ -294,6 +1034,36 @@ type MonitoringConfig struct {
 	PrometheusPort int
 	JaegerEndpoint string
 	LogLevel       string
+	// ShutdownTimeout bounds how long the metrics server and trace exporter
+	// may take to drain on shutdown, e.g. "5s".
+	ShutdownTimeout string
+	Tracing         TracingConfig
+}
+
+// TracingConfig selects the traces that are recorded and exported.
+type TracingConfig struct {
+	// Sampler is "always", "never", "probabilistic" or "rate_limited".
+	// Traces continued from a caller keep the caller's decision.
+	Sampler string
+	// Ratio is the fraction of traces the probabilistic sampler keeps.
+	Ratio float64
+	// RatePerSecond is how many traces the rate_limited sampler starts a
+	// second.
+	RatePerSecond float64
+	Tail          TailSamplingConfig
+}
+
+// TailSamplingConfig decides on whole traces once they finish, keeping
+// every trace with an error or a slow span and Ratio of the others. It
+// sees only the traces the head sampler kept.
+type TailSamplingConfig struct {
+	Enabled bool
+	Ratio   float64
+	// LatencyThreshold marks a span as slow, e.g. "2s"; empty disables
+	// the latency rule.
+	LatencyThreshold string
+	// MaxTraces bounds the traces held in memory until they finish.
+	MaxTraces int
 }
 
 // AuthConfig holds authentication configuration
@@ -304,6 +1074,23 @@ type AuthConfig struct {
 	NonceExpiry        string
 	SIWEDomain         string
 	SIWEURI            string
+	// MaxInMemoryChallenges caps the in-memory challenge store used when
+	// Redis is unavailable.
+	MaxInMemoryChallenges int
+	// ChallengeOverflowPolicy is "evict_oldest" or "reject".
+	ChallengeOverflowPolicy string
+	// ChallengeMessageFormat is the login message clients get when they do
+	// not ask for one: "siwe" or "personal_sign".
+	ChallengeMessageFormat string
+	// ChallengeMessageTemplate is a text/template for personal_sign login
+	// messages over {{.Domain}}, {{.Address}}, {{.ChainID}}, {{.Nonce}},
+	// {{.IssuedAt}}, {{.ExpiresAt}} and {{.Statement}}. Empty uses the
+	// built-in message.
+	ChallengeMessageTemplate string
+	// ChallengeStatement is the SIWE statement line and {{.Statement}}.
+	ChallengeStatement string
+	// AdminWallets may call /admin endpoints. Empty disables them.
+	AdminWallets []string
 }
 
 // CORSConfig holds CORS configuration
@@ -311,6 +1098,15 @@ type CORSConfig struct {
 	AllowedOrigins []string
 }
 
+// RetryBudgetConfig caps how fast failed work is retried across the
+// process, so an outage does not turn into a retry storm.
+type RetryBudgetConfig struct {
+	// RatePerSecond is the sustained retry rate. Zero disables the budget.
+	RatePerSecond float64
+	// Burst is how many retries may run back to back before the rate applies.
+	Burst int
+}
+
 // CircuitBreakerConfig holds circuit breaker configuration
 type CircuitBreakerConfig struct {
 	Enabled          bool
@@ -321,277 +1117,532 @@ type CircuitBreakerConfig struct {
 	WindowTime       string
 }
 
-// LoadConfig loads configuration from environment and config files.
-// Reads base config.yaml first, then merges environment-specific config
-// (config.{STREAMGATE_ENV}.yaml). STREAMGATE_ENV defaults to "dev".
+// LoadConfig loads configuration from flags, the environment, config
+// files and defaults, in that order of precedence:
+//
+//  1. -set key=value flags registered with BindFlags;
+//  2. environment variables: STREAMGATE_SERVER_PORT, or SERVER_PORT, for
+//     server.port, plus the older names in envAliases;
+//  3. config.yaml, then config.{STREAMGATE_ENV}.yaml merged over it;
+//     STREAMGATE_ENV defaults to "dev";
+//  4. the defaults in setDefaults.
+//
+// Keys in a config file or flag that no setting reads are rejected.
+// Secret references in the secret settings, e.g. env:DB_PASSWORD or
+// vault:kv/streamgate#jwt_secret, are replaced with their values through
+// Secrets().
 func LoadConfig() (*Config, error) {
+	cfg, _, err := loadConfig()
+	return cfg, err
+}
+
+// loadConfig is LoadConfig, also returning the secret references it
+// resolved by setting.
+func loadConfig() (*Config, map[string]secretRef, error) {
 	setDefaults()
 
 	viper.SetEnvPrefix("")
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	viper.AutomaticEnv()
 
-	// Database — STREAMGATE_DB_* (legacy DATABASE_* also supported)
-	_ = viper.BindEnv("database.host", "STREAMGATE_DB_HOST")
-	_ = viper.BindEnv("database.port", "STREAMGATE_DB_PORT")
-	_ = viper.BindEnv("database.user", "STREAMGATE_DB_USER")
-	_ = viper.BindEnv("database.password", "STREAMGATE_DB_PASSWORD")
-	_ = viper.BindEnv("database.database", "STREAMGATE_DB_NAME")
-	_ = viper.BindEnv("database.sslmode", "STREAMGATE_DB_SSLMODE")
-	_ = viper.BindEnv("database.maxconns", "STREAMGATE_DB_MAXCONNS")
-	_ = viper.BindEnv("database.max_idle_conns", "STREAMGATE_DB_MAX_IDLE_CONNS")
-	_ = viper.BindEnv("database.conn_max_lifetime", "STREAMGATE_DB_CONN_MAX_LIFETIME")
-
-	// Redis
-	_ = viper.BindEnv("redis.host", "STREAMGATE_REDIS_HOST")
-	_ = viper.BindEnv("redis.port", "STREAMGATE_REDIS_PORT")
-	_ = viper.BindEnv("redis.password", "STREAMGATE_REDIS_PASSWORD")
-	_ = viper.BindEnv("redis.db", "STREAMGATE_REDIS_DB")
-
-	// Storage
-	_ = viper.BindEnv("storage.type", "STREAMGATE_STORAGE_TYPE")
-	_ = viper.BindEnv("storage.endpoint", "STREAMGATE_STORAGE_ENDPOINT")
-	_ = viper.BindEnv("storage.accesskey", "STREAMGATE_STORAGE_ACCESS_KEY")
-	_ = viper.BindEnv("storage.secretkey", "STREAMGATE_STORAGE_SECRET_KEY")
-	_ = viper.BindEnv("storage.bucket", "STREAMGATE_STORAGE_BUCKET")
-	_ = viper.BindEnv("storage.region", "STREAMGATE_STORAGE_REGION")
-	_ = viper.BindEnv("storage.use_ssl", "STREAMGATE_STORAGE_USE_SSL")
-
-	// NATS
-	_ = viper.BindEnv("nats.url", "STREAMGATE_NATS_URL")
-
-	// Consul
-	_ = viper.BindEnv("consul.address", "STREAMGATE_CONSUL_HOST")
-	_ = viper.BindEnv("consul.port", "STREAMGATE_CONSUL_PORT")
-
-	// Monitoring
-	_ = viper.BindEnv("monitoring.jaeger_endpoint", "STREAMGATE_JAEGER_ENDPOINT")
-
-	// Web3
-	_ = viper.BindEnv("web3.ethereum_rpc", "STREAMGATE_ETH_RPC")
-	_ = viper.BindEnv("web3.solana_rpc", "STREAMGATE_SOLANA_RPC")
-	_ = viper.BindEnv("web3.ethereum_ws_url", "STREAMGATE_ETH_WS_URL")
-	_ = viper.BindEnv("web3.transaction.private_key_hex", "STREAMGATE_PRIVATE_KEY_HEX")
-	_ = viper.BindEnv("web3.anvil_demo_contract", "STREAMGATE_ANVIL_DEMO_CONTRACT")
-	_ = viper.BindEnv("web3.anvil_deployer_key", "STREAMGATE_ANVIL_DEPLOYER_KEY")
-
-	// Auth
-	_ = viper.BindEnv("auth.jwt_secret", "STREAMGATE_JWT_SECRET")
-	_ = viper.BindEnv("app.debug", "APP_DEBUG")
-	_ = viper.BindEnv("server.port", "STREAMGATE_SERVER_PORT")
-
-	// CORS
-	_ = viper.BindEnv("cors.allowed_origins", "STREAMGATE_CORS_ORIGINS")
+	keys := newConfigReader()
 
 	configPaths := []string{"./config", "."}
 
-	if err := readConfigWithExpansion("config", false, configPaths...); err != nil {
+	path, fileSet, err := readConfigWithExpansion("config", false, configPaths...)
+	if err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			return nil, fmt.Errorf("error reading config file: %w", err)
+			return nil, nil, fmt.Errorf("error reading config file: %w", err)
 		}
 	}
+	keys.addFile(path, fileSet)
 
 	env := os.Getenv("STREAMGATE_ENV")
 	if env == "" {
 		env = "dev"
 	}
-	if err := readConfigWithExpansion("config."+env, true, configPaths...); err != nil {
+	path, fileSet, err = readConfigWithExpansion("config."+env, true, configPaths...)
+	if err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			return nil, fmt.Errorf("error reading environment config file: %w", err)
+			return nil, nil, fmt.Errorf("error reading environment config file: %w", err)
+		}
+	}
+	keys.addFile(path, fileSet)
+	if err := keys.applyFlags(flagOverrides); err != nil {
+		return nil, nil, err
+	}
+	if source := keys.GetString("remote.source"); source != "" {
+		fileSet, err = readRemoteConfig(source)
+		if err != nil {
+			return nil, nil, err
 		}
+		keys.addFile(source, fileSet)
 	}
 
 	cfg := &Config{
-		AppName:     viper.GetString("app.name"),
-		Mode:        viper.GetString("app.mode"),
-		ServiceName: viper.GetString("app.service_name"),
-		Port:        viper.GetInt("app.port"),
-		Debug:       viper.GetBool("app.debug"),
+		Version:     keys.GetString("version"),
+		AppName:     keys.GetString("app.name"),
+		Mode:        keys.GetString("app.mode"),
+		ServiceName: keys.GetString("app.service_name"),
+		Port:        keys.GetInt("app.port"),
+		Debug:       keys.GetBool("app.debug"),
 
 		Server: ServerConfig{
-			Port:         viper.GetInt("server.port"),
-			ReadTimeout:  viper.GetInt("server.read_timeout"),
-			WriteTimeout: viper.GetInt("server.write_timeout"),
+			Port:            keys.GetInt("server.port"),
+			ReadTimeout:     keys.GetInt("server.read_timeout"),
+			WriteTimeout:    keys.GetInt("server.write_timeout"),
+			DrainDelay:      keys.GetString("server.drain_delay"),
+			ShutdownTimeout: keys.GetString("server.shutdown_timeout"),
 		},
 
 		GRPC: GRPCConfig{
-			Port: viper.GetInt("grpc.port"),
+			Port:         keys.GetInt("grpc.port"),
+			UnaryTimeout: keys.GetString("grpc.unary_timeout"),
 		},
 
 		Consul: ConsulConfig{
-			Address: viper.GetString("consul.address"),
-			Port:    viper.GetInt("consul.port"),
+			Address: keys.GetString("consul.address"),
+			Port:    keys.GetInt("consul.port"),
+		},
+
+		Discovery: DiscoveryConfig{
+			Backend: keys.GetString("discovery.backend"),
+			Etcd: EtcdDiscoveryConfig{
+				Endpoints: splitCommaSlice(keys.GetStringSlice("discovery.etcd.endpoints")),
+				Prefix:    keys.GetString("discovery.etcd.prefix"),
+				TTL:       keys.GetString("discovery.etcd.ttl"),
+			},
+			DNS: DNSDiscoveryConfig{
+				Domain: keys.GetString("discovery.dns.domain"),
+				Port:   keys.GetInt("discovery.dns.port"),
+			},
+		},
+
+		Remote: RemoteConfig{
+			Source:       keys.GetString("remote.source"),
+			PollInterval: keys.GetString("remote.poll_interval"),
 		},
 
 		Database: DatabaseConfig{
-			Host:            viper.GetString("database.host"),
-			Port:            viper.GetInt("database.port"),
-			User:            viper.GetString("database.user"),
-			Password:        viper.GetString("database.password"),
-			Database:        viper.GetString("database.database"),
-			SSLMode:         viper.GetString("database.sslmode"),
-			MaxConns:        viper.GetInt("database.maxconns"),
-			MaxIdleConns:    viper.GetInt("database.max_idle_conns"),
-			ConnMaxLifetime: viper.GetString("database.conn_max_lifetime"),
+			Host:              keys.GetString("database.host"),
+			Port:              keys.GetInt("database.port"),
+			User:              keys.GetString("database.user"),
+			Password:          keys.GetString("database.password"),
+			Database:          keys.GetString("database.database"),
+			SSLMode:           keys.GetString("database.sslmode"),
+			MaxConns:          keys.GetInt("database.maxconns"),
+			MaxIdleConns:      keys.GetInt("database.max_idle_conns"),
+			ConnMaxLifetime:   keys.GetString("database.conn_max_lifetime"),
+			ReplicaDSNs:       keys.dsnList("database.replica_dsns"),
+			ReplicaRetryAfter: keys.GetString("database.replica_retry_after"),
 		},
 
 		Redis: RedisConfig{
-			Host:     viper.GetString("redis.host"),
-			Port:     viper.GetInt("redis.port"),
-			Password: viper.GetString("redis.password"),
-			DB:       viper.GetInt("redis.db"),
-			PoolSize: viper.GetInt("redis.poolsize"),
+			Host:     keys.GetString("redis.host"),
+			Port:     keys.GetInt("redis.port"),
+			Password: keys.GetString("redis.password"),
+			DB:       keys.GetInt("redis.db"),
+			PoolSize: keys.GetInt("redis.poolsize"),
 		},
 
 		Storage: StorageConfig{
-			Type:      viper.GetString("storage.type"),
-			Endpoint:  viper.GetString("storage.endpoint"),
-			AccessKey: viper.GetString("storage.accesskey"),
-			SecretKey: viper.GetString("storage.secretkey"),
-			Bucket:    viper.GetString("storage.bucket"),
-			Region:    viper.GetString("storage.region"),
-			UseSSL:    viper.GetBool("storage.use_ssl"),
+			Type:      keys.GetString("storage.type"),
+			Endpoint:  keys.GetString("storage.endpoint"),
+			AccessKey: keys.GetString("storage.accesskey"),
+			SecretKey: keys.GetString("storage.secretkey"),
+			Bucket:    keys.GetString("storage.bucket"),
+			Region:    keys.GetString("storage.region"),
+			UseSSL:    keys.GetBool("storage.use_ssl"),
+			Path:      keys.GetString("storage.path"),
+			IPFS: StorageIPFSConfig{
+				APIURL:            keys.GetString("storage.ipfs.api_url"),
+				Root:              keys.GetString("storage.ipfs.root"),
+				Gateways:          keys.GetStringSlice("storage.ipfs.gateways"),
+				PinningServiceURL: keys.GetString("storage.ipfs.pinning_service_url"),
+				PinningToken:      keys.GetString("storage.ipfs.pinning_token"),
+				CacheSizeMB:       keys.GetInt("storage.ipfs.cache_size_mb"),
+			},
 		},
 
 		NATS: NATSConfig{
-			URL: viper.GetString("nats.url"),
+			URL: keys.GetString("nats.url"),
+		},
+		EventBus: EventBusConfig{
+			Driver: keys.GetString("event_bus.driver"),
+			Group:  keys.GetString("event_bus.group"),
+			JetStream: JetStreamBusConfig{
+				Stream:     keys.GetString("event_bus.jetstream.stream"),
+				AckWait:    keys.GetString("event_bus.jetstream.ack_wait"),
+				MaxDeliver: keys.GetInt("event_bus.jetstream.max_deliver"),
+			},
+			Kafka: KafkaBusConfig{
+				RESTURL:     keys.GetString("event_bus.kafka.rest_url"),
+				TopicPrefix: keys.GetString("event_bus.kafka.topic_prefix"),
+			},
 		},
 
 		Web3: Web3Config{
-			EthereumRPC:       viper.GetString("web3.ethereum_rpc"),
-			EthereumWSURL:     viper.GetString("web3.ethereum_ws_url"),
-			SolanaRPC:         viper.GetString("web3.solana_rpc"),
-			ChainID:           viper.GetInt64("web3.chain_id"),
-			BlockTag:          viper.GetString("web3.block_tag"),
-			AnvilDemoContract: viper.GetString("web3.anvil_demo_contract"),
-			AnvilDeployerKey:  viper.GetString("web3.anvil_deployer_key"),
+			EthereumRPC:       keys.GetString("web3.ethereum_rpc"),
+			EthereumRPCs:      splitCommaSlice(keys.GetStringSlice("web3.ethereum_rpcs")),
+			EthereumWSURL:     keys.GetString("web3.ethereum_ws_url"),
+			SolanaRPC:         keys.GetString("web3.solana_rpc"),
+			ChainID:           keys.GetInt64("web3.chain_id"),
+			BlockTag:          keys.GetString("web3.block_tag"),
+			AnvilDemoContract: keys.GetString("web3.anvil_demo_contract"),
+			AnvilDeployerKey:  keys.GetString("web3.anvil_deployer_key"),
+			RPCHealthInterval: keys.GetString("web3.rpc_health_interval"),
+			NFTCacheTTL:       keys.GetString("web3.nft_cache_ttl"),
 			Transaction: TransactionConfig{
-				PrivateKeyHex:            viper.GetString("web3.transaction.private_key_hex"),
-				GasLimit:                 viper.GetUint64("web3.transaction.gas_limit"),
-				GasMultiplier:            viper.GetFloat64("web3.transaction.gas_multiplier"),
-				Confirmations:            viper.GetUint64("web3.transaction.confirmations"),
-				MaxFeePerGasGwei:         viper.GetFloat64("web3.transaction.max_fee_per_gas_gwei"),
-				MaxFeePerGasCapGwei:      viper.GetFloat64("web3.transaction.max_fee_per_gas_cap_gwei"),
-				MaxPriorityFeePerGasGwei: viper.GetFloat64("web3.transaction.max_priority_fee_per_gas_gwei"),
-				EIP1559:                  viper.GetBool("web3.transaction.eip1559"),
+				PrivateKeyHex:            keys.GetString("web3.transaction.private_key_hex"),
+				GasLimit:                 keys.GetUint64("web3.transaction.gas_limit"),
+				GasMultiplier:            keys.GetFloat64("web3.transaction.gas_multiplier"),
+				Confirmations:            keys.GetUint64("web3.transaction.confirmations"),
+				MaxFeePerGasGwei:         keys.GetFloat64("web3.transaction.max_fee_per_gas_gwei"),
+				MaxFeePerGasCapGwei:      keys.GetFloat64("web3.transaction.max_fee_per_gas_cap_gwei"),
+				MaxPriorityFeePerGasGwei: keys.GetFloat64("web3.transaction.max_priority_fee_per_gas_gwei"),
+				EIP1559:                  keys.GetBool("web3.transaction.eip1559"),
 			},
 			RateLimit: RPCRateLimitConfig{
-				Enabled: viper.GetBool("web3.rate_limit.enabled"),
-				Rate:    viper.GetFloat64("web3.rate_limit.rate"),
-				Burst:   viper.GetFloat64("web3.rate_limit.burst"),
+				Enabled: keys.GetBool("web3.rate_limit.enabled"),
+				Rate:    keys.GetFloat64("web3.rate_limit.rate"),
+				Burst:   keys.GetFloat64("web3.rate_limit.burst"),
 			},
 		},
 
 		Monitoring: MonitoringConfig{
-			PrometheusPort: viper.GetInt("monitoring.prometheus_port"),
-			JaegerEndpoint: viper.GetString("monitoring.jaeger_endpoint"),
-			LogLevel:       viper.GetString("monitoring.log_level"),
+			PrometheusPort:  keys.GetInt("monitoring.prometheus_port"),
+			JaegerEndpoint:  keys.GetString("monitoring.jaeger_endpoint"),
+			ShutdownTimeout: keys.GetString("monitoring.shutdown_timeout"),
+			LogLevel:        keys.GetString("monitoring.log_level"),
+			Tracing: TracingConfig{
+				Sampler:       keys.GetString("monitoring.tracing.sampler"),
+				Ratio:         keys.GetFloat64("monitoring.tracing.ratio"),
+				RatePerSecond: keys.GetFloat64("monitoring.tracing.rate_per_second"),
+				Tail: TailSamplingConfig{
+					Enabled:          keys.GetBool("monitoring.tracing.tail.enabled"),
+					Ratio:            keys.GetFloat64("monitoring.tracing.tail.ratio"),
+					LatencyThreshold: keys.GetString("monitoring.tracing.tail.latency_threshold"),
+					MaxTraces:        keys.GetInt("monitoring.tracing.tail.max_traces"),
+				},
+			},
 		},
 
 		Transcoding: TranscodingConfig{
-			Enabled:       viper.GetBool("transcoding.enabled"),
-			MaxWorkers:    viper.GetInt("transcoding.max_workers"),
-			QueueSize:     viper.GetInt("transcoding.queue_size"),
-			OutputFormats: splitCommaSlice(viper.GetStringSlice("transcoding.output_formats")),
+			Enabled:              keys.GetBool("transcoding.enabled"),
+			MaxWorkers:           keys.GetInt("transcoding.max_workers"),
+			QueueSize:            keys.GetInt("transcoding.queue_size"),
+			OutputFormats:        splitCommaSlice(keys.GetStringSlice("transcoding.output_formats")),
+			PartDuration:         keys.GetString("transcoding.part_duration"),
+			QueueMaxWait:         keys.GetString("transcoding.queue_max_wait"),
+			QueueStore:           keys.GetString("transcoding.queue_store"),
+			VisibilityTimeout:    keys.GetString("transcoding.visibility_timeout"),
+			Distributed:          keys.GetBool("transcoding.distributed"),
+			AllowPartialVariants: keys.GetBool("transcoding.allow_partial_variants"),
+			PackagerPath:         keys.GetString("transcoding.packager_path"),
+			Hardware:             keys.GetString("transcoding.hardware"),
+			VAAPIDevice:          keys.GetString("transcoding.vaapi_device"),
+			Codecs:               splitCommaSlice(keys.GetStringSlice("transcoding.codecs")),
+			Budget: TranscodeBudgetConfig{
+				MonthlyLimit:       keys.GetFloat64("transcoding.budget.monthly_limit"),
+				PerRungSecond:      keys.GetFloat64("transcoding.budget.per_rung_second"),
+				PerMegapixelSecond: keys.GetFloat64("transcoding.budget.per_megapixel_second"),
+				DefaultDuration:    keys.GetString("transcoding.budget.default_duration"),
+			},
+			PerTitle: PerTitleConfig{
+				Enabled:        keys.GetBool("transcoding.per_title.enabled"),
+				TargetVMAF:     keys.GetFloat64("transcoding.per_title.target_vmaf"),
+				MinBitrate:     keys.GetInt("transcoding.per_title.min_bitrate"),
+				MaxBitrate:     keys.GetInt("transcoding.per_title.max_bitrate"),
+				MinRungs:       keys.GetInt("transcoding.per_title.min_rungs"),
+				MaxRungs:       keys.GetInt("transcoding.per_title.max_rungs"),
+				Samples:        keys.GetInt("transcoding.per_title.samples"),
+				SampleDuration: keys.GetString("transcoding.per_title.sample_duration"),
+			},
+			Storyboard: StoryboardConfig{
+				Enabled:  keys.GetBool("transcoding.storyboard.enabled"),
+				Interval: keys.GetString("transcoding.storyboard.interval"),
+				Columns:  keys.GetInt("transcoding.storyboard.columns"),
+				Rows:     keys.GetInt("transcoding.storyboard.rows"),
+				Width:    keys.GetInt("transcoding.storyboard.width"),
+			},
+			Loudness: LoudnessConfig{
+				Enabled:    keys.GetBool("transcoding.loudness.enabled"),
+				Integrated: keys.GetFloat64("transcoding.loudness.integrated"),
+				TruePeak:   keys.GetFloat64("transcoding.loudness.true_peak"),
+				Range:      keys.GetFloat64("transcoding.loudness.range"),
+			},
+		},
+
+		Upload: UploadConfig{
+			Scan: UploadScanConfig{
+				Backend:    keys.GetString("upload.scan.backend"),
+				ClamAVAddr: keys.GetString("upload.scan.clamav_addr"),
+				HTTPURL:    keys.GetString("upload.scan.http_url"),
+				Timeout:    keys.GetString("upload.scan.timeout"),
+			},
 		},
 
 		Streaming: StreamingConfig{
-			HLSSegmentDuration:   viper.GetInt("streaming.hls_segment_duration"),
-			DASHSegmentDuration:  viper.GetInt("streaming.dash_segment_duration"),
-			CacheEnabled:         viper.GetBool("streaming.cache_enabled"),
-			CacheTTL:             viper.GetString("streaming.cache_ttl"),
-			MaxConcurrentStreams: viper.GetInt("streaming.max_concurrent_streams"),
+			HLSSegmentDuration:   keys.GetInt("streaming.hls_segment_duration"),
+			DASHSegmentDuration:  keys.GetInt("streaming.dash_segment_duration"),
+			CacheEnabled:         keys.GetBool("streaming.cache_enabled"),
+			CacheTTL:             keys.GetString("streaming.cache_ttl"),
+			MaxConcurrentStreams: keys.GetInt("streaming.max_concurrent_streams"),
+			MaxManifestSegments:  keys.GetInt("streaming.max_manifest_segments"),
+			LiveWindowSegments:   keys.GetInt("streaming.live_window_segments"),
+			Egress: EgressConfig{
+				CountryHeader: keys.GetString("streaming.egress.country_header"),
+				DefaultRegion: keys.GetString("streaming.egress.default_region"),
+			},
+			DRM: DRMConfig{
+				LicenseTimeout: keys.GetString("streaming.drm.license_timeout"),
+			},
+		},
+
+		Live: LiveConfig{
+			Enabled:         keys.GetBool("live.enabled"),
+			RTMPPort:        keys.GetInt("live.rtmp_port"),
+			SRTPort:         keys.GetInt("live.srt_port"),
+			WorkDir:         keys.GetString("live.work_dir"),
+			FFmpegPath:      keys.GetString("live.ffmpeg_path"),
+			SegmentDuration: keys.GetInt("live.segment_duration"),
+		},
+
+		Moderation: ModerationConfig{
+			Enabled:       keys.GetBool("moderation.enabled"),
+			ProviderURL:   keys.GetString("moderation.provider_url"),
+			APIKey:        keys.GetString("moderation.api_key"),
+			FFmpegPath:    keys.GetString("moderation.ffmpeg_path"),
+			FrameInterval: keys.GetString("moderation.frame_interval"),
+			MaxFrames:     keys.GetInt("moderation.max_frames"),
+			Threshold:     keys.GetFloat64("moderation.threshold"),
+			AutoApprove:   keys.GetBool("moderation.auto_approve"),
+			Timeout:       keys.GetString("moderation.timeout"),
+			WebhookURLs:   keys.GetStringSlice("moderation.webhook_urls"),
+			WebhookSecret: keys.GetString("moderation.webhook_secret"),
+		},
+
+		Archive: ArchiveConfig{
+			Enabled: keys.GetBool("archive.enabled"),
+			Arweave: ArweaveArchiveConfig{
+				BundlerURL: keys.GetString("archive.arweave.bundler_url"),
+				GatewayURL: keys.GetString("archive.arweave.gateway_url"),
+				WalletPath: keys.GetString("archive.arweave.wallet_path"),
+			},
+			Timeout: keys.GetString("archive.timeout"),
+		},
+
+		Lifecycle: LifecycleConfig{
+			Enabled:                  keys.GetBool("lifecycle.enabled"),
+			Interval:                 keys.GetString("lifecycle.interval"),
+			DryRun:                   keys.GetBool("lifecycle.dry_run"),
+			ColdBucket:               keys.GetString("lifecycle.cold_bucket"),
+			ColdAfterDays:            keys.GetInt("lifecycle.cold_after_days"),
+			PurgeRenditionsAfterDays: keys.GetInt("lifecycle.purge_renditions_after_days"),
+			KeepRenditions:           keys.GetStringSlice("lifecycle.keep_renditions"),
+			OrphanChunkAge:           keys.GetString("lifecycle.orphan_chunk_age"),
+			BatchSize:                keys.GetInt("lifecycle.batch_size"),
+		},
+
+		Search: SearchConfig{
+			Enabled: keys.GetBool("search.enabled"),
+			Driver:  keys.GetString("search.driver"),
+			Elasticsearch: ElasticsearchSearchConfig{
+				URL:      keys.GetString("search.elasticsearch.url"),
+				Index:    keys.GetString("search.elasticsearch.index"),
+				APIKey:   keys.GetString("search.elasticsearch.api_key"),
+				Username: keys.GetString("search.elasticsearch.username"),
+				Password: keys.GetString("search.elasticsearch.password"),
+				Timeout:  keys.GetString("search.elasticsearch.timeout"),
+			},
+			ReindexInterval: keys.GetString("search.reindex_interval"),
+		},
+		Webhooks: WebhooksConfig{
+			Enabled:        keys.GetBool("webhooks.enabled"),
+			MaxAttempts:    keys.GetInt("webhooks.max_attempts"),
+			InitialBackoff: keys.GetString("webhooks.initial_backoff"),
+			MaxBackoff:     keys.GetString("webhooks.max_backoff"),
+			Timeout:        keys.GetString("webhooks.timeout"),
+			PollInterval:   keys.GetString("webhooks.poll_interval"),
+		},
+		Audit: AuditConfig{
+			Enabled:   keys.GetBool("audit.enabled"),
+			Retention: keys.GetString("audit.retention"),
+			Export: AuditExportConfig{
+				Enabled:       keys.GetBool("audit.export.enabled"),
+				Sink:          keys.GetString("audit.export.sink"),
+				Format:        keys.GetString("audit.export.format"),
+				Path:          keys.GetString("audit.export.path"),
+				MaxBytes:      keys.GetInt64("audit.export.max_bytes"),
+				Bucket:        keys.GetString("audit.export.bucket"),
+				Prefix:        keys.GetString("audit.export.prefix"),
+				URL:           keys.GetString("audit.export.url"),
+				BatchSize:     keys.GetInt("audit.export.batch_size"),
+				FlushInterval: keys.GetString("audit.export.flush_interval"),
+			},
+		},
+
+		Encryption: EncryptionConfig{
+			Enabled:   keys.GetBool("encryption.enabled"),
+			KeyDir:    keys.GetString("encryption.key_dir"),
+			MasterKey: keys.GetString("encryption.master_key"),
 		},
 
 		RateLimiting: RateLimitingConfig{
-			Enabled:           viper.GetBool("rate_limiting.enabled"),
-			RequestsPerMinute: viper.GetInt("rate_limiting.requests_per_minute"),
-			RequestsPerHour:   viper.GetInt("rate_limiting.requests_per_hour"),
-			BurstSize:         viper.GetInt("rate_limiting.burst_size"),
+			Enabled:           keys.GetBool("rate_limiting.enabled"),
+			RequestsPerMinute: keys.GetInt("rate_limiting.requests_per_minute"),
+			RequestsPerHour:   keys.GetInt("rate_limiting.requests_per_hour"),
+			BurstSize:         keys.GetInt("rate_limiting.burst_size"),
 		},
 
 		CircuitBreaker: CircuitBreakerConfig{
-			Enabled:          viper.GetBool("circuit_breaker.enabled"),
-			FailureThreshold: viper.GetInt("circuit_breaker.failure_threshold"),
-			SuccessThreshold: viper.GetInt("circuit_breaker.success_threshold"),
-			Timeout:          viper.GetString("circuit_breaker.timeout"),
-			MaxRequests:      viper.GetInt("circuit_breaker.max_requests"),
-			WindowTime:       viper.GetString("circuit_breaker.window_time"),
+			Enabled:          keys.GetBool("circuit_breaker.enabled"),
+			FailureThreshold: keys.GetInt("circuit_breaker.failure_threshold"),
+			SuccessThreshold: keys.GetInt("circuit_breaker.success_threshold"),
+			Timeout:          keys.GetString("circuit_breaker.timeout"),
+			MaxRequests:      keys.GetInt("circuit_breaker.max_requests"),
+			WindowTime:       keys.GetString("circuit_breaker.window_time"),
+		},
+
+		RetryBudget: RetryBudgetConfig{
+			RatePerSecond: keys.GetFloat64("retry_budget.rate_per_second"),
+			Burst:         keys.GetInt("retry_budget.burst"),
 		},
 
 		Logging: LoggingConfig{
-			Level:      viper.GetString("logging.level"),
-			Format:     viper.GetString("logging.format"),
-			Output:     viper.GetString("logging.output"),
-			File:       viper.GetString("logging.file"),
-			MaxSize:    viper.GetInt("logging.max_size"),
-			MaxBackups: viper.GetInt("logging.max_backups"),
-			MaxAge:     viper.GetInt("logging.max_age"),
-			Compress:   viper.GetBool("logging.compress"),
+			Level:      keys.GetString("logging.level"),
+			Format:     keys.GetString("logging.format"),
+			Output:     keys.GetString("logging.output"),
+			File:       keys.GetString("logging.file"),
+			MaxSize:    keys.GetInt("logging.max_size"),
+			MaxBackups: keys.GetInt("logging.max_backups"),
+			MaxAge:     keys.GetInt("logging.max_age"),
+			Compress:   keys.GetBool("logging.compress"),
 		},
 
 		Features: FeaturesConfig{
-			NFTGating:       viper.GetBool("features.nft_gating"),
-			SignatureAuth:   viper.GetBool("features.signature_auth"),
-			ChunkedUpload:   viper.GetBool("features.chunked_upload"),
-			ResumableUpload: viper.GetBool("features.resumable_upload"),
-			AdaptiveBitrate: viper.GetBool("features.adaptive_bitrate"),
-			MultiCodec:      viper.GetBool("features.multi_codec"),
+			NFTGating:       keys.GetBool("features.nft_gating"),
+			SignatureAuth:   keys.GetBool("features.signature_auth"),
+			ChunkedUpload:   keys.GetBool("features.chunked_upload"),
+			ResumableUpload: keys.GetBool("features.resumable_upload"),
+			AdaptiveBitrate: keys.GetBool("features.adaptive_bitrate"),
+			MultiCodec:      keys.GetBool("features.multi_codec"),
 		},
 
 		Auth: AuthConfig{
-			JWTSecret:          viper.GetString("auth.jwt_secret"),
-			JWTExpiry:          viper.GetString("auth.jwt_expiry"),
-			RefreshTokenExpiry: viper.GetString("auth.refresh_token_expiry"),
-			NonceExpiry:        viper.GetString("auth.nonce_expiry"),
-			SIWEDomain:         viper.GetString("auth.siwe_domain"),
-			SIWEURI:            viper.GetString("auth.siwe_uri"),
+			JWTSecret:          keys.GetString("auth.jwt_secret"),
+			JWTExpiry:          keys.GetString("auth.jwt_expiry"),
+			RefreshTokenExpiry: keys.GetString("auth.refresh_token_expiry"),
+			NonceExpiry:        keys.GetString("auth.nonce_expiry"),
+			SIWEDomain:         keys.GetString("auth.siwe_domain"),
+			SIWEURI:            keys.GetString("auth.siwe_uri"),
+
+			MaxInMemoryChallenges:   keys.GetInt("auth.max_in_memory_challenges"),
+			ChallengeOverflowPolicy: keys.GetString("auth.challenge_overflow_policy"),
+
+			ChallengeMessageFormat:   keys.GetString("auth.challenge_message_format"),
+			ChallengeMessageTemplate: keys.GetString("auth.challenge_message_template"),
+			ChallengeStatement:       keys.GetString("auth.challenge_statement"),
+
+			AdminWallets: splitCommaSlice(keys.GetStringSlice("auth.admin_wallets")),
 		},
 
 		CORS: CORSConfig{
-			AllowedOrigins: splitCommaSlice(viper.GetStringSlice("cors.allowed_origins")),
+			AllowedOrigins: splitCommaSlice(keys.GetStringSlice("cors.allowed_origins")),
 		},
 
 		Plugins: PluginsConfig{
-			Enabled: splitCommaSlice(viper.GetStringSlice("plugins.enabled")),
+			Enabled:       splitCommaSlice(keys.GetStringSlice("plugins.enabled")),
+			HotReload:     keys.GetBool("plugins.hot_reload"),
+			WatchInterval: keys.GetString("plugins.watch_interval"),
+			TrustedKeys:   splitCommaSlice(keys.GetStringSlice("plugins.trusted_keys")),
+			AllowUnsigned: splitCommaSlice(keys.GetStringSlice("plugins.allow_unsigned")),
+			UsageInterval: keys.GetString("plugins.usage_interval"),
+		},
+
+		Analytics: AnalyticsConfig{
+			BucketSize: keys.GetString("analytics.bucket_size"),
+			Retention:  keys.GetString("analytics.retention"),
+		},
+
+		Worker: WorkerConfig{
+			SchedulesStore: keys.GetString("worker.schedules_store"),
+			SchedulesFile:  keys.GetString("worker.schedules_file"),
 		},
 	}
 
 	// Load web3 chains separately: UnmarshalKey is needed for slice-of-structs.
 	var chains []ChainConfigEntry
-	if err := viper.UnmarshalKey("web3.chains", &chains); err == nil && len(chains) > 0 {
+	if err := keys.UnmarshalKey("web3.chains", &chains); err == nil && len(chains) > 0 {
 		cfg.Web3.Chains = chains
 	}
-
-	if cfg.Server.Port <= 0 || cfg.Server.Port > 65535 {
-		return nil, fmt.Errorf("invalid server port: %d", cfg.Server.Port)
+	var networks map[string]ChainConfigEntry
+	if err := keys.UnmarshalKey("web3.networks", &networks); err == nil && len(networks) > 0 {
+		cfg.Web3.Networks = networks
 	}
-
-	if cfg.Database.Host == "" {
-		return nil, fmt.Errorf("database host is required")
+	var external []ExternalPluginConfig
+	if err := keys.UnmarshalKey("plugins.external", &external); err == nil && len(external) > 0 {
+		cfg.Plugins.External = external
 	}
-
-	if cfg.Database.Port <= 0 || cfg.Database.Port > 65535 {
-		return nil, fmt.Errorf("invalid database port: %d", cfg.Database.Port)
+	var auditHeaders map[string]string
+	if err := keys.UnmarshalKey("audit.export.headers", &auditHeaders); err == nil && len(auditHeaders) > 0 {
+		cfg.Audit.Export.Headers = auditHeaders
 	}
-
-	if cfg.Redis.Host == "" {
-		return nil, fmt.Errorf("redis host is required")
+	var pluginLimits map[string]PluginLimits
+	if err := keys.UnmarshalKey("plugins.limits", &pluginLimits); err == nil && len(pluginLimits) > 0 {
+		cfg.Plugins.Limits = pluginLimits
+	}
+	var qualities []QualityConfig
+	if err := keys.UnmarshalKey("transcoding.qualities", &qualities); err == nil && len(qualities) > 0 {
+		cfg.Transcoding.Qualities = qualities
+	}
+	var codecLadders map[string][]QualityConfig
+	if err := keys.UnmarshalKey("transcoding.codec_ladders", &codecLadders); err == nil && len(codecLadders) > 0 {
+		cfg.Transcoding.CodecLadders = codecLadders
+	}
+	var regions []EgressRegionConfig
+	if err := keys.UnmarshalKey("streaming.egress.regions", &regions); err == nil && len(regions) > 0 {
+		cfg.Streaming.Egress.Regions = regions
+	}
+	var licenseServers []LicenseServerConfig
+	if err := keys.UnmarshalKey("streaming.drm.license_servers", &licenseServers); err == nil && len(licenseServers) > 0 {
+		cfg.Streaming.DRM.LicenseServers = licenseServers
+	}
+	var upstreams []UpstreamConfig
+	if err := keys.UnmarshalKey("gateway.upstreams", &upstreams); err == nil && len(upstreams) > 0 {
+		cfg.Gateway.Upstreams = upstreams
 	}
 
-	if cfg.Storage.Endpoint == "" {
-		return nil, fmt.Errorf("storage endpoint is required")
+	// Unknown keys, unresolvable secrets and invalid settings are reported
+	// together, so they can all be fixed at once.
+	var errs []error
+	if err := keys.checkUnknown(); err != nil {
+		errs = append(errs, err)
+	}
+	refs, err := Secrets().resolveConfig(context.Background(), cfg)
+	if err != nil {
+		errs = append(errs, err)
 	}
 
-	if cfg.GRPC.Port <= 0 || cfg.GRPC.Port > 65535 {
-		return nil, fmt.Errorf("invalid gRPC port: %d", cfg.GRPC.Port)
+	// gcs and azure default to the public service endpoint; the MinIO dev
+	// default is never meant for them.
+	if (cfg.Storage.Type == "gcs" || cfg.Storage.Type == "azure") && cfg.Storage.Endpoint == defaultStorageEndpoint {
+		cfg.Storage.Endpoint = ""
+	}
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
 	}
 
-	return cfg, nil
+	return cfg, refs, nil
 }
 
 // setDefaults sets default configuration values
 func setDefaults() {
+	viper.SetDefault("version", CurrentVersion)
+
 	// Application defaults
 	viper.SetDefault("app.name", "streamgate")
 	viper.SetDefault("app.mode", "monolith")
@@ -606,11 +1657,20 @@ func setDefaults() {
 
 	// gRPC defaults
 	viper.SetDefault("grpc.port", 9090)
+	viper.SetDefault("grpc.unary_timeout", "30s")
 
 	// Consul defaults
 	viper.SetDefault("consul.address", "localhost")
 	viper.SetDefault("consul.port", 8500)
 
+	// Discovery defaults
+	viper.SetDefault("discovery.backend", "consul")
+	viper.SetDefault("discovery.etcd.endpoints", []string{"http://localhost:2379"})
+	viper.SetDefault("discovery.etcd.prefix", "/streamgate/services/")
+	viper.SetDefault("discovery.etcd.ttl", "15s")
+	viper.SetDefault("discovery.dns.port", 8080)
+	viper.SetDefault("remote.poll_interval", "30s")
+
 	// Database defaults
 	viper.SetDefault("database.host", "localhost")
 	viper.SetDefault("database.port", 5432)
@@ -621,6 +1681,7 @@ func setDefaults() {
 	viper.SetDefault("database.maxconns", 100)
 	viper.SetDefault("database.max_idle_conns", 5)
 	viper.SetDefault("database.conn_max_lifetime", "5m")
+	viper.SetDefault("database.replica_retry_after", "30s")
 
 	// Redis defaults
 	viper.SetDefault("redis.host", "localhost")
@@ -631,32 +1692,86 @@ func setDefaults() {
 
 	// Storage defaults
 	viper.SetDefault("storage.type", "minio")
-	viper.SetDefault("storage.endpoint", "localhost:9000")
+	viper.SetDefault("storage.endpoint", defaultStorageEndpoint)
 	viper.SetDefault("storage.accesskey", "minioadmin") // #nosec G101 -- dev default, ValidateProduction() warns
 	viper.SetDefault("storage.secretkey", "minioadmin") // #nosec G101 -- dev default, ValidateProduction() warns
 	viper.SetDefault("storage.bucket", "streamgate")
 	viper.SetDefault("storage.region", "us-east-1")
 	viper.SetDefault("storage.use_ssl", false)
+	viper.SetDefault("storage.ipfs.api_url", "localhost:5001")
+	viper.SetDefault("storage.ipfs.root", "/streamgate")
+	viper.SetDefault("storage.ipfs.gateways", []string{"https://ipfs.io"})
+	viper.SetDefault("storage.ipfs.cache_size_mb", 256)
 
 	// NATS defaults
 	viper.SetDefault("nats.url", "nats://localhost:4222")
 
+	// Event bus defaults
+	viper.SetDefault("event_bus.driver", "")
+	viper.SetDefault("event_bus.group", "streamgate")
+	viper.SetDefault("event_bus.jetstream.stream", "STREAMGATE")
+	viper.SetDefault("event_bus.jetstream.ack_wait", "30s")
+	viper.SetDefault("event_bus.jetstream.max_deliver", 5)
+	viper.SetDefault("event_bus.kafka.rest_url", "http://localhost:8082")
+	viper.SetDefault("event_bus.kafka.topic_prefix", "streamgate")
+
 	// Web3 defaults
 	viper.SetDefault("web3.ethereum_rpc", "https://sepolia.infura.io/v3/YOUR_KEY")
 	viper.SetDefault("web3.solana_rpc", "https://api.devnet.solana.com")
 	viper.SetDefault("web3.chain_id", 11155111) // Sepolia
 	viper.SetDefault("web3.block_tag", "safe")
+	viper.SetDefault("web3.rpc_health_interval", "30s")
+	viper.SetDefault("web3.nft_cache_ttl", "60s")
 
 	// Monitoring defaults
 	viper.SetDefault("monitoring.prometheus_port", 9090)
 	viper.SetDefault("monitoring.jaeger_endpoint", "localhost:4317")
+	viper.SetDefault("monitoring.shutdown_timeout", "5s")
 	viper.SetDefault("monitoring.log_level", "info")
+	viper.SetDefault("monitoring.tracing.sampler", "always")
+	viper.SetDefault("monitoring.tracing.ratio", 0.1)
+	viper.SetDefault("monitoring.tracing.rate_per_second", 100)
+	viper.SetDefault("monitoring.tracing.tail.ratio", 0.1)
+	viper.SetDefault("monitoring.tracing.tail.latency_threshold", "2s")
+	viper.SetDefault("monitoring.tracing.tail.max_traces", 10000)
 
 	// Transcoding defaults
 	viper.SetDefault("transcoding.enabled", true)
 	viper.SetDefault("transcoding.max_workers", 4)
 	viper.SetDefault("transcoding.queue_size", 100)
 	viper.SetDefault("transcoding.output_formats", []string{"hls", "dash"})
+	viper.SetDefault("transcoding.allow_partial_variants", false)
+	viper.SetDefault("transcoding.part_duration", "1s")
+	viper.SetDefault("transcoding.queue_max_wait", "10m")
+	viper.SetDefault("transcoding.queue_store", "memory")
+	viper.SetDefault("transcoding.visibility_timeout", "5m")
+	viper.SetDefault("transcoding.distributed", false)
+	viper.SetDefault("transcoding.hardware", "none")
+	viper.SetDefault("transcoding.codecs", []string{"h264"})
+	viper.SetDefault("transcoding.vaapi_device", "/dev/dri/renderD128")
+	viper.SetDefault("transcoding.budget.monthly_limit", 0)
+	viper.SetDefault("transcoding.budget.per_rung_second", 0.5)
+	viper.SetDefault("transcoding.budget.per_megapixel_second", 1.0)
+	viper.SetDefault("transcoding.budget.default_duration", "10m")
+	viper.SetDefault("transcoding.per_title.enabled", true)
+	viper.SetDefault("transcoding.per_title.target_vmaf", 93)
+	viper.SetDefault("transcoding.per_title.min_bitrate", 200000)
+	viper.SetDefault("transcoding.per_title.max_bitrate", 8000000)
+	viper.SetDefault("transcoding.per_title.min_rungs", 2)
+	viper.SetDefault("transcoding.per_title.max_rungs", 6)
+	viper.SetDefault("transcoding.per_title.samples", 3)
+	viper.SetDefault("transcoding.per_title.sample_duration", "4s")
+	viper.SetDefault("upload.scan.clamav_addr", "localhost:3310")
+	viper.SetDefault("upload.scan.timeout", "5m")
+	viper.SetDefault("transcoding.storyboard.enabled", true)
+	viper.SetDefault("transcoding.storyboard.interval", "10s")
+	viper.SetDefault("transcoding.storyboard.columns", 5)
+	viper.SetDefault("transcoding.storyboard.rows", 5)
+	viper.SetDefault("transcoding.storyboard.width", 160)
+	viper.SetDefault("transcoding.loudness.enabled", true)
+	viper.SetDefault("transcoding.loudness.integrated", -23)
+	viper.SetDefault("transcoding.loudness.true_peak", -1)
+	viper.SetDefault("transcoding.loudness.range", 7)
 
 	// Streaming defaults
 	viper.SetDefault("streaming.hls_segment_duration", 10)
@@ -664,6 +1779,70 @@ func setDefaults() {
 	viper.SetDefault("streaming.cache_enabled", true)
 	viper.SetDefault("streaming.cache_ttl", "3600s")
 	viper.SetDefault("streaming.max_concurrent_streams", 1000)
+	viper.SetDefault("streaming.max_manifest_segments", 10000)
+	viper.SetDefault("streaming.live_window_segments", 30)
+	viper.SetDefault("streaming.egress.country_header", "CF-IPCountry")
+	viper.SetDefault("streaming.drm.license_timeout", "10s")
+
+	// Live ingest defaults
+	viper.SetDefault("live.enabled", false)
+	viper.SetDefault("live.rtmp_port", 1935)
+	viper.SetDefault("live.srt_port", 9000)
+	viper.SetDefault("live.work_dir", "/tmp/streamgate-live")
+	viper.SetDefault("live.ffmpeg_path", "ffmpeg")
+	viper.SetDefault("live.segment_duration", 2)
+
+	// Moderation defaults
+	viper.SetDefault("moderation.enabled", false)
+	viper.SetDefault("moderation.ffmpeg_path", "ffmpeg")
+	viper.SetDefault("moderation.frame_interval", "10s")
+	viper.SetDefault("moderation.max_frames", 10)
+	viper.SetDefault("moderation.threshold", 0.8)
+	viper.SetDefault("moderation.auto_approve", true)
+	viper.SetDefault("moderation.timeout", "10m")
+
+	// Archive defaults
+	viper.SetDefault("archive.enabled", false)
+	viper.SetDefault("archive.arweave.bundler_url", "https://upload.ardrive.io/v1/tx")
+	viper.SetDefault("archive.arweave.gateway_url", "https://arweave.net")
+	viper.SetDefault("archive.timeout", "1h")
+
+	// Lifecycle defaults
+	viper.SetDefault("lifecycle.enabled", false)
+	viper.SetDefault("lifecycle.interval", "24h")
+	viper.SetDefault("lifecycle.dry_run", false)
+	viper.SetDefault("lifecycle.orphan_chunk_age", "72h")
+	viper.SetDefault("lifecycle.batch_size", 500)
+
+	// Search defaults
+	viper.SetDefault("search.enabled", true)
+	viper.SetDefault("search.driver", "postgres")
+	viper.SetDefault("search.elasticsearch.index", "streamgate-content")
+	viper.SetDefault("search.elasticsearch.timeout", "10s")
+	viper.SetDefault("search.reindex_interval", "1h")
+
+	// Webhook defaults
+	viper.SetDefault("webhooks.enabled", true)
+	viper.SetDefault("webhooks.max_attempts", 8)
+	viper.SetDefault("webhooks.initial_backoff", "30s")
+	viper.SetDefault("webhooks.max_backoff", "6h")
+	viper.SetDefault("webhooks.timeout", "10s")
+	viper.SetDefault("webhooks.poll_interval", "5s")
+
+	viper.SetDefault("audit.enabled", true)
+	viper.SetDefault("audit.retention", "2160h")
+	viper.SetDefault("audit.export.enabled", false)
+	viper.SetDefault("audit.export.sink", "file")
+	viper.SetDefault("audit.export.format", "jsonl")
+	viper.SetDefault("audit.export.path", "/var/log/streamgate/audit.jsonl")
+	viper.SetDefault("audit.export.max_bytes", 100<<20)
+	viper.SetDefault("audit.export.prefix", "audit")
+	viper.SetDefault("audit.export.batch_size", 100)
+	viper.SetDefault("audit.export.flush_interval", "5s")
+
+	// Content encryption defaults
+	viper.SetDefault("encryption.enabled", false)
+	viper.SetDefault("encryption.key_dir", "/var/lib/streamgate/keys")
 
 	// Rate limiting defaults
 	viper.SetDefault("rate_limiting.enabled", true)
@@ -679,6 +1858,10 @@ func setDefaults() {
 	viper.SetDefault("circuit_breaker.max_requests", 3)
 	viper.SetDefault("circuit_breaker.window_time", "1m")
 
+	// Retry budget defaults
+	viper.SetDefault("retry_budget.rate_per_second", 5)
+	viper.SetDefault("retry_budget.burst", 20)
+
 	// Logging defaults
 	viper.SetDefault("logging.level", "info")
 	viper.SetDefault("logging.format", "json")
@@ -700,6 +1883,8 @@ func setDefaults() {
 	viper.SetDefault("upload.storage_quota", 50*1024*1024*1024)
 	viper.SetDefault("upload.allowed_formats", []string{".mp4", ".webm", ".avi", ".mkv", ".mov", ".mpeg", ".mpg"})
 	viper.SetDefault("upload.max_chunks", 10000)
+	viper.SetDefault("upload.min_chunk_size", 5*1024*1024)
+	viper.SetDefault("upload.max_chunk_size", 512*1024*1024)
 	viper.SetDefault("transcode.profiles", []string{"720p"})
 	viper.SetDefault("features.adaptive_bitrate", true)
 	viper.SetDefault("features.multi_codec", true)
@@ -707,12 +1892,27 @@ func setDefaults() {
 	// Auth defaults: must set via AUTH_JWT_SECRET env var
 	viper.SetDefault("auth.jwt_secret", "")
 	viper.SetDefault("auth.nonce_expiry", "5m")
+	viper.SetDefault("auth.max_in_memory_challenges", 100000)
+	viper.SetDefault("auth.challenge_overflow_policy", "evict_oldest")
+	viper.SetDefault("auth.challenge_message_format", "siwe")
 
 	// CORS defaults
 	viper.SetDefault("cors.allowed_origins", []string{})
 
 	// Plugins defaults
 	viper.SetDefault("plugins.enabled", []string{})
+	viper.SetDefault("plugins.hot_reload", false)
+	viper.SetDefault("plugins.watch_interval", "5s")
+	viper.SetDefault("plugins.trusted_keys", []string{})
+	viper.SetDefault("plugins.allow_unsigned", []string{})
+	viper.SetDefault("plugins.usage_interval", "15s")
+
+	// Analytics defaults
+	viper.SetDefault("analytics.bucket_size", "1h")
+	viper.SetDefault("analytics.retention", "168h")
+
+	// Worker defaults
+	viper.SetDefault("worker.schedules_file", "")
 }
 
 // GetDSN returns the database connection string
@@ -728,6 +1928,36 @@ func (c *DatabaseConfig) GetDSN() string {
 	)
 }
 
+// DefaultMonitoringShutdownTimeout is used when monitoring.shutdown_timeout
+// is unset or invalid.
+const DefaultMonitoringShutdownTimeout = 5 * time.Second
+
+// GetShutdownTimeout returns ShutdownTimeout parsed as a duration, falling
+// back to DefaultMonitoringShutdownTimeout.
+func (c *MonitoringConfig) GetShutdownTimeout() time.Duration {
+	if d, err := time.ParseDuration(c.ShutdownTimeout); err == nil && d > 0 {
+		return d
+	}
+	return DefaultMonitoringShutdownTimeout
+}
+
+// Sampling returns the sampling the tracer provider applies. An invalid
+// latency threshold disables the latency rule.
+func (c *TracingConfig) Sampling() monitoring.SamplingConfig {
+	threshold, _ := time.ParseDuration(c.Tail.LatencyThreshold)
+	return monitoring.SamplingConfig{
+		Strategy:      c.Sampler,
+		Ratio:         c.Ratio,
+		RatePerSecond: c.RatePerSecond,
+		Tail: monitoring.TailSamplingConfig{
+			Enabled:          c.Tail.Enabled,
+			Ratio:            c.Tail.Ratio,
+			LatencyThreshold: threshold,
+			MaxTraces:        c.Tail.MaxTraces,
+		},
+	}
+}
+
 type ValidationError struct {
 	Critical []string
 	Warnings []string
@@ -876,6 +2106,7 @@ func (c *Config) ValidateProduction(log *zap.Logger) error {
 // This is the canonical default configuration for StreamGate.
 func DefaultConfig() *Config {
 	return &Config{
+		Version: CurrentVersion,
 		AppName: "streamgate",
 		Mode:    "monolith",
 		Port:    8080,
@@ -896,6 +2127,22 @@ func DefaultConfig() *Config {
 			Port:    8500,
 		},
 
+		Discovery: DiscoveryConfig{
+			Backend: "consul",
+			Etcd: EtcdDiscoveryConfig{
+				Endpoints: []string{"http://localhost:2379"},
+				Prefix:    "/streamgate/services/",
+				TTL:       "15s",
+			},
+			DNS: DNSDiscoveryConfig{
+				Port: 8080,
+			},
+		},
+
+		Remote: RemoteConfig{
+			PollInterval: "30s",
+		},
+
 		Database: DatabaseConfig{
 			Host:            "localhost",
 			Port:            5432,
@@ -931,10 +2178,12 @@ func DefaultConfig() *Config {
 		},
 
 		Web3: Web3Config{
-			EthereumRPC: envOr("STREAMGATE_ETH_RPC", "https://sepolia.infura.io/v3/YOUR_KEY"),
-			SolanaRPC:   "https://api.devnet.solana.com",
-			ChainID:     11155111,
-			BlockTag:    "safe",
+			EthereumRPC:       envOr("STREAMGATE_ETH_RPC", "https://sepolia.infura.io/v3/YOUR_KEY"),
+			SolanaRPC:         "https://api.devnet.solana.com",
+			ChainID:           11155111,
+			BlockTag:          "safe",
+			RPCHealthInterval: "30s",
+			NFTCacheTTL:       "60s",
 			Transaction: TransactionConfig{
 				GasMultiplier:            1.2,
 				Confirmations:            2,
@@ -950,16 +2199,47 @@ func DefaultConfig() *Config {
 		},
 
 		Monitoring: MonitoringConfig{
-			PrometheusPort: 9090,
-			JaegerEndpoint: "localhost:4317",
-			LogLevel:       "info",
+			PrometheusPort:  9090,
+			JaegerEndpoint:  "localhost:4317",
+			LogLevel:        "info",
+			ShutdownTimeout: "5s",
 		},
 
 		Transcoding: TranscodingConfig{
-			Enabled:       true,
-			MaxWorkers:    4,
-			QueueSize:     100,
-			OutputFormats: []string{"hls", "dash"},
+			Enabled:           true,
+			MaxWorkers:        4,
+			QueueSize:         100,
+			OutputFormats:     []string{"hls", "dash"},
+			PartDuration:      "1s",
+			QueueMaxWait:      "10m",
+			QueueStore:        "memory",
+			VisibilityTimeout: "5m",
+			Hardware:          "none",
+			VAAPIDevice:       "/dev/dri/renderD128",
+			Codecs:            []string{"h264"},
+			PerTitle: PerTitleConfig{
+				Enabled:        true,
+				TargetVMAF:     93,
+				MinBitrate:     200000,
+				MaxBitrate:     8000000,
+				MinRungs:       2,
+				MaxRungs:       6,
+				Samples:        3,
+				SampleDuration: "4s",
+			},
+			Storyboard: StoryboardConfig{
+				Enabled:  true,
+				Interval: "10s",
+				Columns:  5,
+				Rows:     5,
+				Width:    160,
+			},
+			Loudness: LoudnessConfig{
+				Enabled:    true,
+				Integrated: -23,
+				TruePeak:   -1,
+				Range:      7,
+			},
 		},
 
 		Streaming: StreamingConfig{
@@ -968,6 +2248,21 @@ func DefaultConfig() *Config {
 			CacheEnabled:         true,
 			CacheTTL:             "3600s",
 			MaxConcurrentStreams: 1000,
+			DRM: DRMConfig{
+				LicenseTimeout: "10s",
+			},
+		},
+
+		Live: LiveConfig{
+			RTMPPort:        1935,
+			SRTPort:         9000,
+			WorkDir:         "/tmp/streamgate-live",
+			FFmpegPath:      "ffmpeg",
+			SegmentDuration: 2,
+		},
+
+		Encryption: EncryptionConfig{
+			KeyDir: "/var/lib/streamgate/keys",
 		},
 
 		RateLimiting: RateLimitingConfig{
@@ -986,6 +2281,11 @@ func DefaultConfig() *Config {
 			WindowTime:       "1m",
 		},
 
+		RetryBudget: RetryBudgetConfig{
+			RatePerSecond: 5,
+			Burst:         20,
+		},
+
 		Logging: LoggingConfig{
 			Level:      "info",
 			Format:     "json",
@@ -1020,7 +2320,14 @@ func DefaultConfig() *Config {
 		},
 
 		Plugins: PluginsConfig{
-			Enabled: []string{},
+			Enabled:       []string{},
+			WatchInterval: "5s",
+			UsageInterval: "15s",
+		},
+
+		Analytics: AnalyticsConfig{
+			BucketSize: "1h",
+			Retention:  "168h",
 		},
 	}
 }
@@ -1035,19 +2342,54 @@ func envOr(key, fallback string) string {
 }
 
 // ConfigChangeHandler is called when configuration changes are detected.
+// On Reload, returning an error rejects newConfig: the handlers that
+// already accepted it are called again with the arguments swapped to
+// return to oldConfig, and oldConfig stays active. A panic is recovered
+// and treated as an error.
 type ConfigChangeHandler func(oldConfig, newConfig *Config) error
 
+// HandlerOption configures a registered config change handler.
+type HandlerOption func(*registeredHandler)
+
+type registeredHandler struct {
+	id       int
+	priority int
+	handler  ConfigChangeHandler
+}
+
+// WithPriority orders the handler among the others: handlers run by
+// ascending priority, and in the order they were added within one. The
+// default is 0.
+func WithPriority(priority int) HandlerOption {
+	return func(rh *registeredHandler) { rh.priority = priority }
+}
+
+// call runs the handler, turning a panic into an error so one faulty
+// handler neither takes the process down nor skips the rollback.
+func (rh *registeredHandler) call(oldConfig, newConfig *Config) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("config change handler %d panicked: %v", rh.id, r)
+		}
+	}()
+	return rh.handler(oldConfig, newConfig)
+}
+
 // ConfigManager wraps a Config with thread-safe access, file-based
 // persistence, hot-reload via file watching, and change handlers.
 // This is the canonical config manager for StreamGate.
 type ConfigManager struct {
-	config       *Config
-	configPath   string
-	mu           sync.RWMutex
-	logger       *zap.Logger
-	handlers     []ConfigChangeHandler
-	hotReload    bool
-	lastModified time.Time
+	config        *Config
+	configPath    string
+	mu            sync.RWMutex
+	logger        *zap.Logger
+	handlers      []*registeredHandler // in call order
+	nextHandlerID int
+	hotReload     bool
+	lastModified  time.Time
+	eventBus      event.EventBus
+	secrets       *SecretResolver
+	secretRefs    map[string]secretRef
 }
 
 // NewConfigManager creates a new configuration manager
@@ -1055,7 +2397,7 @@ func NewConfigManager(configPath string, logger *zap.Logger) *ConfigManager {
 	return &ConfigManager{
 		configPath: configPath,
 		logger:     logger,
-		handlers:   make([]ConfigChangeHandler, 0),
+		handlers:   make([]*registeredHandler, 0),
 		hotReload:  false,
 	}
 }
@@ -1067,47 +2409,233 @@ func (cm *ConfigManager) Get() *Config {
 	return cm.config
 }
 
-// Load loads configuration from the JSON file at configPath
+// Load loads configuration from the YAML, JSON or TOML file at configPath,
+// by extension. It has the same validate-before-swap semantics as Reload.
 func (cm *ConfigManager) Load() error {
+	return cm.Reload()
+}
+
+// Reload re-reads configuration from configPath (or via viper when the file
+// does not exist) and applies it in two phases: the candidate is parsed and
+// validated, then offered to the change handlers, and only swapped in once
+// every handler accepts it. When any step fails, handlers that accepted it
+// are rolled back, the last-known-good config stays active, a
+// config.reload.failed event is published and the error is returned.
+func (cm *ConfigManager) Reload() error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	var cfg *Config
+	cfg, refs, modTime, err := cm.readConfig()
+	if err == nil {
+		err = validateConfig(cfg)
+	}
+	if err == nil && cm.config != nil {
+		err = cm.offer(cm.config, cfg)
+	}
+	if !modTime.IsZero() {
+		// Record the mtime even on failure so Watch does not retry the same
+		// broken file on every tick.
+		cm.lastModified = modTime
+	}
+	if err != nil {
+		cm.reloadFailed(err)
+		return err
+	}
+	monitoring.ConfigReloadsTotal.WithLabelValues("success").Inc()
 
-	if info, err := os.Stat(cm.configPath); err == nil && !info.IsDir() {
-		data, err := os.ReadFile(cm.configPath)
-		if err != nil {
-			return fmt.Errorf("failed to read config file %s: %w", cm.configPath, err)
-		}
+	cm.config = cfg
+	cm.secretRefs = refs
+	return nil
+}
 
-		cfg = &Config{}
-		if err := yaml.Unmarshal(data, cfg); err != nil {
-			return fmt.Errorf("failed to parse config file %s: %w", cm.configPath, err)
+// offer passes candidate to the change handlers in order. When one rejects
+// it, those that accepted it are called again in reverse order with the
+// arguments swapped, to return to current, and the rejection is returned.
+// Callers must hold cm.mu.
+func (cm *ConfigManager) offer(current, candidate *Config) error {
+	for i, rh := range cm.handlers {
+		err := rh.call(current, candidate)
+		if err == nil {
+			continue
 		}
-		cm.lastModified = info.ModTime()
-	} else {
-		var err error
-		cfg, err = LoadConfig()
+		for j := i - 1; j >= 0; j-- {
+			if rbErr := cm.handlers[j].call(candidate, current); rbErr != nil {
+				cm.logger.Error("Config change handler failed to roll back",
+					zap.Int("handler", cm.handlers[j].id),
+					zap.Error(rbErr))
+			}
+		}
+		return fmt.Errorf("config change handler %d rejected the new configuration: %w", rh.id, err)
+	}
+	return nil
+}
+
+// readConfig parses the file at configPath, falling back to viper when it
+// does not exist, and resolves its secret references. The returned mod
+// time is zero for the viper path.
+func (cm *ConfigManager) readConfig() (*Config, map[string]secretRef, time.Time, error) {
+	info, err := os.Stat(cm.configPath)
+	if err != nil || info.IsDir() {
+		cfg, refs, err := loadConfig()
 		if err != nil {
-			return fmt.Errorf("failed to load config via viper: %w", err)
+			return nil, nil, time.Time{}, fmt.Errorf("failed to load config via viper: %w", err)
 		}
+		return cfg, refs, time.Time{}, nil
 	}
 
-	oldConfig := cm.config
-	cm.config = cfg
+	data, err := os.ReadFile(cm.configPath)
+	if err != nil {
+		return nil, nil, time.Time{}, fmt.Errorf("failed to read config file %s: %w", cm.configPath, err)
+	}
 
-	if oldConfig != nil && len(cm.handlers) > 0 {
-		for _, handler := range cm.handlers {
-			if err := handler(oldConfig, cfg); err != nil {
-				cm.logger.Error("Config change handler failed", zap.Error(err))
+	cfg, err := decodeConfig(data, formatOf(cm.configPath))
+	if err != nil {
+		return nil, nil, info.ModTime(), fmt.Errorf("failed to parse config file %s: %w", cm.configPath, err)
+	}
+	refs, err := cm.secretResolver().resolveConfig(context.Background(), cfg)
+	if err != nil {
+		return nil, nil, info.ModTime(), fmt.Errorf("config file %s: %w", cm.configPath, err)
+	}
+	return cfg, refs, info.ModTime(), nil
+}
+
+// reloadFailed reports a rejected reload. Callers must hold cm.mu.
+func (cm *ConfigManager) reloadFailed(err error) {
+	monitoring.ConfigReloadsTotal.WithLabelValues("failed").Inc()
+	cm.logger.Error("Config reload rejected, keeping last-known-good configuration",
+		zap.String("path", cm.configPath),
+		zap.Bool("has_last_known_good", cm.config != nil),
+		zap.Error(err))
+
+	if cm.eventBus == nil {
+		return
+	}
+	evt := &event.Event{
+		Type:      event.EventTypeConfigReloadFailed,
+		Source:    "config",
+		Timestamp: time.Now().Unix(),
+		Data: map[string]interface{}{
+			"path":  cm.configPath,
+			"error": err.Error(),
+		},
+	}
+	if pubErr := cm.eventBus.Publish(context.Background(), evt); pubErr != nil {
+		cm.logger.Warn("Failed to publish config reload failure", zap.Error(pubErr))
+	}
+}
+
+// SetEventBus sets the bus that receives config.reload.failed events.
+func (cm *ConfigManager) SetEventBus(bus event.EventBus) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.eventBus = bus
+}
+
+// SetSecretResolver sets the resolver for secret references in the config
+// file. The default is Secrets().
+func (cm *ConfigManager) SetSecretResolver(r *SecretResolver) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.secrets = r
+}
+
+// secretResolver returns the resolver in use. Callers must hold cm.mu.
+func (cm *ConfigManager) secretResolver() *SecretResolver {
+	if cm.secrets != nil {
+		return cm.secrets
+	}
+	return Secrets()
+}
+
+// WatchSecrets fetches the secrets the config references every interval
+// and reloads the config when one was rotated, so change handlers see the
+// new value. It returns when ctx is done.
+func (cm *ConfigManager) WatchSecrets(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := cm.refreshSecrets(ctx); err != nil {
+				cm.logger.Warn("Secret refresh failed", zap.Error(err))
 			}
 		}
 	}
+}
 
-	return nil
+// refreshSecrets refetches the cached secrets and reloads on rotation.
+func (cm *ConfigManager) refreshSecrets(ctx context.Context) error {
+	cm.mu.RLock()
+	r := cm.secretResolver()
+	cm.mu.RUnlock()
+
+	changed, err := r.Refresh(ctx)
+	if !changed {
+		return err
+	}
+	cm.logger.Info("Secret rotated, reloading configuration")
+	return errors.Join(err, cm.Reload())
+}
+
+// WatchRemote polls the remote.source document of the current config every
+// interval and reloads when its revision changes, so change handlers see
+// centrally made changes. It is meant for managers that load through
+// LoadConfig, i.e. whose configPath does not exist. It returns when ctx is
+// done, or an error if no remote source is configured.
+func (cm *ConfigManager) WatchRemote(ctx context.Context, interval time.Duration) error {
+	cfg := cm.Get()
+	if cfg == nil || cfg.Remote.Source == "" {
+		return fmt.Errorf("no remote config source configured")
+	}
+	src, err := OpenRemoteSource(cfg.Remote.Source)
+	if err != nil {
+		return fmt.Errorf("invalid remote.source %q: %w", cfg.Remote.Source, err)
+	}
+
+	cm.logger.Info("Starting remote configuration watcher",
+		zap.Stringer("source", src),
+		zap.Duration("interval", interval))
+
+	var revision uint64
+	if _, revision, err = src.Fetch(ctx); err != nil {
+		cm.logger.Warn("Remote config fetch failed", zap.Stringer("source", src), zap.Error(err))
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := cm.pollRemote(ctx, src, &revision); err != nil {
+				cm.logger.Warn("Remote config poll failed", zap.Stringer("source", src), zap.Error(err))
+			}
+		}
+	}
+}
+
+// pollRemote reloads when src's revision differs from *revision, which it
+// then updates. A rejected reload is not retried until the next change.
+func (cm *ConfigManager) pollRemote(ctx context.Context, src RemoteSource, revision *uint64) error {
+	_, rev, err := src.Fetch(ctx)
+	if err != nil || rev == *revision {
+		return err
+	}
+	*revision = rev
+	cm.logger.Info("Remote configuration changed, reloading",
+		zap.Stringer("source", src),
+		zap.Uint64("revision", rev))
+	return cm.Reload()
 }
 
-// Save saves the current configuration to the JSON file at configPath
+// Save writes the current configuration to configPath in the format its
+// extension names: YAML, JSON or TOML. Settings read as secret references
+// are written as the references, not their values.
 func (cm *ConfigManager) Save() error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
@@ -1116,7 +2644,7 @@ func (cm *ConfigManager) Save() error {
 		return fmt.Errorf("no configuration to save")
 	}
 
-	data, err := yaml.Marshal(cm.config)
+	data, err := encodeConfig(unresolveConfig(cm.config, cm.secretRefs), formatOf(cm.configPath))
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
@@ -1132,7 +2660,8 @@ func (cm *ConfigManager) Save() error {
 	return nil
 }
 
-// Validate validates the current configuration
+// Validate checks the current configuration as Reload does and returns a
+// *SchemaError listing every problem found.
 func (cm *ConfigManager) Validate() error {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
@@ -1141,17 +2670,33 @@ func (cm *ConfigManager) Validate() error {
 		return fmt.Errorf("configuration is not loaded")
 	}
 
-	if cm.config.Server.Port <= 0 || cm.config.Server.Port > 65535 {
-		return fmt.Errorf("invalid server port: %d", cm.config.Server.Port)
-	}
+	return validateConfig(cm.config)
+}
 
-	if cm.config.Database.Host == "" {
-		return fmt.Errorf("database host is required")
+// validateChallengeMessage rejects login message settings that would only
+// fail once a wallet asks for a challenge.
+func validateChallengeMessage(a *AuthConfig) error {
+	switch a.ChallengeMessageFormat {
+	case "", "siwe", "personal_sign":
+	default:
+		return fmt.Errorf("invalid auth.challenge_message_format %q: want siwe or personal_sign", a.ChallengeMessageFormat)
+	}
+	if strings.ContainsAny(a.ChallengeStatement, "\r\n") {
+		return fmt.Errorf("auth.challenge_statement must be a single line")
+	}
+	if _, err := signature.ParseLoginMessageTemplate(a.ChallengeMessageTemplate); err != nil {
+		return fmt.Errorf("auth.challenge_message_template: %w", err)
 	}
-
 	return nil
 }
 
+// validateConfig checks a config ConfigManager read. A config file may
+// leave settings out, so beyond server.port and database.host only the
+// settings it sets are checked.
+func validateConfig(cfg *Config) error {
+	return cfg.validate(&Validation{partial: true})
+}
+
 // Update updates the configuration and notifies change handlers
 func (cm *ConfigManager) Update(newConfig *Config) error {
 	cm.mu.Lock()
@@ -1160,10 +2705,10 @@ func (cm *ConfigManager) Update(newConfig *Config) error {
 	oldConfig := cm.config
 	cm.config = newConfig
 
-	if oldConfig != nil && len(cm.handlers) > 0 {
-		for _, handler := range cm.handlers {
-			if err := handler(oldConfig, newConfig); err != nil {
-				cm.logger.Error("Config change handler failed", zap.Error(err))
+	if oldConfig != nil {
+		for _, rh := range cm.handlers {
+			if err := rh.call(oldConfig, newConfig); err != nil {
+				cm.logger.Error("Config change handler failed", zap.Int("handler", rh.id), zap.Error(err))
 			}
 		}
 	}
@@ -1171,24 +2716,72 @@ func (cm *ConfigManager) Update(newConfig *Config) error {
 	return nil
 }
 
-// AddChangeHandler adds a handler for configuration changes and returns its index.
-func (cm *ConfigManager) AddChangeHandler(handler ConfigChangeHandler) int {
+// Apply overlays patch, a YAML or JSON document of the settings to change
+// in the key names of a config file (server.readtimeout), on the current
+// configuration and switches to the result. Like Reload, it validates the
+// result and offers it to the change handlers first, keeping the current
+// configuration when either fails. The change lasts until the next Reload.
+func (cm *ConfigManager) Apply(patch []byte) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	cm.handlers = append(cm.handlers, handler)
-	return len(cm.handlers) - 1
+	if cm.config == nil {
+		return fmt.Errorf("configuration is not loaded")
+	}
+	cfg, err := overlayConfig(cm.config, patch)
+	if err != nil {
+		return fmt.Errorf("invalid config patch: %w", err)
+	}
+	if err = validateConfig(cfg); err == nil {
+		err = cm.offer(cm.config, cfg)
+	}
+	if err != nil {
+		cm.reloadFailed(err)
+		return err
+	}
+	monitoring.ConfigReloadsTotal.WithLabelValues("success").Inc()
+
+	cm.config = cfg
+	return nil
 }
 
-// RemoveChangeHandler removes a configuration change handler by index.
-func (cm *ConfigManager) RemoveChangeHandler(handlerIndex int) {
+// ChangedSections returns the names of the top-level sections that differ
+// between oldCfg and newCfg, e.g. "Database" or "Auth".
+func ChangedSections(oldCfg, newCfg *Config) []string {
+	ov, nv := reflect.ValueOf(oldCfg).Elem(), reflect.ValueOf(newCfg).Elem()
+	var changed []string
+	for i := 0; i < ov.NumField(); i++ {
+		if !reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			changed = append(changed, ov.Type().Field(i).Name)
+		}
+	}
+	return changed
+}
+
+// AddChangeHandler adds a handler for configuration changes and returns
+// its ID for RemoveChangeHandler. IDs are not reused.
+func (cm *ConfigManager) AddChangeHandler(handler ConfigChangeHandler, opts ...HandlerOption) int {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	if handlerIndex < 0 || handlerIndex >= len(cm.handlers) {
-		return
+	rh := &registeredHandler{id: cm.nextHandlerID, handler: handler}
+	cm.nextHandlerID++
+	for _, opt := range opts {
+		opt(rh)
 	}
-	cm.handlers = append(cm.handlers[:handlerIndex], cm.handlers[handlerIndex+1:]...)
+	// After every handler of the same or a lower priority.
+	i := sort.Search(len(cm.handlers), func(i int) bool { return cm.handlers[i].priority > rh.priority })
+	cm.handlers = slices.Insert(cm.handlers, i, rh)
+	return rh.id
+}
+
+// RemoveChangeHandler removes the handler AddChangeHandler returned id
+// for. Unknown IDs are ignored.
+func (cm *ConfigManager) RemoveChangeHandler(id int) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	cm.handlers = slices.DeleteFunc(cm.handlers, func(rh *registeredHandler) bool { return rh.id == id })
 }
 
 // SetHotReload enables or disables hot reload
@@ -1258,15 +2851,7 @@ func (cm *ConfigManager) Watch(ctx context.Context, interval time.Duration) erro
 
 			if info.ModTime().After(lastMod) {
 				cm.logger.Info("Configuration file changed, reloading")
-				cfg, err := LoadConfig()
-				if err != nil {
-					cm.logger.Error("Failed to reload configuration", zap.Error(err))
-					continue
-				}
-				_ = cm.Update(cfg)
-				cm.mu.Lock()
-				cm.lastModified = info.ModTime()
-				cm.mu.Unlock()
+				_ = cm.Reload()
 			}
 		}
 	}
@@ -1,11 +1,17 @@
 package config
 
 import (
+	"context"
 	"encoding/hex"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/rtcdance/streamgate/pkg/core/event"
+	"github.com/rtcdance/streamgate/pkg/monitoring"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -146,6 +152,16 @@ func TestLoadConfig(t *testing.T) {
 		_ = os.Unsetenv("STREAMGATE_DB_MAXCONNS")
 	})
 
+	t.Run("load config with read replica env vars", func(t *testing.T) {
+		t.Setenv("STREAMGATE_DB_REPLICA_DSNS", "host=replica-1 dbname=streamgate, host=replica-2 dbname=streamgate")
+		t.Setenv("STREAMGATE_DB_REPLICA_RETRY_AFTER", "10s")
+
+		cfg, err := LoadConfig()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"host=replica-1 dbname=streamgate", "host=replica-2 dbname=streamgate"}, cfg.Database.ReplicaDSNs)
+		assert.Equal(t, "10s", cfg.Database.ReplicaRetryAfter)
+	})
+
 	t.Run("load config with redis env vars", func(t *testing.T) {
 		_ = os.Setenv("STREAMGATE_REDIS_HOST", "redis-host")
 		_ = os.Setenv("STREAMGATE_REDIS_PORT", "6380")
@@ -178,6 +194,29 @@ func TestLoadConfig(t *testing.T) {
 		_ = os.Unsetenv("STREAMGATE_STORAGE_BUCKET")
 	})
 
+	t.Run("load config with non-MinIO storage types", func(t *testing.T) {
+		_ = os.Setenv("STREAMGATE_STORAGE_TYPE", "azure")
+		cfg, err := LoadConfig()
+		require.NoError(t, err)
+		assert.Empty(t, cfg.Storage.Endpoint, "MinIO dev endpoint is dropped for azure")
+
+		_ = os.Setenv("STREAMGATE_STORAGE_TYPE", "local")
+		_, err = LoadConfig()
+		assert.ErrorContains(t, err, "storage path is required")
+
+		_ = os.Setenv("STREAMGATE_STORAGE_PATH", "/var/lib/streamgate")
+		cfg, err = LoadConfig()
+		require.NoError(t, err)
+		assert.Equal(t, "/var/lib/streamgate", cfg.Storage.Path)
+
+		_ = os.Setenv("STREAMGATE_STORAGE_TYPE", "ftp")
+		_, err = LoadConfig()
+		assert.ErrorContains(t, err, "unsupported storage type")
+
+		_ = os.Unsetenv("STREAMGATE_STORAGE_TYPE")
+		_ = os.Unsetenv("STREAMGATE_STORAGE_PATH")
+	})
+
 	t.Run("load config with web3 env vars", func(t *testing.T) {
 		_ = os.Setenv("STREAMGATE_ETH_RPC", "https://mainnet.infura.io/v3/test-key")
 		_ = os.Setenv("STREAMGATE_SOLANA_RPC", "https://api.mainnet-beta.solana.com")
@@ -252,6 +291,268 @@ func TestLoadConfig_InvalidGRPCPort(t *testing.T) {
 	assert.Contains(t, err.Error(), "invalid gRPC port")
 }
 
+func TestLoadConfig_InvalidChallengeMessage(t *testing.T) {
+	defer viper.Reset()
+
+	viper.Set("auth.challenge_message_template", "Sign in to Acme")
+	_, err := LoadConfig()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "auth.challenge_message_template")
+
+	viper.Set("auth.challenge_message_template", "Acme login\nNonce: {{.Nonce}}\nIssued At: {{.IssuedAt}}")
+	viper.Set("auth.challenge_message_format", "eip191")
+	_, err = LoadConfig()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "auth.challenge_message_format")
+
+	viper.Set("auth.challenge_message_format", "personal_sign")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "personal_sign", cfg.Auth.ChallengeMessageFormat)
+}
+
+func TestLoadConfig_InvalidDRM(t *testing.T) {
+	defer viper.Reset()
+
+	viper.Set("transcoding.qualities", []map[string]interface{}{
+		{"name": "1080p", "width": 1920, "height": 1080, "bitrate": 5000000, "drm": "cbcs"},
+	})
+	_, err := LoadConfig()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "drm requires encryption")
+
+	viper.Set("encryption.enabled", true)
+	viper.Set("encryption.master_key", strings.Repeat("ab", 32))
+	viper.Set("streaming.drm.license_servers", []map[string]interface{}{{"system": "playready", "url": "https://license.example.com"}})
+	_, err = LoadConfig()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "playready")
+
+	viper.Set("streaming.drm.license_servers", []map[string]interface{}{{"system": "fairplay", "url": "https://license.example.com"}})
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "cbcs", cfg.Transcoding.Qualities[0].DRM)
+	assert.Equal(t, "fairplay", cfg.Streaming.DRM.LicenseServers[0].System)
+}
+
+func TestLoadConfig_InvalidHardware(t *testing.T) {
+	defer viper.Reset()
+
+	viper.Set("transcoding.hardware", "cuda")
+	_, err := LoadConfig()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "transcoding.hardware")
+
+	viper.Set("transcoding.hardware", "auto")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "auto", cfg.Transcoding.Hardware)
+	assert.Equal(t, "/dev/dri/renderD128", cfg.Transcoding.VAAPIDevice)
+}
+
+func TestLoadConfig_InvalidQueueStore(t *testing.T) {
+	defer viper.Reset()
+
+	viper.Set("transcoding.queue_store", "postgres")
+	_, err := LoadConfig()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "transcoding.queue_store")
+
+	viper.Set("transcoding.queue_store", "memory")
+	viper.Set("transcoding.distributed", true)
+	_, err = LoadConfig()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "transcoding.distributed")
+
+	viper.Set("transcoding.queue_store", "redis")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "redis", cfg.Transcoding.QueueStore)
+	assert.True(t, cfg.Transcoding.Distributed)
+}
+
+func TestLoadConfig_Codecs(t *testing.T) {
+	defer viper.Reset()
+
+	viper.Set("transcoding.codecs", []string{"h264", "vp9"})
+	_, err := LoadConfig()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "vp9")
+
+	viper.Set("transcoding.codecs", []string{"h264", "hevc"})
+	viper.Set("transcoding.codec_ladders", map[string]interface{}{
+		"hevc": []map[string]interface{}{{"name": "1080p", "width": 1920, "height": 1080, "bitrate": 3000000}},
+	})
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"h264", "hevc"}, cfg.Transcoding.Codecs)
+	require.Len(t, cfg.Transcoding.CodecLadders["hevc"], 1)
+	assert.Equal(t, 3000000, cfg.Transcoding.CodecLadders["hevc"][0].Bitrate)
+
+	viper.Set("transcoding.codec_ladders", map[string]interface{}{"h264": []map[string]interface{}{}})
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "codec_ladders")
+}
+
+func TestLoadConfig_PerTitle(t *testing.T) {
+	defer viper.Reset()
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.True(t, cfg.Transcoding.PerTitle.Enabled)
+	assert.Equal(t, 200000, cfg.Transcoding.PerTitle.MinBitrate)
+	assert.Equal(t, 6, cfg.Transcoding.PerTitle.MaxRungs)
+
+	viper.Set("transcoding.per_title.min_bitrate", 9000000)
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "min_bitrate")
+
+	viper.Set("transcoding.per_title.enabled", false)
+	cfg, err = LoadConfig()
+	require.NoError(t, err, "bounds are not checked while per-title encoding is off")
+	assert.False(t, cfg.Transcoding.PerTitle.Enabled)
+}
+
+func TestLoadConfig_Storyboard(t *testing.T) {
+	defer viper.Reset()
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, StoryboardConfig{Enabled: true, Interval: "10s", Columns: 5, Rows: 5, Width: 160}, cfg.Transcoding.Storyboard)
+
+	viper.Set("transcoding.storyboard.interval", "often")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "storyboard.interval")
+
+	viper.Set("transcoding.storyboard.interval", "5s")
+	viper.Set("transcoding.storyboard.width", 161)
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "storyboard")
+}
+
+func TestLoadConfig_Loudness(t *testing.T) {
+	defer viper.Reset()
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, LoudnessConfig{Enabled: true, Integrated: -23, TruePeak: -1, Range: 7}, cfg.Transcoding.Loudness)
+
+	viper.Set("transcoding.loudness.integrated", -16)
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, -16.0, cfg.Transcoding.Loudness.Integrated)
+
+	viper.Set("transcoding.loudness.true_peak", 2)
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "loudness.true_peak")
+
+	viper.Set("transcoding.loudness.enabled", false)
+	_, err = LoadConfig()
+	assert.NoError(t, err, "a disabled target is not validated")
+}
+
+func TestLoadConfig_TracingSampling(t *testing.T) {
+	defer viper.Reset()
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, monitoring.SamplingConfig{
+		Strategy:      "always",
+		Ratio:         0.1,
+		RatePerSecond: 100,
+		Tail:          monitoring.TailSamplingConfig{Ratio: 0.1, LatencyThreshold: 2 * time.Second, MaxTraces: 10000},
+	}, cfg.Monitoring.Tracing.Sampling())
+
+	viper.Set("monitoring.tracing.sampler", "probabilistic")
+	viper.Set("monitoring.tracing.ratio", 2)
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "monitoring.tracing")
+
+	viper.Set("monitoring.tracing.sampler", "sometimes")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "monitoring.tracing")
+
+	viper.Set("monitoring.tracing.sampler", "rate_limited")
+	viper.Set("monitoring.tracing.tail.enabled", true)
+	viper.Set("monitoring.tracing.tail.latency_threshold", "slow")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "latency_threshold")
+}
+
+func TestLoadConfig_Audit(t *testing.T) {
+	defer viper.Reset()
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.True(t, cfg.Audit.Enabled)
+	assert.Equal(t, 90*24*time.Hour, cfg.Audit.GetRetention())
+
+	viper.Set("audit.retention", "0")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Zero(t, cfg.Audit.GetRetention(), "zero keeps entries forever")
+
+	viper.Set("audit.retention", "forever")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "audit.retention")
+}
+
+func TestLoadConfig_AuditExport(t *testing.T) {
+	defer viper.Reset()
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.False(t, cfg.Audit.Export.Enabled)
+	assert.Equal(t, "file", cfg.Audit.Export.Sink)
+	assert.Equal(t, 5*time.Second, cfg.Audit.Export.GetFlushInterval())
+
+	viper.Set("audit.export.enabled", true)
+	viper.Set("audit.export.sink", "http")
+	viper.Set("audit.export.format", "cef")
+	viper.Set("audit.export.url", "https://siem.example.com/ingest")
+	viper.Set("audit.export.headers", map[string]string{"authorization": "Bearer t"})
+	viper.Set("audit.export.batch_size", 500)
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "cef", cfg.Audit.Export.Format)
+	assert.Equal(t, "https://siem.example.com/ingest", cfg.Audit.Export.URL)
+	assert.Equal(t, "Bearer t", cfg.Audit.Export.Headers["authorization"])
+	assert.Equal(t, 500, cfg.Audit.Export.BatchSize)
+
+	viper.Set("audit.export.url", "")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "audit export url is required")
+
+	viper.Set("audit.export.sink", "syslog")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "audit.export.sink")
+}
+
+func TestLoadConfig_Shutdown(t *testing.T) {
+	defer viper.Reset()
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultDrainDelay, cfg.Server.GetDrainDelay())
+	assert.Equal(t, DefaultShutdownTimeout, cfg.Server.GetShutdownTimeout())
+
+	viper.Set("server.drain_delay", "0s")
+	viper.Set("server.shutdown_timeout", "1m")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Zero(t, cfg.Server.GetDrainDelay(), "zero disables the drain delay")
+	assert.Equal(t, time.Minute, cfg.Server.GetShutdownTimeout())
+
+	viper.Set("server.shutdown_timeout", "0s")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "server.shutdown_timeout")
+
+	viper.Set("server.shutdown_timeout", "")
+	viper.Set("server.drain_delay", "-1s")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "server.drain_delay")
+}
+
 func TestDefaultConfig(t *testing.T) {
 	cfg := DefaultConfig()
 
@@ -284,6 +585,8 @@ func TestDefaultConfig(t *testing.T) {
 	assert.Equal(t, "nats://localhost:4222", cfg.NATS.URL)
 	assert.Equal(t, int64(11155111), cfg.Web3.ChainID)
 	assert.Equal(t, "safe", cfg.Web3.BlockTag)
+	assert.Equal(t, "30s", cfg.Web3.RPCHealthInterval)
+	assert.Equal(t, "60s", cfg.Web3.NFTCacheTTL)
 	assert.True(t, cfg.Web3.Transaction.EIP1559)
 	assert.Equal(t, float64(1.2), cfg.Web3.Transaction.GasMultiplier)
 	assert.Equal(t, uint64(2), cfg.Web3.Transaction.Confirmations)
@@ -292,8 +595,30 @@ func TestDefaultConfig(t *testing.T) {
 	assert.Equal(t, 4, cfg.Transcoding.MaxWorkers)
 	assert.Equal(t, 100, cfg.Transcoding.QueueSize)
 	assert.Equal(t, []string{"hls", "dash"}, cfg.Transcoding.OutputFormats)
+	assert.Equal(t, "1s", cfg.Transcoding.PartDuration)
+	assert.Equal(t, "10m", cfg.Transcoding.QueueMaxWait)
+	assert.Equal(t, "memory", cfg.Transcoding.QueueStore)
+	assert.Equal(t, "5m", cfg.Transcoding.VisibilityTimeout)
+	assert.Empty(t, cfg.Transcoding.PackagerPath)
+	assert.Equal(t, "none", cfg.Transcoding.Hardware)
+	assert.Equal(t, "/dev/dri/renderD128", cfg.Transcoding.VAAPIDevice)
+	assert.Equal(t, []string{"h264"}, cfg.Transcoding.Codecs)
+	assert.True(t, cfg.Transcoding.PerTitle.Enabled)
+	assert.Equal(t, float64(93), cfg.Transcoding.PerTitle.TargetVMAF)
+	assert.Equal(t, "4s", cfg.Transcoding.PerTitle.SampleDuration)
+	assert.True(t, cfg.Transcoding.Storyboard.Enabled)
+	assert.Equal(t, "10s", cfg.Transcoding.Storyboard.Interval)
+	assert.Equal(t, LoudnessConfig{Enabled: true, Integrated: -23, TruePeak: -1, Range: 7}, cfg.Transcoding.Loudness)
 	assert.Equal(t, 10, cfg.Streaming.HLSSegmentDuration)
 	assert.True(t, cfg.Streaming.CacheEnabled)
+	assert.Equal(t, "10s", cfg.Streaming.DRM.LicenseTimeout)
+	assert.Empty(t, cfg.Streaming.DRM.LicenseServers)
+	assert.False(t, cfg.Live.Enabled)
+	assert.Equal(t, 1935, cfg.Live.RTMPPort)
+	assert.Equal(t, 9000, cfg.Live.SRTPort)
+	assert.Equal(t, 2, cfg.Live.SegmentDuration)
+	assert.False(t, cfg.Encryption.Enabled)
+	assert.Equal(t, "/var/lib/streamgate/keys", cfg.Encryption.KeyDir)
 	assert.True(t, cfg.RateLimiting.Enabled)
 	assert.True(t, cfg.CircuitBreaker.Enabled)
 	assert.True(t, cfg.Features.NFTGating)
@@ -939,6 +1264,82 @@ grpc:
 	})
 }
 
+func TestConfigManagerReloadKeepsLastKnownGood(t *testing.T) {
+	goodYAML := `appname: good
+server:
+  port: 8080
+database:
+  host: localhost
+`
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "corrupt yaml", content: "server: [port: 8080\n", wantErr: "failed to parse config file"},
+		{name: "invalid port", content: "appname: bad\nserver:\n  port: 70000\ndatabase:\n  host: localhost\n", wantErr: "invalid server port"},
+		{name: "missing db host", content: "appname: bad\nserver:\n  port: 8080\n", wantErr: "database host is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(goodYAML), 0o644))
+
+			bus, err := event.NewMemoryEventBus()
+			require.NoError(t, err)
+			defer bus.Close()
+			failed := make(chan *event.Event, 1)
+			_, err = bus.Subscribe(context.Background(), event.EventTypeConfigReloadFailed, func(_ context.Context, e *event.Event) error {
+				failed <- e
+				return nil
+			})
+			require.NoError(t, err)
+
+			cm := NewConfigManager(path, zap.NewNop())
+			cm.SetEventBus(bus)
+			require.NoError(t, cm.Load())
+
+			var handlerCalled bool
+			cm.AddChangeHandler(func(old, new_ *Config) error {
+				handlerCalled = true
+				return nil
+			})
+
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o644))
+			err = cm.Reload()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+
+			assert.Equal(t, "good", cm.Get().AppName)
+			assert.Equal(t, 8080, cm.Get().Server.Port)
+			assert.False(t, handlerCalled)
+
+			select {
+			case e := <-failed:
+				assert.Equal(t, path, e.Data["path"])
+				assert.Contains(t, e.Data["error"], tt.wantErr)
+			case <-time.After(time.Second):
+				t.Fatal("expected config.reload.failed event")
+			}
+		})
+	}
+}
+
+func TestConfigManagerReloadAppliesValidConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("appname: first\nserver:\n  port: 8080\ndatabase:\n  host: localhost\n"), 0o644))
+
+	cm := NewConfigManager(path, zap.NewNop())
+	require.NoError(t, cm.Load())
+
+	require.NoError(t, os.WriteFile(path, []byte("appname: second\nserver:\n  port: 9090\ndatabase:\n  host: db\n"), 0o644))
+	require.NoError(t, cm.Reload())
+
+	assert.Equal(t, "second", cm.Get().AppName)
+	assert.Equal(t, 9090, cm.Get().Server.Port)
+}
+
 func TestLoadOrCreate(t *testing.T) {
 	t.Run("creates default when file not found", func(t *testing.T) {
 		dir := t.TempDir()
@@ -970,6 +1371,25 @@ func TestChainConfigEntry(t *testing.T) {
 	assert.False(t, entry.IsTestnet)
 }
 
+func TestWeb3Config_ChainEntries(t *testing.T) {
+	w := Web3Config{
+		Chains: []ChainConfigEntry{{ID: 1, Name: "Ethereum"}},
+		Networks: map[string]ChainConfigEntry{
+			"polygon": {ID: 137, RPCs: []string{"https://polygon.example.com"}},
+			"base":    {ID: 8453, Name: "Base Mainnet", Confirmations: 10},
+		},
+	}
+
+	entries := w.ChainEntries()
+	require.Len(t, entries, 3)
+	assert.Equal(t, int64(1), entries[0].ID)
+	assert.Equal(t, "base", entries[1].Slug)
+	assert.Equal(t, "Base Mainnet", entries[1].Name)
+	assert.Equal(t, uint64(10), entries[1].Confirmations)
+	assert.Equal(t, "polygon", entries[2].Slug)
+	assert.Equal(t, "polygon", entries[2].Name, "name defaults to the network key")
+}
+
 func TestRPCRateLimitConfig(t *testing.T) {
 	cfg := RPCRateLimitConfig{
 		Enabled: true,
@@ -997,3 +1417,166 @@ func TestTransactionConfig(t *testing.T) {
 	assert.Equal(t, float64(1.2), cfg.GasMultiplier)
 	assert.True(t, cfg.EIP1559)
 }
+
+func TestConfigManagerReloadRollsBackOnHandlerRejection(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("appname: first\nserver:\n  port: 8080\ndatabase:\n  host: localhost\n"), 0o644))
+
+	bus, err := event.NewMemoryEventBus()
+	require.NoError(t, err)
+	defer bus.Close()
+	failed := make(chan *event.Event, 1)
+	_, err = bus.Subscribe(context.Background(), event.EventTypeConfigReloadFailed, func(_ context.Context, e *event.Event) error {
+		failed <- e
+		return nil
+	})
+	require.NoError(t, err)
+
+	cm := NewConfigManager(path, zap.NewNop())
+	cm.SetEventBus(bus)
+	require.NoError(t, cm.Load())
+
+	var calls []string
+	reject := true
+	cm.AddChangeHandler(func(old, new_ *Config) error {
+		calls = append(calls, "a:"+old.AppName+"->"+new_.AppName)
+		return nil
+	})
+	cm.AddChangeHandler(func(old, new_ *Config) error {
+		calls = append(calls, "b:"+old.AppName+"->"+new_.AppName)
+		if reject {
+			return errors.New("port change needs a restart")
+		}
+		return nil
+	})
+	cm.AddChangeHandler(func(old, new_ *Config) error {
+		calls = append(calls, "c:"+old.AppName+"->"+new_.AppName)
+		return nil
+	})
+
+	require.NoError(t, os.WriteFile(path, []byte("appname: second\nserver:\n  port: 9090\ndatabase:\n  host: localhost\n"), 0o644))
+	err = cm.Reload()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "handler 1 rejected")
+	assert.Contains(t, err.Error(), "port change needs a restart")
+	assert.Equal(t, []string{"a:first->second", "b:first->second", "a:second->first"}, calls,
+		"handlers after the rejecting one are skipped and earlier ones rolled back")
+	assert.Equal(t, "first", cm.Get().AppName)
+	assert.Equal(t, 8080, cm.Get().Server.Port)
+
+	select {
+	case e := <-failed:
+		assert.Contains(t, e.Data["error"], "port change needs a restart")
+	case <-time.After(time.Second):
+		t.Fatal("expected config.reload.failed event")
+	}
+
+	calls = nil
+	reject = false
+	require.NoError(t, cm.Reload())
+	assert.Equal(t, []string{"a:first->second", "b:first->second", "c:first->second"}, calls)
+	assert.Equal(t, "second", cm.Get().AppName)
+}
+
+func TestConfigManagerChangeHandlers(t *testing.T) {
+	newManager := func() *ConfigManager {
+		cm := NewConfigManager(filepath.Join(t.TempDir(), "config.yaml"), zap.NewNop())
+		cm.config = DefaultConfig()
+		return cm
+	}
+
+	t.Run("remove by id", func(t *testing.T) {
+		cm := newManager()
+		var calls []string
+		a := cm.AddChangeHandler(func(_, _ *Config) error { calls = append(calls, "a"); return nil })
+		b := cm.AddChangeHandler(func(_, _ *Config) error { calls = append(calls, "b"); return nil })
+		c := cm.AddChangeHandler(func(_, _ *Config) error { calls = append(calls, "c"); return nil })
+		assert.Equal(t, []int{0, 1, 2}, []int{a, b, c})
+
+		// Removing a handler does not shift the IDs of later ones.
+		cm.RemoveChangeHandler(a)
+		cm.RemoveChangeHandler(c)
+		cm.RemoveChangeHandler(a)
+		cm.RemoveChangeHandler(99)
+		require.NoError(t, cm.Update(DefaultConfig()))
+		assert.Equal(t, []string{"b"}, calls)
+
+		assert.Equal(t, 3, cm.AddChangeHandler(func(_, _ *Config) error { return nil }), "IDs are not reused")
+	})
+
+	t.Run("priority", func(t *testing.T) {
+		cm := newManager()
+		var calls []string
+		add := func(name string, opts ...HandlerOption) {
+			cm.AddChangeHandler(func(_, _ *Config) error { calls = append(calls, name); return nil }, opts...)
+		}
+		add("default-1")
+		add("late", WithPriority(10))
+		add("early", WithPriority(-10))
+		add("default-2")
+		add("early-2", WithPriority(-10))
+
+		require.NoError(t, cm.Update(DefaultConfig()))
+		assert.Equal(t, []string{"early", "early-2", "default-1", "default-2", "late"}, calls)
+	})
+
+	t.Run("panic isolated on update", func(t *testing.T) {
+		cm := newManager()
+		var after bool
+		cm.AddChangeHandler(func(_, _ *Config) error { panic("boom") })
+		cm.AddChangeHandler(func(_, _ *Config) error { after = true; return nil })
+
+		newCfg := DefaultConfig()
+		newCfg.AppName = "new-app"
+		require.NotPanics(t, func() { require.NoError(t, cm.Update(newCfg)) })
+		assert.True(t, after, "handlers after a panicking one still run")
+		assert.Equal(t, "new-app", cm.Get().AppName)
+	})
+
+	t.Run("panic rejects reload", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("appname: first\nserver:\n  port: 8080\ndatabase:\n  host: localhost\n"), 0o644))
+		cm := NewConfigManager(path, zap.NewNop())
+		require.NoError(t, cm.Load())
+
+		var calls []string
+		cm.AddChangeHandler(func(old, new_ *Config) error {
+			calls = append(calls, old.AppName+"->"+new_.AppName)
+			return nil
+		})
+		cm.AddChangeHandler(func(_, _ *Config) error { panic("boom") })
+
+		require.NoError(t, os.WriteFile(path, []byte("appname: second\nserver:\n  port: 8080\ndatabase:\n  host: localhost\n"), 0o644))
+		err := cm.Reload()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "config change handler 1 panicked: boom")
+		assert.Equal(t, []string{"first->second", "second->first"}, calls)
+		assert.Equal(t, "first", cm.Get().AppName)
+	})
+}
+
+func TestConfigManagerApply(t *testing.T) {
+	cm := NewConfigManager(filepath.Join(t.TempDir(), "config.yaml"), zap.NewNop())
+	cm.config = DefaultConfig()
+	cm.config.Auth.AdminWallets = []string{"0xabc"}
+	old := cm.Get()
+
+	var reject error
+	cm.AddChangeHandler(func(_, _ *Config) error { return reject })
+
+	require.NoError(t, cm.Apply([]byte("server:\n  drain_delay: 9s\nauth:\n  adminwallets: [\"0xdef\"]\n")))
+	cfg := cm.Get()
+	assert.Equal(t, "9s", cfg.Server.DrainDelay)
+	assert.Equal(t, old.Server.Port, cfg.Server.Port, "settings the patch leaves out are kept")
+	assert.Equal(t, []string{"0xdef"}, cfg.Auth.AdminWallets, "lists are replaced")
+	assert.Empty(t, old.Server.DrainDelay, "the previous config is not modified")
+	assert.Equal(t, []string{"Server", "Auth"}, ChangedSections(old, cfg))
+
+	assert.ErrorContains(t, cm.Apply([]byte(`{"server": {"drainDelay": "1s"}}`)), "invalid config patch")
+	assert.Error(t, cm.Apply([]byte("server:\n  port: -1\n")))
+	reject = errors.New("needs a restart")
+	assert.ErrorContains(t, cm.Apply([]byte("appname: other\n")), "needs a restart")
+	assert.Same(t, cfg, cm.Get(), "a rejected patch keeps the current config")
+
+	assert.Error(t, NewConfigManager("", zap.NewNop()).Apply([]byte("appname: x\n")))
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+	"go.yaml.in/yaml/v3"
+)
+
+// fileFormat is the encoding of a ConfigManager file.
+type fileFormat string
+
+const (
+	formatYAML fileFormat = "yaml"
+	formatJSON fileFormat = "json"
+	formatTOML fileFormat = "toml"
+)
+
+// formatOf returns the format of the file at path from its extension:
+// .json, .toml, or YAML for .yaml, .yml and anything else.
+func formatOf(path string) fileFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return formatJSON
+	case ".toml":
+		return formatTOML
+	default:
+		return formatYAML
+	}
+}
+
+// decodeConfig parses a config file. Every format uses the same key names,
+// the lowercased field names (server.readtimeout), and keys Config lacks are
+// rejected, as LoadConfig rejects unknown keys, so a typo fails the load
+// instead of being dropped.
+func decodeConfig(data []byte, format fileFormat) (*Config, error) {
+	if format == formatTOML {
+		// TOML goes through viper and is then decoded as YAML, so the
+		// three formats share the field mapping and strictness.
+		v := viper.New()
+		v.SetConfigType("toml")
+		if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+		var err error
+		if data, err = yaml.Marshal(v.AllSettings()); err != nil {
+			return nil, err
+		}
+	}
+
+	// JSON is YAML, so one strict decoder covers both.
+	cfg := &Config{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// overlayConfig returns a copy of cfg with the settings patch sets applied
+// on top. Maps are merged and lists replaced; unknown keys are rejected as
+// decodeConfig rejects them.
+func overlayConfig(cfg *Config, patch []byte) (*Config, error) {
+	out := *cfg
+	// The decoder replaces lists but merges into maps in place, so the
+	// maps are copied to leave cfg as it is.
+	out.Transcoding.CodecLadders = maps.Clone(cfg.Transcoding.CodecLadders)
+	out.Web3.Networks = maps.Clone(cfg.Web3.Networks)
+	out.Plugins.Limits = maps.Clone(cfg.Plugins.Limits)
+
+	dec := yaml.NewDecoder(bytes.NewReader(patch))
+	dec.KnownFields(true)
+	if err := dec.Decode(&out); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// encodeConfig renders cfg in format with the key names decodeConfig reads.
+func encodeConfig(cfg *Config, format fileFormat) ([]byte, error) {
+	data, err := yaml.Marshal(cfg)
+	if err != nil || format == formatYAML {
+		return data, err
+	}
+
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	switch format {
+	case formatJSON:
+		out, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(out, '\n'), nil
+	case formatTOML:
+		// TOML has no null; an unset value is left out and decodes
+		// back to its zero value.
+		v := viper.New()
+		v.SetConfigType("toml")
+		for key, value := range dropNulls(doc) {
+			v.Set(key, value)
+		}
+		var buf bytes.Buffer
+		if err := v.WriteConfigTo(&buf); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unsupported config format %q", format)
+}
+
+// dropNulls returns doc without its null values, at any depth.
+func dropNulls(doc map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(doc))
+	for key, value := range doc {
+		if value = dropNull(value); value != nil {
+			out[key] = value
+		}
+	}
+	return out
+}
+
+func dropNull(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return dropNulls(v)
+	case []interface{}:
+		out := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			if item = dropNull(item); item != nil {
+				out = append(out, item)
+			}
+		}
+		return out
+	}
+	return value
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestFormatOf(t *testing.T) {
+	tests := []struct {
+		path string
+		want fileFormat
+	}{
+		{"config.yaml", formatYAML},
+		{"config.yml", formatYAML},
+		{"config.json", formatJSON},
+		{"/etc/streamgate/Config.TOML", formatTOML},
+		{"config", formatYAML},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, formatOf(tt.path), tt.path)
+	}
+}
+
+func TestConfigManager_SaveRoundTrip(t *testing.T) {
+	tests := []struct {
+		file string
+		want string // a line only the format produces
+	}{
+		{"config.yaml", "appname: streamgate"},
+		{"config.yml", "appname: streamgate"},
+		{"config.json", `"appname": "streamgate"`},
+		{"config.toml", "appname = 'streamgate'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			cm := NewConfigManager(path, zap.NewNop())
+			cm.config = DefaultConfig()
+			cm.config.Server.DrainDelay = "7s"
+			cm.config.Gateway.Upstreams = []UpstreamConfig{{Name: "api", URL: "http://api:8080", Prefixes: []string{"/api"}}}
+			require.NoError(t, cm.Save())
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Contains(t, string(data), tt.want)
+
+			cm2 := NewConfigManager(path, zap.NewNop())
+			require.NoError(t, cm2.Load())
+			cfg := cm2.Get()
+			assert.Equal(t, "7s", cfg.Server.DrainDelay)
+			assert.Equal(t, cm.config.Gateway.Upstreams, cfg.Gateway.Upstreams)
+			assert.Equal(t, cm.config.Web3.Transaction, cfg.Web3.Transaction)
+
+			// Saving what was loaded writes the same file.
+			require.NoError(t, cm2.Save())
+			again, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, string(data), string(again))
+		})
+	}
+}
+
+func TestConfigManager_LoadFormats(t *testing.T) {
+	tests := []struct {
+		file    string
+		data    string
+		wantErr string
+	}{
+		{
+			file: "config.json",
+			data: `{"appname": "streamgate", "server": {"port": 9090}, "database": {"host": "db"}}`,
+		},
+		{
+			file: "config.toml",
+			data: "appname = \"streamgate\"\n\n[server]\nport = 9090\n\n[database]\nhost = \"db\"\n",
+		},
+		{
+			file: "config.yml",
+			data: "appname: streamgate\nserver:\n  port: 9090\ndatabase:\n  host: db\n",
+		},
+		{
+			file:    "config.json",
+			data:    `{"appname": "streamgate", "server": {"port": 9090, "prot": 1}}`,
+			wantErr: "field prot not found",
+		},
+		{
+			file:    "config.toml",
+			data:    "appname = \"streamgate\"\n\n[server]\nport = 9090\nprot = 1\n",
+			wantErr: "field prot not found",
+		},
+		{
+			file:    "config.toml",
+			data:    "appname = \n",
+			wantErr: "failed to parse config file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file+" "+tt.wantErr, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			require.NoError(t, os.WriteFile(path, []byte(tt.data), 0o600))
+
+			cm := NewConfigManager(path, zap.NewNop())
+			err := cm.Load()
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "streamgate", cm.Get().AppName)
+			assert.Equal(t, 9090, cm.Get().Server.Port)
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// CurrentVersion is the config schema version this binary reads and writes.
+const CurrentVersion = "1.1.0"
+
+// legacyVersion is assumed for config files without a version key, which
+// predate versioning.
+const legacyVersion = "1.0.0"
+
+// Migration upgrades a config document from one schema version to the
+// next. Apply edits the parsed YAML in place and reports whether it
+// changed anything; it must be a no-op on documents already in the new
+// shape, since unversioned files are treated as legacyVersion.
+type Migration struct {
+	From        string
+	To          string
+	Description string
+	Apply       func(doc *yaml.Node) (bool, error)
+}
+
+// migrations is the ordered upgrade path to CurrentVersion.
+var migrations = []Migration{
+	{
+		From:        "1.0.0",
+		To:          "1.1.0",
+		Description: "move the single web3.ethereum block into web3.chains",
+		Apply:       migrateEthereumToChains,
+	},
+}
+
+// MigrateDocument upgrades the YAML config in data to CurrentVersion. It
+// returns the migrated document and whether it differs from data: true
+// when a migration changed the content or an explicitly older version was
+// bumped. Documents newer than CurrentVersion are rejected.
+func MigrateDocument(data []byte) ([]byte, bool, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, false, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return data, false, nil
+	}
+	root := doc.Content[0]
+
+	version, explicit := legacyVersion, false
+	if v := mappingValue(root, "version"); v != nil {
+		version, explicit = v.Value, true
+	}
+	switch c, err := compareVersions(version, CurrentVersion); {
+	case err != nil:
+		return nil, false, fmt.Errorf("config version: %w", err)
+	case c > 0:
+		return nil, false, fmt.Errorf("config version %s is newer than the %s supported by this binary", version, CurrentVersion)
+	case c == 0:
+		return data, false, nil
+	}
+
+	changed := explicit
+	for _, m := range migrations {
+		if c, _ := compareVersions(m.From, version); c < 0 {
+			continue
+		}
+		applied, err := m.Apply(root)
+		if err != nil {
+			return nil, false, fmt.Errorf("config migration %s -> %s (%s): %w", m.From, m.To, m.Description, err)
+		}
+		changed = changed || applied
+	}
+	if !changed {
+		return data, false, nil
+	}
+	versionNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: CurrentVersion, Style: yaml.DoubleQuotedStyle}
+	if explicit {
+		setMappingValue(root, "version", versionNode)
+	} else {
+		root.Content = append([]*yaml.Node{stringNode("version"), versionNode}, root.Content...)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, false, err
+	}
+	return buf.Bytes(), true, nil
+}
+
+// migrateConfigFile migrates the raw (unexpanded) contents of path and, if
+// anything changed, saves the migrated form back with the original kept
+// alongside as path.bak. A failed save is reported but not fatal: the
+// migrated document is still used, so read-only mounts keep working.
+func migrateConfigFile(path string, data []byte) ([]byte, error) {
+	migrated, changed, err := MigrateDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if !changed {
+		return data, nil
+	}
+	if err := saveMigrated(path, data, migrated); err != nil {
+		fmt.Fprintf(os.Stderr, "config %s migrated to version %s in memory but not saved: %v\n", path, CurrentVersion, err)
+	}
+	return migrated, nil
+}
+
+func saveMigrated(path string, original, migrated []byte) error {
+	perm := os.FileMode(0o600)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	if err := os.WriteFile(path+".bak", original, perm); err != nil {
+		return err
+	}
+	return os.WriteFile(path, migrated, perm)
+}
+
+// migrateEthereumToChains rewrites the 1.0.0 single-chain block
+//
+//	web3:
+//	  ethereum: {name, chain_id, rpc_url, ws_url}
+//
+// into a web3.chains entry plus the flat ethereum_rpc, ethereum_ws_url
+// and chain_id keys. Keys already present in the new shape win.
+func migrateEthereumToChains(root *yaml.Node) (bool, error) {
+	web3 := mappingValue(root, "web3")
+	if web3 == nil || web3.Kind != yaml.MappingNode {
+		return false, nil
+	}
+	eth := mappingValue(web3, "ethereum")
+	if eth == nil {
+		return false, nil
+	}
+	var legacy struct {
+		Name    string `yaml:"name"`
+		ChainID int64  `yaml:"chain_id"`
+		RPCURL  string `yaml:"rpc_url"`
+		WSURL   string `yaml:"ws_url"`
+	}
+	if err := eth.Decode(&legacy); err != nil {
+		return false, fmt.Errorf("web3.ethereum: %w", err)
+	}
+	deleteMappingKey(web3, "ethereum")
+
+	if legacy.RPCURL != "" && mappingValue(web3, "ethereum_rpc") == nil {
+		setMappingValue(web3, "ethereum_rpc", stringNode(legacy.RPCURL))
+	}
+	if legacy.WSURL != "" && mappingValue(web3, "ethereum_ws_url") == nil {
+		setMappingValue(web3, "ethereum_ws_url", stringNode(legacy.WSURL))
+	}
+	if legacy.ChainID != 0 && mappingValue(web3, "chain_id") == nil {
+		setMappingValue(web3, "chain_id", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.FormatInt(legacy.ChainID, 10)})
+	}
+	if legacy.ChainID == 0 || legacy.RPCURL == "" {
+		return true, nil
+	}
+
+	chains := mappingValue(web3, "chains")
+	if chains == nil {
+		chains = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		setMappingValue(web3, "chains", chains)
+	}
+	if chains.Kind != yaml.SequenceNode {
+		return false, fmt.Errorf("web3.chains is not a list")
+	}
+	for _, c := range chains.Content {
+		var existing ChainConfigEntry
+		if err := c.Decode(&existing); err == nil && existing.ID == legacy.ChainID {
+			return true, nil
+		}
+	}
+	name := legacy.Name
+	if name == "" {
+		name = "ethereum"
+	}
+	var entry yaml.Node
+	if err := entry.Encode(struct {
+		ID       int64    `yaml:"id"`
+		Name     string   `yaml:"name"`
+		RPC      string   `yaml:"rpc_url"`
+		RPCs     []string `yaml:"rpc_urls"`
+		Currency string   `yaml:"currency"`
+	}{legacy.ChainID, name, legacy.RPCURL, []string{legacy.RPCURL}, "ETH"}); err != nil {
+		return false, err
+	}
+	chains.Content = append(chains.Content, &entry)
+	return true, nil
+}
+
+func stringNode(s string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+}
+
+// mappingValue returns the value node for key in mapping m, or nil.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue replaces key's value in m, appending the key if absent.
+func setMappingValue(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = value
+			return
+		}
+	}
+	m.Content = append(m.Content, stringNode(key), value)
+}
+
+func deleteMappingKey(m *yaml.Node, key string) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// compareVersions compares dotted numeric versions such as "1.0.0".
+func compareVersions(a, b string) (int, error) {
+	pa, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	pb, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+	for i := range pa {
+		switch {
+		case pa[i] < pb[i]:
+			return -1, nil
+		case pa[i] > pb[i]:
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func parseVersion(v string) ([3]int, error) {
+	var out [3]int
+	parts := strings.Split(strings.TrimPrefix(v, "v"), ".")
+	if len(parts) == 0 || len(parts) > 3 {
+		return out, fmt.Errorf("invalid version %q", v)
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return out, fmt.Errorf("invalid version %q", v)
+		}
+		out[i] = n
+	}
+	return out, nil
+}
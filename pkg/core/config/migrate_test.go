@@ -0,0 +1,156 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.yaml.in/yaml/v3"
+)
+
+const v100Config = `version: "1.0.0"
+server:
+  port: 8080
+web3:
+  ethereum:
+    name: sepolia
+    chain_id: 11155111
+    rpc_url: ${TEST_MIGRATE_RPC:-https://rpc.sepolia.org}
+    ws_url: wss://rpc.sepolia.org
+  # finality used for NFT checks
+  block_tag: safe
+`
+
+func TestMigrateDocument_EthereumToChains(t *testing.T) {
+	out, changed, err := MigrateDocument([]byte(v100Config))
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	var doc struct {
+		Version string `yaml:"version"`
+		Web3    struct {
+			Ethereum      map[string]any     `yaml:"ethereum"`
+			EthereumRPC   string             `yaml:"ethereum_rpc"`
+			EthereumWSURL string             `yaml:"ethereum_ws_url"`
+			ChainID       int64              `yaml:"chain_id"`
+			BlockTag      string             `yaml:"block_tag"`
+			Chains        []ChainConfigEntry `yaml:"chains"`
+		} `yaml:"web3"`
+	}
+	require.NoError(t, yaml.Unmarshal(out, &doc))
+	assert.Equal(t, CurrentVersion, doc.Version)
+	assert.Nil(t, doc.Web3.Ethereum, "the legacy block is removed")
+	assert.Equal(t, "${TEST_MIGRATE_RPC:-https://rpc.sepolia.org}", doc.Web3.EthereumRPC, "env references are kept unexpanded")
+	assert.Equal(t, "wss://rpc.sepolia.org", doc.Web3.EthereumWSURL)
+	assert.Equal(t, int64(11155111), doc.Web3.ChainID)
+	assert.Equal(t, "safe", doc.Web3.BlockTag)
+	require.Len(t, doc.Web3.Chains, 1)
+	assert.Equal(t, ChainConfigEntry{
+		ID:       11155111,
+		Name:     "sepolia",
+		RPC:      "${TEST_MIGRATE_RPC:-https://rpc.sepolia.org}",
+		RPCs:     []string{"${TEST_MIGRATE_RPC:-https://rpc.sepolia.org}"},
+		Currency: "ETH",
+	}, doc.Web3.Chains[0])
+	assert.Contains(t, string(out), "# finality used for NFT checks", "comments survive")
+
+	again, changed, err := MigrateDocument(out)
+	require.NoError(t, err)
+	assert.False(t, changed, "a migrated document is current")
+	assert.Equal(t, out, again)
+}
+
+func TestMigrateDocument_KeepsExistingChain(t *testing.T) {
+	in := `web3:
+  ethereum:
+    chain_id: 1
+    rpc_url: https://old.example
+  chains:
+    - id: 1
+      name: ethereum
+      rpc_url: https://new.example
+`
+	out, changed, err := MigrateDocument([]byte(in))
+	require.NoError(t, err)
+	require.True(t, changed, "an unversioned file with legacy keys is migrated")
+
+	var doc struct {
+		Version string `yaml:"version"`
+		Web3    struct {
+			Chains []ChainConfigEntry `yaml:"chains"`
+		} `yaml:"web3"`
+	}
+	require.NoError(t, yaml.Unmarshal(out, &doc))
+	assert.Equal(t, CurrentVersion, doc.Version)
+	assert.True(t, strings.HasPrefix(string(out), `version: "1.1.0"`), "the version key is added at the top")
+	require.Len(t, doc.Web3.Chains, 1)
+	assert.Equal(t, "https://new.example", doc.Web3.Chains[0].RPC)
+}
+
+func TestMigrateDocument_NoChanges(t *testing.T) {
+	for name, in := range map[string]string{
+		"current":             "version: \"1.1.0\"\nweb3:\n  chain_id: 1\n",
+		"unversioned, modern": "server:\n  port: 8080\n",
+		"empty":               "",
+		"unversioned overlay": "logging:\n  level: debug\n",
+	} {
+		out, changed, err := MigrateDocument([]byte(in))
+		require.NoError(t, err, name)
+		assert.False(t, changed, name)
+		assert.Equal(t, in, string(out), name)
+	}
+}
+
+func TestMigrateDocument_VersionChecks(t *testing.T) {
+	_, _, err := MigrateDocument([]byte("version: \"9.0.0\"\n"))
+	assert.ErrorContains(t, err, "newer than")
+
+	_, _, err = MigrateDocument([]byte("version: latest\n"))
+	assert.ErrorContains(t, err, "invalid version")
+
+	out, changed, err := MigrateDocument([]byte("version: \"1.0.0\"\nserver:\n  port: 8080\n"))
+	require.NoError(t, err)
+	assert.True(t, changed, "an explicit old version is bumped even when nothing moved")
+	assert.Contains(t, string(out), `version: "1.1.0"`)
+}
+
+func TestLoadConfig_MigratesOldVersion(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Cleanup(viper.Reset)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "config"), 0o755))
+	path := filepath.Join(dir, "config", "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(v100Config), 0o640))
+	t.Setenv("TEST_MIGRATE_RPC", "https://rpc.example")
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, CurrentVersion, cfg.Version)
+	assert.Equal(t, "https://rpc.example", cfg.Web3.EthereumRPC)
+	assert.Equal(t, int64(11155111), cfg.Web3.ChainID)
+	require.Len(t, cfg.Web3.Chains, 1)
+	assert.Equal(t, "https://rpc.example", cfg.Web3.Chains[0].RPC)
+
+	saved, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(saved), `version: "1.1.0"`)
+	assert.Contains(t, string(saved), "${TEST_MIGRATE_RPC:-https://rpc.sepolia.org}", "secrets and env values are not written back")
+	assert.NotContains(t, string(saved), "ethereum:")
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+
+	backup, err := os.ReadFile(path + ".bak")
+	require.NoError(t, err)
+	assert.Equal(t, v100Config, string(backup))
+
+	// The saved file loads as current without another rewrite.
+	require.NoError(t, os.Remove(path+".bak"))
+	_, err = LoadConfig()
+	require.NoError(t, err)
+	assert.NoFileExists(t, path+".bak")
+}
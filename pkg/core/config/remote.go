@@ -0,0 +1,149 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/rtcdance/streamgate/pkg/discovery/etcdkv"
+	"github.com/spf13/viper"
+)
+
+// remoteFetchTimeout bounds one read of the remote config document.
+const remoteFetchTimeout = 10 * time.Second
+
+// RemoteSource is a key in a central store holding a YAML (or JSON) config
+// document, shared by every service that points remote.source at it.
+type RemoteSource interface {
+	// Fetch returns the document and its revision, which changes whenever
+	// the document does.
+	Fetch(ctx context.Context) (data []byte, revision uint64, err error)
+	String() string
+}
+
+// OpenRemoteSource returns the source a remote.source URI names:
+//
+//	etcd://host:2379[,host:2379...]/key   an etcd v3 key (etcds:// for TLS)
+//	consul://host:8500/key                a Consul KV key (consuls:// for TLS)
+//
+// It does not connect; Fetch does.
+func OpenRemoteSource(uri string) (RemoteSource, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" || strings.Trim(u.Path, "/") == "" {
+		return nil, fmt.Errorf("want scheme://host/key")
+	}
+
+	switch u.Scheme {
+	case "etcd", "etcds":
+		scheme := "http"
+		if u.Scheme == "etcds" {
+			scheme = "https"
+		}
+		var endpoints []string
+		for _, host := range strings.Split(u.Host, ",") {
+			endpoints = append(endpoints, scheme+"://"+host)
+		}
+		return NewEtcdSource(endpoints, u.Path), nil
+	case "consul", "consuls":
+		consulCfg := api.DefaultConfig()
+		consulCfg.Address = u.Host
+		consulCfg.Scheme = "http"
+		if u.Scheme == "consuls" {
+			consulCfg.Scheme = "https"
+		}
+		client, err := api.NewClient(consulCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Consul client: %w", err)
+		}
+		return NewConsulSource(client.KV(), strings.TrimPrefix(u.Path, "/")), nil
+	}
+	return nil, fmt.Errorf("unsupported scheme %q: want etcd, etcds, consul or consuls", u.Scheme)
+}
+
+// readRemoteConfig merges the document at the remote.source URI over the
+// config files read so far and returns the keys it sets.
+func readRemoteConfig(uri string) ([]string, error) {
+	src, err := OpenRemoteSource(uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote.source %q: %w", uri, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), remoteFetchTimeout)
+	defer cancel()
+	data, _, err := src.Fetch(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading remote config: %w", err)
+	}
+
+	expanded := expandEnvWithDefaults(string(data))
+	keys, err := fileKeys(expanded)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing remote config %s: %w", src, err)
+	}
+	viper.SetConfigType("yaml")
+	if err := viper.MergeConfig(strings.NewReader(expanded)); err != nil {
+		return nil, fmt.Errorf("error parsing remote config %s: %w", src, err)
+	}
+	return keys, nil
+}
+
+// EtcdSource reads a config document from an etcd v3 key through etcd's
+// JSON gateway, so no etcd client library is needed.
+type EtcdSource struct {
+	kv  *etcdkv.Client
+	key string
+}
+
+// NewEtcdSource creates a source for key on the etcd cluster at endpoints,
+// e.g. "http://etcd:2379". Endpoints are tried in order.
+func NewEtcdSource(endpoints []string, key string) *EtcdSource {
+	return &EtcdSource{kv: etcdkv.New(endpoints), key: key}
+}
+
+// Fetch returns the key's value and its mod revision.
+func (s *EtcdSource) Fetch(ctx context.Context) ([]byte, uint64, error) {
+	kv, err := s.kv.Get(ctx, s.key)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", s, err)
+	}
+	if kv == nil {
+		return nil, 0, fmt.Errorf("%s: key not found", s)
+	}
+	return kv.Value, uint64(kv.ModRevision), nil
+}
+
+func (s *EtcdSource) String() string {
+	return "etcd key " + s.key
+}
+
+// ConsulSource reads a config document from a Consul KV key.
+type ConsulSource struct {
+	kv  *api.KV
+	key string
+}
+
+// NewConsulSource creates a source for key, e.g. "streamgate/config.yaml".
+func NewConsulSource(kv *api.KV, key string) *ConsulSource {
+	return &ConsulSource{kv: kv, key: key}
+}
+
+// Fetch returns the key's value and its modify index.
+func (s *ConsulSource) Fetch(ctx context.Context) ([]byte, uint64, error) {
+	pair, _, err := s.kv.Get(s.key, (&api.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", s, err)
+	}
+	if pair == nil {
+		return nil, 0, fmt.Errorf("%s: key not found", s)
+	}
+	return pair.Value, pair.ModifyIndex, nil
+}
+
+func (s *ConsulSource) String() string {
+	return "consul key " + s.key
+}
@@ -0,0 +1,191 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeKV serves one key over etcd's v3 JSON gateway and Consul's KV API.
+type fakeKV struct {
+	mu       sync.Mutex
+	key      string
+	value    string
+	revision uint64
+}
+
+func newFakeKV(t *testing.T, key, value string) (*fakeKV, string) {
+	f := &fakeKV{key: key, value: value, revision: 1}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, strings.TrimPrefix(srv.URL, "http://")
+}
+
+func (f *fakeKV) set(value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.value = value
+	f.revision++
+}
+
+func (f *fakeKV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.URL.Path == "/v3/kv/range":
+		var req struct {
+			Key []byte `json:"key"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		resp := map[string]any{}
+		if string(req.Key) == f.key {
+			resp["kvs"] = []map[string]any{{
+				"key":          []byte(f.key),
+				"value":        []byte(f.value),
+				"mod_revision": strconv.FormatUint(f.revision, 10),
+			}}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	case r.URL.Path == "/v1/kv/"+strings.TrimPrefix(f.key, "/"):
+		_ = json.NewEncoder(w).Encode([]map[string]any{{
+			"Key":         f.key,
+			"Value":       []byte(f.value),
+			"ModifyIndex": f.revision,
+		}})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestOpenRemoteSource(t *testing.T) {
+	tests := []struct {
+		uri     string
+		want    string
+		wantErr string
+	}{
+		{"etcd://etcd:2379/streamgate/config.yaml", "etcd key /streamgate/config.yaml", ""},
+		{"etcds://etcd-1:2379,etcd-2:2379/streamgate/config.yaml", "etcd key /streamgate/config.yaml", ""},
+		{"consul://consul:8500/streamgate/config.yaml", "consul key streamgate/config.yaml", ""},
+		{"consuls://consul:8501/streamgate/config.yaml", "consul key streamgate/config.yaml", ""},
+		{"etcd://etcd:2379/", "", "want scheme://host/key"},
+		{"zookeeper://zk:2181/streamgate", "", `unsupported scheme "zookeeper"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			src, err := OpenRemoteSource(tt.uri)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, src.String())
+		})
+	}
+}
+
+func TestRemoteSource_Fetch(t *testing.T) {
+	kv, addr := newFakeKV(t, "/streamgate/config.yaml", "server:\n  port: 9090\n")
+
+	for _, scheme := range []string{"etcd", "consul"} {
+		t.Run(scheme, func(t *testing.T) {
+			src, err := OpenRemoteSource(scheme + "://" + addr + kv.key)
+			require.NoError(t, err)
+
+			data, rev, err := src.Fetch(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, "server:\n  port: 9090\n", string(data))
+			assert.Equal(t, kv.revision, rev)
+
+			missing, err := OpenRemoteSource(scheme + "://" + addr + "/streamgate/other.yaml")
+			require.NoError(t, err)
+			_, _, err = missing.Fetch(context.Background())
+			assert.ErrorContains(t, err, "key not found")
+		})
+	}
+
+	// Later endpoints are tried when one is down.
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	data, _, err := NewEtcdSource([]string{down.URL, "http://" + addr}, kv.key).Fetch(context.Background())
+	require.NoError(t, err)
+	assert.NotEmpty(t, data)
+}
+
+func TestLoadConfig_RemoteSource(t *testing.T) {
+	kv, addr := newFakeKV(t, "/streamgate/config.yaml", "server:\n  port: 9090\nredis:\n  host: redis.internal\n")
+	writeConfigDir(t, map[string]string{"config.yaml": `
+server:
+  port: 8080
+redis:
+  port: 6380
+remote:
+  source: etcd://` + addr + `/streamgate/config.yaml
+`})
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 9090, cfg.Server.Port, "the remote document overrides the file")
+	assert.Equal(t, "redis.internal", cfg.Redis.Host)
+	assert.Equal(t, 6380, cfg.Redis.Port, "settings it leaves out come from the file")
+
+	t.Setenv("STREAMGATE_SERVER_PORT", "7070")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 7070, cfg.Server.Port, "the environment overrides the remote document")
+
+	kv.set("server:\n  prot: 9090\n")
+	_, err = LoadConfig()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "server.prot")
+
+	kv.mu.Lock()
+	kv.key = "/streamgate/moved.yaml"
+	kv.mu.Unlock()
+	_, err = LoadConfig()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "key not found")
+}
+
+func TestConfigManager_PollRemote(t *testing.T) {
+	kv, addr := newFakeKV(t, "streamgate/config.yaml", "server:\n  port: 9090\n")
+	writeConfigDir(t, map[string]string{"config.yaml": "remote:\n  source: consul://" + addr + "/streamgate/config.yaml\n"})
+
+	cm := NewConfigManager("", zap.NewNop())
+	require.NoError(t, cm.Load())
+	assert.Equal(t, 9090, cm.Get().Server.Port)
+
+	var changed []int
+	cm.AddChangeHandler(func(_, newConfig *Config) error {
+		changed = append(changed, newConfig.Server.Port)
+		return nil
+	})
+
+	src, err := OpenRemoteSource(cm.Get().Remote.Source)
+	require.NoError(t, err)
+	revision := kv.revision
+	require.NoError(t, cm.pollRemote(context.Background(), src, &revision))
+	assert.Empty(t, changed, "no reload while the revision is unchanged")
+
+	kv.set("server:\n  port: 9191\n")
+	require.NoError(t, cm.pollRemote(context.Background(), src, &revision))
+	assert.Equal(t, []int{9191}, changed)
+	assert.Equal(t, 9191, cm.Get().Server.Port)
+	assert.Equal(t, kv.revision, revision)
+
+	assert.ErrorContains(t, NewConfigManager("", zap.NewNop()).WatchRemote(context.Background(), 0),
+		"no remote config source configured")
+}
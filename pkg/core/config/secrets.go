@@ -0,0 +1,355 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+)
+
+// DefaultSecretTTL is how long a resolved secret is reused before it is
+// fetched again.
+const DefaultSecretTTL = 5 * time.Minute
+
+// SecretProvider fetches secrets for one reference scheme. ref is the
+// reference without its scheme, e.g. "kv/streamgate#jwt_secret" for
+// "vault:kv/streamgate#jwt_secret".
+type SecretProvider interface {
+	GetSecret(ctx context.Context, ref string) (string, error)
+}
+
+// SecretResolver expands secret references in config values:
+//
+//	env:DB_PASSWORD                      the DB_PASSWORD environment variable
+//	vault:kv/streamgate#jwt_secret       field jwt_secret of the Vault KV v2
+//	                                     secret streamgate in mount kv
+//	aws:prod/streamgate#jwt_secret       field jwt_secret of the JSON AWS
+//	                                     Secrets Manager secret prod/streamgate,
+//	                                     or the whole secret without #field
+//
+// Any other value is used as is. Resolved values are cached for the TTL;
+// when a fetch fails after that the cached value is kept, so a secret store
+// outage does not fail a reload.
+type SecretResolver struct {
+	ttl       time.Duration
+	now       func() time.Time
+	mu        sync.Mutex
+	providers map[string]SecretProvider
+	cache     map[string]cachedSecret
+}
+
+type cachedSecret struct {
+	value   string
+	fetched time.Time
+}
+
+// NewSecretResolver returns a resolver with the env, vault and aws
+// providers. Vault is reached at VAULT_ADDR with VAULT_TOKEN; AWS uses the
+// SDK's default credential chain and region.
+func NewSecretResolver(ttl time.Duration) *SecretResolver {
+	r := &SecretResolver{
+		ttl:       ttl,
+		now:       time.Now,
+		providers: make(map[string]SecretProvider),
+		cache:     make(map[string]cachedSecret),
+	}
+	r.Register("env", envSecrets{})
+	r.Register("vault", NewVaultSecrets(os.Getenv("VAULT_ADDR"), os.Getenv("VAULT_TOKEN")))
+	r.Register("aws", &awsSecrets{})
+	return r
+}
+
+var (
+	secretsOnce sync.Once
+	secrets     *SecretResolver
+)
+
+// Secrets returns the resolver LoadConfig and ConfigManager use, with
+// DefaultSecretTTL. Register providers on it before loading config.
+func Secrets() *SecretResolver {
+	secretsOnce.Do(func() { secrets = NewSecretResolver(DefaultSecretTTL) })
+	return secrets
+}
+
+// Register sets the provider for references starting with scheme + ":".
+func (r *SecretResolver) Register(scheme string, p SecretProvider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[scheme] = p
+}
+
+// provider returns the provider for value's scheme and the reference
+// without it, or nil when value is not a reference.
+func (r *SecretResolver) provider(value string) (SecretProvider, string) {
+	scheme, ref, ok := strings.Cut(value, ":")
+	if !ok {
+		return nil, ""
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.providers[scheme], ref
+}
+
+// Resolve returns the secret value refers to, or value itself when it is
+// not a reference.
+func (r *SecretResolver) Resolve(ctx context.Context, value string) (string, error) {
+	p, ref := r.provider(value)
+	if p == nil {
+		return value, nil
+	}
+
+	r.mu.Lock()
+	cached, ok := r.cache[value]
+	r.mu.Unlock()
+	if ok && r.now().Sub(cached.fetched) < r.ttl {
+		return cached.value, nil
+	}
+
+	secret, err := p.GetSecret(ctx, ref)
+	if err != nil {
+		if ok {
+			return cached.value, nil
+		}
+		return "", err
+	}
+	r.mu.Lock()
+	r.cache[value] = cachedSecret{value: secret, fetched: r.now()}
+	r.mu.Unlock()
+	return secret, nil
+}
+
+// Refresh fetches every cached secret again, whatever its age, and reports
+// whether any value changed, i.e. a secret was rotated.
+func (r *SecretResolver) Refresh(ctx context.Context) (bool, error) {
+	r.mu.Lock()
+	refs := make([]string, 0, len(r.cache))
+	for ref := range r.cache {
+		refs = append(refs, ref)
+	}
+	r.mu.Unlock()
+
+	changed := false
+	var errs []error
+	for _, value := range refs {
+		p, ref := r.provider(value)
+		if p == nil {
+			continue
+		}
+		secret, err := p.GetSecret(ctx, ref)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", value, err))
+			continue
+		}
+		r.mu.Lock()
+		if r.cache[value].value != secret {
+			changed = true
+		}
+		r.cache[value] = cachedSecret{value: secret, fetched: r.now()}
+		r.mu.Unlock()
+	}
+	return changed, errors.Join(errs...)
+}
+
+// secretField is a config setting that may hold a secret reference.
+type secretField struct {
+	key   string
+	value *string
+}
+
+// secretFields returns the settings of cfg that may hold secret references.
+func secretFields(cfg *Config) []secretField {
+	return []secretField{
+		{"database.password", &cfg.Database.Password},
+		{"redis.password", &cfg.Redis.Password},
+		{"storage.accesskey", &cfg.Storage.AccessKey},
+		{"storage.secretkey", &cfg.Storage.SecretKey},
+		{"storage.ipfs.pinning_token", &cfg.Storage.IPFS.PinningToken},
+		{"auth.jwt_secret", &cfg.Auth.JWTSecret},
+		{"web3.transaction.private_key_hex", &cfg.Web3.Transaction.PrivateKeyHex},
+		{"web3.anvil_deployer_key", &cfg.Web3.AnvilDeployerKey},
+		{"moderation.api_key", &cfg.Moderation.APIKey},
+		{"moderation.webhook_secret", &cfg.Moderation.WebhookSecret},
+		{"search.elasticsearch.api_key", &cfg.Search.Elasticsearch.APIKey},
+		{"search.elasticsearch.password", &cfg.Search.Elasticsearch.Password},
+		{"encryption.master_key", &cfg.Encryption.MasterKey},
+	}
+}
+
+// ResolveConfig replaces the secret references in cfg with their values.
+func (r *SecretResolver) ResolveConfig(ctx context.Context, cfg *Config) error {
+	_, err := r.resolveConfig(ctx, cfg)
+	return err
+}
+
+// secretRef is a reference a setting held and the value it resolved to.
+type secretRef struct {
+	ref   string
+	value string
+}
+
+// resolveConfig is ResolveConfig, returning the references it replaced by
+// setting so they can be written back instead of the values.
+func (r *SecretResolver) resolveConfig(ctx context.Context, cfg *Config) (map[string]secretRef, error) {
+	refs := make(map[string]secretRef)
+	var errs []error
+	for _, f := range secretFields(cfg) {
+		if p, _ := r.provider(*f.value); p == nil {
+			continue
+		}
+		secret, err := r.Resolve(ctx, *f.value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("resolve %s: %w", f.key, err))
+			continue
+		}
+		refs[f.key] = secretRef{ref: *f.value, value: secret}
+		*f.value = secret
+	}
+	return refs, errors.Join(errs...)
+}
+
+// unresolveConfig returns a copy of cfg with the references resolveConfig
+// replaced put back, except in settings changed since.
+func unresolveConfig(cfg *Config, refs map[string]secretRef) *Config {
+	out := *cfg
+	for _, f := range secretFields(&out) {
+		if ref, ok := refs[f.key]; ok && *f.value == ref.value {
+			*f.value = ref.ref
+		}
+	}
+	return &out
+}
+
+// envSecrets resolves env:NAME references.
+type envSecrets struct{}
+
+func (envSecrets) GetSecret(_ context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// splitSecretRef splits "path#field" into its path and field.
+func splitSecretRef(ref string) (path, field string) {
+	path, field, _ = strings.Cut(ref, "#")
+	return path, field
+}
+
+// VaultSecrets reads fields of Vault KV version 2 secrets over the HTTP API.
+type VaultSecrets struct {
+	addr   string
+	token  string
+	client *http.Client
+}
+
+// NewVaultSecrets returns a provider for the Vault server at addr.
+func NewVaultSecrets(addr, token string) *VaultSecrets {
+	return &VaultSecrets{
+		addr:   strings.TrimSuffix(addr, "/"),
+		token:  token,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// GetSecret resolves "mount/path#field".
+func (v *VaultSecrets) GetSecret(ctx context.Context, ref string) (string, error) {
+	if v.addr == "" {
+		return "", errors.New("vault: VAULT_ADDR is not set")
+	}
+	path, field := splitSecretRef(ref)
+	mount, name, ok := strings.Cut(path, "/")
+	if !ok || name == "" || field == "" {
+		return "", fmt.Errorf("vault: want mount/path#field, got %q", ref)
+	}
+
+	u := v.addr + "/v1/" + url.PathEscape(mount) + "/data/" + name
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", fmt.Errorf("vault: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault: read %s: status %d", path, resp.StatusCode)
+	}
+
+	var body struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("vault: decode %s: %w", path, err)
+	}
+	return secretValue(body.Data.Data, path, field)
+}
+
+// awsSecrets resolves references to AWS Secrets Manager secrets. The
+// client is created on first use.
+type awsSecrets struct {
+	once   sync.Once
+	client secretsmanageriface.SecretsManagerAPI
+	err    error
+}
+
+// GetSecret resolves "secret-id#field", or "secret-id" for the whole
+// secret string.
+func (a *awsSecrets) GetSecret(ctx context.Context, ref string) (string, error) {
+	a.once.Do(func() {
+		if a.client != nil {
+			return
+		}
+		sess, err := session.NewSessionWithOptions(session.Options{SharedConfigState: session.SharedConfigEnable})
+		if err != nil {
+			a.err = fmt.Errorf("aws: create session: %w", err)
+			return
+		}
+		a.client = secretsmanager.New(sess)
+	})
+	if a.err != nil {
+		return "", a.err
+	}
+
+	id, field := splitSecretRef(ref)
+	out, err := a.client.GetSecretValueWithContext(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(id)})
+	if err != nil {
+		return "", fmt.Errorf("aws: get %s: %w", id, err)
+	}
+	secret := aws.StringValue(out.SecretString)
+	if field == "" {
+		return secret, nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("aws: secret %s is not a JSON object: %w", id, err)
+	}
+	return secretValue(fields, id, field)
+}
+
+// secretValue returns field of a secret's key/value pairs as a string.
+func secretValue(fields map[string]interface{}, path, field string) (string, error) {
+	value, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no field %s", path, field)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(value), nil
+}
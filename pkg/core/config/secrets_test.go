@@ -0,0 +1,257 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeSecrets is a SecretProvider serving values from a map.
+type fakeSecrets struct {
+	values map[string]string
+	err    error
+	calls  int
+}
+
+func (f *fakeSecrets) GetSecret(_ context.Context, ref string) (string, error) {
+	f.calls++
+	if f.err != nil {
+		return "", f.err
+	}
+	value, ok := f.values[ref]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return value, nil
+}
+
+func TestSecretResolver_Resolve(t *testing.T) {
+	t.Setenv("TEST_SECRET_DB_PASSWORD", "s3cret")
+	r := NewSecretResolver(time.Minute)
+	r.Register("fake", &fakeSecrets{values: map[string]string{"jwt": "from-fake"}})
+
+	tests := []struct {
+		value   string
+		want    string
+		wantErr string
+	}{
+		{"plain", "plain", ""},
+		{"https://rpc.example", "https://rpc.example", ""},
+		{"env:TEST_SECRET_DB_PASSWORD", "s3cret", ""},
+		{"env:TEST_SECRET_MISSING", "", "TEST_SECRET_MISSING is not set"},
+		{"fake:jwt", "from-fake", ""},
+		{"fake:other", "", "not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := r.Resolve(context.Background(), tt.value)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSecretResolver_CacheAndRotation(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	fake := &fakeSecrets{values: map[string]string{"jwt": "v1"}}
+	r := NewSecretResolver(time.Minute)
+	r.now = func() time.Time { return now }
+	r.Register("fake", fake)
+
+	got, err := r.Resolve(ctx, "fake:jwt")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", got)
+
+	fake.values["jwt"] = "v2"
+	got, _ = r.Resolve(ctx, "fake:jwt")
+	assert.Equal(t, "v1", got, "cached within the TTL")
+	assert.Equal(t, 1, fake.calls)
+
+	now = now.Add(2 * time.Minute)
+	got, _ = r.Resolve(ctx, "fake:jwt")
+	assert.Equal(t, "v2", got, "fetched again after the TTL")
+
+	fake.err = errors.New("store down")
+	now = now.Add(2 * time.Minute)
+	got, err = r.Resolve(ctx, "fake:jwt")
+	require.NoError(t, err, "a failed refetch keeps the cached value")
+	assert.Equal(t, "v2", got)
+
+	changed, err := r.Refresh(ctx)
+	assert.Error(t, err)
+	assert.False(t, changed)
+
+	fake.err = nil
+	changed, err = r.Refresh(ctx)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	fake.values["jwt"] = "v3"
+	changed, err = r.Refresh(ctx)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	got, _ = r.Resolve(ctx, "fake:jwt")
+	assert.Equal(t, "v3", got)
+}
+
+func TestVaultSecrets(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if req.URL.Path != "/v1/kv/data/streamgate/prod" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"data":{"jwt_secret":"from-vault","port":5432}}}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		token   string
+		ref     string
+		want    string
+		wantErr string
+	}{
+		{"field", "root", "kv/streamgate/prod#jwt_secret", "from-vault", ""},
+		{"number field", "root", "kv/streamgate/prod#port", "5432", ""},
+		{"missing field", "root", "kv/streamgate/prod#nope", "", "has no field nope"},
+		{"missing secret", "root", "kv/other#jwt_secret", "", "status 404"},
+		{"bad token", "wrong", "kv/streamgate/prod#jwt_secret", "", "status 403"},
+		{"no field", "root", "kv/streamgate/prod", "", "want mount/path#field"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewVaultSecrets(srv.URL+"/", tt.token).GetSecret(context.Background(), tt.ref)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := NewVaultSecrets("", "").GetSecret(context.Background(), "kv/x#y")
+	assert.ErrorContains(t, err, "VAULT_ADDR is not set")
+}
+
+// fakeSecretsManager serves GetSecretValue from a map.
+type fakeSecretsManager struct {
+	secretsmanageriface.SecretsManagerAPI
+	secrets map[string]string
+}
+
+func (f *fakeSecretsManager) GetSecretValueWithContext(_ aws.Context, in *secretsmanager.GetSecretValueInput, _ ...request.Option) (*secretsmanager.GetSecretValueOutput, error) {
+	secret, ok := f.secrets[aws.StringValue(in.SecretId)]
+	if !ok {
+		return nil, errors.New("ResourceNotFoundException")
+	}
+	return &secretsmanager.GetSecretValueOutput{SecretString: aws.String(secret)}, nil
+}
+
+func TestAWSSecrets(t *testing.T) {
+	a := &awsSecrets{client: &fakeSecretsManager{secrets: map[string]string{
+		"prod/streamgate": `{"jwt_secret":"from-aws"}`,
+		"prod/plain":      "whole-secret",
+	}}}
+
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr string
+	}{
+		{"prod/streamgate#jwt_secret", "from-aws", ""},
+		{"prod/plain", "whole-secret", ""},
+		{"prod/plain#field", "", "not a JSON object"},
+		{"prod/missing", "", "ResourceNotFoundException"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := a.GetSecret(context.Background(), tt.ref)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLoadConfig_ResolvesSecrets(t *testing.T) {
+	writeConfigDir(t, map[string]string{"config.yaml": `
+server:
+  port: 8080
+database:
+  host: db
+  password: env:TEST_SECRET_DB_PASSWORD
+auth:
+  jwt_secret: env:TEST_SECRET_MISSING
+`})
+	t.Setenv("TEST_SECRET_DB_PASSWORD", "s3cret")
+
+	_, err := LoadConfig()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "resolve auth.jwt_secret")
+
+	t.Setenv("TEST_SECRET_MISSING", "jwt-from-env")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", cfg.Database.Password)
+	assert.Equal(t, "jwt-from-env", cfg.Auth.JWTSecret)
+}
+
+func TestConfigManager_Secrets(t *testing.T) {
+	fake := &fakeSecrets{values: map[string]string{"db": "v1"}}
+	r := NewSecretResolver(time.Hour)
+	r.Register("fake", fake)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("server:\n  port: 8080\ndatabase:\n  host: db\n  password: fake:db\n"), 0o600))
+
+	cm := NewConfigManager(path, zap.NewNop())
+	cm.SetSecretResolver(r)
+	require.NoError(t, cm.Load())
+	assert.Equal(t, "v1", cm.Get().Database.Password)
+
+	var rotated string
+	cm.AddChangeHandler(func(_, newConfig *Config) error {
+		rotated = newConfig.Database.Password
+		return nil
+	})
+	fake.values["db"] = "v2"
+	require.NoError(t, cm.refreshSecrets(context.Background()))
+	assert.Equal(t, "v2", rotated)
+	assert.Equal(t, "v2", cm.Get().Database.Password)
+
+	// Save writes the reference back, not the value.
+	require.NoError(t, cm.Save())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "password: fake:db")
+	assert.NotContains(t, string(data), "v2")
+}
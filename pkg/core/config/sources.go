@@ -0,0 +1,216 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// envAliases are the environment variables some keys accept besides the
+// STREAMGATE_<KEY> and <KEY> names every key has. An alias takes
+// precedence over both.
+var envAliases = map[string]string{
+	"database.host":                    "STREAMGATE_DB_HOST",
+	"database.port":                    "STREAMGATE_DB_PORT",
+	"database.user":                    "STREAMGATE_DB_USER",
+	"database.password":                "STREAMGATE_DB_PASSWORD",
+	"database.database":                "STREAMGATE_DB_NAME",
+	"database.sslmode":                 "STREAMGATE_DB_SSLMODE",
+	"database.maxconns":                "STREAMGATE_DB_MAXCONNS",
+	"database.max_idle_conns":          "STREAMGATE_DB_MAX_IDLE_CONNS",
+	"database.conn_max_lifetime":       "STREAMGATE_DB_CONN_MAX_LIFETIME",
+	"database.replica_dsns":            "STREAMGATE_DB_REPLICA_DSNS",
+	"database.replica_retry_after":     "STREAMGATE_DB_REPLICA_RETRY_AFTER",
+	"storage.accesskey":                "STREAMGATE_STORAGE_ACCESS_KEY",
+	"storage.secretkey":                "STREAMGATE_STORAGE_SECRET_KEY",
+	"consul.address":                   "STREAMGATE_CONSUL_HOST",
+	"monitoring.jaeger_endpoint":       "STREAMGATE_JAEGER_ENDPOINT",
+	"monitoring.tracing.sampler":       "STREAMGATE_TRACING_SAMPLER",
+	"monitoring.tracing.ratio":         "STREAMGATE_TRACING_RATIO",
+	"web3.ethereum_rpc":                "STREAMGATE_ETH_RPC",
+	"web3.ethereum_rpcs":               "STREAMGATE_ETH_RPCS",
+	"web3.solana_rpc":                  "STREAMGATE_SOLANA_RPC",
+	"web3.ethereum_ws_url":             "STREAMGATE_ETH_WS_URL",
+	"web3.transaction.private_key_hex": "STREAMGATE_PRIVATE_KEY_HEX",
+	"web3.anvil_demo_contract":         "STREAMGATE_ANVIL_DEMO_CONTRACT",
+	"web3.anvil_deployer_key":          "STREAMGATE_ANVIL_DEPLOYER_KEY",
+	"auth.jwt_secret":                  "STREAMGATE_JWT_SECRET",
+	"auth.admin_wallets":               "STREAMGATE_ADMIN_WALLETS",
+	"cors.allowed_origins":             "STREAMGATE_CORS_ORIGINS",
+}
+
+// envNames returns the environment variables key is read from, highest
+// precedence first.
+func envNames(key string) []string {
+	name := strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+	names := []string{"STREAMGATE_" + name, name}
+	if alias, ok := envAliases[key]; ok {
+		names = append([]string{alias}, names...)
+	}
+	return names
+}
+
+// overrideFlags collects repeated -set key=value flags.
+type overrideFlags []string
+
+func (f *overrideFlags) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *overrideFlags) Set(value string) error {
+	if key, _, ok := strings.Cut(value, "="); !ok || strings.TrimSpace(key) == "" {
+		return fmt.Errorf("want key=value, got %q", value)
+	}
+	*f = append(*f, value)
+	return nil
+}
+
+// flagOverrides holds the -set flags registered by BindFlags.
+var flagOverrides overrideFlags
+
+// BindFlags registers the -set flag on fs. Each -set key=value overrides
+// key over the environment and config files, e.g. -set server.port=9000;
+// it may be repeated. Call it before fs is parsed and LoadConfig runs.
+func BindFlags(fs *flag.FlagSet) {
+	fs.Var(&flagOverrides, "set", "override a config key, e.g. -set server.port=9000 (repeatable)")
+}
+
+// configReader reads keys for LoadConfig. It binds each key to its
+// environment variables before reading it and records it, so keys a
+// config file or flag sets but no setting reads can be rejected.
+type configReader struct {
+	read  map[string]bool
+	files []configSource
+}
+
+// configSource is a config file, or the flags, and the keys it sets.
+type configSource struct {
+	name string
+	keys []string
+}
+
+func newConfigReader() *configReader {
+	return &configReader{read: make(map[string]bool)}
+}
+
+func (r *configReader) key(key string) string {
+	r.read[key] = true
+	_ = viper.BindEnv(append([]string{key}, envNames(key)...)...)
+	return key
+}
+
+func (r *configReader) GetString(key string) string {
+	return viper.GetString(r.key(key))
+}
+
+func (r *configReader) GetBool(key string) bool {
+	return viper.GetBool(r.key(key))
+}
+
+func (r *configReader) GetInt(key string) int {
+	return viper.GetInt(r.key(key))
+}
+
+func (r *configReader) GetInt64(key string) int64 {
+	return viper.GetInt64(r.key(key))
+}
+
+func (r *configReader) GetUint64(key string) uint64 {
+	return viper.GetUint64(r.key(key))
+}
+
+func (r *configReader) GetFloat64(key string) float64 {
+	return viper.GetFloat64(r.key(key))
+}
+
+func (r *configReader) GetStringSlice(key string) []string {
+	return viper.GetStringSlice(r.key(key))
+}
+
+func (r *configReader) UnmarshalKey(key string, rawVal interface{}) error {
+	return viper.UnmarshalKey(r.key(key), rawVal)
+}
+
+// dsnList reads a list of connection strings. Key/value DSNs contain
+// spaces, so a string value (e.g. from the environment) is split on commas
+// only rather than on whitespace as viper does.
+func (r *configReader) dsnList(key string) []string {
+	if v, ok := viper.Get(r.key(key)).(string); ok {
+		return splitCommaSlice([]string{v})
+	}
+	return splitCommaSlice(viper.GetStringSlice(key))
+}
+
+// addFile records the keys a config file sets. An empty path, for a file
+// that was not found, is ignored.
+func (r *configReader) addFile(path string, keys []string) {
+	if path != "" {
+		r.files = append(r.files, configSource{name: path, keys: keys})
+	}
+}
+
+// applyFlags sets the -set overrides in viper, above every other source.
+func (r *configReader) applyFlags(overrides []string) error {
+	var keys []string
+	for _, o := range overrides {
+		key, value, ok := strings.Cut(o, "=")
+		if !ok {
+			return fmt.Errorf("invalid -set %q: want key=value", o)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		viper.Set(key, value)
+		keys = append(keys, key)
+	}
+	if len(keys) > 0 {
+		r.files = append(r.files, configSource{name: "-set flags", keys: keys})
+	}
+	return nil
+}
+
+// known reports whether key, or a section or list containing it, is read.
+func (r *configReader) known(key string) bool {
+	for {
+		if r.read[key] {
+			return true
+		}
+		i := strings.LastIndexByte(key, '.')
+		if i < 0 {
+			return false
+		}
+		key = key[:i]
+	}
+}
+
+// checkUnknown returns an error naming every key a config file or flag
+// sets that no setting reads, usually a typo or a removed setting. Call it
+// once every key has been read.
+func (r *configReader) checkUnknown() error {
+	var errs []error
+	for _, src := range r.files {
+		var unknown []string
+		for _, key := range src.keys {
+			if !r.known(key) {
+				unknown = append(unknown, key)
+			}
+		}
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			errs = append(errs, fmt.Errorf("unknown config keys in %s: %s", src.name, strings.Join(unknown, ", ")))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fileKeys returns the keys the YAML document in data sets.
+func fileKeys(data string) ([]string, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return v.AllKeys(), nil
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeConfigDir writes files into ./config of a fresh working directory
+// and resets viper and the -set flags after the test.
+func writeConfigDir(t *testing.T, files map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Cleanup(viper.Reset)
+	t.Cleanup(func() { flagOverrides = nil })
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "config"), 0o755))
+	for name, data := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "config", name), []byte(data), 0o644))
+	}
+}
+
+func TestLoadConfig_Precedence(t *testing.T) {
+	writeConfigDir(t, map[string]string{
+		"config.yaml": `
+server:
+  port: 7000
+grpc:
+  port: 7001
+redis:
+  port: 7002
+database:
+  host: file-host
+`,
+		"config.dev.yaml": `
+redis:
+  port: 7012
+`,
+	})
+	t.Setenv("STREAMGATE_GRPC_PORT", "7101")
+	t.Setenv("REDIS_PORT", "7102")
+	t.Setenv("STREAMGATE_DATABASE_HOST", "generic-host")
+	t.Setenv("STREAMGATE_DB_HOST", "alias-host")
+	flagOverrides = overrideFlags{"grpc.port=7201"}
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 7000, cfg.Server.Port, "file")
+	assert.Equal(t, 7201, cfg.GRPC.Port, "flag over env")
+	assert.Equal(t, 7102, cfg.Redis.Port, "env over environment file")
+	assert.Equal(t, "alias-host", cfg.Database.Host, "alias over generic name")
+	assert.Equal(t, 30, cfg.Server.ReadTimeout, "default")
+}
+
+func TestLoadConfig_UnknownKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		flags   overrideFlags
+		wantErr []string
+	}{
+		{
+			name:    "typo in file",
+			files:   map[string]string{"config.yaml": "server:\n  port: 8080\n  prot: 8081\n"},
+			wantErr: []string{"unknown config keys in", "config.yaml", "server.prot"},
+		},
+		{
+			name:    "removed setting",
+			files:   map[string]string{"config.yaml": "server:\n  port: 8080\nstorage:\n  s3:\n    bucket: media\n"},
+			wantErr: []string{"storage.s3.bucket"},
+		},
+		{
+			name:    "environment file",
+			files:   map[string]string{"config.yaml": "server:\n  port: 8080\n", "config.dev.yaml": "cors:\n  max_age: 60\n"},
+			wantErr: []string{"config.dev.yaml", "cors.max_age"},
+		},
+		{
+			name:    "flag",
+			files:   map[string]string{"config.yaml": "server:\n  port: 8080\n"},
+			flags:   overrideFlags{"servr.port=9000"},
+			wantErr: []string{"-set flags", "servr.port"},
+		},
+		{
+			name:  "keys inside a section read whole",
+			files: map[string]string{"config.yaml": "server:\n  port: 8080\ngateway:\n  upstreams:\n    - name: api\n      url: http://api:8080\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeConfigDir(t, tt.files)
+			flagOverrides = tt.flags
+
+			_, err := LoadConfig()
+			if len(tt.wantErr) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			for _, want := range tt.wantErr {
+				assert.Contains(t, err.Error(), want)
+			}
+		})
+	}
+}
+
+func TestBindFlags(t *testing.T) {
+	t.Cleanup(func() { flagOverrides = nil })
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	BindFlags(fs)
+	require.NoError(t, fs.Parse([]string{"-set", "server.port=9000", "-set", "auth.jwt_secret=a=b"}))
+	assert.Equal(t, overrideFlags{"server.port=9000", "auth.jwt_secret=a=b"}, flagOverrides)
+
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	BindFlags(fs)
+	assert.Error(t, fs.Parse([]string{"-set", "server.port"}))
+	assert.Error(t, fs.Parse([]string{"-set", "=9000"}))
+}
@@ -0,0 +1,455 @@
+package config
+
+import (
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/monitoring"
+)
+
+// Problem is one invalid setting.
+type Problem struct {
+	Key     string // the setting, e.g. "storage.endpoint"
+	Message string
+	Hint    string // how to fix it, if there is more to say than Message
+}
+
+// SchemaError lists every problem validation found, so they can all be
+// fixed at once.
+type SchemaError struct {
+	Problems []Problem
+}
+
+func (e *SchemaError) Error() string {
+	lines := []string{fmt.Sprintf("invalid config: %d problem(s)", len(e.Problems))}
+	for _, p := range e.Problems {
+		lines = append(lines, "  - "+p.Message)
+		if p.Hint != "" {
+			lines = append(lines, "    hint: "+p.Hint)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validation collects the problems validators report.
+type Validation struct {
+	// partial is set for configs read without defaults, whose unset
+	// settings are left to be filled later; only what is set is checked,
+	// besides server.port and database.host.
+	partial  bool
+	problems []Problem
+}
+
+// Errorf reports a problem with key.
+func (v *Validation) Errorf(key, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+// Hint adds a hint to the last problem reported.
+func (v *Validation) Hint(format string, args ...interface{}) {
+	if n := len(v.problems); n > 0 {
+		v.problems[n-1].Hint = fmt.Sprintf(format, args...)
+	}
+}
+
+// Require reports key when value is empty. name is how the message calls
+// the setting.
+func (v *Validation) Require(key, name, value string) {
+	if value == "" && !v.partial {
+		v.Errorf(key, "%s is required", name)
+		v.Hint("%s", setHint(key))
+	}
+}
+
+// setHint tells where key can be set.
+func setHint(key string) string {
+	return fmt.Sprintf("set %s in the config file, %s in the environment or -set %s=...", key, envNames(key)[0], key)
+}
+
+// Port reports a port outside 1-65535. name is how the message calls it.
+func (v *Validation) Port(key, name string, port int) {
+	if port == 0 && v.partial {
+		return
+	}
+	if port <= 0 || port > 65535 {
+		v.Errorf(key, "invalid %s port: %d", name, port)
+		v.Hint("%s must be between 1 and 65535", key)
+	}
+}
+
+// Duration reports a value that is set but is not a Go duration, or is
+// negative. allowZero accepts "0" and other zero durations.
+func (v *Validation) Duration(key, value string, allowZero bool) {
+	if value == "" {
+		return
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 || (d == 0 && !allowZero) {
+		v.Errorf(key, "invalid %s %q", key, value)
+		v.Hint("use a duration such as 30s, 5m or 1h")
+	}
+}
+
+// OneOf reports a value that is set but not one of allowed.
+func (v *Validation) OneOf(key, value string, allowed ...string) {
+	if value == "" {
+		return
+	}
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.Errorf(key, "invalid %s %q: must be %s", key, value, orList(allowed))
+}
+
+// URL reports a value that is set but is not an absolute URL with one of
+// schemes.
+func (v *Validation) URL(key, value string, schemes ...string) {
+	if value == "" {
+		return
+	}
+	u, err := url.Parse(value)
+	if err == nil && u.Host != "" {
+		for _, s := range schemes {
+			if u.Scheme == s {
+				return
+			}
+		}
+	}
+	v.Errorf(key, "invalid %s %q: must be a %s URL", key, value, orList(schemes))
+}
+
+// orList joins words as "a, b or c".
+func orList(words []string) string {
+	if len(words) < 2 {
+		return strings.Join(words, "")
+	}
+	return strings.Join(words[:len(words)-1], ", ") + " or " + words[len(words)-1]
+}
+
+// A Validator checks one section of a config, reporting to v.
+type Validator func(cfg *Config, v *Validation)
+
+type namedValidator struct {
+	section string
+	check   Validator
+}
+
+var (
+	validatorsMu sync.RWMutex
+	validators   = []namedValidator{
+		{"server", validateServer},
+		{"database", validateDatabase},
+		{"redis", validateRedis},
+		{"storage", validateStorage},
+		{"streaming", validateStreaming},
+		{"transcoding", validateTranscoding},
+		{"security", validateSecurity},
+		{"web3", validateWeb3},
+		{"monitoring", validateMonitoring},
+		{"remote", validateRemote},
+		{"plugins", validatePlugins},
+		{"worker", validateWorker},
+	}
+)
+
+// RegisterValidator adds a validator for a section, e.g. a plugin's
+// settings, run by Validate after the built-in ones.
+func RegisterValidator(section string, check Validator) {
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+	validators = append(validators, namedValidator{section: section, check: check})
+}
+
+// Validate checks every section of c and returns a *SchemaError listing
+// all the problems found, or nil.
+func (c *Config) Validate() error {
+	return c.validate(&Validation{})
+}
+
+func (c *Config) validate(v *Validation) error {
+	validatorsMu.RLock()
+	checks := append([]namedValidator(nil), validators...)
+	validatorsMu.RUnlock()
+
+	for _, nv := range checks {
+		nv.check(c, v)
+	}
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return &SchemaError{Problems: v.problems}
+}
+
+func validateServer(cfg *Config, v *Validation) {
+	if cfg.Server.Port <= 0 || cfg.Server.Port > 65535 {
+		v.Errorf("server.port", "invalid server port: %d", cfg.Server.Port)
+		v.Hint("server.port must be between 1 and 65535")
+	}
+	v.Port("grpc.port", "gRPC", cfg.GRPC.Port)
+	v.Duration("grpc.unary_timeout", cfg.GRPC.UnaryTimeout, false)
+	if cfg.Server.ReadTimeout < 0 || cfg.Server.WriteTimeout < 0 {
+		v.Errorf("server.read_timeout", "server.read_timeout and server.write_timeout must not be negative")
+	}
+	v.Duration("server.drain_delay", cfg.Server.DrainDelay, true)
+	v.Duration("server.shutdown_timeout", cfg.Server.ShutdownTimeout, false)
+}
+
+func validateDatabase(cfg *Config, v *Validation) {
+	db := cfg.Database
+	if db.Host == "" {
+		v.Errorf("database.host", "database host is required")
+		v.Hint("%s", setHint("database.host"))
+	}
+	v.Port("database.port", "database", db.Port)
+	if db.MaxConns < 0 || db.MaxIdleConns < 0 {
+		v.Errorf("database.maxconns", "database.maxconns and database.max_idle_conns must not be negative")
+	}
+	v.Duration("database.conn_max_lifetime", db.ConnMaxLifetime, true)
+	v.Duration("database.replica_retry_after", db.ReplicaRetryAfter, false)
+}
+
+func validateRedis(cfg *Config, v *Validation) {
+	v.Require("redis.host", "redis host", cfg.Redis.Host)
+	v.Port("redis.port", "redis", cfg.Redis.Port)
+	if cfg.Redis.DB < 0 || cfg.Redis.DB > 15 {
+		v.Errorf("redis.db", "invalid redis.db %d: must be between 0 and 15", cfg.Redis.DB)
+	}
+}
+
+func validateStorage(cfg *Config, v *Validation) {
+	s := cfg.Storage
+	switch s.Type {
+	case "", "minio", "s3":
+		v.Require("storage.endpoint", "storage endpoint", s.Endpoint)
+		if (s.AccessKey == "") != (s.SecretKey == "") {
+			v.Errorf("storage.accesskey", "storage.accesskey and storage.secretkey must be set together")
+		}
+	case "gcs", "azure":
+	case "ipfs":
+		v.URL("storage.ipfs.api_url", s.IPFS.APIURL, "http", "https")
+		v.URL("storage.ipfs.pinning_service_url", s.IPFS.PinningServiceURL, "http", "https")
+		if s.IPFS.PinningServiceURL != "" && s.IPFS.PinningToken == "" {
+			v.Errorf("storage.ipfs.pinning_token", "storage.ipfs.pinning_token is required with a pinning service")
+		}
+	case "local":
+		if s.Path == "" {
+			v.Errorf("storage.path", "storage path is required for local storage")
+			v.Hint("%s", setHint("storage.path"))
+		}
+	default:
+		v.Errorf("storage.type", "unsupported storage type: %s", s.Type)
+		v.Hint("storage.type must be minio, s3, gcs, azure, ipfs or local")
+	}
+}
+
+func validateStreaming(cfg *Config, v *Validation) {
+	if cfg.Live.Enabled && (cfg.Live.RTMPPort <= 0 || cfg.Live.RTMPPort > 65535) {
+		v.Errorf("live.rtmp_port", "invalid live RTMP port: %d", cfg.Live.RTMPPort)
+	}
+	if cfg.Live.Enabled && (cfg.Live.SRTPort < 0 || cfg.Live.SRTPort > 65535) {
+		v.Errorf("live.srt_port", "invalid live SRT port: %d", cfg.Live.SRTPort)
+	}
+	for _, server := range cfg.Streaming.DRM.LicenseServers {
+		if server.System != "widevine" && server.System != "fairplay" {
+			v.Errorf("streaming.drm.license_servers", "invalid license server system %q: must be widevine or fairplay", server.System)
+		}
+		if !strings.HasPrefix(server.URL, "https://") && !strings.HasPrefix(server.URL, "http://") {
+			v.Errorf("streaming.drm.license_servers", "invalid %s license server URL %q", server.System, server.URL)
+		}
+	}
+	v.Duration("streaming.drm.license_timeout", cfg.Streaming.DRM.LicenseTimeout, false)
+}
+
+func validateTranscoding(cfg *Config, v *Validation) {
+	t := cfg.Transcoding
+	v.OneOf("transcoding.queue_store", t.QueueStore, "memory", "redis")
+	if t.Distributed && t.QueueStore != "redis" {
+		v.Errorf("transcoding.distributed", "transcoding.distributed requires transcoding.queue_store: redis")
+	}
+	v.OneOf("transcoding.hardware", t.Hardware, "none", "auto", "nvenc", "qsv", "vaapi")
+	for _, codec := range t.Codecs {
+		if codec != "h264" && codec != "hevc" && codec != "av1" {
+			v.Errorf("transcoding.codecs", "invalid transcoding.codecs entry %q: must be h264, hevc or av1", codec)
+		}
+	}
+	for codec := range t.CodecLadders {
+		if codec != "hevc" && codec != "av1" {
+			v.Errorf("transcoding.codec_ladders", "invalid transcoding.codec_ladders key %q: must be hevc or av1", codec)
+		}
+	}
+	if pt := t.PerTitle; pt.Enabled {
+		if pt.TargetVMAF <= 0 || pt.TargetVMAF > 100 {
+			v.Errorf("transcoding.per_title.target_vmaf", "invalid transcoding.per_title.target_vmaf %v: must be in (0, 100]", pt.TargetVMAF)
+		}
+		if pt.MinBitrate > 0 && pt.MaxBitrate > 0 && pt.MinBitrate > pt.MaxBitrate {
+			v.Errorf("transcoding.per_title.min_bitrate", "transcoding.per_title: min_bitrate exceeds max_bitrate")
+		}
+		if pt.MinRungs > 0 && pt.MaxRungs > 0 && pt.MinRungs > pt.MaxRungs {
+			v.Errorf("transcoding.per_title.min_rungs", "transcoding.per_title: min_rungs exceeds max_rungs")
+		}
+	}
+	if sb := t.Storyboard; sb.Enabled {
+		if d, err := time.ParseDuration(sb.Interval); err != nil || d <= 0 {
+			v.Errorf("transcoding.storyboard.interval", "invalid transcoding.storyboard.interval %q", sb.Interval)
+		}
+		if sb.Columns <= 0 || sb.Rows <= 0 || sb.Width <= 0 || sb.Width%2 != 0 {
+			v.Errorf("transcoding.storyboard", "transcoding.storyboard: columns and rows must be positive and width positive and even")
+		}
+	}
+	if ln := t.Loudness; ln.Enabled {
+		// The bounds are those FFmpeg's loudnorm filter accepts.
+		if ln.Integrated < -70 || ln.Integrated > -5 {
+			v.Errorf("transcoding.loudness.integrated", "transcoding.loudness.integrated must be between -70 and -5 LUFS")
+		}
+		if ln.TruePeak < -9 || ln.TruePeak > 0 {
+			v.Errorf("transcoding.loudness.true_peak", "transcoding.loudness.true_peak must be between -9 and 0 dBTP")
+		}
+		if ln.Range < 1 || ln.Range > 50 {
+			v.Errorf("transcoding.loudness.range", "transcoding.loudness.range must be between 1 and 50 LU")
+		}
+	}
+	for _, q := range t.Qualities {
+		if q.DRM == "" {
+			continue
+		}
+		if q.DRM != "cenc" && q.DRM != "cbcs" {
+			v.Errorf("transcoding.qualities", "transcoding.qualities: %s: invalid drm %q: must be cenc or cbcs", q.Name, q.DRM)
+		}
+		// DRM keys live in the same sealed store as AES-128 keys.
+		if !cfg.Encryption.Enabled {
+			v.Errorf("transcoding.qualities", "transcoding.qualities: %s: drm requires encryption to be enabled", q.Name)
+			v.Hint("set encryption.enabled: true and encryption.master_key")
+		}
+	}
+}
+
+func validateSecurity(cfg *Config, v *Validation) {
+	if cfg.Encryption.Enabled {
+		if key, err := hex.DecodeString(cfg.Encryption.MasterKey); err != nil || len(key) != 32 {
+			v.Errorf("encryption.master_key", "encryption.master_key must be 64 hex characters when encryption is enabled")
+			v.Hint("generate one with: openssl rand -hex 32")
+		}
+	}
+	if err := validateChallengeMessage(&cfg.Auth); err != nil {
+		v.Errorf("auth", "%v", err)
+	}
+	v.Duration("auth.jwt_expiry", cfg.Auth.JWTExpiry, false)
+	v.Duration("auth.refresh_token_expiry", cfg.Auth.RefreshTokenExpiry, false)
+	v.Duration("auth.nonce_expiry", cfg.Auth.NonceExpiry, false)
+	for _, origin := range cfg.CORS.AllowedOrigins {
+		if origin != "*" {
+			v.URL("cors.allowed_origins", origin, "http", "https")
+		}
+	}
+	if rl := cfg.RateLimiting; rl.Enabled && (rl.RequestsPerMinute < 0 || rl.RequestsPerHour < 0 || rl.BurstSize < 0) {
+		v.Errorf("rate_limiting", "rate_limiting limits must not be negative")
+	}
+	v.Duration("circuit_breaker.timeout", cfg.CircuitBreaker.Timeout, false)
+	v.Duration("circuit_breaker.window_time", cfg.CircuitBreaker.WindowTime, false)
+	v.Duration("audit.retention", cfg.Audit.Retention, true)
+	if ex := cfg.Audit.Export; ex.Enabled {
+		v.OneOf("audit.export.sink", ex.Sink, "file", "object", "http")
+		v.OneOf("audit.export.format", ex.Format, "jsonl", "cef")
+		switch ex.Sink {
+		case "file":
+			v.Require("audit.export.path", "audit export path", ex.Path)
+		case "http":
+			v.Require("audit.export.url", "audit export url", ex.URL)
+			v.URL("audit.export.url", ex.URL, "http", "https")
+		}
+		v.Duration("audit.export.flush_interval", ex.FlushInterval, false)
+	}
+}
+
+func validateWeb3(cfg *Config, v *Validation) {
+	w := cfg.Web3
+	v.URL("web3.ethereum_rpc", w.EthereumRPC, "http", "https", "ws", "wss")
+	for _, rpc := range w.EthereumRPCs {
+		v.URL("web3.ethereum_rpcs", rpc, "http", "https", "ws", "wss")
+	}
+	v.URL("web3.ethereum_ws_url", w.EthereumWSURL, "ws", "wss")
+	v.URL("web3.solana_rpc", w.SolanaRPC, "http", "https")
+	if w.ChainID < 0 {
+		v.Errorf("web3.chain_id", "invalid web3.chain_id %d", w.ChainID)
+	}
+	v.OneOf("web3.block_tag", w.BlockTag, "safe", "finalized", "latest")
+	for _, chain := range w.ChainEntries() {
+		if chain.ID <= 0 {
+			v.Errorf("web3.chains", "web3 chain %q: id must be positive", chain.Name)
+		}
+		v.URL("web3.chains", chain.RPC, "http", "https", "ws", "wss")
+		for _, rpc := range chain.RPCs {
+			v.URL("web3.chains", rpc, "http", "https", "ws", "wss")
+		}
+	}
+	if pk := w.Transaction.PrivateKeyHex; pk != "" {
+		if key, err := hex.DecodeString(strings.TrimPrefix(pk, "0x")); err != nil || len(key) != 32 {
+			v.Errorf("web3.transaction.private_key_hex", "web3.transaction.private_key_hex must be 64 hex characters")
+		}
+	}
+	if m := w.Transaction.GasMultiplier; m != 0 && m < 1 {
+		v.Errorf("web3.transaction.gas_multiplier", "invalid web3.transaction.gas_multiplier %v: must be at least 1", m)
+	}
+	if rl := w.RateLimit; rl.Enabled && (rl.Rate <= 0 || rl.Burst <= 0) {
+		v.Errorf("web3.rate_limit", "web3.rate_limit: rate and burst must be positive when enabled")
+	}
+	v.Duration("web3.rpc_health_interval", w.RPCHealthInterval, true)
+	v.Duration("web3.nft_cache_ttl", w.NFTCacheTTL, true)
+}
+
+func validateMonitoring(cfg *Config, v *Validation) {
+	if _, err := monitoring.NewSampler(cfg.Monitoring.Tracing.Sampling()); err != nil {
+		v.Errorf("monitoring.tracing", "invalid monitoring.tracing: %v", err)
+	}
+	if tail := cfg.Monitoring.Tracing.Tail; tail.Enabled {
+		v.Duration("monitoring.tracing.tail.latency_threshold", tail.LatencyThreshold, true)
+	}
+}
+
+func validateRemote(cfg *Config, v *Validation) {
+	if source := cfg.Remote.Source; source != "" {
+		if _, err := OpenRemoteSource(source); err != nil {
+			v.Errorf("remote.source", "invalid remote.source %q: %v", source, err)
+			v.Hint("use etcd://host:2379/key, consul://host:8500/key, or etcds:// and consuls:// for TLS")
+		}
+	}
+	v.Duration("remote.poll_interval", cfg.Remote.PollInterval, false)
+}
+
+func validateWorker(cfg *Config, v *Validation) {
+	v.OneOf("worker.schedules_store", cfg.Worker.SchedulesStore, "file", "postgres")
+}
+
+func validatePlugins(cfg *Config, v *Validation) {
+	seen := make(map[string]bool)
+	for _, name := range cfg.Plugins.Enabled {
+		if seen[name] {
+			v.Errorf("plugins.enabled", "plugin %q is enabled twice", name)
+		}
+		seen[name] = true
+	}
+	for i, ext := range cfg.Plugins.External {
+		if ext.Path == "" {
+			v.Errorf("plugins.external", "plugins.external[%d].path is required", i)
+		}
+	}
+	v.Duration("plugins.watch_interval", cfg.Plugins.WatchInterval, false)
+	v.Duration("plugins.usage_interval", cfg.Plugins.UsageInterval, false)
+	for _, name := range slices.Sorted(maps.Keys(cfg.Plugins.Limits)) {
+		if limits := cfg.Plugins.Limits[name]; limits.MaxConcurrentJobs < 0 || limits.MaxMemoryMB < 0 || limits.MaxGoroutines < 0 {
+			v.Errorf("plugins.limits", "plugins.limits.%s must not be negative", name)
+		}
+	}
+	if len(cfg.Plugins.External) > 0 && len(cfg.Plugins.TrustedKeys) == 0 && len(cfg.Plugins.AllowUnsigned) == 0 {
+		v.Errorf("plugins.trusted_keys", "plugins.external needs plugins.trusted_keys to verify the plugins' signatures")
+		v.Hint("sign each binary with minisign and list the public key; for development, list its path in plugins.allow_unsigned")
+	}
+}
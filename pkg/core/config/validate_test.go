@@ -0,0 +1,177 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func problemKeys(t *testing.T, err error) []string {
+	t.Helper()
+	var se *SchemaError
+	require.True(t, errors.As(err, &se), "want *SchemaError, got %v", err)
+	keys := make([]string, len(se.Problems))
+	for i, p := range se.Problems {
+		keys[i] = p.Key
+	}
+	return keys
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   []string
+	}{
+		{
+			name:   "defaults",
+			modify: func(*Config) {},
+		},
+		{
+			name: "every problem reported",
+			modify: func(c *Config) {
+				c.Server.Port = 0
+				c.Redis.Host = ""
+				c.Storage.Endpoint = ""
+				c.Web3.BlockTag = "pending"
+				c.Auth.JWTExpiry = "2 hours"
+			},
+			want: []string{"server.port", "redis.host", "storage.endpoint", "auth.jwt_expiry", "web3.block_tag"},
+		},
+		{
+			name: "web3",
+			modify: func(c *Config) {
+				c.Web3.EthereumRPC = "sepolia.infura.io"
+				c.Web3.EthereumWSURL = "https://rpc.example"
+				c.Web3.Transaction.PrivateKeyHex = "0x1234"
+				c.Web3.Transaction.GasMultiplier = 0.5
+				c.Web3.Chains = []ChainConfigEntry{{Name: "anvil", RPC: "http://localhost:8545"}}
+			},
+			want: []string{
+				"web3.ethereum_rpc", "web3.ethereum_ws_url", "web3.chains",
+				"web3.transaction.private_key_hex", "web3.transaction.gas_multiplier",
+			},
+		},
+		{
+			name: "storage credentials set together",
+			modify: func(c *Config) {
+				c.Storage.SecretKey = ""
+			},
+			want: []string{"storage.accesskey"},
+		},
+		{
+			name: "security",
+			modify: func(c *Config) {
+				c.Encryption.Enabled = true
+				c.CORS.AllowedOrigins = []string{"*", "https://app.example", "app.example"}
+				c.Audit.Retention = "-1h"
+			},
+			want: []string{"encryption.master_key", "cors.allowed_origins", "audit.retention"},
+		},
+		{
+			name: "plugins",
+			modify: func(c *Config) {
+				c.Plugins.Enabled = []string{"api", "upload", "api"}
+			},
+			want: []string{"plugins.enabled"},
+		},
+		{
+			name: "unsigned external plugins",
+			modify: func(c *Config) {
+				c.Plugins.External = []ExternalPluginConfig{{Path: "/opt/plugins/watermark"}}
+			},
+			want: []string{"plugins.trusted_keys"},
+		},
+		{
+			name: "plugin limits",
+			modify: func(c *Config) {
+				c.Plugins.UsageInterval = "often"
+				c.Plugins.Limits = map[string]PluginLimits{"transcoder": {MaxConcurrentJobs: 4}, "watermark": {MaxMemoryMB: -1}}
+			},
+			want: []string{"plugins.usage_interval", "plugins.limits"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.modify(cfg)
+			err := cfg.Validate()
+			if len(tt.want) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			assert.Equal(t, tt.want, problemKeys(t, err))
+		})
+	}
+}
+
+func TestConfig_ValidateHints(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Database.Host = ""
+	cfg.Encryption.Enabled = true
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid config: 2 problem(s)")
+	assert.Contains(t, err.Error(), "  - database host is required\n    hint: set database.host in the config file, STREAMGATE_DB_HOST in the environment")
+	assert.Contains(t, err.Error(), "hint: generate one with: openssl rand -hex 32")
+}
+
+func TestValidateConfig_Partial(t *testing.T) {
+	// A ConfigManager file with only the required settings is valid.
+	cfg := &Config{Server: ServerConfig{Port: 8080}, Database: DatabaseConfig{Host: "db"}}
+	require.NoError(t, validateConfig(cfg))
+	assert.ElementsMatch(t, []string{"grpc.port", "database.port", "redis.host", "redis.port", "storage.endpoint"},
+		problemKeys(t, cfg.Validate()))
+
+	// What it does set is still checked.
+	cfg.Redis.Port = 70000
+	cfg.Transcoding.Hardware = "gpu"
+	assert.Equal(t, []string{"redis.port", "transcoding.hardware"}, problemKeys(t, validateConfig(cfg)))
+}
+
+func TestRegisterValidator(t *testing.T) {
+	saved := validators
+	t.Cleanup(func() { validators = saved })
+
+	RegisterValidator("transcoder", func(cfg *Config, v *Validation) {
+		if cfg.Transcoding.MaxWorkers > 64 {
+			v.Errorf("transcoding.max_workers", "transcoding.max_workers %d exceeds 64", cfg.Transcoding.MaxWorkers)
+		}
+	})
+
+	cfg := DefaultConfig()
+	require.NoError(t, cfg.Validate())
+	cfg.Transcoding.MaxWorkers = 100
+	assert.Equal(t, []string{"transcoding.max_workers"}, problemKeys(t, cfg.Validate()))
+}
+
+func TestLoadConfig_ReportsAllProblems(t *testing.T) {
+	writeConfigDir(t, map[string]string{"config.yaml": `
+server:
+  port: 70000
+  prot: 1
+grpc:
+  port: 0
+database:
+  host: db
+auth:
+  jwt_secret: env:TEST_VALIDATE_MISSING
+transcoding:
+  hardware: gpu
+`})
+
+	_, err := LoadConfig()
+	require.Error(t, err)
+	for _, want := range []string{
+		"unknown config keys in", "server.prot",
+		"resolve auth.jwt_secret",
+		"invalid config: 3 problem(s)", "invalid server port: 70000", "invalid gRPC port: 0", "transcoding.hardware",
+	} {
+		assert.Contains(t, err.Error(), want)
+	}
+	assert.Equal(t, []string{"server.port", "grpc.port", "transcoding.hardware"}, problemKeys(t, err))
+}
@@ -0,0 +1,87 @@
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultConsumerGroup is the consumer group of subscriptions on broker
+// buses that name none.
+const DefaultConsumerGroup = "streamgate"
+
+const (
+	defaultJetStreamStream = "STREAMGATE"
+	defaultAckWait         = 30 * time.Second
+	defaultMaxDeliver      = 5
+	defaultRetryBackoff    = time.Second
+	maxRetryBackoff        = time.Minute
+)
+
+// JetStreamConfig configures a JetStreamEventBus. Zero values take the
+// defaults.
+type JetStreamConfig struct {
+	URL string
+	// Stream is the JetStream stream holding all StreamGate subjects.
+	Stream string
+	// Group is the consumer group of subscriptions that name none.
+	Group string
+	// AckWait is how long a delivery may be handled before it is redelivered.
+	AckWait time.Duration
+	// MaxDeliver caps deliveries of an event whose handler keeps failing.
+	MaxDeliver int
+}
+
+func (c *JetStreamConfig) applyDefaults() {
+	if c.Stream == "" {
+		c.Stream = defaultJetStreamStream
+	}
+	if c.Group == "" {
+		c.Group = DefaultConsumerGroup
+	}
+	if c.AckWait <= 0 {
+		c.AckWait = defaultAckWait
+	}
+	if c.MaxDeliver == 0 {
+		c.MaxDeliver = defaultMaxDeliver
+	}
+}
+
+// durableName builds a JetStream consumer name for group on subject; the
+// names may not contain dots, wildcards or separators.
+func durableName(group, subject string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', '/', '\\', ' ', '\t', '\r', '\n':
+			return '_'
+		}
+		return r
+	}, group+"__"+subject)
+}
+
+// retryDelay is the backoff before redelivering an event whose handler
+// failed on its given delivery attempt.
+func retryDelay(attempt int) time.Duration {
+	d := defaultRetryBackoff
+	for i := 1; i < attempt && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxRetryBackoff)
+}
+
+// encodeScoped resolves event's tenant scope and encodes it for a broker,
+// returning the tenant and bare event type it is routed by.
+func encodeScoped(event *Event) (tenant, eventType string, data []byte, err error) {
+	tenant, eventType, err = tenantNamespace(DefaultTenantTopicPrefix).scope(event.Tenant, event.Type)
+	if err != nil {
+		return "", "", nil, err
+	}
+	scoped := *event
+	scoped.Tenant, scoped.Type = tenant, eventType
+	data, err = json.Marshal(&scoped)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("failed to marshal event: %w", err)
+	}
+	return tenant, eventType, data, nil
+}
@@ -2,18 +2,27 @@ package event
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"sync/atomic"
 
+	"github.com/rtcdance/streamgate/pkg/monitoring"
+
 	"go.uber.org/zap"
 )
 
 type Event struct {
+	// ID identifies the event across redeliveries. Subscribers use it with
+	// a Deduplicator to skip duplicates; it may be empty.
+	ID        string                 `json:"id,omitempty"`
 	Type      string                 `json:"type"`
 	Source    string                 `json:"source"`
 	Timestamp int64                  `json:"timestamp"`
 	Data      map[string]interface{} `json:"data"`
+	// Tenant scopes the event to one tenant's subscribers. It may be left
+	// empty when Type is already a tenant topic (see TenantTopic).
+	Tenant string `json:"tenant,omitempty"`
 }
 
 const (
@@ -31,25 +40,109 @@ const (
 	EventTypeJobFailed           = "job.failed"
 	EventTypeAlertTriggered      = "alert.triggered"
 	EventTypeAlertResolved       = "alert.resolved"
+	EventTypeConfigReloadFailed  = "config.reload.failed"
+	EventTypeUploadCompleted     = "upload.completed"
+	EventTypeUploadScanFailed    = "upload.scan_failed"
 )
 
 type EventHandler func(ctx context.Context, event *Event) error
 
 type EventBus interface {
 	Publish(ctx context.Context, event *Event) error
-	Subscribe(ctx context.Context, eventType string, handler EventHandler) (string, error)
+	Subscribe(ctx context.Context, eventType string, handler EventHandler, opts ...SubscribeOption) (string, error)
 	Unsubscribe(ctx context.Context, subscriptionID string) error
 	Close() error
 }
 
-const defaultMaxConcurrency = 64
+const (
+	defaultMaxConcurrency = 64
+	// defaultMaxQueued caps the events waiting behind one ordering key.
+	defaultMaxQueued = 1024
+)
+
+var (
+	// ErrQueueFull is returned by Publish when an ordering key already has
+	// the subscription's maximum number of events waiting. The event is
+	// still delivered to the publish's other subscriptions.
+	ErrQueueFull = errors.New("ordered event queue is full")
+	// ErrBusClosed is returned by Publish for ordered subscriptions once
+	// the bus is closed.
+	ErrBusClosed = errors.New("event bus is closed")
+)
 
 var nextSubscriptionID atomic.Int64
 
+// SubscribeOption configures how a subscription's handler is invoked.
+type SubscribeOption func(*subscribeOptions)
+
+type subscribeOptions struct {
+	orderingKey func(*Event) string
+	concurrency int
+	maxQueued   int
+	tenant      string
+	group       string
+}
+
+// WithOrderingKey serializes delivery per key: events for which key returns
+// the same value are handled one at a time in publish order, while events
+// with different keys may be handled in parallel. Use it for handlers that
+// drive per-entity state machines (e.g. keyed by content ID).
+func WithOrderingKey(key func(*Event) string) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.orderingKey = key
+	}
+}
+
+// WithConcurrency caps the number of this subscription's handlers running at
+// once. With an ordering key it caps the number of keys processed in
+// parallel. n <= 0 leaves the subscription bounded only by the bus limit.
+func WithConcurrency(n int) SubscribeOption {
+	return func(o *subscribeOptions) {
+		if n > 0 {
+			o.concurrency = n
+		}
+	}
+}
+
+// WithMaxQueued caps the events waiting behind one ordering key; Publish
+// returns ErrQueueFull once the cap is reached. n <= 0 keeps the default of
+// 1024. It has no effect without WithOrderingKey.
+func WithMaxQueued(n int) SubscribeOption {
+	return func(o *subscribeOptions) {
+		if n > 0 {
+			o.maxQueued = n
+		}
+	}
+}
+
+// WithConsumerGroup makes the subscriptions that share group split the
+// events between them: each event is handled by one member of the group
+// rather than by all of them. Broker-backed buses keep the group's
+// position on the broker, so members on other instances share the load
+// and events published while no member runs are delivered later.
+func WithConsumerGroup(group string) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.group = group
+	}
+}
+
 type subscription struct {
-	id        string
-	eventType string
-	handler   EventHandler
+	id          string
+	eventType   string
+	tenant      string
+	group       string
+	handler     EventHandler
+	orderingKey func(*Event) string
+	maxQueued   int
+	sem         chan struct{}
+
+	mu     sync.Mutex
+	queues map[string][]queuedEvent
+}
+
+type queuedEvent struct {
+	ctx   context.Context
+	event *Event
 }
 
 type MemoryEventBus struct {
@@ -59,12 +152,19 @@ type MemoryEventBus struct {
 	sem            chan struct{}
 	maxConcurrency int
 	log            *zap.Logger
+	tenants        tenantNamespace
+
+	// done is closed by Close to stop the ordered-queue drainers.
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
 func NewMemoryEventBus(opts ...MemoryEventBusOption) (*MemoryEventBus, error) {
 	b := &MemoryEventBus{
 		subscriptions:  make(map[string]*subscription),
 		maxConcurrency: defaultMaxConcurrency,
+		tenants:        DefaultTenantTopicPrefix,
+		done:           make(chan struct{}),
 	}
 	for _, opt := range opts {
 		opt(b)
@@ -89,58 +189,225 @@ func WithLogger(log *zap.Logger) MemoryEventBusOption {
 	}
 }
 
-func (b *MemoryEventBus) Publish(ctx context.Context, event *Event) error {
+// WithTenantTopicPrefix changes the first segment of tenant-scoped topics
+// from DefaultTenantTopicPrefix.
+func WithTenantTopicPrefix(prefix string) MemoryEventBusOption {
+	return func(b *MemoryEventBus) {
+		if prefix != "" {
+			b.tenants = tenantNamespace(prefix)
+		}
+	}
+}
+
+// match resolves event's tenant scope and returns the subscriptions it is
+// delivered to, along with the event as handlers see it: Type is the bare
+// event type and Tenant is set. A subscription only matches events of its
+// own tenant; unscoped subscriptions only see unscoped events.
+func (b *MemoryEventBus) match(event *Event) ([]*subscription, *Event, error) {
+	tenant, eventType, err := b.tenants.scope(event.Tenant, event.Type)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tenant != event.Tenant || eventType != event.Type {
+		scoped := *event
+		scoped.Tenant, scoped.Type = tenant, eventType
+		event = &scoped
+	}
+
 	var subs []*subscription
+	grouped := make(map[string]bool)
 	b.mu.RLock()
 	for _, sub := range b.subscriptions {
-		if sub.eventType == event.Type {
-			subs = append(subs, sub)
+		if sub.eventType != eventType || sub.tenant != tenant {
+			continue
+		}
+		// Map iteration order is random, so taking the first member of a
+		// group spreads its events across the members.
+		if sub.group != "" {
+			if grouped[sub.group] {
+				continue
+			}
+			grouped[sub.group] = true
 		}
+		subs = append(subs, sub)
 	}
 	b.mu.RUnlock()
+	return subs, event, nil
+}
 
+// Publish delivers event to every matching subscription. A subscription
+// that cannot take the event does not stop delivery to the others; the
+// failures are returned joined, each naming its subscription.
+func (b *MemoryEventBus) Publish(ctx context.Context, event *Event) error {
+	subs, event, err := b.match(event)
+	if err != nil {
+		return err
+	}
 	if len(subs) == 0 {
 		return nil
 	}
 
+	var errs []error
 	for _, sub := range subs {
+		if err := b.deliver(ctx, sub, event); err != nil {
+			errs = append(errs, fmt.Errorf("subscription %s: %w", sub.id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deliver queues event for an ordered subscription, or starts its handler
+// once the subscription and bus limits have room.
+func (b *MemoryEventBus) deliver(ctx context.Context, sub *subscription, event *Event) error {
+	if sub.orderingKey != nil {
+		return b.enqueueOrdered(ctx, sub, event)
+	}
+	if sub.sem != nil {
 		select {
-		case b.sem <- struct{}{}:
+		case sub.sem <- struct{}{}:
 		case <-ctx.Done():
 			return ctx.Err()
 		}
-		b.wg.Add(1)
-		go func(s *subscription) {
-			defer b.wg.Done()
-			defer func() { <-b.sem }()
-			defer func() {
-				if r := recover(); r != nil {
+	}
+	select {
+	case b.sem <- struct{}{}:
+	case <-ctx.Done():
+		if sub.sem != nil {
+			<-sub.sem
+		}
+		return ctx.Err()
+	}
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer func() { <-b.sem }()
+		if sub.sem != nil {
+			defer func() { <-sub.sem }()
+		}
+		b.dispatch(ctx, sub, event)
+	}()
+	return nil
+}
+
+// enqueueOrdered appends event to its key's queue and starts a drainer for
+// the key if none is running. The drainer exits once the queue is empty or
+// the bus is closed. Queued events keep the values of the publisher's ctx
+// but not its cancellation: Publish has returned by the time they run.
+func (b *MemoryEventBus) enqueueOrdered(ctx context.Context, sub *subscription, event *Event) error {
+	key := sub.orderingKey(event)
+
+	sub.mu.Lock()
+	select {
+	case <-b.done:
+		sub.mu.Unlock()
+		return ErrBusClosed
+	default:
+	}
+	q, running := sub.queues[key]
+	if len(q) >= sub.maxQueued {
+		sub.mu.Unlock()
+		return ErrQueueFull
+	}
+	sub.queues[key] = append(q, queuedEvent{ctx: context.WithoutCancel(ctx), event: event})
+	sub.mu.Unlock()
+	if running {
+		return nil
+	}
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for {
+			sub.mu.Lock()
+			q := sub.queues[key]
+			select {
+			case <-b.done:
+				// Drop what is still queued; the events were never
+				// started and Close must not wait on them.
+				if len(q) > 0 {
+					monitoring.EventsDroppedTotal.WithLabelValues(sub.eventType).Add(float64(len(q)))
 					if b.log != nil {
-						b.log.Error("Recovered panic in event handler", zap.Any("panic", r), zap.String("event_type", event.Type))
+						b.log.Warn("Dropping queued events on close",
+							zap.Int("count", len(q)), zap.String("event_type", sub.eventType))
 					}
 				}
-			}()
-			if err := s.handler(ctx, event); err != nil {
-				if b.log != nil {
-					b.log.Error("Error handling event", zap.Error(err), zap.String("event_type", event.Type))
-				}
+				q = nil
+			default:
 			}
-		}(sub)
-	}
+			if len(q) == 0 {
+				delete(sub.queues, key)
+				sub.mu.Unlock()
+				return
+			}
+			next := q[0]
+			sub.queues[key] = q[1:]
+			sub.mu.Unlock()
 
+			if !b.acquire(sub) {
+				// Closed while waiting: put the event back so the next
+				// pass drops it with the rest.
+				sub.mu.Lock()
+				sub.queues[key] = append([]queuedEvent{next}, sub.queues[key]...)
+				sub.mu.Unlock()
+				continue
+			}
+			b.dispatch(next.ctx, sub, next.event)
+			b.release(sub)
+		}
+	}()
 	return nil
 }
 
-func (b *MemoryEventBus) PublishSync(ctx context.Context, event *Event) error {
-	var subs []*subscription
-	b.mu.RLock()
-	for _, sub := range b.subscriptions {
-		if sub.eventType == event.Type {
-			subs = append(subs, sub)
+// acquire takes a slot from the subscription and bus limits for an ordered
+// event. It gives up, leaving both limits as they were, when the bus is
+// closed.
+func (b *MemoryEventBus) acquire(sub *subscription) bool {
+	if sub.sem != nil {
+		select {
+		case sub.sem <- struct{}{}:
+		case <-b.done:
+			return false
 		}
 	}
-	b.mu.RUnlock()
+	select {
+	case b.sem <- struct{}{}:
+		return true
+	case <-b.done:
+	}
+	if sub.sem != nil {
+		<-sub.sem
+	}
+	return false
+}
+
+func (b *MemoryEventBus) release(sub *subscription) {
+	<-b.sem
+	if sub.sem != nil {
+		<-sub.sem
+	}
+}
+
+// dispatch runs the handler, logging errors and recovering panics.
+func (b *MemoryEventBus) dispatch(ctx context.Context, sub *subscription, event *Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			if b.log != nil {
+				b.log.Error("Recovered panic in event handler", zap.Any("panic", r), zap.String("event_type", event.Type))
+			}
+		}
+	}()
+	if err := sub.handler(ctx, event); err != nil {
+		if b.log != nil {
+			b.log.Error("Error handling event", zap.Error(err), zap.String("event_type", event.Type))
+		}
+	}
+}
 
+func (b *MemoryEventBus) PublishSync(ctx context.Context, event *Event) error {
+	subs, event, err := b.match(event)
+	if err != nil {
+		return err
+	}
 	if len(subs) == 0 {
 		return nil
 	}
@@ -168,12 +435,34 @@ func (b *MemoryEventBus) PublishSync(ctx context.Context, event *Event) error {
 	return firstErr
 }
 
-func (b *MemoryEventBus) Subscribe(ctx context.Context, eventType string, handler EventHandler) (string, error) {
+func (b *MemoryEventBus) Subscribe(ctx context.Context, eventType string, handler EventHandler, opts ...SubscribeOption) (string, error) {
+	var o subscribeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	tenant, eventType, err := b.tenants.scope(o.tenant, eventType)
+	if err != nil {
+		return "", err
+	}
+
 	id := fmtSubscriptionID()
 	sub := &subscription{
-		id:        id,
-		eventType: eventType,
-		handler:   handler,
+		id:          id,
+		eventType:   eventType,
+		tenant:      tenant,
+		group:       o.group,
+		handler:     handler,
+		orderingKey: o.orderingKey,
+	}
+	if o.concurrency > 0 {
+		sub.sem = make(chan struct{}, o.concurrency)
+	}
+	if o.orderingKey != nil {
+		sub.queues = make(map[string][]queuedEvent)
+		sub.maxQueued = defaultMaxQueued
+		if o.maxQueued > 0 {
+			sub.maxQueued = o.maxQueued
+		}
 	}
 
 	b.mu.Lock()
@@ -191,7 +480,10 @@ func (b *MemoryEventBus) Unsubscribe(ctx context.Context, subscriptionID string)
 	return nil
 }
 
+// Close stops the bus's ordered-queue drainers, dropping events still
+// waiting behind a key, and waits for the handlers already running.
 func (b *MemoryEventBus) Close() error {
+	b.closeOnce.Do(func() { close(b.done) })
 	b.wg.Wait()
 	return nil
 }
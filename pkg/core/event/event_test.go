@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -528,6 +529,7 @@ func TestEvent(t *testing.T) {
 		assert.Equal(t, "job.failed", EventTypeJobFailed)
 		assert.Equal(t, "alert.triggered", EventTypeAlertTriggered)
 		assert.Equal(t, "alert.resolved", EventTypeAlertResolved)
+		assert.Equal(t, "upload.scan_failed", EventTypeUploadScanFailed)
 	})
 }
 
@@ -843,3 +845,319 @@ func TestConcurrentPublishSubscribe(t *testing.T) {
 	require.NoError(t, bus.Close())
 	assert.Equal(t, int64(100), receivedCount.Load())
 }
+
+func TestMemoryEventBus_OrderingKey(t *testing.T) {
+	bus, err := NewMemoryEventBus()
+	require.NoError(t, err)
+
+	byContent := func(e *Event) string { return e.Data["content_id"].(string) }
+
+	var mu sync.Mutex
+	seen := map[string][]int{}
+	inFlight := map[string]int{}
+	var overlapSameKey atomic.Bool
+	var active, maxActive atomic.Int32
+
+	handler := func(_ context.Context, e *Event) error {
+		key := byContent(e)
+		mu.Lock()
+		inFlight[key]++
+		if inFlight[key] > 1 {
+			overlapSameKey.Store(true)
+		}
+		mu.Unlock()
+
+		n := active.Add(1)
+		for {
+			m := maxActive.Load()
+			if n <= m || maxActive.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		active.Add(-1)
+
+		mu.Lock()
+		inFlight[key]--
+		seen[key] = append(seen[key], e.Data["seq"].(int))
+		mu.Unlock()
+		return nil
+	}
+
+	_, err = bus.Subscribe(context.Background(), "content.state", handler, WithOrderingKey(byContent))
+	require.NoError(t, err)
+
+	keys := []string{"a", "b", "c", "d"}
+	const perKey = 10
+	for i := 0; i < perKey; i++ {
+		for _, k := range keys {
+			require.NoError(t, bus.Publish(context.Background(), &Event{
+				Type: "content.state",
+				Data: map[string]interface{}{"content_id": k, "seq": i},
+			}))
+		}
+	}
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		n := 0
+		for _, s := range seen {
+			n += len(s)
+		}
+		return n == len(keys)*perKey
+	}, 5*time.Second, time.Millisecond)
+	require.NoError(t, bus.Close())
+
+	assert.False(t, overlapSameKey.Load(), "events with the same key must not run concurrently")
+	assert.Greater(t, maxActive.Load(), int32(1), "different keys should run in parallel")
+	for _, k := range keys {
+		want := make([]int, perKey)
+		for i := range want {
+			want[i] = i
+		}
+		assert.Equal(t, want, seen[k], "key %s delivered out of order", k)
+	}
+}
+
+func TestMemoryEventBus_WithConcurrency(t *testing.T) {
+	bus, err := NewMemoryEventBus()
+	require.NoError(t, err)
+
+	var active, maxActive, handled atomic.Int32
+	handler := func(_ context.Context, _ *Event) error {
+		n := active.Add(1)
+		for {
+			m := maxActive.Load()
+			if n <= m || maxActive.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		active.Add(-1)
+		handled.Add(1)
+		return nil
+	}
+
+	_, err = bus.Subscribe(context.Background(), "work", handler, WithConcurrency(3))
+	require.NoError(t, err)
+
+	for i := 0; i < 20; i++ {
+		require.NoError(t, bus.Publish(context.Background(), &Event{Type: "work"}))
+	}
+	require.NoError(t, bus.Close())
+
+	assert.Equal(t, int32(20), handled.Load())
+	assert.LessOrEqual(t, maxActive.Load(), int32(3))
+	assert.Greater(t, maxActive.Load(), int32(1))
+}
+
+func TestMemoryEventBus_OrderingKeyWithConcurrency(t *testing.T) {
+	bus, err := NewMemoryEventBus()
+	require.NoError(t, err)
+
+	var active, maxActive, handled atomic.Int32
+	handler := func(_ context.Context, _ *Event) error {
+		n := active.Add(1)
+		for {
+			m := maxActive.Load()
+			if n <= m || maxActive.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(2 * time.Millisecond)
+		active.Add(-1)
+		handled.Add(1)
+		return nil
+	}
+
+	_, err = bus.Subscribe(context.Background(), "work", handler,
+		WithOrderingKey(func(e *Event) string { return e.Source }),
+		WithConcurrency(1))
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		for _, src := range []string{"x", "y", "z"} {
+			require.NoError(t, bus.Publish(context.Background(), &Event{Type: "work", Source: src}))
+		}
+	}
+	require.Eventually(t, func() bool { return handled.Load() == 30 }, 5*time.Second, time.Millisecond)
+	require.NoError(t, bus.Close())
+
+	assert.Equal(t, int32(1), maxActive.Load())
+}
+
+func TestMemoryEventBus_OrderingKeyQueueFull(t *testing.T) {
+	bus, err := NewMemoryEventBus()
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	handler := func(context.Context, *Event) error {
+		once.Do(func() { close(started) })
+		<-release
+		return nil
+	}
+	_, err = bus.Subscribe(context.Background(), "work", handler,
+		WithOrderingKey(func(e *Event) string { return e.Source }),
+		WithMaxQueued(2))
+	require.NoError(t, err)
+
+	// The first event is taken off the queue and blocks the key's drainer.
+	require.NoError(t, bus.Publish(context.Background(), &Event{Type: "work", Source: "x"}))
+	<-started
+	require.NoError(t, bus.Publish(context.Background(), &Event{Type: "work", Source: "x"}))
+	require.NoError(t, bus.Publish(context.Background(), &Event{Type: "work", Source: "x"}))
+	assert.ErrorIs(t, bus.Publish(context.Background(), &Event{Type: "work", Source: "x"}), ErrQueueFull)
+	assert.NoError(t, bus.Publish(context.Background(), &Event{Type: "work", Source: "y"}), "other keys have their own queue")
+
+	close(release)
+	require.NoError(t, bus.Close())
+}
+
+func TestMemoryEventBus_OrderingKeyDetachesPublisherContext(t *testing.T) {
+	bus, err := NewMemoryEventBus()
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var handled atomic.Int32
+	var secondCtxErr atomic.Value
+	type ctxKey struct{}
+	handler := func(ctx context.Context, e *Event) error {
+		if e.ID == "first" {
+			close(started)
+			<-release
+		}
+		if e.ID == "second" {
+			secondCtxErr.Store(fmt.Sprintf("%v %v", ctx.Err(), ctx.Value(ctxKey{})))
+		}
+		handled.Add(1)
+		return nil
+	}
+	_, err = bus.Subscribe(context.Background(), "work", handler,
+		WithOrderingKey(func(e *Event) string { return e.Source }),
+		WithConcurrency(1))
+	require.NoError(t, err)
+
+	require.NoError(t, bus.Publish(context.Background(), &Event{ID: "first", Type: "work", Source: "x"}))
+	<-started
+	// The second key waits on the concurrency limit. Publish has returned,
+	// so canceling its context must not drop the event.
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "v"))
+	require.NoError(t, bus.Publish(ctx, &Event{ID: "second", Type: "work", Source: "y"}))
+	cancel()
+
+	close(release)
+	require.Eventually(t, func() bool { return handled.Load() == 2 }, 5*time.Second, time.Millisecond)
+	require.NoError(t, bus.Close())
+	assert.Equal(t, "<nil> v", secondCtxErr.Load(), "handlers keep the publisher's values but not its cancellation")
+}
+
+func TestMemoryEventBus_PublishDeliversPastFullQueue(t *testing.T) {
+	bus, err := NewMemoryEventBus()
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	blocking := func(context.Context, *Event) error {
+		once.Do(func() { close(started) })
+		<-release
+		return nil
+	}
+	fullID, err := bus.Subscribe(context.Background(), "work", blocking,
+		WithOrderingKey(func(e *Event) string { return e.Source }),
+		WithMaxQueued(1))
+	require.NoError(t, err)
+	require.NoError(t, bus.Publish(context.Background(), &Event{Type: "work", Source: "x"}))
+	<-started
+	require.NoError(t, bus.Publish(context.Background(), &Event{Type: "work", Source: "x"}))
+
+	var handled atomic.Int32
+	_, err = bus.Subscribe(context.Background(), "work", func(context.Context, *Event) error {
+		handled.Add(1)
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = bus.Publish(context.Background(), &Event{Type: "work", Source: "x"})
+	assert.ErrorIs(t, err, ErrQueueFull)
+	assert.Contains(t, err.Error(), fullID)
+	require.Eventually(t, func() bool { return handled.Load() == 1 }, 5*time.Second, time.Millisecond,
+		"the other subscription still receives the event")
+
+	close(release)
+	require.NoError(t, bus.Close())
+}
+
+func TestMemoryEventBus_CloseStopsOrderedDrainers(t *testing.T) {
+	bus, err := NewMemoryEventBus()
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var handled atomic.Int32
+	handler := func(_ context.Context, e *Event) error {
+		if e.ID == "first" {
+			close(started)
+			<-release
+		}
+		handled.Add(1)
+		return nil
+	}
+	_, err = bus.Subscribe(context.Background(), "work", handler,
+		WithOrderingKey(func(e *Event) string { return e.Source }),
+		WithConcurrency(1))
+	require.NoError(t, err)
+
+	require.NoError(t, bus.Publish(context.Background(), &Event{ID: "first", Type: "work", Source: "x"}))
+	<-started
+	require.NoError(t, bus.Publish(context.Background(), &Event{Type: "work", Source: "x"}))
+	require.NoError(t, bus.Publish(context.Background(), &Event{Type: "work", Source: "y"}))
+
+	closed := make(chan error, 1)
+	go func() { closed <- bus.Close() }()
+	require.Eventually(t, func() bool {
+		select {
+		case <-bus.done:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, time.Millisecond)
+	close(release)
+
+	select {
+	case err := <-closed:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not stop the ordered drainers")
+	}
+	assert.Equal(t, int32(1), handled.Load(), "queued events are dropped, the running one finishes")
+	assert.ErrorIs(t, bus.Publish(context.Background(), &Event{Type: "work", Source: "x"}), ErrBusClosed)
+}
+
+func TestMemoryEventBus_ConsumerGroup(t *testing.T) {
+	bus, err := NewMemoryEventBus()
+	require.NoError(t, err)
+
+	var workerA, workerB, audit atomic.Int32
+	count := func(n *atomic.Int32) EventHandler {
+		return func(context.Context, *Event) error { n.Add(1); return nil }
+	}
+	_, err = bus.Subscribe(context.Background(), "upload.completed", count(&workerA), WithConsumerGroup("workers"))
+	require.NoError(t, err)
+	_, err = bus.Subscribe(context.Background(), "upload.completed", count(&workerB), WithConsumerGroup("workers"))
+	require.NoError(t, err)
+	_, err = bus.Subscribe(context.Background(), "upload.completed", count(&audit))
+	require.NoError(t, err)
+
+	for i := 0; i < 20; i++ {
+		require.NoError(t, bus.Publish(context.Background(), &Event{Type: "upload.completed"}))
+	}
+	require.NoError(t, bus.Close())
+
+	assert.Equal(t, int32(20), workerA.Load()+workerB.Load(), "each event reaches one group member")
+	assert.Equal(t, int32(20), audit.Load(), "ungrouped subscriptions see every event")
+}
@@ -0,0 +1,127 @@
+package event
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultDedupTTL is how long a processed event ID is remembered when no
+// TTL is configured. It should outlive the broker's redelivery window.
+const DefaultDedupTTL = 24 * time.Hour
+
+// SeenSet records event IDs that have been claimed for processing.
+type SeenSet interface {
+	// MarkSeen records id for ttl. It returns false if id was already
+	// recorded and has not expired.
+	MarkSeen(ctx context.Context, id string, ttl time.Duration) (bool, error)
+	// Forget removes id so a later delivery is processed again.
+	Forget(ctx context.Context, id string) error
+}
+
+// Deduplicator skips duplicate deliveries of at-least-once events. A nil
+// Deduplicator processes every delivery.
+type Deduplicator struct {
+	seen SeenSet
+	ttl  time.Duration
+	log  *zap.Logger
+}
+
+// NewDeduplicator creates a Deduplicator backed by seen. ttl <= 0 uses
+// DefaultDedupTTL.
+func NewDeduplicator(seen SeenSet, ttl time.Duration, log *zap.Logger) *Deduplicator {
+	if ttl <= 0 {
+		ttl = DefaultDedupTTL
+	}
+	if log == nil {
+		log = zap.NewNop()
+	}
+	return &Deduplicator{seen: seen, ttl: ttl, log: log}
+}
+
+// ProcessOnce runs handler unless eventID was already claimed. It reports
+// whether handler ran. When handler fails the claim is released so the
+// next delivery retries. If the seen-set is unreachable the handler runs
+// anyway: a duplicate is cheaper than a lost event.
+func (d *Deduplicator) ProcessOnce(ctx context.Context, eventID string, handler func(context.Context) error) (bool, error) {
+	if d == nil || eventID == "" {
+		return true, handler(ctx)
+	}
+	first, err := d.seen.MarkSeen(ctx, eventID, d.ttl)
+	if err != nil {
+		d.log.Warn("Dedup seen-set unavailable, processing event without idempotency guard",
+			zap.String("event_id", eventID), zap.Error(err))
+		return true, handler(ctx)
+	}
+	if !first {
+		d.log.Debug("Skipping duplicate event delivery", zap.String("event_id", eventID))
+		return false, nil
+	}
+	if err := handler(ctx); err != nil {
+		d.Forget(ctx, eventID)
+		return true, err
+	}
+	return true, nil
+}
+
+// Forget releases eventID so its next delivery is processed, e.g. when a
+// task is deliberately re-enqueued.
+func (d *Deduplicator) Forget(ctx context.Context, eventID string) {
+	if d == nil || eventID == "" {
+		return
+	}
+	if err := d.seen.Forget(context.WithoutCancel(ctx), eventID); err != nil {
+		d.log.Warn("Failed to release dedup claim", zap.String("event_id", eventID), zap.Error(err))
+	}
+}
+
+// Handler wraps h so events are handled at most once per Event.ID. Events
+// without an ID are always handled.
+func (d *Deduplicator) Handler(h EventHandler) EventHandler {
+	return func(ctx context.Context, event *Event) error {
+		_, err := d.ProcessOnce(ctx, event.ID, func(ctx context.Context) error {
+			return h(ctx, event)
+		})
+		return err
+	}
+}
+
+// MemorySeenSet is a process-local SeenSet with lazy expiry. It only
+// de-duplicates deliveries to the same process.
+type MemorySeenSet struct {
+	mu      sync.Mutex
+	entries map[string]time.Time // id → expiresAt
+	now     func() time.Time
+}
+
+// NewMemorySeenSet creates an empty in-memory seen-set.
+func NewMemorySeenSet() *MemorySeenSet {
+	return &MemorySeenSet{entries: make(map[string]time.Time), now: time.Now}
+}
+
+func (s *MemorySeenSet) MarkSeen(_ context.Context, id string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if expiresAt, ok := s.entries[id]; ok && now.Before(expiresAt) {
+		return false, nil
+	}
+	s.entries[id] = now.Add(ttl)
+	if len(s.entries)%1024 == 0 {
+		for k, expiresAt := range s.entries {
+			if !now.Before(expiresAt) {
+				delete(s.entries, k)
+			}
+		}
+	}
+	return true, nil
+}
+
+func (s *MemorySeenSet) Forget(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, id)
+	return nil
+}
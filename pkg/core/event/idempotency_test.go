@@ -0,0 +1,162 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// flakySeenSet fails every call, standing in for an unreachable Redis.
+type flakySeenSet struct{}
+
+func (flakySeenSet) MarkSeen(context.Context, string, time.Duration) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func (flakySeenSet) Forget(context.Context, string) error { return errors.New("connection refused") }
+
+func TestDeduplicator_ProcessOnce_SkipsDuplicate(t *testing.T) {
+	d := NewDeduplicator(NewMemorySeenSet(), time.Hour, zap.NewNop())
+	var calls int
+	handler := func(context.Context) error {
+		calls++
+		return nil
+	}
+
+	ran, err := d.ProcessOnce(context.Background(), "evt-1", handler)
+	require.NoError(t, err)
+	assert.True(t, ran)
+
+	ran, err = d.ProcessOnce(context.Background(), "evt-1", handler)
+	require.NoError(t, err)
+	assert.False(t, ran, "second delivery is skipped")
+	assert.Equal(t, 1, calls)
+
+	ran, _ = d.ProcessOnce(context.Background(), "evt-2", handler)
+	assert.True(t, ran)
+	assert.Equal(t, 2, calls)
+}
+
+func TestDeduplicator_ProcessOnce_FailureReleasesClaim(t *testing.T) {
+	d := NewDeduplicator(NewMemorySeenSet(), time.Hour, zap.NewNop())
+	boom := errors.New("transcode failed")
+	var calls int
+
+	ran, err := d.ProcessOnce(context.Background(), "evt-1", func(context.Context) error {
+		calls++
+		return boom
+	})
+	assert.True(t, ran)
+	assert.ErrorIs(t, err, boom)
+
+	ran, err = d.ProcessOnce(context.Background(), "evt-1", func(context.Context) error {
+		calls++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.True(t, ran, "a failed attempt must not block the retry")
+	assert.Equal(t, 2, calls)
+}
+
+func TestDeduplicator_ProcessOnce_ConcurrentDeliveries(t *testing.T) {
+	d := NewDeduplicator(NewMemorySeenSet(), time.Hour, zap.NewNop())
+	var calls atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = d.ProcessOnce(context.Background(), "evt-1", func(context.Context) error {
+				calls.Add(1)
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestDeduplicator_ProcessOnce_Passthrough(t *testing.T) {
+	var calls int
+	handler := func(context.Context) error {
+		calls++
+		return nil
+	}
+
+	var nilDedup *Deduplicator
+	ran, err := nilDedup.ProcessOnce(context.Background(), "evt-1", handler)
+	require.NoError(t, err)
+	assert.True(t, ran)
+	nilDedup.Forget(context.Background(), "evt-1")
+
+	d := NewDeduplicator(NewMemorySeenSet(), time.Hour, zap.NewNop())
+	_, _ = d.ProcessOnce(context.Background(), "", handler)
+	_, _ = d.ProcessOnce(context.Background(), "", handler)
+
+	unavailable := NewDeduplicator(flakySeenSet{}, time.Hour, zap.NewNop())
+	ran, err = unavailable.ProcessOnce(context.Background(), "evt-1", handler)
+	require.NoError(t, err)
+	assert.True(t, ran, "an unreachable seen-set fails open")
+
+	assert.Equal(t, 4, calls)
+}
+
+func TestMemorySeenSet_Expiry(t *testing.T) {
+	s := NewMemorySeenSet()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	first, err := s.MarkSeen(context.Background(), "evt-1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, first)
+
+	first, _ = s.MarkSeen(context.Background(), "evt-1", time.Minute)
+	assert.False(t, first)
+
+	now = now.Add(time.Minute)
+	first, _ = s.MarkSeen(context.Background(), "evt-1", time.Minute)
+	assert.True(t, first, "expired IDs can be claimed again")
+
+	require.NoError(t, s.Forget(context.Background(), "evt-1"))
+	first, _ = s.MarkSeen(context.Background(), "evt-1", time.Minute)
+	assert.True(t, first)
+}
+
+func TestDeduplicator_HandlerOnMemoryBus(t *testing.T) {
+	bus, err := NewMemoryEventBus()
+	require.NoError(t, err)
+	defer bus.Close()
+
+	d := NewDeduplicator(NewMemorySeenSet(), time.Hour, zap.NewNop())
+	var calls atomic.Int32
+	done := make(chan struct{}, 3)
+	_, err = bus.Subscribe(context.Background(), EventTypeJobSubmitted, func(ctx context.Context, e *Event) error {
+		defer func() { done <- struct{}{} }()
+		return d.Handler(func(context.Context, *Event) error {
+			calls.Add(1)
+			return nil
+		})(ctx, e)
+	}, WithConcurrency(1))
+	require.NoError(t, err)
+
+	ev := &Event{ID: "job-42", Type: EventTypeJobSubmitted, Data: map[string]interface{}{"content_id": "c1"}}
+	require.NoError(t, bus.Publish(context.Background(), ev))
+	require.NoError(t, bus.Publish(context.Background(), ev))
+	require.NoError(t, bus.Publish(context.Background(), &Event{ID: "job-43", Type: EventTypeJobSubmitted}))
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for deliveries")
+		}
+	}
+	assert.Equal(t, int32(2), calls.Load())
+}
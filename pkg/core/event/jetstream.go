@@ -0,0 +1,213 @@
+//go:build nats_integration
+
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+// JetStreamEventBus is a NATS JetStream implementation of EventBus with
+// at-least-once delivery. Every subscription is a durable consumer of its
+// consumer group, so instances in one group share the events and resume
+// where the group left off. Handlers that fail are redelivered with
+// backoff up to MaxDeliver times; use a Deduplicator to skip repeats.
+type JetStreamEventBus struct {
+	conn   *nats.Conn
+	js     jetstream.JetStream
+	cfg    JetStreamConfig
+	logger *zap.Logger
+
+	mu            sync.Mutex
+	subscriptions map[string]jetstream.ConsumeContext
+}
+
+// NewJetStreamEventBus connects to NATS and creates or updates the stream
+// that captures all StreamGate subjects.
+func NewJetStreamEventBus(cfg JetStreamConfig, logger *zap.Logger) (*JetStreamEventBus, error) {
+	cfg.applyDefaults()
+	logger.Info("Connecting to NATS JetStream", zap.String("url", cfg.URL), zap.String("stream", cfg.Stream))
+
+	conn, err := nats.Connect(cfg.URL,
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(2*time.Second),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			logger.Warn("NATS disconnected", zap.Error(err))
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			logger.Info("NATS reconnected", zap.String("url", nc.ConnectedUrl()))
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open JetStream: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     cfg.Stream,
+		Subjects: []string{"streamgate.>"},
+		Storage:  jetstream.FileStorage,
+	}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create JetStream stream %s: %w", cfg.Stream, err)
+	}
+
+	return &JetStreamEventBus{
+		conn:          conn,
+		js:            js,
+		cfg:           cfg,
+		logger:        logger,
+		subscriptions: make(map[string]jetstream.ConsumeContext),
+	}, nil
+}
+
+// Publish stores the event in the stream. Events with an ID are
+// deduplicated by the server within its duplicate window.
+func (b *JetStreamEventBus) Publish(ctx context.Context, event *Event) error {
+	tenant, eventType, data, err := encodeScoped(event)
+	if err != nil {
+		return err
+	}
+	var opts []jetstream.PublishOpt
+	if event.ID != "" {
+		opts = append(opts, jetstream.WithMsgID(event.ID))
+	}
+	subject := natsSubject(tenant, eventType)
+	if _, err := b.js.Publish(ctx, subject, data, opts...); err != nil {
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
+	return nil
+}
+
+// Subscribe attaches handler to the durable consumer of its group for
+// eventType. New groups start with events published from now on. Events
+// of one subscription are handled one at a time, which satisfies any
+// ordering key; ForTenant and WithConsumerGroup are applied.
+func (b *JetStreamEventBus) Subscribe(ctx context.Context, eventType string, handler EventHandler, opts ...SubscribeOption) (string, error) {
+	var o subscribeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	tenant, eventType, err := tenantNamespace(DefaultTenantTopicPrefix).scope(o.tenant, eventType)
+	if err != nil {
+		return "", err
+	}
+	group := o.group
+	if group == "" {
+		group = b.cfg.Group
+	}
+	subject := natsSubject(tenant, eventType)
+
+	consumer, err := b.js.CreateOrUpdateConsumer(ctx, b.cfg.Stream, jetstream.ConsumerConfig{
+		Durable:       durableName(group, subject),
+		FilterSubject: subject,
+		DeliverPolicy: jetstream.DeliverNewPolicy,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       b.cfg.AckWait,
+		MaxDeliver:    b.cfg.MaxDeliver,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create consumer: %w", err)
+	}
+
+	handlerCtx := context.WithoutCancel(ctx)
+	cc, err := consumer.Consume(func(msg jetstream.Msg) {
+		b.handle(handlerCtx, tenant, msg, handler)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	subID := fmtSubscriptionID()
+	b.mu.Lock()
+	b.subscriptions[subID] = cc
+	b.mu.Unlock()
+
+	b.logger.Info("Subscribed to events",
+		zap.String("type", eventType), zap.String("subject", subject), zap.String("group", group))
+	return subID, nil
+}
+
+// handle runs handler for one delivery and acknowledges it. Undecodable
+// and cross-tenant messages are terminated rather than redelivered.
+func (b *JetStreamEventBus) handle(ctx context.Context, tenant string, msg jetstream.Msg, handler EventHandler) {
+	var event Event
+	if err := json.Unmarshal(msg.Data(), &event); err != nil {
+		b.logger.Error("Failed to unmarshal event", zap.String("subject", msg.Subject()), zap.Error(err))
+		_ = msg.Term()
+		return
+	}
+	if event.Tenant != tenant {
+		b.logger.Warn("Dropping event with mismatched tenant",
+			zap.String("subject", msg.Subject()), zap.String("tenant", event.Tenant))
+		_ = msg.Term()
+		return
+	}
+
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic in event handler: %v", r)
+			}
+		}()
+		return handler(ctx, &event)
+	}()
+	if err == nil {
+		if err := msg.Ack(); err != nil && !errors.Is(err, nats.ErrConnectionClosed) {
+			b.logger.Warn("Failed to ack event", zap.String("type", event.Type), zap.Error(err))
+		}
+		return
+	}
+
+	attempt := 1
+	if md, mdErr := msg.Metadata(); mdErr == nil {
+		attempt = int(md.NumDelivered)
+	}
+	b.logger.Error("Error handling event, will redeliver",
+		zap.String("type", event.Type), zap.Int("attempt", attempt), zap.Error(err))
+	_ = msg.NakWithDelay(retryDelay(attempt))
+}
+
+// Unsubscribe stops the subscription. Its durable consumer is kept so the
+// group can resume.
+func (b *JetStreamEventBus) Unsubscribe(ctx context.Context, subscriptionID string) error {
+	b.mu.Lock()
+	cc, ok := b.subscriptions[subscriptionID]
+	delete(b.subscriptions, subscriptionID)
+	b.mu.Unlock()
+	if ok {
+		cc.Stop()
+	}
+	return nil
+}
+
+// Close stops all subscriptions and drains the connection.
+func (b *JetStreamEventBus) Close() error {
+	b.mu.Lock()
+	for id, cc := range b.subscriptions {
+		cc.Stop()
+		delete(b.subscriptions, id)
+	}
+	b.mu.Unlock()
+
+	if err := b.conn.Drain(); err != nil {
+		b.conn.Close()
+		return fmt.Errorf("failed to drain NATS connection: %w", err)
+	}
+	b.logger.Info("JetStream event bus closed")
+	return nil
+}
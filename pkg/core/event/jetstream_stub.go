@@ -0,0 +1,32 @@
+//go:build !nats_integration
+
+package event
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+type JetStreamEventBus struct{}
+
+func NewJetStreamEventBus(cfg JetStreamConfig, logger *zap.Logger) (*JetStreamEventBus, error) {
+	return nil, fmt.Errorf("NATS JetStream not available: build with -tags nats_integration")
+}
+
+func (b *JetStreamEventBus) Publish(ctx context.Context, event *Event) error {
+	return fmt.Errorf("NATS JetStream not available")
+}
+
+func (b *JetStreamEventBus) Subscribe(ctx context.Context, eventType string, handler EventHandler, opts ...SubscribeOption) (string, error) {
+	return "", fmt.Errorf("NATS JetStream not available")
+}
+
+func (b *JetStreamEventBus) Unsubscribe(ctx context.Context, subscriptionID string) error {
+	return fmt.Errorf("NATS JetStream not available")
+}
+
+func (b *JetStreamEventBus) Close() error {
+	return nil
+}
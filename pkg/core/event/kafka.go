@@ -0,0 +1,369 @@
+package event
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	kafkaJSONContentType = "application/vnd.kafka.json.v2+json"
+	kafkaContentType     = "application/vnd.kafka.v2+json"
+	defaultTopicPrefix   = "streamgate"
+	defaultPollTimeout   = time.Second
+)
+
+// KafkaConfig configures a KafkaEventBus. Zero values take the defaults.
+type KafkaConfig struct {
+	// RESTURL is the base URL of a Kafka REST Proxy speaking the v2 API.
+	RESTURL string
+	// Group is the consumer group of subscriptions that name none.
+	Group string
+	// TopicPrefix starts every topic name, e.g. "streamgate.upload.completed".
+	TopicPrefix string
+	// PollTimeout is how long a fetch waits for records.
+	PollTimeout time.Duration
+	HTTPClient  *http.Client
+}
+
+func (c *KafkaConfig) applyDefaults() {
+	c.RESTURL = strings.TrimSuffix(c.RESTURL, "/")
+	if c.Group == "" {
+		c.Group = DefaultConsumerGroup
+	}
+	if c.TopicPrefix == "" {
+		c.TopicPrefix = defaultTopicPrefix
+	}
+	if c.PollTimeout <= 0 {
+		c.PollTimeout = defaultPollTimeout
+	}
+	if c.HTTPClient == nil {
+		c.HTTPClient = &http.Client{Timeout: c.PollTimeout + 30*time.Second}
+	}
+}
+
+// KafkaEventBus is a Kafka implementation of EventBus, reached through a
+// Kafka REST Proxy so no client library is linked in. Each event type is
+// a topic; each subscription is a consumer instance in its consumer group,
+// so instances in one group split the partitions. Offsets are committed
+// only after handlers succeed, which gives at-least-once delivery: a
+// failed event is fetched again after a backoff, together with the rest
+// of its partition. Use a Deduplicator to skip repeats.
+type KafkaEventBus struct {
+	cfg    KafkaConfig
+	logger *zap.Logger
+
+	mu            sync.Mutex
+	subscriptions map[string]*kafkaConsumer
+	wg            sync.WaitGroup
+}
+
+type kafkaConsumer struct {
+	baseURI string
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+type kafkaRecord struct {
+	Topic     string          `json:"topic"`
+	Key       json.RawMessage `json:"key"`
+	Value     json.RawMessage `json:"value"`
+	Partition int             `json:"partition"`
+	Offset    int64           `json:"offset"`
+}
+
+type kafkaOffset struct {
+	Topic     string `json:"topic"`
+	Partition int    `json:"partition"`
+	Offset    int64  `json:"offset"`
+}
+
+// NewKafkaEventBus returns a bus over the REST Proxy at cfg.RESTURL. It
+// does not connect until the first publish or subscribe.
+func NewKafkaEventBus(cfg KafkaConfig, logger *zap.Logger) (*KafkaEventBus, error) {
+	cfg.applyDefaults()
+	if cfg.RESTURL == "" {
+		return nil, fmt.Errorf("kafka REST proxy url is required")
+	}
+	return &KafkaEventBus{cfg: cfg, logger: logger, subscriptions: make(map[string]*kafkaConsumer)}, nil
+}
+
+// kafkaTopic maps a tenant scope and event type to a topic name. Kafka
+// topics only allow letters, digits, dots, underscores and hyphens.
+func kafkaTopic(prefix, tenant, eventType string) string {
+	topic := prefix + "." + eventType
+	if tenant != "" {
+		topic = prefix + "." + DefaultTenantTopicPrefix + "." + tenant + "." + eventType
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, topic)
+}
+
+// Publish produces the event to its topic, keyed by event ID so retried
+// publishes land on the same partition.
+func (b *KafkaEventBus) Publish(ctx context.Context, event *Event) error {
+	tenant, eventType, data, err := encodeScoped(event)
+	if err != nil {
+		return err
+	}
+	record := map[string]interface{}{"value": json.RawMessage(data)}
+	if event.ID != "" {
+		record["key"] = event.ID
+	}
+	var resp struct {
+		Offsets []struct {
+			Error string `json:"error"`
+		} `json:"offsets"`
+	}
+	topic := kafkaTopic(b.cfg.TopicPrefix, tenant, eventType)
+	if err := b.call(ctx, http.MethodPost, b.cfg.RESTURL+"/topics/"+topic, kafkaJSONContentType,
+		map[string]interface{}{"records": []interface{}{record}}, &resp); err != nil {
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
+	for _, o := range resp.Offsets {
+		if o.Error != "" {
+			return fmt.Errorf("failed to publish event: %s", o.Error)
+		}
+	}
+	return nil
+}
+
+// Subscribe creates a consumer instance in the subscription's group and
+// polls its topic until Unsubscribe or Close. Records are handled one at a
+// time in partition order, which satisfies any ordering key; ForTenant and
+// WithConsumerGroup are applied. New groups start at the earliest offset.
+func (b *KafkaEventBus) Subscribe(ctx context.Context, eventType string, handler EventHandler, opts ...SubscribeOption) (string, error) {
+	var o subscribeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	tenant, eventType, err := tenantNamespace(DefaultTenantTopicPrefix).scope(o.tenant, eventType)
+	if err != nil {
+		return "", err
+	}
+	group := o.group
+	if group == "" {
+		group = b.cfg.Group
+	}
+	topic := kafkaTopic(b.cfg.TopicPrefix, tenant, eventType)
+
+	suffix := make([]byte, 6)
+	_, _ = rand.Read(suffix)
+	var created struct {
+		BaseURI string `json:"base_uri"`
+	}
+	if err := b.call(ctx, http.MethodPost, b.cfg.RESTURL+"/consumers/"+group, kafkaContentType, map[string]string{
+		"name":               group + "-" + hex.EncodeToString(suffix),
+		"format":             "json",
+		"auto.offset.reset":  "earliest",
+		"auto.commit.enable": "false",
+	}, &created); err != nil {
+		return "", fmt.Errorf("failed to create kafka consumer: %w", err)
+	}
+	if err := b.call(ctx, http.MethodPost, created.BaseURI+"/subscription", kafkaContentType,
+		map[string][]string{"topics": {topic}}, nil); err != nil {
+		_ = b.call(context.WithoutCancel(ctx), http.MethodDelete, created.BaseURI, kafkaContentType, nil, nil)
+		return "", fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	pollCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	c := &kafkaConsumer{baseURI: created.BaseURI, cancel: cancel, done: make(chan struct{})}
+	subID := fmtSubscriptionID()
+	b.mu.Lock()
+	b.subscriptions[subID] = c
+	b.mu.Unlock()
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer close(c.done)
+		b.poll(pollCtx, c, tenant, handler)
+	}()
+
+	b.logger.Info("Subscribed to events",
+		zap.String("type", eventType), zap.String("topic", topic), zap.String("group", group))
+	return subID, nil
+}
+
+// poll fetches and handles records until ctx is done.
+func (b *KafkaEventBus) poll(ctx context.Context, c *kafkaConsumer, tenant string, handler EventHandler) {
+	failures := 0
+	for ctx.Err() == nil {
+		var records []kafkaRecord
+		url := fmt.Sprintf("%s/records?timeout=%d", c.baseURI, b.cfg.PollTimeout.Milliseconds())
+		if err := b.call(ctx, http.MethodGet, url, kafkaJSONContentType, nil, &records); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			failures++
+			b.logger.Warn("Failed to fetch kafka records", zap.Error(err))
+			sleepCtx(ctx, retryDelay(failures))
+			continue
+		}
+		if b.handleBatch(ctx, c, tenant, records, handler) {
+			failures = 0
+			continue
+		}
+		failures++
+		sleepCtx(ctx, retryDelay(failures))
+	}
+}
+
+// handleBatch handles records and commits the offsets of those handled.
+// When a handler fails, the rest of that record's partition is skipped and
+// the consumer is rewound to it; handleBatch then returns false.
+func (b *KafkaEventBus) handleBatch(ctx context.Context, c *kafkaConsumer, tenant string, records []kafkaRecord, handler EventHandler) bool {
+	handled := make(map[kafkaOffset]int64)
+	var rewind []kafkaOffset
+	failed := make(map[kafkaOffset]bool)
+	for _, rec := range records {
+		part := kafkaOffset{Topic: rec.Topic, Partition: rec.Partition}
+		if failed[part] {
+			continue
+		}
+		if err := b.handleRecord(ctx, tenant, rec, handler); err != nil {
+			b.logger.Error("Error handling event, will redeliver",
+				zap.String("topic", rec.Topic), zap.Int("partition", rec.Partition), zap.Int64("offset", rec.Offset), zap.Error(err))
+			failed[part] = true
+			rewind = append(rewind, kafkaOffset{Topic: rec.Topic, Partition: rec.Partition, Offset: rec.Offset})
+			continue
+		}
+		handled[part] = rec.Offset
+	}
+
+	writeCtx := context.WithoutCancel(ctx)
+	if len(handled) > 0 {
+		// The proxy commits the position after each offset given.
+		offsets := make([]kafkaOffset, 0, len(handled))
+		for part, off := range handled {
+			offsets = append(offsets, kafkaOffset{Topic: part.Topic, Partition: part.Partition, Offset: off})
+		}
+		if err := b.call(writeCtx, http.MethodPost, c.baseURI+"/offsets", kafkaContentType,
+			map[string]interface{}{"offsets": offsets}, nil); err != nil {
+			b.logger.Warn("Failed to commit kafka offsets", zap.Error(err))
+		}
+	}
+	if len(rewind) > 0 {
+		if err := b.call(writeCtx, http.MethodPost, c.baseURI+"/positions", kafkaContentType,
+			map[string]interface{}{"offsets": rewind}, nil); err != nil {
+			b.logger.Warn("Failed to rewind kafka consumer", zap.Error(err))
+		}
+		return false
+	}
+	return true
+}
+
+// handleRecord decodes and handles one record. Undecodable and
+// cross-tenant records are dropped rather than retried forever.
+func (b *KafkaEventBus) handleRecord(ctx context.Context, tenant string, rec kafkaRecord, handler EventHandler) (err error) {
+	var event Event
+	if err := json.Unmarshal(rec.Value, &event); err != nil {
+		b.logger.Error("Failed to unmarshal event", zap.String("topic", rec.Topic), zap.Error(err))
+		return nil
+	}
+	if event.Tenant != tenant {
+		b.logger.Warn("Dropping event with mismatched tenant",
+			zap.String("topic", rec.Topic), zap.String("tenant", event.Tenant))
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic in event handler: %v", r)
+		}
+	}()
+	return handler(ctx, &event)
+}
+
+// Unsubscribe stops polling and deletes the consumer instance so its
+// partitions move to the rest of the group.
+func (b *KafkaEventBus) Unsubscribe(ctx context.Context, subscriptionID string) error {
+	b.mu.Lock()
+	c, ok := b.subscriptions[subscriptionID]
+	delete(b.subscriptions, subscriptionID)
+	b.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	c.cancel()
+	<-c.done
+	if err := b.call(context.WithoutCancel(ctx), http.MethodDelete, c.baseURI, kafkaContentType, nil, nil); err != nil {
+		return fmt.Errorf("failed to delete kafka consumer: %w", err)
+	}
+	return nil
+}
+
+// Close unsubscribes everything.
+func (b *KafkaEventBus) Close() error {
+	b.mu.Lock()
+	ids := make([]string, 0, len(b.subscriptions))
+	for id := range b.subscriptions {
+		ids = append(ids, id)
+	}
+	b.mu.Unlock()
+
+	for _, id := range ids {
+		if err := b.Unsubscribe(context.Background(), id); err != nil {
+			b.logger.Warn("Failed to close kafka subscription", zap.String("subscription_id", id), zap.Error(err))
+		}
+	}
+	b.wg.Wait()
+	return nil
+}
+
+// call sends a REST Proxy request, encoding body and decoding the
+// response into out when they are non-nil.
+func (b *KafkaEventBus) call(ctx context.Context, method, url, contentType string, body, out interface{}) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, r)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", contentType)
+
+	resp, err := b.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("kafka rest proxy returned %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
@@ -0,0 +1,163 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeKafkaProxy is a single-partition Kafka REST Proxy with one consumer
+// instance.
+type fakeKafkaProxy struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	topics    map[string][]json.RawMessage
+	topic     string
+	position  int64
+	committed int64
+	rewinds   int
+	deleted   bool
+}
+
+func newFakeKafkaProxy(t *testing.T) *fakeKafkaProxy {
+	p := &fakeKafkaProxy{topics: make(map[string][]json.RawMessage), committed: -1}
+	p.Server = httptest.NewServer(http.HandlerFunc(p.serve))
+	t.Cleanup(p.Close)
+	return p
+}
+
+func (p *fakeKafkaProxy) serve(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	const instance = "/consumers/workers/instances/i1"
+	var body struct {
+		Records []struct {
+			Value json.RawMessage `json:"value"`
+		} `json:"records"`
+		Topics  []string      `json:"topics"`
+		Offsets []kafkaOffset `json:"offsets"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/topics/"):
+		topic := strings.TrimPrefix(r.URL.Path, "/topics/")
+		for _, rec := range body.Records {
+			p.topics[topic] = append(p.topics[topic], rec.Value)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"offsets": []map[string]interface{}{{"partition": 0}}})
+	case r.URL.Path == "/consumers/workers":
+		_ = json.NewEncoder(w).Encode(map[string]string{"instance_id": "i1", "base_uri": p.URL + instance})
+	case r.URL.Path == instance+"/subscription":
+		p.topic = body.Topics[0]
+		w.WriteHeader(http.StatusNoContent)
+	case r.URL.Path == instance+"/records":
+		var recs []kafkaRecord
+		for ; p.position < int64(len(p.topics[p.topic])); p.position++ {
+			recs = append(recs, kafkaRecord{Topic: p.topic, Value: p.topics[p.topic][p.position], Offset: p.position})
+		}
+		if len(recs) == 0 {
+			time.Sleep(5 * time.Millisecond)
+		}
+		_ = json.NewEncoder(w).Encode(recs)
+	case r.URL.Path == instance+"/offsets":
+		p.committed = body.Offsets[0].Offset
+		w.WriteHeader(http.StatusNoContent)
+	case r.URL.Path == instance+"/positions":
+		p.position = body.Offsets[0].Offset
+		p.rewinds++
+		w.WriteHeader(http.StatusNoContent)
+	case r.URL.Path == instance && r.Method == http.MethodDelete:
+		p.deleted = true
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (p *fakeKafkaProxy) state() (committed int64, rewinds int, deleted bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.committed, p.rewinds, p.deleted
+}
+
+func TestKafkaTopic(t *testing.T) {
+	tests := []struct {
+		tenant, eventType, want string
+	}{
+		{"", "upload.completed", "streamgate.upload.completed"},
+		{"acme", "transcode.task.completed", "streamgate.tenant.acme.transcode.task.completed"},
+		{"a:b", "plugin.loaded", "streamgate.tenant.a_b.plugin.loaded"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, kafkaTopic("streamgate", tt.tenant, tt.eventType))
+	}
+}
+
+func TestKafkaEventBus_AtLeastOnce(t *testing.T) {
+	proxy := newFakeKafkaProxy(t)
+	bus, err := NewKafkaEventBus(KafkaConfig{RESTURL: proxy.URL, Group: "workers", PollTimeout: 10 * time.Millisecond}, zap.NewNop())
+	require.NoError(t, err)
+
+	// Published before anyone subscribes; the new group starts at the
+	// earliest offset and still receives it.
+	ctx := context.Background()
+	require.NoError(t, bus.Publish(ctx, &Event{ID: "e1", Type: TenantTopic("acme", "upload.completed"), Data: map[string]interface{}{"n": 1.0}}))
+	assert.Len(t, proxy.topics["streamgate.tenant.acme.upload.completed"], 1)
+
+	var calls atomic.Int32
+	got := make(chan *Event, 2)
+	_, err = bus.Subscribe(ctx, "upload.completed", func(_ context.Context, e *Event) error {
+		if calls.Add(1) == 1 {
+			return errors.New("transient")
+		}
+		got <- e
+		return nil
+	}, ForTenant("acme"))
+	require.NoError(t, err)
+
+	select {
+	case e := <-got:
+		assert.Equal(t, "e1", e.ID)
+		assert.Equal(t, "acme", e.Tenant)
+		assert.Equal(t, "upload.completed", e.Type)
+	case <-time.After(5 * time.Second):
+		t.Fatal("event was not redelivered")
+	}
+	require.NoError(t, bus.Close())
+
+	committed, rewinds, deleted := proxy.state()
+	assert.Equal(t, int64(0), committed)
+	assert.Equal(t, 1, rewinds)
+	assert.True(t, deleted, "closing removes the consumer from its group")
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestKafkaEventBus_Errors(t *testing.T) {
+	_, err := NewKafkaEventBus(KafkaConfig{}, zap.NewNop())
+	assert.Error(t, err)
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	bus, err := NewKafkaEventBus(KafkaConfig{RESTURL: down.URL}, zap.NewNop())
+	require.NoError(t, err)
+
+	assert.ErrorContains(t, bus.Publish(context.Background(), &Event{Type: "upload.completed"}), "503")
+	_, err = bus.Subscribe(context.Background(), "upload.completed", func(context.Context, *Event) error { return nil })
+	assert.Error(t, err)
+	assert.ErrorIs(t, bus.Publish(context.Background(), &Event{Type: "upload.completed", Tenant: "a/b"}), ErrInvalidTenant)
+}
@@ -69,12 +69,19 @@ func (b *NATSEventBus) Publish(ctx context.Context, event *Event) error {
 		return fmt.Errorf("NATS connection not available")
 	}
 
-	data, err := json.Marshal(event)
+	tenant, eventType, err := tenantNamespace(DefaultTenantTopicPrefix).scope(event.Tenant, event.Type)
+	if err != nil {
+		return err
+	}
+	scoped := *event
+	scoped.Tenant, scoped.Type = tenant, eventType
+
+	data, err := json.Marshal(&scoped)
 	if err != nil {
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
-	subject := fmt.Sprintf("streamgate.%s", event.Type)
+	subject := natsSubject(tenant, eventType)
 	if err := b.conn.Publish(subject, data); err != nil {
 		b.logger.Error("Failed to publish event",
 			zap.String("type", event.Type),
@@ -88,14 +95,32 @@ func (b *NATSEventBus) Publish(ctx context.Context, event *Event) error {
 	return nil
 }
 
-// Subscribe subscribes to events via NATS
-func (b *NATSEventBus) Subscribe(ctx context.Context, eventType string, handler EventHandler) (string, error) {
+// Subscribe subscribes to events via NATS. NATS invokes a subscription's
+// callback serially, which already satisfies any ordering key; ForTenant
+// and WithConcurrency are applied. WithConcurrency runs up to n handlers
+// at once for subscriptions without an ordering key; those with one stay
+// serial, which is within any cap.
+func (b *NATSEventBus) Subscribe(ctx context.Context, eventType string, handler EventHandler, opts ...SubscribeOption) (string, error) {
 	if b.conn == nil || !b.conn.IsConnected() {
 		b.logger.Error("NATS connection not available")
 		return "", fmt.Errorf("NATS connection not available")
 	}
 
-	subject := fmt.Sprintf("streamgate.%s", eventType)
+	var o subscribeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	tenant, eventType, err := tenantNamespace(DefaultTenantTopicPrefix).scope(o.tenant, eventType)
+	if err != nil {
+		return "", err
+	}
+
+	subject := natsSubject(tenant, eventType)
+
+	var sem chan struct{}
+	if o.concurrency > 1 && o.orderingKey == nil {
+		sem = make(chan struct{}, o.concurrency)
+	}
 
 	sub, err := b.conn.Subscribe(subject, func(msg *nats.Msg) {
 		var event Event
@@ -103,12 +128,26 @@ func (b *NATSEventBus) Subscribe(ctx context.Context, eventType string, handler
 			b.logger.Error("Failed to unmarshal event", zap.Error(err))
 			return
 		}
+		// The subject already scopes delivery; drop anything whose payload
+		// claims another tenant rather than trust a misbehaving publisher.
+		if event.Tenant != tenant {
+			b.logger.Warn("Dropping event with mismatched tenant",
+				zap.String("subject", msg.Subject),
+				zap.String("tenant", event.Tenant))
+			return
+		}
 
-		if err := handler(ctx, &event); err != nil {
-			b.logger.Error("Error handling event",
-				zap.Error(err),
-				zap.String("type", event.Type))
+		if sem == nil {
+			b.handle(ctx, handler, &event)
+			return
 		}
+		// Blocking here while n handlers run holds back further messages
+		// in the subscription's pending buffer.
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem }()
+			b.handle(ctx, handler, &event)
+		}()
 	})
 
 	if err != nil {
@@ -131,6 +170,23 @@ func (b *NATSEventBus) Subscribe(ctx context.Context, eventType string, handler
 	return subID, nil
 }
 
+// handle runs handler for event, logging its error.
+func (b *NATSEventBus) handle(ctx context.Context, handler EventHandler, event *Event) {
+	if err := handler(ctx, event); err != nil {
+		b.logger.Error("Error handling event",
+			zap.Error(err),
+			zap.String("type", event.Type))
+	}
+}
+
+// natsSubject maps a tenant scope and event type to a NATS subject.
+func natsSubject(tenant, eventType string) string {
+	if tenant == "" {
+		return "streamgate." + eventType
+	}
+	return "streamgate." + TenantTopic(tenant, eventType)
+}
+
 // Unsubscribe unsubscribes from events
 func (b *NATSEventBus) Unsubscribe(ctx context.Context, subscriptionID string) error {
 	b.mu.Lock()
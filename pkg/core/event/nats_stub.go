@@ -20,7 +20,7 @@ func (b *NATSEventBus) Publish(ctx context.Context, event *Event) error {
 	return fmt.Errorf("NATS not available")
 }
 
-func (b *NATSEventBus) Subscribe(ctx context.Context, eventType string, handler EventHandler) (string, error) {
+func (b *NATSEventBus) Subscribe(ctx context.Context, eventType string, handler EventHandler, opts ...SubscribeOption) (string, error) {
 	return "", fmt.Errorf("NATS not available")
 }
 
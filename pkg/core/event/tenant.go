@@ -0,0 +1,83 @@
+package event
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultTenantTopicPrefix is the first segment of tenant-scoped topics,
+// e.g. "tenant/acme/transcode.task.completed".
+const DefaultTenantTopicPrefix = "tenant"
+
+var (
+	// ErrInvalidTenant is returned for tenant IDs that are empty or contain
+	// characters that would make the topic ambiguous.
+	ErrInvalidTenant = errors.New("invalid tenant id")
+	// ErrCrossTenant is returned when an event's Tenant disagrees with the
+	// tenant named in its topic, or a subscription names two tenants.
+	ErrCrossTenant = errors.New("event topic belongs to a different tenant")
+)
+
+// TenantTopic returns the tenant-scoped topic for eventType under the
+// default prefix.
+func TenantTopic(tenantID, eventType string) string {
+	return DefaultTenantTopicPrefix + "/" + tenantID + "/" + eventType
+}
+
+// ValidateTenantID checks that id can be embedded in a topic.
+func ValidateTenantID(id string) error {
+	if id == "" || strings.ContainsAny(id, "/.*> \t\r\n") {
+		return fmt.Errorf("%w: %q", ErrInvalidTenant, id)
+	}
+	return nil
+}
+
+// ForTenant scopes a subscription to one tenant. It is equivalent to
+// subscribing to the tenant's topic for the event type.
+func ForTenant(tenantID string) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.tenant = tenantID
+	}
+}
+
+// tenantNamespace splits and builds tenant-scoped topics for one prefix.
+type tenantNamespace string
+
+// split parses "<prefix>/<tenant>/<type>". ok is false for unscoped topics.
+func (ns tenantNamespace) split(topic string) (tenantID, eventType string, ok bool, err error) {
+	rest, found := strings.CutPrefix(topic, string(ns)+"/")
+	if !found {
+		return "", topic, false, nil
+	}
+	tenantID, eventType, found = strings.Cut(rest, "/")
+	if !found || eventType == "" {
+		return "", "", false, fmt.Errorf("malformed tenant topic %q", topic)
+	}
+	if err := ValidateTenantID(tenantID); err != nil {
+		return "", "", false, err
+	}
+	return tenantID, eventType, true, nil
+}
+
+// scope resolves the tenant and bare event type an event or subscription
+// refers to, from an explicit tenant and/or a tenant-scoped topic. Both may
+// be given only if they agree.
+func (ns tenantNamespace) scope(tenantID, topic string) (string, string, error) {
+	topicTenant, eventType, scoped, err := ns.split(topic)
+	if err != nil {
+		return "", "", err
+	}
+	if scoped {
+		if tenantID != "" && tenantID != topicTenant {
+			return "", "", fmt.Errorf("%w: %q in topic, %q on event", ErrCrossTenant, topicTenant, tenantID)
+		}
+		return topicTenant, eventType, nil
+	}
+	if tenantID != "" {
+		if err := ValidateTenantID(tenantID); err != nil {
+			return "", "", err
+		}
+	}
+	return tenantID, eventType, nil
+}
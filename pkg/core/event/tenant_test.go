@@ -0,0 +1,152 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const completedType = "transcode.task.completed"
+
+type recordingHandler struct {
+	mu     sync.Mutex
+	events []*Event
+}
+
+func (r *recordingHandler) handle(_ context.Context, e *Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+	return nil
+}
+
+func (r *recordingHandler) received() []*Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*Event(nil), r.events...)
+}
+
+func TestTenantTopic(t *testing.T) {
+	assert.Equal(t, "tenant/acme/transcode.task.completed", TenantTopic("acme", completedType))
+
+	ns := tenantNamespace(DefaultTenantTopicPrefix)
+	tenant, eventType, err := ns.scope("", TenantTopic("acme", completedType))
+	require.NoError(t, err)
+	assert.Equal(t, "acme", tenant)
+	assert.Equal(t, completedType, eventType)
+
+	tenant, eventType, err = ns.scope("", completedType)
+	require.NoError(t, err)
+	assert.Empty(t, tenant)
+	assert.Equal(t, completedType, eventType)
+
+	_, _, err = ns.scope("globex", TenantTopic("acme", completedType))
+	assert.True(t, errors.Is(err, ErrCrossTenant))
+
+	for _, bad := range []string{"tenant/acme", "tenant/acme/", "tenant/a.b/x", "tenant//x"} {
+		_, _, err = ns.scope("", bad)
+		assert.Error(t, err, bad)
+	}
+	_, _, err = ns.scope("a/b", completedType)
+	assert.True(t, errors.Is(err, ErrInvalidTenant))
+}
+
+func TestMemoryEventBus_TenantIsolation(t *testing.T) {
+	publishers := map[string]func(*MemoryEventBus, *Event) error{
+		"async": func(b *MemoryEventBus, e *Event) error { return b.Publish(context.Background(), e) },
+		"sync":  func(b *MemoryEventBus, e *Event) error { return b.PublishSync(context.Background(), e) },
+	}
+
+	for name, publish := range publishers {
+		t.Run(name, func(t *testing.T) {
+			bus, err := NewMemoryEventBus()
+			require.NoError(t, err)
+			ctx := context.Background()
+
+			var tenantA, tenantB, unscoped recordingHandler
+			_, err = bus.Subscribe(ctx, completedType, tenantA.handle, ForTenant("a"))
+			require.NoError(t, err)
+			_, err = bus.Subscribe(ctx, TenantTopic("b", completedType), tenantB.handle)
+			require.NoError(t, err)
+			_, err = bus.Subscribe(ctx, completedType, unscoped.handle)
+			require.NoError(t, err)
+
+			// Tenant B publishes both ways: by field and by topic.
+			require.NoError(t, publish(bus, &Event{Type: completedType, Tenant: "b", Data: map[string]interface{}{"n": 1}}))
+			require.NoError(t, publish(bus, &Event{Type: TenantTopic("b", completedType), Data: map[string]interface{}{"n": 2}}))
+			require.NoError(t, publish(bus, &Event{Type: completedType, Data: map[string]interface{}{"n": 3}}))
+			require.NoError(t, bus.Close())
+
+			got := tenantB.received()
+			require.Len(t, got, 2)
+			for _, e := range got {
+				assert.Equal(t, "b", e.Tenant)
+				assert.Equal(t, completedType, e.Type, "handlers see the bare event type")
+			}
+			assert.Empty(t, tenantA.received(), "tenant A must never see tenant B's events")
+			require.Len(t, unscoped.received(), 1, "unscoped subscribers only see unscoped events")
+			assert.Equal(t, 3, unscoped.received()[0].Data["n"])
+		})
+	}
+}
+
+func TestMemoryEventBus_TenantMismatchRejected(t *testing.T) {
+	bus, err := NewMemoryEventBus()
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	var tenantA recordingHandler
+	_, err = bus.Subscribe(ctx, completedType, tenantA.handle, ForTenant("a"))
+	require.NoError(t, err)
+
+	// An event claiming tenant A but addressed to B's topic is refused
+	// rather than delivered to either tenant.
+	err = bus.Publish(ctx, &Event{Type: TenantTopic("b", completedType), Tenant: "a"})
+	assert.True(t, errors.Is(err, ErrCrossTenant))
+	err = bus.PublishSync(ctx, &Event{Type: TenantTopic("b", completedType), Tenant: "a"})
+	assert.True(t, errors.Is(err, ErrCrossTenant))
+
+	_, err = bus.Subscribe(ctx, TenantTopic("b", completedType), tenantA.handle, ForTenant("a"))
+	assert.True(t, errors.Is(err, ErrCrossTenant))
+	_, err = bus.Subscribe(ctx, completedType, tenantA.handle, ForTenant(""))
+	assert.NoError(t, err, "empty tenant leaves the subscription unscoped")
+
+	require.NoError(t, bus.Close())
+	assert.Empty(t, tenantA.received())
+}
+
+func TestMemoryEventBus_TenantTopicPrefix(t *testing.T) {
+	bus, err := NewMemoryEventBus(WithTenantTopicPrefix("org"))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	received := make(chan *Event, 1)
+	_, err = bus.Subscribe(ctx, "org/acme/"+completedType, func(_ context.Context, e *Event) error {
+		received <- e
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, bus.Publish(ctx, &Event{Type: completedType, Tenant: "acme"}))
+	select {
+	case e := <-received:
+		assert.Equal(t, "acme", e.Tenant)
+	case <-time.After(time.Second):
+		t.Fatal("event not delivered under custom prefix")
+	}
+}
+
+func TestMemoryEventBus_TenantPublishDoesNotMutateEvent(t *testing.T) {
+	bus, err := NewMemoryEventBus()
+	require.NoError(t, err)
+
+	e := &Event{Type: TenantTopic("a", completedType)}
+	require.NoError(t, bus.PublishSync(context.Background(), e))
+	assert.Equal(t, TenantTopic("a", completedType), e.Type)
+	assert.Empty(t, e.Tenant)
+}
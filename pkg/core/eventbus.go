@@ -0,0 +1,54 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/rtcdance/streamgate/pkg/core/event"
+
+	"go.uber.org/zap"
+)
+
+// newEventBus builds the event bus selected by cfg.EventBus.Driver,
+// falling back to the mode default when no driver is set.
+func newEventBus(cfg *config.Config, logger *zap.Logger) (event.EventBus, error) {
+	driver := cfg.EventBus.Driver
+	if driver == "" {
+		driver = "nats"
+		if cfg.Mode == "monolith" || cfg.Mode == "monolithic" {
+			driver = "memory"
+		}
+	}
+
+	switch driver {
+	case "memory":
+		return event.NewMemoryEventBus()
+	case "nats":
+		return event.NewNATSEventBus(cfg.NATS.URL, logger)
+	case "jetstream":
+		var ackWait time.Duration
+		if s := cfg.EventBus.JetStream.AckWait; s != "" {
+			d, err := time.ParseDuration(s)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("invalid event_bus.jetstream.ack_wait %q", s)
+			}
+			ackWait = d
+		}
+		return event.NewJetStreamEventBus(event.JetStreamConfig{
+			URL:        cfg.NATS.URL,
+			Stream:     cfg.EventBus.JetStream.Stream,
+			Group:      cfg.EventBus.Group,
+			AckWait:    ackWait,
+			MaxDeliver: cfg.EventBus.JetStream.MaxDeliver,
+		}, logger)
+	case "kafka":
+		return event.NewKafkaEventBus(event.KafkaConfig{
+			RESTURL:     cfg.EventBus.Kafka.RESTURL,
+			Group:       cfg.EventBus.Group,
+			TopicPrefix: cfg.EventBus.Kafka.TopicPrefix,
+		}, logger)
+	default:
+		return nil, fmt.Errorf("unknown event_bus.driver %q", driver)
+	}
+}
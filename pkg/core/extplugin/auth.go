@@ -0,0 +1,53 @@
+package extplugin
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// tokenKey carries the per-launch token in a plugin's environment.
+	tokenKey = "STREAMGATE_PLUGIN_TOKEN"
+	// tokenMetadata is the request metadata the host sends the token in.
+	tokenMetadata = "x-streamgate-plugin-token"
+)
+
+// newToken returns a random token for one plugin process.
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate plugin token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// tokenCredentials sends the token with every call to the plugin. The
+// connection is a Unix socket, so no transport security is required.
+type tokenCredentials string
+
+func (t tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{tokenMetadata: string(t)}, nil
+}
+
+func (t tokenCredentials) RequireTransportSecurity() bool { return false }
+
+// requireToken rejects calls that do not carry token, so only the host
+// that started the plugin can drive it.
+func requireToken(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		got := md.Get(tokenMetadata)
+		if len(got) != 1 || subtle.ConstantTimeCompare([]byte(got[0]), []byte(token)) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "invalid plugin token")
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,257 @@
+package extplugin
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core"
+	"github.com/rtcdance/streamgate/pkg/core/config"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Client is a plugin binary run as a separate process. It implements
+// core.Plugin, so it is registered with the kernel like a built-in plugin;
+// if the process crashes, its health check fails and the host keeps
+// running. Init after Stop or a crash starts a new process, and Reload
+// swaps in a new binary while the plugin runs.
+type Client struct {
+	cfg    config.ExternalPluginConfig
+	trust  *Trust
+	logger *zap.Logger
+	name   string
+	deps   []string
+
+	// lifecycle serialises Init, Stop and Reload, which may replace proc.
+	lifecycle sync.Mutex
+	tried     time.Time // modification time of the binary last reloaded from
+
+	mu      sync.RWMutex
+	proc    *process
+	version string
+}
+
+var (
+	_ core.StandalonePlugin = (*Client)(nil)
+	_ core.ResourceReporter = (*Client)(nil)
+)
+
+// Open starts the plugin binary cfg.Path, negotiates the protocol version
+// and reads the plugin's name, version and dependencies. The process runs
+// until Stop or Kill. The binary, and every binary started for the plugin
+// later, must pass trust.Verify.
+func Open(ctx context.Context, cfg config.ExternalPluginConfig, trust *Trust, logger *zap.Logger) (*Client, error) {
+	proc, desc, err := startProcess(ctx, cfg, trust, logger)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		cfg:     cfg,
+		trust:   trust,
+		logger:  logger,
+		name:    desc.name,
+		deps:    desc.deps,
+		tried:   proc.modTime,
+		proc:    proc,
+		version: desc.version,
+	}, nil
+}
+
+// Register opens every plugin in the kernel config's plugins.external and
+// registers it with kernel, running only binaries signed by
+// plugins.trusted_keys or listed in plugins.allow_unsigned. On failure the
+// plugins opened so far are killed.
+func Register(ctx context.Context, kernel *core.Microkernel, logger *zap.Logger) error {
+	plugins := kernel.GetConfig().Plugins
+	trust, err := NewTrust(plugins)
+	if err != nil {
+		return err
+	}
+	var opened []*Client
+	for _, cfg := range plugins.External {
+		if trust.allowsUnsigned(cfg.Path) {
+			logger.Warn("Running plugin without verifying its signature", zap.String("plugin_path", cfg.Path))
+		}
+		c, err := Open(ctx, cfg, trust, logger)
+		if err == nil {
+			err = kernel.RegisterPlugin(c)
+			if err != nil {
+				_ = c.Kill(ctx)
+			}
+		}
+		if err != nil {
+			for _, o := range opened {
+				_ = o.Kill(ctx)
+			}
+			return fmt.Errorf("failed to load external plugin %s: %w", cfg.Path, err)
+		}
+		opened = append(opened, c)
+	}
+	return nil
+}
+
+func (c *Client) Name() string        { return c.name }
+func (c *Client) DependsOn() []string { return c.deps }
+
+// Version returns the version of the running binary, which changes on
+// Reload.
+func (c *Client) Version() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.version
+}
+
+// Standalone reports true: the plugin is its own process, not a server the
+// api-gateway takes over in monolith mode.
+func (c *Client) Standalone() bool { return true }
+
+// Init passes the plugin its settings, first starting a new process if
+// the last one has exited. The kernel itself stays in the host process.
+func (c *Client) Init(ctx context.Context, _ *core.Microkernel) error {
+	c.lifecycle.Lock()
+	defer c.lifecycle.Unlock()
+
+	proc := c.current()
+	if proc.exitError() != nil {
+		next, version, err := c.spawn(ctx)
+		if err != nil {
+			return err
+		}
+		c.swap(next, version)
+		c.tried = next.modTime
+		proc = next
+	}
+	return proc.init(ctx, c.cfg.Settings)
+}
+
+func (c *Client) Start(ctx context.Context) error {
+	return c.current().call(ctx, "Start", &emptypb.Empty{}, &emptypb.Empty{})
+}
+
+// Stop asks the plugin to stop, then ends its process.
+func (c *Client) Stop(ctx context.Context) error {
+	c.lifecycle.Lock()
+	defer c.lifecycle.Unlock()
+	return c.current().stop(ctx)
+}
+
+func (c *Client) Health(ctx context.Context) error {
+	return c.current().call(ctx, "Health", &emptypb.Empty{}, &emptypb.Empty{})
+}
+
+// ResourceUsage returns what the plugin's current process uses. Memory
+// and CPU time are measured by the host from the process; only the
+// goroutine count comes from the plugin, and a plugin that cannot answer
+// for it is reported as an error.
+func (c *Client) ResourceUsage(ctx context.Context) (core.ResourceUsage, error) {
+	proc := c.current()
+	usage, err := hostUsage(proc.cmd.Process.Pid)
+	if err != nil {
+		return core.ResourceUsage{}, fmt.Errorf("plugin %s: %w", c.name, err)
+	}
+	resp := &structpb.Struct{}
+	if err := proc.call(ctx, "Usage", &emptypb.Empty{}, resp); err != nil {
+		return usage, err
+	}
+	usage.Goroutines = int(resp.GetFields()["goroutines"].GetNumberValue())
+	return usage, nil
+}
+
+// Kill closes the connection and the plugin's stdin, which makes Serve
+// return, and kills the process if it has not exited within killGrace or
+// by the time ctx is done.
+func (c *Client) Kill(ctx context.Context) error {
+	return c.current().kill(ctx)
+}
+
+// Reload starts the plugin's binary again, which may have been replaced,
+// and brings the new process up beside the running one. Only once the new
+// process is initialised, started and healthy does it take over; the old
+// one then finishes the calls in flight and is stopped. If the new process
+// fails, it is killed and the old one keeps running. The two run at the
+// same time, so a plugin must not hold anything only one process can have,
+// such as a fixed port.
+func (c *Client) Reload(ctx context.Context) error {
+	c.lifecycle.Lock()
+	defer c.lifecycle.Unlock()
+
+	old := c.current()
+	if old.exitError() != nil {
+		return fmt.Errorf("plugin %s is not running", c.name)
+	}
+	// Whatever the outcome, this binary is not tried again until it changes.
+	c.tried = artifactModTime(c.cfg, old.binary)
+	next, version, err := c.spawn(ctx)
+	if err == nil {
+		err = next.init(ctx, c.cfg.Settings)
+	}
+	if err == nil {
+		err = next.call(ctx, "Start", &emptypb.Empty{}, &emptypb.Empty{})
+	}
+	if err == nil {
+		err = next.call(ctx, "Health", &emptypb.Empty{}, &emptypb.Empty{})
+	}
+	if err != nil {
+		if next != nil {
+			_ = next.stop(ctx)
+		}
+		return fmt.Errorf("plugin %s: new version failed, keeping the running one: %w", c.name, err)
+	}
+
+	oldVersion := c.Version()
+	c.swap(next, version)
+	c.logger.Info("Plugin reloaded",
+		zap.String("plugin", c.name),
+		zap.String("old_version", oldVersion),
+		zap.String("version", version))
+	if err := old.drain(ctx); err != nil {
+		c.logger.Warn("Failed to stop the old plugin process", zap.String("plugin", c.name), zap.Error(err))
+	}
+	return nil
+}
+
+// spawn starts a new process for the plugin, which must still have the
+// same name and dependencies: the kernel resolved its start order from
+// them.
+func (c *Client) spawn(ctx context.Context) (*process, string, error) {
+	proc, desc, err := startProcess(ctx, c.cfg, c.trust, c.logger)
+	if err != nil {
+		return nil, "", err
+	}
+	if desc.name != c.name || !slices.Equal(desc.deps, c.deps) {
+		_ = proc.kill(ctx)
+		return nil, "", fmt.Errorf("plugin %s: binary %s now describes plugin %s depending on %v, want %s depending on %v",
+			c.name, c.cfg.Path, desc.name, desc.deps, c.name, c.deps)
+	}
+	return proc, desc.version, nil
+}
+
+// changed reports whether the plugin's binary or its signature was
+// modified since it was last started or reloaded from, at least settle
+// ago, so that a binary still being written is left alone.
+func (c *Client) changed(settle time.Duration) bool {
+	mod := artifactModTime(c.cfg, c.current().binary)
+	if mod.IsZero() {
+		return false
+	}
+	c.lifecycle.Lock()
+	defer c.lifecycle.Unlock()
+	return !mod.Equal(c.tried) && time.Since(mod) >= settle
+}
+
+func (c *Client) current() *process {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.proc
+}
+
+func (c *Client) swap(proc *process, version string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.proc, c.version = proc, version
+}
@@ -0,0 +1,195 @@
+// Package extplugin runs plugins as separate binaries that the kernel talks
+// to over gRPC, in the manner of HashiCorp's go-plugin. Unlike Go's
+// plugin.Open, a plugin need not be built with the host's toolchain or
+// dependency versions, it works on every platform, and a crash takes down
+// only the plugin process.
+//
+// The host starts the binary with MagicCookieKey set, the protocol
+// versions it speaks in STREAMGATE_PLUGIN_PROTOCOL_VERSIONS and a random
+// token in STREAMGATE_PLUGIN_TOKEN. The plugin picks the highest version
+// both sides speak, listens on a Unix socket only its user can open and
+// prints one handshake line to stdout:
+//
+//	CORE-PROTOCOL-VERSION|APP-PROTOCOL-VERSION|NETWORK|ADDRESS|grpc
+//
+// e.g. "1|1|unix|/tmp/streamgate-plugin-123/plugin.sock|grpc". The host
+// then dials ADDRESS and sends the token with every call; the plugin
+// rejects calls without it. The plugin exits when the host closes its
+// stdin or signals it.
+//
+// A plugin binary's main is:
+//
+//	func main() { extplugin.Serve(&myPlugin{}) }
+package extplugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"google.golang.org/grpc"
+)
+
+const (
+	// MagicCookieKey and MagicCookieValue are set in a plugin's
+	// environment by the host. Serve refuses to run without them, so a
+	// plugin binary started by hand explains itself instead of waiting
+	// for a host.
+	MagicCookieKey   = "STREAMGATE_PLUGIN_MAGIC_COOKIE"
+	MagicCookieValue = "b7e0c2a94f1d4e3a8c6b5d2f0a9e1c47"
+
+	// protocolVersionsKey lists the app protocol versions the host speaks,
+	// comma separated.
+	protocolVersionsKey = "STREAMGATE_PLUGIN_PROTOCOL_VERSIONS"
+
+	// coreProtocolVersion is the version of the handshake itself.
+	coreProtocolVersion = 1
+
+	// ProtocolVersion is the newest version of the gRPC service in rpc.go.
+	// Bump it on incompatible changes, keeping the old version in
+	// supportedVersions for as long as old plugins must keep working.
+	ProtocolVersion = 1
+)
+
+// supportedVersions are the app protocol versions this build speaks.
+var supportedVersions = []int{ProtocolVersion}
+
+// Plugin is implemented by out-of-process plugins and passed to Serve.
+type Plugin interface {
+	Name() string
+	Version() string
+	// DependsOn names the plugins, built-in or external, that must be
+	// started first.
+	DependsOn() []string
+	// Init receives the plugin's plugins.external[].settings.
+	Init(ctx context.Context, settings map[string]string) error
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+	Health(ctx context.Context) error
+}
+
+// Serve runs p as a plugin process and exits when the host is done with
+// it. It is meant to be all of a plugin binary's main.
+func Serve(p Plugin) {
+	os.Exit(serve(p, os.Stdin, os.Stdout, os.Stderr))
+}
+
+func serve(p Plugin, stdin io.Reader, stdout, stderr io.Writer) int {
+	if os.Getenv(MagicCookieKey) != MagicCookieValue {
+		fmt.Fprintln(stderr, "This binary is a StreamGate plugin. It is started by StreamGate, not run directly.")
+		return 1
+	}
+	version, err := negotiate(os.Getenv(protocolVersionsKey))
+	if err != nil {
+		fmt.Fprintf(stderr, "plugin %s: %v\n", p.Name(), err)
+		return 1
+	}
+
+	token := os.Getenv(tokenKey)
+	if token == "" {
+		fmt.Fprintf(stderr, "plugin %s: started without %s\n", p.Name(), tokenKey)
+		return 1
+	}
+	// Processes the plugin starts have no business with it.
+	_ = os.Unsetenv(tokenKey)
+
+	lis, cleanup, err := listen()
+	if err != nil {
+		fmt.Fprintf(stderr, "plugin %s: failed to listen: %v\n", p.Name(), err)
+		return 1
+	}
+	defer cleanup()
+	srv := grpc.NewServer(grpc.UnaryInterceptor(requireToken(token)))
+	srv.RegisterService(&serviceDesc, p)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		// The host closing stdin, or dying, ends the plugin too.
+		_, _ = io.Copy(io.Discard, stdin)
+		stop()
+	}()
+	go func() {
+		<-ctx.Done()
+		srv.GracefulStop()
+	}()
+
+	fmt.Fprintf(stdout, "%d|%d|unix|%s|grpc\n", coreProtocolVersion, version, lis.Addr())
+	if err := srv.Serve(lis); err != nil {
+		fmt.Fprintf(stderr, "plugin %s: %v\n", p.Name(), err)
+		return 1
+	}
+	return 0
+}
+
+// listen listens on a socket in a new directory only the plugin's user
+// can enter, and returns the function that removes both.
+func listen() (net.Listener, func(), error) {
+	dir, err := os.MkdirTemp("", "streamgate-plugin-")
+	if err != nil {
+		return nil, nil, err
+	}
+	path := filepath.Join(dir, "plugin.sock")
+	lis, err := net.Listen("unix", path)
+	if err == nil {
+		err = os.Chmod(path, 0o600)
+	}
+	if err != nil {
+		if lis != nil {
+			_ = lis.Close()
+		}
+		_ = os.RemoveAll(dir)
+		return nil, nil, err
+	}
+	return lis, func() { _ = os.RemoveAll(dir) }, nil
+}
+
+// negotiate returns the highest version in hostVersions, a comma-separated
+// list, that this build supports.
+func negotiate(hostVersions string) (int, error) {
+	best := 0
+	for _, field := range strings.Split(hostVersions, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			continue
+		}
+		if v > best && slices.Contains(supportedVersions, v) {
+			best = v
+		}
+	}
+	if best == 0 {
+		return 0, fmt.Errorf("no common protocol version: host speaks %q, plugin speaks %v", hostVersions, supportedVersions)
+	}
+	return best, nil
+}
+
+// parseHandshake parses a plugin's handshake line into the address to
+// dial and the negotiated protocol version.
+func parseHandshake(line string) (target string, version int, err error) {
+	parts := strings.Split(strings.TrimSpace(line), "|")
+	if len(parts) != 5 {
+		return "", 0, fmt.Errorf("invalid handshake %q: want CORE|APP|NETWORK|ADDRESS|grpc", line)
+	}
+	if parts[0] != strconv.Itoa(coreProtocolVersion) {
+		return "", 0, fmt.Errorf("unsupported core protocol version %s, want %d", parts[0], coreProtocolVersion)
+	}
+	version, err = strconv.Atoi(parts[1])
+	if err != nil || !slices.Contains(supportedVersions, version) {
+		return "", 0, fmt.Errorf("plugin chose protocol version %s, host speaks %v", parts[1], supportedVersions)
+	}
+	if parts[4] != "grpc" {
+		return "", 0, fmt.Errorf("unsupported plugin protocol %q, want grpc", parts[4])
+	}
+	if parts[2] != "unix" {
+		return "", 0, fmt.Errorf("unsupported network %q, want unix", parts[2])
+	}
+	return "unix:" + parts[3], version, nil
+}
@@ -0,0 +1,373 @@
+package extplugin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core"
+	"github.com/rtcdance/streamgate/pkg/core/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// testPluginEnv makes the test binary serve testPlugin instead of running
+// the tests, so it can stand in for a plugin binary.
+const testPluginEnv = "EXTPLUGIN_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(testPluginEnv) != "" {
+		var deps []string
+		if d := os.Getenv("EXTPLUGIN_TEST_DEPS"); d != "" {
+			deps = strings.Split(d, ",")
+		}
+		version := "1.2.0"
+		if v := os.Getenv("EXTPLUGIN_TEST_VERSION"); v != "" {
+			version = v
+		}
+		Serve(&testPlugin{version: version, deps: deps, failStart: os.Getenv("EXTPLUGIN_TEST_FAIL_START") != ""})
+	}
+	os.Exit(m.Run())
+}
+
+type testPlugin struct {
+	version   string
+	deps      []string
+	failStart bool
+	settings  map[string]string
+	started   bool
+}
+
+func (p *testPlugin) Name() string        { return "echo" }
+func (p *testPlugin) Version() string     { return p.version }
+func (p *testPlugin) DependsOn() []string { return p.deps }
+
+func (p *testPlugin) Init(_ context.Context, settings map[string]string) error {
+	if settings["fail"] == "init" {
+		return errors.New("bad settings")
+	}
+	p.settings = settings
+	return nil
+}
+
+func (p *testPlugin) Start(context.Context) error {
+	if p.settings["fail"] == "crash" {
+		os.Exit(3)
+	}
+	if p.failStart {
+		return errors.New("broken build")
+	}
+	p.started = true
+	return nil
+}
+
+func (p *testPlugin) Stop(context.Context) error {
+	p.started = false
+	return nil
+}
+
+func (p *testPlugin) Health(context.Context) error {
+	if !p.started {
+		return errors.New("not started")
+	}
+	return nil
+}
+
+// allowAll lets the tests run the unsigned test binary.
+var allowAll = &Trust{allowUnsigned: []string{"*"}}
+
+func openTestPlugin(t *testing.T, settings map[string]string) *Client {
+	t.Helper()
+	return openTestPluginAt(t, os.Args[0], settings)
+}
+
+func openTestPluginAt(t *testing.T, path string, settings map[string]string) *Client {
+	t.Helper()
+	t.Setenv(testPluginEnv, "1")
+	c, err := Open(context.Background(), config.ExternalPluginConfig{Path: path, Settings: settings}, allowAll, zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Kill(context.Background()) })
+	return c
+}
+
+func TestClient_Lifecycle(t *testing.T) {
+	t.Setenv("EXTPLUGIN_TEST_DEPS", "auth,cache")
+	c := openTestPlugin(t, map[string]string{"greeting": "hi"})
+	ctx := context.Background()
+
+	assert.Equal(t, "echo", c.Name())
+	assert.Equal(t, "1.2.0", c.Version())
+	assert.Equal(t, []string{"auth", "cache"}, c.DependsOn())
+
+	require.NoError(t, c.Init(ctx, nil))
+	assert.ErrorContains(t, c.Health(ctx), "plugin echo: Health: not started")
+	require.NoError(t, c.Start(ctx))
+	require.NoError(t, c.Health(ctx))
+
+	usage, err := c.ResourceUsage(ctx)
+	require.NoError(t, err)
+	assert.Positive(t, usage.Goroutines)
+	assert.Positive(t, usage.MemoryBytes)
+	host, err := hostUsage(c.current().cmd.Process.Pid)
+	require.NoError(t, err)
+	assert.InDelta(t, host.MemoryBytes, usage.MemoryBytes, 64<<20, "measured by the host")
+
+	require.NoError(t, c.Stop(ctx))
+	assert.ErrorContains(t, c.Health(ctx), "plugin echo: process exited")
+	_, err = c.ResourceUsage(ctx)
+	assert.Error(t, err, "an exited process has no usage")
+
+	// Init after Stop starts a new process.
+	require.NoError(t, c.Init(ctx, nil))
+	require.NoError(t, c.Start(ctx))
+	require.NoError(t, c.Health(ctx))
+}
+
+func TestClient_RequiresToken(t *testing.T) {
+	c := openTestPlugin(t, nil)
+	target := strings.TrimPrefix(c.current().conn.Target(), "unix:")
+	info, err := os.Stat(target)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	for name, opts := range map[string][]grpc.DialOption{
+		"no token":    nil,
+		"wrong token": {grpc.WithPerRPCCredentials(tokenCredentials("guess"))},
+	} {
+		t.Run(name, func(t *testing.T) {
+			conn, err := grpc.NewClient("unix:"+target, append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))...)
+			require.NoError(t, err)
+			defer conn.Close()
+			err = conn.Invoke(context.Background(), "/"+serviceName+"/Describe", &emptypb.Empty{}, &structpb.Struct{})
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		})
+	}
+	assert.NoError(t, c.current().call(context.Background(), "Describe", &emptypb.Empty{}, &structpb.Struct{}), "the host sends the token")
+}
+
+// copyTestBinary copies the test binary to a temporary directory, so a
+// test can replace it.
+func copyTestBinary(t *testing.T) string {
+	t.Helper()
+	data, err := os.ReadFile(os.Args[0])
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "echo-plugin")
+	require.NoError(t, os.WriteFile(path, data, 0o755))
+	return path
+}
+
+func pid(c *Client) int { return c.current().cmd.Process.Pid }
+
+func TestClient_Reload(t *testing.T) {
+	c := openTestPluginAt(t, copyTestBinary(t), nil)
+	ctx := context.Background()
+	require.NoError(t, c.Init(ctx, nil))
+	require.NoError(t, c.Start(ctx))
+	old := c.current()
+
+	t.Setenv("EXTPLUGIN_TEST_VERSION", "1.3.0")
+	require.NoError(t, c.Reload(ctx))
+	assert.Equal(t, "1.3.0", c.Version())
+	assert.NotEqual(t, old.cmd.Process.Pid, pid(c))
+	require.NoError(t, c.Health(ctx))
+	<-old.exited
+
+	tests := []struct {
+		name string
+		env  string
+		want string
+	}{
+		{"unhealthy", "EXTPLUGIN_TEST_FAIL_START", "Start: broken build"},
+		{"dependencies changed", "EXTPLUGIN_TEST_DEPS", "now describes plugin echo depending on [x]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.env, "x")
+			running := pid(c)
+			err := c.Reload(ctx)
+			assert.ErrorContains(t, err, "keeping the running one")
+			assert.ErrorContains(t, err, tt.want)
+			assert.Equal(t, running, pid(c), "rolled back")
+			assert.Equal(t, "1.3.0", c.Version())
+			require.NoError(t, c.Health(ctx))
+		})
+	}
+
+	require.NoError(t, c.Stop(ctx))
+	assert.ErrorContains(t, c.Reload(ctx), "plugin echo is not running")
+}
+
+func TestClient_PluginErrors(t *testing.T) {
+	c := openTestPlugin(t, map[string]string{"fail": "init"})
+	assert.ErrorContains(t, c.Init(context.Background(), nil), "plugin echo: Init: bad settings")
+}
+
+func TestClient_CrashIsolated(t *testing.T) {
+	c := openTestPlugin(t, map[string]string{"fail": "crash"})
+	ctx := context.Background()
+	require.NoError(t, c.Init(ctx, nil))
+
+	require.Error(t, c.Start(ctx))
+	<-c.current().exited
+	err := c.Health(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "plugin echo: process exited: exit status 3")
+}
+
+func TestOpen_Errors(t *testing.T) {
+	_, err := Open(context.Background(), config.ExternalPluginConfig{Path: "/nonexistent/plugin"}, allowAll, zap.NewNop())
+	assert.ErrorContains(t, err, "failed to start plugin /nonexistent/plugin")
+
+	// The test binary without testPluginEnv is no plugin: it prints test
+	// output instead of a handshake.
+	_, err = Open(context.Background(), config.ExternalPluginConfig{Path: os.Args[0], Args: []string{"-test.run=^$"}}, allowAll, zap.NewNop())
+	assert.ErrorContains(t, err, "invalid handshake")
+}
+
+func TestServe_Handshake(t *testing.T) {
+	tests := []struct {
+		name     string
+		cookie   string
+		versions string
+		want     string
+	}{
+		{"run by hand", "", "1", "is a StreamGate plugin"},
+		{"no common version", MagicCookieValue, "2,3", `no common protocol version: host speaks "2,3"`},
+		{"no token", MagicCookieValue, "1", "started without STREAMGATE_PLUGIN_TOKEN"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(MagicCookieKey, tt.cookie)
+			t.Setenv(protocolVersionsKey, tt.versions)
+			t.Setenv(tokenKey, "")
+			var stdout, stderr bytes.Buffer
+			assert.Equal(t, 1, serve(&testPlugin{}, strings.NewReader(""), &stdout, &stderr))
+			assert.Empty(t, stdout.String())
+			assert.Contains(t, stderr.String(), tt.want)
+		})
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	saved := supportedVersions
+	t.Cleanup(func() { supportedVersions = saved })
+	supportedVersions = []int{1, 2, 3}
+
+	tests := []struct {
+		host    string
+		want    int
+		wantErr bool
+	}{
+		{"1", 1, false},
+		{"1,2", 2, false},
+		{"4, 3, x", 3, false},
+		{"4,5", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := negotiate(tt.host)
+		if tt.wantErr {
+			assert.Error(t, err, tt.host)
+			continue
+		}
+		require.NoError(t, err, tt.host)
+		assert.Equal(t, tt.want, got, tt.host)
+	}
+}
+
+func TestParseHandshake(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    string
+		wantErr string
+	}{
+		{"1|1|unix|/tmp/plugin.sock|grpc\n", "unix:/tmp/plugin.sock", ""},
+		{"hello", "", "invalid handshake"},
+		{"2|1|unix|/tmp/plugin.sock|grpc", "", "core protocol version 2"},
+		{"1|9|unix|/tmp/plugin.sock|grpc", "", "plugin chose protocol version 9"},
+		{"1|1|unix|/tmp/plugin.sock|netrpc", "", `unsupported plugin protocol "netrpc"`},
+		{"1|1|tcp|127.0.0.1:4000|grpc", "", `unsupported network "tcp", want unix`},
+	}
+	for _, tt := range tests {
+		target, _, err := parseHandshake(tt.line)
+		if tt.wantErr != "" {
+			assert.ErrorContains(t, err, tt.wantErr, tt.line)
+			continue
+		}
+		require.NoError(t, err, tt.line)
+		assert.Equal(t, tt.want, target)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	t.Setenv(testPluginEnv, "1")
+	cfg := &config.Config{Mode: "monolith"}
+	cfg.Plugins.External = []config.ExternalPluginConfig{{Path: os.Args[0]}}
+	cfg.Plugins.AllowUnsigned = []string{os.Args[0]}
+	kernel, err := core.NewMicrokernel(cfg, zap.NewNop())
+	require.NoError(t, err)
+
+	require.NoError(t, Register(context.Background(), kernel, zap.NewNop()))
+	p, err := kernel.GetPlugin("echo")
+	require.NoError(t, err)
+
+	// Started in monolith mode too, as it is not served by the gateway.
+	require.NoError(t, kernel.Start(context.Background()))
+	require.NoError(t, p.Health(context.Background()))
+	require.NoError(t, kernel.Shutdown(context.Background()))
+	assert.ErrorContains(t, p.Health(context.Background()), "process exited")
+}
+
+func TestReloadChanged(t *testing.T) {
+	t.Setenv(testPluginEnv, "1")
+	path := copyTestBinary(t)
+	cfg := &config.Config{Mode: "monolith"}
+	cfg.Plugins.External = []config.ExternalPluginConfig{{Path: path}}
+	cfg.Plugins.AllowUnsigned = []string{"*"}
+	kernel, err := core.NewMicrokernel(cfg, zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, Register(context.Background(), kernel, zap.NewNop()))
+	ctx := context.Background()
+	require.NoError(t, kernel.Start(ctx))
+	defer func() { _ = kernel.Shutdown(ctx) }()
+	p, err := kernel.GetPlugin("echo")
+	require.NoError(t, err)
+	c := p.(*Client)
+
+	started := pid(c)
+	reloadChanged(ctx, kernel, 0, zap.NewNop())
+	assert.Equal(t, started, pid(c), "binary unchanged")
+
+	touch := func(mod time.Time) { require.NoError(t, os.Chtimes(path, mod, mod)) }
+	touch(time.Now())
+	reloadChanged(ctx, kernel, time.Minute, zap.NewNop())
+	assert.Equal(t, started, pid(c), "binary may still be being written")
+
+	t.Setenv("EXTPLUGIN_TEST_VERSION", "1.3.0")
+	touch(time.Now().Add(-time.Hour))
+	reloadChanged(ctx, kernel, time.Minute, zap.NewNop())
+	assert.NotEqual(t, started, pid(c))
+	assert.Equal(t, "1.3.0", p.Version())
+
+	// A binary that fails is not tried again until it changes.
+	t.Setenv("EXTPLUGIN_TEST_FAIL_START", "1")
+	touch(time.Now().Add(-2 * time.Hour))
+	reloaded := pid(c)
+	reloadChanged(ctx, kernel, 0, zap.NewNop())
+	assert.Equal(t, reloaded, pid(c))
+	assert.False(t, c.changed(0))
+	require.NoError(t, p.Health(ctx))
+}
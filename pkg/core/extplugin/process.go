@@ -0,0 +1,316 @@
+package extplugin
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	// handshakeTimeout bounds how long a new process may take to print
+	// its handshake line.
+	handshakeTimeout = 30 * time.Second
+	// killGrace is how long kill waits for the process to exit on its own
+	// before killing it.
+	killGrace = 5 * time.Second
+)
+
+// process is one run of a plugin binary.
+type process struct {
+	name    string    // the plugin's name once described, else the binary path
+	binary  string    // resolved path of the binary
+	modTime time.Time // see artifactModTime
+	logger  *zap.Logger
+
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	conn  *grpc.ClientConn
+
+	exited  chan struct{}
+	exitErr error // set before exited is closed
+	killMu  sync.Mutex
+
+	// inflight is read-locked by every call, so drain can wait for them.
+	inflight sync.RWMutex
+}
+
+// description is what a plugin reports about itself.
+type description struct {
+	name    string
+	version string
+	deps    []string
+}
+
+// startProcess verifies the plugin binary cfg.Path against trust, starts
+// it, negotiates the protocol version and asks the plugin to describe
+// itself.
+func startProcess(ctx context.Context, cfg config.ExternalPluginConfig, trust *Trust, logger *zap.Logger) (*process, description, error) {
+	cmd := exec.Command(cfg.Path, cfg.Args...)
+	versions := make([]string, len(supportedVersions))
+	for i, v := range supportedVersions {
+		versions[i] = strconv.Itoa(v)
+	}
+	token, err := newToken()
+	if err != nil {
+		return nil, description{}, err
+	}
+	cmd.Env = append(os.Environ(),
+		MagicCookieKey+"="+MagicCookieValue,
+		protocolVersionsKey+"="+strings.Join(versions, ","),
+		tokenKey+"="+token)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, description{}, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, description{}, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, description{}, err
+	}
+	// Stat before verifying, so a binary replaced meanwhile still counts
+	// as changed.
+	binary := cmd.Path
+	modTime := artifactModTime(cfg, binary)
+	if cmd.Err == nil {
+		// Run a private copy of the bytes that were verified, not the
+		// file at binary, which could be swapped in between.
+		runPath, cleanup, err := trust.VerifiedCopy(cfg, binary)
+		if err != nil {
+			return nil, description{}, fmt.Errorf("plugin %s: %w", cfg.Path, err)
+		}
+		defer cleanup()
+		cmd.Path = runPath
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, description{}, fmt.Errorf("failed to start plugin %s: %w", cfg.Path, err)
+	}
+
+	p := &process{
+		name:    cfg.Path,
+		binary:  binary,
+		modTime: modTime,
+		logger:  logger.With(zap.String("plugin_path", cfg.Path), zap.Int("pid", cmd.Process.Pid)),
+		cmd:     cmd,
+		stdin:   stdin,
+		exited:  make(chan struct{}),
+	}
+
+	handshake := make(chan string, 1)
+	var output sync.WaitGroup
+	output.Add(2)
+	go func() {
+		defer output.Done()
+		scanner := bufio.NewScanner(stdout)
+		if scanner.Scan() {
+			handshake <- scanner.Text()
+		}
+		for scanner.Scan() {
+			p.logger.Info("Plugin output", zap.String("line", scanner.Text()))
+		}
+	}()
+	go func() {
+		defer output.Done()
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			p.logger.Warn("Plugin stderr", zap.String("line", scanner.Text()))
+		}
+	}()
+	go func() {
+		// Wait only once the pipes are drained, as exec requires.
+		output.Wait()
+		p.exitErr = cmd.Wait()
+		close(p.exited)
+	}()
+
+	timer := time.NewTimer(handshakeTimeout)
+	defer timer.Stop()
+	var line string
+	select {
+	case line = <-handshake:
+	case <-p.exited:
+		if p.exitErr == nil {
+			return nil, description{}, fmt.Errorf("plugin %s exited before the handshake", cfg.Path)
+		}
+		return nil, description{}, fmt.Errorf("plugin %s exited before the handshake: %w", cfg.Path, p.exitErr)
+	case <-timer.C:
+		p.forceKill()
+		return nil, description{}, fmt.Errorf("plugin %s: no handshake within %s", cfg.Path, handshakeTimeout)
+	case <-ctx.Done():
+		p.forceKill()
+		return nil, description{}, ctx.Err()
+	}
+
+	target, version, err := parseHandshake(line)
+	if err != nil {
+		p.forceKill()
+		return nil, description{}, fmt.Errorf("plugin %s: %w", cfg.Path, err)
+	}
+	p.conn, err = grpc.NewClient(target,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(tokenCredentials(token)))
+	if err != nil {
+		p.forceKill()
+		return nil, description{}, fmt.Errorf("plugin %s: %w", cfg.Path, err)
+	}
+
+	desc := &structpb.Struct{}
+	if err := p.call(ctx, "Describe", &emptypb.Empty{}, desc); err != nil {
+		_ = p.kill(context.Background())
+		return nil, description{}, err
+	}
+	fields := desc.GetFields()
+	d := description{
+		name:    fields["name"].GetStringValue(),
+		version: fields["version"].GetStringValue(),
+	}
+	for _, dep := range fields["depends_on"].GetListValue().GetValues() {
+		d.deps = append(d.deps, dep.GetStringValue())
+	}
+	if d.name == "" {
+		_ = p.kill(context.Background())
+		return nil, description{}, fmt.Errorf("plugin %s did not report a name", cfg.Path)
+	}
+
+	p.name = d.name
+	p.logger = p.logger.With(zap.String("plugin", d.name))
+	p.logger.Info("Plugin process started",
+		zap.String("version", d.version),
+		zap.Int("protocol_version", version))
+	return p, d, nil
+}
+
+// artifactModTime returns when the binary at path or its signature was
+// last modified, whichever is later: a new binary may be deployed before
+// its signature.
+func artifactModTime(cfg config.ExternalPluginConfig, path string) time.Time {
+	var latest time.Time
+	for _, name := range []string{path, signaturePath(cfg, path)} {
+		if info, err := os.Stat(name); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
+
+// init passes the plugin its settings.
+func (p *process) init(ctx context.Context, settings map[string]string) error {
+	fields := make(map[string]interface{}, len(settings))
+	for k, v := range settings {
+		fields[k] = v
+	}
+	req, err := structpb.NewStruct(map[string]interface{}{"settings": fields})
+	if err != nil {
+		return err
+	}
+	return p.call(ctx, "Init", req, &emptypb.Empty{})
+}
+
+// call invokes method on the plugin, reporting a crashed process as such.
+func (p *process) call(ctx context.Context, method string, in, out proto.Message) error {
+	p.inflight.RLock()
+	defer p.inflight.RUnlock()
+
+	if err := p.exitError(); err != nil {
+		return err
+	}
+	if err := p.conn.Invoke(ctx, "/"+serviceName+"/"+method, in, out); err != nil {
+		if exitErr := p.exitError(); exitErr != nil {
+			return exitErr
+		}
+		return fmt.Errorf("plugin %s: %s: %s", p.name, method, status.Convert(err).Message())
+	}
+	return nil
+}
+
+// drain waits until the calls in flight have returned, or ctx is done,
+// then stops the plugin and ends the process.
+func (p *process) drain(ctx context.Context) error {
+	drained := make(chan struct{})
+	go func() {
+		// A barrier: new calls are not sent here any more.
+		p.inflight.Lock()
+		p.inflight.Unlock() //nolint:staticcheck // empty critical section on purpose
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		p.logger.Warn("Plugin calls still in flight, stopping it anyway")
+	}
+	return p.stop(ctx)
+}
+
+// stop asks the plugin to stop, then ends the process. A process that has
+// already exited has nothing to stop.
+func (p *process) stop(ctx context.Context) error {
+	if p.exitError() != nil {
+		return nil
+	}
+	err := p.call(ctx, "Stop", &emptypb.Empty{}, &emptypb.Empty{})
+	return errors.Join(err, p.kill(ctx))
+}
+
+// kill closes the connection and the plugin's stdin, which makes Serve
+// return, and kills the process if it has not exited within killGrace or
+// by the time ctx is done.
+func (p *process) kill(ctx context.Context) error {
+	p.killMu.Lock()
+	defer p.killMu.Unlock()
+
+	if p.conn != nil {
+		_ = p.conn.Close()
+	}
+	_ = p.stdin.Close()
+
+	timer := time.NewTimer(killGrace)
+	defer timer.Stop()
+	select {
+	case <-p.exited:
+		return nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	p.logger.Warn("Plugin did not exit, killing it")
+	p.forceKill()
+	<-p.exited
+	return nil
+}
+
+func (p *process) forceKill() {
+	_ = p.cmd.Process.Kill()
+}
+
+// exitError returns an error if the process has exited.
+func (p *process) exitError() error {
+	select {
+	case <-p.exited:
+		if p.exitErr == nil {
+			return fmt.Errorf("plugin %s: process exited", p.name)
+		}
+		return fmt.Errorf("plugin %s: process exited: %v", p.name, p.exitErr)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,89 @@
+package extplugin
+
+import (
+	"context"
+	"runtime"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// The plugin service, protocol version 1. Its messages are well-known
+// types, so no generated code is needed on either side:
+//
+//	Describe(Empty) Struct  {name, version, depends_on: [string]}
+//	Init(Struct) Empty      {settings: {string: string}}
+//	Start(Empty) Empty
+//	Stop(Empty) Empty
+//	Health(Empty) Empty
+//	Usage(Empty) Struct     {goroutines}
+//
+// Usage reports only what the host cannot see from outside the process;
+// memory and CPU time are read from /proc by the host.
+const serviceName = "streamgate.plugin.v1.Plugin"
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*Plugin)(nil),
+	Methods: []grpc.MethodDesc{
+		unary("Describe", newEmpty, func(_ context.Context, p Plugin, _ *emptypb.Empty) (proto.Message, error) {
+			deps := make([]interface{}, len(p.DependsOn()))
+			for i, dep := range p.DependsOn() {
+				deps[i] = dep
+			}
+			return structpb.NewStruct(map[string]interface{}{
+				"name":       p.Name(),
+				"version":    p.Version(),
+				"depends_on": deps,
+			})
+		}),
+		unary("Init", newStruct, func(ctx context.Context, p Plugin, req *structpb.Struct) (proto.Message, error) {
+			settings := make(map[string]string)
+			for k, v := range req.GetFields()["settings"].GetStructValue().GetFields() {
+				settings[k] = v.GetStringValue()
+			}
+			return &emptypb.Empty{}, p.Init(ctx, settings)
+		}),
+		unary("Start", newEmpty, func(ctx context.Context, p Plugin, _ *emptypb.Empty) (proto.Message, error) {
+			return &emptypb.Empty{}, p.Start(ctx)
+		}),
+		unary("Stop", newEmpty, func(ctx context.Context, p Plugin, _ *emptypb.Empty) (proto.Message, error) {
+			return &emptypb.Empty{}, p.Stop(ctx)
+		}),
+		unary("Health", newEmpty, func(ctx context.Context, p Plugin, _ *emptypb.Empty) (proto.Message, error) {
+			return &emptypb.Empty{}, p.Health(ctx)
+		}),
+		unary("Usage", newEmpty, func(context.Context, Plugin, *emptypb.Empty) (proto.Message, error) {
+			return structpb.NewStruct(map[string]interface{}{
+				"goroutines": runtime.NumGoroutine(),
+			})
+		}),
+	},
+}
+
+func newEmpty() *emptypb.Empty    { return &emptypb.Empty{} }
+func newStruct() *structpb.Struct { return &structpb.Struct{} }
+
+// unary describes a method that decodes a Req and calls call on the
+// served Plugin.
+func unary[Req proto.Message](name string, newReq func() Req, call func(context.Context, Plugin, Req) (proto.Message, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			req := newReq()
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return call(ctx, srv.(Plugin), req.(Req))
+			}
+			if interceptor == nil {
+				return handler(ctx, req)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/" + name}
+			return interceptor(ctx, req, info, handler)
+		},
+	}
+}
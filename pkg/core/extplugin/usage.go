@@ -0,0 +1,53 @@
+package extplugin
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/rtcdance/streamgate/pkg/core"
+)
+
+// userHZ is the unit of the CPU times in /proc/<pid>/stat, fixed at 100
+// by the Linux ABI whatever the kernel's tick rate.
+const userHZ = 100
+
+// hostUsage reads the resident memory and CPU time of process pid from
+// /proc, so the host measures a plugin's process rather than trusting what
+// the plugin reports about itself.
+func hostUsage(pid int) (core.ResourceUsage, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return core.ResourceUsage{}, fmt.Errorf("read process %d usage: %w", pid, err)
+	}
+	// The command name in parentheses may contain spaces; the fields
+	// after it start with the state, field 3 in proc(5).
+	i := bytes.LastIndexByte(data, ')')
+	if i < 0 {
+		return core.ResourceUsage{}, fmt.Errorf("read process %d usage: malformed stat", pid)
+	}
+	fields := bytes.Fields(data[i+1:])
+	field := func(n int) (uint64, error) {
+		if n-3 >= len(fields) {
+			return 0, fmt.Errorf("read process %d usage: stat has %d fields", pid, len(fields)+2)
+		}
+		return strconv.ParseUint(string(fields[n-3]), 10, 64)
+	}
+	utime, err := field(14)
+	if err != nil {
+		return core.ResourceUsage{}, err
+	}
+	stime, err := field(15)
+	if err != nil {
+		return core.ResourceUsage{}, err
+	}
+	rss, err := field(24)
+	if err != nil {
+		return core.ResourceUsage{}, err
+	}
+	return core.ResourceUsage{
+		MemoryBytes: rss * uint64(os.Getpagesize()),
+		CPUSeconds:  float64(utime+stime) / userHZ,
+	}, nil
+}
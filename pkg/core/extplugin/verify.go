@@ -0,0 +1,197 @@
+package extplugin
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Plugin binaries are signed with minisign (https://jedisct1.github.io/minisign/):
+//
+//	minisign -G -p plugins.pub -s plugins.key
+//	minisign -S -s plugins.key -m watermark
+//
+// The second line of plugins.pub goes in plugins.trusted_keys, and the
+// signature, watermark.minisig, is deployed next to the binary.
+const (
+	sigAlgorithm       = "Ed" // signs the file itself, minisign -l
+	sigAlgorithmHashed = "ED" // signs the file's BLAKE2b-512 hash, the default
+
+	untrustedPrefix = "untrusted comment:"
+	trustedPrefix   = "trusted comment: "
+)
+
+// ErrUntrusted is returned for a plugin binary that is not signed by a
+// trusted key.
+var ErrUntrusted = errors.New("plugin binary is not signed by a trusted key")
+
+// Trust decides which plugin binaries may run: those signed by one of its
+// keys, and those allowed to run unsigned. The zero Trust allows none.
+type Trust struct {
+	keys          map[uint64]ed25519.PublicKey // by minisign key ID
+	allowUnsigned []string
+}
+
+// NewTrust returns the Trust configured by plugins.trusted_keys and
+// plugins.allow_unsigned.
+func NewTrust(cfg config.PluginsConfig) (*Trust, error) {
+	t := &Trust{
+		keys:          make(map[uint64]ed25519.PublicKey, len(cfg.TrustedKeys)),
+		allowUnsigned: cfg.AllowUnsigned,
+	}
+	for _, s := range cfg.TrustedKeys {
+		id, key, err := parsePublicKey(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid plugins.trusted_keys entry %q: %w", s, err)
+		}
+		t.keys[id] = key
+	}
+	return t, nil
+}
+
+// Verify checks that the binary at path, which plugin cfg runs, has a
+// valid signature by a trusted key, unless cfg.Path is allowed to run
+// unsigned. The signature is read from cfg.Signature or path+".minisig".
+func (t *Trust) Verify(cfg config.ExternalPluginConfig, path string) error {
+	if t != nil && t.allowsUnsigned(cfg.Path) {
+		return nil
+	}
+	_, err := t.verified(cfg, path)
+	return err
+}
+
+// VerifiedCopy verifies the binary at path like Verify and returns a
+// private, read-only copy of the bytes it checked for exec to run, so a
+// binary swapped in after the check never runs. cleanup removes the copy
+// once the process has started. A plugin allowed to run unsigned runs
+// from path.
+func (t *Trust) VerifiedCopy(cfg config.ExternalPluginConfig, path string) (string, func(), error) {
+	if t != nil && t.allowsUnsigned(cfg.Path) {
+		return path, func() {}, nil
+	}
+	data, err := t.verified(cfg, path)
+	if err != nil {
+		return "", nil, err
+	}
+	dir, err := os.MkdirTemp("", "streamgate-plugin-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to copy plugin binary: %w", err)
+	}
+	cleanup := func() { _ = os.RemoveAll(dir) }
+	copyPath := filepath.Join(dir, filepath.Base(path))
+	f, err := os.OpenFile(copyPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o500)
+	if err == nil {
+		_, err = f.Write(data)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to copy plugin binary: %w", err)
+	}
+	return copyPath, cleanup, nil
+}
+
+// verified reads the binary at path and returns it if it has a valid
+// signature by a trusted key.
+func (t *Trust) verified(cfg config.ExternalPluginConfig, path string) ([]byte, error) {
+	if t == nil {
+		return nil, ErrUntrusted
+	}
+	sigPath := signaturePath(cfg, path)
+	sig, err := os.ReadFile(sigPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUntrusted, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.verify(data, sig); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrUntrusted, sigPath, err)
+	}
+	return data, nil
+}
+
+// allowsUnsigned reports whether plugins.allow_unsigned lets the plugin
+// at path, as configured, run without a valid signature.
+func (t *Trust) allowsUnsigned(path string) bool {
+	return slices.Contains(t.allowUnsigned, "*") || slices.Contains(t.allowUnsigned, path)
+}
+
+// verify checks sig, the contents of a minisign signature file, against
+// data.
+func (t *Trust) verify(data, sig []byte) error {
+	lines := strings.Split(strings.TrimRight(string(sig), "\r\n"), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], untrustedPrefix) || !strings.HasPrefix(lines[2], trustedPrefix) {
+		return errors.New("not a minisign signature")
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(raw) != 2+8+ed25519.SignatureSize {
+		return errors.New("malformed signature")
+	}
+	algorithm, id, signature := string(raw[:2]), binary.LittleEndian.Uint64(raw[2:10]), raw[10:]
+	key, ok := t.keys[id]
+	if !ok {
+		return fmt.Errorf("signed by unknown key %016X", id)
+	}
+
+	switch algorithm {
+	case sigAlgorithm:
+	case sigAlgorithmHashed:
+		sum := blake2b.Sum512(data)
+		data = sum[:]
+	default:
+		return fmt.Errorf("unsupported signature algorithm %q", algorithm)
+	}
+	if !ed25519.Verify(key, data, signature) {
+		return errors.New("signature does not match")
+	}
+
+	// The global signature covers the signature and the trusted comment,
+	// so the comment cannot be swapped either.
+	global, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return errors.New("malformed trusted comment signature")
+	}
+	comment := strings.TrimSuffix(strings.TrimPrefix(lines[2], trustedPrefix), "\r")
+	if !ed25519.Verify(key, append(bytes.Clone(signature), comment...), global) {
+		return errors.New("trusted comment signature does not match")
+	}
+	return nil
+}
+
+// parsePublicKey parses a minisign public key: the base64 line of a .pub
+// file, or the whole file.
+func parsePublicKey(s string) (uint64, ed25519.PublicKey, error) {
+	var line string
+	for _, l := range strings.Split(strings.TrimSpace(s), "\n") {
+		if l = strings.TrimSpace(l); l != "" && !strings.HasPrefix(l, untrustedPrefix) {
+			line = l
+		}
+	}
+	raw, err := base64.StdEncoding.DecodeString(line)
+	if err != nil || len(raw) != 2+8+ed25519.PublicKeySize || string(raw[:2]) != sigAlgorithm {
+		return 0, nil, errors.New("not a minisign public key")
+	}
+	return binary.LittleEndian.Uint64(raw[2:10]), ed25519.PublicKey(raw[10:]), nil
+}
+
+func signaturePath(cfg config.ExternalPluginConfig, path string) string {
+	if cfg.Signature != "" {
+		return cfg.Signature
+	}
+	return path + ".minisig"
+}
@@ -0,0 +1,182 @@
+package extplugin
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rtcdance/streamgate/pkg/core"
+	"github.com/rtcdance/streamgate/pkg/core/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/blake2b"
+)
+
+// testSigner signs like minisign -S, with and without -l.
+type testSigner struct {
+	id  [8]byte
+	key ed25519.PrivateKey
+}
+
+func newTestSigner(t *testing.T, id byte) *testSigner {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	return &testSigner{id: [8]byte{id, 1, 2, 3, 4, 5, 6, 7}, key: key}
+}
+
+// publicKey returns the key as in a minisign .pub file.
+func (s *testSigner) publicKey() string {
+	raw := append([]byte(sigAlgorithm), s.id[:]...)
+	raw = append(raw, s.key.Public().(ed25519.PublicKey)...)
+	return "untrusted comment: minisign public key\n" + base64.StdEncoding.EncodeToString(raw) + "\n"
+}
+
+func (s *testSigner) sign(data []byte, hashed bool, comment string) []byte {
+	algorithm := sigAlgorithm
+	if hashed {
+		algorithm = sigAlgorithmHashed
+		sum := blake2b.Sum512(data)
+		data = sum[:]
+	}
+	sig := ed25519.Sign(s.key, data)
+	raw := append(append([]byte(algorithm), s.id[:]...), sig...)
+	global := ed25519.Sign(s.key, append(sig, comment...))
+	return []byte(fmt.Sprintf("untrusted comment: signature from minisign secret key\n%s\ntrusted comment: %s\n%s\n",
+		base64.StdEncoding.EncodeToString(raw), comment, base64.StdEncoding.EncodeToString(global)))
+}
+
+func TestTrust_Verify(t *testing.T) {
+	signer, stranger := newTestSigner(t, 1), newTestSigner(t, 2)
+	trust, err := NewTrust(config.PluginsConfig{
+		TrustedKeys:   []string{signer.publicKey()},
+		AllowUnsigned: []string{"/opt/plugins/dev"},
+	})
+	require.NoError(t, err)
+	data := []byte("plugin binary")
+	comment := "timestamp:1700000000\tfile:echo"
+
+	tests := []struct {
+		name string
+		data []byte
+		sig  []byte
+		want string
+	}{
+		{"signed", data, signer.sign(data, true, comment), ""},
+		{"signed unhashed", data, signer.sign(data, false, comment), ""},
+		{"tampered binary", []byte("plugin binary!"), signer.sign(data, true, comment), "signature does not match"},
+		{"unknown key", data, stranger.sign(data, true, comment), "signed by unknown key 0706050403020102"},
+		{
+			"tampered comment", data,
+			[]byte(strings.Replace(string(signer.sign(data, true, comment)), "file:echo", "file:evil", 1)),
+			"trusted comment signature does not match",
+		},
+		{"not a signature", data, []byte("hello\n"), "not a minisign signature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "echo")
+			require.NoError(t, os.WriteFile(path, tt.data, 0o755))
+			require.NoError(t, os.WriteFile(path+".minisig", tt.sig, 0o644))
+
+			err := trust.Verify(config.ExternalPluginConfig{Path: path}, path)
+			if tt.want == "" {
+				require.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrUntrusted)
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+
+	t.Run("signature elsewhere", func(t *testing.T) {
+		dir := t.TempDir()
+		path, sigPath := filepath.Join(dir, "echo"), filepath.Join(dir, "echo.sig")
+		require.NoError(t, os.WriteFile(path, data, 0o755))
+		require.NoError(t, os.WriteFile(sigPath, signer.sign(data, true, comment), 0o644))
+		require.NoError(t, trust.Verify(config.ExternalPluginConfig{Path: path, Signature: sigPath}, path))
+		assert.ErrorIs(t, trust.Verify(config.ExternalPluginConfig{Path: path}, path), ErrUntrusted, "no echo.minisig")
+	})
+
+	t.Run("allowed unsigned", func(t *testing.T) {
+		require.NoError(t, trust.Verify(config.ExternalPluginConfig{Path: "/opt/plugins/dev"}, "/nonexistent"))
+		var none *Trust
+		assert.ErrorIs(t, none.Verify(config.ExternalPluginConfig{Path: "/opt/plugins/dev"}, "/nonexistent"), ErrUntrusted)
+	})
+}
+
+func TestTrust_VerifiedCopy(t *testing.T) {
+	signer := newTestSigner(t, 1)
+	trust, err := NewTrust(config.PluginsConfig{
+		TrustedKeys:   []string{signer.publicKey()},
+		AllowUnsigned: []string{"/opt/plugins/dev"},
+	})
+	require.NoError(t, err)
+	data := []byte("plugin binary")
+	path := filepath.Join(t.TempDir(), "echo")
+	require.NoError(t, os.WriteFile(path, data, 0o755))
+	require.NoError(t, os.WriteFile(path+".minisig", signer.sign(data, true, "file:echo"), 0o644))
+
+	copyPath, cleanup, err := trust.VerifiedCopy(config.ExternalPluginConfig{Path: path}, path)
+	require.NoError(t, err)
+	assert.NotEqual(t, path, copyPath)
+	// Swapping the binary after the check does not change what runs.
+	require.NoError(t, os.WriteFile(path, []byte("evil binary"), 0o755))
+	got, err := os.ReadFile(copyPath)
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+	info, err := os.Stat(copyPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o500), info.Mode().Perm())
+	cleanup()
+	_, err = os.Stat(filepath.Dir(copyPath))
+	assert.True(t, os.IsNotExist(err))
+
+	_, _, err = trust.VerifiedCopy(config.ExternalPluginConfig{Path: path}, path)
+	assert.ErrorIs(t, err, ErrUntrusted, "the swapped binary")
+
+	copyPath, _, err = trust.VerifiedCopy(config.ExternalPluginConfig{Path: "/opt/plugins/dev"}, "/opt/plugins/dev")
+	require.NoError(t, err)
+	assert.Equal(t, "/opt/plugins/dev", copyPath, "unsigned plugins run in place")
+}
+
+func TestParsePublicKey(t *testing.T) {
+	// minisign's own release key.
+	id, key, err := parsePublicKey("RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3")
+	require.NoError(t, err)
+	assert.Equal(t, "E7620F1842B4E81F", fmt.Sprintf("%016X", id))
+	assert.Len(t, key, ed25519.PublicKeySize)
+
+	_, err = NewTrust(config.PluginsConfig{TrustedKeys: []string{"bm90IGEga2V5"}})
+	assert.ErrorContains(t, err, "not a minisign public key")
+}
+
+func TestRegister_Signed(t *testing.T) {
+	t.Setenv(testPluginEnv, "1")
+	signer := newTestSigner(t, 1)
+	path := copyTestBinary(t)
+	cfg := &config.Config{Mode: "monolith"}
+	cfg.Plugins.External = []config.ExternalPluginConfig{{Path: path}}
+	cfg.Plugins.TrustedKeys = []string{signer.publicKey()}
+	kernel, err := core.NewMicrokernel(cfg, zap.NewNop())
+	require.NoError(t, err)
+
+	err = Register(context.Background(), kernel, zap.NewNop())
+	assert.ErrorIs(t, err, ErrUntrusted)
+	assert.ErrorContains(t, err, "echo-plugin.minisig")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path+".minisig", signer.sign(data, true, "file:echo-plugin"), 0o644))
+	require.NoError(t, Register(context.Background(), kernel, zap.NewNop()))
+	require.NoError(t, kernel.Start(context.Background()))
+	require.NoError(t, kernel.Shutdown(context.Background()))
+}
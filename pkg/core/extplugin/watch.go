@@ -0,0 +1,56 @@
+package extplugin
+
+import (
+	"context"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core"
+
+	"go.uber.org/zap"
+)
+
+// Watch checks the binaries of kernel's external plugins every interval
+// and reloads each running plugin whose binary changed, see
+// Client.Reload. It does nothing while hot reload is disabled in the
+// kernel's config manager, and returns when ctx is done.
+//
+// The binaries are polled rather than watched with inotify, which also
+// catches a binary replaced by a rename, as deploy tools do.
+func Watch(ctx context.Context, kernel *core.Microkernel, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if kernel.GetConfigManager().IsHotReloadEnabled() {
+				reloadChanged(ctx, kernel, interval, logger)
+			}
+		}
+	}
+}
+
+// reloadChanged reloads the running external plugins whose binary was
+// modified at least settle ago. A binary that fails to come up is not
+// tried again until it changes once more.
+func reloadChanged(ctx context.Context, kernel *core.Microkernel, settle time.Duration, logger *zap.Logger) {
+	for _, info := range kernel.Plugins() {
+		if info.State != core.StateRunning {
+			// Started again, it runs the new binary anyway.
+			continue
+		}
+		p, err := kernel.GetPlugin(info.Name)
+		if err != nil {
+			continue
+		}
+		c, ok := p.(*Client)
+		if !ok || !c.changed(settle) {
+			continue
+		}
+		logger.Info("Plugin binary changed, reloading", zap.String("plugin", c.Name()), zap.String("path", c.cfg.Path))
+		if err := c.Reload(ctx); err != nil {
+			logger.Error("Plugin hot reload failed", zap.String("plugin", c.Name()), zap.Error(err))
+		}
+	}
+}
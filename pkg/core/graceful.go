@@ -2,6 +2,8 @@ package core
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
@@ -53,42 +55,159 @@ func DrainMiddleware() gin.HandlerFunc {
 // The caller should invoke this in a goroutine after starting the server:
 //
 //	go core.GracefulShutdown(server, logger, 30*time.Second)
+//
+// Servers with more to stop than one HTTP server should use a Runner.
 func GracefulShutdown(server *http.Server, logger *zap.Logger, drainTimeout time.Duration) {
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	r := NewRunner(logger, 0, drainTimeout)
+	r.OnShutdown("http", server.Shutdown)
+	if err := r.Wait(context.Background()); err != nil {
+		logger.Warn("Graceful shutdown incomplete", zap.Error(err))
+		return
+	}
+	logger.Info("All connections drained, server stopped")
+}
 
-	sig := <-sigChan
-	logger.Info("Received shutdown signal, draining connections",
-		zap.String("signal", sig.String()),
-		zap.Duration("drain_timeout", drainTimeout))
+// shutdownState is set once a Runner receives a termination signal.
+var shutdownState atomic.Bool
 
-	// Mark draining so DrainMiddleware rejects new requests
-	drainState.Store(true)
+// IsShuttingDown reports whether the process received a termination
+// signal. Readiness handlers report not ready from then on, while requests
+// are still served, so load balancers stop routing here before the server
+// stops accepting requests.
+func IsShuttingDown() bool {
+	return shutdownState.Load()
+}
 
-	// Second signal channel for force quit
-	forceChan := make(chan os.Signal, 1)
-	signal.Notify(forceChan, syscall.SIGINT, syscall.SIGTERM)
+// shutdownStep stops one part of the process.
+type shutdownStep struct {
+	name string
+	stop func(ctx context.Context) error
+}
 
-	// Begin shutdown with drain timeout
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
-	defer cancel()
+// Runner runs a server process until SIGINT or SIGTERM and shuts it down
+// the way Kubernetes expects:
+//
+//  1. readiness fails at once (IsShuttingDown), so the pod leaves the
+//     Service endpoints while it keeps serving;
+//  2. after the drain delay, which covers endpoint propagation, new
+//     requests are rejected (SetDraining);
+//  3. the shutdown steps run in the order added, within the shutdown
+//     timeout, letting in-flight work finish or checkpoint.
+//
+// A second signal skips the drain delay, or aborts a shutdown in progress.
+type Runner struct {
+	logger     *zap.Logger
+	drainDelay time.Duration
+	timeout    time.Duration
+	steps      []shutdownStep
+	signals    chan os.Signal
+	errs       chan error
+}
 
-	done := make(chan struct{})
+// NewRunner returns a Runner listening for SIGINT and SIGTERM.
+func NewRunner(logger *zap.Logger, drainDelay, timeout time.Duration) *Runner {
+	r := &Runner{
+		logger:     logger,
+		drainDelay: drainDelay,
+		timeout:    timeout,
+		signals:    make(chan os.Signal, 2),
+		errs:       make(chan error, 1),
+	}
+	signal.Notify(r.signals, syscall.SIGINT, syscall.SIGTERM)
+	return r
+}
+
+// OnShutdown adds a shutdown step. Steps run in the order they were
+// added, each with what is left of the shutdown timeout.
+func (r *Runner) OnShutdown(name string, stop func(ctx context.Context) error) {
+	r.steps = append(r.steps, shutdownStep{name: name, stop: stop})
+}
+
+// ServeHTTP starts srv and adds its graceful shutdown as a step. A server
+// that fails to serve shuts the process down.
+func (r *Runner) ServeHTTP(name string, srv *http.Server) {
 	go func() {
-		if err := server.Shutdown(shutdownCtx); err != nil {
-			logger.Error("HTTP server shutdown error", zap.Error(err))
+		r.logger.Info("Starting HTTP server", zap.String("server", name), zap.String("addr", srv.Addr))
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			r.Fail(fmt.Errorf("%s server: %w", name, err))
 		}
-		close(done)
 	}()
+	r.OnShutdown(name, srv.Shutdown)
+}
+
+// Fail shuts the process down because of err, as a signal would.
+func (r *Runner) Fail(err error) {
+	select {
+	case r.errs <- err:
+	default:
+	}
+}
+
+// Wait blocks until a termination signal, a Fail or the end of ctx, then
+// shuts down. It returns the errors of the steps that failed, and an
+// error when the shutdown was aborted or timed out.
+func (r *Runner) Wait(ctx context.Context) error {
+	defer signal.Stop(r.signals)
+
+	var cause error
+	select {
+	case sig := <-r.signals:
+		r.logger.Info("Received shutdown signal", zap.String("signal", sig.String()))
+	case cause = <-r.errs:
+		r.logger.Error("Shutting down after server failure", zap.Error(cause))
+	case <-ctx.Done():
+		r.logger.Info("Shutting down", zap.Error(ctx.Err()))
+	}
+
+	shutdownState.Store(true)
+	if r.drainDelay > 0 {
+		r.logger.Info("Readiness failing, waiting for load balancers to stop routing",
+			zap.Duration("drain_delay", r.drainDelay))
+		timer := time.NewTimer(r.drainDelay)
+		select {
+		case <-timer.C:
+		case sig := <-r.signals:
+			timer.Stop()
+			r.logger.Warn("Second signal received, skipping drain delay", zap.String("signal", sig.String()))
+		}
+	}
+
+	SetDraining()
+	r.logger.Info("Drain state activated, rejecting new requests", zap.Duration("timeout", r.timeout))
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- r.runSteps(shutdownCtx) }()
 
 	select {
-	case <-done:
-		logger.Info("All connections drained, server stopped")
-	case sig := <-forceChan:
-		logger.Warn("Second signal received, forcing exit",
-			zap.String("signal", sig.String()))
-		cancel()
+	case err := <-done:
+		return errors.Join(cause, err)
+	case sig := <-r.signals:
+		r.logger.Warn("Second signal received, aborting shutdown", zap.String("signal", sig.String()))
+		return errors.Join(cause, fmt.Errorf("shutdown aborted by %s", sig))
 	case <-shutdownCtx.Done():
-		logger.Warn("Drain timeout exceeded, forcing shutdown")
+		// Give the running step a moment to notice the deadline.
+		select {
+		case err := <-done:
+			return errors.Join(cause, err)
+		case <-time.After(time.Second):
+		}
+		return errors.Join(cause, fmt.Errorf("shutdown timed out after %s", r.timeout))
+	}
+}
+
+func (r *Runner) runSteps(ctx context.Context) error {
+	var errs []error
+	for _, step := range r.steps {
+		start := time.Now()
+		if err := step.stop(ctx); err != nil {
+			r.logger.Error("Shutdown step failed", zap.String("step", step.name), zap.Error(err))
+			errs = append(errs, fmt.Errorf("%s: %w", step.name, err))
+			continue
+		}
+		r.logger.Info("Shutdown step completed", zap.String("step", step.name), zap.Duration("took", time.Since(start)))
 	}
+	return errors.Join(errs...)
 }
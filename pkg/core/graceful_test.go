@@ -0,0 +1,102 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func resetShutdownState(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() {
+		shutdownState.Store(false)
+		drainState.Store(false)
+	})
+}
+
+func TestRunner_Wait_ShutsDownInOrder(t *testing.T) {
+	resetShutdownState(t)
+	r := NewRunner(zap.NewNop(), 50*time.Millisecond, time.Second)
+
+	var order []string
+	r.OnShutdown("http", func(context.Context) error {
+		assert.True(t, IsDraining(), "new requests are rejected before servers stop")
+		order = append(order, "http")
+		return nil
+	})
+	r.OnShutdown("kernel", func(context.Context) error {
+		order = append(order, "kernel")
+		return errors.New("plugin stuck")
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- r.Wait(context.Background()) }()
+	r.signals <- syscall.SIGTERM
+
+	require.Eventually(t, IsShuttingDown, time.Second, time.Millisecond)
+	assert.False(t, IsDraining(), "requests are still served during the drain delay")
+
+	err := <-done
+	assert.ErrorContains(t, err, "kernel: plugin stuck")
+	assert.Equal(t, []string{"http", "kernel"}, order)
+}
+
+func TestRunner_Wait(t *testing.T) {
+	tests := []struct {
+		name       string
+		drainDelay time.Duration
+		trigger    func(r *Runner)
+		stop       func(ctx context.Context) error
+		wantErr    string
+	}{
+		{
+			name:       "second signal skips drain delay",
+			drainDelay: time.Hour,
+			trigger: func(r *Runner) {
+				r.signals <- syscall.SIGTERM
+				r.signals <- syscall.SIGINT
+			},
+			stop: func(context.Context) error { return nil },
+		},
+		{
+			name:    "server failure",
+			trigger: func(r *Runner) { r.Fail(errors.New("listen tcp :8080: address already in use")) },
+			stop:    func(context.Context) error { return nil },
+			wantErr: "address already in use",
+		},
+		{
+			name:    "step ignores deadline",
+			trigger: func(r *Runner) { r.signals <- syscall.SIGTERM },
+			stop: func(context.Context) error {
+				time.Sleep(5 * time.Second)
+				return nil
+			},
+			wantErr: "shutdown timed out",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetShutdownState(t)
+			r := NewRunner(zap.NewNop(), tt.drainDelay, 50*time.Millisecond)
+			r.OnShutdown("step", tt.stop)
+			tt.trigger(r)
+
+			start := time.Now()
+			err := r.Wait(context.Background())
+			assert.Less(t, time.Since(start), 3*time.Second)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+			assert.True(t, IsShuttingDown())
+			assert.True(t, IsDraining())
+		})
+	}
+}
@@ -2,40 +2,51 @@ package core
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/rtcdance/streamgate/pkg/core/config"
 	"github.com/rtcdance/streamgate/pkg/core/event"
+	"github.com/rtcdance/streamgate/pkg/discovery"
+	"github.com/rtcdance/streamgate/pkg/health"
 	"github.com/rtcdance/streamgate/pkg/service"
+	"github.com/rtcdance/streamgate/pkg/storage"
 
 	"go.uber.org/zap"
 )
 
 // topoSort returns plugin names in dependency order (Kahn's algorithm).
-// plugins that depend on others appear after their dependencies.
+// plugins that depend on others appear after their dependencies; ties are
+// broken by name, so the order is the same on every run. Dependencies on
+// plugins not in plugins are ignored.
 func topoSort(plugins map[string]Plugin, deps map[string][]string) ([]string, error) {
+	names := make([]string, 0, len(plugins))
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
 	inDegree := make(map[string]int, len(plugins))
 	graph := make(map[string][]string, len(plugins)) // dep → dependents
-
-	for name := range plugins {
-		if _, ok := inDegree[name]; !ok {
-			inDegree[name] = 0
-		}
+	for _, name := range names {
 		for _, dep := range deps[name] {
-			if dep == name {
-				continue // skip self-dependency
+			if _, ok := plugins[dep]; !ok || dep == name {
+				continue // missing or self-dependency
 			}
 			graph[dep] = append(graph[dep], name)
 			inDegree[name]++
 		}
 	}
 
-	queue := make([]string, 0, len(plugins))
-	for name, deg := range inDegree {
-		if deg == 0 {
+	var queue []string
+	for _, name := range names {
+		if inDegree[name] == 0 {
 			queue = append(queue, name)
 		}
 	}
@@ -54,11 +65,44 @@ func topoSort(plugins map[string]Plugin, deps map[string][]string) ([]string, er
 	}
 
 	if len(result) != len(plugins) {
-		return nil, fmt.Errorf("circular dependency detected among plugins: %d of %d resolved", len(result), len(plugins))
+		return nil, fmt.Errorf("circular dependency detected among plugins: %s",
+			strings.Join(findCycle(names, graph, inDegree), " -> "))
 	}
 	return result, nil
 }
 
+// findCycle returns a dependency cycle among the plugins topoSort could
+// not order, those left with a positive inDegree, as "a", "b", "a" for a
+// depending on b depending on a.
+func findCycle(names []string, graph map[string][]string, inDegree map[string]int) []string {
+	var start string
+	for _, name := range names {
+		if inDegree[name] > 0 {
+			start = name
+			break
+		}
+	}
+	// Every unresolved plugin has an unresolved dependency, so walking
+	// dependencies from one must come back to a plugin already visited.
+	dependsOn := make(map[string]string)
+	for dep, dependents := range graph {
+		for _, d := range dependents {
+			if inDegree[d] > 0 && inDegree[dep] > 0 && (dependsOn[d] == "" || dep < dependsOn[d]) {
+				dependsOn[d] = dep
+			}
+		}
+	}
+	seen := make(map[string]int)
+	var path []string
+	for node := start; ; node = dependsOn[node] {
+		if i, ok := seen[node]; ok {
+			return append(path[i:], node)
+		}
+		seen[node] = len(path)
+		path = append(path, node)
+	}
+}
+
 // Plugin defines the interface for all plugins
 type Plugin interface {
 	Name() string
@@ -74,16 +118,49 @@ type Plugin interface {
 	DependsOn() []string
 }
 
+// StandalonePlugin is implemented by plugins that do not serve through the
+// api-gateway, like out-of-process plugins. When Standalone reports true
+// the kernel starts, stops and health-checks them in monolith mode too.
+type StandalonePlugin interface {
+	Plugin
+	Standalone() bool
+}
+
+// PluginState is a plugin's lifecycle state as tracked by the kernel.
+type PluginState string
+
+const (
+	StateRegistered  PluginState = "registered"
+	StateInitialized PluginState = "initialized"
+	StateRunning     PluginState = "running"
+	StateStopped     PluginState = "stopped"
+	// StateError means a lifecycle call failed or panicked; the plugin
+	// stays in this state until its next successful transition.
+	StateError PluginState = "error"
+)
+
+// PluginStatus is a plugin's state and, in StateError, the failure message.
+type PluginStatus struct {
+	State PluginState
+	Error string
+}
+
 // Microkernel is the core of the system
 type Microkernel struct {
 	config      *config.Config
+	configs     *config.ConfigManager
 	logger      *zap.Logger
-	plugins     map[string]Plugin // name → plugin (fast lookup)
-	pluginOrder []string          // topological order for Init/Start/Stop
+	plugins     map[string]Plugin       // name → plugin (fast lookup)
+	status      map[string]PluginStatus // name → lifecycle status
+	pluginOrder []string                // topological order for Init/Start/Stop
 	eventBus    event.EventBus
 	registry    service.ServiceRegistry
 	clientPool  *service.ClientPool
+	audit       storage.AuditLogger
+	health      *health.HealthChecker
 	mu          sync.RWMutex
+	admin       sync.Mutex // serializes StartPlugin, StopPlugin and ReloadPlugin
+	resources   resources
 	started     bool
 	ctx         context.Context
 	cancel      context.CancelFunc
@@ -109,16 +186,7 @@ func NewMicrokernel(cfg *config.Config, logger *zap.Logger) (*Microkernel, error
 		}
 	}
 
-	// Initialize event bus based on mode
-	var eventBus event.EventBus
-	var err error
-
-	if cfg.Mode == "monolith" || cfg.Mode == "monolithic" {
-		eventBus, err = event.NewMemoryEventBus()
-	} else {
-		eventBus, err = event.NewNATSEventBus(cfg.NATS.URL, logger)
-	}
-
+	eventBus, err := newEventBus(cfg, logger)
 	if err != nil {
 		cancel()
 		return nil, fmt.Errorf("failed to initialize event bus: %w", err)
@@ -127,7 +195,7 @@ func NewMicrokernel(cfg *config.Config, logger *zap.Logger) (*Microkernel, error
 	// Initialize service registry for microservice mode
 	var registry service.ServiceRegistry
 	if cfg.Mode == "microservice" {
-		registry, err = service.NewConsulRegistry(cfg, logger)
+		registry, err = discovery.New(cfg, logger)
 		if err != nil {
 			cancel()
 			return nil, fmt.Errorf("failed to initialize service registry: %w", err)
@@ -140,19 +208,73 @@ func NewMicrokernel(cfg *config.Config, logger *zap.Logger) (*Microkernel, error
 		clientPool = service.NewClientPool(registry, logger)
 	}
 
-	return &Microkernel{
+	m := &Microkernel{
 		config:     cfg,
+		configs:    newConfigManager(cfg, eventBus, logger),
 		logger:     logger,
 		plugins:    make(map[string]Plugin),
+		status:     make(map[string]PluginStatus),
 		eventBus:   eventBus,
 		registry:   registry,
 		clientPool: clientPool,
+		health:     health.NewHealthChecker(logger),
 		ctx:        ctx,
 		cancel:     cancel,
-	}, nil
+	}
+	m.health.SetMode(cfg.Mode)
+	m.health.RegisterCheck("plugins", m.Health, health.WithCacheTTL(pluginHealthCacheTTL))
+	return m, nil
+}
+
+// newConfigManager returns a manager holding cfg that reloads through
+// config.LoadConfig, so a remote.source document is read again.
+func newConfigManager(cfg *config.Config, bus event.EventBus, logger *zap.Logger) *config.ConfigManager {
+	cm := config.NewConfigManager("", logger)
+	_ = cm.Update(cfg)
+	cm.SetEventBus(bus)
+	cm.SetHotReload(cfg.Plugins.HotReload)
+	// Runs before the plugins' handlers, so they see the fixed-up config.
+	cm.AddChangeHandler(func(oldCfg, newCfg *config.Config) error {
+		// The mains set these after loading; keep them across reloads.
+		newCfg.Mode, newCfg.ServiceName = oldCfg.Mode, oldCfg.ServiceName
+		logger.Info("Configuration changed", zap.Strings("sections", config.ChangedSections(oldCfg, newCfg)))
+		return nil
+	}, config.WithPriority(-1))
+	return cm
+}
+
+// pluginHealthCacheTTL bounds how often readiness probes run the plugins'
+// own health checks.
+const pluginHealthCacheTTL = 5 * time.Second
+
+// HealthChecks returns the registry plugins add their dependency checks
+// to. Its results are served on each plugin server's /health/ready.
+func (m *Microkernel) HealthChecks() *health.HealthChecker {
+	return m.health
+}
+
+// ReadyHandler serves the readiness of every registered check. Plugin
+// servers built without a kernel have nothing to probe and report ready.
+// Once the process is shutting down it reports not ready regardless.
+func (m *Microkernel) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	if IsShuttingDown() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = json.NewEncoder(w).Encode(health.ReadinessResponse{Ready: false, Timestamp: time.Now()})
+		return
+	}
+	if m == nil || m.health == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
+		return
+	}
+	m.health.ReadinessHandler()(w, r)
 }
 
-// RegisterPlugin registers a plugin with the microkernel
+// RegisterPlugin registers a plugin with the microkernel. A plugin whose
+// dependencies would form a cycle with those already registered is
+// rejected; dependencies not registered yet are only required by Start.
 func (m *Microkernel) RegisterPlugin(plugin Plugin) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -162,6 +284,11 @@ func (m *Microkernel) RegisterPlugin(plugin Plugin) error {
 	}
 
 	m.plugins[plugin.Name()] = plugin
+	if _, err := topoSort(m.plugins, m.pluginDeps()); err != nil {
+		delete(m.plugins, plugin.Name())
+		return fmt.Errorf("failed to register plugin %s: %w", plugin.Name(), err)
+	}
+	m.status[plugin.Name()] = PluginStatus{State: StateRegistered}
 	m.logger.Info("Plugin registered",
 		zap.String("name", plugin.Name()),
 		zap.String("version", plugin.Version()))
@@ -169,6 +296,49 @@ func (m *Microkernel) RegisterPlugin(plugin Plugin) error {
 	return nil
 }
 
+// PluginOrder returns the order Start initializes and starts the
+// registered plugins in; Shutdown stops them in reverse. It fails when a
+// dependency is not registered.
+func (m *Microkernel) PluginOrder() ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.resolveOrder()
+}
+
+// pluginDeps returns each registered plugin's dependencies. Callers must
+// hold m.mu.
+func (m *Microkernel) pluginDeps() map[string][]string {
+	deps := make(map[string][]string, len(m.plugins))
+	for name, plugin := range m.plugins {
+		deps[name] = plugin.DependsOn()
+	}
+	return deps
+}
+
+// resolveOrder checks that every dependency is registered and sorts the
+// plugins topologically. Callers must hold m.mu.
+func (m *Microkernel) resolveOrder() ([]string, error) {
+	deps := m.pluginDeps()
+	names := make([]string, 0, len(deps))
+	for name := range deps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, dep := range deps[name] {
+			if _, ok := m.plugins[dep]; !ok {
+				return nil, fmt.Errorf("plugin %q depends on %q which is not registered", name, dep)
+			}
+		}
+	}
+
+	order, err := topoSort(m.plugins, deps)
+	if err != nil {
+		return nil, fmt.Errorf("plugin dependency sort failed: %w", err)
+	}
+	return order, nil
+}
+
 // GetPlugin retrieves a plugin by name
 func (m *Microkernel) GetPlugin(name string) (Plugin, error) {
 	m.mu.RLock()
@@ -182,6 +352,91 @@ func (m *Microkernel) GetPlugin(name string) (Plugin, error) {
 	return plugin, nil
 }
 
+// GetPluginStatus returns the lifecycle status of a registered plugin
+func (m *Microkernel) GetPluginStatus(name string) (PluginStatus, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status, exists := m.status[name]
+	if !exists {
+		return PluginStatus{}, fmt.Errorf("plugin %s not found", name)
+	}
+	return status, nil
+}
+
+func (m *Microkernel) setPluginState(name string, state PluginState, err error) {
+	status := PluginStatus{State: state}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	m.mu.Lock()
+	m.status[name] = status
+	m.mu.Unlock()
+}
+
+// callPlugin runs one lifecycle method of plugin, converting a panic into
+// an error so a faulty plugin cannot take down the process. A panic always
+// moves the plugin to StateError; on success it moves to next, and on a
+// returned error to StateError unless next is empty (health checks).
+func (m *Microkernel) callPlugin(plugin Plugin, op string, next PluginState, fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("plugin %s panicked during %s: %v", plugin.Name(), op, r)
+			m.logger.Error("Recovered panic in plugin",
+				zap.String("name", plugin.Name()),
+				zap.String("op", op),
+				zap.Any("panic", r),
+				zap.Stack("stack"))
+			m.setPluginState(plugin.Name(), StateError, err)
+			return
+		}
+		switch {
+		case err == nil && next != "":
+			m.setPluginState(plugin.Name(), next, nil)
+		case err != nil && next != "":
+			m.setPluginState(plugin.Name(), StateError, err)
+		}
+	}()
+	return fn()
+}
+
+func (m *Microkernel) initPlugin(ctx context.Context, plugin Plugin) error {
+	return m.callPlugin(plugin, "init", StateInitialized, func() error { return plugin.Init(ctx, m) })
+}
+
+func (m *Microkernel) startPlugin(ctx context.Context, plugin Plugin) error {
+	err := m.callPlugin(plugin, "start", StateRunning, func() error { return plugin.Start(ctx) })
+	m.auditPlugin(ctx, "plugin.load", plugin, err)
+	return err
+}
+
+func (m *Microkernel) stopPlugin(ctx context.Context, plugin Plugin) error {
+	err := m.callPlugin(plugin, "stop", StateStopped, func() error { return plugin.Stop(ctx) })
+	m.auditPlugin(ctx, "plugin.unload", plugin, err)
+	return err
+}
+
+// SetAuditLogger makes the kernel record plugin starts and stops in the
+// audit log. Call it before Start.
+func (m *Microkernel) SetAuditLogger(al storage.AuditLogger) {
+	m.audit = al
+}
+
+func (m *Microkernel) auditPlugin(ctx context.Context, action string, plugin Plugin, err error) {
+	if m.audit == nil {
+		return
+	}
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	}
+	m.audit.Log(ctx, action, "system", "plugin", plugin.Name(), err == nil, errMsg, plugin.Version())
+}
+
+func (m *Microkernel) checkPlugin(ctx context.Context, plugin Plugin) error {
+	return m.callPlugin(plugin, "health check", "", func() error { return plugin.Health(ctx) })
+}
+
 // GetEventBus returns the event bus
 func (m *Microkernel) GetEventBus() event.EventBus {
 	return m.eventBus
@@ -202,6 +457,13 @@ func (m *Microkernel) GetConfig() *config.Config {
 	return m.config
 }
 
+// GetConfigManager returns the manager holding the kernel's config. With
+// remote.source set it reloads on central changes once the kernel has
+// started; plugins react to them with AddChangeHandler.
+func (m *Microkernel) GetConfigManager() *config.ConfigManager {
+	return m.configs
+}
+
 // GetLogger returns the logger
 func (m *Microkernel) GetLogger() *zap.Logger {
 	return m.logger
@@ -220,35 +482,16 @@ func (m *Microkernel) Start(ctx context.Context) error {
 	m.logger.Info("Starting microkernel", zap.String("mode", m.config.Mode))
 
 	// Compute topological order for plugin init/start/stop
-	m.mu.RLock()
-	deps := make(map[string][]string, len(m.plugins))
-	for name, plugin := range m.plugins {
-		deps[name] = plugin.DependsOn()
-	}
-	pluginNames := make(map[string]bool, len(m.plugins))
-	for name := range m.plugins {
-		pluginNames[name] = true
-	}
-	m.mu.RUnlock()
-
-	order, err := topoSort(m.plugins, deps)
-	if err != nil {
-		return fmt.Errorf("plugin dependency sort failed: %w", err)
-	}
-
-	for name, pluginDeps := range deps {
-		for _, dep := range pluginDeps {
-			if !pluginNames[dep] {
-				return fmt.Errorf("plugin %q depends on %q which is not registered", name, dep)
-			}
-		}
-	}
-
 	m.mu.Lock()
+	order, err := m.resolveOrder()
 	m.pluginOrder = order
 	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	m.logger.Info("Plugin order resolved", zap.Strings("order", order))
 
-	// Register service with Consul if in microservice mode
+	// Register service with discovery if in microservice mode
 	if m.registry != nil && m.config.Mode == "microservice" {
 		serviceID := fmt.Sprintf("%s-%d", m.config.ServiceName, m.config.Server.Port)
 		address := os.Getenv("SERVICE_HOST")
@@ -280,7 +523,9 @@ func (m *Microkernel) Start(ctx context.Context) error {
 			return fmt.Errorf("failed to register service: %w", err)
 		}
 
-		m.logger.Info("Service registered with Consul", zap.String("service_id", serviceID))
+		m.logger.Info("Service registered",
+			zap.String("service_id", serviceID),
+			zap.String("backend", m.config.Discovery.Backend))
 	}
 
 	// Initialize all plugins in dependency order
@@ -295,12 +540,12 @@ func (m *Microkernel) Start(ctx context.Context) error {
 
 	var initialized []Plugin
 	for _, plugin := range orderedPlugins {
-		if err := plugin.Init(ctx, m); err != nil {
+		if err := m.initPlugin(ctx, plugin); err != nil {
 			m.logger.Error("Failed to initialize plugin",
 				zap.String("name", plugin.Name()),
 				zap.Error(err))
 			for i := len(initialized) - 1; i >= 0; i-- {
-				if stopErr := initialized[i].Stop(ctx); stopErr != nil {
+				if stopErr := m.stopPlugin(ctx, initialized[i]); stopErr != nil {
 					m.logger.Error("Error stopping plugin during rollback",
 						zap.String("name", initialized[i].Name()),
 						zap.Error(stopErr))
@@ -313,18 +558,18 @@ func (m *Microkernel) Start(ctx context.Context) error {
 
 	var started []Plugin
 	for _, plugin := range orderedPlugins {
-		if m.config.Mode == "monolith" && plugin.Name() != "api-gateway" {
+		if m.servedByGateway(plugin) {
 			m.logger.Info("Skipping plugin HTTP server in monolith mode (routes served by api-gateway)",
 				zap.String("name", plugin.Name()))
 			started = append(started, plugin)
 			continue
 		}
-		if err := plugin.Start(ctx); err != nil {
+		if err := m.startPlugin(ctx, plugin); err != nil {
 			m.logger.Error("Failed to start plugin",
 				zap.String("name", plugin.Name()),
 				zap.Error(err))
 			for i := len(started) - 1; i >= 0; i-- {
-				if stopErr := started[i].Stop(ctx); stopErr != nil {
+				if stopErr := m.stopPlugin(ctx, started[i]); stopErr != nil {
 					m.logger.Error("Error stopping plugin during rollback",
 						zap.String("name", started[i].Name()),
 						zap.Error(stopErr))
@@ -336,6 +581,15 @@ func (m *Microkernel) Start(ctx context.Context) error {
 		m.logger.Info("Plugin started", zap.String("name", plugin.Name()))
 	}
 
+	if m.config.Remote.Source != "" {
+		go func() {
+			if err := m.configs.WatchRemote(m.ctx, m.config.Remote.GetPollInterval()); err != nil {
+				m.logger.Error("Remote config watcher stopped", zap.Error(err))
+			}
+		}()
+	}
+	go m.watchResources(m.ctx, m.config.Plugins.GetUsageInterval())
+
 	m.logger.Info("Microkernel started successfully")
 	return nil
 }
@@ -366,10 +620,10 @@ func (m *Microkernel) Shutdown(ctx context.Context) error {
 
 	for i := len(orderedPlugins) - 1; i >= 0; i-- {
 		plugin := orderedPlugins[i]
-		if m.config.Mode == "monolith" && plugin.Name() != "api-gateway" {
+		if m.servedByGateway(plugin) {
 			continue
 		}
-		if err := plugin.Stop(shutdownCtx); err != nil {
+		if err := m.stopPlugin(shutdownCtx, plugin); err != nil {
 			m.logger.Error("Error stopping plugin",
 				zap.String("name", plugin.Name()),
 				zap.Error(err))
@@ -403,6 +657,16 @@ func (m *Microkernel) Shutdown(ctx context.Context) error {
 	return nil
 }
 
+// servedByGateway reports whether plugin's own server is skipped because
+// the api-gateway serves its routes, as in monolith mode.
+func (m *Microkernel) servedByGateway(plugin Plugin) bool {
+	if m.config.Mode != "monolith" || plugin.Name() == "api-gateway" {
+		return false
+	}
+	sp, ok := plugin.(StandalonePlugin)
+	return !ok || !sp.Standalone()
+}
+
 // Health checks the health of the microkernel and all plugins
 func (m *Microkernel) Health(ctx context.Context) error {
 	m.mu.RLock()
@@ -413,10 +677,10 @@ func (m *Microkernel) Health(ctx context.Context) error {
 	m.mu.RUnlock()
 
 	for _, plugin := range plugins {
-		if m.config.Mode == "monolith" && plugin.Name() != "api-gateway" {
+		if m.servedByGateway(plugin) {
 			continue
 		}
-		if err := plugin.Health(ctx); err != nil {
+		if err := m.checkPlugin(ctx, plugin); err != nil {
 			m.logger.Error("Plugin health check failed",
 				zap.String("name", plugin.Name()),
 				zap.Error(err))
@@ -2,11 +2,16 @@ package core
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/rtcdance/streamgate/pkg/core/config"
 	"github.com/rtcdance/streamgate/pkg/core/event"
+	"github.com/rtcdance/streamgate/pkg/health"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -94,6 +99,34 @@ func TestNewMicrokernel_NilConfig(t *testing.T) {
 	assert.Contains(t, err.Error(), "config is required")
 }
 
+func TestNewEventBus(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "monolith default", cfg: config.Config{Mode: "monolith"}, want: &event.MemoryEventBus{}},
+		{name: "explicit memory", cfg: config.Config{Mode: "microservice", EventBus: config.EventBusConfig{Driver: "memory"}}, want: &event.MemoryEventBus{}},
+		{name: "kafka", cfg: config.Config{EventBus: config.EventBusConfig{Driver: "kafka", Kafka: config.KafkaBusConfig{RESTURL: "http://kafka-rest:8082"}}}, want: &event.KafkaEventBus{}},
+		{name: "kafka without proxy", cfg: config.Config{EventBus: config.EventBusConfig{Driver: "kafka"}}, wantErr: true},
+		{name: "bad ack wait", cfg: config.Config{EventBus: config.EventBusConfig{Driver: "jetstream", JetStream: config.JetStreamBusConfig{AckWait: "soon"}}}, wantErr: true},
+		{name: "unknown driver", cfg: config.Config{EventBus: config.EventBusConfig{Driver: "rabbitmq"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus, err := newEventBus(&tt.cfg, zap.NewNop())
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.IsType(t, tt.want, bus)
+			assert.NoError(t, bus.Close())
+		})
+	}
+}
+
 func TestNewMicrokernel_MonolithicMode(t *testing.T) {
 	logger := zap.NewNop()
 	cfg := &config.Config{Mode: "monolithic"}
@@ -244,6 +277,21 @@ func TestMicrokernel_GetConfig(t *testing.T) {
 	assert.Equal(t, cfg, retrievedConfig)
 }
 
+func TestMicrokernel_GetConfigManager(t *testing.T) {
+	cfg := &config.Config{Mode: "monolith", ServiceName: "api-gateway"}
+	kernel, err := NewMicrokernel(cfg, zap.NewNop())
+	require.NoError(t, err)
+
+	cm := kernel.GetConfigManager()
+	assert.Same(t, cfg, cm.Get())
+
+	// Reloaded configs keep the mode and service name the main set.
+	require.NoError(t, cm.Update(&config.Config{AppName: "reloaded"}))
+	assert.Equal(t, "reloaded", cm.Get().AppName)
+	assert.Equal(t, "monolith", cm.Get().Mode)
+	assert.Equal(t, "api-gateway", cm.Get().ServiceName)
+}
+
 func TestMicrokernel_GetLogger(t *testing.T) {
 	logger := zap.NewNop()
 	kernel := newTestKernel(t)
@@ -275,6 +323,75 @@ func TestMicrokernel_Start_Success(t *testing.T) {
 	assert.True(t, p.started)
 }
 
+// auditRecorder records the audit entries it is given.
+type auditRecorder struct {
+	entries []string
+}
+
+func (a *auditRecorder) Log(_ context.Context, action, _, _, resourceID string, success bool, _, _ string) {
+	a.entries = append(a.entries, fmt.Sprintf("%s %s %v", action, resourceID, success))
+}
+
+func (a *auditRecorder) Close() error { return nil }
+
+func TestMicrokernel_AuditsPluginLifecycle(t *testing.T) {
+	kernel := newTestKernel(t)
+	audit := &auditRecorder{}
+	kernel.SetAuditLogger(audit)
+	require.NoError(t, kernel.RegisterPlugin(&mockPlugin{name: "api-gateway", version: "1.0.0"}))
+
+	require.NoError(t, kernel.Start(context.Background()))
+	require.NoError(t, kernel.Shutdown(context.Background()))
+
+	assert.Equal(t, []string{"plugin.load api-gateway true", "plugin.unload api-gateway true"}, audit.entries)
+}
+
+func TestMicrokernel_ReadyHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		healthErr error
+		depErr    error
+		wantCode  int
+	}{
+		{"all healthy", nil, nil, http.StatusOK},
+		{"plugin unhealthy", errors.New("stalled"), nil, http.StatusServiceUnavailable},
+		{"dependency unhealthy", nil, errors.New("connection refused"), http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kernel := newTestKernel(t)
+			require.NoError(t, kernel.RegisterPlugin(&mockPlugin{name: "api-gateway", version: "1.0.0", healthErr: tt.healthErr}))
+			kernel.HealthChecks().RegisterCheck("api.postgres", func(context.Context) error { return tt.depErr })
+
+			w := httptest.NewRecorder()
+			kernel.ReadyHandler(w, httptest.NewRequest(http.MethodGet, "/health/ready", http.NoBody))
+			assert.Equal(t, tt.wantCode, w.Code)
+
+			var resp health.ReadinessResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Contains(t, resp.Checks, "plugins")
+			assert.Contains(t, resp.Checks, "api.postgres")
+		})
+	}
+
+	t.Run("nil kernel", func(t *testing.T) {
+		var kernel *Microkernel
+		w := httptest.NewRecorder()
+		kernel.ReadyHandler(w, httptest.NewRequest(http.MethodGet, "/ready", http.NoBody))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("shutting down", func(t *testing.T) {
+		shutdownState.Store(true)
+		defer shutdownState.Store(false)
+		kernel := newTestKernel(t)
+
+		w := httptest.NewRecorder()
+		kernel.ReadyHandler(w, httptest.NewRequest(http.MethodGet, "/ready", http.NoBody))
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	})
+}
+
 func TestMicrokernel_Start_MultiplePlugins(t *testing.T) {
 	kernel := newTestKernel(t)
 	p1 := &mockPlugin{name: "api-gateway", version: "1.0.0"}
@@ -404,6 +521,90 @@ func TestMicrokernel_Health_UnhealthyPlugin(t *testing.T) {
 	assert.Contains(t, err.Error(), "health check failed")
 }
 
+// panickingPlugin panics in the lifecycle methods selected by its flags.
+type panickingPlugin struct {
+	mockPlugin
+	panicOnStart  bool
+	panicOnHealth bool
+}
+
+func (p *panickingPlugin) Start(ctx context.Context) error {
+	if p.panicOnStart {
+		panic("start exploded")
+	}
+	return p.mockPlugin.Start(ctx)
+}
+
+func (p *panickingPlugin) Health(ctx context.Context) error {
+	if p.panicOnHealth {
+		panic("health exploded")
+	}
+	return p.mockPlugin.Health(ctx)
+}
+
+func TestMicrokernel_Start_PluginPanicRecovered(t *testing.T) {
+	kernel := newTestKernel(t)
+	dep := &mockPlugin{name: "auth", version: "1.0.0"}
+	p := &panickingPlugin{mockPlugin: mockPlugin{name: "api-gateway", version: "1.0.0", deps: []string{"auth"}}, panicOnStart: true}
+	require.NoError(t, kernel.RegisterPlugin(dep))
+	require.NoError(t, kernel.RegisterPlugin(p))
+
+	var err error
+	require.NotPanics(t, func() { err = kernel.Start(context.Background()) })
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to start plugin api-gateway")
+	assert.Contains(t, err.Error(), "start exploded")
+
+	status, err := kernel.GetPluginStatus("api-gateway")
+	require.NoError(t, err)
+	assert.Equal(t, StateError, status.State)
+	assert.Contains(t, status.Error, "start exploded")
+
+	status, err = kernel.GetPluginStatus("auth")
+	require.NoError(t, err)
+	assert.Equal(t, StateStopped, status.State, "plugins started before the panic are rolled back")
+}
+
+func TestMicrokernel_Health_PluginPanicRecovered(t *testing.T) {
+	kernel := newTestKernel(t)
+	p := &panickingPlugin{mockPlugin: mockPlugin{name: "api-gateway", version: "1.0.0"}}
+	require.NoError(t, kernel.RegisterPlugin(p))
+	require.NoError(t, kernel.Start(context.Background()))
+
+	status, err := kernel.GetPluginStatus("api-gateway")
+	require.NoError(t, err)
+	assert.Equal(t, StateRunning, status.State)
+
+	p.panicOnHealth = true
+	require.NotPanics(t, func() { err = kernel.Health(context.Background()) })
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "health exploded")
+
+	status, err = kernel.GetPluginStatus("api-gateway")
+	require.NoError(t, err)
+	assert.Equal(t, StateError, status.State)
+	assert.Contains(t, status.Error, "health exploded")
+}
+
+func TestMicrokernel_Health_ErrorKeepsState(t *testing.T) {
+	kernel := newTestKernel(t)
+	p := &mockPlugin{name: "api-gateway", version: "1.0.0"}
+	require.NoError(t, kernel.RegisterPlugin(p))
+	require.NoError(t, kernel.Start(context.Background()))
+
+	p.healthErr = fmt.Errorf("unhealthy")
+	assert.Error(t, kernel.Health(context.Background()))
+	status, err := kernel.GetPluginStatus("api-gateway")
+	require.NoError(t, err)
+	assert.Equal(t, StateRunning, status.State, "a failed health check is not a lifecycle failure")
+}
+
+func TestMicrokernel_GetPluginStatus_NotFound(t *testing.T) {
+	kernel := newTestKernel(t)
+	_, err := kernel.GetPluginStatus("missing")
+	assert.Error(t, err)
+}
+
 func TestMicrokernel_Health_NoPlugins(t *testing.T) {
 	kernel := newTestKernel(t)
 
@@ -706,3 +907,59 @@ func TestMicrokernel_Shutdown_EventBusCloseError(t *testing.T) {
 	err := kernel.Shutdown(context.Background())
 	assert.NoError(t, err)
 }
+
+func TestTopoSort_Deterministic(t *testing.T) {
+	plugins := map[string]Plugin{}
+	for _, name := range []string{"e", "d", "c", "b", "a"} {
+		plugins[name] = &mockPlugin{name: name}
+	}
+	deps := map[string][]string{"a": {"d"}, "c": {"missing"}}
+
+	for i := 0; i < 10; i++ {
+		order, err := topoSort(plugins, deps)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"b", "c", "d", "e", "a"}, order)
+	}
+}
+
+func TestTopoSort_CycleNamed(t *testing.T) {
+	plugins := map[string]Plugin{}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		plugins[name] = &mockPlugin{name: name}
+	}
+	deps := map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"b"}, "d": {"a"}}
+
+	_, err := topoSort(plugins, deps)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "b -> c -> b")
+}
+
+func TestMicrokernel_RegisterPlugin_RejectsCycle(t *testing.T) {
+	kernel := newTestKernel(t)
+	require.NoError(t, kernel.RegisterPlugin(&mockPlugin{name: "a", deps: []string{"b"}}))
+	require.NoError(t, kernel.RegisterPlugin(&mockPlugin{name: "b", deps: []string{"c"}}))
+
+	err := kernel.RegisterPlugin(&mockPlugin{name: "c", deps: []string{"a"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "circular dependency")
+	assert.Contains(t, err.Error(), "a -> b -> c -> a")
+
+	_, err = kernel.GetPlugin("c")
+	assert.Error(t, err, "the rejected plugin is not registered")
+	require.NoError(t, kernel.RegisterPlugin(&mockPlugin{name: "c"}))
+}
+
+func TestMicrokernel_PluginOrder(t *testing.T) {
+	kernel := newTestKernel(t)
+	require.NoError(t, kernel.RegisterPlugin(&mockPlugin{name: "api", deps: []string{"auth", "cache"}}))
+	require.NoError(t, kernel.RegisterPlugin(&mockPlugin{name: "auth", deps: []string{"cache"}}))
+
+	_, err := kernel.PluginOrder()
+	assert.ErrorContains(t, err, `plugin "api" depends on "cache" which is not registered`)
+
+	require.NoError(t, kernel.RegisterPlugin(&mockPlugin{name: "cache"}))
+	require.NoError(t, kernel.RegisterPlugin(&mockPlugin{name: "worker"}))
+	order, err := kernel.PluginOrder()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cache", "worker", "auth", "api"}, order)
+}
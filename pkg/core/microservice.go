@@ -2,11 +2,9 @@ package core
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
 
 	"github.com/rtcdance/streamgate/pkg/core/config"
 	"github.com/rtcdance/streamgate/pkg/core/logger"
@@ -14,9 +12,14 @@ import (
 )
 
 // RunMicroservice starts a microservice with the given name and plugin constructor.
-// It handles config loading, kernel startup, signal-based graceful shutdown, and
+// It handles config loading, kernel startup, graceful shutdown through a Runner, and
 // error logging — eliminating the ~76-line boilerplate in every cmd/microservices/<name>/main.go.
 func RunMicroservice(name string, newPlugin func(*config.Config, *zap.Logger) Plugin) {
+	if !flag.Parsed() {
+		config.BindFlags(flag.CommandLine)
+		flag.Parse()
+	}
+
 	log := logger.NewDevelopmentLogger("streamgate-" + name)
 	defer func() { _ = log.Sync() }()
 
@@ -56,15 +59,9 @@ func RunMicroservice(name string, newPlugin func(*config.Config, *zap.Logger) Pl
 	log.Info(fmt.Sprintf("StreamGate %s Service started successfully", name),
 		zap.Int("port", cfg.Server.Port))
 
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	sig := <-sigChan
-	log.Info("Received shutdown signal", zap.String("signal", sig.String()))
-
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	if err := kernel.Shutdown(shutdownCtx); err != nil {
+	runner := NewRunner(log, cfg.Server.GetDrainDelay(), cfg.Server.GetShutdownTimeout())
+	runner.OnShutdown("kernel", kernel.Shutdown)
+	if err := runner.Wait(ctx); err != nil {
 		log.Error("Error during shutdown", zap.Error(err))
 		os.Exit(1)
 	}
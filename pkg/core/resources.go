@@ -0,0 +1,301 @@
+package core
+
+import (
+	"context"
+	"maps"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+var (
+	pluginJobsInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "streamgate_plugin_jobs_in_flight",
+		Help: "Jobs a plugin is running, see Microkernel.AcquireJob",
+	}, []string{"plugin"})
+	pluginJobsWaiting = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "streamgate_plugin_jobs_waiting",
+		Help: "Jobs waiting for a slot under plugins.limits.<plugin>.max_concurrent_jobs",
+	}, []string{"plugin"})
+	pluginGoroutines = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "streamgate_plugin_goroutines",
+		Help: "Goroutines in a plugin's own process",
+	}, []string{"plugin"})
+	pluginMemoryBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "streamgate_plugin_memory_bytes",
+		Help: "Resident memory of a plugin's own process",
+	}, []string{"plugin"})
+	pluginCPUSeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "streamgate_plugin_cpu_seconds",
+		Help: "CPU time used by a plugin's current process",
+	}, []string{"plugin"})
+	pluginLimitRestartsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "streamgate_plugin_limit_restarts_total",
+		Help: "Plugin restarts for exceeding a hard limit, by limit",
+	}, []string{"plugin", "limit"})
+)
+
+func init() {
+	for _, c := range []prometheus.Collector{
+		pluginJobsInFlight, pluginJobsWaiting, pluginGoroutines,
+		pluginMemoryBytes, pluginCPUSeconds, pluginLimitRestartsTotal,
+	} {
+		if err := prometheus.Register(c); err != nil {
+			if _, ok := err.(prometheus.AlreadyRegisteredError); !ok {
+				panic(err)
+			}
+		}
+	}
+}
+
+// usageTimeout bounds a ResourceReporter's ResourceUsage call.
+const usageTimeout = 5 * time.Second
+
+// ResourceUsage is what a plugin process uses.
+type ResourceUsage struct {
+	Goroutines  int     `json:"goroutines"`
+	MemoryBytes uint64  `json:"memory_bytes"`
+	CPUSeconds  float64 `json:"cpu_seconds"`
+}
+
+// ResourceReporter is implemented by plugins that run in a process of
+// their own, whose usage the host can measure, like out-of-process
+// plugins. Built-in plugins share the host process, whose usage cannot be
+// told apart by plugin; for them only jobs are counted.
+type ResourceReporter interface {
+	Plugin
+	ResourceUsage(ctx context.Context) (ResourceUsage, error)
+}
+
+// PluginUsage is a plugin's resource usage and limits.
+type PluginUsage struct {
+	Name         string              `json:"name"`
+	JobsInFlight int                 `json:"jobs_in_flight"`
+	JobsWaiting  int                 `json:"jobs_waiting"`
+	Limits       config.PluginLimits `json:"limits"`
+	Process      *ResourceUsage      `json:"process,omitempty"` // for a ResourceReporter
+	Restarts     map[string]int      `json:"limit_restarts,omitempty"`
+	Error        string              `json:"error,omitempty"`
+
+	reporter bool // a running ResourceReporter, whose usage was asked for
+}
+
+// jobSlots counts a plugin's jobs against its max_concurrent_jobs.
+type jobSlots struct {
+	inFlight int
+	waiting  int
+	freed    chan struct{} // closed and replaced whenever a slot is freed
+}
+
+// resources is the kernel's per-plugin resource accounting.
+type resources struct {
+	mu         sync.Mutex
+	jobs       map[string]*jobSlots
+	restarts   map[string]map[string]int // plugin → limit → restarts
+	unenforced map[string]bool           // plugins warned about process limits
+}
+
+func (m *Microkernel) pluginLimits(name string) config.PluginLimits {
+	return m.configs.Get().Plugins.Limits[name]
+}
+
+// AcquireJob takes one of plugin's job slots, waiting while it already
+// runs plugins.limits.<plugin>.max_concurrent_jobs jobs, and returns the
+// function that gives the slot back. Without a limit it only counts the
+// job. It fails once ctx is done.
+func (m *Microkernel) AcquireJob(ctx context.Context, plugin string) (release func(), err error) {
+	r := &m.resources
+	r.mu.Lock()
+	slots := r.slotsLocked(plugin)
+	for {
+		limit := m.pluginLimits(plugin).MaxConcurrentJobs
+		if limit <= 0 || slots.inFlight < limit {
+			break
+		}
+		freed := slots.freed
+		slots.waiting++
+		pluginJobsWaiting.WithLabelValues(plugin).Inc()
+		r.mu.Unlock()
+
+		select {
+		case <-freed:
+		case <-ctx.Done():
+		}
+
+		r.mu.Lock()
+		slots.waiting--
+		pluginJobsWaiting.WithLabelValues(plugin).Dec()
+		if err := ctx.Err(); err != nil {
+			r.mu.Unlock()
+			return nil, err
+		}
+	}
+	slots.inFlight++
+	pluginJobsInFlight.WithLabelValues(plugin).Inc()
+	r.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			slots.inFlight--
+			pluginJobsInFlight.WithLabelValues(plugin).Dec()
+			close(slots.freed)
+			slots.freed = make(chan struct{})
+		})
+	}, nil
+}
+
+func (r *resources) slotsLocked(plugin string) *jobSlots {
+	if r.jobs == nil {
+		r.jobs = make(map[string]*jobSlots)
+	}
+	slots, ok := r.jobs[plugin]
+	if !ok {
+		slots = &jobSlots{freed: make(chan struct{})}
+		r.jobs[plugin] = slots
+	}
+	return slots
+}
+
+// PluginUsage lists each registered plugin's resource usage and limits by
+// name, asking ResourceReporters for their process's usage.
+func (m *Microkernel) PluginUsage(ctx context.Context) []PluginUsage {
+	m.mu.RLock()
+	plugins := make([]Plugin, 0, len(m.plugins))
+	for _, plugin := range m.plugins {
+		plugins = append(plugins, plugin)
+	}
+	m.mu.RUnlock()
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name() < plugins[j].Name() })
+
+	usages := make([]PluginUsage, 0, len(plugins))
+	for _, plugin := range plugins {
+		name := plugin.Name()
+		usage := PluginUsage{Name: name, Limits: m.pluginLimits(name)}
+		m.resources.mu.Lock()
+		if slots, ok := m.resources.jobs[name]; ok {
+			usage.JobsInFlight, usage.JobsWaiting = slots.inFlight, slots.waiting
+		}
+		usage.Restarts = maps.Clone(m.resources.restarts[name])
+		m.resources.mu.Unlock()
+
+		if reporter, ok := plugin.(ResourceReporter); ok && m.pluginState(name) == StateRunning {
+			usage.reporter = true
+			usageCtx, cancel := context.WithTimeout(ctx, usageTimeout)
+			process, err := reporter.ResourceUsage(usageCtx)
+			cancel()
+			if err != nil {
+				usage.Error = err.Error()
+			} else {
+				usage.Process = &process
+			}
+		}
+		usages = append(usages, usage)
+	}
+	return usages
+}
+
+// watchResources samples the plugins' resource usage every interval until
+// ctx is done, see enforceLimits.
+func (m *Microkernel) watchResources(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.enforceLimits(ctx)
+		}
+	}
+}
+
+// enforceLimits updates the resource metrics of the running
+// ResourceReporters and restarts those over plugins.limits.<plugin>.
+// max_memory_mb or max_goroutines, or whose usage cannot be read: a plugin
+// that does not answer in time is treated as unhealthy.
+func (m *Microkernel) enforceLimits(ctx context.Context) {
+	for _, usage := range m.PluginUsage(ctx) {
+		if !usage.reporter {
+			m.warnUnenforced(usage)
+			continue
+		}
+		process := usage.Process
+		var exceeded string
+		if process == nil {
+			exceeded = "usage"
+			m.logger.Warn("Plugin usage unavailable, restarting it",
+				zap.String("name", usage.Name),
+				zap.String("error", usage.Error))
+		} else {
+			pluginGoroutines.WithLabelValues(usage.Name).Set(float64(process.Goroutines))
+			pluginMemoryBytes.WithLabelValues(usage.Name).Set(float64(process.MemoryBytes))
+			pluginCPUSeconds.WithLabelValues(usage.Name).Set(process.CPUSeconds)
+
+			switch limits := usage.Limits; {
+			case limits.MaxMemoryMB > 0 && process.MemoryBytes > uint64(limits.MaxMemoryMB)<<20:
+				exceeded = "max_memory_mb"
+			case limits.MaxGoroutines > 0 && process.Goroutines > limits.MaxGoroutines:
+				exceeded = "max_goroutines"
+			default:
+				continue
+			}
+			m.logger.Warn("Plugin exceeds a hard limit, restarting it",
+				zap.String("name", usage.Name),
+				zap.String("limit", exceeded),
+				zap.Int("goroutines", process.Goroutines),
+				zap.Uint64("memory_bytes", process.MemoryBytes))
+		}
+		m.resources.mu.Lock()
+		if m.resources.restarts == nil {
+			m.resources.restarts = make(map[string]map[string]int)
+		}
+		if m.resources.restarts[usage.Name] == nil {
+			m.resources.restarts[usage.Name] = make(map[string]int)
+		}
+		m.resources.restarts[usage.Name][exceeded]++
+		m.resources.mu.Unlock()
+		pluginLimitRestartsTotal.WithLabelValues(usage.Name, exceeded).Inc()
+
+		opCtx, cancel := context.WithTimeout(ctx, adminOpTimeout)
+		if err := m.ReloadPlugin(opCtx, usage.Name); err != nil {
+			m.logger.Error("Failed to restart plugin",
+				zap.String("name", usage.Name),
+				zap.String("limit", exceeded),
+				zap.Error(err))
+		}
+		cancel()
+	}
+}
+
+// warnUnenforced logs, once per plugin, that max_memory_mb and
+// max_goroutines set for a plugin that is not a ResourceReporter, such as
+// a built-in plugin sharing the host process, are not enforced.
+func (m *Microkernel) warnUnenforced(usage PluginUsage) {
+	if usage.Limits.MaxMemoryMB <= 0 && usage.Limits.MaxGoroutines <= 0 {
+		return
+	}
+	if plugin, err := m.GetPlugin(usage.Name); err == nil {
+		if _, ok := plugin.(ResourceReporter); ok {
+			return // a reporter that is not running
+		}
+	}
+	m.resources.mu.Lock()
+	defer m.resources.mu.Unlock()
+	if m.resources.unenforced[usage.Name] {
+		return
+	}
+	if m.resources.unenforced == nil {
+		m.resources.unenforced = make(map[string]bool)
+	}
+	m.resources.unenforced[usage.Name] = true
+	m.logger.Warn("Plugin runs in the host process, its max_memory_mb and max_goroutines are not enforced",
+		zap.String("name", usage.Name))
+}
@@ -0,0 +1,140 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// reportingPlugin is a mockPlugin that reports its own resource usage.
+type reportingPlugin struct {
+	mockPlugin
+	usage  ResourceUsage
+	err    error
+	starts int
+}
+
+func (p *reportingPlugin) Start(ctx context.Context) error {
+	p.starts++
+	return p.mockPlugin.Start(ctx)
+}
+
+func (p *reportingPlugin) ResourceUsage(context.Context) (ResourceUsage, error) {
+	return p.usage, p.err
+}
+
+func TestMicrokernel_AcquireJob(t *testing.T) {
+	kernel := newAdminTestKernel(t)
+	require.NoError(t, kernel.GetConfigManager().Apply([]byte("plugins:\n  limits:\n    db: {max_concurrent_jobs: 2}\n")))
+
+	release1, err := kernel.AcquireJob(context.Background(), "db")
+	require.NoError(t, err)
+	release2, err := kernel.AcquireJob(context.Background(), "db")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = kernel.AcquireJob(ctx, "db")
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "both slots taken")
+
+	acquired := make(chan func())
+	go func() {
+		release, err := kernel.AcquireJob(context.Background(), "db")
+		assert.NoError(t, err)
+		acquired <- release
+	}()
+	assert.Eventually(t, func() bool { return kernel.PluginUsage(context.Background())[1].JobsWaiting == 1 },
+		time.Second, time.Millisecond)
+
+	release1()
+	release1() // a second call is a no-op
+	release3 := <-acquired
+
+	usage := kernel.PluginUsage(context.Background())
+	require.Len(t, usage, 2)
+	assert.Equal(t, PluginUsage{Name: "auth"}, usage[0])
+	assert.Equal(t, 2, usage[1].JobsInFlight)
+	assert.Equal(t, 0, usage[1].JobsWaiting)
+	assert.Equal(t, 2, usage[1].Limits.MaxConcurrentJobs)
+
+	release2()
+	release3()
+	// Other plugins are only counted.
+	for range 5 {
+		_, err := kernel.AcquireJob(context.Background(), "auth")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 5, kernel.PluginUsage(context.Background())[0].JobsInFlight)
+}
+
+func TestMicrokernel_EnforceLimits(t *testing.T) {
+	kernel := newAdminTestKernel(t)
+	hungry := &reportingPlugin{
+		mockPlugin: mockPlugin{name: "hungry", version: "1.0.0"},
+		usage:      ResourceUsage{Goroutines: 10, MemoryBytes: 300 << 20, CPUSeconds: 1.5},
+	}
+	busy := &reportingPlugin{
+		mockPlugin: mockPlugin{name: "busy", version: "1.0.0"},
+		usage:      ResourceUsage{Goroutines: 5000, MemoryBytes: 64 << 20},
+	}
+	for _, p := range []*reportingPlugin{hungry, busy} {
+		require.NoError(t, kernel.RegisterPlugin(p))
+		require.NoError(t, kernel.StartPlugin(context.Background(), p.name))
+	}
+	require.NoError(t, kernel.GetConfigManager().Apply([]byte(
+		"plugins:\n  limits:\n    hungry: {max_memory_mb: 256}\n    busy: {max_memory_mb: 256}\n")))
+
+	kernel.enforceLimits(context.Background())
+	assert.Equal(t, 2, hungry.starts, "restarted over max_memory_mb")
+	assert.Equal(t, 1, busy.starts, "within its limits")
+	hungry.usage.MemoryBytes = 32 << 20 // the new process starts small
+
+	require.NoError(t, kernel.GetConfigManager().Apply([]byte("plugins:\n  limits:\n    busy: {max_goroutines: 1000}\n")))
+	kernel.enforceLimits(context.Background())
+	assert.Equal(t, 2, busy.starts, "restarted over max_goroutines")
+
+	var usage PluginUsage
+	for _, u := range kernel.PluginUsage(context.Background()) {
+		if u.Name == "hungry" {
+			usage = u
+		}
+	}
+	assert.Equal(t, &hungry.usage, usage.Process)
+	assert.Equal(t, map[string]int{"max_memory_mb": 1}, usage.Restarts)
+	for _, info := range kernel.Plugins() {
+		assert.Equal(t, StateRunning, info.State, info.Name)
+	}
+}
+
+func TestMicrokernel_EnforceLimits_Unhealthy(t *testing.T) {
+	kernel := newAdminTestKernel(t)
+	logs, observed := observer.New(zap.WarnLevel)
+	kernel.logger = zap.New(logs)
+	wedged := &reportingPlugin{
+		mockPlugin: mockPlugin{name: "wedged", version: "1.0.0"},
+		err:        context.DeadlineExceeded,
+	}
+	require.NoError(t, kernel.RegisterPlugin(wedged))
+	require.NoError(t, kernel.StartPlugin(context.Background(), "wedged"))
+	require.NoError(t, kernel.GetConfigManager().Apply([]byte("plugins:\n  limits:\n    auth: {max_memory_mb: 256}\n")))
+
+	kernel.enforceLimits(context.Background())
+	assert.Equal(t, 2, wedged.starts, "restarted without any limit set")
+	kernel.enforceLimits(context.Background())
+	assert.Equal(t, 3, wedged.starts)
+
+	for _, u := range kernel.PluginUsage(context.Background()) {
+		if u.Name == "wedged" {
+			assert.Equal(t, map[string]int{"usage": 2}, u.Restarts)
+			assert.Equal(t, context.DeadlineExceeded.Error(), u.Error)
+		}
+	}
+	unenforced := observed.FilterMessageSnippet("not enforced").All()
+	require.Len(t, unenforced, 1, "warned once")
+	assert.Equal(t, "auth", unenforced[0].ContextMap()["name"])
+}
@@ -0,0 +1,414 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// ErrPoolQueueFull is returned by TrySubmit when the queue has no room.
+	ErrPoolQueueFull = errors.New("worker pool queue is full")
+	// ErrPoolClosed is returned when submitting to a pool that is draining
+	// or stopped, or was never started.
+	ErrPoolClosed = errors.New("worker pool is closed")
+)
+
+var (
+	workerPoolWorkers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "streamgate_worker_pool_workers",
+		Help: "Number of workers in the pool",
+	}, []string{"pool"})
+	workerPoolBusy = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "streamgate_worker_pool_busy_workers",
+		Help: "Number of workers currently running an item",
+	}, []string{"pool"})
+	workerPoolQueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "streamgate_worker_pool_queue_depth",
+		Help: "Items waiting in the pool's bounded queue",
+	}, []string{"pool"})
+	workerPoolItemsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "streamgate_worker_pool_items_total",
+		Help: "Items handled or rejected by the pool, by result",
+	}, []string{"pool", "result"})
+)
+
+func init() {
+	for _, c := range []prometheus.Collector{workerPoolWorkers, workerPoolBusy, workerPoolQueueDepth, workerPoolItemsTotal} {
+		if err := prometheus.Register(c); err != nil {
+			if _, ok := err.(prometheus.AlreadyRegisteredError); !ok {
+				panic(err)
+			}
+		}
+	}
+}
+
+// WorkerFunc handles one item. worker is the index of the worker running it,
+// stable for the worker's lifetime and in [0, Size()) unless the pool was
+// shrunk while the item was in flight.
+type WorkerFunc[T any] func(ctx context.Context, worker int, item T)
+
+// WorkerPoolConfig configures a WorkerPool.
+type WorkerPoolConfig[T any] struct {
+	// Name labels the pool's metrics.
+	Name string
+	// Workers is the initial worker count.
+	Workers int
+	// QueueSize bounds the queue Submit and TrySubmit add to.
+	QueueSize int
+	// OnDiscard, if set, is called with items the pool accepted but will
+	// not run: those left queued when Stop returns and those Feed could
+	// not submit. Callers that own the items use it to put them back.
+	OnDiscard func(item T)
+}
+
+// WorkerPoolStats is a snapshot of pool state.
+type WorkerPoolStats struct {
+	Workers   int
+	Busy      int
+	Queued    int
+	Completed int64
+	Panicked  int64
+	Rejected  int64
+}
+
+// WorkerPool runs items from a bounded queue on a resizable set of
+// workers. Submit blocks while the queue is full and TrySubmit fails, so
+// producers feel backpressure instead of growing an unbounded backlog.
+type WorkerPool[T any] struct {
+	name      string
+	fn        WorkerFunc[T]
+	onDiscard func(T)
+	queue     chan T
+	// closing is closed once the pool stops accepting items; drain is
+	// closed after that, once in-flight submitters have landed theirs.
+	closing   chan struct{}
+	closeOnce sync.Once
+	drain     chan struct{}
+
+	mu      sync.RWMutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stops   []context.CancelFunc
+	closed  bool
+	started bool
+	wg      sync.WaitGroup
+	// submitters tracks Submit/TrySubmit calls that passed the closed check
+	// so Drain can wait for them before telling workers to finish up.
+	submitters sync.WaitGroup
+
+	busy      atomic.Int64
+	completed atomic.Int64
+	panicked  atomic.Int64
+	rejected  atomic.Int64
+	initial   int
+}
+
+// NewWorkerPool creates a pool; call Start to launch its workers.
+func NewWorkerPool[T any](cfg WorkerPoolConfig[T], fn WorkerFunc[T]) *WorkerPool[T] {
+	if cfg.Name == "" {
+		cfg.Name = "default"
+	}
+	size := cfg.QueueSize
+	if size <= 0 {
+		size = 1
+	}
+	return &WorkerPool[T]{
+		name:      cfg.Name,
+		fn:        fn,
+		onDiscard: cfg.OnDiscard,
+		queue:     make(chan T, size),
+		closing:   make(chan struct{}),
+		drain:     make(chan struct{}),
+		initial:   cfg.Workers,
+	}
+}
+
+// Start launches the initial workers. Workers and in-flight items are
+// cancelled when ctx is done.
+func (p *WorkerPool[T]) Start(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.started {
+		return fmt.Errorf("worker pool %s already started", p.name)
+	}
+	p.started = true
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	p.resizeLocked(p.initial)
+	return nil
+}
+
+// Submit enqueues item, blocking while the queue is full until room frees up
+// or ctx is done. This is the backpressure path for producers that can wait.
+func (p *WorkerPool[T]) Submit(ctx context.Context, item T) error {
+	if err := p.enter(); err != nil {
+		return err
+	}
+	defer p.submitters.Done()
+
+	select {
+	case p.queue <- item:
+		workerPoolQueueDepth.WithLabelValues(p.name).Set(float64(len(p.queue)))
+		return nil
+	case <-ctx.Done():
+		p.reject()
+		return ctx.Err()
+	case <-p.ctx.Done():
+		p.reject()
+		return ErrPoolClosed
+	}
+}
+
+// TrySubmit enqueues item without blocking, returning ErrPoolQueueFull if the
+// queue has no room.
+func (p *WorkerPool[T]) TrySubmit(item T) error {
+	if err := p.enter(); err != nil {
+		return err
+	}
+	defer p.submitters.Done()
+
+	select {
+	case p.queue <- item:
+		workerPoolQueueDepth.WithLabelValues(p.name).Set(float64(len(p.queue)))
+		return nil
+	default:
+		p.reject()
+		return ErrPoolQueueFull
+	}
+}
+
+// Feed submits the items next returns until ctx is done or the pool
+// closes. It puts the pool behind a queue the caller keeps, e.g. one
+// ordered by priority: Submit blocks while the pool is full, so next is not
+// called again, and the caller's queue keeps its order, until a worker
+// frees room. next must block until an item is available or ctx is done.
+// An item next returned that cannot be submitted goes to OnDiscard.
+func (p *WorkerPool[T]) Feed(ctx context.Context, next func(ctx context.Context) (T, error)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-p.closing:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		item, err := next(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			continue
+		}
+		if err := p.Submit(ctx, item); err != nil {
+			p.discard(item)
+			return err
+		}
+	}
+}
+
+// enter registers a submitter if the pool is accepting items. The lock is
+// not held while the submitter blocks, so Resize and Stop stay responsive
+// under backpressure.
+func (p *WorkerPool[T]) enter() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if !p.started || p.closed {
+		p.reject()
+		return ErrPoolClosed
+	}
+	p.submitters.Add(1)
+	return nil
+}
+
+func (p *WorkerPool[T]) reject() {
+	p.rejected.Add(1)
+	workerPoolItemsTotal.WithLabelValues(p.name, "rejected").Inc()
+}
+
+func (p *WorkerPool[T]) discard(item T) {
+	workerPoolItemsTotal.WithLabelValues(p.name, "discarded").Inc()
+	if p.onDiscard != nil {
+		p.onDiscard(item)
+	}
+}
+
+// close marks the pool as no longer accepting items. Callers hold p.mu.
+func (p *WorkerPool[T]) close() {
+	p.closed = true
+	p.closeOnce.Do(func() { close(p.closing) })
+}
+
+// Resize grows or shrinks the pool to n workers. Removed workers finish
+// their current item before exiting.
+func (p *WorkerPool[T]) Resize(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.started || p.closed {
+		p.initial = n
+		return
+	}
+	p.resizeLocked(n)
+}
+
+func (p *WorkerPool[T]) resizeLocked(n int) {
+	if n < 0 {
+		n = 0
+	}
+	for len(p.stops) < n {
+		wctx, stop := context.WithCancel(p.ctx)
+		idx := len(p.stops)
+		p.stops = append(p.stops, stop)
+		p.wg.Add(1)
+		go p.runWorker(wctx, idx)
+	}
+	for len(p.stops) > n {
+		last := len(p.stops) - 1
+		p.stops[last]()
+		p.stops = p.stops[:last]
+	}
+	workerPoolWorkers.WithLabelValues(p.name).Set(float64(len(p.stops)))
+}
+
+// Size returns the current worker count.
+func (p *WorkerPool[T]) Size() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.stops)
+}
+
+// QueueLen returns the number of items waiting in the queue.
+func (p *WorkerPool[T]) QueueLen() int {
+	return len(p.queue)
+}
+
+// runWorker takes items until wctx is done (pool stopped or worker
+// removed) or, once draining, the queue is empty.
+func (p *WorkerPool[T]) runWorker(wctx context.Context, idx int) {
+	defer p.wg.Done()
+
+	for {
+		var item T
+		select {
+		case <-wctx.Done():
+			return
+		case item = <-p.queue:
+		case <-p.drain:
+			select {
+			case item = <-p.queue:
+			default:
+				return
+			}
+		}
+		workerPoolQueueDepth.WithLabelValues(p.name).Set(float64(len(p.queue)))
+		if p.ctx.Err() != nil {
+			// The select may pick the item over a Stop that arrived
+			// with it; a stopped pool must not start new work.
+			p.discard(item)
+			return
+		}
+		p.run(idx, item)
+	}
+}
+
+func (p *WorkerPool[T]) run(idx int, item T) {
+	workerPoolBusy.WithLabelValues(p.name).Set(float64(p.busy.Add(1)))
+	defer func() {
+		workerPoolBusy.WithLabelValues(p.name).Set(float64(p.busy.Add(-1)))
+		if r := recover(); r != nil {
+			p.panicked.Add(1)
+			workerPoolItemsTotal.WithLabelValues(p.name, "panicked").Inc()
+			return
+		}
+		p.completed.Add(1)
+		workerPoolItemsTotal.WithLabelValues(p.name, "completed").Inc()
+	}()
+	p.fn(p.ctx, idx, item)
+}
+
+// Drain stops accepting new items, lets workers finish everything already
+// queued, and waits for them to exit or ctx to expire.
+func (p *WorkerPool[T]) Drain(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.started || p.closed {
+		p.mu.Unlock()
+		return p.wait(ctx)
+	}
+	p.close()
+	p.mu.Unlock()
+
+	// No new submitters can enter; once the in-flight ones land their
+	// items, workers empty the queue and exit.
+	p.submitters.Wait()
+	close(p.drain)
+
+	err := p.wait(ctx)
+	p.mu.Lock()
+	p.stops = nil
+	workerPoolWorkers.WithLabelValues(p.name).Set(0)
+	p.mu.Unlock()
+	return err
+}
+
+// Stop cancels all workers and in-flight items without draining the queue
+// and waits for them to exit or ctx to expire. Items still queued go to
+// OnDiscard.
+func (p *WorkerPool[T]) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.close()
+	p.stops = nil
+	workerPoolWorkers.WithLabelValues(p.name).Set(0)
+	p.mu.Unlock()
+
+	// Submitters blocked on a full queue return now that the pool's context
+	// is done; once they have, nothing more can land in the queue.
+	p.submitters.Wait()
+	err := p.wait(ctx)
+	for {
+		select {
+		case item := <-p.queue:
+			p.discard(item)
+		default:
+			workerPoolQueueDepth.WithLabelValues(p.name).Set(0)
+			return err
+		}
+	}
+}
+
+func (p *WorkerPool[T]) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stats returns a snapshot of the pool's state.
+func (p *WorkerPool[T]) Stats() WorkerPoolStats {
+	return WorkerPoolStats{
+		Workers:   p.Size(),
+		Busy:      int(p.busy.Load()),
+		Queued:    p.QueueLen(),
+		Completed: p.completed.Load(),
+		Panicked:  p.panicked.Load(),
+		Rejected:  p.rejected.Load(),
+	}
+}
@@ -0,0 +1,240 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerPool_SubmitAndDrain(t *testing.T) {
+	var sum atomic.Int64
+	p := NewWorkerPool(WorkerPoolConfig[int]{Name: "test-drain", Workers: 3, QueueSize: 100},
+		func(_ context.Context, _ int, n int) {
+			time.Sleep(time.Millisecond)
+			sum.Add(int64(n))
+		})
+	require.NoError(t, p.Start(context.Background()))
+
+	for i := 1; i <= 50; i++ {
+		require.NoError(t, p.Submit(context.Background(), i))
+	}
+	require.NoError(t, p.Drain(context.Background()))
+
+	assert.Equal(t, int64(50*51/2), sum.Load(), "drain must finish every queued item")
+	assert.Equal(t, int64(50), p.Stats().Completed)
+	assert.Equal(t, 0, p.Size())
+	assert.ErrorIs(t, p.Submit(context.Background(), 1), ErrPoolClosed)
+}
+
+func TestWorkerPool_Backpressure(t *testing.T) {
+	release := make(chan struct{})
+	p := NewWorkerPool(WorkerPoolConfig[int]{Name: "test-backpressure", Workers: 1, QueueSize: 2},
+		func(_ context.Context, _ int, _ int) { <-release })
+	require.NoError(t, p.Start(context.Background()))
+	defer func() { _ = p.Stop(context.Background()) }()
+
+	// One item occupies the worker, two fill the queue.
+	require.NoError(t, p.Submit(context.Background(), 1))
+	require.Eventually(t, func() bool { return p.Stats().Busy == 1 }, time.Second, time.Millisecond)
+	require.NoError(t, p.Submit(context.Background(), 2))
+	require.NoError(t, p.Submit(context.Background(), 3))
+
+	assert.ErrorIs(t, p.TrySubmit(4), ErrPoolQueueFull)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, p.Submit(ctx, 4), context.DeadlineExceeded, "Submit must block while the queue is full")
+	assert.Equal(t, int64(2), p.Stats().Rejected)
+
+	// A blocked Submit proceeds as soon as the worker frees a slot.
+	submitted := make(chan error, 1)
+	go func() { submitted <- p.Submit(context.Background(), 5) }()
+	select {
+	case <-submitted:
+		t.Fatal("Submit returned before room was available")
+	case <-time.After(20 * time.Millisecond):
+	}
+	release <- struct{}{}
+	require.NoError(t, <-submitted)
+	close(release)
+}
+
+func TestWorkerPool_Resize(t *testing.T) {
+	var mu sync.Mutex
+	active, peak := 0, 0
+	release := make(chan struct{})
+	p := NewWorkerPool(WorkerPoolConfig[int]{Name: "test-resize", Workers: 1, QueueSize: 100},
+		func(_ context.Context, _ int, _ int) {
+			mu.Lock()
+			active++
+			if active > peak {
+				peak = active
+			}
+			mu.Unlock()
+			<-release
+			mu.Lock()
+			active--
+			mu.Unlock()
+		})
+	require.NoError(t, p.Start(context.Background()))
+	defer func() { _ = p.Stop(context.Background()) }()
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, p.Submit(context.Background(), i))
+	}
+	require.Eventually(t, func() bool { return p.Stats().Busy == 1 }, time.Second, time.Millisecond)
+
+	p.Resize(4)
+	assert.Equal(t, 4, p.Size())
+	require.Eventually(t, func() bool { return p.Stats().Busy == 4 }, time.Second, time.Millisecond)
+
+	p.Resize(2)
+	assert.Equal(t, 2, p.Size())
+	close(release)
+	require.NoError(t, p.Drain(context.Background()))
+
+	assert.Equal(t, 4, peak)
+	assert.Equal(t, int64(10), p.Stats().Completed, "shrinking must not drop in-flight items")
+}
+
+func TestWorkerPool_Feed(t *testing.T) {
+	items := make(chan string, 3)
+	items <- "a"
+	items <- "b"
+	items <- "c"
+	next := func(ctx context.Context) (string, error) {
+		select {
+		case s := <-items:
+			return s, nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	var mu sync.Mutex
+	var got []string
+	p := NewWorkerPool(WorkerPoolConfig[string]{Name: "test-feed", Workers: 1, QueueSize: 1},
+		func(_ context.Context, _ int, s string) {
+			mu.Lock()
+			got = append(got, s)
+			mu.Unlock()
+		})
+	require.NoError(t, p.Start(context.Background()))
+	fed := make(chan error, 1)
+	go func() { fed <- p.Feed(context.Background(), next) }()
+
+	require.Eventually(t, func() bool { return p.Stats().Completed == 3 }, time.Second, time.Millisecond)
+	require.NoError(t, p.Drain(context.Background()))
+	assert.Error(t, <-fed, "Feed returns once the pool closes")
+	assert.Equal(t, []string{"a", "b", "c"}, got)
+}
+
+func TestWorkerPool_FeedBackpressure(t *testing.T) {
+	items := make(chan int, 10)
+	for i := 0; i < 10; i++ {
+		items <- i
+	}
+	next := func(ctx context.Context) (int, error) {
+		select {
+		case n := <-items:
+			return n, nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var discarded []int
+	p := NewWorkerPool(WorkerPoolConfig[int]{
+		Name:      "test-feed-backpressure",
+		Workers:   1,
+		QueueSize: 2,
+		OnDiscard: func(n int) {
+			mu.Lock()
+			discarded = append(discarded, n)
+			mu.Unlock()
+		},
+	}, func(_ context.Context, _ int, _ int) { <-release })
+	require.NoError(t, p.Start(context.Background()))
+	fed := make(chan error, 1)
+	go func() { fed <- p.Feed(context.Background(), next) }()
+
+	// One item runs, two fill the queue and Feed holds a fourth; the rest
+	// stay with the caller.
+	require.Eventually(t, func() bool { return len(items) == 6 }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	assert.Len(t, items, 6, "Feed must not take more while the pool is full")
+	assert.Equal(t, 2, p.QueueLen())
+
+	close(release)
+	require.NoError(t, p.Stop(context.Background()))
+	<-fed
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 10-len(items), int(p.Stats().Completed)+len(discarded),
+		"items taken from the caller either run or are handed back")
+}
+
+func TestWorkerPool_StopDiscardsQueued(t *testing.T) {
+	started := make(chan struct{})
+	var discarded []int
+	p := NewWorkerPool(WorkerPoolConfig[int]{
+		Name:      "test-stop-discard",
+		Workers:   1,
+		QueueSize: 2,
+		OnDiscard: func(n int) { discarded = append(discarded, n) },
+	}, func(ctx context.Context, _ int, _ int) {
+		close(started)
+		<-ctx.Done()
+	})
+	require.NoError(t, p.Start(context.Background()))
+	require.NoError(t, p.Submit(context.Background(), 1))
+	<-started
+	require.NoError(t, p.Submit(context.Background(), 2))
+	require.NoError(t, p.Submit(context.Background(), 3))
+
+	require.NoError(t, p.Stop(context.Background()))
+	assert.Equal(t, []int{2, 3}, discarded)
+	assert.Equal(t, 0, p.QueueLen())
+}
+
+func TestWorkerPool_PanicRecovered(t *testing.T) {
+	p := NewWorkerPool(WorkerPoolConfig[int]{Name: "test-panic", Workers: 1, QueueSize: 2},
+		func(_ context.Context, _ int, n int) {
+			if n == 0 {
+				panic("boom")
+			}
+		})
+	require.NoError(t, p.Start(context.Background()))
+	require.NoError(t, p.Submit(context.Background(), 0))
+	require.NoError(t, p.Submit(context.Background(), 1))
+	require.NoError(t, p.Drain(context.Background()))
+
+	stats := p.Stats()
+	assert.Equal(t, int64(1), stats.Panicked)
+	assert.Equal(t, int64(1), stats.Completed)
+}
+
+func TestWorkerPool_StopCancelsInFlight(t *testing.T) {
+	started := make(chan struct{})
+	p := NewWorkerPool(WorkerPoolConfig[int]{Name: "test-stop", Workers: 1, QueueSize: 1},
+		func(ctx context.Context, _ int, _ int) {
+			close(started)
+			<-ctx.Done()
+		})
+	require.NoError(t, p.Start(context.Background()))
+	require.NoError(t, p.Submit(context.Background(), 1))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, p.Stop(ctx))
+	assert.True(t, errors.Is(p.TrySubmit(2), ErrPoolClosed))
+}
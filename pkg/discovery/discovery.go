@@ -0,0 +1,114 @@
+// Package discovery registers microservices on start and resolves them by
+// name. Consul, etcd and static DNS backends are supported.
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+
+	"go.uber.org/zap"
+)
+
+// Backend names accepted in discovery.backend.
+const (
+	BackendConsul = "consul"
+	BackendEtcd   = "etcd"
+	BackendDNS    = "dns"
+)
+
+// Registry handles service registration and discovery
+type Registry interface {
+	Register(ctx context.Context, service *ServiceInfo) error
+	Deregister(ctx context.Context, serviceID string) error
+	Discover(ctx context.Context, serviceName string) ([]*ServiceInfo, error)
+	Watch(ctx context.Context, serviceName string) (<-chan []*ServiceInfo, error)
+	Health(ctx context.Context) error
+}
+
+// ServiceInfo contains service registration information
+type ServiceInfo struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	Address  string            `json:"address"`
+	Port     int               `json:"port"`
+	Tags     []string          `json:"tags"`
+	Metadata map[string]string `json:"metadata"`
+	Check    *HealthCheck      `json:"check"`
+}
+
+// HealthCheck contains health check configuration
+type HealthCheck struct {
+	HTTP     string `json:"http"`
+	Interval string `json:"interval"`
+	Timeout  string `json:"timeout"`
+}
+
+// HostPort returns the instance address in host:port form.
+func (s *ServiceInfo) HostPort() string {
+	return net.JoinHostPort(s.Address, strconv.Itoa(s.Port))
+}
+
+// New returns the registry selected by cfg.Discovery.Backend.
+func New(cfg *config.Config, logger *zap.Logger) (Registry, error) {
+	switch strings.ToLower(cfg.Discovery.Backend) {
+	case "", BackendConsul:
+		return NewConsulRegistry(cfg, logger)
+	case BackendEtcd:
+		return NewEtcdRegistry(cfg.Discovery.Etcd, logger)
+	case BackendDNS:
+		return NewDNSRegistry(cfg.Discovery.DNS, logger), nil
+	default:
+		return nil, fmt.Errorf("unknown discovery backend %q", cfg.Discovery.Backend)
+	}
+}
+
+// pollWatch implements Watch for backends without change notifications:
+// it calls discover every interval and sends the instances whenever the
+// set changes. The channel is closed when ctx is done.
+func pollWatch(ctx context.Context, interval time.Duration, discover func(context.Context) ([]*ServiceInfo, error), logger *zap.Logger, serviceName string) <-chan []*ServiceInfo {
+	ch := make(chan []*ServiceInfo)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		last := ""
+		first := true
+		for {
+			services, err := discover(ctx)
+			if err != nil {
+				logger.Warn("Error watching services",
+					zap.String("service_name", serviceName),
+					zap.Error(err))
+			} else if key := instanceSetKey(services); first || key != last {
+				first, last = false, key
+				select {
+				case ch <- services:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+func instanceSetKey(services []*ServiceInfo) string {
+	keys := make([]string, len(services))
+	for i, s := range services {
+		keys[i] = s.ID + "@" + s.HostPort()
+	}
+	slices.Sort(keys)
+	return strings.Join(keys, ",")
+}
@@ -0,0 +1,148 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestDNSRegistry_Discover(t *testing.T) {
+	r := NewDNSRegistry(config.DNSDiscoveryConfig{Domain: "svc.cluster.local.", Port: 9000}, zap.NewNop())
+	r.lookupSRV = func(_ context.Context, service, proto, name string) (string, []*net.SRV, error) {
+		if service == "upload" && proto == "tcp" && name == "svc.cluster.local" {
+			return "", []*net.SRV{{Target: "upload-0.svc.cluster.local.", Port: 8081}}, nil
+		}
+		return "", nil, &net.DNSError{Err: "no such host", IsNotFound: true}
+	}
+	r.lookupHost = func(_ context.Context, host string) ([]string, error) {
+		switch host {
+		case "auth.svc.cluster.local":
+			return []string{"10.0.0.1", "10.0.0.2"}, nil
+		case "broken.svc.cluster.local":
+			return nil, &net.DNSError{Err: "server misbehaving", IsTemporary: true}
+		}
+		return nil, &net.DNSError{Err: "no such host", IsNotFound: true}
+	}
+	ctx := context.Background()
+
+	found, err := r.Discover(ctx, "upload")
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "upload-0.svc.cluster.local:8081", found[0].HostPort(), "SRV records carry their own port")
+
+	found, err = r.Discover(ctx, "auth")
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	assert.Equal(t, "10.0.0.2:9000", found[1].HostPort(), "A records use the configured port")
+
+	found, err = r.Discover(ctx, "missing")
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	_, err = r.Discover(ctx, "broken")
+	assert.Error(t, err)
+
+	assert.NoError(t, r.Register(ctx, &ServiceInfo{ID: "x"}), "registration is managed by the platform")
+	assert.NoError(t, r.Deregister(ctx, "x"))
+}
+
+type countingRegistry struct {
+	Registry
+	mu        sync.Mutex
+	calls     int
+	instances []*ServiceInfo
+	err       error
+}
+
+func (r *countingRegistry) Discover(context.Context, string) ([]*ServiceInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	return r.instances, r.err
+}
+
+func TestResolver_CachesAndRotates(t *testing.T) {
+	reg := &countingRegistry{instances: []*ServiceInfo{
+		{Address: "10.0.0.1", Port: 80},
+		{Address: "10.0.0.2", Port: 80},
+	}}
+	now := time.Now()
+	res := NewResolver(reg, time.Second)
+	res.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		addr, err := res.Resolve(ctx, "metadata")
+		require.NoError(t, err)
+		got = append(got, addr)
+	}
+	assert.Equal(t, []string{"10.0.0.1:80", "10.0.0.2:80", "10.0.0.1:80", "10.0.0.2:80"}, got)
+	assert.Equal(t, 1, reg.calls, "lookups are cached within the TTL")
+
+	// Once the TTL passes the registry is asked again; if it fails, the
+	// last known instances keep serving.
+	now = now.Add(2 * time.Second)
+	reg.err = errors.New("consul down")
+	_, err := res.Resolve(ctx, "metadata")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, reg.calls)
+
+	_, err = res.Resolve(ctx, "never-seen")
+	assert.Error(t, err)
+
+	reg.err = nil
+	reg.instances = nil
+	_, err = res.Resolve(ctx, "empty")
+	assert.ErrorContains(t, err, "no instances")
+}
+
+func TestPollWatch_SendsOnChange(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var mu sync.Mutex
+	instances := []*ServiceInfo{{ID: "a", Address: "10.0.0.1", Port: 80}}
+	ch := pollWatch(ctx, 10*time.Millisecond, func(context.Context) ([]*ServiceInfo, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return instances, nil
+	}, zap.NewNop(), "svc")
+
+	first := <-ch
+	require.Len(t, first, 1)
+
+	mu.Lock()
+	instances = append(instances, &ServiceInfo{ID: "b", Address: "10.0.0.2", Port: 80})
+	mu.Unlock()
+	select {
+	case next := <-ch:
+		assert.Len(t, next, 2)
+	case <-time.After(time.Second):
+		t.Fatal("no update after the instance set changed")
+	}
+
+	cancel()
+	for range ch {
+	}
+}
+
+func TestNew_UnknownBackend(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Discovery.Backend = "zookeeper"
+	_, err := New(cfg, zap.NewNop())
+	assert.ErrorContains(t, err, "unknown discovery backend")
+
+	cfg.Discovery.Backend = BackendDNS
+	reg, err := New(cfg, zap.NewNop())
+	require.NoError(t, err)
+	assert.IsType(t, &DNSRegistry{}, reg)
+}
@@ -0,0 +1,120 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultDNSPort   = 8080
+	dnsWatchInterval = 10 * time.Second
+)
+
+// DNSRegistry implements Registry on records managed outside StreamGate,
+// such as Kubernetes or docker-compose service names. Register and
+// Deregister are no-ops. Discover tries SRV records
+// _<name>._tcp.<domain> first and falls back to the A/AAAA records of
+// <name>.<domain> on the configured port.
+type DNSRegistry struct {
+	domain string
+	port   int
+	logger *zap.Logger
+
+	lookupSRV  func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+}
+
+// NewDNSRegistry creates a DNS registry using the system resolver.
+func NewDNSRegistry(cfg config.DNSDiscoveryConfig, logger *zap.Logger) *DNSRegistry {
+	port := cfg.Port
+	if port <= 0 {
+		port = defaultDNSPort
+	}
+	logger.Info("Initializing DNS registry",
+		zap.String("domain", cfg.Domain),
+		zap.Int("port", port))
+	return &DNSRegistry{
+		domain:     strings.Trim(cfg.Domain, "."),
+		port:       port,
+		logger:     logger,
+		lookupSRV:  net.DefaultResolver.LookupSRV,
+		lookupHost: net.DefaultResolver.LookupHost,
+	}
+}
+
+// Register is a no-op: DNS records are managed by the platform.
+func (r *DNSRegistry) Register(ctx context.Context, service *ServiceInfo) error {
+	r.logger.Debug("DNS discovery: skipping registration", zap.String("service_id", service.ID))
+	return nil
+}
+
+// Deregister is a no-op: DNS records are managed by the platform.
+func (r *DNSRegistry) Deregister(ctx context.Context, serviceID string) error {
+	return nil
+}
+
+// Discover resolves serviceName. A name with no records yields no
+// instances rather than an error.
+func (r *DNSRegistry) Discover(ctx context.Context, serviceName string) ([]*ServiceInfo, error) {
+	if r.domain != "" {
+		_, srvs, err := r.lookupSRV(ctx, serviceName, "tcp", r.domain)
+		if err != nil && !isNotFound(err) {
+			return nil, fmt.Errorf("failed to discover services: %w", err)
+		}
+		if len(srvs) > 0 {
+			services := make([]*ServiceInfo, 0, len(srvs))
+			for _, srv := range srvs {
+				services = append(services, r.instance(serviceName, strings.TrimSuffix(srv.Target, "."), int(srv.Port)))
+			}
+			return services, nil
+		}
+	}
+
+	host := serviceName
+	if r.domain != "" {
+		host += "." + r.domain
+	}
+	addrs, err := r.lookupHost(ctx, host)
+	if err != nil {
+		if isNotFound(err) {
+			return []*ServiceInfo{}, nil
+		}
+		return nil, fmt.Errorf("failed to discover services: %w", err)
+	}
+	services := make([]*ServiceInfo, 0, len(addrs))
+	for _, addr := range addrs {
+		services = append(services, r.instance(serviceName, addr, r.port))
+	}
+	return services, nil
+}
+
+// Watch polls Discover and sends the instances whenever they change.
+func (r *DNSRegistry) Watch(ctx context.Context, serviceName string) (<-chan []*ServiceInfo, error) {
+	return pollWatch(ctx, dnsWatchInterval, func(ctx context.Context) ([]*ServiceInfo, error) {
+		return r.Discover(ctx, serviceName)
+	}, r.logger, serviceName), nil
+}
+
+// Health always succeeds; resolution failures surface from Discover.
+func (r *DNSRegistry) Health(ctx context.Context) error {
+	return nil
+}
+
+func (r *DNSRegistry) instance(name, address string, port int) *ServiceInfo {
+	s := &ServiceInfo{Name: name, Address: address, Port: port}
+	s.ID = name + "-" + s.HostPort()
+	return s
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
@@ -0,0 +1,242 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/rtcdance/streamgate/pkg/discovery/etcdkv"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultEtcdPrefix = "/streamgate/services/"
+	defaultEtcdTTL    = 15 * time.Second
+	etcdWatchInterval = 5 * time.Second
+)
+
+// EtcdRegistry implements Registry on etcd's v3 JSON gateway, so no etcd
+// client library is needed. Each instance is stored under
+// <prefix><name>/<id> on a lease that is kept alive until Deregister;
+// a crashed service drops out when its lease expires.
+type EtcdRegistry struct {
+	kv     *etcdkv.Client
+	prefix string
+	ttl    time.Duration
+	logger *zap.Logger
+
+	mu     sync.Mutex
+	leases map[string]*etcdLease // by service ID
+}
+
+// etcdLease is owned by its keepAlive goroutine until done is closed.
+type etcdLease struct {
+	id     int64
+	key    string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewEtcdRegistry creates an etcd registry.
+func NewEtcdRegistry(cfg config.EtcdDiscoveryConfig, logger *zap.Logger) (*EtcdRegistry, error) {
+	if len(cfg.Endpoints) == 0 {
+		return nil, fmt.Errorf("etcd discovery: no endpoints configured")
+	}
+	ttl := defaultEtcdTTL
+	if cfg.TTL != "" {
+		d, err := time.ParseDuration(cfg.TTL)
+		if err != nil || d < 3*time.Second {
+			return nil, fmt.Errorf("etcd discovery: invalid ttl %q (minimum 3s)", cfg.TTL)
+		}
+		ttl = d
+	}
+	prefix := cfg.Prefix
+	if prefix == "" {
+		prefix = defaultEtcdPrefix
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	kv := etcdkv.New(cfg.Endpoints)
+
+	logger.Info("Initializing etcd registry",
+		zap.Strings("endpoints", kv.Endpoints()),
+		zap.String("prefix", prefix))
+	return &EtcdRegistry{
+		kv:     kv,
+		prefix: prefix,
+		ttl:    ttl,
+		logger: logger,
+		leases: make(map[string]*etcdLease),
+	}, nil
+}
+
+// Register stores service under a lease and keeps the lease alive in the
+// background until Deregister.
+func (r *EtcdRegistry) Register(ctx context.Context, service *ServiceInfo) error {
+	r.logger.Info("Registering service",
+		zap.String("service_id", service.ID),
+		zap.String("service_name", service.Name))
+
+	value, err := json.Marshal(service)
+	if err != nil {
+		return fmt.Errorf("failed to encode service: %w", err)
+	}
+	key := r.prefix + service.Name + "/" + service.ID
+	leaseID, err := r.grantAndPut(ctx, key, value)
+	if err != nil {
+		r.logger.Error("Failed to register service",
+			zap.String("service_id", service.ID),
+			zap.Error(err))
+		return fmt.Errorf("failed to register service: %w", err)
+	}
+
+	keepCtx, cancel := context.WithCancel(context.Background())
+	lease := &etcdLease{id: leaseID, key: key, cancel: cancel, done: make(chan struct{})}
+	r.mu.Lock()
+	prev := r.leases[service.ID]
+	r.leases[service.ID] = lease
+	r.mu.Unlock()
+	if prev != nil {
+		prev.cancel()
+		<-prev.done
+	}
+	go r.keepAlive(keepCtx, lease, value)
+
+	r.logger.Info("Service registered successfully",
+		zap.String("service_id", service.ID),
+		zap.String("service_name", service.Name))
+	return nil
+}
+
+// Deregister stops the keepalive and revokes the lease, which deletes the
+// key. IDs registered by another process are deleted by key.
+func (r *EtcdRegistry) Deregister(ctx context.Context, serviceID string) error {
+	r.logger.Info("Deregistering service", zap.String("service_id", serviceID))
+
+	r.mu.Lock()
+	lease := r.leases[serviceID]
+	delete(r.leases, serviceID)
+	r.mu.Unlock()
+
+	var err error
+	if lease != nil {
+		lease.cancel()
+		<-lease.done
+		err = r.kv.Call(ctx, "/v3/lease/revoke", map[string]any{"ID": fmt.Sprint(lease.id)}, nil)
+	} else {
+		err = r.deleteByID(ctx, serviceID)
+	}
+	if err != nil {
+		r.logger.Error("Failed to deregister service",
+			zap.String("service_id", serviceID),
+			zap.Error(err))
+		return fmt.Errorf("failed to deregister service: %w", err)
+	}
+	r.logger.Info("Service deregistered successfully", zap.String("service_id", serviceID))
+	return nil
+}
+
+// Discover lists the registered instances of serviceName.
+func (r *EtcdRegistry) Discover(ctx context.Context, serviceName string) ([]*ServiceInfo, error) {
+	kvs, err := r.kv.Range(ctx, r.prefix+serviceName+"/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover services: %w", err)
+	}
+	services := make([]*ServiceInfo, 0, len(kvs))
+	for _, kv := range kvs {
+		var svc ServiceInfo
+		if err := json.Unmarshal(kv.Value, &svc); err != nil {
+			r.logger.Warn("Skipping malformed service entry", zap.String("key", string(kv.Key)), zap.Error(err))
+			continue
+		}
+		services = append(services, &svc)
+	}
+	return services, nil
+}
+
+// Watch polls Discover and sends the instances whenever they change.
+func (r *EtcdRegistry) Watch(ctx context.Context, serviceName string) (<-chan []*ServiceInfo, error) {
+	return pollWatch(ctx, etcdWatchInterval, func(ctx context.Context) ([]*ServiceInfo, error) {
+		return r.Discover(ctx, serviceName)
+	}, r.logger, serviceName), nil
+}
+
+// Health checks that an etcd endpoint answers.
+func (r *EtcdRegistry) Health(ctx context.Context) error {
+	if err := r.kv.Call(ctx, "/v3/maintenance/status", struct{}{}, nil); err != nil {
+		return fmt.Errorf("etcd health check failed: %w", err)
+	}
+	return nil
+}
+
+func (r *EtcdRegistry) keepAlive(ctx context.Context, lease *etcdLease, value []byte) {
+	defer close(lease.done)
+	ticker := time.NewTicker(r.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		var resp struct {
+			Result struct {
+				TTL int64 `json:"TTL,string"`
+			} `json:"result"`
+		}
+		err := r.kv.Call(ctx, "/v3/lease/keepalive", map[string]any{"ID": fmt.Sprint(lease.id)}, &resp)
+		if err == nil && resp.Result.TTL > 0 {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			r.logger.Warn("etcd lease keepalive failed", zap.String("key", lease.key), zap.Error(err))
+			continue
+		}
+		// The lease expired (etcd was unreachable for longer than the
+		// TTL); register again under a new one.
+		id, err := r.grantAndPut(ctx, lease.key, value)
+		if err != nil {
+			r.logger.Warn("etcd re-registration failed", zap.String("key", lease.key), zap.Error(err))
+			continue
+		}
+		lease.id = id
+		r.logger.Info("etcd lease expired; service re-registered", zap.String("key", lease.key))
+	}
+}
+
+func (r *EtcdRegistry) grantAndPut(ctx context.Context, key string, value []byte) (int64, error) {
+	var grant struct {
+		ID int64 `json:"ID,string"`
+	}
+	if err := r.kv.Call(ctx, "/v3/lease/grant", map[string]any{"TTL": int64(r.ttl / time.Second)}, &grant); err != nil {
+		return 0, err
+	}
+	if err := r.kv.Put(ctx, key, value, grant.ID); err != nil {
+		return 0, err
+	}
+	return grant.ID, nil
+}
+
+func (r *EtcdRegistry) deleteByID(ctx context.Context, serviceID string) error {
+	kvs, err := r.kv.Range(ctx, r.prefix)
+	if err != nil {
+		return err
+	}
+	for _, kv := range kvs {
+		if strings.HasSuffix(string(kv.Key), "/"+serviceID) {
+			if err := r.kv.Delete(ctx, kv.Key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeEtcd serves the subset of the etcd v3 JSON gateway the registry uses.
+type fakeEtcd struct {
+	mu         sync.Mutex
+	nextLease  int64
+	leases     map[int64]bool
+	kvs        map[string]fakeKV
+	keepalives int
+}
+
+type fakeKV struct {
+	value []byte
+	lease int64
+}
+
+func newFakeEtcd(t *testing.T) (*fakeEtcd, *httptest.Server) {
+	f := &fakeEtcd{leases: map[int64]bool{}, kvs: map[string]fakeKV{}}
+	srv := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeEtcd) serve(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Key      []byte `json:"key"`
+		RangeEnd []byte `json:"range_end"`
+		Value    []byte `json:"value"`
+		Lease    string `json:"lease"`
+		ID       string `json:"ID"`
+		TTL      int64  `json:"TTL"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var resp any = map[string]any{}
+	switch r.URL.Path {
+	case "/v3/lease/grant":
+		f.nextLease++
+		f.leases[f.nextLease] = true
+		resp = map[string]string{"ID": strconv.FormatInt(f.nextLease, 10), "TTL": strconv.FormatInt(req.TTL, 10)}
+	case "/v3/kv/put":
+		lease, _ := strconv.ParseInt(req.Lease, 10, 64)
+		f.kvs[string(req.Key)] = fakeKV{value: req.Value, lease: lease}
+	case "/v3/kv/range":
+		var kvs []map[string][]byte
+		for k, kv := range f.kvs {
+			if k >= string(req.Key) && k < string(req.RangeEnd) {
+				kvs = append(kvs, map[string][]byte{"key": []byte(k), "value": kv.value})
+			}
+		}
+		resp = map[string]any{"kvs": kvs}
+	case "/v3/kv/deleterange":
+		delete(f.kvs, string(req.Key))
+	case "/v3/lease/keepalive":
+		f.keepalives++
+		id, _ := strconv.ParseInt(req.ID, 10, 64)
+		result := map[string]string{"ID": req.ID}
+		if f.leases[id] {
+			result["TTL"] = "15"
+		}
+		resp = map[string]any{"result": result}
+	case "/v3/lease/revoke":
+		id, _ := strconv.ParseInt(req.ID, 10, 64)
+		f.expire(id)
+	case "/v3/maintenance/status":
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// expire drops a lease and its keys, as etcd does on revoke or timeout.
+func (f *fakeEtcd) expire(id int64) {
+	delete(f.leases, id)
+	for k, kv := range f.kvs {
+		if kv.lease == id {
+			delete(f.kvs, k)
+		}
+	}
+}
+
+func newTestEtcdRegistry(t *testing.T, endpoints ...string) *EtcdRegistry {
+	t.Helper()
+	r, err := NewEtcdRegistry(config.EtcdDiscoveryConfig{Endpoints: endpoints, TTL: "3s"}, zap.NewNop())
+	require.NoError(t, err)
+	return r
+}
+
+func TestEtcdRegistry_RegisterDiscoverDeregister(t *testing.T) {
+	f, srv := newFakeEtcd(t)
+	// The first endpoint is down; calls fail over to the second.
+	r := newTestEtcdRegistry(t, "http://127.0.0.1:1", srv.URL)
+	ctx := context.Background()
+
+	require.NoError(t, r.Register(ctx, &ServiceInfo{ID: "upload-8080", Name: "upload", Address: "10.0.0.1", Port: 8080}))
+	require.NoError(t, r.Register(ctx, &ServiceInfo{ID: "upload-8081", Name: "upload", Address: "10.0.0.2", Port: 8081}))
+	require.NoError(t, r.Register(ctx, &ServiceInfo{ID: "auth-8080", Name: "auth", Address: "10.0.0.3", Port: 8080}))
+	assert.Contains(t, f.kvs, "/streamgate/services/upload/upload-8080")
+
+	found, err := r.Discover(ctx, "upload")
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	assert.ElementsMatch(t, []string{"10.0.0.1:8080", "10.0.0.2:8081"}, []string{found[0].HostPort(), found[1].HostPort()})
+
+	require.NoError(t, r.Deregister(ctx, "upload-8080"))
+	found, err = r.Discover(ctx, "upload")
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "upload-8081", found[0].ID)
+
+	// Unknown IDs (registered by another process) are deleted by key.
+	other := newTestEtcdRegistry(t, srv.URL)
+	require.NoError(t, other.Deregister(ctx, "auth-8080"))
+	found, err = r.Discover(ctx, "auth")
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	assert.NoError(t, r.Health(ctx))
+	require.NoError(t, r.Deregister(ctx, "upload-8081"))
+	require.NoError(t, r.Deregister(ctx, "auth-8080"))
+}
+
+func TestEtcdRegistry_ReRegistersExpiredLease(t *testing.T) {
+	f, srv := newFakeEtcd(t)
+	r := newTestEtcdRegistry(t, srv.URL)
+	ctx := context.Background()
+	require.NoError(t, r.Register(ctx, &ServiceInfo{ID: "cache-1", Name: "cache", Address: "10.0.0.9", Port: 8080}))
+
+	f.mu.Lock()
+	f.expire(1)
+	f.mu.Unlock()
+
+	// Keepalives run every ttl/3 = 1s; the first one finds the lease gone.
+	require.Eventually(t, func() bool {
+		found, err := r.Discover(ctx, "cache")
+		return err == nil && len(found) == 1
+	}, 3*time.Second, 50*time.Millisecond)
+	require.NoError(t, r.Deregister(ctx, "cache-1"))
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	assert.Empty(t, f.kvs)
+	assert.Positive(t, f.keepalives)
+}
+
+func TestNewEtcdRegistry_Validation(t *testing.T) {
+	_, err := NewEtcdRegistry(config.EtcdDiscoveryConfig{}, zap.NewNop())
+	assert.Error(t, err)
+	_, err = NewEtcdRegistry(config.EtcdDiscoveryConfig{Endpoints: []string{"http://etcd:2379"}, TTL: "1s"}, zap.NewNop())
+	assert.Error(t, err, "a TTL under 3s cannot be kept alive reliably")
+}
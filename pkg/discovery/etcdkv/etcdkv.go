@@ -0,0 +1,156 @@
+// Package etcdkv talks to etcd's v3 JSON gateway, so no etcd client
+// library is needed. It is shared by the etcd service registry and the
+// etcd remote config source.
+package etcdkv
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client sends requests to an etcd cluster, trying its endpoints in order.
+type Client struct {
+	endpoints []string
+	http      *http.Client
+}
+
+// KV is a key-value pair as the gateway returns it.
+type KV struct {
+	Key         []byte `json:"key"`
+	Value       []byte `json:"value"`
+	ModRevision int64  `json:"mod_revision,string"`
+}
+
+// New creates a client for the cluster at endpoints, e.g. "http://etcd:2379".
+func New(endpoints []string) *Client {
+	trimmed := make([]string, len(endpoints))
+	for i, ep := range endpoints {
+		trimmed[i] = strings.TrimSuffix(ep, "/")
+	}
+	return &Client{
+		endpoints: trimmed,
+		http:      &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Endpoints returns the endpoints the client tries, in order.
+func (c *Client) Endpoints() []string {
+	return c.endpoints
+}
+
+// Get returns the pair stored under key, or nil if there is none.
+func (c *Client) Get(ctx context.Context, key string) (*KV, error) {
+	var resp struct {
+		KVs []KV `json:"kvs"`
+	}
+	if err := c.Call(ctx, "/v3/kv/range", map[string]any{"key": encode([]byte(key))}, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.KVs) == 0 {
+		return nil, nil
+	}
+	return &resp.KVs[0], nil
+}
+
+// Range returns the pairs whose keys start with prefix.
+func (c *Client) Range(ctx context.Context, prefix string) ([]KV, error) {
+	var resp struct {
+		KVs []KV `json:"kvs"`
+	}
+	req := map[string]any{
+		"key":       encode([]byte(prefix)),
+		"range_end": encode(PrefixEnd([]byte(prefix))),
+	}
+	if err := c.Call(ctx, "/v3/kv/range", req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.KVs, nil
+}
+
+// Put stores value under key, attached to lease unless lease is 0.
+func (c *Client) Put(ctx context.Context, key string, value []byte, lease int64) error {
+	req := map[string]any{
+		"key":   encode([]byte(key)),
+		"value": encode(value),
+	}
+	if lease != 0 {
+		req["lease"] = fmt.Sprint(lease)
+	}
+	return c.Call(ctx, "/v3/kv/put", req, nil)
+}
+
+// Delete removes key.
+func (c *Client) Delete(ctx context.Context, key []byte) error {
+	return c.Call(ctx, "/v3/kv/deleterange", map[string]any{"key": encode(key)}, nil)
+}
+
+// Call POSTs body to path on each endpoint in turn until one answers, and
+// decodes the response into out unless it is nil. Endpoints that cannot be
+// reached or answer with a 5xx are skipped; other errors are returned.
+func (c *Client) Call(ctx context.Context, path string, body, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	var lastErr error
+	for _, ep := range c.endpoints {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep+path, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := c.http.Do(req)
+		if err != nil {
+			lastErr = err
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+		_ = resp.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			lastErr = fmt.Errorf("etcd %s: %s: %s", path, resp.Status, strings.TrimSpace(string(data)))
+			if resp.StatusCode >= 500 {
+				continue
+			}
+			return lastErr
+		}
+		if out != nil {
+			if err := json.Unmarshal(data, out); err != nil {
+				return fmt.Errorf("etcd %s: decode response: %w", path, err)
+			}
+		}
+		return nil
+	}
+	return lastErr
+}
+
+// PrefixEnd returns the range_end that selects every key starting with
+// prefix: prefix with its last byte incremented.
+func PrefixEnd(prefix []byte) []byte {
+	end := bytes.Clone(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return []byte{0}
+}
+
+// encode converts a key or value to the base64 the gateway expects.
+func encode(b []byte) string {
+	return base64.StdEncoding.EncodeToString(b)
+}
@@ -0,0 +1,62 @@
+package etcdkv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Get(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Key []byte `json:"key"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if r.URL.Path != "/v3/kv/range" || string(req.Key) != "/app/config" {
+			_ = json.NewEncoder(w).Encode(map[string]any{})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"kvs": []map[string]any{{
+			"key":          req.Key,
+			"value":        []byte("port: 9090"),
+			"mod_revision": "7",
+		}}})
+	}))
+	defer srv.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	// Later endpoints are tried when one is down.
+	c := New([]string{down.URL, srv.URL + "/"})
+	kv, err := c.Get(context.Background(), "/app/config")
+	require.NoError(t, err)
+	require.NotNil(t, kv)
+	assert.Equal(t, "port: 9090", string(kv.Value))
+	assert.Equal(t, int64(7), kv.ModRevision)
+
+	kv, err = c.Get(context.Background(), "/app/missing")
+	require.NoError(t, err)
+	assert.Nil(t, kv)
+}
+
+func TestClient_CallError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	err := New([]string{srv.URL}).Call(context.Background(), "/v3/kv/range", struct{}{}, nil)
+	assert.ErrorContains(t, err, "etcd /v3/kv/range: 400 Bad Request: bad request")
+}
+
+func TestPrefixEnd(t *testing.T) {
+	assert.Equal(t, []byte("/a/c"), PrefixEnd([]byte("/a/b")))
+	assert.Equal(t, []byte("/b"), PrefixEnd([]byte{'/', 'a', 0xff}))
+	assert.True(t, bytes.Equal([]byte{0}, PrefixEnd([]byte{0xff})))
+}
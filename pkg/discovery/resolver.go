@@ -0,0 +1,80 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultResolverTTL is how long a Resolver reuses a lookup.
+const DefaultResolverTTL = 5 * time.Second
+
+// Resolver picks an instance of a service for each outgoing request, for
+// callers such as the gateway proxy. Lookups are cached for the TTL so the
+// registry is not queried per request, instances are rotated round-robin,
+// and if the registry becomes unreachable the last known instances keep
+// being served.
+type Resolver struct {
+	registry Registry
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]resolvedService
+	rr    atomic.Uint64
+}
+
+type resolvedService struct {
+	instances []*ServiceInfo
+	at        time.Time
+}
+
+// NewResolver creates a Resolver over registry; ttl <= 0 uses
+// DefaultResolverTTL.
+func NewResolver(registry Registry, ttl time.Duration) *Resolver {
+	if ttl <= 0 {
+		ttl = DefaultResolverTTL
+	}
+	return &Resolver{
+		registry: registry,
+		ttl:      ttl,
+		now:      time.Now,
+		cache:    make(map[string]resolvedService),
+	}
+}
+
+// Resolve returns host:port of an instance of serviceName.
+func (r *Resolver) Resolve(ctx context.Context, serviceName string) (string, error) {
+	instances, err := r.instances(ctx, serviceName)
+	if err != nil {
+		return "", err
+	}
+	if len(instances) == 0 {
+		return "", fmt.Errorf("no instances of service %q", serviceName)
+	}
+	i := r.rr.Add(1) - 1
+	return instances[i%uint64(len(instances))].HostPort(), nil
+}
+
+func (r *Resolver) instances(ctx context.Context, serviceName string) ([]*ServiceInfo, error) {
+	r.mu.Lock()
+	cached, ok := r.cache[serviceName]
+	r.mu.Unlock()
+	if ok && r.now().Sub(cached.at) < r.ttl {
+		return cached.instances, nil
+	}
+
+	instances, err := r.registry.Discover(ctx, serviceName)
+	if err != nil {
+		if ok && len(cached.instances) > 0 {
+			return cached.instances, nil
+		}
+		return nil, err
+	}
+	r.mu.Lock()
+	r.cache[serviceName] = resolvedService{instances: instances, at: r.now()}
+	r.mu.Unlock()
+	return instances, nil
+}
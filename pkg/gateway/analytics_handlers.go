@@ -0,0 +1,105 @@
+package gateway
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/middleware"
+	"github.com/rtcdance/streamgate/pkg/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// playbackWalletKey carries the wallet from a validated playback token, for
+// streaming routes that are not behind the JWT middleware.
+const playbackWalletKey = "playback_wallet"
+
+const defaultAnalyticsWindow = 24 * time.Hour
+
+// RegisterAnalyticsRoutes registers the operator analytics endpoints,
+// restricted to adminWallets.
+func RegisterAnalyticsRoutes(router *gin.RouterGroup, analytics *service.AccessAnalytics, adminWallets []string) {
+	admin := router.Group(APIPrefix+"/admin", requireAdminWallet(adminWallets))
+	admin.GET("/analytics/top", handleTopContentAccess(analytics))
+}
+
+// requireAdminWallet allows only authenticated wallets listed in admins.
+func requireAdminWallet(admins []string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(admins))
+	for _, w := range admins {
+		allowed[strings.ToLower(w)] = true
+	}
+	return func(c *gin.Context) {
+		wallet := middleware.GetWalletAddress(c)
+		if wallet == "" || !allowed[strings.ToLower(wallet)] {
+			abortWithError(c, http.StatusForbidden, ErrForbidden, "admin access required")
+			return
+		}
+		c.Next()
+	}
+}
+
+func handleTopContentAccess(analytics *service.AccessAnalytics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		window := defaultAnalyticsWindow
+		if w := c.Query("window"); w != "" {
+			d, err := time.ParseDuration(w)
+			if err != nil || d <= 0 {
+				abortWithError(c, http.StatusBadRequest, ErrInvalidRequest, "window must be a positive duration, e.g. 24h")
+				return
+			}
+			window = d
+		}
+		if window > analytics.Retention() {
+			window = analytics.Retention()
+		}
+		limit := 10
+		if l := c.Query("limit"); l != "" {
+			if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+				limit = parsed
+			}
+		}
+
+		to := time.Now()
+		from := to.Add(-window)
+		respondOK(c, gin.H{
+			"window":  window.String(),
+			"from":    from.UTC(),
+			"to":      to.UTC(),
+			"content": analytics.Top(from, to, limit),
+		})
+	}
+}
+
+// accessAnalyticsMiddleware records successful streaming responses: a
+// master manifest fetch counts as a view, and every manifest and segment
+// response adds its bytes to the content's bandwidth.
+func accessAnalyticsMiddleware(analytics *service.AccessAnalytics) gin.HandlerFunc {
+	manifestPath := APIPrefix + "/streaming/:id/manifest.m3u8"
+	segmentPath := APIPrefix + "/streaming/:id/segment/:num"
+	dashManifestPath := APIPrefix + "/streaming/:id/dash/" + dashManifestName
+	dashChunkPath := APIPrefix + "/streaming/:id/dash/:chunk"
+	return func(c *gin.Context) {
+		c.Next()
+
+		path := c.FullPath()
+		if path != manifestPath && path != segmentPath && path != dashManifestPath && path != dashChunkPath {
+			return
+		}
+		if c.Writer.Status() != http.StatusOK {
+			return
+		}
+		wallet := c.GetString(playbackWalletKey)
+		if wallet == "" {
+			wallet = middleware.GetWalletAddress(c)
+		}
+		analytics.Record(service.AccessEvent{
+			ContentID: c.Param("id"),
+			Wallet:    strings.ToLower(wallet),
+			View:      (path == manifestPath && c.Query("quality") == "") || path == dashManifestPath,
+			Bytes:     int64(max(c.Writer.Size(), 0)),
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAnalyticsRouter(analytics *service.AccessAnalytics, wallet string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if wallet != "" {
+			c.Set("wallet_address", wallet)
+		}
+		c.Next()
+	})
+	r.Use(accessAnalyticsMiddleware(analytics))
+	r.GET(APIPrefix+"/streaming/:id/manifest.m3u8", func(c *gin.Context) {
+		c.String(http.StatusOK, "#EXTM3U\n")
+	})
+	r.GET(APIPrefix+"/streaming/:id/segment/:num", func(c *gin.Context) {
+		if c.Param("num") == "404" {
+			abortWithError(c, http.StatusNotFound, ErrNotFound, "segment not found")
+			return
+		}
+		c.Set(playbackWalletKey, "0xViewer")
+		c.Data(http.StatusOK, "video/mp2t", make([]byte, 1000))
+	})
+	RegisterAnalyticsRoutes(r.Group("/"), analytics, []string{"0xADMIN"})
+	return r
+}
+
+func TestAccessAnalyticsMiddleware_RecordsStreamingAccess(t *testing.T) {
+	analytics := service.NewAccessAnalytics(service.AccessAnalyticsConfig{})
+	r := newAnalyticsRouter(analytics, "0xViewer")
+
+	for _, path := range []string{
+		"/streaming/c1/manifest.m3u8",
+		"/streaming/c1/manifest.m3u8?quality=720p",
+		"/streaming/c1/segment/0",
+		"/streaming/c1/segment/404",
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, APIPrefix+path, nil))
+	}
+
+	top := analytics.Top(time.Now().Add(-time.Hour), time.Now().Add(time.Hour), 10)
+	require.Len(t, top, 1)
+	assert.Equal(t, "c1", top[0].ContentID)
+	assert.Equal(t, int64(1), top[0].Views, "only the master manifest counts as a view")
+	assert.Equal(t, 1, top[0].UniqueWallets, "wallets are compared case-insensitively")
+	assert.Equal(t, int64(2*len("#EXTM3U\n")+1000), top[0].Bytes, "failed responses are not counted")
+}
+
+func TestTopContentAccess_RequiresAdmin(t *testing.T) {
+	analytics := service.NewAccessAnalytics(service.AccessAnalyticsConfig{})
+
+	for _, wallet := range []string{"", "0xother"} {
+		w := httptest.NewRecorder()
+		newAnalyticsRouter(analytics, wallet).ServeHTTP(w, httptest.NewRequest(http.MethodGet, APIPrefix+"/admin/analytics/top", nil))
+		assert.Equal(t, http.StatusForbidden, w.Code, wallet)
+	}
+
+	w := httptest.NewRecorder()
+	newAnalyticsRouter(analytics, "0xadmin").ServeHTTP(w, httptest.NewRequest(http.MethodGet, APIPrefix+"/admin/analytics/top", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestTopContentAccess_Report(t *testing.T) {
+	analytics := service.NewAccessAnalytics(service.AccessAnalyticsConfig{})
+	for _, ev := range []service.AccessEvent{
+		{ContentID: "c1", Wallet: "0xa", View: true},
+		{ContentID: "c2", Wallet: "0xa", View: true},
+		{ContentID: "c2", Wallet: "0xb", View: true},
+		{ContentID: "c3", Wallet: "0xc", View: true, Time: time.Now().Add(-3 * time.Hour)},
+	} {
+		analytics.Record(ev)
+	}
+	r := newAnalyticsRouter(analytics, "0xadmin")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, APIPrefix+"/admin/analytics/top?window=1h&limit=5", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Window  string                  `json:"window"`
+		Content []service.ContentAccess `json:"content"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "1h0m0s", resp.Window)
+	require.Len(t, resp.Content, 2, "c3 is outside the window")
+	assert.Equal(t, "c2", resp.Content[0].ContentID)
+	assert.Equal(t, 2, resp.Content[0].UniqueWallets)
+	assert.Equal(t, "c1", resp.Content[1].ContentID)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, APIPrefix+"/admin/analytics/top?window=bogus", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
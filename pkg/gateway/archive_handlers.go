@@ -0,0 +1,75 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/rtcdance/streamgate/pkg/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterArchiveRoutes registers inspection, manual archiving and restore
+// of archived originals, restricted to adminWallets.
+func RegisterArchiveRoutes(router *gin.RouterGroup, svc *service.ArchiveService, adminWallets []string) {
+	admin := router.Group(APIPrefix+"/admin/archive", requireAdminWallet(adminWallets))
+	admin.GET("/:content_id", getArchive(svc))
+	admin.POST("/:content_id", archiveContent(svc))
+	admin.POST("/:content_id/restore", restoreArchive(svc))
+}
+
+// registerArchiveHook archives each original once it is transcoded. The
+// hook fires per profile and archiving can take far longer than the
+// transcode worker should wait, so runs happen in the background.
+func registerArchiveHook(transcodingSvc *service.TranscodingService, archiveSvc *service.ArchiveService) {
+	transcodingSvc.RegisterPostTranscodeHook(func(_ context.Context, contentID, _, _ string) {
+		archiveSvc.ArchiveAsync(contentID)
+	})
+}
+
+func getArchive(svc *service.ArchiveService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rec, err := svc.Get(c.Request.Context(), c.Param("content_id"))
+		if err != nil {
+			abortWithArchiveError(c, err)
+			return
+		}
+		respondOK(c, rec)
+	}
+}
+
+func archiveContent(svc *service.ArchiveService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rec, err := svc.Archive(c.Request.Context(), c.Param("content_id"))
+		if err != nil {
+			abortWithArchiveError(c, err)
+			return
+		}
+		respondOK(c, rec)
+	}
+}
+
+func restoreArchive(svc *service.ArchiveService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rec, err := svc.Restore(c.Request.Context(), c.Param("content_id"))
+		if err != nil {
+			abortWithArchiveError(c, err)
+			return
+		}
+		respondOK(c, rec)
+	}
+}
+
+func abortWithArchiveError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		abortWithError(c, http.StatusNotFound, ErrNotFound, "content not found")
+	case errors.Is(err, service.ErrAlreadyExists):
+		abortWithError(c, http.StatusConflict, ErrInvalidRequest, "archive already in progress")
+	case errors.Is(err, service.ErrInvalidRequest):
+		abortWithErrorDetail(c, http.StatusBadRequest, ErrInvalidRequest, "content cannot be archived or restored", err.Error())
+	default:
+		abortWithErrorDetail(c, http.StatusInternalServerError, ErrInternalError, internalErrMsg(c, err), err.Error())
+	}
+}
@@ -0,0 +1,61 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rtcdance/streamgate/pkg/service"
+	stg "github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// archiveRow scans a contents row for an original that was never archived.
+type archiveRow struct{}
+
+func (archiveRow) Scan(dest ...interface{}) error {
+	*dest[0].(*sql.NullString) = sql.NullString{String: "/streamgate/uploads/u1/movie.mp4", Valid: true}
+	*dest[1].(*sql.NullInt64) = sql.NullInt64{Int64: 1024, Valid: true}
+	*dest[2].(*[]byte) = []byte(`{}`)
+	return nil
+}
+
+func TestArchiveRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := &categoryMockDB{queryRowFn: func(_ context.Context, _ string, args ...interface{}) *stg.CancelRow {
+		if args[0] != "content-1" {
+			return stg.NewErrorCancelRow(sql.ErrNoRows)
+		}
+		return stg.NewTestCancelRow(archiveRow{})
+	}}
+	svc := service.NewArchiveService(db, nil, nil, service.ArchiveConfig{}, zap.NewNop())
+
+	tests := []struct {
+		name   string
+		wallet string
+		method string
+		path   string
+		want   int
+	}{
+		{"non-admin", "0xother", http.MethodGet, "/content-1", http.StatusForbidden},
+		{"get", "0xADMIN", http.MethodGet, "/content-1", http.StatusOK},
+		{"unknown content", "0xADMIN", http.MethodGet, "/missing", http.StatusNotFound},
+		{"restore unarchived", "0xADMIN", http.MethodPost, "/content-1/restore", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(func(c *gin.Context) { c.Set("wallet_address", tt.wallet); c.Next() })
+			RegisterArchiveRoutes(r.Group("/"), svc, []string{"0xADMIN"})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(tt.method, APIPrefix+"/admin/archive"+tt.path, nil))
+			assert.Equal(t, tt.want, w.Code, w.Body.String())
+		})
+	}
+}
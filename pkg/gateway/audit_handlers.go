@@ -0,0 +1,76 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/models"
+	"github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditLogQuerier reads the audit log; *storage.PostgresAuditLogger
+// implements it.
+type AuditLogQuerier interface {
+	Query(ctx context.Context, q storage.AuditQuery) ([]models.AuditLog, error)
+}
+
+// RegisterAuditRoutes registers the audit log query, restricted to
+// adminWallets.
+func RegisterAuditRoutes(router *gin.RouterGroup, audit AuditLogQuerier, adminWallets []string) {
+	admin := router.Group(APIPrefix+"/admin", requireAdminWallet(adminWallets))
+	admin.GET("/audit-logs", listAuditLogs(audit))
+}
+
+// auditContextMiddleware records the client IP in the request context so
+// audit entries logged while serving the request carry it.
+func auditContextMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(storage.WithAuditClientIP(c.Request.Context(), c.ClientIP()))
+		c.Next()
+	}
+}
+
+// listAuditLogs returns entries newest first, filtered by actor, action,
+// resource, resource_id, success and an RFC 3339 since/until range.
+func listAuditLogs(audit AuditLogQuerier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, offset := contentPagination(c)
+		q := storage.AuditQuery{
+			Actor:      c.Query("actor"),
+			Action:     c.Query("action"),
+			Resource:   c.Query("resource"),
+			ResourceID: c.Query("resource_id"),
+			Limit:      limit,
+			Offset:     offset,
+		}
+		if v := c.Query("success"); v != "" {
+			success, err := strconv.ParseBool(v)
+			if err != nil {
+				abortWithError(c, http.StatusBadRequest, ErrInvalidRequest, "success must be true or false")
+				return
+			}
+			q.Success = &success
+		}
+		for param, dst := range map[string]*time.Time{"since": &q.Since, "until": &q.Until} {
+			if v := c.Query(param); v != "" {
+				t, err := time.Parse(time.RFC3339, v)
+				if err != nil {
+					abortWithError(c, http.StatusBadRequest, ErrInvalidRequest, param+" must be an RFC 3339 timestamp")
+					return
+				}
+				*dst = t
+			}
+		}
+
+		entries, err := audit.Query(c.Request.Context(), q)
+		if err != nil {
+			abortWithErrorDetail(c, http.StatusInternalServerError, ErrInternalError, internalErrMsg(c, err), err.Error())
+			return
+		}
+		respondOK(c, gin.H{"entries": entries, "limit": limit, "offset": offset})
+	}
+}
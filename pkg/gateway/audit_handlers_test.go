@@ -0,0 +1,65 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/models"
+	"github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAuditLog answers every query with one entry and keeps the query.
+type fakeAuditLog struct {
+	got storage.AuditQuery
+}
+
+func (f *fakeAuditLog) Query(_ context.Context, q storage.AuditQuery) ([]models.AuditLog, error) {
+	f.got = q
+	return []models.AuditLog{{ID: 1, Action: "auth.wallet_login", Actor: "0xabc", IP: "203.0.113.7"}}, nil
+}
+
+func TestAuditRoutes(t *testing.T) {
+	const base = APIPrefix + "/admin/audit-logs"
+	tests := []struct {
+		name     string
+		wallet   string
+		query    string
+		wantCode int
+		wantBody string
+	}{
+		{"non-admin", "0xother", "", http.StatusForbidden, ""},
+		{"list", "0xADMIN", "", http.StatusOK, `"ip":"203.0.113.7"`},
+		{"filtered", "0xADMIN", "?actor=0xabc&success=false&since=2026-01-01T00:00:00Z&limit=5", http.StatusOK, `"limit":5`},
+		{"bad success", "0xADMIN", "?success=maybe", http.StatusBadRequest, "success must be true or false"},
+		{"bad since", "0xADMIN", "?since=yesterday", http.StatusBadRequest, "since must be an RFC 3339 timestamp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit := &fakeAuditLog{}
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(func(c *gin.Context) { c.Set("wallet_address", tt.wallet); c.Next() })
+			RegisterAuditRoutes(r.Group("/"), audit, []string{"0xADMIN"})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, base+tt.query, http.NoBody))
+			assert.Equal(t, tt.wantCode, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), tt.wantBody)
+
+			if tt.name == "filtered" {
+				require.NotNil(t, audit.got.Success)
+				assert.False(t, *audit.got.Success)
+				assert.Equal(t, "0xabc", audit.got.Actor)
+				assert.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), audit.got.Since)
+				assert.Equal(t, 5, audit.got.Limit)
+			}
+		})
+	}
+}
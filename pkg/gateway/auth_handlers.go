@@ -10,6 +10,7 @@ import (
 	"github.com/rtcdance/streamgate/pkg/middleware"
 	"github.com/rtcdance/streamgate/pkg/monitoring"
 	"github.com/rtcdance/streamgate/pkg/service"
+	"github.com/rtcdance/streamgate/pkg/storage"
 	"github.com/rtcdance/streamgate/pkg/util"
 
 	"github.com/gin-gonic/gin"
@@ -48,7 +49,7 @@ func handleAuthChallenge(cfg *config.Config, authService *service.AuthService) g
 			Address  string `json:"address"`
 			Wallet   string `json:"wallet"`
 			ChainID  int64  `json:"chain_id"`
-			SignType string `json:"sign_type" binding:"omitempty,oneof=personal_sign eip712"`
+			SignType string `json:"sign_type" binding:"omitempty,oneof=siwe personal_sign eip712"`
 		}
 		if errs := BindAndValidate(c, &req); errs != nil {
 			abortWithValidationError(c, errs)
@@ -68,6 +69,11 @@ func handleAuthChallenge(cfg *config.Config, authService *service.AuthService) g
 			chainID = cfg.Web3.ChainID
 		}
 		challenge, err := authService.GenerateWalletChallenge(c.Request.Context(), wallet, chainID, req.SignType)
+		if errors.Is(err, storage.ErrChallengeStoreFull) {
+			c.Header("Retry-After", "1")
+			abortWithError(c, http.StatusTooManyRequests, ErrRateLimited, "too many pending login challenges, retry shortly")
+			return
+		}
 		if err != nil {
 			abortWithErrorDetail(c, http.StatusInternalServerError, ErrInternalError, internalErrMsg(c, err), err.Error())
 			return
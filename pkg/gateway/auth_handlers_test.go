@@ -139,6 +139,45 @@ func TestAuthHandlers_Challenge(t *testing.T) {
 	})
 }
 
+func TestAuthHandlers_ChallengeStoreFull(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	store := storage.NewMemoryChallengeStore(storage.WithMaxChallenges(1), storage.WithChallengeOverflowPolicy(storage.OverflowReject))
+	defer store.Close()
+	authService := service.NewAuthServiceWithDeps(
+		"test-jwt-secret-key-for-testing-",
+		newMockAuthStorage(),
+		service.NewMultiChainSignatureVerifier(zap.NewNop(), nil),
+		store,
+		5*time.Minute,
+		storage.NewMemoryTokenBlacklist(),
+	)
+	authRL := middleware.NewRateLimiter(middleware.RateLimitConfig{
+		RequestsPerMinute: 10,
+		WindowSize:        time.Minute,
+		CleanupInterval:   5 * time.Minute,
+	}, nil)
+	RegisterAuthRoutes(r, zap.NewNop(), config.DefaultConfig(), authService, authRL)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"wallet":   "0x742d35Cc6634C0532925a3b844Bc454e4438f44e",
+		"chain_id": 1,
+	})
+	codes := make([]int, 2)
+	for i := range codes {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/auth/challenge", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		codes[i] = w.Code
+		if i == 1 {
+			assert.Equal(t, "1", w.Header().Get("Retry-After"))
+			assert.Contains(t, w.Body.String(), ErrRateLimited)
+		}
+	}
+	assert.Equal(t, []int{http.StatusOK, http.StatusTooManyRequests}, codes)
+}
+
 func TestAuthHandlers_Register(t *testing.T) {
 	r, _ := setupAuthRouter()
 
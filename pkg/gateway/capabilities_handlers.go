@@ -0,0 +1,91 @@
+package gateway
+
+import (
+	"github.com/rtcdance/streamgate/pkg/core/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CapabilityChain describes a chain the deployment accepts for NFT gating.
+type CapabilityChain struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name,omitempty"`
+	Slug      string `json:"slug,omitempty"`
+	IsTestnet bool   `json:"testnet"`
+}
+
+// Capabilities is the self-description returned by GET /capabilities.
+type Capabilities struct {
+	Version       string            `json:"api_version"`
+	Chains        []CapabilityChain `json:"chains"`
+	NFTStandards  []string          `json:"nft_standards"`
+	OutputFormats []string          `json:"output_formats"`
+	MaxUploadSize int64             `json:"max_upload_size"`
+	AuthSchemes   []string          `json:"auth_schemes"`
+	Features      map[string]bool   `json:"features"`
+}
+
+// BuildCapabilities derives the capability document from the running config.
+func BuildCapabilities(cfg *config.Config) Capabilities {
+	caps := Capabilities{
+		Version:       APIVersion,
+		Chains:        []CapabilityChain{},
+		NFTStandards:  []string{"erc721", "erc1155"},
+		OutputFormats: []string{},
+		MaxUploadSize: maxUploadSize,
+		AuthSchemes:   []string{},
+		Features: map[string]bool{
+			"nft_gating":       cfg.Features.NFTGating,
+			"signature_auth":   cfg.Features.SignatureAuth,
+			"chunked_upload":   cfg.Features.ChunkedUpload,
+			"resumable_upload": cfg.Features.ResumableUpload,
+			"adaptive_bitrate": cfg.Features.AdaptiveBitrate,
+			"multi_codec":      cfg.Features.MultiCodec,
+			"transcoding":      cfg.Transcoding.Enabled,
+		},
+	}
+
+	if cfg.Upload.MaxSize > 0 && cfg.Upload.MaxSize < maxUploadSize {
+		caps.MaxUploadSize = cfg.Upload.MaxSize
+	}
+
+	entries := cfg.Web3.ChainEntries()
+	for _, chain := range entries {
+		if chain.Disabled {
+			continue
+		}
+		caps.Chains = append(caps.Chains, CapabilityChain{ID: chain.ID, Name: chain.Name, Slug: chain.Slug, IsTestnet: chain.IsTestnet})
+	}
+	if len(entries) == 0 && cfg.Web3.ChainID != 0 {
+		caps.Chains = append(caps.Chains, CapabilityChain{ID: cfg.Web3.ChainID})
+	}
+
+	seen := make(map[string]bool)
+	for _, f := range cfg.Transcoding.OutputFormats {
+		if !seen[f] {
+			seen[f] = true
+			caps.OutputFormats = append(caps.OutputFormats, f)
+		}
+	}
+
+	if cfg.Features.SignatureAuth {
+		if cfg.Auth.SIWEDomain != "" {
+			caps.AuthSchemes = append(caps.AuthSchemes, "siwe")
+		}
+		caps.AuthSchemes = append(caps.AuthSchemes, "eip191", "eip712")
+		if cfg.Web3.SolanaRPC != "" {
+			caps.AuthSchemes = append(caps.AuthSchemes, "solana")
+			caps.NFTStandards = append(caps.NFTStandards, "metaplex")
+		}
+	}
+
+	return caps
+}
+
+// RegisterCapabilitiesRoute registers the public GET /capabilities endpoint.
+func RegisterCapabilitiesRoute(router *gin.Engine, cfg *config.Config) {
+	caps := BuildCapabilities(cfg)
+	router.GET("/capabilities", func(c *gin.Context) {
+		respondOK(c, caps)
+	})
+}
@@ -0,0 +1,78 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getCapabilities(t *testing.T, cfg *config.Config) Capabilities {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	RegisterCapabilitiesRoute(r, cfg)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/capabilities", http.NoBody)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var caps Capabilities
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &caps))
+	return caps
+}
+
+func TestCapabilities_ReflectsConfig(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Web3.Chains = []config.ChainConfigEntry{
+		{ID: 1, Name: "Ethereum"},
+		{ID: 137, Name: "Polygon", Disabled: true},
+		{ID: 11155111, Name: "Sepolia", IsTestnet: true},
+	}
+	cfg.Upload.MaxSize = 100 * 1024 * 1024
+	cfg.Features.ResumableUpload = false
+
+	caps := getCapabilities(t, cfg)
+
+	ids := make([]int64, 0, len(caps.Chains))
+	for _, c := range caps.Chains {
+		ids = append(ids, c.ID)
+	}
+	assert.Equal(t, []int64{1, 11155111}, ids)
+	assert.NotContains(t, ids, int64(137))
+	assert.Equal(t, []string{"hls", "dash"}, caps.OutputFormats)
+	assert.Equal(t, int64(100*1024*1024), caps.MaxUploadSize)
+	assert.Equal(t, []string{"siwe", "eip191", "eip712", "solana"}, caps.AuthSchemes)
+	assert.Contains(t, caps.NFTStandards, "erc721")
+	assert.Contains(t, caps.NFTStandards, "metaplex")
+	assert.False(t, caps.Features["resumable_upload"])
+	assert.True(t, caps.Features["nft_gating"])
+	assert.Equal(t, APIVersion, caps.Version)
+}
+
+func TestCapabilities_DefaultChainWhenNoneConfigured(t *testing.T) {
+	cfg := config.DefaultConfig()
+
+	caps := getCapabilities(t, cfg)
+
+	require.Len(t, caps.Chains, 1)
+	assert.Equal(t, cfg.Web3.ChainID, caps.Chains[0].ID)
+	assert.Equal(t, maxUploadSize, caps.MaxUploadSize)
+}
+
+func TestCapabilities_SignatureAuthDisabled(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Features.SignatureAuth = false
+	cfg.Web3.SolanaRPC = ""
+
+	caps := getCapabilities(t, cfg)
+
+	assert.Empty(t, caps.AuthSchemes)
+	assert.NotContains(t, caps.NFTStandards, "metaplex")
+}
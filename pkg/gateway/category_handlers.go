@@ -16,6 +16,7 @@ func RegisterCategoryRoutes(router *gin.RouterGroup, svc *service.CategoryServic
 	router.GET(APIPrefix+"/categories/:id", getCategory(svc))
 	router.PUT(APIPrefix+"/categories/:id", updateCategory(svc))
 	router.DELETE(APIPrefix+"/categories/:id", deleteCategory(svc))
+	router.GET(APIPrefix+"/content/:id/categories", listContentCategories(svc))
 	router.POST(APIPrefix+"/content/:id/categories/:catId", bindContentCategory(svc))
 	router.DELETE(APIPrefix+"/content/:id/categories/:catId", unbindContentCategory(svc))
 	router.GET(APIPrefix+"/categories/:id/content", listContentByCategory(svc))
@@ -120,6 +121,17 @@ func deleteCategory(svc *service.CategoryService) gin.HandlerFunc {
 	}
 }
 
+func listContentCategories(svc *service.CategoryService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cats, err := svc.ListCategoriesForContent(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			abortWithErrorDetail(c, http.StatusInternalServerError, ErrInternalError, internalErrMsg(c, err), err.Error())
+			return
+		}
+		respondOK(c, gin.H{"categories": cats})
+	}
+}
+
 func bindContentCategory(svc *service.CategoryService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		contentID := c.Param("id")
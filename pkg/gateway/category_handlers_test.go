@@ -475,3 +475,20 @@ func TestListContentByCategory_InvalidPagination(t *testing.T) {
 	r.ServeHTTP(w, req)
 	assert.Equal(t, http.StatusOK, w.Code)
 }
+
+func TestListContentCategories(t *testing.T) {
+	var capturedID interface{}
+	db := &categoryMockDB{
+		queryFn: func(ctx context.Context, query string, args ...interface{}) (stg.Rows, error) {
+			capturedID = args[0]
+			return &contentListRows{}, nil
+		},
+	}
+	r := setupCategoryRouter(service.NewCategoryService(db, zap.NewNop()))
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/content/c1/categories", http.NoBody)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "c1", capturedID)
+	assert.JSONEq(t, `{"categories":[]}`, w.Body.String())
+}
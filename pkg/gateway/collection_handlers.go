@@ -0,0 +1,190 @@
+package gateway
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/rtcdance/streamgate/pkg/middleware"
+	"github.com/rtcdance/streamgate/pkg/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterCollectionRoutes registers creator collections. Anyone may
+// browse a collection; only its owner may change it.
+func RegisterCollectionRoutes(router *gin.RouterGroup, svc *service.CollectionService) {
+	router.GET(APIPrefix+"/collections", listCollections(svc))
+	router.POST(APIPrefix+"/collections", createCollection(svc))
+	router.GET(APIPrefix+"/collections/:id", getCollection(svc))
+	router.PUT(APIPrefix+"/collections/:id", updateCollection(svc))
+	router.DELETE(APIPrefix+"/collections/:id", deleteCollection(svc))
+	router.GET(APIPrefix+"/collections/:id/items", listCollectionItems(svc))
+	router.POST(APIPrefix+"/collections/:id/items", addCollectionItems(svc))
+	router.DELETE(APIPrefix+"/collections/:id/items/:content_id", removeCollectionItem(svc))
+}
+
+// requireCollectionWallet returns the caller's wallet, or aborts with 401
+// for anonymous callers.
+func requireCollectionWallet(c *gin.Context) (string, bool) {
+	wallet := middleware.GetWalletAddress(c)
+	if wallet == "" {
+		abortWithError(c, http.StatusUnauthorized, ErrUnauthorized, "authentication required")
+		return "", false
+	}
+	return wallet, true
+}
+
+// listCollections lists the collections of ?owner=, or of the caller when
+// no owner is given.
+func listCollections(svc *service.CollectionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		owner := c.Query("owner")
+		if owner == "" {
+			var ok bool
+			if owner, ok = requireCollectionWallet(c); !ok {
+				return
+			}
+		}
+		limit, offset := contentPagination(c)
+		cols, err := svc.ListByOwner(c.Request.Context(), owner, limit, offset)
+		if err != nil {
+			abortWithCollectionError(c, err)
+			return
+		}
+		respondOK(c, gin.H{"collections": cols, "limit": limit, "offset": offset})
+	}
+}
+
+func createCollection(svc *service.CollectionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		wallet, ok := requireCollectionWallet(c)
+		if !ok {
+			return
+		}
+		var req struct {
+			Name        string `json:"name" binding:"required"`
+			Description string `json:"description"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abortWithErrorDetail(c, http.StatusBadRequest, ErrInvalidRequest, "invalid request body", err.Error())
+			return
+		}
+		col, err := svc.Create(c.Request.Context(), wallet, req.Name, req.Description)
+		if err != nil {
+			abortWithCollectionError(c, err)
+			return
+		}
+		respondCreated(c, col)
+	}
+}
+
+func getCollection(svc *service.CollectionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		col, err := svc.Get(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			abortWithCollectionError(c, err)
+			return
+		}
+		respondOK(c, col)
+	}
+}
+
+func updateCollection(svc *service.CollectionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		wallet, ok := requireCollectionWallet(c)
+		if !ok {
+			return
+		}
+		var req struct {
+			Name        *string `json:"name"`
+			Description *string `json:"description"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abortWithErrorDetail(c, http.StatusBadRequest, ErrInvalidRequest, "invalid request body", err.Error())
+			return
+		}
+		col, err := svc.Update(c.Request.Context(), c.Param("id"), wallet, req.Name, req.Description)
+		if err != nil {
+			abortWithCollectionError(c, err)
+			return
+		}
+		respondOK(c, col)
+	}
+}
+
+func deleteCollection(svc *service.CollectionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		wallet, ok := requireCollectionWallet(c)
+		if !ok {
+			return
+		}
+		if err := svc.Delete(c.Request.Context(), c.Param("id"), wallet); err != nil {
+			abortWithCollectionError(c, err)
+			return
+		}
+		respondNoContent(c)
+	}
+}
+
+func listCollectionItems(svc *service.CollectionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, offset := contentPagination(c)
+		items, err := svc.ListItems(c.Request.Context(), c.Param("id"), limit, offset)
+		if err != nil {
+			abortWithCollectionError(c, err)
+			return
+		}
+		respondOK(c, gin.H{"items": items, "limit": limit, "offset": offset})
+	}
+}
+
+func addCollectionItems(svc *service.CollectionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		wallet, ok := requireCollectionWallet(c)
+		if !ok {
+			return
+		}
+		var req struct {
+			ContentIDs []string `json:"content_ids" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abortWithErrorDetail(c, http.StatusBadRequest, ErrInvalidRequest, "invalid request body", err.Error())
+			return
+		}
+		added, err := svc.AddItems(c.Request.Context(), c.Param("id"), wallet, req.ContentIDs)
+		if err != nil {
+			abortWithCollectionError(c, err)
+			return
+		}
+		respondOK(c, gin.H{"added": added})
+	}
+}
+
+func removeCollectionItem(svc *service.CollectionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		wallet, ok := requireCollectionWallet(c)
+		if !ok {
+			return
+		}
+		if err := svc.RemoveItem(c.Request.Context(), c.Param("id"), wallet, c.Param("content_id")); err != nil {
+			abortWithCollectionError(c, err)
+			return
+		}
+		respondNoContent(c)
+	}
+}
+
+func abortWithCollectionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrCollectionNotFound):
+		abortWithError(c, http.StatusNotFound, ErrNotFound, "collection not found")
+	case errors.Is(err, service.ErrNotCollectionOwner):
+		abortWithError(c, http.StatusForbidden, ErrForbidden, "collection belongs to another wallet")
+	case errors.Is(err, service.ErrNotFound):
+		abortWithError(c, http.StatusNotFound, ErrNotFound, "content is not in the collection")
+	case errors.Is(err, service.ErrInvalidRequest):
+		abortWithErrorDetail(c, http.StatusBadRequest, ErrInvalidRequest, "invalid collection request", err.Error())
+	default:
+		abortWithErrorDetail(c, http.StatusInternalServerError, ErrInternalError, internalErrMsg(c, err), err.Error())
+	}
+}
@@ -0,0 +1,108 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/service"
+	stg "github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+const testCollectionID = "7f1d9a8e-2b7c-4c39-9d1e-2f3a4b5c6d7e"
+
+// collectionRow answers the collection queries: the owner lookup, the item
+// count and the full collection row.
+type collectionRow struct{ query string }
+
+func (r collectionRow) Scan(dest ...interface{}) error {
+	switch {
+	case strings.Contains(r.query, "SELECT owner_id"):
+		*dest[0].(*string) = "0xowner"
+	case strings.HasPrefix(strings.TrimSpace(r.query), "SELECT COUNT(*)"):
+		*dest[0].(*int) = 1
+	default:
+		*dest[0].(*string) = testCollectionID
+		*dest[1].(*string) = "0xowner"
+		*dest[2].(*string) = "Favourites"
+		*dest[3].(*string) = ""
+		*dest[4].(*int) = 1
+		*dest[5].(*time.Time) = time.Now()
+		*dest[6].(*time.Time) = time.Now()
+	}
+	return nil
+}
+
+func setupCollectionRouter(wallet string) *gin.Engine {
+	db := &categoryMockDB{
+		queryFn: func(_ context.Context, _ string, _ ...interface{}) (stg.Rows, error) {
+			return &contentListRows{}, nil
+		},
+		queryRowFn: func(_ context.Context, query string, _ ...interface{}) *stg.CancelRow {
+			return stg.NewTestCancelRow(collectionRow{query})
+		},
+		execFn: func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
+			return &categoryMockResult{rowsAffected: 1}, nil
+		},
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if wallet != "" {
+			c.Set("wallet_address", wallet)
+		}
+		c.Next()
+	})
+	RegisterCollectionRoutes(r.Group("/"), service.NewCollectionService(db, zap.NewNop()))
+	return r
+}
+
+func TestCollectionRoutes(t *testing.T) {
+	const base = "/api/v1/collections"
+	const item = "11111111-1111-4111-8111-111111111111"
+	tests := []struct {
+		name     string
+		wallet   string
+		method   string
+		path     string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{"create", "0xOwner", http.MethodPost, base, `{"name":"Favourites"}`, http.StatusCreated, `"owner_id":"0xowner"`},
+		{"create anonymously", "", http.MethodPost, base, `{"name":"Favourites"}`, http.StatusUnauthorized, "authentication required"},
+		{"create without name", "0xOwner", http.MethodPost, base, `{}`, http.StatusBadRequest, "invalid request body"},
+		{"list by owner", "", http.MethodGet, base + "?owner=0xOwner", "", http.StatusOK, `"collections":[]`},
+		{"list own anonymously", "", http.MethodGet, base, "", http.StatusUnauthorized, "authentication required"},
+		{"get", "", http.MethodGet, base + "/" + testCollectionID, "", http.StatusOK, `"item_count":1`},
+		{"get unknown id", "", http.MethodGet, base + "/nope", "", http.StatusNotFound, "collection not found"},
+		{"list items", "", http.MethodGet, base + "/" + testCollectionID + "/items", "", http.StatusOK, `"items":[]`},
+		{"rename", "0xOwner", http.MethodPut, base + "/" + testCollectionID, `{"name":"Best"}`, http.StatusOK, `"name":"Best"`},
+		{"rename to blank", "0xOwner", http.MethodPut, base + "/" + testCollectionID, `{"name":" "}`, http.StatusBadRequest, "invalid collection request"},
+		{"add items", "0xOwner", http.MethodPost, base + "/" + testCollectionID + "/items", `{"content_ids":["` + item + `"]}`, http.StatusOK, `"added":1`},
+		{"add invalid item", "0xOwner", http.MethodPost, base + "/" + testCollectionID + "/items", `{"content_ids":["x"]}`, http.StatusBadRequest, "invalid content id"},
+		{"add to another wallet's collection", "0xOther", http.MethodPost, base + "/" + testCollectionID + "/items", `{"content_ids":["` + item + `"]}`, http.StatusForbidden, "another wallet"},
+		{"remove item", "0xOwner", http.MethodDelete, base + "/" + testCollectionID + "/items/" + item, "", http.StatusNoContent, ""},
+		{"delete by another wallet", "0xOther", http.MethodDelete, base + "/" + testCollectionID, "", http.StatusForbidden, "another wallet"},
+		{"delete", "0xOwner", http.MethodDelete, base + "/" + testCollectionID, "", http.StatusNoContent, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := setupCollectionRouter(tt.wallet)
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tt.wantCode, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), tt.wantBody)
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/rtcdance/streamgate/pkg/middleware"
+	"github.com/rtcdance/streamgate/pkg/models"
 	"github.com/rtcdance/streamgate/pkg/service"
 
 	"github.com/gin-gonic/gin"
@@ -17,6 +18,7 @@ import (
 func RegisterContentRoutes(router gin.IRouter, log *zap.Logger, contentSvc *service.ContentService) {
 	content := router.Group(APIPrefix + "/content")
 	content.GET("", handleListContents(contentSvc, log))
+	content.GET("/mine", handleListMyContents(contentSvc, log))
 	content.GET("/:id", handleGetContent(contentSvc, log))
 	content.POST("", handleCreateContent(contentSvc, log))
 	content.PUT("/:id", handleUpdateContent(contentSvc, log))
@@ -31,18 +33,7 @@ func handleListContents(contentSvc *service.ContentService, log *zap.Logger) gin
 			return
 		}
 		wallet := middleware.GetWalletAddress(c)
-		limit := 20
-		offset := 0
-		if v := c.Query("limit"); v != "" {
-			if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 100 {
-				limit = n
-			}
-		}
-		if v := c.Query("offset"); v != "" {
-			if n, err := strconv.Atoi(v); err == nil && n >= 0 {
-				offset = n
-			}
-		}
+		limit, offset := contentPagination(c)
 		ownerID := wallet
 		items, totalCount, err := contentSvc.ListContentsWithCount(c.Request.Context(), ownerID, limit, offset)
 		if err != nil {
@@ -53,6 +44,52 @@ func handleListContents(contentSvc *service.ContentService, log *zap.Logger) gin
 	}
 }
 
+// handleListMyContents lists the authenticated wallet's content, optionally
+// filtered by ?status=.
+func handleListMyContents(contentSvc *service.ContentService, log *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if contentSvc == nil {
+			abortWithError(c, http.StatusServiceUnavailable, ErrContentUnavailable, "content service unavailable")
+			return
+		}
+		wallet := middleware.GetWalletAddress(c)
+		if wallet == "" {
+			abortWithError(c, http.StatusUnauthorized, ErrUnauthorized, "authentication required")
+			return
+		}
+		status := c.Query("status")
+		if status != "" && !models.IsValidContentStatus(models.ContentStatus(status)) {
+			abortWithError(c, http.StatusBadRequest, ErrInvalidRequest, "invalid status filter")
+			return
+		}
+		limit, offset := contentPagination(c)
+		items, totalCount, err := contentSvc.ListContentsByStatusWithCount(c.Request.Context(), wallet, status, limit, offset)
+		if err != nil {
+			log.Error("Failed to list wallet content", zap.String("wallet", wallet), zap.Error(err))
+			abortWithErrorDetail(c, http.StatusInternalServerError, ErrInternalError, "failed to list content", err.Error())
+			return
+		}
+		respondOK(c, gin.H{"items": items, "total_count": totalCount, "limit": limit, "offset": offset, "status": status})
+	}
+}
+
+// contentPagination reads ?limit= (1-100, default 20) and ?offset=,
+// ignoring out-of-range values.
+func contentPagination(c *gin.Context) (limit, offset int) {
+	limit = 20
+	if v := c.Query("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 100 {
+			limit = n
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			offset = n
+		}
+	}
+	return limit, offset
+}
+
 func handleGetContent(contentSvc *service.ContentService, log *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if contentSvc == nil {
@@ -884,3 +884,163 @@ func TestHandlePutDelete_NilService(t *testing.T) {
 		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
 	})
 }
+
+// contentListRows serves rows for the owner/status listing query from an
+// in-memory table, honouring its owner, status, limit and offset arguments.
+type contentListRows struct {
+	rows  []service.Content
+	total int
+	idx   int
+}
+
+func (r *contentListRows) Next() bool   { return r.idx < len(r.rows) }
+func (r *contentListRows) Close() error { return nil }
+func (r *contentListRows) Err() error   { return nil }
+
+func (r *contentListRows) Scan(dest ...interface{}) error {
+	c := r.rows[r.idx]
+	r.idx++
+	*dest[0].(*int) = r.total
+	*dest[1].(*string) = c.ID
+	*dest[2].(*string) = c.Title
+	*dest[4].(*string) = c.Type
+	*dest[9].(*sql.NullString) = sql.NullString{String: c.Status, Valid: true}
+	*dest[10].(*sql.NullString) = sql.NullString{String: c.OwnerID, Valid: true}
+	*dest[11].(*time.Time) = c.CreatedAt
+	*dest[12].(*time.Time) = c.UpdatedAt
+	return nil
+}
+
+func contentListDB(table []service.Content) *contentMockDB {
+	return &contentMockDB{
+		queryFn: func(ctx context.Context, query string, args ...interface{}) (stg.Rows, error) {
+			owner, status := args[0].(string), args[1].(string)
+			limit, offset := args[2].(int), args[3].(int)
+			var matched []service.Content
+			for _, c := range table {
+				if c.OwnerID == owner && (status == "" || c.Status == status) {
+					matched = append(matched, c)
+				}
+			}
+			page := matched[min(offset, len(matched)):min(offset+limit, len(matched))]
+			return &contentListRows{rows: page, total: len(matched)}, nil
+		},
+	}
+}
+
+type myContentResponse struct {
+	Items []struct {
+		ID      string `json:"id"`
+		OwnerID string `json:"owner_id"`
+		Status  string `json:"status"`
+	} `json:"items"`
+	TotalCount int    `json:"total_count"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	Status     string `json:"status"`
+}
+
+func getMyContent(t *testing.T, r *gin.Engine, query string) (int, myContentResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/content/mine"+query, http.NoBody)
+	r.ServeHTTP(w, req)
+	var resp myContentResponse
+	if w.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	}
+	return w.Code, resp
+}
+
+func TestHandleListMyContents_OnlyOwnContent(t *testing.T) {
+	now := time.Now()
+	table := []service.Content{
+		{ID: "a1", OwnerID: "0xAlice", Status: "ready", CreatedAt: now},
+		{ID: "b1", OwnerID: "0xBob", Status: "ready", CreatedAt: now},
+		{ID: "a2", OwnerID: "0xAlice", Status: "pending", CreatedAt: now},
+		{ID: "b2", OwnerID: "0xBob", Status: "pending", CreatedAt: now},
+	}
+	svc := service.NewContentService(contentListDB(table), newContentMockObjStore(), newContentMockCache())
+
+	for wallet, want := range map[string][]string{"0xAlice": {"a1", "a2"}, "0xBob": {"b1", "b2"}} {
+		code, resp := getMyContent(t, setupContentRouter(svc, wallet), "")
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, 2, resp.TotalCount)
+		var ids []string
+		for _, item := range resp.Items {
+			assert.Equal(t, wallet, item.OwnerID)
+			ids = append(ids, item.ID)
+		}
+		assert.Equal(t, want, ids)
+	}
+
+	code, resp := getMyContent(t, setupContentRouter(svc, "0xCarol"), "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Empty(t, resp.Items)
+	assert.Zero(t, resp.TotalCount)
+}
+
+func TestHandleListMyContents_Pagination(t *testing.T) {
+	var table []service.Content
+	for i := 0; i < 5; i++ {
+		table = append(table, service.Content{ID: fmt.Sprintf("a%d", i), OwnerID: "0xAlice", Status: "ready"})
+	}
+	table = append(table, service.Content{ID: "b0", OwnerID: "0xBob", Status: "ready"})
+	svc := service.NewContentService(contentListDB(table), newContentMockObjStore(), newContentMockCache())
+	r := setupContentRouter(svc, "0xAlice")
+
+	var seen []string
+	for offset := 0; offset < 5; offset += 2 {
+		code, resp := getMyContent(t, r, fmt.Sprintf("?limit=2&offset=%d", offset))
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, 5, resp.TotalCount)
+		assert.Equal(t, 2, resp.Limit)
+		assert.Equal(t, offset, resp.Offset)
+		for _, item := range resp.Items {
+			seen = append(seen, item.ID)
+		}
+	}
+	assert.Equal(t, []string{"a0", "a1", "a2", "a3", "a4"}, seen)
+
+	code, resp := getMyContent(t, r, "?limit=2&offset=10")
+	require.Equal(t, http.StatusOK, code)
+	assert.Empty(t, resp.Items)
+}
+
+func TestHandleListMyContents_StatusFilter(t *testing.T) {
+	table := []service.Content{
+		{ID: "a1", OwnerID: "0xAlice", Status: "ready"},
+		{ID: "a2", OwnerID: "0xAlice", Status: "failed"},
+		{ID: "b1", OwnerID: "0xBob", Status: "failed"},
+	}
+	svc := service.NewContentService(contentListDB(table), newContentMockObjStore(), newContentMockCache())
+	r := setupContentRouter(svc, "0xAlice")
+
+	code, resp := getMyContent(t, r, "?status=failed")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Items, 1)
+	assert.Equal(t, "a2", resp.Items[0].ID)
+	assert.Equal(t, 1, resp.TotalCount)
+	assert.Equal(t, "failed", resp.Status)
+
+	code, _ = getMyContent(t, r, "?status=bogus")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestHandleListMyContents_Errors(t *testing.T) {
+	code, _ := getMyContent(t, setupContentRouter(nil, "0xAlice"), "")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+
+	svc := service.NewContentService(contentListDB(nil), newContentMockObjStore(), newContentMockCache())
+	code, _ = getMyContent(t, setupContentRouter(svc, ""), "")
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	failing := &contentMockDB{
+		queryFn: func(ctx context.Context, query string, args ...interface{}) (stg.Rows, error) {
+			return nil, errors.New("db error")
+		},
+	}
+	svc = service.NewContentService(failing, newContentMockObjStore(), newContentMockCache())
+	code, _ = getMyContent(t, setupContentRouter(svc, "0xAlice"), "")
+	assert.Equal(t, http.StatusInternalServerError, code)
+}
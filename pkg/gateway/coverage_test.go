@@ -15,6 +15,7 @@ import (
 	"github.com/rtcdance/streamgate/pkg/storage"
 	"github.com/rtcdance/streamgate/pkg/web3"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 
 	"github.com/gin-gonic/gin"
@@ -74,7 +75,7 @@ func TestProvideChallengeStore_WithInjected(t *testing.T) {
 
 	log := zap.NewNop()
 	res := &AppResources{}
-	result := provideChallengeStore(rc, log, 5*time.Minute, nil, res)
+	result := provideChallengeStore(rc, &config.Config{}, log, 5*time.Minute, nil, res)
 	assert.Equal(t, store, result)
 }
 
@@ -82,11 +83,23 @@ func TestProvideChallengeStore_NilRedis(t *testing.T) {
 	rc := &RouterConfig{}
 	log := zap.NewNop()
 	res := &AppResources{}
-	result := provideChallengeStore(rc, log, 5*time.Minute, nil, res)
+	result := provideChallengeStore(rc, &config.Config{}, log, 5*time.Minute, nil, res)
 	assert.NotNil(t, result)
 	assert.NotNil(t, res.ChallengeStore)
 }
 
+func TestProvideChallengeStore_NilRedisHonorsCap(t *testing.T) {
+	cfg := &config.Config{Auth: config.AuthConfig{MaxInMemoryChallenges: 1, ChallengeOverflowPolicy: "reject"}}
+	res := &AppResources{}
+	store := provideChallengeStore(&RouterConfig{}, cfg, zap.NewNop(), 5*time.Minute, nil, res)
+	defer res.ChallengeStore.Close()
+
+	ctx := context.Background()
+	require.NoError(t, store.SaveChallenge(ctx, &storage.WalletChallenge{ID: "c1", ExpiresAt: time.Now().Add(time.Minute)}))
+	err := store.SaveChallenge(ctx, &storage.WalletChallenge{ID: "c2", ExpiresAt: time.Now().Add(time.Minute)})
+	assert.ErrorIs(t, err, storage.ErrChallengeStoreFull)
+}
+
 func TestProvideTokenBlacklist_NilRedis(t *testing.T) {
 	log := zap.NewNop()
 	res := &AppResources{}
@@ -100,14 +113,14 @@ func TestProvideContentService_WithInjected(t *testing.T) {
 	mockSvc := &service.ContentService{}
 	rc.ContentService = mockSvc
 
-	result := provideContentService(rc, nil, log)
+	result := provideContentService(rc, nil, log, nil)
 	assert.Equal(t, mockSvc, result)
 }
 
 func TestProvideContentService_NilDB(t *testing.T) {
 	rc := &RouterConfig{}
 	log := zap.NewNop()
-	result := provideContentService(rc, nil, log)
+	result := provideContentService(rc, nil, log, nil)
 	assert.Nil(t, result)
 }
 
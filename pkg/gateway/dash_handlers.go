@@ -0,0 +1,216 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/middleware"
+	"github.com/rtcdance/streamgate/pkg/monitoring"
+	"github.com/rtcdance/streamgate/pkg/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// dashManifestName is the manifest TranscodeToDASH writes next to its
+// init-*.m4s and chunk-*.m4s files.
+const dashManifestName = "manifest.mpd"
+
+// segmentTemplateAttr matches the URL-bearing SegmentTemplate attributes of
+// an FFmpeg-generated MPD; mpdBaseURL matches its BaseURL elements.
+var (
+	segmentTemplateAttr = regexp.MustCompile(`\b(initialization|media)="([^"]*)"`)
+	mpdBaseURL          = regexp.MustCompile(`\s*<BaseURL>[^<]*</BaseURL>`)
+)
+
+// dashObjectPrefix is where a content's DASH output is stored: the
+// transcoding service uploads a profile's files under streams/<id>/<profile>/.
+func dashObjectPrefix(contentID string) string {
+	return fmt.Sprintf("streams/%s/dash/", contentID)
+}
+
+// dashManifestCacheKey keeps DASH templates apart from HLS manifests in the
+// shared manifest cache.
+func dashManifestCacheKey(contentID string) string {
+	return "dash:" + contentID
+}
+
+// templateMPD points the stored manifest's SegmentTemplate URLs at the DASH
+// chunk route, with a {{PLAYBACK_TOKEN}} placeholder so one template serves
+// every session. BaseURL elements are dropped since the rewritten URLs are
+// absolute.
+func templateMPD(raw, contentID string) string {
+	out := segmentTemplateAttr.ReplaceAllStringFunc(raw, func(attr string) string {
+		m := segmentTemplateAttr.FindStringSubmatch(attr)
+		name := m[2]
+		if strings.Contains(name, "/") || strings.Contains(name, ":") {
+			return attr
+		}
+		return fmt.Sprintf(`%s="%s/streaming/%s/dash/%s?playback_token={{PLAYBACK_TOKEN}}"`, m[1], APIPrefix, contentID, name)
+	})
+	return mpdBaseURL.ReplaceAllString(out, "")
+}
+
+// validateDASHChunkName accepts a single .m4s file name such as
+// init-0.m4s or chunk-0-00001.m4s.
+func validateDASHChunkName(name string) bool {
+	if strings.Contains(name, "\\") || strings.Contains(name, "..") || strings.Contains(name, "/") {
+		return false
+	}
+	if path.Clean(name) != name {
+		return false
+	}
+	return strings.HasSuffix(name, ".m4s")
+}
+
+// RegisterDASHRoutes registers the DASH manifest route. Like the HLS master
+// manifest it sits behind JWT auth and the NFT gate, and issues the
+// playback token embedded in every chunk URL.
+func RegisterDASHRoutes(router gin.IRouter, log *zap.Logger, authService *service.AuthService, objStorage service.SegmentStorage, limiter *streamLimiter, cache *StreamingCache, bucket ...string) {
+	if cache == nil {
+		cache = NewStreamingCache()
+	}
+	segBucket := "streamgate"
+	if len(bucket) > 0 && bucket[0] != "" {
+		segBucket = bucket[0]
+	}
+
+	router.GET(APIPrefix+"/streaming/:id/dash/"+dashManifestName, func(c *gin.Context) {
+		contentID := c.Param("id")
+		if !isValidContentID(contentID) {
+			abortWithError(c, http.StatusBadRequest, ErrInvalidRequest, "invalid content ID")
+			return
+		}
+
+		wallet := middleware.GetWalletAddress(c)
+		contract := middleware.GetNFTContract(c)
+		var chainID int64 = 1
+		if v, ok := c.Get("nft_chain_id"); ok {
+			if id, ok := v.(int64); ok {
+				chainID = id
+			}
+		}
+		if limiter != nil && !limiter.tryAcquire() {
+			c.Header("Retry-After", "1")
+			abortWithError(c, http.StatusServiceUnavailable, ErrStreamLimitReached, "too many concurrent streams; try again shortly")
+			return
+		}
+		if limiter != nil {
+			defer limiter.release()
+		}
+		monitoring.StreamingManifestsTotal.Inc()
+
+		template, ok := cache.GetManifest(dashManifestCacheKey(contentID), wallet)
+		if ok {
+			monitoring.StreamingCacheHitsTotal.WithLabelValues("manifest").Inc()
+		} else {
+			if objStorage == nil {
+				abortWithError(c, http.StatusNotFound, ErrContentNotFound, "content not ready; transcode may still be processing")
+				return
+			}
+			raw, err := objStorage.Download(c.Request.Context(), segBucket, dashObjectPrefix(contentID)+dashManifestName)
+			if err != nil || len(raw) == 0 {
+				middleware.GetLogger(c, log).Debug("DASH manifest unavailable",
+					zap.String("content_id", contentID),
+					zap.Error(err))
+				abortWithError(c, http.StatusNotFound, ErrContentNotFound, "content not ready; transcode may still be processing")
+				return
+			}
+			template = templateMPD(string(raw), contentID)
+			cache.SetManifest(dashManifestCacheKey(contentID), template, wallet)
+		}
+
+		playbackToken, err := authService.GeneratePlaybackToken(c.Request.Context(), wallet, contentID, contract, c.Query("token_id"), chainID, 30*time.Minute, c.GetHeader("X-Client-Fingerprint"))
+		if err != nil {
+			abortWithErrorDetail(c, http.StatusInternalServerError, ErrInternalError, internalErrMsg(c, err), err.Error())
+			return
+		}
+
+		c.Header("Content-Type", "application/dash+xml")
+		c.Header("Cache-Control", "private, max-age=30") // per-user token in body; browser-only cache
+		c.String(http.StatusOK, strings.ReplaceAll(template, "{{PLAYBACK_TOKEN}}", playbackToken))
+	})
+}
+
+// RegisterDASHSegmentRoute registers the DASH chunk route. As with HLS
+// segments it must be registered before the JWT middleware: players fetch
+// chunks with the playback token from the manifest, not a JWT.
+func RegisterDASHSegmentRoute(router gin.IRouter, log *zap.Logger, authService *service.AuthService, objStorage service.SegmentStorage, limiter *streamLimiter, bucket ...string) {
+	segBucket := "streamgate"
+	if len(bucket) > 0 && bucket[0] != "" {
+		segBucket = bucket[0]
+	}
+
+	router.GET(APIPrefix+"/streaming/:id/dash/:chunk", func(c *gin.Context) {
+		playbackToken := extractPlaybackToken(c)
+		if playbackToken == "" {
+			abortWithError(c, http.StatusUnauthorized, ErrUnauthorized, "missing playback token")
+			return
+		}
+		contentID := c.Param("id")
+		if !isValidContentID(contentID) {
+			abortWithError(c, http.StatusBadRequest, ErrInvalidRequest, "invalid content ID")
+			return
+		}
+		claims, err := authService.ValidatePlaybackToken(c.Request.Context(), playbackToken, contentID, c.GetHeader("X-Client-Fingerprint"))
+		if err != nil {
+			middleware.GetLogger(c, log).Warn("playback token validation failed",
+				zap.String("content_id", contentID),
+				zap.Error(err))
+			abortWithError(c, http.StatusUnauthorized, ErrUnauthorized, "invalid playback token")
+			return
+		}
+		c.Set(playbackWalletKey, claims.WalletAddress)
+
+		chunk := c.Param("chunk")
+		if !validateDASHChunkName(chunk) {
+			abortWithError(c, http.StatusBadRequest, ErrInvalidRequest, "invalid segment name")
+			return
+		}
+		if objStorage == nil {
+			abortWithError(c, http.StatusNotFound, ErrNotFound, "segment not found")
+			return
+		}
+		if limiter != nil && !limiter.tryAcquire() {
+			c.Header("Retry-After", "1")
+			abortWithError(c, http.StatusServiceUnavailable, ErrStreamLimitReached, "too many concurrent streams; try again shortly")
+			return
+		}
+		if limiter != nil {
+			defer limiter.release()
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+		defer cancel()
+
+		start := time.Now()
+		rc, err := objStorage.DownloadStream(ctx, segBucket, dashObjectPrefix(contentID)+chunk)
+		if err != nil || rc == nil {
+			monitoring.ObserveContext(c.Request.Context(), monitoring.StreamingDownloadDuration.WithLabelValues("fail"), time.Since(start).Seconds())
+			middleware.GetLogger(c, log).Warn("DASH segment download failed",
+				zap.String("content_id", contentID),
+				zap.String("segment", chunk),
+				zap.Error(err))
+			abortWithError(c, http.StatusServiceUnavailable, ErrContentUnavailable, "segment unavailable")
+			return
+		}
+		defer func() { _ = rc.Close() }()
+
+		c.Header("Content-Type", "video/iso.segment")
+		c.Header("Cache-Control", "private, max-age=86400")
+		c.Header("Vary", "Authorization")
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Status(http.StatusOK)
+		if _, err := io.Copy(c.Writer, rc); err != nil {
+			log.Warn("segment download interrupted", zap.String("content_id", contentID), zap.Error(err))
+		}
+		monitoring.ObserveContext(c.Request.Context(), monitoring.StreamingDownloadDuration.WithLabelValues("success"), time.Since(start).Seconds())
+		monitoring.StreamingSegmentsTotal.WithLabelValues("dash").Inc()
+	})
+}
@@ -0,0 +1,147 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const testMPD = `<?xml version="1.0" encoding="utf-8"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="static">
+	<BaseURL>./</BaseURL>
+	<Period id="0" start="PT0.0S">
+		<AdaptationSet id="0" contentType="video">
+			<SegmentTemplate timescale="12800" initialization="init-$RepresentationID$.m4s" media="chunk-$RepresentationID$-$Number%05d$.m4s" startNumber="1"/>
+			<Representation id="0" mimeType="video/mp4" codecs="avc1.64001f" bandwidth="2800000" width="1280" height="720"/>
+		</AdaptationSet>
+	</Period>
+</MPD>`
+
+type dashMemStorage struct {
+	mockSegmentStorage
+	objects   map[string][]byte
+	downloads int
+}
+
+func (m *dashMemStorage) Download(_ context.Context, _, name string) ([]byte, error) {
+	m.downloads++
+	data, ok := m.objects[name]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return data, nil
+}
+
+func (m *dashMemStorage) DownloadStream(ctx context.Context, bucket, name string) (io.ReadCloser, error) {
+	data, err := m.Download(ctx, bucket, name)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+var _ service.SegmentStorage = (*dashMemStorage)(nil)
+
+func newDASHTestRouter(t *testing.T, store *dashMemStorage) (*gin.Engine, *service.AuthService) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	authSvc := service.NewAuthService("test-secret-that-is-at-least-32-chars", nil)
+	limiter := newStreamLimiter(100)
+	r := gin.New()
+	RegisterDASHSegmentRoute(r, zap.NewNop(), authSvc, store, limiter)
+	r.Use(func(c *gin.Context) {
+		c.Set("wallet_address", "0x742d35Cc6634C0532925a3b844Bc9e7595f2bD18")
+		c.Next()
+	})
+	RegisterDASHRoutes(r, zap.NewNop(), authSvc, store, limiter, NewStreamingCache())
+	return r, authSvc
+}
+
+func TestTemplateMPD(t *testing.T) {
+	out := templateMPD(testMPD, "movie-1")
+	assert.Contains(t, out, `initialization="/api/v1/streaming/movie-1/dash/init-$RepresentationID$.m4s?playback_token={{PLAYBACK_TOKEN}}"`)
+	assert.Contains(t, out, `media="/api/v1/streaming/movie-1/dash/chunk-$RepresentationID$-$Number%05d$.m4s?playback_token={{PLAYBACK_TOKEN}}"`)
+	assert.NotContains(t, out, "BaseURL")
+
+	external := `<SegmentTemplate media="https://cdn.example.com/chunk-$Number$.m4s"/>`
+	assert.Equal(t, external, templateMPD(external, "movie-1"))
+}
+
+func TestValidateDASHChunkName(t *testing.T) {
+	assert.True(t, validateDASHChunkName("init-0.m4s"))
+	assert.True(t, validateDASHChunkName("chunk-0-00001.m4s"))
+	assert.False(t, validateDASHChunkName("manifest.mpd"))
+	assert.False(t, validateDASHChunkName("../720p/seg.m4s"))
+	assert.False(t, validateDASHChunkName("chunk-0-00001.ts"))
+}
+
+func TestDASHRoutes_Manifest(t *testing.T) {
+	store := &dashMemStorage{objects: map[string][]byte{"streams/movie-1/dash/manifest.mpd": []byte(testMPD)}}
+	r, authSvc := newDASHTestRouter(t, store)
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, APIPrefix+"/streaming/movie-1/dash/manifest.mpd", http.NoBody))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/dash+xml", w.Header().Get("Content-Type"))
+		assert.Equal(t, "private, max-age=30", w.Header().Get("Cache-Control"))
+
+		body := w.Body.String()
+		assert.NotContains(t, body, "{{PLAYBACK_TOKEN}}")
+		_, after, ok := strings.Cut(body, "playback_token=")
+		require.True(t, ok)
+		token, _, _ := strings.Cut(after, `"`)
+		_, err := authSvc.ValidatePlaybackToken(context.Background(), token, "movie-1", "")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, store.downloads, "template should be cached")
+}
+
+func TestDASHRoutes_ManifestNotReady(t *testing.T) {
+	r, _ := newDASHTestRouter(t, &dashMemStorage{objects: map[string][]byte{}})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, APIPrefix+"/streaming/movie-1/dash/manifest.mpd", http.NoBody))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDASHSegmentRoute(t *testing.T) {
+	store := &dashMemStorage{objects: map[string][]byte{"streams/movie-1/dash/chunk-0-00001.m4s": []byte("moof")}}
+	r, authSvc := newDASHTestRouter(t, store)
+	token, err := authSvc.GeneratePlaybackToken(context.Background(), "0x742d35Cc6634C0532925a3b844Bc9e7595f2bD18", "movie-1", "", "", 1, 2*time.Minute, "")
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, APIPrefix+"/streaming/movie-1/dash/chunk-0-00001.m4s?playback_token="+token, http.NoBody))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "video/iso.segment", w.Header().Get("Content-Type"))
+	assert.Equal(t, "private, max-age=86400", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "moof", w.Body.String())
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, APIPrefix+"/streaming/movie-1/dash/chunk-0-00001.m4s", http.NoBody))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, APIPrefix+"/streaming/movie-1/dash/chunk-0-00009.m4s?playback_token="+token, http.NoBody))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	otherToken, err := authSvc.GeneratePlaybackToken(context.Background(), "0x742d35Cc6634C0532925a3b844Bc9e7595f2bD18", "movie-2", "", "", 1, 2*time.Minute, "")
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, APIPrefix+"/streaming/movie-1/dash/chunk-0-00001.m4s?playback_token="+otherToken, http.NoBody))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
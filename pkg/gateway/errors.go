@@ -46,24 +46,28 @@ type APIError struct {
 
 // Error code constants
 const (
-	ErrInvalidRequest     = "INVALID_REQUEST"
-	ErrUnauthorized       = "UNAUTHORIZED"
-	ErrTokenRevoked       = "TOKEN_REVOKED"
-	ErrTokenExpired       = "TOKEN_EXPIRED"
-	ErrForbidden          = "FORBIDDEN"
-	ErrNFTRequired        = "NFT_REQUIRED"
-	ErrNFTVerifyError     = "NFT_VERIFY_ERROR"
-	ErrMissingContract    = "MISSING_CONTRACT"
-	ErrContentNotFound    = "CONTENT_NOT_FOUND"
-	ErrContentForbidden   = "CONTENT_FORBIDDEN"
-	ErrContentUnavailable = "CONTENT_UNAVAILABLE"
-	ErrUploadFailed       = "UPLOAD_FAILED"
-	ErrNotFound           = "NOT_FOUND"
-	ErrRateLimited        = "RATE_LIMITED"
-	ErrPayloadTooLarge    = "PAYLOAD_TOO_LARGE"
-	ErrStreamLimitReached = "STREAM_LIMIT_REACHED"
-	ErrHealthCheckFailed  = "HEALTH_CHECK_FAILED"
-	ErrInternalError      = "INTERNAL_ERROR"
+	ErrInvalidRequest      = "INVALID_REQUEST"
+	ErrUnauthorized        = "UNAUTHORIZED"
+	ErrTokenRevoked        = "TOKEN_REVOKED"
+	ErrTokenExpired        = "TOKEN_EXPIRED"
+	ErrForbidden           = "FORBIDDEN"
+	ErrNFTRequired         = "NFT_REQUIRED"
+	ErrNFTVerifyError      = "NFT_VERIFY_ERROR"
+	ErrMissingContract     = "MISSING_CONTRACT"
+	ErrUnsupportedChain    = "UNSUPPORTED_CHAIN"
+	ErrContentNotFound     = "CONTENT_NOT_FOUND"
+	ErrContentForbidden    = "CONTENT_FORBIDDEN"
+	ErrContentUnavailable  = "CONTENT_UNAVAILABLE"
+	ErrUploadFailed        = "UPLOAD_FAILED"
+	ErrNotFound            = "NOT_FOUND"
+	ErrRateLimited         = "RATE_LIMITED"
+	ErrPayloadTooLarge     = "PAYLOAD_TOO_LARGE"
+	ErrStreamLimitReached  = "STREAM_LIMIT_REACHED"
+	ErrBudgetExceeded      = "BUDGET_EXCEEDED"
+	ErrHealthCheckFailed   = "HEALTH_CHECK_FAILED"
+	ErrUpstreamUnavailable = "UPSTREAM_UNAVAILABLE"
+	ErrNotImplemented      = "NOT_IMPLEMENTED"
+	ErrInternalError       = "INTERNAL_ERROR"
 )
 
 // WithDetail adds detail to the error.
@@ -3,13 +3,11 @@ package gateway
 import (
 	"context"
 	"embed"
-	"strconv"
 	"time"
 
 	"github.com/rtcdance/streamgate/pkg/core"
 	"github.com/rtcdance/streamgate/pkg/core/config"
 	"github.com/rtcdance/streamgate/pkg/middleware"
-	"github.com/rtcdance/streamgate/pkg/monitoring"
 	"github.com/rtcdance/streamgate/pkg/service"
 
 	"github.com/gin-gonic/gin"
@@ -39,9 +37,15 @@ func SetupRouter(cfg *config.Config, log *zap.Logger, opts ...RouterOption) (*gi
 	resources.Web3Service = web3Svc
 
 	challengeTTL := parseChallengeTTL(cfg)
-	challengeStore := provideChallengeStore(rc, log, challengeTTL, sharedRedis, resources)
+	challengeStore := provideChallengeStore(rc, cfg, log, challengeTTL, sharedRedis, resources)
 
-	authService := provideAuthService(rc, cfg, log, web3Svc, challengeStore, challengeTTL, sharedRedis, resources)
+	db, sqlDB := provideDatabase(cfg, log, resources)
+	objStorage := provideObjectStorage(rc, cfg, log, resources)
+	resources.SegmentStorage = objStorage
+
+	auditLogger := provideAuditLogger(cfg, log, sqlDB, objStorage, resources)
+
+	authService := provideAuthService(rc, cfg, log, web3Svc, challengeStore, challengeTTL, sharedRedis, auditLogger, resources)
 	resources.AuthService = authService
 
 	nftCache := NewNFTAccessCache()
@@ -62,14 +66,10 @@ func SetupRouter(cfg *config.Config, log *zap.Logger, opts ...RouterOption) (*gi
 	}
 	resources.NFTVerifier = nftVerifier
 
-	db, _ := provideDatabase(cfg, log, resources)
-	contentSvc := provideContentService(rc, db, log)
+	contentSvc := provideContentService(rc, db, log, auditLogger)
 	resources.ContentService = contentSvc
 
-	objStorage := provideObjectStorage(rc, cfg, log, resources)
-	resources.SegmentStorage = objStorage
-
-	transcodingSvc := provideTranscodingService(cfg, log, db, objStorage, resources)
+	transcodingSvc := provideTranscodingService(cfg, log, db, objStorage, sharedRedis, resources)
 	resources.TranscodingSvc = transcodingSvc
 
 	if transcodingSvc != nil {
@@ -88,8 +88,42 @@ func SetupRouter(cfg *config.Config, log *zap.Logger, opts ...RouterOption) (*gi
 	uploadSvc := provideUploadService(rc, cfg, log, db, objStorage, transcodingSvc)
 	resources.UploadService = uploadSvc
 
+	moderationSvc, err := provideModerationService(cfg, log, db, uploadSvc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	archiveSvc, err := provideArchiveService(cfg, log, db, objStorage, transcodingSvc)
+	if err != nil {
+		return nil, nil, err
+	}
+	resources.ArchiveSvc = archiveSvc
+
+	lifecycleMgr, err := provideLifecycleManager(cfg, log, db, objStorage)
+	if err != nil {
+		return nil, nil, err
+	}
+	resources.Lifecycle = lifecycleMgr
+
+	searchSvc, err := provideSearchService(cfg, log, db, transcodingSvc)
+	if err != nil {
+		return nil, nil, err
+	}
+	resources.SearchSvc = searchSvc
+
+	webhookSvc, err := provideWebhookService(cfg, log, db, uploadSvc, transcodingSvc)
+	if err != nil {
+		return nil, nil, err
+	}
+	resources.WebhookSvc = webhookSvc
+
 	provideOTelTracing(cfg, log, resources)
 
+	upstreams, err := provideUpstreams(cfg, log)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.New()
 	setupMiddleware(router, cfg, log, sharedRedis, resources)
@@ -97,7 +131,7 @@ func SetupRouter(cfg *config.Config, log *zap.Logger, opts ...RouterOption) (*gi
 	svc := &serviceInit{
 		Web3Service:     web3Svc,
 		AuthService:     authService,
-		StreamingSvc:    service.NewStreamingService(db, nil, nil, "", log.Named("streaming")),
+		StreamingSvc:    provideStreamingService(cfg, log, db),
 		NFTVerifier:     nftVerifier,
 		NFTCache:        nftCache,
 		NFTCacheBackend: nftCacheBackend,
@@ -107,14 +141,35 @@ func SetupRouter(cfg *config.Config, log *zap.Logger, opts ...RouterOption) (*gi
 		TranscodingSvc:  transcodingSvc,
 		UploadService:   uploadSvc,
 		DemoNFTMinter:   newDemoNFTMinter(cfg, log),
+		AccessAnalytics: provideAccessAnalytics(cfg, log),
+		LiveSvc:         provideLiveService(cfg, log, resources),
+		ModerationSvc:   moderationSvc,
+		ArchiveSvc:      archiveSvc,
+		Lifecycle:       lifecycleMgr,
+		SearchSvc:       searchSvc,
+		WebhookSvc:      webhookSvc,
+		AuditLogger:     auditLogger,
+		Upstreams:       upstreams,
+		Kernel:          rc.Kernel,
 	}
 	resources.StreamingSvc = svc.StreamingSvc
+	if svc.LiveSvc != nil {
+		svc.StreamingSvc.SetLiveStreams(svc.LiveSvc)
+	}
+	if webhookSvc != nil && svc.LiveSvc != nil {
+		svc.LiveSvc.RegisterStartHook(webhookStreamStarted(webhookSvc))
+	}
 
 	if db != nil {
 		svc.GatingRuleSvc = service.NewGatingRuleService(db, log.Named("gating-rule"))
 		svc.GatingRuleResolver = NewGatingRuleResolverAdapter(svc.GatingRuleSvc)
 		svc.PlaybackStatsSvc = service.NewPlaybackStatsService(db, log.Named("playback-stats"))
 		svc.CategorySvc = service.NewCategoryService(db, log.Named("category"))
+		svc.TagSvc = service.NewTagService(db, log.Named("tags"))
+		svc.CollectionSvc = service.NewCollectionService(db, log.Named("collection"))
+		if searchSvc != nil {
+			svc.TagSvc.RegisterChangeHook(searchSvc.IndexAsync)
+		}
 	}
 
 	registerRoutes(router, cfg, log, svc, resources)
@@ -141,6 +196,7 @@ func setupMiddleware(router *gin.Engine, cfg *config.Config, log *zap.Logger, re
 	res.MiddlewareSvc = middlewareSvc
 
 	router.Use(RequestIDMiddleware())
+	router.Use(auditContextMiddleware())
 	router.Use(middlewareSvc.RecoveryMiddleware())
 	router.Use(rlHandler)
 	router.Use(core.DrainMiddleware())
@@ -155,16 +211,5 @@ func setupMiddleware(router *gin.Engine, cfg *config.Config, log *zap.Logger, re
 }
 
 func prometheusMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		startedAt := time.Now()
-		c.Next()
-		route := c.FullPath()
-		if route == "" {
-			route = c.Request.URL.Path
-		}
-		status := strconv.Itoa(c.Writer.Status())
-		monitoring.HTTPRequestsTotal.WithLabelValues(c.Request.Method, route, status).Inc()
-		elapsed := time.Since(startedAt).Milliseconds()
-		monitoring.ServiceRequestDuration.WithLabelValues("api-gateway").Observe(float64(elapsed))
-	}
+	return middleware.MetricsMiddleware("api-gateway")
 }
@@ -7,6 +7,8 @@ import (
 	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -212,7 +214,7 @@ func TestProvideAuthService_WithInjected(t *testing.T) {
 	challengeStore := storage.NewMemoryChallengeStore()
 	defer challengeStore.Close()
 
-	result := provideAuthService(rc, cfg, log, web3Svc, challengeStore, 5*time.Minute, nil, res)
+	result := provideAuthService(rc, cfg, log, web3Svc, challengeStore, 5*time.Minute, nil, nil, res)
 	assert.Equal(t, injected, result)
 }
 
@@ -408,7 +410,7 @@ func TestGatingRuleResolverAdapter_NilSvc(t *testing.T) {
 func TestProvideContentService_WithDB(t *testing.T) {
 	rc := &RouterConfig{}
 	log := zap.NewNop()
-	result := provideContentService(rc, &providerMockDB{}, log)
+	result := provideContentService(rc, &providerMockDB{}, log, nil)
 	assert.NotNil(t, result)
 }
 
@@ -568,7 +570,7 @@ func TestProvideAuthService_JWTExpiryParsing(t *testing.T) {
 	)
 	rc.AuthService = injected
 
-	authSvc := provideAuthService(rc, cfg, log, web3Svc, challengeStore, 5*time.Minute, nil, res)
+	authSvc := provideAuthService(rc, cfg, log, web3Svc, challengeStore, 5*time.Minute, nil, nil, res)
 	assert.Equal(t, injected, authSvc)
 }
 
@@ -599,7 +601,7 @@ func TestProvideAuthService_InvalidJWTExpiry(t *testing.T) {
 	challengeStore := storage.NewMemoryChallengeStore()
 	defer challengeStore.Close()
 
-	authSvc := provideAuthService(rc, cfg, log, web3Svc, challengeStore, 5*time.Minute, nil, res)
+	authSvc := provideAuthService(rc, cfg, log, web3Svc, challengeStore, 5*time.Minute, nil, nil, res)
 	assert.NotNil(t, authSvc)
 }
 
@@ -623,6 +625,28 @@ func TestProvideObjectStorage_WithInjected(t *testing.T) {
 	assert.Equal(t, mockStorage, result)
 }
 
+func TestProvideAuditExporter(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Audit.Export = config.AuditExportConfig{
+		Enabled: true,
+		Sink:    "file",
+		Format:  "jsonl",
+		Path:    filepath.Join(t.TempDir(), "audit", "audit.jsonl"),
+	}
+	exporter, err := provideAuditExporter(cfg, zap.NewNop(), nil)
+	require.NoError(t, err)
+	exporter.Start()
+	exporter.Log(context.Background(), "plugin.stop", "0xadmin", "plugin", "transcoder", true, "", "")
+	require.NoError(t, exporter.Close())
+	data, err := os.ReadFile(cfg.Audit.Export.Path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"action":"plugin.stop"`)
+
+	cfg.Audit.Export.Sink = "object"
+	_, err = provideAuditExporter(cfg, zap.NewNop(), nil)
+	assert.ErrorContains(t, err, "needs object storage")
+}
+
 func TestSetupRouter_HealthEndpointWorks(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	log := zap.NewNop()
@@ -223,7 +223,7 @@ func TestGwCov_NFTRoutes_VerifyWithoutTokenID(t *testing.T) {
 func TestGwCov_NFTRoutes_VerifyCacheHit(t *testing.T) {
 	verifier := &gwCovVerifier{}
 	cache := newGwCovCache()
-	cache.Set(context.Background(), "1:0x742d35Cc6634C0532925a3b844Bc9e7595f2bD18:0x1234567890abcdef1234567890abcdef12345678:42", middleware.NFTAccessEntry{
+	cache.Set(context.Background(), "1:0x742d35cc6634c0532925a3b844bc9e7595f2bd18:0x1234567890abcdef1234567890abcdef12345678:42", middleware.NFTAccessEntry{
 		HasNFT:  true,
 		Balance: big.NewInt(1),
 		Expires: time.Now().Add(time.Hour),
@@ -255,7 +255,7 @@ func TestGwCov_NFTRoutes_VerifyCachePopulated(t *testing.T) {
 	r.ServeHTTP(w, req)
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), `"cache_hit":false`)
-	key := "1:0x742d35Cc6634C0532925a3b844Bc9e7595f2bD18:0x1234567890abcdef1234567890abcdef12345678:99"
+	key := "1:0x742d35cc6634c0532925a3b844bc9e7595f2bd18:0x1234567890abcdef1234567890abcdef12345678:99"
 	entry, ok := cache.Get(context.Background(), key)
 	assert.True(t, ok)
 	assert.True(t, entry.HasNFT)
@@ -462,7 +462,7 @@ func TestGwCov_ProvideUploadService_NilObjStorage(t *testing.T) {
 
 func TestGwCov_ProvideContentService_WithDB(t *testing.T) {
 	rc := &RouterConfig{}
-	svc := provideContentService(rc, nil, zap.NewNop())
+	svc := provideContentService(rc, nil, zap.NewNop(), nil)
 	assert.Nil(t, svc)
 }
 
@@ -470,7 +470,7 @@ func TestGwCov_ProvideContentService_Injected(t *testing.T) {
 	rc := &RouterConfig{
 		ContentService: &service.ContentService{},
 	}
-	svc := provideContentService(rc, nil, zap.NewNop())
+	svc := provideContentService(rc, nil, zap.NewNop(), nil)
 	assert.NotNil(t, svc)
 }
 
@@ -2,9 +2,12 @@ package gateway
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"math"
+	"net/url"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -30,6 +33,7 @@ import (
 	"github.com/rtcdance/streamgate/pkg/core/config"
 	"github.com/rtcdance/streamgate/pkg/middleware"
 	"github.com/rtcdance/streamgate/pkg/service"
+	"github.com/rtcdance/streamgate/pkg/storage"
 
 	jwt "github.com/golang-jwt/jwt/v4"
 )
@@ -43,6 +47,7 @@ type GRPCServices struct {
 	SegmentStorage service.SegmentStorage
 	UploadService  *service.UploadService
 	TranscodingSvc *service.TranscodingService
+	LiveSvc        *service.LiveService
 	DB             Pinger
 	Cache          Pinger
 	Blacklist      middleware.TokenBlacklistChecker
@@ -70,16 +75,21 @@ func SetupGRPCServer(ctx context.Context, cfg *config.Config, log *zap.Logger, s
 			PermitWithoutStream: true,
 		}),
 		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		// Outermost first: metrics and logging see the code a recovered
+		// panic or a rejected token produces.
 		grpc.ChainUnaryInterceptor(
 			grpcRequestIDUnaryInterceptor(),
-			grpcRecoveryInterceptor(log),
+			middleware.UnaryServerMetrics(),
 			grpcLoggingInterceptor(log),
+			grpcRecoveryInterceptor(log),
+			middleware.UnaryServerDeadline(cfg.GRPC.GetUnaryTimeout()),
 			grpcAuthInterceptor(jwtSecret, svcs.Blacklist, log),
 		),
 		grpc.ChainStreamInterceptor(
 			grpcRequestIDStreamInterceptor(),
-			grpcStreamRecoveryInterceptor(log),
+			middleware.StreamServerMetrics(),
 			grpcStreamLoggingInterceptor(log),
+			grpcStreamRecoveryInterceptor(log),
 			grpcStreamAuthInterceptor(jwtSecret, svcs.Blacklist, log),
 		),
 	}
@@ -155,6 +165,7 @@ func SetupGRPCServer(ctx context.Context, cfg *config.Config, log *zap.Logger, s
 		contentSrv := &contentGrpcServer{
 			contentSvc:   svcs.ContentService,
 			transcodeSvc: svcs.TranscodingSvc,
+			nftVerifier:  svcs.NFTVerifier,
 			log:          log,
 		}
 		contentv1.RegisterContentServiceServer(srv, contentSrv)
@@ -165,7 +176,9 @@ func SetupGRPCServer(ctx context.Context, cfg *config.Config, log *zap.Logger, s
 			authSvc:      svcs.AuthService,
 			nftVerifier:  svcs.NFTVerifier,
 			streamingSvc: svcs.StreamingSvc,
+			contentSvc:   svcs.ContentService,
 			segStore:     svcs.SegmentStorage,
+			liveSvc:      svcs.LiveSvc,
 			log:          log,
 		}
 		streamingv1.RegisterStreamingServiceServer(srv, streamingSrv)
@@ -269,6 +282,9 @@ func (s *authGrpcServer) GetNonce(ctx context.Context, req *authv1.GetNonceReque
 		chainID = -1
 	}
 	challenge, err := s.authSvc.GenerateWalletChallenge(ctx, req.WalletAddress, chainID)
+	if errors.Is(err, storage.ErrChallengeStoreFull) {
+		return nil, status.Error(codes.ResourceExhausted, "too many pending login challenges")
+	}
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to generate challenge")
 	}
@@ -566,6 +582,7 @@ type contentGrpcServer struct {
 	contentv1.UnimplementedContentServiceServer
 	contentSvc   *service.ContentService
 	transcodeSvc *service.TranscodingService
+	nftVerifier  middleware.NFTOwnershipChecker
 	log          *zap.Logger
 }
 
@@ -593,8 +610,48 @@ func (s *contentGrpcServer) GetContent(ctx context.Context, req *contentv1.GetCo
 	}, nil
 }
 
+// VerifyAccess reports whether the caller may play the content: its owner
+// always may, anyone else by holding the NFT named in the request.
 func (s *contentGrpcServer) VerifyAccess(ctx context.Context, req *contentv1.VerifyAccessRequest) (*contentv1.VerifyAccessResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "VerifyAccess not yet implemented")
+	if req.ContentId == "" {
+		return nil, status.Error(codes.InvalidArgument, "content_id is required")
+	}
+	if err := validateID(req.ContentId); err != nil {
+		return nil, err
+	}
+	wallet := grpcWalletFromContext(ctx)
+	if wallet == "" {
+		return nil, status.Error(codes.Unauthenticated, "wallet address required")
+	}
+	if req.WalletAddress != "" && !strings.EqualFold(req.WalletAddress, wallet) {
+		return nil, status.Error(codes.PermissionDenied, "access can only be verified for the authenticated wallet")
+	}
+
+	content, err := s.contentSvc.GetContent(ctx, req.ContentId)
+	if err != nil || content == nil {
+		return nil, status.Error(codes.NotFound, "content not found")
+	}
+	if strings.EqualFold(content.OwnerID, wallet) {
+		return &contentv1.VerifyAccessResponse{HasAccess: true, AccessType: "owner"}, nil
+	}
+	if req.ContractAddress == "" {
+		return &contentv1.VerifyAccessResponse{}, nil
+	}
+	if s.nftVerifier == nil {
+		return nil, status.Error(codes.Internal, "NFT verification service unavailable; access denied for safety")
+	}
+	owned, err := s.nftVerifier.VerifyNFTOwnership(ctx, grpcNFTChainID(ctx), req.ContractAddress, req.TokenId, wallet)
+	if err != nil {
+		s.log.Warn("NFT ownership verification failed",
+			zap.String("wallet", wallet),
+			zap.String("contract", req.ContractAddress),
+			zap.Error(err))
+		return nil, status.Error(codes.PermissionDenied, "NFT ownership verification failed")
+	}
+	if !owned {
+		return &contentv1.VerifyAccessResponse{RequiredNfts: []string{req.ContractAddress}}, nil
+	}
+	return &contentv1.VerifyAccessResponse{HasAccess: true, AccessType: "nft"}, nil
 }
 
 func (s *contentGrpcServer) GetTranscodeStatus(ctx context.Context, req *contentv1.GetTranscodeStatusRequest) (*contentv1.GetTranscodeStatusResponse, error) {
@@ -695,29 +752,74 @@ type streamingGrpcServer struct {
 	authSvc      *service.AuthService
 	nftVerifier  middleware.NFTOwnershipChecker
 	streamingSvc *service.StreamingService
+	contentSvc   *service.ContentService
 	segStore     service.SegmentStorage
+	liveSvc      *service.LiveService
 	log          *zap.Logger
 }
 
 func (s *streamingGrpcServer) GetStreamURL(ctx context.Context, req *streamingv1.GetStreamURLRequest) (*streamingv1.GetStreamURLResponse, error) {
-	_ = req
-	return nil, status.Error(codes.Unimplemented, "GetStreamURL not yet implemented")
+	if f := strings.ToLower(req.Format); f != "" && f != "hls" {
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported format %q; only hls is available", req.Format)
+	}
+	if p := strings.ToLower(req.Protocol); p != "" && p != "hls" {
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported protocol %q; only hls is available", req.Protocol)
+	}
+
+	// Stream URLs are handed to players that fetch sub-manifests and
+	// segments for the whole session, so use the master manifest TTL.
+	const ttl = 30 * time.Minute
+	_, playbackToken, err := s.authorizePlayback(ctx, req.ContentId, ttl)
+	if err != nil {
+		return nil, err
+	}
+	qualitySegments := s.listQualitySegments(ctx, req.ContentId)
+	if len(qualitySegments) == 0 {
+		return nil, status.Error(codes.NotFound, "content not ready; transcode may still be processing")
+	}
+
+	manifestURL := fmt.Sprintf("%s/streaming/%s/manifest.m3u8", APIPrefix, req.ContentId)
+	qualities := make([]string, 0, len(qualitySegments))
+	for q := range qualitySegments {
+		qualities = append(qualities, q)
+	}
+	sort.Strings(qualities)
+
+	resp := &streamingv1.GetStreamURLResponse{
+		StreamUrl:          manifestURL + "?playback_token=" + url.QueryEscape(playbackToken),
+		ManifestUrl:        manifestURL,
+		AvailableQualities: make([]*streamingv1.QualityOption, 0, len(qualities)),
+		ExpiresAt:          time.Now().Add(ttl).Unix(),
+	}
+	for _, q := range qualities {
+		opt := &streamingv1.QualityOption{
+			Quality: q,
+			Url:     fmt.Sprintf("%s?quality=%s&playback_token=%s", manifestURL, url.QueryEscape(q), url.QueryEscape(playbackToken)),
+		}
+		resp.AvailableQualities = append(resp.AvailableQualities, opt)
+		if q == req.Quality {
+			resp.StreamUrl = opt.Url
+		}
+	}
+	return resp, nil
 }
 
-func (s *streamingGrpcServer) GetManifest(ctx context.Context, req *streamingv1.GetManifestRequest) (*streamingv1.GetManifestResponse, error) {
+// authorizePlayback checks the caller's wallet owns the NFT named in the
+// x-nft-* metadata and issues a playback token for contentID valid for ttl.
+func (s *streamingGrpcServer) authorizePlayback(ctx context.Context, contentID string, ttl time.Duration) (string, string, error) {
 	wallet := grpcWalletFromContext(ctx)
 	if wallet == "" {
-		return nil, status.Error(codes.Unauthenticated, "wallet address required")
+		return "", "", status.Error(codes.Unauthenticated, "wallet address required")
 	}
-	if req.ContentId == "" {
-		return nil, status.Error(codes.InvalidArgument, "content_id is required")
+	if contentID == "" {
+		return "", "", status.Error(codes.InvalidArgument, "content_id is required")
 	}
-	if err := validateID(req.ContentId); err != nil {
-		return nil, err
+	if err := validateID(contentID); err != nil {
+		return "", "", err
 	}
 
 	var contract, tokenID string
-	var chainID int64 = 1
+	chainID := grpcNFTChainID(ctx)
 	if md, ok := metadata.FromIncomingContext(ctx); ok {
 		if v := md.Get("x-nft-contract"); len(v) > 0 {
 			contract = v[0]
@@ -725,18 +827,13 @@ func (s *streamingGrpcServer) GetManifest(ctx context.Context, req *streamingv1.
 		if v := md.Get("x-nft-token-id"); len(v) > 0 {
 			tokenID = v[0]
 		}
-		if v := md.Get("x-nft-chain-id"); len(v) > 0 {
-			if parsed, err := fmt.Sscanf(v[0], "%d", &chainID); parsed != 1 || err != nil {
-				chainID = 1
-			}
-		}
 	}
 
 	if contract == "" {
-		return nil, status.Error(codes.PermissionDenied, "NFT contract address required; provide x-nft-contract metadata header")
+		return "", "", status.Error(codes.PermissionDenied, "NFT contract address required; provide x-nft-contract metadata header")
 	}
 	if s.nftVerifier == nil {
-		return nil, status.Error(codes.Internal, "NFT verification service unavailable; access denied for safety")
+		return "", "", status.Error(codes.Internal, "NFT verification service unavailable; access denied for safety")
 	}
 	owned, err := s.nftVerifier.VerifyNFTOwnership(ctx, chainID, contract, tokenID, wallet)
 	if err != nil {
@@ -744,48 +841,77 @@ func (s *streamingGrpcServer) GetManifest(ctx context.Context, req *streamingv1.
 			zap.String("wallet", wallet),
 			zap.String("contract", contract),
 			zap.Error(err))
-		return nil, status.Error(codes.PermissionDenied, "NFT ownership verification failed")
+		return "", "", status.Error(codes.PermissionDenied, "NFT ownership verification failed")
 	}
 	if !owned {
-		return nil, status.Error(codes.PermissionDenied, "NFT ownership required")
+		return "", "", status.Error(codes.PermissionDenied, "NFT ownership required")
 	}
 
-	playbackToken, err := s.authSvc.GeneratePlaybackToken(ctx, wallet, req.ContentId, contract, tokenID, chainID, 2*time.Minute, "")
+	playbackToken, err := s.authSvc.GeneratePlaybackToken(ctx, wallet, contentID, contract, tokenID, chainID, ttl, "")
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to generate playback token")
+		return "", "", status.Error(codes.Internal, "failed to generate playback token")
 	}
+	return wallet, playbackToken, nil
+}
 
-	segmentPrefix := fmt.Sprintf("streams/%s/", req.ContentId)
+// grpcNFTChainID returns the chain named in the x-nft-chain-id metadata,
+// defaulting to Ethereum mainnet.
+func grpcNFTChainID(ctx context.Context) int64 {
+	var chainID int64 = 1
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get("x-nft-chain-id"); len(v) > 0 {
+			if parsed, err := fmt.Sscanf(v[0], "%d", &chainID); parsed != 1 || err != nil {
+				chainID = 1
+			}
+		}
+	}
+	return chainID
+}
+
+// listQualitySegments returns the latest transcoded segments of contentID
+// grouped by quality, or nil when none are stored yet.
+func (s *streamingGrpcServer) listQualitySegments(ctx context.Context, contentID string) map[string][]string {
+	if s.segStore == nil {
+		return nil
+	}
+	segmentPrefix := fmt.Sprintf("streams/%s/", contentID)
+	objs, err := s.segStore.ListObjects(ctx, "streamgate", segmentPrefix)
+	if err != nil {
+		s.log.Warn("Failed to list segments",
+			zap.String("content_id", contentID),
+			zap.Error(err))
+	}
 	qualitySegments := make(map[string][]string)
-	if s.segStore != nil {
-		objs, err := s.segStore.ListObjects(ctx, "streamgate", segmentPrefix)
-		if err != nil {
-			s.log.Warn("Failed to list segments",
-				zap.String("content_id", req.ContentId),
-				zap.Error(err))
+	for _, key := range objs {
+		if !strings.HasSuffix(key, ".ts") {
+			continue
 		}
-		for _, key := range objs {
-			if !strings.HasSuffix(key, ".ts") {
-				continue
-			}
-			rel := strings.TrimPrefix(key, segmentPrefix)
-			parts := strings.SplitN(rel, "/", 2)
-			quality := "default"
-			segName := rel
-			if len(parts) == 2 {
-				quality = parts[0]
-				segName = parts[1]
-			}
-			qualitySegments[quality] = append(qualitySegments[quality], segName)
+		rel := strings.TrimPrefix(key, segmentPrefix)
+		parts := strings.SplitN(rel, "/", 2)
+		quality := "default"
+		segName := rel
+		if len(parts) == 2 {
+			quality = parts[0]
+			segName = parts[1]
 		}
+		qualitySegments[quality] = append(qualitySegments[quality], segName)
+	}
+	return latestSegmentsByQuality(qualitySegments)
+}
+
+func (s *streamingGrpcServer) GetManifest(ctx context.Context, req *streamingv1.GetManifestRequest) (*streamingv1.GetManifestResponse, error) {
+	wallet, playbackToken, err := s.authorizePlayback(ctx, req.ContentId, 2*time.Minute)
+	if err != nil {
+		return nil, err
 	}
+	qualitySegments := s.listQualitySegments(ctx, req.ContentId)
 	if len(qualitySegments) == 0 {
 		return nil, status.Error(codes.NotFound, "content not ready; transcode may still be processing")
 	}
 
 	var manifest string
 	segments := make([]*streamingv1.SegmentInfo, 0)
-	manifest, err = s.streamingSvc.GenerateHLSPlaylist(req.ContentId, qualitySegments, playbackToken)
+	manifest, err = s.streamingSvc.GenerateHLSPlaylist(req.ContentId, qualitySegments, playbackToken, s.streamingSvc.IsLive(req.ContentId))
 	if err != nil {
 		s.log.Error("failed to generate HLS playlist",
 			zap.String("content_id", req.ContentId),
@@ -850,16 +976,122 @@ func (s *streamingGrpcServer) GetSegment(ctx context.Context, req *streamingv1.G
 	}, nil
 }
 
+// StartStream starts playback of the ready stream of a content for an NFT
+// holder, returning a tokenized manifest URL like GetStreamURL.
 func (s *streamingGrpcServer) StartStream(ctx context.Context, req *streamingv1.StartStreamRequest) (*streamingv1.StartStreamResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "StartStream not yet implemented")
+	if s.streamingSvc == nil {
+		return nil, status.Error(codes.Unavailable, "streaming service not available")
+	}
+	if req.WalletAddress != "" && !strings.EqualFold(req.WalletAddress, grpcWalletFromContext(ctx)) {
+		return nil, status.Error(codes.PermissionDenied, "streams can only be started for the authenticated wallet")
+	}
+	const ttl = 30 * time.Minute
+	_, playbackToken, err := s.authorizePlayback(ctx, req.ContentId, ttl)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := s.streamingSvc.GetStream(ctx, req.ContentId)
+	if errors.Is(err, service.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "content not ready; transcode may still be processing")
+	}
+	if err != nil {
+		s.log.Error("failed to load stream", zap.String("content_id", req.ContentId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to load stream")
+	}
+	manifestURL := fmt.Sprintf("%s/streaming/%s/manifest.m3u8", APIPrefix, req.ContentId)
+	return &streamingv1.StartStreamResponse{
+		StreamId:  stream.ID,
+		StreamUrl: manifestURL + "?playback_token=" + url.QueryEscape(playbackToken),
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	}, nil
 }
 
+// StopStream ends a live stream on behalf of its owner, revoking its key
+// and disconnecting the encoder.
 func (s *streamingGrpcServer) StopStream(ctx context.Context, req *streamingv1.StopStreamRequest) (*streamingv1.StopStreamResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "StopStream not yet implemented")
+	wallet, err := s.liveStreamCaller(ctx, req.StreamId)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := s.liveSvc.StopStream(req.StreamId, wallet)
+	if err != nil {
+		return nil, liveStreamStatus(err)
+	}
+	return &streamingv1.StopStreamResponse{
+		Success:  true,
+		Duration: liveStreamDuration(stream),
+	}, nil
 }
 
+// GetStreamStats returns the state of a live stream to its owner.
+// Transferred bytes and bitrate are not tracked per stream and stay zero.
 func (s *streamingGrpcServer) GetStreamStats(ctx context.Context, req *streamingv1.GetStreamStatsRequest) (*streamingv1.GetStreamStatsResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "GetStreamStats not yet implemented")
+	wallet, err := s.liveStreamCaller(ctx, req.StreamId)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := s.liveSvc.GetStream(req.StreamId)
+	if err != nil {
+		return nil, liveStreamStatus(err)
+	}
+	if !strings.EqualFold(stream.WalletAddress, wallet) {
+		return nil, liveStreamStatus(service.ErrNotLiveStreamOwner)
+	}
+	resp := &streamingv1.GetStreamStatsResponse{
+		StreamId: stream.ID,
+		Status:   string(stream.State),
+		Duration: liveStreamDuration(stream),
+	}
+	if stream.StartedAt != nil {
+		resp.StartedAt = stream.StartedAt.Unix()
+	}
+	return resp, nil
+}
+
+// liveStreamCaller checks a live stream request and returns the caller's
+// wallet.
+func (s *streamingGrpcServer) liveStreamCaller(ctx context.Context, streamID string) (string, error) {
+	if s.liveSvc == nil {
+		return "", status.Error(codes.Unavailable, "live streaming not available")
+	}
+	wallet := grpcWalletFromContext(ctx)
+	if wallet == "" {
+		return "", status.Error(codes.Unauthenticated, "wallet address required")
+	}
+	if streamID == "" {
+		return "", status.Error(codes.InvalidArgument, "stream_id is required")
+	}
+	if err := validateID(streamID); err != nil {
+		return "", err
+	}
+	return wallet, nil
+}
+
+// liveStreamStatus maps live service errors to gRPC statuses.
+func liveStreamStatus(err error) error {
+	switch {
+	case errors.Is(err, service.ErrLiveStreamNotFound):
+		return status.Error(codes.NotFound, "stream not found")
+	case errors.Is(err, service.ErrNotLiveStreamOwner):
+		return status.Error(codes.PermissionDenied, "not authorized to access this stream")
+	case errors.Is(err, service.ErrLiveStreamEnded):
+		return status.Error(codes.FailedPrecondition, "stream has ended")
+	default:
+		return status.Error(codes.Internal, "live stream operation failed")
+	}
+}
+
+// liveStreamDuration is how long the stream's current or last publish has
+// run, in seconds: up to its end once stopped, and up to now otherwise.
+func liveStreamDuration(stream service.LiveStream) int64 {
+	if stream.StartedAt == nil {
+		return 0
+	}
+	end := time.Now()
+	if stream.EndedAt != nil {
+		end = *stream.EndedAt
+	}
+	return int64(end.Sub(*stream.StartedAt).Seconds())
 }
 
 // ============================== Upload Service ==============================
@@ -893,7 +1125,7 @@ func (s *uploadGrpcServer) InitUpload(ctx context.Context, req *uploadv1.InitUpl
 	if totalChunks <= 0 {
 		totalChunks = 1
 	}
-	if totalChunks > 10000 {
+	if totalChunks > s.uploadSvc.MaxChunks() {
 		return nil, status.Error(codes.InvalidArgument, "too many chunks")
 	}
 	if totalChunks > math.MaxInt32 {
@@ -1066,7 +1298,7 @@ func grpcWalletFromContext(ctx context.Context) string {
 // grpcRequestIDKey is the context key for the gRPC request ID.
 type grpcRequestIDKey struct{}
 
-const grpcRequestIDHeader = "x-request-id"
+const grpcRequestIDHeader = middleware.GRPCRequestIDHeader
 
 // grpcRequestIDUnaryInterceptor extracts x-request-id from gRPC metadata
 // and injects it into the context for correlation with HTTP-side logging.
@@ -1074,7 +1306,7 @@ func grpcRequestIDUnaryInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		if md, ok := metadata.FromIncomingContext(ctx); ok {
 			if vals := md.Get(grpcRequestIDHeader); len(vals) > 0 && vals[0] != "" {
-				ctx = context.WithValue(ctx, grpcRequestIDKey{}, vals[0])
+				ctx = context.WithValue(middleware.ContextWithRequestID(ctx, vals[0]), grpcRequestIDKey{}, vals[0])
 			}
 		}
 		return handler(ctx, req)
@@ -1086,7 +1318,7 @@ func grpcRequestIDStreamInterceptor() grpc.StreamServerInterceptor {
 	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 		if md, ok := metadata.FromIncomingContext(ss.Context()); ok {
 			if vals := md.Get(grpcRequestIDHeader); len(vals) > 0 && vals[0] != "" {
-				ctx := context.WithValue(ss.Context(), grpcRequestIDKey{}, vals[0])
+				ctx := context.WithValue(middleware.ContextWithRequestID(ss.Context(), vals[0]), grpcRequestIDKey{}, vals[0])
 				ws := &wrappedStream{ServerStream: ss, ctx: ctx}
 				return handler(srv, ws)
 			}
@@ -1165,57 +1397,15 @@ func verifyGRPCJWT(ctx context.Context, fullMethod, jwtSecret string, blacklist
 }
 
 func grpcRecoveryInterceptor(log *zap.Logger) grpc.UnaryServerInterceptor {
-	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Error("gRPC panic recovered",
-					zap.String("method", info.FullMethod),
-					zap.Any("panic", r),
-				)
-				err = status.Error(codes.Internal, "internal server error")
-			}
-		}()
-		return handler(ctx, req)
-	}
+	return middleware.UnaryServerRecovery(log)
 }
 
 func grpcLoggingInterceptor(log *zap.Logger) grpc.UnaryServerInterceptor {
-	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		start := time.Now()
-		resp, err := handler(ctx, req)
-		latency := time.Since(start)
-		code := status.Code(err)
-		fields := []zap.Field{
-			zap.String("method", info.FullMethod),
-			zap.String("code", code.String()),
-			zap.Duration("latency", latency),
-		}
-		if reqID, ok := ctx.Value(grpcRequestIDKey{}).(string); ok && reqID != "" {
-			fields = append(fields, zap.String("request_id", reqID))
-		}
-		if err != nil {
-			fields = append(fields, zap.Error(err))
-		}
-		if code != codes.OK {
-			log.Warn("gRPC unary call", fields...)
-		}
-		return resp, err
-	}
+	return middleware.UnaryServerLogging(log)
 }
 
 func grpcStreamRecoveryInterceptor(log *zap.Logger) grpc.StreamServerInterceptor {
-	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Error("gRPC stream panic recovered",
-					zap.String("method", info.FullMethod),
-					zap.Any("panic", r),
-				)
-				err = status.Error(codes.Internal, "internal server error")
-			}
-		}()
-		return handler(srv, ss)
-	}
+	return middleware.StreamServerRecovery(log)
 }
 
 type wrappedStream struct {
@@ -1226,21 +1416,7 @@ type wrappedStream struct {
 func (w *wrappedStream) Context() context.Context { return w.ctx }
 
 func grpcStreamLoggingInterceptor(log *zap.Logger) grpc.StreamServerInterceptor {
-	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-		start := time.Now()
-		err := handler(srv, ss)
-		latency := time.Since(start)
-		code := status.Code(err)
-		if code != codes.OK {
-			log.Warn("gRPC stream call",
-				zap.String("method", info.FullMethod),
-				zap.String("code", code.String()),
-				zap.Duration("latency", latency),
-				zap.Error(err),
-			)
-		}
-		return err
-	}
+	return middleware.StreamServerLogging(log)
 }
 
 var _ *service.UploadService
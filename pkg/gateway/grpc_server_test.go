@@ -2,10 +2,12 @@ package gateway
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"io"
 	"math/big"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -19,6 +21,7 @@ import (
 	streamingv1 "github.com/rtcdance/streamgate/pkg/api/v1/streaming"
 	uploadv1 "github.com/rtcdance/streamgate/pkg/api/v1/upload"
 	"github.com/rtcdance/streamgate/pkg/service"
+	stg "github.com/rtcdance/streamgate/pkg/storage"
 
 	jwt "github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/assert"
@@ -831,13 +834,49 @@ func TestContentGrpcServer_GetContent(t *testing.T) {
 	})
 }
 
+// grpcContentService serves content c1, owned by owner, from its cache.
+func grpcContentService(owner string) *service.ContentService {
+	cache := newContentMockCache()
+	_ = cache.Set("content:c1", &service.Content{ID: "c1", Title: "Test", OwnerID: owner})
+	return service.NewContentService(&contentMockDB{}, newContentMockObjStore(), cache)
+}
+
 func TestContentGrpcServer_VerifyAccess(t *testing.T) {
-	srv := &contentGrpcServer{}
-	resp, err := srv.VerifyAccess(context.Background(), &contentv1.VerifyAccessRequest{})
-	assert.Error(t, err)
-	assert.Nil(t, resp)
-	st, _ := status.FromError(err)
-	assert.Equal(t, codes.Unimplemented, st.Code())
+	log := zap.NewNop()
+	ctx := context.WithValue(context.Background(), grpcWalletKey, "0xViewer")
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		owner    string
+		verifier middleware.NFTOwnershipChecker
+		req      *contentv1.VerifyAccessRequest
+		code     codes.Code
+		want     *contentv1.VerifyAccessResponse
+	}{
+		{"empty content id", ctx, "0xOwner", nil, &contentv1.VerifyAccessRequest{}, codes.InvalidArgument, nil},
+		{"no wallet", context.Background(), "0xOwner", nil, &contentv1.VerifyAccessRequest{ContentId: "c1"}, codes.Unauthenticated, nil},
+		{"other wallet", ctx, "0xOwner", nil, &contentv1.VerifyAccessRequest{ContentId: "c1", WalletAddress: "0xOther"}, codes.PermissionDenied, nil},
+		{"not found", ctx, "0xOwner", nil, &contentv1.VerifyAccessRequest{ContentId: "c2"}, codes.NotFound, nil},
+		{"owner", ctx, "0xviewer", nil, &contentv1.VerifyAccessRequest{ContentId: "c1"}, codes.OK,
+			&contentv1.VerifyAccessResponse{HasAccess: true, AccessType: "owner"}},
+		{"no contract", ctx, "0xOwner", nil, &contentv1.VerifyAccessRequest{ContentId: "c1"}, codes.OK,
+			&contentv1.VerifyAccessResponse{}},
+		{"nft holder", ctx, "0xOwner", &grpcMockNFTChecker{owns: true}, &contentv1.VerifyAccessRequest{ContentId: "c1", ContractAddress: "0xContract"}, codes.OK,
+			&contentv1.VerifyAccessResponse{HasAccess: true, AccessType: "nft"}},
+		{"nft not held", ctx, "0xOwner", &grpcMockNFTChecker{}, &contentv1.VerifyAccessRequest{ContentId: "c1", ContractAddress: "0xContract"}, codes.OK,
+			&contentv1.VerifyAccessResponse{RequiredNfts: []string{"0xContract"}}},
+		{"verification error", ctx, "0xOwner", &grpcMockNFTChecker{ownsErr: errors.New("rpc down")}, &contentv1.VerifyAccessRequest{ContentId: "c1", ContractAddress: "0xContract"}, codes.PermissionDenied, nil},
+		{"no verifier", ctx, "0xOwner", nil, &contentv1.VerifyAccessRequest{ContentId: "c1", ContractAddress: "0xContract"}, codes.Internal, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &contentGrpcServer{contentSvc: grpcContentService(tt.owner), nftVerifier: tt.verifier, log: log}
+			resp, err := srv.VerifyAccess(tt.ctx, tt.req)
+			assert.Equal(t, tt.code, status.Code(err))
+			assert.Equal(t, tt.want, resp)
+		})
+	}
 }
 
 func TestContentGrpcServer_GetTranscodeStatus(t *testing.T) {
@@ -925,31 +964,217 @@ func TestContentGrpcServer_DeleteContent(t *testing.T) {
 	})
 }
 
-func TestStreamingGrpcServer_Unimplemented(t *testing.T) {
-	srv := &streamingGrpcServer{}
+func TestStreamingGrpcServer_StartStream(t *testing.T) {
+	log := zap.NewNop()
+	authSvc := service.NewAuthService("test-secret-key-that-is-at-least-32-chars", newGrpcMockAuthStorage())
+	ctx := context.WithValue(context.Background(), grpcWalletKey, "0xWallet")
+	ctx = metadata.NewIncomingContext(ctx, metadata.New(map[string]string{
+		"x-nft-contract": "0xContract",
+	}))
+	cache := newContentMockCache()
+	_ = cache.Set("stream:c1", &service.StreamInfo{ID: "stream_c1_1", ContentID: "c1", Status: "ready"})
+	noStreams := &contentMockDB{
+		queryRowFn: func(_ context.Context, _ string, _ ...interface{}) *stg.CancelRow {
+			return stg.NewErrorCancelRow(sql.ErrNoRows)
+		},
+	}
+	streamingSvc := service.NewStreamingService(noStreams, nil, cache, "")
+	srv := &streamingGrpcServer{authSvc: authSvc, nftVerifier: &grpcMockNFTChecker{owns: true}, streamingSvc: streamingSvc, log: log}
+
+	t.Run("ready stream", func(t *testing.T) {
+		resp, err := srv.StartStream(ctx, &streamingv1.StartStreamRequest{ContentId: "c1"})
+		require.NoError(t, err)
+		assert.Equal(t, "stream_c1_1", resp.StreamId)
+		assert.True(t, strings.HasPrefix(resp.StreamUrl, "/api/v1/streaming/c1/manifest.m3u8?playback_token="))
+		assert.Greater(t, resp.ExpiresAt, time.Now().Add(29*time.Minute).Unix())
 
-	t.Run("GetStreamURL", func(t *testing.T) {
-		resp, err := srv.GetStreamURL(context.Background(), &streamingv1.GetStreamURLRequest{})
-		assert.Error(t, err)
+		token := resp.StreamUrl[strings.Index(resp.StreamUrl, "playback_token=")+len("playback_token="):]
+		_, err = authSvc.ValidatePlaybackToken(context.Background(), token, "c1", "")
+		assert.NoError(t, err)
+	})
+
+	t.Run("no ready stream", func(t *testing.T) {
+		resp, err := srv.StartStream(ctx, &streamingv1.StartStreamRequest{ContentId: "c2"})
 		assert.Nil(t, resp)
+		assert.Equal(t, codes.NotFound, status.Code(err))
 	})
 
-	t.Run("StartStream", func(t *testing.T) {
-		resp, err := srv.StartStream(context.Background(), &streamingv1.StartStreamRequest{})
-		assert.Error(t, err)
+	t.Run("other wallet", func(t *testing.T) {
+		resp, err := srv.StartStream(ctx, &streamingv1.StartStreamRequest{ContentId: "c1", WalletAddress: "0xOther"})
 		assert.Nil(t, resp)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
 	})
 
-	t.Run("StopStream", func(t *testing.T) {
-		resp, err := srv.StopStream(context.Background(), &streamingv1.StopStreamRequest{})
-		assert.Error(t, err)
+	t.Run("NFT not owned", func(t *testing.T) {
+		denied := &streamingGrpcServer{authSvc: authSvc, nftVerifier: &grpcMockNFTChecker{}, streamingSvc: streamingSvc, log: log}
+		resp, err := denied.StartStream(ctx, &streamingv1.StartStreamRequest{ContentId: "c1"})
 		assert.Nil(t, resp)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
 	})
 
-	t.Run("GetStreamStats", func(t *testing.T) {
-		resp, err := srv.GetStreamStats(context.Background(), &streamingv1.GetStreamStatsRequest{})
-		assert.Error(t, err)
+	t.Run("no streaming service", func(t *testing.T) {
+		resp, err := (&streamingGrpcServer{}).StartStream(ctx, &streamingv1.StartStreamRequest{ContentId: "c1"})
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+}
+
+// grpcLiveService returns a live service with one idle stream owned by
+// 0xOwner.
+func grpcLiveService(t *testing.T) (*service.LiveService, string) {
+	svc := service.NewLiveService(&dirLivePackager{dir: t.TempDir()}, zap.NewNop())
+	t.Cleanup(svc.Close)
+	stream, _, err := svc.CreateStream("0xOwner", "test")
+	require.NoError(t, err)
+	return svc, stream.ID
+}
+
+func TestStreamingGrpcServer_StopStream(t *testing.T) {
+	log := zap.NewNop()
+	owner := context.WithValue(context.Background(), grpcWalletKey, "0xOwner")
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		streamID func(id string) string
+		code     codes.Code
+	}{
+		{"owner", owner, func(id string) string { return id }, codes.OK},
+		{"not owner", context.WithValue(context.Background(), grpcWalletKey, "0xViewer"), func(id string) string { return id }, codes.PermissionDenied},
+		{"no wallet", context.Background(), func(id string) string { return id }, codes.Unauthenticated},
+		{"not found", owner, func(string) string { return "unknown" }, codes.NotFound},
+		{"empty stream id", owner, func(string) string { return "" }, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			liveSvc, id := grpcLiveService(t)
+			srv := &streamingGrpcServer{liveSvc: liveSvc, log: log}
+			resp, err := srv.StopStream(tt.ctx, &streamingv1.StopStreamRequest{StreamId: tt.streamID(id)})
+			assert.Equal(t, tt.code, status.Code(err))
+			stream, getErr := liveSvc.GetStream(id)
+			require.NoError(t, getErr)
+			if tt.code == codes.OK {
+				assert.True(t, resp.Success)
+				assert.Equal(t, service.LiveStreamState("ended"), stream.State)
+			} else {
+				assert.Equal(t, service.LiveStreamState("idle"), stream.State)
+			}
+		})
+	}
+
+	t.Run("already ended", func(t *testing.T) {
+		liveSvc, id := grpcLiveService(t)
+		srv := &streamingGrpcServer{liveSvc: liveSvc, log: log}
+		_, err := srv.StopStream(owner, &streamingv1.StopStreamRequest{StreamId: id})
+		require.NoError(t, err)
+		_, err = srv.StopStream(owner, &streamingv1.StopStreamRequest{StreamId: id})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("no live service", func(t *testing.T) {
+		resp, err := (&streamingGrpcServer{}).StopStream(owner, &streamingv1.StopStreamRequest{StreamId: "stream_c1_1"})
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+}
+
+func TestStreamingGrpcServer_GetStreamStats(t *testing.T) {
+	liveSvc, id := grpcLiveService(t)
+	srv := &streamingGrpcServer{liveSvc: liveSvc, log: zap.NewNop()}
+	owner := context.WithValue(context.Background(), grpcWalletKey, "0xOwner")
+
+	resp, err := srv.GetStreamStats(owner, &streamingv1.GetStreamStatsRequest{StreamId: id})
+	require.NoError(t, err)
+	assert.Equal(t, &streamingv1.GetStreamStatsResponse{StreamId: id, Status: "idle"}, resp)
+
+	_, err = srv.GetStreamStats(context.WithValue(context.Background(), grpcWalletKey, "0xViewer"), &streamingv1.GetStreamStatsRequest{StreamId: id})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = srv.GetStreamStats(owner, &streamingv1.GetStreamStatsRequest{StreamId: "unknown"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = srv.GetStreamStats(owner, &streamingv1.GetStreamStatsRequest{StreamId: "a/b"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = liveSvc.StopStream(id, "0xOwner")
+	require.NoError(t, err)
+	resp, err = srv.GetStreamStats(owner, &streamingv1.GetStreamStatsRequest{StreamId: id})
+	require.NoError(t, err)
+	assert.Equal(t, "ended", resp.Status)
+}
+
+func TestLiveStreamDuration(t *testing.T) {
+	started := time.Unix(1700000000, 0)
+	ended := started.Add(2 * time.Minute)
+	assert.Zero(t, liveStreamDuration(service.LiveStream{}))
+	assert.Equal(t, int64(120), liveStreamDuration(service.LiveStream{StartedAt: &started, EndedAt: &ended}))
+	assert.Greater(t, liveStreamDuration(service.LiveStream{StartedAt: &started}), int64(120))
+}
+
+func TestStreamingGrpcServer_GetStreamURL(t *testing.T) {
+	log := zap.NewNop()
+	authSvc := service.NewAuthService("test-secret-key-that-is-at-least-32-chars", newGrpcMockAuthStorage())
+	ctx := context.WithValue(context.Background(), grpcWalletKey, "0xWallet")
+	ctx = metadata.NewIncomingContext(ctx, metadata.New(map[string]string{
+		"x-nft-contract": "0xContract",
+	}))
+	segStore := newGrpcMockSegmentStorage()
+	segStore.list = []string{
+		"streams/c1/720p/seg_000.ts",
+		"streams/c1/360p/seg_000.ts",
+		"streams/c1/720p/index.m3u8",
+	}
+	srv := &streamingGrpcServer{
+		authSvc:     authSvc,
+		nftVerifier: &grpcMockNFTChecker{owns: true},
+		segStore:    segStore,
+		log:         log,
+	}
+
+	t.Run("requested quality", func(t *testing.T) {
+		resp, err := srv.GetStreamURL(ctx, &streamingv1.GetStreamURLRequest{ContentId: "c1", Quality: "720p"})
+		require.NoError(t, err)
+		assert.Equal(t, "/api/v1/streaming/c1/manifest.m3u8", resp.ManifestUrl)
+		require.Len(t, resp.AvailableQualities, 2)
+		assert.Equal(t, "360p", resp.AvailableQualities[0].Quality)
+		assert.Equal(t, "720p", resp.AvailableQualities[1].Quality)
+		assert.Equal(t, resp.AvailableQualities[1].Url, resp.StreamUrl)
+		assert.Contains(t, resp.StreamUrl, "quality=720p&playback_token=")
+		assert.Greater(t, resp.ExpiresAt, time.Now().Add(29*time.Minute).Unix())
+
+		token := resp.StreamUrl[strings.Index(resp.StreamUrl, "playback_token=")+len("playback_token="):]
+		_, err = authSvc.ValidatePlaybackToken(context.Background(), token, "c1", "")
+		assert.NoError(t, err, "the embedded token authorizes playback")
+	})
+
+	t.Run("unknown quality falls back to master", func(t *testing.T) {
+		resp, err := srv.GetStreamURL(ctx, &streamingv1.GetStreamURLRequest{ContentId: "c1", Quality: "4k"})
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(resp.StreamUrl, resp.ManifestUrl+"?playback_token="))
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		resp, err := srv.GetStreamURL(ctx, &streamingv1.GetStreamURLRequest{ContentId: "c1", Format: "dash"})
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("no wallet", func(t *testing.T) {
+		resp, err := srv.GetStreamURL(context.Background(), &streamingv1.GetStreamURLRequest{ContentId: "c1"})
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("NFT not owned", func(t *testing.T) {
+		denied := &streamingGrpcServer{authSvc: authSvc, nftVerifier: &grpcMockNFTChecker{}, segStore: segStore, log: log}
+		resp, err := denied.GetStreamURL(ctx, &streamingv1.GetStreamURLRequest{ContentId: "c1"})
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("not transcoded", func(t *testing.T) {
+		empty := &streamingGrpcServer{authSvc: authSvc, nftVerifier: &grpcMockNFTChecker{owns: true}, segStore: newGrpcMockSegmentStorage(), log: log}
+		resp, err := empty.GetStreamURL(ctx, &streamingv1.GetStreamURLRequest{ContentId: "c1"})
 		assert.Nil(t, resp)
+		assert.Equal(t, codes.NotFound, status.Code(err))
 	})
 }
 
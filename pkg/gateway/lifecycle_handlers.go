@@ -0,0 +1,36 @@
+package gateway
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/rtcdance/streamgate/pkg/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterLifecycleRoutes registers the dry-run report and manual runs of
+// the storage lifecycle policies, restricted to adminWallets.
+func RegisterLifecycleRoutes(router *gin.RouterGroup, m *service.LifecycleManager, adminWallets []string) {
+	admin := router.Group(APIPrefix+"/admin/lifecycle", requireAdminWallet(adminWallets))
+	admin.GET("/report", runLifecycle(m, true))
+	admin.POST("/run", runLifecycle(m, false))
+}
+
+// runLifecycle applies the policies, or with dryRun only reports what they
+// would change. Failed actions are listed in the report rather than
+// failing the request.
+func runLifecycle(m *service.LifecycleManager, dryRun bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report, err := m.Run(c.Request.Context(), dryRun)
+		switch {
+		case errors.Is(err, service.ErrAlreadyExists):
+			abortWithError(c, http.StatusConflict, ErrInvalidRequest, "lifecycle run already in progress")
+			return
+		case err != nil:
+			abortWithErrorDetail(c, http.StatusInternalServerError, ErrInternalError, internalErrMsg(c, err), err.Error())
+			return
+		}
+		respondOK(c, report)
+	}
+}
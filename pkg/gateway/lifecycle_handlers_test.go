@@ -0,0 +1,52 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rtcdance/streamgate/pkg/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestLifecycleRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// No policy is enabled, so runs touch neither the database nor storage.
+	m := service.NewLifecycleManager(&categoryMockDB{}, nil, service.LifecycleConfig{}, zap.NewNop())
+
+	tests := []struct {
+		name   string
+		wallet string
+		method string
+		path   string
+		want   int
+		dryRun bool
+	}{
+		{"non-admin", "0xother", http.MethodGet, "/report", http.StatusForbidden, false},
+		{"report", "0xADMIN", http.MethodGet, "/report", http.StatusOK, true},
+		{"run", "0xADMIN", http.MethodPost, "/run", http.StatusOK, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(func(c *gin.Context) { c.Set("wallet_address", tt.wallet); c.Next() })
+			RegisterLifecycleRoutes(r.Group("/"), m, []string{"0xADMIN"})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(tt.method, APIPrefix+"/admin/lifecycle"+tt.path, nil))
+			require.Equal(t, tt.want, w.Code, w.Body.String())
+			if tt.want != http.StatusOK {
+				return
+			}
+			var report service.LifecycleReport
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+			assert.Equal(t, tt.dryRun, report.DryRun)
+			assert.Empty(t, report.Actions)
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/middleware"
+	"github.com/rtcdance/streamgate/pkg/monitoring"
+	"github.com/rtcdance/streamgate/pkg/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// livePlaybackSubject scopes live playback tokens so they cannot be used
+// for VOD content, and vice versa.
+func livePlaybackSubject(streamID string) string {
+	return "live:" + streamID
+}
+
+// rewriteLivePlaylist points the packager's relative segment URIs at the
+// live segment route, with the viewer's playback token.
+func rewriteLivePlaylist(playlist []byte, streamID, playbackToken string) string {
+	lines := strings.Split(string(playlist), "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines[i] = fmt.Sprintf("%s/live/streams/%s/%s?playback_token=%s", APIPrefix, streamID, line, playbackToken)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// liveIngestURL is the RTMP URL encoders publish to, on the host the API
+// was reached at. The stream key is appended as the stream name.
+func liveIngestURL(c *gin.Context, rtmpPort int) string {
+	host := c.Request.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return fmt.Sprintf("rtmp://%s/live", net.JoinHostPort(host, fmt.Sprint(rtmpPort)))
+}
+
+// liveSRTURL is the SRT URL encoders publish to, with the stream key as
+// the stream ID.
+func liveSRTURL(c *gin.Context, srtPort int, key string) string {
+	host := c.Request.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return fmt.Sprintf("srt://%s?streamid=%s", net.JoinHostPort(host, fmt.Sprint(srtPort)), url.QueryEscape(key))
+}
+
+func writeLiveError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrLiveStreamNotFound):
+		abortWithError(c, http.StatusNotFound, ErrNotFound, "live stream not found")
+	case errors.Is(err, service.ErrNotLiveStreamOwner):
+		abortWithError(c, http.StatusForbidden, ErrForbidden, "live stream belongs to another wallet")
+	case errors.Is(err, service.ErrLiveStreamEnded):
+		abortWithError(c, http.StatusGone, ErrContentUnavailable, "live stream has ended")
+	case errors.Is(err, service.ErrTooManyLiveStreams):
+		abortWithError(c, http.StatusTooManyRequests, ErrRateLimited, "too many open live streams; stop one first")
+	case errors.Is(err, service.ErrLivePlaylistNotReady):
+		c.Header("Retry-After", "2")
+		abortWithError(c, http.StatusNotFound, ErrContentNotFound, "live stream has not started")
+	case errors.Is(err, service.ErrInvalidLiveSegment):
+		abortWithError(c, http.StatusBadRequest, ErrInvalidRequest, "invalid segment name")
+	case errors.Is(err, os.ErrNotExist):
+		abortWithError(c, http.StatusNotFound, ErrNotFound, "segment not found")
+	case errors.Is(err, service.ErrInvalidLiveStreamKey):
+		abortWithError(c, http.StatusUnauthorized, ErrUnauthorized, "invalid stream key")
+	case errors.Is(err, service.ErrLiveStreamActive):
+		abortWithError(c, http.StatusConflict, ErrInvalidRequest, "live stream already has a publisher")
+	default:
+		abortWithErrorDetail(c, http.StatusInternalServerError, ErrInternalError, internalErrMsg(c, err), err.Error())
+	}
+}
+
+// RegisterLiveRoutes registers the live stream lifecycle routes and the
+// live playlist. Starting a stream issues a wallet-bound stream key for
+// RTMP and SRT ingest; only the owning wallet can stop it. srtPort is
+// 0 when SRT ingest is off.
+func RegisterLiveRoutes(router gin.IRouter, log *zap.Logger, authService *service.AuthService, svc *service.LiveService, chainID int64, rtmpPort, srtPort int) {
+	router.POST(APIPrefix+"/live/streams", func(c *gin.Context) {
+		wallet := middleware.GetWalletAddress(c)
+		if wallet == "" {
+			abortWithError(c, http.StatusUnauthorized, ErrUnauthorized, "wallet authentication required")
+			return
+		}
+		var req struct {
+			Title string `json:"title"`
+		}
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				abortWithErrorDetail(c, http.StatusBadRequest, ErrInvalidRequest, "invalid request body", err.Error())
+				return
+			}
+		}
+		stream, key, err := svc.CreateStream(wallet, req.Title)
+		if err != nil {
+			writeLiveError(c, err)
+			return
+		}
+		middleware.GetLogger(c, log).Info("Live stream created",
+			zap.String("stream_id", stream.ID),
+			zap.String("wallet", wallet))
+		resp := gin.H{
+			"stream":     stream,
+			"stream_key": key,
+			"ingest_url": liveIngestURL(c, rtmpPort),
+		}
+		if srtPort > 0 {
+			resp["srt_url"] = liveSRTURL(c, srtPort, key)
+		}
+		respondCreated(c, resp)
+	})
+
+	router.GET(APIPrefix+"/live/streams", func(c *gin.Context) {
+		wallet := middleware.GetWalletAddress(c)
+		if wallet == "" {
+			abortWithError(c, http.StatusUnauthorized, ErrUnauthorized, "wallet authentication required")
+			return
+		}
+		streams := svc.ListStreams(wallet)
+		if streams == nil {
+			streams = []service.LiveStream{}
+		}
+		respondOK(c, gin.H{"streams": streams})
+	})
+
+	router.GET(APIPrefix+"/live/streams/:id", func(c *gin.Context) {
+		stream, err := svc.GetStream(c.Param("id"))
+		if err != nil {
+			writeLiveError(c, err)
+			return
+		}
+		respondOK(c, stream)
+	})
+
+	router.POST(APIPrefix+"/live/streams/:id/stop", func(c *gin.Context) {
+		stream, err := svc.StopStream(c.Param("id"), middleware.GetWalletAddress(c))
+		if err != nil {
+			writeLiveError(c, err)
+			return
+		}
+		respondOK(c, stream)
+	})
+
+	router.GET(APIPrefix+"/live/streams/:id/index.m3u8", func(c *gin.Context) {
+		streamID := c.Param("id")
+		wallet := middleware.GetWalletAddress(c)
+		playlist, err := svc.Playlist(streamID)
+		if err != nil {
+			writeLiveError(c, err)
+			return
+		}
+		playbackToken, err := authService.GeneratePlaybackToken(c.Request.Context(), wallet, livePlaybackSubject(streamID), "", "", chainID, 30*time.Minute, c.GetHeader("X-Client-Fingerprint"))
+		if err != nil {
+			abortWithErrorDetail(c, http.StatusInternalServerError, ErrInternalError, internalErrMsg(c, err), err.Error())
+			return
+		}
+		if wallet != "" {
+			_ = svc.TouchViewer(streamID, wallet)
+		}
+		monitoring.StreamingManifestsTotal.Inc()
+
+		c.Header("Content-Type", "application/vnd.apple.mpegurl")
+		// Live playlists change every segment; players poll them.
+		c.Header("Cache-Control", "no-cache")
+		c.String(http.StatusOK, rewriteLivePlaylist(playlist, streamID, playbackToken))
+	})
+}
+
+// RegisterLiveSegmentRoute registers the live segment route. Like the VOD
+// segment routes it must be registered before the JWT middleware: players
+// authenticate with the playback token from the live playlist.
+func RegisterLiveSegmentRoute(router gin.IRouter, log *zap.Logger, authService *service.AuthService, svc *service.LiveService, limiter *streamLimiter) {
+	router.GET(APIPrefix+"/live/streams/:id/:segment", func(c *gin.Context) {
+		playbackToken := extractPlaybackToken(c)
+		if playbackToken == "" {
+			abortWithError(c, http.StatusUnauthorized, ErrUnauthorized, "missing playback token")
+			return
+		}
+		streamID := c.Param("id")
+		claims, err := authService.ValidatePlaybackToken(c.Request.Context(), playbackToken, livePlaybackSubject(streamID), c.GetHeader("X-Client-Fingerprint"))
+		if err != nil {
+			middleware.GetLogger(c, log).Warn("live playback token validation failed",
+				zap.String("stream_id", streamID),
+				zap.Error(err))
+			abortWithError(c, http.StatusUnauthorized, ErrUnauthorized, "invalid playback token")
+			return
+		}
+		c.Set(playbackWalletKey, claims.WalletAddress)
+
+		path, err := svc.SegmentPath(streamID, c.Param("segment"))
+		if err != nil {
+			writeLiveError(c, err)
+			return
+		}
+		if limiter != nil && !limiter.tryAcquire() {
+			c.Header("Retry-After", "1")
+			abortWithError(c, http.StatusServiceUnavailable, ErrStreamLimitReached, "too many concurrent streams; try again shortly")
+			return
+		}
+		if limiter != nil {
+			defer limiter.release()
+		}
+		if claims.WalletAddress != "" {
+			_ = svc.TouchViewer(streamID, claims.WalletAddress)
+		}
+
+		c.Header("Content-Type", "video/mp2t")
+		c.Header("Cache-Control", "private, max-age=60")
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.File(path)
+		monitoring.StreamingSegmentsTotal.WithLabelValues("live").Inc()
+	})
+}
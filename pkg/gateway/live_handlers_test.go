@@ -0,0 +1,180 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rtcdance/streamgate/pkg/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// dirLivePackager serves playlists and segments written into a directory
+// by the test; publishing is never started.
+type dirLivePackager struct {
+	dir string
+}
+
+func (p *dirLivePackager) Start(string, service.LiveInputFormat) (io.WriteCloser, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
+func (p *dirLivePackager) Playlist(streamID string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(p.dir, streamID, "index.m3u8"))
+	if os.IsNotExist(err) {
+		return nil, service.ErrLivePlaylistNotReady
+	}
+	return data, err
+}
+
+func (p *dirLivePackager) SegmentPath(streamID, name string) (string, error) {
+	if strings.Contains(name, "..") {
+		return "", service.ErrInvalidLiveSegment
+	}
+	path := filepath.Join(p.dir, streamID, name)
+	_, err := os.Stat(path)
+	return path, err
+}
+
+func (p *dirLivePackager) Remove(streamID string) error {
+	return os.RemoveAll(filepath.Join(p.dir, streamID))
+}
+
+const liveTestWallet = "0x742d35Cc6634C0532925a3b844Bc9e7595f2bD18"
+
+func newLiveTestRouter(t *testing.T, packager service.LivePackager) (*gin.Engine, *service.LiveService, *string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	authSvc := service.NewAuthService("test-secret-that-is-at-least-32-chars", nil)
+	svc := service.NewLiveService(packager, zap.NewNop())
+	wallet := liveTestWallet
+	r := gin.New()
+	RegisterLiveSegmentRoute(r, zap.NewNop(), authSvc, svc, newStreamLimiter(100))
+	r.Use(func(c *gin.Context) {
+		c.Set("wallet_address", wallet)
+		c.Next()
+	})
+	RegisterLiveRoutes(r, zap.NewNop(), authSvc, svc, 1, 1935, 9000)
+	return r, svc, &wallet
+}
+
+func TestRewriteLivePlaylist(t *testing.T) {
+	in := "#EXTM3U\n#EXT-X-TARGETDURATION:2\n#EXTINF:2.000,\nseg_00003.ts\n"
+	out := rewriteLivePlaylist([]byte(in), "s1", "tok")
+	assert.Contains(t, out, "#EXT-X-TARGETDURATION:2\n")
+	assert.Contains(t, out, "\n/api/v1/live/streams/s1/seg_00003.ts?playback_token=tok\n")
+}
+
+func TestLiveRoutes_Lifecycle(t *testing.T) {
+	r, _, wallet := newLiveTestRouter(t, &dirLivePackager{dir: t.TempDir()})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/live/streams", strings.NewReader(`{"title":"launch"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Host = "streamgate.example.com:8080"
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var created struct {
+		Stream    service.LiveStream `json:"stream"`
+		StreamKey string             `json:"stream_key"`
+		IngestURL string             `json:"ingest_url"`
+		SRTURL    string             `json:"srt_url"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "launch", created.Stream.Title)
+	assert.Equal(t, "idle", string(created.Stream.State))
+	assert.NotEmpty(t, created.StreamKey)
+	assert.Equal(t, "rtmp://streamgate.example.com:1935/live", created.IngestURL)
+	assert.Equal(t, "srt://streamgate.example.com:9000?streamid="+created.StreamKey, created.SRTURL)
+	id := created.Stream.ID
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/live/streams/"+id, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"viewers":0`)
+	assert.NotContains(t, w.Body.String(), created.StreamKey, "the key is only returned on creation")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/live/streams", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), id)
+
+	*wallet = "0x0000000000000000000000000000000000000001"
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/live/streams/"+id+"/stop", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	*wallet = liveTestWallet
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/live/streams/"+id+"/stop", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"state":"ended"`)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/live/streams/"+id+"/stop", nil))
+	assert.Equal(t, http.StatusGone, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/live/streams/missing", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestLiveRoutes_Playback(t *testing.T) {
+	dir := t.TempDir()
+	r, svc, _ := newLiveTestRouter(t, &dirLivePackager{dir: dir})
+	stream, _, err := svc.CreateStream(liveTestWallet, "")
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/live/streams/"+stream.ID+"/index.m3u8", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code, "no playlist before the encoder connects")
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, stream.ID), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, stream.ID, "index.m3u8"), []byte("#EXTM3U\n#EXTINF:2.000,\nseg_00000.ts\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, stream.ID, "seg_00000.ts"), []byte("TS"), 0o644))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/live/streams/"+stream.ID+"/index.m3u8", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+	var segmentURL string
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if strings.HasPrefix(line, "/api/v1/live/") {
+			segmentURL = line
+		}
+	}
+	require.NotEmpty(t, segmentURL)
+
+	got, err := svc.GetStream(stream.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.Viewers)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, segmentURL, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "TS", w.Body.String())
+	assert.Equal(t, "video/mp2t", w.Header().Get("Content-Type"))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/live/streams/"+stream.ID+"/seg_00000.ts", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// A token for one stream does not open another.
+	other, _, err := svc.CreateStream(liveTestWallet, "")
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, strings.Replace(segmentURL, stream.ID, other.ID, 1), nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,107 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/middleware"
+	"github.com/rtcdance/streamgate/pkg/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// moderationInputExpiry is how long the URL ffmpeg samples an upload from
+// stays valid.
+const moderationInputExpiry = 2 * time.Hour
+
+// RegisterModerationRoutes registers the moderator review queue,
+// restricted to adminWallets.
+func RegisterModerationRoutes(router *gin.RouterGroup, svc *service.ModerationService, adminWallets []string) {
+	admin := router.Group(APIPrefix+"/admin/moderation", requireAdminWallet(adminWallets))
+	admin.GET("/pending", listPendingModeration(svc))
+	admin.GET("/:content_id", getModeration(svc))
+	admin.POST("/:content_id/review", reviewContent(svc))
+}
+
+// registerModerationHook submits every processed upload for moderation.
+// The hook's own deadline is far shorter than sampling can take, so the
+// moderation service bounds the run instead.
+func registerModerationHook(uploadSvc *service.UploadService, moderationSvc *service.ModerationService, log *zap.Logger) {
+	uploadSvc.RegisterPostUploadHook(func(ctx context.Context, uploadID, contentID, _ string) {
+		ctx = context.WithoutCancel(ctx)
+		inputURL, err := uploadSvc.GetDownloadURL(ctx, uploadID, moderationInputExpiry)
+		if err != nil {
+			log.Warn("No input URL for moderation, holding content for manual review",
+				zap.String("content_id", contentID), zap.Error(err))
+		}
+		if _, err := moderationSvc.Submit(ctx, contentID, inputURL); err != nil {
+			log.Warn("Moderation failed, content held for review",
+				zap.String("content_id", contentID), zap.Error(err))
+		}
+	})
+}
+
+func listPendingModeration(svc *service.ModerationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		if limit <= 0 || limit > 200 {
+			limit = 50
+		}
+		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		if offset < 0 {
+			offset = 0
+		}
+		recs, err := svc.ListPending(c.Request.Context(), limit, offset)
+		if err != nil {
+			abortWithErrorDetail(c, http.StatusInternalServerError, ErrInternalError, internalErrMsg(c, err), err.Error())
+			return
+		}
+		respondOK(c, gin.H{"content": recs, "limit": limit, "offset": offset})
+	}
+}
+
+func getModeration(svc *service.ModerationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rec, err := svc.Get(c.Request.Context(), c.Param("content_id"))
+		if err != nil {
+			abortWithModerationError(c, err)
+			return
+		}
+		respondOK(c, rec)
+	}
+}
+
+func reviewContent(svc *service.ModerationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Decision string `json:"decision" binding:"required"`
+			Note     string `json:"note"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abortWithErrorDetail(c, http.StatusBadRequest, ErrInvalidRequest, "invalid request body", err.Error())
+			return
+		}
+		rec, err := svc.Review(c.Request.Context(), c.Param("content_id"), req.Decision,
+			middleware.GetWalletAddress(c), req.Note)
+		if err != nil {
+			abortWithModerationError(c, err)
+			return
+		}
+		respondOK(c, rec)
+	}
+}
+
+func abortWithModerationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		abortWithError(c, http.StatusNotFound, ErrNotFound, "content not found")
+	case errors.Is(err, service.ErrInvalidRequest):
+		abortWithErrorDetail(c, http.StatusBadRequest, ErrInvalidRequest, "invalid review", err.Error())
+	default:
+		abortWithErrorDetail(c, http.StatusInternalServerError, ErrInternalError, internalErrMsg(c, err), err.Error())
+	}
+}
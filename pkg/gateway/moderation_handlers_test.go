@@ -0,0 +1,103 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/service"
+	stg "github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// moderationRow scans a contents row whose metadata holds status.
+type moderationRow struct{ status string }
+
+func (r *moderationRow) Scan(dest ...interface{}) error {
+	*dest[0].(*string) = "content-1"
+	*dest[1].(*sql.NullString) = sql.NullString{String: "Clip", Valid: true}
+	*dest[2].(*sql.NullString) = sql.NullString{String: "0xowner", Valid: true}
+	*dest[3].(*[]byte) = []byte(`{"moderation_status":"` + r.status + `","moderation_flagged":true}`)
+	*dest[4].(*time.Time) = time.Now()
+	return nil
+}
+
+func newModerationRouter(db stg.DB, wallet string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	svc := service.NewModerationService(db, nil, nil, service.ModerationConfig{}, zap.NewNop())
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if wallet != "" {
+			c.Set("wallet_address", wallet)
+		}
+		c.Next()
+	})
+	RegisterModerationRoutes(r.Group("/"), svc, []string{"0xADMIN"})
+	return r
+}
+
+func TestModerationRoutes_RequireAdmin(t *testing.T) {
+	for _, wallet := range []string{"", "0xother"} {
+		w := httptest.NewRecorder()
+		newModerationRouter(&categoryMockDB{}, wallet).ServeHTTP(w,
+			httptest.NewRequest(http.MethodGet, APIPrefix+"/admin/moderation/pending", nil))
+		assert.Equal(t, http.StatusForbidden, w.Code, wallet)
+	}
+}
+
+func TestReviewContent(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		found      bool
+		wantCode   int
+		wantStatus string
+	}{
+		{name: "approve", body: `{"decision":"approve","note":"fine"}`, found: true, wantCode: http.StatusOK, wantStatus: "approved"},
+		{name: "reject", body: `{"decision":"reject"}`, found: true, wantCode: http.StatusOK, wantStatus: "rejected"},
+		{name: "bad decision", body: `{"decision":"later"}`, found: true, wantCode: http.StatusBadRequest},
+		{name: "missing decision", body: `{}`, found: true, wantCode: http.StatusBadRequest},
+		{name: "unknown content", body: `{"decision":"approve"}`, wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patch string
+			db := &categoryMockDB{
+				queryRowFn: func(context.Context, string, ...interface{}) *stg.CancelRow {
+					if !tt.found {
+						return stg.NewErrorCancelRow(sql.ErrNoRows)
+					}
+					return stg.NewTestCancelRow(&moderationRow{status: "pending_review"})
+				},
+				execFn: func(_ context.Context, _ string, args ...interface{}) (sql.Result, error) {
+					patch = args[1].(string)
+					return &categoryMockResult{rowsAffected: 1}, nil
+				},
+			}
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, APIPrefix+"/admin/moderation/content-1/review", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			newModerationRouter(db, "0xadmin").ServeHTTP(w, req)
+
+			require.Equal(t, tt.wantCode, w.Code, w.Body.String())
+			if tt.wantCode != http.StatusOK {
+				assert.Empty(t, patch)
+				return
+			}
+			var rec service.ModerationRecord
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rec))
+			assert.Equal(t, tt.wantStatus, rec.Status)
+			assert.Equal(t, "0xadmin", rec.Reviewer)
+			assert.Contains(t, patch, `"moderation_status":"`+tt.wantStatus+`"`)
+		})
+	}
+}
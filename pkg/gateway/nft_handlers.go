@@ -14,6 +14,7 @@ import (
 	"github.com/rtcdance/streamgate/pkg/middleware"
 	"github.com/rtcdance/streamgate/pkg/service"
 	"github.com/rtcdance/streamgate/pkg/util"
+	"github.com/rtcdance/streamgate/pkg/web3"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
@@ -43,6 +44,21 @@ func parseChainID(c *gin.Context, defaultChainID int64) int64 {
 	return chainID
 }
 
+// resolveChain resolves a chain name such as "polygon", or a decimal chain
+// ID, and aborts with 400 if the chain is unknown. Empty chain returns
+// fallback.
+func resolveChain(c *gin.Context, chain string, fallback int64) (int64, bool) {
+	if chain == "" {
+		return fallback, true
+	}
+	chainID, err := web3.ResolveChainID(chain)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, ErrUnsupportedChain, err.Error())
+		return 0, false
+	}
+	return chainID, true
+}
+
 func handleNFTBalance(c *gin.Context, verifier middleware.NFTOwnershipChecker, defaultChainID int64) {
 	wallet := middleware.GetWalletAddress(c)
 	contract := c.Query("contract")
@@ -50,7 +66,10 @@ func handleNFTBalance(c *gin.Context, verifier middleware.NFTOwnershipChecker, d
 		abortWithError(c, http.StatusBadRequest, ErrMissingContract, "valid contract address is required (0x-prefixed 40-hex)")
 		return
 	}
-	chainID := parseChainID(c, defaultChainID)
+	chainID, ok := resolveChain(c, c.Query("chain"), parseChainID(c, defaultChainID))
+	if !ok {
+		return
+	}
 	balance, err := verifier.GetNFTBalance(c.Request.Context(), chainID, contract, wallet)
 	if err != nil {
 		abortWithError(c, http.StatusInternalServerError, ErrNFTVerifyError, "NFT balance check failed")
@@ -66,7 +85,10 @@ func handleNFTOwnership(c *gin.Context, verifier middleware.NFTOwnershipChecker,
 		abortWithError(c, http.StatusBadRequest, ErrMissingContract, "valid contract address is required (0x-prefixed 40-hex)")
 		return
 	}
-	chainID := parseChainID(c, defaultChainID)
+	chainID, ok := resolveChain(c, c.Query("chain"), parseChainID(c, defaultChainID))
+	if !ok {
+		return
+	}
 	wallet := middleware.GetWalletAddress(c)
 	hasNFT, err := verifier.VerifyNFTOwnership(c.Request.Context(), chainID, contract, tokenID, wallet)
 	if err != nil {
@@ -79,12 +101,16 @@ func handleNFTOwnership(c *gin.Context, verifier middleware.NFTOwnershipChecker,
 func handleNFTVerify(c *gin.Context, log *zap.Logger, verifier middleware.NFTOwnershipChecker, cache middleware.NFTAccessCache, defaultChainID int64, cacheTTL time.Duration, blockProver middleware.BlockProver) {
 	var req struct {
 		ChainID         int64  `json:"chain_id"`
+		Chain           string `json:"chain"` // chain name such as "polygon"; overrides chain_id
 		Address         string `json:"address"`
 		Wallet          string `json:"wallet"`
 		OwnerAddress    string `json:"owner_address"`
 		Contract        string `json:"contract"`
 		ContractAddress string `json:"contract_address"`
 		TokenID         string `json:"token_id"`
+		// Standard is optional ("erc721" or "erc1155"); when empty it is
+		// detected via ERC-165 if the verifier supports detection.
+		Standard string `json:"standard"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		abortWithError(c, http.StatusBadRequest, ErrInvalidRequest, "invalid request")
@@ -116,15 +142,28 @@ func handleNFTVerify(c *gin.Context, log *zap.Logger, verifier middleware.NFTOwn
 			return
 		}
 	}
+	standard, ok := normalizeNFTStandard(req.Standard)
+	if !ok {
+		abortWithError(c, http.StatusBadRequest, ErrInvalidRequest, "standard must be erc721 or erc1155")
+		return
+	}
 	chainID := req.ChainID
 	if chainID == 0 {
 		chainID = defaultChainID
 	}
+	chain := req.Chain
+	if chain == "" {
+		chain = c.Query("chain")
+	}
+	chainID, ok = resolveChain(c, chain, chainID)
+	if !ok {
+		return
+	}
 	var hasNFT bool
 	var balance *big.Int
 	var err error
 	var cacheHit bool
-	cacheKey := fmt.Sprintf("%d:%s:%s:%s", chainID, wallet, contract, req.TokenID)
+	cacheKey := fmt.Sprintf("%d:%s:%s:%s", chainID, strings.ToLower(wallet), strings.ToLower(contract), req.TokenID)
 	if cache != nil && !bypassCache {
 		if entry, ok := cache.Get(c.Request.Context(), cacheKey); ok && entry.Expires.After(time.Now()) {
 			hasNFT = entry.HasNFT
@@ -132,6 +171,15 @@ func handleNFTVerify(c *gin.Context, log *zap.Logger, verifier middleware.NFTOwn
 			cacheHit = true
 		}
 	}
+	if !cacheHit && req.TokenID == "" && standard == "" {
+		if detector, ok := verifier.(middleware.NFTStandardDetector); ok {
+			standard = detector.DetectContractType(c.Request.Context(), chainID, contract)
+		}
+	}
+	if !cacheHit && req.TokenID == "" && standard == nftStandardERC1155 {
+		abortWithError(c, http.StatusBadRequest, ErrInvalidRequest, "token_id is required for ERC-1155 contracts")
+		return
+	}
 	if !cacheHit {
 		if req.TokenID != "" {
 			hasNFT, err = verifier.VerifyNFTOwnership(c.Request.Context(), chainID, contract, req.TokenID, wallet)
@@ -169,7 +217,30 @@ func handleNFTVerify(c *gin.Context, log *zap.Logger, verifier middleware.NFTOwn
 	if balance == nil {
 		balance = big.NewInt(0)
 	}
-	respondOK(c, gin.H{"has_nft": hasNFT, "balance": balance.String(), "chain_id": chainID, "contract": contract, "cache_hit": cacheHit, "bypass_cache": bypassCache})
+	resp := gin.H{"has_nft": hasNFT, "balance": balance.String(), "chain_id": chainID, "contract": contract, "cache_hit": cacheHit, "bypass_cache": bypassCache}
+	if standard != "" && standard != "unknown" {
+		resp["standard"] = standard
+	}
+	respondOK(c, resp)
+}
+
+const (
+	nftStandardERC721  = "ERC-721"
+	nftStandardERC1155 = "ERC-1155"
+)
+
+// normalizeNFTStandard maps a client-supplied standard to the names
+// DetectContractType returns. Empty input yields "" so the caller detects it.
+func normalizeNFTStandard(s string) (string, bool) {
+	switch strings.ToLower(strings.ReplaceAll(strings.TrimSpace(s), "-", "")) {
+	case "":
+		return "", true
+	case "erc721":
+		return nftStandardERC721, true
+	case "erc1155":
+		return nftStandardERC1155, true
+	}
+	return "", false
 }
 
 // --- NFT Access Cache ---
@@ -307,3 +307,74 @@ func TestNFTRoutes_Verify_DefaultChainID(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, float64(1), resp["chain_id"])
 }
+
+type nftRouteDetectingChecker struct {
+	nftRouteMockChecker
+	standard string
+	detected int
+}
+
+func (m *nftRouteDetectingChecker) DetectContractType(_ context.Context, _ int64, _ string) string {
+	m.detected++
+	return m.standard
+}
+
+func TestNFTRoutes_Verify_DetectsStandard(t *testing.T) {
+	body := `{"contract":"0x1234567890abcdef1234567890abcdef12345678","wallet":"0x1234567890abcdef1234567890abcdef12345678"}`
+
+	checker := &nftRouteDetectingChecker{nftRouteMockChecker: nftRouteMockChecker{balance: big.NewInt(1)}, standard: "ERC-721"}
+	r := setupNFTRouter(checker, nil, "")
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/nft/verify", bytes.NewBufferString(body))
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "ERC-721", resp["standard"])
+
+	checker = &nftRouteDetectingChecker{standard: "ERC-1155"}
+	r = setupNFTRouter(checker, nil, "")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/api/v1/nft/verify", bytes.NewBufferString(body))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "ERC-1155 needs a token_id")
+}
+
+func TestNFTRoutes_Verify_ExplicitStandard(t *testing.T) {
+	checker := &nftRouteDetectingChecker{nftRouteMockChecker: nftRouteMockChecker{balance: big.NewInt(1)}, standard: "ERC-1155"}
+	r := setupNFTRouter(checker, nil, "")
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/nft/verify", bytes.NewBufferString(`{"contract":"0x1234567890abcdef1234567890abcdef12345678","wallet":"0x1234567890abcdef1234567890abcdef12345678","standard":"erc721"}`))
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Zero(t, checker.detected, "an explicit standard skips detection")
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/api/v1/nft/verify", bytes.NewBufferString(`{"contract":"0x1234567890abcdef1234567890abcdef12345678","standard":"erc20"}`))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestNFTRoutes_ChainName(t *testing.T) {
+	r := setupNFTRouter(&nftRouteMockChecker{balance: big.NewInt(1)}, nil, "0x1234567890abcdef1234567890abcdef12345678")
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/nft?contract=0x1234567890abcdef1234567890abcdef12345678&chain=polygon", http.NoBody)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, float64(137), resp["chain_id"])
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/api/v1/nft/verify", bytes.NewBufferString(`{"contract":"0x1234567890abcdef1234567890abcdef12345678","wallet":"0x1234567890abcdef1234567890abcdef12345678","chain":"base","chain_id":1,"standard":"erc721"}`))
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, float64(8453), resp["chain_id"], "chain overrides chain_id")
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/api/v1/nft?contract=0x1234567890abcdef1234567890abcdef12345678&chain=dogechain", http.NoBody)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), ErrUnsupportedChain)
+}
@@ -0,0 +1,31 @@
+package gateway
+
+import (
+	"net/http"
+
+	"github.com/rtcdance/streamgate/pkg/core"
+	"github.com/rtcdance/streamgate/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterPluginAdminRoutes serves the kernel's plugin admin API under
+// APIPrefix+"/admin", restricted to adminWallets: listing, starting,
+// stopping and reloading plugins and patching the running config.
+func RegisterPluginAdminRoutes(router *gin.RouterGroup, kernel *core.Microkernel, adminWallets []string) {
+	admin := router.Group(APIPrefix+"/admin", requireAdminWallet(adminWallets))
+	handle := pluginAdmin(kernel)
+	admin.GET("/plugins", handle)
+	admin.POST("/plugins/:name/:action", handle)
+	admin.PUT("/config", handle)
+}
+
+// pluginAdmin passes the request on to kernel.AdminHandler with the
+// authenticated wallet in its context.
+func pluginAdmin(kernel *core.Microkernel) gin.HandlerFunc {
+	handler := http.StripPrefix(APIPrefix, kernel.AdminHandler())
+	return func(c *gin.Context) {
+		ctx := core.WithAdminWallet(c.Request.Context(), middleware.GetWalletAddress(c))
+		handler.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+	}
+}
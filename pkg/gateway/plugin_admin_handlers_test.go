@@ -0,0 +1,55 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rtcdance/streamgate/pkg/core"
+	"github.com/rtcdance/streamgate/pkg/core/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPluginAdminRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{Mode: "monolith"}
+	cfg.Auth.AdminWallets = []string{"0xADMIN"}
+	kernel, err := core.NewMicrokernel(cfg, zap.NewNop())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		wallet string
+		header string // a client-supplied X-Wallet-Address
+		method string
+		path   string
+		want   int
+	}{
+		{"non-admin", "0xother", "", http.MethodGet, "/plugins", http.StatusForbidden},
+		{"spoofed header", "0xother", "0xADMIN", http.MethodGet, "/plugins", http.StatusForbidden},
+		{"list", "0xadmin", "", http.MethodGet, "/plugins", http.StatusOK},
+		{"unknown plugin", "0xADMIN", "", http.MethodPost, "/plugins/nope/reload", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(func(c *gin.Context) { c.Set("wallet_address", tt.wallet); c.Next() })
+			RegisterPluginAdminRoutes(r.Group("/"), kernel, cfg.Auth.AdminWallets)
+
+			req := httptest.NewRequest(tt.method, APIPrefix+"/admin"+tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("X-Wallet-Address", tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tt.want, w.Code, w.Body.String())
+			if tt.want == http.StatusOK {
+				assert.JSONEq(t, `{"plugins":[]}`, w.Body.String())
+			}
+		})
+	}
+}
@@ -5,13 +5,19 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/rtcdance/streamgate/pkg/core/event"
+	"github.com/rtcdance/streamgate/pkg/discovery"
+	"github.com/rtcdance/streamgate/pkg/middleware"
 	"github.com/rtcdance/streamgate/pkg/monitoring"
 	"github.com/rtcdance/streamgate/pkg/plugins/transcoder"
+	"github.com/rtcdance/streamgate/pkg/resilience"
 	"github.com/rtcdance/streamgate/pkg/service"
 	"github.com/rtcdance/streamgate/pkg/storage"
+	"github.com/rtcdance/streamgate/pkg/web3"
 
 	"github.com/go-redis/redis/v8"
 	_ "github.com/lib/pq"
@@ -53,13 +59,22 @@ func provideWeb3Service(rc *RouterConfig, cfg *config.Config, log *zap.Logger) (
 	return svc, nil
 }
 
-func provideChallengeStore(rc *RouterConfig, log *zap.Logger, challengeTTL time.Duration, redisClient *redis.Client, res *AppResources) storage.ChallengeStore {
+func provideChallengeStore(rc *RouterConfig, cfg *config.Config, log *zap.Logger, challengeTTL time.Duration, redisClient *redis.Client, res *AppResources) storage.ChallengeStore {
 	if rc.ChallengeStore != nil {
 		return rc.ChallengeStore
 	}
 	if redisClient == nil {
-		log.Warn("Redis unavailable, falling back to in-memory challenge store")
-		store := storage.NewMemoryChallengeStore()
+		log.Warn("Redis unavailable, falling back to in-memory challenge store",
+			zap.Int("max_challenges", cfg.Auth.MaxInMemoryChallenges))
+		policy, err := storage.ParseOverflowPolicy(cfg.Auth.ChallengeOverflowPolicy)
+		if err != nil {
+			log.Warn("Invalid auth.challenge_overflow_policy, evicting oldest challenges", zap.Error(err))
+			policy = storage.OverflowEvictOldest
+		}
+		store := storage.NewMemoryChallengeStore(
+			storage.WithMaxChallenges(cfg.Auth.MaxInMemoryChallenges),
+			storage.WithChallengeOverflowPolicy(policy),
+		)
 		res.ChallengeStore = store
 		return store
 	}
@@ -82,7 +97,7 @@ func provideTokenBlacklist(log *zap.Logger, redisClient *redis.Client, res *AppR
 	return rbl
 }
 
-func provideAuthService(rc *RouterConfig, cfg *config.Config, log *zap.Logger, web3Svc *service.Web3Service, challengeStore storage.ChallengeStore, challengeTTL time.Duration, redisClient *redis.Client, res *AppResources) *service.AuthService {
+func provideAuthService(rc *RouterConfig, cfg *config.Config, log *zap.Logger, web3Svc *service.Web3Service, challengeStore storage.ChallengeStore, challengeTTL time.Duration, redisClient *redis.Client, auditLogger *storage.PostgresAuditLogger, res *AppResources) *service.AuthService {
 	if rc.AuthService != nil {
 		return rc.AuthService
 	}
@@ -98,7 +113,7 @@ func provideAuthService(rc *RouterConfig, cfg *config.Config, log *zap.Logger, w
 		}
 	}
 
-	return service.NewAuthService(cfg.Auth.JWTSecret, nil,
+	opts := []service.AuthServiceOption{
 		service.WithSignatureVerifier(signatureVerifier),
 		service.WithEIP712Verifier(eip712Verifier),
 		service.WithChallengeStore(challengeStore),
@@ -106,7 +121,24 @@ func provideAuthService(rc *RouterConfig, cfg *config.Config, log *zap.Logger, w
 		service.WithTokenBlacklist(tokenBlacklist),
 		service.WithJWTExpiry(jwtExpiry),
 		service.WithSIWEDomain(cfg.Auth.SIWEDomain, cfg.Auth.SIWEURI),
-	)
+		service.WithChallengeMessage(cfg.Auth.ChallengeMessageFormat, loginMessageTemplate(cfg, log), cfg.Auth.ChallengeStatement),
+	}
+	if auditLogger != nil {
+		opts = append(opts, service.WithAuditLogger(auditLogger))
+	}
+	return service.NewAuthService(cfg.Auth.JWTSecret, nil, opts...)
+}
+
+// loginMessageTemplate compiles the configured personal_sign template.
+// LoadConfig has already validated it; a bad template in a hand-built
+// config falls back to the built-in message.
+func loginMessageTemplate(cfg *config.Config, log *zap.Logger) *web3.LoginMessageTemplate {
+	tmpl, err := web3.ParseLoginMessageTemplate(cfg.Auth.ChallengeMessageTemplate)
+	if err != nil {
+		log.Warn("Ignoring invalid login message template", zap.Error(err))
+		return nil
+	}
+	return tmpl
 }
 
 func provideDatabase(cfg *config.Config, log *zap.Logger, res *AppResources) (db storage.DB, sqlDB *sql.DB) {
@@ -151,52 +183,139 @@ func provideDatabase(cfg *config.Config, log *zap.Logger, res *AppResources) (db
 	}
 	db = pg
 	sqlDB = d
+	if replicas := openReadReplicas(cfg, log, res); len(replicas) > 0 {
+		retryAfter, _ := time.ParseDuration(cfg.Database.ReplicaRetryAfter)
+		db = storage.NewReplicaDB(pg, replicas, storage.ReplicaConfig{RetryAfter: retryAfter})
+		log.Info("Read replicas enabled", zap.Int("replicas", len(replicas)))
+	}
 	return
 }
 
-func provideContentService(rc *RouterConfig, db storage.DB, log *zap.Logger) *service.ContentService {
+// openReadReplicas connects to cfg.Database.ReplicaDSNs. A replica that
+// does not answer at startup is still added: routing skips it while it
+// fails and picks it up once it recovers.
+func openReadReplicas(cfg *config.Config, log *zap.Logger, res *AppResources) []storage.DB {
+	var replicas []storage.DB
+	for i, dsn := range cfg.Database.ReplicaDSNs {
+		d, err := sql.Open("postgres", dsn)
+		if err != nil {
+			log.Warn("Ignoring invalid read replica DSN", zap.Int("replica", i), zap.Error(err))
+			continue
+		}
+		pingCtx, pingCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := d.PingContext(pingCtx); err != nil {
+			log.Warn("Read replica ping failed, reads will use the primary until it recovers",
+				zap.Int("replica", i), zap.Error(err))
+		}
+		pingCancel()
+		pg := storage.NewPostgresDBFromDB(d)
+		pg.SetMaxOpenConns(cfg.Database.MaxConns)
+		if cfg.Database.MaxIdleConns > 0 {
+			pg.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+		}
+		res.ReplicaDBs = append(res.ReplicaDBs, d)
+		replicas = append(replicas, pg)
+	}
+	return replicas
+}
+
+func provideContentService(rc *RouterConfig, db storage.DB, log *zap.Logger, auditLogger *storage.PostgresAuditLogger) *service.ContentService {
 	if rc.ContentService != nil {
 		return rc.ContentService
 	}
 	if db != nil {
 		log.Info("Content service initialized")
-		return service.NewContentService(db, nil, nil)
+		svc := service.NewContentService(db, nil, nil)
+		if auditLogger != nil {
+			svc.SetAuditLogger(auditLogger)
+		}
+		return svc
 	}
 	log.Warn("Content service unavailable, database not connected")
 	return nil
 }
 
+func provideStreamingService(cfg *config.Config, log *zap.Logger, db storage.DB) *service.StreamingService {
+	svc := service.NewStreamingService(db, nil, nil, "", log.Named("streaming"))
+	svc.SetManifestLimits(service.ManifestLimits{
+		MaxSegments: cfg.Streaming.MaxManifestSegments,
+		LiveWindow:  cfg.Streaming.LiveWindowSegments,
+	})
+	if egress := cfg.Streaming.Egress; len(egress.Regions) > 0 {
+		regions := make([]service.EgressRegion, 0, len(egress.Regions))
+		for _, r := range egress.Regions {
+			regions = append(regions, service.EgressRegion{Name: r.Name, BaseURL: r.BaseURL, Countries: r.Countries})
+		}
+		router, err := service.NewEgressRouter(egress.CountryHeader, egress.DefaultRegion, regions)
+		if err != nil {
+			log.Error("Invalid egress region config, serving segments from origin", zap.Error(err))
+		} else {
+			svc.SetEgressRouter(router)
+		}
+	}
+	return svc
+}
+
+func provideAccessAnalytics(cfg *config.Config, log *zap.Logger) *service.AccessAnalytics {
+	var ac service.AccessAnalyticsConfig
+	if d, err := time.ParseDuration(cfg.Analytics.BucketSize); err == nil {
+		ac.BucketSize = d
+	} else if cfg.Analytics.BucketSize != "" {
+		log.Warn("Invalid analytics bucket size, using default", zap.String("value", cfg.Analytics.BucketSize))
+	}
+	if d, err := time.ParseDuration(cfg.Analytics.Retention); err == nil {
+		ac.Retention = d
+	} else if cfg.Analytics.Retention != "" {
+		log.Warn("Invalid analytics retention, using default", zap.String("value", cfg.Analytics.Retention))
+	}
+	return service.NewAccessAnalytics(ac)
+}
+
+// provideUpstreams builds the gateway's microservice dispatcher. Service
+// discovery is only connected when an upstream is named by service.
+func provideUpstreams(cfg *config.Config, log *zap.Logger) (*upstreamDispatcher, error) {
+	var resolver *discovery.Resolver
+	for _, u := range cfg.Gateway.Upstreams {
+		if u.URL == "" && u.Service != "" {
+			registry, err := discovery.New(cfg, log.Named("discovery"))
+			if err != nil {
+				return nil, fmt.Errorf("gateway upstream discovery: %w", err)
+			}
+			resolver = discovery.NewResolver(registry, discovery.DefaultResolverTTL)
+			break
+		}
+	}
+	return newUpstreamDispatcher(cfg.Gateway.Upstreams, buildCircuitBreakerConfig(cfg), resolver, log.Named("upstream"))
+}
+
 func provideObjectStorage(rc *RouterConfig, cfg *config.Config, log *zap.Logger, res *AppResources) service.SegmentStorage {
 	if rc.SegmentStorage != nil {
 		return rc.SegmentStorage
 	}
-	minioCfg := storage.MinIOConfig{
-		Endpoint:        cfg.Storage.Endpoint,
-		AccessKeyID:     cfg.Storage.AccessKey,
-		SecretAccessKey: cfg.Storage.SecretKey,
-		UseSSL:          cfg.Storage.UseSSL,
-	}
-	ms, err := storage.NewMinIOStorage(minioCfg)
+	store, err := storage.NewObjectStorageFromConfig(cfg.Storage)
 	if err != nil {
-		log.Warn("MinIO unavailable, segment serving disabled", zap.Error(err))
+		log.Warn("Object storage unavailable, segment serving disabled",
+			zap.String("type", cfg.Storage.Type), zap.Error(err))
 		return nil
 	}
-	res.ObjStorage = ms
+	res.ObjStorage = store
 	bucketCtx, bucketCancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer bucketCancel()
-	if err := ms.CreateBucket(bucketCtx, "streamgate"); err != nil {
+	if err := store.CreateBucket(bucketCtx, "streamgate"); err != nil {
 		log.Warn("Failed to create streamgate bucket", zap.Error(err))
 	}
-	log.Info("MinIO storage initialized", zap.String("endpoint", cfg.Storage.Endpoint))
-	return ms
+	log.Info("Object storage initialized",
+		zap.String("type", cfg.Storage.Type), zap.String("endpoint", cfg.Storage.Endpoint))
+	return store
 }
 
-func provideTranscodingService(cfg *config.Config, log *zap.Logger, db storage.DB, objStorage service.SegmentStorage, res *AppResources) *service.TranscodingService {
+func provideTranscodingService(cfg *config.Config, log *zap.Logger, db storage.DB, objStorage service.SegmentStorage, redisClient *redis.Client, res *AppResources) *service.TranscodingService {
 	ffmpegCfg := &transcoder.FFmpegConfig{
-		FFmpegPath:  "ffmpeg",
-		FFprobePath: "ffprobe",
-		TempDir:     os.TempDir(),
-		Timeout:     30 * time.Minute,
+		FFmpegPath:           "ffmpeg",
+		FFprobePath:          "ffprobe",
+		TempDir:              os.TempDir(),
+		Timeout:              30 * time.Minute,
+		AllowPartialVariants: cfg.Transcoding.AllowPartialVariants,
 	}
 	ft := transcoder.NewFFmpegTranscoder(ffmpegCfg, log.Named("ffmpeg"))
 	videoTranscoder := &ffmpegRouterAdapter{ft: ft, log: log.Named("ffmpeg")}
@@ -212,15 +331,45 @@ func provideTranscodingService(cfg *config.Config, log *zap.Logger, db storage.D
 		res.NATSQueue = nq
 	}
 
+	var seen event.SeenSet
+	if redisClient != nil {
+		seen = storage.NewRedisSeenSet(redisClient, "transcode")
+	} else {
+		log.Warn("Redis unavailable, transcode de-duplication is limited to this process")
+		seen = event.NewMemorySeenSet()
+	}
+
 	svc := service.NewTranscodingService(db, transcodingQueue,
 		service.WithTranscoder(videoTranscoder),
 		service.WithStorage(objStorage),
 		service.WithLogger(log),
+		service.WithDeduplicator(event.NewDeduplicator(seen, 0, log.Named("transcode-dedup"))),
+		service.WithBudget(transcodeBudgetConfig(cfg.Transcoding.Budget)),
+		service.WithRetryBudget(newRetryBudget(cfg)),
 	)
 	svc.StartWorker(log.Named("transcode-worker"))
 	return svc
 }
 
+func newRetryBudget(cfg *config.Config) *resilience.RetryBudget {
+	return resilience.NewRetryBudget(resilience.RetryBudgetConfig{
+		RatePerSecond: cfg.RetryBudget.RatePerSecond,
+		Burst:         cfg.RetryBudget.Burst,
+	})
+}
+
+func transcodeBudgetConfig(c config.TranscodeBudgetConfig) service.TranscodeBudgetConfig {
+	defaultDuration, _ := time.ParseDuration(c.DefaultDuration)
+	return service.TranscodeBudgetConfig{
+		MonthlyLimit: c.MonthlyLimit,
+		Weights: service.TranscodeCostWeights{
+			PerRungSecond:      c.PerRungSecond,
+			PerMegapixelSecond: c.PerMegapixelSecond,
+		},
+		DefaultDuration: defaultDuration,
+	}
+}
+
 func provideUploadService(rc *RouterConfig, cfg *config.Config, log *zap.Logger, db storage.DB, objStorage service.SegmentStorage, transcodingSvc *service.TranscodingService) *service.UploadService {
 	if rc.UploadService != nil {
 		return rc.UploadService
@@ -240,14 +389,30 @@ func provideUploadService(rc *RouterConfig, cfg *config.Config, log *zap.Logger,
 	if cfg.Upload.StorageQuota > 0 {
 		svc.SetStorageQuota(cfg.Upload.StorageQuota)
 	}
+	svc.SetChunkSizePolicy(service.ChunkSizePolicy{
+		MinChunkSize: cfg.Upload.MinChunkSize,
+		MaxChunkSize: cfg.Upload.MaxChunkSize,
+		MaxChunks:    cfg.Upload.MaxChunks,
+	})
 	var presigner service.PresignedURLer
 	if ps, ok := objStorage.(service.PresignedURLer); ok {
 		presigner = ps
 		svc.SetPresigner(ps)
 	}
-	if ups, ok := objStorage.(service.UploadPresignedURLer); ok {
+	if ups, ok := storage.AsPresignedUploader(objStorage); ok {
 		svc.SetUploadPresigner(ups)
 	}
+	if mu, ok := storage.AsMultipartUploader(objStorage); ok {
+		svc.SetMultipartUploader(mu)
+	}
+	scanner, err := service.NewUploadScanner(cfg.Upload.Scan)
+	if err != nil {
+		log.Error("Upload malware scanning misconfigured, upload service disabled", zap.Error(err))
+		return nil
+	}
+	if scanner != nil {
+		svc.SetScanner(scanner)
+	}
 	svc.RegisterAutoTranscodeHook(service.AutoTranscodeHookDeps{
 		TranscodingSvc: transcodingSvc,
 		Presigner:      presigner,
@@ -257,16 +422,257 @@ func provideUploadService(rc *RouterConfig, cfg *config.Config, log *zap.Logger,
 	return svc
 }
 
+// provideModerationService builds the moderation service and submits every
+// processed upload to it. A misconfigured moderation setup fails startup
+// rather than publishing uploads unreviewed.
+func provideModerationService(cfg *config.Config, log *zap.Logger, db storage.DB, uploadSvc *service.UploadService) (*service.ModerationService, error) {
+	if db == nil {
+		if cfg.Moderation.Enabled {
+			log.Warn("Database unavailable, content moderation disabled")
+		}
+		return nil, nil
+	}
+	svc, err := service.NewModerationServiceFromConfig(db, cfg.Moderation, log.Named("moderation"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure moderation: %w", err)
+	}
+	if svc != nil && uploadSvc != nil {
+		registerModerationHook(uploadSvc, svc, log.Named("moderation"))
+	}
+	return svc, nil
+}
+
+// provideArchiveService builds the Arweave archive service and archives
+// every original once it is transcoded. A misconfigured archive fails
+// startup rather than silently leaving originals unarchived.
+func provideArchiveService(cfg *config.Config, log *zap.Logger, db storage.DB, objStorage service.SegmentStorage, transcodingSvc *service.TranscodingService) (*service.ArchiveService, error) {
+	if db == nil || objStorage == nil {
+		if cfg.Archive.Enabled {
+			log.Warn("Database or object storage unavailable, archiving disabled")
+		}
+		return nil, nil
+	}
+	svc, err := service.NewArchiveServiceFromConfig(db, objStorage, cfg.Archive, cfg.Storage.Bucket, log.Named("archive"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure archive: %w", err)
+	}
+	if svc != nil && transcodingSvc != nil {
+		registerArchiveHook(transcodingSvc, svc)
+	}
+	return svc, nil
+}
+
+// provideLifecycleManager builds the storage lifecycle manager and starts
+// its scheduled runs.
+func provideLifecycleManager(cfg *config.Config, log *zap.Logger, db storage.DB, objStorage service.SegmentStorage) (*service.LifecycleManager, error) {
+	if db == nil || objStorage == nil {
+		if cfg.Lifecycle.Enabled {
+			log.Warn("Database or object storage unavailable, lifecycle management disabled")
+		}
+		return nil, nil
+	}
+	m, interval, err := service.NewLifecycleManagerFromConfig(db, objStorage, cfg.Lifecycle, cfg.Storage.Bucket, log.Named("lifecycle"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure lifecycle: %w", err)
+	}
+	if m == nil {
+		return nil, nil
+	}
+	if bc, ok := objStorage.(interface {
+		CreateBucket(ctx context.Context, bucket string) error
+	}); ok && cfg.Lifecycle.ColdBucket != "" {
+		bucketCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := bc.CreateBucket(bucketCtx, cfg.Lifecycle.ColdBucket); err != nil {
+			log.Warn("Failed to create cold storage bucket", zap.String("bucket", cfg.Lifecycle.ColdBucket), zap.Error(err))
+		}
+	}
+	if interval > 0 {
+		m.Start(interval, cfg.Lifecycle.DryRun)
+	}
+	log.Info("Storage lifecycle management enabled",
+		zap.Duration("interval", interval), zap.Bool("dry_run", cfg.Lifecycle.DryRun))
+	return m, nil
+}
+
+// provideSearchService builds the metadata search service. Indexing
+// backends are rebuilt on a schedule and fed newly transcoded content.
+func provideSearchService(cfg *config.Config, log *zap.Logger, db storage.DB, transcodingSvc *service.TranscodingService) (*service.SearchService, error) {
+	if db == nil {
+		if cfg.Search.Enabled {
+			log.Warn("Database unavailable, metadata search disabled")
+		}
+		return nil, nil
+	}
+	svc, interval, err := service.NewSearchServiceFromConfig(db, cfg.Search, log.Named("search"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure search: %w", err)
+	}
+	if svc == nil || !svc.Indexed() {
+		return svc, nil
+	}
+	if transcodingSvc != nil {
+		registerSearchHook(transcodingSvc, svc)
+	}
+	svc.Start(interval)
+	log.Info("Metadata search index enabled",
+		zap.String("driver", cfg.Search.Driver), zap.Duration("reindex_interval", interval))
+	return svc, nil
+}
+
+// provideAuditLogger starts the audit log writer and its retention purge
+// when the database is available and audit.enabled is set, along with the
+// exporter when audit.export is enabled.
+func provideAuditLogger(cfg *config.Config, log *zap.Logger, sqlDB *sql.DB, objStorage service.SegmentStorage, res *AppResources) *storage.PostgresAuditLogger {
+	if !cfg.Audit.Enabled {
+		if cfg.Audit.Export.Enabled {
+			log.Warn("Audit log disabled, audit export disabled")
+		}
+		return nil
+	}
+	if sqlDB == nil {
+		log.Warn("Database unavailable, audit log disabled")
+		return nil
+	}
+	al := storage.NewPostgresAuditLogger(storage.NewPostgresDBFromDB(sqlDB), log.Named("audit"))
+	al.SetRetention(cfg.Audit.GetRetention())
+	if cfg.Audit.Export.Enabled {
+		exporter, err := provideAuditExporter(cfg, log, objStorage)
+		if err != nil {
+			log.Error("Audit export misconfigured, audit export disabled", zap.Error(err))
+		} else {
+			exporter.Start()
+			al.SetExporter(exporter)
+			res.AuditExporter = exporter
+			log.Info("Audit export enabled",
+				zap.String("sink", cfg.Audit.Export.Sink), zap.String("format", cfg.Audit.Export.Format))
+		}
+	}
+	al.Start()
+	res.AuditLogger = al
+	log.Info("Audit log enabled", zap.Duration("retention", cfg.Audit.GetRetention()))
+	return al
+}
+
+// provideAuditExporter builds the exporter for the audit.export sink.
+func provideAuditExporter(cfg *config.Config, log *zap.Logger, objStorage service.SegmentStorage) (*storage.AuditExporter, error) {
+	ex := cfg.Audit.Export
+	var sink storage.AuditSink
+	switch ex.Sink {
+	case "file":
+		if err := os.MkdirAll(filepath.Dir(ex.Path), 0o750); err != nil {
+			return nil, fmt.Errorf("create audit export directory: %w", err)
+		}
+		fs, err := storage.NewFileAuditSink(ex.Path, ex.MaxBytes)
+		if err != nil {
+			return nil, err
+		}
+		sink = fs
+	case "object":
+		store, ok := objStorage.(storage.ObjectStorage)
+		if !ok {
+			return nil, fmt.Errorf("object sink needs object storage")
+		}
+		bucket := ex.Bucket
+		if bucket == "" {
+			bucket = cfg.Storage.Bucket
+		}
+		sink = storage.NewObjectAuditSink(store, bucket, ex.Prefix)
+	case "http":
+		sink = storage.NewHTTPAuditSink(ex.URL, ex.Headers, nil)
+	default:
+		return nil, fmt.Errorf("unknown audit export sink %q", ex.Sink)
+	}
+	exporter, err := storage.NewAuditExporter(sink, storage.AuditExporterConfig{
+		Format:        storage.AuditFormat(ex.Format),
+		BatchSize:     ex.BatchSize,
+		FlushInterval: ex.GetFlushInterval(),
+	}, log.Named("audit-export"))
+	if err != nil {
+		_ = sink.Close()
+		return nil, err
+	}
+	return exporter, nil
+}
+
+// provideWebhookService builds the webhook service, starts its delivery
+// worker and emits upload and transcode events. Live and NFT gate events
+// are wired where those services are built.
+func provideWebhookService(cfg *config.Config, log *zap.Logger, db storage.DB, uploadSvc *service.UploadService, transcodingSvc *service.TranscodingService) (*service.WebhookService, error) {
+	if db == nil {
+		if cfg.Webhooks.Enabled {
+			log.Warn("Database unavailable, webhooks disabled")
+		}
+		return nil, nil
+	}
+	svc, err := service.NewWebhookServiceFromConfig(db, cfg.Webhooks, log.Named("webhook"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure webhooks: %w", err)
+	}
+	if svc == nil {
+		return nil, nil
+	}
+	if uploadSvc != nil {
+		uploadSvc.RegisterPostUploadHook(func(_ context.Context, uploadID, contentID, ownerID string) {
+			svc.EmitAsync(service.WebhookEventUploadCompleted, map[string]interface{}{
+				"upload_id": uploadID, "content_id": contentID, "owner_id": ownerID,
+			})
+		})
+	}
+	if transcodingSvc != nil {
+		transcodingSvc.RegisterPostTranscodeHook(func(_ context.Context, contentID, profile, outputURL string) {
+			svc.EmitAsync(service.WebhookEventTranscodeCompleted, map[string]interface{}{
+				"content_id": contentID, "profile": profile, "output_url": outputURL,
+			})
+		})
+		transcodingSvc.RegisterTranscodeFailedHook(func(_ context.Context, taskID, contentID, errorMsg string) {
+			svc.EmitAsync(service.WebhookEventTranscodeFailed, map[string]interface{}{
+				"task_id": taskID, "content_id": contentID, "error": errorMsg,
+			})
+		})
+	}
+	svc.Start()
+	log.Info("Webhook delivery enabled")
+	return svc, nil
+}
+
+// webhookNFTDenied emits nft.verification.failed for NFT gate denials.
+func webhookNFTDenied(svc *service.WebhookService) func(context.Context, middleware.NFTDenial) {
+	return func(_ context.Context, d middleware.NFTDenial) {
+		svc.EmitAsync(service.WebhookEventNFTVerificationFailed, map[string]interface{}{
+			"wallet_address": d.WalletAddress,
+			"content_id":     d.ContentID,
+			"contract":       d.Contract,
+			"token_id":       d.TokenID,
+			"chain_id":       d.ChainID,
+			"reason":         string(d.Reason),
+		})
+	}
+}
+
+// webhookStreamStarted emits stream.started when a live stream goes live.
+func webhookStreamStarted(svc *service.WebhookService) func(service.LiveStream) {
+	return func(st service.LiveStream) {
+		svc.EmitAsync(service.WebhookEventStreamStarted, map[string]interface{}{
+			"stream_id":      st.ID,
+			"wallet_address": st.WalletAddress,
+			"title":          st.Title,
+			"protocol":       st.Protocol,
+		})
+	}
+}
+
 func provideOTelTracing(cfg *config.Config, log *zap.Logger, res *AppResources) {
 	if cfg.Monitoring.JaegerEndpoint == "" {
 		return
 	}
-	shutdown, err := monitoring.InitOTelTracing(context.Background(), "streamgate", cfg.Monitoring.JaegerEndpoint, log)
+	shutdown, err := monitoring.InitOTelTracing(context.Background(), "streamgate", cfg.Monitoring.JaegerEndpoint, cfg.Monitoring.Tracing.Sampling(), log)
 	if err != nil {
 		log.Warn("OTel tracing init failed, continuing without tracing", zap.Error(err))
 		return
 	}
 	res.OTelShutdown = shutdown
+	res.OTelShutdownTimeout = cfg.Monitoring.GetShutdownTimeout()
 }
 
 func parseChallengeTTL(cfg *config.Config) time.Duration {
@@ -278,3 +684,35 @@ func parseChallengeTTL(cfg *config.Config) time.Duration {
 	}
 	return ttl
 }
+
+// provideLiveService starts RTMP and SRT ingest when live streaming is
+// enabled. An RTMP port that cannot be bound disables live streaming rather
+// than failing startup; an SRT port only disables SRT.
+func provideLiveService(cfg *config.Config, log *zap.Logger, res *AppResources) *service.LiveService {
+	if !cfg.Live.Enabled {
+		return nil
+	}
+	packager := service.NewLiveFFmpegPackager(service.LiveFFmpegConfig{
+		FFmpegPath:      cfg.Live.FFmpegPath,
+		WorkDir:         cfg.Live.WorkDir,
+		SegmentDuration: cfg.Live.SegmentDuration,
+		WindowSegments:  cfg.Streaming.LiveWindowSegments,
+	}, log.Named("live-packager"))
+	svc := service.NewLiveService(packager, log.Named("live"))
+	rtmp := service.NewRTMPServer(fmt.Sprintf(":%d", cfg.Live.RTMPPort), svc, log.Named("rtmp"))
+	if err := rtmp.Start(); err != nil {
+		log.Error("RTMP ingest unavailable, live streaming disabled", zap.Error(err))
+		return nil
+	}
+	res.RTMPServer = rtmp
+	if cfg.Live.SRTPort > 0 {
+		srt := service.NewSRTServer(fmt.Sprintf(":%d", cfg.Live.SRTPort), svc, log.Named("srt"))
+		if err := srt.Start(); err != nil {
+			log.Error("SRT ingest unavailable", zap.Error(err))
+		} else {
+			res.SRTServer = srt
+		}
+	}
+	res.LiveSvc = svc
+	return svc
+}
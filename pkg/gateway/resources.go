@@ -8,6 +8,7 @@ import (
 	"io"
 	"time"
 
+	"github.com/rtcdance/streamgate/pkg/core"
 	"github.com/rtcdance/streamgate/pkg/core/config"
 	"github.com/rtcdance/streamgate/pkg/middleware"
 	"github.com/rtcdance/streamgate/pkg/service"
@@ -21,6 +22,7 @@ import (
 // Callers should defer resources.Close() to ensure cleanup on shutdown.
 type AppResources struct {
 	DB              *sql.DB
+	ReplicaDBs      []*sql.DB
 	ChallengeStore  io.Closer
 	ObjStorage      io.Closer
 	TokenBlacklist  io.Closer
@@ -28,18 +30,39 @@ type AppResources struct {
 	AuthRateLimiter middleware.RateLimiter
 	SharedRedis     *redis.Client
 	OTelShutdown    func(ctx context.Context) error
-	AuthService     *service.AuthService
-	Web3Service     *service.Web3Service
-	NFTVerifier     middleware.NFTOwnershipChecker
-	StreamingSvc    *service.StreamingService
-	ContentService  *service.ContentService
-	SegmentStorage  service.SegmentStorage
-	UploadService   *service.UploadService
-	TranscodingSvc  *service.TranscodingService
-	NFTCache        *NFTAccessCache
-	StreamingCache  *StreamingCache
-	NATSQueue       io.Closer
-	MiddlewareSvc   *middleware.Service
+	// OTelShutdownTimeout bounds the trace exporter flush in Close;
+	// zero uses config.DefaultMonitoringShutdownTimeout.
+	OTelShutdownTimeout time.Duration
+	AuthService         *service.AuthService
+	Web3Service         *service.Web3Service
+	NFTVerifier         middleware.NFTOwnershipChecker
+	StreamingSvc        *service.StreamingService
+	ContentService      *service.ContentService
+	SegmentStorage      service.SegmentStorage
+	UploadService       *service.UploadService
+	TranscodingSvc      *service.TranscodingService
+	NFTCache            *NFTAccessCache
+	StreamingCache      *StreamingCache
+	NATSQueue           io.Closer
+	MiddlewareSvc       *middleware.Service
+	RTMPServer          io.Closer
+	SRTServer           io.Closer
+	LiveSvc             *service.LiveService
+	ArchiveSvc          *service.ArchiveService
+	Lifecycle           *service.LifecycleManager
+	SearchSvc           *service.SearchService
+	WebhookSvc          *service.WebhookService
+	AuditLogger         *storage.PostgresAuditLogger
+	AuditExporter       *storage.AuditExporter
+}
+
+// Drain lets in-flight transcodes finish while ctx allows and checkpoints
+// the rest. Call it before Close on shutdown.
+func (r *AppResources) Drain(ctx context.Context) error {
+	if r.TranscodingSvc == nil {
+		return nil
+	}
+	return r.TranscodingSvc.Drain(ctx)
 }
 
 // Close releases all held resources. Errors from individual closes are
@@ -55,9 +78,41 @@ func (r *AppResources) Close() error {
 	if r.TranscodingSvc != nil {
 		r.TranscodingSvc.StopWorker()
 	}
+	if r.RTMPServer != nil {
+		if err := r.RTMPServer.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close rtmp server: %w", err))
+		}
+	}
+	if r.SRTServer != nil {
+		if err := r.SRTServer.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close srt server: %w", err))
+		}
+	}
+	if r.LiveSvc != nil {
+		r.LiveSvc.Close()
+	}
 	if r.UploadService != nil {
 		r.UploadService.Close()
 	}
+	if r.ArchiveSvc != nil {
+		r.ArchiveSvc.Close()
+	}
+	if r.Lifecycle != nil {
+		r.Lifecycle.Close()
+	}
+	if r.SearchSvc != nil {
+		r.SearchSvc.Close()
+	}
+	if r.WebhookSvc != nil {
+		r.WebhookSvc.Close()
+	}
+	if r.AuditLogger != nil {
+		_ = r.AuditLogger.Close()
+	}
+	// After the logger, so the entries it last handed over are flushed.
+	if r.AuditExporter != nil {
+		_ = r.AuditExporter.Close()
+	}
 	if r.NFTCache != nil {
 		r.NFTCache.Stop()
 	}
@@ -69,6 +124,11 @@ func (r *AppResources) Close() error {
 			errs = append(errs, fmt.Errorf("close db: %w", err))
 		}
 	}
+	for _, d := range r.ReplicaDBs {
+		if err := d.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close read replica: %w", err))
+		}
+	}
 	if r.ChallengeStore != nil {
 		if err := r.ChallengeStore.Close(); err != nil {
 			errs = append(errs, fmt.Errorf("close challenge store: %w", err))
@@ -90,7 +150,11 @@ func (r *AppResources) Close() error {
 		}
 	}
 	if r.OTelShutdown != nil {
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		timeout := r.OTelShutdownTimeout
+		if timeout <= 0 {
+			timeout = config.DefaultMonitoringShutdownTimeout
+		}
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
 		if err := r.OTelShutdown(shutdownCtx); err != nil {
 			errs = append(errs, fmt.Errorf("shutdown otel: %w", err))
@@ -111,6 +175,8 @@ type RouterConfig struct {
 	NFTVerifier    middleware.NFTOwnershipChecker
 	ContentService *service.ContentService
 	UploadService  *service.UploadService
+	// Kernel, when set, is managed through the plugin admin routes.
+	Kernel *core.Microkernel
 }
 
 // RouterOption configures a RouterConfig.
@@ -151,6 +217,11 @@ func WithUploadService(svc *service.UploadService) RouterOption {
 	return func(c *RouterConfig) { c.UploadService = svc }
 }
 
+// WithKernel serves the plugin admin API of the kernel running the gateway.
+func WithKernel(kernel *core.Microkernel) RouterOption {
+	return func(c *RouterConfig) { c.Kernel = kernel }
+}
+
 // serviceInit groups the internal service dependencies created by SetupRouter
 // for passing to registerRoutes.
 type serviceInit struct {
@@ -164,12 +235,24 @@ type serviceInit struct {
 	GatingRuleResolver middleware.GatingRuleResolver
 	PlaybackStatsSvc   *service.PlaybackStatsService
 	CategorySvc        *service.CategoryService
+	TagSvc             *service.TagService
+	CollectionSvc      *service.CollectionService
 	DB                 storage.DB
 	ContentService     *service.ContentService
 	SegmentStorage     service.SegmentStorage
 	TranscodingSvc     *service.TranscodingService
 	UploadService      *service.UploadService
 	DemoNFTMinter      *service.DemoNFTMinter
+	AccessAnalytics    *service.AccessAnalytics
+	LiveSvc            *service.LiveService
+	ModerationSvc      *service.ModerationService
+	ArchiveSvc         *service.ArchiveService
+	Lifecycle          *service.LifecycleManager
+	SearchSvc          *service.SearchService
+	WebhookSvc         *service.WebhookService
+	AuditLogger        *storage.PostgresAuditLogger
+	Upstreams          *upstreamDispatcher
+	Kernel             *core.Microkernel
 }
 
 func newDemoNFTMinter(cfg *config.Config, log *zap.Logger) *service.DemoNFTMinter {
@@ -18,12 +18,14 @@ import (
 	"github.com/rtcdance/streamgate/pkg/web3"
 
 	"github.com/gin-gonic/gin"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/go-redis/redis/v8"
 	"go.uber.org/zap"
 )
 
 func registerRoutes(router *gin.Engine, cfg *config.Config, log *zap.Logger, svc *serviceInit, res *AppResources) {
-	registerInfrastructureRoutes(router, log, svc.DB, svc.SegmentStorage, res.MiddlewareSvc, cfg)
+	healthChecker := registerInfrastructureRoutes(router, log, svc.DB, svc.SegmentStorage, res.MiddlewareSvc, cfg)
+	registerDependencyChecks(healthChecker, cfg, res.SharedRedis, svc.Web3Service, svc.TranscodingSvc)
+	RegisterCapabilitiesRoute(router, cfg)
 
 	/* Global JWT middleware for all /api/v1/ routes.
 	   Public endpoints are excluded via SkipPaths so we don't need
@@ -38,7 +40,7 @@ func registerRoutes(router *gin.Engine, cfg *config.Config, log *zap.Logger, svc
 			APIPrefix + "/auth/refresh",
 			APIPrefix + "/web3/rpc-status",
 			APIPrefix + "/web3/supported-chains",
-			"/health", "/ready", "/metrics", "/docs",
+			"/health", "/health/live", "/health/ready", "/ready", "/metrics", "/docs", "/capabilities",
 		},
 	}
 	streamLim := newStreamLimiter(cfg.Streaming.MaxConcurrentStreams)
@@ -46,13 +48,28 @@ func registerRoutes(router *gin.Engine, cfg *config.Config, log *zap.Logger, svc
 	if streamCache == nil {
 		streamCache = NewStreamingCache()
 	}
+	// Recorded around the streaming routes; must be installed before they
+	// are registered.
+	if svc.AccessAnalytics != nil {
+		router.Use(accessAnalyticsMiddleware(svc.AccessAnalytics))
+	}
 	// Segment route must be registered before JWT middleware — HLS.js sends
 	// segment requests without an Authorization header, using playback_token
 	// query param for auth instead.
 	RegisterStreamingSegmentRoute(router, log, svc.AuthService, svc.SegmentStorage, streamLim, streamCache, cfg.Storage.Bucket)
+	RegisterDASHSegmentRoute(router, log, svc.AuthService, svc.SegmentStorage, streamLim, cfg.Storage.Bucket)
+	if svc.LiveSvc != nil {
+		RegisterLiveSegmentRoute(router, log, svc.AuthService, svc.LiveSvc, streamLim)
+	}
 
 	router.Use(middleware.JWTAuthMiddleware(jwtConfig, log))
 
+	// Configured microservices take over their prefixes from the
+	// in-process handlers registered below.
+	if svc.Upstreams != nil {
+		router.Use(svc.Upstreams.middleware())
+	}
+
 	authRL := middleware.NewRateLimiter(middleware.RateLimitConfig{
 		RequestsPerMinute: 10,
 		WindowSize:        time.Minute,
@@ -88,8 +105,16 @@ func buildCircuitBreakerConfig(cfg *config.Config) middleware.CircuitBreakerConf
 	return cbConfig
 }
 
-func registerInfrastructureRoutes(router *gin.Engine, log *zap.Logger, db storage.DB, objStorage service.SegmentStorage, mwSvc *middleware.Service, cfg *config.Config) {
+// rpcMaxStall is how long the chain head may stay put before the rpc
+// check reports the node as no longer syncing.
+const rpcMaxStall = 2 * time.Minute
+
+// registerInfrastructureRoutes registers the health, readiness, metrics and
+// docs routes and returns the health registry further checks are added to.
+func registerInfrastructureRoutes(router *gin.Engine, log *zap.Logger, db storage.DB, objStorage service.SegmentStorage, mwSvc *middleware.Service, cfg *config.Config) *health.HealthChecker {
 	healthChecker := health.NewHealthChecker(log)
+	healthChecker.SetMode(cfg.Mode)
+	checkOpts := health.ProbeOptions()
 
 	cbConfig := buildCircuitBreakerConfig(cfg)
 
@@ -108,17 +133,11 @@ func registerInfrastructureRoutes(router *gin.Engine, log *zap.Logger, db storag
 				})
 			}
 			return db.Ping(ctx)
-		})
+		}, checkOpts...)
 	}
 	if objStorage != nil {
-		healthChecker.RegisterCheck("storage", func(ctx context.Context) error {
-			checkCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-			defer cancel()
-			if _, err := objStorage.Exists(checkCtx, cfg.Storage.Bucket, "__health_check__"); err != nil {
-				return fmt.Errorf("storage check failed: %w", err)
-			}
-			return nil
-		})
+		healthChecker.RegisterCheck("storage",
+			health.ObjectStoreCheck(objStorage.Exists, cfg.Storage.Bucket, "__health_check__"), checkOpts...)
 	}
 
 	router.GET("/health", func(c *gin.Context) {
@@ -132,16 +151,23 @@ func registerInfrastructureRoutes(router *gin.Engine, log *zap.Logger, db storag
 		}
 		respond(c, status, resp)
 	})
-	router.GET("/metrics", func(c *gin.Context) {
-		promhttp.Handler().ServeHTTP(c.Writer, c.Request)
-	})
-	router.GET("/ready", func(c *gin.Context) {
+	router.GET("/metrics", gin.WrapH(monitoring.MetricsHandler()))
+	ready := func(c *gin.Context) {
+		if core.IsShuttingDown() {
+			respond(c, http.StatusServiceUnavailable, health.ReadinessResponse{Ready: false, Timestamp: time.Now()})
+			return
+		}
 		resp := healthChecker.Readiness(c.Request.Context())
 		status := http.StatusOK
 		if !resp.Ready {
 			status = http.StatusServiceUnavailable
 		}
 		respond(c, status, resp)
+	}
+	router.GET("/ready", ready)
+	router.GET("/health/ready", ready)
+	router.GET("/health/live", func(c *gin.Context) {
+		respondOK(c, healthChecker.Liveness(c.Request.Context()))
 	})
 	router.GET("/circuit-breakers", func(c *gin.Context) {
 		if mwSvc == nil {
@@ -169,6 +195,36 @@ func registerInfrastructureRoutes(router *gin.Engine, log *zap.Logger, db storag
 	})
 	router.StaticFile("/docs/openapi.yaml", filepath.Join(".", "docs", "api", "openapi.yaml"))
 	router.Static("/demo", "./h5-demo")
+	return healthChecker
+}
+
+// registerDependencyChecks adds the Redis, chain RPC and transcoding queue
+// checks for the dependencies that are configured. The RPC check is not
+// critical: playback of ungated content keeps working without the chain.
+func registerDependencyChecks(hc *health.HealthChecker, cfg *config.Config, redisClient *redis.Client, web3Svc *service.Web3Service, transcodingSvc *service.TranscodingService) {
+	checkOpts := health.ProbeOptions()
+	if redisClient != nil {
+		hc.RegisterCheck("redis", func(ctx context.Context) error {
+			return redisClient.Ping(ctx).Err()
+		}, checkOpts...)
+	}
+	if web3Svc != nil && cfg.Web3.EthereumRPC != "" {
+		hc.RegisterCheck("rpc", health.BlockNumberCheck(func(ctx context.Context) (uint64, error) {
+			header, err := web3Svc.HeaderByNumber(ctx, nil)
+			if err != nil {
+				return 0, err
+			}
+			if header == nil {
+				return 0, fmt.Errorf("no latest block header")
+			}
+			return header.Number, nil
+		}, rpcMaxStall), append(checkOpts, health.NonCritical())...)
+	}
+	if transcodingSvc != nil {
+		// Tasks past the queue size wait their turn, so a deep queue
+		// degrades rather than fails.
+		hc.RegisterCheck("transcoding_queue", health.QueueDepthCheck(transcodingSvc.QueueDepth, cfg.Transcoding.QueueSize, 0), checkOpts...)
+	}
 }
 
 func registerProtectedRoutes(router *gin.Engine, cfg *config.Config, log *zap.Logger, svc *serviceInit, streamLim *streamLimiter, streamCache *StreamingCache) {
@@ -179,7 +235,8 @@ func registerProtectedRoutes(router *gin.Engine, cfg *config.Config, log *zap.Lo
 	nftGroup.Use(cbSvc.CircuitBreakerMiddleware("nft-verify", middleware.CircuitBreakerConfig{
 		FailureThreshold: 5, SuccessThreshold: 3, Timeout: 30 * time.Second,
 	}))
-	RegisterNFTRoutes(nftGroup, log, svc.NFTVerifier, svc.NFTCacheBackend, cfg.Web3.ChainID, 60*time.Second)
+	nftCacheTTL := parseNFTCacheTTL(cfg.Web3.NFTCacheTTL, log)
+	RegisterNFTRoutes(nftGroup, log, svc.NFTVerifier, svc.NFTCacheBackend, cfg.Web3.ChainID, nftCacheTTL)
 	RegisterNFTDevMintRoute(router, svc.DemoNFTMinter, log)
 
 	RegisterUploadRoutes(router, log, svc.UploadService)
@@ -190,10 +247,16 @@ func registerProtectedRoutes(router *gin.Engine, cfg *config.Config, log *zap.Lo
 		Cache:          svc.NFTCacheBackend,
 		RuleResolver:   svc.GatingRuleResolver,
 		DefaultChainID: cfg.Web3.ChainID,
-		CacheTTL:       60 * time.Second,
+		CacheTTL:       nftCacheTTL,
 		MarketplaceURL: "https://opensea.io/assets/ethereum/{contract}/{token_id}",
 		BlockTag:       parseBlockTag(cfg.Web3.BlockTag),
 	}
+	if svc.WebhookSvc != nil {
+		nftGateConfig.OnDenied = webhookNFTDenied(svc.WebhookSvc)
+	}
+	if svc.AuditLogger != nil {
+		nftGateConfig.AuditLogger = svc.AuditLogger
+	}
 	nftGateConfig.Enabled.Store(cfg.Features.NFTGating)
 	streamingGroup := router.Group("/")
 	streamingGroup.Use(middleware.NFTGateMiddleware(&nftGateConfig, log))
@@ -205,6 +268,7 @@ func registerProtectedRoutes(router *gin.Engine, cfg *config.Config, log *zap.Lo
 		c.Next()
 	})
 	RegisterStreamingRoutes(streamingGroup, log, svc.AuthService, svc.StreamingSvc, svc.SegmentStorage, streamLim, streamCache, cfg.Storage.Bucket)
+	RegisterDASHRoutes(streamingGroup, log, svc.AuthService, svc.SegmentStorage, streamLim, streamCache, cfg.Storage.Bucket)
 
 	RegisterContentRoutes(router, log, svc.ContentService)
 	RegisterTranscodingRoutes(router, log, svc.TranscodingSvc, cfg.Debug)
@@ -220,6 +284,54 @@ func registerProtectedRoutes(router *gin.Engine, cfg *config.Config, log *zap.Lo
 	if svc.CategorySvc != nil {
 		RegisterCategoryRoutes(rootG, svc.CategorySvc)
 	}
+	if svc.TagSvc != nil {
+		RegisterTagRoutes(rootG, svc.TagSvc, svc.ContentService)
+	}
+	if svc.CollectionSvc != nil {
+		RegisterCollectionRoutes(rootG, svc.CollectionSvc)
+	}
+	if svc.LiveSvc != nil {
+		RegisterLiveRoutes(rootG, log, svc.AuthService, svc.LiveSvc, cfg.Web3.ChainID, cfg.Live.RTMPPort, cfg.Live.SRTPort)
+	}
+	if svc.AccessAnalytics != nil {
+		RegisterAnalyticsRoutes(rootG, svc.AccessAnalytics, cfg.Auth.AdminWallets)
+	}
+	if svc.ModerationSvc != nil {
+		RegisterModerationRoutes(rootG, svc.ModerationSvc, cfg.Auth.AdminWallets)
+	}
+	if svc.ArchiveSvc != nil {
+		RegisterArchiveRoutes(rootG, svc.ArchiveSvc, cfg.Auth.AdminWallets)
+	}
+	if svc.Lifecycle != nil {
+		RegisterLifecycleRoutes(rootG, svc.Lifecycle, cfg.Auth.AdminWallets)
+	}
+	if svc.SearchSvc != nil {
+		RegisterSearchRoutes(rootG, svc.SearchSvc, cfg.Auth.AdminWallets)
+	}
+	if svc.WebhookSvc != nil {
+		RegisterWebhookRoutes(rootG, svc.WebhookSvc, cfg.Auth.AdminWallets)
+	}
+	if svc.AuditLogger != nil {
+		RegisterAuditRoutes(rootG, svc.AuditLogger, cfg.Auth.AdminWallets)
+	}
+	if svc.Kernel != nil {
+		RegisterPluginAdminRoutes(rootG, svc.Kernel, cfg.Auth.AdminWallets)
+	}
+}
+
+// parseNFTCacheTTL parses web3.nft_cache_ttl, falling back to 60s when it is
+// empty or invalid.
+func parseNFTCacheTTL(s string, log *zap.Logger) time.Duration {
+	const defaultTTL = 60 * time.Second
+	if s == "" {
+		return defaultTTL
+	}
+	ttl, err := time.ParseDuration(s)
+	if err != nil || ttl <= 0 {
+		log.Warn("Invalid web3.nft_cache_ttl, using default", zap.String("value", s), zap.Duration("default", defaultTTL))
+		return defaultTTL
+	}
+	return ttl
 }
 
 func parseBlockTag(s string) web3.BlockTag {
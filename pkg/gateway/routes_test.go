@@ -4,15 +4,18 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"io"
 	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/rtcdance/streamgate/pkg/health"
 	"github.com/rtcdance/streamgate/pkg/middleware"
 	"github.com/rtcdance/streamgate/pkg/service"
 	"github.com/rtcdance/streamgate/pkg/storage"
@@ -94,6 +97,14 @@ func TestParseBlockTagFromRoutes(t *testing.T) {
 	assert.Equal(t, web3.BlockTagSafe, parseBlockTag("pending"))
 }
 
+func TestParseNFTCacheTTL(t *testing.T) {
+	log := zap.NewNop()
+	assert.Equal(t, 5*time.Minute, parseNFTCacheTTL("5m", log))
+	assert.Equal(t, 60*time.Second, parseNFTCacheTTL("", log))
+	assert.Equal(t, 60*time.Second, parseNFTCacheTTL("soon", log))
+	assert.Equal(t, 60*time.Second, parseNFTCacheTTL("-1s", log))
+}
+
 func TestRegisterInfrastructureRoutes_HealthEndpoint(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
@@ -185,6 +196,31 @@ func TestRegisterInfrastructureRoutes_ReadyEndpoint(t *testing.T) {
 	assert.Equal(t, true, resp["ready"])
 }
 
+func TestRegisterInfrastructureRoutes_HealthReadyDetails(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	cfg := config.DefaultConfig()
+
+	mockDB := &routesMockDB{}
+	hc := registerInfrastructureRoutes(router, zap.NewNop(), mockDB, nil, nil, cfg)
+	hc.RegisterCheck("rpc", func(context.Context) error { return errors.New("no head") }, health.NonCritical())
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/ready", http.NoBody))
+		require.Equal(t, http.StatusOK, w.Code, "degraded dependencies keep the gateway ready")
+
+		var resp health.ReadinessResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.True(t, resp.Ready)
+		assert.Equal(t, health.StatusHealthy, resp.Checks["database"].Status)
+		assert.True(t, resp.Checks["database"].Critical)
+		assert.Equal(t, health.StatusDegraded, resp.Checks["rpc"].Status)
+		assert.Equal(t, "no head", resp.Checks["rpc"].Message)
+	}
+	assert.Equal(t, int32(1), mockDB.pings.Load(), "the second probe is served from cache")
+}
+
 func TestRegisterInfrastructureRoutes_ReadyNotReady(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
@@ -895,6 +931,7 @@ func TestRegisterInfrastructureRoutes_WithCBService_RegistersBreakers(t *testing
 
 type routesMockDB struct {
 	pingErr error
+	pings   atomic.Int32
 }
 
 func (m *routesMockDB) Query(_ context.Context, _ string, _ ...interface{}) (storage.Rows, error) {
@@ -918,6 +955,7 @@ func (m *routesMockDB) InTransaction(_ context.Context, _ func(*sql.Tx) error) e
 }
 
 func (m *routesMockDB) Ping(_ context.Context) error {
+	m.pings.Add(1)
 	return m.pingErr
 }
 
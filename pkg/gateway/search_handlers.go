@@ -0,0 +1,67 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/rtcdance/streamgate/pkg/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterSearchRoutes registers metadata search and, for backends with
+// their own index, an index rebuild restricted to adminWallets.
+func RegisterSearchRoutes(router *gin.RouterGroup, svc *service.SearchService, adminWallets []string) {
+	router.GET(APIPrefix+"/metadata/search", searchMetadata(svc))
+	if svc.Indexed() {
+		admin := router.Group(APIPrefix+"/admin/search", requireAdminWallet(adminWallets))
+		admin.POST("/reindex", reindexSearch(svc))
+	}
+}
+
+// registerSearchHook indexes content once it is transcoded and ready to
+// be found.
+func registerSearchHook(transcodingSvc *service.TranscodingService, svc *service.SearchService) {
+	transcodingSvc.RegisterPostTranscodeHook(func(_ context.Context, contentID, _, _ string) {
+		svc.IndexAsync(contentID)
+	})
+}
+
+func searchMetadata(svc *service.SearchService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q, err := service.ParseSearchQuery(c.Request.URL.Query())
+		if err != nil {
+			abortWithSearchError(c, err)
+			return
+		}
+		res, err := svc.Search(c.Request.Context(), q)
+		if err != nil {
+			abortWithSearchError(c, err)
+			return
+		}
+		respondOK(c, res)
+	}
+}
+
+func reindexSearch(svc *service.SearchService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		n, err := svc.Reindex(c.Request.Context())
+		if err != nil {
+			abortWithSearchError(c, err)
+			return
+		}
+		respondOK(c, gin.H{"indexed": n})
+	}
+}
+
+func abortWithSearchError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidRequest):
+		abortWithErrorDetail(c, http.StatusBadRequest, ErrInvalidRequest, "invalid search query", err.Error())
+	case errors.Is(err, service.ErrAlreadyExists):
+		abortWithError(c, http.StatusConflict, ErrInvalidRequest, "reindex already in progress")
+	default:
+		abortWithErrorDetail(c, http.StatusInternalServerError, ErrInternalError, internalErrMsg(c, err), err.Error())
+	}
+}
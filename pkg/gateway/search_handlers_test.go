@@ -0,0 +1,92 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rtcdance/streamgate/pkg/service"
+	stg "github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSearchRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	empty := &categoryMockDB{queryFn: func(context.Context, string, ...interface{}) (stg.Rows, error) {
+		return &contentListRows{}, nil
+	}}
+	failing := &categoryMockDB{queryFn: func(context.Context, string, ...interface{}) (stg.Rows, error) {
+		return nil, errors.New("connection refused")
+	}}
+
+	tests := []struct {
+		name   string
+		db     stg.DB
+		method string
+		path   string
+		want   int
+	}{
+		{"search", empty, http.MethodGet, "/metadata/search?q=cats&tag=pets&min_duration=30", http.StatusOK},
+		{"filters only", empty, http.MethodGet, "/metadata/search?creator=0xabc", http.StatusOK},
+		{"bad limit", empty, http.MethodGet, "/metadata/search?limit=all", http.StatusBadRequest},
+		{"bad cursor", empty, http.MethodGet, "/metadata/search?cursor=nope", http.StatusBadRequest},
+		{"database error", failing, http.MethodGet, "/metadata/search?q=cats", http.StatusInternalServerError},
+		{"no index to rebuild", empty, http.MethodPost, "/admin/search/reindex", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := service.NewSearchService(tt.db, service.NewPostgresSearchBackend(tt.db), zap.NewNop())
+			r := gin.New()
+			RegisterSearchRoutes(r.Group("/"), svc, []string{"0xADMIN"})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(tt.method, APIPrefix+tt.path, nil))
+			require.Equal(t, tt.want, w.Code, w.Body.String())
+			if tt.want != http.StatusOK {
+				return
+			}
+			var res service.SearchResult
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+			assert.Empty(t, res.Hits)
+			assert.Empty(t, res.NextCursor)
+			require.NotNil(t, res.Facets)
+		})
+	}
+}
+
+func TestSearchRoutes_Reindex(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cluster := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer cluster.Close()
+	backend, err := service.NewElasticsearchBackend(service.ElasticsearchSearchConfig{URL: cluster.URL})
+	require.NoError(t, err)
+	db := &categoryMockDB{queryFn: func(context.Context, string, ...interface{}) (stg.Rows, error) {
+		return &contentListRows{}, nil
+	}}
+	svc := service.NewSearchService(db, backend, zap.NewNop())
+
+	for _, tt := range []struct {
+		wallet string
+		want   int
+	}{
+		{"0xother", http.StatusForbidden},
+		{"0xADMIN", http.StatusOK},
+	} {
+		r := gin.New()
+		r.Use(func(c *gin.Context) { c.Set("wallet_address", tt.wallet); c.Next() })
+		RegisterSearchRoutes(r.Group("/"), svc, []string{"0xADMIN"})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, APIPrefix+"/admin/search/reindex", nil))
+		require.Equal(t, tt.want, w.Code, w.Body.String())
+	}
+}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/rtcdance/streamgate/pkg/middleware"
 	"github.com/rtcdance/streamgate/pkg/monitoring"
+	"github.com/rtcdance/streamgate/pkg/plugins/transcoder"
 	"github.com/rtcdance/streamgate/pkg/service"
 
 	"github.com/gin-gonic/gin"
@@ -141,6 +142,7 @@ func (sc *StreamingCache) SetSegmentIndex(contentID string, qualities map[string
 
 func (sc *StreamingCache) Invalidate(contentID string) {
 	sc.manifests.Delete(contentID)
+	sc.manifests.Delete(dashManifestCacheKey(contentID))
 	sc.segmentIdx.Delete(contentID)
 }
 
@@ -196,7 +198,7 @@ func RegisterStreamingRoutes(router gin.IRouter, log *zap.Logger, authService *s
 				abortWithError(c, http.StatusUnauthorized, ErrUnauthorized, "invalid playback token")
 				return
 			}
-			_ = claims
+			c.Set(playbackWalletKey, claims.WalletAddress)
 
 			if limiter != nil && !limiter.tryAcquire() {
 				c.Header("Retry-After", "1")
@@ -218,9 +220,10 @@ func RegisterStreamingRoutes(router gin.IRouter, log *zap.Logger, authService *s
 				abortWithError(c, http.StatusNotFound, ErrContentNotFound, "quality not found")
 				return
 			}
-			manifest := service.BuildMediaPlaylist(contentID, quality, segs, playbackToken)
+			live := streamingSvc.IsLive(contentID)
+			manifest := routeSegments(c, streamingSvc, mediaPlaylist(streamingSvc, contentID, quality, segs, playbackToken, live))
 			c.Header("Content-Type", "application/vnd.apple.mpegurl")
-			c.Header("Cache-Control", "private, max-age=30")
+			c.Header("Cache-Control", manifestCacheControl(live))
 			c.String(http.StatusOK, manifest)
 			return
 		}
@@ -256,12 +259,16 @@ func RegisterStreamingRoutes(router gin.IRouter, log *zap.Logger, authService *s
 
 		if cached, ok := cache.GetManifest(contentID, wallet); ok {
 			monitoring.StreamingCacheHitsTotal.WithLabelValues("manifest").Inc()
-			rendered := strings.ReplaceAll(cached, "{{PLAYBACK_TOKEN}}", playbackToken)
+			rendered := routeSegments(c, streamingSvc, strings.ReplaceAll(cached, "{{PLAYBACK_TOKEN}}", playbackToken))
 			c.Header("Content-Type", "application/vnd.apple.mpegurl")
 			c.String(http.StatusOK, rendered)
 			return
 		}
 
+		// Only VOD manifests are cached, so the live lookup is needed on a
+		// miss alone.
+		live := streamingSvc.IsLive(contentID)
+
 		var qualitySegments map[string][]string
 		if cached, ok := cache.GetSegmentIndex(contentID); ok {
 			monitoring.StreamingCacheHitsTotal.WithLabelValues("segment_index").Inc()
@@ -288,6 +295,7 @@ func RegisterStreamingRoutes(router gin.IRouter, log *zap.Logger, authService *s
 						}
 					}
 				}
+				qs = latestSegmentsByQuality(qs)
 				if len(qs) > 0 {
 					cache.SetSegmentIndex(contentID, qs)
 				}
@@ -318,25 +326,27 @@ func RegisterStreamingRoutes(router gin.IRouter, log *zap.Logger, authService *s
 				abortWithError(c, http.StatusNotFound, ErrContentNotFound, "quality not found for this content")
 				return
 			}
-			manifest := service.BuildMediaPlaylist(contentID, quality, segs, "{{PLAYBACK_TOKEN}}")
-			rendered := strings.ReplaceAll(manifest, "{{PLAYBACK_TOKEN}}", playbackToken)
+			manifest := mediaPlaylist(streamingSvc, contentID, quality, segs, "{{PLAYBACK_TOKEN}}", live)
+			rendered := routeSegments(c, streamingSvc, strings.ReplaceAll(manifest, "{{PLAYBACK_TOKEN}}", playbackToken))
 			c.Header("Content-Type", "application/vnd.apple.mpegurl")
-			c.Header("Cache-Control", "private, max-age=30")
+			c.Header("Cache-Control", manifestCacheControl(live))
 			c.String(http.StatusOK, rendered)
 			return
 		}
 
-		manifest, err := streamingSvc.GenerateHLSPlaylist(contentID, qualitySegments, "{{PLAYBACK_TOKEN}}")
+		manifest, err := streamingSvc.GenerateHLSPlaylist(contentID, qualitySegments, "{{PLAYBACK_TOKEN}}", live)
 		if err != nil {
 			abortWithErrorDetail(c, http.StatusInternalServerError, ErrInternalError, internalErrMsg(c, err), err.Error())
 			return
 		}
 
-		cache.SetManifest(contentID, manifest, wallet)
+		if !live {
+			cache.SetManifest(contentID, manifest, wallet)
+		}
 
-		rendered := strings.ReplaceAll(manifest, "{{PLAYBACK_TOKEN}}", playbackToken)
+		rendered := routeSegments(c, streamingSvc, strings.ReplaceAll(manifest, "{{PLAYBACK_TOKEN}}", playbackToken))
 		c.Header("Content-Type", "application/vnd.apple.mpegurl")
-		c.Header("Cache-Control", "private, max-age=30") // per-user token in body; browser-only cache
+		c.Header("Cache-Control", manifestCacheControl(live))
 		c.String(http.StatusOK, rendered)
 	})
 	log.Info("Streaming routes registered")
@@ -368,6 +378,7 @@ func RegisterStreamingSegmentRoute(router gin.IRouter, log *zap.Logger, authServ
 			abortWithError(c, http.StatusUnauthorized, ErrUnauthorized, "invalid playback token")
 			return
 		}
+		c.Set(playbackWalletKey, claims.WalletAddress)
 		if claims.Contract != "" || claims.TokenID != "" {
 			// Playback token carries NFT contract/tokenID; verify it matches
 			// the NFT gate context to prevent token reuse across content.
@@ -442,7 +453,7 @@ func RegisterStreamingSegmentRoute(router gin.IRouter, log *zap.Logger, authServ
 
 			if best.rc == nil {
 				cancel()
-				monitoring.StreamingDownloadDuration.WithLabelValues("fail").Observe(time.Since(start).Seconds())
+				monitoring.ObserveContext(c.Request.Context(), monitoring.StreamingDownloadDuration.WithLabelValues("fail"), time.Since(start).Seconds())
 				middleware.GetLogger(c, log).Warn("Segment download failed",
 					zap.String("content_id", contentID),
 					zap.String("segment", segName))
@@ -459,7 +470,7 @@ func RegisterStreamingSegmentRoute(router gin.IRouter, log *zap.Logger, authServ
 				log.Warn("segment download interrupted", zap.String("content_id", c.Param("id")), zap.Error(err))
 			}
 			cancel()
-			monitoring.StreamingDownloadDuration.WithLabelValues("success").Observe(time.Since(start).Seconds())
+			monitoring.ObserveContext(c.Request.Context(), monitoring.StreamingDownloadDuration.WithLabelValues("success"), time.Since(start).Seconds())
 			quality := c.Query("quality")
 			if quality == "" {
 				quality = "default"
@@ -467,7 +478,6 @@ func RegisterStreamingSegmentRoute(router gin.IRouter, log *zap.Logger, authServ
 			monitoring.StreamingSegmentsTotal.WithLabelValues(quality).Inc()
 			return
 		}
-		_ = claims
 		abortWithError(c, http.StatusNotFound, ErrNotFound, "segment not found")
 	})
 }
@@ -489,6 +499,35 @@ func extractPlaybackToken(c *gin.Context) string {
 	return ""
 }
 
+// mediaPlaylist renders a media playlist, applying the streaming service's
+// manifest limits when one is configured: VOD playlists are capped, and
+// those of a live stream are a sliding window without an end.
+func mediaPlaylist(streamingSvc *service.StreamingService, contentID, quality string, segs []string, playbackToken string, live bool) string {
+	if streamingSvc == nil {
+		return service.BuildMediaPlaylist(contentID, quality, segs, playbackToken)
+	}
+	return streamingSvc.GenerateMediaPlaylist(contentID, quality, segs, playbackToken, live)
+}
+
+// manifestCacheControl is the Cache-Control of a rendered manifest. A live
+// playlist changes with every segment, so browsers must not keep it.
+func manifestCacheControl(live bool) string {
+	if live {
+		return "no-cache"
+	}
+	return "private, max-age=30" // per-user token in body; browser-only cache
+}
+
+// routeSegments points a rendered manifest's segment URLs at the egress
+// region for the requesting client. Manifests are cached before routing so
+// one cached copy serves every region.
+func routeSegments(c *gin.Context, streamingSvc *service.StreamingService, manifest string) string {
+	if streamingSvc == nil {
+		return manifest
+	}
+	return streamingSvc.RouteSegmentURLs(manifest, c.Request.Header)
+}
+
 func extractSegmentNumber(segName string) int {
 	base := segName
 	if idx := strings.LastIndex(segName, "/"); idx >= 0 {
@@ -507,6 +546,15 @@ func extractSegmentNumber(segName string) int {
 	return n
 }
 
+// latestSegmentsByQuality drops segments left behind by earlier transcode
+// runs so a manifest never mixes two runs' segments within one variant.
+func latestSegmentsByQuality(qs map[string][]string) map[string][]string {
+	for q, segs := range qs {
+		qs[q] = transcoder.FilterLatestSegments(segs)
+	}
+	return qs
+}
+
 func isValidContentID(id string) bool {
 	if id == "" || len(id) > 256 {
 		return false
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/rtcdance/streamgate/pkg/plugins/transcoder"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -270,3 +271,37 @@ func TestLRUCache_Overwrite(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, "value2", val)
 }
+
+func TestLatestSegmentsByQuality_ReTranscode(t *testing.T) {
+	oldV := transcoder.NewSegmentVersion(time.Unix(1700000000, 0))
+	newV := transcoder.NewSegmentVersion(time.Unix(1700003600, 0))
+	qs := map[string][]string{
+		"1280x720": {
+			transcoder.SegmentName("1280x720", oldV, 0),
+			transcoder.SegmentName("1280x720", newV, 0),
+		},
+		"640x360": {"640x360_000.ts"},
+	}
+
+	got := latestSegmentsByQuality(qs)
+
+	assert.Equal(t, []string{transcoder.SegmentName("1280x720", newV, 0)}, got["1280x720"])
+	assert.Equal(t, []string{"640x360_000.ts"}, got["640x360"])
+}
+
+func TestBuildSegmentCandidates_OldSegmentStillReferenceable(t *testing.T) {
+	oldV := transcoder.NewSegmentVersion(time.Unix(1700000000, 0))
+	newV := transcoder.NewSegmentVersion(time.Unix(1700003600, 0))
+	cache := NewStreamingCache()
+	cache.SetSegmentIndex("c1", map[string][]string{
+		"1280x720": {transcoder.SegmentName("1280x720", newV, 0)},
+	})
+
+	oldSeg := transcoder.SegmentName("1280x720", oldV, 0)
+	assert.True(t, validateSegmentName(oldSeg))
+	keys := make([]string, 0)
+	for _, c := range buildSegmentCandidates("c1", oldSeg, "1280x720", cache) {
+		keys = append(keys, c.key)
+	}
+	assert.Contains(t, keys, "streams/c1/1280x720/"+oldSeg)
+}
@@ -0,0 +1,136 @@
+package gateway
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/rtcdance/streamgate/pkg/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterTagRoutes registers tag browsing for everyone and tag editing
+// for the owners of the tagged content.
+func RegisterTagRoutes(router *gin.RouterGroup, tagSvc *service.TagService, contentSvc *service.ContentService) {
+	router.GET(APIPrefix+"/tags", listTags(tagSvc))
+	router.GET(APIPrefix+"/tags/:tag/content", listContentByTag(tagSvc))
+	router.GET(APIPrefix+"/content/:id/tags", getContentTags(tagSvc, contentSvc))
+	router.PUT(APIPrefix+"/content/:id/tags", setContentTags(tagSvc, contentSvc))
+	router.POST(APIPrefix+"/content/:id/tags", addContentTags(tagSvc, contentSvc))
+	router.DELETE(APIPrefix+"/content/:id/tags/:tag", removeContentTag(tagSvc, contentSvc))
+}
+
+type tagsRequest struct {
+	Tags []string `json:"tags" binding:"required"`
+}
+
+func listTags(svc *service.TagService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, _ := contentPagination(c)
+		tags, err := svc.ListTags(c.Request.Context(), c.Query("prefix"), limit)
+		if err != nil {
+			abortWithTagError(c, err)
+			return
+		}
+		respondOK(c, gin.H{"tags": tags})
+	}
+}
+
+func listContentByTag(svc *service.TagService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, offset := contentPagination(c)
+		items, err := svc.ListContentByTag(c.Request.Context(), c.Param("tag"), limit, offset)
+		if err != nil {
+			abortWithTagError(c, err)
+			return
+		}
+		respondOK(c, gin.H{"items": items, "limit": limit, "offset": offset})
+	}
+}
+
+// requireTaggableContent checks the caller owns the content named by :id.
+func requireTaggableContent(c *gin.Context, contentSvc *service.ContentService) bool {
+	if contentSvc == nil {
+		abortWithError(c, http.StatusServiceUnavailable, ErrContentUnavailable, "content service unavailable")
+		return false
+	}
+	_, ok := requireContentOwner(c, contentSvc)
+	return ok
+}
+
+func getContentTags(svc *service.TagService, contentSvc *service.ContentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireTaggableContent(c, contentSvc) {
+			return
+		}
+		tags, err := svc.GetTags(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			abortWithTagError(c, err)
+			return
+		}
+		respondOK(c, gin.H{"tags": tags})
+	}
+}
+
+func setContentTags(svc *service.TagService, contentSvc *service.ContentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req tagsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abortWithErrorDetail(c, http.StatusBadRequest, ErrInvalidRequest, "invalid request body", err.Error())
+			return
+		}
+		if !requireTaggableContent(c, contentSvc) {
+			return
+		}
+		tags, err := svc.SetTags(c.Request.Context(), c.Param("id"), req.Tags)
+		if err != nil {
+			abortWithTagError(c, err)
+			return
+		}
+		respondOK(c, gin.H{"tags": tags})
+	}
+}
+
+func addContentTags(svc *service.TagService, contentSvc *service.ContentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req tagsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abortWithErrorDetail(c, http.StatusBadRequest, ErrInvalidRequest, "invalid request body", err.Error())
+			return
+		}
+		if !requireTaggableContent(c, contentSvc) {
+			return
+		}
+		tags, err := svc.AddTags(c.Request.Context(), c.Param("id"), req.Tags)
+		if err != nil {
+			abortWithTagError(c, err)
+			return
+		}
+		respondOK(c, gin.H{"tags": tags})
+	}
+}
+
+func removeContentTag(svc *service.TagService, contentSvc *service.ContentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireTaggableContent(c, contentSvc) {
+			return
+		}
+		tags, err := svc.RemoveTag(c.Request.Context(), c.Param("id"), c.Param("tag"))
+		if err != nil {
+			abortWithTagError(c, err)
+			return
+		}
+		respondOK(c, gin.H{"tags": tags})
+	}
+}
+
+func abortWithTagError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidRequest):
+		abortWithErrorDetail(c, http.StatusBadRequest, ErrInvalidRequest, "invalid tags", err.Error())
+	case errors.Is(err, service.ErrNotFound):
+		abortWithError(c, http.StatusNotFound, ErrContentNotFound, "content not found")
+	default:
+		abortWithErrorDetail(c, http.StatusInternalServerError, ErrInternalError, internalErrMsg(c, err), err.Error())
+	}
+}
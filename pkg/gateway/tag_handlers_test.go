@@ -0,0 +1,116 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rtcdance/streamgate/pkg/service"
+	stg "github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// tagArrayRow answers a tags query with a Postgres array literal.
+type tagArrayRow string
+
+func (r tagArrayRow) Scan(dest ...interface{}) error {
+	return dest[0].(sql.Scanner).Scan([]byte(r))
+}
+
+func setupTagRouter(db *categoryMockDB, wallet string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	cache := newContentMockCache()
+	cache.data["content:c1"] = &service.Content{ID: "c1", OwnerID: "0xOwner"}
+	contentSvc := service.NewContentService(db, newContentMockObjStore(), cache)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if wallet != "" {
+			c.Set("wallet_address", wallet)
+		}
+		c.Next()
+	})
+	RegisterTagRoutes(r.Group("/"), service.NewTagService(db, zap.NewNop()), contentSvc)
+	return r
+}
+
+func TestTagRoutes(t *testing.T) {
+	db := &categoryMockDB{
+		queryFn: func(_ context.Context, _ string, _ ...interface{}) (stg.Rows, error) {
+			return &contentListRows{}, nil
+		},
+		queryRowFn: func(_ context.Context, _ string, _ ...interface{}) *stg.CancelRow {
+			return stg.NewTestCancelRow(tagArrayRow("{jazz}"))
+		},
+		execFn: func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
+			return &categoryMockResult{rowsAffected: 1}, nil
+		},
+	}
+
+	tests := []struct {
+		name     string
+		wallet   string
+		method   string
+		path     string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{"popular tags", "", http.MethodGet, "/api/v1/tags?prefix=ja", "", http.StatusOK, `"tags":[]`},
+		{"content by tag", "", http.MethodGet, "/api/v1/tags/jazz/content", "", http.StatusOK, `"items":[]`},
+		{"owner reads tags", "0xowner", http.MethodGet, "/api/v1/content/c1/tags", "", http.StatusOK, `"tags":["jazz"]`},
+		{"owner adds tags", "0xOwner", http.MethodPost, "/api/v1/content/c1/tags", `{"tags":["Live Music"]}`, http.StatusOK, `"tags":["jazz","live music"]`},
+		{"owner replaces tags", "0xOwner", http.MethodPut, "/api/v1/content/c1/tags", `{"tags":[]}`, http.StatusOK, `"tags":[]`},
+		{"owner removes tag", "0xOwner", http.MethodDelete, "/api/v1/content/c1/tags/JAZZ", "", http.StatusOK, `"tags":[]`},
+		{"invalid tag", "0xOwner", http.MethodPost, "/api/v1/content/c1/tags", `{"tags":["a,b"]}`, http.StatusBadRequest, "invalid tags"},
+		{"missing body", "0xOwner", http.MethodPut, "/api/v1/content/c1/tags", `{}`, http.StatusBadRequest, "invalid request body"},
+		{"other wallet", "0xOther", http.MethodPost, "/api/v1/content/c1/tags", `{"tags":["x"]}`, http.StatusForbidden, "not authorized"},
+		{"anonymous", "", http.MethodGet, "/api/v1/content/c1/tags", "", http.StatusForbidden, "not authorized"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := setupTagRouter(db, tt.wallet)
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tt.wantCode, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), tt.wantBody)
+		})
+	}
+}
+
+func TestTagRoutes_Errors(t *testing.T) {
+	db := &categoryMockDB{
+		queryFn: func(_ context.Context, _ string, _ ...interface{}) (stg.Rows, error) {
+			return nil, errors.New("db down")
+		},
+		queryRowFn: func(_ context.Context, _ string, _ ...interface{}) *stg.CancelRow {
+			return stg.NewErrorCancelRow(sql.ErrNoRows)
+		},
+	}
+	r := setupTagRouter(db, "0xOwner")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/tags", http.NoBody))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	// The content was deleted after its cached copy was read.
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/content/c1/tags", http.NoBody))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	gin.SetMode(gin.TestMode)
+	noContent := gin.New()
+	RegisterTagRoutes(noContent.Group("/"), service.NewTagService(db, zap.NewNop()), nil)
+	w = httptest.NewRecorder()
+	noContent.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/content/c1/tags", http.NoBody))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
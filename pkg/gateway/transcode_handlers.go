@@ -2,6 +2,7 @@ package gateway
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -22,9 +23,11 @@ func RegisterTranscodingRoutes(router gin.IRouter, log *zap.Logger, svc *service
 	transcode := router.Group(APIPrefix + "/transcode")
 	transcode.POST("/submit", handleTranscodeSubmit(svc, log, allowLocal))
 	transcode.GET("/status/:id", handleTranscodeStatus(svc, log))
+	transcode.GET("/:id/status", handleTranscodeStatus(svc, log))
 	transcode.POST("/cancel/:id", handleTranscodeCancel(svc, log))
 	transcode.GET("/tasks", handleTranscodeTasks(svc, log))
 	transcode.GET("/profiles", handleTranscodeProfiles(svc, log))
+	transcode.GET("/budget", handleTranscodeBudget(svc, log))
 	log.Info("Transcoding routes registered")
 }
 
@@ -67,6 +70,10 @@ func handleTranscodeSubmit(svc *service.TranscodingService, log *zap.Logger, all
 		}
 		wallet := middleware.GetWalletAddress(c)
 		taskID, err := svc.Transcode(c.Request.Context(), req.ContentID, req.Profile, req.InputURL, req.Priority, wallet)
+		if errors.Is(err, service.ErrTranscodeBudgetExceeded) {
+			abortWithErrorDetail(c, http.StatusPaymentRequired, ErrBudgetExceeded, "monthly transcode budget exceeded", err.Error())
+			return
+		}
 		if err != nil {
 			abortWithErrorDetail(c, http.StatusInternalServerError, ErrInternalError, internalErrMsg(c, err), err.Error())
 			return
@@ -185,6 +192,28 @@ func handleTranscodeProfiles(svc *service.TranscodingService, log *zap.Logger) g
 	}
 }
 
+// handleTranscodeBudget reports the caller's transcode spend for the
+// current month.
+func handleTranscodeBudget(svc *service.TranscodingService, log *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if svc == nil {
+			abortWithError(c, http.StatusServiceUnavailable, ErrInternalError, "transcoding service unavailable")
+			return
+		}
+		wallet := middleware.GetWalletAddress(c)
+		if wallet == "" {
+			abortWithError(c, http.StatusUnauthorized, ErrUnauthorized, "authentication required")
+			return
+		}
+		usage, err := svc.BudgetUsage(c.Request.Context(), wallet)
+		if err != nil {
+			abortWithErrorDetail(c, http.StatusInternalServerError, ErrInternalError, internalErrMsg(c, err), err.Error())
+			return
+		}
+		respondOK(c, usage)
+	}
+}
+
 // --- Internal adapters ---
 
 type ffmpegRouterAdapter struct {
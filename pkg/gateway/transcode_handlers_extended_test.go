@@ -7,8 +7,10 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rtcdance/streamgate/pkg/service"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -99,6 +101,46 @@ func TestTranscodeHandlers_Submit_Success(t *testing.T) {
 	assert.Equal(t, "pending", resp["status"])
 }
 
+func TestTranscodeHandlers_Submit_BudgetExceeded(t *testing.T) {
+	svc := service.NewTranscodingService(nil, nil, service.WithBudget(service.TranscodeBudgetConfig{
+		MonthlyLimit:   150,
+		Weights:        service.TranscodeCostWeights{PerRungSecond: 1},
+		DurationLookup: func(context.Context, string) (time.Duration, error) { return 100 * time.Second, nil },
+	}))
+	r := setupTranscodeRouterWithService("0xOwner1234567890abcdef1234567890abcdef12", svc)
+	submit := func(contentID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/transcode/submit", bytes.NewBufferString(`{"content_id":"`+contentID+`","profile":"720p","input_url":"https://example.com/video.mp4"}`))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusAccepted, submit("c1").Code)
+	w := submit("c2")
+	assert.Equal(t, http.StatusPaymentRequired, w.Code)
+	var errResp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+	assert.Equal(t, ErrBudgetExceeded, errResp["code"])
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/transcode/budget", http.NoBody)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var usage service.TranscodeBudgetUsage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &usage))
+	assert.InDelta(t, 100.0, usage.Reserved, 1e-9)
+	assert.InDelta(t, 150.0, usage.Limit, 1e-9)
+}
+
+func TestTranscodeHandlers_Budget_NoWallet(t *testing.T) {
+	r := setupTranscodeRouterWithService("", newTestTranscodingService())
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/transcode/budget", http.NoBody)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
 func TestTranscodeHandlers_Submit_NilService(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
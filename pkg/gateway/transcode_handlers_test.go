@@ -47,6 +47,16 @@ func TestTranscodeHandlers_SubmitAndStatus(t *testing.T) {
 		assert.Equal(t, http.StatusOK, w.Code)
 	})
 
+	t.Run("owner can check status by task path", func(t *testing.T) {
+		require.NoError(t, svc.UpdateTaskProgress(context.Background(), taskID, 45))
+		r := setupTranscodeRouterWithService(ownerWallet, svc)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/transcode/"+taskID+"/status", http.NoBody)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"progress":45`)
+	})
+
 	t.Run("different wallet gets 403", func(t *testing.T) {
 		r2 := setupTranscodeRouterWithService("0xAttacker1234567890abcdef1234567890ab", svc)
 
@@ -1,6 +1,7 @@
 package gateway
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/rtcdance/streamgate/pkg/middleware"
 	"github.com/rtcdance/streamgate/pkg/service"
+	"github.com/rtcdance/streamgate/pkg/storage"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -136,6 +138,7 @@ func RegisterUploadRoutes(router gin.IRouter, log *zap.Logger, uploadSvc *servic
 
 	upload.POST("", handleWholeFileUpload(uploadSvc, log))
 	upload.GET("/list", handleUploadList(uploadSvc, log))
+	upload.POST("/negotiate", handleChunkSizeNegotiate(uploadSvc, log))
 	upload.POST("/init", handleChunkedUploadInit(uploadSvc, log))
 	upload.POST("/chunk", handleChunkUpload(uploadSvc, log))
 	upload.POST("/:id/complete", handleChunkedUploadComplete(uploadSvc, log))
@@ -144,8 +147,12 @@ func RegisterUploadRoutes(router gin.IRouter, log *zap.Logger, uploadSvc *servic
 	upload.GET("/:id/download-url", handleDownloadURL(uploadSvc, log))
 	upload.POST("/:id/batch-chunks", handleBatchChunkUpload(uploadSvc, log))
 	upload.GET("/:id/chunks", handleChunkStatuses(uploadSvc, log))
+	upload.GET("/:id/missing-chunks", handleMissingChunks(uploadSvc, log))
 	upload.POST("/presigned-init", handlePresignedUploadInit(uploadSvc, log))
 	upload.POST("/:id/complete-presigned", handleCompletePresignedUpload(uploadSvc, log))
+	upload.POST("/multipart-init", handleMultipartUploadInit(uploadSvc, log))
+	upload.POST("/:id/complete-multipart", handleCompleteMultipartUpload(uploadSvc, log))
+	upload.DELETE("/:id/multipart", handleAbortMultipartUpload(uploadSvc, log))
 	upload.DELETE("/:id", handleDeleteUpload(uploadSvc, log))
 
 	log.Info("Upload routes registered")
@@ -279,6 +286,59 @@ func handleChunkStatuses(uploadSvc *service.UploadService, log *zap.Logger) gin.
 	}
 }
 
+// handleMissingChunks lists the chunk indices a client still has to send,
+// so an interrupted upload can resume instead of starting over.
+func handleMissingChunks(uploadSvc *service.UploadService, log *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if uploadSvc == nil {
+			abortWithError(c, http.StatusServiceUnavailable, ErrUploadFailed, "upload service unavailable")
+			return
+		}
+
+		wallet := middleware.GetWalletAddress(c)
+		if wallet == "" {
+			abortWithError(c, http.StatusUnauthorized, ErrUnauthorized, "wallet authentication required")
+			return
+		}
+
+		uploadID := c.Param("id")
+		if _, ok := sanitizeObjectKey(uploadID); !ok {
+			abortWithError(c, http.StatusBadRequest, ErrInvalidRequest, "upload_id contains invalid characters")
+			return
+		}
+		var totalChunks int
+		if v := c.Query("total_chunks"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 || n > uploadSvc.MaxChunks() {
+				abortWithError(c, http.StatusBadRequest, ErrInvalidRequest, fmt.Sprintf("total_chunks must be between 1 and %d", uploadSvc.MaxChunks()))
+				return
+			}
+			totalChunks = n
+		}
+
+		info, err := uploadSvc.GetUploadStatus(c.Request.Context(), uploadID)
+		if err != nil {
+			abortWithErrorDetail(c, http.StatusNotFound, ErrNotFound, "upload not found", err.Error())
+			return
+		}
+		if !strings.EqualFold(info.OwnerID, wallet) {
+			abortWithError(c, http.StatusForbidden, ErrForbidden, "not authorized to view this upload")
+			return
+		}
+
+		status, err := uploadSvc.GetResumeStatus(c.Request.Context(), uploadID, totalChunks)
+		if errors.Is(err, service.ErrInvalidRequest) {
+			abortWithErrorDetail(c, http.StatusBadRequest, ErrInvalidRequest, "total_chunks is required for this upload", err.Error())
+			return
+		}
+		if err != nil {
+			abortWithErrorDetail(c, http.StatusInternalServerError, ErrUploadFailed, "failed to get chunk statuses", err.Error())
+			return
+		}
+		respondOK(c, status)
+	}
+}
+
 func handleDeleteUpload(uploadSvc *service.UploadService, log *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if uploadSvc == nil {
@@ -317,6 +377,47 @@ func handleDeleteUpload(uploadSvc *service.UploadService, log *zap.Logger) gin.H
 	}
 }
 
+// handleChunkSizeNegotiate returns the server-chosen chunk size for a declared
+// file size, optionally narrowed by client constraints. Clients call it before
+// /init and pass the returned chunk_size and total_chunks through.
+func handleChunkSizeNegotiate(uploadSvc *service.UploadService, log *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if uploadSvc == nil {
+			abortWithError(c, http.StatusServiceUnavailable, ErrUploadFailed, "upload service unavailable")
+			return
+		}
+
+		wallet := middleware.GetWalletAddress(c)
+		if wallet == "" {
+			abortWithError(c, http.StatusUnauthorized, ErrUnauthorized, "wallet authentication required")
+			return
+		}
+		var req struct {
+			TotalSize int64 `json:"total_size" binding:"required"`
+			service.ChunkConstraints
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abortWithError(c, http.StatusBadRequest, ErrInvalidRequest, "total_size is required")
+			return
+		}
+		if req.TotalSize <= 0 {
+			abortWithError(c, http.StatusBadRequest, ErrInvalidRequest, "total_size must be positive")
+			return
+		}
+		if req.TotalSize > maxUploadSize {
+			abortWithError(c, http.StatusRequestEntityTooLarge, ErrPayloadTooLarge, fmt.Sprintf("file size %d exceeds maximum allowed size %d", req.TotalSize, maxUploadSize))
+			return
+		}
+
+		plan, err := uploadSvc.NegotiateChunkSize(req.TotalSize, req.ChunkConstraints)
+		if err != nil {
+			abortWithErrorDetail(c, http.StatusUnprocessableEntity, ErrInvalidRequest, "unable to negotiate chunk size", err.Error())
+			return
+		}
+		respondOK(c, plan)
+	}
+}
+
 func handleChunkedUploadInit(uploadSvc *service.UploadService, log *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if uploadSvc == nil {
@@ -333,6 +434,8 @@ func handleChunkedUploadInit(uploadSvc *service.UploadService, log *zap.Logger)
 			Filename    string `json:"filename" binding:"required"`
 			TotalSize   int64  `json:"total_size" binding:"required"`
 			TotalChunks int    `json:"total_chunks" binding:"required"`
+			ChunkSize   int64  `json:"chunk_size"`
+			Checksum    string `json:"checksum"`
 		}
 		if err := c.ShouldBindJSON(&req); err != nil {
 			abortWithError(c, http.StatusBadRequest, ErrInvalidRequest, "filename, total_size, and total_chunks are required")
@@ -350,8 +453,8 @@ func handleChunkedUploadInit(uploadSvc *service.UploadService, log *zap.Logger)
 			abortWithError(c, http.StatusBadRequest, ErrInvalidRequest, "total_chunks must be positive")
 			return
 		}
-		if req.TotalChunks > 10000 {
-			abortWithError(c, http.StatusBadRequest, ErrInvalidRequest, "total_chunks exceeds maximum allowed")
+		if req.TotalChunks > uploadSvc.MaxChunks() {
+			abortWithError(c, http.StatusBadRequest, ErrInvalidRequest, fmt.Sprintf("total_chunks exceeds maximum allowed %d", uploadSvc.MaxChunks()))
 			return
 		}
 
@@ -361,7 +464,11 @@ func handleChunkedUploadInit(uploadSvc *service.UploadService, log *zap.Logger)
 			return
 		}
 
-		uploadID, err := uploadSvc.InitiateChunkedUpload(c.Request.Context(), req.Filename, req.TotalSize, req.TotalChunks, wallet)
+		uploadID, err := uploadSvc.InitiateChunkedUploadWithChecksum(c.Request.Context(), req.Filename, req.TotalSize, req.TotalChunks, req.ChunkSize, req.Checksum, wallet)
+		if errors.Is(err, service.ErrInvalidRequest) {
+			abortWithErrorDetail(c, http.StatusBadRequest, ErrInvalidRequest, "invalid chunk plan or checksum", err.Error())
+			return
+		}
 		if err != nil {
 			abortWithErrorDetail(c, http.StatusInternalServerError, ErrUploadFailed, "failed to initiate chunked upload", err.Error())
 			return
@@ -437,6 +544,10 @@ func handleChunkUpload(uploadSvc *service.UploadService, log *zap.Logger) gin.Ha
 		}
 
 		if err := uploadSvc.UploadChunkStream(c.Request.Context(), uploadID, chunkIndex, reader, file.Size, wallet); err != nil {
+			if errors.Is(err, service.ErrInvalidRequest) {
+				abortWithErrorDetail(c, http.StatusBadRequest, ErrInvalidRequest, "chunk does not match the upload's chunk layout", err.Error())
+				return
+			}
 			abortWithErrorDetail(c, http.StatusInternalServerError, ErrUploadFailed, "chunk upload failed", err.Error())
 			return
 		}
@@ -551,6 +662,10 @@ func handleChunkedUploadComplete(uploadSvc *service.UploadService, log *zap.Logg
 		}
 
 		if err := uploadSvc.CompleteChunkedUpload(c.Request.Context(), uploadID, req.TotalChunks); err != nil {
+			if errors.Is(err, service.ErrChecksumMismatch) {
+				abortWithErrorDetail(c, http.StatusUnprocessableEntity, ErrInvalidRequest, "assembled file does not match checksum; resend all chunks", err.Error())
+				return
+			}
 			abortWithErrorDetail(c, http.StatusInternalServerError, ErrUploadFailed, "failed to complete chunked upload", err.Error())
 			return
 		}
@@ -645,6 +760,10 @@ func handleCompletePresignedUpload(uploadSvc *service.UploadService, log *zap.Lo
 		}
 		contentID, err := uploadSvc.CompleteUploadWithTx(c.Request.Context(), uploadID)
 		if err != nil {
+			if errors.Is(err, service.ErrUploadQuarantined) {
+				abortWithErrorDetail(c, http.StatusUnprocessableEntity, ErrUploadFailed, "upload was flagged by the malware scan and quarantined", err.Error())
+				return
+			}
 			abortWithErrorDetail(c, http.StatusInternalServerError, ErrUploadFailed, "failed to complete presigned upload", err.Error())
 			return
 		}
@@ -662,6 +781,161 @@ func handleCompletePresignedUpload(uploadSvc *service.UploadService, log *zap.Lo
 	}
 }
 
+// handleMultipartUploadInit starts a direct-to-storage multipart upload. The
+// client PUTs each part to its presigned URL and then calls
+// complete-multipart with the returned ETags. A 501 means the store cannot
+// do this and the client should use the chunked /init flow instead.
+func handleMultipartUploadInit(uploadSvc *service.UploadService, log *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if uploadSvc == nil {
+			abortWithError(c, http.StatusServiceUnavailable, ErrUploadFailed, "upload service unavailable")
+			return
+		}
+
+		wallet := middleware.GetWalletAddress(c)
+		if wallet == "" {
+			abortWithError(c, http.StatusUnauthorized, ErrUnauthorized, "wallet authentication required")
+			return
+		}
+
+		var req struct {
+			Filename    string `json:"filename" binding:"required"`
+			TotalSize   int64  `json:"total_size" binding:"required"`
+			ContentType string `json:"content_type"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abortWithError(c, http.StatusBadRequest, ErrInvalidRequest, "filename and total_size are required")
+			return
+		}
+		if req.TotalSize <= 0 {
+			abortWithError(c, http.StatusBadRequest, ErrInvalidRequest, "total_size must be positive")
+			return
+		}
+		if req.TotalSize > maxUploadSize {
+			abortWithError(c, http.StatusRequestEntityTooLarge, ErrPayloadTooLarge,
+				fmt.Sprintf("file size %d exceeds maximum allowed size %d", req.TotalSize, maxUploadSize))
+			return
+		}
+
+		ext := filepath.Ext(req.Filename)
+		if _, allowed := allowedVideoExtensions[ext]; !allowed && ext != "" {
+			abortWithError(c, http.StatusBadRequest, ErrInvalidRequest,
+				fmt.Sprintf("file extension %s not allowed; accepted: mp4, webm, avi, mkv, mov, mpeg", ext))
+			return
+		}
+
+		plan, err := uploadSvc.InitiateMultipartUpload(c.Request.Context(), req.Filename, req.TotalSize, req.ContentType, wallet)
+		switch {
+		case errors.Is(err, service.ErrMultipartNotConfigured):
+			abortWithError(c, http.StatusNotImplemented, ErrNotImplemented, "direct multipart upload is not available; use /init")
+			return
+		case errors.Is(err, service.ErrInvalidRequest):
+			abortWithErrorDetail(c, http.StatusBadRequest, ErrInvalidRequest, "invalid multipart upload request", err.Error())
+			return
+		case err != nil:
+			abortWithErrorDetail(c, http.StatusInternalServerError, ErrUploadFailed, "failed to initiate multipart upload", err.Error())
+			return
+		}
+
+		respondCreated(c, plan)
+	}
+}
+
+func handleCompleteMultipartUpload(uploadSvc *service.UploadService, log *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uploadID, ok := authorizeMultipartUpload(c, uploadSvc, "complete")
+		if !ok {
+			return
+		}
+
+		var req struct {
+			Parts []storage.CompletedPart `json:"parts" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abortWithError(c, http.StatusBadRequest, ErrInvalidRequest, "parts are required")
+			return
+		}
+
+		err := uploadSvc.CompleteMultipartUpload(c.Request.Context(), uploadID, req.Parts)
+		switch {
+		case errors.Is(err, service.ErrMultipartNotConfigured):
+			abortWithError(c, http.StatusNotImplemented, ErrNotImplemented, "direct multipart upload is not available")
+			return
+		case errors.Is(err, service.ErrInvalidRequest):
+			abortWithErrorDetail(c, http.StatusBadRequest, ErrInvalidRequest, "invalid parts", err.Error())
+			return
+		case err != nil:
+			abortWithErrorDetail(c, http.StatusInternalServerError, ErrUploadFailed, "failed to complete multipart upload", err.Error())
+			return
+		}
+
+		respondOK(c, gin.H{
+			"upload_id": uploadID,
+			"status":    "completed",
+		})
+	}
+}
+
+func handleAbortMultipartUpload(uploadSvc *service.UploadService, log *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uploadID, ok := authorizeMultipartUpload(c, uploadSvc, "abort")
+		if !ok {
+			return
+		}
+
+		err := uploadSvc.AbortMultipartUpload(c.Request.Context(), uploadID)
+		switch {
+		case errors.Is(err, service.ErrMultipartNotConfigured):
+			abortWithError(c, http.StatusNotImplemented, ErrNotImplemented, "direct multipart upload is not available")
+			return
+		case errors.Is(err, service.ErrInvalidRequest):
+			abortWithErrorDetail(c, http.StatusBadRequest, ErrInvalidRequest, "upload cannot be aborted", err.Error())
+			return
+		case err != nil:
+			abortWithErrorDetail(c, http.StatusInternalServerError, ErrUploadFailed, "failed to abort multipart upload", err.Error())
+			return
+		}
+
+		respondOK(c, gin.H{
+			"upload_id": uploadID,
+			"status":    "aborted",
+		})
+	}
+}
+
+// authorizeMultipartUpload checks that the caller owns the upload named in
+// the path and returns its ID. It aborts the request and returns false
+// otherwise.
+func authorizeMultipartUpload(c *gin.Context, uploadSvc *service.UploadService, action string) (string, bool) {
+	if uploadSvc == nil {
+		abortWithError(c, http.StatusServiceUnavailable, ErrUploadFailed, "upload service unavailable")
+		return "", false
+	}
+
+	wallet := middleware.GetWalletAddress(c)
+	if wallet == "" {
+		abortWithError(c, http.StatusUnauthorized, ErrUnauthorized, "wallet authentication required")
+		return "", false
+	}
+
+	uploadID := c.Param("id")
+	if _, ok := sanitizeObjectKey(uploadID); !ok {
+		abortWithError(c, http.StatusBadRequest, ErrInvalidRequest, "upload_id contains invalid characters")
+		return "", false
+	}
+
+	info, err := uploadSvc.GetUploadStatus(c.Request.Context(), uploadID)
+	if err != nil {
+		abortWithError(c, http.StatusNotFound, ErrNotFound, "upload not found")
+		return "", false
+	}
+	if !strings.EqualFold(info.OwnerID, wallet) {
+		abortWithError(c, http.StatusForbidden, ErrForbidden, "not authorized to "+action+" this upload")
+		return "", false
+	}
+	return uploadID, true
+}
+
 func handleCompleteUpload(uploadSvc *service.UploadService, log *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if uploadSvc == nil {
@@ -691,26 +965,28 @@ func handleCompleteUpload(uploadSvc *service.UploadService, log *zap.Logger) gin
 			return
 		}
 
-		contentID, err := uploadSvc.CompleteUploadWithTx(c.Request.Context(), uploadID)
+		contentID, jobID, err := uploadSvc.CompleteUploadWithJob(c.Request.Context(), uploadID)
 		if err != nil {
+			if errors.Is(err, service.ErrUploadQuarantined) {
+				abortWithErrorDetail(c, http.StatusUnprocessableEntity, ErrUploadFailed, "upload was flagged by the malware scan and quarantined", err.Error())
+				return
+			}
 			abortWithErrorDetail(c, http.StatusInternalServerError, ErrUploadFailed, "failed to complete upload", err.Error())
 			return
 		}
 
-		info, err = uploadSvc.GetUploadStatus(c.Request.Context(), uploadID)
-		if err != nil {
-			respondOK(c, gin.H{
-				"upload_id":  uploadID,
-				"content_id": contentID,
-				"status":     "processed",
-			})
-			return
-		}
-		respondOK(c, gin.H{
+		resp := gin.H{
 			"upload_id":  uploadID,
 			"content_id": contentID,
-			"status":     info.Status,
-		})
+			"status":     "processed",
+		}
+		if jobID != "" {
+			resp["transcoding_job_id"] = jobID
+		}
+		if info, err = uploadSvc.GetUploadStatus(c.Request.Context(), uploadID); err == nil {
+			resp["status"] = info.Status
+		}
+		respondOK(c, resp)
 	}
 }
 
@@ -742,7 +1018,7 @@ func handleUploadStatus(uploadSvc *service.UploadService, log *zap.Logger) gin.H
 			return
 		}
 		progress, _ := uploadSvc.GetUploadProgress(c.Request.Context(), uploadID)
-		respondOK(c, gin.H{
+		resp := gin.H{
 			"upload_id":    info.ID,
 			"filename":     info.Filename,
 			"size":         info.Size,
@@ -752,7 +1028,18 @@ func handleUploadStatus(uploadSvc *service.UploadService, log *zap.Logger) gin.H
 			"progress":     progress,
 			"owner_id":     info.OwnerID,
 			"created_at":   info.CreatedAt.Format(time.RFC3339),
-		})
+		}
+		scanStatus, signature, err := uploadSvc.GetScanStatus(c.Request.Context(), uploadID)
+		if err != nil {
+			log.Warn("Failed to get scan status", zap.String("upload_id", uploadID), zap.Error(err))
+		}
+		if scanStatus != "" {
+			resp["scan_status"] = scanStatus
+		}
+		if signature != "" {
+			resp["scan_signature"] = signature
+		}
+		respondOK(c, resp)
 	}
 }
 
@@ -312,6 +312,75 @@ func TestUploadHandlers_ChunkedInit_Success(t *testing.T) {
 	assert.Equal(t, float64(5), resp["total_chunks"])
 }
 
+func TestUploadHandlers_ChunkedInit_ChunkPlan(t *testing.T) {
+	db := &uploadMockDB{
+		execFn: func(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+			return &uploadMockResult{}, nil
+		},
+	}
+	svc := service.NewUploadService(db, newUploadMockObjStore(), "bucket")
+	svc.SetStorageQuota(0)
+	svc.SetChunkSizePolicy(service.ChunkSizePolicy{MaxChunks: 100})
+	r := setupUploadRouter(svc, "0xOwner")
+
+	post := func(body string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/upload/init", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	const size = 524288000
+	plan, err := svc.NegotiateChunkSize(size, service.ChunkConstraints{})
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusCreated, post(fmt.Sprintf(`{"filename":"test.mp4","total_size":%d,"total_chunks":%d,"chunk_size":%d}`, size, plan.TotalChunks, plan.ChunkSize)))
+	assert.Equal(t, http.StatusBadRequest, post(fmt.Sprintf(`{"filename":"test.mp4","total_size":%d,"total_chunks":%d,"chunk_size":%d}`, size, plan.TotalChunks+1, plan.ChunkSize)), "count does not match the chunk size")
+	assert.Equal(t, http.StatusBadRequest, post(`{"filename":"test.mp4","total_size":524288000,"total_chunks":101}`), "configured chunk limit")
+}
+
+func TestUploadHandlers_Negotiate(t *testing.T) {
+	svc := service.NewUploadService(&uploadMockDB{}, newUploadMockObjStore(), "bucket")
+	r := setupUploadRouter(svc, "0xOwner")
+
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/upload/negotiate", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("returns chunk plan", func(t *testing.T) {
+		w := post(`{"total_size":524288000}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		var plan service.ChunkPlan
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &plan))
+		assert.Equal(t, int64(8*1024*1024), plan.ChunkSize)
+		assert.Equal(t, 63, plan.TotalChunks)
+	})
+
+	t.Run("honours client max", func(t *testing.T) {
+		w := post(`{"total_size":524288000,"max_chunk_size":6291456}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		var plan service.ChunkPlan
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &plan))
+		assert.Equal(t, int64(6*1024*1024), plan.ChunkSize)
+	})
+
+	t.Run("missing total_size", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, post(`{}`).Code)
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		assert.Equal(t, http.StatusRequestEntityTooLarge, post(fmt.Sprintf(`{"total_size":%d}`, maxUploadSize+1)).Code)
+	})
+
+	t.Run("unsatisfiable constraints", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnprocessableEntity, post(`{"total_size":524288000,"max_chunk_size":1024}`).Code)
+	})
+}
+
 func TestUploadHandlers_UploadStatus_NotFound(t *testing.T) {
 	db := &uploadMockDB{
 		queryRowFn: func(ctx context.Context, query string, args ...interface{}) *stg.CancelRow {
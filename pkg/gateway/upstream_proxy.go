@@ -0,0 +1,320 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/rtcdance/streamgate/pkg/discovery"
+	"github.com/rtcdance/streamgate/pkg/middleware"
+	"github.com/rtcdance/streamgate/pkg/resilience"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultUpstreamTimeout = 30 * time.Second
+	upstreamRetryBackoff   = 100 * time.Millisecond
+	// maxReplayableBody caps how much of a request body is buffered so
+	// it can be resent on retry; larger bodies are sent once.
+	maxReplayableBody = 1 << 20
+)
+
+// hopHeaders are connection-scoped and never forwarded (RFC 9110 §7.6.1).
+var hopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// upstream is one standalone microservice that owns a set of path
+// prefixes. It has either a fixed target or a service name to resolve.
+type upstream struct {
+	name     string
+	target   *url.URL
+	service  string
+	prefixes []string
+	timeout  time.Duration
+	retries  int
+	breaker  *resilience.CircuitBreaker
+}
+
+// upstreamDispatcher forwards requests whose path matches a configured
+// prefix to the owning microservice. Requests are authenticated at the
+// gateway; the caller's wallet is passed on as X-Wallet-Address, which is
+// what the microservice handlers read.
+type upstreamDispatcher struct {
+	upstreams []*upstream
+	resolver  *discovery.Resolver
+	client    *http.Client
+	log       *zap.Logger
+}
+
+// newUpstreamDispatcher builds a dispatcher from cfg, or returns nil when
+// no upstreams are configured. resolver is required only by upstreams
+// configured with a service name.
+func newUpstreamDispatcher(cfg []config.UpstreamConfig, cbConfig resilience.CircuitBreakerConfig, resolver *discovery.Resolver, log *zap.Logger) (*upstreamDispatcher, error) {
+	if len(cfg) == 0 {
+		return nil, nil
+	}
+	d := &upstreamDispatcher{
+		resolver: resolver,
+		client: &http.Client{
+			// Redirects are the client's to follow, not the gateway's.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		log: log,
+	}
+	for _, uc := range cfg {
+		var target *url.URL
+		switch {
+		case uc.URL != "":
+			var err error
+			target, err = url.Parse(uc.URL)
+			if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+				return nil, fmt.Errorf("gateway upstream %q: invalid url %q", uc.Name, uc.URL)
+			}
+		case uc.Service != "":
+			if resolver == nil {
+				return nil, fmt.Errorf("gateway upstream %q: service discovery is not available", uc.Name)
+			}
+		default:
+			return nil, fmt.Errorf("gateway upstream %q: url or service is required", uc.Name)
+		}
+		if len(uc.Prefixes) == 0 {
+			return nil, fmt.Errorf("gateway upstream %q: no prefixes", uc.Name)
+		}
+		for _, p := range uc.Prefixes {
+			if !strings.HasPrefix(p, APIPrefix+"/") {
+				return nil, fmt.Errorf("gateway upstream %q: prefix %q is outside %s", uc.Name, p, APIPrefix)
+			}
+		}
+		timeout := defaultUpstreamTimeout
+		if uc.Timeout != "" {
+			var err error
+			if timeout, err = time.ParseDuration(uc.Timeout); err != nil || timeout <= 0 {
+				return nil, fmt.Errorf("gateway upstream %q: invalid timeout %q", uc.Name, uc.Timeout)
+			}
+		}
+		d.upstreams = append(d.upstreams, &upstream{
+			name:     uc.Name,
+			target:   target,
+			service:  uc.Service,
+			prefixes: uc.Prefixes,
+			timeout:  timeout,
+			retries:  max(uc.Retries, 0),
+			breaker:  resilience.NewCircuitBreaker("upstream-"+uc.Name, cbConfig, log),
+		})
+	}
+	return d, nil
+}
+
+// match returns the upstream with the longest prefix covering path.
+func (d *upstreamDispatcher) match(path string) *upstream {
+	var best *upstream
+	bestLen := 0
+	for _, u := range d.upstreams {
+		for _, p := range u.prefixes {
+			p = strings.TrimSuffix(p, "/")
+			if (path == p || strings.HasPrefix(path, p+"/")) && len(p) > bestLen {
+				best, bestLen = u, len(p)
+			}
+		}
+	}
+	return best
+}
+
+// middleware forwards matching requests and aborts the chain; anything
+// else falls through to the in-process handlers.
+func (d *upstreamDispatcher) middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		u := d.match(c.Request.URL.Path)
+		if u == nil {
+			c.Next()
+			return
+		}
+		d.forward(c, u)
+		c.Abort()
+	}
+}
+
+func (d *upstreamDispatcher) forward(c *gin.Context, u *upstream) {
+	if !u.breaker.Allow() {
+		abortWithError(c, http.StatusServiceUnavailable, ErrUpstreamUnavailable, u.name+" service unavailable")
+		return
+	}
+	target, err := d.resolve(c.Request.Context(), u)
+	if err != nil {
+		d.log.Warn("Upstream resolution failed",
+			zap.String("upstream", u.name),
+			zap.Error(err))
+		abortWithError(c, http.StatusServiceUnavailable, ErrUpstreamUnavailable, u.name+" service unavailable")
+		return
+	}
+
+	body, replayable, err := readReplayableBody(c.Request, u.retries > 0 && isIdempotent(c.Request.Method))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, ErrInvalidRequest, "failed to read request body")
+		return
+	}
+	attempts := 1
+	if replayable {
+		attempts += u.retries
+	}
+
+	var resp *http.Response
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			select {
+			case <-c.Request.Context().Done():
+				abortWithError(c, http.StatusGatewayTimeout, ErrUpstreamUnavailable, u.name+" service timed out")
+				return
+			case <-time.After(upstreamRetryBackoff << (i - 1)):
+			}
+		}
+		// Attempts are few; each timeout is released when forward returns,
+		// after the final response body has been copied.
+		ctx, cancel := context.WithTimeout(c.Request.Context(), u.timeout)
+		defer cancel()
+		if body != nil {
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		resp, err = d.client.Do(outboundRequest(ctx, c, target))
+		if err == nil && !isRetryableStatus(resp.StatusCode) {
+			break
+		}
+		if i < attempts-1 && resp != nil {
+			_ = resp.Body.Close()
+		}
+	}
+	// The breaker sees one outcome per request, after retries.
+	if err != nil || isRetryableStatus(resp.StatusCode) {
+		u.breaker.RecordFailure()
+	} else {
+		u.breaker.RecordSuccess()
+	}
+	if err != nil {
+		d.log.Warn("Upstream request failed",
+			zap.String("upstream", u.name),
+			zap.String("path", c.Request.URL.Path),
+			zap.Error(err))
+		if ctxErr := c.Request.Context().Err(); ctxErr != nil || isTimeout(err) {
+			abortWithError(c, http.StatusGatewayTimeout, ErrUpstreamUnavailable, u.name+" service timed out")
+			return
+		}
+		abortWithError(c, http.StatusBadGateway, ErrUpstreamUnavailable, u.name+" service unreachable")
+		return
+	}
+	defer resp.Body.Close()
+
+	// Upstream headers replace the gateway's, except CORS which the
+	// gateway owns for every route.
+	header := c.Writer.Header()
+	for k, vv := range resp.Header {
+		if strings.HasPrefix(k, "Access-Control-") {
+			continue
+		}
+		header[k] = vv
+	}
+	for _, h := range hopHeaders {
+		header.Del(h)
+	}
+	c.Status(resp.StatusCode)
+	if _, err := io.Copy(c.Writer, resp.Body); err != nil {
+		d.log.Debug("Upstream response copy interrupted",
+			zap.String("upstream", u.name),
+			zap.Error(err))
+	}
+}
+
+// resolve returns u's fixed target or, for a service upstream, one of its
+// currently discovered instances.
+func (d *upstreamDispatcher) resolve(ctx context.Context, u *upstream) (*url.URL, error) {
+	if u.target != nil {
+		return u.target, nil
+	}
+	hostPort, err := d.resolver.Resolve(ctx, u.service)
+	if err != nil {
+		return nil, err
+	}
+	return &url.URL{Scheme: "http", Host: hostPort}, nil
+}
+
+// outboundRequest rewrites the incoming request for target. The gateway's
+// own identity headers replace any the client sent.
+func outboundRequest(ctx context.Context, c *gin.Context, target *url.URL) *http.Request {
+	in := c.Request
+	out := in.Clone(ctx)
+	out.RequestURI = ""
+	out.URL.Scheme = target.Scheme
+	out.URL.Host = target.Host
+	out.URL.Path = strings.TrimSuffix(target.Path, "/") + in.URL.Path
+	out.URL.RawPath = ""
+	out.Host = target.Host
+	for _, h := range hopHeaders {
+		out.Header.Del(h)
+	}
+	out.Header.Del("X-Wallet-Address")
+	if wallet := middleware.GetWalletAddress(c); wallet != "" {
+		out.Header.Set("X-Wallet-Address", wallet)
+	}
+	if reqID, ok := c.Get("request_id"); ok {
+		if id, ok := reqID.(string); ok && id != "" {
+			out.Header.Set("X-Request-ID", id)
+		}
+	}
+	if host, _, err := net.SplitHostPort(in.RemoteAddr); err == nil {
+		if prior := in.Header.Get("X-Forwarded-For"); prior != "" {
+			host = prior + ", " + host
+		}
+		out.Header.Set("X-Forwarded-For", host)
+	}
+	out.Header.Set("X-Forwarded-Host", in.Host)
+	return out
+}
+
+// readReplayableBody buffers r's body when replay is wanted and it is
+// small enough, restoring r.Body either way.
+func readReplayableBody(r *http.Request, replay bool) ([]byte, bool, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, replay, nil
+	}
+	if !replay || r.ContentLength > maxReplayableBody {
+		return nil, false, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxReplayableBody+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if len(body) > maxReplayableBody {
+		r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+		return nil, false, nil
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, true, nil
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func isRetryableStatus(code int) bool {
+	return code == http.StatusBadGateway || code == http.StatusServiceUnavailable || code == http.StatusGatewayTimeout
+}
+
+func isTimeout(err error) bool {
+	var t interface{ Timeout() bool }
+	return errors.As(err, &t) && t.Timeout()
+}
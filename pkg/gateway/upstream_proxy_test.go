@@ -0,0 +1,211 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/rtcdance/streamgate/pkg/discovery"
+	"github.com/rtcdance/streamgate/pkg/resilience"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newUpstreamRouter(t *testing.T, upstreams []config.UpstreamConfig, cb resilience.CircuitBreakerConfig) *gin.Engine {
+	t.Helper()
+	d, err := newUpstreamDispatcher(upstreams, cb, nil, zap.NewNop())
+	require.NoError(t, err)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("wallet_address", "0xCaller")
+		c.Next()
+	})
+	r.Use(d.middleware())
+	r.GET(APIPrefix+"/content", func(c *gin.Context) {
+		c.String(http.StatusOK, "local")
+	})
+	return r
+}
+
+func TestUpstreamDispatcher_ForwardsMatchingPrefix(t *testing.T) {
+	var got *http.Request
+	var gotBody string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		w.Header().Set("X-Upstream", "metadata")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer backend.Close()
+
+	r := newUpstreamRouter(t, []config.UpstreamConfig{
+		{Name: "metadata", URL: backend.URL, Prefixes: []string{APIPrefix + "/metadata"}},
+	}, resilience.DefaultCircuitBreakerConfig())
+
+	req := httptest.NewRequest(http.MethodPost, APIPrefix+"/metadata/create?draft=1", strings.NewReader(`{"title":"x"}`))
+	req.Header.Set("X-Wallet-Address", "0xSpoofed")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, `{"ok":true}`, w.Body.String())
+	assert.Equal(t, "metadata", w.Header().Get("X-Upstream"))
+	require.NotNil(t, got)
+	assert.Equal(t, APIPrefix+"/metadata/create", got.URL.Path)
+	assert.Equal(t, "draft=1", got.URL.RawQuery)
+	assert.Equal(t, `{"title":"x"}`, gotBody)
+	assert.Equal(t, "0xCaller", got.Header.Get("X-Wallet-Address"), "the gateway's wallet replaces the client's")
+
+	// Paths outside every prefix, including look-alikes, stay in process.
+	for _, path := range []string{APIPrefix + "/content", APIPrefix + "/metadatax"} {
+		got = nil
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Nil(t, got, path)
+	}
+}
+
+func TestUpstreamDispatcher_RetriesIdempotentRequests(t *testing.T) {
+	var calls atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	r := newUpstreamRouter(t, []config.UpstreamConfig{
+		{Name: "cache", URL: backend.URL, Prefixes: []string{APIPrefix + "/cache"}, Retries: 2},
+	}, resilience.DefaultCircuitBreakerConfig())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, APIPrefix+"/cache/stats", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ok", w.Body.String())
+	assert.Equal(t, int32(2), calls.Load())
+
+	calls.Store(0)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, APIPrefix+"/cache/set", strings.NewReader("{}")))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code, "POST is not retried")
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestUpstreamDispatcher_CircuitBreakerOpens(t *testing.T) {
+	var calls atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer backend.Close()
+
+	cb := resilience.DefaultCircuitBreakerConfig()
+	cb.Timeout = time.Minute
+	r := newUpstreamRouter(t, []config.UpstreamConfig{
+		{Name: "auth", URL: backend.URL, Prefixes: []string{APIPrefix + "/auth/verify-nft"}},
+	}, cb)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, APIPrefix+"/auth/verify-nft", nil))
+	assert.Equal(t, http.StatusBadGateway, w.Code, "the upstream's own error is passed through")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, APIPrefix+"/auth/verify-nft", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), ErrUpstreamUnavailable)
+	assert.Equal(t, int32(1), calls.Load(), "an open breaker short-circuits")
+}
+
+func TestUpstreamDispatcher_Unreachable(t *testing.T) {
+	backend := httptest.NewServer(http.NotFoundHandler())
+	addr := backend.URL
+	backend.Close()
+
+	r := newUpstreamRouter(t, []config.UpstreamConfig{
+		{Name: "upload", URL: addr, Prefixes: []string{APIPrefix + "/upload"}, Timeout: "1s"},
+	}, resilience.DefaultCircuitBreakerConfig())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, APIPrefix+"/upload/list", nil))
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Contains(t, w.Body.String(), ErrUpstreamUnavailable)
+}
+
+func TestNewUpstreamDispatcher_Validation(t *testing.T) {
+	d, err := newUpstreamDispatcher(nil, resilience.DefaultCircuitBreakerConfig(), nil, zap.NewNop())
+	assert.NoError(t, err)
+	assert.Nil(t, d, "no upstreams keeps everything in process")
+
+	for name, uc := range map[string]config.UpstreamConfig{
+		"bad url":        {Name: "x", URL: "metadata:8080", Prefixes: []string{APIPrefix + "/metadata"}},
+		"no prefixes":    {Name: "x", URL: "http://metadata:8080"},
+		"outside api":    {Name: "x", URL: "http://metadata:8080", Prefixes: []string{"/metrics"}},
+		"bad timeout":    {Name: "x", URL: "http://metadata:8080", Prefixes: []string{APIPrefix + "/metadata"}, Timeout: "soon"},
+		"zero timeout":   {Name: "x", URL: "http://metadata:8080", Prefixes: []string{APIPrefix + "/metadata"}, Timeout: "0s"},
+		"unsupported ws": {Name: "x", URL: "ws://metadata:8080", Prefixes: []string{APIPrefix + "/metadata"}},
+		"no target":      {Name: "x", Prefixes: []string{APIPrefix + "/metadata"}},
+		"no discovery":   {Name: "x", Service: "metadata", Prefixes: []string{APIPrefix + "/metadata"}},
+	} {
+		_, err := newUpstreamDispatcher([]config.UpstreamConfig{uc}, resilience.DefaultCircuitBreakerConfig(), nil, zap.NewNop())
+		assert.Error(t, err, name)
+	}
+}
+
+type staticRegistry struct {
+	discovery.Registry
+	instances []*discovery.ServiceInfo
+}
+
+func (r *staticRegistry) Discover(context.Context, string) ([]*discovery.ServiceInfo, error) {
+	return r.instances, nil
+}
+
+func TestUpstreamDispatcher_ResolvesServiceByName(t *testing.T) {
+	var hits atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		_, _ = w.Write([]byte("discovered"))
+	}))
+	defer backend.Close()
+	host, portStr, err := net.SplitHostPort(strings.TrimPrefix(backend.URL, "http://"))
+	require.NoError(t, err)
+	port, err := strconv.Atoi(portStr)
+	require.NoError(t, err)
+
+	registry := &staticRegistry{}
+	resolver := discovery.NewResolver(registry, time.Nanosecond)
+	d, err := newUpstreamDispatcher([]config.UpstreamConfig{
+		{Name: "metadata", Service: "metadata", Prefixes: []string{APIPrefix + "/metadata"}},
+	}, resilience.DefaultCircuitBreakerConfig(), resolver, zap.NewNop())
+	require.NoError(t, err)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(d.middleware())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, APIPrefix+"/metadata/search", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code, "no instances registered yet")
+
+	registry.instances = []*discovery.ServiceInfo{{ID: "metadata-1", Name: "metadata", Address: host, Port: port}}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, APIPrefix+"/metadata/search", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "discovered", w.Body.String())
+	assert.Equal(t, int32(1), hits.Load())
+}
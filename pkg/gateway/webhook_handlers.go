@@ -0,0 +1,156 @@
+package gateway
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/rtcdance/streamgate/pkg/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterWebhookRoutes registers webhook endpoint management, the
+// delivery log and redelivery, restricted to adminWallets.
+func RegisterWebhookRoutes(router *gin.RouterGroup, svc *service.WebhookService, adminWallets []string) {
+	admin := router.Group(APIPrefix+"/admin/webhooks", requireAdminWallet(adminWallets))
+	admin.GET("/endpoints", listWebhookEndpoints(svc))
+	admin.POST("/endpoints", createWebhookEndpoint(svc))
+	admin.GET("/endpoints/:id", getWebhookEndpoint(svc))
+	admin.PUT("/endpoints/:id", updateWebhookEndpoint(svc))
+	admin.DELETE("/endpoints/:id", deleteWebhookEndpoint(svc))
+	admin.POST("/endpoints/:id/redeliver", redeliverDeadWebhooks(svc))
+	admin.GET("/deliveries", listWebhookDeliveries(svc))
+	admin.POST("/deliveries/:id/redeliver", redeliverWebhook(svc))
+}
+
+func listWebhookEndpoints(svc *service.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		eps, err := svc.ListEndpoints(c.Request.Context())
+		if err != nil {
+			abortWithWebhookError(c, err)
+			return
+		}
+		respondOK(c, gin.H{"endpoints": eps})
+	}
+}
+
+// createWebhookEndpoint registers an endpoint. The signing secret is
+// generated unless given and is only ever returned in this response.
+func createWebhookEndpoint(svc *service.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			URL         string   `json:"url" binding:"required"`
+			Secret      string   `json:"secret"`
+			Events      []string `json:"events"`
+			Description string   `json:"description"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abortWithErrorDetail(c, http.StatusBadRequest, ErrInvalidRequest, "invalid request body", err.Error())
+			return
+		}
+		ep, err := svc.CreateEndpoint(c.Request.Context(), req.URL, req.Secret, req.Events, req.Description)
+		if err != nil {
+			abortWithWebhookError(c, err)
+			return
+		}
+		respondCreated(c, ep)
+	}
+}
+
+func getWebhookEndpoint(svc *service.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ep, err := svc.GetEndpoint(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			abortWithWebhookError(c, err)
+			return
+		}
+		respondOK(c, ep)
+	}
+}
+
+func updateWebhookEndpoint(svc *service.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			URL          *string   `json:"url"`
+			Events       *[]string `json:"events"`
+			Description  *string   `json:"description"`
+			Active       *bool     `json:"active"`
+			RotateSecret bool      `json:"rotate_secret"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abortWithErrorDetail(c, http.StatusBadRequest, ErrInvalidRequest, "invalid request body", err.Error())
+			return
+		}
+		ep, err := svc.UpdateEndpoint(c.Request.Context(), c.Param("id"), service.WebhookEndpointUpdate{
+			URL:          req.URL,
+			Events:       req.Events,
+			Description:  req.Description,
+			Active:       req.Active,
+			RotateSecret: req.RotateSecret,
+		})
+		if err != nil {
+			abortWithWebhookError(c, err)
+			return
+		}
+		respondOK(c, ep)
+	}
+}
+
+func deleteWebhookEndpoint(svc *service.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := svc.DeleteEndpoint(c.Request.Context(), c.Param("id")); err != nil {
+			abortWithWebhookError(c, err)
+			return
+		}
+		respondNoContent(c)
+	}
+}
+
+// listWebhookDeliveries lists deliveries newest first, optionally only
+// those of ?endpoint_id= or in ?status= (pending, delivered or dead).
+func listWebhookDeliveries(svc *service.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, offset := contentPagination(c)
+		filter := service.WebhookDeliveryFilter{EndpointID: c.Query("endpoint_id"), Status: c.Query("status")}
+		deliveries, err := svc.ListDeliveries(c.Request.Context(), filter, limit, offset)
+		if err != nil {
+			abortWithWebhookError(c, err)
+			return
+		}
+		respondOK(c, gin.H{"deliveries": deliveries, "limit": limit, "offset": offset})
+	}
+}
+
+func redeliverWebhook(svc *service.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := svc.Redeliver(c.Request.Context(), c.Param("id")); err != nil {
+			abortWithWebhookError(c, err)
+			return
+		}
+		respondAccepted(c, gin.H{"id": c.Param("id"), "status": "pending"})
+	}
+}
+
+// redeliverDeadWebhooks requeues every dead-lettered delivery of an
+// endpoint.
+func redeliverDeadWebhooks(svc *service.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		n, err := svc.RedeliverDead(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			abortWithWebhookError(c, err)
+			return
+		}
+		respondAccepted(c, gin.H{"requeued": n})
+	}
+}
+
+func abortWithWebhookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		abortWithError(c, http.StatusNotFound, ErrNotFound, "webhook not found")
+	case errors.Is(err, service.ErrInvalidRequest):
+		abortWithErrorDetail(c, http.StatusBadRequest, ErrInvalidRequest, "invalid webhook request", err.Error())
+	default:
+		abortWithErrorDetail(c, http.StatusInternalServerError, ErrInternalError, internalErrMsg(c, err), err.Error())
+	}
+}
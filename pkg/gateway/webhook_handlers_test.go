@@ -0,0 +1,98 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/service"
+	stg "github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+const testWebhookID = "7f1d9a8e-2b7c-4c39-9d1e-2f3a4b5c6d7e"
+
+// webhookEndpointRow answers the endpoint lookup.
+type webhookEndpointRow struct{}
+
+func (webhookEndpointRow) Scan(dest ...interface{}) error {
+	*dest[0].(*string) = testWebhookID
+	*dest[1].(*string) = "https://example.com/hook"
+	if err := dest[2].(sql.Scanner).Scan([]byte("{upload.completed}")); err != nil {
+		return err
+	}
+	*dest[3].(*string) = ""
+	*dest[4].(*bool) = true
+	*dest[5].(*time.Time) = time.Now()
+	*dest[6].(*time.Time) = time.Now()
+	return nil
+}
+
+func setupWebhookRouter(wallet string, affected int64) *gin.Engine {
+	db := &categoryMockDB{
+		queryFn: func(_ context.Context, _ string, _ ...interface{}) (stg.Rows, error) {
+			return &contentListRows{}, nil
+		},
+		queryRowFn: func(_ context.Context, _ string, _ ...interface{}) *stg.CancelRow {
+			return stg.NewTestCancelRow(webhookEndpointRow{})
+		},
+		execFn: func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
+			return &categoryMockResult{rowsAffected: affected}, nil
+		},
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("wallet_address", wallet); c.Next() })
+	RegisterWebhookRoutes(r.Group("/"), service.NewWebhookService(db, service.WebhookConfig{}, zap.NewNop()), []string{"0xADMIN"})
+	return r
+}
+
+func TestWebhookRoutes(t *testing.T) {
+	const base = APIPrefix + "/admin/webhooks"
+	const delivery = "11111111-1111-4111-8111-111111111111"
+	tests := []struct {
+		name     string
+		wallet   string
+		affected int64
+		method   string
+		path     string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{"non-admin", "0xother", 1, http.MethodGet, base + "/endpoints", "", http.StatusForbidden, ""},
+		{"list endpoints", "0xADMIN", 1, http.MethodGet, base + "/endpoints", "", http.StatusOK, `"endpoints":[]`},
+		{"create", "0xADMIN", 1, http.MethodPost, base + "/endpoints", `{"url":"https://example.com/hook","events":["transcode.failed"]}`, http.StatusCreated, `"secret":"whsec_`},
+		{"create with unknown event", "0xADMIN", 1, http.MethodPost, base + "/endpoints", `{"url":"https://example.com/hook","events":["nope"]}`, http.StatusBadRequest, "invalid webhook request"},
+		{"create without url", "0xADMIN", 1, http.MethodPost, base + "/endpoints", `{}`, http.StatusBadRequest, "invalid request body"},
+		{"get", "0xADMIN", 1, http.MethodGet, base + "/endpoints/" + testWebhookID, "", http.StatusOK, `"events":["upload.completed"]`},
+		{"get unknown id", "0xADMIN", 1, http.MethodGet, base + "/endpoints/nope", "", http.StatusNotFound, "webhook not found"},
+		{"deactivate", "0xADMIN", 1, http.MethodPut, base + "/endpoints/" + testWebhookID, `{"active":false}`, http.StatusOK, `"active":false`},
+		{"rotate secret", "0xADMIN", 1, http.MethodPut, base + "/endpoints/" + testWebhookID, `{"rotate_secret":true}`, http.StatusOK, `"secret":"whsec_`},
+		{"delete", "0xADMIN", 1, http.MethodDelete, base + "/endpoints/" + testWebhookID, "", http.StatusNoContent, ""},
+		{"delete unknown", "0xADMIN", 0, http.MethodDelete, base + "/endpoints/" + testWebhookID, "", http.StatusNotFound, "webhook not found"},
+		{"list dead letters", "0xADMIN", 1, http.MethodGet, base + "/deliveries?status=dead&endpoint_id=" + testWebhookID, "", http.StatusOK, `"deliveries":[]`},
+		{"list unknown status", "0xADMIN", 1, http.MethodGet, base + "/deliveries?status=lost", "", http.StatusBadRequest, "invalid webhook request"},
+		{"redeliver", "0xADMIN", 1, http.MethodPost, base + "/deliveries/" + delivery + "/redeliver", "", http.StatusAccepted, `"status":"pending"`},
+		{"redeliver unknown", "0xADMIN", 0, http.MethodPost, base + "/deliveries/" + delivery + "/redeliver", "", http.StatusNotFound, "webhook not found"},
+		{"redeliver dead letters", "0xADMIN", 3, http.MethodPost, base + "/endpoints/" + testWebhookID + "/redeliver", "", http.StatusAccepted, `"requeued":3`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := setupWebhookRouter(tt.wallet, tt.affected)
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tt.wantCode, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), tt.wantBody)
+		})
+	}
+}
@@ -3,6 +3,7 @@ package health
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"runtime"
@@ -10,6 +11,8 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/rtcdance/streamgate/pkg/monitoring"
+
 	"go.uber.org/zap"
 )
 
@@ -22,9 +25,53 @@ const (
 	StatusDegraded  HealthStatus = "degraded"
 )
 
-// HealthCheck represents a health check function
+// HealthCheck represents a health check function. Returning an error
+// wrapped with Degraded reports the check degraded instead of unhealthy.
 type HealthCheck func(ctx context.Context) error
 
+type degradedError struct{ err error }
+
+func (e degradedError) Error() string { return e.err.Error() }
+func (e degradedError) Unwrap() error { return e.err }
+
+// Degraded marks err as a degraded condition: the dependency still works
+// but needs attention, like a queue filling up. Degraded checks do not
+// make the service unready.
+func Degraded(err error) error {
+	if err == nil {
+		return nil
+	}
+	return degradedError{err: err}
+}
+
+// CheckOption configures a registered health check.
+type CheckOption func(*registeredCheck)
+
+type registeredCheck struct {
+	check    HealthCheck
+	timeout  time.Duration
+	cacheTTL time.Duration
+	critical bool
+}
+
+// WithCheckTimeout bounds the check by timeout instead of the checker's
+// timeout set with SetTimeout.
+func WithCheckTimeout(timeout time.Duration) CheckOption {
+	return func(rc *registeredCheck) { rc.timeout = timeout }
+}
+
+// WithCacheTTL reuses the check's last result for ttl, so probes polling
+// /health/ready do not hit the dependency on every request.
+func WithCacheTTL(ttl time.Duration) CheckOption {
+	return func(rc *registeredCheck) { rc.cacheTTL = ttl }
+}
+
+// NonCritical reports failures of the check as degraded: the service keeps
+// serving without the dependency, with reduced functionality.
+func NonCritical() CheckOption {
+	return func(rc *registeredCheck) { rc.critical = false }
+}
+
 // HealthCheckResult represents the result of a health check
 type HealthCheckResult struct {
 	Name      string       `json:"name"`
@@ -32,6 +79,8 @@ type HealthCheckResult struct {
 	Message   string       `json:"message,omitempty"`
 	Duration  int64        `json:"duration_ms,omitempty"`
 	Timestamp time.Time    `json:"timestamp"`
+	Critical  bool         `json:"critical"`
+	Cached    bool         `json:"cached,omitempty"`
 	Details   interface{}  `json:"details,omitempty"`
 }
 
@@ -60,7 +109,7 @@ type ReadinessResponse struct {
 
 // HealthChecker manages health checks
 type HealthChecker struct {
-	checks  map[string]HealthCheck
+	checks  map[string]*registeredCheck
 	results map[string]HealthCheckResult
 	mu      sync.RWMutex
 	logger  *zap.Logger
@@ -80,7 +129,7 @@ func (hc *HealthChecker) SetMode(mode string) {
 // NewHealthChecker creates a new health checker
 func NewHealthChecker(logger *zap.Logger) *HealthChecker {
 	return &HealthChecker{
-		checks:  make(map[string]HealthCheck),
+		checks:  make(map[string]*registeredCheck),
 		results: make(map[string]HealthCheckResult),
 		logger:  logger,
 		timeout: 5 * time.Second,
@@ -89,12 +138,19 @@ func NewHealthChecker(logger *zap.Logger) *HealthChecker {
 	}
 }
 
-// RegisterCheck registers a health check
-func (hc *HealthChecker) RegisterCheck(name string, check HealthCheck) {
+// RegisterCheck registers a health check. Checks are critical unless
+// registered NonCritical, and replace an earlier check of the same name.
+func (hc *HealthChecker) RegisterCheck(name string, check HealthCheck, opts ...CheckOption) {
+	rc := &registeredCheck{check: check, critical: true}
+	for _, opt := range opts {
+		opt(rc)
+	}
+
 	hc.mu.Lock()
 	defer hc.mu.Unlock()
 
-	hc.checks[name] = check
+	hc.checks[name] = rc
+	delete(hc.results, name)
 	hc.logger.Info("Health check registered", zap.String("check", name))
 }
 
@@ -105,13 +161,21 @@ func (hc *HealthChecker) UnregisterCheck(name string) {
 
 	delete(hc.checks, name)
 	delete(hc.results, name)
+	for _, status := range []HealthStatus{StatusHealthy, StatusDegraded, StatusUnhealthy} {
+		monitoring.HealthCheckStatus.DeleteLabelValues(name, string(status))
+	}
+	monitoring.HealthCheckDurationSeconds.DeleteLabelValues(name)
 	hc.logger.Info("Health check unregistered", zap.String("check", name))
 }
 
-// Check executes a single health check
+// Check executes a single health check, or returns its last result while
+// that is within the check's cache TTL. A check that outlives its timeout
+// is reported unhealthy even if it ignores its context.
 func (hc *HealthChecker) Check(ctx context.Context, name string) HealthCheckResult {
 	hc.mu.RLock()
-	check, exists := hc.checks[name]
+	rc, exists := hc.checks[name]
+	last, hasLast := hc.results[name]
+	timeout := hc.timeout
 	hc.mu.RUnlock()
 
 	if !exists {
@@ -122,51 +186,99 @@ func (hc *HealthChecker) Check(ctx context.Context, name string) HealthCheckResu
 			Timestamp: time.Now(),
 		}
 	}
+	if rc.cacheTTL > 0 && hasLast && time.Since(last.Timestamp) < rc.cacheTTL {
+		last.Cached = true
+		return last
+	}
+	if rc.timeout > 0 {
+		timeout = rc.timeout
+	}
 
 	start := time.Now()
-	checkCtx, cancel := context.WithTimeout(ctx, hc.timeout)
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	err := check(checkCtx)
+	done := make(chan error, 1)
+	go func() { done <- rc.check(checkCtx) }()
+	var err error
+	select {
+	case err = <-done:
+	case <-checkCtx.Done():
+		err = fmt.Errorf("check did not finish: %w", checkCtx.Err())
+	}
 	duration := time.Since(start)
 
 	result := HealthCheckResult{
 		Name:      name,
 		Timestamp: time.Now(),
 		Duration:  duration.Milliseconds(),
+		Critical:  rc.critical,
 	}
 
-	if err != nil {
-		result.Status = StatusUnhealthy
-		result.Message = err.Error()
-	} else {
+	var degraded degradedError
+	switch {
+	case err == nil:
 		result.Status = StatusHealthy
 		result.Message = "OK"
+	case errors.As(err, &degraded) || !rc.critical:
+		result.Status = StatusDegraded
+		result.Message = err.Error()
+	default:
+		result.Status = StatusUnhealthy
+		result.Message = err.Error()
 	}
 
 	hc.mu.Lock()
-	hc.results[name] = result
+	// Skip results of a check replaced or removed while it ran.
+	if hc.checks[name] == rc {
+		hc.results[name] = result
+	}
 	hc.mu.Unlock()
 
+	recordCheckMetrics(result, duration)
 	return result
 }
 
-// CheckAll executes all registered health checks
+// recordCheckMetrics exports result as one-hot status gauges, so alerts can
+// select streamgate_health_check_status{status="unhealthy"} == 1.
+func recordCheckMetrics(result HealthCheckResult, duration time.Duration) {
+	for _, status := range []HealthStatus{StatusHealthy, StatusDegraded, StatusUnhealthy} {
+		v := 0.0
+		if result.Status == status {
+			v = 1
+		}
+		monitoring.HealthCheckStatus.WithLabelValues(result.Name, string(status)).Set(v)
+	}
+	monitoring.HealthCheckDurationSeconds.WithLabelValues(result.Name).Set(duration.Seconds())
+}
+
+// CheckAll executes all registered health checks concurrently, so the
+// response takes as long as the slowest check rather than their sum.
 func (hc *HealthChecker) CheckAll(ctx context.Context) HealthResponse {
 	hc.mu.RLock()
-	checks := make(map[string]HealthCheck, len(hc.checks))
-	for k, v := range hc.checks {
-		checks[k] = v
+	names := make([]string, 0, len(hc.checks))
+	for name := range hc.checks {
+		names = append(names, name)
 	}
 	hc.mu.RUnlock()
 
-	results := make(map[string]HealthCheckResult, len(checks))
-	overallStatus := StatusHealthy
-
-	for name := range checks {
-		result := hc.Check(ctx, name)
-		results[name] = result
+	results := make(map[string]HealthCheckResult, len(names))
+	var resultsMu sync.Mutex
+	var wg sync.WaitGroup
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			result := hc.Check(ctx, name)
+			resultsMu.Lock()
+			results[name] = result
+			resultsMu.Unlock()
+		}(name)
+	}
+	wg.Wait()
 
+	overallStatus := StatusHealthy
+	for _, result := range results {
 		if result.Status == StatusUnhealthy {
 			overallStatus = StatusUnhealthy
 		} else if result.Status == StatusDegraded && overallStatus == StatusHealthy {
@@ -257,6 +369,14 @@ func (hc *HealthChecker) HTTPHandler() http.HandlerFunc {
 	}
 }
 
+// ReadinessHandler serves the readiness probe on whatever path it is
+// mounted at, with every check's result in the body.
+func (hc *HealthChecker) ReadinessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hc.handleReadiness(w, r, r.Context())
+	}
+}
+
 // handleLiveness handles liveness probe
 func (hc *HealthChecker) handleLiveness(w http.ResponseWriter, r *http.Request, ctx context.Context) {
 	response := hc.Liveness(ctx)
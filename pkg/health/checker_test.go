@@ -11,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -430,3 +431,117 @@ func TestCommonHealthChecks_DependencyCheck_Error(t *testing.T) {
 	assert.Contains(t, err.Error(), "dependency 'payment' check failed")
 	assert.Contains(t, err.Error(), "timeout")
 }
+
+func TestCheck_Options(t *testing.T) {
+	tests := []struct {
+		name       string
+		check      HealthCheck
+		opts       []CheckOption
+		wantStatus HealthStatus
+		wantMsg    string
+	}{
+		{"critical failure", func(ctx context.Context) error { return errors.New("down") }, nil, StatusUnhealthy, "down"},
+		{"non-critical failure", func(ctx context.Context) error { return errors.New("down") }, []CheckOption{NonCritical()}, StatusDegraded, "down"},
+		{"degraded error", func(ctx context.Context) error { return Degraded(errors.New("backlog")) }, nil, StatusDegraded, "backlog"},
+		{"timeout ignored by check", func(ctx context.Context) error {
+			time.Sleep(200 * time.Millisecond)
+			return nil
+		}, []CheckOption{WithCheckTimeout(10 * time.Millisecond)}, StatusUnhealthy, "context deadline exceeded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc := NewHealthChecker(zap.NewNop())
+			hc.RegisterCheck("dep", tt.check, tt.opts...)
+
+			result := hc.Check(context.Background(), "dep")
+			assert.Equal(t, tt.wantStatus, result.Status)
+			assert.Contains(t, result.Message, tt.wantMsg)
+		})
+	}
+}
+
+func TestCheck_CacheTTL(t *testing.T) {
+	hc := NewHealthChecker(zap.NewNop())
+	calls := 0
+	hc.RegisterCheck("db", func(ctx context.Context) error {
+		calls++
+		return nil
+	}, WithCacheTTL(50*time.Millisecond))
+
+	first := hc.Check(context.Background(), "db")
+	second := hc.Check(context.Background(), "db")
+	assert.False(t, first.Cached)
+	assert.True(t, second.Cached)
+	assert.Equal(t, 1, calls)
+
+	time.Sleep(60 * time.Millisecond)
+	third := hc.Check(context.Background(), "db")
+	assert.False(t, third.Cached)
+	assert.Equal(t, 2, calls)
+}
+
+func TestCheckAll_RunsConcurrently(t *testing.T) {
+	hc := NewHealthChecker(zap.NewNop())
+	for _, name := range []string{"a", "b", "c"} {
+		hc.RegisterCheck(name, func(ctx context.Context) error {
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		})
+	}
+
+	start := time.Now()
+	resp := hc.CheckAll(context.Background())
+	assert.Len(t, resp.Checks, 3)
+	assert.Less(t, time.Since(start), 140*time.Millisecond)
+}
+
+// statusGauges returns the status gauges exported for check.
+func statusGauges(t *testing.T, check string) map[string]float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	gauges := make(map[string]float64)
+	for _, mf := range families {
+		if mf.GetName() != "streamgate_health_check_status" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			labels := make(map[string]string, len(m.GetLabel()))
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["check"] == check {
+				gauges[labels["status"]] = m.GetGauge().GetValue()
+			}
+		}
+	}
+	return gauges
+}
+
+func TestCheck_ExportsStatusGauges(t *testing.T) {
+	hc := NewHealthChecker(zap.NewNop())
+	hc.RegisterCheck("gauge_test", func(ctx context.Context) error { return Degraded(errors.New("slow")) })
+
+	hc.Check(context.Background(), "gauge_test")
+	assert.Equal(t, map[string]float64{"healthy": 0, "degraded": 1, "unhealthy": 0}, statusGauges(t, "gauge_test"))
+
+	hc.UnregisterCheck("gauge_test")
+	assert.Empty(t, statusGauges(t, "gauge_test"))
+}
+
+func TestReadinessHandler(t *testing.T) {
+	hc := NewHealthChecker(zap.NewNop())
+	hc.RegisterCheck("db", func(ctx context.Context) error { return nil })
+	hc.RegisterCheck("rpc", func(ctx context.Context) error { return errors.New("no head") }, NonCritical())
+
+	w := httptest.NewRecorder()
+	hc.ReadinessHandler()(w, httptest.NewRequest(http.MethodGet, "/ready", http.NoBody))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var readyResp ReadinessResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &readyResp))
+	assert.True(t, readyResp.Ready)
+	assert.Equal(t, StatusDegraded, readyResp.Checks["rpc"].Status)
+	assert.False(t, readyResp.Checks["rpc"].Critical)
+	assert.True(t, readyResp.Checks["db"].Critical)
+}
@@ -0,0 +1,83 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Defaults for dependency probes: a dependency is pinged at most once per
+// DefaultProbeCacheTTL however often readiness is polled, and a probe
+// slower than DefaultProbeTimeout counts as failed.
+const (
+	DefaultProbeTimeout  = 2 * time.Second
+	DefaultProbeCacheTTL = 5 * time.Second
+)
+
+// ProbeOptions returns the default options for a dependency probe.
+func ProbeOptions() []CheckOption {
+	return []CheckOption{WithCheckTimeout(DefaultProbeTimeout), WithCacheTTL(DefaultProbeCacheTTL)}
+}
+
+// Dependency probes for RegisterCheck. Database and Redis pings need no
+// wrapper: storage.DB.Ping already has the HealthCheck signature, and a
+// Redis client's ping is func(ctx) error { return c.Ping(ctx).Err() }.
+
+// ObjectStoreCheck HEADs key in bucket. Whether the object exists does not
+// matter; the request failing does.
+func ObjectStoreCheck(exists func(ctx context.Context, bucket, key string) (bool, error), bucket, key string) HealthCheck {
+	return func(ctx context.Context) error {
+		if _, err := exists(ctx, bucket, key); err != nil {
+			return fmt.Errorf("object store unreachable: %w", err)
+		}
+		return nil
+	}
+}
+
+// BlockNumberCheck asks the RPC endpoint for the latest block number. The
+// check degrades when the head has not advanced for maxStall, which means
+// the node stopped syncing; zero disables that.
+func BlockNumberCheck(blockNumber func(ctx context.Context) (uint64, error), maxStall time.Duration) HealthCheck {
+	var (
+		mu        sync.Mutex
+		lastBlock uint64
+		lastMoved time.Time
+	)
+	return func(ctx context.Context) error {
+		n, err := blockNumber(ctx)
+		if err != nil {
+			return fmt.Errorf("rpc blockNumber failed: %w", err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		now := time.Now()
+		if n != lastBlock || lastMoved.IsZero() {
+			lastBlock, lastMoved = n, now
+			return nil
+		}
+		if maxStall > 0 && now.Sub(lastMoved) > maxStall {
+			return Degraded(fmt.Errorf("chain head stuck at block %d for %s", n, now.Sub(lastMoved).Round(time.Second)))
+		}
+		return nil
+	}
+}
+
+// QueueDepthCheck reads a queue's depth. It degrades at degradedAt queued
+// items and fails at unhealthyAt; a threshold of zero is not checked.
+func QueueDepthCheck(depth func(ctx context.Context) (int, error), degradedAt, unhealthyAt int) HealthCheck {
+	return func(ctx context.Context) error {
+		n, err := depth(ctx)
+		if err != nil {
+			return fmt.Errorf("queue depth unavailable: %w", err)
+		}
+		if unhealthyAt > 0 && n >= unhealthyAt {
+			return fmt.Errorf("queue depth %d reached limit %d", n, unhealthyAt)
+		}
+		if degradedAt > 0 && n >= degradedAt {
+			return Degraded(fmt.Errorf("queue depth %d above %d", n, degradedAt))
+		}
+		return nil
+	}
+}
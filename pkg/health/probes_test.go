@@ -0,0 +1,84 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObjectStoreCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		exists  bool
+		err     error
+		wantErr bool
+	}{
+		{"object missing", false, nil, false},
+		{"object present", true, nil, false},
+		{"request failed", false, errors.New("connection reset"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotBucket, gotKey string
+			check := ObjectStoreCheck(func(_ context.Context, bucket, key string) (bool, error) {
+				gotBucket, gotKey = bucket, key
+				return tt.exists, tt.err
+			}, "media", "__health_check__")
+
+			err := check(context.Background())
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, "media", gotBucket)
+			assert.Equal(t, "__health_check__", gotKey)
+		})
+	}
+}
+
+func TestBlockNumberCheck(t *testing.T) {
+	block := uint64(100)
+	var rpcErr error
+	check := BlockNumberCheck(func(context.Context) (uint64, error) { return block, rpcErr }, 20*time.Millisecond)
+
+	assert.NoError(t, check(context.Background()))
+	assert.NoError(t, check(context.Background()), "head within maxStall")
+
+	time.Sleep(30 * time.Millisecond)
+	err := check(context.Background())
+	var degraded degradedError
+	assert.True(t, errors.As(err, &degraded), "stalled head degrades: %v", err)
+
+	block++
+	assert.NoError(t, check(context.Background()), "advancing head recovers")
+
+	rpcErr = errors.New("dial tcp: refused")
+	err = check(context.Background())
+	assert.ErrorIs(t, err, rpcErr)
+	assert.False(t, errors.As(err, &degraded))
+}
+
+func TestQueueDepthCheck(t *testing.T) {
+	tests := []struct {
+		name         string
+		depth        int
+		err          error
+		wantErr      bool
+		wantDegraded bool
+	}{
+		{"below thresholds", 5, nil, false, false},
+		{"degraded", 80, nil, true, true},
+		{"full", 100, nil, true, false},
+		{"depth unavailable", 0, errors.New("redis down"), true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := QueueDepthCheck(func(context.Context) (int, error) { return tt.depth, tt.err }, 80, 100)
+
+			err := check(context.Background())
+			assert.Equal(t, tt.wantErr, err != nil)
+			var degraded degradedError
+			assert.Equal(t, tt.wantDegraded, errors.As(err, &degraded))
+		})
+	}
+}
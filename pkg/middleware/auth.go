@@ -56,18 +56,18 @@ func JWTAuthMiddleware(config JWTAuthConfig, logger *zap.Logger) gin.HandlerFunc
 
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing authorization header", "code": "UNAUTHORIZED"})
+			abortDenied(c, http.StatusUnauthorized, DenialUnauthenticated, "UNAUTHORIZED", "missing authorization header", nil)
 			return
 		}
 
 		if !strings.HasPrefix(authHeader, "Bearer ") {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization format, expected Bearer token", "code": "UNAUTHORIZED"})
+			abortDenied(c, http.StatusUnauthorized, DenialUnauthenticated, "UNAUTHORIZED", "invalid authorization format, expected Bearer token", nil)
 			return
 		}
 
 		tokenStr := strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
 		if tokenStr == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "empty bearer token", "code": "UNAUTHORIZED"})
+			abortDenied(c, http.StatusUnauthorized, DenialUnauthenticated, "UNAUTHORIZED", "empty bearer token", nil)
 			return
 		}
 
@@ -108,7 +108,7 @@ func JWTAuthMiddleware(config JWTAuthConfig, logger *zap.Logger) gin.HandlerFunc
 
 		if err != nil {
 			logger.Debug("JWT parse failed", zap.Error(err))
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token", "code": "UNAUTHORIZED"})
+			abortDenied(c, http.StatusUnauthorized, DenialUnauthenticated, "UNAUTHORIZED", "invalid token", nil)
 			return
 		}
 
@@ -118,30 +118,30 @@ func JWTAuthMiddleware(config JWTAuthConfig, logger *zap.Logger) gin.HandlerFunc
 		// accepted before their stated issuance time.
 		now := time.Now()
 		if exp, ok := claims["exp"].(float64); ok && now.After(time.Unix(int64(exp), 0).Add(30*time.Second)) {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token expired", "code": "UNAUTHORIZED"})
+			abortDenied(c, http.StatusUnauthorized, DenialExpiredWindow, "UNAUTHORIZED", "token expired", nil)
 			return
 		}
 		if nbf, ok := claims["nbf"].(float64); ok && now.Before(time.Unix(int64(nbf), 0).Add(-30*time.Second)) {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token not yet valid", "code": "UNAUTHORIZED"})
+			abortDenied(c, http.StatusUnauthorized, DenialExpiredWindow, "UNAUTHORIZED", "token not yet valid", nil)
 			return
 		}
 		if iat, ok := claims["iat"].(float64); ok && now.Add(30*time.Second).Before(time.Unix(int64(iat), 0)) {
 			// iat must not be in the future beyond leeway window.
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token issued in the future", "code": "UNAUTHORIZED"})
+			abortDenied(c, http.StatusUnauthorized, DenialExpiredWindow, "UNAUTHORIZED", "token issued in the future", nil)
 			return
 		}
 
 		if config.Blacklist != nil {
 			jti, _ := claims["jti"].(string)
 			if jti != "" && config.Blacklist.IsTokenRevoked(c.Request.Context(), jti) {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token revoked", "code": "TOKEN_REVOKED"})
+				abortDenied(c, http.StatusUnauthorized, DenialDenylisted, "TOKEN_REVOKED", "token revoked", nil)
 				return
 			}
 		}
 
 		walletAddress, _ := claims["wallet_address"].(string)
 		if walletAddress == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token missing wallet address", "code": "UNAUTHORIZED"})
+			abortDenied(c, http.StatusUnauthorized, DenialUnauthenticated, "UNAUTHORIZED", "token missing wallet address", nil)
 			return
 		}
 
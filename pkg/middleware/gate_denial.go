@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DenialReason is the machine-readable cause attached to every auth and
+// NFT gate denial so clients can react without parsing error strings.
+type DenialReason string
+
+const (
+	DenialUnauthenticated DenialReason = "unauthenticated"
+	DenialInvalidRequest  DenialReason = "invalid_request"
+	DenialExpiredWindow   DenialReason = "expired_window"
+	DenialDenylisted      DenialReason = "denylisted"
+	DenialWrongChain      DenialReason = "wrong_chain"
+	DenialNoNFT           DenialReason = "no_nft"
+	DenialRPCUnavailable  DenialReason = "rpc_unavailable"
+)
+
+const denialReasonKey = "denial_reason"
+
+var gateDenialsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "streamgate_gate_denials_total",
+	Help: "Total auth and NFT gate denials by reason",
+}, []string{"reason"})
+
+func init() {
+	if err := prometheus.Register(gateDenialsTotal); err != nil {
+		if _, ok := err.(prometheus.AlreadyRegisteredError); !ok {
+			panic(err)
+		}
+	}
+}
+
+// abortDenied aborts the request with a structured denial body and records
+// the reason both on the context and in streamgate_gate_denials_total.
+func abortDenied(c *gin.Context, status int, reason DenialReason, code, message string, extra gin.H) {
+	gateDenialsTotal.WithLabelValues(string(reason)).Inc()
+	c.Set(denialReasonKey, reason)
+	body := gin.H{"error": message, "code": code, "reason": reason}
+	for k, v := range extra {
+		body[k] = v
+	}
+	c.AbortWithStatusJSON(status, body)
+}
+
+// GetDenialReason returns the reason the request was denied, or "" if it
+// was not denied by the auth or NFT gate middleware.
+func GetDenialReason(c *gin.Context) DenialReason {
+	v, _ := c.Get(denialReasonKey)
+	r, _ := v.(DenialReason)
+	return r
+}
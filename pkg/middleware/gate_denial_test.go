@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type revokeAllBlacklist struct{}
+
+func (revokeAllBlacklist) IsTokenRevoked(_ context.Context, _ string) bool { return true }
+
+func gateDenialCount(t *testing.T, reason DenialReason) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, mf := range families {
+		if mf.GetName() != "streamgate_gate_denials_total" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "reason" && l.GetValue() == string(reason) {
+					return m.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func TestGateDenial_ReasonCodes(t *testing.T) {
+	const secret = "test-secret-that-is-at-least-32-chars"
+	owns := func(n int64) *mockNFTOwnershipCheckerOld {
+		return &mockNFTOwnershipCheckerOld{
+			balanceFn: func(_ context.Context, _ int64, _, _ string) (*big.Int, error) {
+				return big.NewInt(n), nil
+			},
+		}
+	}
+	rpcDown := &mockNFTOwnershipCheckerOld{
+		balanceFn: func(_ context.Context, _ int64, _, _ string) (*big.Int, error) {
+			return nil, errors.New("dial tcp: connection refused")
+		},
+	}
+	revokedToken := func() string {
+		tok := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"wallet_address": "0xRevoked",
+			"jti":            "revoked-jti",
+			"exp":            time.Now().Add(time.Hour).Unix(),
+			"iat":            time.Now().Unix(),
+		})
+		s, _ := tok.SignedString([]byte(secret))
+		return s
+	}
+
+	tests := []struct {
+		name     string
+		verifier NFTOwnershipChecker
+		path     string
+		token    string
+		status   int
+		reason   DenialReason
+		code     string
+	}{
+		{
+			name:   "missing token",
+			path:   "/stream/123/manifest.m3u8?contract=" + testContractAddr,
+			status: http.StatusUnauthorized,
+			reason: DenialUnauthenticated,
+			code:   "UNAUTHORIZED",
+		},
+		{
+			name:   "expired token",
+			path:   "/stream/123/manifest.m3u8?contract=" + testContractAddr,
+			token:  generateTestJWT(secret, "0xOwner", time.Now().Add(-time.Hour)),
+			status: http.StatusUnauthorized,
+			reason: DenialExpiredWindow,
+			code:   "UNAUTHORIZED",
+		},
+		{
+			name:   "revoked token",
+			path:   "/stream/123/manifest.m3u8?contract=" + testContractAddr,
+			token:  revokedToken(),
+			status: http.StatusUnauthorized,
+			reason: DenialDenylisted,
+			code:   "TOKEN_REVOKED",
+		},
+		{
+			name:     "invalid contract",
+			verifier: owns(1),
+			path:     "/stream/123/manifest.m3u8?contract=0xABC",
+			token:    generateTestJWT(secret, "0xOwner", time.Now().Add(time.Hour)),
+			status:   http.StatusBadRequest,
+			reason:   DenialInvalidRequest,
+			code:     "INVALID_CONTRACT",
+		},
+		{
+			name:     "unsupported chain",
+			verifier: owns(1),
+			path:     "/stream/123/manifest.m3u8?chain_id=999999&contract=" + testContractAddr,
+			token:    generateTestJWT(secret, "0xOwner", time.Now().Add(time.Hour)),
+			status:   http.StatusForbidden,
+			reason:   DenialWrongChain,
+			code:     "UNSUPPORTED_CHAIN",
+		},
+		{
+			name:     "no nft",
+			verifier: owns(0),
+			path:     "/stream/123/manifest.m3u8?contract=" + testContractAddr,
+			token:    generateTestJWT(secret, "0xOwner", time.Now().Add(time.Hour)),
+			status:   http.StatusForbidden,
+			reason:   DenialNoNFT,
+			code:     "NFT_REQUIRED",
+		},
+		{
+			name:     "rpc unavailable",
+			verifier: rpcDown,
+			path:     "/stream/123/manifest.m3u8?contract=" + testContractAddr,
+			token:    generateTestJWT(secret, "0xOwner", time.Now().Add(time.Hour)),
+			status:   http.StatusInternalServerError,
+			reason:   DenialRPCUnavailable,
+			code:     "NFT_VERIFY_ERROR",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(JWTAuthMiddleware(JWTAuthConfig{Secret: secret, Blacklist: revokeAllBlacklist{}}, zap.NewNop()))
+			router.Use(NFTGateMiddleware(&NFTGateConfig{Verifier: tt.verifier, DefaultChainID: 1}, zap.NewNop()))
+			router.GET("/stream/:id/manifest.m3u8", func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			before := gateDenialCount(t, tt.reason)
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, http.NoBody)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			var resp map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, string(tt.reason), resp["reason"])
+			assert.Equal(t, tt.code, resp["code"])
+			assert.Equal(t, before+1, gateDenialCount(t, tt.reason))
+		})
+	}
+}
+
+func TestGetDenialReason(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	assert.Equal(t, DenialReason(""), GetDenialReason(c))
+
+	abortDenied(c, http.StatusForbidden, DenialNoNFT, "NFT_REQUIRED", "nft access denied", nil)
+	assert.Equal(t, DenialNoNFT, GetDenialReason(c))
+	assert.True(t, c.IsAborted())
+}
@@ -0,0 +1,232 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/monitoring"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCRequestIDHeader carries the request ID between services, as
+// X-Request-ID does over HTTP.
+const GRPCRequestIDHeader = "x-request-id"
+
+// grpcServerStream overrides the context of a server stream.
+type grpcServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *grpcServerStream) Context() context.Context { return s.ctx }
+
+// streamContext is the context of ss, or the background context when
+// there is no stream.
+func streamContext(ss grpc.ServerStream) context.Context {
+	if ss == nil {
+		return context.Background()
+	}
+	return ss.Context()
+}
+
+// incomingRequestID returns ctx carrying the caller's request ID, if any.
+func incomingRequestID(ctx context.Context) context.Context {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if vals := md.Get(GRPCRequestIDHeader); len(vals) > 0 && vals[0] != "" {
+			return ContextWithRequestID(ctx, vals[0])
+		}
+	}
+	return ctx
+}
+
+// UnaryServerRequestID makes the caller's x-request-id available through
+// RequestIDFromCtx.
+func UnaryServerRequestID() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(incomingRequestID(ctx), req)
+	}
+}
+
+// StreamServerRequestID is UnaryServerRequestID for streams.
+func StreamServerRequestID() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &grpcServerStream{ServerStream: ss, ctx: incomingRequestID(ss.Context())})
+	}
+}
+
+// UnaryServerRecovery turns a panicking handler into an Internal error.
+func UnaryServerRecovery(log *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Error("gRPC panic recovered",
+					append(grpcLogFields(ctx, info.FullMethod), zap.Any("panic", r))...)
+				resp, err = nil, status.Error(codes.Internal, "internal server error")
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerRecovery is UnaryServerRecovery for streams.
+func StreamServerRecovery(log *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Error("gRPC stream panic recovered",
+					append(grpcLogFields(streamContext(ss), info.FullMethod), zap.Any("panic", r))...)
+				err = status.Error(codes.Internal, "internal server error")
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+// UnaryServerLogging logs every call with its code, latency, request ID
+// and trace ID: failed calls at warn level, the rest at debug.
+func UnaryServerLogging(log *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logGRPCCall(ctx, log, "gRPC unary call", info.FullMethod, time.Since(start), err)
+		return resp, err
+	}
+}
+
+// StreamServerLogging is UnaryServerLogging for streams.
+func StreamServerLogging(log *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logGRPCCall(streamContext(ss), log, "gRPC stream call", info.FullMethod, time.Since(start), err)
+		return err
+	}
+}
+
+func logGRPCCall(ctx context.Context, log *zap.Logger, msg string, method string, latency time.Duration, err error) {
+	code := status.Code(err)
+	fields := append(grpcLogFields(ctx, method),
+		zap.String("code", code.String()),
+		zap.Duration("latency", latency))
+	if code == codes.OK {
+		log.Debug(msg, fields...)
+		return
+	}
+	log.Warn(msg, append(fields, zap.Error(err))...)
+}
+
+func grpcLogFields(ctx context.Context, method string) []zap.Field {
+	fields := []zap.Field{zap.String("method", method)}
+	if id := RequestIDFromCtx(ctx); id != "" {
+		fields = append(fields, zap.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		fields = append(fields, zap.String("trace_id", sc.TraceID().String()))
+	}
+	return fields
+}
+
+// UnaryServerMetrics records the count by method and code and the
+// latency by method of every call, with the trace ID as exemplar.
+func UnaryServerMetrics() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		observeGRPC(ctx, monitoring.GRPCServerHandledTotal, monitoring.GRPCServerHandlingSeconds, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerMetrics is UnaryServerMetrics for streams; the latency is
+// the stream's lifetime.
+func StreamServerMetrics() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		observeGRPC(streamContext(ss), monitoring.GRPCServerHandledTotal, monitoring.GRPCServerHandlingSeconds, info.FullMethod, start, err)
+		return err
+	}
+}
+
+func observeGRPC(ctx context.Context, total *prometheus.CounterVec, seconds *prometheus.HistogramVec, method string, start time.Time, err error) {
+	total.WithLabelValues(method, status.Code(err).String()).Inc()
+	monitoring.ObserveContext(ctx, seconds.WithLabelValues(method), time.Since(start).Seconds())
+}
+
+// UnaryServerDeadline gives calls that arrive without a deadline one of
+// timeout, so a handler cannot run, and call other services, forever.
+// Deadlines set by the caller are kept and propagate to outgoing calls
+// made with the handler's context.
+func UnaryServerDeadline(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if _, ok := ctx.Deadline(); ok || timeout <= 0 {
+			return handler(ctx, req)
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+// outgoingRequestID forwards the request ID in ctx to the callee.
+func outgoingRequestID(ctx context.Context) context.Context {
+	if id := RequestIDFromCtx(ctx); id != "" {
+		return metadata.AppendToOutgoingContext(ctx, GRPCRequestIDHeader, id)
+	}
+	return ctx
+}
+
+// UnaryClientInterceptor forwards the request ID, applies timeout to calls
+// without a deadline, fails calls whose deadline has passed without
+// sending them, and records client metrics and failed calls.
+func UnaryClientInterceptor(log *zap.Logger, timeout time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if err := ctx.Err(); err != nil {
+			return status.FromContextError(err).Err()
+		}
+		if _, ok := ctx.Deadline(); !ok && timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		start := time.Now()
+		err := invoker(outgoingRequestID(ctx), method, req, reply, cc, opts...)
+		observeGRPC(ctx, monitoring.GRPCClientHandledTotal, monitoring.GRPCClientHandlingSeconds, method, start, err)
+		if err != nil {
+			logGRPCCall(ctx, log, "gRPC client call", method, time.Since(start), err)
+		}
+		return err
+	}
+}
+
+// StreamClientInterceptor forwards the request ID and records client
+// metrics for opening the stream.
+func StreamClientInterceptor(log *zap.Logger) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		cs, err := streamer(outgoingRequestID(ctx), desc, cc, method, opts...)
+		observeGRPC(ctx, monitoring.GRPCClientHandledTotal, monitoring.GRPCClientHandlingSeconds, method, start, err)
+		if err != nil {
+			logGRPCCall(ctx, log, "gRPC client stream", method, time.Since(start), err)
+		}
+		return cs, err
+	}
+}
+
+// GRPCDialOptions returns the tracing and interceptor options every
+// client connection between services uses.
+func GRPCDialOptions(log *zap.Logger, timeout time.Duration) []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+		grpc.WithChainUnaryInterceptor(UnaryClientInterceptor(log, timeout)),
+		grpc.WithChainStreamInterceptor(StreamClientInterceptor(log)),
+	}
+}
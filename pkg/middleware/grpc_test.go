@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var unaryInfo = &grpc.UnaryServerInfo{FullMethod: "/content.ContentService/GetContent"}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context { return s.ctx }
+
+func TestUnaryServerRequestID(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(GRPCRequestIDHeader, "req-1"))
+	var got string
+	_, err := UnaryServerRequestID()(ctx, nil, unaryInfo, func(ctx context.Context, _ interface{}) (interface{}, error) {
+		got = RequestIDFromCtx(ctx)
+		return nil, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "req-1", got)
+
+	err = StreamServerRequestID()(nil, &fakeServerStream{ctx: ctx}, nil, func(_ interface{}, ss grpc.ServerStream) error {
+		got = RequestIDFromCtx(ss.Context())
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "req-1", got)
+}
+
+func TestServerRecovery(t *testing.T) {
+	core, logs := observer.New(zap.ErrorLevel)
+	ctx := ContextWithRequestID(context.Background(), "req-1")
+
+	resp, err := UnaryServerRecovery(zap.New(core))(ctx, nil, unaryInfo, func(context.Context, interface{}) (interface{}, error) {
+		panic("boom")
+	})
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	err = StreamServerRecovery(zap.New(core))(nil, &fakeServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: "/m"}, func(interface{}, grpc.ServerStream) error {
+		panic("boom")
+	})
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	require.Equal(t, 2, logs.Len())
+	assert.Equal(t, "req-1", logs.All()[0].ContextMap()["request_id"])
+}
+
+func TestUnaryServerLogging(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantLevel bool
+	}{
+		{"ok is not logged at warn", nil, false},
+		{"failure is logged at warn", status.Error(codes.NotFound, "no such content"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.WarnLevel)
+			ctx := ContextWithRequestID(context.Background(), "req-1")
+			_, err := UnaryServerLogging(zap.New(core))(ctx, nil, unaryInfo, func(context.Context, interface{}) (interface{}, error) {
+				return nil, tt.err
+			})
+			assert.Equal(t, tt.err, err)
+			if !tt.wantLevel {
+				assert.Zero(t, logs.Len())
+				return
+			}
+			require.Equal(t, 1, logs.Len())
+			fields := logs.All()[0].ContextMap()
+			assert.Equal(t, "NotFound", fields["code"])
+			assert.Equal(t, unaryInfo.FullMethod, fields["method"])
+			assert.Equal(t, "req-1", fields["request_id"])
+		})
+	}
+}
+
+func TestUnaryServerDeadline(t *testing.T) {
+	var deadline time.Time
+	var ok bool
+	handler := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		deadline, ok = ctx.Deadline()
+		return nil, nil
+	}
+
+	_, _ = UnaryServerDeadline(time.Minute)(context.Background(), nil, unaryInfo, handler)
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+
+	callerCtx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	_, _ = UnaryServerDeadline(time.Minute)(callerCtx, nil, unaryInfo, handler)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), deadline, time.Second, "the caller's deadline wins")
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	interceptor := UnaryClientInterceptor(zap.NewNop(), time.Minute)
+
+	var sent metadata.MD
+	var deadline time.Time
+	invoker := func(ctx context.Context, _ string, _, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		sent, _ = metadata.FromOutgoingContext(ctx)
+		deadline, _ = ctx.Deadline()
+		return nil
+	}
+	ctx := ContextWithRequestID(context.Background(), "req-1")
+	require.NoError(t, interceptor(ctx, "/m", nil, nil, nil, invoker))
+	assert.Equal(t, []string{"req-1"}, sent.Get(GRPCRequestIDHeader))
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+
+	expired, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	called := false
+	err := interceptor(expired, "/m", nil, nil, nil, func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+		called = true
+		return nil
+	})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.False(t, called, "an expired call is not sent")
+}
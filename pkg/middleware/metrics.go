@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/monitoring"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UnmatchedRoute labels requests no route matched, so probes of random
+// paths cannot grow the route label without bound.
+const UnmatchedRoute = "unmatched"
+
+// MetricsMiddleware records RED metrics for every request: the count and
+// duration by method, route template and status, and the requests in
+// flight. Routes are labelled by template, e.g. /api/v1/content/:id, never
+// by the raw URL. service labels the overall request duration. Durations
+// of sampled requests carry the trace ID as exemplar.
+func MetricsMiddleware(service string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		startedAt := time.Now()
+		monitoring.HTTPRequestsInFlight.Inc()
+		defer monitoring.HTTPRequestsInFlight.Dec()
+
+		c.Next()
+
+		elapsed := time.Since(startedAt)
+		method, route := metricMethod(c.Request.Method), metricRoute(c)
+		status := strconv.Itoa(c.Writer.Status())
+		monitoring.HTTPRequestsTotal.WithLabelValues(method, route, status).Inc()
+		monitoring.ObserveContext(c.Request.Context(),
+			monitoring.HTTPRequestDurationSeconds.WithLabelValues(method, route, status), elapsed.Seconds())
+		monitoring.ServiceRequestDuration.WithLabelValues(service).Observe(float64(elapsed.Milliseconds()))
+	}
+}
+
+func metricRoute(c *gin.Context) string {
+	if route := c.FullPath(); route != "" {
+		return route
+	}
+	return UnmatchedRoute
+}
+
+// metricMethod keeps the standard methods and folds the rest into one
+// label value.
+func metricMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions, http.MethodConnect, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
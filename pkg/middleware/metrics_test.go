@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// requestMetrics returns the request count and duration sample count
+// recorded for the label values.
+func requestMetrics(t *testing.T, method, route, status string) (count float64, samples uint64) {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	want := map[string]string{"method": method, "route": route, "status": status}
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			labels := make(map[string]string, len(m.GetLabel()))
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if !assert.ObjectsAreEqual(want, labels) {
+				continue
+			}
+			switch mf.GetName() {
+			case "streamgate_http_requests_total":
+				count = m.GetCounter().GetValue()
+			case "streamgate_http_request_duration_seconds":
+				samples = m.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+	return count, samples
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(MetricsMiddleware("test"))
+	router.GET("/api/v1/content/:id", func(c *gin.Context) {
+		if c.Param("id") == "missing" {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		route  string
+		status string
+	}{
+		{"route template", http.MethodGet, "/api/v1/content/42", "/api/v1/content/:id", "200"},
+		{"handler status", http.MethodGet, "/api/v1/content/missing", "/api/v1/content/:id", "404"},
+		{"unmatched path", http.MethodGet, "/wp-login.php", UnmatchedRoute, "404"},
+		{"nonstandard method", "PROPFIND", "/api/v1/content/42", UnmatchedRoute, "404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := metricMethod(tt.method)
+			count, samples := requestMetrics(t, method, tt.route, tt.status)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, http.NoBody))
+
+			newCount, newSamples := requestMetrics(t, method, tt.route, tt.status)
+			assert.Equal(t, count+1, newCount)
+			assert.Equal(t, samples+1, newSamples)
+		})
+	}
+	assert.Equal(t, "OTHER", metricMethod("PROPFIND"))
+}
@@ -69,6 +69,12 @@ type NFTOwnershipChecker interface {
 	GetNFTInfo(ctx context.Context, chainID int64, contractAddress, tokenID string) (*NFTMetadata, error)
 }
 
+// NFTStandardDetector is optionally implemented by an NFTOwnershipChecker
+// that can classify a contract as "ERC-721", "ERC-1155" or "unknown".
+type NFTStandardDetector interface {
+	DetectContractType(ctx context.Context, chainID int64, contractAddress string) string
+}
+
 type NFTMetadata struct {
 	Name            string
 	TokenURI        string
@@ -113,9 +119,23 @@ type NFTGateConfig struct {
 	MarketplaceURL     string
 	BlockTag           web3.BlockTag
 	AutoDetectStandard bool
-	Enabled            atomic.Bool // atomic for safe concurrent runtime toggling
-	reorgActive        atomic.Bool
-	reorgDetectedAt    atomic.Int64
+	// OnDenied, if set, is called when a wallet fails NFT verification:
+	// it lacks the NFT, the chain is unsupported or the check errored.
+	// It runs on the request path and must not block.
+	OnDenied        func(ctx context.Context, denial NFTDenial)
+	Enabled         atomic.Bool // atomic for safe concurrent runtime toggling
+	reorgActive     atomic.Bool
+	reorgDetectedAt atomic.Int64
+}
+
+// NFTDenial describes a request the NFT gate turned away.
+type NFTDenial struct {
+	WalletAddress string
+	ContentID     string
+	Contract      string
+	TokenID       string
+	ChainID       int64
+	Reason        DenialReason
 }
 
 type blockHashEntry struct {
@@ -201,7 +221,7 @@ func NFTGateMiddleware(config *NFTGateConfig, logger *zap.Logger) gin.HandlerFun
 
 		walletAddress := GetWalletAddress(c)
 		if walletAddress == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required", "code": "UNAUTHORIZED"})
+			abortDenied(c, http.StatusUnauthorized, DenialUnauthenticated, "UNAUTHORIZED", "authentication required", nil)
 			return
 		}
 
@@ -209,7 +229,9 @@ func NFTGateMiddleware(config *NFTGateConfig, logger *zap.Logger) gin.HandlerFun
 
 		resolvedRules, minBalance, gatingRequired, errResp := resolveNFTGateRules(c, config, logger, contentID, &contract, &tokenID, &chainID)
 		if errResp != nil {
-			c.AbortWithStatusJSON(http.StatusBadRequest, errResp)
+			code, _ := errResp["code"].(string)
+			msg, _ := errResp["error"].(string)
+			abortDenied(c, http.StatusBadRequest, DenialInvalidRequest, code, msg, gin.H{"hint": errResp["hint"]})
 			return
 		}
 
@@ -221,9 +243,14 @@ func NFTGateMiddleware(config *NFTGateConfig, logger *zap.Logger) gin.HandlerFun
 		}
 
 		if !common.IsHexAddress(contract) {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
-				"error": "invalid contract address format",
-				"code":  "INVALID_CONTRACT",
+			abortDenied(c, http.StatusBadRequest, DenialInvalidRequest, "INVALID_CONTRACT", "invalid contract address format", nil)
+			return
+		}
+
+		if _, ok := web3.GetChainConfig(chainID); !ok {
+			nftGateAudit(c, config, NFTDenial{WalletAddress: walletAddress, ContentID: contentID, Contract: contract, TokenID: tokenID, ChainID: chainID, Reason: DenialWrongChain})
+			abortDenied(c, http.StatusForbidden, DenialWrongChain, "UNSUPPORTED_CHAIN", "chain not supported for NFT gating", gin.H{
+				"chain_id": chainID,
 			})
 			return
 		}
@@ -234,9 +261,8 @@ func NFTGateMiddleware(config *NFTGateConfig, logger *zap.Logger) gin.HandlerFun
 		hasNFT, err := resolveOwnershipWithAutoDetect(c.Request.Context(), config, logger, cacheKey, chainID, contract, tokenID, walletAddress, minBalance, autoDetect)
 		if err != nil {
 			logger.Error("NFT verification failed", zap.Error(err))
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-				"error":      "verification service unavailable",
-				"code":       "NFT_VERIFY_ERROR",
+			notifyNFTDenied(c, config, NFTDenial{WalletAddress: walletAddress, ContentID: contentID, Contract: contract, TokenID: tokenID, ChainID: chainID, Reason: DenialRPCUnavailable})
+			abortDenied(c, http.StatusInternalServerError, DenialRPCUnavailable, "NFT_VERIFY_ERROR", "verification service unavailable", gin.H{
 				"chain_id":   chainID,
 				"chain_name": chainName(chainID),
 			})
@@ -361,13 +387,22 @@ func tryFallbackGatingRules(c *gin.Context, config *NFTGateConfig, logger *zap.L
 	return false, contract, tokenID, chainID
 }
 
-func nftGateDenied(c *gin.Context, config *NFTGateConfig, walletAddress, contract, tokenID string, chainID int64, contentID string) {
+func nftGateAudit(c *gin.Context, config *NFTGateConfig, d NFTDenial) {
 	if config.AuditLogger != nil {
-		config.AuditLogger.Log(c.Request.Context(), "nft.gate_denied", walletAddress, "content", contentID, false, "nft_access_denied", contract)
+		config.AuditLogger.Log(c.Request.Context(), "nft.gate_denied", d.WalletAddress, "content", d.ContentID, false, string(d.Reason), d.Contract)
+	}
+	notifyNFTDenied(c, config, d)
+}
+
+func notifyNFTDenied(c *gin.Context, config *NFTGateConfig, d NFTDenial) {
+	if config.OnDenied != nil {
+		config.OnDenied(c.Request.Context(), d)
 	}
+}
+
+func nftGateDenied(c *gin.Context, config *NFTGateConfig, walletAddress, contract, tokenID string, chainID int64, contentID string) {
+	nftGateAudit(c, config, NFTDenial{WalletAddress: walletAddress, ContentID: contentID, Contract: contract, TokenID: tokenID, ChainID: chainID, Reason: DenialNoNFT})
 	resp := gin.H{
-		"error": "nft access denied",
-		"code":  "NFT_REQUIRED",
 		"required_nft": gin.H{
 			"contract":   contract,
 			"chain_id":   chainID,
@@ -382,7 +417,7 @@ func nftGateDenied(c *gin.Context, config *NFTGateConfig, walletAddress, contrac
 		url = strings.ReplaceAll(url, "{token_id}", tokenID)
 		resp["required_nft"].(gin.H)["marketplace_url"] = url
 	}
-	c.AbortWithStatusJSON(http.StatusForbidden, resp)
+	abortDenied(c, http.StatusForbidden, DenialNoNFT, "NFT_REQUIRED", "nft access denied", resp)
 }
 
 func resolveOwnership(ctx context.Context, config *NFTGateConfig, logger *zap.Logger, cacheKey string, chainID int64, contract, tokenID, walletAddress string, minBalance int) (bool, error) {
@@ -562,11 +597,13 @@ func GetNFTContract(c *gin.Context) string {
 	return contract
 }
 
+// nftCacheKey builds "chain:wallet:contract:token". Addresses are lowercased
+// so Transfer-event invalidation can find the entry by prefix.
 func nftCacheKey(chainID int64, wallet, contract, tokenID string) string {
 	if tokenID == "" {
 		tokenID = "__collection__"
 	}
-	return fmt.Sprintf("%d:%s:%s:%s", chainID, wallet, contract, tokenID)
+	return fmt.Sprintf("%d:%s:%s:%s", chainID, strings.ToLower(wallet), strings.ToLower(contract), tokenID)
 }
 
 func parseInt64(s string) (int64, error) {
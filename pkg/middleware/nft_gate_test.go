@@ -11,6 +11,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
 
@@ -218,7 +219,7 @@ func TestNFTGateMiddleware_CacheHit(t *testing.T) {
 		},
 		Cache: &mockNFTAccessCacheOld{
 			entries: map[string]NFTAccessEntry{
-				"1:0xOwner:" + testContractAddr + ":__collection__": {
+				"1:0xowner:" + testContractAddr + ":__collection__": {
 					HasNFT:  true,
 					Balance: big.NewInt(5),
 					Expires: time.Now().Add(time.Minute),
@@ -370,12 +371,12 @@ func TestGetNFTContract_Missing(t *testing.T) {
 
 func TestNftCacheKey(t *testing.T) {
 	key := nftCacheKey(1, "0xWallet", "0xContract", "42")
-	assert.Equal(t, "1:0xWallet:0xContract:42", key)
+	assert.Equal(t, "1:0xwallet:0xcontract:42", key, "addresses are lowercased")
 }
 
 func TestNftCacheKey_Collection(t *testing.T) {
 	key := nftCacheKey(1, "0xWallet", "0xContract", "")
-	assert.Equal(t, "1:0xWallet:0xContract:__collection__", key)
+	assert.Equal(t, "1:0xwallet:0xcontract:__collection__", key)
 }
 
 func TestParseInt64(t *testing.T) {
@@ -640,6 +641,49 @@ func TestNFTGateMiddleware_AuditLoggerOnDenied(t *testing.T) {
 	assert.Contains(t, audit.logs, "nft.gate_denied")
 }
 
+func TestNFTGateMiddleware_OnDenied(t *testing.T) {
+	tests := []struct {
+		name       string
+		balance    int64
+		err        error
+		wantCode   int
+		wantReason DenialReason
+	}{
+		{"no nft", 0, nil, http.StatusForbidden, DenialNoNFT},
+		{"rpc error", 0, context.DeadlineExceeded, http.StatusInternalServerError, DenialRPCUnavailable},
+		{"owner", 1, nil, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var denials []NFTDenial
+			config := NFTGateConfig{
+				Verifier: &mockNFTOwnershipCheckerOld{
+					balanceFn: func(_ context.Context, _ int64, _ string, _ string) (*big.Int, error) {
+						return big.NewInt(tt.balance), tt.err
+					},
+				},
+				OnDenied:       func(_ context.Context, d NFTDenial) { denials = append(denials, d) },
+				DefaultChainID: 1,
+			}
+			router := setupNFTGateRouter(&config)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, authRequestWithWallet("/stream/123/manifest.m3u8?contract="+testContractAddr, "0xOwner"))
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			if tt.wantReason == "" {
+				assert.Empty(t, denials)
+				return
+			}
+			require.Len(t, denials, 1)
+			assert.Equal(t, tt.wantReason, denials[0].Reason)
+			assert.Equal(t, int64(1), denials[0].ChainID)
+			assert.NotEmpty(t, denials[0].WalletAddress)
+			assert.NotEmpty(t, denials[0].Contract)
+		})
+	}
+}
+
 func TestNFTGateMiddleware_AuditLoggerOnPassed(t *testing.T) {
 	audit := &mockAuditLogger{}
 	config := NFTGateConfig{
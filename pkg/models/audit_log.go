@@ -11,5 +11,7 @@ type AuditLog struct {
 	Success    bool      `json:"success"`
 	ErrorMsg   string    `json:"error_msg"`
 	Details    string    `json:"details"`
+	IP         string    `json:"ip"`
+	TraceID    string    `json:"trace_id"`
 	CreatedAt  time.Time `json:"created_at"`
 }
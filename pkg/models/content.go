@@ -52,6 +52,16 @@ var validContentTransitions = map[ContentStatus][]ContentStatus{
 	ContentStatusPending: {StatusReady, ContentStatusFailed},
 }
 
+// IsValidContentStatus reports whether status is a known content status.
+func IsValidContentStatus(status ContentStatus) bool {
+	switch status {
+	case StatusDraft, StatusProcessing, StatusPublished, StatusArchived,
+		StatusReady, ContentStatusFailed, ContentStatusPending:
+		return true
+	}
+	return false
+}
+
 func IsValidContentTransition(from, to ContentStatus) bool {
 	if from == to {
 		return true
@@ -77,3 +87,21 @@ type ContentMetadata struct {
 	Language   string   `json:"language"`
 	Subtitles  []string `json:"subtitles"`
 }
+
+// PublicContentSQL is the SQL condition, over contents aliased c, that
+// selects content anyone may browse: transcoded or published, and not held
+// or rejected by moderation.
+const PublicContentSQL = `c.status IN ('ready', 'published') AND COALESCE(c.metadata->>'moderation_status', 'approved') = 'approved'`
+
+// ContentSummary is the listing form of a content item used by browse
+// pages.
+type ContentSummary struct {
+	ID           string    `json:"id"`
+	Title        string    `json:"title"`
+	Type         string    `json:"type"`
+	OwnerID      string    `json:"owner_id"`
+	ThumbnailURL string    `json:"thumbnail_url,omitempty"`
+	Duration     int64     `json:"duration"`
+	Tags         []string  `json:"tags"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,54 @@
+package monitoring
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ExemplarTraceIDLabel is the exemplar label Grafana follows from a
+// histogram bucket to the trace that landed in it.
+const ExemplarTraceIDLabel = "trace_id"
+
+// Native histogram settings of the latency histograms. The sparse buckets
+// are kept next to the classic ones: Prometheus ingests them only when it
+// scrapes with native histograms enabled and otherwise sees the classic
+// buckets as before, so turning them on is a scrape-side choice.
+const (
+	nativeHistogramBucketFactor     = 1.1
+	nativeHistogramMaxBucketNumber  = 160
+	nativeHistogramMinResetDuration = time.Hour
+)
+
+// latencyHistogramOpts adds native histogram buckets to opts.
+func latencyHistogramOpts(opts prometheus.HistogramOpts) prometheus.HistogramOpts {
+	opts.NativeHistogramBucketFactor = nativeHistogramBucketFactor
+	opts.NativeHistogramMaxBucketNumber = nativeHistogramMaxBucketNumber
+	opts.NativeHistogramMinResetDuration = nativeHistogramMinResetDuration
+	return opts
+}
+
+// ObserveContext records v on obs. When ctx carries a sampled span the
+// trace ID is attached as an exemplar; unsampled traces are not exported,
+// so linking to them would lead nowhere.
+func ObserveContext(ctx context.Context, obs prometheus.Observer, v float64) {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsSampled() {
+		if eo, ok := obs.(prometheus.ExemplarObserver); ok {
+			eo.ObserveWithExemplar(v, prometheus.Labels{ExemplarTraceIDLabel: sc.TraceID().String()})
+			return
+		}
+	}
+	obs.Observe(v)
+}
+
+// MetricsHandler serves the default registry for /metrics. OpenMetrics is
+// offered so scrapers that ask for it receive exemplars; the plain text
+// format, which cannot carry them, is still served to everyone else.
+func MetricsHandler() http.Handler {
+	return promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}))
+}
@@ -0,0 +1,99 @@
+package monitoring
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// tracedContext returns a context carrying a remote span context.
+func tracedContext(traceID string, sampled bool) context.Context {
+	tid, _ := trace.TraceIDFromHex(traceID)
+	cfg := trace.SpanContextConfig{TraceID: tid, SpanID: trace.SpanID{1}}
+	if sampled {
+		cfg.TraceFlags = trace.FlagsSampled
+	}
+	return trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(cfg))
+}
+
+func TestObserveContext(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantTID string
+	}{
+		{"sampled trace", tracedContext("4bf92f3577b34da6a3ce929d0e0e4736", true), "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{"unsampled trace", tracedContext("4bf92f3577b34da6a3ce929d0e0e4736", false), ""},
+		{"no trace", context.Background(), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := prometheus.NewHistogram(latencyHistogramOpts(prometheus.HistogramOpts{
+				Name:    "test_latency_seconds",
+				Buckets: prometheus.DefBuckets,
+			}))
+			reg := prometheus.NewRegistry()
+			require.NoError(t, reg.Register(h))
+
+			ObserveContext(tt.ctx, h, 0.3)
+
+			families, err := reg.Gather()
+			require.NoError(t, err)
+			require.Len(t, families, 1)
+			hist := families[0].GetMetric()[0].GetHistogram()
+			assert.Equal(t, uint64(1), hist.GetSampleCount())
+			assert.NotEmpty(t, hist.GetPositiveSpan(), "native buckets are kept")
+
+			var traceIDs []string
+			for _, b := range hist.GetBucket() {
+				for _, l := range b.GetExemplar().GetLabel() {
+					traceIDs = append(traceIDs, l.GetValue())
+				}
+			}
+			if tt.wantTID == "" {
+				assert.Empty(t, traceIDs)
+				return
+			}
+			assert.Equal(t, []string{tt.wantTID}, traceIDs)
+		})
+	}
+}
+
+func TestMetricsHandler_OpenMetricsExemplars(t *testing.T) {
+	const traceID = "0af7651916cd43dd8448eb211c80319c"
+	mc := NewMetricsCollector(zap.NewNop())
+	mc.RecordTimerContext(tracedContext(traceID, true), "exemplar_test_op", 250*time.Millisecond, nil)
+
+	tests := []struct {
+		name         string
+		accept       string
+		wantExemplar bool
+	}{
+		{"openmetrics", "application/openmetrics-text; version=1.0.0", true},
+		{"plain text", "text/plain", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody)
+			req.Header.Set("Accept", tt.accept)
+			w := httptest.NewRecorder()
+			MetricsHandler().ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			body, err := io.ReadAll(w.Body)
+			require.NoError(t, err)
+			assert.Contains(t, string(body), `streamgate_plugin_duration_seconds_bucket{metric="exemplar_test_op"`)
+			assert.Equal(t, tt.wantExemplar, strings.Contains(string(body), `# {trace_id="`+traceID+`"}`))
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package monitoring
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"sync"
@@ -28,11 +29,11 @@ var (
 		[]string{"metric"},
 	)
 	pluginHistogramSeconds = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
+		latencyHistogramOpts(prometheus.HistogramOpts{
 			Name:    "streamgate_plugin_duration_seconds",
 			Help:    "Histogram of plugin-level durations tracked by MetricsCollector",
 			Buckets: prometheus.DefBuckets,
-		},
+		}),
 		[]string{"metric"},
 	)
 	serviceRequestsTotal = prometheus.NewCounterVec(
@@ -72,14 +73,37 @@ var (
 		},
 		[]string{"cache"},
 	)
+	StreamingManifestSegments = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "streamgate_streaming_manifest_segments",
+		Help:    "Segments per variant when generating a media playlist, before limits are applied",
+		Buckets: []float64{10, 50, 100, 500, 1000, 2500, 5000, 10000, 25000},
+	})
+	StreamingManifestLimitTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "streamgate_streaming_manifest_limit_total",
+			Help: "Media playlists affected by manifest limits, by outcome (near_limit, truncated, windowed)",
+		},
+		[]string{"outcome"},
+	)
+	StreamingEgressRoutedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "streamgate_streaming_egress_routed_total",
+			Help: "Manifests whose segment URLs were routed, by egress region (origin when unrouted)",
+		},
+		[]string{"region"},
+	)
 	StreamingDownloadDuration = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
+		latencyHistogramOpts(prometheus.HistogramOpts{
 			Name:    "streamgate_streaming_download_seconds",
 			Help:    "Segment download duration from object storage in seconds",
 			Buckets: prometheus.DefBuckets,
-		},
+		}),
 		[]string{"status"},
 	)
+	LiveStreamsActive = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "streamgate_live_streams_active",
+		Help: "Current number of live streams with a connected RTMP publisher",
+	})
 	TranscodingQueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
 		Name: "streamgate_transcoding_queue_depth",
 		Help: "Current number of pending transcoding tasks in the queue",
@@ -88,6 +112,49 @@ var (
 		Name: "streamgate_transcoding_workers_active",
 		Help: "Current number of active transcoding worker goroutines",
 	})
+	TranscodingQueueWaitSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "streamgate_transcoding_queue_wait_seconds",
+			Help:    "Time transcoding tasks waited in the queue before a worker took them, by priority class",
+			Buckets: []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200, 3600},
+		},
+		[]string{"priority"},
+	)
+	TranscodingQueueStarvedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "streamgate_transcoding_queue_starved_total",
+			Help: "Transcoding tasks dispatched out of turn after waiting past the queue's maximum wait, by priority class",
+		},
+		[]string{"priority"},
+	)
+	EventDuplicatesSkippedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "streamgate_event_duplicates_skipped_total",
+			Help: "Redelivered events skipped because they were already processed, by subscriber",
+		},
+		[]string{"subscriber"},
+	)
+	EventsDroppedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "streamgate_events_dropped_total",
+			Help: "Published events dropped before their handler ran because the event bus closed, by event type",
+		},
+		[]string{"event_type"},
+	)
+	MemoryStoreEvictionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "streamgate_memory_store_evictions_total",
+			Help: "Entries evicted or rejected because a bounded in-memory store was full, by store and overflow policy",
+		},
+		[]string{"store", "policy"},
+	)
+	RetriesDeferredTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "streamgate_retries_deferred_total",
+			Help: "Retries delayed because the shared retry budget was exhausted, by component",
+		},
+		[]string{"component"},
+	)
 	AuthOperationsTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "streamgate_auth_operations_total",
@@ -95,6 +162,37 @@ var (
 		},
 		[]string{"operation", "status"},
 	)
+	AuthChallengesTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "streamgate_auth_challenges_total",
+			Help: "Total authentication challenges issued",
+		},
+	)
+	AuthVerificationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "streamgate_auth_verifications_total",
+			Help: "Total challenge verifications by result (success, invalid, expired, used, exhausted, not_found, error)",
+		},
+		[]string{"result"},
+	)
+	AuthSessionsActive = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "streamgate_auth_sessions_active",
+			Help: "Current number of live authentication sessions",
+		},
+	)
+	AuthSessionsCreatedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "streamgate_auth_sessions_created_total",
+			Help: "Total authentication sessions created",
+		},
+	)
+	AuthSessionsRevokedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "streamgate_auth_sessions_revoked_total",
+			Help: "Total authentication sessions explicitly revoked",
+		},
+	)
 	EventIndexerEventsTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "streamgate_event_indexer_events_total",
@@ -122,6 +220,13 @@ var (
 		},
 		[]string{"mode"},
 	)
+	ConfigReloadsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "streamgate_config_reloads_total",
+			Help: "Total configuration reload attempts by result",
+		},
+		[]string{"result"},
+	)
 )
 
 func init() {
@@ -135,14 +240,30 @@ func init() {
 		StreamingSegmentsTotal,
 		StreamingManifestsTotal,
 		StreamingCacheHitsTotal,
+		StreamingManifestSegments,
+		StreamingManifestLimitTotal,
+		StreamingEgressRoutedTotal,
 		StreamingDownloadDuration,
+		LiveStreamsActive,
 		TranscodingQueueDepth,
 		TranscodingWorkersActive,
+		TranscodingQueueWaitSeconds,
+		TranscodingQueueStarvedTotal,
+		EventDuplicatesSkippedTotal,
+		EventsDroppedTotal,
+		MemoryStoreEvictionsTotal,
+		RetriesDeferredTotal,
 		AuthOperationsTotal,
+		AuthChallengesTotal,
+		AuthVerificationsTotal,
+		AuthSessionsActive,
+		AuthSessionsCreatedTotal,
+		AuthSessionsRevokedTotal,
 		EventIndexerEventsTotal,
 		EventIndexerReorgsTotal,
 		EventIndexerCurrentBlock,
 		EventIndexerIndexDuration,
+		ConfigReloadsTotal,
 		prometheus.NewGoCollector(),
 		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
 	} {
@@ -255,6 +376,12 @@ func (mc *MetricsCollector) SetGauge(name string, value float64, tags map[string
 
 // RecordHistogram records a histogram metric and bridges to Prometheus.
 func (mc *MetricsCollector) RecordHistogram(name string, value float64, tags map[string]string) {
+	mc.RecordHistogramContext(context.Background(), name, value, tags)
+}
+
+// RecordHistogramContext is RecordHistogram that links the Prometheus
+// observation to the sampled trace in ctx, if any, through an exemplar.
+func (mc *MetricsCollector) RecordHistogramContext(ctx context.Context, name string, value float64, tags map[string]string) {
 	mc.mu.Lock()
 	defer mc.mu.Unlock()
 
@@ -288,7 +415,7 @@ func (mc *MetricsCollector) RecordHistogram(name string, value float64, tags map
 	metric.LastUpdated = time.Now()
 
 	// Bridge to Prometheus — value is in ms from callers, convert to seconds
-	pluginHistogramSeconds.WithLabelValues(name).Observe(value / 1000.0)
+	ObserveContext(ctx, pluginHistogramSeconds.WithLabelValues(name), value/1000.0)
 }
 
 // RecordTimer records a timer metric
@@ -296,6 +423,11 @@ func (mc *MetricsCollector) RecordTimer(name string, duration time.Duration, tag
 	mc.RecordHistogram(name, float64(duration.Milliseconds()), tags)
 }
 
+// RecordTimerContext is RecordTimer with a trace exemplar from ctx.
+func (mc *MetricsCollector) RecordTimerContext(ctx context.Context, name string, duration time.Duration, tags map[string]string) {
+	mc.RecordHistogramContext(ctx, name, float64(duration.Milliseconds()), tags)
+}
+
 // GetMetric gets a metric by name
 func (mc *MetricsCollector) GetMetric(name string) *Metric {
 	mc.mu.RLock()
@@ -8,7 +8,7 @@ import (
 )
 
 // Standard Prometheus metrics registered on the default registry.
-// The /metrics endpoint in gateway.go uses promhttp.Handler() which serves
+// The /metrics endpoint in gateway.go uses MetricsHandler() which serves
 // these metrics plus the bridge metrics defined in metrics.go.
 var (
 	HTTPRequestsTotal = prometheus.NewCounterVec(
@@ -18,6 +18,50 @@ var (
 		},
 		[]string{"method", "route", "status"},
 	)
+	HTTPRequestDurationSeconds = prometheus.NewHistogramVec(
+		latencyHistogramOpts(prometheus.HistogramOpts{
+			Name:    "streamgate_http_request_duration_seconds",
+			Help:    "HTTP request duration in seconds by route template",
+			Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		}),
+		[]string{"method", "route", "status"},
+	)
+	HTTPRequestsInFlight = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "streamgate_http_requests_in_flight",
+			Help: "HTTP requests currently being served",
+		},
+	)
+	GRPCServerHandledTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "streamgate_grpc_server_handled_total",
+			Help: "Total gRPC calls handled by method and status code",
+		},
+		[]string{"method", "code"},
+	)
+	GRPCServerHandlingSeconds = prometheus.NewHistogramVec(
+		latencyHistogramOpts(prometheus.HistogramOpts{
+			Name:    "streamgate_grpc_server_handling_seconds",
+			Help:    "gRPC call handling time in seconds by method",
+			Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		}),
+		[]string{"method"},
+	)
+	GRPCClientHandledTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "streamgate_grpc_client_handled_total",
+			Help: "Total gRPC calls made to other services by method and status code",
+		},
+		[]string{"method", "code"},
+	)
+	GRPCClientHandlingSeconds = prometheus.NewHistogramVec(
+		latencyHistogramOpts(prometheus.HistogramOpts{
+			Name:    "streamgate_grpc_client_handling_seconds",
+			Help:    "gRPC client call time in seconds by method",
+			Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		}),
+		[]string{"method"},
+	)
 	ServiceRequestDuration = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "streamgate_service_request_duration_ms",
@@ -33,6 +77,20 @@ var (
 		},
 		[]string{"status"},
 	)
+	HealthCheckStatus = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "streamgate_health_check_status",
+			Help: "Last result of each health check, 1 for the status it reported",
+		},
+		[]string{"check", "status"},
+	)
+	HealthCheckDurationSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "streamgate_health_check_duration_seconds",
+			Help: "Duration of the last run of each health check",
+		},
+		[]string{"check"},
+	)
 	RPCFailoverTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "streamgate_rpc_failover_total",
@@ -41,13 +99,34 @@ var (
 		[]string{"operation", "from_provider", "to_provider"},
 	)
 	RPCLatencySeconds = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
+		latencyHistogramOpts(prometheus.HistogramOpts{
 			Name:    "streamgate_rpc_latency_seconds",
 			Help:    "RPC call latency in seconds",
 			Buckets: []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
-		},
+		}),
 		[]string{"operation", "rpc_provider"},
 	)
+	RPCEndpointUp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "streamgate_rpc_endpoint_up",
+			Help: "Whether the last health probe of an RPC endpoint succeeded (1) or not (0)",
+		},
+		[]string{"chain_id", "rpc_provider"},
+	)
+	RPCEndpointProbeLatencySeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "streamgate_rpc_endpoint_probe_latency_seconds",
+			Help: "eth_blockNumber latency of the last RPC endpoint health probe",
+		},
+		[]string{"chain_id", "rpc_provider"},
+	)
+	RPCEndpointErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "streamgate_rpc_endpoint_errors_total",
+			Help: "Total RPC endpoint errors by reason (error, rate_limited, lagging)",
+		},
+		[]string{"chain_id", "rpc_provider", "reason"},
+	)
 )
 
 func init() {
@@ -61,10 +140,21 @@ func init() {
 		}
 	}
 	register(HTTPRequestsTotal)
+	register(HTTPRequestDurationSeconds)
+	register(HTTPRequestsInFlight)
+	register(GRPCServerHandledTotal)
+	register(GRPCServerHandlingSeconds)
+	register(GRPCClientHandledTotal)
+	register(GRPCClientHandlingSeconds)
 	register(ServiceRequestDuration)
 	register(HealthCheckTotal)
+	register(HealthCheckStatus)
+	register(HealthCheckDurationSeconds)
 	register(RPCFailoverTotal)
 	register(RPCLatencySeconds)
+	register(RPCEndpointUp)
+	register(RPCEndpointProbeLatencySeconds)
+	register(RPCEndpointErrorsTotal)
 }
 
 // RPCProviderFromURL extracts a stable provider identifier from an RPC URL.
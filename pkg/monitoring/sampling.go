@@ -0,0 +1,250 @@
+package monitoring
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Head sampling strategies for SamplingConfig.Strategy.
+const (
+	SamplerAlways        = "always"
+	SamplerNever         = "never"
+	SamplerProbabilistic = "probabilistic"
+	SamplerRateLimited   = "rate_limited"
+)
+
+const defaultTailMaxTraces = 10000
+
+// SamplingConfig decides which traces are recorded and exported. The head
+// sampler decides when a trace starts; spans continuing a remote trace
+// follow the caller's decision. Tail sampling then looks at each finished
+// trace the head sampler kept, so to tail-sample all traffic use
+// SamplerAlways.
+type SamplingConfig struct {
+	// Strategy is one of the Sampler constants; "" is SamplerAlways.
+	Strategy string
+	// Ratio is the fraction of traces SamplerProbabilistic keeps.
+	Ratio float64
+	// RatePerSecond is how many traces SamplerRateLimited starts per
+	// second.
+	RatePerSecond float64
+	Tail          TailSamplingConfig
+}
+
+// TailSamplingConfig keeps every trace with an error or a slow span and a
+// fraction of the rest.
+type TailSamplingConfig struct {
+	Enabled bool
+	// Ratio is the fraction of healthy traces kept.
+	Ratio float64
+	// LatencyThreshold marks a span as slow; zero disables the latency
+	// rule.
+	LatencyThreshold time.Duration
+	// MaxTraces bounds the traces buffered while their root span is open.
+	// When full, the oldest trace is decided on the spans seen so far.
+	MaxTraces int
+}
+
+// NewSampler returns the head sampler for cfg.
+func NewSampler(cfg SamplingConfig) (sdktrace.Sampler, error) {
+	var root sdktrace.Sampler
+	switch cfg.Strategy {
+	case "", SamplerAlways:
+		root = sdktrace.AlwaysSample()
+	case SamplerNever:
+		root = sdktrace.NeverSample()
+	case SamplerProbabilistic:
+		if cfg.Ratio < 0 || cfg.Ratio > 1 {
+			return nil, fmt.Errorf("sampling ratio must be between 0 and 1, got %v", cfg.Ratio)
+		}
+		root = sdktrace.TraceIDRatioBased(cfg.Ratio)
+	case SamplerRateLimited:
+		if cfg.RatePerSecond <= 0 {
+			return nil, fmt.Errorf("rate-limited sampling needs a positive rate, got %v", cfg.RatePerSecond)
+		}
+		root = RateLimitedSampler(cfg.RatePerSecond)
+	default:
+		return nil, fmt.Errorf("unknown sampling strategy %q", cfg.Strategy)
+	}
+	if cfg.Tail.Enabled && (cfg.Tail.Ratio < 0 || cfg.Tail.Ratio > 1) {
+		return nil, fmt.Errorf("tail sampling ratio must be between 0 and 1, got %v", cfg.Tail.Ratio)
+	}
+	return sdktrace.ParentBased(root), nil
+}
+
+// rateLimitedSampler samples up to a number of traces per second from a
+// token bucket holding one second of traces.
+type rateLimitedSampler struct {
+	perSecond float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// RateLimitedSampler samples at most perSecond new traces a second,
+// allowing a burst of one second's worth.
+func RateLimitedSampler(perSecond float64) sdktrace.Sampler {
+	return &rateLimitedSampler{perSecond: perSecond, tokens: perSecond, now: time.Now}
+}
+
+func (s *rateLimitedSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	decision := sdktrace.Drop
+	if s.take() {
+		decision = sdktrace.RecordAndSample
+	}
+	return sdktrace.SamplingResult{
+		Decision:   decision,
+		Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+	}
+}
+
+func (s *rateLimitedSampler) take() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if !s.last.IsZero() {
+		s.tokens = math.Min(s.perSecond, s.tokens+now.Sub(s.last).Seconds()*s.perSecond)
+	}
+	s.last = now
+	if s.tokens < 1 {
+		return false
+	}
+	s.tokens--
+	return true
+}
+
+func (s *rateLimitedSampler) Description() string {
+	return fmt.Sprintf("RateLimited{%g}", s.perSecond)
+}
+
+// tailSamplingProcessor buffers the ended spans of each trace until its
+// local root span ends, then passes the whole trace to next or drops it.
+type tailSamplingProcessor struct {
+	next sdktrace.SpanProcessor
+	cfg  TailSamplingConfig
+
+	mu     sync.Mutex
+	traces map[trace.TraceID]*tailTrace
+	order  []trace.TraceID
+}
+
+type tailTrace struct {
+	spans []sdktrace.ReadOnlySpan
+	keep  bool
+}
+
+// NewTailSamplingProcessor wraps next, usually a batch span processor, so
+// it only receives the traces cfg keeps. Traces are kept whole.
+func NewTailSamplingProcessor(next sdktrace.SpanProcessor, cfg TailSamplingConfig) sdktrace.SpanProcessor {
+	if cfg.MaxTraces <= 0 {
+		cfg.MaxTraces = defaultTailMaxTraces
+	}
+	return &tailSamplingProcessor{next: next, cfg: cfg, traces: make(map[trace.TraceID]*tailTrace)}
+}
+
+func (p *tailSamplingProcessor) OnStart(ctx context.Context, s sdktrace.ReadWriteSpan) {
+	p.next.OnStart(ctx, s)
+}
+
+func (p *tailSamplingProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	id := s.SpanContext().TraceID()
+	var flush [][]sdktrace.ReadOnlySpan
+
+	p.mu.Lock()
+	t, ok := p.traces[id]
+	if !ok {
+		t = &tailTrace{}
+		p.traces[id] = t
+		p.order = append(p.order, id)
+	}
+	t.spans = append(t.spans, s)
+	t.keep = t.keep || p.notable(s)
+	if parent := s.Parent(); !parent.IsValid() || parent.IsRemote() {
+		if spans := p.decide(id); spans != nil {
+			flush = append(flush, spans)
+		}
+	}
+	for len(p.traces) > p.cfg.MaxTraces {
+		if spans := p.decide(p.order[0]); spans != nil {
+			flush = append(flush, spans)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, spans := range flush {
+		for _, span := range spans {
+			p.next.OnEnd(span)
+		}
+	}
+}
+
+// notable reports whether s alone is reason to keep its trace.
+func (p *tailSamplingProcessor) notable(s sdktrace.ReadOnlySpan) bool {
+	if s.Status().Code == codes.Error {
+		return true
+	}
+	return p.cfg.LatencyThreshold > 0 && s.EndTime().Sub(s.StartTime()) >= p.cfg.LatencyThreshold
+}
+
+// decide forgets trace id and returns its spans if it is kept. Healthy
+// traces are kept by trace ID, so every instance keeps the same ones.
+// Callers hold p.mu.
+func (p *tailSamplingProcessor) decide(id trace.TraceID) []sdktrace.ReadOnlySpan {
+	t, ok := p.traces[id]
+	if !ok {
+		return nil
+	}
+	delete(p.traces, id)
+	for i, o := range p.order {
+		if o == id {
+			p.order = append(p.order[:i], p.order[i+1:]...)
+			break
+		}
+	}
+	if t.keep || traceIDBelow(id, p.cfg.Ratio) {
+		return t.spans
+	}
+	return nil
+}
+
+// traceIDBelow is the decision TraceIDRatioBased makes for id.
+func traceIDBelow(id trace.TraceID, ratio float64) bool {
+	if ratio >= 1 {
+		return true
+	}
+	x := binary.BigEndian.Uint64(id[8:16]) >> 1
+	return x < uint64(ratio*(1<<63))
+}
+
+// flushAll passes on the kept traces still buffered.
+func (p *tailSamplingProcessor) flushAll() {
+	p.mu.Lock()
+	var kept []sdktrace.ReadOnlySpan
+	for len(p.order) > 0 {
+		kept = append(kept, p.decide(p.order[0])...)
+	}
+	p.mu.Unlock()
+	for _, s := range kept {
+		p.next.OnEnd(s)
+	}
+}
+
+func (p *tailSamplingProcessor) ForceFlush(ctx context.Context) error {
+	p.flushAll()
+	return p.next.ForceFlush(ctx)
+}
+
+func (p *tailSamplingProcessor) Shutdown(ctx context.Context) error {
+	p.flushAll()
+	return p.next.Shutdown(ctx)
+}
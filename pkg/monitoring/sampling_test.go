@@ -0,0 +1,126 @@
+package monitoring
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNewSampler(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     SamplingConfig
+		want    string
+		wantErr bool
+	}{
+		{"default", SamplingConfig{}, "AlwaysOnSampler", false},
+		{"never", SamplingConfig{Strategy: SamplerNever}, "AlwaysOffSampler", false},
+		{"probabilistic", SamplingConfig{Strategy: SamplerProbabilistic, Ratio: 0.25}, "TraceIDRatioBased{0.25}", false},
+		{"rate limited", SamplingConfig{Strategy: SamplerRateLimited, RatePerSecond: 10}, "RateLimited{10}", false},
+		{"ratio out of range", SamplingConfig{Strategy: SamplerProbabilistic, Ratio: 1.5}, "", true},
+		{"no rate", SamplingConfig{Strategy: SamplerRateLimited}, "", true},
+		{"tail ratio out of range", SamplingConfig{Tail: TailSamplingConfig{Enabled: true, Ratio: -1}}, "", true},
+		{"unknown", SamplingConfig{Strategy: "sometimes"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSampler(tt.cfg)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, s.Description(), "ParentBased{root:"+tt.want)
+		})
+	}
+}
+
+func TestRateLimitedSampler(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s := RateLimitedSampler(2).(*rateLimitedSampler)
+	s.now = func() time.Time { return now }
+	sample := func() bool {
+		return s.ShouldSample(sdktrace.SamplingParameters{ParentContext: context.Background()}).Decision == sdktrace.RecordAndSample
+	}
+
+	assert.True(t, sample())
+	assert.True(t, sample())
+	assert.False(t, sample(), "the burst is one second of traces")
+
+	now = now.Add(500 * time.Millisecond)
+	assert.True(t, sample())
+	assert.False(t, sample())
+
+	now = now.Add(time.Minute)
+	assert.True(t, sample())
+	assert.True(t, sample())
+	assert.False(t, sample(), "idle time does not grow the burst")
+}
+
+// endedSpan ends a span that ran for d with the given status.
+func endedSpan(ctx context.Context, tr trace.Tracer, name string, d time.Duration, code codes.Code) context.Context {
+	start := time.Now()
+	ctx, span := tr.Start(ctx, name, trace.WithTimestamp(start))
+	span.SetStatus(code, "")
+	span.End(trace.WithTimestamp(start.Add(d)))
+	return ctx
+}
+
+func TestTailSamplingProcessor(t *testing.T) {
+	tests := []struct {
+		name      string
+		ratio     float64
+		childTime time.Duration
+		childCode codes.Code
+		wantSpans int
+	}{
+		{"healthy trace dropped", 0, time.Millisecond, codes.Ok, 0},
+		{"error keeps whole trace", 0, time.Millisecond, codes.Error, 2},
+		{"slow span keeps whole trace", 0, time.Second, codes.Unset, 2},
+		{"ratio keeps healthy traces", 1, time.Millisecond, codes.Ok, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(
+				NewTailSamplingProcessor(rec, TailSamplingConfig{Ratio: tt.ratio, LatencyThreshold: 500 * time.Millisecond})))
+			tr := tp.Tracer("test")
+
+			ctx, root := tr.Start(context.Background(), "request")
+			endedSpan(ctx, tr, "db.query", tt.childTime, tt.childCode)
+			assert.Empty(t, rec.Ended(), "spans wait for the root")
+			root.End()
+
+			assert.Len(t, rec.Ended(), tt.wantSpans)
+			require.NoError(t, tp.Shutdown(context.Background()))
+		})
+	}
+}
+
+func TestTailSamplingProcessor_Buffering(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(
+		NewTailSamplingProcessor(rec, TailSamplingConfig{MaxTraces: 1})))
+	tr := tp.Tracer("test")
+
+	// The first trace's root is still open when a second trace arrives,
+	// so it is decided early on its failed child.
+	ctx1, root1 := tr.Start(context.Background(), "first")
+	endedSpan(ctx1, tr, "child", time.Millisecond, codes.Error)
+	ctx2, root2 := tr.Start(context.Background(), "second")
+	endedSpan(ctx2, tr, "child", time.Millisecond, codes.Error)
+	require.Len(t, rec.Ended(), 1)
+	assert.Equal(t, root1.SpanContext().TraceID(), rec.Ended()[0].SpanContext().TraceID())
+
+	// Shutdown passes on what is still buffered.
+	require.NoError(t, tp.Shutdown(context.Background()))
+	require.Len(t, rec.Ended(), 2)
+	assert.Equal(t, root2.SpanContext().TraceID(), rec.Ended()[1].SpanContext().TraceID())
+}
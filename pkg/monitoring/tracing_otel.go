@@ -17,7 +17,14 @@ import (
 	"google.golang.org/grpc/credentials"
 )
 
-func InitOTelTracing(ctx context.Context, serviceName, endpoint string, logger *zap.Logger) (shutdown func(ctx context.Context) error, err error) {
+// InitOTelTracing installs a tracer provider exporting to the OTLP
+// endpoint, sampled as described by sampling. Without a reachable
+// endpoint tracing stays disabled.
+func InitOTelTracing(ctx context.Context, serviceName, endpoint string, sampling SamplingConfig, logger *zap.Logger) (shutdown func(ctx context.Context) error, err error) {
+	sampler, err := NewSampler(sampling)
+	if err != nil {
+		return nil, err
+	}
 	endpoint = strings.TrimPrefix(endpoint, "http://")
 	endpoint = strings.TrimPrefix(endpoint, "https://")
 	endpoint = strings.TrimSuffix(endpoint, "/api/traces")
@@ -63,10 +70,15 @@ func InitOTelTracing(ctx context.Context, serviceName, endpoint string, logger *
 		return nil, fmt.Errorf("failed to create resource: %w", err)
 	}
 
+	var processor sdktrace.SpanProcessor = sdktrace.NewBatchSpanProcessor(exporter,
+		sdktrace.WithBatchTimeout(5*time.Second),
+	)
+	if sampling.Tail.Enabled {
+		processor = NewTailSamplingProcessor(processor, sampling.Tail)
+	}
 	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(exporter,
-			sdktrace.WithBatchTimeout(5*time.Second),
-		),
+		sdktrace.WithSampler(sampler),
+		sdktrace.WithSpanProcessor(processor),
 		sdktrace.WithResource(res),
 	)
 
@@ -78,7 +90,9 @@ func InitOTelTracing(ctx context.Context, serviceName, endpoint string, logger *
 
 	logger.Info("OTel tracing initialized",
 		zap.String("service", serviceName),
-		zap.String("endpoint", endpoint))
+		zap.String("endpoint", endpoint),
+		zap.String("sampler", sampler.Description()),
+		zap.Bool("tail_sampling", sampling.Tail.Enabled))
 
 	return tp.Shutdown, nil
 }
@@ -80,7 +80,7 @@ func (p *GatewayPlugin) Init(ctx context.Context, kernel *core.Microkernel) erro
 func (p *GatewayPlugin) Start(ctx context.Context) error {
 	p.logger.Info("Starting API Gateway", zap.Int("port", p.config.Server.Port))
 
-	router, resources, err := gateway.SetupRouter(p.config, p.logger)
+	router, resources, err := gateway.SetupRouter(p.config, p.logger, gateway.WithKernel(p.kernel))
 	if err != nil {
 		return fmt.Errorf("failed to setup router: %w", err)
 	}
@@ -128,6 +128,7 @@ func (p *GatewayPlugin) Start(ctx context.Context) error {
 		SegmentStorage: resources.SegmentStorage,
 		UploadService:  resources.UploadService,
 		TranscodingSvc: resources.TranscodingSvc,
+		LiveSvc:        resources.LiveSvc,
 	}
 	p.healthCtx, p.healthCancel = context.WithCancel(context.Background())
 	p.grpcServer = gateway.SetupGRPCServer(p.healthCtx, p.config, p.logger, grpcServices)
@@ -171,6 +172,9 @@ func (p *GatewayPlugin) Stop(ctx context.Context) error {
 	}
 
 	if p.resources != nil {
+		if err := p.resources.Drain(ctx); err != nil {
+			p.logger.Error("Error draining transcodes", zap.Error(err))
+		}
 		if err := p.resources.Close(); err != nil {
 			p.logger.Error("Error closing resources", zap.Error(err))
 		}
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"container/list"
+	"errors"
+
+	"github.com/rtcdance/streamgate/pkg/storage"
+)
+
+// DefaultMaxSessions caps sessions when SessionConfig leaves MaxSessions
+// unset.
+const DefaultMaxSessions = 100000
+
+var (
+	// ErrTooManyChallenges is returned when the challenge store is full and
+	// its overflow policy rejects new entries.
+	ErrTooManyChallenges = storage.ErrChallengeStoreFull
+	// ErrTooManySessions is returned when the session map is full and its
+	// overflow policy rejects new entries.
+	ErrTooManySessions = errors.New("too many active sessions")
+)
+
+// lruIndex orders map keys from most to least recently used so a bounded
+// map can find its eviction candidate in O(1). Callers hold their own lock.
+type lruIndex struct {
+	order *list.List // string keys, most recent at front
+	elems map[string]*list.Element
+}
+
+func newLRUIndex() *lruIndex {
+	return &lruIndex{order: list.New(), elems: make(map[string]*list.Element)}
+}
+
+// touch marks key as most recently used, adding it if absent.
+func (x *lruIndex) touch(key string) {
+	if elem, ok := x.elems[key]; ok {
+		x.order.MoveToFront(elem)
+		return
+	}
+	x.elems[key] = x.order.PushFront(key)
+}
+
+func (x *lruIndex) remove(key string) {
+	if elem, ok := x.elems[key]; ok {
+		x.order.Remove(elem)
+		delete(x.elems, key)
+	}
+}
+
+// oldest returns the least recently used key.
+func (x *lruIndex) oldest() (string, bool) {
+	elem := x.order.Back()
+	if elem == nil {
+		return "", false
+	}
+	return elem.Value.(string), true
+}
@@ -8,19 +8,24 @@ import (
 	"crypto/subtle"
 	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
+	"github.com/rtcdance/streamgate/pkg/monitoring"
+	"github.com/rtcdance/streamgate/pkg/storage"
+
 	"go.uber.org/zap"
 )
 
 // ChallengeResponseAuth handles challenge-response authentication
 type ChallengeResponseAuth struct {
-	logger     *zap.Logger
-	challenges map[string]*Challenge
-	mu         sync.RWMutex
-	config     *AuthConfig
+	logger *zap.Logger
+	store  ChallengeStore
+	config *AuthConfig
+	// ownStore is the default store created here, closed by Close.
+	ownStore *StorageChallengeStore
 }
 
 // Challenge represents an authentication challenge
@@ -39,23 +44,55 @@ type AuthConfig struct {
 	ChallengeTTL     time.Duration
 	MaxAttempts      int
 	RequireSignature bool
+	// MaxChallenges caps pending challenges; <= 0 uses
+	// storage.DefaultMaxMemoryChallenges. Only the default in-memory store
+	// applies it.
+	MaxChallenges int
+	// OverflowPolicy decides what happens when MaxChallenges is reached.
+	// Empty evicts the oldest challenge.
+	OverflowPolicy storage.OverflowPolicy
+	// Store holds pending challenges. Nil keeps them in memory, which only
+	// works when challenges are answered on the replica that issued them.
+	Store ChallengeStore
+}
+
+// DefaultAuthConfig returns the configuration used when none is given.
+func DefaultAuthConfig() *AuthConfig {
+	return &AuthConfig{
+		ChallengeTTL:     5 * time.Minute,
+		MaxAttempts:      3,
+		RequireSignature: true,
+	}
 }
 
 // NewChallengeResponseAuth creates a new challenge-response authentication handler
 func NewChallengeResponseAuth(logger *zap.Logger, config *AuthConfig) *ChallengeResponseAuth {
 	if config == nil {
-		config = &AuthConfig{
-			ChallengeTTL:     5 * time.Minute,
-			MaxAttempts:      3,
-			RequireSignature: true,
-		}
+		config = DefaultAuthConfig()
 	}
 
-	return &ChallengeResponseAuth{
-		logger:     logger,
-		challenges: make(map[string]*Challenge),
-		config:     config,
+	cra := &ChallengeResponseAuth{
+		logger: logger,
+		store:  config.Store,
+		config: config,
+	}
+	if cra.store == nil {
+		cra.ownStore = NewStorageChallengeStore(storage.NewMemoryChallengeStore(
+			storage.WithMaxChallenges(config.MaxChallenges),
+			storage.WithChallengeOverflowPolicy(config.OverflowPolicy),
+		))
+		cra.store = cra.ownStore
+	}
+	return cra
+}
+
+// Close releases the default in-memory store. A store passed in
+// AuthConfig.Store is left to its owner.
+func (cra *ChallengeResponseAuth) Close() error {
+	if cra.ownStore == nil {
+		return nil
 	}
+	return cra.ownStore.Close()
 }
 
 // GenerateChallenge generates a new authentication challenge
@@ -80,9 +117,14 @@ func (cra *ChallengeResponseAuth) GenerateChallenge(ctx context.Context, clientI
 		MaxAttempts: cra.config.MaxAttempts,
 	}
 
-	cra.mu.Lock()
-	cra.challenges[challengeID] = challenge
-	cra.mu.Unlock()
+	if err := cra.store.Save(ctx, challenge); err != nil {
+		if errors.Is(err, ErrTooManyChallenges) {
+			cra.logger.Warn("Challenge rejected, store is full",
+				zap.String("client_id", clientID))
+		}
+		return nil, err
+	}
+	monitoring.AuthChallengesTotal.Inc()
 
 	cra.logger.Debug("Challenge generated",
 		zap.String("challenge_id", challengeID),
@@ -91,35 +133,36 @@ func (cra *ChallengeResponseAuth) GenerateChallenge(ctx context.Context, clientI
 	return challenge, nil
 }
 
+// attemptResult maps a ChallengeStore.Attempt error to its verification
+// metric label.
+func attemptResult(err error) string {
+	switch {
+	case errors.Is(err, ErrChallengeNotFound):
+		return "not_found"
+	case errors.Is(err, ErrChallengeUsed):
+		return "used"
+	case errors.Is(err, ErrChallengeExpired):
+		return "expired"
+	case errors.Is(err, ErrChallengeExhausted):
+		return "exhausted"
+	}
+	return "error"
+}
+
 // VerifyResponse verifies a challenge response
 func (cra *ChallengeResponseAuth) VerifyResponse(ctx context.Context, challengeID, response string, verifier ResponseVerifier) (bool, error) {
 	cra.logger.Debug("Verifying response",
 		zap.String("challenge_id", challengeID))
 
-	cra.mu.Lock()
-	defer cra.mu.Unlock()
-
-	challenge, exists := cra.challenges[challengeID]
-	if !exists {
-		return false, fmt.Errorf("challenge not found: %s", challengeID)
-	}
+	result := "error"
+	defer func() { monitoring.AuthVerificationsTotal.WithLabelValues(result).Inc() }()
 
-	if challenge.Used {
-		return false, fmt.Errorf("challenge already used: %s", challengeID)
-	}
-
-	if time.Now().After(challenge.ExpiresAt) {
-		delete(cra.challenges, challengeID)
-		return false, fmt.Errorf("challenge expired: %s", challengeID)
-	}
-
-	if challenge.Attempts >= challenge.MaxAttempts {
-		delete(cra.challenges, challengeID)
-		return false, fmt.Errorf("max attempts exceeded: %s", challengeID)
+	challenge, err := cra.store.Attempt(ctx, challengeID, time.Now())
+	if err != nil {
+		result = attemptResult(err)
+		return false, err
 	}
 
-	challenge.Attempts++
-
 	expectedResponse, err := verifier.ComputeResponse(challenge.Nonce)
 	if err != nil {
 		return false, fmt.Errorf("failed to compute expected response: %w", err)
@@ -129,10 +172,15 @@ func (cra *ChallengeResponseAuth) VerifyResponse(ctx context.Context, challengeI
 		cra.logger.Warn("Invalid response",
 			zap.String("challenge_id", challengeID),
 			zap.Int("attempt", challenge.Attempts))
+		result = "invalid"
 		return false, nil
 	}
 
-	challenge.Used = true
+	if err := cra.store.MarkUsed(ctx, challengeID); err != nil {
+		result = attemptResult(err)
+		return false, err
+	}
+	result = "success"
 
 	cra.logger.Debug("Response verified",
 		zap.String("challenge_id", challengeID))
@@ -146,30 +194,15 @@ func (cra *ChallengeResponseAuth) VerifySignature(ctx context.Context, challenge
 		zap.String("challenge_id", challengeID),
 		zap.String("public_key", publicKey))
 
-	cra.mu.Lock()
-	defer cra.mu.Unlock()
-
-	challenge, exists := cra.challenges[challengeID]
-	if !exists {
-		return false, fmt.Errorf("challenge not found: %s", challengeID)
-	}
-
-	if challenge.Used {
-		return false, fmt.Errorf("challenge already used: %s", challengeID)
-	}
-
-	if time.Now().After(challenge.ExpiresAt) {
-		delete(cra.challenges, challengeID)
-		return false, fmt.Errorf("challenge expired: %s", challengeID)
-	}
+	result := "error"
+	defer func() { monitoring.AuthVerificationsTotal.WithLabelValues(result).Inc() }()
 
-	if challenge.Attempts >= challenge.MaxAttempts {
-		delete(cra.challenges, challengeID)
-		return false, fmt.Errorf("max attempts exceeded: %s", challengeID)
+	challenge, err := cra.store.Attempt(ctx, challengeID, time.Now())
+	if err != nil {
+		result = attemptResult(err)
+		return false, err
 	}
 
-	challenge.Attempts++
-
 	valid, err := verifier.VerifySignature(publicKey, challenge.Nonce, signature)
 	if err != nil {
 		return false, fmt.Errorf("signature verification failed: %w", err)
@@ -179,10 +212,15 @@ func (cra *ChallengeResponseAuth) VerifySignature(ctx context.Context, challenge
 		cra.logger.Warn("Invalid signature",
 			zap.String("challenge_id", challengeID),
 			zap.Int("attempt", challenge.Attempts))
+		result = "invalid"
 		return false, nil
 	}
 
-	challenge.Used = true
+	if err := cra.store.MarkUsed(ctx, challengeID); err != nil {
+		result = attemptResult(err)
+		return false, err
+	}
+	result = "success"
 
 	cra.logger.Debug("Signature verified",
 		zap.String("challenge_id", challengeID))
@@ -192,32 +230,14 @@ func (cra *ChallengeResponseAuth) VerifySignature(ctx context.Context, challenge
 
 // GetChallenge retrieves a challenge by ID
 func (cra *ChallengeResponseAuth) GetChallenge(ctx context.Context, challengeID string) (*Challenge, error) {
-	cra.mu.RLock()
-	defer cra.mu.RUnlock()
-
-	challenge, exists := cra.challenges[challengeID]
-	if !exists {
-		return nil, fmt.Errorf("challenge not found: %s", challengeID)
-	}
-
-	return challenge, nil
+	return cra.store.Get(ctx, challengeID)
 }
 
 // CleanupExpiredChallenges removes expired challenges
 func (cra *ChallengeResponseAuth) CleanupExpiredChallenges(ctx context.Context) error {
 	cra.logger.Debug("Cleaning up expired challenges")
 
-	cra.mu.Lock()
-	defer cra.mu.Unlock()
-
-	now := time.Now()
-	for challengeID, challenge := range cra.challenges {
-		if now.After(challenge.ExpiresAt) || challenge.Used {
-			delete(cra.challenges, challengeID)
-		}
-	}
-
-	return nil
+	return cra.store.Cleanup(ctx, time.Now())
 }
 
 // ResponseVerifier defines the interface for verifying challenge responses
@@ -299,6 +319,7 @@ type Session struct {
 // SessionManager manages authenticated sessions
 type SessionManager struct {
 	sessions map[string]*Session
+	order    *lruIndex
 	mu       sync.RWMutex
 	logger   *zap.Logger
 	config   *SessionConfig
@@ -309,6 +330,11 @@ type SessionManager struct {
 type SessionConfig struct {
 	SessionTTL      time.Duration
 	CleanupInterval time.Duration
+	// MaxSessions caps live sessions; <= 0 uses DefaultMaxSessions.
+	MaxSessions int
+	// OverflowPolicy decides what happens when MaxSessions is reached.
+	// Empty evicts the least recently refreshed session.
+	OverflowPolicy storage.OverflowPolicy
 }
 
 // NewSessionManager creates a new session manager
@@ -322,6 +348,7 @@ func NewSessionManager(logger *zap.Logger, config *SessionConfig) *SessionManage
 
 	sm := &SessionManager{
 		sessions: make(map[string]*Session),
+		order:    newLRUIndex(),
 		logger:   logger,
 		config:   config,
 	}
@@ -352,8 +379,17 @@ func (sm *SessionManager) CreateSession(ctx context.Context, clientID, publicKey
 	}
 
 	sm.mu.Lock()
+	if err := sm.makeRoom(session.CreatedAt); err != nil {
+		sm.mu.Unlock()
+		sm.logger.Warn("Session rejected, store is full",
+			zap.String("client_id", clientID))
+		return nil, err
+	}
 	sm.sessions[sessionID] = session
+	sm.order.touch(sessionID)
 	sm.mu.Unlock()
+	monitoring.AuthSessionsCreatedTotal.Inc()
+	monitoring.AuthSessionsActive.Inc()
 
 	sm.logger.Debug("Session created",
 		zap.String("session_id", sessionID))
@@ -407,6 +443,7 @@ func (sm *SessionManager) RefreshSession(ctx context.Context, sessionID string)
 
 	session.ExpiresAt = time.Now().Add(sm.config.SessionTTL)
 	session.LastActivity = time.Now()
+	sm.order.touch(sessionID)
 
 	sm.logger.Debug("Session refreshed",
 		zap.String("session_id", sessionID))
@@ -422,7 +459,9 @@ func (sm *SessionManager) RevokeSession(ctx context.Context, sessionID string) e
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	delete(sm.sessions, sessionID)
+	if sm.deleteSession(sessionID) {
+		monitoring.AuthSessionsRevokedTotal.Inc()
+	}
 
 	sm.logger.Debug("Session revoked",
 		zap.String("session_id", sessionID))
@@ -461,9 +500,50 @@ func (sm *SessionManager) cleanupExpiredSessions() {
 	now := time.Now()
 	for sessionID, session := range sm.sessions {
 		if now.After(session.ExpiresAt) {
-			delete(sm.sessions, sessionID)
+			sm.deleteSession(sessionID)
+		}
+	}
+}
+
+// deleteSession removes a session and reports whether it existed. The
+// caller must hold sm.mu.
+func (sm *SessionManager) deleteSession(sessionID string) bool {
+	sm.order.remove(sessionID)
+	if _, ok := sm.sessions[sessionID]; !ok {
+		return false
+	}
+	delete(sm.sessions, sessionID)
+	monitoring.AuthSessionsActive.Dec()
+	return true
+}
+
+// makeRoom frees a slot for a new session, dropping expired sessions
+// before live ones. The caller must hold sm.mu.
+func (sm *SessionManager) makeRoom(now time.Time) error {
+	limit := sm.config.MaxSessions
+	if limit <= 0 {
+		limit = DefaultMaxSessions
+	}
+	policy := sm.config.OverflowPolicy
+	if policy == "" {
+		policy = storage.OverflowEvictOldest
+	}
+	for len(sm.sessions) >= limit {
+		id, ok := sm.order.oldest()
+		if !ok {
+			return nil
+		}
+		if s := sm.sessions[id]; s == nil || now.After(s.ExpiresAt) {
+			sm.deleteSession(id)
+			continue
 		}
+		monitoring.MemoryStoreEvictionsTotal.WithLabelValues("auth_session", string(policy)).Inc()
+		if policy == storage.OverflowReject {
+			return ErrTooManySessions
+		}
+		sm.deleteSession(id)
 	}
+	return nil
 }
 
 // generateSessionID generates a unique session ID
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/storage"
+)
+
+var (
+	// ErrChallengeNotFound is returned for unknown or already removed
+	// challenges.
+	ErrChallengeNotFound = storage.ErrChallengeNotFound
+	// ErrChallengeUsed is returned when a challenge was already answered.
+	ErrChallengeUsed = storage.ErrChallengeUsed
+	// ErrChallengeExpired is returned when a challenge outlived its TTL.
+	ErrChallengeExpired = storage.ErrChallengeExpired
+	// ErrChallengeExhausted is returned when a challenge ran out of attempts.
+	ErrChallengeExhausted = storage.ErrChallengeExhausted
+)
+
+// ChallengeStore holds pending challenges for ChallengeResponseAuth. A
+// shared store such as storage.RedisChallengeStore lets a challenge issued by one
+// replica be answered on another.
+type ChallengeStore interface {
+	// Save stores a new challenge until its ExpiresAt.
+	Save(ctx context.Context, challenge *Challenge) error
+	// Get returns a challenge, or ErrChallengeNotFound.
+	Get(ctx context.Context, challengeID string) (*Challenge, error)
+	// Attempt atomically checks that a challenge is unused, unexpired and
+	// under its attempt limit, counts one attempt and returns the updated
+	// challenge. Expired and exhausted challenges are removed.
+	Attempt(ctx context.Context, challengeID string, now time.Time) (*Challenge, error)
+	// MarkUsed atomically consumes a challenge. Of concurrent callers only
+	// one succeeds; the rest get ErrChallengeUsed.
+	MarkUsed(ctx context.Context, challengeID string) error
+	// Cleanup removes expired and used challenges. Stores with native
+	// expiry may do nothing.
+	Cleanup(ctx context.Context, now time.Time) error
+}
+
+// StorageChallengeStore keeps challenges in a storage.AttemptChallengeStore,
+// the same stores that hold the gateway's wallet login challenges.
+type StorageChallengeStore struct {
+	store storage.AttemptChallengeStore
+}
+
+// NewStorageChallengeStore returns a ChallengeStore backed by store.
+func NewStorageChallengeStore(store storage.AttemptChallengeStore) *StorageChallengeStore {
+	return &StorageChallengeStore{store: store}
+}
+
+// Save stores challenge. It returns ErrTooManyChallenges when the store is
+// full and its policy rejects.
+func (s *StorageChallengeStore) Save(ctx context.Context, challenge *Challenge) error {
+	return s.store.SaveChallenge(ctx, toWalletChallenge(challenge))
+}
+
+// Get returns the stored challenge.
+func (s *StorageChallengeStore) Get(ctx context.Context, challengeID string) (*Challenge, error) {
+	challenge, err := s.store.GetChallenge(ctx, challengeID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, challengeID)
+	}
+	return fromWalletChallenge(challenge), nil
+}
+
+// Attempt counts an attempt against the challenge.
+func (s *StorageChallengeStore) Attempt(ctx context.Context, challengeID string, now time.Time) (*Challenge, error) {
+	challenge, err := s.store.AttemptChallenge(ctx, challengeID, now)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, challengeID)
+	}
+	return fromWalletChallenge(challenge), nil
+}
+
+// MarkUsed consumes the challenge.
+func (s *StorageChallengeStore) MarkUsed(ctx context.Context, challengeID string) error {
+	if err := s.store.MarkChallengeUsed(ctx, challengeID, time.Now()); err != nil {
+		return fmt.Errorf("%w: %s", err, challengeID)
+	}
+	return nil
+}
+
+// Cleanup removes expired challenges from stores that sweep on demand.
+// Used challenges are reclaimed when the store needs their slot.
+func (s *StorageChallengeStore) Cleanup(ctx context.Context, now time.Time) error {
+	if sweeper, ok := s.store.(interface{ EvictExpired(time.Time) }); ok {
+		sweeper.EvictExpired(now)
+	}
+	return nil
+}
+
+// Close closes the underlying store when it holds resources.
+func (s *StorageChallengeStore) Close() error {
+	if closer, ok := s.store.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func toWalletChallenge(c *Challenge) *storage.WalletChallenge {
+	wc := &storage.WalletChallenge{
+		ID:          c.ID,
+		Nonce:       c.Nonce,
+		IssuedAt:    c.Timestamp,
+		ExpiresAt:   c.ExpiresAt,
+		Attempts:    c.Attempts,
+		MaxAttempts: c.MaxAttempts,
+	}
+	if c.Used {
+		wc.UsedAt = time.Now()
+	}
+	return wc
+}
+
+func fromWalletChallenge(wc *storage.WalletChallenge) *Challenge {
+	return &Challenge{
+		ID:          wc.ID,
+		Nonce:       wc.Nonce,
+		Timestamp:   wc.IssuedAt,
+		ExpiresAt:   wc.ExpiresAt,
+		Used:        !wc.UsedAt.IsZero(),
+		Attempts:    wc.Attempts,
+		MaxAttempts: wc.MaxAttempts,
+	}
+}
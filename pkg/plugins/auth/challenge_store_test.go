@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/rtcdance/streamgate/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestRedisChallengeStore(t *testing.T, ttl time.Duration) (*StorageChallengeStore, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return NewStorageChallengeStore(storage.NewRedisChallengeStoreWithClient(client, ttl)), mr
+}
+
+func TestStorageChallengeStore_RedisSharedAcrossReplicas(t *testing.T) {
+	cfg := DefaultAuthConfig()
+	store, mr := newTestRedisChallengeStore(t, cfg.ChallengeTTL)
+	cfg.Store = store
+	issuer := NewChallengeResponseAuth(zap.NewNop(), cfg)
+	other := NewChallengeResponseAuth(zap.NewNop(), cfg)
+	ctx := context.Background()
+	verifier := NewSHA256Verifier("secret")
+
+	challenge, err := issuer.GenerateChallenge(ctx, "client-1")
+	require.NoError(t, err)
+	assert.True(t, mr.Exists(challenge.ID))
+	assert.InDelta(t, cfg.ChallengeTTL.Seconds(), mr.TTL(challenge.ID).Seconds(), 1)
+
+	valid, err := other.VerifyResponse(ctx, challenge.ID, "wrong", verifier)
+	require.NoError(t, err)
+	assert.False(t, valid)
+	got, err := issuer.GetChallenge(ctx, challenge.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.Attempts)
+	assert.Equal(t, challenge.Nonce, got.Nonce)
+
+	response, err := verifier.ComputeResponse(challenge.Nonce)
+	require.NoError(t, err)
+	valid, err = other.VerifyResponse(ctx, challenge.ID, response, verifier)
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	_, err = issuer.VerifyResponse(ctx, challenge.ID, response, verifier)
+	assert.ErrorIs(t, err, ErrChallengeUsed)
+}
+
+func TestStorageChallengeStore_RedisUseOnceUnderConcurrency(t *testing.T) {
+	cfg := DefaultAuthConfig()
+	store, _ := newTestRedisChallengeStore(t, cfg.ChallengeTTL)
+	cfg.Store = store
+	cfg.MaxAttempts = 100
+	cra := NewChallengeResponseAuth(zap.NewNop(), cfg)
+	ctx := context.Background()
+	verifier := NewSHA256Verifier("secret")
+
+	challenge, err := cra.GenerateChallenge(ctx, "client-1")
+	require.NoError(t, err)
+	response, err := verifier.ComputeResponse(challenge.Nonce)
+	require.NoError(t, err)
+
+	var successes atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if valid, err := cra.VerifyResponse(ctx, challenge.ID, response, verifier); err == nil && valid {
+				successes.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), successes.Load())
+}
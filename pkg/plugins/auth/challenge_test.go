@@ -8,6 +8,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -204,7 +207,7 @@ func TestChallengeResponseAuth_CleanupExpiredChallenges(t *testing.T) {
 	err = cra.CleanupExpiredChallenges(ctx)
 	require.NoError(t, err)
 
-	assert.Len(t, cra.challenges, 0)
+	assert.Zero(t, cra.ownStore.store.(*storage.MemoryChallengeStore).Len())
 }
 
 func TestSHA256Verifier(t *testing.T) {
@@ -355,3 +358,190 @@ func (m *mockSignatureVerifier) VerifySignature(publicKey, message, signature st
 	}
 	return m.valid, nil
 }
+
+func TestChallengeResponseAuth_CapEvictsOldest(t *testing.T) {
+	cra := NewChallengeResponseAuth(zap.NewNop(), &AuthConfig{ChallengeTTL: 5 * time.Minute, MaxAttempts: 3, MaxChallenges: 2})
+	ctx := context.Background()
+
+	first, err := cra.GenerateChallenge(ctx, "client-1")
+	require.NoError(t, err)
+	second, err := cra.GenerateChallenge(ctx, "client-2")
+	require.NoError(t, err)
+	third, err := cra.GenerateChallenge(ctx, "client-3")
+	require.NoError(t, err)
+
+	_, err = cra.GetChallenge(ctx, first.ID)
+	assert.Error(t, err, "the oldest challenge is evicted")
+	for _, c := range []*Challenge{second, third} {
+		_, err = cra.GetChallenge(ctx, c.ID)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 2, cra.ownStore.store.(*storage.MemoryChallengeStore).Len())
+}
+
+func TestChallengeResponseAuth_CapRejectsNew(t *testing.T) {
+	cra := NewChallengeResponseAuth(zap.NewNop(), &AuthConfig{
+		ChallengeTTL:   5 * time.Minute,
+		MaxAttempts:    3,
+		MaxChallenges:  1,
+		OverflowPolicy: storage.OverflowReject,
+	})
+	ctx := context.Background()
+
+	first, err := cra.GenerateChallenge(ctx, "client-1")
+	require.NoError(t, err)
+	_, err = cra.GenerateChallenge(ctx, "client-2")
+	assert.ErrorIs(t, err, ErrTooManyChallenges)
+
+	_, err = cra.GetChallenge(ctx, first.ID)
+	assert.NoError(t, err, "the existing challenge is kept")
+
+	// A used challenge no longer holds a slot.
+	require.NoError(t, cra.store.MarkUsed(ctx, first.ID))
+	_, err = cra.GenerateChallenge(ctx, "client-2")
+	assert.NoError(t, err)
+}
+
+func TestSessionManager_CapEvictsLeastRecentlyRefreshed(t *testing.T) {
+	sm := NewSessionManager(zap.NewNop(), &SessionConfig{SessionTTL: time.Hour, CleanupInterval: time.Hour, MaxSessions: 2})
+	defer sm.Close()
+	ctx := context.Background()
+
+	a, err := sm.CreateSession(ctx, "client-a", "pk-a")
+	require.NoError(t, err)
+	b, err := sm.CreateSession(ctx, "client-b", "pk-b")
+	require.NoError(t, err)
+	require.NoError(t, sm.RefreshSession(ctx, a.ID))
+
+	c, err := sm.CreateSession(ctx, "client-c", "pk-c")
+	require.NoError(t, err)
+
+	_, err = sm.GetSession(ctx, b.ID)
+	assert.Error(t, err, "b was refreshed least recently")
+	for _, s := range []*Session{a, c} {
+		_, err = sm.GetSession(ctx, s.ID)
+		assert.NoError(t, err)
+	}
+}
+
+func TestSessionManager_CapRejectsNew(t *testing.T) {
+	sm := NewSessionManager(zap.NewNop(), &SessionConfig{
+		SessionTTL:      time.Hour,
+		CleanupInterval: time.Hour,
+		MaxSessions:     1,
+		OverflowPolicy:  storage.OverflowReject,
+	})
+	defer sm.Close()
+	ctx := context.Background()
+
+	a, err := sm.CreateSession(ctx, "client-a", "pk-a")
+	require.NoError(t, err)
+	_, err = sm.CreateSession(ctx, "client-b", "pk-b")
+	assert.ErrorIs(t, err, ErrTooManySessions)
+
+	require.NoError(t, sm.RevokeSession(ctx, a.ID))
+	_, err = sm.CreateSession(ctx, "client-b", "pk-b")
+	assert.NoError(t, err, "revoking frees the slot")
+}
+
+// authMetric reads a counter or gauge from the default registry, filtered
+// by its "result" label when result is non-empty.
+func authMetric(t *testing.T, name, result string) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, mf := range families {
+		if mf.GetName() != name {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			if result != "" {
+				matched := false
+				for _, l := range m.GetLabel() {
+					if l.GetName() == "result" && l.GetValue() == result {
+						matched = true
+					}
+				}
+				if !matched {
+					continue
+				}
+			}
+			if m.GetGauge() != nil {
+				return m.GetGauge().GetValue()
+			}
+			return m.GetCounter().GetValue()
+		}
+	}
+	return 0
+}
+
+func TestChallengeResponseAuth_Metrics(t *testing.T) {
+	cra := NewChallengeResponseAuth(zap.NewNop(), &AuthConfig{ChallengeTTL: 5 * time.Minute, MaxAttempts: 1})
+	verifier := NewSHA256Verifier("secret")
+	ctx := context.Background()
+
+	issued := authMetric(t, "streamgate_auth_challenges_total", "")
+	success := authMetric(t, "streamgate_auth_verifications_total", "success")
+	invalid := authMetric(t, "streamgate_auth_verifications_total", "invalid")
+	used := authMetric(t, "streamgate_auth_verifications_total", "used")
+	exhausted := authMetric(t, "streamgate_auth_verifications_total", "exhausted")
+	notFound := authMetric(t, "streamgate_auth_verifications_total", "not_found")
+
+	good, err := cra.GenerateChallenge(ctx, "client-1")
+	require.NoError(t, err)
+	bad, err := cra.GenerateChallenge(ctx, "client-2")
+	require.NoError(t, err)
+	assert.Equal(t, issued+2, authMetric(t, "streamgate_auth_challenges_total", ""))
+
+	expected, err := verifier.ComputeResponse(good.Nonce)
+	require.NoError(t, err)
+	ok, err := cra.VerifyResponse(ctx, good.ID, expected, verifier)
+	require.NoError(t, err)
+	require.True(t, ok)
+	_, err = cra.VerifyResponse(ctx, good.ID, expected, verifier)
+	require.Error(t, err)
+
+	ok, err = cra.VerifyResponse(ctx, bad.ID, "wrong", verifier)
+	require.NoError(t, err)
+	require.False(t, ok)
+	_, err = cra.VerifyResponse(ctx, bad.ID, "wrong", verifier)
+	require.Error(t, err)
+
+	_, err = cra.VerifyResponse(ctx, "missing", expected, verifier)
+	require.Error(t, err)
+
+	assert.Equal(t, success+1, authMetric(t, "streamgate_auth_verifications_total", "success"))
+	assert.Equal(t, invalid+1, authMetric(t, "streamgate_auth_verifications_total", "invalid"))
+	assert.Equal(t, used+1, authMetric(t, "streamgate_auth_verifications_total", "used"))
+	assert.Equal(t, exhausted+1, authMetric(t, "streamgate_auth_verifications_total", "exhausted"))
+	assert.Equal(t, notFound+1, authMetric(t, "streamgate_auth_verifications_total", "not_found"))
+}
+
+func TestSessionManager_Metrics(t *testing.T) {
+	sm := NewSessionManager(zap.NewNop(), &SessionConfig{SessionTTL: time.Hour, CleanupInterval: time.Hour})
+	defer sm.Close()
+	ctx := context.Background()
+
+	active := authMetric(t, "streamgate_auth_sessions_active", "")
+	created := authMetric(t, "streamgate_auth_sessions_created_total", "")
+	revoked := authMetric(t, "streamgate_auth_sessions_revoked_total", "")
+
+	a, err := sm.CreateSession(ctx, "client-a", "pk-a")
+	require.NoError(t, err)
+	b, err := sm.CreateSession(ctx, "client-b", "pk-b")
+	require.NoError(t, err)
+	assert.Equal(t, created+2, authMetric(t, "streamgate_auth_sessions_created_total", ""))
+	assert.Equal(t, active+2, authMetric(t, "streamgate_auth_sessions_active", ""))
+
+	require.NoError(t, sm.RevokeSession(ctx, a.ID))
+	require.NoError(t, sm.RevokeSession(ctx, a.ID))
+	assert.Equal(t, revoked+1, authMetric(t, "streamgate_auth_sessions_revoked_total", ""), "revoking a missing session is not counted")
+	assert.Equal(t, active+1, authMetric(t, "streamgate_auth_sessions_active", ""))
+
+	sm.mu.Lock()
+	sm.sessions[b.ID].ExpiresAt = time.Now().Add(-time.Second)
+	sm.mu.Unlock()
+	sm.cleanupExpiredSessions()
+	assert.Equal(t, active, authMetric(t, "streamgate_auth_sessions_active", ""), "expired sessions leave the gauge")
+	assert.Equal(t, revoked+1, authMetric(t, "streamgate_auth_sessions_revoked_total", ""), "expiry is not a revocation")
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/rtcdance/streamgate/pkg/core"
@@ -43,11 +44,10 @@ func (h *AuthHandler) HealthHandler(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
 }
 
-// ReadyHandler handles readiness check requests
+// ReadyHandler reports the kernel's health checks, including the
+// dependencies this plugin registered.
 func (h *AuthHandler) ReadyHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
+	h.kernel.ReadyHandler(w, r)
 }
 
 // VerifySignatureHandler handles signature verification requests
@@ -67,6 +67,8 @@ func (h *AuthHandler) VerifySignatureHandler(w http.ResponseWriter, r *http.Requ
 		Address   string `json:"address"`
 		Message   string `json:"message"`
 		Signature string `json:"signature"`
+		// ChainType is "evm" (default) or "solana".
+		ChainType string `json:"chain_type"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -77,7 +79,13 @@ func (h *AuthHandler) VerifySignatureHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	valid, err := h.verifier.VerifySignature(ctx, req.Address, req.Message, req.Signature)
+	valid, err := h.verifier.VerifySignatureForChain(ctx, req.ChainType, req.Address, req.Message, req.Signature)
+	if errors.Is(err, ErrUnsupportedChainType) {
+		h.metricsCollector.IncrementCounter("verify_signature_decode_error", map[string]string{})
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "unsupported chain_type"})
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to verify signature", zap.Error(err))
 		h.metricsCollector.IncrementCounter("verify_signature_failed", map[string]string{})
@@ -3,7 +3,9 @@ package auth
 import (
 	"bytes"
 	"context"
+	"crypto/ed25519"
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
@@ -14,6 +16,8 @@ import (
 
 	"github.com/rtcdance/streamgate/pkg/core"
 	"github.com/rtcdance/streamgate/pkg/core/config"
+
+	"github.com/gagliardetto/solana-go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -111,6 +115,46 @@ func TestAuthHandler_VerifySignatureHandler_WithMockVerifier(t *testing.T) {
 	assert.Equal(t, true, resp["valid"])
 }
 
+func TestAuthHandler_VerifySignatureHandler_ChainType(t *testing.T) {
+	handler := newTestAuthHandler(t)
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	address := solana.PublicKeyFromBytes(pub).String()
+	message := "Sign in to StreamGate"
+	signature := solana.SignatureFromBytes(ed25519.Sign(priv, []byte(message))).String()
+
+	for name, tc := range map[string]struct {
+		chainType string
+		message   string
+		status    int
+		valid     bool
+	}{
+		"solana":        {ChainTypeSolana, message, http.StatusOK, true},
+		"solana upper":  {"SOLANA", message, http.StatusOK, true},
+		"wrong message": {ChainTypeSolana, "something else", http.StatusOK, false},
+		"unknown chain": {"bitcoin", message, http.StatusBadRequest, false},
+		"evm default":   {"", message, http.StatusInternalServerError, false},
+	} {
+		body, _ := json.Marshal(map[string]string{
+			"address":    address,
+			"message":    tc.message,
+			"signature":  signature,
+			"chain_type": tc.chainType,
+		})
+		req := httptest.NewRequest(http.MethodPost, "/verify-signature", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+
+		handler.VerifySignatureHandler(rec, req)
+
+		assert.Equal(t, tc.status, rec.Code, name)
+		if tc.status == http.StatusOK {
+			var resp map[string]interface{}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, tc.valid, resp["valid"], name)
+		}
+	}
+}
+
 func TestAuthHandler_VerifySignatureHandler_VerifierError(t *testing.T) {
 	kernel, err := core.NewMicrokernel(&config.Config{Mode: "monolith"}, zap.NewNop())
 	require.NoError(t, err)
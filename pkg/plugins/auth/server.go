@@ -5,11 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/rtcdance/streamgate/pkg/core"
 	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/rtcdance/streamgate/pkg/health"
+	"github.com/rtcdance/streamgate/pkg/storage"
+	"github.com/rtcdance/streamgate/pkg/web3"
 
+	"github.com/go-redis/redis/v8"
 	"go.uber.org/zap"
 )
 
@@ -35,22 +40,67 @@ type AuthServer struct {
 	kernel   *core.Microkernel
 	server   *http.Server
 	verifier *AuthVerifier
+	redis    *redis.Client
 }
 
 // NewAuthServer creates a new auth server
 func NewAuthServer(cfg *config.Config, logger *zap.Logger, kernel *core.Microkernel) (*AuthServer, error) {
 	verifier := NewAuthVerifier(logger)
 
+	// Challenges go to Redis when it is reachable so any replica can
+	// verify a challenge another one issued.
+	redisClient := connectChallengeRedis(cfg, logger)
+	if redisClient != nil {
+		authConfig := DefaultAuthConfig()
+		authConfig.Store = NewStorageChallengeStore(
+			storage.NewRedisChallengeStoreWithClient(redisClient, authConfig.ChallengeTTL))
+		_ = verifier.challengeAuth.Close()
+		verifier.challengeAuth = NewChallengeResponseAuth(logger, authConfig)
+	}
+
 	return &AuthServer{
 		config:   cfg,
 		logger:   logger,
 		kernel:   kernel,
 		verifier: verifier,
+		redis:    redisClient,
 	}, nil
 }
 
+// connectChallengeRedis returns a client for cfg.Redis, or nil when Redis
+// is not configured or unreachable.
+func connectChallengeRedis(cfg *config.Config, logger *zap.Logger) *redis.Client {
+	if cfg.Redis.Host == "" {
+		return nil
+	}
+	addr := fmt.Sprintf("%s:%d", cfg.Redis.Host, cfg.Redis.Port)
+	client := redis.NewClient(&redis.Options{
+		Addr:         addr,
+		Password:     cfg.Redis.Password,
+		DB:           cfg.Redis.DB,
+		PoolSize:     cfg.Redis.PoolSize,
+		DialTimeout:  5 * time.Second,
+		ReadTimeout:  3 * time.Second,
+		WriteTimeout: 3 * time.Second,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		logger.Warn("Redis unavailable, keeping auth challenges in memory",
+			zap.String("addr", addr), zap.Error(err))
+		_ = client.Close()
+		return nil
+	}
+	return client
+}
+
 // Start starts the auth server
 func (s *AuthServer) Start(ctx context.Context) error {
+	if s.kernel != nil && s.redis != nil {
+		s.kernel.HealthChecks().RegisterCheck(redisHealthCheck, func(ctx context.Context) error {
+			return s.redis.Ping(ctx).Err()
+		}, health.ProbeOptions()...)
+	}
 	handler := NewAuthHandler(s.verifier, s.logger, s.kernel)
 
 	mux := http.NewServeMux()
@@ -94,10 +144,22 @@ func (s *AuthServer) Stop(ctx context.Context) error {
 			return err
 		}
 	}
+	if s.kernel != nil {
+		s.kernel.HealthChecks().UnregisterCheck(redisHealthCheck)
+	}
+	if s.redis != nil {
+		_ = s.redis.Close()
+	}
+	if s.verifier != nil {
+		_ = s.verifier.challengeAuth.Close()
+	}
 
 	return nil
 }
 
+// redisHealthCheck is the kernel health check pinging the challenge store.
+const redisHealthCheck = "auth.redis"
+
 // Health checks the health of the auth server
 func (s *AuthServer) Health(ctx context.Context) error {
 	if s.server == nil {
@@ -107,22 +169,35 @@ func (s *AuthServer) Health(ctx context.Context) error {
 	return nil
 }
 
+// Chain types accepted by AuthVerifier.VerifySignatureForChain.
+const (
+	ChainTypeEVM    = "evm"
+	ChainTypeSolana = "solana"
+)
+
+// ErrUnsupportedChainType is returned for chain types other than
+// ChainTypeEVM and ChainTypeSolana.
+var ErrUnsupportedChainType = errors.New("unsupported chain type")
+
 // AuthVerifier handles authentication verification.
 // When injected verifier interfaces are set, it delegates to real implementations.
-// When absent, methods return explicit "not configured" errors.
+// When absent, methods return explicit "not configured" errors. Solana
+// signatures need no backend and are always verified with ed25519.
 type AuthVerifier struct {
-	logger        *zap.Logger
-	sigVerifier   WalletSignatureVerifier
-	nftVerifier   NFTOwnershipVerifier
-	jwtVerifier   JWTTokenVerifier
-	challengeAuth *ChallengeResponseAuth
+	logger         *zap.Logger
+	sigVerifier    WalletSignatureVerifier
+	solanaVerifier WalletSignatureVerifier
+	nftVerifier    NFTOwnershipVerifier
+	jwtVerifier    JWTTokenVerifier
+	challengeAuth  *ChallengeResponseAuth
 }
 
 // NewAuthVerifier creates a new auth verifier without backend verifiers.
 func NewAuthVerifier(logger *zap.Logger) *AuthVerifier {
 	return &AuthVerifier{
-		logger:        logger,
-		challengeAuth: NewChallengeResponseAuth(logger, nil),
+		logger:         logger,
+		solanaVerifier: web3.NewEd25519Verifier(logger),
+		challengeAuth:  NewChallengeResponseAuth(logger, nil),
 	}
 }
 
@@ -134,11 +209,12 @@ func NewAuthVerifierWithVerifiers(
 	jwtVerifier JWTTokenVerifier,
 ) *AuthVerifier {
 	return &AuthVerifier{
-		logger:        logger,
-		sigVerifier:   sigVerifier,
-		nftVerifier:   nftVerifier,
-		jwtVerifier:   jwtVerifier,
-		challengeAuth: NewChallengeResponseAuth(logger, nil),
+		logger:         logger,
+		sigVerifier:    sigVerifier,
+		solanaVerifier: web3.NewEd25519Verifier(logger),
+		nftVerifier:    nftVerifier,
+		jwtVerifier:    jwtVerifier,
+		challengeAuth:  NewChallengeResponseAuth(logger, nil),
 	}
 }
 
@@ -151,6 +227,20 @@ func (v *AuthVerifier) VerifySignature(ctx context.Context, address, message, si
 	return v.sigVerifier.VerifySignature(ctx, address, message, signature)
 }
 
+// VerifySignatureForChain verifies a wallet signature on chainType. EVM
+// signatures go to the injected WalletSignatureVerifier; Solana ones are
+// base58 ed25519 signatures of the raw message, as Phantom signMessage
+// produces. An empty chainType is EVM.
+func (v *AuthVerifier) VerifySignatureForChain(ctx context.Context, chainType, address, message, signature string) (bool, error) {
+	switch strings.ToLower(chainType) {
+	case "", ChainTypeEVM:
+		return v.VerifySignature(ctx, address, message, signature)
+	case ChainTypeSolana:
+		return v.solanaVerifier.VerifySignature(ctx, address, message, signature)
+	}
+	return false, fmt.Errorf("%w: %q", ErrUnsupportedChainType, chainType)
+}
+
 // VerifyNFT verifies NFT ownership.
 // Returns an error if no NFTOwnershipVerifier is configured.
 func (v *AuthVerifier) VerifyNFT(ctx context.Context, address, contractAddress, tokenID string) (bool, error) {
@@ -44,11 +44,10 @@ func (h *CacheHandler) HealthHandler(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
 }
 
-// ReadyHandler handles readiness check requests
+// ReadyHandler reports the kernel's health checks, including the
+// dependencies this plugin registered.
 func (h *CacheHandler) ReadyHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
+	h.kernel.ReadyHandler(w, r)
 }
 
 // GetHandler handles cache get requests
@@ -0,0 +1,69 @@
+package keys
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"go.uber.org/zap"
+)
+
+// DeliveryPath is where the streaming service serves content keys.
+const DeliveryPath = "/api/v1/stream/key"
+
+// DeliveryURI is the key URI for contentID's EXT-X-KEY tags.
+func DeliveryURI(contentID string) string {
+	return DeliveryPath + "?content_id=" + url.QueryEscape(contentID)
+}
+
+// KeyHandler delivers content keys to players. It does no authorization
+// of its own: the streaming service mounts it behind the same auth and NFT
+// gate as playlists and segments, so only entitled sessions get a key.
+type KeyHandler struct {
+	store  *KeyStore
+	logger *zap.Logger
+}
+
+// NewKeyHandler creates a key delivery handler over store.
+func NewKeyHandler(store *KeyStore, logger *zap.Logger) *KeyHandler {
+	return &KeyHandler{store: store, logger: logger}
+}
+
+// ServeKey answers GET ?content_id=... with the raw 16-byte key, as
+// EXT-X-KEY URIs expect.
+func (h *KeyHandler) ServeKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeKeyError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	contentID := r.URL.Query().Get("content_id")
+	if contentID == "" {
+		writeKeyError(w, http.StatusBadRequest, "missing content_id")
+		return
+	}
+
+	key, err := h.store.Get(contentID)
+	switch {
+	case errors.Is(err, ErrKeyNotFound), errors.Is(err, ErrInvalidContentID):
+		writeKeyError(w, http.StatusNotFound, "content key not found")
+		return
+	case err != nil:
+		h.logger.Error("Failed to read content key", zap.String("content_id", contentID), zap.Error(err))
+		writeKeyError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	// Keys are per-viewer authorized, so no shared cache may keep them.
+	w.Header().Set("Cache-Control", "no-store, private")
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(key.Key)
+}
+
+func writeKeyError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
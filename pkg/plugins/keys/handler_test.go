@@ -0,0 +1,44 @@
+package keys
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestKeyHandler_ServeKey(t *testing.T) {
+	store := newTestStore(t)
+	key, err := store.Issue("content-1")
+	require.NoError(t, err)
+	h := NewKeyHandler(store, zap.NewNop())
+
+	w := httptest.NewRecorder()
+	h.ServeKey(w, httptest.NewRequest(http.MethodGet, DeliveryURI("content-1"), nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, key.Key, w.Body.Bytes())
+	assert.Equal(t, "application/octet-stream", w.Header().Get("Content-Type"))
+	assert.Equal(t, "no-store, private", w.Header().Get("Cache-Control"))
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		status int
+	}{
+		{"unknown content", http.MethodGet, DeliveryURI("content-2"), http.StatusNotFound},
+		{"invalid content ID", http.MethodGet, DeliveryURI("../content-1"), http.StatusNotFound},
+		{"missing content ID", http.MethodGet, DeliveryPath, http.StatusBadRequest},
+		{"wrong method", http.MethodPost, DeliveryURI("content-1"), http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeKey(w, httptest.NewRequest(tt.method, tt.target, nil))
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package keys
+
+import (
+	"github.com/rtcdance/streamgate/pkg/core"
+	"github.com/rtcdance/streamgate/pkg/core/config"
+
+	"go.uber.org/zap"
+)
+
+func init() {
+	core.RegisterPluginFactory("keys", NewKeysPlugin)
+}
+
+// NewKeysPlugin creates the key service plugin, which manages the
+// per-content keys HLS segments are encrypted with.
+func NewKeysPlugin(cfg *config.Config, logger *zap.Logger) core.Plugin {
+	return core.NewGenericPlugin("keys", cfg, logger, func(kernel *core.Microkernel) (core.ServerLifecycle, error) {
+		return NewKeyServer(cfg, logger, kernel)
+	})
+}
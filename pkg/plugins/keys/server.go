@@ -0,0 +1,64 @@
+package keys
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rtcdance/streamgate/pkg/core"
+	"github.com/rtcdance/streamgate/pkg/core/config"
+
+	"go.uber.org/zap"
+)
+
+// KeyServer owns the content key store. It has no listener of its own:
+// the transcoder issues keys through the store and the streaming service
+// delivers them behind its NFT gate.
+type KeyServer struct {
+	config *config.Config
+	logger *zap.Logger
+	kernel *core.Microkernel
+	store  *KeyStore
+}
+
+// NewKeyServer creates a key server. The store is only opened when
+// encryption is enabled.
+func NewKeyServer(cfg *config.Config, logger *zap.Logger, kernel *core.Microkernel) (*KeyServer, error) {
+	s := &KeyServer{config: cfg, logger: logger, kernel: kernel}
+	if !cfg.Encryption.Enabled {
+		return s, nil
+	}
+	store, err := NewKeyStoreFromConfig(cfg.Encryption)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open key store: %w", err)
+	}
+	s.store = store
+	return s, nil
+}
+
+// Start starts the key server
+func (s *KeyServer) Start(ctx context.Context) error {
+	if s.store == nil {
+		s.logger.Info("Content encryption disabled, key service idle")
+		return nil
+	}
+	s.logger.Info("Key service ready", zap.String("key_dir", s.config.Encryption.KeyDir))
+	return nil
+}
+
+// Stop stops the key server
+func (s *KeyServer) Stop(ctx context.Context) error {
+	return nil
+}
+
+// Health checks the key store
+func (s *KeyServer) Health(ctx context.Context) error {
+	if s.store == nil {
+		return nil
+	}
+	return s.store.Health()
+}
+
+// Store returns the key store, or nil when encryption is disabled.
+func (s *KeyServer) Store() *KeyStore {
+	return s.store
+}
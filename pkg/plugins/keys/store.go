@@ -0,0 +1,246 @@
+package keys
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+)
+
+// KeySize is the length of an AES-128 content key and of its IV.
+const KeySize = 16
+
+var (
+	// ErrKeyNotFound is returned for content that has no key, e.g. because
+	// it was transcoded before encryption was enabled.
+	ErrKeyNotFound = errors.New("content key not found")
+	// ErrInvalidContentID is returned for IDs that cannot name a key file.
+	ErrInvalidContentID = errors.New("invalid content ID")
+)
+
+// ContentKey is the AES-128 key and IV a content's HLS segments are
+// encrypted with.
+type ContentKey struct {
+	ContentID string `json:"content_id"`
+	Key       []byte `json:"key"`
+	IV        []byte `json:"iv"`
+	// KeyID identifies DRM keys to license servers (the CENC KID). It is
+	// empty for AES-128 keys.
+	KeyID     []byte    `json:"key_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IVHex renders the IV as the EXT-X-KEY IV attribute.
+func (k *ContentKey) IVHex() string {
+	return "0x" + hex.EncodeToString(k.IV)
+}
+
+// keyKind separates a content's AES-128 key from its DRM key. They are
+// stored apart so the AES-128 delivery endpoint can never hand out the key
+// a DRM license protects.
+type keyKind string
+
+const (
+	kindAES128 keyKind = ""
+	kindDRM    keyKind = ".drm"
+)
+
+// KeyStore keeps one key per content in a directory, each sealed with a
+// master key (AES-256-GCM) so a copy of the directory alone does not
+// expose them. Every service that encrypts or serves segments opens the
+// same directory.
+type KeyStore struct {
+	dir  string
+	aead cipher.AEAD
+}
+
+// NewKeyStore opens the store in dir, creating it if needed. masterKey
+// must be 32 bytes.
+func NewKeyStore(dir string, masterKey []byte) (*KeyStore, error) {
+	if len(masterKey) != 32 {
+		return nil, fmt.Errorf("master key must be 32 bytes, got %d", len(masterKey))
+	}
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create key directory: %w", err)
+	}
+	return &KeyStore{dir: dir, aead: aead}, nil
+}
+
+// NewKeyStoreFromConfig opens the store configured in cfg.
+func NewKeyStoreFromConfig(cfg config.EncryptionConfig) (*KeyStore, error) {
+	masterKey, err := hex.DecodeString(cfg.MasterKey)
+	if err != nil {
+		return nil, fmt.Errorf("encryption.master_key: %w", err)
+	}
+	return NewKeyStore(cfg.KeyDir, masterKey)
+}
+
+// Get returns the content's key.
+func (s *KeyStore) Get(contentID string) (*ContentKey, error) {
+	return s.get(contentID, kindAES128)
+}
+
+// Issue returns the content's key, generating it on first use. Callers
+// racing to issue the same content's key, even from other processes, all
+// get the key that was stored first.
+func (s *KeyStore) Issue(contentID string) (*ContentKey, error) {
+	return s.issue(contentID, kindAES128)
+}
+
+// GetDRM returns the content's DRM key.
+func (s *KeyStore) GetDRM(contentID string) (*ContentKey, error) {
+	return s.get(contentID, kindDRM)
+}
+
+// IssueDRM returns the content's DRM key, generating it and its key ID on
+// first use like Issue.
+func (s *KeyStore) IssueDRM(contentID string) (*ContentKey, error) {
+	return s.issue(contentID, kindDRM)
+}
+
+func (s *KeyStore) get(contentID string, kind keyKind) (*ContentKey, error) {
+	if !validContentID(contentID) {
+		return nil, ErrInvalidContentID
+	}
+	sealed, err := os.ReadFile(s.path(contentID, kind))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read content key: %w", err)
+	}
+	return s.open(contentID, kind, sealed)
+}
+
+func (s *KeyStore) issue(contentID string, kind keyKind) (*ContentKey, error) {
+	key, err := s.get(contentID, kind)
+	if !errors.Is(err, ErrKeyNotFound) {
+		return key, err
+	}
+
+	key = &ContentKey{
+		ContentID: contentID,
+		Key:       make([]byte, KeySize),
+		IV:        make([]byte, KeySize),
+		CreatedAt: time.Now().UTC(),
+	}
+	if _, err := io.ReadFull(rand.Reader, key.Key); err != nil {
+		return nil, fmt.Errorf("failed to generate content key: %w", err)
+	}
+	if _, err := io.ReadFull(rand.Reader, key.IV); err != nil {
+		return nil, fmt.Errorf("failed to generate content IV: %w", err)
+	}
+	if kind == kindDRM {
+		key.KeyID = make([]byte, KeySize)
+		if _, err := io.ReadFull(rand.Reader, key.KeyID); err != nil {
+			return nil, fmt.Errorf("failed to generate key ID: %w", err)
+		}
+	}
+	sealed, err := s.seal(key, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	// Write to a temp file and link it into place: the link fails if
+	// another issuer got there first, and readers never see a partial key.
+	tmp, err := os.CreateTemp(s.dir, ".issue-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to store content key: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(sealed); err != nil {
+		_ = tmp.Close()
+		return nil, fmt.Errorf("failed to store content key: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to store content key: %w", err)
+	}
+	if err := os.Link(tmp.Name(), s.path(contentID, kind)); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return s.get(contentID, kind)
+		}
+		return nil, fmt.Errorf("failed to store content key: %w", err)
+	}
+	return key, nil
+}
+
+// Health reports whether the key directory is usable.
+func (s *KeyStore) Health() error {
+	info, err := os.Stat(s.dir)
+	if err != nil {
+		return fmt.Errorf("key directory unavailable: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("key directory %s is not a directory", s.dir)
+	}
+	return nil
+}
+
+func (s *KeyStore) path(contentID string, kind keyKind) string {
+	return filepath.Join(s.dir, contentID+string(kind)+".key")
+}
+
+// seal encrypts key under the master key, bound to its content ID and kind
+// so a key file cannot be renamed to unlock other content, or to pass a
+// DRM key off as an AES-128 one.
+func (s *KeyStore) seal(key *ContentKey, kind keyKind) ([]byte, error) {
+	plain, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to seal content key: %w", err)
+	}
+	return s.aead.Seal(nonce, nonce, plain, []byte(key.ContentID+string(kind))), nil
+}
+
+func (s *KeyStore) open(contentID string, kind keyKind, sealed []byte) (*ContentKey, error) {
+	n := s.aead.NonceSize()
+	if len(sealed) < n {
+		return nil, fmt.Errorf("content key for %s is corrupt", contentID)
+	}
+	plain, err := s.aead.Open(nil, sealed[:n], sealed[n:], []byte(contentID+string(kind)))
+	if err != nil {
+		return nil, fmt.Errorf("content key for %s cannot be unsealed: %w", contentID, err)
+	}
+	var key ContentKey
+	if err := json.Unmarshal(plain, &key); err != nil {
+		return nil, fmt.Errorf("content key for %s is corrupt: %w", contentID, err)
+	}
+	if len(key.Key) != KeySize || len(key.IV) != KeySize || (kind == kindDRM && len(key.KeyID) != KeySize) {
+		return nil, fmt.Errorf("content key for %s is corrupt", contentID)
+	}
+	return &key, nil
+}
+
+// validContentID accepts the IDs the gateway issues: letters, digits, '-'
+// and '_'.
+func validContentID(id string) bool {
+	if id == "" || len(id) > 256 {
+		return false
+	}
+	for _, ch := range id {
+		if (ch < 'a' || ch > 'z') && (ch < 'A' || ch > 'Z') && (ch < '0' || ch > '9') && ch != '-' && ch != '_' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,133 @@
+package keys
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testMasterKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func newTestStore(t *testing.T) *KeyStore {
+	t.Helper()
+	store, err := NewKeyStore(t.TempDir(), testMasterKey(1))
+	require.NoError(t, err)
+	return store
+}
+
+func TestKeyStore_IssueAndGet(t *testing.T) {
+	store := newTestStore(t)
+
+	_, err := store.Get("content-1")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	key, err := store.Issue("content-1")
+	require.NoError(t, err)
+	assert.Len(t, key.Key, KeySize)
+	assert.Len(t, key.IV, KeySize)
+	assert.Equal(t, "content-1", key.ContentID)
+
+	again, err := store.Issue("content-1")
+	require.NoError(t, err)
+	assert.Equal(t, key.Key, again.Key, "a content keeps its key")
+	got, err := store.Get("content-1")
+	require.NoError(t, err)
+	assert.Equal(t, key.IV, got.IV)
+
+	other, err := store.Issue("content-2")
+	require.NoError(t, err)
+	assert.NotEqual(t, key.Key, other.Key)
+}
+
+func TestKeyStore_ConcurrentIssueAgrees(t *testing.T) {
+	dir := t.TempDir()
+	var wg sync.WaitGroup
+	got := make([][]byte, 8)
+	for i := range got {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Separate stores stand in for separate transcoder processes.
+			store, err := NewKeyStore(dir, testMasterKey(1))
+			require.NoError(t, err)
+			key, err := store.Issue("content-1")
+			require.NoError(t, err)
+			got[i] = key.Key
+		}(i)
+	}
+	wg.Wait()
+	for _, k := range got[1:] {
+		assert.Equal(t, got[0], k)
+	}
+}
+
+func TestKeyStore_SealedAtRest(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewKeyStore(dir, testMasterKey(1))
+	require.NoError(t, err)
+	key, err := store.Issue("content-1")
+	require.NoError(t, err)
+
+	sealed, err := os.ReadFile(filepath.Join(dir, "content-1.key"))
+	require.NoError(t, err)
+	assert.False(t, bytes.Contains(sealed, key.Key), "the key file must not hold the key in the clear")
+
+	other, err := NewKeyStore(dir, testMasterKey(2))
+	require.NoError(t, err)
+	_, err = other.Get("content-1")
+	assert.Error(t, err, "another master key cannot unseal the key")
+
+	// A key file copied under another content's name does not unlock it.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "content-2.key"), sealed, 0o600))
+	_, err = store.Get("content-2")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestKeyStore_DRMKeysAreSeparate(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewKeyStore(dir, testMasterKey(1))
+	require.NoError(t, err)
+	aes, err := store.Issue("content-1")
+	require.NoError(t, err)
+	assert.Empty(t, aes.KeyID)
+
+	drm, err := store.IssueDRM("content-1")
+	require.NoError(t, err)
+	assert.Len(t, drm.KeyID, KeySize)
+	assert.NotEqual(t, aes.Key, drm.Key)
+	again, err := store.GetDRM("content-1")
+	require.NoError(t, err)
+	assert.Equal(t, drm.KeyID, again.KeyID)
+
+	// The DRM key file cannot be passed off as the AES-128 key served to
+	// any authorized viewer.
+	_, err = store.IssueDRM("content-2")
+	require.NoError(t, err)
+	sealed, err := os.ReadFile(filepath.Join(dir, "content-2.drm.key"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "content-2.key"), sealed, 0o600))
+	_, err = store.Get("content-2")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrKeyNotFound)
+	_, err = store.GetDRM("content-3")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestKeyStore_RejectsInvalidContentID(t *testing.T) {
+	store := newTestStore(t)
+	for _, id := range []string{"", "../etc/passwd", "a/b", "a.b"} {
+		_, err := store.Issue(id)
+		assert.ErrorIs(t, err, ErrInvalidContentID, id)
+	}
+
+	_, err := NewKeyStore(t.TempDir(), []byte("short"))
+	assert.Error(t, err)
+}
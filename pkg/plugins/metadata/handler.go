@@ -2,10 +2,12 @@ package metadata
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/rtcdance/streamgate/pkg/core"
 	"github.com/rtcdance/streamgate/pkg/monitoring"
+	"github.com/rtcdance/streamgate/pkg/service"
 	"go.uber.org/zap"
 )
 
@@ -15,6 +17,9 @@ type MetadataHandler struct {
 	logger           *zap.Logger
 	kernel           *core.Microkernel
 	metricsCollector *monitoring.MetricsCollector
+	// search serves SearchMetadataHandler from the content database when
+	// set; otherwise the in-memory store is searched.
+	search *service.SearchService
 }
 
 // NewMetadataHandler creates a new metadata handler
@@ -43,11 +48,10 @@ func (h *MetadataHandler) HealthHandler(w http.ResponseWriter, r *http.Request)
 	_ = json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
 }
 
-// ReadyHandler handles readiness check requests
+// ReadyHandler reports the kernel's health checks, including the
+// dependencies this plugin registered.
 func (h *MetadataHandler) ReadyHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
+	h.kernel.ReadyHandler(w, r)
 }
 
 // GetMetadataHandler handles metadata retrieval requests
@@ -115,6 +119,9 @@ func (h *MetadataHandler) CreateMetadataHandler(w http.ResponseWriter, r *http.R
 		_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid metadata"})
 		return
 	}
+	if !h.normalizeTags(w, &metadata, "create") {
+		return
+	}
 
 	if err := h.db.CreateMetadata(ctx, &metadata); err != nil {
 		h.logger.Error("Failed to create metadata", zap.Error(err))
@@ -154,6 +161,9 @@ func (h *MetadataHandler) UpdateMetadataHandler(w http.ResponseWriter, r *http.R
 		_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid metadata"})
 		return
 	}
+	if !h.normalizeTags(w, &metadata, "update") {
+		return
+	}
 
 	if err := h.db.UpdateMetadata(ctx, &metadata); err != nil {
 		h.logger.Error("Failed to update metadata", zap.Error(err))
@@ -173,6 +183,24 @@ func (h *MetadataHandler) UpdateMetadataHandler(w http.ResponseWriter, r *http.R
 	_ = json.NewEncoder(w).Encode(metadata)
 }
 
+// normalizeTags normalizes metadata's tags in place, answering 400 when
+// they are invalid.
+func (h *MetadataHandler) normalizeTags(w http.ResponseWriter, metadata *ContentMetadata, op string) bool {
+	if len(metadata.Tags) == 0 {
+		return true
+	}
+	tags, err := service.NormalizeTags(metadata.Tags)
+	if err != nil {
+		h.metricsCollector.IncrementCounter(op+"_metadata_invalid_tags", map[string]string{})
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return false
+	}
+	metadata.Tags = tags
+	return true
+}
+
 // DeleteMetadataHandler handles metadata deletion requests
 func (h *MetadataHandler) DeleteMetadataHandler(w http.ResponseWriter, r *http.Request) {
 
@@ -221,6 +249,11 @@ func (h *MetadataHandler) SearchMetadataHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	if h.search != nil {
+		h.searchContent(w, r)
+		return
+	}
+
 	// Check rate limit
 
 	ctx := r.Context()
@@ -251,6 +284,33 @@ func (h *MetadataHandler) SearchMetadataHandler(w http.ResponseWriter, r *http.R
 	_ = json.NewEncoder(w).Encode(results)
 }
 
+// searchContent runs a full-text and faceted search of published content.
+func (h *MetadataHandler) searchContent(w http.ResponseWriter, r *http.Request) {
+	q, err := service.ParseSearchQuery(r.URL.Query())
+	var res *service.SearchResult
+	if err == nil {
+		res, err = h.search.Search(r.Context(), q)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case err == nil:
+		h.metricsCollector.IncrementCounter("search_metadata_success", map[string]string{})
+		h.metricsCollector.RecordHistogram("search_metadata_results", float64(len(res.Hits)), map[string]string{})
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(res)
+	case errors.Is(err, service.ErrInvalidRequest):
+		h.metricsCollector.IncrementCounter("search_metadata_invalid_query", map[string]string{})
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+	default:
+		h.logger.Error("Failed to search metadata", zap.Error(err))
+		h.metricsCollector.IncrementCounter("search_metadata_failed", map[string]string{})
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to search metadata"})
+	}
+}
+
 // NotFoundHandler handles 404 requests
 func (h *MetadataHandler) NotFoundHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
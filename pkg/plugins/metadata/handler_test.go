@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -11,6 +12,8 @@ import (
 
 	"github.com/rtcdance/streamgate/pkg/core"
 	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/rtcdance/streamgate/pkg/service"
+	"github.com/rtcdance/streamgate/pkg/service/search"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -131,6 +134,23 @@ func TestMetadataHandler_CreateMetadataHandler_Success(t *testing.T) {
 	assert.Equal(t, http.StatusCreated, rec.Code)
 }
 
+func TestMetadataHandler_CreateMetadataHandler_Tags(t *testing.T) {
+	handler := newTestMetadataHandler(t)
+
+	body, _ := json.Marshal(ContentMetadata{ContentID: "content-1", Tags: []string{" Jazz ", "jazz", "Live  Music"}})
+	rec := httptest.NewRecorder()
+	handler.CreateMetadataHandler(rec, httptest.NewRequest(http.MethodPost, "/create", bytes.NewReader(body)))
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var got ContentMetadata
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+	assert.Equal(t, []string{"jazz", "live music"}, got.Tags)
+
+	body, _ = json.Marshal(ContentMetadata{ContentID: "content-2", Tags: []string{"a,b"}})
+	rec = httptest.NewRecorder()
+	handler.CreateMetadataHandler(rec, httptest.NewRequest(http.MethodPost, "/create", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
 func TestMetadataHandler_UpdateMetadataHandler_MethodNotAllowed(t *testing.T) {
 	handler := newTestMetadataHandler(t)
 
@@ -734,3 +754,47 @@ func TestMetadataServer_CRUDViaHTTP(t *testing.T) {
 	defer stopCancel()
 	_ = server.Stop(stopCtx)
 }
+
+func TestMetadataHandler_SearchMetadataHandler_SearchService(t *testing.T) {
+	handler := newTestMetadataHandler(t)
+	backend := &stubSearchBackend{hits: []service.SearchHit{{ContentID: "c1", Title: "Cats"}}}
+	handler.search = service.NewSearchService(nil, backend, zap.NewNop())
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"text", "?q=cats&tag=pets", http.StatusOK},
+		{"filters without text", "?creator=0xabc&max_duration=600", http.StatusOK},
+		{"bad duration", "?min_duration=long", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/metadata/search"+tt.query, http.NoBody)
+			rec := httptest.NewRecorder()
+			handler.SearchMetadataHandler(rec, req)
+			require.Equal(t, tt.want, rec.Code, rec.Body.String())
+			if tt.want != http.StatusOK {
+				return
+			}
+			var res service.SearchResult
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+			assert.Equal(t, "c1", res.Hits[0].ContentID)
+		})
+	}
+
+	backend.err = errors.New("connection refused")
+	rec := httptest.NewRecorder()
+	handler.SearchMetadataHandler(rec, httptest.NewRequest(http.MethodGet, "/api/v1/metadata/search?q=cats", http.NoBody))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+type stubSearchBackend struct {
+	hits []service.SearchHit
+	err  error
+}
+
+func (b *stubSearchBackend) Search(_ context.Context, _ service.SearchQuery, _ *search.Cursor, _ int, _ bool) ([]service.SearchHit, *search.Facets, error) {
+	return b.hits, nil, b.err
+}
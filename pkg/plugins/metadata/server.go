@@ -9,6 +9,9 @@ import (
 
 	"github.com/rtcdance/streamgate/pkg/core"
 	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/rtcdance/streamgate/pkg/health"
+	"github.com/rtcdance/streamgate/pkg/service"
+	"github.com/rtcdance/streamgate/pkg/storage"
 
 	"go.uber.org/zap"
 )
@@ -20,26 +23,59 @@ type MetadataServer struct {
 	kernel *core.Microkernel
 	server *http.Server
 	db     *MetadataDB
+	pg     *storage.PostgresDB
+	search *service.SearchService
 }
 
-// NewMetadataServer creates a new metadata server
+// NewMetadataServer creates a new metadata server. With search enabled it
+// searches the content database, falling back to the in-memory store when
+// the database is unreachable.
 func NewMetadataServer(cfg *config.Config, logger *zap.Logger, kernel *core.Microkernel) (*MetadataServer, error) {
 	db, err := NewMetadataDB(cfg, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create metadata database: %w", err)
 	}
 
-	return &MetadataServer{
+	s := &MetadataServer{
 		config: cfg,
 		logger: logger,
 		kernel: kernel,
 		db:     db,
-	}, nil
+	}
+	if !cfg.Search.Enabled {
+		return s, nil
+	}
+
+	pg := storage.NewPostgresDB()
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		cfg.Database.Host, cfg.Database.Port, cfg.Database.User, cfg.Database.Password, cfg.Database.Database, cfg.Database.SSLMode)
+	poolCfg := storage.PoolConfigFromValues(cfg.Database.MaxConns, cfg.Database.MaxIdleConns, 0, 0)
+	if err := pg.ConnectWithConfig(dsn, poolCfg); err != nil {
+		logger.Warn("Database unavailable, metadata search limited to the in-memory store", zap.Error(err))
+		return s, nil
+	}
+	svc, interval, err := service.NewSearchServiceFromConfig(pg, cfg.Search, logger.Named("search"))
+	if err != nil {
+		_ = pg.Close()
+		return nil, err
+	}
+	svc.Start(interval)
+	s.pg = pg
+	s.search = svc
+	return s, nil
 }
 
+// postgresHealthCheck is the kernel health check pinging the content
+// database.
+const postgresHealthCheck = "metadata.postgres"
+
 // Start starts the metadata server
 func (s *MetadataServer) Start(ctx context.Context) error {
+	if s.kernel != nil && s.pg != nil {
+		s.kernel.HealthChecks().RegisterCheck(postgresHealthCheck, s.pg.Ping, health.ProbeOptions()...)
+	}
 	handler := NewMetadataHandler(s.db, s.logger, s.kernel)
+	handler.search = s.search
 
 	mux := http.NewServeMux()
 
@@ -83,6 +119,18 @@ func (s *MetadataServer) Stop(ctx context.Context) error {
 		}
 	}
 
+	if s.kernel != nil {
+		s.kernel.HealthChecks().UnregisterCheck(postgresHealthCheck)
+	}
+	if s.search != nil {
+		s.search.Close()
+	}
+	if s.pg != nil {
+		if err := s.pg.Close(); err != nil {
+			s.logger.Error("Error closing content database", zap.Error(err))
+		}
+	}
+
 	if s.db != nil {
 		if err := s.db.Close(); err != nil {
 			s.logger.Error("Error closing metadata database", zap.Error(err))
@@ -198,6 +246,10 @@ type ContentMetadata struct {
 	Duration    int    `json:"duration"` // in seconds
 	FileSize    int64  `json:"file_size"`
 	Format      string `json:"format"`
-	CreatedAt   int64  `json:"created_at"`
-	UpdatedAt   int64  `json:"updated_at"`
+	// Tags are normalized with service.NormalizeTags; Categories holds
+	// category slugs.
+	Tags       []string `json:"tags,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+	CreatedAt  int64    `json:"created_at"`
+	UpdatedAt  int64    `json:"updated_at"`
 }
@@ -0,0 +1,149 @@
+package moderation
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rtcdance/streamgate/pkg/core"
+	"github.com/rtcdance/streamgate/pkg/service"
+
+	"go.uber.org/zap"
+)
+
+// ModerationHandler serves the moderator review API. Every moderation
+// endpoint requires an X-Wallet-Address listed in auth.admin_wallets.
+type ModerationHandler struct {
+	svc    *service.ModerationService
+	admins map[string]bool
+	logger *zap.Logger
+	kernel *core.Microkernel
+}
+
+// NewModerationHandler creates a moderation handler
+func NewModerationHandler(svc *service.ModerationService, adminWallets []string, logger *zap.Logger, kernel *core.Microkernel) *ModerationHandler {
+	admins := make(map[string]bool, len(adminWallets))
+	for _, w := range adminWallets {
+		admins[strings.ToLower(w)] = true
+	}
+	return &ModerationHandler{svc: svc, admins: admins, logger: logger, kernel: kernel}
+}
+
+// HealthHandler handles health check requests
+func (h *ModerationHandler) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	if h.kernel != nil {
+		if err := h.kernel.Health(r.Context()); err != nil {
+			h.logger.Error("Health check failed", zap.Error(err))
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "unhealthy", "error": err.Error()})
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "healthy"})
+}
+
+// ReadyHandler reports the kernel's health checks, including the
+// dependencies this plugin registered.
+func (h *ModerationHandler) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	h.kernel.ReadyHandler(w, r)
+}
+
+// PendingHandler lists content waiting for review: GET ?limit=&offset=
+func (h *ModerationHandler) PendingHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.allow(w, r, http.MethodGet) {
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	recs, err := h.svc.ListPending(r.Context(), limit, offset)
+	if err != nil {
+		h.writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"content": recs, "limit": limit, "offset": offset})
+}
+
+// StatusHandler returns the moderation state of one item: GET ?content_id=
+func (h *ModerationHandler) StatusHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.allow(w, r, http.MethodGet) {
+		return
+	}
+	contentID := r.URL.Query().Get("content_id")
+	if contentID == "" {
+		writeError(w, http.StatusBadRequest, "missing content_id")
+		return
+	}
+	rec, err := h.svc.Get(r.Context(), contentID)
+	if err != nil {
+		h.writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rec)
+}
+
+// ReviewHandler records a decision: POST {"content_id", "decision", "note"}
+// where decision is "approve" or "reject".
+func (h *ModerationHandler) ReviewHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.allow(w, r, http.MethodPost) {
+		return
+	}
+	var req struct {
+		ContentID string `json:"content_id"`
+		Decision  string `json:"decision"`
+		Note      string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ContentID == "" {
+		writeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	rec, err := h.svc.Review(r.Context(), req.ContentID, req.Decision, r.Header.Get("X-Wallet-Address"), req.Note)
+	if err != nil {
+		h.writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rec)
+}
+
+// allow checks the method and that the caller is a moderator, writing the
+// error response when not.
+func (h *ModerationHandler) allow(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return false
+	}
+	if !h.admins[strings.ToLower(r.Header.Get("X-Wallet-Address"))] {
+		writeError(w, http.StatusForbidden, "admin access required")
+		return false
+	}
+	return true
+}
+
+func (h *ModerationHandler) writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		writeError(w, http.StatusNotFound, "content not found")
+	case errors.Is(err, service.ErrInvalidRequest):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		h.logger.Error("Moderation request failed", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
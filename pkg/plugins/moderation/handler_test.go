@@ -0,0 +1,71 @@
+package moderation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rtcdance/streamgate/pkg/service"
+	"github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// emptyDB holds no content.
+type emptyDB struct{}
+
+func (emptyDB) Query(context.Context, string, ...interface{}) (storage.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+func (emptyDB) QueryRow(context.Context, string, ...interface{}) *storage.CancelRow {
+	return storage.NewErrorCancelRow(sql.ErrNoRows)
+}
+func (emptyDB) Exec(context.Context, string, ...interface{}) (sql.Result, error) {
+	return nil, errors.New("not implemented")
+}
+func (emptyDB) Begin(context.Context) (*sql.Tx, error) { return nil, errors.New("not implemented") }
+func (emptyDB) InTransaction(context.Context, func(*sql.Tx) error) error {
+	return errors.New("not implemented")
+}
+func (emptyDB) Ping(context.Context) error { return nil }
+func (emptyDB) Close() error               { return nil }
+
+func TestModerationHandler(t *testing.T) {
+	svc := service.NewModerationService(emptyDB{}, nil, nil, service.ModerationConfig{}, zap.NewNop())
+	h := NewModerationHandler(svc, []string{"0xADMIN"}, zap.NewNop(), nil)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		target  string
+		body    string
+		wallet  string
+		status  int
+	}{
+		{"not an admin", h.StatusHandler, http.MethodGet, "/api/v1/moderation/content?content_id=c1", "", "0xother", http.StatusForbidden},
+		{"no wallet", h.PendingHandler, http.MethodGet, "/api/v1/moderation/pending", "", "", http.StatusForbidden},
+		{"wrong method", h.ReviewHandler, http.MethodGet, "/api/v1/moderation/review", "", "0xadmin", http.StatusMethodNotAllowed},
+		{"missing content ID", h.StatusHandler, http.MethodGet, "/api/v1/moderation/content", "", "0xadmin", http.StatusBadRequest},
+		{"unknown content", h.StatusHandler, http.MethodGet, "/api/v1/moderation/content?content_id=c1", "", "0xadmin", http.StatusNotFound},
+		{"invalid review", h.ReviewHandler, http.MethodPost, "/api/v1/moderation/review", `{"decision":"approve"}`, "0xadmin", http.StatusBadRequest},
+		{"bad decision", h.ReviewHandler, http.MethodPost, "/api/v1/moderation/review", `{"content_id":"c1","decision":"hide"}`, "0xadmin", http.StatusBadRequest},
+		{"review unknown content", h.ReviewHandler, http.MethodPost, "/api/v1/moderation/review", `{"content_id":"c1","decision":"reject"}`, "0xadmin", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.wallet != "" {
+				req.Header.Set("X-Wallet-Address", tt.wallet)
+			}
+			w := httptest.NewRecorder()
+			tt.handler(w, req)
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+		})
+	}
+}
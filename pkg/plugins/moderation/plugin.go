@@ -0,0 +1,20 @@
+package moderation
+
+import (
+	"github.com/rtcdance/streamgate/pkg/core"
+	"github.com/rtcdance/streamgate/pkg/core/config"
+
+	"go.uber.org/zap"
+)
+
+func init() {
+	core.RegisterPluginFactory("moderation", NewModerationPlugin)
+}
+
+// NewModerationPlugin creates the moderation service plugin, which reviews
+// completed uploads and serves the moderator queue.
+func NewModerationPlugin(cfg *config.Config, logger *zap.Logger) core.Plugin {
+	return core.NewGenericPlugin("moderation", cfg, logger, func(kernel *core.Microkernel) (core.ServerLifecycle, error) {
+		return NewModerationServer(cfg, logger, kernel)
+	})
+}
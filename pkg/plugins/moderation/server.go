@@ -0,0 +1,162 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core"
+	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/rtcdance/streamgate/pkg/core/event"
+	"github.com/rtcdance/streamgate/pkg/service"
+	"github.com/rtcdance/streamgate/pkg/storage"
+
+	"go.uber.org/zap"
+)
+
+// ModerationServer submits completed uploads for moderation and serves the
+// moderator review API.
+type ModerationServer struct {
+	config   *config.Config
+	logger   *zap.Logger
+	kernel   *core.Microkernel
+	server   *http.Server
+	db       *storage.PostgresDB
+	svc      *service.ModerationService
+	eventBus event.EventBus
+	subID    string
+	wg       sync.WaitGroup
+}
+
+// NewModerationServer creates a moderation server. The database is only
+// opened when moderation is enabled.
+func NewModerationServer(cfg *config.Config, logger *zap.Logger, kernel *core.Microkernel) (*ModerationServer, error) {
+	s := &ModerationServer{config: cfg, logger: logger, kernel: kernel}
+	if !cfg.Moderation.Enabled {
+		return s, nil
+	}
+
+	pg := storage.NewPostgresDB()
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		cfg.Database.Host, cfg.Database.Port, cfg.Database.User, cfg.Database.Password, cfg.Database.Database, cfg.Database.SSLMode)
+	poolCfg := storage.PoolConfigFromValues(cfg.Database.MaxConns, cfg.Database.MaxIdleConns, 0, 0)
+	if err := pg.ConnectWithConfig(dsn, poolCfg); err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	svc, err := service.NewModerationServiceFromConfig(pg, cfg.Moderation, logger)
+	if err != nil {
+		_ = pg.Close()
+		return nil, err
+	}
+	s.db = pg
+	s.svc = svc
+	if kernel != nil {
+		s.eventBus = kernel.GetEventBus()
+	}
+	return s, nil
+}
+
+// Start subscribes to completed uploads and starts the review API.
+func (s *ModerationServer) Start(ctx context.Context) error {
+	if s.svc == nil {
+		s.logger.Info("Content moderation disabled, moderation service idle")
+		return nil
+	}
+	if s.eventBus != nil {
+		id, err := s.eventBus.Subscribe(ctx, event.EventTypeUploadCompleted, s.handleUploadCompleted)
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to uploads: %w", err)
+		}
+		s.subID = id
+	} else {
+		s.logger.Warn("No event bus, uploads will not be submitted for moderation")
+	}
+
+	handler := NewModerationHandler(s.svc, s.config.Auth.AdminWallets, s.logger, s.kernel)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", handler.HealthHandler)
+	mux.HandleFunc("/health/live", handler.HealthHandler)
+	mux.HandleFunc("/health/ready", handler.ReadyHandler)
+	mux.HandleFunc("/ready", handler.ReadyHandler)
+
+	// Moderator endpoints
+	mux.HandleFunc("/api/v1/moderation/pending", handler.PendingHandler)
+	mux.HandleFunc("/api/v1/moderation/content", handler.StatusHandler)
+	mux.HandleFunc("/api/v1/moderation/review", handler.ReviewHandler)
+
+	s.server = &http.Server{
+		Addr:         fmt.Sprintf(":%d", s.config.Server.Port),
+		Handler:      mux,
+		ReadTimeout:  time.Duration(s.config.Server.ReadTimeout) * time.Second,
+		WriteTimeout: time.Duration(s.config.Server.WriteTimeout) * time.Second,
+	}
+
+	go func() {
+		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			s.logger.Error("Moderation server error", zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+// handleUploadCompleted submits the uploaded content for moderation in the
+// background; sampling takes far longer than an event handler should.
+// Uploads without a transcode input are held for a moderator.
+func (s *ModerationServer) handleUploadCompleted(ctx context.Context, e *event.Event) error {
+	contentID, _ := e.Data["content_id"].(string)
+	if contentID == "" {
+		return nil
+	}
+	inputURL, _ := e.Data["input_url"].(string)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if _, err := s.svc.Submit(context.WithoutCancel(ctx), contentID, inputURL); err != nil {
+			s.logger.Warn("Moderation failed, content held for review",
+				zap.String("content_id", contentID), zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+// Stop stops the moderation server, waiting for submissions in flight.
+func (s *ModerationServer) Stop(ctx context.Context) error {
+	if s.subID != "" {
+		if err := s.eventBus.Unsubscribe(ctx, s.subID); err != nil {
+			s.logger.Warn("Failed to unsubscribe from uploads", zap.Error(err))
+		}
+	}
+	if s.server != nil {
+		if err := s.server.Shutdown(ctx); err != nil {
+			s.logger.Error("Error shutting down moderation server", zap.Error(err))
+			return err
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.logger.Warn("Moderation submissions still running at shutdown")
+	}
+
+	if s.db != nil {
+		return s.db.Close()
+	}
+	return nil
+}
+
+// Health checks the moderation server
+func (s *ModerationServer) Health(ctx context.Context) error {
+	if s.db == nil {
+		return nil
+	}
+	return s.db.Ping(ctx)
+}
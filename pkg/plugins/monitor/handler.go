@@ -5,7 +5,6 @@ import (
 	"net/http"
 	"sync"
 
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rtcdance/streamgate/pkg/core"
 	"github.com/rtcdance/streamgate/pkg/monitoring"
 	"go.uber.org/zap"
@@ -47,11 +46,10 @@ func (h *MonitorHandler) HealthHandler(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
 }
 
-// ReadyHandler handles readiness check requests
+// ReadyHandler reports the kernel's health checks, including the
+// dependencies this plugin registered.
 func (h *MonitorHandler) ReadyHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
+	h.kernel.ReadyHandler(w, r)
 }
 
 // GetHealthHandler handles health status requests
@@ -92,6 +90,26 @@ func (h *MonitorHandler) GetMetricsHandler(w http.ResponseWriter, r *http.Reques
 	_ = json.NewEncoder(w).Encode(metrics)
 }
 
+// GetPluginsHandler reports each plugin's resource usage and limits: the
+// jobs it runs, and for out-of-process plugins their process's goroutines,
+// memory and CPU time.
+func (h *MonitorHandler) GetPluginsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.metricsCollector.IncrementCounter("get_plugins_invalid_method", map[string]string{})
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	usage := h.kernel.PluginUsage(r.Context())
+
+	h.metricsCollector.IncrementCounter("get_plugins_success", map[string]string{})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"plugins": usage})
+}
+
 // GetAlertsHandler handles alert requests
 func (h *MonitorHandler) GetAlertsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -140,7 +158,7 @@ func (h *MonitorHandler) GetLogsHandler(w http.ResponseWriter, r *http.Request)
 // PrometheusMetricsHandler handles Prometheus metrics requests
 func (h *MonitorHandler) PrometheusMetricsHandler(w http.ResponseWriter, r *http.Request) {
 	h.metricsCollector.IncrementCounter("prometheus_metrics_success", map[string]string{})
-	promhttp.Handler().ServeHTTP(w, r)
+	monitoring.MetricsHandler().ServeHTTP(w, r)
 }
 
 // NotFoundHandler handles 404 requests
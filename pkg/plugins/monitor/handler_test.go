@@ -3,6 +3,7 @@ package monitor
 import (
 	"context"
 	"encoding/json"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -99,6 +100,27 @@ func TestMonitorHandler_GetMetricsHandler_Success(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
 
+func TestMonitorHandler_GetPluginsHandler(t *testing.T) {
+	handler := newTestMonitorHandler(t)
+	release, err := handler.kernel.AcquireJob(context.Background(), "transcoder")
+	require.NoError(t, err)
+	defer release()
+
+	rec := httptest.NewRecorder()
+	handler.GetPluginsHandler(rec, httptest.NewRequest(http.MethodPost, "/plugins", http.NoBody))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	require.NoError(t, handler.kernel.RegisterPlugin(core.NewGenericPlugin("transcoder", handler.kernel.GetConfig(), zap.NewNop(), nil)))
+	rec = httptest.NewRecorder()
+	handler.GetPluginsHandler(rec, httptest.NewRequest(http.MethodGet, "/plugins", http.NoBody))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp struct{ Plugins []core.PluginUsage }
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Plugins, 1)
+	assert.Equal(t, "transcoder", resp.Plugins[0].Name)
+	assert.Equal(t, 1, resp.Plugins[0].JobsInFlight)
+}
+
 func TestMonitorHandler_GetAlertsHandler_MethodNotAllowed(t *testing.T) {
 	handler := newTestMonitorHandler(t)
 
@@ -571,3 +593,87 @@ func TestMonitorServer_Routes(t *testing.T) {
 	defer stopCancel()
 	_ = server.Stop(stopCtx)
 }
+
+func startTestMonitorServer(t *testing.T, shutdownTimeout string) *MonitorServer {
+	t.Helper()
+	cfg := &config.Config{Mode: "monolith"}
+	cfg.Server.Port = 0
+	cfg.Server.ReadTimeout = 30
+	cfg.Server.WriteTimeout = 30
+	cfg.Monitoring.ShutdownTimeout = shutdownTimeout
+
+	kernel, err := core.NewMicrokernel(cfg, zap.NewNop())
+	require.NoError(t, err)
+	server, err := NewMonitorServer(cfg, zap.NewNop(), kernel)
+	require.NoError(t, err)
+	require.NoError(t, server.Start(context.Background()))
+	return server
+}
+
+func assertPortReleased(t *testing.T, addr string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", addr)
+	require.NoError(t, err, "port should be free after Stop returns")
+	_ = ln.Close()
+}
+
+func TestMonitorServer_StopReleasesPort(t *testing.T) {
+	server := startTestMonitorServer(t, "2s")
+	addr := server.Addr()
+	require.NotEmpty(t, addr)
+
+	resp, err := http.Get("http://" + addr + "/health")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	require.NoError(t, server.Stop(context.Background()))
+	select {
+	case <-server.Done():
+	default:
+		t.Fatal("Done must be closed when Stop returns")
+	}
+	assertPortReleased(t, addr)
+}
+
+func TestMonitorServer_StopHonorsShutdownTimeout(t *testing.T) {
+	server := startTestMonitorServer(t, "200ms")
+	addr := server.Addr()
+
+	// A half-written request keeps the connection active, so Shutdown can
+	// only finish by hitting the configured timeout.
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET /health HTTP/1.1\r\nHost: x\r\n"))
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	err = server.Stop(context.Background())
+	elapsed := time.Since(start)
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.GreaterOrEqual(t, elapsed, 200*time.Millisecond)
+	assert.Less(t, elapsed, 2*time.Second, "Stop must not outlive the shutdown timeout")
+	assertPortReleased(t, addr)
+}
+
+func TestMonitorServer_StartFailsOnBusyPort(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	cfg := &config.Config{Mode: "monolith"}
+	cfg.Server.Port = ln.Addr().(*net.TCPAddr).Port
+	server, err := NewMonitorServer(cfg, zap.NewNop(), nil)
+	require.NoError(t, err)
+	assert.Error(t, server.Start(context.Background()))
+}
+
+func TestMonitoringConfig_GetShutdownTimeout(t *testing.T) {
+	assert.Equal(t, config.DefaultMonitoringShutdownTimeout, (&config.MonitoringConfig{}).GetShutdownTimeout())
+	assert.Equal(t, config.DefaultMonitoringShutdownTimeout, (&config.MonitoringConfig{ShutdownTimeout: "soon"}).GetShutdownTimeout())
+	assert.Equal(t, 750*time.Millisecond, (&config.MonitoringConfig{ShutdownTimeout: "750ms"}).GetShutdownTimeout())
+}
@@ -3,6 +3,7 @@ package monitor
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"runtime"
 	"sync"
@@ -22,6 +23,9 @@ type MonitorServer struct {
 	kernel    *core.Microkernel
 	server    *http.Server
 	collector *MetricsCollector
+	listener  net.Listener
+	// done is closed once the serve loop has exited and the port is free.
+	done chan struct{}
 }
 
 // NewMonitorServer creates a new monitor server
@@ -50,6 +54,7 @@ func (s *MonitorServer) Start(ctx context.Context) error {
 	// Monitoring endpoints
 	mux.HandleFunc("/api/v1/monitor/health", handler.GetHealthHandler)
 	mux.HandleFunc("/api/v1/monitor/metrics", handler.GetMetricsHandler)
+	mux.HandleFunc("/api/v1/monitor/plugins", handler.GetPluginsHandler)
 	mux.HandleFunc("/api/v1/monitor/alerts", handler.GetAlertsHandler)
 	mux.HandleFunc("/api/v1/monitor/logs", handler.GetLogsHandler)
 
@@ -66,11 +71,21 @@ func (s *MonitorServer) Start(ctx context.Context) error {
 		WriteTimeout: time.Duration(s.config.Server.WriteTimeout) * time.Second,
 	}
 
+	// Bind before returning so a busy port fails Start instead of being
+	// logged from the serve goroutine.
+	ln, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", s.server.Addr, err)
+	}
+	s.listener = ln
+	s.done = make(chan struct{})
+
 	// Start metrics collection
 	s.collector.Start(ctx)
 
 	go func() {
-		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		defer close(s.done)
+		if err := s.server.Serve(ln); err != nil && err != http.ErrServerClosed {
 			s.logger.Error("Monitor server error", zap.Error(err))
 		}
 	}()
@@ -78,12 +93,39 @@ func (s *MonitorServer) Start(ctx context.Context) error {
 	return nil
 }
 
-// Stop stops the monitor server
+// Addr returns the address the server is listening on, or "" before Start.
+func (s *MonitorServer) Addr() string {
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Done is closed once the server has stopped serving and released its
+// port. It is nil before Start.
+func (s *MonitorServer) Done() <-chan struct{} {
+	return s.done
+}
+
+// Stop drains in-flight requests for up to monitoring.shutdown_timeout (or
+// until ctx ends, if sooner), then closes any remaining connections. It
+// returns only after the serve loop has exited, so the kernel's shutdown
+// sequence does not race the port being released.
 func (s *MonitorServer) Stop(ctx context.Context) error {
+	var shutdownErr error
 	if s.server != nil {
-		if err := s.server.Shutdown(ctx); err != nil {
-			s.logger.Error("Error shutting down monitor server", zap.Error(err))
-			return err
+		timeout := s.config.Monitoring.GetShutdownTimeout()
+		shutdownCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		if err := s.server.Shutdown(shutdownCtx); err != nil {
+			s.logger.Warn("Monitor server did not drain in time, closing connections",
+				zap.Duration("timeout", timeout), zap.Error(err))
+			_ = s.server.Close()
+			shutdownErr = fmt.Errorf("shutdown monitor server: %w", err)
+		}
+		if s.done != nil {
+			<-s.done
 		}
 	}
 
@@ -91,7 +133,7 @@ func (s *MonitorServer) Stop(ctx context.Context) error {
 		s.collector.Stop()
 	}
 
-	return nil
+	return shutdownErr
 }
 
 // Health checks the health of the monitor server
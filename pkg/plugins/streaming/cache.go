@@ -1,32 +1,66 @@
 package streaming
 
-import "go.uber.org/zap"
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// defaultStreamCacheTTL bounds how long rendition listings are reused, so a
+// re-transcode is picked up without restarting the plugin.
+const defaultStreamCacheTTL = 30 * time.Second
+
+type streamCacheEntry struct {
+	value     interface{}
+	expiresAt time.Time
+}
 
 // StreamCache caches streaming data
 type StreamCache struct {
-	logger *zap.Logger
+	logger  *zap.Logger
+	ttl     time.Duration
+	mu      sync.RWMutex
+	entries map[string]streamCacheEntry
 }
 
 // NewStreamCache creates a new stream cache
 func NewStreamCache(logger *zap.Logger) *StreamCache {
 	return &StreamCache{
-		logger: logger,
+		logger:  logger,
+		ttl:     defaultStreamCacheTTL,
+		entries: make(map[string]streamCacheEntry),
 	}
 }
 
 // Get gets cached stream
 func (c *StreamCache) Get(key string) (interface{}, bool) {
-	return nil, false
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.value, true
 }
 
 // Set sets stream cache
 func (c *StreamCache) Set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = streamCacheEntry{value: value, expiresAt: time.Now().Add(c.ttl)}
 }
 
 // Delete deletes cached stream
 func (c *StreamCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
 }
 
 // Close closes the cache
 func (c *StreamCache) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]streamCacheEntry)
 }
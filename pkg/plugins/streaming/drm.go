@@ -0,0 +1,69 @@
+package streaming
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// audioTrackSuffix selects the audio playlist of a protected rendition,
+// e.g. quality "1920x1080/audio".
+const audioTrackSuffix = "/audio"
+
+// mapURIPattern matches the URI attribute of an EXT-X-MAP tag.
+var mapURIPattern = regexp.MustCompile(`URI="([^"]*)"`)
+
+// storedPlaylist serves the playlist the transcoder stored for an fMP4
+// rendition with its segment and init section URIs pointed at the segment
+// endpoint. DRM key tags are kept as stored: they carry the DRM system data
+// players need to request licenses. AES-128 key tags are replaced with the
+// plugin's own, whose URI authenticates like the playlist request.
+func (p *HLSPackager) storedPlaylist(ctx context.Context, rendition Rendition, contentID string, audio bool, query url.Values) (string, error) {
+	key := rendition.playlist
+	if audio {
+		if !rendition.HasAudio {
+			return "", ErrRenditionNotFound
+		}
+		key = rendition.audio
+	}
+	data, err := p.store.Download(ctx, p.bucket, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to read stored playlist: %w", err)
+	}
+
+	segmentURL := func(uri string) string {
+		return playlistURL("/api/v1/stream/segment", contentID, rendition.Quality, path.Base(uri), query)
+	}
+	var b strings.Builder
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#EXT-X-KEY:METHOD=AES-128"):
+			key, err := p.contentKey(contentID)
+			if err != nil {
+				return "", err
+			}
+			if key == nil {
+				return "", fmt.Errorf("no content key for encrypted rendition %s", rendition.Quality)
+			}
+			line = strings.TrimSuffix(keyTag(key, query), "\n")
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			line = mapURIPattern.ReplaceAllStringFunc(line, func(attr string) string {
+				uri := mapURIPattern.FindStringSubmatch(attr)[1]
+				return fmt.Sprintf("URI=\"%s\"", segmentURL(uri))
+			})
+		case !strings.HasPrefix(line, "#"):
+			line = segmentURL(line)
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
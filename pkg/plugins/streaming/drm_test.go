@@ -0,0 +1,79 @@
+package streaming
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const storedDRMPlaylist = `#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-TARGETDURATION:6
+#EXT-X-PLAYLIST-TYPE:VOD
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="skd://premium",KEYFORMAT="com.apple.streamingkeydelivery",KEYFORMATVERSIONS="1"
+#EXT-X-MAP:URI="1080p_v1_init.mp4"
+#EXTINF:6.000,
+1080p_v1_000.m4s
+#EXTINF:6.000,
+1080p_v1_001.m4s
+#EXT-X-ENDLIST
+`
+
+// newDRMStore holds content "premium" with a clear 480p rendition and a
+// 1080p rendition re-transcoded with DRM, leaving a clear segment behind.
+func newDRMStore() *fakeSegmentStore {
+	store := newFakeSegmentStore()
+	for name, data := range map[string]string{
+		"480p/" + testSegment("480p", 0):   "clear",
+		"1080p/" + testSegment("1080p", 0): "clear-before-drm",
+		"1080p/1080p.m3u8":                 storedDRMPlaylist,
+		"1080p/1080p_audio.m3u8":           storedDRMPlaylist,
+		"1080p/1080p_v1_init.mp4":          "init",
+		"1080p/1080p_v1_000.m4s":           "cipher-0",
+		"1080p/1080p_v1_001.m4s":           "cipher-1",
+	} {
+		store.objects["streams/premium/"+name] = []byte(data)
+	}
+	return store
+}
+
+func TestHLSPackager_ProtectedRendition(t *testing.T) {
+	p := NewHLSPackager(newDRMStore(), "streamgate", nil, zap.NewNop())
+	query := url.Values{"token": {"abc"}}
+
+	master, err := p.MasterPlaylist(context.Background(), "premium", query)
+	require.NoError(t, err)
+	assert.Contains(t, master, `#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="audio-1080p",NAME="audio",DEFAULT=YES,AUTOSELECT=YES,URI="/api/v1/stream/hls?content_id=premium&quality=1080p%2Faudio&token=abc"`)
+	assert.Contains(t, master, `RESOLUTION=1920x1080,CODECS="avc1.640028,mp4a.40.2",AUDIO="audio-1080p"`)
+
+	playlist, err := p.MediaPlaylist(context.Background(), "premium", "1080p", query)
+	require.NoError(t, err)
+	assert.Contains(t, playlist, `#EXT-X-KEY:METHOD=SAMPLE-AES,URI="skd://premium"`, "key tags are served as stored")
+	assert.Contains(t, playlist, `#EXT-X-MAP:URI="/api/v1/stream/segment?content_id=premium&quality=1080p&segment_id=1080p_v1_init.mp4&token=abc"`)
+	assert.Contains(t, playlist, "\n/api/v1/stream/segment?content_id=premium&quality=1080p&segment_id=1080p_v1_001.m4s&token=abc\n")
+	assert.NotContains(t, playlist, ".ts", "the clear segments from before DRM are not listed")
+
+	audio, err := p.MediaPlaylist(context.Background(), "premium", "1080p/audio", query)
+	require.NoError(t, err)
+	assert.Contains(t, audio, "#EXT-X-MAP:")
+
+	_, err = p.MediaPlaylist(context.Background(), "premium", "480p/audio", nil)
+	assert.ErrorIs(t, err, ErrRenditionNotFound)
+
+	data, err := p.Segment(context.Background(), "premium", "1080p", "1080p_v1_000.m4s")
+	require.NoError(t, err)
+	assert.Equal(t, "cipher-0", string(data))
+}
+
+func TestHLSPackager_ProtectedRenditionWaitsForPlaylist(t *testing.T) {
+	store := newFakeSegmentStore()
+	store.objects["streams/premium/1080p/1080p_v1_000.m4s"] = []byte("cipher-0")
+	p := NewHLSPackager(store, "streamgate", nil, zap.NewNop())
+
+	_, err := p.Renditions(context.Background(), "premium")
+	assert.ErrorIs(t, err, ErrContentNotFound)
+}
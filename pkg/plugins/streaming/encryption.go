@@ -0,0 +1,86 @@
+package streaming
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/rtcdance/streamgate/pkg/plugins/keys"
+)
+
+// contentKey returns the key the content's segments are encrypted with, or
+// nil for content served in the clear: encryption is off, or the content
+// was transcoded before it was turned on.
+func (p *HLSPackager) contentKey(contentID string) (*keys.ContentKey, error) {
+	if p.keys == nil {
+		return nil, nil
+	}
+	key, err := p.keys.Get(contentID)
+	if errors.Is(err, keys.ErrKeyNotFound) {
+		return nil, nil
+	}
+	return key, err
+}
+
+// keyTag renders the EXT-X-KEY tag for encrypted content. The key URI
+// carries the playlist's query so the key request authenticates like the
+// segment requests do.
+func keyTag(key *keys.ContentKey, query url.Values) string {
+	if key == nil {
+		return ""
+	}
+	q := url.Values{}
+	for k, v := range query {
+		q[k] = v
+	}
+	q.Set("content_id", key.ContentID)
+	return fmt.Sprintf("#EXT-X-KEY:METHOD=AES-128,URI=\"%s?%s\",IV=%s\n", keys.DeliveryPath, q.Encode(), key.IVHex())
+}
+
+// reencryptParts joins encrypted LL-HLS parts into one encrypted parent
+// segment. Each part is padded on its own, so the ciphertexts cannot just
+// be concatenated the way clear MPEG-TS parts can.
+func reencryptParts(key *keys.ContentKey, parts [][]byte) ([]byte, error) {
+	var plain []byte
+	for _, part := range parts {
+		data, err := decryptAES128(key, part)
+		if err != nil {
+			return nil, err
+		}
+		plain = append(plain, data...)
+	}
+	return encryptAES128(key, plain)
+}
+
+// encryptAES128 encrypts data as HLS METHOD=AES-128 does: AES-128-CBC with
+// PKCS#7 padding.
+func encryptAES128(key *keys.ContentKey, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key.Key)
+	if err != nil {
+		return nil, err
+	}
+	pad := aes.BlockSize - len(data)%aes.BlockSize
+	out := append(append([]byte(nil), data...), bytes.Repeat([]byte{byte(pad)}, pad)...)
+	cipher.NewCBCEncrypter(block, key.IV).CryptBlocks(out, out)
+	return out, nil
+}
+
+func decryptAES128(key *keys.ContentKey, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key.Key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("encrypted segment is not a whole number of blocks")
+	}
+	out := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, key.IV).CryptBlocks(out, data)
+	pad := int(out[len(out)-1])
+	if pad == 0 || pad > aes.BlockSize || !bytes.Equal(out[len(out)-pad:], bytes.Repeat([]byte{byte(pad)}, pad)) {
+		return nil, fmt.Errorf("encrypted segment has invalid padding")
+	}
+	return out[:len(out)-pad], nil
+}
@@ -0,0 +1,63 @@
+package streaming
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/rtcdance/streamgate/pkg/plugins/keys"
+	"github.com/rtcdance/streamgate/pkg/plugins/transcoder"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestKeyStore(t *testing.T) *keys.KeyStore {
+	t.Helper()
+	store, err := keys.NewKeyStore(t.TempDir(), bytes.Repeat([]byte{7}, 32))
+	require.NoError(t, err)
+	return store
+}
+
+func TestHLSPackager_EncryptedPlaylist(t *testing.T) {
+	p := NewHLSPackager(newFakeSegmentStore(), "streamgate", nil, zap.NewNop())
+	p.keys = newTestKeyStore(t)
+
+	playlist, err := p.MediaPlaylist(context.Background(), "test-123", "720p", url.Values{"token": {"abc"}})
+	require.NoError(t, err)
+	assert.NotContains(t, playlist, "#EXT-X-KEY", "content transcoded before encryption stays in the clear")
+
+	key, err := p.keys.Issue("test-123")
+	require.NoError(t, err)
+	playlist, err = p.MediaPlaylist(context.Background(), "test-123", "720p", url.Values{"token": {"abc"}})
+	require.NoError(t, err)
+	assert.Contains(t, playlist, fmt.Sprintf(
+		"#EXT-X-PLAYLIST-TYPE:VOD\n#EXT-X-KEY:METHOD=AES-128,URI=\"/api/v1/stream/key?content_id=test-123&token=abc\",IV=%s\n", key.IVHex()))
+}
+
+func TestHLSPackager_EncryptedLowLatency(t *testing.T) {
+	store := newLLStore(14)
+	p := NewHLSPackager(store, "streamgate", nil, zap.NewNop())
+	p.keys = newTestKeyStore(t)
+	key, err := p.keys.Issue("premiere")
+	require.NoError(t, err)
+	for i := 0; i < 14; i++ {
+		part, err := encryptAES128(key, []byte(fmt.Sprintf("[%d]", i)))
+		require.NoError(t, err)
+		store.objects["streams/premiere/720p/"+testPart(i)] = part
+	}
+
+	playlist, err := p.MediaPlaylist(context.Background(), "premiere", "720p", nil)
+	require.NoError(t, err)
+	assert.Contains(t, playlist, "#EXT-X-KEY:METHOD=AES-128,URI=\"/api/v1/stream/key?content_id=premiere\",IV="+key.IVHex()+"\n")
+
+	// The parent segment decrypts as a whole with the playlist's key and IV.
+	data, err := p.Segment(context.Background(), "premiere", "720p", transcoder.SegmentName("720p", testVersion, 1))
+	require.NoError(t, err)
+	plain, err := decryptAES128(key, data)
+	require.NoError(t, err)
+	assert.Equal(t, "[6][7][8][9][10][11]", string(plain))
+}
@@ -1,8 +1,14 @@
 package streaming
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/rtcdance/streamgate/pkg/core"
 	"github.com/rtcdance/streamgate/pkg/monitoring"
@@ -10,18 +16,27 @@ import (
 	"go.uber.org/zap"
 )
 
+// blockingRequestTimeout bounds LL-HLS blocking playlist reloads and
+// preload hint requests at three target durations.
+const blockingRequestTimeout = 3 * time.Duration(defaultSegmentDuration) * time.Second
+
+var errInvalidBlockingRequest = errors.New("invalid _HLS_msn or _HLS_part")
+
 // StreamingHandler handles streaming requests
 type StreamingHandler struct {
 	cache            *StreamCache
+	packager         *HLSPackager
 	logger           *zap.Logger
 	kernel           *core.Microkernel
 	metricsCollector *monitoring.MetricsCollector
 }
 
-// NewStreamingHandler creates a new streaming handler
-func NewStreamingHandler(cache *StreamCache, logger *zap.Logger, kernel *core.Microkernel) *StreamingHandler {
+// NewStreamingHandler creates a new streaming handler. packager may be nil
+// when no object storage is configured; HLS requests then fail with 503.
+func NewStreamingHandler(cache *StreamCache, packager *HLSPackager, logger *zap.Logger, kernel *core.Microkernel) *StreamingHandler {
 	return &StreamingHandler{
 		cache:            cache,
+		packager:         packager,
 		logger:           logger,
 		kernel:           kernel,
 		metricsCollector: monitoring.NewMetricsCollector(logger),
@@ -45,11 +60,10 @@ func (h *StreamingHandler) HealthHandler(w http.ResponseWriter, r *http.Request)
 	_ = json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
 }
 
-// ReadyHandler handles readiness check requests
+// ReadyHandler reports the kernel's health checks, including the
+// dependencies this plugin registered.
 func (h *StreamingHandler) ReadyHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
+	h.kernel.ReadyHandler(w, r)
 }
 
 // GetHLSPlaylistHandler handles HLS playlist requests
@@ -69,11 +83,51 @@ func (h *StreamingHandler) GetHLSPlaylistHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
-	h.logger.Info("Generating HLS playlist", zap.String("content_id", contentID))
+	if h.packager == nil {
+		writeStorageUnavailable(w)
+		return
+	}
+
+	// Forward everything but the routing parameters, e.g. a playback token.
+	forward := r.URL.Query()
+	forward.Del("content_id")
+	forward.Del("quality")
+	forward.Del("segment_id")
+	for key := range forward {
+		if strings.HasPrefix(key, "_HLS_") {
+			forward.Del(key)
+		}
+	}
+
+	quality := r.URL.Query().Get("quality")
+	var (
+		playlist string
+		err      error
+	)
+	if quality == "" {
+		h.logger.Info("Generating HLS master playlist", zap.String("content_id", contentID))
+		playlist, err = h.packager.MasterPlaylist(r.Context(), contentID, forward)
+	} else {
+		h.logger.Info("Generating HLS media playlist", zap.String("content_id", contentID), zap.String("quality", quality))
+		err = h.blockForPart(r, contentID, quality)
+		if err == nil {
+			playlist, err = h.packager.MediaPlaylist(r.Context(), contentID, quality, forward)
+		}
+	}
+	if err != nil {
+		h.writePackagerError(w, contentID, err)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	if strings.Contains(playlist, "#EXT-X-ENDLIST") || quality == "" {
+		w.Header().Set("Cache-Control", "private, max-age=30")
+	} else {
+		// A low-latency playlist still being written changes every part.
+		w.Header().Set("Cache-Control", "no-cache")
+	}
 	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write([]byte("#EXTM3U\n#EXT-X-VERSION:3\n"))
+	_, _ = w.Write([]byte(playlist))
 }
 
 // GetDASHManifestHandler handles DASH manifest requests
@@ -115,11 +169,82 @@ func (h *StreamingHandler) GetSegmentHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	h.logger.Info("Retrieving segment", zap.String("content_id", contentID), zap.String("segment_id", segmentID))
+	if h.packager == nil {
+		writeStorageUnavailable(w)
+		return
+	}
 
-	w.Header().Set("Content-Type", "video/mp2t")
-	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write([]byte{})
+	quality := r.URL.Query().Get("quality")
+	h.logger.Debug("Retrieving segment",
+		zap.String("content_id", contentID),
+		zap.String("quality", quality),
+		zap.String("segment_id", segmentID))
+
+	ctx, cancel := context.WithTimeout(r.Context(), blockingRequestTimeout)
+	defer cancel()
+	data, err := h.packager.Segment(ctx, contentID, quality, segmentID)
+	if err != nil {
+		h.writePackagerError(w, contentID, err)
+		return
+	}
+
+	// Segments never change once written; a re-transcode uses new names.
+	w.Header().Set("Content-Type", contentTypeFor(segmentID))
+	w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	http.ServeContent(w, r, segmentID, time.Time{}, bytes.NewReader(data))
+}
+
+// blockForPart holds an LL-HLS blocking playlist reload, signalled by the
+// _HLS_msn and _HLS_part parameters, until the requested part exists.
+func (h *StreamingHandler) blockForPart(r *http.Request, contentID, quality string) error {
+	msnParam := r.URL.Query().Get("_HLS_msn")
+	if msnParam == "" {
+		return nil
+	}
+	msn, err := strconv.Atoi(msnParam)
+	if err != nil || msn < 0 {
+		return errInvalidBlockingRequest
+	}
+	part := -1
+	if partParam := r.URL.Query().Get("_HLS_part"); partParam != "" {
+		if part, err = strconv.Atoi(partParam); err != nil || part < 0 {
+			return errInvalidBlockingRequest
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), blockingRequestTimeout)
+	defer cancel()
+	return h.packager.WaitForPart(ctx, contentID, quality, msn, part)
+}
+
+// writePackagerError maps packager errors to 404 for missing content, 400
+// for bad blocking requests, 503 when a blocking request times out and 502
+// for storage failures.
+func (h *StreamingHandler) writePackagerError(w http.ResponseWriter, contentID string, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case errors.Is(err, ErrContentNotFound) || errors.Is(err, ErrRenditionNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	case errors.Is(err, ErrPartTooFar) || errors.Is(err, errInvalidBlockingRequest):
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	case errors.Is(err, context.DeadlineExceeded):
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "part not available yet"})
+		return
+	}
+	h.logger.Error("Failed to read stream from storage", zap.String("content_id", contentID), zap.Error(err))
+	w.WriteHeader(http.StatusBadGateway)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": "storage unavailable"})
+}
+
+func writeStorageUnavailable(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": "stream storage not configured"})
 }
 
 // GetStreamInfoHandler handles stream info requests
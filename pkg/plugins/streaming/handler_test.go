@@ -24,7 +24,8 @@ func newTestStreamingHandler(t *testing.T) *StreamingHandler {
 	kernel, err := core.NewMicrokernel(&config.Config{Mode: "monolith"}, zap.NewNop())
 	require.NoError(t, err)
 	cache := NewStreamCache(zap.NewNop())
-	return NewStreamingHandler(cache, zap.NewNop(), kernel)
+	packager := NewHLSPackager(newFakeSegmentStore(), "streamgate", cache, zap.NewNop())
+	return NewStreamingHandler(cache, packager, zap.NewNop(), kernel)
 }
 
 func TestStreamingHandler_HealthHandler_Healthy(t *testing.T) {
@@ -82,6 +83,7 @@ func TestStreamingHandler_GetHLSPlaylistHandler_Success(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rec.Code)
 	assert.Equal(t, "application/vnd.apple.mpegurl", rec.Header().Get("Content-Type"))
 	assert.Contains(t, rec.Body.String(), "#EXTM3U")
+	assert.Contains(t, rec.Body.String(), "#EXT-X-STREAM-INF")
 }
 
 func TestStreamingHandler_GetDASHManifestHandler_MethodNotAllowed(t *testing.T) {
@@ -144,13 +146,54 @@ func TestStreamingHandler_GetSegmentHandler_MissingIDs(t *testing.T) {
 func TestStreamingHandler_GetSegmentHandler_Success(t *testing.T) {
 	handler := newTestStreamingHandler(t)
 
-	req := httptest.NewRequest(http.MethodGet, "/segment?content_id=test-123&segment_id=seg-1", http.NoBody)
+	req := httptest.NewRequest(http.MethodGet, "/segment?content_id=test-123&quality=720p&segment_id="+testSegment("720p", 0), http.NoBody)
 	rec := httptest.NewRecorder()
 
 	handler.GetSegmentHandler(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 	assert.Equal(t, "video/mp2t", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "bytes", rec.Header().Get("Accept-Ranges"))
+	assert.Contains(t, rec.Header().Get("Cache-Control"), "immutable")
+	assert.Equal(t, "720p-seg-0", rec.Body.String())
+}
+
+func TestStreamingHandler_GetSegmentHandler_Range(t *testing.T) {
+	handler := newTestStreamingHandler(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/segment?content_id=test-123&quality=720p&segment_id="+testSegment("720p", 0), http.NoBody)
+	req.Header.Set("Range", "bytes=0-3")
+	rec := httptest.NewRecorder()
+
+	handler.GetSegmentHandler(rec, req)
+
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, "bytes 0-3/10", rec.Header().Get("Content-Range"))
+	assert.Equal(t, "720p", rec.Body.String())
+}
+
+func TestStreamingHandler_GetSegmentHandler_NotFound(t *testing.T) {
+	handler := newTestStreamingHandler(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/segment?content_id=test-123&quality=720p&segment_id=../../secret.ts", http.NoBody)
+	rec := httptest.NewRecorder()
+
+	handler.GetSegmentHandler(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestStreamingHandler_GetHLSPlaylistHandler_NoStorage(t *testing.T) {
+	kernel, err := core.NewMicrokernel(&config.Config{Mode: "monolith"}, zap.NewNop())
+	require.NoError(t, err)
+	handler := NewStreamingHandler(NewStreamCache(zap.NewNop()), nil, zap.NewNop(), kernel)
+
+	req := httptest.NewRequest(http.MethodGet, "/hls?content_id=test-123", http.NoBody)
+	rec := httptest.NewRecorder()
+
+	handler.GetHLSPlaylistHandler(rec, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
 }
 
 func TestStreamingHandler_GetStreamInfoHandler_MethodNotAllowed(t *testing.T) {
@@ -200,13 +243,6 @@ func TestStreamingHandler_NotFoundHandler(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, rec.Code)
 }
 
-func TestHLSGenerator_Generate(t *testing.T) {
-	gen := &HLSGenerator{}
-	result, err := gen.Generate("content-1")
-	require.NoError(t, err)
-	assert.Contains(t, result, "#EXTM3U")
-}
-
 func TestDASHGenerator_Generate(t *testing.T) {
 	gen := &DASHGenerator{}
 	result, err := gen.Generate("content-1")
@@ -222,7 +258,12 @@ func TestStreamCache_Operations(t *testing.T) {
 	assert.Nil(t, val)
 
 	cache.Set("key1", "value1")
+	val, ok = cache.Get("key1")
+	assert.True(t, ok)
+	assert.Equal(t, "value1", val)
 	cache.Delete("key1")
+	_, ok = cache.Get("key1")
+	assert.False(t, ok)
 
 	cache.Close()
 }
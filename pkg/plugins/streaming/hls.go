@@ -1,9 +1,553 @@
 package streaming
 
-// HLSGenerator generates HLS playlists
-type HLSGenerator struct{}
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
-// Generate generates HLS playlist
-func (g *HLSGenerator) Generate(contentID string) (string, error) {
-	return "#EXTM3U\n#EXT-X-VERSION:3\n", nil
+	"github.com/rtcdance/streamgate/pkg/plugins/keys"
+	"github.com/rtcdance/streamgate/pkg/plugins/transcoder"
+
+	"go.uber.org/zap"
+)
+
+// defaultSegmentDuration is the transcoder's -hls_time, used when a
+// rendition has no playlist to read segment durations from.
+const defaultSegmentDuration = 6.0
+
+var (
+	// ErrContentNotFound is returned when storage holds no renditions for
+	// the content, e.g. because transcoding has not finished.
+	ErrContentNotFound = errors.New("content not found")
+	// ErrRenditionNotFound is returned for an unknown quality or segment.
+	ErrRenditionNotFound = errors.New("rendition not found")
+)
+
+// SegmentStore is the object storage the packager reads renditions from.
+type SegmentStore interface {
+	ListObjects(ctx context.Context, bucket, prefix string) ([]string, error)
+	Download(ctx context.Context, bucket, objectName string) ([]byte, error)
+}
+
+// Segment is one media segment of a rendition.
+type Segment struct {
+	Name     string
+	Duration float64
+}
+
+// Rendition is one transcoded quality, stored under
+// streams/<content>/<quality>/ by the transcoding service.
+type Rendition struct {
+	Quality  string
+	Segments []Segment
+
+	// LowLatency renditions are stored as LL-HLS parts. Segments then lists
+	// the parent segments the parts are grouped into, which the packager
+	// assembles on request.
+	LowLatency bool
+	Parts      []Part
+	PartTarget float64
+	// Complete is false while a low-latency rendition is still being
+	// written, i.e. until the transcoder stores its playlist.
+	Complete bool
+
+	// FMP4 renditions are DRM-packaged, HEVC or AV1 rungs. Their playlists,
+	// DRM key tags included, are written by the transcoder and served as
+	// stored; Segments is empty.
+	FMP4 bool
+	// Codec is the rendition's video codec family, from its quality name.
+	Codec string
+	// HasAudio is set for fMP4 renditions whose audio is a separate
+	// playlist, as shaka-packager writes it.
+	HasAudio bool
+	// AudioOnly renditions are alternate audio tracks, which the video
+	// renditions reference as their AUDIO group instead of being listed
+	// as variants. Language is the track's language tag.
+	AudioOnly bool
+	Language  string
+	// Subtitles renditions are WebVTT subtitles uploaded for the content,
+	// one file covering the whole presentation, in Language.
+	Subtitles bool
+	playlist  string
+	audio     string
+
+	// stored holds every segment in storage, including those of older
+	// transcode runs, so players holding a previous playlist can finish.
+	stored map[string]bool
+	// parentParts maps a parent segment name to its parts.
+	parentParts map[string][]string
+}
+
+// HLSPackager builds master and media playlists from the renditions in
+// object storage and reads their segments.
+type HLSPackager struct {
+	store  SegmentStore
+	bucket string
+	cache  *StreamCache
+	logger *zap.Logger
+	// keys holds the content keys of encrypted renditions; nil when
+	// content encryption is off.
+	keys *keys.KeyStore
+
+	// pollInterval paces LL-HLS blocking requests; defaultPartPollInterval
+	// when zero.
+	pollInterval time.Duration
+}
+
+// NewHLSPackager creates a packager over bucket. Rendition listings are
+// cached in cache, which may be nil.
+func NewHLSPackager(store SegmentStore, bucket string, cache *StreamCache, logger *zap.Logger) *HLSPackager {
+	return &HLSPackager{store: store, bucket: bucket, cache: cache, logger: logger}
+}
+
+// subtitleQualityPrefix starts the rendition directory of each subtitle
+// language, e.g. "subtitles-eng", as the upload service stores them.
+const subtitleQualityPrefix = "subtitles-"
+
+// subtitleGroupID is the EXT-X-MEDIA group of the subtitle renditions.
+const subtitleGroupID = "subs"
+
+func contentPrefix(contentID string) string {
+	return fmt.Sprintf("streams/%s/", contentID)
+}
+
+// Renditions lists the content's renditions, ordered by quality name, with
+// segments in playback order. Only the newest transcode run's segments are
+// included.
+func (p *HLSPackager) Renditions(ctx context.Context, contentID string) ([]Rendition, error) {
+	return p.renditions(ctx, contentID, false)
+}
+
+// renditions reads the listing from storage when fresh is set or nothing is
+// cached. Listings with a low-latency rendition still being written are not
+// cached, since they change with every part.
+func (p *HLSPackager) renditions(ctx context.Context, contentID string, fresh bool) ([]Rendition, error) {
+	cacheKey := "renditions:" + contentID
+	if p.cache != nil && !fresh {
+		if cached, ok := p.cache.Get(cacheKey); ok {
+			return cached.([]Rendition), nil
+		}
+	}
+
+	prefix := contentPrefix(contentID)
+	keys, err := p.store.ListObjects(ctx, p.bucket, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list renditions: %w", err)
+	}
+
+	segments := make(map[string][]string)
+	fmp4 := make(map[string][]string)
+	playlists := make(map[string]string)
+	audioPlaylists := make(map[string]string)
+	subtitles := make(map[string]string)
+	for _, key := range keys {
+		rel := strings.TrimPrefix(key, prefix)
+		quality, name := path.Split(rel)
+		quality = strings.TrimSuffix(quality, "/")
+		if quality == "" || strings.Contains(quality, "/") {
+			continue
+		}
+		switch path.Ext(name) {
+		case ".ts":
+			segments[quality] = append(segments[quality], name)
+		case ".m4s", ".mp4":
+			fmp4[quality] = append(fmp4[quality], name)
+		case ".vtt":
+			if strings.HasPrefix(quality, subtitleQualityPrefix) {
+				subtitles[quality] = name
+			}
+		case ".m3u8":
+			switch {
+			case name == "master.m3u8":
+			case strings.HasSuffix(name, "_audio.m3u8"):
+				audioPlaylists[quality] = key
+			default:
+				playlists[quality] = key
+			}
+		}
+	}
+	if len(segments) == 0 && len(fmp4) == 0 {
+		return nil, ErrContentNotFound
+	}
+
+	renditions := make([]Rendition, 0, len(segments)+len(fmp4))
+	for quality, stored := range fmp4 {
+		if playlists[quality] == "" {
+			// Still being packaged; the playlist is written last.
+			continue
+		}
+		// A rung re-transcoded with DRM is never served in the clear
+		// again, so its older MPEG-TS segments are left unlisted.
+		delete(segments, quality)
+		_, codec := transcoder.ParseVariantName(quality)
+		rendition := Rendition{
+			Quality:  quality,
+			Complete: true,
+			FMP4:     true,
+			Codec:    codec,
+			HasAudio: audioPlaylists[quality] != "",
+			playlist: playlists[quality],
+			audio:    audioPlaylists[quality],
+			stored:   make(map[string]bool, len(stored)),
+		}
+		for _, name := range stored {
+			rendition.stored[name] = true
+		}
+		renditions = append(renditions, rendition)
+	}
+	cacheable := true
+	for quality, stored := range segments {
+		names := transcoder.FilterLatestSegments(stored)
+		sort.Slice(names, func(i, j int) bool {
+			return segmentNumber(names[i]) < segmentNumber(names[j])
+		})
+		durations := p.readDurations(ctx, playlists[quality])
+		rendition := Rendition{Quality: quality, Codec: transcoder.CodecH264, Complete: true, stored: make(map[string]bool, len(stored))}
+		if _, language, ok := transcoder.ParseAudioRenditionName(quality); ok {
+			rendition.AudioOnly = true
+			rendition.Language = language
+		}
+		for _, name := range stored {
+			rendition.stored[name] = true
+		}
+		if len(names) > 0 && transcoder.IsSegmentPart(names[0]) {
+			rendition.groupParts(names, durations, playlists[quality] != "")
+			cacheable = cacheable && rendition.Complete
+		} else {
+			rendition.Segments = make([]Segment, len(names))
+			for i, name := range names {
+				d, ok := durations[name]
+				if !ok {
+					d = defaultSegmentDuration
+				}
+				rendition.Segments[i] = Segment{Name: name, Duration: d}
+			}
+		}
+		renditions = append(renditions, rendition)
+	}
+	if len(renditions) == 0 {
+		return nil, ErrContentNotFound
+	}
+	for quality, name := range subtitles {
+		renditions = append(renditions, Rendition{
+			Quality:   quality,
+			Complete:  true,
+			Subtitles: true,
+			Language:  strings.TrimPrefix(quality, subtitleQualityPrefix),
+			Segments:  []Segment{{Name: name}},
+			stored:    map[string]bool{name: true},
+		})
+	}
+	sort.Slice(renditions, func(i, j int) bool { return renditions[i].Quality < renditions[j].Quality })
+
+	if p.cache != nil {
+		if cacheable {
+			p.cache.Set(cacheKey, renditions)
+		} else {
+			p.cache.Delete(cacheKey)
+		}
+	}
+	return renditions, nil
+}
+
+// readDurations reads EXTINF durations from the playlist the transcoder
+// stored next to a rendition's segments. A missing or unreadable playlist
+// yields an empty map.
+func (p *HLSPackager) readDurations(ctx context.Context, key string) map[string]float64 {
+	durations := make(map[string]float64)
+	if key == "" {
+		return durations
+	}
+	data, err := p.store.Download(ctx, p.bucket, key)
+	if err != nil {
+		p.logger.Debug("Rendition playlist unavailable, using default segment duration",
+			zap.String("playlist", key), zap.Error(err))
+		return durations
+	}
+
+	var pending float64
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "#EXTINF:"):
+			value, _, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			pending, _ = strconv.ParseFloat(value, 64)
+		case line == "" || strings.HasPrefix(line, "#"):
+		default:
+			if pending > 0 {
+				durations[path.Base(line)] = pending
+			}
+			pending = 0
+		}
+	}
+	return durations
+}
+
+// MasterPlaylist renders the multivariant playlist. Variant URIs point at
+// the plugin's HLS endpoint; query carries any parameters that must be
+// forwarded, such as a playback token. Alternate audio renditions form
+// the AUDIO group of every variant without an audio playlist of its own.
+func (p *HLSPackager) MasterPlaylist(ctx context.Context, contentID string, query url.Values) (string, error) {
+	renditions, err := p.Renditions(ctx, contentID)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+	alternateAudio, subtitles := false, false
+	for _, r := range renditions {
+		uri := playlistURL("/api/v1/stream/hls", contentID, r.Quality, "", query)
+		switch {
+		case r.AudioOnly:
+			position, _, _ := transcoder.ParseAudioRenditionName(r.Quality)
+			b.WriteString(transcoder.AudioMediaTag(position, r.Language, r.Language, uri))
+			alternateAudio = true
+		case r.Subtitles:
+			fmt.Fprintf(&b, "#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID=\"%s\",LANGUAGE=\"%s\",NAME=\"%s\",DEFAULT=NO,AUTOSELECT=YES,URI=\"%s\"\n",
+				subtitleGroupID, r.Language, r.Language, uri)
+			subtitles = true
+		}
+	}
+	for _, r := range renditions {
+		if r.AudioOnly || r.Subtitles {
+			continue
+		}
+		if r.HasAudio {
+			fmt.Fprintf(&b, "#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"audio-%s\",NAME=\"audio\",DEFAULT=YES,AUTOSELECT=YES,URI=\"%s\"\n",
+				r.Quality, playlistURL("/api/v1/stream/hls", contentID, r.Quality+audioTrackSuffix, "", query))
+		}
+		fmt.Fprintf(&b, "#EXT-X-STREAM-INF:BANDWIDTH=%d", bandwidthForQuality(r.Quality))
+		if resolution := resolutionForQuality(r.Quality); resolution != "" {
+			_, height, _ := strings.Cut(resolution, "x")
+			h, _ := strconv.Atoi(height)
+			fmt.Fprintf(&b, ",RESOLUTION=%s,CODECS=\"%s\"", resolution, transcoder.HLSCodecs(r.Codec, h))
+		}
+		if r.HasAudio {
+			fmt.Fprintf(&b, ",AUDIO=\"audio-%s\"", r.Quality)
+		} else if alternateAudio {
+			fmt.Fprintf(&b, ",AUDIO=\"%s\"", transcoder.AudioGroupID)
+		}
+		if subtitles {
+			fmt.Fprintf(&b, ",SUBTITLES=\"%s\"", subtitleGroupID)
+		}
+		b.WriteString("\n")
+		b.WriteString(playlistURL("/api/v1/stream/hls", contentID, r.Quality, "", query))
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+// MediaPlaylist renders the playlist of one rendition: a VOD playlist, or
+// an LL-HLS playlist for low-latency renditions.
+func (p *HLSPackager) MediaPlaylist(ctx context.Context, contentID, quality string, query url.Values) (string, error) {
+	quality, audio := strings.CutSuffix(quality, audioTrackSuffix)
+	rendition, err := p.rendition(ctx, contentID, quality)
+	if err != nil {
+		return "", err
+	}
+	if rendition.Subtitles && !audio {
+		return p.subtitlePlaylist(ctx, rendition, contentID, query)
+	}
+	if rendition.FMP4 {
+		return p.storedPlaylist(ctx, rendition, contentID, audio, query)
+	}
+	if audio {
+		return "", ErrRenditionNotFound
+	}
+	// Without the key tag players could not decrypt the segments, so an
+	// unreadable key fails the playlist.
+	key, err := p.contentKey(contentID)
+	if err != nil {
+		return "", err
+	}
+	if rendition.LowLatency {
+		return lowLatencyPlaylist(rendition, contentID, keyTag(key, query), query), nil
+	}
+
+	target := 0.0
+	for _, s := range rendition.Segments {
+		target = math.Max(target, s.Duration)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:%d\n#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-PLAYLIST-TYPE:VOD\n", int(math.Ceil(target)))
+	b.WriteString(keyTag(key, query))
+	for _, s := range rendition.Segments {
+		fmt.Fprintf(&b, "#EXTINF:%.3f,\n", s.Duration)
+		b.WriteString(playlistURL("/api/v1/stream/segment", contentID, quality, s.Name, query))
+		b.WriteString("\n")
+	}
+	b.WriteString("#EXT-X-ENDLIST\n")
+	return b.String(), nil
+}
+
+// subtitlePlaylist renders the playlist of a subtitle rendition: its
+// WebVTT file as a single segment lasting the whole presentation, which is
+// as long as the longest video rendition.
+func (p *HLSPackager) subtitlePlaylist(ctx context.Context, rendition Rendition, contentID string, query url.Values) (string, error) {
+	renditions, err := p.Renditions(ctx, contentID)
+	if err != nil {
+		return "", err
+	}
+	duration := 0.0
+	for _, r := range renditions {
+		if r.AudioOnly || r.Subtitles {
+			continue
+		}
+		total := 0.0
+		if r.FMP4 {
+			for _, d := range p.readDurations(ctx, r.playlist) {
+				total += d
+			}
+		}
+		for _, s := range r.Segments {
+			total += s.Duration
+		}
+		duration = math.Max(duration, total)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:%d\n#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-PLAYLIST-TYPE:VOD\n", int(math.Ceil(duration)))
+	fmt.Fprintf(&b, "#EXTINF:%.3f,\n", duration)
+	b.WriteString(playlistURL("/api/v1/stream/segment", contentID, rendition.Quality, rendition.Segments[0].Name, query))
+	b.WriteString("\n#EXT-X-ENDLIST\n")
+	return b.String(), nil
+}
+
+// Segment reads one segment. Only segments present in the rendition's
+// listing can be read, which also rules out path traversal through the
+// segment name.
+func (p *HLSPackager) Segment(ctx context.Context, contentID, quality, name string) ([]byte, error) {
+	rendition, err := p.rendition(ctx, contentID, quality)
+	if err != nil {
+		return nil, err
+	}
+	if !rendition.Complete && !rendition.stored[name] && name == rendition.nextPartName() {
+		// Preload hint: hold the request until the part is written.
+		if err := p.awaitPart(ctx, contentID, quality, name); err != nil {
+			return nil, err
+		}
+		if rendition, err = p.findRendition(ctx, contentID, quality, true); err != nil {
+			return nil, err
+		}
+	}
+	prefix := contentPrefix(contentID) + quality + "/"
+	if rendition.stored[name] {
+		return p.store.Download(ctx, p.bucket, prefix+name)
+	}
+	parts, ok := rendition.parentParts[name]
+	if !ok {
+		return nil, ErrRenditionNotFound
+	}
+	key, err := p.contentKey(contentID)
+	if err != nil {
+		return nil, err
+	}
+	chunks := make([][]byte, 0, len(parts))
+	for _, part := range parts {
+		chunk, err := p.store.Download(ctx, p.bucket, prefix+part)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+	if key != nil {
+		return reencryptParts(key, chunks)
+	}
+	// A parent segment is its parts back to back; MPEG-TS concatenates.
+	return bytes.Join(chunks, nil), nil
+}
+
+func (p *HLSPackager) rendition(ctx context.Context, contentID, quality string) (Rendition, error) {
+	return p.findRendition(ctx, contentID, quality, false)
+}
+
+func (p *HLSPackager) findRendition(ctx context.Context, contentID, quality string, fresh bool) (Rendition, error) {
+	renditions, err := p.renditions(ctx, contentID, fresh)
+	if err != nil {
+		return Rendition{}, err
+	}
+	for _, r := range renditions {
+		if r.Quality == quality {
+			return r, nil
+		}
+	}
+	return Rendition{}, ErrRenditionNotFound
+}
+
+func playlistURL(endpoint, contentID, quality, segment string, query url.Values) string {
+	q := url.Values{}
+	for k, v := range query {
+		q[k] = v
+	}
+	q.Set("content_id", contentID)
+	q.Set("quality", quality)
+	if segment != "" {
+		q.Set("segment_id", segment)
+	}
+	return endpoint + "?" + q.Encode()
+}
+
+// segmentNumber returns the trailing number of a segment name, e.g. 12 for
+// "720p_v3_012.ts".
+func segmentNumber(name string) int {
+	base := strings.TrimSuffix(name, path.Ext(name))
+	end := len(base)
+	for end > 0 && base[end-1] >= '0' && base[end-1] <= '9' {
+		end--
+	}
+	n, _ := strconv.Atoi(base[end:])
+	return n
+}
+
+// resolutionForQuality maps "720p", "1280x720" or "1280x720-hevc" to a
+// RESOLUTION value.
+func resolutionForQuality(quality string) string {
+	quality, _ = transcoder.ParseVariantName(quality)
+	if strings.Contains(quality, "x") {
+		return quality
+	}
+	switch quality {
+	case "1080p":
+		return "1920x1080"
+	case "720p":
+		return "1280x720"
+	case "480p":
+		return "854x480"
+	case "360p":
+		return "640x360"
+	}
+	return ""
+}
+
+// bandwidthForQuality estimates the peak bit rate from the frame height.
+func bandwidthForQuality(quality string) int {
+	quality, _ = transcoder.ParseVariantName(quality)
+	height := 0
+	if _, h, ok := strings.Cut(quality, "x"); ok {
+		height, _ = strconv.Atoi(h)
+	} else {
+		height, _ = strconv.Atoi(strings.TrimSuffix(quality, "p"))
+	}
+	switch {
+	case height >= 1080:
+		return 5000000
+	case height >= 720:
+		return 2800000
+	case height >= 480:
+		return 1400000
+	default:
+		return 800000
+	}
 }
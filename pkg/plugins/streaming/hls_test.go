@@ -0,0 +1,213 @@
+package streaming
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/plugins/transcoder"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeSegmentStore struct {
+	objects map[string][]byte
+	lists   int
+	listErr error
+}
+
+var (
+	testOldVersion = transcoder.NewSegmentVersion(time.Unix(1700000000, 0))
+	testVersion    = transcoder.NewSegmentVersion(time.Unix(1800000000, 0))
+)
+
+// newFakeSegmentStore holds content "test-123" in two renditions, with a
+// leftover segment from an older transcode run in 720p.
+func newFakeSegmentStore() *fakeSegmentStore {
+	store := &fakeSegmentStore{objects: make(map[string][]byte)}
+	for _, q := range []string{"480p", "720p"} {
+		for i := 0; i < 3; i++ {
+			name := "streams/test-123/" + q + "/" + transcoder.SegmentName(q, testVersion, i)
+			store.objects[name] = []byte(fmt.Sprintf("%s-seg-%d", q, i))
+		}
+	}
+	store.objects["streams/test-123/720p/"+transcoder.SegmentName("720p", testOldVersion, 0)] = []byte("stale")
+	store.objects["streams/test-123/720p/720p.m3u8"] = []byte(fmt.Sprintf(
+		"#EXTM3U\n#EXT-X-TARGETDURATION:7\n#EXTINF:6.006,\n%s\n#EXTINF:6.5,\n%s\n#EXTINF:2.25,\n%s\n#EXT-X-ENDLIST\n",
+		testSegment("720p", 0), testSegment("720p", 1), testSegment("720p", 2)))
+	return store
+}
+
+func testSegment(quality string, seq int) string {
+	return transcoder.SegmentName(quality, testVersion, seq)
+}
+
+func (s *fakeSegmentStore) ListObjects(_ context.Context, _, prefix string) ([]string, error) {
+	s.lists++
+	if s.listErr != nil {
+		return nil, s.listErr
+	}
+	var keys []string
+	for k := range s.objects {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func (s *fakeSegmentStore) Download(_ context.Context, _, name string) ([]byte, error) {
+	data, ok := s.objects[name]
+	if !ok {
+		return nil, errors.New("object not found")
+	}
+	return data, nil
+}
+
+func TestHLSPackager_Renditions(t *testing.T) {
+	p := NewHLSPackager(newFakeSegmentStore(), "streamgate", nil, zap.NewNop())
+
+	renditions, err := p.Renditions(context.Background(), "test-123")
+	require.NoError(t, err)
+	require.Len(t, renditions, 2)
+	assert.Equal(t, "480p", renditions[0].Quality)
+	assert.Equal(t, "720p", renditions[1].Quality)
+
+	hd := renditions[1].Segments
+	require.Len(t, hd, 3)
+	assert.Equal(t, Segment{Name: testSegment("720p", 0), Duration: 6.006}, hd[0])
+	assert.Equal(t, Segment{Name: testSegment("720p", 2), Duration: 2.25}, hd[2])
+	assert.Equal(t, defaultSegmentDuration, renditions[0].Segments[0].Duration)
+}
+
+func TestHLSPackager_ContentNotFound(t *testing.T) {
+	p := NewHLSPackager(newFakeSegmentStore(), "streamgate", nil, zap.NewNop())
+
+	_, err := p.MasterPlaylist(context.Background(), "missing", nil)
+	assert.ErrorIs(t, err, ErrContentNotFound)
+
+	_, err = p.MediaPlaylist(context.Background(), "test-123", "1080p", nil)
+	assert.ErrorIs(t, err, ErrRenditionNotFound)
+}
+
+func TestHLSPackager_MasterPlaylist(t *testing.T) {
+	p := NewHLSPackager(newFakeSegmentStore(), "streamgate", nil, zap.NewNop())
+
+	playlist, err := p.MasterPlaylist(context.Background(), "test-123", url.Values{"token": {"abc"}})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(playlist, "#EXTM3U\n"))
+	assert.Contains(t, playlist, `#EXT-X-STREAM-INF:BANDWIDTH=2800000,RESOLUTION=1280x720,CODECS="avc1.64001f,mp4a.40.2"`+"\n")
+	assert.Contains(t, playlist, "/api/v1/stream/hls?content_id=test-123&quality=720p&token=abc\n")
+}
+
+func TestHLSPackager_HEVCRendition(t *testing.T) {
+	store := newFakeSegmentStore()
+	for name, data := range map[string]string{
+		"1920x1080-hevc/1920x1080-hevc.m3u8":        "#EXTM3U\n#EXT-X-MAP:URI=\"1920x1080-hevc_v1_init.mp4\"\n#EXTINF:6.000,\n1920x1080-hevc_v1_000.m4s\n#EXT-X-ENDLIST\n",
+		"1920x1080-hevc/1920x1080-hevc_v1_init.mp4": "init",
+		"1920x1080-hevc/1920x1080-hevc_v1_000.m4s":  "frag-0",
+	} {
+		store.objects["streams/test-123/"+name] = []byte(data)
+	}
+	p := NewHLSPackager(store, "streamgate", nil, zap.NewNop())
+
+	master, err := p.MasterPlaylist(context.Background(), "test-123", nil)
+	require.NoError(t, err)
+	assert.Contains(t, master, `RESOLUTION=1920x1080,CODECS="hvc1.1.6.L120.90,mp4a.40.2"`+"\n")
+	assert.Contains(t, master, `RESOLUTION=1280x720,CODECS="avc1.64001f,mp4a.40.2"`+"\n")
+
+	playlist, err := p.MediaPlaylist(context.Background(), "test-123", "1920x1080-hevc", nil)
+	require.NoError(t, err)
+	assert.Contains(t, playlist, "segment_id=1920x1080-hevc_v1_init.mp4")
+	assert.Contains(t, playlist, "\n/api/v1/stream/segment?content_id=test-123&quality=1920x1080-hevc&segment_id=1920x1080-hevc_v1_000.m4s\n")
+}
+
+func TestHLSPackager_AlternateAudio(t *testing.T) {
+	store := newFakeSegmentStore()
+	for _, quality := range []string{"audio-0-eng", "audio-1-fra"} {
+		store.objects["streams/test-123/"+quality+"/"+testSegment(quality, 0)] = []byte("audio")
+	}
+	p := NewHLSPackager(store, "streamgate", nil, zap.NewNop())
+
+	master, err := p.MasterPlaylist(context.Background(), "test-123", nil)
+	require.NoError(t, err)
+	assert.Contains(t, master, `#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="audio",LANGUAGE="eng",NAME="eng",DEFAULT=YES,AUTOSELECT=YES,URI="/api/v1/stream/hls?content_id=test-123&quality=audio-0-eng"`+"\n")
+	assert.Contains(t, master, `LANGUAGE="fra",NAME="fra",DEFAULT=NO,`)
+	assert.Contains(t, master, `RESOLUTION=1280x720,CODECS="avc1.64001f,mp4a.40.2",AUDIO="audio"`+"\n")
+	assert.NotContains(t, master, "\n/api/v1/stream/hls?content_id=test-123&quality=audio-0-eng\n", "audio renditions are not variants")
+
+	playlist, err := p.MediaPlaylist(context.Background(), "test-123", "audio-1-fra", nil)
+	require.NoError(t, err)
+	assert.Contains(t, playlist, "quality=audio-1-fra&segment_id="+testSegment("audio-1-fra", 0))
+}
+
+func TestHLSPackager_Subtitles(t *testing.T) {
+	store := newFakeSegmentStore()
+	store.objects["streams/test-123/subtitles-eng/subtitles.vtt"] = []byte("WEBVTT\n\n00:01.000 --> 00:02.000\nHi\n")
+	p := NewHLSPackager(store, "streamgate", nil, zap.NewNop())
+
+	master, err := p.MasterPlaylist(context.Background(), "test-123", nil)
+	require.NoError(t, err)
+	assert.Contains(t, master, `#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",LANGUAGE="eng",NAME="eng",DEFAULT=NO,AUTOSELECT=YES,URI="/api/v1/stream/hls?content_id=test-123&quality=subtitles-eng"`+"\n")
+	assert.Contains(t, master, `CODECS="avc1.64001f,mp4a.40.2",SUBTITLES="subs"`+"\n")
+	assert.NotContains(t, master, "\n/api/v1/stream/hls?content_id=test-123&quality=subtitles-eng\n", "subtitles are not variants")
+
+	playlist, err := p.MediaPlaylist(context.Background(), "test-123", "subtitles-eng", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(playlist, "#EXTINF:"))
+	assert.NotContains(t, playlist, "#EXTINF:0.000,", "the cue file lasts as long as the video")
+	assert.Contains(t, playlist, "\n/api/v1/stream/segment?content_id=test-123&quality=subtitles-eng&segment_id=subtitles.vtt\n#EXT-X-ENDLIST\n")
+
+	vtt, err := p.Segment(context.Background(), "test-123", "subtitles-eng", "subtitles.vtt")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(vtt), "WEBVTT"))
+}
+
+func TestHLSPackager_MediaPlaylist(t *testing.T) {
+	p := NewHLSPackager(newFakeSegmentStore(), "streamgate", nil, zap.NewNop())
+
+	playlist, err := p.MediaPlaylist(context.Background(), "test-123", "720p", nil)
+	require.NoError(t, err)
+	assert.Contains(t, playlist, "#EXT-X-TARGETDURATION:7\n")
+	assert.Contains(t, playlist, "#EXTINF:6.006,\n/api/v1/stream/segment?content_id=test-123&quality=720p&segment_id="+testSegment("720p", 0)+"\n")
+	assert.NotContains(t, playlist, testOldVersion)
+	assert.True(t, strings.HasSuffix(playlist, "#EXT-X-ENDLIST\n"))
+}
+
+func TestHLSPackager_CachesListing(t *testing.T) {
+	store := newFakeSegmentStore()
+	p := NewHLSPackager(store, "streamgate", NewStreamCache(zap.NewNop()), zap.NewNop())
+
+	_, err := p.MasterPlaylist(context.Background(), "test-123", nil)
+	require.NoError(t, err)
+	_, err = p.Segment(context.Background(), "test-123", "480p", testSegment("480p", 1))
+	require.NoError(t, err)
+	assert.Equal(t, 1, store.lists)
+}
+
+func TestHLSPackager_SegmentFromOlderRun(t *testing.T) {
+	p := NewHLSPackager(newFakeSegmentStore(), "streamgate", nil, zap.NewNop())
+
+	data, err := p.Segment(context.Background(), "test-123", "720p", transcoder.SegmentName("720p", testOldVersion, 0))
+	require.NoError(t, err)
+	assert.Equal(t, "stale", string(data))
+
+	_, err = p.Segment(context.Background(), "test-123", "720p", "../480p/"+testSegment("480p", 0))
+	assert.ErrorIs(t, err, ErrRenditionNotFound)
+}
+
+func TestHLSPackager_ListError(t *testing.T) {
+	store := newFakeSegmentStore()
+	store.listErr = errors.New("connection refused")
+	p := NewHLSPackager(store, "streamgate", nil, zap.NewNop())
+
+	_, err := p.Renditions(context.Background(), "test-123")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrContentNotFound)
+}
@@ -0,0 +1,170 @@
+package streaming
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/storage"
+
+	"go.uber.org/zap"
+)
+
+// maxGatewayObjectSize caps what is read from a gateway for one object;
+// segments and playlists are far smaller.
+const maxGatewayObjectSize = 64 << 20
+
+// IPFSObjectStore is IPFS-backed object storage: objects are listed by key
+// and resolved to the CID they are fetched by.
+type IPFSObjectStore interface {
+	SegmentStore
+	storage.ContentAddresser
+}
+
+// IPFSGatewayStore is a SegmentStore over IPFS storage that lists
+// renditions through the node but fetches their bytes by CID from HTTP
+// gateways, falling back to the node when every gateway fails. Gateways
+// are trusted to return the bytes the CID names. Fetched objects are cached
+// by CID, which always names the same bytes, so entries never go stale and
+// are only evicted for space.
+type IPFSGatewayStore struct {
+	store    IPFSObjectStore
+	gateways []string
+	client   *http.Client
+	cache    *cidCache
+	logger   *zap.Logger
+}
+
+// NewIPFSGatewayStore creates a gateway store caching up to cacheBytes of
+// fetched objects.
+func NewIPFSGatewayStore(store IPFSObjectStore, gateways []string, cacheBytes int64, logger *zap.Logger) *IPFSGatewayStore {
+	trimmed := make([]string, 0, len(gateways))
+	for _, gw := range gateways {
+		if gw = strings.TrimSuffix(strings.TrimSpace(gw), "/"); gw != "" {
+			trimmed = append(trimmed, gw)
+		}
+	}
+	return &IPFSGatewayStore{
+		store:    store,
+		gateways: trimmed,
+		client:   &http.Client{Timeout: 20 * time.Second},
+		cache:    newCIDCache(cacheBytes),
+		logger:   logger,
+	}
+}
+
+// ListObjects lists objects through the node.
+func (s *IPFSGatewayStore) ListObjects(ctx context.Context, bucket, prefix string) ([]string, error) {
+	return s.store.ListObjects(ctx, bucket, prefix)
+}
+
+// Download resolves the object's CID and returns its bytes from the cache,
+// a gateway or, failing those, the node.
+func (s *IPFSGatewayStore) Download(ctx context.Context, bucket, objectName string) ([]byte, error) {
+	cid, err := s.store.CID(ctx, bucket, objectName)
+	if err != nil {
+		return nil, err
+	}
+	if data, ok := s.cache.get(cid); ok {
+		return data, nil
+	}
+	for _, gw := range s.gateways {
+		data, err := s.fetch(ctx, gw, cid)
+		if err == nil {
+			s.cache.add(cid, data)
+			return data, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		s.logger.Debug("IPFS gateway fetch failed",
+			zap.String("gateway", gw), zap.String("cid", cid), zap.Error(err))
+	}
+
+	data, err := s.store.Download(ctx, bucket, objectName)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.add(cid, data)
+	return data, nil
+}
+
+func (s *IPFSGatewayStore) fetch(ctx context.Context, gateway, cid string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gateway+"/ipfs/"+cid, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gateway returned %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxGatewayObjectSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxGatewayObjectSize {
+		return nil, fmt.Errorf("object exceeds %d bytes", maxGatewayObjectSize)
+	}
+	return data, nil
+}
+
+// cidCache is an LRU cache of object bytes by CID, bounded by total size.
+type cidCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+type cidCacheEntry struct {
+	cid  string
+	data []byte
+}
+
+func newCIDCache(maxBytes int64) *cidCache {
+	return &cidCache{maxBytes: maxBytes, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (c *cidCache) get(cid string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[cid]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*cidCacheEntry).data, true
+}
+
+// add caches data unless it alone exceeds the cache, evicting the least
+// recently used entries to make room.
+func (c *cidCache) add(cid string, data []byte) {
+	n := int64(len(data))
+	if n > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[cid]; ok {
+		return
+	}
+	for c.size+n > c.maxBytes {
+		oldest := c.order.Back()
+		entry := oldest.Value.(*cidCacheEntry)
+		c.order.Remove(oldest)
+		delete(c.entries, entry.cid)
+		c.size -= int64(len(entry.data))
+	}
+	c.entries[cid] = c.order.PushFront(&cidCacheEntry{cid: cid, data: data})
+	c.size += n
+}
@@ -0,0 +1,121 @@
+package streaming
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeIPFSStore resolves every key to the CID "cid-<key>" and serves the
+// node's copy of objects.
+type fakeIPFSStore struct {
+	objects   map[string][]byte
+	downloads int
+}
+
+func (f *fakeIPFSStore) ListObjects(_ context.Context, _, prefix string) ([]string, error) {
+	var keys []string
+	for k := range f.objects {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func (f *fakeIPFSStore) Download(_ context.Context, _, key string) ([]byte, error) {
+	f.downloads++
+	data, ok := f.objects[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return data, nil
+}
+
+func (f *fakeIPFSStore) CID(_ context.Context, _, key string) (string, error) {
+	if _, ok := f.objects[key]; !ok {
+		return "", errors.New("not found")
+	}
+	return "cid-" + strings.ReplaceAll(key, "/", "_"), nil
+}
+
+func gatewayServer(t *testing.T, status int, body string, hits *int32) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		if !strings.HasPrefix(r.URL.Path, "/ipfs/cid-") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestIPFSGatewayStore_Download(t *testing.T) {
+	ctx := context.Background()
+	var downHits, upHits int32
+	down := gatewayServer(t, http.StatusBadGateway, "", &downHits)
+	up := gatewayServer(t, http.StatusOK, "from-gateway", &upHits)
+
+	node := &fakeIPFSStore{objects: map[string][]byte{"streams/c1/720p/seg_000.ts": []byte("from-node")}}
+	store := NewIPFSGatewayStore(node, []string{down, up + "/"}, 1<<20, zap.NewNop())
+
+	data, err := store.Download(ctx, "media", "streams/c1/720p/seg_000.ts")
+	require.NoError(t, err)
+	assert.Equal(t, "from-gateway", string(data), "failed gateways are skipped")
+
+	data, err = store.Download(ctx, "media", "streams/c1/720p/seg_000.ts")
+	require.NoError(t, err)
+	assert.Equal(t, "from-gateway", string(data))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&upHits), "second read is served from cache")
+	assert.Zero(t, node.downloads)
+
+	_, err = store.Download(ctx, "media", "missing")
+	assert.Error(t, err)
+
+	keys, err := store.ListObjects(ctx, "media", "streams/c1/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"streams/c1/720p/seg_000.ts"}, keys)
+}
+
+func TestIPFSGatewayStore_FallsBackToNode(t *testing.T) {
+	var hits int32
+	down := gatewayServer(t, http.StatusInternalServerError, "", &hits)
+	node := &fakeIPFSStore{objects: map[string][]byte{"seg.ts": []byte("from-node")}}
+	store := NewIPFSGatewayStore(node, []string{down}, 1<<20, zap.NewNop())
+
+	data, err := store.Download(context.Background(), "media", "seg.ts")
+	require.NoError(t, err)
+	assert.Equal(t, "from-node", string(data))
+	assert.Equal(t, 1, node.downloads)
+}
+
+func TestCIDCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newCIDCache(10)
+	c.add("a", []byte("1234"))
+	c.add("b", []byte("1234"))
+	_, _ = c.get("a")
+	c.add("c", []byte("1234"))
+	c.add("huge", []byte("12345678901"))
+
+	_, ok := c.get("b")
+	assert.False(t, ok, "least recently used entry is evicted")
+	_, ok = c.get("a")
+	assert.True(t, ok)
+	_, ok = c.get("c")
+	assert.True(t, ok)
+	_, ok = c.get("huge")
+	assert.False(t, ok, "entries larger than the cache are not kept")
+	assert.Equal(t, int64(8), c.size)
+}
@@ -0,0 +1,264 @@
+package streaming
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/rtcdance/streamgate/pkg/plugins/keys"
+
+	"go.uber.org/zap"
+)
+
+const (
+	licensePath            = "/api/v1/stream/license"
+	licenseCertificatePath = licensePath + "/certificate"
+
+	// maxLicenseChallenge bounds challenge bodies; CDM requests are a few KB.
+	maxLicenseChallenge = 64 << 10
+	// maxLicenseResponse bounds what is read back from license servers.
+	maxLicenseResponse = 1 << 20
+)
+
+// DRMSystem names the DRM system a license is requested for.
+type DRMSystem string
+
+const (
+	DRMSystemWidevine DRMSystem = "widevine"
+	DRMSystemFairPlay DRMSystem = "fairplay"
+)
+
+var (
+	// ErrUnsupportedDRMSystem is returned by license proxies for systems
+	// they have no license server for.
+	ErrUnsupportedDRMSystem = errors.New("unsupported DRM system")
+	// ErrNoCertificate is returned when a system has no certificate to
+	// serve.
+	ErrNoCertificate = errors.New("no certificate for DRM system")
+)
+
+// LicenseRequest is a player's license challenge for one content, after
+// the viewer passed authentication and the content's NFT gate.
+type LicenseRequest struct {
+	ContentID string
+	System    DRMSystem
+	// Challenge is the CDM license request (Widevine) or SPC (FairPlay),
+	// passed through unread.
+	Challenge []byte
+	// KeyID is the content's CENC key ID, by which license servers look
+	// up the key.
+	KeyID []byte
+	// Wallet is the authenticated viewer.
+	Wallet string
+}
+
+// LicenseProxy obtains licenses from the operator's license servers. It is
+// the extension point for license servers that need more than the HTTP
+// pass-through of HTTPLicenseProxy, e.g. signed requests or a key provider
+// handshake.
+type LicenseProxy interface {
+	// Acquire returns the license answering req.Challenge.
+	Acquire(ctx context.Context, req *LicenseRequest) ([]byte, error)
+	// Certificate returns the certificate players need before requesting
+	// licenses, i.e. the FairPlay application certificate.
+	Certificate(ctx context.Context, system DRMSystem) ([]byte, error)
+}
+
+// HTTPLicenseProxy forwards challenges to one license server per DRM
+// system as POST bodies, identifying the content, key and viewer in
+// X-Streamgate-* headers.
+type HTTPLicenseProxy struct {
+	servers map[DRMSystem]config.LicenseServerConfig
+	client  *http.Client
+}
+
+// NewHTTPLicenseProxy creates a proxy for the configured license servers.
+func NewHTTPLicenseProxy(cfg config.DRMConfig) (*HTTPLicenseProxy, error) {
+	timeout := 10 * time.Second
+	if cfg.LicenseTimeout != "" {
+		d, err := time.ParseDuration(cfg.LicenseTimeout)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("streaming.drm.license_timeout: invalid duration %q", cfg.LicenseTimeout)
+		}
+		timeout = d
+	}
+	servers := make(map[DRMSystem]config.LicenseServerConfig, len(cfg.LicenseServers))
+	for _, server := range cfg.LicenseServers {
+		servers[DRMSystem(server.System)] = server
+	}
+	return &HTTPLicenseProxy{servers: servers, client: &http.Client{Timeout: timeout}}, nil
+}
+
+// Acquire implements LicenseProxy.
+func (p *HTTPLicenseProxy) Acquire(ctx context.Context, req *LicenseRequest) ([]byte, error) {
+	server, ok := p.servers[req.System]
+	if !ok {
+		return nil, ErrUnsupportedDRMSystem
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, bytes.NewReader(req.Challenge))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/octet-stream")
+	httpReq.Header.Set("X-Streamgate-Content-Id", req.ContentID)
+	httpReq.Header.Set("X-Streamgate-Key-Id", hex.EncodeToString(req.KeyID))
+	httpReq.Header.Set("X-Streamgate-Wallet", req.Wallet)
+	return p.do(httpReq, req.System)
+}
+
+// Certificate implements LicenseProxy.
+func (p *HTTPLicenseProxy) Certificate(ctx context.Context, system DRMSystem) ([]byte, error) {
+	server, ok := p.servers[system]
+	if !ok {
+		return nil, ErrUnsupportedDRMSystem
+	}
+	if server.CertificateURL == "" {
+		return nil, ErrNoCertificate
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, server.CertificateURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return p.do(httpReq, system)
+}
+
+func (p *HTTPLicenseProxy) do(req *http.Request, system DRMSystem) ([]byte, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s license server unreachable: %w", system, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxLicenseResponse))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s license server response: %w", system, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s license server returned %d", system, resp.StatusCode)
+	}
+	return body, nil
+}
+
+// licenseService serves license and certificate requests through the
+// configured proxy, for content that has a DRM key.
+type licenseService struct {
+	keys   *keys.KeyStore
+	logger *zap.Logger
+
+	mu    sync.RWMutex
+	proxy LicenseProxy
+}
+
+func newLicenseService(store *keys.KeyStore, logger *zap.Logger) *licenseService {
+	return &licenseService{keys: store, logger: logger}
+}
+
+func (l *licenseService) setProxy(proxy LicenseProxy) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.proxy = proxy
+}
+
+func (l *licenseService) getProxy() LicenseProxy {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.proxy
+}
+
+// LicenseHandler answers POST /api/v1/stream/license?content_id=...&system=...
+// with the license for the challenge in the body.
+func (l *licenseService) LicenseHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeLicenseError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	proxy := l.getProxy()
+	if proxy == nil {
+		writeLicenseError(w, http.StatusNotImplemented, "DRM licensing not configured")
+		return
+	}
+	contentID := r.URL.Query().Get("content_id")
+	system := DRMSystem(r.URL.Query().Get("system"))
+	if contentID == "" || system == "" {
+		writeLicenseError(w, http.StatusBadRequest, "missing content_id or system")
+		return
+	}
+
+	key, err := l.keys.GetDRM(contentID)
+	if errors.Is(err, keys.ErrKeyNotFound) || errors.Is(err, keys.ErrInvalidContentID) {
+		writeLicenseError(w, http.StatusNotFound, "content is not DRM protected")
+		return
+	}
+	if err != nil {
+		l.logger.Error("Failed to read DRM key", zap.String("content_id", contentID), zap.Error(err))
+		writeLicenseError(w, http.StatusInternalServerError, "failed to read content key")
+		return
+	}
+
+	challenge, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxLicenseChallenge))
+	if err != nil || len(challenge) == 0 {
+		writeLicenseError(w, http.StatusBadRequest, "invalid license challenge")
+		return
+	}
+
+	license, err := proxy.Acquire(r.Context(), &LicenseRequest{
+		ContentID: contentID,
+		System:    system,
+		Challenge: challenge,
+		KeyID:     key.KeyID,
+		Wallet:    walletFromContext(r.Context()),
+	})
+	if errors.Is(err, ErrUnsupportedDRMSystem) {
+		writeLicenseError(w, http.StatusBadRequest, "unsupported DRM system")
+		return
+	}
+	if err != nil {
+		l.logger.Warn("License request failed",
+			zap.String("content_id", contentID), zap.String("system", string(system)), zap.Error(err))
+		writeLicenseError(w, http.StatusBadGateway, "license server error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Cache-Control", "no-store, private")
+	_, _ = w.Write(license)
+}
+
+// CertificateHandler answers GET /api/v1/stream/license/certificate?system=...
+func (l *licenseService) CertificateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeLicenseError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	proxy := l.getProxy()
+	if proxy == nil {
+		writeLicenseError(w, http.StatusNotImplemented, "DRM licensing not configured")
+		return
+	}
+	system := DRMSystem(r.URL.Query().Get("system"))
+	cert, err := proxy.Certificate(r.Context(), system)
+	if errors.Is(err, ErrUnsupportedDRMSystem) || errors.Is(err, ErrNoCertificate) {
+		writeLicenseError(w, http.StatusNotFound, "no certificate for DRM system")
+		return
+	}
+	if err != nil {
+		l.logger.Warn("Certificate request failed", zap.String("system", string(system)), zap.Error(err))
+		writeLicenseError(w, http.StatusBadGateway, "license server error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	_, _ = w.Write(cert)
+}
+
+func writeLicenseError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
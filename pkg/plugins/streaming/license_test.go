@@ -0,0 +1,145 @@
+package streaming
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestHTTPLicenseProxy(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/cert":
+			_, _ = w.Write([]byte("app-cert"))
+		case "/down":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			_, _ = w.Write([]byte("license"))
+		}
+	}))
+	defer server.Close()
+
+	proxy, err := NewHTTPLicenseProxy(config.DRMConfig{LicenseServers: []config.LicenseServerConfig{
+		{System: "fairplay", URL: server.URL + "/fairplay", CertificateURL: server.URL + "/cert"},
+		{System: "widevine", URL: server.URL + "/down"},
+	}})
+	require.NoError(t, err)
+
+	license, err := proxy.Acquire(context.Background(), &LicenseRequest{
+		ContentID: "premium", System: DRMSystemFairPlay, Challenge: []byte("spc"), KeyID: []byte{0xab}, Wallet: "0xwallet",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "license", string(license))
+	assert.Equal(t, http.MethodPost, got.Method)
+	assert.Equal(t, "spc", string(body))
+	assert.Equal(t, "premium", got.Header.Get("X-Streamgate-Content-Id"))
+	assert.Equal(t, "ab", got.Header.Get("X-Streamgate-Key-Id"))
+	assert.Equal(t, "0xwallet", got.Header.Get("X-Streamgate-Wallet"))
+
+	cert, err := proxy.Certificate(context.Background(), DRMSystemFairPlay)
+	require.NoError(t, err)
+	assert.Equal(t, "app-cert", string(cert))
+
+	_, err = proxy.Acquire(context.Background(), &LicenseRequest{System: DRMSystemWidevine, Challenge: []byte("c")})
+	assert.ErrorContains(t, err, "returned 500")
+	_, err = proxy.Certificate(context.Background(), DRMSystemWidevine)
+	assert.ErrorIs(t, err, ErrNoCertificate)
+	_, err = proxy.Acquire(context.Background(), &LicenseRequest{System: "playready"})
+	assert.ErrorIs(t, err, ErrUnsupportedDRMSystem)
+
+	_, err = NewHTTPLicenseProxy(config.DRMConfig{LicenseTimeout: "soon"})
+	assert.Error(t, err)
+}
+
+type stubLicenseProxy struct {
+	req *LicenseRequest
+	err error
+}
+
+func (s *stubLicenseProxy) Acquire(_ context.Context, req *LicenseRequest) ([]byte, error) {
+	s.req = req
+	return []byte("license"), s.err
+}
+
+func (s *stubLicenseProxy) Certificate(context.Context, DRMSystem) ([]byte, error) {
+	return []byte("cert"), s.err
+}
+
+func TestLicenseService_LicenseHandler(t *testing.T) {
+	store := newTestKeyStore(t)
+	key, err := store.IssueDRM("premium")
+	require.NoError(t, err)
+	_, err = store.Issue("clear")
+	require.NoError(t, err)
+	l := newLicenseService(store, zap.NewNop())
+
+	w := httptest.NewRecorder()
+	l.LicenseHandler(w, httptest.NewRequest(http.MethodPost, licensePath+"?content_id=premium&system=widevine", strings.NewReader("challenge")))
+	assert.Equal(t, http.StatusNotImplemented, w.Code, "no proxy configured")
+
+	proxy := &stubLicenseProxy{}
+	l.setProxy(proxy)
+	r := httptest.NewRequest(http.MethodPost, licensePath+"?content_id=premium&system=widevine", strings.NewReader("challenge"))
+	r = r.WithContext(context.WithValue(r.Context(), walletContextKey{}, "0xwallet"))
+	w = httptest.NewRecorder()
+	l.LicenseHandler(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "license", w.Body.String())
+	assert.Equal(t, "no-store, private", w.Header().Get("Cache-Control"))
+	assert.Equal(t, key.KeyID, proxy.req.KeyID)
+	assert.Equal(t, "challenge", string(proxy.req.Challenge))
+	assert.Equal(t, "0xwallet", proxy.req.Wallet)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		err    error
+		status int
+	}{
+		{"content without DRM key", http.MethodPost, "?content_id=clear&system=widevine", "c", nil, http.StatusNotFound},
+		{"missing system", http.MethodPost, "?content_id=premium", "c", nil, http.StatusBadRequest},
+		{"empty challenge", http.MethodPost, "?content_id=premium&system=widevine", "", nil, http.StatusBadRequest},
+		{"unsupported system", http.MethodPost, "?content_id=premium&system=playready", "c", ErrUnsupportedDRMSystem, http.StatusBadRequest},
+		{"license server down", http.MethodPost, "?content_id=premium&system=widevine", "c", io.ErrUnexpectedEOF, http.StatusBadGateway},
+		{"wrong method", http.MethodGet, "?content_id=premium&system=widevine", "", nil, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy.err = tt.err
+			w := httptest.NewRecorder()
+			l.LicenseHandler(w, httptest.NewRequest(tt.method, licensePath+tt.target, strings.NewReader(tt.body)))
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+func TestLicenseService_CertificateHandler(t *testing.T) {
+	l := newLicenseService(newTestKeyStore(t), zap.NewNop())
+	proxy := &stubLicenseProxy{}
+	l.setProxy(proxy)
+
+	w := httptest.NewRecorder()
+	l.CertificateHandler(w, httptest.NewRequest(http.MethodGet, licenseCertificatePath+"?system=fairplay", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "cert", w.Body.String())
+
+	proxy.err = ErrNoCertificate
+	w = httptest.NewRecorder()
+	l.CertificateHandler(w, httptest.NewRequest(http.MethodGet, licenseCertificatePath+"?system=widevine", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
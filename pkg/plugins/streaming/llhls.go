@@ -0,0 +1,234 @@
+package streaming
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/plugins/transcoder"
+)
+
+// defaultPartPollInterval is how often a blocking request re-lists storage
+// while waiting for a part to appear.
+const defaultPartPollInterval = 200 * time.Millisecond
+
+// ErrPartTooFar is returned for a blocking reload request more than two
+// segments beyond the end of the playlist.
+var ErrPartTooFar = errors.New("requested part is too far beyond the playlist")
+
+// Part is one LL-HLS partial segment.
+type Part struct {
+	Name     string
+	Duration float64
+	// MSN is the media sequence number of the parent segment and Index the
+	// part's position within it.
+	MSN   int
+	Index int
+}
+
+// groupParts fills in a low-latency rendition from its part names, in
+// playback order. Parts are grouped into parent segments of about
+// defaultSegmentDuration; while the rendition is still being written, the
+// trailing parts that do not yet fill a parent stay ungrouped.
+func (r *Rendition) groupParts(names []string, durations map[string]float64, complete bool) {
+	r.LowLatency = true
+	r.Complete = complete
+	r.parentParts = make(map[string][]string)
+
+	defaultPart := transcoder.DefaultPartDuration.Seconds()
+	for _, name := range names {
+		if d, ok := durations[name]; ok {
+			r.PartTarget = math.Max(r.PartTarget, d)
+		}
+	}
+	if r.PartTarget == 0 {
+		r.PartTarget = defaultPart
+	}
+	perSegment := int(math.Max(1, math.Round(defaultSegmentDuration/r.PartTarget)))
+
+	r.Parts = make([]Part, len(names))
+	for i, name := range names {
+		d, ok := durations[name]
+		if !ok {
+			d = r.PartTarget
+		}
+		r.Parts[i] = Part{Name: name, Duration: d, MSN: i / perSegment, Index: i % perSegment}
+	}
+
+	for start := 0; start < len(names); start += perSegment {
+		end := start + perSegment
+		if end > len(names) {
+			if !complete {
+				break
+			}
+			end = len(names)
+		}
+		parent := parentSegmentName(names[start], start/perSegment)
+		var duration float64
+		for _, part := range r.Parts[start:end] {
+			duration += part.Duration
+		}
+		r.Segments = append(r.Segments, Segment{Name: parent, Duration: duration})
+		r.parentParts[parent] = names[start:end]
+	}
+}
+
+// parentSegmentName names the parent segment msn after one of its parts:
+// "720p_v..._p00012.ts" belongs to parents named "720p_v..._002.ts".
+func parentSegmentName(partName string, msn int) string {
+	base := strings.TrimSuffix(partName, ".ts")
+	if idx := strings.LastIndex(base, "_"); idx >= 0 {
+		base = base[:idx]
+	}
+	return fmt.Sprintf("%s_%03d.ts", base, msn)
+}
+
+// nextPartName is the name the transcoder will give the part after the
+// rendition's last one, advertised as the preload hint.
+func (r *Rendition) nextPartName() string {
+	if len(r.Parts) == 0 {
+		return ""
+	}
+	last := r.Parts[len(r.Parts)-1].Name
+	base := strings.TrimSuffix(last, ".ts")
+	idx := strings.LastIndex(base, "_p")
+	if idx < 0 {
+		return ""
+	}
+	seq, err := strconv.Atoi(base[idx+2:])
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s_p%05d.ts", base[:idx], seq+1)
+}
+
+// hasPart reports whether part (or, with part < 0, the whole parent) of
+// media sequence msn is in the rendition.
+func (r *Rendition) hasPart(msn, part int) bool {
+	if msn < len(r.Segments) {
+		return true
+	}
+	if part < 0 {
+		return false
+	}
+	for _, p := range r.Parts {
+		if p.MSN == msn && p.Index == part {
+			return true
+		}
+	}
+	return false
+}
+
+// nextMSN is the media sequence number of the first parent not yet
+// complete.
+func (r *Rendition) nextMSN() int {
+	return len(r.Segments)
+}
+
+// lowLatencyPlaylist renders an LL-HLS media playlist. Parts are listed for
+// the last three target durations only, as RFC 8216bis recommends.
+func lowLatencyPlaylist(r Rendition, contentID, keyTag string, query url.Values) string {
+	target := defaultSegmentDuration
+	for _, s := range r.Segments {
+		target = math.Max(target, s.Duration)
+	}
+	targetDuration := int(math.Ceil(target))
+	partsFromMSN := r.nextMSN() - int(math.Ceil(3*float64(targetDuration)/defaultSegmentDuration))
+
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:9\n")
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", targetDuration)
+	fmt.Fprintf(&b, "#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=%.3f\n", 3*r.PartTarget)
+	fmt.Fprintf(&b, "#EXT-X-PART-INF:PART-TARGET=%.3f\n", r.PartTarget)
+	b.WriteString("#EXT-X-MEDIA-SEQUENCE:0\n")
+	if r.Complete {
+		b.WriteString("#EXT-X-PLAYLIST-TYPE:VOD\n")
+	}
+	b.WriteString(keyTag)
+
+	writeParts := func(msn int) {
+		if msn < partsFromMSN {
+			return
+		}
+		for _, part := range r.Parts {
+			if part.MSN == msn {
+				// Every part starts on a forced keyframe.
+				fmt.Fprintf(&b, "#EXT-X-PART:DURATION=%.3f,URI=\"%s\",INDEPENDENT=YES\n",
+					part.Duration, playlistURL("/api/v1/stream/segment", contentID, r.Quality, part.Name, query))
+			}
+		}
+	}
+	for msn, s := range r.Segments {
+		writeParts(msn)
+		fmt.Fprintf(&b, "#EXTINF:%.3f,\n", s.Duration)
+		b.WriteString(playlistURL("/api/v1/stream/segment", contentID, r.Quality, s.Name, query))
+		b.WriteString("\n")
+	}
+
+	if r.Complete {
+		b.WriteString("#EXT-X-ENDLIST\n")
+		return b.String()
+	}
+	writeParts(r.nextMSN())
+	if next := r.nextPartName(); next != "" {
+		fmt.Fprintf(&b, "#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"%s\"\n",
+			playlistURL("/api/v1/stream/segment", contentID, r.Quality, next, query))
+	}
+	return b.String()
+}
+
+// WaitForPart implements LL-HLS blocking playlist reload: it returns once
+// the rendition holds part of media sequence msn (the whole parent when
+// part is negative), or the rendition is complete. It returns ErrPartTooFar
+// for requests more than two segments ahead, and the context's error if the
+// part does not appear in time.
+func (p *HLSPackager) WaitForPart(ctx context.Context, contentID, quality string, msn, part int) error {
+	rendition, err := p.findRendition(ctx, contentID, quality, true)
+	if err != nil {
+		return err
+	}
+	if !rendition.LowLatency {
+		return nil
+	}
+	if msn > rendition.nextMSN()+2 {
+		return ErrPartTooFar
+	}
+	return p.poll(ctx, contentID, quality, func(r Rendition) bool {
+		return r.Complete || r.hasPart(msn, part)
+	})
+}
+
+// awaitPart waits for the hinted next part of a rendition being written.
+func (p *HLSPackager) awaitPart(ctx context.Context, contentID, quality, name string) error {
+	return p.poll(ctx, contentID, quality, func(r Rendition) bool {
+		return r.Complete || r.stored[name]
+	})
+}
+
+func (p *HLSPackager) poll(ctx context.Context, contentID, quality string, done func(Rendition) bool) error {
+	interval := p.pollInterval
+	if interval <= 0 {
+		interval = defaultPartPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		rendition, err := p.findRendition(ctx, contentID, quality, true)
+		if err != nil {
+			return err
+		}
+		if done(rendition) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
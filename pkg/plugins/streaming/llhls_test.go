@@ -0,0 +1,174 @@
+package streaming
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/plugins/transcoder"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// lockedSegmentStore lets a test add parts while requests are blocked.
+type lockedSegmentStore struct {
+	mu sync.Mutex
+	fakeSegmentStore
+}
+
+func (s *lockedSegmentStore) ListObjects(ctx context.Context, bucket, prefix string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fakeSegmentStore.ListObjects(ctx, bucket, prefix)
+}
+
+func (s *lockedSegmentStore) Download(ctx context.Context, bucket, name string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fakeSegmentStore.Download(ctx, bucket, name)
+}
+
+func (s *lockedSegmentStore) addPart(seq int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects["streams/premiere/720p/"+testPart(seq)] = []byte(fmt.Sprintf("[%d]", seq))
+}
+
+func testPart(seq int) string {
+	return transcoder.SegmentPartName("720p", testVersion, seq)
+}
+
+// newLLStore holds n one-second parts of a 720p premiere still being
+// transcoded (no playlist yet).
+func newLLStore(n int) *lockedSegmentStore {
+	store := &lockedSegmentStore{fakeSegmentStore: fakeSegmentStore{objects: make(map[string][]byte)}}
+	for i := 0; i < n; i++ {
+		store.addPart(i)
+	}
+	return store
+}
+
+func TestHLSPackager_LowLatencyGrouping(t *testing.T) {
+	p := NewHLSPackager(newLLStore(14), "streamgate", nil, zap.NewNop())
+
+	renditions, err := p.Renditions(context.Background(), "premiere")
+	require.NoError(t, err)
+	require.Len(t, renditions, 1)
+	r := renditions[0]
+	assert.True(t, r.LowLatency)
+	assert.False(t, r.Complete)
+	assert.Equal(t, 1.0, r.PartTarget)
+	require.Len(t, r.Segments, 2, "two full parents of six parts; two parts pending")
+	assert.Equal(t, transcoder.SegmentName("720p", testVersion, 1), r.Segments[1].Name)
+	assert.Equal(t, 6.0, r.Segments[1].Duration)
+	assert.Equal(t, testPart(14), r.nextPartName())
+
+	data, err := p.Segment(context.Background(), "premiere", "720p", r.Segments[1].Name)
+	require.NoError(t, err)
+	assert.Equal(t, "[6][7][8][9][10][11]", string(data))
+}
+
+func TestHLSPackager_LowLatencyPlaylist(t *testing.T) {
+	p := NewHLSPackager(newLLStore(14), "streamgate", nil, zap.NewNop())
+
+	playlist, err := p.MediaPlaylist(context.Background(), "premiere", "720p", nil)
+	require.NoError(t, err)
+	assert.Contains(t, playlist, "#EXT-X-VERSION:9\n")
+	assert.Contains(t, playlist, "#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=3.000\n")
+	assert.Contains(t, playlist, "#EXT-X-PART-INF:PART-TARGET=1.000\n")
+	assert.Contains(t, playlist, `#EXT-X-PART:DURATION=1.000,URI="/api/v1/stream/segment?content_id=premiere&quality=720p&segment_id=`+testPart(13)+`",INDEPENDENT=YES`)
+	assert.Contains(t, playlist, `#EXT-X-PRELOAD-HINT:TYPE=PART,URI="/api/v1/stream/segment?content_id=premiere&quality=720p&segment_id=`+testPart(14)+`"`)
+	assert.NotContains(t, playlist, "#EXT-X-ENDLIST")
+	assert.Equal(t, 2, strings.Count(playlist, "#EXTINF:"))
+}
+
+func TestHLSPackager_LowLatencyComplete(t *testing.T) {
+	store := newLLStore(14)
+	store.objects["streams/premiere/720p/720p.m3u8"] = []byte("#EXTM3U\n#EXTINF:1.000,\n" + testPart(0) + "\n#EXTINF:0.500,\n" + testPart(13) + "\n#EXT-X-ENDLIST\n")
+	p := NewHLSPackager(store, "streamgate", NewStreamCache(zap.NewNop()), zap.NewNop())
+
+	renditions, err := p.Renditions(context.Background(), "premiere")
+	require.NoError(t, err)
+	r := renditions[0]
+	assert.True(t, r.Complete)
+	require.Len(t, r.Segments, 3, "the short trailing parent is published once complete")
+	assert.Equal(t, 1.5, r.Segments[2].Duration)
+
+	playlist, err := p.MediaPlaylist(context.Background(), "premiere", "720p", nil)
+	require.NoError(t, err)
+	assert.Contains(t, playlist, "#EXT-X-ENDLIST\n")
+	assert.NotContains(t, playlist, "PRELOAD-HINT")
+}
+
+func TestHLSPackager_WaitForPart(t *testing.T) {
+	store := newLLStore(14)
+	p := NewHLSPackager(store, "streamgate", nil, zap.NewNop())
+	p.pollInterval = 5 * time.Millisecond
+
+	assert.ErrorIs(t, p.WaitForPart(context.Background(), "premiere", "720p", 5, -1), ErrPartTooFar)
+	require.NoError(t, p.WaitForPart(context.Background(), "premiere", "720p", 2, 1))
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		store.addPart(14)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, p.WaitForPart(ctx, "premiere", "720p", 2, 2))
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, p.WaitForPart(ctx, "premiere", "720p", 3, -1), context.DeadlineExceeded)
+}
+
+func TestStreamingHandler_LowLatencyBlockingReload(t *testing.T) {
+	store := newLLStore(14)
+	p := NewHLSPackager(store, "streamgate", nil, zap.NewNop())
+	p.pollInterval = 5 * time.Millisecond
+	handler := newTestStreamingHandler(t)
+	handler.packager = p
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		store.addPart(14)
+	}()
+	req := httptest.NewRequest(http.MethodGet, "/hls?content_id=premiere&quality=720p&_HLS_msn=2&_HLS_part=2", http.NoBody)
+	rec := httptest.NewRecorder()
+	handler.GetHLSPlaylistHandler(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "no-cache", rec.Header().Get("Cache-Control"))
+	assert.Contains(t, rec.Body.String(), "segment_id="+testPart(14)+`"`)
+	assert.NotContains(t, rec.Body.String(), "_HLS_msn")
+
+	req = httptest.NewRequest(http.MethodGet, "/hls?content_id=premiere&quality=720p&_HLS_msn=9", http.NoBody)
+	rec = httptest.NewRecorder()
+	handler.GetHLSPlaylistHandler(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestStreamingHandler_LowLatencyPreloadHint(t *testing.T) {
+	store := newLLStore(14)
+	p := NewHLSPackager(store, "streamgate", nil, zap.NewNop())
+	p.pollInterval = 5 * time.Millisecond
+	handler := newTestStreamingHandler(t)
+	handler.packager = p
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		store.addPart(14)
+	}()
+	req := httptest.NewRequest(http.MethodGet, "/segment?content_id=premiere&quality=720p&segment_id="+testPart(14), http.NoBody)
+	rec := httptest.NewRecorder()
+	handler.GetSegmentHandler(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "[14]", rec.Body.String())
+}
@@ -0,0 +1,176 @@
+package streaming
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/rtcdance/streamgate/pkg/middleware"
+	"github.com/rtcdance/streamgate/pkg/service"
+	"github.com/rtcdance/streamgate/pkg/storage"
+
+	"go.uber.org/zap"
+)
+
+type walletContextKey struct{}
+
+// walletFromContext returns the wallet requireAuth read from the JWT.
+func walletFromContext(ctx context.Context) string {
+	wallet, _ := ctx.Value(walletContextKey{}).(string)
+	return wallet
+}
+
+// nftGate blocks manifest and segment requests for gated content unless the
+// authenticated wallet satisfies at least one of the content's gating rules.
+// Content without active rules is served to any authenticated wallet.
+type nftGate struct {
+	verifier middleware.NFTOwnershipChecker
+	rules    middleware.GatingRuleResolver
+	logger   *zap.Logger
+}
+
+func newNFTGate(verifier middleware.NFTOwnershipChecker, rules middleware.GatingRuleResolver, logger *zap.Logger) *nftGate {
+	return &nftGate{verifier: verifier, rules: rules, logger: logger}
+}
+
+// wrap must run inside requireAuth. It fails closed: if the rules or the
+// chain cannot be read, the request is rejected with 503.
+func (g *nftGate) wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		contentID := r.URL.Query().Get("content_id")
+		if contentID == "" {
+			// Let the handler report the missing parameter.
+			next(w, r)
+			return
+		}
+		wallet := walletFromContext(r.Context())
+		if wallet == "" {
+			writeGateError(w, http.StatusUnauthorized, "UNAUTHORIZED", "authentication required", nil)
+			return
+		}
+
+		rules, err := g.rules.GetActiveRulesForContent(r.Context(), contentID)
+		if err != nil {
+			g.logger.Error("Failed to load gating rules", zap.String("content_id", contentID), zap.Error(err))
+			writeGateError(w, http.StatusServiceUnavailable, "GATING_UNAVAILABLE", "gating policy unavailable", nil)
+			return
+		}
+		if len(rules) == 0 {
+			next(w, r)
+			return
+		}
+
+		var verifyErr error
+		for _, rule := range rules {
+			ok, err := g.satisfies(r.Context(), rule, wallet)
+			if err != nil {
+				verifyErr = err
+				continue
+			}
+			if ok {
+				next(w, r)
+				return
+			}
+		}
+		if verifyErr != nil {
+			g.logger.Error("NFT verification failed",
+				zap.String("content_id", contentID),
+				zap.String("wallet", wallet),
+				zap.Error(verifyErr))
+			writeGateError(w, http.StatusServiceUnavailable, "NFT_VERIFY_ERROR", "verification service unavailable", nil)
+			return
+		}
+
+		g.logger.Info("Stream access denied",
+			zap.String("content_id", contentID),
+			zap.String("wallet", wallet))
+		required := map[string]interface{}{
+			"contract": rules[0].ContractAddress,
+			"chain_id": rules[0].ChainID,
+		}
+		if rules[0].TokenID != "" {
+			required["token_id"] = rules[0].TokenID
+		}
+		if rules[0].MinBalance > 1 {
+			required["min_balance"] = rules[0].MinBalance
+		}
+		writeGateError(w, http.StatusForbidden, "NFT_REQUIRED", "nft access denied", map[string]interface{}{"required_nft": required})
+	}
+}
+
+// satisfies checks one rule: ownership of TokenID when set, otherwise a
+// balance of at least MinBalance (and at least one) on the contract.
+func (g *nftGate) satisfies(ctx context.Context, rule middleware.GatingRule, wallet string) (bool, error) {
+	if rule.TokenID != "" {
+		return g.verifier.VerifyNFTOwnership(ctx, rule.ChainID, rule.ContractAddress, rule.TokenID, wallet)
+	}
+	balance, err := g.verifier.GetNFTBalance(ctx, rule.ChainID, rule.ContractAddress, wallet)
+	if err != nil {
+		return false, err
+	}
+	minBalance := int64(rule.MinBalance)
+	if minBalance < 1 {
+		minBalance = 1
+	}
+	return balance != nil && balance.Cmp(big.NewInt(minBalance)) >= 0, nil
+}
+
+func writeGateError(w http.ResponseWriter, status int, code, message string, extra map[string]interface{}) {
+	body := map[string]interface{}{"error": message, "code": code}
+	for k, v := range extra {
+		body[k] = v
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// gatingRuleLookup adapts GatingRuleService to middleware.GatingRuleResolver.
+type gatingRuleLookup struct {
+	svc *service.GatingRuleService
+}
+
+func (l gatingRuleLookup) GetActiveRulesForContent(ctx context.Context, contentID string) ([]middleware.GatingRule, error) {
+	rules, err := l.svc.GetActiveRulesForContent(ctx, contentID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]middleware.GatingRule, len(rules))
+	for i, r := range rules {
+		result[i] = middleware.GatingRule{
+			ContractAddress: r.ContractAddress,
+			TokenID:         r.TokenID,
+			ChainID:         r.ChainID,
+			Standard:        r.Standard,
+			MinBalance:      r.MinBalance,
+		}
+	}
+	return result, nil
+}
+
+// newNFTGateFromConfig connects the gating rule store and the Web3 service
+// used by the gate. The returned function releases both.
+func newNFTGateFromConfig(cfg *config.Config, logger *zap.Logger) (*nftGate, func(), error) {
+	pg := storage.NewPostgresDB()
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		cfg.Database.Host, cfg.Database.Port, cfg.Database.User, cfg.Database.Password, cfg.Database.Database, cfg.Database.SSLMode)
+	if err := pg.ConnectWithConfig(dsn, storage.PoolConfigFromValues(cfg.Database.MaxConns, cfg.Database.MaxIdleConns, 0, 0)); err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to gating rule database: %w", err)
+	}
+
+	web3Svc, err := service.NewWeb3Service(service.DefaultWeb3Deps(cfg, logger), cfg, logger)
+	if err != nil {
+		_ = pg.Close()
+		return nil, nil, fmt.Errorf("failed to initialize Web3 service: %w", err)
+	}
+
+	rules := gatingRuleLookup{svc: service.NewGatingRuleService(pg, logger.Named("gating-rule"))}
+	closeFn := func() {
+		web3Svc.Close()
+		_ = pg.Close()
+	}
+	return newNFTGate(web3Svc, rules, logger), closeFn, nil
+}
@@ -0,0 +1,111 @@
+package streaming
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rtcdance/streamgate/pkg/middleware"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+const gateTestWallet = "0x742d35Cc6634C0532925a3b844Bc9e7595f2bD18"
+
+type gateTestVerifier struct {
+	balance *big.Int
+	owns    bool
+	err     error
+}
+
+func (v *gateTestVerifier) VerifyNFTOwnership(_ context.Context, _ int64, _, _, _ string) (bool, error) {
+	return v.owns, v.err
+}
+func (v *gateTestVerifier) GetNFTBalance(_ context.Context, _ int64, _, _ string) (*big.Int, error) {
+	return v.balance, v.err
+}
+func (v *gateTestVerifier) VerifyNFTOwnershipAutoDetect(_ context.Context, _ int64, _, _, _ string) (bool, error) {
+	return v.owns, v.err
+}
+func (v *gateTestVerifier) VerifyNFTCollectionAutoDetect(_ context.Context, _ int64, _, _ string) (bool, error) {
+	return v.owns, v.err
+}
+func (v *gateTestVerifier) GetNFTInfo(_ context.Context, _ int64, _, _ string) (*middleware.NFTMetadata, error) {
+	return nil, nil
+}
+
+type gateTestRules struct {
+	rules []middleware.GatingRule
+	err   error
+}
+
+func (r *gateTestRules) GetActiveRulesForContent(_ context.Context, _ string) ([]middleware.GatingRule, error) {
+	return r.rules, r.err
+}
+
+func serveGated(t *testing.T, gate *nftGate, target string) (*httptest.ResponseRecorder, bool) {
+	t.Helper()
+	server := newTestStreamingServer(t)
+	server.gate = gate
+
+	called := false
+	handler := server.requireAuth(server.requireNFT(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+	req.Header.Set("Authorization", "Bearer "+makeTestJWT(t, "test-secret", gateTestWallet))
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec, called
+}
+
+func TestNFTGate_MinBalance(t *testing.T) {
+	rules := &gateTestRules{rules: []middleware.GatingRule{{ContractAddress: "0xabc", ChainID: 1, MinBalance: 2}}}
+
+	rec, called := serveGated(t, newNFTGate(&gateTestVerifier{balance: big.NewInt(2)}, rules, zap.NewNop()), "/api/v1/stream/hls?content_id=c1")
+	assert.True(t, called)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec, called = serveGated(t, newNFTGate(&gateTestVerifier{balance: big.NewInt(1)}, rules, zap.NewNop()), "/api/v1/stream/segment?content_id=c1&segment_id=1")
+	assert.False(t, called)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "NFT_REQUIRED")
+	assert.Contains(t, rec.Body.String(), `"min_balance":2`)
+}
+
+func TestNFTGate_AnyRuleUnlocks(t *testing.T) {
+	rules := &gateTestRules{rules: []middleware.GatingRule{
+		{ContractAddress: "0xabc", ChainID: 1},
+		{ContractAddress: "0xdef", ChainID: 137, TokenID: "7"},
+	}}
+	rec, called := serveGated(t, newNFTGate(&gateTestVerifier{balance: big.NewInt(0), owns: true}, rules, zap.NewNop()), "/api/v1/stream/hls?content_id=c1")
+	assert.True(t, called)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestNFTGate_UngatedContent(t *testing.T) {
+	rec, called := serveGated(t, newNFTGate(&gateTestVerifier{}, &gateTestRules{}, zap.NewNop()), "/api/v1/stream/hls?content_id=free")
+	assert.True(t, called)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestNFTGate_FailsClosed(t *testing.T) {
+	rec, called := serveGated(t, newNFTGate(&gateTestVerifier{}, &gateTestRules{err: errors.New("db down")}, zap.NewNop()), "/api/v1/stream/hls?content_id=c1")
+	assert.False(t, called)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	rules := &gateTestRules{rules: []middleware.GatingRule{{ContractAddress: "0xabc", ChainID: 1}}}
+	rec, called = serveGated(t, newNFTGate(&gateTestVerifier{err: errors.New("rpc down")}, rules, zap.NewNop()), "/api/v1/stream/hls?content_id=c1")
+	assert.False(t, called)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestNFTGate_DisabledByDefault(t *testing.T) {
+	server := newTestStreamingServer(t)
+	assert.Nil(t, server.gate)
+}
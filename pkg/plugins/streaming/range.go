@@ -349,17 +349,26 @@ func parseRangeSpec(spec string, fileSize int64) (*FileRange, error) {
 
 // getContentType returns the content type for a file
 func (rh *RangeHandler) getContentType(filePath string) string {
+	return contentTypeFor(filePath)
+}
+
+// contentTypeFor maps a media file extension to its MIME type.
+func contentTypeFor(filePath string) string {
 	ext := strings.ToLower(filepath.Ext(filePath))
 
 	switch ext {
 	case ".mp4":
 		return "video/mp4"
+	case ".m4s":
+		return "video/iso.segment"
 	case ".webm":
 		return "video/webm"
 	case ".ogg":
 		return "video/ogg"
 	case ".mp3":
 		return "audio/mpeg"
+	case ".aac":
+		return "audio/aac"
 	case ".wav":
 		return "audio/wav"
 	case ".flac":
@@ -370,6 +379,8 @@ func (rh *RangeHandler) getContentType(filePath string) string {
 		return "application/dash+xml"
 	case ".ts":
 		return "video/mp2t"
+	case ".vtt":
+		return "text/vtt"
 	default:
 		return "application/octet-stream"
 	}
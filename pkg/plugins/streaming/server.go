@@ -10,6 +10,8 @@ import (
 
 	"github.com/rtcdance/streamgate/pkg/core"
 	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/rtcdance/streamgate/pkg/plugins/keys"
+	"github.com/rtcdance/streamgate/pkg/storage"
 
 	"github.com/golang-jwt/jwt/v4"
 	"go.uber.org/zap"
@@ -22,18 +24,102 @@ type StreamingServer struct {
 	kernel *core.Microkernel
 	server *http.Server
 	cache  *StreamCache
+
+	// packager is nil when object storage cannot be set up.
+	packager *HLSPackager
+
+	// gate is nil unless features.nft_gating is on.
+	gate      *nftGate
+	closeGate func()
+
+	// keys is nil unless encryption.enabled is on.
+	keys *keys.KeyStore
+	// licenses is nil without a key store, since DRM keys live there.
+	licenses *licenseService
 }
 
-// NewStreamingServer creates a new streaming server
+// NewStreamingServer creates a new streaming server. With NFT gating
+// enabled it also connects to the gating rule database and the chain; a
+// failure to do so is returned rather than serving gated content openly.
 func NewStreamingServer(cfg *config.Config, logger *zap.Logger, kernel *core.Microkernel) (*StreamingServer, error) {
-	cache := NewStreamCache(logger)
-
-	return &StreamingServer{
+	s := &StreamingServer{
 		config: cfg,
 		logger: logger,
 		kernel: kernel,
-		cache:  cache,
-	}, nil
+	}
+
+	if cfg.Features.NFTGating {
+		gate, closeGate, err := newNFTGateFromConfig(cfg, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up NFT gating: %w", err)
+		}
+		s.gate = gate
+		s.closeGate = closeGate
+	}
+
+	if cfg.Encryption.Enabled {
+		store, err := keys.NewKeyStoreFromConfig(cfg.Encryption)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open key store: %w", err)
+		}
+		s.keys = store
+		s.licenses = newLicenseService(store, logger.Named("license"))
+		if len(cfg.Streaming.DRM.LicenseServers) > 0 {
+			proxy, err := NewHTTPLicenseProxy(cfg.Streaming.DRM)
+			if err != nil {
+				return nil, err
+			}
+			s.licenses.setProxy(proxy)
+		}
+	}
+
+	s.cache = NewStreamCache(logger)
+
+	store, err := createSegmentStore(cfg, logger)
+	if err != nil {
+		logger.Warn("Object storage unavailable, HLS playback disabled", zap.Error(err))
+	} else {
+		bucket := cfg.Storage.Bucket
+		if bucket == "" {
+			bucket = "streamgate"
+		}
+		s.packager = NewHLSPackager(store, bucket, s.cache, logger)
+		s.packager.keys = s.keys
+	}
+	return s, nil
+}
+
+// SetLicenseProxy replaces the license proxy of DRM-protected content,
+// e.g. with one for a license server that HTTPLicenseProxy cannot talk to.
+// It has no effect unless encryption is enabled.
+func (s *StreamingServer) SetLicenseProxy(proxy LicenseProxy) {
+	if s.licenses != nil {
+		s.licenses.setProxy(proxy)
+	}
+}
+
+// createSegmentStore connects to the object storage the transcoder writes
+// renditions to. IPFS storage with gateways configured is read through
+// them.
+func createSegmentStore(cfg *config.Config, logger *zap.Logger) (SegmentStore, error) {
+	store, err := storage.NewObjectStorageFromConfig(cfg.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Storage.Type == "ipfs" && len(cfg.Storage.IPFS.Gateways) > 0 {
+		cacheBytes := int64(cfg.Storage.IPFS.CacheSizeMB) << 20
+		return NewIPFSGatewayStore(store, cfg.Storage.IPFS.Gateways, cacheBytes, logger.Named("ipfs")), nil
+	}
+	return store, nil
+}
+
+// requireNFT applies the NFT gate, if enabled, to a handler wrapped by
+// requireAuth.
+func (s *StreamingServer) requireNFT(next http.HandlerFunc) http.HandlerFunc {
+	if s.gate == nil {
+		return next
+	}
+	return s.gate.wrap(next)
 }
 
 // Start starts the streaming server
@@ -78,12 +164,12 @@ func (s *StreamingServer) requireAuth(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		next(w, r)
+		next(w, r.WithContext(context.WithValue(r.Context(), walletContextKey{}, wallet)))
 	}
 }
 
 func (s *StreamingServer) Start(ctx context.Context) error {
-	handler := NewStreamingHandler(s.cache, s.logger, s.kernel)
+	handler := NewStreamingHandler(s.cache, s.packager, s.logger, s.kernel)
 
 	mux := http.NewServeMux()
 
@@ -92,10 +178,17 @@ func (s *StreamingServer) Start(ctx context.Context) error {
 	mux.HandleFunc("/health/ready", handler.ReadyHandler)
 	mux.HandleFunc("/ready", handler.ReadyHandler)
 
-	mux.HandleFunc("/api/v1/stream/hls", s.requireAuth(handler.GetHLSPlaylistHandler))
-	mux.HandleFunc("/api/v1/stream/dash", s.requireAuth(handler.GetDASHManifestHandler))
-	mux.HandleFunc("/api/v1/stream/segment", s.requireAuth(handler.GetSegmentHandler))
+	mux.HandleFunc("/api/v1/stream/hls", s.requireAuth(s.requireNFT(handler.GetHLSPlaylistHandler)))
+	mux.HandleFunc("/api/v1/stream/dash", s.requireAuth(s.requireNFT(handler.GetDASHManifestHandler)))
+	mux.HandleFunc("/api/v1/stream/segment", s.requireAuth(s.requireNFT(handler.GetSegmentHandler)))
 	mux.HandleFunc("/api/v1/stream/info", s.requireAuth(handler.GetStreamInfoHandler))
+	if s.keys != nil {
+		// Keys go only to sessions that pass the same gate as the segments.
+		keyHandler := keys.NewKeyHandler(s.keys, s.logger)
+		mux.HandleFunc(keys.DeliveryPath, s.requireAuth(s.requireNFT(keyHandler.ServeKey)))
+		mux.HandleFunc(licensePath, s.requireAuth(s.requireNFT(s.licenses.LicenseHandler)))
+		mux.HandleFunc(licenseCertificatePath, s.requireAuth(s.licenses.CertificateHandler))
+	}
 
 	mux.HandleFunc("/", handler.NotFoundHandler)
 
@@ -127,6 +220,9 @@ func (s *StreamingServer) Stop(ctx context.Context) error {
 	if s.cache != nil {
 		s.cache.Close()
 	}
+	if s.closeGate != nil {
+		s.closeGate()
+	}
 
 	return nil
 }
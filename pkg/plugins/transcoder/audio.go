@@ -0,0 +1,368 @@
+package transcoder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+	"go.uber.org/zap"
+)
+
+// EBU R128 loudness defaults, used for zero LoudnessConfig fields.
+const (
+	DefaultIntegratedLoudness = -23.0
+	DefaultTruePeak           = -1.0
+	DefaultLoudnessRange      = 7.0
+)
+
+// AudioGroupID is the EXT-X-MEDIA group of the alternate audio renditions.
+const AudioGroupID = "audio"
+
+// undeterminedLanguage is the ISO 639-2 code of tracks without a language.
+const undeterminedLanguage = "und"
+
+// AudioTrack is one audio stream of a source.
+type AudioTrack struct {
+	// Index is the track's position among the source's audio streams, as
+	// selected with -map 0:a:Index.
+	Index    int
+	Codec    string
+	Language string
+	Title    string
+	Channels int
+	// Default is set for the track the source marks as played by default.
+	Default bool
+}
+
+// probeAudioTrack builds the AudioTrack of the index-th audio stream.
+func probeAudioTrack(stream ffprobeStream, index int) AudioTrack {
+	return AudioTrack{
+		Index:    index,
+		Codec:    stream.CodecName,
+		Language: normalizeLanguage(stream.Tags["language"]),
+		Title:    stream.Tags["title"],
+		Channels: stream.Channels,
+		Default:  stream.Disposition["default"] == 1,
+	}
+}
+
+// normalizeLanguage lowercases a language tag and drops anything that is
+// not safe in a file name, so it can name the track's rendition.
+func normalizeLanguage(tag string) string {
+	language := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return -1
+	}, tag)
+	language = strings.Trim(language, "-")
+	if language == "" {
+		return undeterminedLanguage
+	}
+	return language
+}
+
+// LoudnessConfig is the EBU R128 target audio is normalised to with
+// FFmpeg's two-pass loudnorm filter. Zero fields take the Default* values.
+type LoudnessConfig struct {
+	// IntegratedLUFS is the integrated loudness target, in LUFS.
+	IntegratedLUFS float64
+	// TruePeak is the maximum true peak, in dBTP.
+	TruePeak float64
+	// LoudnessRange is the loudness range target, in LU.
+	LoudnessRange float64
+}
+
+// LoudnessFromConfig maps transcoding.loudness to the loudness target, or
+// nil when normalisation is disabled.
+func LoudnessFromConfig(cfg config.LoudnessConfig) *LoudnessConfig {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &LoudnessConfig{IntegratedLUFS: cfg.Integrated, TruePeak: cfg.TruePeak, LoudnessRange: cfg.Range}
+}
+
+func (c LoudnessConfig) withDefaults() LoudnessConfig {
+	if c.IntegratedLUFS == 0 {
+		c.IntegratedLUFS = DefaultIntegratedLoudness
+	}
+	if c.TruePeak == 0 {
+		c.TruePeak = DefaultTruePeak
+	}
+	if c.LoudnessRange == 0 {
+		c.LoudnessRange = DefaultLoudnessRange
+	}
+	return c
+}
+
+// loudnessMeasurement is what the first loudnorm pass measured of a track.
+type loudnessMeasurement struct {
+	Integrated float64
+	TruePeak   float64
+	Range      float64
+	Threshold  float64
+	Offset     float64
+}
+
+// loudnormFilter returns the -af loudnorm filter normalising to target.
+// With a measurement it is the second, linear pass; without one loudnorm
+// normalises dynamically in a single pass.
+func loudnormFilter(target LoudnessConfig, m *loudnessMeasurement) string {
+	filter := fmt.Sprintf("loudnorm=I=%s:TP=%s:LRA=%s",
+		formatLoudness(target.IntegratedLUFS), formatLoudness(target.TruePeak), formatLoudness(target.LoudnessRange))
+	if m == nil {
+		return filter
+	}
+	return filter + fmt.Sprintf(":measured_I=%s:measured_TP=%s:measured_LRA=%s:measured_thresh=%s:offset=%s:linear=true",
+		formatLoudness(m.Integrated), formatLoudness(m.TruePeak), formatLoudness(m.Range),
+		formatLoudness(m.Threshold), formatLoudness(m.Offset))
+}
+
+func formatLoudness(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// measureLoudness runs the first loudnorm pass over one track.
+func (ft *FFmpegTranscoder) measureLoudness(ctx context.Context, inputPath string, track AudioTrack, target LoudnessConfig) (*loudnessMeasurement, error) {
+	args := []string{
+		"-hide_banner", "-nostats",
+		"-i", inputPath,
+		"-map", fmt.Sprintf("0:a:%d", track.Index),
+		"-af", loudnormFilter(target, nil) + ":print_format=json",
+		"-f", "null", "-",
+	}
+	output, err := exec.CommandContext(ctx, ft.config.FFmpegPath, args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("loudness measurement failed: %w", err)
+	}
+	return parseLoudnorm(output)
+}
+
+// parseLoudnorm reads the JSON summary loudnorm prints last to stderr.
+// Silent tracks measure -inf and cannot be normalised linearly.
+func parseLoudnorm(output []byte) (*loudnessMeasurement, error) {
+	start, end := bytes.LastIndexByte(output, '{'), bytes.LastIndexByte(output, '}')
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("loudness measurement reported no result")
+	}
+	var raw struct {
+		InputI       string `json:"input_i"`
+		InputTP      string `json:"input_tp"`
+		InputLRA     string `json:"input_lra"`
+		InputThresh  string `json:"input_thresh"`
+		TargetOffset string `json:"target_offset"`
+	}
+	if err := json.Unmarshal(output[start:end+1], &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse loudness measurement: %w", err)
+	}
+
+	values := make([]float64, 0, 5)
+	for _, s := range []string{raw.InputI, raw.InputTP, raw.InputLRA, raw.InputThresh, raw.TargetOffset} {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil, fmt.Errorf("unusable loudness measurement %q", s)
+		}
+		values = append(values, v)
+	}
+	return &loudnessMeasurement{
+		Integrated: values[0],
+		TruePeak:   values[1],
+		Range:      values[2],
+		Threshold:  values[3],
+		Offset:     values[4],
+	}, nil
+}
+
+// audioPlan is how one HLS transcode carries the source's audio: its
+// tracks in playlist order, the default first, and the loudnorm filter of
+// each, empty when loudness normalisation is off.
+type audioPlan struct {
+	tracks  []AudioTrack
+	filters []string
+	// alternate publishes every track as an audio-only rendition instead
+	// of muxing the default track into each rung.
+	alternate bool
+}
+
+type audioPlanContextKey struct{}
+
+// withAudioPlan makes rungs encoded with the returned context follow plan.
+// runEncode is shared by every rung type, so the plan travels with the
+// context like the HLS and DRM keys.
+func withAudioPlan(ctx context.Context, plan *audioPlan) context.Context {
+	return context.WithValue(ctx, audioPlanContextKey{}, plan)
+}
+
+func audioPlanFromContext(ctx context.Context) *audioPlan {
+	plan, _ := ctx.Value(audioPlanContextKey{}).(*audioPlan)
+	return plan
+}
+
+// planAudio orders the source's tracks and measures their loudness. A
+// source without audio has no plan. A track whose measurement fails is
+// normalised in a single pass rather than failing the transcode.
+func (ft *FFmpegTranscoder) planAudio(ctx context.Context, inputPath string, info *VideoInfo) *audioPlan {
+	tracks := orderAudioTracks(info.AudioTracks)
+	if len(tracks) == 0 {
+		return nil
+	}
+	plan := &audioPlan{tracks: tracks, filters: make([]string, len(tracks)), alternate: len(tracks) > 1}
+	if ft.config.Loudness == nil {
+		return plan
+	}
+	target := ft.config.Loudness.withDefaults()
+	for i, track := range tracks {
+		m, err := ft.measureLoudness(ctx, inputPath, track, target)
+		if err != nil {
+			ft.logger.Warn("Loudness measurement failed, normalising in a single pass",
+				zap.Int("track", track.Index), zap.Error(err))
+		}
+		plan.filters[i] = loudnormFilter(target, m)
+	}
+	return plan
+}
+
+// orderAudioTracks moves the source's default track first, keeping the
+// others in stream order.
+func orderAudioTracks(tracks []AudioTrack) []AudioTrack {
+	ordered := make([]AudioTrack, 0, len(tracks))
+	for _, t := range tracks {
+		if t.Default && (len(ordered) == 0 || !ordered[0].Default) {
+			ordered = append([]AudioTrack{t}, ordered...)
+			continue
+		}
+		ordered = append(ordered, t)
+	}
+	return ordered
+}
+
+// aacArgs encodes the selected audio as stereo AAC.
+func (ft *FFmpegTranscoder) aacArgs() []string {
+	codec := ft.config.AudioCodec
+	if codec == "" {
+		codec = "aac"
+	}
+	return []string{"-c:a", codec, "-b:a", "128k", "-ac", "2"}
+}
+
+// rungAudioArgs returns the audio options of one rung. Without a plan the
+// source's first track is muxed in; with one, its default track is, after
+// loudness normalisation. Rungs of a plan with alternate renditions are
+// video-only, except DRM rungs: FFmpeg and the packager protect the audio
+// they mux, so those keep the default track.
+func (ft *FFmpegTranscoder) rungAudioArgs(plan *audioPlan, profile TranscodeProfile) []string {
+	if plan == nil {
+		return ft.aacArgs()
+	}
+	if plan.alternate && profile.DRM == "" {
+		return []string{"-an"}
+	}
+	args := []string{"-map", "0:v:0", "-map", fmt.Sprintf("0:a:%d", plan.tracks[0].Index)}
+	if plan.filters[0] != "" {
+		args = append(args, "-af", plan.filters[0])
+	}
+	return append(args, ft.aacArgs()...)
+}
+
+// transcodeAudioRenditions writes every track of the plan as an audio-only
+// HLS rendition named by AudioRenditionName.
+func (ft *FFmpegTranscoder) transcodeAudioRenditions(ctx context.Context, inputPath, outputDir, segmentVersion, keyInfo string, plan *audioPlan, totalDuration time.Duration) error {
+	for i, track := range plan.tracks {
+		name := AudioRenditionName(i, track.Language)
+		outputPath := filepath.Join(outputDir, name+".m3u8")
+		args := []string{
+			"-i", inputPath,
+			"-map", fmt.Sprintf("0:a:%d", track.Index),
+			"-vn",
+		}
+		if plan.filters[i] != "" {
+			args = append(args, "-af", plan.filters[i])
+		}
+		args = append(args, ft.aacArgs()...)
+		args = append(args,
+			"-f", "hls",
+			"-hls_time", "6",
+			"-hls_list_size", "0",
+			"-hls_segment_filename", segmentFilePattern(outputPath, segmentVersion),
+		)
+		if keyInfo != "" {
+			args = append(args, "-hls_key_info_file", keyInfo)
+		}
+		args = append(args, "-y", outputPath)
+		if err := ft.runFFmpeg(ctx, args, totalDuration, nil); err != nil {
+			return fmt.Errorf("failed to transcode audio track %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// AudioRenditionName names the alternate audio rendition at position in
+// the playlist, e.g. "audio-0-eng".
+func AudioRenditionName(position int, language string) string {
+	return fmt.Sprintf("audio-%d-%s", position, language)
+}
+
+// ParseAudioRenditionName splits an AudioRenditionName into its position
+// and language; ok is false for video variant names.
+func ParseAudioRenditionName(name string) (position int, language string, ok bool) {
+	rest, found := strings.CutPrefix(name, "audio-")
+	if !found {
+		return 0, "", false
+	}
+	digits, language, found := strings.Cut(rest, "-")
+	if !found || language == "" {
+		return 0, "", false
+	}
+	position, err := strconv.Atoi(digits)
+	if err != nil || position < 0 {
+		return 0, "", false
+	}
+	return position, language, true
+}
+
+// AudioMediaTag returns the EXT-X-MEDIA tag of the alternate audio
+// rendition at position; the first is the default. Undetermined languages
+// are left out of LANGUAGE.
+func AudioMediaTag(position int, language, name, uri string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"%s\"", AudioGroupID)
+	if language != undeterminedLanguage {
+		fmt.Fprintf(&b, ",LANGUAGE=\"%s\"", language)
+	}
+	isDefault := "NO"
+	if position == 0 {
+		isDefault = "YES"
+	}
+	fmt.Fprintf(&b, ",NAME=\"%s\",DEFAULT=%s,AUTOSELECT=YES,URI=\"%s\"\n", quotedStringSafe(name), isDefault, uri)
+	return b.String()
+}
+
+// audioTrackName is the NAME players list a track under: its title, or
+// its language.
+func audioTrackName(track AudioTrack) string {
+	if strings.TrimSpace(track.Title) != "" {
+		return track.Title
+	}
+	return track.Language
+}
+
+// quotedStringSafe drops the characters an HLS quoted-string cannot hold.
+func quotedStringSafe(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '"' || r == '\n' || r == '\r' {
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,172 @@
+package transcoder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const loudnormOutput = `[Parsed_loudnorm_0 @ 0x55d0c8a1e2c0]
+{
+	"input_i" : "-27.61",
+	"input_tp" : "-4.47",
+	"input_lra" : "18.06",
+	"input_thresh" : "-39.20",
+	"output_i" : "-23.01",
+	"output_tp" : "-1.00",
+	"output_lra" : "7.00",
+	"output_thresh" : "-33.52",
+	"normalization_type" : "dynamic",
+	"target_offset" : "0.01"
+}
+`
+
+// fakeAudioFFmpeg replaces cfg's ffprobe with one reporting streams and
+// its ffmpeg with one that prints loudnorm measurements, or fails them
+// when measure is false, and records the arguments of every other run.
+func fakeAudioFFmpeg(t *testing.T, cfg *FFmpegConfig, streams string, measure bool) {
+	t.Helper()
+	probe := `#!/bin/sh
+cat <<'JSON'
+{"streams":[{"codec_type":"video","codec_name":"h264","width":1920,"height":1080},` + streams + `],"format":{"duration":"12.0","size":"1000"}}
+JSON
+`
+	measurement := "echo 'no audio' >&2; exit 1"
+	if measure {
+		measurement = "cat >&2 <<'JSON'\n" + loudnormOutput + "JSON\nexit 0"
+	}
+	ffmpeg := `#!/bin/sh
+case "$*" in
+  *print_format=json*)
+` + measurement + `
+  ;;
+esac
+for arg in "$@"; do last="$arg"; done
+echo "$@" > "$last.args"
+printf '#EXTM3U\n' > "$last"
+`
+	require.NoError(t, os.WriteFile(cfg.FFprobePath, []byte(probe), 0o755))
+	require.NoError(t, os.WriteFile(cfg.FFmpegPath, []byte(ffmpeg), 0o755))
+}
+
+func TestParseProbeOutput_AudioTracks(t *testing.T) {
+	output := []byte(`{"streams":[
+		{"codec_type":"video","codec_name":"h264","width":1920,"height":1080},
+		{"codec_type":"audio","codec_name":"ac3","channels":6,"tags":{"language":"eng","title":"Surround"}},
+		{"codec_type":"audio","codec_name":"aac","channels":2,"disposition":{"default":1},"tags":{"language":"FRE"}},
+		{"codec_type":"audio","codec_name":"opus","channels":2}
+	],"format":{"duration":"60.0"}}`)
+
+	info, err := parseProbeOutput(output)
+	require.NoError(t, err)
+	assert.Equal(t, "ac3", info.AudioCodec)
+	assert.Equal(t, []AudioTrack{
+		{Index: 0, Codec: "ac3", Language: "eng", Title: "Surround", Channels: 6},
+		{Index: 1, Codec: "aac", Language: "fre", Channels: 2, Default: true},
+		{Index: 2, Codec: "opus", Language: "und", Channels: 2},
+	}, info.AudioTracks)
+
+	ordered := orderAudioTracks(info.AudioTracks)
+	assert.Equal(t, []int{1, 0, 2}, []int{ordered[0].Index, ordered[1].Index, ordered[2].Index}, "the default track leads")
+}
+
+func TestParseLoudnorm(t *testing.T) {
+	m, err := parseLoudnorm([]byte("Input #0, mov,mp4\n" + loudnormOutput))
+	require.NoError(t, err)
+	assert.Equal(t, loudnessMeasurement{Integrated: -27.61, TruePeak: -4.47, Range: 18.06, Threshold: -39.2, Offset: 0.01}, *m)
+
+	_, err = parseLoudnorm([]byte(`{"input_i" : "-inf", "input_tp" : "-inf", "input_lra" : "0.00", "input_thresh" : "-70.00", "target_offset" : "0.00"}`))
+	assert.Error(t, err, "silence cannot be normalised linearly")
+
+	_, err = parseLoudnorm([]byte("Output #0, null"))
+	assert.Error(t, err)
+}
+
+func TestLoudnormFilter(t *testing.T) {
+	target := LoudnessConfig{}.withDefaults()
+	assert.Equal(t, "loudnorm=I=-23:TP=-1:LRA=7", loudnormFilter(target, nil))
+
+	m := &loudnessMeasurement{Integrated: -27.61, TruePeak: -4.47, Range: 18.06, Threshold: -39.2, Offset: 0.01}
+	assert.Equal(t,
+		"loudnorm=I=-23:TP=-1:LRA=7:measured_I=-27.61:measured_TP=-4.47:measured_LRA=18.06:measured_thresh=-39.2:offset=0.01:linear=true",
+		loudnormFilter(target, m))
+}
+
+func TestLoudnessFromConfig(t *testing.T) {
+	assert.Nil(t, LoudnessFromConfig(config.LoudnessConfig{Integrated: -16}))
+	assert.Equal(t, &LoudnessConfig{IntegratedLUFS: -16, TruePeak: -1.5, LoudnessRange: 11},
+		LoudnessFromConfig(config.LoudnessConfig{Enabled: true, Integrated: -16, TruePeak: -1.5, Range: 11}))
+}
+
+func TestAudioRenditionName(t *testing.T) {
+	assert.Equal(t, "audio-1-pt-br", AudioRenditionName(1, normalizeLanguage("pt-BR")))
+
+	position, language, ok := ParseAudioRenditionName("audio-1-pt-br")
+	require.True(t, ok)
+	assert.Equal(t, 1, position)
+	assert.Equal(t, "pt-br", language)
+
+	for _, name := range []string{"1280x720", "audio-eng", "audio-x-eng", "audio-0-"} {
+		_, _, ok := ParseAudioRenditionName(name)
+		assert.False(t, ok, name)
+	}
+}
+
+func TestTranscodeToHLS_MultipleAudioTracks(t *testing.T) {
+	cfg := fakeFFmpeg(t, "no-such-filter")
+	cfg.Loudness = &LoudnessConfig{}
+	fakeAudioFFmpeg(t, cfg, `{"codec_type":"audio","codec_name":"aac","channels":2,"tags":{"language":"eng"}},
+{"codec_type":"audio","codec_name":"ac3","channels":6,"disposition":{"default":1},"tags":{"language":"fra","title":"Fran\"cais"}}`, true)
+	ft := NewFFmpegTranscoder(cfg, zap.NewNop())
+	outputDir := t.TempDir()
+
+	profiles := []TranscodeProfile{{Resolution: "1280x720", Bitrate: "2500k", Format: "hls"}}
+	require.NoError(t, ft.TranscodeToHLS(context.Background(), filepath.Join(cfg.TempDir, "input.mp4"), outputDir, profiles, nil, nil))
+
+	args, err := os.ReadFile(filepath.Join(outputDir, "audio-0-fra.m3u8.args"))
+	require.NoError(t, err)
+	assert.Contains(t, string(args), "-map 0:a:1 -vn -af loudnorm=I=-23:TP=-1:LRA=7:measured_I=-27.61:")
+	assert.Contains(t, string(args), "linear=true -c:a aac -b:a 128k -ac 2")
+	args, err = os.ReadFile(filepath.Join(outputDir, "audio-1-eng.m3u8.args"))
+	require.NoError(t, err)
+	assert.Contains(t, string(args), "-map 0:a:0 -vn")
+
+	args, err = os.ReadFile(filepath.Join(outputDir, "1280x720.m3u8.args"))
+	require.NoError(t, err)
+	assert.Contains(t, string(args), " -an ")
+	assert.NotContains(t, string(args), "-c:a")
+
+	master, err := os.ReadFile(filepath.Join(outputDir, "master.m3u8"))
+	require.NoError(t, err)
+	assert.Contains(t, string(master), `#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="audio",LANGUAGE="fra",NAME="Francais",DEFAULT=YES,AUTOSELECT=YES,URI="audio-0-fra.m3u8"`+"\n")
+	assert.Contains(t, string(master), `#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="audio",LANGUAGE="eng",NAME="eng",DEFAULT=NO,AUTOSELECT=YES,URI="audio-1-eng.m3u8"`+"\n")
+	assert.Contains(t, string(master), `CODECS="avc1.64001f,mp4a.40.2",AUDIO="audio"`+"\n1280x720.m3u8\n")
+}
+
+func TestTranscodeToHLS_SingleAudioTrack(t *testing.T) {
+	cfg := fakeFFmpeg(t, "no-such-filter")
+	cfg.Loudness = &LoudnessConfig{IntegratedLUFS: -16}
+	fakeAudioFFmpeg(t, cfg, `{"codec_type":"audio","codec_name":"aac","channels":2}`, false)
+	ft := NewFFmpegTranscoder(cfg, zap.NewNop())
+	outputDir := t.TempDir()
+
+	profiles := []TranscodeProfile{{Resolution: "1280x720", Bitrate: "2500k", Format: "hls"}}
+	require.NoError(t, ft.TranscodeToHLS(context.Background(), filepath.Join(cfg.TempDir, "input.mp4"), outputDir, profiles, nil, nil))
+
+	args, err := os.ReadFile(filepath.Join(outputDir, "1280x720.m3u8.args"))
+	require.NoError(t, err)
+	assert.Contains(t, string(args), "-map 0:v:0 -map 0:a:0 -af loudnorm=I=-16:TP=-1:LRA=7 -c:a aac",
+		"a failed measurement normalises in a single pass")
+
+	master, err := os.ReadFile(filepath.Join(outputDir, "master.m3u8"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(master), "#EXT-X-MEDIA")
+	assert.NotContains(t, string(master), "AUDIO=")
+	assert.NoFileExists(t, filepath.Join(outputDir, "audio-0-und.m3u8"))
+}
@@ -0,0 +1,170 @@
+package transcoder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/rtcdance/streamgate/pkg/core/event"
+)
+
+// cancelWaitTimeout bounds how long CancelTask waits for a running task's
+// worker to stop FFmpeg and clean up.
+const cancelWaitTimeout = 30 * time.Second
+
+// checkpointReserve is kept back from the shutdown deadline to checkpoint
+// the tasks still running when it comes.
+const checkpointReserve = 5 * time.Second
+
+// errTaskCancelled is the cause of a running task's context when the task
+// is cancelled, telling it apart from the worker pool shutting down.
+var errTaskCancelled = errors.New("task cancelled")
+
+// errTaskCheckpointed is the cause of a running task's context when the
+// pool stops before the task finishes.
+var errTaskCheckpointed = errors.New("task checkpointed for shutdown")
+
+// taskRun is a task being transcoded by this pool.
+type taskRun struct {
+	cancel context.CancelCauseFunc
+	done   chan struct{}
+}
+
+// startRun registers a task as running and returns the context its
+// transcode runs under. Cancelling the context kills its FFmpeg processes.
+// The release func must be called once the task has reached its final state.
+func (wp *WorkerPool) startRun(taskID string) (context.Context, func()) {
+	parent := wp.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancelCause(parent)
+	run := &taskRun{cancel: cancel, done: make(chan struct{})}
+
+	wp.mu.Lock()
+	if wp.running == nil {
+		wp.running = make(map[string]*taskRun)
+	}
+	wp.running[taskID] = run
+	wp.mu.Unlock()
+
+	return ctx, func() {
+		wp.mu.Lock()
+		if wp.running[taskID] == run {
+			delete(wp.running, taskID)
+		}
+		wp.mu.Unlock()
+		cancel(nil)
+		close(run.done)
+	}
+}
+
+// cancelRunning cancels a task this pool is transcoding. It returns a
+// channel closed once the task has stopped, or false if it is not running.
+func (wp *WorkerPool) cancelRunning(taskID string) (<-chan struct{}, bool) {
+	wp.mu.RLock()
+	run, ok := wp.running[taskID]
+	wp.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	run.cancel(errTaskCancelled)
+	return run.done, true
+}
+
+// checkpointRunning stops every task this pool is transcoding so it can
+// be checkpointed.
+func (wp *WorkerPool) checkpointRunning() {
+	wp.mu.RLock()
+	defer wp.mu.RUnlock()
+	for _, run := range wp.running {
+		run.cancel(errTaskCheckpointed)
+	}
+}
+
+// outputDir returns the directory a task's HLS output is written to.
+func (wp *WorkerPool) outputDir(taskID string) string {
+	dir := os.TempDir()
+	if wp.ffmpeg != nil && wp.ffmpeg.config.TempDir != "" {
+		dir = wp.ffmpeg.config.TempDir
+	}
+	return filepath.Join(dir, "streamgate-transcode-"+taskID)
+}
+
+// finishCancelled removes what a cancelled task wrote, marks it cancelled
+// and announces it with the task's final state.
+func (wp *WorkerPool) finishCancelled(task *TranscodeTask) {
+	if err := os.RemoveAll(wp.outputDir(task.ID)); err != nil {
+		wp.logger.Warn("Failed to remove cancelled task output",
+			zap.String("task_id", task.ID), zap.Error(err))
+	}
+
+	completedAt := time.Now()
+	_ = wp.taskQueue.TransitionStatus(task.ID, func(t *TranscodeTask) {
+		t.Status = TaskStatusCancelled
+		t.CompletedAt = &completedAt
+		t.Error = ""
+		t.FailureReason = ""
+		t.Retryable = false
+		t.FailedVariants = nil
+	})
+	final := *task
+	if current, err := wp.taskQueue.GetTask(task.ID); err == nil {
+		final = *current
+	}
+	wp.logger.Info("Transcode task cancelled", zap.String("task_id", task.ID))
+
+	pubCtx, pubCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer pubCancel()
+	_ = wp.eventBus.Publish(pubCtx, &event.Event{
+		Type: "transcode.task.cancelled",
+		Data: map[string]interface{}{"task": &final},
+	})
+}
+
+// finishCheckpointed removes what a task stopped by shutdown wrote and
+// puts it back to pending. Its run is not counted as an attempt; the next
+// transcoder to claim it starts over.
+func (wp *WorkerPool) finishCheckpointed(task *TranscodeTask) {
+	if err := os.RemoveAll(wp.outputDir(task.ID)); err != nil {
+		wp.logger.Warn("Failed to remove checkpointed task output",
+			zap.String("task_id", task.ID), zap.Error(err))
+	}
+	_ = wp.taskQueue.TransitionStatus(task.ID, func(t *TranscodeTask) {
+		t.Status = TaskStatusPending
+		t.WorkerID = ""
+		t.Instance = ""
+		t.StartedAt = nil
+		t.Progress = 0
+		t.Speed = ""
+	})
+	wp.logger.Info("Transcode task checkpointed for shutdown", zap.String("task_id", task.ID))
+}
+
+// cancelTask stops a task this pool is transcoding and waits for it to
+// reach its final state. It returns false if the task is not running here.
+func (wp *WorkerPool) cancelTask(taskID string) (bool, error) {
+	done, ok := wp.cancelRunning(taskID)
+	if !ok {
+		return false, nil
+	}
+	select {
+	case <-done:
+	case <-time.After(cancelWaitTimeout):
+		return true, fmt.Errorf("task %s did not stop within %s", taskID, cancelWaitTimeout)
+	}
+
+	task, err := wp.taskQueue.GetTask(taskID)
+	if err != nil {
+		return true, err
+	}
+	if task.Status != TaskStatusCancelled {
+		return true, fmt.Errorf("task %s finished as %s before it could be cancelled", taskID, task.Status)
+	}
+	return true, nil
+}
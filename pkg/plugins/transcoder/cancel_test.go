@@ -0,0 +1,114 @@
+package transcoder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranscoderPlugin_CancelRunningTask(t *testing.T) {
+	pool, cfg := newFailingPool(t, "")
+	// An encoder that starts writing output and then never finishes.
+	hang := "#!/bin/sh\nfor arg in \"$@\"; do last=\"$arg\"; done\nprintf '#EXTM3U\\n' > \"$last\"\nexec sleep 30\n"
+	require.NoError(t, os.WriteFile(cfg.FFmpegPath, []byte(hang), 0o755))
+	plugin := &TranscoderPlugin{taskQueue: pool.taskQueue, workerPool: pool}
+
+	events := make(chan *event.Event, 1)
+	_, err := pool.eventBus.Subscribe(context.Background(), "transcode.task.cancelled", func(_ context.Context, e *event.Event) error {
+		events <- e
+		return nil
+	})
+	require.NoError(t, err)
+
+	task := &TranscodeTask{ID: "task-1", FilePath: filepath.Join(cfg.TempDir, "input.mp4"), Profiles: BuiltinLadder(), MaxRetries: 3}
+	require.NoError(t, pool.taskQueue.Enqueue(task))
+	queued, err := pool.taskQueue.Dequeue(context.Background())
+	require.NoError(t, err)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pool.processTask(&Worker{ID: "worker-1"}, queued)
+	}()
+
+	outputDir := pool.outputDir(task.ID)
+	require.Eventually(t, func() bool {
+		matches, _ := filepath.Glob(filepath.Join(outputDir, "*.m3u8"))
+		return len(matches) > 0
+	}, 5*time.Second, 10*time.Millisecond, "FFmpeg starts writing output")
+
+	start := time.Now()
+	require.NoError(t, plugin.CancelTask(task.ID))
+	assert.Less(t, time.Since(start), 10*time.Second, "FFmpeg is killed, not waited for")
+	<-done
+
+	got, err := pool.taskQueue.GetTask(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusCancelled, got.Status)
+	assert.NotNil(t, got.CompletedAt)
+	assert.Empty(t, got.Error)
+	assert.Zero(t, pool.taskQueue.Len(), "cancelled tasks are not retried")
+	assert.NoDirExists(t, outputDir)
+
+	select {
+	case e := <-events:
+		published, ok := e.Data["task"].(*TranscodeTask)
+		require.True(t, ok)
+		assert.Equal(t, TaskStatusCancelled, published.Status)
+	case <-time.After(time.Second):
+		t.Fatal("no cancelled event")
+	}
+
+	assert.Error(t, plugin.CancelTask(task.ID), "finished tasks cannot be cancelled again")
+}
+
+func TestTranscoderPlugin_CancelBeforeStart(t *testing.T) {
+	pool, cfg := newFailingPool(t, "")
+	plugin := &TranscoderPlugin{taskQueue: pool.taskQueue, workerPool: pool}
+
+	task := &TranscodeTask{ID: "task-1", FilePath: filepath.Join(cfg.TempDir, "input.mp4"), Profiles: BuiltinLadder()}
+	require.NoError(t, pool.taskQueue.Enqueue(task))
+	queued, err := pool.taskQueue.Dequeue(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, plugin.CancelTask(task.ID))
+	pool.processTask(&Worker{ID: "worker-1"}, queued)
+
+	got, err := pool.taskQueue.GetTask(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusCancelled, got.Status, "a task cancelled before it starts never runs")
+	assert.Nil(t, got.StartedAt)
+	assert.NoDirExists(t, pool.outputDir(task.ID))
+}
+
+func TestWorkerPool_Stop_CheckpointsRunningTask(t *testing.T) {
+	pool, cfg := newFailingPool(t, "")
+	hang := "#!/bin/sh\nfor arg in \"$@\"; do last=\"$arg\"; done\nprintf '#EXTM3U\\n' > \"$last\"\nexec sleep 30\n"
+	require.NoError(t, os.WriteFile(cfg.FFmpegPath, []byte(hang), 0o755))
+	require.NoError(t, pool.Start(context.Background(), 1))
+
+	task := &TranscodeTask{ID: "task-1", FilePath: filepath.Join(cfg.TempDir, "input.mp4"), Profiles: BuiltinLadder(), MaxRetries: 3}
+	require.NoError(t, pool.taskQueue.Enqueue(task))
+	outputDir := pool.outputDir(task.ID)
+	require.Eventually(t, func() bool {
+		matches, _ := filepath.Glob(filepath.Join(outputDir, "*.m3u8"))
+		return len(matches) > 0
+	}, 5*time.Second, 10*time.Millisecond, "FFmpeg starts writing output")
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkpointReserve+200*time.Millisecond)
+	defer cancel()
+	require.NoError(t, pool.Stop(ctx))
+
+	got, err := pool.taskQueue.GetTask(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusPending, got.Status)
+	assert.Zero(t, got.RetryCount, "a checkpoint is not an attempt")
+	assert.Nil(t, got.StartedAt)
+	assert.Empty(t, got.WorkerID)
+	assert.NoDirExists(t, outputDir)
+}
@@ -0,0 +1,99 @@
+package transcoder
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Video codec families of HLS rungs.
+const (
+	CodecH264 = "h264"
+	CodecHEVC = "hevc"
+	CodecAV1  = "av1"
+)
+
+// audioCodecString is the CODECS entry of the AAC-LC audio every rung
+// carries.
+const audioCodecString = "mp4a.40.2"
+
+// codecLevel is the lowest level of each codec that fits frames up to
+// maxHeight at 30fps, as written in CODECS strings: H.264 level_idc in hex,
+// HEVC general_level_idc (level × 30) and AV1 seq_level_idx.
+type codecLevel struct {
+	maxHeight int
+	avc       string
+	hevc      int
+	av1       int
+}
+
+var codecLevels = []codecLevel{
+	{maxHeight: 480, avc: "1e", hevc: 90, av1: 4},
+	{maxHeight: 720, avc: "1f", hevc: 93, av1: 5},
+	{maxHeight: 1080, avc: "28", hevc: 120, av1: 8},
+	{maxHeight: 2160, avc: "33", hevc: 153, av1: 12},
+}
+
+// Name returns the rung's variant name, which names its playlist and
+// segments: the resolution, suffixed with the codec for HEVC and AV1 rungs
+// so ladders of several codecs can share resolutions, e.g.
+// "1920x1080-hevc".
+func (p TranscodeProfile) Name() string {
+	if family := codecFamily(p.Codec); family == CodecHEVC || family == CodecAV1 {
+		return p.Resolution + "-" + family
+	}
+	return p.Resolution
+}
+
+// ParseVariantName splits a variant name into its resolution and codec
+// family; names without a codec suffix are H.264.
+func ParseVariantName(name string) (resolution, codec string) {
+	if resolution, codec, ok := strings.Cut(name, "-"); ok && (codec == CodecHEVC || codec == CodecAV1) {
+		return resolution, codec
+	}
+	return name, CodecH264
+}
+
+// fragmentedMP4 reports whether a rung is written as fMP4 rather than
+// MPEG-TS; HLS carries HEVC and AV1 only in fMP4.
+func (p TranscodeProfile) fragmentedMP4() bool {
+	family := codecFamily(p.Codec)
+	return family == CodecHEVC || family == CodecAV1
+}
+
+// HLSCodecs returns the CODECS attribute of a rung of the given codec
+// family and frame height, at the profile rungs are encoded with (H.264
+// High, HEVC Main, AV1 Main 8-bit) and the level the height needs.
+func HLSCodecs(codec string, height int) string {
+	level := codecLevels[len(codecLevels)-1]
+	for _, l := range codecLevels {
+		if height <= l.maxHeight {
+			level = l
+			break
+		}
+	}
+
+	var video string
+	switch codec {
+	case CodecHEVC:
+		video = fmt.Sprintf("hvc1.1.6.L%d.90", level.hevc)
+	case CodecAV1:
+		video = fmt.Sprintf("av01.0.%02dM.08", level.av1)
+	default:
+		video = "avc1.6400" + level.avc
+	}
+	return video + "," + audioCodecString
+}
+
+// codecArgs returns the options that pin a family to the profile HLSCodecs
+// advertises.
+func codecArgs(family string) []string {
+	switch family {
+	case CodecH264:
+		return []string{"-profile:v", "high"}
+	case CodecHEVC:
+		// Apple players require the hvc1 sample entry.
+		return []string{"-profile:v", "main", "-tag:v", "hvc1"}
+	default:
+		return nil
+	}
+}
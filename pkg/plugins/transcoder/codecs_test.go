@@ -0,0 +1,96 @@
+package transcoder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTranscodeProfile_Name(t *testing.T) {
+	assert.Equal(t, "1920x1080", TranscodeProfile{Resolution: "1920x1080"}.Name())
+	assert.Equal(t, "1920x1080", TranscodeProfile{Resolution: "1920x1080", Codec: "h264_nvenc"}.Name())
+	assert.Equal(t, "1920x1080-hevc", TranscodeProfile{Resolution: "1920x1080", Codec: "libx265"}.Name())
+	assert.Equal(t, "1280x720-av1", TranscodeProfile{Resolution: "1280x720", Codec: CodecAV1}.Name())
+
+	resolution, codec := ParseVariantName("1920x1080-hevc")
+	assert.Equal(t, "1920x1080", resolution)
+	assert.Equal(t, CodecHEVC, codec)
+	resolution, codec = ParseVariantName("720p")
+	assert.Equal(t, "720p", resolution)
+	assert.Equal(t, CodecH264, codec)
+}
+
+func TestHLSCodecs(t *testing.T) {
+	assert.Equal(t, "avc1.64001e,mp4a.40.2", HLSCodecs(CodecH264, 360))
+	assert.Equal(t, "avc1.640028,mp4a.40.2", HLSCodecs(CodecH264, 1080))
+	assert.Equal(t, "hvc1.1.6.L93.90,mp4a.40.2", HLSCodecs(CodecHEVC, 720))
+	assert.Equal(t, "av01.0.08M.08,mp4a.40.2", HLSCodecs(CodecAV1, 1080))
+	assert.Equal(t, "av01.0.12M.08,mp4a.40.2", HLSCodecs(CodecAV1, 4320), "heights past the table use its top level")
+}
+
+func TestTranscodeToHLS_HEVC(t *testing.T) {
+	cfg := fakeFFmpeg(t, "no-such-filter")
+	ffmpeg := `#!/bin/sh
+for arg in "$@"; do last="$arg"; done
+echo "$@" > "$last.args"
+printf '#EXTM3U\n' > "$last"
+`
+	require.NoError(t, os.WriteFile(cfg.FFmpegPath, []byte(ffmpeg), 0o755))
+	ft := NewFFmpegTranscoder(cfg, zap.NewNop())
+	outputDir := t.TempDir()
+
+	profiles := []TranscodeProfile{
+		{Resolution: "1920x1080", Bitrate: "3000k", Format: "hls", Codec: CodecHEVC},
+		{Resolution: "1920x1080", Bitrate: "5000k", Format: "hls"},
+	}
+	require.NoError(t, ft.TranscodeToHLS(context.Background(), filepath.Join(cfg.TempDir, "input.mp4"), outputDir, profiles, nil, nil))
+
+	args, err := os.ReadFile(filepath.Join(outputDir, "1920x1080-hevc.m3u8.args"))
+	require.NoError(t, err)
+	assert.Contains(t, string(args), "-c:v libx265")
+	assert.Contains(t, string(args), "-tag:v hvc1")
+	assert.Contains(t, string(args), "-hls_segment_type fmp4")
+
+	args, err = os.ReadFile(filepath.Join(outputDir, "1920x1080.m3u8.args"))
+	require.NoError(t, err)
+	assert.Contains(t, string(args), "-profile:v high")
+	assert.NotContains(t, string(args), "fmp4")
+
+	master, err := os.ReadFile(filepath.Join(outputDir, "master.m3u8"))
+	require.NoError(t, err)
+	assert.Contains(t, string(master), "RESOLUTION=1920x1080,CODECS=\"hvc1.1.6.L120.90,mp4a.40.2\"\n1920x1080-hevc.m3u8\n")
+	assert.Contains(t, string(master), "RESOLUTION=1920x1080,CODECS=\"avc1.640028,mp4a.40.2\"\n1920x1080.m3u8\n")
+}
+
+func TestCodecLaddersFromConfig(t *testing.T) {
+	assert.Nil(t, CodecLaddersFromConfig(config.TranscodingConfig{}))
+
+	ladder := CodecLaddersFromConfig(config.TranscodingConfig{
+		Codecs: []string{CodecHEVC, CodecH264, CodecAV1},
+		Qualities: []config.QualityConfig{
+			{Name: "720p", Width: 1280, Height: 720, Bitrate: 2500000},
+		},
+		CodecLadders: map[string][]config.QualityConfig{
+			CodecHEVC: {{Name: "720p", Width: 1280, Height: 720, Bitrate: 1500000, Codec: "hevc_nvenc"}},
+		},
+	})
+	require.Len(t, ladder, 2+len(BuiltinLadder()))
+	assert.Equal(t, TranscodeProfile{Resolution: "1280x720", Bitrate: "1500k", Format: "hls", Codec: "hevc_nvenc"}, ladder[0])
+	assert.Equal(t, TranscodeProfile{Resolution: "1280x720", Bitrate: "2500k", Format: "hls"}, ladder[1])
+	assert.Equal(t, BuiltinCodecLadder(CodecAV1), ladder[2:])
+	assert.NoError(t, ValidateLadder(ladder))
+}
+
+func TestValidateLadder_MixedCodecs(t *testing.T) {
+	ladder := append(BuiltinLadder(), BuiltinCodecLadder(CodecHEVC)...)
+	assert.NoError(t, ValidateLadder(ladder), "each codec's rungs are ordered on their own")
+
+	ladder = append(ladder, TranscodeProfile{Resolution: "1920x1080", Bitrate: "2500k", Format: "hls", Codec: CodecHEVC})
+	assert.ErrorIs(t, ValidateLadder(ladder), ErrInvalidLadder)
+}
@@ -0,0 +1,133 @@
+package transcoder
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/event"
+)
+
+const (
+	// claimPollInterval is how often a shared queue looks for tasks when
+	// it has room.
+	claimPollInterval = time.Second
+	// progressReportInterval spaces progress events and checkpoints of a
+	// running task.
+	progressReportInterval = 5 * time.Second
+)
+
+// share stores a submission for whichever transcoder sharing the store has
+// room first, this one included.
+func (tq *TaskQueue) share(task *TranscodeTask) error {
+	ctx, cancel := context.WithTimeout(context.Background(), taskStoreTimeout)
+	defer cancel()
+	if err := tq.store.Save(ctx, task); err != nil {
+		return err
+	}
+	atomic.AddInt64(&tq.metrics.TotalEnqueued, 1)
+	tq.nudge()
+	return nil
+}
+
+// nudge asks MaintainLeases to claim tasks now rather than at its next poll.
+func (tq *TaskQueue) nudge() {
+	select {
+	case tq.claimNow <- struct{}{}:
+	default:
+	}
+}
+
+// claimTasks claims stored tasks while the queue holds fewer than its
+// slots.
+func (tq *TaskQueue) claimTasks(ctx context.Context, now time.Time) (int, error) {
+	tq.mu.RLock()
+	room := tq.slots - len(tq.held)
+	tq.mu.RUnlock()
+	if room <= 0 {
+		return 0, nil
+	}
+	return tq.reclaim(ctx, now, room)
+}
+
+// lookup returns a task this queue does not hold from the store. Only a
+// shared queue looks there, as its tasks may be held by other transcoders.
+func (tq *TaskQueue) lookup(taskID string) (*TranscodeTask, error) {
+	if !tq.shared || tq.store == nil {
+		return nil, fmt.Errorf("task not found: %s", taskID)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), taskStoreTimeout)
+	defer cancel()
+	return tq.store.Get(ctx, taskID)
+}
+
+// cancelStored cancels a task this queue does not hold. It takes the
+// task's lease first, so a task another transcoder has queued or is
+// running cannot be cancelled from here.
+func (tq *TaskQueue) cancelStored(taskID string) error {
+	task, err := tq.lookup(taskID)
+	if err != nil {
+		return err
+	}
+	if task.Status == TaskStatusProcessing {
+		return fmt.Errorf("cannot cancel task running on transcoder %s", task.Instance)
+	}
+	if taskFinished(task.Status) {
+		return fmt.Errorf("cannot cancel %s task", task.Status)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), taskStoreTimeout)
+	defer cancel()
+	now := time.Now()
+	leased, err := tq.store.Lease(ctx, taskID, tq.owner, now, now.Add(tq.visibility))
+	if err != nil {
+		return err
+	}
+	if !leased {
+		return fmt.Errorf("task %s is held by another transcoder", taskID)
+	}
+	task.Status = TaskStatusCancelled
+	if err := tq.store.Save(ctx, task); err != nil {
+		return fmt.Errorf("failed to cancel task %s: %w", taskID, err)
+	}
+	return nil
+}
+
+// checkpoint saves a running task's progress so transcoders sharing the
+// store report it too.
+func (tq *TaskQueue) checkpoint(taskID string) {
+	if tq.store == nil {
+		return
+	}
+	tq.mu.RLock()
+	task, exists := tq.tasks[taskID]
+	var snapshot TranscodeTask
+	if exists {
+		snapshot = *task
+	}
+	tq.mu.RUnlock()
+	if exists && snapshot.Status == TaskStatusProcessing {
+		tq.record(snapshot)
+	}
+}
+
+// reportProgress publishes a running task's progress, for clients of any
+// transcoder, and checkpoints it in the task store.
+func (wp *WorkerPool) reportProgress(taskID string, p *TranscodeProgress) {
+	wp.taskQueue.checkpoint(taskID)
+	if wp.eventBus == nil {
+		return
+	}
+	pubCtx, pubCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer pubCancel()
+	_ = wp.eventBus.Publish(pubCtx, &event.Event{
+		Type: "transcode.task.progress",
+		Data: map[string]interface{}{
+			"task_id":  taskID,
+			"progress": p.Progress,
+			"speed":    p.Speed,
+			"instance": wp.taskQueue.owner,
+		},
+	})
+}
@@ -0,0 +1,130 @@
+package transcoder
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newSharedQueue(store TaskStore, owner string) *TaskQueue {
+	return NewTaskQueue(10, 0, WithTaskStore(store, owner, time.Minute), WithSharedQueue(1))
+}
+
+func TestSharedQueue_InstancesClaimByPriority(t *testing.T) {
+	ctx := context.Background()
+	store := newTestRedisTaskStore(t)
+	a, b := newSharedQueue(store, "host-a"), newSharedQueue(store, "host-b")
+
+	require.NoError(t, a.Enqueue(&TranscodeTask{ID: "low", Priority: 1}))
+	require.NoError(t, a.Enqueue(&TranscodeTask{ID: "high", Priority: 8}))
+	require.NoError(t, a.Enqueue(&TranscodeTask{ID: "normal", Priority: 5}))
+	assert.Zero(t, a.Len(), "submissions wait in the store until claimed")
+
+	claimed, err := a.claimTasks(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, claimed, "one slot, one task")
+	claimed, err = b.claimTasks(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, claimed)
+	assert.Equal(t, []string{"high"}, dequeueIDs(t, a, 1))
+	assert.Equal(t, []string{"normal"}, dequeueIDs(t, b, 1))
+
+	claimed, err = a.claimTasks(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, claimed, "a is busy")
+
+	require.NoError(t, b.TransitionStatus("normal", func(t *TranscodeTask) { t.Status = TaskStatusProcessing; t.Instance = "host-b" }))
+	task, err := a.GetTask("normal")
+	require.NoError(t, err, "any instance reports any task")
+	assert.Equal(t, TaskStatusProcessing, task.Status)
+	assert.Equal(t, "host-b", task.Instance)
+
+	require.NoError(t, a.TransitionStatus("high", func(t *TranscodeTask) { t.Status = TaskStatusCompleted }))
+	claimed, err = a.claimTasks(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, claimed)
+	assert.Equal(t, []string{"low"}, dequeueIDs(t, a, 1))
+}
+
+func TestSharedQueue_ReassignsDeadInstanceTasks(t *testing.T) {
+	ctx := context.Background()
+	store := newTestRedisTaskStore(t)
+	a, b := newSharedQueue(store, "host-a"), newSharedQueue(store, "host-b")
+
+	require.NoError(t, a.Enqueue(&TranscodeTask{ID: "task-1", MaxRetries: 3}))
+	_, err := b.claimTasks(ctx, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, []string{"task-1"}, dequeueIDs(t, b, 1))
+	require.NoError(t, b.TransitionStatus("task-1", func(t *TranscodeTask) { t.Status = TaskStatusProcessing }))
+
+	claimed, err := a.claimTasks(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, claimed, "b's lease is live")
+
+	// b stops renewing; once its lease lapses a takes the task over.
+	claimed, err = a.claimTasks(ctx, time.Now().Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, claimed)
+	task, err := a.GetTask("task-1")
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusPending, task.Status)
+	assert.Equal(t, 1, task.RetryCount)
+}
+
+func TestSharedQueue_CancelUnclaimedTask(t *testing.T) {
+	ctx := context.Background()
+	store := newTestRedisTaskStore(t)
+	a, b := newSharedQueue(store, "host-a"), newSharedQueue(store, "host-b")
+
+	require.NoError(t, a.Enqueue(&TranscodeTask{ID: "task-1"}))
+	require.NoError(t, b.CancelTask("task-1"))
+
+	task, err := a.GetTask("task-1")
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusCancelled, task.Status)
+	claimed, err := a.claimTasks(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, claimed)
+
+	assert.ErrorIs(t, b.CancelTask("missing"), ErrTaskNotFound)
+}
+
+func TestWorkerPool_ReportProgress(t *testing.T) {
+	bus, err := event.NewMemoryEventBus()
+	require.NoError(t, err)
+	store := newTestRedisTaskStore(t)
+	queue := NewTaskQueue(10, 0, WithTaskStore(store, "host-a", time.Minute))
+	pool := &WorkerPool{taskQueue: queue, eventBus: bus, logger: zap.NewNop(), metrics: &WorkerMetrics{}}
+
+	events := make(chan *event.Event, 1)
+	_, err = bus.Subscribe(context.Background(), "transcode.task.progress", func(_ context.Context, e *event.Event) error {
+		events <- e
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, queue.Enqueue(&TranscodeTask{ID: "task-1"}))
+	require.NoError(t, queue.TransitionStatus("task-1", func(t *TranscodeTask) { t.Status = TaskStatusProcessing }))
+	progress := &TranscodeProgress{Progress: 42, Speed: "2.0x"}
+	require.NoError(t, queue.UpdateProgress("task-1", progress))
+	pool.reportProgress("task-1", progress)
+
+	select {
+	case e := <-events:
+		data := e.Data
+		assert.Equal(t, "task-1", data["task_id"])
+		assert.Equal(t, 42.0, data["progress"])
+		assert.Equal(t, "host-a", data["instance"])
+	case <-time.After(time.Second):
+		t.Fatal("no progress event")
+	}
+
+	stored, err := store.Get(context.Background(), "task-1")
+	require.NoError(t, err)
+	assert.Equal(t, 42.0, stored.Progress, "progress is checkpointed for other instances")
+}
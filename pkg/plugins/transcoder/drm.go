@@ -0,0 +1,263 @@
+package transcoder
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DRMScheme is the common encryption scheme a DRM rung is packaged with.
+type DRMScheme string
+
+const (
+	// DRMSchemeCENC is AES-CTR common encryption, played by Widevine.
+	DRMSchemeCENC DRMScheme = "cenc"
+	// DRMSchemeCBCS is AES-CBC pattern encryption, played by FairPlay and
+	// by current Widevine clients.
+	DRMSchemeCBCS DRMScheme = "cbcs"
+)
+
+// Valid reports whether s is a supported scheme.
+func (s DRMScheme) Valid() bool {
+	return s == DRMSchemeCENC || s == DRMSchemeCBCS
+}
+
+// widevineSystemID is the Widevine DRM system ID, written to PSSH boxes and
+// used as the HLS KEYFORMAT.
+var widevineSystemID = [16]byte{0xed, 0xef, 0x8b, 0xa9, 0x79, 0xd6, 0x4a, 0xce, 0xa3, 0xc8, 0x27, 0xdc, 0xd5, 0x1d, 0x21, 0xed}
+
+const widevineKeyFormat = "urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed"
+
+// DRMKey is the key a transcode packages its DRM rungs with. Licenses for
+// it are issued by the operator's license server, never by the AES-128 key
+// endpoint.
+type DRMKey struct {
+	ContentID string
+	// KeyID is the 16-byte CENC KID players request licenses for.
+	KeyID []byte
+	Key   []byte
+}
+
+type drmKeyContextKey struct{}
+
+// WithDRMKey makes HLS transcodes run with the returned context package
+// their DRM rungs with key. Like WithHLSKey it travels with the context so
+// the shared transcoder entry points keep their signatures.
+func WithDRMKey(ctx context.Context, key *DRMKey) context.Context {
+	return context.WithValue(ctx, drmKeyContextKey{}, key)
+}
+
+func drmKeyFromContext(ctx context.Context) *DRMKey {
+	key, _ := ctx.Value(drmKeyContextKey{}).(*DRMKey)
+	return key
+}
+
+// HasDRMProfile reports whether any profile is DRM packaged.
+func HasDRMProfile(profiles []TranscodeProfile) bool {
+	for _, p := range profiles {
+		if p.DRM != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// fmp4SegmentPattern and fmp4InitName name the fMP4 output of DRM, HEVC
+// and AV1 rungs, and drmAudioPlaylist the audio playlist of packaged DRM
+// rungs. Names keep the variant prefix and version token so the rung
+// uploads and versions like an MPEG-TS one.
+func fmp4SegmentPattern(playlistPath, version, track string) string {
+	dir := filepath.Dir(playlistPath)
+	return filepath.Join(dir, drmTrackPrefix(playlistPath, track)+version+"_%03d.m4s")
+}
+
+func fmp4InitName(playlistPath, version, track string) string {
+	return drmTrackPrefix(playlistPath, track) + version + "_init.mp4"
+}
+
+func drmAudioPlaylist(resolution string) string {
+	return resolution + "_audio.m3u8"
+}
+
+func drmTrackPrefix(playlistPath, track string) string {
+	variant := strings.TrimSuffix(filepath.Base(playlistPath), filepath.Ext(playlistPath))
+	if track == "" {
+		return variant + "_"
+	}
+	return variant + "_" + track + "_"
+}
+
+// transcodeDRMVariant writes a DRM rung as encrypted fMP4 HLS. Without a
+// packager, FFmpeg encrypts CENC rungs itself with audio and video muxed;
+// with one, FFmpeg writes a clear intermediate MP4 that shaka-packager
+// encrypts, which puts audio in a playlist of its own.
+func (ft *FFmpegTranscoder) transcodeDRMVariant(ctx context.Context, inputPath, outputPath, segmentVersion string, key *DRMKey, profile TranscodeProfile, info *VideoInfo, totalDuration time.Duration, callback ProgressCallback) error {
+	if key == nil {
+		return fmt.Errorf("DRM profile %s requires a content key", profile.Resolution)
+	}
+	if len(key.Key) != 16 || len(key.KeyID) != 16 {
+		return fmt.Errorf("DRM key and key ID must be 16 bytes")
+	}
+	if !profile.DRM.Valid() {
+		return fmt.Errorf("unsupported DRM scheme %q", profile.DRM)
+	}
+
+	if ft.config.PackagerPath == "" {
+		if profile.DRM != DRMSchemeCENC {
+			return fmt.Errorf("DRM scheme %s requires shaka-packager", profile.DRM)
+		}
+		outputArgs := []string{
+			"-f", "hls",
+			"-hls_time", "6",
+			"-hls_list_size", "0",
+			"-hls_segment_type", "fmp4",
+			"-hls_fmp4_init_filename", fmp4InitName(outputPath, segmentVersion, ""),
+			"-hls_segment_filename", fmp4SegmentPattern(outputPath, segmentVersion, ""),
+			"-hls_segment_options", fmt.Sprintf("encryption_scheme=cenc-aes-ctr:encryption_key=%s:encryption_kid=%s",
+				hex.EncodeToString(key.Key), hex.EncodeToString(key.KeyID)),
+			"-y", outputPath,
+		}
+		if err := ft.runEncode(ctx, inputPath, profile, info, nil, outputArgs, totalDuration, callback); err != nil {
+			return err
+		}
+		return writeDRMKeyTags(outputPath, profile.DRM, key)
+	}
+
+	// The intermediate is clear, so it goes to a private temp directory
+	// rather than next to the uploaded output.
+	dir, err := os.MkdirTemp(ft.config.TempDir, "streamgate-drm-*")
+	if err != nil {
+		return fmt.Errorf("failed to create packaging directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	intermediate := filepath.Join(dir, profile.Name()+".mp4")
+	// Keyframes every segment let the packager cut 6s segments.
+	keyframeArgs := []string{"-force_key_frames", "expr:gte(t,n_forced*6)"}
+	outputArgs := []string{"-f", "mp4", "-y", intermediate}
+	if err := ft.runEncode(ctx, inputPath, profile, info, keyframeArgs, outputArgs, totalDuration, callback); err != nil {
+		return err
+	}
+
+	outputDir := filepath.Dir(outputPath)
+	audioPlaylist := filepath.Join(outputDir, drmAudioPlaylist(profile.Name()))
+	packagerMaster := filepath.Join(outputDir, profile.Name()+"_packager.m3u8")
+	systems := "Widevine"
+	if profile.DRM == DRMSchemeCBCS {
+		systems = "Widevine,FairPlay"
+	}
+	packagerArgs := []string{
+		fmt.Sprintf("in=%s,stream=video,init_segment=%s,segment_template=%s,playlist_name=%s",
+			intermediate,
+			filepath.Join(outputDir, fmp4InitName(outputPath, segmentVersion, "")),
+			shakaTemplate(fmp4SegmentPattern(outputPath, segmentVersion, "")),
+			filepath.Base(outputPath)),
+		fmt.Sprintf("in=%s,stream=audio,init_segment=%s,segment_template=%s,playlist_name=%s,hls_group_id=audio,hls_name=audio",
+			intermediate,
+			filepath.Join(outputDir, fmp4InitName(outputPath, segmentVersion, "audio")),
+			shakaTemplate(fmp4SegmentPattern(outputPath, segmentVersion, "audio")),
+			filepath.Base(audioPlaylist)),
+		"--segment_duration", "6",
+		"--protection_scheme", string(profile.DRM),
+		"--enable_raw_key_encryption",
+		"--keys", fmt.Sprintf("label=:key_id=%s:key=%s", hex.EncodeToString(key.KeyID), hex.EncodeToString(key.Key)),
+		"--protection_systems", systems,
+		"--hls_playlist_type", "VOD",
+		// The packager writes rung playlists next to its master playlist,
+		// which is dropped for ours.
+		"--hls_master_playlist_output", packagerMaster,
+	}
+	defer func() { _ = os.Remove(packagerMaster) }()
+	cmd := exec.CommandContext(ctx, ft.config.PackagerPath, packagerArgs...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("packager failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	for _, playlist := range []string{outputPath, audioPlaylist} {
+		if err := writeDRMKeyTags(playlist, profile.DRM, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shakaTemplate turns an FFmpeg %03d segment pattern into the packager's
+// $Number%03d$ template.
+func shakaTemplate(pattern string) string {
+	return strings.Replace(pattern, "%03d", "$Number%03d$", 1)
+}
+
+// writeDRMKeyTags replaces whatever key tags the packager wrote in a rung
+// playlist with the ones for key, so both packaging paths signal keys the
+// same way and players find the license by content ID.
+func writeDRMKeyTags(playlistPath string, scheme DRMScheme, key *DRMKey) error {
+	data, err := os.ReadFile(playlistPath)
+	if err != nil {
+		return fmt.Errorf("failed to read DRM playlist: %w", err)
+	}
+
+	var b strings.Builder
+	written := false
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#EXT-X-KEY:") {
+			continue
+		}
+		if !written && (strings.HasPrefix(line, "#EXT-X-MAP:") || strings.HasPrefix(line, "#EXTINF:")) {
+			b.WriteString(drmKeyTags(scheme, key))
+			written = true
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	if !written {
+		return fmt.Errorf("DRM playlist %s has no segments", filepath.Base(playlistPath))
+	}
+	return os.WriteFile(playlistPath, []byte(b.String()), 0o644)
+}
+
+// drmKeyTags renders the EXT-X-KEY tags of a DRM rung: Widevine for both
+// schemes, and FairPlay for CBCS. The FairPlay skd:// URI carries the
+// content ID, which players send back with their license request.
+func drmKeyTags(scheme DRMScheme, key *DRMKey) string {
+	method := "SAMPLE-AES-CTR"
+	if scheme == DRMSchemeCBCS {
+		method = "SAMPLE-AES"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "#EXT-X-KEY:METHOD=%s,URI=\"data:text/plain;base64,%s\",KEYID=0x%s,KEYFORMAT=\"%s\",KEYFORMATVERSIONS=\"1\"\n",
+		method, base64.StdEncoding.EncodeToString(widevinePSSH(key)), hex.EncodeToString(key.KeyID), widevineKeyFormat)
+	if scheme == DRMSchemeCBCS {
+		fmt.Fprintf(&b, "#EXT-X-KEY:METHOD=SAMPLE-AES,URI=\"skd://%s\",KEYFORMAT=\"com.apple.streamingkeydelivery\",KEYFORMATVERSIONS=\"1\"\n", key.ContentID)
+	}
+	return b.String()
+}
+
+// widevinePSSH builds a version 0 Widevine PSSH box whose data is the
+// WidevinePsshData protobuf with the key ID (field 2) and content ID
+// (field 4).
+func widevinePSSH(key *DRMKey) []byte {
+	var data []byte
+	data = append(data, 0x12)
+	data = binary.AppendUvarint(data, uint64(len(key.KeyID)))
+	data = append(data, key.KeyID...)
+	data = append(data, 0x22)
+	data = binary.AppendUvarint(data, uint64(len(key.ContentID)))
+	data = append(data, key.ContentID...)
+
+	box := make([]byte, 0, 32+len(data))
+	box = binary.BigEndian.AppendUint32(box, uint32(32+len(data)))
+	box = append(box, "pssh"...)
+	box = binary.BigEndian.AppendUint32(box, 0) // version 0, no flags
+	box = append(box, widevineSystemID[:]...)
+	box = binary.BigEndian.AppendUint32(box, uint32(len(data)))
+	return append(box, data...)
+}
@@ -0,0 +1,159 @@
+package transcoder
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func testDRMKey() *DRMKey {
+	return &DRMKey{ContentID: "premium-1", KeyID: bytes.Repeat([]byte{3}, 16), Key: bytes.Repeat([]byte{4}, 16)}
+}
+
+// drmPlaylist is what the packagers write for a rung, including a key tag
+// of their own that must be replaced.
+const drmPlaylist = `#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-TARGETDURATION:6
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="skd://packager"
+#EXT-X-MAP:URI="init.mp4"
+#EXTINF:6.000,
+seg_000.m4s
+#EXT-X-ENDLIST
+`
+
+func TestTranscodeToHLS_DRMWithFFmpeg(t *testing.T) {
+	cfg := fakeFFmpeg(t, "no-such-filter")
+	ffmpeg := `#!/bin/sh
+for arg in "$@"; do last="$arg"; done
+echo "$@" > "$last.args"
+cat > "$last" <<'EOF'
+` + drmPlaylist + `EOF
+`
+	require.NoError(t, os.WriteFile(cfg.FFmpegPath, []byte(ffmpeg), 0o755))
+	ft := NewFFmpegTranscoder(cfg, zap.NewNop())
+	outputDir := t.TempDir()
+
+	key := testDRMKey()
+	profile := TranscodeProfile{Resolution: "1280x720", Bitrate: "2500k", Format: "hls", DRM: DRMSchemeCENC}
+	ctx := WithDRMKey(context.Background(), key)
+	require.NoError(t, ft.TranscodeToHLS(ctx, filepath.Join(cfg.TempDir, "input.mp4"), outputDir, []TranscodeProfile{profile}, nil, nil))
+
+	args, err := os.ReadFile(filepath.Join(outputDir, "1280x720.m3u8.args"))
+	require.NoError(t, err)
+	assert.Contains(t, string(args), "-hls_segment_type fmp4")
+	assert.Contains(t, string(args), "encryption_scheme=cenc-aes-ctr:encryption_key="+hex.EncodeToString(key.Key)+":encryption_kid="+hex.EncodeToString(key.KeyID))
+
+	playlist, err := os.ReadFile(filepath.Join(outputDir, "1280x720.m3u8"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(playlist), "skd://packager")
+	assert.Contains(t, string(playlist), `#EXT-X-KEY:METHOD=SAMPLE-AES-CTR,URI="data:text/plain;base64,`)
+	assert.Contains(t, string(playlist), "KEYID=0x"+hex.EncodeToString(key.KeyID)+",KEYFORMAT=\""+widevineKeyFormat+"\"")
+	assert.NotContains(t, string(playlist), "streamingkeydelivery", "CENC is not playable by FairPlay")
+	assert.Less(t, strings.Index(string(playlist), "#EXT-X-KEY"), strings.Index(string(playlist), "#EXT-X-MAP"))
+}
+
+func TestTranscodeToHLS_DRMWithPackager(t *testing.T) {
+	cfg := fakeFFmpeg(t, "no-such-filter")
+	ffmpeg := `#!/bin/sh
+for arg in "$@"; do last="$arg"; done
+touch "$last"
+`
+	require.NoError(t, os.WriteFile(cfg.FFmpegPath, []byte(ffmpeg), 0o755))
+	// Write each stream's playlist next to the master playlist, as
+	// shaka-packager does.
+	packager := `#!/bin/sh
+echo "$@" > "` + filepath.Join(cfg.TempDir, "packager.args") + `"
+for arg in "$@"; do
+  if [ "$prev" = "--hls_master_playlist_output" ]; then master="$arg"; fi
+  prev="$arg"
+done
+dir=$(dirname "$master")
+touch "$master"
+for arg in "$@"; do
+  case "$arg" in
+    in=*) name=$(echo "$arg" | sed 's/.*playlist_name=\([^,]*\).*/\1/')
+      cat > "$dir/$name" <<'EOF'
+` + drmPlaylist + `EOF
+    ;;
+  esac
+done
+`
+	cfg.PackagerPath = filepath.Join(cfg.TempDir, "packager")
+	require.NoError(t, os.WriteFile(cfg.PackagerPath, []byte(packager), 0o755))
+	ft := NewFFmpegTranscoder(cfg, zap.NewNop())
+	outputDir := t.TempDir()
+
+	key := testDRMKey()
+	profiles := []TranscodeProfile{
+		{Resolution: "1920x1080", Bitrate: "5000k", Format: "hls", DRM: DRMSchemeCBCS},
+		{Resolution: "640x360", Bitrate: "500k", Format: "hls"},
+	}
+	ctx := WithDRMKey(context.Background(), key)
+	require.NoError(t, ft.TranscodeToHLS(ctx, filepath.Join(cfg.TempDir, "input.mp4"), outputDir, profiles, nil, nil))
+
+	args, err := os.ReadFile(filepath.Join(cfg.TempDir, "packager.args"))
+	require.NoError(t, err)
+	assert.Contains(t, string(args), "--protection_scheme cbcs")
+	assert.Contains(t, string(args), "--protection_systems Widevine,FairPlay")
+	assert.Contains(t, string(args), "label=:key_id="+hex.EncodeToString(key.KeyID)+":key="+hex.EncodeToString(key.Key))
+	assert.NoFileExists(t, filepath.Join(outputDir, "1920x1080_packager.m3u8"))
+	matches, _ := filepath.Glob(filepath.Join(cfg.TempDir, "streamgate-drm-*"))
+	assert.Empty(t, matches, "the clear intermediate is removed")
+
+	for _, name := range []string{"1920x1080.m3u8", "1920x1080_audio.m3u8"} {
+		playlist, err := os.ReadFile(filepath.Join(outputDir, name))
+		require.NoError(t, err)
+		assert.Contains(t, string(playlist), `#EXT-X-KEY:METHOD=SAMPLE-AES,URI="skd://premium-1",KEYFORMAT="com.apple.streamingkeydelivery"`, name)
+		assert.Contains(t, string(playlist), `#EXT-X-KEY:METHOD=SAMPLE-AES,URI="data:text/plain;base64,`, name)
+	}
+
+	master, err := os.ReadFile(filepath.Join(outputDir, "master.m3u8"))
+	require.NoError(t, err)
+	assert.Contains(t, string(master), `#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="audio-1920x1080",NAME="audio",DEFAULT=YES,AUTOSELECT=YES,URI="1920x1080_audio.m3u8"`)
+	assert.Contains(t, string(master), `RESOLUTION=1920x1080,CODECS="avc1.640028,mp4a.40.2",AUDIO="audio-1920x1080"`)
+	assert.Contains(t, string(master), "#EXT-X-STREAM-INF:BANDWIDTH=500000,RESOLUTION=640x360,CODECS=\"avc1.64001e,mp4a.40.2\"\n")
+}
+
+func TestTranscodeToHLS_DRMFailsClosed(t *testing.T) {
+	cfg := fakeFFmpeg(t, "no-such-filter")
+	ft := NewFFmpegTranscoder(cfg, zap.NewNop())
+	input := filepath.Join(cfg.TempDir, "input.mp4")
+	cenc := TranscodeProfile{Resolution: "1280x720", Bitrate: "2500k", Format: "hls", DRM: DRMSchemeCENC}
+	cbcs := TranscodeProfile{Resolution: "1280x720", Bitrate: "2500k", Format: "hls", DRM: DRMSchemeCBCS}
+
+	err := ft.TranscodeToHLS(context.Background(), input, t.TempDir(), []TranscodeProfile{cenc}, nil, nil)
+	assert.ErrorContains(t, err, "requires a content key")
+
+	ctx := WithDRMKey(context.Background(), testDRMKey())
+	err = ft.TranscodeToHLS(ctx, input, t.TempDir(), []TranscodeProfile{cbcs}, nil, nil)
+	assert.ErrorContains(t, err, "requires shaka-packager")
+}
+
+func TestWidevinePSSH(t *testing.T) {
+	key := testDRMKey()
+	box := widevinePSSH(key)
+	require.Len(t, box, 32+2+16+2+len(key.ContentID))
+	assert.Equal(t, "pssh", string(box[4:8]))
+	assert.Equal(t, widevineSystemID[:], box[12:28])
+	assert.Equal(t, append([]byte{0x12, 16}, key.KeyID...), box[32:50])
+
+	tags := drmKeyTags(DRMSchemeCENC, key)
+	assert.Contains(t, tags, base64.StdEncoding.EncodeToString(box))
+}
+
+func TestValidateLadder_DRM(t *testing.T) {
+	ladder := []TranscodeProfile{{Resolution: "1920x1080", Bitrate: "5000k", Format: "hls", DRM: "playready"}}
+	assert.ErrorIs(t, ValidateLadder(ladder), ErrInvalidLadder)
+	ladder[0].DRM = DRMSchemeCBCS
+	assert.NoError(t, ValidateLadder(ladder))
+}
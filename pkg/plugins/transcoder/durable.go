@@ -0,0 +1,237 @@
+package transcoder
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// taskStoreTimeout bounds each call to the task store.
+const taskStoreTimeout = 5 * time.Second
+
+// persist saves a task entering the queue and leases it to this queue.
+func (tq *TaskQueue) persist(task *TranscodeTask) error {
+	if tq.store == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), taskStoreTimeout)
+	defer cancel()
+
+	if err := tq.store.Save(ctx, task); err != nil {
+		return err
+	}
+	now := time.Now()
+	leased, err := tq.store.Lease(ctx, task.ID, tq.owner, now, now.Add(tq.visibility))
+	if err != nil {
+		return err
+	}
+	if !leased {
+		return fmt.Errorf("task %s is leased to another transcoder", task.ID)
+	}
+
+	tq.mu.Lock()
+	tq.held[task.ID] = struct{}{}
+	tq.mu.Unlock()
+	return nil
+}
+
+// record saves a task's new state. A failed save is logged rather than
+// returned: this queue's copy stays authoritative while it holds the
+// lease, and the task is redelivered from its last saved state otherwise.
+func (tq *TaskQueue) record(task TranscodeTask) {
+	if tq.store == nil {
+		return
+	}
+	if taskFinished(task.Status) {
+		tq.mu.Lock()
+		delete(tq.held, task.ID)
+		tq.mu.Unlock()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), taskStoreTimeout)
+	defer cancel()
+	if err := tq.store.Save(ctx, &task); err != nil {
+		tq.logger.Warn("Failed to save task state",
+			zap.String("task_id", task.ID),
+			zap.String("status", string(task.Status)),
+			zap.Error(err))
+	}
+}
+
+// Recover claims the stored tasks left unfinished by a transcoder that
+// stopped, including this one before a restart, and queues them again. It
+// returns how many tasks were recovered.
+func (tq *TaskQueue) Recover(ctx context.Context) (int, error) {
+	if tq.store == nil {
+		return 0, nil
+	}
+	if tq.shared {
+		return tq.claimTasks(ctx, time.Now())
+	}
+	return tq.reclaim(ctx, time.Now(), -1)
+}
+
+// MaintainLeases renews the leases of the tasks this queue holds and
+// reclaims tasks whose transcoder stopped renewing theirs, until ctx is
+// done. A shared queue also claims new tasks whenever it has room. It
+// returns at once without a store.
+func (tq *TaskQueue) MaintainLeases(ctx context.Context) {
+	if tq.store == nil {
+		return
+	}
+	ticker := time.NewTicker(tq.visibility / 3)
+	defer ticker.Stop()
+	var poll <-chan time.Time
+	if tq.shared {
+		pollTicker := time.NewTicker(claimPollInterval)
+		defer pollTicker.Stop()
+		poll = pollTicker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll:
+			tq.logClaimed(tq.claimTasks(ctx, time.Now()))
+		case <-tq.claimNow:
+			tq.logClaimed(tq.claimTasks(ctx, time.Now()))
+		case <-ticker.C:
+			now := time.Now()
+			tq.renewLeases(ctx, now)
+			if tq.shared {
+				tq.logClaimed(tq.claimTasks(ctx, now))
+			} else {
+				tq.logClaimed(tq.reclaim(ctx, now, -1))
+			}
+		}
+	}
+}
+
+func (tq *TaskQueue) logClaimed(claimed int, err error) {
+	if err != nil {
+		tq.logger.Warn("Failed to claim tasks", zap.Error(err))
+	} else if claimed > 0 {
+		tq.logger.Info("Claimed transcode tasks", zap.Int("tasks", claimed))
+	}
+}
+
+// renewLeases extends the lease of every task this queue holds. A task
+// whose lease another transcoder took over is dropped if still queued;
+// one already running finishes, as delivery is at least once.
+func (tq *TaskQueue) renewLeases(ctx context.Context, now time.Time) {
+	tq.mu.RLock()
+	ids := make([]string, 0, len(tq.held))
+	for id := range tq.held {
+		ids = append(ids, id)
+	}
+	tq.mu.RUnlock()
+
+	for _, id := range ids {
+		callCtx, cancel := context.WithTimeout(ctx, taskStoreTimeout)
+		leased, err := tq.store.Lease(callCtx, id, tq.owner, now, now.Add(tq.visibility))
+		cancel()
+		if err != nil {
+			tq.logger.Warn("Failed to renew task lease", zap.String("task_id", id), zap.Error(err))
+			continue
+		}
+		if leased {
+			continue
+		}
+
+		tq.mu.Lock()
+		delete(tq.held, id)
+		if _, queued := tq.queued[id]; queued {
+			tq.removeQueued(id)
+			delete(tq.tasks, id)
+		}
+		tq.mu.Unlock()
+		tq.logger.Warn("Lost task lease to another transcoder", zap.String("task_id", id))
+	}
+}
+
+// reclaim leases up to limit, or all when negative, of the claimable
+// stored tasks this queue does not hold, highest priority first, and
+// queues them. A task that was running counts the interrupted run as an
+// attempt, so one that keeps killing its transcoder eventually fails.
+func (tq *TaskQueue) reclaim(ctx context.Context, now time.Time, limit int) (int, error) {
+	callCtx, cancel := context.WithTimeout(ctx, taskStoreTimeout)
+	tasks, err := tq.store.Claimable(callCtx, tq.owner, now)
+	cancel()
+	if err != nil {
+		return 0, err
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].Priority != tasks[j].Priority {
+			return tasks[i].Priority > tasks[j].Priority
+		}
+		return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+	})
+
+	recovered := 0
+	for _, task := range tasks {
+		if limit >= 0 && recovered >= limit {
+			break
+		}
+		tq.mu.RLock()
+		_, held := tq.held[task.ID]
+		tq.mu.RUnlock()
+		if held {
+			continue
+		}
+
+		callCtx, cancel := context.WithTimeout(ctx, taskStoreTimeout)
+		leased, err := tq.store.Lease(callCtx, task.ID, tq.owner, now, now.Add(tq.visibility))
+		cancel()
+		if err != nil {
+			return recovered, err
+		}
+		if !leased {
+			continue
+		}
+
+		if task.Status == TaskStatusProcessing {
+			task.RetryCount++
+		}
+		if task.MaxRetries > 0 && task.RetryCount >= task.MaxRetries {
+			completedAt := now
+			task.Status = TaskStatusFailed
+			task.Error = "transcoder stopped while running the task"
+			task.FailureReason = FailureTransientInfra
+			task.Retryable = true
+			task.CompletedAt = &completedAt
+			tq.mu.Lock()
+			tq.tasks[task.ID] = task
+			tq.mu.Unlock()
+			tq.record(*task)
+			continue
+		}
+
+		task.Status = TaskStatusPending
+		task.WorkerID = ""
+		task.Instance = ""
+		task.StartedAt = nil
+		task.Progress = 0
+		task.Speed = ""
+		callCtx, cancel = context.WithTimeout(ctx, taskStoreTimeout)
+		err = tq.store.Save(callCtx, task)
+		cancel()
+		if err != nil {
+			return recovered, err
+		}
+
+		tq.mu.Lock()
+		mapCopy := *task
+		queueCopy := *task
+		tq.tasks[task.ID] = &mapCopy
+		tq.push(&queueCopy, task.CreatedAt)
+		tq.held[task.ID] = struct{}{}
+		tq.mu.Unlock()
+		tq.signal()
+		recovered++
+	}
+	return recovered, nil
+}
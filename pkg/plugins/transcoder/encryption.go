@@ -0,0 +1,61 @@
+package transcoder
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// HLSKey is the AES-128 key a transcode encrypts its HLS segments with.
+type HLSKey struct {
+	// URI is written to the EXT-X-KEY tags of the generated playlists.
+	URI string
+	Key []byte
+	// IV is used for every segment; players read it from EXT-X-KEY.
+	IV []byte
+}
+
+type hlsKeyContextKey struct{}
+
+// WithHLSKey makes HLS transcodes run with the returned context encrypt
+// their segments with key. The transcoder entry points are shared by
+// callers that do not know about content IDs, so the key travels with the
+// context rather than through every signature.
+func WithHLSKey(ctx context.Context, key *HLSKey) context.Context {
+	return context.WithValue(ctx, hlsKeyContextKey{}, key)
+}
+
+func hlsKeyFromContext(ctx context.Context) *HLSKey {
+	key, _ := ctx.Value(hlsKeyContextKey{}).(*HLSKey)
+	return key
+}
+
+// writeKeyInfo writes the key and an ffmpeg -hls_key_info_file for it to
+// a private temp directory, which must stay outside the output directory
+// so the key is never uploaded next to the segments. The returned cleanup
+// removes both files.
+func (ft *FFmpegTranscoder) writeKeyInfo(key *HLSKey) (string, func(), error) {
+	if len(key.Key) != 16 || len(key.IV) != 16 {
+		return "", nil, fmt.Errorf("HLS key and IV must be 16 bytes")
+	}
+	dir, err := os.MkdirTemp(ft.config.TempDir, "streamgate-hlskey-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create key directory: %w", err)
+	}
+	cleanup := func() { _ = os.RemoveAll(dir) }
+
+	keyPath := filepath.Join(dir, "content.key")
+	if err := os.WriteFile(keyPath, key.Key, 0o600); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to write HLS key: %w", err)
+	}
+	infoPath := filepath.Join(dir, "content.keyinfo")
+	info := fmt.Sprintf("%s\n%s\n%s\n", key.URI, keyPath, hex.EncodeToString(key.IV))
+	if err := os.WriteFile(infoPath, []byte(info), 0o600); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to write HLS key info: %w", err)
+	}
+	return infoPath, cleanup, nil
+}
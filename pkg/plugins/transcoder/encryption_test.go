@@ -0,0 +1,58 @@
+package transcoder
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTranscodeToHLS_EncryptsWithContextKey(t *testing.T) {
+	cfg := fakeFFmpeg(t, "no-such-filter")
+	// Record the key info ffmpeg is given and the key it points at.
+	ffmpeg := `#!/bin/sh
+for arg in "$@"; do
+  last="$arg"
+  if [ "$prev" = "-hls_key_info_file" ]; then info="$arg"; fi
+  prev="$arg"
+done
+printf '#EXTM3U\n' > "$last"
+cp "$info" "$last.keyinfo"
+cp "$(sed -n 2p "$info")" "$last.key"
+`
+	require.NoError(t, os.WriteFile(cfg.FFmpegPath, []byte(ffmpeg), 0o755))
+	ft := NewFFmpegTranscoder(cfg, zap.NewNop())
+	outputDir := t.TempDir()
+
+	key := &HLSKey{URI: "/api/v1/stream/key?content_id=c1", Key: bytes.Repeat([]byte{1}, 16), IV: bytes.Repeat([]byte{2}, 16)}
+	ctx := WithHLSKey(context.Background(), key)
+	require.NoError(t, ft.TranscodeToHLS(ctx, filepath.Join(cfg.TempDir, "input.mp4"), outputDir, []TranscodeProfile{BuiltinLadder()[0]}, nil, nil))
+
+	info, err := os.ReadFile(filepath.Join(outputDir, "1920x1080.m3u8.keyinfo"))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(info)), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, key.URI, lines[0])
+	assert.Equal(t, hex.EncodeToString(key.IV), lines[2])
+	assert.False(t, strings.HasPrefix(lines[1], outputDir), "the key must not be written where segments are uploaded from")
+	written, err := os.ReadFile(filepath.Join(outputDir, "1920x1080.m3u8.key"))
+	require.NoError(t, err)
+	assert.Equal(t, key.Key, written)
+	assert.NoFileExists(t, lines[1], "the key file is removed after the transcode")
+}
+
+func TestTranscodeToHLS_RejectsMalformedKey(t *testing.T) {
+	cfg := fakeFFmpeg(t, "no-such-filter")
+	ft := NewFFmpegTranscoder(cfg, zap.NewNop())
+
+	ctx := WithHLSKey(context.Background(), &HLSKey{Key: []byte("short"), IV: make([]byte, 16)})
+	err := ft.TranscodeToHLS(ctx, filepath.Join(cfg.TempDir, "input.mp4"), t.TempDir(), BuiltinLadder(), nil, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,101 @@
+package transcoder
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// FailureReason classifies why a transcode task failed so clients can tell
+// a retryable failure from a permanent one.
+type FailureReason string
+
+const (
+	// FailureSourceInvalid means the source cannot be transcoded as-is:
+	// missing, corrupt, unsupported, or outside configured limits.
+	FailureSourceInvalid FailureReason = "source_invalid"
+	// FailureTransientInfra covers process, storage and other environment
+	// errors that may succeed on another attempt.
+	FailureTransientInfra FailureReason = "transient_infra"
+	// FailureTimeout means the task ran past its deadline.
+	FailureTimeout FailureReason = "timeout"
+	// FailureCancelled means the task or the worker pool was cancelled.
+	FailureCancelled FailureReason = "cancelled"
+)
+
+// Retryable reports whether a failure of this kind is worth retrying
+// automatically.
+func (r FailureReason) Retryable() bool {
+	return r == FailureTransientInfra
+}
+
+// sourceInvalidMarkers are substrings of errors raised for sources that
+// will fail the same way on every attempt. Matching is case-insensitive.
+var sourceInvalidMarkers = []string{
+	"input validation failed",
+	"not a valid media file",
+	"invalid data found when processing input",
+	"moov atom not found",
+	"could not find codec parameters",
+	"unsupported codec",
+	"decoder not found",
+	"zero duration",
+	"failed to extract video duration",
+}
+
+var timeoutMarkers = []string{
+	"deadline exceeded",
+	"timed out",
+	"timeout",
+}
+
+var cancelledMarkers = []string{
+	"context canceled",
+	"cancelled",
+}
+
+// ClassifyFailure maps a transcode error to a FailureReason. Context errors
+// are matched by identity; otherwise the message is checked against known
+// markers. Anything unrecognised is treated as transient.
+func ClassifyFailure(err error) FailureReason {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.Canceled):
+		return FailureCancelled
+	case errors.Is(err, context.DeadlineExceeded):
+		return FailureTimeout
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, group := range []struct {
+		reason  FailureReason
+		markers []string
+	}{
+		{FailureSourceInvalid, sourceInvalidMarkers},
+		{FailureTimeout, timeoutMarkers},
+		{FailureCancelled, cancelledMarkers},
+	} {
+		for _, m := range group.markers {
+			if strings.Contains(msg, m) {
+				return group.reason
+			}
+		}
+	}
+	return FailureTransientInfra
+}
+
+// classifyTaskFailure is ClassifyFailure, except that a killed FFmpeg
+// process is attributed to ctx when ctx has ended: exec reports such kills
+// as a bare signal rather than the context error.
+func classifyTaskFailure(ctx context.Context, err error) FailureReason {
+	if ctx != nil {
+		switch ctx.Err() {
+		case context.Canceled:
+			return FailureCancelled
+		case context.DeadlineExceeded:
+			return FailureTimeout
+		}
+	}
+	return ClassifyFailure(err)
+}
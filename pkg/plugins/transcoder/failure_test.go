@@ -0,0 +1,152 @@
+package transcoder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	cases := []struct {
+		err  error
+		want FailureReason
+	}{
+		{errors.New("input validation failed: input file is not a valid media file: ffprobe failed: exit status 1, output: input.mp4: Invalid data found when processing input"), FailureSourceInvalid},
+		{errors.New("input validation failed: input file has zero duration (possibly corrupted)"), FailureSourceInvalid},
+		{errors.New("input validation failed: input duration 90000.0s exceeds maximum 36000.0s"), FailureSourceInvalid},
+		{errors.New("[mov,mp4,m4a] moov atom not found"), FailureSourceInvalid},
+		{errors.New("Decoder not found for stream #0:0"), FailureSourceInvalid},
+		{errors.New("failed to transcode to 1280x720: FFmpeg process failed: exit status 1"), FailureTransientInfra},
+		{errors.New("failed to create output directory: mkdir /tmp/out: no space left on device"), FailureTransientInfra},
+		{errors.New("FFmpeg transcoder not initialized"), FailureTransientInfra},
+		{errors.New("read tcp 10.0.0.1:443: i/o timeout"), FailureTimeout},
+		{fmt.Errorf("probe: %w", context.DeadlineExceeded), FailureTimeout},
+		{fmt.Errorf("FFmpeg process failed: %w", context.Canceled), FailureCancelled},
+		{errors.New("task cancelled by user"), FailureCancelled},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, ClassifyFailure(tc.err), tc.err.Error())
+	}
+	assert.Equal(t, FailureReason(""), ClassifyFailure(nil))
+
+	assert.True(t, FailureTransientInfra.Retryable())
+	for _, r := range []FailureReason{FailureSourceInvalid, FailureTimeout, FailureCancelled} {
+		assert.False(t, r.Retryable(), r)
+	}
+}
+
+func TestClassifyTaskFailure_EndedContext(t *testing.T) {
+	killed := errors.New("FFmpeg process failed: signal: killed")
+	assert.Equal(t, FailureTransientInfra, classifyTaskFailure(context.Background(), killed))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, FailureCancelled, classifyTaskFailure(ctx, killed))
+
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	assert.Equal(t, FailureTimeout, classifyTaskFailure(ctx, killed))
+}
+
+// newFailingPool returns a pool whose transcoder runs the fakeFFmpeg
+// stand-ins, with ffprobe replaced by probeScript when it is non-empty.
+func newFailingPool(t *testing.T, probeScript string) (*WorkerPool, *FFmpegConfig) {
+	t.Helper()
+	cfg := fakeFFmpeg(t, "scale=")
+	if probeScript != "" {
+		require.NoError(t, os.WriteFile(cfg.FFprobePath, []byte(probeScript), 0o755))
+	}
+	bus, err := event.NewMemoryEventBus()
+	require.NoError(t, err)
+	return &WorkerPool{
+		taskQueue: newTestTaskQueue(4),
+		eventBus:  bus,
+		logger:    zap.NewNop(),
+		ffmpeg:    NewFFmpegTranscoder(cfg, zap.NewNop()),
+		ctx:       context.Background(),
+		metrics:   &WorkerMetrics{},
+	}, cfg
+}
+
+func TestWorkerPool_PermanentFailureNotRetried(t *testing.T) {
+	pool, cfg := newFailingPool(t, "#!/bin/sh\necho 'input.mp4: Invalid data found when processing input' >&2\nexit 1\n")
+	task := &TranscodeTask{
+		ID:         "task-corrupt",
+		FilePath:   filepath.Join(cfg.TempDir, "input.mp4"),
+		Profiles:   BuiltinLadder(),
+		MaxRetries: 3,
+	}
+	require.NoError(t, pool.taskQueue.Enqueue(task))
+	queued, err := pool.taskQueue.Dequeue(context.Background())
+	require.NoError(t, err)
+
+	pool.processTask(&Worker{ID: "worker-1"}, queued)
+
+	got, err := pool.taskQueue.GetTask(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusFailed, got.Status)
+	assert.Equal(t, FailureSourceInvalid, got.FailureReason)
+	assert.False(t, got.Retryable)
+	assert.Equal(t, 1, got.RetryCount)
+	assert.Zero(t, pool.taskQueue.Len(), "permanent failures must not be re-enqueued")
+}
+
+func TestWorkerPool_TransientFailureRetriedUntilLimit(t *testing.T) {
+	pool, cfg := newFailingPool(t, "")
+	task := &TranscodeTask{
+		ID:         "task-flaky",
+		FilePath:   filepath.Join(cfg.TempDir, "input.mp4"),
+		Profiles:   BuiltinLadder(),
+		MaxRetries: 2,
+	}
+	require.NoError(t, pool.taskQueue.Enqueue(task))
+
+	attempts := 0
+	for pool.taskQueue.Len() > 0 {
+		require.Less(t, attempts, 5, "retries must stop at MaxRetries")
+		queued, err := pool.taskQueue.Dequeue(context.Background())
+		require.NoError(t, err)
+		pool.processTask(&Worker{ID: "worker-1"}, queued)
+		attempts++
+	}
+
+	got, err := pool.taskQueue.GetTask(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, TaskStatusFailed, got.Status)
+	assert.Equal(t, FailureTransientInfra, got.FailureReason)
+	assert.True(t, got.Retryable)
+	assert.Equal(t, 2, got.RetryCount)
+}
+
+func TestTranscoderHandler_GetTaskStatusReportsFailureReason(t *testing.T) {
+	handler := newTestTranscoderHandler(t)
+	require.NoError(t, handler.plugin.taskQueue.UpdateTask(&TranscodeTask{
+		ID:            "task-1",
+		Status:        TaskStatusFailed,
+		Error:         "input validation failed: input file has zero duration (possibly corrupted)",
+		FailureReason: FailureSourceInvalid,
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/transcode/status/task-1", http.NoBody)
+	rec := httptest.NewRecorder()
+	handler.GetTaskStatusHandler(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "source_invalid", body["FailureReason"])
+	assert.Equal(t, false, body["Retryable"])
+}
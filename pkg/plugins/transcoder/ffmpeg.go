@@ -1,15 +1,15 @@
 package transcoder
 
 import (
-	"bytes"
+	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -29,12 +29,37 @@ type FFmpegConfig struct {
 	AudioCodec     string
 	MaxFileSize    int64   // Maximum input file size in bytes (0 = no limit)
 	MaxDuration    float64 // Maximum input duration in seconds (0 = no limit)
+	// AllowPartialVariants makes TranscodeToHLS publish the variants that
+	// succeeded instead of failing the whole ladder when some rungs fail.
+	AllowPartialVariants bool
+	// LowLatencyProfiles lists the resolutions (e.g. "1280x720") written as
+	// LL-HLS parts of PartDuration instead of full-length segments.
+	LowLatencyProfiles []string
+	// PartDuration is the LL-HLS part length; DefaultPartDuration when zero.
+	PartDuration time.Duration
+	// PackagerPath is the shaka-packager binary DRM rungs are packaged
+	// with. When empty, FFmpeg packages CENC rungs and CBCS rungs fail.
+	PackagerPath string
+	// HardwareAccel is the hardware encoder family ProbeHardware tries when
+	// EnableHardware is set; HardwareAuto or empty probes them all.
+	HardwareAccel HardwareAccel
+	// VAAPIDevice is the render node for VAAPI; DefaultVAAPIDevice when
+	// empty.
+	VAAPIDevice string
+	// PerTitle, when set, bounds the ladders PerTitleLadder derives for
+	// tasks submitted without profiles.
+	PerTitle *PerTitleConfig
+	// Loudness, when set, is the EBU R128 target HLS transcodes normalise
+	// every audio track to; nil leaves levels untouched.
+	Loudness *LoudnessConfig
 }
 
 // FFmpegTranscoder handles FFmpeg transcoding operations
 type FFmpegTranscoder struct {
 	config *FFmpegConfig
 	logger *zap.Logger
+	// hwAccel is the hardware encoder family found by ProbeHardware.
+	hwAccel HardwareAccel
 }
 
 // VideoInfo contains video file information
@@ -50,6 +75,31 @@ type VideoInfo struct {
 	TotalFrames  int64
 	FileSize     int64
 	Format       string
+	// Rotation is the clockwise rotation in degrees (0, 90, 180 or 270)
+	// a player applies to the coded frames, taken from the display matrix
+	// side data or the legacy rotate tag.
+	Rotation int
+	// DisplayWidth and DisplayHeight are the dimensions as shown to the
+	// viewer, after applying the sample aspect ratio and Rotation.
+	DisplayWidth  int
+	DisplayHeight int
+	// AudioTracks lists every audio stream of the source, in stream order.
+	AudioTracks []AudioTrack
+}
+
+// IsPortrait reports whether the video is taller than it is wide when
+// displayed.
+func (v *VideoInfo) IsPortrait() bool {
+	return v.DisplayHeight > v.DisplayWidth
+}
+
+// ShortSide returns the smaller display dimension, which is what ladder
+// rungs such as "720p" refer to regardless of orientation.
+func (v *VideoInfo) ShortSide() int {
+	if v.DisplayWidth > 0 && v.DisplayWidth < v.DisplayHeight {
+		return v.DisplayWidth
+	}
+	return v.DisplayHeight
 }
 
 // TranscodeProgress represents transcoding progress
@@ -91,13 +141,23 @@ type ffprobeFormat struct {
 }
 
 type ffprobeStream struct {
-	CodecName  string `json:"codec_name"`
-	CodecType  string `json:"codec_type"`
-	Width      int    `json:"width"`
-	Height     int    `json:"height"`
-	RFrameRate string `json:"r_frame_rate"`
-	NBFrames   string `json:"nb_frames"`
-	BitRate    string `json:"bit_rate"`
+	CodecName         string            `json:"codec_name"`
+	CodecType         string            `json:"codec_type"`
+	Width             int               `json:"width"`
+	Height            int               `json:"height"`
+	SampleAspectRatio string            `json:"sample_aspect_ratio"`
+	RFrameRate        string            `json:"r_frame_rate"`
+	NBFrames          string            `json:"nb_frames"`
+	BitRate           string            `json:"bit_rate"`
+	Channels          int               `json:"channels"`
+	Disposition       map[string]int    `json:"disposition"`
+	Tags              map[string]string `json:"tags"`
+	SideDataList      []ffprobeSideData `json:"side_data_list"`
+}
+
+type ffprobeSideData struct {
+	SideDataType string  `json:"side_data_type"`
+	Rotation     float64 `json:"rotation"`
 }
 
 type ffprobeOutput struct {
@@ -121,6 +181,11 @@ func (ft *FFmpegTranscoder) GetVideoInfo(ctx context.Context, inputPath string)
 		return nil, fmt.Errorf("ffprobe failed: %w, output: %s", err, string(output))
 	}
 
+	return parseProbeOutput(output)
+}
+
+// parseProbeOutput builds a VideoInfo from ffprobe's JSON output.
+func parseProbeOutput(output []byte) (*VideoInfo, error) {
 	var probe ffprobeOutput
 	if err := json.Unmarshal(output, &probe); err != nil {
 		return nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
@@ -146,6 +211,8 @@ func (ft *FFmpegTranscoder) GetVideoInfo(ctx context.Context, inputPath string)
 				info.VideoCodec = stream.CodecName
 				info.Width = stream.Width
 				info.Height = stream.Height
+				info.Rotation = streamRotation(stream)
+				info.DisplayWidth, info.DisplayHeight = displayDimensions(stream, info.Rotation)
 				if stream.BitRate != "" {
 					if br, err := strconv.Atoi(stream.BitRate); err == nil {
 						info.VideoBitrate = br
@@ -168,6 +235,7 @@ func (ft *FFmpegTranscoder) GetVideoInfo(ctx context.Context, inputPath string)
 				}
 			}
 		case "audio":
+			info.AudioTracks = append(info.AudioTracks, probeAudioTrack(stream, len(info.AudioTracks)))
 			if info.AudioCodec == "" {
 				info.AudioCodec = stream.CodecName
 				if stream.BitRate != "" {
@@ -186,6 +254,69 @@ func (ft *FFmpegTranscoder) GetVideoInfo(ctx context.Context, inputPath string)
 	return info, nil
 }
 
+// streamRotation returns the clockwise display rotation of a video stream,
+// normalised to 0, 90, 180 or 270. The display matrix side data reports
+// counter-clockwise degrees; the legacy rotate tag written by older muxers
+// reports clockwise degrees.
+func streamRotation(stream ffprobeStream) int {
+	degrees := 0
+	found := false
+	for _, sd := range stream.SideDataList {
+		if sd.SideDataType == "Display Matrix" {
+			degrees = -int(math.Round(sd.Rotation))
+			found = true
+			break
+		}
+	}
+	if !found {
+		if tag, ok := stream.Tags["rotate"]; ok {
+			if r, err := strconv.Atoi(strings.TrimSpace(tag)); err == nil {
+				degrees = r
+			}
+		}
+	}
+	// Snap to the nearest quarter turn; other angles are not produced by
+	// cameras and ffmpeg cannot transpose them losslessly anyway.
+	degrees = ((degrees % 360) + 360) % 360
+	return ((degrees + 45) / 90 % 4) * 90
+}
+
+// displayDimensions applies the sample aspect ratio and rotation to the
+// coded frame size.
+func displayDimensions(stream ffprobeStream, rotation int) (int, int) {
+	w, h := stream.Width, stream.Height
+	if num, den, ok := parseRatio(stream.SampleAspectRatio, ":"); ok && num != den {
+		w = evenRound(float64(w) * float64(num) / float64(den))
+	}
+	if rotation == 90 || rotation == 270 {
+		w, h = h, w
+	}
+	return w, h
+}
+
+// parseRatio parses "num<sep>den" with both parts positive.
+func parseRatio(s, sep string) (int, int, bool) {
+	parts := strings.Split(s, sep)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	num, err := strconv.Atoi(parts[0])
+	if err != nil || num <= 0 {
+		return 0, 0, false
+	}
+	den, err := strconv.Atoi(parts[1])
+	if err != nil || den <= 0 {
+		return 0, 0, false
+	}
+	return num, den, true
+}
+
+// evenRound rounds to the nearest even integer, as most encoders require
+// even frame dimensions.
+func evenRound(v float64) int {
+	return int(math.Round(v/2)) * 2
+}
+
 // ValidateMediaFile validates that an input file is a playable media file
 // within configured size and duration limits. Returns VideoInfo on success.
 // For HTTP/HTTPS URLs, the os.Stat check is skipped and ffprobe is used
@@ -222,7 +353,8 @@ func (ft *FFmpegTranscoder) ValidateMediaFile(ctx context.Context, inputPath str
 		zap.Float64("duration", info.Duration),
 		zap.Int64("size", info.FileSize),
 		zap.Int("width", info.Width),
-		zap.Int("height", info.Height))
+		zap.Int("height", info.Height),
+		zap.Int("rotation", info.Rotation))
 
 	return info, nil
 }
@@ -254,23 +386,134 @@ func (ft *FFmpegTranscoder) Transcode(ctx context.Context, inputPath, outputPath
 	return ft.runFFmpeg(ctx, args, 0, callback)
 }
 
+// HLSResult reports the outcome of each variant of an HLS transcode.
+type HLSResult struct {
+	// Variants lists the rungs that were transcoded and are in the master
+	// playlist.
+	Variants []TranscodeProfile
+	// Failed lists the rungs that could not be transcoded.
+	Failed []VariantFailure
+	// AudioTracks lists the source tracks published as alternate audio
+	// renditions, in playlist order; empty when the rungs carry audio.
+	AudioTracks []AudioTrack
+}
+
+// VariantFailure records why one rung failed.
+type VariantFailure struct {
+	Profile TranscodeProfile
+	Err     error
+}
+
+// Partial reports whether some, but not all, variants failed.
+func (r *HLSResult) Partial() bool {
+	return len(r.Failed) > 0 && len(r.Variants) > 0
+}
+
+// FailedResolutions returns the variant names of the failed rungs: their
+// resolutions, with a codec suffix for HEVC and AV1 rungs.
+func (r *HLSResult) FailedResolutions() []string {
+	out := make([]string, 0, len(r.Failed))
+	for _, f := range r.Failed {
+		out = append(out, f.Profile.Name())
+	}
+	return out
+}
+
 // TranscodeToHLS transcodes video to HLS format with multiple quality levels.
 // It validates the input file before transcoding and cleans up partial outputs on failure.
+// With FFmpegConfig.AllowPartialVariants set it behaves like
+// TranscodeToHLSPartial and only fails when no variant succeeds.
 func (ft *FFmpegTranscoder) TranscodeToHLS(ctx context.Context, inputPath, outputDir string, profiles []TranscodeProfile, callback ProgressCallback, variantProgressFn func(variant string, progress float64)) error {
+	if ft.config.AllowPartialVariants {
+		_, err := ft.TranscodeToHLSPartial(ctx, inputPath, outputDir, profiles, callback, variantProgressFn)
+		return err
+	}
+
+	result, info, err := ft.transcodeHLSVariants(ctx, inputPath, outputDir, profiles, callback, variantProgressFn)
+	if err != nil {
+		return err
+	}
+	if len(result.Failed) > 0 {
+		// Clean up partial outputs on failure
+		ft.cleanupPartialOutput(outputDir)
+		first := result.Failed[0]
+		return fmt.Errorf("failed to transcode to %s: %w", first.Profile.Name(), first.Err)
+	}
+
+	return ft.generateHLSMasterPlaylist(outputDir, result.Variants, result.AudioTracks, info.IsPortrait())
+}
+
+// TranscodeToHLSPartial transcodes every variant even if some fail, writes a
+// master playlist listing only the variants that succeeded, and removes the
+// failed variants' partial output. It returns an error only when the input
+// is invalid or every variant failed; otherwise the result lists the failed
+// rungs so callers can report a degraded ladder.
+func (ft *FFmpegTranscoder) TranscodeToHLSPartial(ctx context.Context, inputPath, outputDir string, profiles []TranscodeProfile, callback ProgressCallback, variantProgressFn func(variant string, progress float64)) (*HLSResult, error) {
+	result, info, err := ft.transcodeHLSVariants(ctx, inputPath, outputDir, profiles, callback, variantProgressFn)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Variants) == 0 {
+		ft.cleanupPartialOutput(outputDir)
+		if len(result.Failed) == 0 {
+			return result, fmt.Errorf("no variants to transcode")
+		}
+		first := result.Failed[0]
+		return result, fmt.Errorf("all variants failed, first %s: %w", first.Profile.Name(), first.Err)
+	}
+
+	for _, f := range result.Failed {
+		ft.cleanupVariantOutput(outputDir, f.Profile)
+		ft.logger.Warn("HLS variant failed, publishing remaining variants",
+			zap.String("variant", f.Profile.Name()),
+			zap.Error(f.Err))
+	}
+
+	if err := ft.generateHLSMasterPlaylist(outputDir, result.Variants, result.AudioTracks, info.IsPortrait()); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// transcodeHLSVariants validates the input and transcodes each profile,
+// recording per-variant outcomes. A source with several audio tracks has
+// each written as an alternate audio rendition first. The returned error
+// covers failures that affect every variant.
+func (ft *FFmpegTranscoder) transcodeHLSVariants(ctx context.Context, inputPath, outputDir string, profiles []TranscodeProfile, callback ProgressCallback, variantProgressFn func(variant string, progress float64)) (*HLSResult, *VideoInfo, error) {
 	info, err := ft.ValidateMediaFile(ctx, inputPath)
 	if err != nil {
-		return fmt.Errorf("input validation failed: %w", err)
+		return nil, nil, fmt.Errorf("input validation failed: %w", err)
 	}
 
 	totalDuration := time.Duration(info.Duration * float64(time.Second))
 
 	if err := os.MkdirAll(outputDir, 0o755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+		return nil, nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	var keyInfo string
+	if key := hlsKeyFromContext(ctx); key != nil {
+		path, cleanup, err := ft.writeKeyInfo(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer cleanup()
+		keyInfo = path
 	}
 
-	var firstErr error
+	result := &HLSResult{}
+	segmentVersion := NewSegmentVersion(time.Now())
+	plan := ft.planAudio(ctx, inputPath, info)
+	if plan != nil && plan.alternate {
+		if err := ft.transcodeAudioRenditions(ctx, inputPath, outputDir, segmentVersion, keyInfo, plan, totalDuration); err != nil {
+			ft.cleanupPartialOutput(outputDir)
+			return nil, nil, err
+		}
+		result.AudioTracks = plan.tracks
+	}
+	ctx = withAudioPlan(ctx, plan)
 	for _, profile := range profiles {
-		outputPath := filepath.Join(outputDir, fmt.Sprintf("%s.m3u8", profile.Resolution))
+		outputPath := filepath.Join(outputDir, profile.Name()+".m3u8")
 		variantCB := callback
 		if variantProgressFn != nil {
 			p := profile
@@ -278,23 +521,22 @@ func (ft *FFmpegTranscoder) TranscodeToHLS(ctx context.Context, inputPath, outpu
 				if callback != nil {
 					callback(pg)
 				}
-				variantProgressFn(p.Resolution, pg.Progress)
+				variantProgressFn(p.Name(), pg.Progress)
 			}
 		}
-		if err := ft.transcodeToHLSVariant(ctx, inputPath, outputPath, profile, totalDuration, variantCB); err != nil {
-			if firstErr == nil {
-				firstErr = fmt.Errorf("failed to transcode to %s: %w", profile.Resolution, err)
-			}
+		var err error
+		if profile.DRM != "" {
+			err = ft.transcodeDRMVariant(ctx, inputPath, outputPath, segmentVersion, drmKeyFromContext(ctx), profile, info, totalDuration, variantCB)
+		} else {
+			err = ft.transcodeToHLSVariant(ctx, inputPath, outputPath, segmentVersion, keyInfo, profile, info, totalDuration, variantCB)
 		}
+		if err != nil {
+			result.Failed = append(result.Failed, VariantFailure{Profile: profile, Err: err})
+			continue
+		}
+		result.Variants = append(result.Variants, profile)
 	}
-
-	if firstErr != nil {
-		// Clean up partial outputs on failure
-		ft.cleanupPartialOutput(outputDir)
-		return firstErr
-	}
-
-	return ft.generateHLSMasterPlaylist(outputDir, profiles)
+	return result, info, nil
 }
 
 func selectABRProfiles(sourceHeight int) []TranscodeProfile {
@@ -329,6 +571,8 @@ func parseProfileHeight(resolution string) int {
 	return h
 }
 
+// probeSourceHeight returns the source's short display side, so a rotated
+// 1080x1920 phone video selects the same rungs as a 1920x1080 one.
 func (ft *FFmpegTranscoder) probeSourceHeight(ctx context.Context, inputPath string) int {
 	info, err := ft.GetVideoInfo(ctx, inputPath)
 	if err != nil || info == nil {
@@ -338,9 +582,9 @@ func (ft *FFmpegTranscoder) probeSourceHeight(ctx context.Context, inputPath str
 		return 0
 	}
 	if ft.logger != nil {
-		ft.logger.Info("probeSourceHeight", zap.String("input", inputPath), zap.Int("height", info.Height))
+		ft.logger.Info("probeSourceHeight", zap.String("input", inputPath), zap.Int("height", info.ShortSide()), zap.Int("rotation", info.Rotation))
 	}
-	return info.Height
+	return info.ShortSide()
 }
 
 var defaultProfileMap = map[string]TranscodeProfile{
@@ -374,7 +618,8 @@ func (ft *FFmpegTranscoder) TranscodeHLS(ctx context.Context, inputPath, outputD
 	}, nil)
 }
 
-// cleanupPartialOutput removes .ts and .m3u8 files from a failed transcode attempt
+// cleanupPartialOutput removes the playlists and segments of a failed
+// transcode attempt.
 func (ft *FFmpegTranscoder) cleanupPartialOutput(outputDir string) {
 	entries, err := os.ReadDir(outputDir)
 	if err != nil {
@@ -382,7 +627,7 @@ func (ft *FFmpegTranscoder) cleanupPartialOutput(outputDir string) {
 	}
 	for _, entry := range entries {
 		name := entry.Name()
-		if strings.HasSuffix(name, ".ts") || strings.HasSuffix(name, ".m3u8") {
+		if isHLSOutput(name) || strings.HasSuffix(name, ".m3u8") {
 			if err := os.Remove(filepath.Join(outputDir, name)); err != nil {
 				ft.logger.Warn("Failed to clean up partial output", zap.String("file", name), zap.Error(err))
 			}
@@ -390,54 +635,195 @@ func (ft *FFmpegTranscoder) cleanupPartialOutput(outputDir string) {
 	}
 }
 
-// transcodeToHLSVariant transcodes a single HLS variant
-func (ft *FFmpegTranscoder) transcodeToHLSVariant(ctx context.Context, inputPath, outputPath string, profile TranscodeProfile, totalDuration time.Duration, callback ProgressCallback) error {
-	videoCodec := ft.config.VideoCodec
-	if videoCodec == "" {
-		videoCodec = "libx264"
+// isHLSOutput reports whether name is a segment written by a transcode:
+// MPEG-TS, or the fMP4 segments and init sections of DRM rungs.
+func isHLSOutput(name string) bool {
+	return strings.HasSuffix(name, ".ts") || strings.HasSuffix(name, ".m4s") || strings.HasSuffix(name, "_init.mp4")
+}
+
+// orientedResolution returns the profile's width and height, swapped for
+// portrait sources so the rung keeps the source orientation.
+func orientedResolution(profile TranscodeProfile, portrait bool) (int, int, bool) {
+	w, h, ok := parseRatio(profile.Resolution, "x")
+	if !ok {
+		return 0, 0, false
 	}
+	if portrait {
+		w, h = h, w
+	}
+	return w, h, true
+}
 
-	audioCodec := ft.config.AudioCodec
-	if audioCodec == "" {
-		audioCodec = "aac"
+// hlsVideoFilter builds the -vf chain for a variant. Rotation is applied
+// explicitly (ffmpeg runs with -noautorotate) so the output frames are
+// upright and carry no rotation metadata that players might apply twice.
+func hlsVideoFilter(profile TranscodeProfile, info *VideoInfo) string {
+	var filters []string
+	portrait := false
+	if info != nil {
+		switch info.Rotation {
+		case 90:
+			filters = append(filters, "transpose=clock")
+		case 180:
+			filters = append(filters, "hflip", "vflip")
+		case 270:
+			filters = append(filters, "transpose=cclock")
+		}
+		portrait = info.IsPortrait()
 	}
+	if w, h, ok := orientedResolution(profile, portrait); ok {
+		filters = append(filters, fmt.Sprintf("scale=%d:%d", w, h), "setsar=1")
+	} else {
+		filters = append(filters, fmt.Sprintf("scale=%s", profile.Resolution))
+	}
+	return strings.Join(filters, ",")
+}
 
-	args := []string{
+// cleanupVariantOutput removes one variant's playlist and segments, leaving
+// other variants in outputDir untouched.
+func (ft *FFmpegTranscoder) cleanupVariantOutput(outputDir string, profile TranscodeProfile) {
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		variant := profile.Name()
+		if name == variant+".m3u8" || name == drmAudioPlaylist(variant) ||
+			(strings.HasPrefix(name, variant+"_") && isHLSOutput(name)) {
+			if err := os.Remove(filepath.Join(outputDir, name)); err != nil {
+				ft.logger.Warn("Failed to clean up failed variant output", zap.String("file", name), zap.Error(err))
+			}
+		}
+	}
+}
+
+// transcodeToHLSVariant transcodes a single HLS variant. A non-empty
+// keyInfo is an -hls_key_info_file that AES-128 encrypts the segments.
+func (ft *FFmpegTranscoder) transcodeToHLSVariant(ctx context.Context, inputPath, outputPath, segmentVersion, keyInfo string, profile TranscodeProfile, info *VideoInfo, totalDuration time.Duration, callback ProgressCallback) error {
+	segmentTime := "6"
+	segmentPattern := segmentFilePattern(outputPath, segmentVersion)
+	var keyframeArgs []string
+	if partDuration, ok := ft.lowLatencyPart(profile); ok {
+		// Every part must start on a keyframe so players can begin
+		// decoding at any part (INDEPENDENT=YES).
+		seconds := strconv.FormatFloat(partDuration.Seconds(), 'f', -1, 64)
+		segmentTime = seconds
+		segmentPattern = partFilePattern(outputPath, segmentVersion)
+		keyframeArgs = []string{"-force_key_frames", "expr:gte(t,n_forced*" + seconds + ")"}
+	}
+
+	outputArgs := []string{
+		"-f", "hls",
+		"-hls_time", segmentTime,
+		"-hls_list_size", "0",
+	}
+	if profile.fragmentedMP4() {
+		segmentPattern = fmp4SegmentPattern(outputPath, segmentVersion, "")
+		outputArgs = append(outputArgs,
+			"-hls_segment_type", "fmp4",
+			"-hls_fmp4_init_filename", fmp4InitName(outputPath, segmentVersion, ""),
+		)
+	}
+	outputArgs = append(outputArgs, "-hls_segment_filename", segmentPattern)
+	if keyInfo != "" {
+		outputArgs = append(outputArgs, "-hls_key_info_file", keyInfo)
+	}
+	outputArgs = append(outputArgs, "-y", outputPath)
+
+	return ft.runEncode(ctx, inputPath, profile, info, keyframeArgs, outputArgs, totalDuration, callback)
+}
+
+// encodeArgs returns the FFmpeg input and encoding arguments of one rung
+// encoded with videoCodec, ahead of the output format options. A
+// non-empty subtitles file is burned into the scaled picture; audioArgs
+// select and encode the rung's audio, if any.
+func (ft *FFmpegTranscoder) encodeArgs(inputPath, videoCodec string, accel HardwareAccel, profile TranscodeProfile, info *VideoInfo, subtitles string, keyframeArgs, audioArgs []string) []string {
+	filter := hlsVideoFilter(profile, info)
+	if subtitles != "" {
+		filter += "," + subtitlesFilter(subtitles)
+	}
+	if accel == HardwareVAAPI {
+		// VAAPI encodes surfaces uploaded after software scaling.
+		filter += ",format=nv12,hwupload"
+	}
+
+	args := ft.hardwareInputArgs(accel)
+	args = append(args,
+		"-noautorotate",
 		"-i", inputPath,
 		"-c:v", videoCodec,
-		"-preset", "ultrafast",
-		"-crf", "28",
-		"-vf", fmt.Sprintf("scale=%s", profile.Resolution),
+	)
+	args = append(args, encoderOptions(videoCodec, accel)...)
+	args = append(args, codecArgs(codecFamily(videoCodec))...)
+	args = append(args,
+		"-vf", filter,
+		"-metadata:s:v:0", "rotate=0",
 		"-b:v", profile.Bitrate,
 		"-maxrate", profile.Bitrate,
 		"-bufsize", fmt.Sprintf("%dk", parseBitrate(profile.Bitrate)*2),
-		"-c:a", audioCodec,
-		"-b:a", "128k",
-		"-ac", "2",
-		"-f", "hls",
-		"-hls_time", "6",
-		"-hls_list_size", "0",
-		"-hls_segment_filename", fmt.Sprintf("%s_%%03d.ts", outputPath[:len(outputPath)-5]),
-		"-y",
-		outputPath,
-	}
+	)
+	args = append(args, keyframeArgs...)
+	return append(args, audioArgs...)
+}
 
-	return ft.runFFmpeg(ctx, args, totalDuration, callback)
+// lowLatencyPart returns the LL-HLS part duration for profile, and whether
+// the profile is configured for low-latency output at all. LL-HLS parts
+// are MPEG-TS, so fMP4 rungs are never low-latency.
+func (ft *FFmpegTranscoder) lowLatencyPart(profile TranscodeProfile) (time.Duration, bool) {
+	if profile.fragmentedMP4() {
+		return 0, false
+	}
+	for _, r := range ft.config.LowLatencyProfiles {
+		if r == profile.Resolution {
+			if ft.config.PartDuration > 0 {
+				return ft.config.PartDuration, true
+			}
+			return DefaultPartDuration, true
+		}
+	}
+	return 0, false
 }
 
-// generateHLSMasterPlaylist generates the HLS master playlist
-func (ft *FFmpegTranscoder) generateHLSMasterPlaylist(outputDir string, profiles []TranscodeProfile) error {
+// generateHLSMasterPlaylist generates the HLS master playlist. Variant
+// playlists keep the landscape profile name; RESOLUTION reports the actual
+// output dimensions and CODECS lets players skip rungs they cannot decode.
+// Rungs without an audio playlist of their own reference the alternate
+// audio renditions of audio, if any.
+func (ft *FFmpegTranscoder) generateHLSMasterPlaylist(outputDir string, profiles []TranscodeProfile, audio []AudioTrack, portrait bool) error {
 	masterPath := filepath.Join(outputDir, "master.m3u8")
 
 	var builder strings.Builder
 	builder.WriteString("#EXTM3U\n")
 	builder.WriteString("#EXT-X-VERSION:3\n\n")
+	for i, track := range audio {
+		name := AudioRenditionName(i, track.Language)
+		builder.WriteString(AudioMediaTag(i, track.Language, audioTrackName(track), name+".m3u8"))
+	}
+	audioGroup := ""
+	if len(audio) > 0 {
+		audioGroup = fmt.Sprintf(",AUDIO=\"%s\"", AudioGroupID)
+	}
 
 	for _, profile := range profiles {
-		variantPath := fmt.Sprintf("%s.m3u8", profile.Resolution)
+		variant := profile.Name()
+		variantPath := variant + ".m3u8"
 		bandwidth := parseBitrate(profile.Bitrate) * 1000
-
-		fmt.Fprintf(&builder, "#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%s\n", bandwidth, profile.Resolution)
+		resolution := profile.Resolution
+		if w, h, ok := orientedResolution(profile, portrait); ok {
+			resolution = fmt.Sprintf("%dx%d", w, h)
+		}
+		_, codec := ParseVariantName(variant)
+		codecs := HLSCodecs(codec, parseProfileHeight(profile.Resolution))
+
+		if profile.DRM != "" && ft.config.PackagerPath != "" {
+			// Packaged DRM rungs carry audio in a playlist of their own.
+			group := "audio-" + variant
+			fmt.Fprintf(&builder, "#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"%s\",NAME=\"audio\",DEFAULT=YES,AUTOSELECT=YES,URI=\"%s\"\n", group, drmAudioPlaylist(variant))
+			fmt.Fprintf(&builder, "#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%s,CODECS=\"%s\",AUDIO=\"%s\"\n", bandwidth, resolution, codecs, group)
+		} else {
+			fmt.Fprintf(&builder, "#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%s,CODECS=\"%s\"%s\n", bandwidth, resolution, codecs, audioGroup)
+		}
 		fmt.Fprintf(&builder, "%s\n", variantPath)
 	}
 
@@ -535,10 +921,17 @@ func (ft *FFmpegTranscoder) ConcatVideos(ctx context.Context, inputPaths []strin
 	return ft.runFFmpeg(ctx, args, 0, callback)
 }
 
-// runFFmpeg executes FFmpeg command with progress monitoring
+// runFFmpeg executes FFmpeg command with progress monitoring. FFmpeg writes
+// machine-readable key=value progress to stdout via -progress pipe:1;
+// stderr only carries the log, of which the last line is kept for errors.
 func (ft *FFmpegTranscoder) runFFmpeg(ctx context.Context, args []string, totalDuration time.Duration, callback ProgressCallback) error {
+	args = append([]string{"-progress", "pipe:1", "-nostats"}, args...)
 	cmd := exec.CommandContext(ctx, ft.config.FFmpegPath, args...)
 
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
 		return fmt.Errorf("failed to create stderr pipe: %w", err)
@@ -552,100 +945,91 @@ func (ft *FFmpegTranscoder) runFFmpeg(ctx context.Context, args []string, totalD
 	go func() {
 		defer close(progressDone)
 		if callback != nil {
-			ft.monitorProgress(stderr, totalDuration, callback)
+			ft.monitorProgress(stdout, totalDuration, callback)
 		} else {
-			_, _ = io.Copy(io.Discard, stderr)
+			_, _ = io.Copy(io.Discard, stdout)
 		}
 	}()
 
+	var lastLogLine string
+	scanner := bufio.NewScanner(stderr)
+	scanner.Buffer(make([]byte, 0, 4096), 1<<20)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lastLogLine = line
+		}
+	}
+	_, _ = io.Copy(io.Discard, stderr)
+
 	<-progressDone
 
 	if err := cmd.Wait(); err != nil {
+		if lastLogLine != "" {
+			return fmt.Errorf("FFmpeg process failed: %w: %s", err, lastLogLine)
+		}
 		return fmt.Errorf("FFmpeg process failed: %w", err)
 	}
 
 	return nil
 }
 
-var progressRegex = regexp.MustCompile(`frame=\s*(\d+)\s+fps=\s*([\d.]+)\s+q=\s*([-.\d]+)\s+L?size=\s*(\S+)\s+time=\s*([\d:.]+)\s+bitrate=\s*(\S+)\s+speed=\s*([\d.]+)x`)
-
-// monitorProgress monitors FFmpeg progress output
-func (ft *FFmpegTranscoder) monitorProgress(stderrPipe io.Reader, totalDuration time.Duration, callback ProgressCallback) {
-	buf := make([]byte, 4096)
-	var lineBuf []byte
-	var totalLines int
+// monitorProgress parses the key=value blocks FFmpeg writes with -progress
+// and reports each block, which ends with a progress=continue|end line.
+func (ft *FFmpegTranscoder) monitorProgress(progressPipe io.Reader, totalDuration time.Duration, callback ProgressCallback) {
+	scanner := bufio.NewScanner(progressPipe)
+	progress := &TranscodeProgress{}
+	var blocks int
+
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if value == "N/A" {
+			continue
+		}
 
-	for {
-		n, err := stderrPipe.Read(buf)
-		if n > 0 {
-			lineBuf = append(lineBuf, buf[:n]...)
-			for {
-				idx := bytes.IndexAny(lineBuf, "\r\n")
-				if idx < 0 {
-					break
-				}
-				line := string(lineBuf[:idx])
-				lineBuf = lineBuf[idx+1:]
-				if len(lineBuf) > 0 && lineBuf[0] == '\n' {
-					lineBuf = lineBuf[1:]
-				}
-				if line == "" {
-					continue
+		switch key {
+		case "frame":
+			progress.Frame, _ = strconv.ParseInt(value, 10, 64)
+		case "fps":
+			progress.FPS, _ = strconv.ParseFloat(value, 64)
+		case "bitrate":
+			progress.CurrentBitrate = value
+		case "speed":
+			progress.Speed = strings.TrimSuffix(value, "x")
+		case "out_time_us", "out_time_ms":
+			// out_time_ms is in microseconds too, a long-standing FFmpeg quirk.
+			if us, err := strconv.ParseInt(value, 10, 64); err == nil && us >= 0 {
+				progress.Processed = time.Duration(us) * time.Microsecond
+			}
+		case "out_time":
+			if progress.Processed == 0 {
+				progress.Processed = parseTime(value)
+			}
+		case "progress":
+			blocks++
+			if totalDuration > 0 {
+				pct := float64(progress.Processed) / float64(totalDuration) * 100
+				if pct > 99 {
+					pct = 99
 				}
-
-				totalLines++
-				matches := progressRegex.FindStringSubmatch(line)
-				if len(matches) >= 8 {
-					frame, _ := strconv.ParseInt(matches[1], 10, 64)
-					fps, _ := strconv.ParseFloat(matches[2], 64)
-					bitrate := matches[6]
-					speed := matches[7]
-
-					timeStr := matches[5]
-					processed := parseTime(timeStr)
-
-					progress := &TranscodeProgress{
-						Frame:          frame,
-						FPS:            fps,
-						CurrentBitrate: bitrate,
-						Speed:          speed,
-						Processed:      processed,
-					}
-
-					if totalDuration > 0 {
-						pct := float64(processed) / float64(totalDuration) * 100
-						if pct > 99 {
-							pct = 99
-						}
-						progress.Progress = pct
-						if processed < totalDuration {
-							progress.Remaining = totalDuration - processed
-						}
-						ft.logger.Debug("ffmpeg progress",
-							zap.Duration("processed", processed),
-							zap.Duration("total", totalDuration),
-							zap.Float64("pct", pct))
-					}
-
-					callback(progress)
-				} else if totalLines <= 3 {
-					if strings.HasPrefix(line, "ffmpeg version") || strings.HasPrefix(line, "  ") {
-						continue
-					}
-					ft.logger.Debug("ffmpeg stderr line (no regex match)",
-						zap.Int("line_num", totalLines),
-						zap.String("line", line))
+				progress.Progress = pct
+				if progress.Processed < totalDuration {
+					progress.Remaining = totalDuration - progress.Processed
 				}
 			}
-		}
-		if err != nil {
-			ft.logger.Debug("ffmpeg monitor done",
-				zap.Int("total_lines", totalLines),
-				zap.Duration("total_duration", totalDuration),
-				zap.Error(err))
-			break
+			callback(progress)
+			progress = &TranscodeProgress{}
 		}
 	}
+
+	ft.logger.Debug("ffmpeg progress monitor done",
+		zap.Int("blocks", blocks),
+		zap.Duration("total_duration", totalDuration),
+		zap.Error(scanner.Err()))
+	_, _ = io.Copy(io.Discard, progressPipe)
 }
 
 // parseTime parses time string in format HH:MM:SS.mmm
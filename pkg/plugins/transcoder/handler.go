@@ -45,6 +45,9 @@ type submitTranscodeRequest struct {
 	FilePath string             `json:"file_path"`
 	Profiles []TranscodeProfile `json:"profiles"`
 	Priority int                `json:"priority"`
+	// Subtitles maps languages to subtitle files on the transcoder host,
+	// for profiles that burn subtitles in.
+	Subtitles map[string]string `json:"subtitles"`
 }
 
 // NewTranscoderHandler creates a new transcoder handler
@@ -63,6 +66,10 @@ func resolveTaskID(r *http.Request, prefix string) string {
 		return taskID
 	}
 
+	if id, ok := taskStatusPathID(r.URL.Path); ok {
+		return id
+	}
+
 	if strings.HasPrefix(r.URL.Path, prefix) {
 		taskID = strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/")
 		if taskID != "" {
@@ -94,11 +101,10 @@ func (h *TranscoderHandler) HealthHandler(w http.ResponseWriter, r *http.Request
 	_ = json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
 }
 
-// ReadyHandler handles readiness check requests
+// ReadyHandler reports the kernel's health checks, including the
+// dependencies this plugin registered.
 func (h *TranscoderHandler) ReadyHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
+	h.kernel.ReadyHandler(w, r)
 }
 
 // SubmitTaskHandler handles transcoding task submission
@@ -131,6 +137,18 @@ func (h *TranscoderHandler) SubmitTaskHandler(w http.ResponseWriter, r *http.Req
 		Profiles:   req.Profiles,
 		MaxRetries: 3,
 	}
+	for language, path := range req.Subtitles {
+		path = sanitizeFilePath(strings.TrimSpace(path))
+		if path == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid subtitle path for " + language})
+			return
+		}
+		if task.Subtitles == nil {
+			task.Subtitles = make(map[string]string, len(req.Subtitles))
+		}
+		task.Subtitles[language] = path
+	}
 
 	if err := h.plugin.SubmitTask(&task); err != nil {
 		h.logger.Error("Failed to submit task", zap.Error(err))
@@ -326,6 +344,29 @@ func (h *TranscoderHandler) ListProfilesHandler(w http.ResponseWriter, r *http.R
 	_ = json.NewEncoder(w).Encode(map[string]interface{}{"profiles": profiles})
 }
 
+// taskStatusPathID extracts the task ID from /api/v1/transcode/{id}/status.
+func taskStatusPathID(path string) (string, bool) {
+	rest, ok := strings.CutPrefix(path, "/api/v1/transcode/")
+	if !ok {
+		return "", false
+	}
+	id, ok := strings.CutSuffix(strings.TrimSuffix(rest, "/"), "/status")
+	if !ok || id == "" || strings.Contains(id, "/") {
+		return "", false
+	}
+	return id, true
+}
+
+// TaskRouteHandler serves per-task routes under /api/v1/transcode/ that the
+// fixed routes don't match, i.e. GET /api/v1/transcode/{id}/status.
+func (h *TranscoderHandler) TaskRouteHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := taskStatusPathID(r.URL.Path); ok {
+		h.GetTaskStatusHandler(w, r)
+		return
+	}
+	h.NotFoundHandler(w, r)
+}
+
 // NotFoundHandler handles 404 requests
 func (h *TranscoderHandler) NotFoundHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
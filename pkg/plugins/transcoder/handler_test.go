@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -372,12 +373,7 @@ func TestTranscoderPluginWrapper_Stop_NoServer(t *testing.T) {
 }
 
 func TestTaskQueue_UpdateTask(t *testing.T) {
-	tq := &TaskQueue{
-		tasks:   make(map[string]*TranscodeTask),
-		queue:   make(chan *TranscodeTask, 10),
-		maxSize: 10,
-		metrics: &QueueMetrics{},
-	}
+	tq := newTestTaskQueue(10)
 
 	err := tq.Enqueue(&TranscodeTask{ID: "task-1"})
 	require.NoError(t, err)
@@ -390,13 +386,28 @@ func TestTaskQueue_UpdateTask(t *testing.T) {
 	assert.Equal(t, TaskStatusProcessing, task.Status)
 }
 
+func TestTaskQueue_UpdateProgress(t *testing.T) {
+	tq := newTestTaskQueue(10)
+	require.NoError(t, tq.Enqueue(&TranscodeTask{ID: "task-1"}))
+
+	require.NoError(t, tq.UpdateProgress("task-1", &TranscodeProgress{Progress: 10}))
+	task, err := tq.GetTask("task-1")
+	require.NoError(t, err)
+	assert.Zero(t, task.Progress, "pending tasks have no progress")
+
+	require.NoError(t, tq.TransitionStatus("task-1", func(t *TranscodeTask) { t.Status = TaskStatusProcessing }))
+	require.NoError(t, tq.UpdateProgress("task-1", &TranscodeProgress{Progress: 42.5, Speed: "1.5"}))
+	task, err = tq.GetTask("task-1")
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusProcessing, task.Status)
+	assert.Equal(t, 42.5, task.Progress)
+	assert.Equal(t, "1.5", task.Speed)
+
+	assert.Error(t, tq.UpdateProgress("missing", &TranscodeProgress{}))
+}
+
 func TestTaskQueue_Len(t *testing.T) {
-	tq := &TaskQueue{
-		tasks:   make(map[string]*TranscodeTask),
-		queue:   make(chan *TranscodeTask, 10),
-		maxSize: 10,
-		metrics: &QueueMetrics{},
-	}
+	tq := newTestTaskQueue(10)
 
 	assert.Equal(t, 0, tq.Len())
 
@@ -754,7 +765,7 @@ func TestFFmpegTranscoder_GenerateHLSMasterPlaylist(t *testing.T) {
 		{Resolution: "1280x720", Bitrate: "2500k", Format: "hls"},
 	}
 
-	err := ft.generateHLSMasterPlaylist(tmpDir, profiles)
+	err := ft.generateHLSMasterPlaylist(tmpDir, profiles, nil, false)
 	require.NoError(t, err)
 
 	data, err := os.ReadFile(filepath.Join(tmpDir, "master.m3u8"))
@@ -765,169 +776,85 @@ func TestFFmpegTranscoder_GenerateHLSMasterPlaylist(t *testing.T) {
 	assert.Contains(t, content, "1280x720")
 }
 
+// progressBlock renders one block of FFmpeg -progress output.
+func progressBlock(frame int, outTime time.Duration, state string) string {
+	return fmt.Sprintf("frame=%d\nfps=25.00\nstream_0_0_q=28.0\nbitrate=2048.0kbits/s\ntotal_size=1024\n"+
+		"out_time_us=%d\nout_time_ms=%d\nout_time=00:00:%09.6f\ndup_frames=0\ndrop_frames=0\nspeed=1.5x\nprogress=%s\n",
+		frame, outTime.Microseconds(), outTime.Microseconds(), outTime.Seconds(), state)
+}
+
 func TestMonitorProgress(t *testing.T) {
-	tests := []struct {
-		name    string
-		output  string
-		matches bool
-	}{
-		{"no match", "some random output", false},
-	}
+	ft := NewFFmpegTranscoder(&FFmpegConfig{TempDir: t.TempDir()}, zap.NewNop())
 
-	for _, tc := range tests {
-		t.Run(tc.name, func(t *testing.T) {
-			matches := progressRegex.FindStringSubmatch(tc.output)
-			if tc.matches {
-				assert.GreaterOrEqual(t, len(matches), 8)
-			} else {
-				assert.Empty(t, matches)
-			}
-		})
-	}
+	var calls int
+	ft.monitorProgress(strings.NewReader("some random output\nframe= 100 fps= 25.0 q=28.0\n"), 10*time.Second, func(*TranscodeProgress) {
+		calls++
+	})
+	assert.Zero(t, calls, "only progress= lines complete a block")
 }
 
 func TestMonitorProgress_Callback(t *testing.T) {
-	pr, pw, err := os.Pipe()
-	require.NoError(t, err)
-
-	_, err = pw.WriteString("frame= 100 fps= 25.0 q=28.0 size= 1024 time=00:00:04.00 bitrate=2048.0kbits/s speed=1.0x\n")
-	require.NoError(t, err)
-	pw.Close()
-
-	cfg := &FFmpegConfig{TempDir: t.TempDir()}
-	ft := NewFFmpegTranscoder(cfg, zap.NewNop())
+	ft := NewFFmpegTranscoder(&FFmpegConfig{TempDir: t.TempDir()}, zap.NewNop())
 
 	var received *TranscodeProgress
-	callback := func(p *TranscodeProgress) {
+	ft.monitorProgress(strings.NewReader(progressBlock(100, 4*time.Second, "continue")), 10*time.Second, func(p *TranscodeProgress) {
 		received = p
-	}
-
-	done := make(chan struct{})
-	go func() {
-		ft.monitorProgress(pr, 10*time.Second, callback)
-		close(done)
-	}()
-
-	select {
-	case <-done:
-	case <-time.After(2 * time.Second):
-		t.Fatal("monitorProgress timed out")
-	}
+	})
 
 	require.NotNil(t, received, "callback should have been called with progress data")
 	assert.Equal(t, int64(100), received.Frame)
 	assert.Equal(t, 25.0, received.FPS)
 	assert.Equal(t, "2048.0kbits/s", received.CurrentBitrate)
-	assert.Equal(t, "1.0", received.Speed)
+	assert.Equal(t, "1.5", received.Speed)
+	assert.Equal(t, 4*time.Second, received.Processed)
+	assert.Equal(t, 6*time.Second, received.Remaining)
 	assert.InDelta(t, 40.0, received.Progress, 0.1)
 }
 
-func TestMonitorProgress_CarriageReturn(t *testing.T) {
-	pr, pw, err := os.Pipe()
-	require.NoError(t, err)
-
-	go func() {
-		_, _ = pw.WriteString("frame=  50 fps= 25.0 q=28.0 size=  512 time=00:00:02.00 bitrate=2048.0kbits/s speed=1.0x\r")
-		_, _ = pw.WriteString("frame= 100 fps= 25.0 q=28.0 size= 1024 time=00:00:04.00 bitrate=2048.0kbits/s speed=1.0x\r")
-		_, _ = pw.WriteString("frame= 150 fps= 25.0 q=28.0 size= 1536 time=00:00:06.00 bitrate=2048.0kbits/s speed=1.0x\n")
-		pw.Close()
-	}()
-
-	cfg := &FFmpegConfig{TempDir: t.TempDir()}
-	ft := NewFFmpegTranscoder(cfg, zap.NewNop())
+func TestMonitorProgress_Blocks(t *testing.T) {
+	ft := NewFFmpegTranscoder(&FFmpegConfig{TempDir: t.TempDir()}, zap.NewNop())
+	output := progressBlock(50, 2*time.Second, "continue") +
+		progressBlock(100, 4*time.Second, "continue") +
+		progressBlock(250, 10*time.Second, "end")
 
 	var calls []float64
-	callback := func(p *TranscodeProgress) {
+	ft.monitorProgress(strings.NewReader(output), 10*time.Second, func(p *TranscodeProgress) {
 		calls = append(calls, p.Progress)
-	}
-
-	done := make(chan struct{})
-	go func() {
-		ft.monitorProgress(pr, 10*time.Second, callback)
-		close(done)
-	}()
-
-	select {
-	case <-done:
-	case <-time.After(2 * time.Second):
-		t.Fatal("monitorProgress timed out")
-	}
+	})
 
-	assert.Equal(t, 3, len(calls), "expected 3 progress callbacks for \\r and \\n separated lines")
+	require.Len(t, calls, 3)
 	assert.InDelta(t, 20.0, calls[0], 0.1)
 	assert.InDelta(t, 40.0, calls[1], 0.1)
-	assert.InDelta(t, 60.0, calls[2], 0.1)
+	assert.InDelta(t, 99.0, calls[2], 0.1, "progress is capped until the task completes")
 }
 
-func TestMonitorProgress_MixedSeparators(t *testing.T) {
-	pr, pw, err := os.Pipe()
-	require.NoError(t, err)
-
-	go func() {
-		_, _ = pw.WriteString("frame=  50 fps= 25.0 q=28.0 size=  512 time=00:00:02.00 bitrate=2048.0kbits/s speed=1.0x\r\n")
-		_, _ = pw.WriteString("frame= 100 fps= 25.0 q=28.0 size= 1024 time=00:00:04.00 bitrate=2048.0kbits/s speed=1.0x\r")
-		_, _ = pw.WriteString("frame= 200 fps= 25.0 q=28.0 size= 2048 time=00:00:08.00 bitrate=2048.0kbits/s speed=1.0x\n")
-		pw.Close()
-	}()
+func TestMonitorProgress_NotAvailable(t *testing.T) {
+	ft := NewFFmpegTranscoder(&FFmpegConfig{TempDir: t.TempDir()}, zap.NewNop())
+	// Before the first packet is muxed FFmpeg reports N/A.
+	output := "frame=0\nfps=0.00\nbitrate=N/A\nout_time_us=N/A\nout_time=N/A\nspeed=N/A\nprogress=continue\n"
 
-	cfg := &FFmpegConfig{TempDir: t.TempDir()}
-	ft := NewFFmpegTranscoder(cfg, zap.NewNop())
-
-	var calls []float64
-	callback := func(p *TranscodeProgress) {
-		calls = append(calls, p.Progress)
-	}
-
-	done := make(chan struct{})
-	go func() {
-		ft.monitorProgress(pr, 10*time.Second, callback)
-		close(done)
-	}()
-
-	select {
-	case <-done:
-	case <-time.After(2 * time.Second):
-		t.Fatal("monitorProgress timed out")
-	}
+	var received *TranscodeProgress
+	ft.monitorProgress(strings.NewReader(output), 10*time.Second, func(p *TranscodeProgress) {
+		received = p
+	})
 
-	assert.Equal(t, 3, len(calls), "expected 3 progress callbacks for mixed \\r\\n/\\r/\\n separators")
-	assert.InDelta(t, 20.0, calls[0], 0.1)
-	assert.InDelta(t, 40.0, calls[1], 0.1)
-	assert.InDelta(t, 80.0, calls[2], 0.1)
+	require.NotNil(t, received)
+	assert.Zero(t, received.Processed)
+	assert.Zero(t, received.Progress)
+	assert.Empty(t, received.Speed)
 }
 
 func TestMonitorProgress_ZeroDuration(t *testing.T) {
-	pr, pw, err := os.Pipe()
-	require.NoError(t, err)
-
-	_, err = pw.WriteString("frame= 100 fps= 25.0 q=28.0 size= 1024 time=00:00:04.00 bitrate=2048.0kbits/s speed=1.0x\n")
-	require.NoError(t, err)
-	pw.Close()
-
-	cfg := &FFmpegConfig{TempDir: t.TempDir()}
-	ft := NewFFmpegTranscoder(cfg, zap.NewNop())
+	ft := NewFFmpegTranscoder(&FFmpegConfig{TempDir: t.TempDir()}, zap.NewNop())
 
 	var received *TranscodeProgress
-	callback := func(p *TranscodeProgress) {
+	ft.monitorProgress(strings.NewReader(progressBlock(100, 4*time.Second, "continue")), 0, func(p *TranscodeProgress) {
 		received = p
-	}
-
-	done := make(chan struct{})
-	go func() {
-		ft.monitorProgress(pr, 0, callback)
-		close(done)
-	}()
-
-	select {
-	case <-done:
-	case <-time.After(2 * time.Second):
-		t.Fatal("monitorProgress timed out")
-	}
+	})
 
-	if received != nil {
-		assert.Equal(t, float64(0), received.Progress, "Progress should be 0 when totalDuration is 0")
-		assert.Equal(t, time.Duration(4*time.Second), received.Processed)
-	}
+	require.NotNil(t, received)
+	assert.Equal(t, float64(0), received.Progress, "Progress should be 0 when totalDuration is 0")
+	assert.Equal(t, 4*time.Second, received.Processed)
 }
 
 func TestFFmpegTranscoder_Transcode_CustomCodecs(t *testing.T) {
@@ -0,0 +1,146 @@
+package transcoder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeFFmpeg installs ffprobe and ffmpeg stand-ins. ffprobe prints a
+// landscape 1080p probe; ffmpeg fails for any variant whose scale filter
+// mentions failScale and otherwise writes the playlist and one segment.
+func fakeFFmpeg(t *testing.T, failScale string) *FFmpegConfig {
+	t.Helper()
+	dir := t.TempDir()
+
+	probe := `#!/bin/sh
+cat <<'JSON'
+{"streams":[{"codec_type":"video","codec_name":"h264","width":1920,"height":1080}],"format":{"duration":"12.0","size":"1000"}}
+JSON
+`
+	ffmpeg := `#!/bin/sh
+for arg in "$@"; do
+  case "$arg" in
+    *` + failScale + `*) echo "encoder exploded" >&2; exit 1 ;;
+  esac
+  last="$arg"
+  if [ "$prev" = "-hls_segment_filename" ]; then seg="$arg"; fi
+  prev="$arg"
+done
+printf '#EXTM3U\n' > "$last"
+touch "$(printf "$seg" 0)"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ffprobe"), []byte(probe), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ffmpeg"), []byte(ffmpeg), 0o755))
+
+	input := filepath.Join(dir, "input.mp4")
+	require.NoError(t, os.WriteFile(input, []byte("not really a video"), 0o644))
+
+	return &FFmpegConfig{
+		FFmpegPath:  filepath.Join(dir, "ffmpeg"),
+		FFprobePath: filepath.Join(dir, "ffprobe"),
+		TempDir:     dir,
+	}
+}
+
+func TestTranscodeToHLSPartial_OneRungFails(t *testing.T) {
+	cfg := fakeFFmpeg(t, "scale=1280:720")
+	ft := NewFFmpegTranscoder(cfg, zap.NewNop())
+	outputDir := t.TempDir()
+
+	result, err := ft.TranscodeToHLSPartial(context.Background(), filepath.Join(cfg.TempDir, "input.mp4"), outputDir, BuiltinLadder(), nil, nil)
+	require.NoError(t, err)
+
+	assert.True(t, result.Partial())
+	assert.Equal(t, []string{"1280x720"}, result.FailedResolutions())
+	require.Len(t, result.Failed, 1)
+	assert.Contains(t, result.Failed[0].Err.Error(), "FFmpeg process failed")
+	assert.Contains(t, result.Failed[0].Err.Error(), "encoder exploded", "the last FFmpeg log line is kept")
+	assert.Len(t, result.Variants, 3)
+
+	master, err := os.ReadFile(filepath.Join(outputDir, "master.m3u8"))
+	require.NoError(t, err)
+	for _, res := range []string{"1920x1080", "854x480", "640x360"} {
+		assert.Contains(t, string(master), res+".m3u8")
+		assert.FileExists(t, filepath.Join(outputDir, res+".m3u8"))
+	}
+	assert.NotContains(t, string(master), "1280x720")
+	assert.NoFileExists(t, filepath.Join(outputDir, "1280x720.m3u8"))
+}
+
+func TestTranscodeToHLSPartial_AllRungsFail(t *testing.T) {
+	cfg := fakeFFmpeg(t, "scale=")
+	ft := NewFFmpegTranscoder(cfg, zap.NewNop())
+	outputDir := t.TempDir()
+
+	result, err := ft.TranscodeToHLSPartial(context.Background(), filepath.Join(cfg.TempDir, "input.mp4"), outputDir, BuiltinLadder(), nil, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "all variants failed")
+	require.NotNil(t, result)
+	assert.Len(t, result.Failed, 4)
+	assert.NoFileExists(t, filepath.Join(outputDir, "master.m3u8"))
+}
+
+func TestTranscodeToHLS_StrictModeFailsWholeLadder(t *testing.T) {
+	cfg := fakeFFmpeg(t, "scale=1280:720")
+	ft := NewFFmpegTranscoder(cfg, zap.NewNop())
+	outputDir := t.TempDir()
+
+	err := ft.TranscodeToHLS(context.Background(), filepath.Join(cfg.TempDir, "input.mp4"), outputDir, BuiltinLadder(), nil, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to transcode to 1280x720")
+
+	entries, err := os.ReadDir(outputDir)
+	require.NoError(t, err)
+	for _, e := range entries {
+		assert.False(t, strings.HasSuffix(e.Name(), ".m3u8"), "strict mode removes all playlists, found %s", e.Name())
+	}
+}
+
+func TestTranscodeToHLS_AllowPartialVariants(t *testing.T) {
+	cfg := fakeFFmpeg(t, "scale=854:480")
+	cfg.AllowPartialVariants = true
+	ft := NewFFmpegTranscoder(cfg, zap.NewNop())
+	outputDir := t.TempDir()
+
+	require.NoError(t, ft.TranscodeToHLS(context.Background(), filepath.Join(cfg.TempDir, "input.mp4"), outputDir, BuiltinLadder(), nil, nil))
+
+	master, err := os.ReadFile(filepath.Join(outputDir, "master.m3u8"))
+	require.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(string(master), "#EXT-X-STREAM-INF"))
+	assert.NotContains(t, string(master), "854x480")
+}
+
+func TestTranscodeToHLS_LowLatencyProfile(t *testing.T) {
+	cfg := fakeFFmpeg(t, "n_forced")
+	cfg.LowLatencyProfiles = []string{"1280x720"}
+	cfg.PartDuration = 500 * time.Millisecond
+	ft := NewFFmpegTranscoder(cfg, zap.NewNop())
+
+	// The fake ffmpeg rejects the forced-keyframe expression, so only the
+	// low-latency rung fails.
+	result, err := ft.TranscodeToHLSPartial(context.Background(), filepath.Join(cfg.TempDir, "input.mp4"), t.TempDir(), BuiltinLadder(), nil, nil)
+	require.NoError(t, err)
+	require.Len(t, result.Failed, 1)
+	assert.Equal(t, "1280x720", result.Failed[0].Profile.Resolution)
+
+	cfg = fakeFFmpeg(t, "no-such-filter")
+	cfg.LowLatencyProfiles = []string{"1280x720"}
+	ft = NewFFmpegTranscoder(cfg, zap.NewNop())
+	outputDir := t.TempDir()
+	require.NoError(t, ft.TranscodeToHLS(context.Background(), filepath.Join(cfg.TempDir, "input.mp4"), outputDir, BuiltinLadder(), nil, nil))
+
+	parts, err := filepath.Glob(filepath.Join(outputDir, "1280x720_*_p00000.ts"))
+	require.NoError(t, err)
+	assert.Len(t, parts, 1)
+	segments, err := filepath.Glob(filepath.Join(outputDir, "1920x1080_*_000.ts"))
+	require.NoError(t, err)
+	assert.Len(t, segments, 1)
+}
@@ -0,0 +1,229 @@
+package transcoder
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// HardwareAccel names a family of FFmpeg hardware encoders.
+type HardwareAccel string
+
+const (
+	HardwareNone  HardwareAccel = ""
+	HardwareNVENC HardwareAccel = "nvenc"
+	HardwareQSV   HardwareAccel = "qsv"
+	HardwareVAAPI HardwareAccel = "vaapi"
+	// HardwareAuto probes the families in hardwareProbeOrder and uses the
+	// first that works.
+	HardwareAuto HardwareAccel = "auto"
+)
+
+// DefaultVAAPIDevice is the render node VAAPI encodes on when none is
+// configured.
+const DefaultVAAPIDevice = "/dev/dri/renderD128"
+
+// hardwareProbeTimeout bounds each probe encode; a wedged driver must not
+// hold up startup.
+const hardwareProbeTimeout = 10 * time.Second
+
+var hardwareProbeOrder = []HardwareAccel{HardwareNVENC, HardwareQSV, HardwareVAAPI}
+
+// softwareEncoders are the encoders of each codec family used without
+// hardware, and as fallback when a hardware encode fails.
+var softwareEncoders = map[string]string{
+	CodecH264: "libx264",
+	CodecHEVC: "libx265",
+	CodecAV1:  "libsvtav1",
+}
+
+// HardwareAccelFromConfig maps transcoding.hardware to the accel to probe
+// for; "none" and unknown values disable hardware encoding.
+func HardwareAccelFromConfig(value string) HardwareAccel {
+	switch accel := HardwareAccel(value); accel {
+	case HardwareAuto, HardwareNVENC, HardwareQSV, HardwareVAAPI:
+		return accel
+	default:
+		return HardwareNone
+	}
+}
+
+// codecFamily returns the codec family (CodecH264, CodecHEVC or CodecAV1)
+// of a family name or encoder name, or "" for other codecs.
+func codecFamily(codec string) string {
+	switch {
+	case codec == CodecH264 || codec == "libx264" || strings.HasPrefix(codec, "h264_"):
+		return CodecH264
+	case codec == CodecHEVC || codec == "h265" || codec == "libx265" || strings.HasPrefix(codec, "hevc_"):
+		return CodecHEVC
+	case codec == CodecAV1 || codec == "libsvtav1" || codec == "libaom-av1" || strings.HasPrefix(codec, "av1_"):
+		return CodecAV1
+	default:
+		return ""
+	}
+}
+
+// encoderAccel returns the hardware family of an encoder name such as
+// "h264_nvenc", or HardwareNone for software encoders and family names.
+func encoderAccel(codec string) HardwareAccel {
+	_, suffix, _ := strings.Cut(codec, "_")
+	for _, accel := range hardwareProbeOrder {
+		if suffix == string(accel) {
+			return accel
+		}
+	}
+	return HardwareNone
+}
+
+// validCodec reports whether codec can be used as a profile codec override.
+func validCodec(codec string) bool {
+	if codecFamily(codec) == "" {
+		return false
+	}
+	return !strings.Contains(codec, "_") || encoderAccel(codec) != HardwareNone
+}
+
+// ProbeHardware picks the hardware encoder family rungs are encoded with.
+// It runs a one-frame test encode per candidate, which fails both when
+// FFmpeg lacks the encoder and when the device is missing or busy. It must
+// be called before transcoding starts; without it, or when no candidate
+// works, rungs are encoded in software.
+func (ft *FFmpegTranscoder) ProbeHardware(ctx context.Context) HardwareAccel {
+	ft.hwAccel = HardwareNone
+	if !ft.config.EnableHardware {
+		return HardwareNone
+	}
+
+	candidates := hardwareProbeOrder
+	if accel := ft.config.HardwareAccel; accel != HardwareNone && accel != HardwareAuto {
+		candidates = []HardwareAccel{accel}
+	}
+	for _, accel := range candidates {
+		if err := ft.probeEncoder(ctx, accel); err != nil {
+			ft.logger.Info("Hardware encoder unavailable",
+				zap.String("accel", string(accel)),
+				zap.Error(err))
+			continue
+		}
+		ft.hwAccel = accel
+		ft.logger.Info("Using hardware encoder", zap.String("accel", string(accel)))
+		return accel
+	}
+
+	ft.logger.Warn("No hardware encoder available, falling back to software encoding")
+	return HardwareNone
+}
+
+func (ft *FFmpegTranscoder) probeEncoder(ctx context.Context, accel HardwareAccel) error {
+	ctx, cancel := context.WithTimeout(ctx, hardwareProbeTimeout)
+	defer cancel()
+
+	args := append([]string{"-hide_banner"}, ft.hardwareInputArgs(accel)...)
+	args = append(args, "-f", "lavfi", "-i", "color=c=black:s=256x144:d=1", "-frames:v", "1")
+	if accel == HardwareVAAPI {
+		args = append(args, "-vf", "format=nv12,hwupload")
+	}
+	args = append(args, "-c:v", "h264_"+string(accel), "-f", "null", "-")
+
+	output, err := exec.CommandContext(ctx, ft.config.FFmpegPath, args...).CombinedOutput()
+	if err != nil {
+		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+		return fmt.Errorf("%w: %s", err, lines[len(lines)-1])
+	}
+	return nil
+}
+
+// videoEncoder returns the encoder for a rung and its hardware family. The
+// profile's codec, or the configured one, selects the codec; a family name
+// is encoded in hardware when a family was probed, and a hardware encoder
+// that was not probed falls back to the software encoder of its family.
+func (ft *FFmpegTranscoder) videoEncoder(profile TranscodeProfile) (string, HardwareAccel) {
+	codec := profile.Codec
+	if codec == "" {
+		codec = ft.config.VideoCodec
+	}
+	if codec == "" {
+		codec = CodecH264
+	}
+	family := codecFamily(codec)
+	if family == "" {
+		return codec, HardwareNone
+	}
+
+	if accel := encoderAccel(codec); accel != HardwareNone {
+		if accel == ft.hwAccel {
+			return codec, accel
+		}
+		return softwareEncoders[family], HardwareNone
+	}
+	if codec == family || codec == "h265" {
+		if ft.hwAccel != HardwareNone {
+			return family + "_" + string(ft.hwAccel), ft.hwAccel
+		}
+		return softwareEncoders[family], HardwareNone
+	}
+	return codec, HardwareNone
+}
+
+// hardwareInputArgs returns the global options an accel needs ahead of the
+// input.
+func (ft *FFmpegTranscoder) hardwareInputArgs(accel HardwareAccel) []string {
+	if accel != HardwareVAAPI {
+		return nil
+	}
+	device := ft.config.VAAPIDevice
+	if device == "" {
+		device = DefaultVAAPIDevice
+	}
+	return []string{"-vaapi_device", device}
+}
+
+// encoderOptions returns the speed and rate control options of an
+// encoder. Software x264/x265 keep the fast preset rungs have always used.
+func encoderOptions(codec string, accel HardwareAccel) []string {
+	switch accel {
+	case HardwareNVENC:
+		return []string{"-preset", "p4", "-rc", "vbr"}
+	case HardwareQSV:
+		return []string{"-preset", "veryfast"}
+	case HardwareVAAPI:
+		return []string{"-rc_mode", "VBR"}
+	}
+	if codecFamily(codec) == CodecAV1 {
+		// SVT-AV1 presets run 0 (slowest) to 13; 10 keeps AV1 rungs
+		// within a few times realtime.
+		return []string{"-preset", "10"}
+	}
+	return []string{"-preset", "ultrafast", "-crf", "28"}
+}
+
+// runEncode encodes one rung with outputArgs after the encoding options. A
+// failed hardware encode is retried once in software, so GPU session
+// limits, driver faults or a GPU without the codec degrade throughput
+// rather than fail the rung.
+func (ft *FFmpegTranscoder) runEncode(ctx context.Context, inputPath string, profile TranscodeProfile, info *VideoInfo, keyframeArgs, outputArgs []string, totalDuration time.Duration, callback ProgressCallback) error {
+	subtitles, err := burnInSubtitles(ctx, profile)
+	if err != nil {
+		return err
+	}
+	codec, accel := ft.videoEncoder(profile)
+	audioArgs := ft.rungAudioArgs(audioPlanFromContext(ctx), profile)
+	args := append(ft.encodeArgs(inputPath, codec, accel, profile, info, subtitles, keyframeArgs, audioArgs), outputArgs...)
+	err = ft.runFFmpeg(ctx, args, totalDuration, callback)
+	if err == nil || accel == HardwareNone || ctx.Err() != nil {
+		return err
+	}
+
+	software := softwareEncoders[codecFamily(codec)]
+	ft.logger.Warn("Hardware encode failed, retrying in software",
+		zap.String("resolution", profile.Resolution),
+		zap.String("encoder", codec),
+		zap.String("fallback", software),
+		zap.Error(err))
+	args = append(ft.encodeArgs(inputPath, software, HardwareNone, profile, info, subtitles, keyframeArgs, audioArgs), outputArgs...)
+	return ft.runFFmpeg(ctx, args, totalDuration, callback)
+}
@@ -0,0 +1,93 @@
+package transcoder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestProbeHardware(t *testing.T) {
+	dir := t.TempDir()
+	ffmpeg := `#!/bin/sh
+case "$*" in *h264_qsv*) exit 0 ;; esac
+echo "Unknown encoder" >&2
+exit 1
+`
+	cfg := &FFmpegConfig{FFmpegPath: filepath.Join(dir, "ffmpeg"), TempDir: dir}
+	require.NoError(t, os.WriteFile(cfg.FFmpegPath, []byte(ffmpeg), 0o755))
+	ft := NewFFmpegTranscoder(cfg, zap.NewNop())
+
+	assert.Equal(t, HardwareNone, ft.ProbeHardware(context.Background()), "hardware is off unless enabled")
+
+	cfg.EnableHardware = true
+	assert.Equal(t, HardwareQSV, ft.ProbeHardware(context.Background()))
+
+	cfg.HardwareAccel = HardwareNVENC
+	assert.Equal(t, HardwareNone, ft.ProbeHardware(context.Background()))
+	assert.Equal(t, HardwareNone, ft.hwAccel)
+}
+
+func TestVideoEncoder(t *testing.T) {
+	tests := []struct {
+		name      string
+		hwAccel   HardwareAccel
+		codec     string
+		wantCodec string
+		wantAccel HardwareAccel
+	}{
+		{"software default", HardwareNone, "", "libx264", HardwareNone},
+		{"hardware default", HardwareNVENC, "", "h264_nvenc", HardwareNVENC},
+		{"hevc family in hardware", HardwareQSV, "hevc", "hevc_qsv", HardwareQSV},
+		{"h265 alias in software", HardwareNone, "h265", "libx265", HardwareNone},
+		{"explicit software encoder", HardwareVAAPI, "libx264", "libx264", HardwareNone},
+		{"unprobed hardware encoder", HardwareNone, "hevc_nvenc", "libx265", HardwareNone},
+		{"probed hardware encoder", HardwareVAAPI, "h264_vaapi", "h264_vaapi", HardwareVAAPI},
+		{"other codec passes through", HardwareNVENC, "libvpx-vp9", "libvpx-vp9", HardwareNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ft := NewFFmpegTranscoder(&FFmpegConfig{}, zap.NewNop())
+			ft.hwAccel = tt.hwAccel
+			codec, accel := ft.videoEncoder(TranscodeProfile{Resolution: "1280x720", Codec: tt.codec})
+			assert.Equal(t, tt.wantCodec, codec)
+			assert.Equal(t, tt.wantAccel, accel)
+		})
+	}
+}
+
+func TestEncodeArgs_VAAPI(t *testing.T) {
+	ft := NewFFmpegTranscoder(&FFmpegConfig{VAAPIDevice: "/dev/dri/renderD129"}, zap.NewNop())
+	profile := TranscodeProfile{Resolution: "1280x720", Bitrate: "2500k"}
+
+	args := ft.encodeArgs("in.mp4", "h264_vaapi", HardwareVAAPI, profile, nil, "", nil, nil)
+	assert.Equal(t, []string{"-vaapi_device", "/dev/dri/renderD129", "-noautorotate"}, args[:3])
+	assert.Contains(t, args, "scale=1280:720,setsar=1,format=nv12,hwupload")
+	assert.NotContains(t, args, "-crf")
+}
+
+func TestTranscodeToHLS_HardwareFallback(t *testing.T) {
+	cfg := fakeFFmpeg(t, "h264_nvenc")
+	ft := NewFFmpegTranscoder(cfg, zap.NewNop())
+	ft.hwAccel = HardwareNVENC
+	outputDir := t.TempDir()
+
+	profiles := []TranscodeProfile{{Resolution: "1280x720", Bitrate: "2500k", Format: "hls"}}
+	require.NoError(t, ft.TranscodeToHLS(context.Background(), filepath.Join(cfg.TempDir, "input.mp4"), outputDir, profiles, nil, nil))
+	assert.FileExists(t, filepath.Join(outputDir, "1280x720.m3u8"))
+}
+
+func TestValidateLadder_Codec(t *testing.T) {
+	ladder := []TranscodeProfile{{Resolution: "1920x1080", Bitrate: "5000k", Format: "hls", Codec: "vp9"}}
+	assert.ErrorIs(t, ValidateLadder(ladder), ErrInvalidLadder)
+	ladder[0].Codec = "h264_cuda"
+	assert.ErrorIs(t, ValidateLadder(ladder), ErrInvalidLadder)
+	for _, codec := range []string{"h264", "hevc", "libx265", "hevc_nvenc"} {
+		ladder[0].Codec = codec
+		assert.NoError(t, ValidateLadder(ladder), codec)
+	}
+}
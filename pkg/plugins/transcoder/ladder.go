@@ -0,0 +1,182 @@
+package transcoder
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+)
+
+const (
+	minLadderBitrateKbps = 100
+	maxLadderBitrateKbps = 100000
+)
+
+// ErrInvalidLadder is returned when a profile ladder fails validation.
+var ErrInvalidLadder = errors.New("invalid profile ladder")
+
+// BuiltinLadder returns the ladder used when none is configured.
+func BuiltinLadder() []TranscodeProfile {
+	ladder := make([]TranscodeProfile, 0, 4)
+	for _, name := range []string{"1080p", "720p", "480p", "360p"} {
+		ladder = append(ladder, defaultProfileMap[name])
+	}
+	return ladder
+}
+
+// builtinCodecBitrates are the bitrates of the built-in HEVC and AV1
+// presets, per BuiltinLadder rung; both codecs need roughly 60% and 50% of
+// the H.264 bitrate for the same quality.
+var builtinCodecBitrates = map[string][]string{
+	CodecHEVC: {"3000k", "1500k", "600k", "300k"},
+	CodecAV1:  {"2500k", "1250k", "500k", "250k"},
+}
+
+// BuiltinCodecLadder returns the built-in ladder preset of codec, or nil
+// for codecs without one.
+func BuiltinCodecLadder(codec string) []TranscodeProfile {
+	bitrates, ok := builtinCodecBitrates[codec]
+	if !ok {
+		return nil
+	}
+	ladder := BuiltinLadder()
+	for i := range ladder {
+		ladder[i].Bitrate = bitrates[i]
+		ladder[i].Codec = codec
+	}
+	return ladder
+}
+
+// CodecLaddersFromConfig returns the default ladder of every configured
+// codec, each ordered highest rung first: Qualities (or BuiltinLadder) for
+// H.264, and for HEVC and AV1 their configured ladder or built-in preset.
+// It returns nil when neither codecs nor qualities are configured.
+func CodecLaddersFromConfig(cfg config.TranscodingConfig) []TranscodeProfile {
+	h264 := LadderFromConfig(cfg.Qualities)
+	if len(cfg.Codecs) == 0 {
+		return h264
+	}
+	var ladder []TranscodeProfile
+	for _, codec := range cfg.Codecs {
+		if codec == CodecH264 {
+			if h264 == nil {
+				h264 = BuiltinLadder()
+			}
+			ladder = append(ladder, h264...)
+			continue
+		}
+		rungs := LadderFromConfig(cfg.CodecLadders[codec])
+		if rungs == nil {
+			rungs = BuiltinCodecLadder(codec)
+		}
+		for i := range rungs {
+			// A rung may name a specific encoder of the codec.
+			if codecFamily(rungs[i].Codec) != codec {
+				rungs[i].Codec = codec
+			}
+		}
+		ladder = append(ladder, rungs...)
+	}
+	return ladder
+}
+
+// LadderFromConfig converts configured transcoding qualities into profiles.
+// It returns nil when no qualities are configured.
+func LadderFromConfig(qualities []config.QualityConfig) []TranscodeProfile {
+	if len(qualities) == 0 {
+		return nil
+	}
+	ladder := make([]TranscodeProfile, 0, len(qualities))
+	for _, q := range qualities {
+		ladder = append(ladder, TranscodeProfile{
+			Resolution:    fmt.Sprintf("%dx%d", q.Width, q.Height),
+			Bitrate:       fmt.Sprintf("%dk", q.Bitrate/1000),
+			Format:        "hls",
+			DRM:           DRMScheme(q.DRM),
+			Codec:         q.Codec,
+			BurnSubtitles: q.BurnSubtitles,
+		})
+	}
+	return ladder
+}
+
+// LowLatencyFromConfig returns the resolutions of the configured rungs
+// marked low_latency.
+func LowLatencyFromConfig(qualities []config.QualityConfig) []string {
+	var resolutions []string
+	for _, q := range qualities {
+		if q.LowLatency {
+			resolutions = append(resolutions, fmt.Sprintf("%dx%d", q.Width, q.Height))
+		}
+	}
+	return resolutions
+}
+
+// ValidateLadder checks that every rung has even, positive dimensions and a
+// bitrate within sane bounds, and that the rungs of each codec are ordered
+// from highest to lowest resolution with non-increasing bitrates.
+func ValidateLadder(ladder []TranscodeProfile) error {
+	if len(ladder) == 0 {
+		return fmt.Errorf("%w: no rungs", ErrInvalidLadder)
+	}
+	type rung struct{ height, kbps int }
+	prev := make(map[string]rung)
+	for i, p := range ladder {
+		w, h, ok := parseRatio(p.Resolution, "x")
+		if !ok {
+			return fmt.Errorf("%w: rung %d: malformed resolution %q", ErrInvalidLadder, i, p.Resolution)
+		}
+		if w%2 != 0 || h%2 != 0 {
+			return fmt.Errorf("%w: rung %d: resolution %s has odd dimensions", ErrInvalidLadder, i, p.Resolution)
+		}
+		kbps := parseBitrate(p.Bitrate)
+		if kbps < minLadderBitrateKbps || kbps > maxLadderBitrateKbps {
+			return fmt.Errorf("%w: rung %d: bitrate %q outside %d-%dk", ErrInvalidLadder, i, p.Bitrate, minLadderBitrateKbps, maxLadderBitrateKbps)
+		}
+		if p.Format != "" && p.Format != "hls" {
+			return fmt.Errorf("%w: rung %d: unsupported format %q", ErrInvalidLadder, i, p.Format)
+		}
+		if p.DRM != "" && !p.DRM.Valid() {
+			return fmt.Errorf("%w: rung %d: unsupported DRM scheme %q", ErrInvalidLadder, i, p.DRM)
+		}
+		if p.Codec != "" && !validCodec(p.Codec) {
+			return fmt.Errorf("%w: rung %d: unsupported codec %q", ErrInvalidLadder, i, p.Codec)
+		}
+		_, codec := ParseVariantName(p.Name())
+		if above, ok := prev[codec]; ok {
+			if h >= above.height {
+				return fmt.Errorf("%w: rung %d: %s must be lower than the %s rung above it", ErrInvalidLadder, i, p.Resolution, codec)
+			}
+			if kbps > above.kbps {
+				return fmt.Errorf("%w: rung %d: bitrate %s exceeds the %s rung above it", ErrInvalidLadder, i, p.Bitrate, codec)
+			}
+		}
+		prev[codec] = rung{height: h, kbps: kbps}
+	}
+	return nil
+}
+
+// PerTitleFromConfig maps transcoding.per_title to the analysis bounds, or
+// nil when per-title encoding is disabled.
+func PerTitleFromConfig(cfg config.PerTitleConfig) (*PerTitleConfig, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	perTitle := &PerTitleConfig{
+		TargetVMAF: cfg.TargetVMAF,
+		MinBitrate: cfg.MinBitrate / 1000,
+		MaxBitrate: cfg.MaxBitrate / 1000,
+		MinRungs:   cfg.MinRungs,
+		MaxRungs:   cfg.MaxRungs,
+		Samples:    cfg.Samples,
+	}
+	if cfg.SampleDuration != "" {
+		d, err := time.ParseDuration(cfg.SampleDuration)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("transcoding.per_title.sample_duration: invalid duration %q", cfg.SampleDuration)
+		}
+		perTitle.SampleDuration = d
+	}
+	return perTitle, nil
+}
@@ -0,0 +1,78 @@
+package transcoder
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateLadder(t *testing.T) {
+	tests := []struct {
+		name   string
+		ladder []TranscodeProfile
+		ok     bool
+	}{
+		{"builtin", BuiltinLadder(), true},
+		{"single rung", []TranscodeProfile{{Resolution: "1280x720", Bitrate: "2500k"}}, true},
+		{"empty", nil, false},
+		{"odd width", []TranscodeProfile{{Resolution: "853x480", Bitrate: "1000k"}}, false},
+		{"odd height", []TranscodeProfile{{Resolution: "1280x721", Bitrate: "2500k"}}, false},
+		{"malformed resolution", []TranscodeProfile{{Resolution: "720p", Bitrate: "2500k"}}, false},
+		{"bitrate too low", []TranscodeProfile{{Resolution: "640x360", Bitrate: "10k"}}, false},
+		{"bitrate too high", []TranscodeProfile{{Resolution: "1920x1080", Bitrate: "500m"}}, false},
+		{"unparseable bitrate", []TranscodeProfile{{Resolution: "1920x1080", Bitrate: "fast"}}, false},
+		{"unsupported format", []TranscodeProfile{{Resolution: "1920x1080", Bitrate: "5000k", Format: "mp4"}}, false},
+		{"ascending rungs", []TranscodeProfile{
+			{Resolution: "640x360", Bitrate: "500k"},
+			{Resolution: "1280x720", Bitrate: "2500k"},
+		}, false},
+		{"duplicate rung", []TranscodeProfile{
+			{Resolution: "1280x720", Bitrate: "2500k"},
+			{Resolution: "1280x720", Bitrate: "2000k"},
+		}, false},
+		{"lower rung with higher bitrate", []TranscodeProfile{
+			{Resolution: "1280x720", Bitrate: "2500k"},
+			{Resolution: "854x480", Bitrate: "3000k"},
+		}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateLadder(tc.ladder)
+			if tc.ok {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, ErrInvalidLadder), "got %v", err)
+			}
+		})
+	}
+}
+
+func TestLadderFromConfig(t *testing.T) {
+	assert.Nil(t, LadderFromConfig(nil))
+
+	ladder := LadderFromConfig([]config.QualityConfig{
+		{Name: "720p", Width: 1280, Height: 720, Bitrate: 2500000},
+		{Name: "360p", Width: 640, Height: 360, Bitrate: 600000},
+	})
+	require.Len(t, ladder, 2)
+	assert.Equal(t, TranscodeProfile{Resolution: "1280x720", Bitrate: "2500k", Format: "hls"}, ladder[0])
+	assert.Equal(t, TranscodeProfile{Resolution: "640x360", Bitrate: "600k", Format: "hls"}, ladder[1])
+	assert.NoError(t, ValidateLadder(ladder))
+
+	ladder = LadderFromConfig([]config.QualityConfig{
+		{Name: "720p", Width: 1280, Height: 720, Bitrate: 2500000, BurnSubtitles: "eng"},
+	})
+	assert.Equal(t, "eng", ladder[0].BurnSubtitles)
+}
+
+func TestLowLatencyFromConfig(t *testing.T) {
+	assert.Nil(t, LowLatencyFromConfig(nil))
+	assert.Equal(t, []string{"1280x720"}, LowLatencyFromConfig([]config.QualityConfig{
+		{Name: "720p", Width: 1280, Height: 720, Bitrate: 2500000, LowLatency: true},
+		{Name: "360p", Width: 640, Height: 360, Bitrate: 600000},
+	}))
+}
@@ -0,0 +1,265 @@
+package transcoder
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Per-title analysis defaults, used for zero PerTitleConfig fields.
+const (
+	DefaultTargetVMAF       = 93.0
+	DefaultPerTitleMinKbps  = 200
+	DefaultPerTitleMaxKbps  = 8000
+	DefaultPerTitleMinRungs = 2
+	DefaultPerTitleMaxRungs = 6
+	DefaultSampleCount      = 3
+	DefaultSampleDuration   = 4 * time.Second
+)
+
+// vmafPerDoubling is how many VMAF points doubling a rung's bitrate gains
+// around the target. It turns one measurement per rung into a bitrate
+// estimate instead of searching for it with repeated encodes.
+const vmafPerDoubling = 12.0
+
+// redundantRungRatio drops a rung whose derived bitrate is above this share
+// of the rung above it: a smaller picture at nearly the same bitrate only
+// looks worse.
+const redundantRungRatio = 0.85
+
+// PerTitleConfig bounds the ladders per-title analysis derives from a
+// source. Zero fields take the Default* values.
+type PerTitleConfig struct {
+	// TargetVMAF is the quality each rung is sized to reach on the samples.
+	TargetVMAF float64
+	// MinBitrate and MaxBitrate bound every derived rung, in kbps.
+	MinBitrate int
+	MaxBitrate int
+	// MinRungs is the number of rungs per codec redundant rungs are kept
+	// to; MaxRungs caps the rungs per codec.
+	MinRungs int
+	MaxRungs int
+	// Samples clips of SampleDuration, spread evenly over the source, are
+	// encoded and scored per rung.
+	Samples        int
+	SampleDuration time.Duration
+}
+
+func (c PerTitleConfig) withDefaults() PerTitleConfig {
+	if c.TargetVMAF <= 0 {
+		c.TargetVMAF = DefaultTargetVMAF
+	}
+	if c.MinBitrate <= 0 {
+		c.MinBitrate = DefaultPerTitleMinKbps
+	}
+	if c.MaxBitrate <= 0 {
+		c.MaxBitrate = DefaultPerTitleMaxKbps
+	}
+	if c.MinRungs <= 0 {
+		c.MinRungs = DefaultPerTitleMinRungs
+	}
+	if c.MaxRungs <= 0 {
+		c.MaxRungs = DefaultPerTitleMaxRungs
+	}
+	if c.Samples <= 0 {
+		c.Samples = DefaultSampleCount
+	}
+	if c.SampleDuration <= 0 {
+		c.SampleDuration = DefaultSampleDuration
+	}
+	return c
+}
+
+var vmafScoreRegex = regexp.MustCompile(`VMAF score[:=] *([0-9.]+)`)
+
+// PerTitleLadder derives a ladder for one source from ladder. Rungs above
+// the source's resolution are dropped, and each remaining rung is encoded
+// on a few samples at its bitrate and scored with VMAF against the source;
+// the score's distance from the target rescales the bitrate. Rungs that end
+// up redundant are dropped, keeping at least MinRungs and at most MaxRungs
+// per codec. Codec, DRM and format of the rungs are kept.
+func (ft *FFmpegTranscoder) PerTitleLadder(ctx context.Context, inputPath string, ladder []TranscodeProfile, cfg PerTitleConfig) ([]TranscodeProfile, error) {
+	cfg = cfg.withDefaults()
+	info, err := ft.GetVideoInfo(ctx, inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to probe source: %w", err)
+	}
+
+	workDir, err := os.MkdirTemp(ft.config.TempDir, "pertitle-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sample directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	samples := sampleWindows(info.Duration, cfg)
+	var derived []TranscodeProfile
+	for _, group := range codecGroups(ladder) {
+		rungs := sourceRungs(group, info.ShortSide())
+		for i := range rungs {
+			score, err := ft.sampleVMAF(ctx, inputPath, workDir, rungs[i], info, samples)
+			if err != nil {
+				return nil, fmt.Errorf("rung %s: %w", rungs[i].Name(), err)
+			}
+			kbps := perTitleBitrate(parseBitrate(rungs[i].Bitrate), score, info, cfg)
+			ft.logger.Debug("Per-title rung analysed",
+				zap.String("variant", rungs[i].Name()),
+				zap.Float64("vmaf", score),
+				zap.String("bitrate", rungs[i].Bitrate),
+				zap.Int("derived_kbps", kbps))
+			rungs[i].Bitrate = fmt.Sprintf("%dk", kbps)
+		}
+		derived = append(derived, pruneRungs(rungs, cfg)...)
+	}
+
+	if err := ValidateLadder(derived); err != nil {
+		return nil, err
+	}
+	return derived, nil
+}
+
+// codecGroups splits a ladder into the rungs of each codec, in order.
+func codecGroups(ladder []TranscodeProfile) [][]TranscodeProfile {
+	var groups [][]TranscodeProfile
+	index := make(map[string]int)
+	for _, p := range ladder {
+		_, codec := ParseVariantName(p.Name())
+		i, ok := index[codec]
+		if !ok {
+			i = len(groups)
+			index[codec] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], p)
+	}
+	return groups
+}
+
+// sourceRungs returns the rungs no taller than the source, or the lowest
+// rung when the source is smaller than all of them.
+func sourceRungs(rungs []TranscodeProfile, sourceHeight int) []TranscodeProfile {
+	var kept []TranscodeProfile
+	for _, p := range rungs {
+		if sourceHeight <= 0 || parseProfileHeight(p.Resolution) <= sourceHeight {
+			kept = append(kept, p)
+		}
+	}
+	if len(kept) == 0 && len(rungs) > 0 {
+		kept = append(kept, rungs[len(rungs)-1])
+	}
+	return kept
+}
+
+// sampleWindow is one sampled clip of the source.
+type sampleWindow struct {
+	start, duration time.Duration
+}
+
+// sampleWindows spreads cfg.Samples clips evenly over the source, centred
+// in equal slices so openings and credits weigh no more than the rest. A
+// source too short for them is sampled whole.
+func sampleWindows(durationSeconds float64, cfg PerTitleConfig) []sampleWindow {
+	total := time.Duration(durationSeconds * float64(time.Second))
+	if total <= time.Duration(cfg.Samples)*cfg.SampleDuration {
+		return []sampleWindow{{duration: total}}
+	}
+	slice := total / time.Duration(cfg.Samples)
+	windows := make([]sampleWindow, 0, cfg.Samples)
+	for i := 0; i < cfg.Samples; i++ {
+		start := time.Duration(i)*slice + (slice-cfg.SampleDuration)/2
+		windows = append(windows, sampleWindow{start: start, duration: cfg.SampleDuration})
+	}
+	return windows
+}
+
+// perTitleBitrate rescales a rung's bitrate by the VMAF it scored, rounded
+// to 50k and bounded by cfg and, when known, the source's own bitrate.
+func perTitleBitrate(kbps int, score float64, info *VideoInfo, cfg PerTitleConfig) int {
+	scaled := float64(kbps) * math.Exp2((cfg.TargetVMAF-score)/vmafPerDoubling)
+	derived := int(math.Round(scaled/50)) * 50
+	if info != nil && info.VideoBitrate > 0 {
+		derived = min(derived, info.VideoBitrate/1000)
+	}
+	return max(cfg.MinBitrate, min(derived, cfg.MaxBitrate))
+}
+
+// pruneRungs makes one codec's rungs non-increasing in bitrate, drops
+// redundant rungs while more than MinRungs remain, and caps them at
+// MaxRungs, keeping the top rungs and the lowest.
+func pruneRungs(rungs []TranscodeProfile, cfg PerTitleConfig) []TranscodeProfile {
+	kept := make([]TranscodeProfile, 0, len(rungs))
+	for i, p := range rungs {
+		if len(kept) > 0 {
+			above := parseBitrate(kept[len(kept)-1].Bitrate)
+			kbps := parseBitrate(p.Bitrate)
+			remaining := len(rungs) - i
+			if float64(kbps) > redundantRungRatio*float64(above) && len(kept)+remaining > cfg.MinRungs {
+				continue
+			}
+			if kbps > above {
+				p.Bitrate = kept[len(kept)-1].Bitrate
+			}
+		}
+		kept = append(kept, p)
+	}
+	if len(kept) > cfg.MaxRungs {
+		kept = append(kept[:cfg.MaxRungs-1], kept[len(kept)-1])
+	}
+	return kept
+}
+
+// sampleVMAF encodes each sample as profile would be and returns the mean
+// VMAF of the encodes against the source scaled the same way.
+func (ft *FFmpegTranscoder) sampleVMAF(ctx context.Context, inputPath, workDir string, profile TranscodeProfile, info *VideoInfo, samples []sampleWindow) (float64, error) {
+	codec, accel := ft.videoEncoder(profile)
+	var total float64
+	for i, s := range samples {
+		window := []string{"-ss", formatSeconds(s.start), "-t", formatSeconds(s.duration)}
+		samplePath := filepath.Join(workDir, fmt.Sprintf("%s_%d.mp4", profile.Name(), i))
+
+		args := append(window, ft.encodeArgs(inputPath, codec, accel, profile, info, "", nil, nil)...)
+		args = append(args, "-an", "-y", samplePath)
+		if err := ft.runFFmpeg(ctx, args, 0, nil); err != nil {
+			return 0, fmt.Errorf("sample encode failed: %w", err)
+		}
+
+		score, err := ft.measureVMAF(ctx, samplePath, inputPath, window, hlsVideoFilter(profile, info))
+		if err != nil {
+			return 0, err
+		}
+		total += score
+	}
+	return total / float64(len(samples)), nil
+}
+
+// measureVMAF scores an encoded sample against the same window of the
+// source, scaled with the rung's filter.
+func (ft *FFmpegTranscoder) measureVMAF(ctx context.Context, samplePath, inputPath string, window []string, filter string) (float64, error) {
+	args := []string{"-hide_banner", "-i", samplePath, "-noautorotate"}
+	args = append(args, window...)
+	args = append(args,
+		"-i", inputPath,
+		"-lavfi", "[0:v]setpts=PTS-STARTPTS[dist];[1:v]"+filter+",setpts=PTS-STARTPTS[ref];[dist][ref]libvmaf",
+		"-f", "null", "-",
+	)
+	output, err := exec.CommandContext(ctx, ft.config.FFmpegPath, args...).CombinedOutput()
+	if err != nil {
+		return 0, fmt.Errorf("VMAF measurement failed: %w", err)
+	}
+	match := vmafScoreRegex.FindSubmatch(output)
+	if match == nil {
+		return 0, fmt.Errorf("VMAF measurement reported no score")
+	}
+	return strconv.ParseFloat(string(match[1]), 64)
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
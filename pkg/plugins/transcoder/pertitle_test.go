@@ -0,0 +1,126 @@
+package transcoder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// vmafFFmpeg fakes an FFmpeg whose VMAF runs score the 1080p rung 99 and
+// every other rung 93, and whose sample encodes write their output.
+const vmafFFmpeg = `#!/bin/sh
+case "$*" in
+  *scale=1920:1080*libvmaf*) echo "VMAF score: 99.000000" >&2 ;;
+  *libvmaf*) echo "VMAF score: 93.000000" >&2 ;;
+  *) for arg in "$@"; do last="$arg"; done; touch "$last" ;;
+esac
+`
+
+func TestPerTitleLadder(t *testing.T) {
+	cfg := fakeFFmpeg(t, "no-such-filter")
+	require.NoError(t, os.WriteFile(cfg.FFmpegPath, []byte(vmafFFmpeg), 0o755))
+	ft := NewFFmpegTranscoder(cfg, zap.NewNop())
+
+	ladder, err := ft.PerTitleLadder(context.Background(), filepath.Join(cfg.TempDir, "input.mp4"), BuiltinLadder(), PerTitleConfig{})
+	require.NoError(t, err)
+	require.Len(t, ladder, 4)
+	assert.Equal(t, "3550k", ladder[0].Bitrate, "a rung above the target VMAF is sized down")
+	assert.Equal(t, "2500k", ladder[1].Bitrate, "a rung at the target keeps its bitrate")
+	assert.Equal(t, "1920x1080", ladder[0].Resolution)
+
+	entries, err := os.ReadDir(cfg.TempDir)
+	require.NoError(t, err)
+	for _, e := range entries {
+		assert.NotContains(t, e.Name(), "pertitle-", "samples are removed")
+	}
+}
+
+func TestPerTitleLadder_VMAFUnavailable(t *testing.T) {
+	cfg := fakeFFmpeg(t, "no-such-filter")
+	ffmpeg := `#!/bin/sh
+case "$*" in
+  *libvmaf*) echo "No such filter: 'libvmaf'" >&2; exit 1 ;;
+  *) for arg in "$@"; do last="$arg"; done; touch "$last" ;;
+esac
+`
+	require.NoError(t, os.WriteFile(cfg.FFmpegPath, []byte(ffmpeg), 0o755))
+	ft := NewFFmpegTranscoder(cfg, zap.NewNop())
+
+	_, err := ft.PerTitleLadder(context.Background(), filepath.Join(cfg.TempDir, "input.mp4"), BuiltinLadder(), PerTitleConfig{})
+	assert.ErrorContains(t, err, "VMAF measurement failed")
+}
+
+func TestSourceRungs(t *testing.T) {
+	rungs := sourceRungs(BuiltinLadder(), 720)
+	require.Len(t, rungs, 3)
+	assert.Equal(t, "1280x720", rungs[0].Resolution)
+
+	rungs = sourceRungs(BuiltinLadder(), 240)
+	require.Len(t, rungs, 1)
+	assert.Equal(t, "640x360", rungs[0].Resolution, "a tiny source keeps the lowest rung")
+}
+
+func TestSampleWindows(t *testing.T) {
+	cfg := PerTitleConfig{}.withDefaults()
+	assert.Equal(t, []sampleWindow{{duration: 10 * time.Second}}, sampleWindows(10, cfg))
+
+	windows := sampleWindows(60, cfg)
+	require.Len(t, windows, 3)
+	assert.Equal(t, sampleWindow{start: 8 * time.Second, duration: 4 * time.Second}, windows[0])
+	assert.Equal(t, sampleWindow{start: 48 * time.Second, duration: 4 * time.Second}, windows[2])
+}
+
+func TestPerTitleBitrate(t *testing.T) {
+	cfg := PerTitleConfig{}.withDefaults()
+	assert.Equal(t, 2500, perTitleBitrate(2500, 93, nil, cfg))
+	assert.Equal(t, 5000, perTitleBitrate(2500, 81, nil, cfg), "one doubling below the target")
+	assert.Equal(t, 8000, perTitleBitrate(5000, 70, nil, cfg), "capped at MaxBitrate")
+	assert.Equal(t, 200, perTitleBitrate(250, 100, nil, cfg), "floored at MinBitrate")
+	assert.Equal(t, 1800, perTitleBitrate(2500, 81, &VideoInfo{VideoBitrate: 1800000}, cfg), "capped at the source bitrate")
+}
+
+func TestPruneRungs(t *testing.T) {
+	rungs := []TranscodeProfile{
+		{Resolution: "1920x1080", Bitrate: "2000k"},
+		{Resolution: "1280x720", Bitrate: "1900k"},
+		{Resolution: "854x480", Bitrate: "1000k"},
+		{Resolution: "640x360", Bitrate: "500k"},
+	}
+	cfg := PerTitleConfig{}.withDefaults()
+
+	pruned := pruneRungs(rungs, cfg)
+	require.Len(t, pruned, 3)
+	assert.Equal(t, "854x480", pruned[1].Resolution, "the 720p rung adds nothing over 1080p")
+
+	cfg.MinRungs = 4
+	pruned = pruneRungs(rungs, cfg)
+	require.Len(t, pruned, 4)
+	assert.Equal(t, "1900k", pruned[1].Bitrate)
+
+	cfg.MinRungs, cfg.MaxRungs = 1, 2
+	pruned = pruneRungs(rungs, cfg)
+	require.Len(t, pruned, 2)
+	assert.Equal(t, "640x360", pruned[1].Resolution, "the lowest rung is kept")
+}
+
+func TestPerTitleFromConfig(t *testing.T) {
+	perTitle, err := PerTitleFromConfig(config.PerTitleConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, perTitle)
+
+	perTitle, err = PerTitleFromConfig(config.PerTitleConfig{Enabled: true, MinBitrate: 300000, MaxBitrate: 6000000, SampleDuration: "2s"})
+	require.NoError(t, err)
+	assert.Equal(t, 300, perTitle.MinBitrate)
+	assert.Equal(t, 6000, perTitle.MaxBitrate)
+	assert.Equal(t, 2*time.Second, perTitle.SampleDuration)
+
+	_, err = PerTitleFromConfig(config.PerTitleConfig{Enabled: true, SampleDuration: "soon"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,296 @@
+package transcoder
+
+import (
+	"container/heap"
+	"sync/atomic"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/monitoring"
+
+	"go.uber.org/zap"
+)
+
+// DefaultQueueMaxWait is how long a task may wait before it is dispatched
+// ahead of higher priority work, when TranscoderConfig.QueueMaxWait is zero.
+const DefaultQueueMaxWait = 10 * time.Minute
+
+// PriorityClass groups task priorities for fair scheduling and metrics.
+type PriorityClass string
+
+const (
+	PriorityClassHigh   PriorityClass = "high"
+	PriorityClassNormal PriorityClass = "normal"
+	PriorityClassLow    PriorityClass = "low"
+)
+
+// priorityClasses lists the classes in dispatch order, and classWeights
+// how many tasks each dispatches per round while the others have work, so
+// a steady stream of high priority tasks slows lower classes down but
+// never stops them.
+var (
+	priorityClasses = [...]PriorityClass{PriorityClassHigh, PriorityClassNormal, PriorityClassLow}
+	classWeights    = [len(priorityClasses)]int{4, 2, 1}
+)
+
+// ClassOf returns the class of a priority on the 0-10 scale submissions
+// use: 7 and above is high, 1 to 3 is low, and the rest, including the
+// unset 0, is normal.
+func ClassOf(priority int) PriorityClass {
+	return priorityClasses[classIndex(priority)]
+}
+
+func classIndex(priority int) int {
+	switch {
+	case priority >= 7:
+		return 0
+	case priority >= 1 && priority <= 3:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// WaitStats summarises how long the dequeued tasks of a priority class
+// waited for a worker.
+type WaitStats struct {
+	Count int64
+	Total time.Duration
+	Max   time.Duration
+	// Starved counts tasks dispatched out of turn because they waited
+	// longer than the queue's maximum wait.
+	Starved int64
+}
+
+// Average returns the mean wait.
+func (s WaitStats) Average() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+// queuedTask is a task waiting in its class's heap.
+type queuedTask struct {
+	task       *TranscodeTask
+	class      int
+	seq        uint64
+	enqueuedAt time.Time
+	index      int
+}
+
+// taskHeap orders the tasks of a class by priority, then arrival.
+type taskHeap []*queuedTask
+
+func (h taskHeap) Len() int { return len(h) }
+
+func (h taskHeap) Less(i, j int) bool {
+	if h[i].task.Priority != h[j].task.Priority {
+		return h[i].task.Priority > h[j].task.Priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h taskHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *taskHeap) Push(x interface{}) {
+	item := x.(*queuedTask)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *taskHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
+
+// TaskQueueOption configures a TaskQueue.
+type TaskQueueOption func(*TaskQueue)
+
+// WithTaskStore persists the queue's tasks in store, leased to owner for
+// visibilityTimeout at a time, DefaultVisibilityTimeout when zero. Owner
+// should be stable across restarts so a restarted transcoder reclaims its
+// own tasks without waiting for their leases to run out.
+func WithTaskStore(store TaskStore, owner string, visibilityTimeout time.Duration) TaskQueueOption {
+	return func(tq *TaskQueue) {
+		if visibilityTimeout <= 0 {
+			visibilityTimeout = DefaultVisibilityTimeout
+		}
+		tq.store = store
+		tq.owner = owner
+		tq.visibility = visibilityTimeout
+	}
+}
+
+// WithSharedQueue makes the queue one of several transcoders sharing its
+// task store: submissions are only stored, and the queue claims stored
+// tasks, highest priority first, while it holds fewer than slots, normally
+// its worker count. It needs WithTaskStore.
+func WithSharedQueue(slots int) TaskQueueOption {
+	return func(tq *TaskQueue) {
+		if slots < 1 {
+			slots = 1
+		}
+		tq.shared = true
+		tq.slots = slots
+	}
+}
+
+// WithQueueLogger sets the logger store failures are reported to.
+func WithQueueLogger(logger *zap.Logger) TaskQueueOption {
+	return func(tq *TaskQueue) { tq.logger = logger }
+}
+
+// NewTaskQueue creates a task queue holding up to maxSize pending tasks.
+// Tasks waiting longer than maxWait, DefaultQueueMaxWait when zero, are
+// dispatched before any other.
+func NewTaskQueue(maxSize int, maxWait time.Duration, opts ...TaskQueueOption) *TaskQueue {
+	if maxWait <= 0 {
+		maxWait = DefaultQueueMaxWait
+	}
+	tq := &TaskQueue{
+		tasks:    make(map[string]*TranscodeTask),
+		queued:   make(map[string]*queuedTask),
+		ready:    make(chan struct{}, 1),
+		maxSize:  maxSize,
+		maxWait:  maxWait,
+		metrics:  &QueueMetrics{WaitTime: make(map[PriorityClass]*WaitStats)},
+		held:     make(map[string]struct{}),
+		claimNow: make(chan struct{}, 1),
+		logger:   zap.NewNop(),
+	}
+	for _, opt := range opts {
+		opt(tq)
+	}
+	return tq
+}
+
+// push adds task to its class's heap. Callers hold tq.mu.
+func (tq *TaskQueue) push(task *TranscodeTask, now time.Time) {
+	tq.removeQueued(task.ID)
+	tq.seq++
+	item := &queuedTask{task: task, class: classIndex(task.Priority), seq: tq.seq, enqueuedAt: now}
+	heap.Push(&tq.classes[item.class], item)
+	tq.queued[task.ID] = item
+}
+
+// requeue puts back a task Dequeue returned but that was not run, so it is
+// dispatched again.
+func (tq *TaskQueue) requeue(task *TranscodeTask) {
+	tq.mu.Lock()
+	tq.push(task, time.Now())
+	tq.mu.Unlock()
+	tq.signal()
+}
+
+// pop removes the next task to dispatch, or returns nil when none is
+// queued. Callers hold tq.mu.
+func (tq *TaskQueue) pop(now time.Time) *TranscodeTask {
+	var oldest *queuedTask
+	for _, h := range tq.classes {
+		for _, item := range h {
+			if oldest == nil || item.seq < oldest.seq {
+				oldest = item
+			}
+		}
+	}
+	if oldest == nil {
+		return nil
+	}
+
+	item, starved := oldest, now.Sub(oldest.enqueuedAt) >= tq.maxWait
+	if starved {
+		heap.Remove(&tq.classes[item.class], item.index)
+	} else {
+		class := tq.nextClass()
+		item = heap.Pop(&tq.classes[class]).(*queuedTask)
+		tq.credits[class]--
+	}
+	delete(tq.queued, item.task.ID)
+	tq.recordWait(item, now.Sub(item.enqueuedAt), starved)
+	return item.task
+}
+
+// nextClass picks the class to dispatch from by weighted round robin:
+// the highest class with queued tasks and credit left this round, with
+// credits refilled once every class with tasks has spent its own.
+func (tq *TaskQueue) nextClass() int {
+	for {
+		for i := range tq.classes {
+			if len(tq.classes[i]) > 0 && tq.credits[i] > 0 {
+				return i
+			}
+		}
+		tq.credits = classWeights
+	}
+}
+
+// removeQueued drops a pending task from its heap. Callers hold tq.mu.
+func (tq *TaskQueue) removeQueued(taskID string) {
+	if item, ok := tq.queued[taskID]; ok {
+		heap.Remove(&tq.classes[item.class], item.index)
+		delete(tq.queued, taskID)
+	}
+}
+
+func (tq *TaskQueue) recordWait(item *queuedTask, wait time.Duration, starved bool) {
+	class := priorityClasses[item.class]
+	stats, ok := tq.metrics.WaitTime[class]
+	if !ok {
+		stats = &WaitStats{}
+		tq.metrics.WaitTime[class] = stats
+	}
+	stats.Count++
+	stats.Total += wait
+	if wait > stats.Max {
+		stats.Max = wait
+	}
+	tq.waitCount++
+	tq.waitTotal += wait
+	tq.metrics.AverageWaitTime = tq.waitTotal / time.Duration(tq.waitCount)
+
+	monitoring.TranscodingQueueWaitSeconds.WithLabelValues(string(class)).Observe(wait.Seconds())
+	if starved {
+		stats.Starved++
+		monitoring.TranscodingQueueStarvedTotal.WithLabelValues(string(class)).Inc()
+	}
+}
+
+// signal wakes one waiting Dequeue, if any.
+func (tq *TaskQueue) signal() {
+	select {
+	case tq.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Metrics returns a snapshot of the queue's statistics.
+func (tq *TaskQueue) Metrics() *QueueMetrics {
+	tq.mu.RLock()
+	defer tq.mu.RUnlock()
+
+	snapshot := &QueueMetrics{
+		TotalEnqueued:   atomic.LoadInt64(&tq.metrics.TotalEnqueued),
+		TotalProcessed:  atomic.LoadInt64(&tq.metrics.TotalProcessed),
+		TotalFailed:     atomic.LoadInt64(&tq.metrics.TotalFailed),
+		CurrentQueueLen: len(tq.queued),
+		AverageWaitTime: tq.metrics.AverageWaitTime,
+		Queued:          make(map[PriorityClass]int, len(priorityClasses)),
+		WaitTime:        make(map[PriorityClass]*WaitStats, len(tq.metrics.WaitTime)),
+	}
+	for i, class := range priorityClasses {
+		snapshot.Queued[class] = len(tq.classes[i])
+	}
+	for class, stats := range tq.metrics.WaitTime {
+		cp := *stats
+		snapshot.WaitTime[class] = &cp
+	}
+	return snapshot
+}
@@ -0,0 +1,116 @@
+package transcoder
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func dequeueIDs(t *testing.T, tq *TaskQueue, n int) []string {
+	t.Helper()
+	ids := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		task, err := tq.Dequeue(context.Background())
+		require.NoError(t, err)
+		ids = append(ids, task.ID)
+	}
+	return ids
+}
+
+func TestClassOf(t *testing.T) {
+	for priority, class := range map[int]PriorityClass{
+		0: PriorityClassNormal, 1: PriorityClassLow, 3: PriorityClassLow, 4: PriorityClassNormal,
+		6: PriorityClassNormal, 7: PriorityClassHigh, 10: PriorityClassHigh, -1: PriorityClassNormal,
+	} {
+		assert.Equal(t, class, ClassOf(priority), priority)
+	}
+}
+
+func TestTaskQueue_DequeuesByPriority(t *testing.T) {
+	tq := newTestTaskQueue(10)
+	require.NoError(t, tq.Enqueue(&TranscodeTask{ID: "normal-1"}))
+	require.NoError(t, tq.Enqueue(&TranscodeTask{ID: "high-7", Priority: 7}))
+	require.NoError(t, tq.Enqueue(&TranscodeTask{ID: "high-10", Priority: 10}))
+	require.NoError(t, tq.Enqueue(&TranscodeTask{ID: "normal-2", Priority: 5}))
+
+	assert.Equal(t, []string{"high-10", "high-7", "normal-2", "normal-1"}, dequeueIDs(t, tq, 4))
+}
+
+func TestTaskQueue_ClassFairness(t *testing.T) {
+	tq := newTestTaskQueue(20)
+	for i := 0; i < 8; i++ {
+		require.NoError(t, tq.Enqueue(&TranscodeTask{ID: fmt.Sprintf("h%d", i), Priority: 10}))
+	}
+	require.NoError(t, tq.Enqueue(&TranscodeTask{ID: "n0"}))
+	require.NoError(t, tq.Enqueue(&TranscodeTask{ID: "l0", Priority: 1}))
+
+	assert.Equal(t, []string{"h0", "h1", "h2", "h3", "n0", "l0", "h4", "h5", "h6", "h7"}, dequeueIDs(t, tq, 10),
+		"lower classes get their share while high priority work is queued")
+}
+
+func TestTaskQueue_StarvationProtection(t *testing.T) {
+	tq := NewTaskQueue(10, time.Minute)
+	require.NoError(t, tq.Enqueue(&TranscodeTask{ID: "low", Priority: 1}))
+	require.NoError(t, tq.Enqueue(&TranscodeTask{ID: "high", Priority: 10}))
+
+	tq.mu.Lock()
+	tq.queued["low"].enqueuedAt = time.Now().Add(-2 * time.Minute)
+	tq.mu.Unlock()
+
+	assert.Equal(t, []string{"low", "high"}, dequeueIDs(t, tq, 2))
+
+	metrics := tq.Metrics()
+	assert.Equal(t, int64(1), metrics.WaitTime[PriorityClassLow].Starved)
+	assert.GreaterOrEqual(t, metrics.WaitTime[PriorityClassLow].Max, 2*time.Minute)
+	assert.Equal(t, int64(1), metrics.WaitTime[PriorityClassHigh].Count)
+	assert.Zero(t, metrics.WaitTime[PriorityClassHigh].Starved)
+}
+
+func TestTaskQueue_CancelRemovesPendingTask(t *testing.T) {
+	tq := newTestTaskQueue(2)
+	require.NoError(t, tq.Enqueue(&TranscodeTask{ID: "task-1"}))
+	require.NoError(t, tq.Enqueue(&TranscodeTask{ID: "task-2"}))
+	assert.Error(t, tq.Enqueue(&TranscodeTask{ID: "task-3"}), "queue is full")
+
+	require.NoError(t, tq.CancelTask("task-1"))
+	assert.Equal(t, 1, tq.Len())
+	require.NoError(t, tq.Enqueue(&TranscodeTask{ID: "task-3"}))
+
+	assert.Equal(t, []string{"task-2", "task-3"}, dequeueIDs(t, tq, 2))
+}
+
+func TestTaskQueue_DequeueWaitsForTask(t *testing.T) {
+	tq := newTestTaskQueue(2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := tq.Dequeue(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	done := make(chan string)
+	go func() {
+		task, err := tq.Dequeue(context.Background())
+		if err == nil {
+			done <- task.ID
+		}
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, tq.Enqueue(&TranscodeTask{ID: "task-1", Priority: 8}))
+
+	select {
+	case id := <-done:
+		assert.Equal(t, "task-1", id)
+	case <-time.After(time.Second):
+		t.Fatal("Dequeue did not wake for the enqueued task")
+	}
+
+	metrics := tq.Metrics()
+	assert.Equal(t, int64(1), metrics.TotalEnqueued)
+	assert.Equal(t, 0, metrics.CurrentQueueLen)
+	assert.Equal(t, int64(1), metrics.WaitTime[PriorityClassHigh].Count)
+}
@@ -0,0 +1,136 @@
+package transcoder
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// segmentVersionLen is the hex width of a segment version token. UnixNano
+// stays within 16 hex digits until the year 2554, so tokens sort
+// lexicographically in creation order.
+const segmentVersionLen = 16
+
+// NewSegmentVersion returns the version token embedded in every segment name
+// produced by one transcode run. Each re-transcode gets a new token so its
+// segment URLs never collide with (and never get served from caches of) a
+// previous run's segments.
+func NewSegmentVersion(now time.Time) string {
+	return fmt.Sprintf("v%0*x", segmentVersionLen, now.UnixNano())
+}
+
+// SegmentName returns the deterministic name of segment seq for a variant.
+func SegmentName(variant, version string, seq int) string {
+	if version == "" {
+		return fmt.Sprintf("%s_%03d.ts", variant, seq)
+	}
+	return fmt.Sprintf("%s_%s_%03d.ts", variant, version, seq)
+}
+
+// DefaultPartDuration is the LL-HLS partial segment length used when a
+// low-latency profile does not set one.
+const DefaultPartDuration = time.Second
+
+// SegmentPartName returns the name of LL-HLS part seq for a variant. Parts
+// carry the same version token as segments and are told apart by the "p"
+// before the sequence number.
+func SegmentPartName(variant, version string, seq int) string {
+	if version == "" {
+		return fmt.Sprintf("%s_p%05d.ts", variant, seq)
+	}
+	return fmt.Sprintf("%s_%s_p%05d.ts", variant, version, seq)
+}
+
+// IsSegmentPart reports whether name is an LL-HLS part written by a
+// low-latency transcode.
+func IsSegmentPart(name string) bool {
+	base := strings.TrimSuffix(path.Base(name), ".ts")
+	idx := strings.LastIndex(base, "_")
+	if idx < 0 || len(base) < idx+3 || base[idx+1] != 'p' {
+		return false
+	}
+	for _, c := range base[idx+2:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// segmentFilePattern returns the FFmpeg -hls_segment_filename pattern that
+// yields SegmentName(variant, version, n) next to the variant playlist.
+func segmentFilePattern(playlistPath, version string) string {
+	dir := filepath.Dir(playlistPath)
+	variant := strings.TrimSuffix(filepath.Base(playlistPath), filepath.Ext(playlistPath))
+	if version == "" {
+		return filepath.Join(dir, variant+"_%03d.ts")
+	}
+	return filepath.Join(dir, variant+"_"+version+"_%03d.ts")
+}
+
+// partFilePattern is segmentFilePattern for low-latency variants, yielding
+// SegmentPartName(variant, version, n).
+func partFilePattern(playlistPath, version string) string {
+	dir := filepath.Dir(playlistPath)
+	variant := strings.TrimSuffix(filepath.Base(playlistPath), filepath.Ext(playlistPath))
+	if version == "" {
+		return filepath.Join(dir, variant+"_p%05d.ts")
+	}
+	return filepath.Join(dir, variant+"_"+version+"_p%05d.ts")
+}
+
+// ParseSegmentVersion extracts the version token from a segment name, or
+// returns "" for legacy unversioned names such as "1280x720_000.ts".
+func ParseSegmentVersion(name string) string {
+	base := path.Base(name)
+	base = strings.TrimSuffix(base, ".ts")
+	parts := strings.Split(base, "_")
+	if len(parts) < 3 {
+		return ""
+	}
+	v := parts[len(parts)-2]
+	if !isSegmentVersion(v) {
+		return ""
+	}
+	return v
+}
+
+func isSegmentVersion(v string) bool {
+	if len(v) != segmentVersionLen+1 || v[0] != 'v' {
+		return false
+	}
+	for _, c := range v[1:] {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// LatestSegmentVersion returns the newest version token present in names.
+// Legacy unversioned segments are treated as older than any versioned run.
+func LatestSegmentVersion(names []string) string {
+	latest := ""
+	for _, n := range names {
+		if v := ParseSegmentVersion(n); v > latest {
+			latest = v
+		}
+	}
+	return latest
+}
+
+// FilterLatestSegments keeps only the segments belonging to the newest
+// transcode run. Older segments stay in storage and remain directly
+// fetchable until they are invalidated, but are no longer advertised.
+func FilterLatestSegments(names []string) []string {
+	latest := LatestSegmentVersion(names)
+	out := make([]string, 0, len(names))
+	for _, n := range names {
+		if ParseSegmentVersion(n) == latest {
+			out = append(out, n)
+		}
+	}
+	return out
+}
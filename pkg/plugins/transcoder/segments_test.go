@@ -0,0 +1,83 @@
+package transcoder
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSegmentName_ReTranscodeProducesNewNames(t *testing.T) {
+	first := NewSegmentVersion(time.Unix(1700000000, 0))
+	second := NewSegmentVersion(time.Unix(1700000000, 1))
+
+	assert.NotEqual(t, SegmentName("1280x720", first, 0), SegmentName("1280x720", second, 0))
+	assert.Less(t, first, second)
+	assert.Equal(t, SegmentName("1280x720", first, 3), SegmentName("1280x720", first, 3))
+}
+
+func TestSegmentName_Legacy(t *testing.T) {
+	assert.Equal(t, "1280x720_007.ts", SegmentName("1280x720", "", 7))
+}
+
+func TestSegmentFilePattern(t *testing.T) {
+	v := NewSegmentVersion(time.Unix(1700000000, 0))
+	dir := filepath.Join("tmp", "out")
+
+	assert.Equal(t, filepath.Join(dir, "1280x720_"+v+"_%03d.ts"), segmentFilePattern(filepath.Join(dir, "1280x720.m3u8"), v))
+	assert.Equal(t, filepath.Join(dir, "1280x720_%03d.ts"), segmentFilePattern(filepath.Join(dir, "1280x720.m3u8"), ""))
+}
+
+func TestParseSegmentVersion(t *testing.T) {
+	v := NewSegmentVersion(time.Unix(1700000000, 0))
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"versioned", SegmentName("1280x720", v, 12), v},
+		{"versioned with quality dir", "1280x720/" + SegmentName("1280x720", v, 0), v},
+		{"legacy", "1280x720_000.ts", ""},
+		{"not a version token", "seg_vzz_001.ts", ""},
+		{"plain", "seg0.ts", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParseSegmentVersion(tt.in))
+		})
+	}
+}
+
+func TestFilterLatestSegments_OldRunsDropped(t *testing.T) {
+	oldV := NewSegmentVersion(time.Unix(1700000000, 0))
+	newV := NewSegmentVersion(time.Unix(1700003600, 0))
+	names := []string{
+		"1280x720_000.ts",
+		SegmentName("1280x720", oldV, 0),
+		SegmentName("1280x720", oldV, 1),
+		SegmentName("1280x720", newV, 0),
+		SegmentName("1280x720", newV, 1),
+	}
+
+	assert.Equal(t, newV, LatestSegmentVersion(names))
+	assert.Equal(t, []string{SegmentName("1280x720", newV, 0), SegmentName("1280x720", newV, 1)}, FilterLatestSegments(names))
+}
+
+func TestFilterLatestSegments_LegacyOnly(t *testing.T) {
+	names := []string{"1280x720_000.ts", "1280x720_001.ts"}
+	assert.Equal(t, names, FilterLatestSegments(names))
+}
+
+func TestSegmentPartName(t *testing.T) {
+	v := NewSegmentVersion(time.Unix(1700000000, 0))
+	name := SegmentPartName("720p", v, 12)
+	assert.Equal(t, "720p_"+v+"_p00012.ts", name)
+	assert.Equal(t, v, ParseSegmentVersion(name))
+	assert.True(t, IsSegmentPart(name))
+	assert.True(t, IsSegmentPart(SegmentPartName("720p", "", 3)))
+	assert.False(t, IsSegmentPart(SegmentName("720p", v, 12)))
+	assert.False(t, IsSegmentPart("720p_"+v+"_p.ts"))
+
+	assert.Equal(t, filepath.Join("out", "720p_"+v+"_p%05d.ts"), partFilePattern(filepath.Join("out", "720p.m3u8"), v))
+}
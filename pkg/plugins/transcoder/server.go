@@ -8,7 +8,11 @@ import (
 
 	"github.com/rtcdance/streamgate/pkg/core"
 	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/rtcdance/streamgate/pkg/health"
+	"github.com/rtcdance/streamgate/pkg/plugins/keys"
+	"github.com/rtcdance/streamgate/pkg/resilience"
 
+	"github.com/go-redis/redis/v8"
 	"go.uber.org/zap"
 )
 
@@ -19,6 +23,7 @@ type TranscoderServer struct {
 	kernel *core.Microkernel
 	server *http.Server
 	plugin *TranscoderPlugin
+	redis  *redis.Client
 }
 
 // NewTranscoderServer creates a new transcoder server
@@ -34,12 +39,71 @@ func NewTranscoderServer(cfg *config.Config, logger *zap.Logger, kernel *core.Mi
 	}
 
 	transcoderConfig := &TranscoderConfig{
-		WorkerPoolSize:      4,
-		MaxConcurrentTasks:  100,
-		MaxQueueSize:        1000,
-		TaskTimeout:         30 * time.Minute,
-		HealthCheckInterval: 1 * time.Minute,
-		ScalingPolicy:       scalingPolicy,
+		WorkerPoolSize:       4,
+		MaxConcurrentTasks:   100,
+		MaxQueueSize:         1000,
+		TaskTimeout:          30 * time.Minute,
+		HealthCheckInterval:  1 * time.Minute,
+		ScalingPolicy:        scalingPolicy,
+		DefaultProfiles:      CodecLaddersFromConfig(cfg.Transcoding),
+		LowLatencyProfiles:   LowLatencyFromConfig(cfg.Transcoding.Qualities),
+		PackagerPath:         cfg.Transcoding.PackagerPath,
+		HardwareAccel:        HardwareAccelFromConfig(cfg.Transcoding.Hardware),
+		VAAPIDevice:          cfg.Transcoding.VAAPIDevice,
+		AllowPartialVariants: cfg.Transcoding.AllowPartialVariants,
+		RetryBudget: resilience.NewRetryBudget(resilience.RetryBudgetConfig{
+			RatePerSecond: cfg.RetryBudget.RatePerSecond,
+			Burst:         cfg.RetryBudget.Burst,
+		}),
+	}
+	if cfg.Transcoding.PartDuration != "" {
+		partDuration, err := time.ParseDuration(cfg.Transcoding.PartDuration)
+		if err != nil || partDuration <= 0 || partDuration > 6*time.Second {
+			return nil, fmt.Errorf("transcoding.part_duration: invalid duration %q", cfg.Transcoding.PartDuration)
+		}
+		transcoderConfig.PartDuration = partDuration
+	}
+	if cfg.Transcoding.QueueMaxWait != "" {
+		maxWait, err := time.ParseDuration(cfg.Transcoding.QueueMaxWait)
+		if err != nil || maxWait <= 0 {
+			return nil, fmt.Errorf("transcoding.queue_max_wait: invalid duration %q", cfg.Transcoding.QueueMaxWait)
+		}
+		transcoderConfig.QueueMaxWait = maxWait
+	}
+	if cfg.Transcoding.VisibilityTimeout != "" {
+		visibility, err := time.ParseDuration(cfg.Transcoding.VisibilityTimeout)
+		if err != nil || visibility < 3*time.Second {
+			return nil, fmt.Errorf("transcoding.visibility_timeout: invalid duration %q", cfg.Transcoding.VisibilityTimeout)
+		}
+		transcoderConfig.VisibilityTimeout = visibility
+	}
+	var redisClient *redis.Client
+	if cfg.Transcoding.QueueStore == "redis" {
+		client, err := connectQueueRedis(cfg)
+		if err != nil {
+			return nil, err
+		}
+		redisClient = client
+		transcoderConfig.TaskStore = NewRedisTaskStore(client, "")
+		transcoderConfig.Distributed = cfg.Transcoding.Distributed
+	}
+	perTitle, err := PerTitleFromConfig(cfg.Transcoding.PerTitle)
+	if err != nil {
+		return nil, err
+	}
+	transcoderConfig.PerTitle = perTitle
+	transcoderConfig.Loudness = LoudnessFromConfig(cfg.Transcoding.Loudness)
+	if cfg.Encryption.Enabled {
+		store, err := keys.NewKeyStoreFromConfig(cfg.Encryption)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open key store: %w", err)
+		}
+		transcoderConfig.KeyStore = store
+	}
+	if len(transcoderConfig.DefaultProfiles) > 0 {
+		if err := ValidateLadder(transcoderConfig.DefaultProfiles); err != nil {
+			return nil, fmt.Errorf("transcoding.qualities: %w", err)
+		}
 	}
 
 	plugin := NewTranscoderPlugin(transcoderConfig)
@@ -49,9 +113,36 @@ func NewTranscoderServer(cfg *config.Config, logger *zap.Logger, kernel *core.Mi
 		logger: logger,
 		kernel: kernel,
 		plugin: plugin,
+		redis:  redisClient,
 	}, nil
 }
 
+// connectQueueRedis returns a client for cfg.Redis. Unlike caches, a
+// configured Redis task queue is required: running without it would
+// silently lose tasks on restart.
+func connectQueueRedis(cfg *config.Config) (*redis.Client, error) {
+	if cfg.Redis.Host == "" {
+		return nil, fmt.Errorf("transcoding.queue_store: redis requires redis.host")
+	}
+	addr := fmt.Sprintf("%s:%d", cfg.Redis.Host, cfg.Redis.Port)
+	client := redis.NewClient(&redis.Options{
+		Addr:         addr,
+		Password:     cfg.Redis.Password,
+		DB:           cfg.Redis.DB,
+		PoolSize:     cfg.Redis.PoolSize,
+		DialTimeout:  5 * time.Second,
+		ReadTimeout:  3 * time.Second,
+		WriteTimeout: 3 * time.Second,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to connect to task queue redis at %s: %w", addr, err)
+	}
+	return client, nil
+}
+
 // Start starts the transcoder server
 func (s *TranscoderServer) Start(ctx context.Context) error {
 	// Initialize plugin
@@ -64,6 +155,9 @@ func (s *TranscoderServer) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to start transcoder plugin: %w", err)
 	}
 
+	if s.kernel != nil {
+		s.registerHealthChecks(s.kernel.HealthChecks())
+	}
 	handler := NewTranscoderHandler(s.plugin, s.logger, s.kernel)
 
 	mux := http.NewServeMux()
@@ -77,6 +171,7 @@ func (s *TranscoderServer) Start(ctx context.Context) error {
 	mux.HandleFunc("/api/v1/transcode/submit", handler.SubmitTaskHandler)
 	mux.HandleFunc("/api/v1/transcode/status", handler.GetTaskStatusHandler)
 	mux.HandleFunc("/api/v1/transcode/status/", handler.GetTaskStatusHandler)
+	mux.HandleFunc("/api/v1/transcode/", handler.TaskRouteHandler)
 	mux.HandleFunc("/api/v1/transcode/cancel", handler.CancelTaskHandler)
 	mux.HandleFunc("/api/v1/transcode/cancel/", handler.CancelTaskHandler)
 	mux.HandleFunc("/api/v1/transcode/list", handler.ListTasksHandler)
@@ -103,8 +198,33 @@ func (s *TranscoderServer) Start(ctx context.Context) error {
 	return nil
 }
 
+// Kernel health checks of the transcoder's dependencies.
+const (
+	redisHealthCheck = "transcoder.redis"
+	queueHealthCheck = "transcoder.queue"
+)
+
+// queueDegradedRatio is how full the task queue gets before the queue
+// check degrades; a full queue rejects submissions and fails it.
+const queueDegradedRatio = 0.8
+
+func (s *TranscoderServer) registerHealthChecks(hc *health.HealthChecker) {
+	if s.redis != nil {
+		hc.RegisterCheck(redisHealthCheck, func(ctx context.Context) error {
+			return s.redis.Ping(ctx).Err()
+		}, health.ProbeOptions()...)
+	}
+	maxQueue := s.plugin.config.MaxQueueSize
+	hc.RegisterCheck(queueHealthCheck,
+		health.QueueDepthCheck(s.plugin.QueueDepth, int(float64(maxQueue)*queueDegradedRatio), maxQueue), health.ProbeOptions()...)
+}
+
 // Stop stops the transcoder server
 func (s *TranscoderServer) Stop(ctx context.Context) error {
+	if s.kernel != nil {
+		s.kernel.HealthChecks().UnregisterCheck(redisHealthCheck)
+		s.kernel.HealthChecks().UnregisterCheck(queueHealthCheck)
+	}
 	if s.server != nil {
 		if err := s.server.Shutdown(ctx); err != nil {
 			s.logger.Error("Error shutting down transcoder server", zap.Error(err))
@@ -118,6 +238,9 @@ func (s *TranscoderServer) Stop(ctx context.Context) error {
 			return err
 		}
 	}
+	if s.redis != nil {
+		_ = s.redis.Close()
+	}
 
 	return nil
 }
@@ -10,6 +10,7 @@ import (
 
 	"github.com/rtcdance/streamgate/pkg/core"
 	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -31,6 +32,74 @@ func newTestTranscoderServer(t *testing.T) *TranscoderServer {
 	return server
 }
 
+func TestNewTranscoderServer_AllowPartialVariants(t *testing.T) {
+	defer viper.Reset()
+	for _, allow := range []bool{false, true} {
+		viper.Set("transcoding.allow_partial_variants", allow)
+		cfg, err := config.LoadConfig()
+		require.NoError(t, err)
+		kernel, err := core.NewMicrokernel(cfg, zap.NewNop())
+		require.NoError(t, err)
+
+		server, err := NewTranscoderServer(cfg, zap.NewNop(), kernel)
+		require.NoError(t, err)
+		assert.Equal(t, allow, server.plugin.config.AllowPartialVariants)
+		require.NoError(t, server.plugin.Init(context.Background(), kernel))
+		assert.Equal(t, allow, server.plugin.workerPool.ffmpeg.config.AllowPartialVariants, "passed on to ffmpeg")
+	}
+}
+
+func TestNewTranscoderServer_InvalidDefaultLadder(t *testing.T) {
+	cfg := &config.Config{Mode: "monolith"}
+	cfg.Transcoding.Qualities = []config.QualityConfig{
+		{Name: "360p", Width: 640, Height: 360, Bitrate: 600000},
+		{Name: "720p", Width: 1280, Height: 720, Bitrate: 2500000},
+	}
+	kernel, err := core.NewMicrokernel(cfg, zap.NewNop())
+	require.NoError(t, err)
+
+	_, err = NewTranscoderServer(cfg, zap.NewNop(), kernel)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrInvalidLadder)
+}
+
+func TestTranscoderServer_SubmitWithoutProfilesUsesDefaultLadder(t *testing.T) {
+	cfg := &config.Config{Mode: "monolith"}
+	cfg.Transcoding.Qualities = []config.QualityConfig{
+		{Name: "720p", Width: 1280, Height: 720, Bitrate: 2500000},
+		{Name: "360p", Width: 640, Height: 360, Bitrate: 600000},
+	}
+	kernel, err := core.NewMicrokernel(cfg, zap.NewNop())
+	require.NoError(t, err)
+	server, err := NewTranscoderServer(cfg, zap.NewNop(), kernel)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, server.Start(ctx))
+	t.Cleanup(func() {
+		_ = server.Stop(context.Background())
+	})
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"file_id":   "file-300",
+		"file_path": "https://example.com/input.mp4",
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/transcode/submit", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	task, err := server.plugin.GetTaskStatus(resp["task_id"].(string))
+	require.NoError(t, err)
+	assert.Equal(t, []TranscodeProfile{
+		{Resolution: "1280x720", Bitrate: "2500k", Format: "hls"},
+		{Resolution: "640x360", Bitrate: "600k", Format: "hls"},
+	}, task.Profiles)
+}
+
 func TestTranscoderServer_StartRegistersRoutes(t *testing.T) {
 	server := newTestTranscoderServer(t)
 
@@ -117,6 +186,17 @@ func TestTranscoderServer_StatusAndCancelByPath(t *testing.T) {
 	server.server.Handler.ServeHTTP(pathStatusRec, pathStatusReq)
 	assert.Contains(t, []int{http.StatusOK, http.StatusInternalServerError}, pathStatusRec.Code)
 
+	taskStatusReq := httptest.NewRequest(http.MethodGet, "/api/v1/transcode/"+taskID+"/status", http.NoBody)
+	taskStatusRec := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(taskStatusRec, taskStatusReq)
+	require.Equal(t, http.StatusOK, taskStatusRec.Code)
+	assert.Contains(t, taskStatusRec.Body.String(), `"Progress":`)
+
+	unknownReq := httptest.NewRequest(http.MethodGet, "/api/v1/transcode/"+taskID+"/other", http.NoBody)
+	unknownRec := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(unknownRec, unknownReq)
+	assert.Equal(t, http.StatusNotFound, unknownRec.Code)
+
 	cancelReq := httptest.NewRequest(http.MethodPost, "/api/v1/transcode/cancel?task_id="+taskID, http.NoBody)
 	cancelRec := httptest.NewRecorder()
 	server.server.Handler.ServeHTTP(cancelRec, cancelReq)
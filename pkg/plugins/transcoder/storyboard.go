@@ -0,0 +1,172 @@
+package transcoder
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+)
+
+// Storyboard defaults, used for zero StoryboardConfig fields.
+const (
+	DefaultStoryboardInterval = 10 * time.Second
+	DefaultStoryboardColumns  = 5
+	DefaultStoryboardRows     = 5
+	DefaultStoryboardWidth    = 160
+)
+
+// StoryboardVTTName is the WebVTT file GenerateStoryboard writes; sprite
+// sheets are written next to it as storyboard_000.jpg, storyboard_001.jpg...
+const StoryboardVTTName = "storyboard.vtt"
+
+// StoryboardConfig shapes the scrub preview storyboard: a thumbnail every
+// Interval, Width pixels wide, tiled Columns × Rows per sprite sheet.
+type StoryboardConfig struct {
+	Interval time.Duration
+	Columns  int
+	Rows     int
+	Width    int
+}
+
+// StoryboardFromConfig maps transcoding.storyboard to the storyboard
+// shape, or nil when storyboards are disabled. An unparsable interval,
+// rejected by config validation, takes the default.
+func StoryboardFromConfig(cfg config.StoryboardConfig) *StoryboardConfig {
+	if !cfg.Enabled {
+		return nil
+	}
+	interval, _ := time.ParseDuration(cfg.Interval)
+	return &StoryboardConfig{Interval: interval, Columns: cfg.Columns, Rows: cfg.Rows, Width: cfg.Width}
+}
+
+func (c StoryboardConfig) withDefaults() StoryboardConfig {
+	if c.Interval <= 0 {
+		c.Interval = DefaultStoryboardInterval
+	}
+	if c.Columns <= 0 {
+		c.Columns = DefaultStoryboardColumns
+	}
+	if c.Rows <= 0 {
+		c.Rows = DefaultStoryboardRows
+	}
+	if c.Width <= 0 {
+		c.Width = DefaultStoryboardWidth
+	}
+	return c
+}
+
+// Storyboard lists the files GenerateStoryboard wrote, by name within the
+// output directory.
+type Storyboard struct {
+	VTT     string
+	Sprites []string
+}
+
+// Files returns the WebVTT file followed by the sprite sheets.
+func (s *Storyboard) Files() []string {
+	return append([]string{s.VTT}, s.Sprites...)
+}
+
+// GenerateStoryboard renders the source's scrub preview storyboard into
+// outputDir: JPEG sprite sheets of upright thumbnails taken every
+// cfg.Interval, and a WebVTT file whose cues point each interval at its
+// tile with a #xywh media fragment. Cue URIs are relative, so the sheets
+// must be served next to the WebVTT file.
+func (ft *FFmpegTranscoder) GenerateStoryboard(ctx context.Context, inputPath, outputDir string, cfg StoryboardConfig) (*Storyboard, error) {
+	cfg = cfg.withDefaults()
+	info, err := ft.GetVideoInfo(ctx, inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to probe source: %w", err)
+	}
+	width, height := storyboardTileSize(info, cfg.Width)
+
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storyboard directory: %w", err)
+	}
+
+	// Thumbnails are scaled upright like the rungs, whose resolutions are
+	// named in landscape, so tiles match what players show.
+	resolution := fmt.Sprintf("%dx%d", width, height)
+	if info.IsPortrait() {
+		resolution = fmt.Sprintf("%dx%d", height, width)
+	}
+	filter := hlsVideoFilter(TranscodeProfile{Resolution: resolution}, info)
+	args := []string{
+		"-noautorotate",
+		"-i", inputPath,
+		"-vf", fmt.Sprintf("fps=1/%s,%s,tile=%dx%d", formatSeconds(cfg.Interval), filter, cfg.Columns, cfg.Rows),
+		"-an",
+		"-q:v", "5",
+		"-start_number", "0",
+		"-y", filepath.Join(outputDir, "storyboard_%03d.jpg"),
+	}
+	if err := ft.runFFmpeg(ctx, args, time.Duration(info.Duration*float64(time.Second)), nil); err != nil {
+		return nil, fmt.Errorf("sprite generation failed: %w", err)
+	}
+
+	thumbs := storyboardThumbCount(info.Duration, cfg.Interval)
+	perSheet := cfg.Columns * cfg.Rows
+	sheets := (thumbs + perSheet - 1) / perSheet
+	board := &Storyboard{VTT: StoryboardVTTName, Sprites: make([]string, 0, sheets)}
+	for i := 0; i < sheets; i++ {
+		name := fmt.Sprintf("storyboard_%03d.jpg", i)
+		if _, err := os.Stat(filepath.Join(outputDir, name)); err != nil {
+			return nil, fmt.Errorf("sprite sheet %s missing: %w", name, err)
+		}
+		board.Sprites = append(board.Sprites, name)
+	}
+
+	vtt := storyboardVTT(info.Duration, cfg, width, height)
+	if err := os.WriteFile(filepath.Join(outputDir, StoryboardVTTName), []byte(vtt), 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write storyboard: %w", err)
+	}
+	return board, nil
+}
+
+// storyboardTileSize returns the tile size of a thumbnail width pixels
+// wide in the source's display aspect ratio, rounded to even dimensions.
+func storyboardTileSize(info *VideoInfo, width int) (int, int) {
+	w, h := info.DisplayWidth, info.DisplayHeight
+	if w <= 0 || h <= 0 {
+		w, h = info.Width, info.Height
+	}
+	if w <= 0 || h <= 0 {
+		return width, evenRound(float64(width) * 9 / 16)
+	}
+	return width, evenRound(float64(width) * float64(h) / float64(w))
+}
+
+// storyboardThumbCount returns the thumbnails taken from a source of the
+// given duration, one at the start of every interval.
+func storyboardThumbCount(durationSeconds float64, interval time.Duration) int {
+	return max(1, int(math.Ceil(durationSeconds/interval.Seconds())))
+}
+
+// storyboardVTT builds the WebVTT storyboard: one cue per interval,
+// pointing at its tile in the sprite sheets, tiles filled row by row.
+func storyboardVTT(durationSeconds float64, cfg StoryboardConfig, width, height int) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+	perSheet := cfg.Columns * cfg.Rows
+	total := time.Duration(durationSeconds * float64(time.Second))
+	for i := 0; i < storyboardThumbCount(durationSeconds, cfg.Interval); i++ {
+		start := time.Duration(i) * cfg.Interval
+		end := min(start+cfg.Interval, total)
+		tile := i % perSheet
+		fmt.Fprintf(&b, "\n%s --> %s\nstoryboard_%03d.jpg#xywh=%d,%d,%d,%d\n",
+			vttTimestamp(start), vttTimestamp(end), i/perSheet,
+			(tile%cfg.Columns)*width, (tile/cfg.Columns)*height, width, height)
+	}
+	return b.String()
+}
+
+// vttTimestamp formats d as a WebVTT timestamp, hh:mm:ss.ttt.
+func vttTimestamp(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
@@ -0,0 +1,77 @@
+package transcoder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestStoryboardVTT(t *testing.T) {
+	cfg := StoryboardConfig{Interval: 10 * time.Second, Columns: 2, Rows: 2}
+	vtt := storyboardVTT(45.5, cfg, 160, 90)
+
+	assert.Equal(t, `WEBVTT
+
+00:00:00.000 --> 00:00:10.000
+storyboard_000.jpg#xywh=0,0,160,90
+
+00:00:10.000 --> 00:00:20.000
+storyboard_000.jpg#xywh=160,0,160,90
+
+00:00:20.000 --> 00:00:30.000
+storyboard_000.jpg#xywh=0,90,160,90
+
+00:00:30.000 --> 00:00:40.000
+storyboard_000.jpg#xywh=160,90,160,90
+
+00:00:40.000 --> 00:00:45.500
+storyboard_001.jpg#xywh=0,0,160,90
+`, vtt)
+	assert.Equal(t, "01:02:03.004", vttTimestamp(time.Hour+2*time.Minute+3*time.Second+4*time.Millisecond))
+}
+
+func TestStoryboardTileSize(t *testing.T) {
+	w, h := storyboardTileSize(&VideoInfo{DisplayWidth: 1920, DisplayHeight: 1080}, 160)
+	assert.Equal(t, []int{160, 90}, []int{w, h})
+	w, h = storyboardTileSize(&VideoInfo{DisplayWidth: 1080, DisplayHeight: 1920}, 160)
+	assert.Equal(t, []int{160, 284}, []int{w, h})
+}
+
+func TestGenerateStoryboard(t *testing.T) {
+	cfg := fakeFFmpeg(t, "no-such-filter")
+	ffmpeg := `#!/bin/sh
+for arg in "$@"; do last="$arg"; done
+echo "$@" > "$(dirname "$last")/args"
+touch "$(printf "$last" 0)"
+`
+	require.NoError(t, os.WriteFile(cfg.FFmpegPath, []byte(ffmpeg), 0o755))
+	ft := NewFFmpegTranscoder(cfg, zap.NewNop())
+	outputDir := filepath.Join(t.TempDir(), "storyboard")
+
+	board, err := ft.GenerateStoryboard(context.Background(), filepath.Join(cfg.TempDir, "input.mp4"), outputDir, StoryboardConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"storyboard.vtt", "storyboard_000.jpg"}, board.Files())
+
+	args, err := os.ReadFile(filepath.Join(outputDir, "args"))
+	require.NoError(t, err)
+	assert.Contains(t, string(args), "fps=1/10.000,scale=160:90,setsar=1,tile=5x5")
+
+	vtt, err := os.ReadFile(filepath.Join(outputDir, "storyboard.vtt"))
+	require.NoError(t, err)
+	assert.Contains(t, string(vtt), "00:00:10.000 --> 00:00:12.000\nstoryboard_000.jpg#xywh=160,0,160,90\n")
+}
+
+func TestGenerateStoryboard_MissingSheet(t *testing.T) {
+	cfg := fakeFFmpeg(t, "no-such-filter")
+	require.NoError(t, os.WriteFile(cfg.FFmpegPath, []byte("#!/bin/sh\nexit 0\n"), 0o755))
+	ft := NewFFmpegTranscoder(cfg, zap.NewNop())
+
+	_, err := ft.GenerateStoryboard(context.Background(), filepath.Join(cfg.TempDir, "input.mp4"), t.TempDir(), StoryboardConfig{})
+	assert.ErrorContains(t, err, "storyboard_000.jpg")
+}
@@ -0,0 +1,47 @@
+package transcoder
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type subtitlesContextKey struct{}
+
+// WithSubtitles makes HLS transcodes run with the returned context burn
+// the subtitle files of tracks, local SubRip, WebVTT or ASS files keyed by
+// language, into the rungs whose BurnSubtitles names that language. Like
+// the content keys they are per transcode, so they travel with the context.
+func WithSubtitles(ctx context.Context, tracks map[string]string) context.Context {
+	return context.WithValue(ctx, subtitlesContextKey{}, tracks)
+}
+
+func subtitlesFromContext(ctx context.Context) map[string]string {
+	tracks, _ := ctx.Value(subtitlesContextKey{}).(map[string]string)
+	return tracks
+}
+
+// burnInSubtitles returns the subtitle file to burn into profile's rung,
+// or "" for rungs without burn-in. A rung asking for a language the
+// transcode has no subtitles for fails rather than publishing without.
+func burnInSubtitles(ctx context.Context, profile TranscodeProfile) (string, error) {
+	if profile.BurnSubtitles == "" {
+		return "", nil
+	}
+	path, ok := subtitlesFromContext(ctx)[profile.BurnSubtitles]
+	if !ok || path == "" {
+		return "", fmt.Errorf("no %s subtitles to burn into %s", profile.BurnSubtitles, profile.Name())
+	}
+	return path, nil
+}
+
+// subtitlesFilterEscaper escapes a path for an option value inside a
+// filter graph, where these characters separate options and filters.
+var subtitlesFilterEscaper = strings.NewReplacer(
+	`\`, `\\`, `'`, `\'`, `:`, `\:`, `,`, `\,`, `;`, `\;`, `[`, `\[`, `]`, `\]`,
+)
+
+// subtitlesFilter renders the subtitles of path onto the frames.
+func subtitlesFilter(path string) string {
+	return "subtitles=filename=" + subtitlesFilterEscaper.Replace(path)
+}
@@ -0,0 +1,48 @@
+package transcoder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSubtitlesFilter(t *testing.T) {
+	assert.Equal(t, `subtitles=filename=/tmp/subs.srt`, subtitlesFilter("/tmp/subs.srt"))
+	assert.Equal(t, `subtitles=filename=/tmp/it\'s\:\,\[1\].srt`, subtitlesFilter("/tmp/it's:,[1].srt"))
+}
+
+func TestTranscodeToHLS_BurnSubtitles(t *testing.T) {
+	cfg := fakeFFmpeg(t, "no-such-filter")
+	ffmpeg := `#!/bin/sh
+for arg in "$@"; do last="$arg"; done
+echo "$@" > "$last.args"
+printf '#EXTM3U\n' > "$last"
+`
+	require.NoError(t, os.WriteFile(cfg.FFmpegPath, []byte(ffmpeg), 0o755))
+	ft := NewFFmpegTranscoder(cfg, zap.NewNop())
+	outputDir := t.TempDir()
+	subtitles := filepath.Join(cfg.TempDir, "eng.srt")
+
+	profiles := []TranscodeProfile{
+		{Resolution: "1280x720", Bitrate: "2500k", Format: "hls", BurnSubtitles: "eng"},
+		{Resolution: "640x360", Bitrate: "500k", Format: "hls"},
+	}
+	ctx := WithSubtitles(context.Background(), map[string]string{"eng": subtitles})
+	require.NoError(t, ft.TranscodeToHLS(ctx, filepath.Join(cfg.TempDir, "input.mp4"), outputDir, profiles, nil, nil))
+
+	args, err := os.ReadFile(filepath.Join(outputDir, "1280x720.m3u8.args"))
+	require.NoError(t, err)
+	assert.Contains(t, string(args), "-vf scale=1280:720,setsar=1,subtitles=filename="+subtitles+" ")
+	args, err = os.ReadFile(filepath.Join(outputDir, "640x360.m3u8.args"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(args), "subtitles=")
+
+	profiles[0].BurnSubtitles = "fra"
+	err = ft.TranscodeToHLS(ctx, filepath.Join(cfg.TempDir, "input.mp4"), t.TempDir(), profiles, nil, nil)
+	assert.ErrorContains(t, err, "no fra subtitles to burn into 1280x720")
+}
@@ -0,0 +1,194 @@
+package transcoder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrTaskNotFound is returned for tasks a store does not hold.
+var ErrTaskNotFound = errors.New("task not found")
+
+const (
+	// DefaultTaskKeyPrefix namespaces task queue keys in Redis.
+	DefaultTaskKeyPrefix = "streamgate:transcoder:"
+	// DefaultVisibilityTimeout is how long a task stays leased to a
+	// transcoder that stopped renewing it before another may take it over.
+	DefaultVisibilityTimeout = 5 * time.Minute
+	// finishedTaskRetention is how long finished tasks stay readable.
+	finishedTaskRetention = 24 * time.Hour
+)
+
+// TaskStore persists a TaskQueue's tasks so they survive restarts. Every
+// unfinished task is leased to the transcoder holding it, queued or in
+// flight, which renews the lease while it lives; a task whose lease runs
+// out is claimable by any transcoder, so delivery is at least once.
+type TaskStore interface {
+	// Save writes the task's state. Saving a finished task releases its
+	// lease and stops it from being claimed.
+	Save(ctx context.Context, task *TranscodeTask) error
+	// Get returns a task's last saved state, or ErrTaskNotFound.
+	Get(ctx context.Context, taskID string) (*TranscodeTask, error)
+	// Lease leases an unfinished task to owner until until, or renews the
+	// lease it holds. It returns false if the task is finished or leased
+	// to another owner until after now.
+	Lease(ctx context.Context, taskID, owner string, now, until time.Time) (bool, error)
+	// Claimable returns the unfinished tasks whose lease ran out by now,
+	// and those leased to owner, such as a restarted transcoder's.
+	Claimable(ctx context.Context, owner string, now time.Time) ([]*TranscodeTask, error)
+}
+
+// taskFinished reports whether a task in status has left the queue for good.
+func taskFinished(status TaskStatus) bool {
+	return status == TaskStatusCompleted || status == TaskStatusFailed || status == TaskStatusCancelled
+}
+
+// RedisTaskStore keeps tasks in Redis: each as JSON under its own key,
+// with the unfinished ones in a sorted set scored by lease expiry and
+// their lease holders in a hash. Saves and leases run as Lua scripts so
+// transcoders sharing the store cannot both lease a task.
+type RedisTaskStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisTaskStore creates a store on client. An empty prefix uses
+// DefaultTaskKeyPrefix. The caller manages the client lifecycle.
+func NewRedisTaskStore(client *redis.Client, prefix string) *RedisTaskStore {
+	if prefix == "" {
+		prefix = DefaultTaskKeyPrefix
+	}
+	return &RedisTaskStore{client: client, prefix: prefix}
+}
+
+func (s *RedisTaskStore) taskKey(taskID string) string { return s.prefix + "task:" + taskID }
+func (s *RedisTaskStore) leasesKey() string            { return s.prefix + "leases" }
+func (s *RedisTaskStore) ownersKey() string            { return s.prefix + "owners" }
+
+// saveTaskLua writes a task. Unfinished tasks join the lease set, unleased
+// if new; finished ones leave it and expire after the retention period.
+var saveTaskLua = redis.NewScript(`
+redis.call('SET', KEYS[1], ARGV[2])
+if ARGV[3] == '1' then
+  redis.call('ZREM', KEYS[2], ARGV[1])
+  redis.call('HDEL', KEYS[3], ARGV[1])
+  redis.call('PEXPIRE', KEYS[1], ARGV[4])
+else
+  redis.call('ZADD', KEYS[2], 'NX', 0, ARGV[1])
+end
+return 1
+`)
+
+// leaseTaskLua leases an unfinished task unless another owner's lease is
+// still running.
+var leaseTaskLua = redis.NewScript(`
+local expiry = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not expiry then
+  return 0
+end
+local holder = redis.call('HGET', KEYS[2], ARGV[1])
+if holder and holder ~= ARGV[2] and tonumber(expiry) > tonumber(ARGV[3]) then
+  return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[4], ARGV[1])
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[2])
+return 1
+`)
+
+// Save writes the task's state.
+func (s *RedisTaskStore) Save(ctx context.Context, task *TranscodeTask) error {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("failed to encode task: %w", err)
+	}
+	finished := "0"
+	if taskFinished(task.Status) {
+		finished = "1"
+	}
+	err = saveTaskLua.Run(ctx, s.client,
+		[]string{s.taskKey(task.ID), s.leasesKey(), s.ownersKey()},
+		task.ID, data, finished, finishedTaskRetention.Milliseconds()).Err()
+	if err != nil {
+		return fmt.Errorf("failed to save task: %w", err)
+	}
+	return nil
+}
+
+// Get returns a task's last saved state.
+func (s *RedisTaskStore) Get(ctx context.Context, taskID string) (*TranscodeTask, error) {
+	data, err := s.client.Get(ctx, s.taskKey(taskID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load task: %w", err)
+	}
+	var task TranscodeTask
+	if err := json.Unmarshal(data, &task); err != nil {
+		return nil, fmt.Errorf("failed to decode task: %w", err)
+	}
+	return &task, nil
+}
+
+// Lease leases the task to owner until until.
+func (s *RedisTaskStore) Lease(ctx context.Context, taskID, owner string, now, until time.Time) (bool, error) {
+	leased, err := leaseTaskLua.Run(ctx, s.client,
+		[]string{s.leasesKey(), s.ownersKey()},
+		taskID, owner, now.UnixMilli(), until.UnixMilli()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to lease task: %w", err)
+	}
+	return leased == 1, nil
+}
+
+// Claimable returns the tasks owner may lease.
+func (s *RedisTaskStore) Claimable(ctx context.Context, owner string, now time.Time) ([]*TranscodeTask, error) {
+	expired, err := s.client.ZRangeByScore(ctx, s.leasesKey(), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(now.UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired leases: %w", err)
+	}
+	owners, err := s.client.HGetAll(ctx, s.ownersKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lease owners: %w", err)
+	}
+
+	seen := make(map[string]bool, len(expired))
+	keys := make([]string, 0, len(expired))
+	for _, id := range expired {
+		seen[id] = true
+		keys = append(keys, s.taskKey(id))
+	}
+	for id, holder := range owners {
+		if holder == owner && !seen[id] {
+			keys = append(keys, s.taskKey(id))
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to load tasks: %w", err)
+	}
+	tasks := make([]*TranscodeTask, 0, len(values))
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var task TranscodeTask
+		if err := json.Unmarshal([]byte(data), &task); err != nil {
+			return nil, fmt.Errorf("failed to decode task: %w", err)
+		}
+		tasks = append(tasks, &task)
+	}
+	return tasks, nil
+}
@@ -0,0 +1,126 @@
+package transcoder
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedisTaskStore(t *testing.T) *RedisTaskStore {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return NewRedisTaskStore(client, "")
+}
+
+func TestRedisTaskStore_Leases(t *testing.T) {
+	ctx := context.Background()
+	store := newTestRedisTaskStore(t)
+	now := time.Now()
+
+	require.NoError(t, store.Save(ctx, &TranscodeTask{ID: "task-1", Status: TaskStatusPending, Priority: 7}))
+	leased, err := store.Lease(ctx, "task-1", "a", now, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, leased)
+
+	leased, err = store.Lease(ctx, "task-1", "b", now, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, leased, "a holds the lease")
+	leased, err = store.Lease(ctx, "task-1", "a", now, now.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.True(t, leased, "the holder renews")
+
+	claimable, err := store.Claimable(ctx, "b", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, claimable)
+	claimable, err = store.Claimable(ctx, "a", now)
+	require.NoError(t, err)
+	require.Len(t, claimable, 1, "a restarted holder reclaims its own tasks")
+	assert.Equal(t, 7, claimable[0].Priority)
+
+	later := now.Add(3 * time.Minute)
+	claimable, err = store.Claimable(ctx, "b", later)
+	require.NoError(t, err)
+	require.Len(t, claimable, 1)
+	leased, err = store.Lease(ctx, "task-1", "b", later, later.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, leased, "an expired lease is taken over")
+
+	require.NoError(t, store.Save(ctx, &TranscodeTask{ID: "task-1", Status: TaskStatusCompleted}))
+	leased, err = store.Lease(ctx, "task-1", "b", later, later.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, leased, "finished tasks cannot be leased")
+	claimable, err = store.Claimable(ctx, "b", later.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, claimable)
+}
+
+func TestTaskQueue_RecoversAfterRestart(t *testing.T) {
+	ctx := context.Background()
+	store := newTestRedisTaskStore(t)
+
+	tq := NewTaskQueue(10, 0, WithTaskStore(store, "host-a", time.Minute))
+	require.NoError(t, tq.Enqueue(&TranscodeTask{ID: "running", FileID: "file-1", MaxRetries: 3}))
+	require.NoError(t, tq.Enqueue(&TranscodeTask{ID: "pending", FileID: "file-2", Priority: 8, MaxRetries: 3}))
+	require.NoError(t, tq.Enqueue(&TranscodeTask{ID: "done", FileID: "file-3", MaxRetries: 3}))
+	for _, id := range dequeueIDs(t, tq, 1) {
+		require.Equal(t, "pending", id, "the high priority task runs first")
+		require.NoError(t, tq.TransitionStatus(id, func(t *TranscodeTask) { t.Status = TaskStatusProcessing }))
+	}
+	require.NoError(t, tq.CancelTask("done"))
+	require.NoError(t, tq.TransitionStatus("running", func(t *TranscodeTask) { t.Status = TaskStatusProcessing }))
+
+	// The process dies; the same host comes back with an empty queue.
+	restarted := NewTaskQueue(10, 0, WithTaskStore(store, "host-a", time.Minute))
+	recovered, err := restarted.Recover(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, recovered)
+	assert.Equal(t, 2, restarted.Len())
+
+	task, err := restarted.GetTask("pending")
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusPending, task.Status)
+	assert.Equal(t, 1, task.RetryCount, "the interrupted run counts as an attempt")
+	assert.Equal(t, "file-2", task.FileID)
+	_, err = restarted.GetTask("done")
+	assert.Error(t, err, "cancelled tasks stay finished")
+
+	other := NewTaskQueue(10, 0, WithTaskStore(store, "host-b", time.Minute))
+	recovered, err = other.Recover(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, recovered, "leases held by a live transcoder are respected")
+}
+
+func TestTaskQueue_ReclaimsExpiredLeases(t *testing.T) {
+	ctx := context.Background()
+	store := newTestRedisTaskStore(t)
+
+	dead := NewTaskQueue(10, 0, WithTaskStore(store, "host-a", time.Minute))
+	require.NoError(t, dead.Enqueue(&TranscodeTask{ID: "task-1", MaxRetries: 1}))
+	require.NoError(t, dead.Enqueue(&TranscodeTask{ID: "task-2", MaxRetries: 3}))
+	require.Len(t, dequeueIDs(t, dead, 1), 1)
+	require.NoError(t, dead.TransitionStatus("task-1", func(t *TranscodeTask) { t.Status = TaskStatusProcessing }))
+
+	survivor := NewTaskQueue(10, 0, WithTaskStore(store, "host-b", time.Minute))
+	recovered, err := survivor.reclaim(ctx, time.Now().Add(2*time.Minute), -1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, recovered)
+	assert.Equal(t, []string{"task-2"}, dequeueIDs(t, survivor, 1))
+
+	task, err := survivor.GetTask("task-1")
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusFailed, task.Status, "a task out of retries fails instead of running again")
+	assert.True(t, task.Retryable)
+
+	// host-a wakes up: its queued copy of task-2 is dropped.
+	dead.renewLeases(ctx, time.Now())
+	assert.Zero(t, dead.Len())
+	_, err = dead.GetTask("task-2")
+	assert.Error(t, err)
+}
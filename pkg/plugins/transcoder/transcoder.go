@@ -2,6 +2,7 @@ package transcoder
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"sync"
@@ -12,6 +13,8 @@ import (
 
 	"github.com/rtcdance/streamgate/pkg/core"
 	"github.com/rtcdance/streamgate/pkg/core/event"
+	"github.com/rtcdance/streamgate/pkg/plugins/keys"
+	"github.com/rtcdance/streamgate/pkg/resilience"
 )
 
 // TranscodeTask represents a transcoding task
@@ -26,10 +29,29 @@ type TranscodeTask struct {
 	CompletedAt *time.Time
 	Profiles    []TranscodeProfile
 	Progress    float64
-	Error       string
-	WorkerID    string
-	RetryCount  int
-	MaxRetries  int
+	// Speed is FFmpeg's encoding speed relative to realtime, e.g. "1.5".
+	Speed string
+	Error string
+	// FailureReason classifies Error; only transient failures are retried.
+	FailureReason FailureReason
+	// Retryable tells clients whether resubmitting the task may succeed.
+	Retryable bool
+	WorkerID  string
+	// Instance is the transcoder running the task when several share a
+	// task store.
+	Instance   string
+	RetryCount int
+	MaxRetries int
+	// FailedVariants lists rungs that failed when partial variants are
+	// allowed; the task still completes with the remaining rungs.
+	FailedVariants []string
+	// AutoLadder marks tasks submitted without profiles. With per-title
+	// encoding on, their default ladder is replaced by one derived from
+	// the source when they first run.
+	AutoLadder bool
+	// Subtitles maps languages to local subtitle files, for rungs that
+	// burn subtitles in.
+	Subtitles map[string]string
 }
 
 // TaskStatus represents the status of a transcoding task
@@ -48,15 +70,46 @@ type TranscodeProfile struct {
 	Resolution string
 	Bitrate    string
 	Format     string
+	// DRM packages the rung as DRM-protected fMP4 with the given scheme;
+	// empty leaves it a regular (possibly AES-128 encrypted) HLS rung.
+	DRM DRMScheme
+	// Codec overrides the video codec of HLS rungs: "h264" or "hevc",
+	// encoded in hardware when available, or an encoder such as "libx265"
+	// or "hevc_nvenc".
+	Codec string
+	// BurnSubtitles renders the subtitles of this language into the
+	// rung's picture, for players without subtitle support; empty keeps
+	// the picture clean.
+	BurnSubtitles string
 }
 
-// TaskQueue manages transcoding tasks with priority queue
+// TaskQueue manages transcoding tasks with a priority queue. Pending tasks
+// wait in a heap per PriorityClass, served by weighted round robin so
+// lower classes keep moving, and any task waiting longer than maxWait is
+// served first. With a TaskStore, tasks are also persisted and leased so
+// they outlive the process.
 type TaskQueue struct {
-	tasks   map[string]*TranscodeTask
-	queue   chan *TranscodeTask
-	mu      sync.RWMutex
-	maxSize int
-	metrics *QueueMetrics
+	tasks     map[string]*TranscodeTask
+	classes   [len(priorityClasses)]taskHeap
+	queued    map[string]*queuedTask
+	credits   [len(priorityClasses)]int
+	seq       uint64
+	ready     chan struct{}
+	mu        sync.RWMutex
+	maxSize   int
+	maxWait   time.Duration
+	waitCount int64
+	waitTotal time.Duration
+	metrics   *QueueMetrics
+
+	store      TaskStore
+	owner      string
+	visibility time.Duration
+	held       map[string]struct{}
+	shared     bool
+	slots      int
+	claimNow   chan struct{}
+	logger     *zap.Logger
 }
 
 // QueueMetrics tracks queue statistics
@@ -66,12 +119,19 @@ type QueueMetrics struct {
 	TotalFailed     int64
 	CurrentQueueLen int
 	AverageWaitTime time.Duration
+	// Queued counts pending tasks by priority class.
+	Queued map[PriorityClass]int
+	// WaitTime summarises queue wait by priority class.
+	WaitTime map[PriorityClass]*WaitStats
 }
 
 // WorkerPool manages concurrent transcoding workers for standalone microservice mode.
+// Concurrency (start, resize, shutdown) is delegated to core.WorkerPool, which
+// pulls tasks from the TaskQueue; this type keeps per-worker health and stats.
 // Deprecated: Prefer service.TranscodingService which provides equivalent scheduling via
 // StartWorker/StopWorker with configurable worker count and integrated retry logic.
 type WorkerPool struct {
+	pool          *core.WorkerPool[*TranscodeTask]
 	workers       []*Worker
 	taskQueue     *TaskQueue
 	eventBus      event.EventBus
@@ -80,9 +140,16 @@ type WorkerPool struct {
 	mu            sync.RWMutex
 	ctx           context.Context
 	cancel        context.CancelFunc
-	wg            sync.WaitGroup
 	metrics       *WorkerMetrics
 	scalingPolicy *ScalingPolicy
+	retryBudget   *resilience.RetryBudget
+	keyStore      *keys.KeyStore
+	// running holds the tasks being transcoded, by ID.
+	running map[string]*taskRun
+	// acquireJob, if set, takes a job slot before a task is dequeued; the
+	// slot is held in jobSlots until the task is processed.
+	acquireJob func(ctx context.Context) (release func(), err error)
+	jobSlots   map[*TranscodeTask]func()
 }
 
 // Worker represents a transcoding worker
@@ -115,6 +182,8 @@ type WorkerMetrics struct {
 	TotalTasksProcessed int64
 	TotalTasksFailed    int64
 	AverageTaskTime     time.Duration
+	// Queue is the task queue's statistics, set by TranscoderPlugin.
+	Queue *QueueMetrics `json:",omitempty"`
 }
 
 // ScalingPolicy defines auto-scaling rules
@@ -141,6 +210,8 @@ type TranscoderPlugin struct {
 	eventBus     event.EventBus
 	logger       *zap.Logger
 	mu           sync.RWMutex
+	// uploadSub is the subscription to upload.completed events.
+	uploadSub string
 }
 
 // TranscoderConfig holds transcoder configuration
@@ -151,6 +222,52 @@ type TranscoderConfig struct {
 	TaskTimeout         time.Duration
 	HealthCheckInterval time.Duration
 	ScalingPolicy       *ScalingPolicy
+	// DefaultProfiles is the ladder applied to submissions without
+	// profiles. BuiltinLadder is used when empty.
+	DefaultProfiles []TranscodeProfile
+	// AllowPartialVariants completes tasks with the rungs that succeeded
+	// instead of failing them when some rungs fail.
+	AllowPartialVariants bool
+	// LowLatencyProfiles enables LL-HLS output for the rungs it names, by
+	// resolution (e.g. "1280x720"), for premieres that need live-like
+	// latency.
+	LowLatencyProfiles []string
+	// PartDuration is the LL-HLS part length; DefaultPartDuration when zero.
+	PartDuration time.Duration
+	// RetryBudget, when set, paces retries of failed tasks.
+	RetryBudget *resilience.RetryBudget
+	// KeyStore, when set, encrypts every task's segments with its
+	// content's AES-128 key, issued on first use. DRM profiles take their
+	// keys from it too and fail without one.
+	KeyStore *keys.KeyStore
+	// PackagerPath is the shaka-packager binary for DRM profiles; FFmpeg
+	// packages CENC profiles when empty.
+	PackagerPath string
+	// HardwareAccel selects the hardware encoder probed at Init;
+	// HardwareNone encodes in software.
+	HardwareAccel HardwareAccel
+	// VAAPIDevice is the render node for HardwareVAAPI.
+	VAAPIDevice string
+	// PerTitle, when set, derives the ladder of tasks submitted without
+	// profiles from their source, within its bounds.
+	PerTitle *PerTitleConfig
+	// Loudness, when set, is the EBU R128 target every audio track is
+	// normalised to.
+	Loudness *LoudnessConfig
+	// QueueMaxWait is how long a task may wait before it is dispatched
+	// ahead of higher priority work; DefaultQueueMaxWait when zero.
+	QueueMaxWait time.Duration
+	// TaskStore, when set, persists tasks so pending and in-flight work
+	// survives restarts; tasks are kept in memory only when nil.
+	TaskStore TaskStore
+	// VisibilityTimeout is how long a stored task stays leased to a
+	// transcoder that stopped renewing it; DefaultVisibilityTimeout when
+	// zero.
+	VisibilityTimeout time.Duration
+	// Distributed shares TaskStore's queue with other transcoders: each
+	// claims tasks while it has idle workers, and tasks whose transcoder
+	// dies are reassigned. It requires a TaskStore.
+	Distributed bool
 }
 
 // NewTranscoderPlugin creates a new transcoder plugin
@@ -183,22 +300,47 @@ func (tp *TranscoderPlugin) Init(ctx context.Context, kernel *core.Microkernel)
 	tp.logger = kernel.GetLogger()
 	tp.eventBus = kernel.GetEventBus()
 
+	if len(tp.config.DefaultProfiles) == 0 {
+		tp.config.DefaultProfiles = BuiltinLadder()
+	}
+	if err := ValidateLadder(tp.config.DefaultProfiles); err != nil {
+		return fmt.Errorf("default profile ladder: %w", err)
+	}
+
 	// Initialize task queue
-	tp.taskQueue = &TaskQueue{
-		tasks:   make(map[string]*TranscodeTask),
-		queue:   make(chan *TranscodeTask, tp.config.MaxQueueSize),
-		maxSize: tp.config.MaxQueueSize,
-		metrics: &QueueMetrics{},
+	queueOpts := []TaskQueueOption{WithQueueLogger(tp.logger.Named("queue"))}
+	if tp.config.TaskStore != nil {
+		owner, err := os.Hostname()
+		if err != nil || owner == "" {
+			owner = tp.name
+		}
+		queueOpts = append(queueOpts, WithTaskStore(tp.config.TaskStore, owner, tp.config.VisibilityTimeout))
+		if tp.config.Distributed {
+			queueOpts = append(queueOpts, WithSharedQueue(tp.config.WorkerPoolSize))
+		}
+	} else if tp.config.Distributed {
+		return fmt.Errorf("distributed transcoding requires a task store")
 	}
+	tp.taskQueue = NewTaskQueue(tp.config.MaxQueueSize, tp.config.QueueMaxWait, queueOpts...)
 
 	// Initialize FFmpeg transcoder
 	ffmpegConfig := &FFmpegConfig{
-		FFmpegPath:  "ffmpeg",
-		FFprobePath: "ffprobe",
-		TempDir:     os.TempDir(),
-		Timeout:     tp.config.TaskTimeout,
+		FFmpegPath:           "ffmpeg",
+		FFprobePath:          "ffprobe",
+		TempDir:              os.TempDir(),
+		Timeout:              tp.config.TaskTimeout,
+		AllowPartialVariants: tp.config.AllowPartialVariants,
+		LowLatencyProfiles:   tp.config.LowLatencyProfiles,
+		PartDuration:         tp.config.PartDuration,
+		PackagerPath:         tp.config.PackagerPath,
+		EnableHardware:       tp.config.HardwareAccel != HardwareNone,
+		HardwareAccel:        tp.config.HardwareAccel,
+		VAAPIDevice:          tp.config.VAAPIDevice,
+		PerTitle:             tp.config.PerTitle,
+		Loudness:             tp.config.Loudness,
 	}
 	ffmpegTranscoder := NewFFmpegTranscoder(ffmpegConfig, tp.logger.Named("ffmpeg"))
+	ffmpegTranscoder.ProbeHardware(ctx)
 
 	// Initialize worker pool
 	tp.workerPool = &WorkerPool{
@@ -209,6 +351,13 @@ func (tp *TranscoderPlugin) Init(ctx context.Context, kernel *core.Microkernel)
 		ffmpeg:        ffmpegTranscoder,
 		metrics:       &WorkerMetrics{},
 		scalingPolicy: tp.config.ScalingPolicy,
+		retryBudget:   tp.config.RetryBudget,
+		keyStore:      tp.config.KeyStore,
+		// plugins.limits.<name>.max_concurrent_jobs applies on top of
+		// the pool size, and can be changed while running.
+		acquireJob: func(ctx context.Context) (func(), error) {
+			return kernel.AcquireJob(ctx, tp.name)
+		},
 	}
 
 	tp.logger.Info("Transcoder plugin initialized",
@@ -223,6 +372,20 @@ func (tp *TranscoderPlugin) Start(ctx context.Context) error {
 	tp.mu.Lock()
 	defer tp.mu.Unlock()
 
+	// Recover tasks left by a previous run before workers take new ones
+	recovered, err := tp.taskQueue.Recover(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to recover tasks: %w", err)
+	}
+	if recovered > 0 {
+		tp.logger.Info("Recovered transcode tasks", zap.Int("tasks", recovered))
+	}
+	go tp.taskQueue.MaintainLeases(ctx)
+
+	if err := tp.subscribeUploads(ctx); err != nil {
+		return fmt.Errorf("failed to subscribe to completed uploads: %w", err)
+	}
+
 	// Start worker pool
 	if err := tp.workerPool.Start(ctx, tp.config.WorkerPoolSize); err != nil {
 		return fmt.Errorf("failed to start worker pool: %w", err)
@@ -243,6 +406,12 @@ func (tp *TranscoderPlugin) Stop(ctx context.Context) error {
 	tp.mu.Lock()
 	defer tp.mu.Unlock()
 
+	if tp.uploadSub != "" {
+		if err := tp.eventBus.Unsubscribe(ctx, tp.uploadSub); err != nil {
+			tp.logger.Warn("Failed to unsubscribe from completed uploads", zap.Error(err))
+		}
+		tp.uploadSub = ""
+	}
 	if err := tp.workerPool.Stop(ctx); err != nil {
 		return fmt.Errorf("failed to stop worker pool: %w", err)
 	}
@@ -273,11 +442,17 @@ func (tp *TranscoderPlugin) HealthCheck() error {
 	return nil
 }
 
-// SubmitTask submits a transcoding task
+// SubmitTask submits a transcoding task. Tasks without profiles get the
+// configured default ladder, or a per-title ladder when that is enabled.
 func (tp *TranscoderPlugin) SubmitTask(task *TranscodeTask) error {
 	tp.mu.RLock()
 	defer tp.mu.RUnlock()
 
+	if len(task.Profiles) == 0 {
+		task.Profiles = tp.defaultProfiles()
+		task.AutoLadder = true
+	}
+
 	if err := tp.taskQueue.Enqueue(task); err != nil {
 		return err
 	}
@@ -292,6 +467,16 @@ func (tp *TranscoderPlugin) SubmitTask(task *TranscodeTask) error {
 	return nil
 }
 
+// defaultProfiles returns a copy of the default ladder so tasks cannot
+// mutate the shared config.
+func (tp *TranscoderPlugin) defaultProfiles() []TranscodeProfile {
+	ladder := tp.config.DefaultProfiles
+	if len(ladder) == 0 {
+		ladder = BuiltinLadder()
+	}
+	return append([]TranscodeProfile(nil), ladder...)
+}
+
 // GetTaskStatus returns the status of a task
 func (tp *TranscoderPlugin) GetTaskStatus(taskID string) (*TranscodeTask, error) {
 	tp.mu.RLock()
@@ -300,12 +485,19 @@ func (tp *TranscoderPlugin) GetTaskStatus(taskID string) (*TranscodeTask, error)
 	return tp.taskQueue.GetTask(taskID)
 }
 
-// CancelTask cancels a transcoding task
+// CancelTask cancels a transcoding task. A task being transcoded has its
+// FFmpeg process killed and its partial output removed before this returns.
 func (tp *TranscoderPlugin) CancelTask(taskID string) error {
 	tp.mu.RLock()
-	defer tp.mu.RUnlock()
+	pool, queue := tp.workerPool, tp.taskQueue
+	tp.mu.RUnlock()
 
-	return tp.taskQueue.CancelTask(taskID)
+	if pool != nil {
+		if running, err := pool.cancelTask(taskID); running {
+			return err
+		}
+	}
+	return queue.CancelTask(taskID)
 }
 
 // GetMetrics returns transcoder metrics
@@ -313,7 +505,22 @@ func (tp *TranscoderPlugin) GetMetrics() *WorkerMetrics {
 	tp.mu.RLock()
 	defer tp.mu.RUnlock()
 
-	return tp.workerPool.GetMetrics()
+	metrics := tp.workerPool.GetMetrics()
+	if tp.taskQueue != nil {
+		metrics.Queue = tp.taskQueue.Metrics()
+	}
+	return metrics
+}
+
+// QueueDepth returns how many tasks are waiting for a worker.
+func (tp *TranscoderPlugin) QueueDepth(_ context.Context) (int, error) {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+
+	if tp.taskQueue == nil {
+		return 0, nil
+	}
+	return tp.taskQueue.Len(), nil
 }
 
 // ScaleWorkers scales the worker pool
@@ -395,58 +602,91 @@ func (tp *TranscoderPlugin) healthCheck(ctx context.Context) {
 
 // Enqueue adds a task to the queue
 func (tq *TaskQueue) Enqueue(task *TranscodeTask) error {
-	tq.mu.Lock()
-	defer tq.mu.Unlock()
-
-	if len(tq.queue) >= tq.maxSize {
+	tq.mu.RLock()
+	full := len(tq.queued) >= tq.maxSize && !tq.shared
+	tq.mu.RUnlock()
+	if full {
 		return fmt.Errorf("task queue is full")
 	}
 
 	task.Status = TaskStatusPending
 	task.CreatedAt = time.Now()
+	if tq.shared {
+		return tq.share(task)
+	}
+	if err := tq.persist(task); err != nil {
+		return err
+	}
+
+	tq.mu.Lock()
 	mapCopy := *task
 	queueCopy := *task
 	tq.tasks[mapCopy.ID] = &mapCopy
-	tq.queue <- &queueCopy
+	tq.push(&queueCopy, task.CreatedAt)
+	tq.mu.Unlock()
 	atomic.AddInt64(&tq.metrics.TotalEnqueued, 1)
+	tq.signal()
 
 	return nil
 }
 
-// Dequeue removes a task from the queue
+// Dequeue removes the next task to run from the queue, blocking until one
+// is queued or ctx is done.
 func (tq *TaskQueue) Dequeue(ctx context.Context) (*TranscodeTask, error) {
-	select {
-	case task := <-tq.queue:
-		return task, nil
-	case <-ctx.Done():
-		return nil, ctx.Err()
+	for {
+		tq.mu.Lock()
+		task := tq.pop(time.Now())
+		more := len(tq.queued) > 0
+		tq.mu.Unlock()
+
+		if task != nil {
+			if more {
+				tq.signal()
+			}
+			return task, nil
+		}
+		if tq.shared {
+			tq.nudge()
+		}
+		select {
+		case <-tq.ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 	}
 }
 
 // GetTask returns a task by ID
 func (tq *TaskQueue) GetTask(taskID string) (*TranscodeTask, error) {
 	tq.mu.RLock()
-	defer tq.mu.RUnlock()
-
 	task, exists := tq.tasks[taskID]
-	if !exists {
-		return nil, fmt.Errorf("task not found: %s", taskID)
+	var copyData TranscodeTask
+	if exists {
+		copyData = *task
 	}
+	tq.mu.RUnlock()
 
-	copyData := *task
+	if !exists {
+		// Another transcoder sharing the store may be running it.
+		return tq.lookup(taskID)
+	}
 	return &copyData, nil
 }
 
 // UpdateTask updates a task
 func (tq *TaskQueue) UpdateTask(task *TranscodeTask) error {
 	tq.mu.Lock()
-	defer tq.mu.Unlock()
-
 	tq.tasks[task.ID] = task
+	snapshot := *task
+	tq.mu.Unlock()
+
+	tq.record(snapshot)
 	return nil
 }
 
-func (tq *TaskQueue) TransitionStatus(taskID string, fn func(*TranscodeTask)) error {
+// UpdateProgress records FFmpeg progress on a processing task. Progress
+// arriving after the task left processing is dropped.
+func (tq *TaskQueue) UpdateProgress(taskID string, progress *TranscodeProgress) error {
 	tq.mu.Lock()
 	defer tq.mu.Unlock()
 
@@ -454,25 +694,53 @@ func (tq *TaskQueue) TransitionStatus(taskID string, fn func(*TranscodeTask)) er
 	if !exists {
 		return fmt.Errorf("task not found: %s", taskID)
 	}
+	if task.Status != TaskStatusProcessing {
+		return nil
+	}
+	task.Progress = progress.Progress
+	task.Speed = progress.Speed
+	return nil
+}
+
+func (tq *TaskQueue) TransitionStatus(taskID string, fn func(*TranscodeTask)) error {
+	tq.mu.Lock()
+	task, exists := tq.tasks[taskID]
+	if !exists {
+		tq.mu.Unlock()
+		return fmt.Errorf("task not found: %s", taskID)
+	}
 	fn(task)
+	snapshot := *task
+	tq.mu.Unlock()
+
+	tq.record(snapshot)
 	return nil
 }
 
 // CancelTask cancels a task
 func (tq *TaskQueue) CancelTask(taskID string) error {
 	tq.mu.Lock()
-	defer tq.mu.Unlock()
-
 	task, exists := tq.tasks[taskID]
 	if !exists {
-		return fmt.Errorf("task not found: %s", taskID)
+		tq.mu.Unlock()
+		return tq.cancelStored(taskID)
 	}
 
 	if task.Status == TaskStatusProcessing {
+		tq.mu.Unlock()
 		return fmt.Errorf("cannot cancel processing task")
 	}
+	if taskFinished(task.Status) {
+		tq.mu.Unlock()
+		return fmt.Errorf("cannot cancel %s task", task.Status)
+	}
 
 	task.Status = TaskStatusCancelled
+	tq.removeQueued(taskID)
+	snapshot := *task
+	tq.mu.Unlock()
+
+	tq.record(snapshot)
 	return nil
 }
 
@@ -481,7 +749,7 @@ func (tq *TaskQueue) Len() int {
 	tq.mu.RLock()
 	defer tq.mu.RUnlock()
 
-	return len(tq.queue)
+	return len(tq.queued)
 }
 
 // WorkerPool methods
@@ -490,44 +758,103 @@ func (tq *TaskQueue) Len() int {
 func (wp *WorkerPool) Start(ctx context.Context, workerCount int) error {
 	wp.ctx, wp.cancel = context.WithCancel(ctx)
 
+	wp.mu.Lock()
 	for i := 0; i < workerCount; i++ {
-		worker := &Worker{
-			ID:     fmt.Sprintf("worker-%d", i),
-			Status: WorkerStatusIdle,
-		}
-		wp.workers = append(wp.workers, worker)
-
-		wp.wg.Add(1)
-		go wp.runWorker(worker)
+		wp.workers = append(wp.workers, newIdleWorker(i))
 	}
-
 	wp.metrics.TotalWorkers = workerCount
 	wp.metrics.ActiveWorkers = workerCount
 	wp.metrics.IdleWorkers = workerCount
+	wp.mu.Unlock()
+
+	// One task waits in the pool's queue; the rest stay in the task queue,
+	// where a higher-priority task can still overtake them.
+	wp.pool = core.NewWorkerPool(core.WorkerPoolConfig[*TranscodeTask]{
+		Name:      "transcoder",
+		Workers:   workerCount,
+		QueueSize: 1,
+		OnDiscard: wp.returnTask,
+	}, func(_ context.Context, idx int, task *TranscodeTask) {
+		defer wp.releaseJob(task)
+		wp.processTask(wp.workerAt(idx), task)
+	})
+	if err := wp.pool.Start(wp.ctx); err != nil {
+		return err
+	}
+	go func() { _ = wp.pool.Feed(wp.ctx, wp.nextTask) }()
 
 	wp.logger.Info("Worker pool started", zap.Int("workers", workerCount))
 	return nil
 }
 
-// Stop stops the worker pool
-func (wp *WorkerPool) Stop(ctx context.Context) error {
-	wp.cancel()
-	done := make(chan struct{})
+// nextTask takes a job slot, if the pool is limited by acquireJob, and
+// dequeues a task. Taking the slot first leaves the task queued while the
+// limit is reached.
+func (wp *WorkerPool) nextTask(ctx context.Context) (*TranscodeTask, error) {
+	if wp.acquireJob == nil {
+		return wp.taskQueue.Dequeue(ctx)
+	}
+	release, err := wp.acquireJob(ctx)
+	if err != nil {
+		return nil, err
+	}
+	task, err := wp.taskQueue.Dequeue(ctx)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	wp.mu.Lock()
+	if wp.jobSlots == nil {
+		wp.jobSlots = make(map[*TranscodeTask]func())
+	}
+	wp.jobSlots[task] = release
+	wp.mu.Unlock()
+	return task, nil
+}
+
+// returnTask puts a task the pool will not run back in the task queue and
+// gives back its job slot.
+func (wp *WorkerPool) returnTask(task *TranscodeTask) {
+	wp.releaseJob(task)
+	wp.taskQueue.requeue(task)
+}
 
-	go func() {
-		wp.wg.Wait()
-		close(done)
-	}()
+// releaseJob gives back the job slot taken for task by nextTask.
+func (wp *WorkerPool) releaseJob(task *TranscodeTask) {
+	wp.mu.Lock()
+	release := wp.jobSlots[task]
+	delete(wp.jobSlots, task)
+	wp.mu.Unlock()
+	if release != nil {
+		release()
+	}
+}
 
-	select {
-	case <-done:
+// Stop stops the worker pool. Running tasks may finish while ctx allows,
+// less a reserve in which those still running are checkpointed: stopped
+// and put back to pending for the next transcoder to claim.
+func (wp *WorkerPool) Stop(ctx context.Context) error {
+	if wp.pool == nil {
+		wp.cancel()
 		return nil
-	case <-ctx.Done():
-		return ctx.Err()
 	}
+	finishCtx := ctx
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		finishCtx, cancel = context.WithDeadline(ctx, deadline.Add(-checkpointReserve))
+		defer cancel()
+	}
+	err := wp.pool.Stop(finishCtx)
+	if err != nil {
+		wp.checkpointRunning()
+		err = wp.pool.Stop(ctx)
+	}
+	wp.cancel()
+	return err
 }
 
-// Scale scales the worker pool
+// Scale scales the worker pool. Workers removed by a scale-down finish
+// their current task before exiting.
 func (wp *WorkerPool) Scale(targetCount int) error {
 	wp.mu.Lock()
 	defer wp.mu.Unlock()
@@ -535,76 +862,92 @@ func (wp *WorkerPool) Scale(targetCount int) error {
 	currentCount := len(wp.workers)
 
 	if targetCount > currentCount {
-		// Scale up
 		for i := currentCount; i < targetCount; i++ {
-			worker := &Worker{
-				ID:     fmt.Sprintf("worker-%d", i),
-				Status: WorkerStatusIdle,
-			}
-			wp.workers = append(wp.workers, worker)
-
-			wp.wg.Add(1)
-			go wp.runWorker(worker)
+			wp.workers = append(wp.workers, newIdleWorker(i))
 		}
 		wp.metrics.TotalWorkers = targetCount
 		wp.metrics.ActiveWorkers = targetCount
 		wp.metrics.IdleWorkers = targetCount
 	} else if targetCount < currentCount {
-		// Scale down - mark excess workers for graceful shutdown
-		excess := wp.workers[targetCount:]
-		for _, w := range excess {
-			w.mu.Lock()
-			w.Status = WorkerStatusUnhealthy // signal shutdown
-			w.mu.Unlock()
-		}
 		wp.workers = wp.workers[:targetCount]
 		wp.metrics.TotalWorkers = targetCount
 	}
+	if wp.pool != nil {
+		wp.pool.Resize(targetCount)
+	}
 
 	wp.logger.Info("Worker pool scaled", zap.Int("target", targetCount), zap.Int("current", currentCount))
 	return nil
 }
 
-// runWorker runs a worker
-func (wp *WorkerPool) runWorker(worker *Worker) {
-	defer wp.wg.Done()
+func newIdleWorker(idx int) *Worker {
+	return &Worker{
+		ID:     fmt.Sprintf("worker-%d", idx),
+		Status: WorkerStatusIdle,
+	}
+}
 
-	for {
-		// Check if this worker was marked for shutdown during scale-down
-		worker.mu.RLock()
-		status := worker.Status
-		worker.mu.RUnlock()
-		if status == WorkerStatusUnhealthy {
-			return
-		}
+// workerAt returns the bookkeeping entry for pool worker idx. A worker that
+// was scaled away while its task was in flight gets a detached entry.
+func (wp *WorkerPool) workerAt(idx int) *Worker {
+	wp.mu.RLock()
+	defer wp.mu.RUnlock()
 
-		select {
-		case <-wp.ctx.Done():
-			return
-		default:
-			task, err := wp.taskQueue.Dequeue(wp.ctx)
-			if err != nil {
-				continue
-			}
+	if idx < len(wp.workers) {
+		return wp.workers[idx]
+	}
+	return newIdleWorker(idx)
+}
 
-			wp.processTask(worker, task)
+// enqueueRetry re-enqueues a failed task, deferring it while the retry
+// budget is exhausted.
+func (wp *WorkerPool) enqueueRetry(task *TranscodeTask) {
+	enqueue := func() {
+		if err := wp.taskQueue.Enqueue(task); err != nil {
+			wp.logger.Error("failed to re-enqueue task for retry", zap.String("task_id", task.ID), zap.Error(err))
 		}
 	}
+	wait := wp.retryBudget.Reserve("transcoder")
+	if wait <= 0 {
+		enqueue()
+		return
+	}
+	wp.logger.Debug("Retry budget exhausted, deferring task retry",
+		zap.String("task_id", task.ID), zap.Duration("wait", wait))
+	time.AfterFunc(wait, func() {
+		if wp.ctx != nil && wp.ctx.Err() != nil {
+			return
+		}
+		enqueue()
+	})
 }
 
 // processTask processes a transcoding task
 func (wp *WorkerPool) processTask(worker *Worker, task *TranscodeTask) {
-	worker.mu.Lock()
-	worker.Status = WorkerStatusBusy
-	worker.CurrentTask = task
-	worker.mu.Unlock()
+	ctx, release := wp.startRun(task.ID)
+	defer release()
 
+	// A task cancelled between leaving the queue and starting is skipped.
 	now := time.Now()
+	cancelled := false
 	_ = wp.taskQueue.TransitionStatus(task.ID, func(t *TranscodeTask) {
+		if t.Status == TaskStatusCancelled {
+			cancelled = true
+			return
+		}
 		t.Status = TaskStatusProcessing
 		t.WorkerID = worker.ID
+		t.Instance = wp.taskQueue.owner
 		t.StartedAt = &now
 	})
+	if cancelled {
+		return
+	}
+
+	worker.mu.Lock()
+	worker.Status = WorkerStatusBusy
+	worker.CurrentTask = task
+	worker.mu.Unlock()
 
 	{
 		pubCtx, pubCancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -616,23 +959,34 @@ func (wp *WorkerPool) processTask(worker *Worker, task *TranscodeTask) {
 	}
 
 	startTime := time.Now()
-	if err := wp.transcode(task); err != nil {
+	if err := wp.transcode(ctx, task); err != nil && errors.Is(context.Cause(ctx), errTaskCancelled) {
+		wp.finishCancelled(task)
+	} else if err != nil && errors.Is(context.Cause(ctx), errTaskCheckpointed) {
+		wp.finishCheckpointed(task)
+	} else if err != nil {
 		errMsg := err.Error()
-		shouldRetry := false
+		reason := classifyTaskFailure(ctx, err)
+		var retry *TranscodeTask
 		_ = wp.taskQueue.TransitionStatus(task.ID, func(t *TranscodeTask) {
 			t.Status = TaskStatusFailed
 			t.Error = errMsg
+			t.FailureReason = reason
+			t.Retryable = reason.Retryable()
 			t.RetryCount++
-			if t.RetryCount < t.MaxRetries {
+			if t.Retryable && t.RetryCount < t.MaxRetries {
 				t.Status = TaskStatusPending
-				shouldRetry = true
+				cp := *t
+				retry = &cp
 			}
 		})
 
-		if shouldRetry {
-			if err := wp.taskQueue.Enqueue(task); err != nil {
-				wp.logger.Error("failed to re-enqueue task for retry", zap.String("task_id", task.ID), zap.Error(err))
-			}
+		if retry != nil {
+			wp.enqueueRetry(retry)
+		} else if !reason.Retryable() {
+			wp.logger.Warn("Transcode task failed permanently",
+				zap.String("task_id", task.ID),
+				zap.String("reason", string(reason)),
+				zap.Error(err))
 		}
 
 		atomic.AddInt64(&wp.taskQueue.metrics.TotalFailed, 1)
@@ -649,8 +1003,11 @@ func (wp *WorkerPool) processTask(worker *Worker, task *TranscodeTask) {
 		_ = wp.taskQueue.TransitionStatus(task.ID, func(t *TranscodeTask) {
 			t.Status = TaskStatusCompleted
 			t.CompletedAt = &completedAt
+			t.Error = ""
+			t.FailureReason = ""
+			t.Retryable = false
 		})
-		wp.taskQueue.metrics.TotalProcessed++
+		atomic.AddInt64(&wp.taskQueue.metrics.TotalProcessed, 1)
 
 		{
 			pubCtx, pubCancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -674,20 +1031,80 @@ func (wp *WorkerPool) processTask(worker *Worker, task *TranscodeTask) {
 }
 
 // transcode performs the actual transcoding using FFmpeg
-func (wp *WorkerPool) transcode(task *TranscodeTask) error {
+func (wp *WorkerPool) transcode(ctx context.Context, task *TranscodeTask) error {
 	if wp.ffmpeg == nil {
 		return fmt.Errorf("FFmpeg transcoder not initialized")
 	}
 
-	// Build output directory from task
-	outputDir := os.TempDir() + "/streamgate-transcode-" + task.ID
+	outputDir := wp.outputDir(task.ID)
 
+	var lastReported time.Time
 	callback := func(p *TranscodeProgress) {
-		task.Progress = p.Progress
-		_ = wp.taskQueue.UpdateTask(task)
+		_ = wp.taskQueue.UpdateProgress(task.ID, p)
+		if time.Since(lastReported) >= progressReportInterval {
+			lastReported = time.Now()
+			wp.reportProgress(task.ID, p)
+		}
+	}
+
+	if task.AutoLadder && wp.ffmpeg.config.PerTitle != nil {
+		wp.applyPerTitleLadder(ctx, task)
+	}
+
+	if wp.keyStore != nil {
+		// Fail closed: with encryption on, nothing is published in the clear.
+		if task.FileID == "" {
+			return fmt.Errorf("content encryption requires a file ID")
+		}
+		key, err := wp.keyStore.Issue(task.FileID)
+		if err != nil {
+			return fmt.Errorf("failed to issue content key: %w", err)
+		}
+		ctx = WithHLSKey(ctx, &HLSKey{URI: keys.DeliveryURI(task.FileID), Key: key.Key, IV: key.IV})
+	}
+	if HasDRMProfile(task.Profiles) {
+		if wp.keyStore == nil {
+			return fmt.Errorf("DRM profiles require content encryption")
+		}
+		key, err := wp.keyStore.IssueDRM(task.FileID)
+		if err != nil {
+			return fmt.Errorf("failed to issue DRM key: %w", err)
+		}
+		ctx = WithDRMKey(ctx, &DRMKey{ContentID: task.FileID, KeyID: key.KeyID, Key: key.Key})
+	}
+	if len(task.Subtitles) > 0 {
+		ctx = WithSubtitles(ctx, task.Subtitles)
 	}
 
-	return wp.ffmpeg.TranscodeToHLS(wp.ctx, task.FilePath, outputDir, task.Profiles, callback, nil)
+	if !wp.ffmpeg.config.AllowPartialVariants {
+		return wp.ffmpeg.TranscodeToHLS(ctx, task.FilePath, outputDir, task.Profiles, callback, nil)
+	}
+	result, err := wp.ffmpeg.TranscodeToHLSPartial(ctx, task.FilePath, outputDir, task.Profiles, callback, nil)
+	if result != nil && len(result.Failed) > 0 {
+		_ = wp.taskQueue.TransitionStatus(task.ID, func(t *TranscodeTask) {
+			t.FailedVariants = result.FailedResolutions()
+		})
+	}
+	return err
+}
+
+// applyPerTitleLadder replaces a task's default ladder with one derived
+// from its source, once: retries reuse the derived ladder. Analysis is best
+// effort, and the task keeps the default ladder when it fails.
+func (wp *WorkerPool) applyPerTitleLadder(ctx context.Context, task *TranscodeTask) {
+	ladder, err := wp.ffmpeg.PerTitleLadder(ctx, task.FilePath, task.Profiles, *wp.ffmpeg.config.PerTitle)
+	if err != nil {
+		wp.logger.Warn("Per-title analysis failed, using the default ladder",
+			zap.String("task_id", task.ID),
+			zap.Error(err))
+		return
+	}
+	task.Profiles = ladder
+	task.AutoLadder = false
+	_ = wp.taskQueue.TransitionStatus(task.ID, func(t *TranscodeTask) {
+		t.Profiles = ladder
+		t.AutoLadder = false
+	})
 }
 
 // HealthCheck performs health checks on workers
@@ -2,9 +2,13 @@ package transcoder
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/rtcdance/streamgate/pkg/core"
+	"github.com/rtcdance/streamgate/pkg/core/config"
 	"github.com/rtcdance/streamgate/pkg/core/event"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -12,12 +16,7 @@ import (
 )
 
 func newTestTaskQueue(size int) *TaskQueue {
-	return &TaskQueue{
-		tasks:   make(map[string]*TranscodeTask),
-		queue:   make(chan *TranscodeTask, size),
-		maxSize: size,
-		metrics: &QueueMetrics{},
-	}
+	return NewTaskQueue(size, 0)
 }
 
 func TestTaskQueue_EnqueueAndCancel(t *testing.T) {
@@ -192,6 +191,40 @@ func TestNewTranscoderPlugin(t *testing.T) {
 	assert.Equal(t, []string{"storage", "event-bus"}, plugin.Dependencies())
 }
 
+func TestTranscoderPlugin_InitRejectsInvalidDefaultLadder(t *testing.T) {
+	kernel, err := core.NewMicrokernel(&config.Config{Mode: "monolith"}, zap.NewNop())
+	require.NoError(t, err)
+
+	plugin := NewTranscoderPlugin(&TranscoderConfig{
+		WorkerPoolSize:  1,
+		MaxQueueSize:    5,
+		ScalingPolicy:   &ScalingPolicy{MinWorkers: 1, MaxWorkers: 1},
+		DefaultProfiles: []TranscodeProfile{{Resolution: "1279x720", Bitrate: "2500k"}},
+	})
+	err = plugin.Init(context.Background(), kernel)
+	assert.ErrorIs(t, err, ErrInvalidLadder)
+}
+
+func TestTranscoderPlugin_InitUsesBuiltinLadder(t *testing.T) {
+	kernel, err := core.NewMicrokernel(&config.Config{Mode: "monolith"}, zap.NewNop())
+	require.NoError(t, err)
+
+	plugin := NewTranscoderPlugin(&TranscoderConfig{
+		WorkerPoolSize: 1,
+		MaxQueueSize:   5,
+		ScalingPolicy:  &ScalingPolicy{MinWorkers: 1, MaxWorkers: 1},
+	})
+	require.NoError(t, plugin.Init(context.Background(), kernel))
+
+	task := &TranscodeTask{ID: "task-default"}
+	require.NoError(t, plugin.SubmitTask(task))
+	assert.Equal(t, BuiltinLadder(), task.Profiles)
+	assert.True(t, task.AutoLadder)
+
+	task.Profiles[0].Bitrate = "1k"
+	assert.Equal(t, BuiltinLadder(), plugin.config.DefaultProfiles, "tasks must not alias the configured ladder")
+}
+
 func TestTranscoderPlugin_Destroy(t *testing.T) {
 	plugin := NewTranscoderPlugin(&TranscoderConfig{
 		WorkerPoolSize: 1,
@@ -329,6 +362,110 @@ func TestParseTime(t *testing.T) {
 	}
 }
 
+func TestParseProbeOutput_Rotation(t *testing.T) {
+	tests := []struct {
+		fixture       string
+		rotation      int
+		width, height int
+		displayW      int
+		displayH      int
+	}{
+		{"ffprobe_rotated_90.json", 90, 1920, 1080, 1080, 1920},
+		{"ffprobe_rotate_tag_270.json", 270, 1280, 720, 720, 1280},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.fixture, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tc.fixture))
+			require.NoError(t, err)
+
+			info, err := parseProbeOutput(data)
+			require.NoError(t, err)
+			assert.Equal(t, tc.rotation, info.Rotation)
+			assert.Equal(t, tc.width, info.Width)
+			assert.Equal(t, tc.height, info.Height)
+			assert.Equal(t, tc.displayW, info.DisplayWidth)
+			assert.Equal(t, tc.displayH, info.DisplayHeight)
+			assert.True(t, info.IsPortrait())
+			assert.Equal(t, tc.displayW, info.ShortSide())
+		})
+	}
+}
+
+func TestStreamRotation(t *testing.T) {
+	matrix := func(deg float64) ffprobeStream {
+		return ffprobeStream{SideDataList: []ffprobeSideData{{SideDataType: "Display Matrix", Rotation: deg}}}
+	}
+	tests := []struct {
+		name   string
+		stream ffprobeStream
+		want   int
+	}{
+		{"none", ffprobeStream{}, 0},
+		{"matrix -90", matrix(-90), 90},
+		{"matrix 90", matrix(90), 270},
+		{"matrix 180", matrix(180), 180},
+		{"matrix -180", matrix(-180), 180},
+		{"matrix near quarter turn", matrix(-89.98), 90},
+		{"tag 90", ffprobeStream{Tags: map[string]string{"rotate": "90"}}, 90},
+		{"tag -90", ffprobeStream{Tags: map[string]string{"rotate": "-90"}}, 270},
+		{"tag garbage", ffprobeStream{Tags: map[string]string{"rotate": "sideways"}}, 0},
+		{"matrix wins over tag", ffprobeStream{
+			Tags:         map[string]string{"rotate": "180"},
+			SideDataList: []ffprobeSideData{{SideDataType: "Display Matrix", Rotation: -90}},
+		}, 90},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, streamRotation(tc.stream))
+		})
+	}
+}
+
+func TestDisplayDimensions_SampleAspectRatio(t *testing.T) {
+	// Anamorphic DV: 720x480 coded with 32:27 pixels displays as 854x480.
+	w, h := displayDimensions(ffprobeStream{Width: 720, Height: 480, SampleAspectRatio: "32:27"}, 0)
+	assert.Equal(t, 854, w)
+	assert.Equal(t, 480, h)
+
+	w, h = displayDimensions(ffprobeStream{Width: 1920, Height: 1080, SampleAspectRatio: "0:1"}, 0)
+	assert.Equal(t, 1920, w)
+	assert.Equal(t, 1080, h)
+}
+
+func TestHLSVideoFilter(t *testing.T) {
+	profile := defaultProfileMap["720p"]
+	tests := []struct {
+		name string
+		info *VideoInfo
+		want string
+	}{
+		{"no probe info", nil, "scale=1280:720,setsar=1"},
+		{"landscape", &VideoInfo{DisplayWidth: 1920, DisplayHeight: 1080}, "scale=1280:720,setsar=1"},
+		{"rotated 90", &VideoInfo{Rotation: 90, DisplayWidth: 1080, DisplayHeight: 1920}, "transpose=clock,scale=720:1280,setsar=1"},
+		{"rotated 270", &VideoInfo{Rotation: 270, DisplayWidth: 720, DisplayHeight: 1280}, "transpose=cclock,scale=720:1280,setsar=1"},
+		{"upside down", &VideoInfo{Rotation: 180, DisplayWidth: 1920, DisplayHeight: 1080}, "hflip,vflip,scale=1280:720,setsar=1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, hlsVideoFilter(profile, tc.info))
+		})
+	}
+}
+
+func TestFFmpegTranscoder_GenerateHLSMasterPlaylist_Portrait(t *testing.T) {
+	tmpDir := t.TempDir()
+	ft := NewFFmpegTranscoder(&FFmpegConfig{TempDir: t.TempDir()}, zap.NewNop())
+
+	profiles := []TranscodeProfile{defaultProfileMap["720p"]}
+	require.NoError(t, ft.generateHLSMasterPlaylist(tmpDir, profiles, nil, true))
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, "master.m3u8"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "RESOLUTION=720x1280")
+	assert.Contains(t, string(data), "1280x720.m3u8", "variant playlist names stay keyed by profile")
+}
+
 func TestFFmpegTranscoder_CleanupTempFiles(t *testing.T) {
 	config := &FFmpegConfig{TempDir: t.TempDir()}
 	ft := NewFFmpegTranscoder(config, zap.NewNop())
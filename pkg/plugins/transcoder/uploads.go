@@ -0,0 +1,63 @@
+package transcoder
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/rtcdance/streamgate/pkg/core/event"
+)
+
+// subscribeUploads makes the plugin create the transcode job each
+// upload.completed event asks for.
+func (tp *TranscoderPlugin) subscribeUploads(ctx context.Context) error {
+	if tp.eventBus == nil {
+		return nil
+	}
+	id, err := tp.eventBus.Subscribe(ctx, event.EventTypeUploadCompleted, tp.handleUploadCompleted)
+	if err != nil {
+		return err
+	}
+	tp.uploadSub = id
+	return nil
+}
+
+// handleUploadCompleted submits the job requested by an upload.completed
+// event under the job ID the uploader already returned to its client. A
+// redelivered event finds the job in place and is ignored.
+func (tp *TranscoderPlugin) handleUploadCompleted(_ context.Context, e *event.Event) error {
+	jobID, _ := e.Data["job_id"].(string)
+	if jobID == "" {
+		return nil
+	}
+	inputURL, _ := e.Data["input_url"].(string)
+	contentID, _ := e.Data["content_id"].(string)
+	if inputURL == "" {
+		tp.logger.Warn("Upload completed without a transcode input",
+			zap.String("job_id", jobID), zap.String("content_id", contentID))
+		return nil
+	}
+	if _, err := tp.GetTaskStatus(jobID); err == nil {
+		return nil
+	}
+
+	err := tp.SubmitTask(&TranscodeTask{
+		ID:         jobID,
+		FileID:     contentID,
+		FilePath:   inputURL,
+		Status:     TaskStatusPending,
+		CreatedAt:  time.Now(),
+		MaxRetries: 3,
+	})
+	if err != nil {
+		tp.logger.Error("Failed to create transcode job for upload",
+			zap.String("job_id", jobID),
+			zap.String("content_id", contentID),
+			zap.Error(err))
+		return err
+	}
+	tp.logger.Info("Created transcode job for upload",
+		zap.String("job_id", jobID), zap.String("content_id", contentID))
+	return nil
+}
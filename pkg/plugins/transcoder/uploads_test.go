@@ -0,0 +1,57 @@
+package transcoder
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTranscoderPlugin_CreatesJobForCompletedUpload(t *testing.T) {
+	bus, err := event.NewMemoryEventBus()
+	require.NoError(t, err)
+	plugin := &TranscoderPlugin{
+		config:    &TranscoderConfig{},
+		taskQueue: newTestTaskQueue(10),
+		eventBus:  bus,
+		logger:    zap.NewNop(),
+	}
+	require.NoError(t, plugin.subscribeUploads(context.Background()))
+
+	completed := &event.Event{
+		ID:   "upload-1",
+		Type: event.EventTypeUploadCompleted,
+		Data: map[string]interface{}{
+			"upload_id":  "upload-1",
+			"content_id": "content-1",
+			"job_id":     "job-1",
+			"input_url":  "https://storage.example.com/key?sig=1",
+		},
+	}
+	require.NoError(t, bus.Publish(context.Background(), completed))
+
+	var task *TranscodeTask
+	require.Eventually(t, func() bool {
+		task, err = plugin.GetTaskStatus("job-1")
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "content-1", task.FileID)
+	assert.Equal(t, "https://storage.example.com/key?sig=1", task.FilePath)
+	assert.Equal(t, TaskStatusPending, task.Status)
+	assert.NotEmpty(t, task.Profiles, "jobs get the default ladder")
+
+	// Redelivery does not create a second job.
+	require.NoError(t, plugin.handleUploadCompleted(context.Background(), completed))
+	assert.Equal(t, 1, plugin.taskQueue.Len())
+
+	// Uploads without a requested job are ignored.
+	require.NoError(t, plugin.handleUploadCompleted(context.Background(), &event.Event{
+		Type: event.EventTypeUploadCompleted,
+		Data: map[string]interface{}{"upload_id": "upload-2", "content_id": "content-2"},
+	}))
+	assert.Equal(t, 1, plugin.taskQueue.Len())
+}
@@ -2,6 +2,7 @@ package upload
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"strconv"
@@ -11,6 +12,7 @@ import (
 	"github.com/rtcdance/streamgate/pkg/core"
 	"github.com/rtcdance/streamgate/pkg/monitoring"
 	"github.com/rtcdance/streamgate/pkg/service"
+	"github.com/rtcdance/streamgate/pkg/storage"
 
 	"go.uber.org/zap"
 )
@@ -43,10 +45,10 @@ func (h *UploadHandler) HealthHandler(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
 }
 
+// ReadyHandler reports the kernel's health checks, including the
+// dependencies this plugin registered.
 func (h *UploadHandler) ReadyHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
+	h.kernel.ReadyHandler(w, r)
 }
 
 func (h *UploadHandler) UploadHandler(w http.ResponseWriter, r *http.Request) {
@@ -116,6 +118,8 @@ func (h *UploadHandler) InitChunkedUploadHandler(w http.ResponseWriter, r *http.
 		Filename    string `json:"filename"`
 		TotalSize   int64  `json:"total_size"`
 		TotalChunks int    `json:"total_chunks"`
+		ChunkSize   int64  `json:"chunk_size"`
+		Checksum    string `json:"checksum"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -123,7 +127,7 @@ func (h *UploadHandler) InitChunkedUploadHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
-	uploadID, err := h.svc.InitiateChunkedUpload(ctx, req.Filename, req.TotalSize, req.TotalChunks, wallet)
+	uploadID, err := h.svc.InitiateChunkedUploadWithChecksum(ctx, req.Filename, req.TotalSize, req.TotalChunks, req.ChunkSize, req.Checksum, wallet)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
@@ -235,7 +239,11 @@ func (h *UploadHandler) CompleteUploadHandler(w http.ResponseWriter, r *http.Req
 	}
 
 	if err := h.svc.CompleteChunkedUpload(ctx, uploadID, req.TotalChunks); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		if errors.Is(err, service.ErrChecksumMismatch) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+		}
 		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
@@ -289,20 +297,28 @@ func (h *UploadHandler) CompleteUploadWithContentHandler(w http.ResponseWriter,
 		return
 	}
 
-	contentID, err := h.svc.CompleteUploadWithTx(ctx, uploadID)
+	contentID, jobID, err := h.svc.CompleteUploadWithJob(ctx, uploadID)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		if errors.Is(err, service.ErrUploadQuarantined) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+		}
 		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+	resp := map[string]interface{}{
 		"upload_id":  uploadID,
 		"content_id": contentID,
 		"status":     "processed",
-	})
+	}
+	if jobID != "" {
+		resp["transcoding_job_id"] = jobID
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
 }
 
 func (h *UploadHandler) GetUploadStatusHandler(w http.ResponseWriter, r *http.Request) {
@@ -338,6 +354,9 @@ func (h *UploadHandler) GetUploadStatusHandler(w http.ResponseWriter, r *http.Re
 		_ = json.NewEncoder(w).Encode(map[string]string{"error": "not authorized to view this upload"})
 		return
 	}
+	if info.ScanStatus, info.ScanSignature, err = h.svc.GetScanStatus(ctx, uploadID); err != nil {
+		h.logger.Warn("Failed to get scan status", zap.String("upload_id", uploadID), zap.Error(err))
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -482,6 +501,223 @@ func (h *UploadHandler) ChunkStatusesHandler(w http.ResponseWriter, r *http.Requ
 	})
 }
 
+// MissingChunksHandler reports which chunks have not been stored yet, so that
+// a client can resume an interrupted upload. total_chunks defaults to the
+// count given when the upload was initiated.
+func (h *UploadHandler) MissingChunksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	ctx := r.Context()
+	wallet := r.Header.Get("X-Wallet-Address")
+	if wallet == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "wallet authentication required"})
+		return
+	}
+
+	uploadID := r.URL.Query().Get("upload_id")
+	if uploadID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "missing upload_id"})
+		return
+	}
+	var totalChunks int
+	if v := r.URL.Query().Get("total_chunks"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > h.svc.MaxChunks() {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid total_chunks"})
+			return
+		}
+		totalChunks = n
+	}
+
+	info, err := h.svc.GetUploadStatus(ctx, uploadID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if !strings.EqualFold(info.OwnerID, wallet) {
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "not authorized"})
+		return
+	}
+
+	status, err := h.svc.GetResumeStatus(ctx, uploadID, totalChunks)
+	if errors.Is(err, service.ErrInvalidRequest) {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(status)
+}
+
+// MultipartInitHandler starts a multipart upload that the client sends
+// straight to object storage through presigned part URLs. It answers 501
+// when the store cannot do this; clients then use /init and /chunk.
+func (h *UploadHandler) MultipartInitHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	ctx := r.Context()
+	wallet := r.Header.Get("X-Wallet-Address")
+	if wallet == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "wallet authentication required"})
+		return
+	}
+
+	var req struct {
+		Filename    string `json:"filename"`
+		TotalSize   int64  `json:"total_size"`
+		ContentType string `json:"content_type"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	plan, err := h.svc.InitiateMultipartUpload(ctx, req.Filename, req.TotalSize, req.ContentType, wallet)
+	if err != nil {
+		w.WriteHeader(multipartErrorStatus(err))
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(plan)
+}
+
+// MultipartCompleteHandler assembles the parts of a direct multipart upload
+// from the part numbers and ETags the client collected.
+func (h *UploadHandler) MultipartCompleteHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	ctx := r.Context()
+	wallet := r.Header.Get("X-Wallet-Address")
+	if wallet == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "wallet authentication required"})
+		return
+	}
+
+	var req struct {
+		UploadID string                  `json:"upload_id"`
+		Parts    []storage.CompletedPart `json:"parts"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
+		return
+	}
+	if req.UploadID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "missing upload_id"})
+		return
+	}
+
+	info, err := h.svc.GetUploadStatus(ctx, req.UploadID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if !strings.EqualFold(info.OwnerID, wallet) {
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "not authorized to complete this upload"})
+		return
+	}
+
+	if err := h.svc.CompleteMultipartUpload(ctx, req.UploadID, req.Parts); err != nil {
+		w.WriteHeader(multipartErrorStatus(err))
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"upload_id": req.UploadID,
+		"status":    "completed",
+	})
+}
+
+// MultipartAbortHandler discards a direct multipart upload and its parts.
+func (h *UploadHandler) MultipartAbortHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	ctx := r.Context()
+	wallet := r.Header.Get("X-Wallet-Address")
+	if wallet == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "wallet authentication required"})
+		return
+	}
+
+	uploadID := r.URL.Query().Get("upload_id")
+	if uploadID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "missing upload_id"})
+		return
+	}
+
+	info, err := h.svc.GetUploadStatus(ctx, uploadID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if !strings.EqualFold(info.OwnerID, wallet) {
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "not authorized to abort this upload"})
+		return
+	}
+
+	if err := h.svc.AbortMultipartUpload(ctx, uploadID); err != nil {
+		w.WriteHeader(multipartErrorStatus(err))
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func multipartErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrMultipartNotConfigured):
+		return http.StatusNotImplemented
+	case errors.Is(err, service.ErrInvalidRequest):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 func (h *UploadHandler) DeleteUploadHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
 		w.WriteHeader(http.StatusMethodNotAllowed)
@@ -525,6 +761,75 @@ func (h *UploadHandler) DeleteUploadHandler(w http.ResponseWriter, r *http.Reque
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// UploadSubtitleHandler attaches a SubRip or WebVTT file, sent as the
+// multipart "file" field, to content_id as its subtitles in language.
+func (h *UploadHandler) UploadSubtitleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	ctx := r.Context()
+	wallet := r.Header.Get("X-Wallet-Address")
+	if wallet == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "wallet authentication required"})
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, service.MaxSubtitleSize+64<<10)
+	if err := r.ParseMultipartForm(service.MaxSubtitleSize); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to parse form"})
+		return
+	}
+	contentID := r.FormValue("content_id")
+	language := r.FormValue("language")
+	if contentID == "" || language == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "missing content_id or language"})
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "no file provided"})
+		return
+	}
+	defer func() { _ = file.Close() }()
+	data, err := io.ReadAll(io.LimitReader(file, service.MaxSubtitleSize+1))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to read file"})
+		return
+	}
+
+	track, err := h.svc.UploadSubtitle(ctx, contentID, language, data, wallet)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, service.ErrInvalidSubtitle), errors.Is(err, service.ErrInvalidRequest):
+			status = http.StatusBadRequest
+		case errors.Is(err, service.ErrNotContentOwner):
+			status = http.StatusForbidden
+		case errors.Is(err, service.ErrNotFound):
+			status = http.StatusNotFound
+		}
+		if status == http.StatusInternalServerError {
+			h.logger.Error("Subtitle upload failed", zap.String("content_id", contentID), zap.Error(err))
+		}
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(track)
+}
+
 func (h *UploadHandler) NotFoundHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusNotFound)
@@ -160,6 +160,56 @@ func TestUploadHandler_UploadHandler_ServiceError(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, rec.Code)
 }
 
+func subtitleRequest(t *testing.T, fields map[string]string, file string) *http.Request {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	for k, v := range fields {
+		require.NoError(t, writer.WriteField(k, v))
+	}
+	if file != "" {
+		part, err := writer.CreateFormFile("file", "subs.srt")
+		require.NoError(t, err)
+		_, _ = part.Write([]byte(file))
+	}
+	require.NoError(t, writer.Close())
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/upload/subtitles", &buf)
+	req.Header.Set("X-Wallet-Address", "0x1234")
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestUploadHandler_UploadSubtitleHandler(t *testing.T) {
+	handler := newTestUploadHandlerWithSvc(t)
+	srt := "1\n00:00:01,000 --> 00:00:02,000\nHello\n"
+
+	rec := httptest.NewRecorder()
+	handler.UploadSubtitleHandler(rec, httptest.NewRequest(http.MethodGet, "/api/v1/upload/subtitles", http.NoBody))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	req := subtitleRequest(t, map[string]string{"content_id": "c1", "language": "eng"}, srt)
+	req.Header.Del("X-Wallet-Address")
+	rec = httptest.NewRecorder()
+	handler.UploadSubtitleHandler(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.UploadSubtitleHandler(rec, subtitleRequest(t, map[string]string{"content_id": "c1"}, srt))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.UploadSubtitleHandler(rec, subtitleRequest(t, map[string]string{"content_id": "c1", "language": "eng"}, ""))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.UploadSubtitleHandler(rec, subtitleRequest(t, map[string]string{"content_id": "c1", "language": "e n g"}, srt))
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "invalid languages are rejected before the content lookup")
+
+	rec = httptest.NewRecorder()
+	handler.UploadSubtitleHandler(rec, subtitleRequest(t, map[string]string{"content_id": "c1", "language": "eng"}, srt))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code, "the test service has no database")
+}
+
 func TestUploadHandler_InitChunkedUploadHandler_MethodNotAllowed(t *testing.T) {
 	handler := newTestUploadHandlerWithSvc(t)
 	req := httptest.NewRequest(http.MethodGet, "/init", http.NoBody)
@@ -546,6 +596,32 @@ func TestUploadHandler_ChunkStatusesHandler_ServiceError(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, rec.Code)
 }
 
+func TestUploadHandler_MissingChunksHandler(t *testing.T) {
+	handler := newTestUploadHandlerWithSvc(t)
+	tests := []struct {
+		name   string
+		query  string
+		wallet string
+		want   int
+	}{
+		{"no wallet", "?upload_id=upload-1&total_chunks=3", "", http.StatusUnauthorized},
+		{"missing id", "?total_chunks=3", "0x1234", http.StatusBadRequest},
+		{"bad total", "?upload_id=upload-1&total_chunks=0", "0x1234", http.StatusBadRequest},
+		{"unknown upload", "?upload_id=upload-1&total_chunks=3", "0x1234", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/missing-chunks"+tt.query, http.NoBody)
+			if tt.wallet != "" {
+				req.Header.Set("X-Wallet-Address", tt.wallet)
+			}
+			rec := httptest.NewRecorder()
+			handler.MissingChunksHandler(rec, req)
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
+
 func TestUploadHandler_DeleteUploadHandler_MethodNotAllowed(t *testing.T) {
 	handler := newTestUploadHandlerWithSvc(t)
 	req := httptest.NewRequest(http.MethodGet, "/delete", http.NoBody)
@@ -9,7 +9,9 @@ import (
 
 	"github.com/rtcdance/streamgate/pkg/core"
 	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/rtcdance/streamgate/pkg/health"
 	"github.com/rtcdance/streamgate/pkg/plugins/transcoder"
+	"github.com/rtcdance/streamgate/pkg/resilience"
 	"github.com/rtcdance/streamgate/pkg/service"
 	"github.com/rtcdance/streamgate/pkg/storage"
 
@@ -23,6 +25,7 @@ type UploadServer struct {
 	server         *http.Server
 	svc            *service.UploadService
 	transcodingSvc *service.TranscodingService
+	outbox         *service.EventOutbox
 }
 
 func NewUploadServer(cfg *config.Config, logger *zap.Logger, kernel *core.Microkernel) (*UploadServer, error) {
@@ -55,6 +58,16 @@ func NewUploadServer(cfg *config.Config, logger *zap.Logger, kernel *core.Microk
 	if presigner, ok := objStore.(service.PresignedURLer); ok {
 		svc.SetPresigner(presigner)
 	}
+	if mu, ok := storage.AsMultipartUploader(objStore); ok {
+		svc.SetMultipartUploader(mu)
+	}
+	scanner, err := service.NewUploadScanner(cfg.Upload.Scan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure upload scanning: %w", err)
+	}
+	if scanner != nil {
+		svc.SetScanner(scanner)
+	}
 	if cfg.Upload.MaxSize > 0 {
 		svc.SetMaxUploadSize(cfg.Upload.MaxSize)
 	}
@@ -70,12 +83,23 @@ func NewUploadServer(cfg *config.Config, logger *zap.Logger, kernel *core.Microk
 	if segStore != nil {
 		transcodingSvc = initTranscodingService(cfg, logger, pg, segStore)
 	}
-	svc.RegisterAutoTranscodeHook(service.AutoTranscodeHookDeps{
-		TranscodingSvc: transcodingSvc,
-		Presigner:      presigner,
-		Bucket:         cfg.Storage.Bucket,
-		Profiles:       cfg.Transcode.Profiles,
-	})
+	// With an event bus the transcoder plugin picks up completed uploads;
+	// the in-process auto-transcode hook is the fallback without one.
+	// The outbox publishes upload.completed from the transaction that
+	// creates the content, so the event survives a broker outage.
+	var outbox *service.EventOutbox
+	if kernel != nil && kernel.GetEventBus() != nil {
+		svc.SetEventBus(kernel.GetEventBus())
+		outbox = service.NewEventOutbox(pg, kernel.GetEventBus(), service.EventOutboxConfig{}, logger.Named("outbox"))
+		svc.SetEventOutbox(outbox)
+	} else {
+		svc.RegisterAutoTranscodeHook(service.AutoTranscodeHookDeps{
+			TranscodingSvc: transcodingSvc,
+			Presigner:      presigner,
+			Bucket:         cfg.Storage.Bucket,
+			Profiles:       cfg.Transcode.Profiles,
+		})
+	}
 
 	return &UploadServer{
 		config:         cfg,
@@ -83,6 +107,7 @@ func NewUploadServer(cfg *config.Config, logger *zap.Logger, kernel *core.Microk
 		kernel:         kernel,
 		svc:            svc,
 		transcodingSvc: transcodingSvc,
+		outbox:         outbox,
 	}, nil
 }
 
@@ -92,9 +117,14 @@ func initTranscodingService(cfg *config.Config, log *zap.Logger, db storage.DB,
 		FFprobePath: "ffprobe",
 		TempDir:     os.TempDir(),
 		Timeout:     30 * time.Minute,
+		Loudness:    transcoder.LoudnessFromConfig(cfg.Transcoding.Loudness),
 	}
 	ft := transcoder.NewFFmpegTranscoder(ffmpegCfg, log.Named("ffmpeg"))
-	videoTranscoder := &ffmpegAdapter{ft: ft, log: log.Named("ffmpeg")}
+	videoTranscoder := &ffmpegAdapter{
+		ft:         ft,
+		log:        log.Named("ffmpeg"),
+		storyboard: transcoder.StoryboardFromConfig(cfg.Transcoding.Storyboard),
+	}
 
 	var transcodingQueue service.TranscodingQueue
 	nq, natsErr := storage.NewNATSTranscodingQueue(cfg.NATS.URL, log.Named("nats-queue"))
@@ -110,6 +140,10 @@ func initTranscodingService(cfg *config.Config, log *zap.Logger, db storage.DB,
 		service.WithTranscoder(videoTranscoder),
 		service.WithStorage(objStorage),
 		service.WithLogger(log),
+		service.WithRetryBudget(resilience.NewRetryBudget(resilience.RetryBudgetConfig{
+			RatePerSecond: cfg.RetryBudget.RatePerSecond,
+			Burst:         cfg.RetryBudget.Burst,
+		})),
 	)
 	return svc
 }
@@ -118,11 +152,20 @@ func (s *UploadServer) GetService() *service.UploadService {
 	return s.svc
 }
 
+// transcodingQueueHealthCheck is the kernel health check on the queue
+// uploads hand their transcoding tasks to.
+const transcodingQueueHealthCheck = "upload.transcoding_queue"
+
 func (s *UploadServer) Start(ctx context.Context) error {
+	if s.kernel != nil && s.transcodingSvc != nil {
+		s.kernel.HealthChecks().RegisterCheck(transcodingQueueHealthCheck,
+			health.QueueDepthCheck(s.transcodingSvc.QueueDepth, s.config.Transcoding.QueueSize, 0), health.ProbeOptions()...)
+	}
 	handler := NewUploadHandler(s.svc, s.logger, s.kernel)
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", handler.HealthHandler)
+	mux.HandleFunc("/health/ready", handler.ReadyHandler)
 	mux.HandleFunc("/ready", handler.ReadyHandler)
 	mux.HandleFunc("/api/v1/upload", handler.UploadHandler)
 	mux.HandleFunc("/api/v1/upload/list", handler.ListUploadsHandler)
@@ -132,8 +175,13 @@ func (s *UploadServer) Start(ctx context.Context) error {
 	mux.HandleFunc("/api/v1/upload/complete-upload", handler.CompleteUploadWithContentHandler)
 	mux.HandleFunc("/api/v1/upload/status", handler.GetUploadStatusHandler)
 	mux.HandleFunc("/api/v1/upload/chunks", handler.ChunkStatusesHandler)
+	mux.HandleFunc("/api/v1/upload/missing-chunks", handler.MissingChunksHandler)
+	mux.HandleFunc("/api/v1/upload/multipart/init", handler.MultipartInitHandler)
+	mux.HandleFunc("/api/v1/upload/multipart/complete", handler.MultipartCompleteHandler)
+	mux.HandleFunc("/api/v1/upload/multipart/abort", handler.MultipartAbortHandler)
 	mux.HandleFunc("/api/v1/upload/download-url", handler.DownloadURLHandler)
 	mux.HandleFunc("/api/v1/upload/delete", handler.DeleteUploadHandler)
+	mux.HandleFunc("/api/v1/upload/subtitles", handler.UploadSubtitleHandler)
 	mux.HandleFunc("/", handler.NotFoundHandler)
 
 	s.server = &http.Server{
@@ -143,6 +191,9 @@ func (s *UploadServer) Start(ctx context.Context) error {
 		WriteTimeout: time.Duration(s.config.Server.WriteTimeout) * time.Second,
 	}
 
+	if s.outbox != nil {
+		s.outbox.Start()
+	}
 	go func() {
 		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			s.logger.Error("Upload server error", zap.Error(err))
@@ -153,9 +204,15 @@ func (s *UploadServer) Start(ctx context.Context) error {
 }
 
 func (s *UploadServer) Stop(ctx context.Context) error {
+	if s.kernel != nil {
+		s.kernel.HealthChecks().UnregisterCheck(transcodingQueueHealthCheck)
+	}
 	if s.svc != nil {
 		s.svc.Close()
 	}
+	if s.outbox != nil {
+		s.outbox.Close()
+	}
 	if s.server != nil {
 		if err := s.server.Shutdown(ctx); err != nil {
 			s.logger.Error("Error shutting down upload server", zap.Error(err))
@@ -176,22 +233,5 @@ func (s *UploadServer) Health(ctx context.Context) error {
 }
 
 func createObjectStorage(cfg *config.Config, logger *zap.Logger) (service.UploadObjectStorage, error) {
-	switch cfg.Storage.Type {
-	case "s3":
-		s3Cfg := storage.S3Config{
-			Region:          cfg.Storage.Region,
-			AccessKeyID:     cfg.Storage.AccessKey,
-			SecretAccessKey: cfg.Storage.SecretKey,
-			Endpoint:        cfg.Storage.Endpoint,
-		}
-		return storage.NewS3Storage(s3Cfg)
-	default:
-		minioCfg := storage.MinIOConfig{
-			Endpoint:        cfg.Storage.Endpoint,
-			AccessKeyID:     cfg.Storage.AccessKey,
-			SecretAccessKey: cfg.Storage.SecretKey,
-			UseSSL:          cfg.Storage.UseSSL,
-		}
-		return storage.NewMinIOStorage(minioCfg)
-	}
+	return storage.NewObjectStorageFromConfig(cfg.Storage)
 }
@@ -14,6 +14,8 @@ import (
 type ffmpegAdapter struct {
 	ft  *transcoder.FFmpegTranscoder
 	log *zap.Logger
+	// storyboard shapes scrub preview storyboards; nil disables them.
+	storyboard *transcoder.StoryboardConfig
 }
 
 var profileDefs = map[string]transcoder.TranscodeProfile{
@@ -105,4 +107,20 @@ func (a *ffmpegAdapter) TranscodeHLS(ctx context.Context, inputPath, outputDir,
 	return a.ft.TranscodeToHLS(ctx, inputPath, outputDir, profiles, callback, variantFn)
 }
 
-var _ service.VideoTranscoder = (*ffmpegAdapter)(nil)
+// GenerateStoryboard renders the scrub preview storyboard into outputDir
+// and returns the files written, or none when storyboards are disabled.
+func (a *ffmpegAdapter) GenerateStoryboard(ctx context.Context, inputPath, outputDir string) ([]string, error) {
+	if a.storyboard == nil {
+		return nil, nil
+	}
+	board, err := a.ft.GenerateStoryboard(ctx, inputPath, outputDir, *a.storyboard)
+	if err != nil {
+		return nil, err
+	}
+	return board.Files(), nil
+}
+
+var (
+	_ service.VideoTranscoder     = (*ffmpegAdapter)(nil)
+	_ service.StoryboardGenerator = (*ffmpegAdapter)(nil)
+)
@@ -0,0 +1,68 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCronSchedule_Next(t *testing.T) {
+	at := func(s string) time.Time {
+		ts, err := time.Parse("2006-01-02 15:04:05", s)
+		require.NoError(t, err)
+		return ts
+	}
+
+	tests := []struct {
+		expr string
+		from string
+		want string
+	}{
+		{"*/15 * * * *", "2024-03-08 10:07:00", "2024-03-08 10:15:00"},
+		{"*/15 * * * *", "2024-03-08 10:15:00", "2024-03-08 10:30:00"},
+		{"5/20 * * * *", "2024-03-08 10:06:00", "2024-03-08 10:25:00"},
+		{"0 3 * * mon-fri", "2024-03-08 04:00:00", "2024-03-11 03:00:00"},
+		{"0 0 1,15 * *", "2024-03-02 00:00:00", "2024-03-15 00:00:00"},
+		{"0 0 * * 7", "2024-03-08 00:00:00", "2024-03-10 00:00:00"},
+		// Both day fields restricted: either matches.
+		{"0 0 13 * fri", "2024-03-01 00:00:00", "2024-03-08 00:00:00"},
+		{"30 12 29 FEB *", "2024-03-01 00:00:00", "2028-02-29 12:30:00"},
+		{"@daily", "2024-03-08 23:59:30", "2024-03-09 00:00:00"},
+		{"@hourly", "2024-12-31 23:30:00", "2025-01-01 00:00:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr+" from "+tt.from, func(t *testing.T) {
+			cron, err := parseCron(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, at(tt.want), cron.next(at(tt.from)))
+		})
+	}
+
+	never, err := parseCron("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, never.next(at("2024-01-01 00:00:00")).IsZero())
+}
+
+func TestParseCron_Invalid(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"* * * *", "want 5 fields, got 4"},
+		{"60 * * * *", `minute: "60" is not between 0 and 59`},
+		{"* * 0 * *", `day of month: "0" is not between 1 and 31`},
+		{"*/0 * * * *", "minute: invalid step"},
+		{"5-1 * * * *", "minute: empty range"},
+		{"* * * * mon-sun", "day of week: empty range"},
+		{"* * * foo *", `month: "foo" is not between 1 and 12`},
+		{"@every 5m", "want 5 fields, got 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := parseCron(tt.expr)
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package worker
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/rtcdance/streamgate/pkg/core"
 	"github.com/rtcdance/streamgate/pkg/monitoring"
@@ -43,11 +44,10 @@ func (h *WorkerHandler) HealthHandler(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
 }
 
-// ReadyHandler handles readiness check requests
+// ReadyHandler reports the kernel's health checks, including the
+// dependencies this plugin registered.
 func (h *WorkerHandler) ReadyHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
+	h.kernel.ReadyHandler(w, r)
 }
 
 // SubmitJobHandler handles job submission
@@ -196,15 +196,12 @@ func (h *WorkerHandler) ScheduleJobHandler(w http.ResponseWriter, r *http.Reques
 
 	h.logger.Info("Scheduling job", zap.String("job_type", scheduled.JobType), zap.String("schedule", scheduled.Schedule))
 
-	job := &Job{
-		ID:     scheduled.ID,
-		Type:   scheduled.JobType,
-		Status: "pending",
-	}
-	if err := h.scheduler.SubmitJob(job); err != nil {
+	created, err := h.scheduler.Schedules().Add(scheduled)
+	if err != nil {
 		h.logger.Error("Failed to schedule job", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(map[string]string{"error": "scheduler not running"})
+		h.metricsCollector.IncrementCounter("schedule_job_failed", map[string]string{})
+		w.WriteHeader(scheduleErrorStatus(err))
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
 
@@ -213,9 +210,81 @@ func (h *WorkerHandler) ScheduleJobHandler(w http.ResponseWriter, r *http.Reques
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(created)
+}
+
+// ListSchedulesHandler lists the recurring jobs
+func (h *WorkerHandler) ListSchedulesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(h.scheduler.Schedules().List())
+}
+
+// EnableScheduleHandler resumes a recurring job
+func (h *WorkerHandler) EnableScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	h.updateSchedule(w, r, h.scheduler.Schedules().Enable)
+}
+
+// DisableScheduleHandler pauses a recurring job
+func (h *WorkerHandler) DisableScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	h.updateSchedule(w, r, h.scheduler.Schedules().Disable)
+}
+
+// DeleteScheduleHandler removes a recurring job
+func (h *WorkerHandler) DeleteScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	h.updateSchedule(w, r, func(id string) (ScheduledJob, error) {
+		return ScheduledJob{ID: id}, h.scheduler.Schedules().Remove(id)
+	})
+}
+
+// updateSchedule applies update to the schedule named by the schedule_id
+// query parameter of a POST.
+func (h *WorkerHandler) updateSchedule(w http.ResponseWriter, r *http.Request, update func(id string) (ScheduledJob, error)) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	id := r.URL.Query().Get("schedule_id")
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "missing schedule_id"})
+		return
+	}
+
+	scheduled, err := update(id)
+	if err != nil {
+		h.logger.Error("Failed to update schedule", zap.String("schedule_id", id), zap.Error(err))
+		w.WriteHeader(scheduleErrorStatus(err))
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(scheduled)
 }
 
+// scheduleErrorStatus maps a Schedules error to an HTTP status.
+func scheduleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidSchedule):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrScheduleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrScheduleExists):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
 // NotFoundHandler handles 404 requests
 func (h *WorkerHandler) NotFoundHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -557,6 +557,58 @@ func TestWorkerHandler_ScheduleJobHandler_Success(t *testing.T) {
 	assert.Equal(t, http.StatusCreated, rec.Code)
 }
 
+func TestWorkerHandler_Schedules(t *testing.T) {
+	handler := newTestWorkerHandler(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handler.scheduler.Start(ctx)
+
+	schedule := func(job ScheduledJob) int {
+		body, _ := json.Marshal(job)
+		rec := httptest.NewRecorder()
+		handler.ScheduleJobHandler(rec, httptest.NewRequest(http.MethodPost, "/schedule", bytes.NewReader(body)))
+		return rec.Code
+	}
+	cleanup := ScheduledJob{ID: "cleanup", JobType: "cleanup", Schedule: "0 3 * * *", Overlap: OverlapSkip}
+	assert.Equal(t, http.StatusCreated, schedule(cleanup))
+	assert.Equal(t, http.StatusConflict, schedule(cleanup))
+	assert.Equal(t, http.StatusBadRequest, schedule(ScheduledJob{ID: "bad", JobType: "cleanup", Schedule: "0 25 * * *"}))
+
+	update := func(h http.HandlerFunc, method, target string) (int, ScheduledJob) {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(method, target, http.NoBody))
+		var job ScheduledJob
+		_ = json.NewDecoder(rec.Body).Decode(&job)
+		return rec.Code, job
+	}
+	code, job := update(handler.EnableScheduleHandler, http.MethodPost, "/schedules/enable?schedule_id=cleanup")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, job.Enabled)
+	assert.NotZero(t, job.NextRun)
+	code, job = update(handler.DisableScheduleHandler, http.MethodPost, "/schedules/disable?schedule_id=cleanup")
+	assert.Equal(t, http.StatusOK, code)
+	assert.False(t, job.Enabled)
+	code, _ = update(handler.EnableScheduleHandler, http.MethodPost, "/schedules/enable?schedule_id=missing")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = update(handler.EnableScheduleHandler, http.MethodPost, "/schedules/enable")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = update(handler.DisableScheduleHandler, http.MethodGet, "/schedules/disable?schedule_id=cleanup")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+
+	rec := httptest.NewRecorder()
+	handler.ListSchedulesHandler(rec, httptest.NewRequest(http.MethodGet, "/schedules", http.NoBody))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var listed []ScheduledJob
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&listed))
+	require.Len(t, listed, 1)
+	assert.Equal(t, "cleanup", listed[0].ID)
+	assert.False(t, listed[0].Enabled)
+
+	code, _ = update(handler.DeleteScheduleHandler, http.MethodPost, "/schedules/delete?schedule_id=cleanup")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, handler.scheduler.Schedules().List())
+}
+
 func TestWorkerHandler_GetJobStatusHandler_Found(t *testing.T) {
 	handler := newTestWorkerHandler(t)
 
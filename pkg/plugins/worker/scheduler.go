@@ -6,6 +6,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/rtcdance/streamgate/pkg/core"
+	"github.com/rtcdance/streamgate/pkg/resilience"
+
 	"go.uber.org/zap"
 )
 
@@ -13,6 +16,7 @@ import (
 type Scheduler struct {
 	jobs      map[string]*Job
 	queue     *PriorityQueue
+	pool      *core.WorkerPool[*Job]
 	workers   map[string]*Worker
 	executors map[string]JobExecutor
 	mu        sync.RWMutex
@@ -23,6 +27,7 @@ type Scheduler struct {
 	config    *SchedulerConfig
 	stats     *SchedulerStats
 	eventChan chan *JobEvent
+	schedules *Schedules
 }
 
 // SchedulerConfig holds scheduler configuration
@@ -33,6 +38,10 @@ type SchedulerConfig struct {
 	MaxRetries      int
 	CleanupInterval time.Duration
 	EnableMetrics   bool
+	// RetryBudget, when set, paces automatic retries of failed jobs.
+	RetryBudget *resilience.RetryBudget
+	// ScheduleStore, when set, persists the recurring job schedules.
+	ScheduleStore ScheduleStore
 }
 
 // SchedulerStats tracks scheduler statistics
@@ -70,7 +79,7 @@ func NewScheduler(config *SchedulerConfig, logger *zap.Logger) *Scheduler {
 		config.MaxRetries = 3
 	}
 
-	return &Scheduler{
+	s := &Scheduler{
 		jobs:      make(map[string]*Job),
 		queue:     NewPriorityQueue(config.QueueSize),
 		workers:   make(map[string]*Worker),
@@ -82,10 +91,24 @@ func NewScheduler(config *SchedulerConfig, logger *zap.Logger) *Scheduler {
 		stats:     &SchedulerStats{},
 		eventChan: make(chan *JobEvent, 1000),
 	}
+	s.schedules = NewSchedules(s, config.ScheduleStore, logger)
+	return s
+}
+
+// Schedules returns the scheduler's recurring jobs. They run once Start
+// has loaded them.
+func (s *Scheduler) Schedules() *Schedules {
+	return s.schedules
 }
 
 // Start starts the scheduler
 func (s *Scheduler) Start() error {
+	// Schedules submit under their own lock, so start them before taking
+	// ours.
+	if err := s.schedules.Start(s.ctx); err != nil {
+		return err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -93,14 +116,24 @@ func (s *Scheduler) Start() error {
 		zap.Int("max_workers", s.config.MaxWorkers),
 		zap.Int("queue_size", s.config.QueueSize))
 
-	// Start workers
+	// Start workers. The priority queue feeds them one job at a time, so
+	// higher-priority jobs still run first.
 	for i := 0; i < s.config.MaxWorkers; i++ {
-		worker := NewWorker(fmt.Sprintf("worker-%d", i), s.logger)
+		worker := NewWorker(workerID(i), s.logger)
 		s.workers[worker.ID] = worker
-
-		s.wg.Add(1)
-		go s.runWorker(worker)
 	}
+	s.pool = core.NewWorkerPool(core.WorkerPoolConfig[*Job]{
+		Name:      "scheduler",
+		Workers:   s.config.MaxWorkers,
+		QueueSize: 1,
+		OnDiscard: func(job *Job) { _ = s.queue.Enqueue(job) },
+	}, func(_ context.Context, idx int, job *Job) {
+		s.executeJob(s.workerAt(idx), job)
+	})
+	if err := s.pool.Start(s.ctx); err != nil {
+		return err
+	}
+	go func() { _ = s.pool.Feed(s.ctx, s.queue.Dequeue) }()
 
 	// Start event processor
 	s.wg.Add(1)
@@ -141,6 +174,9 @@ func (s *Scheduler) Stop() error {
 	// Wait for all workers to finish
 	done := make(chan struct{})
 	go func() {
+		if s.pool != nil {
+			_ = s.pool.Stop(context.Background())
+		}
 		s.wg.Wait()
 		close(done)
 	}()
@@ -433,26 +469,52 @@ func (s *Scheduler) RegisterExecutor(jobType string, executor JobExecutor) {
 	s.logger.Debug("Job executor registered", zap.String("type", jobType))
 }
 
-// runWorker runs a worker
-func (s *Scheduler) runWorker(worker *Worker) {
-	defer s.wg.Done()
+// ResizeWorkers changes the number of workers. Workers removed by a
+// shrink finish their current job before exiting.
+func (s *Scheduler) ResizeWorkers(n int) error {
+	if n < 1 {
+		return fmt.Errorf("worker count must be positive: %d", n)
+	}
 
-	s.logger.Debug("Worker started", zap.String("worker_id", worker.ID))
+	s.mu.Lock()
+	for i := 0; i < n; i++ {
+		id := workerID(i)
+		if _, ok := s.workers[id]; !ok {
+			s.workers[id] = NewWorker(id, s.logger)
+		}
+	}
+	for i := n; ; i++ {
+		id := workerID(i)
+		if _, ok := s.workers[id]; !ok {
+			break
+		}
+		delete(s.workers, id)
+	}
+	s.config.MaxWorkers = n
+	s.mu.Unlock()
 
-	for {
-		select {
-		case <-s.ctx.Done():
-			s.logger.Debug("Worker stopped", zap.String("worker_id", worker.ID))
-			return
-		default:
-			job, err := s.queue.Dequeue(s.ctx)
-			if err != nil {
-				continue
-			}
+	if s.pool != nil {
+		s.pool.Resize(n)
+	}
+	s.logger.Info("Scheduler workers resized", zap.Int("workers", n))
+	return nil
+}
 
-			s.executeJob(worker, job)
-		}
+// workerAt returns the Worker for pool index idx, creating one if the worker
+// was removed by a resize while its job was in flight.
+func (s *Scheduler) workerAt(idx int) *Worker {
+	id := workerID(idx)
+	s.mu.RLock()
+	w, ok := s.workers[id]
+	s.mu.RUnlock()
+	if ok {
+		return w
 	}
+	return NewWorker(id, s.logger)
+}
+
+func workerID(idx int) string {
+	return fmt.Sprintf("worker-%d", idx)
 }
 
 // executeJob executes a job
@@ -460,6 +522,11 @@ func (s *Scheduler) executeJob(worker *Worker, job *Job) {
 	// Update job status under lock to avoid data race with GetJob
 	now := time.Now()
 	s.mu.Lock()
+	// A job cancelled while it waited in the pool is skipped.
+	if job.Status == JobStatusCancelled {
+		s.mu.Unlock()
+		return
+	}
 	job.Status = JobStatusRunning
 	job.WorkerID = worker.ID
 	job.StartedAt = &now
@@ -570,7 +637,11 @@ func (s *Scheduler) failJob(job *Job, err error) {
 		job.Error = ""
 		job.Progress = 0
 
-		if err := s.queue.Enqueue(job); err != nil {
+		if wait := s.config.RetryBudget.Reserve("scheduler"); wait > 0 {
+			s.logger.Debug("Retry budget exhausted, deferring job retry",
+				zap.String("job_id", job.ID), zap.Duration("wait", wait))
+			time.AfterFunc(wait, func() { s.enqueueDeferredRetry(job) })
+		} else if err := s.queue.Enqueue(job); err != nil {
 			s.logger.Error("Failed to enqueue retry job, retry will be lost",
 				zap.String("job_id", job.ID), zap.Error(err))
 		}
@@ -590,6 +661,21 @@ func (s *Scheduler) failJob(job *Job, err error) {
 	}
 }
 
+// enqueueDeferredRetry enqueues a retry held back by the retry budget,
+// unless the job was cancelled or the scheduler stopped meanwhile.
+func (s *Scheduler) enqueueDeferredRetry(job *Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ctx.Err() != nil || job.Status != JobStatusQueued {
+		return
+	}
+	if err := s.queue.Enqueue(job); err != nil {
+		s.logger.Error("Failed to enqueue retry job, retry will be lost",
+			zap.String("job_id", job.ID), zap.Error(err))
+	}
+}
+
 // processEvents processes job events
 func (s *Scheduler) processEvents() {
 	defer s.wg.Done()
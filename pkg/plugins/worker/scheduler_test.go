@@ -3,9 +3,13 @@ package worker
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/rtcdance/streamgate/pkg/resilience"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -50,6 +54,42 @@ func TestScheduler_SubmitJob_DefaultValues(t *testing.T) {
 	assert.False(t, loaded.CreatedAt.IsZero())
 }
 
+func TestScheduler_ResizeWorkers(t *testing.T) {
+	scheduler := NewScheduler(&SchedulerConfig{
+		MaxWorkers: 1,
+		QueueSize:  8,
+		JobTimeout: time.Second,
+	}, zap.NewNop())
+	t.Cleanup(func() { _ = scheduler.Stop() })
+
+	release := make(chan struct{})
+	scheduler.RegisterExecutor("block", NewFuncExecutor("block", func(ctx context.Context, job *Job) (interface{}, error) {
+		<-release
+		return "ok", nil
+	}))
+	require.NoError(t, scheduler.Start())
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, scheduler.SubmitJob(&Job{ID: fmt.Sprintf("job-%d", i), Type: "block"}))
+	}
+	jobsIn := func(status JobStatus) int {
+		jobs, _ := scheduler.ListJobs(status, 10, 0)
+		return len(jobs)
+	}
+	require.Eventually(t, func() bool { return jobsIn(JobStatusRunning) == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, scheduler.ResizeWorkers(3))
+	require.Eventually(t, func() bool { return jobsIn(JobStatusRunning) == 3 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 3, scheduler.pool.Size())
+
+	close(release)
+	require.Eventually(t, func() bool { return jobsIn(JobStatusCompleted) == 3 }, 2*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, scheduler.ResizeWorkers(1))
+	assert.Equal(t, 1, scheduler.pool.Size())
+	assert.Error(t, scheduler.ResizeWorkers(0))
+}
+
 func TestScheduler_SubmitJob_DuplicateID(t *testing.T) {
 	scheduler := NewScheduler(&SchedulerConfig{
 		MaxWorkers:      1,
@@ -296,6 +336,62 @@ func TestScheduler_NoExecutor(t *testing.T) {
 	assert.Contains(t, loaded.Error, "no executor found")
 }
 
+func TestScheduler_RetryBudgetDefersRetries(t *testing.T) {
+	scheduler := NewScheduler(&SchedulerConfig{
+		MaxWorkers:  4,
+		QueueSize:   8,
+		JobTimeout:  time.Second,
+		MaxRetries:  2,
+		RetryBudget: resilience.NewRetryBudget(resilience.RetryBudgetConfig{RatePerSecond: 5, Burst: 1}),
+	}, zap.NewNop())
+	t.Cleanup(func() { _ = scheduler.Stop() })
+
+	// Every job fails its first attempt, as in a storage outage.
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+	retriedAt := make(map[string]time.Time)
+	scheduler.RegisterExecutor("flaky", NewFuncExecutor("flaky", func(ctx context.Context, job *Job) (interface{}, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[job.ID]++
+		if attempts[job.ID] == 1 {
+			return nil, errors.New("storage unavailable")
+		}
+		retriedAt[job.ID] = time.Now()
+		return "ok", nil
+	}))
+	require.NoError(t, scheduler.Start())
+
+	start := time.Now()
+	jobs := make([]*Job, 3)
+	for i := range jobs {
+		jobs[i] = &Job{Type: "flaky"}
+		require.NoError(t, scheduler.SubmitJob(jobs[i]))
+	}
+
+	require.Eventually(t, func() bool {
+		for _, job := range jobs {
+			loaded, err := scheduler.GetJob(job.ID)
+			if err != nil || loaded.Status != JobStatusCompleted {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 20*time.Millisecond, "deferred retries still run once the budget refills")
+
+	// One token of burst, then one retry per 200ms: the last retry cannot
+	// run before ~400ms.
+	mu.Lock()
+	defer mu.Unlock()
+	var last time.Time
+	for _, at := range retriedAt {
+		if at.After(last) {
+			last = at
+		}
+	}
+	assert.GreaterOrEqual(t, last.Sub(start), 350*time.Millisecond, "retries are spread out by the budget")
+}
+
 func TestNewJob(t *testing.T) {
 	job := NewJob("transcode", map[string]interface{}{"file_id": "f1"})
 	assert.Equal(t, "transcode", job.Type)
@@ -0,0 +1,596 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// scheduleTick is how often Schedules checks for due runs.
+	scheduleTick = time.Second
+	// scheduleLeaseTTL is how long a replica holds a SharedScheduleStore's
+	// lease without renewing it. It is renewed once half has run out.
+	scheduleLeaseTTL = 15 * time.Second
+)
+
+var (
+	// ErrInvalidSchedule is returned when adding a malformed schedule.
+	ErrInvalidSchedule = errors.New("invalid schedule")
+	// ErrScheduleNotFound is returned for an unknown schedule ID.
+	ErrScheduleNotFound = errors.New("schedule not found")
+	// ErrScheduleExists is returned when adding a schedule whose ID is taken.
+	ErrScheduleExists = errors.New("schedule already exists")
+	// ErrSchedulesStopped is returned when changing schedules before Start
+	// has loaded the saved ones, or after the scheduler stopped.
+	ErrSchedulesStopped = errors.New("scheduler not running")
+)
+
+// OverlapPolicy decides what a schedule does when it is due while its
+// previous run has not finished.
+type OverlapPolicy string
+
+const (
+	// OverlapSkip drops the run. It is the default.
+	OverlapSkip OverlapPolicy = "skip"
+	// OverlapQueue holds the run until the previous one finishes.
+	OverlapQueue OverlapPolicy = "queue"
+	// OverlapParallel submits the run alongside the previous one.
+	OverlapParallel OverlapPolicy = "parallel"
+)
+
+// ScheduledJob is a recurring job: a job of JobType is submitted whenever
+// the cron expression Schedule matches, in the host's local time.
+type ScheduledJob struct {
+	ID       string                 `json:"id"`
+	JobType  string                 `json:"job_type"`
+	Schedule string                 `json:"schedule"` // cron expression
+	Enabled  bool                   `json:"enabled"`
+	Priority JobPriority            `json:"priority,omitempty"`
+	Payload  map[string]interface{} `json:"payload,omitempty"`
+	// JitterSeconds delays each run by up to this many seconds, so that
+	// schedules sharing an expression do not all start at once. Keep it
+	// well under the interval between runs.
+	JitterSeconds int           `json:"jitter_seconds,omitempty"`
+	Overlap       OverlapPolicy `json:"overlap,omitempty"`
+
+	LastRun   int64  `json:"last_run,omitempty"` // unix seconds
+	NextRun   int64  `json:"next_run,omitempty"`
+	LastJobID string `json:"last_job_id,omitempty"`
+	Queued    int    `json:"queued,omitempty"` // runs held by OverlapQueue
+}
+
+// jobRunner is the job scheduler that schedules submit their runs to.
+type jobRunner interface {
+	SubmitJob(job *Job) error
+	GetJob(jobID string) (*Job, error)
+}
+
+// ScheduleStore persists schedules across restarts.
+type ScheduleStore interface {
+	Load() ([]ScheduledJob, error)
+	Save(schedules []ScheduledJob) error
+}
+
+// SharedScheduleStore is a ScheduleStore several replicas use at once.
+// Schedules reads it before every change so replicas never overwrite one
+// another, and only the replica holding its lease runs the schedules.
+type SharedScheduleStore interface {
+	ScheduleStore
+	// Update calls fn with the saved schedules and, if fn reports a
+	// change, saves the ones it returns, with no other Update in between.
+	Update(fn func(saved []ScheduledJob) (updated []ScheduledJob, changed bool, err error)) error
+	// Lease takes or renews the lease to run the schedules for ttl and
+	// reports whether this replica holds it.
+	Lease(ttl time.Duration) (bool, error)
+	// ReleaseLease gives up the lease if this replica holds it.
+	ReleaseLease() error
+}
+
+// schedule is a ScheduledJob with its parsed expression.
+type schedule struct {
+	ScheduledJob
+	cron *cronSchedule
+}
+
+// Schedules runs recurring jobs on a job scheduler. Changes are saved to
+// its ScheduleStore, if any, along with each schedule's last and next run,
+// so a run missed while the service was down happens once on start.
+//
+// A FileScheduleStore is local to one node, so with one every replica runs
+// every schedule; run a single worker replica with it. With a
+// SharedScheduleStore only the replica holding the lease runs them.
+type Schedules struct {
+	runner  jobRunner
+	store   ScheduleStore
+	logger  *zap.Logger
+	jitter  func(max time.Duration) time.Duration
+	mu      sync.Mutex
+	byID    map[string]*schedule
+	running bool
+	done    chan struct{} // closed when run returns
+
+	// Lease state for a SharedScheduleStore.
+	leader       bool
+	leaseRenewAt time.Time
+}
+
+// NewSchedules returns Schedules submitting to runner. A nil store keeps
+// schedules in memory only.
+func NewSchedules(runner jobRunner, store ScheduleStore, logger *zap.Logger) *Schedules {
+	return &Schedules{
+		runner: runner,
+		store:  store,
+		logger: logger,
+		jitter: func(max time.Duration) time.Duration { return rand.N(max) },
+		byID:   make(map[string]*schedule),
+	}
+}
+
+// Start loads the saved schedules and runs them until ctx is done.
+func (c *Schedules) Start(ctx context.Context) error {
+	var saved []ScheduledJob
+	if c.store != nil {
+		var err error
+		if saved, err = c.store.Load(); err != nil {
+			return fmt.Errorf("failed to load schedules: %w", err)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.running {
+		return nil
+	}
+	c.loadLocked(saved)
+	c.running = true
+	c.done = make(chan struct{})
+
+	go c.run(ctx, c.done)
+	c.logger.Info("Schedules started", zap.Int("schedules", len(c.byID)))
+	return nil
+}
+
+// Done returns a channel closed once the schedules stop running after their
+// Start context is done, and the lease, if any, has been released.
+func (c *Schedules) Done() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.done
+}
+
+func (c *Schedules) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(scheduleTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			c.mu.Lock()
+			c.running = false
+			if shared, ok := c.store.(SharedScheduleStore); ok && c.leader {
+				c.leader = false
+				if err := shared.ReleaseLease(); err != nil {
+					c.logger.Warn("Failed to release schedule lease", zap.Error(err))
+				}
+			}
+			c.mu.Unlock()
+			return
+		case now := <-ticker.C:
+			if shared, ok := c.store.(SharedScheduleStore); ok && !c.holdsLease(shared, now) {
+				continue
+			}
+			c.runDue(now)
+		}
+	}
+}
+
+// holdsLease reports whether this replica may run the schedules, taking or
+// renewing the shared store's lease once half of it has run out.
+func (c *Schedules) holdsLease(shared SharedScheduleStore, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.leader && now.Before(c.leaseRenewAt) {
+		return true
+	}
+	held, err := shared.Lease(scheduleLeaseTTL)
+	if err != nil {
+		c.logger.Warn("Failed to take schedule lease", zap.Error(err))
+		held = false
+	}
+	if held != c.leader {
+		c.logger.Info("Schedule lease changed hands", zap.Bool("held", held))
+	}
+	c.leader = held
+	c.leaseRenewAt = now.Add(scheduleLeaseTTL / 2)
+	return held
+}
+
+// loadLocked replaces the schedules with saved, skipping invalid ones.
+func (c *Schedules) loadLocked(saved []ScheduledJob) {
+	now := time.Now()
+	c.byID = make(map[string]*schedule, len(saved))
+	for _, job := range saved {
+		s, err := newSchedule(job, now, c.jitter)
+		if err != nil {
+			c.logger.Warn("Ignoring invalid saved schedule",
+				zap.String("schedule_id", job.ID), zap.Error(err))
+			continue
+		}
+		c.byID[s.ID] = s
+	}
+}
+
+// updateLocked applies fn to the schedules and saves them if fn reports a
+// change. With a SharedScheduleStore the schedules are first reloaded, and
+// the reload, fn and the save happen in one Update.
+func (c *Schedules) updateLocked(fn func() (bool, error)) error {
+	shared, ok := c.store.(SharedScheduleStore)
+	if !ok {
+		changed, err := fn()
+		if err != nil || !changed {
+			return err
+		}
+		return c.saveLocked()
+	}
+	var fnErr error
+	err := shared.Update(func(saved []ScheduledJob) ([]ScheduledJob, bool, error) {
+		c.loadLocked(saved)
+		changed, err := fn()
+		if err != nil || !changed {
+			fnErr = err
+			return nil, false, nil
+		}
+		return c.listLocked(), true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save schedules: %w", err)
+	}
+	return fnErr
+}
+
+// newSchedule validates job and, unless it has one, works out its next run.
+func newSchedule(job ScheduledJob, now time.Time, jitter func(time.Duration) time.Duration) (*schedule, error) {
+	if job.ID == "" {
+		return nil, errors.New("missing schedule id")
+	}
+	if job.JobType == "" {
+		return nil, errors.New("missing job_type")
+	}
+	if job.JitterSeconds < 0 {
+		return nil, fmt.Errorf("negative jitter_seconds: %d", job.JitterSeconds)
+	}
+	switch job.Overlap {
+	case "":
+		job.Overlap = OverlapSkip
+	case OverlapSkip, OverlapQueue, OverlapParallel:
+	default:
+		return nil, fmt.Errorf("unknown overlap policy %q, want skip, queue or parallel", job.Overlap)
+	}
+	cron, err := parseCron(job.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	if cron.next(now).IsZero() {
+		return nil, fmt.Errorf("cron expression %q never matches", job.Schedule)
+	}
+	s := &schedule{ScheduledJob: job, cron: cron}
+	if s.NextRun == 0 {
+		s.advance(now, jitter)
+	}
+	return s, nil
+}
+
+// advance sets NextRun to the first match after now, plus jitter.
+func (s *schedule) advance(now time.Time, jitter func(time.Duration) time.Duration) {
+	next := s.cron.next(now)
+	if next.IsZero() {
+		s.NextRun = 0
+		return
+	}
+	if s.JitterSeconds > 0 {
+		next = next.Add(jitter(time.Duration(s.JitterSeconds) * time.Second))
+	}
+	s.NextRun = next.Unix()
+}
+
+// Add adds a schedule. Its NextRun is worked out from Schedule; LastRun,
+// LastJobID and Queued are ignored.
+func (c *Schedules) Add(job ScheduledJob) (ScheduledJob, error) {
+	job.LastRun, job.NextRun, job.LastJobID, job.Queued = 0, 0, "", 0
+	s, err := newSchedule(job, time.Now(), c.jitter)
+	if err != nil {
+		return ScheduledJob{}, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.running {
+		return ScheduledJob{}, ErrSchedulesStopped
+	}
+	err = c.updateLocked(func() (bool, error) {
+		if _, ok := c.byID[s.ID]; ok {
+			return false, fmt.Errorf("%w: %s", ErrScheduleExists, s.ID)
+		}
+		c.byID[s.ID] = s
+		return true, nil
+	})
+	if err != nil {
+		if c.byID[s.ID] == s {
+			delete(c.byID, s.ID)
+		}
+		return ScheduledJob{}, err
+	}
+	c.logger.Info("Schedule added",
+		zap.String("schedule_id", s.ID),
+		zap.String("job_type", s.JobType),
+		zap.String("schedule", s.Schedule))
+	return s.copy(), nil
+}
+
+// Remove removes a schedule. Runs already submitted are not cancelled.
+func (c *Schedules) Remove(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.running {
+		return ErrSchedulesStopped
+	}
+	var removed *schedule
+	err := c.updateLocked(func() (bool, error) {
+		s, ok := c.byID[id]
+		if !ok {
+			return false, fmt.Errorf("%w: %s", ErrScheduleNotFound, id)
+		}
+		delete(c.byID, id)
+		removed = s
+		return true, nil
+	})
+	if err != nil {
+		if removed != nil {
+			c.byID[id] = removed
+		}
+		return err
+	}
+	c.logger.Info("Schedule removed", zap.String("schedule_id", id))
+	return nil
+}
+
+// Enable resumes a schedule from its next match.
+func (c *Schedules) Enable(id string) (ScheduledJob, error) {
+	return c.setEnabled(id, true)
+}
+
+// Disable pauses a schedule, dropping runs held by OverlapQueue.
+func (c *Schedules) Disable(id string) (ScheduledJob, error) {
+	return c.setEnabled(id, false)
+}
+
+func (c *Schedules) setEnabled(id string, enabled bool) (ScheduledJob, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.running {
+		return ScheduledJob{}, ErrSchedulesStopped
+	}
+	var (
+		s       *schedule
+		prev    ScheduledJob
+		changed bool
+	)
+	err := c.updateLocked(func() (bool, error) {
+		var ok bool
+		if s, ok = c.byID[id]; !ok {
+			return false, fmt.Errorf("%w: %s", ErrScheduleNotFound, id)
+		}
+		if s.Enabled == enabled {
+			return false, nil
+		}
+		prev, changed = s.ScheduledJob, true
+		s.Enabled = enabled
+		if enabled {
+			// Runs missed while disabled are not made up.
+			s.advance(time.Now(), c.jitter)
+		} else {
+			s.Queued = 0
+		}
+		return true, nil
+	})
+	if err != nil {
+		if changed {
+			s.ScheduledJob = prev
+		}
+		return ScheduledJob{}, err
+	}
+	if changed {
+		c.logger.Info("Schedule updated",
+			zap.String("schedule_id", id), zap.Bool("enabled", enabled))
+	}
+	return s.copy(), nil
+}
+
+// List returns the schedules by ID. With a SharedScheduleStore they are
+// reloaded first, so changes made on other replicas show.
+func (c *Schedules) List() []ScheduledJob {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.store.(SharedScheduleStore); ok && c.running {
+		if saved, err := c.store.Load(); err != nil {
+			c.logger.Warn("Failed to reload schedules", zap.Error(err))
+		} else {
+			c.loadLocked(saved)
+		}
+	}
+	return c.listLocked()
+}
+
+func (c *Schedules) listLocked() []ScheduledJob {
+	jobs := make([]ScheduledJob, 0, len(c.byID))
+	for _, id := range slices.Sorted(maps.Keys(c.byID)) {
+		jobs = append(jobs, c.byID[id].copy())
+	}
+	return jobs
+}
+
+func (s *schedule) copy() ScheduledJob {
+	job := s.ScheduledJob
+	job.Payload = maps.Clone(s.Payload)
+	return job
+}
+
+// runDue submits the runs due at now, applying each schedule's overlap
+// policy, and releases runs held by OverlapQueue once the previous one
+// has finished.
+func (c *Schedules) runDue(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.updateLocked(func() (bool, error) { return c.runDueLocked(now), nil }); err != nil {
+		c.logger.Error("Failed to save schedules", zap.Error(err))
+	}
+}
+
+// runDueLocked does the work of runDue and reports whether any schedule
+// changed.
+func (c *Schedules) runDueLocked(now time.Time) bool {
+	changed := false
+	for _, id := range slices.Sorted(maps.Keys(c.byID)) {
+		s := c.byID[id]
+		if !s.Enabled {
+			continue
+		}
+		busy := c.busy(s)
+		if s.Queued > 0 && !busy {
+			s.Queued--
+			busy = c.submit(s, now)
+			changed = true
+		}
+		if s.NextRun == 0 || now.Unix() < s.NextRun {
+			continue
+		}
+
+		s.advance(now, c.jitter)
+		changed = true
+		switch {
+		case !busy || s.Overlap == OverlapParallel:
+			c.submit(s, now)
+		case s.Overlap == OverlapQueue:
+			s.Queued++
+			c.logger.Debug("Previous run still busy, queueing scheduled run",
+				zap.String("schedule_id", s.ID), zap.String("job_id", s.LastJobID))
+		default:
+			c.logger.Info("Previous run still busy, skipping scheduled run",
+				zap.String("schedule_id", s.ID), zap.String("job_id", s.LastJobID))
+		}
+	}
+	return changed
+}
+
+// busy reports whether the schedule's last run has yet to finish.
+func (c *Schedules) busy(s *schedule) bool {
+	if s.LastJobID == "" {
+		return false
+	}
+	job, err := c.runner.GetJob(s.LastJobID)
+	if err != nil {
+		return false // cleaned up, or from before a restart
+	}
+	switch job.Status {
+	case JobStatusPending, JobStatusQueued, JobStatusRunning:
+		return true
+	}
+	return false
+}
+
+// submit submits a run of s and reports whether it was accepted.
+func (c *Schedules) submit(s *schedule, now time.Time) bool {
+	job := &Job{
+		ID:       fmt.Sprintf("%s-%d", s.ID, time.Now().UnixNano()),
+		Name:     s.ID,
+		Type:     s.JobType,
+		Priority: s.Priority,
+		Status:   JobStatusPending,
+		Payload:  maps.Clone(s.Payload),
+		Metadata: map[string]interface{}{"schedule_id": s.ID},
+	}
+	if err := c.runner.SubmitJob(job); err != nil {
+		c.logger.Error("Failed to submit scheduled job",
+			zap.String("schedule_id", s.ID), zap.Error(err))
+		return false
+	}
+	s.LastRun = now.Unix()
+	s.LastJobID = job.ID
+	c.logger.Debug("Scheduled job submitted",
+		zap.String("schedule_id", s.ID), zap.String("job_id", job.ID))
+	return true
+}
+
+func (c *Schedules) saveLocked() error {
+	if c.store == nil {
+		return nil
+	}
+	if err := c.store.Save(c.listLocked()); err != nil {
+		return fmt.Errorf("failed to save schedules: %w", err)
+	}
+	return nil
+}
+
+// FileScheduleStore keeps schedules in a JSON file.
+type FileScheduleStore struct {
+	path string
+}
+
+// NewFileScheduleStore returns a store keeping schedules at path. The file
+// and its directory are created on the first save.
+func NewFileScheduleStore(path string) *FileScheduleStore {
+	return &FileScheduleStore{path: path}
+}
+
+// Load reads the saved schedules; there are none if the file is missing.
+func (f *FileScheduleStore) Load() ([]ScheduledJob, error) {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var schedules []ScheduledJob
+	if err := json.Unmarshal(data, &schedules); err != nil {
+		return nil, fmt.Errorf("%s: %w", f.path, err)
+	}
+	return schedules, nil
+}
+
+// Save writes the schedules to a temporary file renamed into place, so a
+// crash never leaves a partial file.
+func (f *FileScheduleStore) Save(schedules []ScheduledJob) error {
+	data, err := json.MarshalIndent(schedules, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(f.path)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".schedules-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
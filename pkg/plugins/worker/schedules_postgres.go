@@ -0,0 +1,137 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/storage"
+)
+
+// scheduleStoreTimeout bounds each PostgresScheduleStore call.
+const scheduleStoreTimeout = 10 * time.Second
+
+// scheduleLockID is the advisory lock serializing schedule updates.
+var scheduleLockID = int64(crc32.ChecksumIEEE([]byte("streamgate_worker_schedules")))
+
+// PostgresScheduleStore keeps schedules in Postgres, shared by every worker
+// replica. Updates are serialized with a transaction-scoped advisory lock,
+// and a single lease row picks the replica that runs the schedules.
+type PostgresScheduleStore struct {
+	db     storage.DB
+	holder string
+}
+
+// NewPostgresScheduleStore returns a store on db. holder names this
+// replica in the lease and must differ between replicas.
+func NewPostgresScheduleStore(db storage.DB, holder string) *PostgresScheduleStore {
+	return &PostgresScheduleStore{db: db, holder: holder}
+}
+
+// Load reads the saved schedules.
+func (p *PostgresScheduleStore) Load() ([]ScheduledJob, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), scheduleStoreTimeout)
+	defer cancel()
+	rows, err := p.db.Query(ctx, "SELECT schedule FROM worker_schedules ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schedules: %w", err)
+	}
+	return scanSchedules(rows)
+}
+
+// Save replaces the saved schedules.
+func (p *PostgresScheduleStore) Save(schedules []ScheduledJob) error {
+	return p.Update(func([]ScheduledJob) ([]ScheduledJob, bool, error) {
+		return schedules, true, nil
+	})
+}
+
+// Update implements SharedScheduleStore.
+func (p *PostgresScheduleStore) Update(fn func(saved []ScheduledJob) ([]ScheduledJob, bool, error)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), scheduleStoreTimeout)
+	defer cancel()
+	return p.db.InTransaction(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", scheduleLockID); err != nil {
+			return fmt.Errorf("failed to lock schedules: %w", err)
+		}
+		rows, err := tx.QueryContext(ctx, "SELECT schedule FROM worker_schedules ORDER BY id")
+		if err != nil {
+			return fmt.Errorf("failed to query schedules: %w", err)
+		}
+		saved, err := scanSchedules(rows)
+		if err != nil {
+			return err
+		}
+		updated, changed, err := fn(saved)
+		if err != nil || !changed {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM worker_schedules"); err != nil {
+			return fmt.Errorf("failed to clear schedules: %w", err)
+		}
+		for _, job := range updated {
+			data, err := json.Marshal(job)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx,
+				"INSERT INTO worker_schedules (id, schedule, updated_at) VALUES ($1, $2, CURRENT_TIMESTAMP)",
+				job.ID, data); err != nil {
+				return fmt.Errorf("failed to save schedule %s: %w", job.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Lease implements SharedScheduleStore. The lease row is taken over only
+// once its holder has let it expire.
+func (p *PostgresScheduleStore) Lease(ttl time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), scheduleStoreTimeout)
+	defer cancel()
+	var holder string
+	err := p.db.QueryRow(ctx,
+		`INSERT INTO worker_schedule_lease (id, holder, expires_at)
+		 VALUES (1, $1, CURRENT_TIMESTAMP + make_interval(secs => $2))
+		 ON CONFLICT (id) DO UPDATE SET holder = EXCLUDED.holder, expires_at = EXCLUDED.expires_at
+		 WHERE worker_schedule_lease.holder = EXCLUDED.holder
+		    OR worker_schedule_lease.expires_at < CURRENT_TIMESTAMP
+		 RETURNING holder`,
+		p.holder, ttl.Seconds()).Scan(&holder)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to take schedule lease: %w", err)
+	}
+	return holder == p.holder, nil
+}
+
+// ReleaseLease implements SharedScheduleStore.
+func (p *PostgresScheduleStore) ReleaseLease() error {
+	ctx, cancel := context.WithTimeout(context.Background(), scheduleStoreTimeout)
+	defer cancel()
+	_, err := p.db.Exec(ctx, "DELETE FROM worker_schedule_lease WHERE id = 1 AND holder = $1", p.holder)
+	return err
+}
+
+func scanSchedules(rows storage.Rows) ([]ScheduledJob, error) {
+	defer func() { _ = rows.Close() }()
+	var schedules []ScheduledJob
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan schedule: %w", err)
+		}
+		var job ScheduledJob
+		if err := json.Unmarshal(data, &job); err != nil {
+			return nil, fmt.Errorf("failed to decode schedule: %w", err)
+		}
+		schedules = append(schedules, job)
+	}
+	return schedules, rows.Err()
+}
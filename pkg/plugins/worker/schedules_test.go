@@ -0,0 +1,250 @@
+package worker
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// Schedules under test are rarely due, so the background tick leaves them
+// alone and runDue is driven by hand.
+
+func TestSchedules_Overlap(t *testing.T) {
+	tests := []struct {
+		overlap    OverlapPolicy
+		whileBusy  int // runs submitted while the first is running
+		queued     int
+		afterwards int // runs submitted once it finished
+	}{
+		{OverlapSkip, 1, 0, 1},
+		{OverlapQueue, 1, 1, 2},
+		{OverlapParallel, 2, 0, 2},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.overlap), func(t *testing.T) {
+			scheduler := NewScheduler(&SchedulerConfig{MaxWorkers: 4, QueueSize: 8, JobTimeout: 5 * time.Second}, zap.NewNop())
+			t.Cleanup(func() { _ = scheduler.Stop() })
+			release := make(chan struct{})
+			scheduler.RegisterExecutor("sweep", NewFuncExecutor("sweep", func(ctx context.Context, job *Job) (interface{}, error) {
+				<-release
+				return "ok", nil
+			}))
+			require.NoError(t, scheduler.Start())
+			schedules := scheduler.Schedules()
+
+			added, err := schedules.Add(ScheduledJob{
+				ID: "sweep", JobType: "sweep", Schedule: "@yearly", Enabled: true, Overlap: tt.overlap,
+			})
+			require.NoError(t, err)
+			jobsIn := func(status JobStatus) int {
+				jobs, _ := scheduler.ListJobs(status, 10, 0)
+				return len(jobs)
+			}
+
+			schedules.runDue(time.Unix(added.NextRun, 0))
+			require.Eventually(t, func() bool { return jobsIn(JobStatusRunning) == 1 }, 2*time.Second, 10*time.Millisecond)
+			due := time.Unix(schedules.List()[0].NextRun, 0)
+			schedules.runDue(due)
+			assert.Equal(t, tt.whileBusy, jobsIn(""))
+			assert.Equal(t, tt.queued, schedules.List()[0].Queued)
+
+			close(release)
+			require.Eventually(t, func() bool { return jobsIn(JobStatusCompleted) == tt.whileBusy }, 2*time.Second, 10*time.Millisecond)
+			schedules.runDue(due.Add(time.Second))
+			assert.Equal(t, tt.afterwards, jobsIn(""))
+			assert.Zero(t, schedules.List()[0].Queued)
+
+			job, err := scheduler.GetJob(schedules.List()[0].LastJobID)
+			require.NoError(t, err)
+			assert.Equal(t, "sweep", job.Metadata["schedule_id"])
+		})
+	}
+}
+
+func TestSchedules_Persistence(t *testing.T) {
+	store := NewFileScheduleStore(filepath.Join(t.TempDir(), "state", "schedules.json"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first := NewJobScheduler(zap.NewNop())
+	first.schedules.store = store
+	first.schedules.jitter = func(max time.Duration) time.Duration {
+		assert.Equal(t, 30*time.Second, max)
+		return 10 * time.Second
+	}
+	first.Start(ctx)
+	added, err := first.Schedules().Add(ScheduledJob{
+		ID: "warm", JobType: "cache_warm", Schedule: "@yearly", Enabled: true,
+		Payload: map[string]interface{}{"prefix": "/hot"}, JitterSeconds: 30,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), added.NextRun%60, "jittered")
+	_, err = first.Schedules().Add(ScheduledJob{ID: "sweep", JobType: "reverify", Schedule: "0 4 1 * *", Overlap: OverlapQueue})
+	require.NoError(t, err)
+	_, err = first.Schedules().Enable("sweep")
+	require.NoError(t, err)
+	first.Schedules().runDue(time.Unix(added.NextRun, 0))
+	saved := first.Schedules().List()
+	assert.NotZero(t, saved[1].LastRun, "warm")
+
+	second := NewJobScheduler(zap.NewNop())
+	second.schedules.store = store
+	second.Start(ctx)
+	assert.Equal(t, saved, second.Schedules().List())
+
+	// A run missed while stopped happens once on start.
+	saved[0].NextRun = time.Now().Add(-time.Hour).Unix()
+	require.NoError(t, store.Save(saved))
+	third := NewJobScheduler(zap.NewNop())
+	third.schedules.store = store
+	third.Start(ctx)
+	third.Schedules().runDue(time.Now())
+	third.Schedules().runDue(time.Now())
+	jobs := third.ListJobs()
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "reverify", jobs[0].Type)
+	assert.Greater(t, third.Schedules().List()[0].NextRun, time.Now().Unix())
+}
+
+func TestSchedules_Errors(t *testing.T) {
+	scheduler := NewJobScheduler(zap.NewNop())
+	valid := ScheduledJob{ID: "cleanup", JobType: "cleanup", Schedule: "@daily"}
+
+	_, err := scheduler.Schedules().Add(valid)
+	assert.ErrorIs(t, err, ErrSchedulesStopped, "not started")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	scheduler.Start(ctx)
+	_, err = scheduler.Schedules().Add(valid)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		job  ScheduledJob
+		err  error
+		want string
+	}{
+		{"duplicate", valid, ErrScheduleExists, "cleanup"},
+		{"bad expression", ScheduledJob{ID: "x", JobType: "t", Schedule: "every day"}, ErrInvalidSchedule, "want 5 fields"},
+		{"never", ScheduledJob{ID: "x", JobType: "t", Schedule: "0 0 31 4 *"}, ErrInvalidSchedule, "never matches"},
+		{"overlap", ScheduledJob{ID: "x", JobType: "t", Schedule: "@daily", Overlap: "drop"}, ErrInvalidSchedule, `unknown overlap policy "drop"`},
+		{"jitter", ScheduledJob{ID: "x", JobType: "t", Schedule: "@daily", JitterSeconds: -1}, ErrInvalidSchedule, "negative jitter_seconds"},
+		{"no type", ScheduledJob{ID: "x", Schedule: "@daily"}, ErrInvalidSchedule, "missing job_type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := scheduler.Schedules().Add(tt.job)
+			assert.ErrorIs(t, err, tt.err)
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+
+	_, err = scheduler.Schedules().Disable("missing")
+	assert.ErrorIs(t, err, ErrScheduleNotFound)
+	assert.ErrorIs(t, scheduler.Schedules().Remove("missing"), ErrScheduleNotFound)
+	require.NoError(t, scheduler.Schedules().Remove("cleanup"))
+	assert.Empty(t, scheduler.Schedules().List())
+}
+
+// sharedStore is an in-memory SharedScheduleStore; replicas hand it the
+// name they lease under.
+type sharedStore struct {
+	mu     sync.Mutex
+	saved  []ScheduledJob
+	holder string
+}
+
+type sharedReplica struct {
+	*sharedStore
+	name string
+}
+
+func (s *sharedStore) Load() ([]ScheduledJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.saved), nil
+}
+
+func (s *sharedStore) Save(schedules []ScheduledJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saved = slices.Clone(schedules)
+	return nil
+}
+
+func (s *sharedStore) Update(fn func([]ScheduledJob) ([]ScheduledJob, bool, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	updated, changed, err := fn(slices.Clone(s.saved))
+	if err == nil && changed {
+		s.saved = slices.Clone(updated)
+	}
+	return err
+}
+
+func (r sharedReplica) Lease(time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.holder == "" {
+		r.holder = r.name
+	}
+	return r.holder == r.name, nil
+}
+
+func (r sharedReplica) ReleaseLease() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.holder == r.name {
+		r.holder = ""
+	}
+	return nil
+}
+
+func TestSchedules_SharedStore(t *testing.T) {
+	store := &sharedStore{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	replica := func(name string) *JobScheduler {
+		s := NewJobScheduler(zap.NewNop())
+		s.schedules.store = sharedReplica{store, name}
+		s.Start(ctx)
+		return s
+	}
+	a, b := replica("a"), replica("b")
+
+	// A schedule added on one replica is seen, and not clobbered, by the other.
+	added, err := a.Schedules().Add(ScheduledJob{ID: "sweep", JobType: "reverify", Schedule: "@yearly", Enabled: true})
+	require.NoError(t, err)
+	_, err = b.Schedules().Add(ScheduledJob{ID: "warm", JobType: "cache_warm", Schedule: "@yearly"})
+	require.NoError(t, err)
+	_, err = b.Schedules().Add(ScheduledJob{ID: "sweep", JobType: "reverify", Schedule: "@daily"})
+	assert.ErrorIs(t, err, ErrScheduleExists)
+	assert.Len(t, a.Schedules().List(), 2)
+
+	// Only the lease holder runs them.
+	due := time.Unix(added.NextRun, 0)
+	assert.True(t, a.Schedules().holdsLease(sharedReplica{store, "a"}, due))
+	assert.False(t, b.Schedules().holdsLease(sharedReplica{store, "b"}, due))
+	a.Schedules().runDue(due)
+	require.Len(t, a.ListJobs(), 1)
+	assert.Greater(t, b.Schedules().List()[0].NextRun, added.NextRun, "the run is recorded in the store")
+
+	// Once it is released another replica takes over.
+	require.NoError(t, sharedReplica{store, "a"}.ReleaseLease())
+	assert.True(t, b.Schedules().holdsLease(sharedReplica{store, "b"}, due.Add(scheduleLeaseTTL)))
+
+	cancel()
+	select {
+	case <-a.Schedules().Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("schedules did not stop")
+	}
+}
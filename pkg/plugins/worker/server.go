@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"os"
 	"sync"
 	"time"
 
 	"github.com/rtcdance/streamgate/pkg/core"
 	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/rtcdance/streamgate/pkg/storage"
 
 	"go.uber.org/zap"
 )
@@ -20,20 +22,45 @@ type WorkerServer struct {
 	kernel    *core.Microkernel
 	server    *http.Server
 	scheduler *JobScheduler
+	db        *storage.PostgresDB // schedule store, when kept in Postgres
 }
 
 // NewWorkerServer creates a new worker server
 func NewWorkerServer(cfg *config.Config, logger *zap.Logger, kernel *core.Microkernel) (*WorkerServer, error) {
 	scheduler := NewJobScheduler(logger)
+	var db *storage.PostgresDB
+	switch {
+	case cfg.Worker.SchedulesStore == "postgres":
+		db = storage.NewPostgresDB()
+		dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+			cfg.Database.Host, cfg.Database.Port, cfg.Database.User, cfg.Database.Password, cfg.Database.Database, cfg.Database.SSLMode)
+		poolCfg := storage.PoolConfigFromValues(cfg.Database.MaxConns, cfg.Database.MaxIdleConns, 0, 0)
+		if err := db.ConnectWithConfig(dsn, poolCfg); err != nil {
+			return nil, fmt.Errorf("failed to connect to database for schedules: %w", err)
+		}
+		scheduler.schedules.store = NewPostgresScheduleStore(db, scheduleLeaseHolder())
+	case cfg.Worker.SchedulesFile != "":
+		scheduler.schedules.store = NewFileScheduleStore(cfg.Worker.SchedulesFile)
+	}
 
 	return &WorkerServer{
 		config:    cfg,
 		logger:    logger,
 		kernel:    kernel,
 		scheduler: scheduler,
+		db:        db,
 	}, nil
 }
 
+// scheduleLeaseHolder names this process in the schedule lease.
+func scheduleLeaseHolder() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "worker"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
 // Start starts the worker server
 func (s *WorkerServer) Start(ctx context.Context) error {
 	handler := NewWorkerHandler(s.scheduler, s.logger, s.kernel)
@@ -51,6 +78,10 @@ func (s *WorkerServer) Start(ctx context.Context) error {
 	mux.HandleFunc("/api/v1/jobs/cancel", handler.CancelJobHandler)
 	mux.HandleFunc("/api/v1/jobs/list", handler.ListJobsHandler)
 	mux.HandleFunc("/api/v1/jobs/schedule", handler.ScheduleJobHandler)
+	mux.HandleFunc("/api/v1/jobs/schedules", handler.ListSchedulesHandler)
+	mux.HandleFunc("/api/v1/jobs/schedules/enable", handler.EnableScheduleHandler)
+	mux.HandleFunc("/api/v1/jobs/schedules/disable", handler.DisableScheduleHandler)
+	mux.HandleFunc("/api/v1/jobs/schedules/delete", handler.DeleteScheduleHandler)
 
 	// Catch-all for 404
 	mux.HandleFunc("/", handler.NotFoundHandler)
@@ -86,6 +117,16 @@ func (s *WorkerServer) Stop(ctx context.Context) error {
 	if s.scheduler != nil {
 		s.scheduler.Stop()
 	}
+	if s.db != nil {
+		// Let the schedules give up their lease before the store closes.
+		if done := s.scheduler.Schedules().Done(); done != nil {
+			select {
+			case <-done:
+			case <-ctx.Done():
+			}
+		}
+		_ = s.db.Close()
+	}
 
 	return nil
 }
@@ -112,15 +153,24 @@ type JobScheduler struct {
 	ctx      context.Context
 	cancel   context.CancelFunc
 	mu       sync.RWMutex
+	// schedules submit recurring jobs while the scheduler runs.
+	schedules *Schedules
 }
 
 // NewJobScheduler creates a new job scheduler
 func NewJobScheduler(logger *zap.Logger) *JobScheduler {
-	return &JobScheduler{
+	s := &JobScheduler{
 		logger:   logger,
 		jobQueue: make(chan *Job, 100),
 		jobs:     make(map[string]*Job),
 	}
+	s.schedules = NewSchedules(s, nil, logger)
+	return s
+}
+
+// Schedules returns the scheduler's recurring jobs.
+func (s *JobScheduler) Schedules() *Schedules {
+	return s.schedules
 }
 
 // Start starts the job scheduler
@@ -133,6 +183,9 @@ func (s *JobScheduler) Start(ctx context.Context) {
 	s.running = true
 
 	go s.processJobs()
+	if err := s.schedules.Start(s.ctx); err != nil {
+		s.logger.Error("Recurring jobs will not run", zap.Error(err))
+	}
 	s.logger.Info("Job scheduler started")
 }
 
@@ -232,12 +285,3 @@ func (s *JobScheduler) CancelJob(jobID string) error {
 	job.Status = "cancelled"
 	return nil
 }
-
-type ScheduledJob struct {
-	ID       string `json:"id"`
-	JobType  string `json:"job_type"`
-	Schedule string `json:"schedule"` // cron expression
-	Enabled  bool   `json:"enabled"`
-	LastRun  int64  `json:"last_run,omitempty"`
-	NextRun  int64  `json:"next_run,omitempty"`
-}
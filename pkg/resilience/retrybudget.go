@@ -0,0 +1,88 @@
+package resilience
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/monitoring"
+)
+
+// RetryBudgetConfig configures a RetryBudget.
+type RetryBudgetConfig struct {
+	// RatePerSecond is the sustained number of retries allowed per second.
+	// Zero or negative disables the budget.
+	RatePerSecond float64
+	// Burst is how many retries may run back to back before the rate
+	// applies. Values below 1 are treated as 1.
+	Burst int
+}
+
+// RetryBudget is a token bucket shared by every component in the process
+// that retries failed work. During a widespread outage each failure would
+// otherwise schedule its own retry and they would all land at once; the
+// budget spreads them out at RatePerSecond instead. Retries are never
+// dropped, only deferred. A nil *RetryBudget imposes no limit.
+type RetryBudget struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64 // may go negative: reservations already handed out
+	last   time.Time
+	now    func() time.Time
+}
+
+// NewRetryBudget returns a full budget, or nil when cfg disables it.
+func NewRetryBudget(cfg RetryBudgetConfig) *RetryBudget {
+	if cfg.RatePerSecond <= 0 {
+		return nil
+	}
+	burst := float64(max(cfg.Burst, 1))
+	return &RetryBudget{
+		rate:   cfg.RatePerSecond,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+// Reserve takes one retry from the budget and returns how long the caller
+// must wait before running it. A non-zero wait is counted as deferred under
+// component.
+func (b *RetryBudget) Reserve(component string) time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	b.refillLocked()
+	b.tokens--
+	var wait time.Duration
+	if b.tokens < 0 {
+		wait = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	if wait > 0 {
+		monitoring.RetriesDeferredTotal.WithLabelValues(component).Inc()
+	}
+	return wait
+}
+
+// Available reports how many retries can run immediately.
+func (b *RetryBudget) Available() int {
+	if b == nil {
+		return int(^uint(0) >> 1)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refillLocked()
+	return max(int(b.tokens), 0)
+}
+
+func (b *RetryBudget) refillLocked() {
+	now := b.now()
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(b.burst, b.tokens+elapsed.Seconds()*b.rate)
+	}
+	b.last = now
+}
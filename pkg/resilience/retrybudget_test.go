@@ -0,0 +1,75 @@
+package resilience
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func retriesDeferred(t *testing.T, component string) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, f := range families {
+		if f.GetName() != "streamgate_retries_deferred_total" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "component" && l.GetValue() == component {
+					return m.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func TestRetryBudget_ThrottlesWhenExhausted(t *testing.T) {
+	now := time.Now()
+	b := NewRetryBudget(RetryBudgetConfig{RatePerSecond: 2, Burst: 3})
+	b.now = func() time.Time { return now }
+	b.last = now
+	before := retriesDeferred(t, "test-throttle")
+
+	for i := 0; i < 3; i++ {
+		assert.Zero(t, b.Reserve("test-throttle"), "burst retries run immediately")
+	}
+	assert.Zero(t, b.Available())
+
+	assert.Equal(t, 500*time.Millisecond, b.Reserve("test-throttle"))
+	assert.Equal(t, time.Second, b.Reserve("test-throttle"), "each further retry waits one more interval")
+	assert.Equal(t, 2.0, retriesDeferred(t, "test-throttle")-before)
+}
+
+func TestRetryBudget_ResumesAfterRefill(t *testing.T) {
+	now := time.Now()
+	b := NewRetryBudget(RetryBudgetConfig{RatePerSecond: 10, Burst: 2})
+	b.now = func() time.Time { return now }
+	b.last = now
+
+	b.Reserve("test-refill")
+	b.Reserve("test-refill")
+	require.Positive(t, b.Reserve("test-refill"))
+
+	// The deferred retry's token is repaid first, then the bucket refills
+	// up to the burst and no further.
+	now = now.Add(time.Second)
+	assert.Equal(t, 2, b.Available())
+	assert.Zero(t, b.Reserve("test-refill"))
+	assert.Zero(t, b.Reserve("test-refill"))
+	assert.Positive(t, b.Reserve("test-refill"))
+}
+
+func TestRetryBudget_DisabledIsUnlimited(t *testing.T) {
+	b := NewRetryBudget(RetryBudgetConfig{})
+	assert.Nil(t, b)
+	for i := 0; i < 100; i++ {
+		assert.Zero(t, b.Reserve("test-disabled"))
+	}
+	assert.Positive(t, b.Available())
+	assert.Zero(t, retriesDeferred(t, "test-disabled"))
+}
@@ -0,0 +1,181 @@
+package analytics
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBucketSize is the granularity of stored counters.
+	DefaultBucketSize = time.Hour
+	// DefaultRetention is how long buckets are kept before being pruned.
+	DefaultRetention = 7 * 24 * time.Hour
+)
+
+// AccessEvent is one served request for a piece of content.
+type AccessEvent struct {
+	ContentID string
+	Wallet    string // empty for anonymous access
+	// View marks the start of a playback session (a master manifest
+	// fetch); segment and variant requests only add bandwidth.
+	View  bool
+	Bytes int64
+	Time  time.Time
+}
+
+// ContentAccess is a content item's totals over a report window.
+type ContentAccess struct {
+	ContentID     string `json:"content_id"`
+	Views         int64  `json:"views"`
+	UniqueWallets int    `json:"unique_wallets"`
+	Bytes         int64  `json:"bytes"`
+}
+
+// Config configures an AccessAnalytics store.
+type Config struct {
+	BucketSize time.Duration
+	Retention  time.Duration
+}
+
+type counters struct {
+	views   int64
+	bytes   int64
+	wallets map[string]struct{}
+}
+
+// AccessAnalytics keeps per-content counters in fixed-size time buckets,
+// so top-N reports over any window inside the retention period are exact
+// — including unique wallets, which are unioned across buckets rather
+// than summed. State is held in process memory.
+type AccessAnalytics struct {
+	mu         sync.Mutex
+	bucketSize time.Duration
+	retention  time.Duration
+	buckets    map[int64]map[string]*counters // bucket start (unix s) → content ID → counters
+	now        func() time.Time
+}
+
+// NewAccessAnalytics creates an empty store.
+func NewAccessAnalytics(cfg Config) *AccessAnalytics {
+	if cfg.BucketSize <= 0 {
+		cfg.BucketSize = DefaultBucketSize
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = DefaultRetention
+	}
+	return &AccessAnalytics{
+		bucketSize: cfg.BucketSize,
+		retention:  cfg.Retention,
+		buckets:    make(map[int64]map[string]*counters),
+		now:        time.Now,
+	}
+}
+
+func (a *AccessAnalytics) bucketOf(t time.Time) int64 {
+	return t.Truncate(a.bucketSize).Unix()
+}
+
+// Record adds ev to its time bucket. Events older than the retention
+// period are dropped.
+func (a *AccessAnalytics) Record(ev AccessEvent) {
+	if ev.ContentID == "" {
+		return
+	}
+	now := a.now()
+	if ev.Time.IsZero() {
+		ev.Time = now
+	}
+	if ev.Time.Before(now.Add(-a.retention)) {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	key := a.bucketOf(ev.Time)
+	bucket := a.buckets[key]
+	if bucket == nil {
+		bucket = make(map[string]*counters)
+		a.buckets[key] = bucket
+		a.pruneLocked(now)
+	}
+	c := bucket[ev.ContentID]
+	if c == nil {
+		c = &counters{wallets: make(map[string]struct{})}
+		bucket[ev.ContentID] = c
+	}
+	if ev.View {
+		c.views++
+	}
+	if ev.Bytes > 0 {
+		c.bytes += ev.Bytes
+	}
+	if ev.Wallet != "" {
+		c.wallets[ev.Wallet] = struct{}{}
+	}
+}
+
+// pruneLocked drops buckets that ended before the retention period. It
+// runs when a new bucket is opened. The caller must hold a.mu.
+func (a *AccessAnalytics) pruneLocked(now time.Time) {
+	cutoff := a.bucketOf(now.Add(-a.retention))
+	for key := range a.buckets {
+		if key < cutoff {
+			delete(a.buckets, key)
+		}
+	}
+}
+
+// Top returns up to n content items accessed in [from, to), ranked by
+// views, then unique wallets, then bandwidth. Buckets are included when
+// they start inside the window, so the window is effectively rounded to
+// bucket boundaries.
+func (a *AccessAnalytics) Top(from, to time.Time, n int) []ContentAccess {
+	a.mu.Lock()
+	totals := make(map[string]*ContentAccess)
+	wallets := make(map[string]map[string]struct{})
+	first, last := a.bucketOf(from), to.Unix()
+	for key, bucket := range a.buckets {
+		if key < first || key >= last {
+			continue
+		}
+		for contentID, c := range bucket {
+			t := totals[contentID]
+			if t == nil {
+				t = &ContentAccess{ContentID: contentID}
+				totals[contentID] = t
+				wallets[contentID] = make(map[string]struct{})
+			}
+			t.Views += c.views
+			t.Bytes += c.bytes
+			for w := range c.wallets {
+				wallets[contentID][w] = struct{}{}
+			}
+		}
+	}
+	a.mu.Unlock()
+
+	result := make([]ContentAccess, 0, len(totals))
+	for contentID, t := range totals {
+		t.UniqueWallets = len(wallets[contentID])
+		result = append(result, *t)
+	}
+	slices.SortFunc(result, func(x, y ContentAccess) int {
+		return cmp.Or(
+			cmp.Compare(y.Views, x.Views),
+			cmp.Compare(y.UniqueWallets, x.UniqueWallets),
+			cmp.Compare(y.Bytes, x.Bytes),
+			cmp.Compare(x.ContentID, y.ContentID),
+		)
+	})
+	if n > 0 && len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
+
+// Retention reports how far back Top can see.
+func (a *AccessAnalytics) Retention() time.Duration {
+	return a.retention
+}
@@ -0,0 +1,97 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAnalytics(now *time.Time) *AccessAnalytics {
+	a := NewAccessAnalytics(Config{BucketSize: time.Hour, Retention: 24 * time.Hour})
+	a.now = func() time.Time { return *now }
+	return a
+}
+
+func TestAccessAnalytics_TopRanking(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC)
+	a := newTestAnalytics(&now)
+
+	// c1: 3 views from 2 wallets; c2: 3 views from 3 wallets; c3: 1 view
+	// but the most bandwidth; c4: anonymous bandwidth only.
+	for _, ev := range []AccessEvent{
+		{ContentID: "c1", Wallet: "0xa", View: true, Bytes: 100},
+		{ContentID: "c1", Wallet: "0xa", View: true, Bytes: 100},
+		{ContentID: "c1", Wallet: "0xb", View: true, Bytes: 100},
+		{ContentID: "c1", Wallet: "0xb", Bytes: 5000},
+		{ContentID: "c2", Wallet: "0xa", View: true, Bytes: 10},
+		{ContentID: "c2", Wallet: "0xb", View: true, Bytes: 10},
+		{ContentID: "c2", Wallet: "0xc", View: true, Bytes: 10},
+		{ContentID: "c3", Wallet: "0xd", View: true, Bytes: 1 << 20},
+		{ContentID: "c4", Bytes: 1 << 30},
+	} {
+		a.Record(ev)
+	}
+
+	top := a.Top(now.Add(-time.Hour), now.Add(time.Minute), 10)
+	require.Len(t, top, 4)
+	assert.Equal(t, []ContentAccess{
+		{ContentID: "c2", Views: 3, UniqueWallets: 3, Bytes: 30},
+		{ContentID: "c1", Views: 3, UniqueWallets: 2, Bytes: 5300},
+		{ContentID: "c3", Views: 1, UniqueWallets: 1, Bytes: 1 << 20},
+		{ContentID: "c4", Views: 0, UniqueWallets: 0, Bytes: 1 << 30},
+	}, top, "views first, then unique wallets, then bandwidth")
+
+	assert.Len(t, a.Top(now.Add(-time.Hour), now.Add(time.Minute), 2), 2)
+}
+
+func TestAccessAnalytics_UniqueWalletsAcrossBuckets(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	a := newTestAnalytics(&now)
+
+	for h := 0; h < 5; h++ {
+		at := now.Add(-time.Duration(h) * time.Hour)
+		a.Record(AccessEvent{ContentID: "c1", Wallet: "0xa", View: true, Time: at})
+		a.Record(AccessEvent{ContentID: "c1", Wallet: "0xb", View: true, Time: at})
+	}
+
+	top := a.Top(now.Add(-5*time.Hour), now.Add(time.Second), 10)
+	require.Len(t, top, 1)
+	assert.Equal(t, int64(10), top[0].Views)
+	assert.Equal(t, 2, top[0].UniqueWallets, "wallets are unioned across buckets, not summed")
+}
+
+func TestAccessAnalytics_WindowFiltering(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	a := newTestAnalytics(&now)
+
+	a.Record(AccessEvent{ContentID: "old", View: true, Time: now.Add(-10 * time.Hour)})
+	a.Record(AccessEvent{ContentID: "old", View: true, Time: now.Add(-10 * time.Hour)})
+	a.Record(AccessEvent{ContentID: "recent", View: true, Time: now.Add(-30 * time.Minute)})
+
+	top := a.Top(now.Add(-2*time.Hour), now.Add(time.Second), 10)
+	require.Len(t, top, 1)
+	assert.Equal(t, "recent", top[0].ContentID)
+
+	top = a.Top(now.Add(-12*time.Hour), now.Add(time.Second), 10)
+	require.Len(t, top, 2)
+	assert.Equal(t, "old", top[0].ContentID)
+}
+
+func TestAccessAnalytics_Retention(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	a := newTestAnalytics(&now)
+
+	a.Record(AccessEvent{ContentID: "c1", View: true, Time: now.Add(-48 * time.Hour)})
+	assert.Empty(t, a.Top(now.Add(-72*time.Hour), now, 10), "events older than retention are dropped")
+
+	a.Record(AccessEvent{ContentID: "c1", View: true})
+	now = now.Add(30 * time.Hour)
+	a.Record(AccessEvent{ContentID: "c2", View: true})
+
+	assert.Len(t, a.buckets, 1, "expired buckets are pruned when a new bucket opens")
+	top := a.Top(now.Add(-72*time.Hour), now.Add(time.Second), 10)
+	require.Len(t, top, 1)
+	assert.Equal(t, "c2", top[0].ContentID)
+}
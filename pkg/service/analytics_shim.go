@@ -0,0 +1,14 @@
+package service
+
+import "github.com/rtcdance/streamgate/pkg/service/analytics"
+
+type (
+	AccessAnalytics       = analytics.AccessAnalytics
+	AccessAnalyticsConfig = analytics.Config
+	AccessEvent           = analytics.AccessEvent
+	ContentAccess         = analytics.ContentAccess
+)
+
+var (
+	NewAccessAnalytics = analytics.NewAccessAnalytics
+)
@@ -0,0 +1,312 @@
+// Package archive pushes original uploads to a permanent archival tier and
+// restores them from it.
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/service/serviceerrors"
+	"github.com/rtcdance/streamgate/pkg/storage"
+
+	"go.uber.org/zap"
+)
+
+// appName is the App-Name tag archived originals carry, so they can be
+// found on the archive by tag if the database is lost.
+const appName = "StreamGate"
+
+// Archiver stores data permanently and reads it back by the ID it returns.
+// *storage.ArweaveUploader implements it.
+type Archiver interface {
+	Upload(ctx context.Context, open func() (io.ReadCloser, error), size int64, tags []storage.ArweaveTag) (string, error)
+	Download(ctx context.Context, id string) (io.ReadCloser, error)
+}
+
+// ObjectStore holds the originals being archived and restored.
+type ObjectStore interface {
+	DownloadStream(ctx context.Context, bucket, objectName string) (io.ReadCloser, error)
+	UploadStream(ctx context.Context, bucket, objectName string, reader io.Reader, size int64) error
+}
+
+// Config tunes archiving.
+type Config struct {
+	// Bucket is the bucket uploaded originals are stored in.
+	Bucket string
+	// Timeout bounds one archive or restore run.
+	Timeout time.Duration
+}
+
+// Record is the archival state of one content item.
+type Record struct {
+	ContentID  string     `json:"content_id"`
+	Size       int64      `json:"size"`
+	TxID       string     `json:"arweave_tx,omitempty"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+	RestoredAt *time.Time `json:"restored_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+
+	url string
+}
+
+// metadataState is the archive part of contents.metadata.
+type metadataState struct {
+	TxID       string     `json:"arweave_tx,omitempty"`
+	ArchivedAt *time.Time `json:"arweave_archived_at,omitempty"`
+	RestoredAt *time.Time `json:"arweave_restored_at,omitempty"`
+	Error      string     `json:"arweave_error,omitempty"`
+}
+
+// Service archives original uploads once they are transcoded, records the
+// archive ID in the content's metadata and restores lost originals from
+// the archive.
+type Service struct {
+	db       storage.DB
+	store    ObjectStore
+	archiver Archiver
+	cfg      Config
+	logger   *zap.Logger
+
+	mu       sync.Mutex
+	inflight map[string]bool
+	wg       sync.WaitGroup
+}
+
+// NewArchiveService returns a service archiving originals from store with
+// archiver.
+func NewArchiveService(db storage.DB, store ObjectStore, archiver Archiver, cfg Config, logger *zap.Logger) *Service {
+	if cfg.Bucket == "" {
+		cfg.Bucket = "streamgate"
+	}
+	return &Service{
+		db:       db,
+		store:    store,
+		archiver: archiver,
+		cfg:      cfg,
+		logger:   logger,
+		inflight: make(map[string]bool),
+	}
+}
+
+// Archive pushes the content's original to the archive and records its
+// archive ID. Content already archived is returned unchanged, since the
+// archived copy is permanent. A failed attempt is recorded on the content
+// and can be retried.
+func (s *Service) Archive(ctx context.Context, contentID string) (*Record, error) {
+	if !s.begin(contentID) {
+		return nil, fmt.Errorf("content %s is already being archived: %w", contentID, serviceerrors.ErrAlreadyExists)
+	}
+	defer s.end(contentID)
+
+	rec, err := s.Get(ctx, contentID)
+	if err != nil {
+		return nil, err
+	}
+	if rec.TxID != "" {
+		return rec, nil
+	}
+	key, err := s.objectKey(rec)
+	if err != nil {
+		return nil, err
+	}
+
+	runCtx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	txID, archiveErr := s.archiver.Upload(runCtx, func() (io.ReadCloser, error) {
+		return s.store.DownloadStream(runCtx, s.cfg.Bucket, key)
+	}, rec.Size, []storage.ArweaveTag{
+		{Name: "Content-Type", Value: contentType},
+		{Name: "App-Name", Value: appName},
+		{Name: "Content-Id", Value: contentID},
+	})
+
+	// A cancelled run must still leave its result behind.
+	ctx = context.WithoutCancel(ctx)
+	if archiveErr != nil {
+		rec.Error = archiveErr.Error()
+		if err := s.update(ctx, contentID, map[string]interface{}{"arweave_error": rec.Error}); err != nil {
+			s.logger.Warn("Failed to record archive error", zap.String("content_id", contentID), zap.Error(err))
+		}
+		return rec, fmt.Errorf("archive of %s failed: %w", contentID, archiveErr)
+	}
+
+	now := time.Now().UTC()
+	rec.TxID, rec.ArchivedAt, rec.Error = txID, &now, ""
+	if err := s.update(ctx, contentID, map[string]interface{}{
+		"arweave_tx":          txID,
+		"arweave_archived_at": now,
+	}); err != nil {
+		return nil, fmt.Errorf("archived %s as %s but failed to record it: %w", contentID, txID, err)
+	}
+	s.logger.Info("Original archived",
+		zap.String("content_id", contentID), zap.String("arweave_tx", txID), zap.Int64("size", rec.Size))
+	return rec, nil
+}
+
+// ArchiveAsync archives content in the background, logging failures. Close
+// waits for background runs to finish.
+func (s *Service) ArchiveAsync(contentID string) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		_, err := s.Archive(context.Background(), contentID)
+		switch {
+		case errors.Is(err, serviceerrors.ErrAlreadyExists):
+			// Another profile of the same content finished first.
+		case err != nil:
+			s.logger.Warn("Failed to archive original", zap.String("content_id", contentID), zap.Error(err))
+		}
+	}()
+}
+
+// Restore writes the content's original back to object storage from the
+// archive, e.g. after it was lost or deleted.
+func (s *Service) Restore(ctx context.Context, contentID string) (*Record, error) {
+	rec, err := s.Get(ctx, contentID)
+	if err != nil {
+		return nil, err
+	}
+	if rec.TxID == "" {
+		return nil, fmt.Errorf("content %s has not been archived: %w", contentID, serviceerrors.ErrInvalidRequest)
+	}
+	key, err := s.objectKey(rec)
+	if err != nil {
+		return nil, err
+	}
+
+	runCtx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	rc, err := s.archiver.Download(runCtx, rec.TxID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s from archive: %w", rec.TxID, err)
+	}
+	defer func() { _ = rc.Close() }()
+	counted := &countingReader{r: rc}
+	if err := s.store.UploadStream(runCtx, s.cfg.Bucket, key, counted, rec.Size); err != nil {
+		return nil, fmt.Errorf("failed to restore original: %w", err)
+	}
+	if counted.n != rec.Size {
+		return nil, fmt.Errorf("archive returned %d bytes for %s, expected %d", counted.n, rec.TxID, rec.Size)
+	}
+
+	now := time.Now().UTC()
+	rec.RestoredAt = &now
+	if err := s.update(context.WithoutCancel(ctx), contentID, map[string]interface{}{"arweave_restored_at": now}); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Original restored from archive",
+		zap.String("content_id", contentID), zap.String("arweave_tx", rec.TxID))
+	return rec, nil
+}
+
+// Get returns the archival state of content. TxID is empty for content that
+// was never archived.
+func (s *Service) Get(ctx context.Context, contentID string) (*Record, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+	var url sql.NullString
+	var size sql.NullInt64
+	var metadataJSON []byte
+	err := s.db.QueryRow(ctx, `SELECT url, size, metadata FROM contents WHERE id = $1`, contentID).
+		Scan(&url, &size, &metadataJSON)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("content not found %s: %w", contentID, serviceerrors.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query archive state: %w", err)
+	}
+	rec := &Record{ContentID: contentID, Size: size.Int64, url: url.String}
+	if len(metadataJSON) > 0 {
+		var state metadataState
+		if err := json.Unmarshal(metadataJSON, &state); err != nil {
+			return nil, fmt.Errorf("failed to parse metadata: %w", err)
+		}
+		rec.TxID, rec.ArchivedAt, rec.RestoredAt, rec.Error = state.TxID, state.ArchivedAt, state.RestoredAt, state.Error
+	}
+	return rec, nil
+}
+
+// Close waits for background archive runs.
+func (s *Service) Close() {
+	s.wg.Wait()
+}
+
+// objectKey derives the original's key from the content URL, which uploads
+// record as /<bucket>/<key>.
+func (s *Service) objectKey(rec *Record) (string, error) {
+	key, ok := strings.CutPrefix(rec.url, "/"+s.cfg.Bucket+"/")
+	if !ok || key == "" {
+		return "", fmt.Errorf("content %s has no stored original: %w", rec.ContentID, serviceerrors.ErrInvalidRequest)
+	}
+	if rec.Size <= 0 {
+		return "", fmt.Errorf("content %s has no recorded size: %w", rec.ContentID, serviceerrors.ErrInvalidRequest)
+	}
+	return key, nil
+}
+
+func (s *Service) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.cfg.Timeout > 0 {
+		return context.WithTimeout(ctx, s.cfg.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+func (s *Service) begin(contentID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inflight[contentID] {
+		return false
+	}
+	s.inflight[contentID] = true
+	return true
+}
+
+func (s *Service) end(contentID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.inflight, contentID)
+}
+
+// update merges patch into the content's metadata, dropping any earlier
+// archive error; a failed run records its error in the patch.
+func (s *Service) update(ctx context.Context, contentID string, patch map[string]interface{}) error {
+	patchJSON, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("failed to encode archive state: %w", err)
+	}
+	result, err := s.db.Exec(ctx,
+		`UPDATE contents SET metadata = (COALESCE(metadata, '{}'::jsonb) - 'arweave_error') || $2::jsonb, updated_at = $3 WHERE id = $1`,
+		contentID, string(patchJSON), time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to update archive state: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("content not found %s: %w", contentID, serviceerrors.ErrNotFound)
+	}
+	return nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
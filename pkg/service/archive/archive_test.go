@@ -0,0 +1,256 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/rtcdance/streamgate/pkg/service/serviceerrors"
+	stg "github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type contentRow struct {
+	url      string
+	size     int64
+	metadata map[string]interface{}
+}
+
+// contentDB keeps content rows in memory and applies the service's jsonb
+// merges to their metadata.
+type contentDB struct {
+	mu   sync.Mutex
+	rows map[string]*contentRow
+}
+
+func (m *contentDB) QueryRow(_ context.Context, _ string, args ...interface{}) *stg.CancelRow {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	row, ok := m.rows[args[0].(string)]
+	if !ok {
+		return stg.NewErrorCancelRow(sql.ErrNoRows)
+	}
+	md, _ := json.Marshal(row.metadata)
+	return stg.NewTestCancelRow(&memRow{url: row.url, size: row.size, metadata: md})
+}
+
+func (m *contentDB) Exec(_ context.Context, _ string, args ...interface{}) (sql.Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	row, ok := m.rows[args[0].(string)]
+	if !ok {
+		return result(0), nil
+	}
+	var patch map[string]interface{}
+	if err := json.Unmarshal([]byte(args[1].(string)), &patch); err != nil {
+		return nil, err
+	}
+	delete(row.metadata, "arweave_error")
+	for k, v := range patch {
+		row.metadata[k] = v
+	}
+	return result(1), nil
+}
+
+func (m *contentDB) meta(id, key string) interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rows[id].metadata[key]
+}
+
+func (m *contentDB) Query(context.Context, string, ...interface{}) (stg.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+func (m *contentDB) Begin(context.Context) (*sql.Tx, error) {
+	return nil, errors.New("not implemented")
+}
+func (m *contentDB) InTransaction(context.Context, func(tx *sql.Tx) error) error {
+	return errors.New("not implemented")
+}
+func (m *contentDB) Ping(context.Context) error { return nil }
+func (m *contentDB) Close() error               { return nil }
+
+type result int64
+
+func (r result) LastInsertId() (int64, error) { return 0, nil }
+func (r result) RowsAffected() (int64, error) { return int64(r), nil }
+
+type memRow struct {
+	url      string
+	size     int64
+	metadata []byte
+}
+
+func (r *memRow) Scan(dest ...interface{}) error {
+	*dest[0].(*sql.NullString) = sql.NullString{String: r.url, Valid: true}
+	*dest[1].(*sql.NullInt64) = sql.NullInt64{Int64: r.size, Valid: true}
+	*dest[2].(*[]byte) = r.metadata
+	return nil
+}
+
+// memStore is an object store of in-memory objects.
+type memStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *memStore) DownloadStream(_ context.Context, bucket, name string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.objects[bucket+"/"+name]
+	if !ok {
+		return nil, stg.ErrObjectNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *memStore) UploadStream(_ context.Context, bucket, name string, r io.Reader, _ int64) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[bucket+"/"+name] = data
+	return nil
+}
+
+// memArchiver archives to memory, reading the data twice as the Arweave
+// uploader does.
+type memArchiver struct {
+	mu      sync.Mutex
+	items   map[string][]byte
+	tags    map[string][]stg.ArweaveTag
+	uploads int
+	err     error
+}
+
+func (a *memArchiver) Upload(_ context.Context, open func() (io.ReadCloser, error), size int64, tags []stg.ArweaveTag) (string, error) {
+	if a.err != nil {
+		return "", a.err
+	}
+	var data []byte
+	for pass := 0; pass < 2; pass++ {
+		rc, err := open()
+		if err != nil {
+			return "", err
+		}
+		data, _ = io.ReadAll(rc)
+		_ = rc.Close()
+	}
+	if int64(len(data)) != size {
+		return "", errors.New("size mismatch")
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.uploads++
+	id := "tx-" + string(rune('0'+a.uploads))
+	a.items[id], a.tags[id] = data, tags
+	return id, nil
+}
+
+func (a *memArchiver) Download(_ context.Context, id string) (io.ReadCloser, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	data, ok := a.items[id]
+	if !ok {
+		return nil, stg.ErrObjectNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func newTestService(t *testing.T) (*Service, *contentDB, *memStore, *memArchiver) {
+	t.Helper()
+	original := []byte("original master bytes")
+	db := &contentDB{rows: map[string]*contentRow{
+		"c1":       {url: "/streamgate/uploads/u1/movie.mp4", size: int64(len(original)), metadata: map[string]interface{}{"codec": "h264"}},
+		"external": {url: "https://example.com/movie.mp4", size: 10, metadata: map[string]interface{}{}},
+	}}
+	store := &memStore{objects: map[string][]byte{"streamgate/uploads/u1/movie.mp4": original}}
+	archiver := &memArchiver{items: make(map[string][]byte), tags: make(map[string][]stg.ArweaveTag)}
+	return NewArchiveService(db, store, archiver, Config{}, zap.NewNop()), db, store, archiver
+}
+
+func TestService_ArchiveAndRestore(t *testing.T) {
+	svc, db, store, archiver := newTestService(t)
+	ctx := context.Background()
+
+	rec, err := svc.Archive(ctx, "c1")
+	require.NoError(t, err)
+	assert.Equal(t, "tx-1", rec.TxID)
+	require.NotNil(t, rec.ArchivedAt)
+	assert.Equal(t, "tx-1", db.meta("c1", "arweave_tx"))
+	assert.Equal(t, "h264", db.meta("c1", "codec"))
+	assert.Contains(t, archiver.tags["tx-1"], stg.ArweaveTag{Name: "Content-Type", Value: "video/mp4"})
+	assert.Contains(t, archiver.tags["tx-1"], stg.ArweaveTag{Name: "Content-Id", Value: "c1"})
+
+	// The archived copy is permanent, so archiving again is a no-op.
+	again, err := svc.Archive(ctx, "c1")
+	require.NoError(t, err)
+	assert.Equal(t, "tx-1", again.TxID)
+	assert.Equal(t, 1, archiver.uploads)
+
+	delete(store.objects, "streamgate/uploads/u1/movie.mp4")
+	rec, err = svc.Restore(ctx, "c1")
+	require.NoError(t, err)
+	require.NotNil(t, rec.RestoredAt)
+	assert.Equal(t, []byte("original master bytes"), store.objects["streamgate/uploads/u1/movie.mp4"])
+	assert.NotNil(t, db.meta("c1", "arweave_restored_at"))
+}
+
+func TestService_ArchiveFailureIsRecorded(t *testing.T) {
+	svc, db, _, archiver := newTestService(t)
+	archiver.err = errors.New("bundler unavailable")
+
+	rec, err := svc.Archive(context.Background(), "c1")
+	require.Error(t, err)
+	assert.Equal(t, "bundler unavailable", rec.Error)
+	assert.Equal(t, "bundler unavailable", db.meta("c1", "arweave_error"))
+
+	archiver.err = nil
+	rec, err = svc.Archive(context.Background(), "c1")
+	require.NoError(t, err)
+	assert.Empty(t, rec.Error)
+	assert.Nil(t, db.meta("c1", "arweave_error"))
+}
+
+func TestService_Errors(t *testing.T) {
+	svc, _, _, _ := newTestService(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		call    func() error
+		wantErr error
+	}{
+		{"archive unknown content", func() error { _, err := svc.Archive(ctx, "missing"); return err }, serviceerrors.ErrNotFound},
+		{"archive external url", func() error { _, err := svc.Archive(ctx, "external"); return err }, serviceerrors.ErrInvalidRequest},
+		{"restore unarchived", func() error { _, err := svc.Restore(ctx, "c1"); return err }, serviceerrors.ErrInvalidRequest},
+		{"get unknown content", func() error { _, err := svc.Get(ctx, "missing"); return err }, serviceerrors.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, tt.call(), tt.wantErr)
+		})
+	}
+}
+
+func TestService_ArchiveAsync(t *testing.T) {
+	svc, db, _, archiver := newTestService(t)
+
+	// Hooks fire once per transcoded profile; only one upload happens.
+	for i := 0; i < 3; i++ {
+		svc.ArchiveAsync("c1")
+	}
+	svc.Close()
+	assert.Equal(t, 1, archiver.uploads)
+	assert.Equal(t, "tx-1", db.meta("c1", "arweave_tx"))
+}
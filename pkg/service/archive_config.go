@@ -0,0 +1,44 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/rtcdance/streamgate/pkg/storage"
+
+	"go.uber.org/zap"
+)
+
+// NewArchiveServiceFromConfig builds the Arweave archive service described
+// by cfg over the originals in store, or returns nil when archiving is
+// disabled.
+func NewArchiveServiceFromConfig(db storage.DB, store ArchiveObjectStore, cfg config.ArchiveConfig, bucket string, logger *zap.Logger) (*ArchiveService, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if cfg.Arweave.WalletPath == "" {
+		return nil, fmt.Errorf("archive.arweave.wallet_path is required when archiving is enabled")
+	}
+	wallet, err := os.ReadFile(cfg.Arweave.WalletPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read arweave wallet: %w", err)
+	}
+	uploader, err := storage.NewArweaveUploader(storage.ArweaveConfig{
+		BundlerURL: cfg.Arweave.BundlerURL,
+		GatewayURL: cfg.Arweave.GatewayURL,
+		Wallet:     wallet,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var timeout time.Duration
+	if cfg.Timeout != "" {
+		if timeout, err = time.ParseDuration(cfg.Timeout); err != nil || timeout < 0 {
+			return nil, fmt.Errorf("invalid archive.timeout %q", cfg.Timeout)
+		}
+	}
+	logger.Info("Arweave archiving enabled", zap.String("wallet", uploader.Address()))
+	return NewArchiveService(db, store, uploader, ArchiveConfig{Bucket: bucket, Timeout: timeout}, logger), nil
+}
@@ -0,0 +1,13 @@
+package service
+
+import "github.com/rtcdance/streamgate/pkg/service/archive"
+
+type (
+	ArchiveService     = archive.Service
+	ArchiveConfig      = archive.Config
+	ArchiveRecord      = archive.Record
+	Archiver           = archive.Archiver
+	ArchiveObjectStore = archive.ObjectStore
+)
+
+var NewArchiveService = archive.NewArchiveService
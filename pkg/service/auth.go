@@ -2,9 +2,13 @@ package service
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rsa"
 	"fmt"
 	"io"
+	"slices"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -16,12 +20,17 @@ import (
 	"github.com/rtcdance/streamgate/pkg/web3"
 )
 
-// JWTVerifier validates JWT tokens using either an HMAC secret or RSA public key.
+// JWTVerifier validates JWT tokens using an HMAC secret, an RSA public key
+// or an ECDSA P-256 public key.
 // Use this in streaming/gateway services that only need to verify, not issue, tokens.
 type JWTVerifier struct {
 	hmacSecret  []byte
 	publicKey   *rsa.PublicKey
+	ecPublicKey *ecdsa.PublicKey
 	signingType JWTSigningType
+	// previousKeys verify tokens whose "kid" header names a key that has
+	// been rotated out, until those tokens expire.
+	previousKeys map[string]crypto.PublicKey
 }
 
 // JWTSigningType specifies the algorithm used for JWT signing.
@@ -30,6 +39,7 @@ type JWTSigningType int
 const (
 	JWTHS256 JWTSigningType = iota
 	JWTRS256
+	JWTES256
 )
 
 // NewJWTVerifier creates a verifier-only JWT client for services that do not issue tokens.
@@ -52,19 +62,29 @@ func WithRSAPublicKey(key *rsa.PublicKey) JWTVerifierOption {
 	}
 }
 
+// WithECDSAPublicKey sets the P-256 public key for ES256 verification.
+func WithECDSAPublicKey(key *ecdsa.PublicKey) JWTVerifierOption {
+	return func(v *JWTVerifier) {
+		v.ecPublicKey = key
+		v.signingType = JWTES256
+	}
+}
+
+// WithPreviousPublicKey accepts tokens signed by a rotated-out RSA or ECDSA
+// key, identified by the "kid" header it was issued with.
+func WithPreviousPublicKey(kid string, key crypto.PublicKey) JWTVerifierOption {
+	return func(v *JWTVerifier) {
+		if v.previousKeys == nil {
+			v.previousKeys = make(map[string]crypto.PublicKey)
+		}
+		v.previousKeys[kid] = key
+	}
+}
+
 // ParseToken parses and validates a JWT with 30s clock skew leeway on exp/nbf,
 // matching AuthService.ParseToken to prevent false rejections in distributed deployments.
 func (v *JWTVerifier) ParseToken(tokenString string) (*Claims, error) {
-	claims := &Claims{}
-	validMethods := []string{"HS256"}
-	if v.signingType == JWTRS256 {
-		validMethods = []string{"RS256"}
-	}
-
-	parser := jwt.NewParser(jwt.WithValidMethods(validMethods), jwt.WithoutClaimsValidation())
-	_, err := parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return v.keyFunc(token)
-	})
+	claims, err := v.parse(tokenString)
 	if err != nil {
 		return nil, fmt.Errorf("token verification failed: %w", err)
 	}
@@ -79,19 +99,97 @@ func (v *JWTVerifier) ParseToken(tokenString string) (*Claims, error) {
 	return claims, nil
 }
 
-func (v *JWTVerifier) keyFunc(token *jwt.Token) (interface{}, error) {
+// parse checks tokenString's signature, leaving exp/nbf to the caller.
+func (v *JWTVerifier) parse(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	parser := jwt.NewParser(jwt.WithValidMethods(v.validMethods()), jwt.WithoutClaimsValidation())
+	if _, err := parser.ParseWithClaims(tokenString, claims, v.keyFunc); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// currentKey returns the key tokens are verified with unless their kid
+// names a previous key.
+func (v *JWTVerifier) currentKey() interface{} {
 	switch v.signingType {
 	case JWTRS256:
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return v.publicKey, nil
+		return v.publicKey
+	case JWTES256:
+		return v.ecPublicKey
 	default:
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		return v.hmacSecret
+	}
+}
+
+// validMethods lists the algorithms of the current and previous keys, so a
+// rotation may also change the algorithm.
+func (v *JWTVerifier) validMethods() []string {
+	methods := []string{keyAlgorithm(v.currentKey())}
+	for _, key := range v.previousKeys {
+		if alg := keyAlgorithm(key); alg != "" && !slices.Contains(methods, alg) {
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+func (v *JWTVerifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	key := v.currentKey()
+	if kid, ok := token.Header["kid"].(string); ok {
+		if prev, ok := v.previousKeys[kid]; ok {
+			key = prev
+		}
+	}
+	if token.Method.Alg() != keyAlgorithm(key) {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key, nil
+}
+
+// keyAlgorithm returns the JWT algorithm a verification key is used with.
+func keyAlgorithm(key interface{}) string {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256.Alg()
+	case *ecdsa.PublicKey:
+		return jwt.SigningMethodES256.Alg()
+	case []byte:
+		return jwt.SigningMethodHS256.Alg()
+	}
+	return ""
+}
+
+// ParseSigningKey loads a PEM-encoded RSA or ECDSA P-256 private key and
+// returns the option that signs with it: RS256 or ES256.
+func ParseSigningKey(pemData []byte) (AuthServiceSigningOption, error) {
+	if key, err := jwt.ParseECPrivateKeyFromPEM(pemData); err == nil {
+		if key.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("ES256 requires a P-256 key, got %s", key.Curve.Params().Name)
 		}
-		return v.hmacSecret, nil
+		return WithECDSASigning(key), nil
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(pemData)
+	if err != nil {
+		return nil, fmt.Errorf("signing key is neither an ECDSA nor an RSA private key: %w", err)
+	}
+	if key.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("RS256 key must be at least 2048 bits, got %d", key.N.BitLen())
 	}
+	return WithRSASigning(key), nil
+}
+
+// ParsePublicKey loads a PEM-encoded RSA or ECDSA public key, e.g. one
+// that has been rotated out and is passed to WithPreviousSigningKey.
+func ParsePublicKey(pemData []byte) (crypto.PublicKey, error) {
+	if key, err := jwt.ParseECPublicKeyFromPEM(pemData); err == nil {
+		return key, nil
+	}
+	key, err := jwt.ParseRSAPublicKeyFromPEM(pemData)
+	if err != nil {
+		return nil, fmt.Errorf("public key is neither an ECDSA nor an RSA key: %w", err)
+	}
+	return key, nil
 }
 
 // AuthService handles authentication
@@ -99,7 +197,10 @@ type AuthService struct {
 	jwtSecret         []byte
 	privateKey        *rsa.PrivateKey
 	publicKey         *rsa.PublicKey
+	ecPrivateKey      *ecdsa.PrivateKey
 	signingType       JWTSigningType
+	keyID             string
+	previousKeys      map[string]crypto.PublicKey
 	storage           AuthStorage
 	signatureVerifier web3.SignatureVerifierInterface
 	challengeStore    stg.ChallengeStore
@@ -110,6 +211,9 @@ type AuthService struct {
 	eip712Verifier    web3.EIP712VerifierInterface
 	siweDomain        string
 	siweURI           string
+	defaultSignType   string
+	loginMessage      *web3.LoginMessageTemplate
+	loginStatement    string
 }
 
 // AuthServiceOption configures an AuthService with optional dependencies.
@@ -158,6 +262,23 @@ func WithSIWEDomain(domain, uri string) AuthServiceOption {
 	}
 }
 
+// WithChallengeMessage configures wallet login messages. signType is used
+// when a client does not request one ("siwe" or "personal_sign"; empty
+// keeps "siwe"). tmpl renders personal_sign messages, nil keeping the
+// built-in text, and statement replaces the SIWE statement line and is
+// available to tmpl as {{.Statement}}.
+func WithChallengeMessage(signType string, tmpl *web3.LoginMessageTemplate, statement string) AuthServiceOption {
+	return func(s *AuthService) {
+		if signType != "" {
+			s.defaultSignType = signType
+		}
+		if tmpl != nil {
+			s.loginMessage = tmpl
+		}
+		s.loginStatement = statement
+	}
+}
+
 // WithAuditLogger sets the audit logger for auth operations.
 func WithAuditLogger(al stg.AuditLogger) AuthServiceOption {
 	return func(s *AuthService) { s.auditLogger = al }
@@ -196,6 +317,49 @@ func WithRSASigning(privateKey *rsa.PrivateKey) AuthServiceSigningOption {
 	}
 }
 
+// WithECDSASigning configures the AuthService to use ES256 with a P-256
+// private key; the public key is embedded for verification.
+func WithECDSASigning(privateKey *ecdsa.PrivateKey) AuthServiceSigningOption {
+	return func(s *AuthService) {
+		s.ecPrivateKey = privateKey
+		s.signingType = JWTES256
+	}
+}
+
+// WithSigningKeyID sets the "kid" header of issued tokens. Give each key a
+// new ID when rotating, so tokens signed by the old key can still be
+// verified through WithPreviousSigningKey.
+func WithSigningKeyID(kid string) AuthServiceSigningOption {
+	return func(s *AuthService) {
+		s.keyID = kid
+	}
+}
+
+// WithPreviousSigningKey keeps accepting tokens signed by a rotated-out
+// RSA or ECDSA key, identified by kid, until they expire.
+func WithPreviousSigningKey(kid string, key crypto.PublicKey) AuthServiceSigningOption {
+	return func(s *AuthService) {
+		if s.previousKeys == nil {
+			s.previousKeys = make(map[string]crypto.PublicKey)
+		}
+		s.previousKeys[kid] = key
+	}
+}
+
+// verifier checks tokens against the service's current and previous keys.
+func (s *AuthService) verifier() *JWTVerifier {
+	v := &JWTVerifier{
+		hmacSecret:   s.jwtSecret,
+		publicKey:    s.publicKey,
+		signingType:  s.signingType,
+		previousKeys: s.previousKeys,
+	}
+	if s.ecPrivateKey != nil {
+		v.ecPublicKey = &s.ecPrivateKey.PublicKey
+	}
+	return v
+}
+
 // errSigVerifier returns ErrNotSupported for all verifications.
 // It serves as a safe default when no real signature verifier is injected.
 type errSigVerifier struct{}
@@ -204,6 +368,10 @@ func (errSigVerifier) VerifySignature(_ context.Context, _, _, _ string) (bool,
 	return false, ErrNotSupported
 }
 
+// defaultLoginMessage renders personal_sign challenges when no template is
+// configured. The built-in template always parses.
+var defaultLoginMessage, _ = web3.ParseLoginMessageTemplate("")
+
 func NewAuthService(jwtSecret string, storage AuthStorage, opts ...AuthServiceOption) *AuthService {
 	if len(jwtSecret) < 32 {
 		panic("jwtSecret must be at least 32 characters for HS256 security")
@@ -217,6 +385,8 @@ func NewAuthService(jwtSecret string, storage AuthStorage, opts ...AuthServiceOp
 		jwtExpiry:         2 * time.Hour,
 		siweDomain:        "streamgate.io",
 		siweURI:           "https://streamgate.io/login",
+		defaultSignType:   "siwe",
+		loginMessage:      defaultLoginMessage,
 	}
 	for _, opt := range opts {
 		opt(s)
@@ -296,31 +466,10 @@ func (s *AuthService) Verify(tokenString string) (bool, error) {
 }
 
 func (s *AuthService) ParseToken(tokenString string) (*Claims, error) {
-	claims := &Claims{}
-	validMethods := []string{"HS256"}
-	if s.signingType == JWTRS256 {
-		validMethods = []string{"RS256"}
-	}
-
 	// Parse with signature verification but skip automatic claims
 	// validation. Claims are checked manually below with 30s leeway
 	// to tolerate clock skew between services.
-	parser := jwt.NewParser(jwt.WithValidMethods(validMethods), jwt.WithoutClaimsValidation())
-	_, err := parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		switch s.signingType {
-		case JWTRS256:
-			if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return s.publicKey, nil
-		default:
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return s.jwtSecret, nil
-		}
-	})
-
+	claims, err := s.verifier().parse(tokenString)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
 	}
@@ -338,14 +487,20 @@ func (s *AuthService) ParseToken(tokenString string) (*Claims, error) {
 }
 
 func (s *AuthService) signToken(claims *Claims) (string, error) {
+	var token *jwt.Token
+	var key interface{}
 	switch s.signingType {
 	case JWTRS256:
-		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-		return token.SignedString(s.privateKey)
+		token, key = jwt.NewWithClaims(jwt.SigningMethodRS256, claims), s.privateKey
+	case JWTES256:
+		token, key = jwt.NewWithClaims(jwt.SigningMethodES256, claims), s.ecPrivateKey
 	default:
-		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-		return token.SignedString(s.jwtSecret)
+		token, key = jwt.NewWithClaims(jwt.SigningMethodHS256, claims), s.jwtSecret
+	}
+	if s.keyID != "" {
+		token.Header["kid"] = s.keyID
 	}
+	return token.SignedString(key)
 }
 
 // Register registers a new user
@@ -467,26 +622,7 @@ func (s *AuthService) RefreshToken(ctx context.Context, tokenString string) (str
 // parseTokenAllowExpired parses a JWT token, allowing tokens that expired
 // within the given grace period. This enables token refresh after expiry.
 func (s *AuthService) parseTokenAllowExpired(tokenString string, gracePeriod time.Duration) (*Claims, error) {
-	claims := &Claims{}
-	validMethods := []string{"HS256"}
-	if s.signingType == JWTRS256 {
-		validMethods = []string{"RS256"}
-	}
-	parser := jwt.NewParser(jwt.WithValidMethods(validMethods), jwt.WithoutClaimsValidation())
-	_, err := parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		switch s.signingType {
-		case JWTRS256:
-			if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return s.publicKey, nil
-		default:
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return s.jwtSecret, nil
-		}
-	})
+	claims, err := s.verifier().parse(tokenString)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
 	}
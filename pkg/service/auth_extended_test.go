@@ -2,8 +2,12 @@ package service
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"testing"
 	"time"
 
@@ -634,3 +638,217 @@ func (m *mockAuditLogger) Log(_ context.Context, action, actor, _, _ string, suc
 }
 
 func (m *mockAuditLogger) Close() error { return nil }
+
+func testClaims(username string) *Claims {
+	return &Claims{
+		Username: username,
+		JTI:      generateID(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+		},
+	}
+}
+
+func TestAuthService_SigningAlgorithms(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		opts     []AuthServiceOption
+		verifier *JWTVerifier
+		alg      string
+	}{
+		{
+			name:     "HS256",
+			verifier: NewJWTVerifier("test-secret-that-is-at-least-32-chars"),
+			alg:      "HS256",
+		},
+		{
+			name:     "RS256",
+			opts:     []AuthServiceOption{AuthServiceOption(WithRSASigning(rsaKey))},
+			verifier: NewJWTVerifier("unused", WithRSAPublicKey(&rsaKey.PublicKey)),
+			alg:      "RS256",
+		},
+		{
+			name:     "ES256",
+			opts:     []AuthServiceOption{AuthServiceOption(WithECDSASigning(ecKey))},
+			verifier: NewJWTVerifier("unused", WithECDSAPublicKey(&ecKey.PublicKey)),
+			alg:      "ES256",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := NewAuthService("test-secret-that-is-at-least-32-chars", NewMockAuthStorage(), tt.opts...)
+			token, err := auth.signToken(testClaims("alice"))
+			require.NoError(t, err)
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.alg, parsed.Method.Alg())
+
+			claims, err := auth.ParseToken(token)
+			require.NoError(t, err)
+			assert.Equal(t, "alice", claims.Username)
+
+			claims, err = tt.verifier.ParseToken(token)
+			require.NoError(t, err)
+			assert.Equal(t, "alice", claims.Username)
+
+			refreshed, err := auth.RefreshToken(context.Background(), token)
+			require.NoError(t, err)
+			_, err = auth.ParseToken(refreshed)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestAuthService_RejectsOtherAlgorithms(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherEC, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	hs := NewAuthService("test-secret-that-is-at-least-32-chars", NewMockAuthStorage())
+	rs := NewAuthService("test-secret-that-is-at-least-32-chars", NewMockAuthStorage(), AuthServiceOption(WithRSASigning(rsaKey)))
+	es := NewAuthService("test-secret-that-is-at-least-32-chars", NewMockAuthStorage(), AuthServiceOption(WithECDSASigning(ecKey)))
+	esOther := NewAuthService("test-secret-that-is-at-least-32-chars", NewMockAuthStorage(), AuthServiceOption(WithECDSASigning(otherEC)))
+
+	tests := []struct {
+		name   string
+		signer *AuthService
+		parser *AuthService
+	}{
+		{"HS256 token on ES256 service", hs, es},
+		{"RS256 token on ES256 service", rs, es},
+		{"ES256 token on RS256 service", es, rs},
+		{"ES256 token on HS256 service", es, hs},
+		{"ES256 token signed by another key", esOther, es},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := tt.signer.signToken(testClaims("mallory"))
+			require.NoError(t, err)
+			_, err = tt.parser.ParseToken(token)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestAuthService_KeyRotation(t *testing.T) {
+	oldRSA, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	oldEC, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	newEC, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		oldOpt AuthServiceSigningOption
+		oldPub interface{}
+	}{
+		{"RS256 to ES256", WithRSASigning(oldRSA), &oldRSA.PublicKey},
+		{"ES256 to ES256", WithECDSASigning(oldEC), &oldEC.PublicKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := NewAuthService("test-secret-that-is-at-least-32-chars", NewMockAuthStorage(),
+				AuthServiceOption(tt.oldOpt), AuthServiceOption(WithSigningKeyID("k1")))
+			oldToken, err := old.signToken(testClaims("alice"))
+			require.NoError(t, err)
+
+			rotated := NewAuthService("test-secret-that-is-at-least-32-chars", NewMockAuthStorage(),
+				AuthServiceOption(WithECDSASigning(newEC)),
+				AuthServiceOption(WithSigningKeyID("k2")),
+				AuthServiceOption(WithPreviousSigningKey("k1", tt.oldPub)))
+			newToken, err := rotated.signToken(testClaims("bob"))
+			require.NoError(t, err)
+			parsed, _, err := jwt.NewParser().ParseUnverified(newToken, &Claims{})
+			require.NoError(t, err)
+			assert.Equal(t, "k2", parsed.Header["kid"])
+
+			claims, err := rotated.ParseToken(oldToken)
+			require.NoError(t, err, "tokens of the previous key stay valid")
+			assert.Equal(t, "alice", claims.Username)
+			_, err = rotated.ParseToken(newToken)
+			require.NoError(t, err)
+
+			verifier := NewJWTVerifier("unused", WithECDSAPublicKey(&newEC.PublicKey), WithPreviousPublicKey("k1", tt.oldPub))
+			_, err = verifier.ParseToken(oldToken)
+			assert.NoError(t, err)
+			_, err = verifier.ParseToken(newToken)
+			assert.NoError(t, err)
+
+			// Once the previous key is dropped its tokens are rejected.
+			dropped := NewAuthService("test-secret-that-is-at-least-32-chars", NewMockAuthStorage(),
+				AuthServiceOption(WithECDSASigning(newEC)), AuthServiceOption(WithSigningKeyID("k2")))
+			_, err = dropped.ParseToken(oldToken)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseSigningKey(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecDER, err := x509.MarshalECPrivateKey(ecKey)
+	require.NoError(t, err)
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	p384DER, err := x509.MarshalECPrivateKey(p384)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	smallRSA, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	require.NoError(t, err)
+
+	encode := func(typ string, der []byte) []byte {
+		return pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+	}
+	tests := []struct {
+		name    string
+		pem     []byte
+		want    JWTSigningType
+		wantErr string
+	}{
+		{"EC P-256", encode("EC PRIVATE KEY", ecDER), JWTES256, ""},
+		{"EC P-256 PKCS8", encode("PRIVATE KEY", pkcs8), JWTES256, ""},
+		{"RSA", encode("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)), JWTRS256, ""},
+		{"EC P-384", encode("EC PRIVATE KEY", p384DER), 0, "P-256"},
+		{"RSA 1024", encode("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(smallRSA)), 0, "2048 bits"},
+		{"garbage", []byte("not a key"), 0, "neither"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opt, err := ParseSigningKey(tt.pem)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			auth := NewAuthService("test-secret-that-is-at-least-32-chars", NewMockAuthStorage(), AuthServiceOption(opt))
+			assert.Equal(t, tt.want, auth.signingType)
+			token, err := auth.signToken(testClaims("alice"))
+			require.NoError(t, err)
+			_, err = auth.ParseToken(token)
+			assert.NoError(t, err)
+		})
+	}
+
+	pubDER, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	require.NoError(t, err)
+	pub, err := ParsePublicKey(encode("PUBLIC KEY", pubDER))
+	require.NoError(t, err)
+	assert.Equal(t, &ecKey.PublicKey, pub)
+	_, err = ParsePublicKey([]byte("not a key"))
+	assert.Error(t, err)
+}
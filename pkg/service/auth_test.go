@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"errors"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -15,6 +16,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -871,3 +873,106 @@ func TestAuthService_AuthenticateWithWallet_MissingParams(t *testing.T) {
 		assert.ErrorIs(t, err, ErrInvalidRequest)
 	})
 }
+
+func TestAuthService_ChallengeMessageTemplate(t *testing.T) {
+	tmpl, err := web3.ParseLoginMessageTemplate("Welcome to Acme Video on {{.Domain}}!\n{{.Statement}}\n\nWallet: {{.Address}}\nNonce: {{.Nonce}}\nIssued: {{.IssuedAt}}")
+	require.NoError(t, err)
+	auth := NewAuthService("test-secret-that-is-at-least-32-chars", NewMockAuthStorage(),
+		WithSIWEDomain("acme.example", "https://acme.example/login"),
+		WithChallengeMessage("personal_sign", tmpl, "Sign in to watch your collection."),
+	)
+	verifier := web3.NewSignatureVerifier(zap.NewNop())
+	auth.signatureVerifier = verifier
+
+	privateKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	walletAddress := verifier.GetAddressFromPrivateKey(privateKey)
+
+	challenge, err := auth.GenerateWalletChallenge(context.Background(), walletAddress, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "personal_sign", challenge.SigningType, "configured format applies when the client does not choose")
+	assert.True(t, strings.HasPrefix(challenge.Message, "Welcome to Acme Video on acme.example!\nSign in to watch your collection."))
+	assert.Contains(t, challenge.Message, "Nonce: "+challenge.Nonce)
+	assert.Contains(t, challenge.Message, "Issued: "+challenge.IssuedAt.Format(time.RFC3339))
+
+	signature, err := verifier.SignMessage(challenge.Message, privateKey)
+	require.NoError(t, err)
+	token, err := auth.AuthenticateWithWallet(context.Background(), walletAddress, challenge.ID, signature, 1)
+	require.NoError(t, err)
+	assert.NotEmpty(t, token)
+
+	siwe, err := auth.GenerateWalletChallenge(context.Background(), walletAddress, 1, "siwe")
+	require.NoError(t, err)
+	parsed, err := web3.ParseSIWEMessage(siwe.Message)
+	require.NoError(t, err)
+	assert.Equal(t, "Sign in to watch your collection.", parsed.Statement)
+	assert.Equal(t, siwe.Nonce, parsed.Nonce)
+	assert.Equal(t, siwe.IssuedAt.Format(time.RFC3339), parsed.IssuedAt)
+
+	signature, err = verifier.SignMessage(siwe.Message, privateKey)
+	require.NoError(t, err)
+	_, err = auth.AuthenticateWithWallet(context.Background(), walletAddress, siwe.ID, signature, 1)
+	require.NoError(t, err, "branded SIWE messages still pass domain and nonce validation")
+}
+
+// authCounter reads a streamgate_auth_* counter, filtered by its "result"
+// label when result is non-empty.
+func authCounter(t *testing.T, name, result string) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, mf := range families {
+		if mf.GetName() != name {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			if result == "" {
+				return m.GetCounter().GetValue()
+			}
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "result" && l.GetValue() == result {
+					return m.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func TestAuthService_WalletAuthMetrics(t *testing.T) {
+	auth := NewAuthService("test-secret-that-is-at-least-32-chars", NewMockAuthStorage())
+	verifier := web3.NewSignatureVerifier(zap.NewNop())
+	auth.signatureVerifier = verifier
+	ctx := context.Background()
+
+	issued := authCounter(t, "streamgate_auth_challenges_total", "")
+	success := authCounter(t, "streamgate_auth_verifications_total", "success")
+	invalid := authCounter(t, "streamgate_auth_verifications_total", "invalid")
+	used := authCounter(t, "streamgate_auth_verifications_total", "used")
+
+	privateKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	walletAddress := verifier.GetAddressFromPrivateKey(privateKey)
+	otherKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	challenge, err := auth.GenerateWalletChallenge(ctx, walletAddress, 1, "personal_sign")
+	require.NoError(t, err)
+	assert.Equal(t, issued+1, authCounter(t, "streamgate_auth_challenges_total", ""))
+
+	forged, err := verifier.SignMessage(challenge.Message, otherKey)
+	require.NoError(t, err)
+	_, err = auth.AuthenticateWithWallet(ctx, walletAddress, challenge.ID, forged, 1)
+	require.Error(t, err)
+
+	signature, err := verifier.SignMessage(challenge.Message, privateKey)
+	require.NoError(t, err)
+	_, err = auth.AuthenticateWithWallet(ctx, walletAddress, challenge.ID, signature, 1)
+	require.NoError(t, err)
+	_, err = auth.AuthenticateWithWallet(ctx, walletAddress, challenge.ID, signature, 1)
+	require.Error(t, err)
+
+	assert.Equal(t, invalid+1, authCounter(t, "streamgate_auth_verifications_total", "invalid"))
+	assert.Equal(t, success+1, authCounter(t, "streamgate_auth_verifications_total", "success"))
+	assert.Equal(t, used+1, authCounter(t, "streamgate_auth_verifications_total", "used"))
+}
@@ -89,8 +89,9 @@ type ChainAwareSignatureVerifier interface {
 
 // GenerateWalletChallenge creates and stores a one-time wallet login challenge.
 // Supports both EVM (hex addresses) and Solana (base58 addresses) chains.
-// signType controls the signing method: "siwe" (default, EIP-4361), "personal_sign",
-// or "eip712". Solana chains ignore signType and always use Ed25519 off-chain verification.
+// signType controls the signing method: "siwe" (EIP-4361), "personal_sign", or
+// "eip712"; empty uses the configured default (see WithChallengeMessage). Solana
+// chains ignore signType and always use Ed25519 off-chain verification.
 //
 // When possible, prefer "siwe" — it follows the EIP-4361 standard and provides better
 // wallet UX (structured parsing, human-readable domain, nonce).
@@ -122,7 +123,7 @@ func (s *AuthService) GenerateWalletChallenge(ctx context.Context, walletAddress
 	now := time.Now().UTC()
 	expiresAt := now.Add(s.challengeTTL)
 
-	st := "siwe"
+	st := s.defaultSignType
 	if len(signType) > 0 && signType[0] != "" {
 		st = signType[0]
 	}
@@ -147,6 +148,7 @@ func (s *AuthService) GenerateWalletChallenge(ctx context.Context, walletAddress
 			challenge.Nonce,
 			challenge.IssuedAt,
 			web3.WithSIWEExpirationTime(challenge.ExpiresAt),
+			web3.WithSIWEStatement(s.loginStatement),
 		)
 		challenge.Message = web3.BuildSIWEMessage(siweMsg)
 	case "eip712":
@@ -157,14 +159,19 @@ func (s *AuthService) GenerateWalletChallenge(ctx context.Context, walletAddress
 		}
 		challenge.Message = string(encoded)
 	default:
-		challenge.Message = fmt.Sprintf(
-			"Sign this message to authenticate with StreamGate.\nAddress: %s\nChain ID: %d\nNonce: %s\nIssued At: %s\nExpires At: %s",
-			challenge.WalletAddress,
-			challenge.ChainID,
-			challenge.Nonce,
-			challenge.IssuedAt.Format(time.RFC3339),
-			challenge.ExpiresAt.Format(time.RFC3339),
-		)
+		msg, err := s.loginMessage.Render(web3.LoginMessageFields{
+			Domain:    s.siweDomain,
+			Address:   challenge.WalletAddress,
+			ChainID:   challenge.ChainID,
+			Nonce:     challenge.Nonce,
+			IssuedAt:  challenge.IssuedAt.Format(time.RFC3339),
+			ExpiresAt: challenge.ExpiresAt.Format(time.RFC3339),
+			Statement: s.loginStatement,
+		})
+		if err != nil {
+			return nil, err
+		}
+		challenge.Message = msg
 	}
 
 	if err := s.challengeStore.SaveChallenge(ctx, challenge); err != nil {
@@ -174,6 +181,7 @@ func (s *AuthService) GenerateWalletChallenge(ctx context.Context, walletAddress
 	}
 
 	svcWalletAuthTotal.WithLabelValues("generate_challenge", "success").Inc()
+	monitoring.AuthChallengesTotal.Inc()
 	svcWalletAuthDuration.WithLabelValues("generate_challenge").Observe(time.Since(start).Seconds())
 	return challenge, nil
 }
@@ -193,6 +201,7 @@ func (s *AuthService) AuthenticateWithWallet(ctx context.Context, walletAddress,
 		}
 		svcWalletAuthTotal.WithLabelValues("authenticate", status).Inc()
 		svcWalletAuthDuration.WithLabelValues("authenticate").Observe(time.Since(start).Seconds())
+		monitoring.AuthVerificationsTotal.WithLabelValues(walletVerificationResult(err)).Inc()
 
 		if s.auditLogger != nil {
 			errMsg := ""
@@ -243,6 +252,26 @@ func (s *AuthService) AuthenticateWithWallet(ctx context.Context, walletAddress,
 		return "", ErrChallengeExpired
 	}
 
+	// For SIWE challenges, validate every field of the stored message
+	// before checking the signature. This ensures domain and URI binding
+	// (phishing protection), nonce and address match, and the validity
+	// window per EIP-4361.
+	if challenge.SigningType == "siwe" {
+		parsedSIWE, parseErr := web3.ParseSIWEMessage(challenge.Message)
+		if parseErr != nil {
+			return "", fmt.Errorf("failed to parse SIWE message: %w", parseErr)
+		}
+		if validateErr := web3.ValidateSIWEFields(parsedSIWE, web3.SIWEExpectations{
+			Domain:  s.siweDomain,
+			Address: normalizedAddress,
+			URI:     s.siweURI,
+			Nonce:   challenge.Nonce,
+			ChainID: challenge.ChainID,
+		}); validateErr != nil {
+			return "", fmt.Errorf("SIWE validation failed: %w", validateErr)
+		}
+	}
+
 	// Route to the correct signature verifier based on chain type and signing type
 	var valid bool
 	if isSolanaChain(challenge.ChainID) {
@@ -275,24 +304,6 @@ func (s *AuthService) AuthenticateWithWallet(ctx context.Context, walletAddress,
 		return "", ErrInvalidCredential
 	}
 
-	// For SIWE challenges, validate the parsed message against expected values.
-	// This ensures domain binding (phishing protection), nonce match, and
-	// address consistency per EIP-4361.
-	if challenge.SigningType == "siwe" {
-		parsedSIWE, parseErr := web3.ParseSIWEMessage(challenge.Message)
-		if parseErr != nil {
-			return "", fmt.Errorf("failed to parse SIWE message: %w", parseErr)
-		}
-		if validateErr := web3.ValidateSIWEMessage(parsedSIWE,
-			s.siweDomain,
-			normalizedAddress,
-			challenge.Nonce,
-			challenge.ChainID,
-		); validateErr != nil {
-			return "", fmt.Errorf("SIWE validation failed: %w", validateErr)
-		}
-	}
-
 	if err := s.challengeStore.MarkChallengeUsed(ctx, challengeID, time.Now().UTC()); err != nil {
 		// This catches the TOCTOU race: if a concurrent request consumed the challenge
 		// between the fast-fail check above and this atomic mark, the error here
@@ -303,6 +314,25 @@ func (s *AuthService) AuthenticateWithWallet(ctx context.Context, walletAddress,
 	return s.generateWalletToken(normalizedAddress)
 }
 
+// walletVerificationResult maps an AuthenticateWithWallet outcome to the
+// result label of monitoring.AuthVerificationsTotal.
+func walletVerificationResult(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, stg.ErrChallengeNotFound):
+		return "not_found"
+	case errors.Is(err, stg.ErrChallengeUsed):
+		return "used"
+	case errors.Is(err, ErrChallengeExpired):
+		return "expired"
+	case errors.Is(err, ErrInvalidCredential), errors.Is(err, ErrChainIDMismatch), errors.Is(err, ErrInvalidRequest):
+		return "invalid"
+	default:
+		return "error"
+	}
+}
+
 // buildEIP712Challenge constructs an EIP-712 typed data structure from a wallet challenge.
 // This allows wallets to sign a structured message instead of a plain-text string,
 // providing better user experience and security in MetaMask and similar wallets.
@@ -135,6 +135,33 @@ func (s *CategoryService) UnbindContent(ctx context.Context, contentID, category
 	return nil
 }
 
+// ListCategoriesForContent returns the categories contentID is bound to.
+func (s *CategoryService) ListCategoriesForContent(ctx context.Context, contentID string) ([]*models.Category, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+	query := `SELECT c.id, c.name, c.slug, c.description, c.parent_id, c.created_at
+		FROM content_categories c JOIN content_category_bindings b ON b.category_id = c.id
+		WHERE b.content_id = $1 ORDER BY c.name ASC`
+	rows, err := s.db.Query(ctx, query, contentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list content categories: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	cats := []*models.Category{}
+	for rows.Next() {
+		var cat models.Category
+		var parentID sql.NullString
+		if err := rows.Scan(&cat.ID, &cat.Name, &cat.Slug, &cat.Description, &parentID, &cat.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+		cat.ParentID = parentID.String
+		cats = append(cats, &cat)
+	}
+	return cats, rows.Err()
+}
+
 func (s *CategoryService) ListContentByCategory(ctx context.Context, categoryID string, limit, offset int) ([]string, error) {
 	if s.db == nil {
 		return nil, fmt.Errorf("database not available")
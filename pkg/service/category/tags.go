@@ -0,0 +1,249 @@
+package category
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rtcdance/streamgate/pkg/models"
+	"github.com/rtcdance/streamgate/pkg/service/serviceerrors"
+	"github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+const (
+	// MaxTagsPerContent caps the tags one content item may carry.
+	MaxTagsPerContent = 32
+	maxTagLength      = 50
+	// tagUpdateAttempts bounds the retries when concurrent edits race on
+	// the same item's tags.
+	tagUpdateAttempts = 3
+)
+
+// errTagsChanged reports that the tags were edited between read and write.
+var errTagsChanged = errors.New("tags changed concurrently")
+
+// TagCount is a tag and the number of public content items carrying it.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+// NormalizeTags lowercases and trims tags, collapses inner whitespace and
+// drops duplicates, keeping the first occurrence's position. Tags may not
+// contain commas, which separate tags in query strings.
+func NormalizeTags(tags []string) ([]string, error) {
+	out := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, raw := range tags {
+		tag, err := normalizeTag(raw)
+		if err != nil {
+			return nil, err
+		}
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	if len(out) > MaxTagsPerContent {
+		return nil, fmt.Errorf("at most %d tags are allowed: %w", MaxTagsPerContent, serviceerrors.ErrInvalidRequest)
+	}
+	return out, nil
+}
+
+func normalizeTag(raw string) (string, error) {
+	tag := strings.ToLower(strings.Join(strings.Fields(raw), " "))
+	switch {
+	case tag == "":
+		return "", fmt.Errorf("empty tag: %w", serviceerrors.ErrInvalidRequest)
+	case utf8.RuneCountInString(tag) > maxTagLength:
+		return "", fmt.Errorf("tag %q is longer than %d characters: %w", tag, maxTagLength, serviceerrors.ErrInvalidRequest)
+	case strings.ContainsRune(tag, ',') || strings.IndexFunc(tag, unicode.IsControl) >= 0:
+		return "", fmt.Errorf("tag %q contains invalid characters: %w", tag, serviceerrors.ErrInvalidRequest)
+	}
+	return tag, nil
+}
+
+// TagService manages the free-form tags on content and lists public
+// content by tag.
+type TagService struct {
+	db     storage.DB
+	logger *zap.Logger
+
+	mu    sync.RWMutex
+	hooks []func(contentID string)
+}
+
+func NewTagService(db storage.DB, logger *zap.Logger) *TagService {
+	return &TagService{db: db, logger: logger}
+}
+
+// RegisterChangeHook registers fn to run after an item's tags change, so
+// derived data such as search indexes can be refreshed.
+func (s *TagService) RegisterChangeHook(fn func(contentID string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, fn)
+}
+
+func (s *TagService) GetTags(ctx context.Context, contentID string) ([]string, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+	var tags []string
+	err := s.db.QueryRow(ctx, `SELECT COALESCE(tags, '{}') FROM contents WHERE id = $1`, contentID).Scan(pq.Array(&tags))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("content not found %s: %w", contentID, serviceerrors.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+	return tags, nil
+}
+
+// SetTags replaces the item's tags and returns them normalized.
+func (s *TagService) SetTags(ctx context.Context, contentID string, tags []string) ([]string, error) {
+	tags, err := NormalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	return s.modify(ctx, contentID, func([]string) ([]string, error) { return tags, nil })
+}
+
+// AddTags adds tags the item does not carry yet, after its existing ones.
+func (s *TagService) AddTags(ctx context.Context, contentID string, tags []string) ([]string, error) {
+	added, err := NormalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	return s.modify(ctx, contentID, func(current []string) ([]string, error) {
+		return NormalizeTags(append(append([]string{}, current...), added...))
+	})
+}
+
+// RemoveTag removes tag from the item. Removing a tag it does not carry
+// is not an error.
+func (s *TagService) RemoveTag(ctx context.Context, contentID, tag string) ([]string, error) {
+	tag, err := normalizeTag(tag)
+	if err != nil {
+		return nil, err
+	}
+	return s.modify(ctx, contentID, func(current []string) ([]string, error) {
+		out := make([]string, 0, len(current))
+		for _, t := range current {
+			if t != tag {
+				out = append(out, t)
+			}
+		}
+		return out, nil
+	})
+}
+
+// modify applies fn to the item's tags. The write only lands if the tags
+// are unchanged since they were read, so concurrent edits are retried
+// rather than lost.
+func (s *TagService) modify(ctx context.Context, contentID string, fn func(current []string) ([]string, error)) ([]string, error) {
+	for attempt := 0; attempt < tagUpdateAttempts; attempt++ {
+		current, err := s.GetTags(ctx, contentID)
+		if err != nil {
+			return nil, err
+		}
+		next, err := fn(current)
+		if err != nil {
+			return nil, err
+		}
+		result, err := s.db.Exec(ctx, `UPDATE contents SET tags = $2, updated_at = $3
+			WHERE id = $1 AND COALESCE(tags, '{}') = $4::text[]`,
+			contentID, pq.Array(next), time.Now(), pq.Array(current))
+		if err != nil {
+			return nil, fmt.Errorf("failed to update tags: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			s.notify(contentID)
+			return next, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to update tags of content %s: %w", contentID, errTagsChanged)
+}
+
+func (s *TagService) notify(contentID string) {
+	s.mu.RLock()
+	hooks := s.hooks
+	s.mu.RUnlock()
+	for _, fn := range hooks {
+		fn(contentID)
+	}
+}
+
+// ListTags returns the most used tags on public content, optionally only
+// those starting with prefix.
+func (s *TagService) ListTags(ctx context.Context, prefix string, limit int) ([]TagCount, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	pattern := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix) + "%"
+	query := `SELECT t, COUNT(*) FROM contents c CROSS JOIN LATERAL unnest(c.tags) AS t
+		WHERE ` + models.PublicContentSQL + ` AND t LIKE $1
+		GROUP BY t ORDER BY COUNT(*) DESC, t LIMIT $2`
+	rows, err := s.db.Query(ctx, query, pattern, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	tags := []TagCount{}
+	for rows.Next() {
+		var tc TagCount
+		if err := rows.Scan(&tc.Tag, &tc.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, tc)
+	}
+	return tags, rows.Err()
+}
+
+// ListContentByTag lists public content carrying tag, newest first.
+func (s *TagService) ListContentByTag(ctx context.Context, tag string, limit, offset int) ([]models.ContentSummary, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+	tag, err := normalizeTag(tag)
+	if err != nil {
+		return nil, err
+	}
+	query := `SELECT c.id::text, COALESCE(c.title, ''), COALESCE(c.type, ''), COALESCE(c.owner_id, ''),
+			COALESCE(c.thumbnail_url, ''), COALESCE(c.duration, 0), COALESCE(c.tags, '{}'), c.created_at
+		FROM contents c
+		WHERE ` + models.PublicContentSQL + ` AND c.tags @> ARRAY[$1]::text[]
+		ORDER BY c.created_at DESC, c.id DESC
+		LIMIT $2 OFFSET $3`
+	rows, err := s.db.Query(ctx, query, tag, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list content by tag: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	items := []models.ContentSummary{}
+	for rows.Next() {
+		var item models.ContentSummary
+		if err := rows.Scan(&item.ID, &item.Title, &item.Type, &item.OwnerID, &item.ThumbnailURL,
+			&item.Duration, pq.Array(&item.Tags), &item.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan content: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
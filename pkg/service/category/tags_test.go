@@ -0,0 +1,126 @@
+package category
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rtcdance/streamgate/pkg/service/serviceerrors"
+	stg "github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// scanFunc adapts a function to storage.RowScanner.
+type scanFunc func(dest ...interface{}) error
+
+func (f scanFunc) Scan(dest ...interface{}) error { return f(dest...) }
+
+// tagsRow answers a tags query with a Postgres array literal.
+func tagsRow(literal string) *stg.CancelRow {
+	return stg.NewTestCancelRow(scanFunc(func(dest ...interface{}) error {
+		return dest[0].(sql.Scanner).Scan([]byte(literal))
+	}))
+}
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      []string
+		want    []string
+		wantErr bool
+	}{
+		{"empty", nil, []string{}, false},
+		{"normalized and deduplicated", []string{" Lo-Fi ", "live  music", "lo-fi", "LIVE MUSIC"}, []string{"lo-fi", "live music"}, false},
+		{"blank tag", []string{"ok", "  "}, nil, true},
+		{"comma", []string{"a,b"}, nil, true},
+		{"control character", []string{"a\x00b"}, nil, true},
+		{"too long", []string{strings.Repeat("x", maxTagLength+1)}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeTags(tt.in)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, serviceerrors.ErrInvalidRequest)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	many := make([]string, MaxTagsPerContent+1)
+	for i := range many {
+		many[i] = strings.Repeat("t", i+1)
+	}
+	_, err := NormalizeTags(many)
+	assert.ErrorIs(t, err, serviceerrors.ErrInvalidRequest)
+}
+
+func TestTagService_AddTagsRetriesConcurrentEdits(t *testing.T) {
+	reads := []string{"{a}", "{a,b}"}
+	var updates int
+	db := &mockDB{
+		queryRowFn: func(_ context.Context, _ string, _ ...interface{}) *stg.CancelRow {
+			row := tagsRow(reads[0])
+			reads = reads[1:]
+			return row
+		},
+		execFn: func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
+			updates++
+			// The first write loses the race against another edit.
+			return &mockResult{rowsAffected: int64(updates - 1)}, nil
+		},
+	}
+	svc := NewTagService(db, zap.NewNop())
+	var changed []string
+	svc.RegisterChangeHook(func(id string) { changed = append(changed, id) })
+
+	tags, err := svc.AddTags(context.Background(), "c1", []string{"C", " b "})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, tags)
+	assert.Equal(t, 2, updates)
+	assert.Equal(t, []string{"c1"}, changed)
+}
+
+func TestTagService_Errors(t *testing.T) {
+	svc := NewTagService(&mockDB{
+		queryRowFn: func(_ context.Context, _ string, _ ...interface{}) *stg.CancelRow {
+			return stg.NewErrorCancelRow(sql.ErrNoRows)
+		},
+	}, zap.NewNop())
+	_, err := svc.RemoveTag(context.Background(), "missing", "a")
+	assert.ErrorIs(t, err, serviceerrors.ErrNotFound)
+
+	_, err = svc.SetTags(context.Background(), "c1", []string{""})
+	assert.ErrorIs(t, err, serviceerrors.ErrInvalidRequest)
+
+	stale := NewTagService(&mockDB{
+		queryRowFn: func(_ context.Context, _ string, _ ...interface{}) *stg.CancelRow { return tagsRow("{a}") },
+		execFn: func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
+			return &mockResult{rowsAffected: 0}, nil
+		},
+	}, zap.NewNop())
+	_, err = stale.SetTags(context.Background(), "c1", []string{"b"})
+	assert.ErrorIs(t, err, errTagsChanged)
+
+	_, err = NewTagService(nil, zap.NewNop()).GetTags(context.Background(), "c1")
+	assert.Error(t, err)
+}
+
+func TestTagService_ListTagsEscapesPrefix(t *testing.T) {
+	var args []interface{}
+	svc := NewTagService(&mockDB{
+		queryFn: func(_ context.Context, _ string, a ...interface{}) (stg.Rows, error) {
+			args = a
+			return nil, errors.New("db error")
+		},
+	}, zap.NewNop())
+	_, err := svc.ListTags(context.Background(), " 50%_Off ", 10)
+	require.Error(t, err)
+	assert.Equal(t, []interface{}{`50\%\_off%`, 10}, args)
+}
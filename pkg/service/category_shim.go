@@ -4,8 +4,12 @@ import "github.com/rtcdance/streamgate/pkg/service/category"
 
 type (
 	CategoryService = category.CategoryService
+	TagService      = category.TagService
+	TagCount        = category.TagCount
 )
 
 var (
 	NewCategoryService = category.NewCategoryService
+	NewTagService      = category.NewTagService
+	NormalizeTags      = category.NormalizeTags
 )
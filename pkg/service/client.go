@@ -10,7 +10,9 @@ import (
 	"sync/atomic"
 	"time"
 
-	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/rtcdance/streamgate/pkg/middleware"
+
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/connectivity"
@@ -74,14 +76,13 @@ func (p *ClientPool) GetConnection(ctx context.Context, serviceName string) (*gr
 		return nil, fmt.Errorf("failed to get service address: %w", err)
 	}
 
-	opts := []grpc.DialOption{
+	opts := append([]grpc.DialOption{
 		grpc.WithKeepaliveParams(keepalive.ClientParameters{
 			Time:                30 * time.Second,
 			Timeout:             10 * time.Second,
 			PermitWithoutStream: true,
 		}),
-		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
-	}
+	}, middleware.GRPCDialOptions(p.logger, config.DefaultGRPCUnaryTimeout)...)
 	if p.tlsConfig != nil {
 		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(p.tlsConfig)))
 	} else {
@@ -0,0 +1,328 @@
+// Package collection manages creator-defined collections: named, ordered
+// sets of content items that frontends show as browse pages.
+package collection
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rtcdance/streamgate/pkg/models"
+	"github.com/rtcdance/streamgate/pkg/service/serviceerrors"
+	"github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+const (
+	// MaxItems caps the content items in one collection.
+	MaxItems = 1000
+	// MaxAddBatch caps the items added by one AddItems call.
+	MaxAddBatch          = 100
+	maxNameLength        = 255
+	maxDescriptionLength = 4096
+)
+
+var (
+	ErrCollectionNotFound = fmt.Errorf("collection not found: %w", serviceerrors.ErrNotFound)
+	ErrNotCollectionOwner = errors.New("collection belongs to another wallet")
+	ErrCollectionFull     = fmt.Errorf("collection holds at most %d items: %w", MaxItems, serviceerrors.ErrInvalidRequest)
+)
+
+// Collection is a creator's named set of content items.
+type Collection struct {
+	ID          string    `json:"id"`
+	OwnerID     string    `json:"owner_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	ItemCount   int       `json:"item_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Item is a content item in a collection, in collection order.
+type Item struct {
+	models.ContentSummary
+	Position int       `json:"position"`
+	AddedAt  time.Time `json:"added_at"`
+}
+
+// Service stores collections in Postgres. Listing a collection only shows
+// items that are public, so a collection never exposes content its viewer
+// could not browse to directly.
+type Service struct {
+	db     storage.DB
+	logger *zap.Logger
+}
+
+func NewService(db storage.DB, logger *zap.Logger) *Service {
+	return &Service{db: db, logger: logger}
+}
+
+func validateFields(name, description string) error {
+	switch {
+	case strings.TrimSpace(name) == "":
+		return fmt.Errorf("collection name is required: %w", serviceerrors.ErrInvalidRequest)
+	case utf8.RuneCountInString(name) > maxNameLength:
+		return fmt.Errorf("collection name is longer than %d characters: %w", maxNameLength, serviceerrors.ErrInvalidRequest)
+	case utf8.RuneCountInString(description) > maxDescriptionLength:
+		return fmt.Errorf("collection description is longer than %d characters: %w", maxDescriptionLength, serviceerrors.ErrInvalidRequest)
+	}
+	return nil
+}
+
+// Create creates an empty collection owned by ownerID.
+func (s *Service) Create(ctx context.Context, ownerID, name, description string) (*Collection, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+	name = strings.TrimSpace(name)
+	if ownerID == "" {
+		return nil, fmt.Errorf("collection owner is required: %w", serviceerrors.ErrInvalidRequest)
+	}
+	if err := validateFields(name, description); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	col := &Collection{
+		ID:          uuid.New().String(),
+		OwnerID:     strings.ToLower(ownerID),
+		Name:        name,
+		Description: description,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	_, err := s.db.Exec(ctx, `INSERT INTO collections (id, owner_id, name, description, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`, col.ID, col.OwnerID, col.Name, col.Description, col.CreatedAt, col.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create collection: %w", err)
+	}
+	return col, nil
+}
+
+const collectionColumns = `c.id::text, c.owner_id, c.name, COALESCE(c.description, ''),
+	(SELECT COUNT(*) FROM collection_items i WHERE i.collection_id = c.id), c.created_at, c.updated_at`
+
+func scanCollection(row storage.RowScanner) (*Collection, error) {
+	var col Collection
+	if err := row.Scan(&col.ID, &col.OwnerID, &col.Name, &col.Description, &col.ItemCount, &col.CreatedAt, &col.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &col, nil
+}
+
+// Get returns the collection id.
+func (s *Service) Get(ctx context.Context, id string) (*Collection, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrCollectionNotFound
+	}
+	col, err := scanCollection(s.db.QueryRow(ctx, `SELECT `+collectionColumns+` FROM collections c WHERE c.id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrCollectionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query collection: %w", err)
+	}
+	return col, nil
+}
+
+// ListByOwner lists ownerID's collections, most recently updated first.
+func (s *Service) ListByOwner(ctx context.Context, ownerID string, limit, offset int) ([]*Collection, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+	rows, err := s.db.Query(ctx, `SELECT `+collectionColumns+` FROM collections c
+		WHERE c.owner_id = $1 ORDER BY c.updated_at DESC, c.id LIMIT $2 OFFSET $3`,
+		strings.ToLower(ownerID), limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	cols := []*Collection{}
+	for rows.Next() {
+		col, err := scanCollection(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan collection: %w", err)
+		}
+		cols = append(cols, col)
+	}
+	return cols, rows.Err()
+}
+
+// authorize returns ErrCollectionNotFound or ErrNotCollectionOwner unless
+// wallet owns the collection id.
+func (s *Service) authorize(ctx context.Context, id, wallet string) error {
+	if s.db == nil {
+		return fmt.Errorf("database not available")
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrCollectionNotFound
+	}
+	var owner string
+	err := s.db.QueryRow(ctx, `SELECT owner_id FROM collections WHERE id = $1`, id).Scan(&owner)
+	if err == sql.ErrNoRows {
+		return ErrCollectionNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query collection: %w", err)
+	}
+	if wallet == "" || !strings.EqualFold(owner, wallet) {
+		return ErrNotCollectionOwner
+	}
+	return nil
+}
+
+// Update changes the name and description of wallet's collection id. Nil
+// fields are left unchanged.
+func (s *Service) Update(ctx context.Context, id, wallet string, name, description *string) (*Collection, error) {
+	if err := s.authorize(ctx, id, wallet); err != nil {
+		return nil, err
+	}
+	col, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if name != nil {
+		col.Name = strings.TrimSpace(*name)
+	}
+	if description != nil {
+		col.Description = *description
+	}
+	if err := validateFields(col.Name, col.Description); err != nil {
+		return nil, err
+	}
+	col.UpdatedAt = time.Now().UTC()
+	_, err = s.db.Exec(ctx, `UPDATE collections SET name = $2, description = $3, updated_at = $4 WHERE id = $1`,
+		id, col.Name, col.Description, col.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update collection: %w", err)
+	}
+	return col, nil
+}
+
+// Delete deletes wallet's collection id and its item list. The content
+// itself is untouched.
+func (s *Service) Delete(ctx context.Context, id, wallet string) error {
+	if err := s.authorize(ctx, id, wallet); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(ctx, `DELETE FROM collections WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete collection: %w", err)
+	}
+	return nil
+}
+
+// AddItems appends content to wallet's collection id in the given order
+// and returns how many were added. Items already in the collection, and
+// content that is neither public nor the wallet's own, are skipped.
+func (s *Service) AddItems(ctx context.Context, id, wallet string, contentIDs []string) (int, error) {
+	if len(contentIDs) == 0 || len(contentIDs) > MaxAddBatch {
+		return 0, fmt.Errorf("between 1 and %d content ids are required: %w", MaxAddBatch, serviceerrors.ErrInvalidRequest)
+	}
+	ids := make([]string, 0, len(contentIDs))
+	seen := make(map[string]bool, len(contentIDs))
+	for _, raw := range contentIDs {
+		parsed, err := uuid.Parse(raw)
+		if err != nil {
+			return 0, fmt.Errorf("invalid content id %q: %w", raw, serviceerrors.ErrInvalidRequest)
+		}
+		if cid := parsed.String(); !seen[cid] {
+			seen[cid] = true
+			ids = append(ids, cid)
+		}
+	}
+	if err := s.authorize(ctx, id, wallet); err != nil {
+		return 0, err
+	}
+
+	var count int
+	if err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM collection_items WHERE collection_id = $1`, id).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count collection items: %w", err)
+	}
+	if count+len(ids) > MaxItems {
+		return 0, ErrCollectionFull
+	}
+
+	now := time.Now().UTC()
+	result, err := s.db.Exec(ctx, `INSERT INTO collection_items (collection_id, content_id, position, added_at)
+		SELECT $1, c.id, COALESCE((SELECT MAX(position) FROM collection_items WHERE collection_id = $1), 0) + ids.ord, $3
+		FROM unnest($2::uuid[]) WITH ORDINALITY AS ids(id, ord)
+		JOIN contents c ON c.id = ids.id
+		WHERE (`+models.PublicContentSQL+`) OR LOWER(c.owner_id) = LOWER($4)
+		ORDER BY ids.ord
+		ON CONFLICT (collection_id, content_id) DO NOTHING`, id, pq.Array(ids), now, wallet)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add collection items: %w", err)
+	}
+	added, _ := result.RowsAffected()
+	if added > 0 {
+		s.touch(ctx, id, now)
+	}
+	return int(added), nil
+}
+
+// RemoveItem removes contentID from wallet's collection id.
+func (s *Service) RemoveItem(ctx context.Context, id, wallet, contentID string) error {
+	if err := s.authorize(ctx, id, wallet); err != nil {
+		return err
+	}
+	if _, err := uuid.Parse(contentID); err != nil {
+		return fmt.Errorf("content %s is not in the collection: %w", contentID, serviceerrors.ErrNotFound)
+	}
+	result, err := s.db.Exec(ctx, `DELETE FROM collection_items WHERE collection_id = $1 AND content_id = $2`, id, contentID)
+	if err != nil {
+		return fmt.Errorf("failed to remove collection item: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("content %s is not in the collection: %w", contentID, serviceerrors.ErrNotFound)
+	}
+	s.touch(ctx, id, time.Now().UTC())
+	return nil
+}
+
+// touch bumps the collection's updated_at after its items change.
+func (s *Service) touch(ctx context.Context, id string, at time.Time) {
+	if _, err := s.db.Exec(ctx, `UPDATE collections SET updated_at = $2 WHERE id = $1`, id, at); err != nil {
+		s.logger.Warn("Failed to update collection timestamp", zap.String("collection_id", id), zap.Error(err))
+	}
+}
+
+// ListItems lists the public items of collection id in collection order.
+func (s *Service) ListItems(ctx context.Context, id string, limit, offset int) ([]Item, error) {
+	if _, err := s.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	query := `SELECT c.id::text, COALESCE(c.title, ''), COALESCE(c.type, ''), COALESCE(c.owner_id, ''),
+			COALESCE(c.thumbnail_url, ''), COALESCE(c.duration, 0), COALESCE(c.tags, '{}'), c.created_at,
+			i.position, i.added_at
+		FROM collection_items i JOIN contents c ON c.id = i.content_id
+		WHERE i.collection_id = $1 AND ` + models.PublicContentSQL + `
+		ORDER BY i.position, i.added_at
+		LIMIT $2 OFFSET $3`
+	rows, err := s.db.Query(ctx, query, id, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collection items: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	items := []Item{}
+	for rows.Next() {
+		var item Item
+		if err := rows.Scan(&item.ID, &item.Title, &item.Type, &item.OwnerID, &item.ThumbnailURL, &item.Duration,
+			pq.Array(&item.Tags), &item.CreatedAt, &item.Position, &item.AddedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan collection item: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,176 @@
+package collection
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rtcdance/streamgate/pkg/service/serviceerrors"
+	stg "github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const (
+	collectionID = "7f1d9a8e-2b7c-4c39-9d1e-2f3a4b5c6d7e"
+	contentA     = "11111111-1111-4111-8111-111111111111"
+	contentB     = "22222222-2222-4222-8222-222222222222"
+)
+
+type mockDB struct {
+	queryRowFn func(ctx context.Context, query string, args ...interface{}) *stg.CancelRow
+	execFn     func(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (m *mockDB) Query(context.Context, string, ...interface{}) (stg.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+func (m *mockDB) QueryRow(ctx context.Context, query string, args ...interface{}) *stg.CancelRow {
+	if m.queryRowFn != nil {
+		return m.queryRowFn(ctx, query, args...)
+	}
+	return stg.NewErrorCancelRow(errors.New("not implemented"))
+}
+func (m *mockDB) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if m.execFn != nil {
+		return m.execFn(ctx, query, args...)
+	}
+	return nil, errors.New("not implemented")
+}
+func (m *mockDB) Begin(context.Context) (*sql.Tx, error) { return nil, errors.New("not implemented") }
+func (m *mockDB) InTransaction(context.Context, func(tx *sql.Tx) error) error {
+	return errors.New("not implemented")
+}
+func (m *mockDB) Ping(context.Context) error { return nil }
+func (m *mockDB) Close() error               { return nil }
+
+type mockResult struct{ rowsAffected int64 }
+
+func (m mockResult) LastInsertId() (int64, error) { return 0, nil }
+func (m mockResult) RowsAffected() (int64, error) { return m.rowsAffected, nil }
+
+// valueRow answers a single-column query.
+type valueRow struct{ v interface{} }
+
+func (r valueRow) Scan(dest ...interface{}) error {
+	switch p := dest[0].(type) {
+	case *string:
+		*p = r.v.(string)
+	case *int:
+		*p = r.v.(int)
+	default:
+		return errors.New("unexpected scan destination")
+	}
+	return nil
+}
+
+// ownedDB reports owner as the owner of collectionID and count items in it,
+// and records the statements executed.
+func ownedDB(owner string, count int, execs *[]string) *mockDB {
+	return &mockDB{
+		queryRowFn: func(_ context.Context, query string, _ ...interface{}) *stg.CancelRow {
+			if strings.Contains(query, "COUNT(*)") {
+				return stg.NewTestCancelRow(valueRow{count})
+			}
+			return stg.NewTestCancelRow(valueRow{owner})
+		},
+		execFn: func(_ context.Context, query string, _ ...interface{}) (sql.Result, error) {
+			*execs = append(*execs, strings.Fields(query)[0])
+			return mockResult{rowsAffected: 2}, nil
+		},
+	}
+}
+
+func TestService_Create(t *testing.T) {
+	var args []interface{}
+	svc := NewService(&mockDB{execFn: func(_ context.Context, _ string, a ...interface{}) (sql.Result, error) {
+		args = a
+		return mockResult{rowsAffected: 1}, nil
+	}}, zap.NewNop())
+
+	col, err := svc.Create(context.Background(), "0xABC", "  Best of 2026 ", "")
+	require.NoError(t, err)
+	assert.Equal(t, "0xabc", col.OwnerID)
+	assert.Equal(t, "Best of 2026", col.Name)
+	assert.Equal(t, []interface{}{col.ID, "0xabc", "Best of 2026", ""}, args[:4])
+
+	for _, name := range []string{"", "   ", strings.Repeat("n", maxNameLength+1)} {
+		_, err = svc.Create(context.Background(), "0xabc", name, "")
+		assert.ErrorIs(t, err, serviceerrors.ErrInvalidRequest)
+	}
+	_, err = svc.Create(context.Background(), "", "name", "")
+	assert.ErrorIs(t, err, serviceerrors.ErrInvalidRequest)
+}
+
+func TestService_Ownership(t *testing.T) {
+	var execs []string
+	svc := NewService(ownedDB("0xabc", 0, &execs), zap.NewNop())
+	ctx := context.Background()
+
+	assert.ErrorIs(t, svc.Delete(ctx, collectionID, "0xdef"), ErrNotCollectionOwner)
+	assert.ErrorIs(t, svc.Delete(ctx, collectionID, ""), ErrNotCollectionOwner)
+	assert.ErrorIs(t, svc.Delete(ctx, "not-a-uuid", "0xabc"), serviceerrors.ErrNotFound)
+	_, err := svc.AddItems(ctx, collectionID, "0xdef", []string{contentA})
+	assert.ErrorIs(t, err, ErrNotCollectionOwner)
+	assert.Empty(t, execs)
+
+	require.NoError(t, svc.Delete(ctx, collectionID, "0xABC"))
+	assert.Equal(t, []string{"DELETE"}, execs)
+
+	missing := NewService(&mockDB{queryRowFn: func(_ context.Context, _ string, _ ...interface{}) *stg.CancelRow {
+		return stg.NewErrorCancelRow(sql.ErrNoRows)
+	}}, zap.NewNop())
+	assert.ErrorIs(t, missing.RemoveItem(ctx, collectionID, "0xabc", contentA), ErrCollectionNotFound)
+	_, err = missing.Get(ctx, collectionID)
+	assert.ErrorIs(t, err, serviceerrors.ErrNotFound)
+}
+
+func TestService_AddItems(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		count   int
+		ids     []string
+		want    int
+		wantErr error
+	}{
+		{"added and timestamp bumped", 3, []string{contentA, strings.ToUpper(contentB), contentA}, 2, nil},
+		{"no ids", 0, nil, 0, serviceerrors.ErrInvalidRequest},
+		{"invalid id", 0, []string{"c1"}, 0, serviceerrors.ErrInvalidRequest},
+		{"collection full", MaxItems - 1, []string{contentA, contentB}, 0, ErrCollectionFull},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var execs []string
+			svc := NewService(ownedDB("0xabc", tt.count, &execs), zap.NewNop())
+			n, err := svc.AddItems(ctx, collectionID, "0xabc", tt.ids)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, execs)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, n)
+			assert.Equal(t, []string{"INSERT", "UPDATE"}, execs)
+		})
+	}
+}
+
+func TestService_RemoveItem(t *testing.T) {
+	ctx := context.Background()
+	var execs []string
+	db := ownedDB("0xabc", 0, &execs)
+	svc := NewService(db, zap.NewNop())
+	require.NoError(t, svc.RemoveItem(ctx, collectionID, "0xabc", contentA))
+	assert.Equal(t, []string{"DELETE", "UPDATE"}, execs)
+
+	db.execFn = func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
+		return mockResult{}, nil
+	}
+	assert.ErrorIs(t, svc.RemoveItem(ctx, collectionID, "0xabc", contentB), serviceerrors.ErrNotFound)
+	assert.ErrorIs(t, svc.RemoveItem(ctx, collectionID, "0xabc", "c1"), serviceerrors.ErrNotFound)
+}
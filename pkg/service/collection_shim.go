@@ -0,0 +1,17 @@
+package service
+
+import "github.com/rtcdance/streamgate/pkg/service/collection"
+
+type (
+	CollectionService = collection.Service
+	Collection        = collection.Collection
+	CollectionItem    = collection.Item
+)
+
+var (
+	NewCollectionService = collection.NewService
+
+	ErrCollectionNotFound = collection.ErrCollectionNotFound
+	ErrNotCollectionOwner = collection.ErrNotCollectionOwner
+	ErrCollectionFull     = collection.ErrCollectionFull
+)
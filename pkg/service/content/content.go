@@ -469,6 +469,13 @@ func (s *ContentService) DeleteContent(ctx context.Context, id, ownerID string)
 }
 
 func (s *ContentService) ListContentsWithCount(ctx context.Context, ownerID string, limit, offset int) ([]*Content, int, error) {
+	return s.ListContentsByStatusWithCount(ctx, ownerID, "", limit, offset)
+}
+
+// ListContentsByStatusWithCount lists an owner's contents, newest first,
+// together with the total number of matching rows. An empty status matches
+// every status.
+func (s *ContentService) ListContentsByStatusWithCount(ctx context.Context, ownerID, status string, limit, offset int) ([]*Content, int, error) {
 	if s.db == nil {
 		return nil, 0, fmt.Errorf("database not available")
 	}
@@ -477,12 +484,12 @@ func (s *ContentService) ListContentsWithCount(ctx context.Context, ownerID stri
 		       id, title, description, type, url, thumbnail_url,
 		       duration, size, status, owner_id, created_at, updated_at, metadata
 		FROM contents
-		WHERE owner_id = $1
+		WHERE owner_id = $1 AND ($2 = '' OR status = $2)
 		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3
+		LIMIT $3 OFFSET $4
 	`
 
-	rows, err := s.db.Query(ctx, query, ownerID, limit, offset)
+	rows, err := s.db.Query(ctx, query, ownerID, status, limit, offset)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to query contents: %w", err)
 	}
@@ -486,6 +486,23 @@ func TestContentService_ListContentsWithCount(t *testing.T) {
 	})
 }
 
+func TestContentService_ListContentsByStatusWithCount(t *testing.T) {
+	var gotArgs []interface{}
+	db := &mockDB{
+		queryFn: func(_ context.Context, _ string, args ...interface{}) (stg.Rows, error) {
+			gotArgs = args
+			return nil, errors.New("db error")
+		},
+	}
+	svc := NewContentService(db, newMockObjStore(), newMockCache())
+
+	_, _, _ = svc.ListContentsByStatusWithCount(context.Background(), "owner1", "ready", 10, 20)
+	assert.Equal(t, []interface{}{"owner1", "ready", 10, 20}, gotArgs)
+
+	_, _, _ = svc.ListContentsWithCount(context.Background(), "owner1", 5, 0)
+	assert.Equal(t, []interface{}{"owner1", "", 5, 0}, gotArgs, "unfiltered listing passes an empty status")
+}
+
 func TestContentService_CountContents(t *testing.T) {
 	t.Run("nil db", func(t *testing.T) {
 		svc := NewContentService(nil, newMockObjStore(), newMockCache())
@@ -0,0 +1,476 @@
+// Package lifecycle applies storage tiering and retention policies to
+// stored content: originals move to cold storage, renditions of unwatched
+// content are purged, and chunks left behind by failed uploads are removed.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/service/serviceerrors"
+	"github.com/rtcdance/streamgate/pkg/storage"
+
+	"go.uber.org/zap"
+)
+
+// Policy names, reported on each action.
+const (
+	PolicyColdOriginals   = "cold_originals"
+	PolicyPurgeRenditions = "purge_renditions"
+	PolicyOrphanedChunks  = "orphaned_chunks"
+)
+
+// TierCold is recorded in contents.metadata under storage_tier once the
+// original has moved to the cold bucket.
+const TierCold = "cold"
+
+const (
+	renditionPrefix = "streams/"
+	chunkPrefix     = "chunks/"
+)
+
+// ObjectStore is the object storage policies act on.
+type ObjectStore interface {
+	DownloadStream(ctx context.Context, bucket, objectName string) (io.ReadCloser, error)
+	UploadStream(ctx context.Context, bucket, objectName string, reader io.Reader, size int64) error
+	Delete(ctx context.Context, bucket, objectName string) error
+	ListObjects(ctx context.Context, bucket, prefix string) ([]string, error)
+}
+
+// batchDeleter is implemented by stores that delete many objects at once.
+type batchDeleter interface {
+	DeleteObjects(ctx context.Context, bucket string, objectNames []string) error
+}
+
+// Config defines the policies. A zero age disables its policy.
+type Config struct {
+	// Bucket holds originals, renditions and upload chunks.
+	Bucket string
+	// ColdBucket receives originals older than ColdAfter.
+	ColdBucket string
+	ColdAfter  time.Duration
+	// PurgeRenditionsAfter is how long content must go unwatched before its
+	// renditions are deleted. The original is kept, so they can be rebuilt
+	// by transcoding again.
+	PurgeRenditionsAfter time.Duration
+	// KeepRenditions names rendition directories, e.g. "480p", that are
+	// never purged.
+	KeepRenditions []string
+	// OrphanChunkAge is how long an upload must go untouched before its
+	// chunks are treated as orphaned.
+	OrphanChunkAge time.Duration
+	// BatchSize caps the content items each policy considers per run.
+	BatchSize int
+}
+
+// Action is one change a run made, or would make in a dry run.
+type Action struct {
+	Policy    string   `json:"policy"`
+	ContentID string   `json:"content_id,omitempty"`
+	UploadID  string   `json:"upload_id,omitempty"`
+	Bucket    string   `json:"bucket"`
+	Keys      []string `json:"keys"`
+	// Bytes is the size of the original moved; it is not known for
+	// renditions and chunks.
+	Bytes int64  `json:"bytes,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Report describes one run.
+type Report struct {
+	DryRun     bool      `json:"dry_run"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Actions    []Action  `json:"actions"`
+	Errors     int       `json:"errors"`
+}
+
+func (r *Report) add(a Action) {
+	if a.Error != "" {
+		r.Errors++
+	}
+	r.Actions = append(r.Actions, a)
+}
+
+// Manager applies the lifecycle policies, on demand or on a schedule.
+// Every change is guarded so that managers on several replicas can run at
+// once: an original is only removed from the hot bucket by the run that
+// recorded its move.
+type Manager struct {
+	db     storage.DB
+	store  ObjectStore
+	cfg    Config
+	logger *zap.Logger
+
+	running sync.Mutex
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewManager returns a manager applying cfg to the content in db and store.
+func NewManager(db storage.DB, store ObjectStore, cfg Config, logger *zap.Logger) *Manager {
+	if cfg.Bucket == "" {
+		cfg.Bucket = "streamgate"
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	return &Manager{db: db, store: store, cfg: cfg, logger: logger, stop: make(chan struct{})}
+}
+
+// Run applies every enabled policy and reports what it did. With dryRun it
+// only reports what it would do. Only one run per manager happens at a
+// time.
+func (m *Manager) Run(ctx context.Context, dryRun bool) (*Report, error) {
+	if m.db == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+	if !m.running.TryLock() {
+		return nil, fmt.Errorf("lifecycle run already in progress: %w", serviceerrors.ErrAlreadyExists)
+	}
+	defer m.running.Unlock()
+
+	report := &Report{DryRun: dryRun, StartedAt: time.Now().UTC(), Actions: []Action{}}
+	now := time.Now()
+	var errs []error
+	if m.cfg.ColdAfter > 0 && m.cfg.ColdBucket != "" {
+		errs = append(errs, m.coldOriginals(ctx, now.Add(-m.cfg.ColdAfter), dryRun, report))
+	}
+	if m.cfg.PurgeRenditionsAfter > 0 {
+		errs = append(errs, m.purgeRenditions(ctx, now.Add(-m.cfg.PurgeRenditionsAfter), dryRun, report))
+	}
+	if m.cfg.OrphanChunkAge > 0 {
+		errs = append(errs, m.orphanedChunks(ctx, now.Add(-m.cfg.OrphanChunkAge), dryRun, report))
+	}
+	report.FinishedAt = time.Now().UTC()
+	if err := errors.Join(errs...); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// Start runs the policies every interval until Close. With dryRun the
+// scheduled runs only log what they would do.
+func (m *Manager) Start(interval time.Duration, dryRun bool) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-ticker.C:
+				m.scheduledRun(dryRun)
+			}
+		}
+	}()
+}
+
+func (m *Manager) scheduledRun(dryRun bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-m.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	report, err := m.Run(ctx, dryRun)
+	if errors.Is(err, serviceerrors.ErrAlreadyExists) {
+		return
+	}
+	if report != nil {
+		m.logger.Info("Lifecycle run finished",
+			zap.Bool("dry_run", dryRun),
+			zap.Int("actions", len(report.Actions)),
+			zap.Int("errors", report.Errors))
+	}
+	if err != nil {
+		m.logger.Warn("Lifecycle run failed", zap.Error(err))
+	}
+}
+
+// Close stops scheduled runs, cancelling one in progress.
+func (m *Manager) Close() {
+	select {
+	case <-m.stop:
+	default:
+		close(m.stop)
+	}
+	m.wg.Wait()
+}
+
+// coldOriginals moves originals created before cutoff to the cold bucket.
+// The content URL keeps naming the hot location; storage_tier and
+// cold_bucket in its metadata record where the original now lives.
+func (m *Manager) coldOriginals(ctx context.Context, cutoff time.Time, dryRun bool, report *Report) error {
+	prefix := "/" + m.cfg.Bucket + "/"
+	rows, err := m.db.Query(ctx, `
+		SELECT id, url, COALESCE(size, 0)
+		FROM contents
+		WHERE created_at < $1 AND url LIKE $2
+		  AND COALESCE(metadata->>'storage_tier', '') <> $3
+		ORDER BY created_at ASC
+		LIMIT $4
+	`, cutoff, prefix+"%", TierCold, m.cfg.BatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to query originals: %w", err)
+	}
+	type original struct {
+		id, key string
+		size    int64
+	}
+	var originals []original
+	for rows.Next() {
+		var o original
+		var url string
+		if err := rows.Scan(&o.id, &url, &o.size); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("failed to scan original: %w", err)
+		}
+		o.key = strings.TrimPrefix(url, prefix)
+		originals = append(originals, o)
+	}
+	err = rows.Err()
+	_ = rows.Close()
+	if err != nil {
+		return fmt.Errorf("failed to query originals: %w", err)
+	}
+
+	for _, o := range originals {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		action := Action{Policy: PolicyColdOriginals, ContentID: o.id, Bucket: m.cfg.ColdBucket, Keys: []string{o.key}, Bytes: o.size}
+		if !dryRun {
+			if err := m.moveToCold(ctx, o.id, o.key, o.size); err != nil {
+				action.Error = err.Error()
+			}
+		}
+		report.add(action)
+	}
+	return nil
+}
+
+// moveToCold copies the original to the cold bucket, records the move and
+// only then removes the hot copy, so a failure at any step leaves the
+// original readable where its metadata says it is.
+func (m *Manager) moveToCold(ctx context.Context, contentID, key string, size int64) error {
+	rc, err := m.store.DownloadStream(ctx, m.cfg.Bucket, key)
+	if err != nil {
+		return fmt.Errorf("read original: %w", err)
+	}
+	err = m.store.UploadStream(ctx, m.cfg.ColdBucket, key, rc, size)
+	_ = rc.Close()
+	if err != nil {
+		return fmt.Errorf("copy to cold bucket: %w", err)
+	}
+
+	result, err := m.db.Exec(context.WithoutCancel(ctx), `
+		UPDATE contents
+		SET metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object('storage_tier', $2::text, 'cold_bucket', $3::text),
+		    updated_at = $4
+		WHERE id = $1 AND COALESCE(metadata->>'storage_tier', '') <> $2
+	`, contentID, TierCold, m.cfg.ColdBucket, time.Now())
+	if err != nil {
+		return fmt.Errorf("record move: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		// Another run moved it first and owns removing the hot copy.
+		return nil
+	}
+	if err := m.store.Delete(ctx, m.cfg.Bucket, key); err != nil {
+		return fmt.Errorf("remove hot copy: %w", err)
+	}
+	return nil
+}
+
+// purgeRenditions deletes the renditions of content created before cutoff
+// and not played since.
+func (m *Manager) purgeRenditions(ctx context.Context, cutoff time.Time, dryRun bool, report *Report) error {
+	rows, err := m.db.Query(ctx, `
+		SELECT c.id
+		FROM contents c
+		WHERE c.created_at < $1
+		  AND c.status IN ('ready', 'published')
+		  AND c.metadata->>'renditions_purged_at' IS NULL
+		  AND NOT EXISTS (
+		      SELECT 1 FROM playback_events p
+		      WHERE p.content_id = c.id AND p.created_at >= $1
+		  )
+		ORDER BY c.created_at ASC
+		LIMIT $2
+	`, cutoff, m.cfg.BatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to query unwatched content: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("failed to scan unwatched content: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	err = rows.Err()
+	_ = rows.Close()
+	if err != nil {
+		return fmt.Errorf("failed to query unwatched content: %w", err)
+	}
+
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		keys, err := m.store.ListObjects(ctx, m.cfg.Bucket, renditionPrefix+id+"/")
+		action := Action{Policy: PolicyPurgeRenditions, ContentID: id, Bucket: m.cfg.Bucket}
+		if err != nil {
+			action.Error = fmt.Sprintf("list renditions: %v", err)
+			report.add(action)
+			continue
+		}
+		action.Keys = m.purgeable(id, keys)
+		if dryRun {
+			if len(action.Keys) > 0 {
+				report.add(action)
+			}
+			continue
+		}
+		if len(action.Keys) > 0 {
+			if err := m.deleteAll(ctx, action.Keys); err != nil {
+				action.Error = err.Error()
+				report.add(action)
+				continue
+			}
+		}
+		// Content with nothing left to purge is marked too, so it is not
+		// considered again on every run.
+		if err := m.markPurged(ctx, id); err != nil {
+			action.Error = err.Error()
+		}
+		if len(action.Keys) > 0 || action.Error != "" {
+			report.add(action)
+		}
+	}
+	return nil
+}
+
+func (m *Manager) markPurged(ctx context.Context, contentID string) error {
+	_, err := m.db.Exec(context.WithoutCancel(ctx), `
+		UPDATE contents
+		SET metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object('renditions_purged_at', $2::text),
+		    updated_at = $3
+		WHERE id = $1
+	`, contentID, time.Now().UTC().Format(time.RFC3339), time.Now())
+	if err != nil {
+		return fmt.Errorf("record purge: %w", err)
+	}
+	return nil
+}
+
+// purgeable filters out the renditions in KeepRenditions. Files directly
+// under the content's directory, such as the master playlist, go with the
+// renditions they list.
+func (m *Manager) purgeable(contentID string, keys []string) []string {
+	dir := renditionPrefix + contentID + "/"
+	out := make([]string, 0, len(keys))
+	for _, key := range keys {
+		rendition, _, _ := strings.Cut(strings.TrimPrefix(key, dir), "/")
+		if m.keep(rendition) {
+			continue
+		}
+		out = append(out, key)
+	}
+	return out
+}
+
+func (m *Manager) keep(rendition string) bool {
+	for _, k := range m.cfg.KeepRenditions {
+		if k == rendition {
+			return true
+		}
+	}
+	return false
+}
+
+// orphanedChunks deletes the chunks of uploads that no longer exist or
+// have not been touched since cutoff. Completed uploads merge and delete
+// their chunks, so whatever is left of an old upload is abandoned.
+func (m *Manager) orphanedChunks(ctx context.Context, cutoff time.Time, dryRun bool, report *Report) error {
+	keys, err := m.store.ListObjects(ctx, m.cfg.Bucket, chunkPrefix)
+	if err != nil {
+		return fmt.Errorf("failed to list upload chunks: %w", err)
+	}
+	byUpload := make(map[string][]string)
+	var order []string
+	for _, key := range keys {
+		uploadID, _, ok := strings.Cut(strings.TrimPrefix(key, chunkPrefix), "/")
+		if !ok || uploadID == "" {
+			continue
+		}
+		if _, seen := byUpload[uploadID]; !seen {
+			order = append(order, uploadID)
+		}
+		byUpload[uploadID] = append(byUpload[uploadID], key)
+	}
+
+	for i, uploadID := range order {
+		if i >= m.cfg.BatchSize {
+			break
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		orphaned, err := m.uploadAbandoned(ctx, uploadID, cutoff)
+		if err != nil {
+			report.add(Action{Policy: PolicyOrphanedChunks, UploadID: uploadID, Bucket: m.cfg.Bucket, Error: err.Error()})
+			continue
+		}
+		if !orphaned {
+			continue
+		}
+		action := Action{Policy: PolicyOrphanedChunks, UploadID: uploadID, Bucket: m.cfg.Bucket, Keys: byUpload[uploadID]}
+		if !dryRun {
+			if err := m.deleteAll(ctx, action.Keys); err != nil {
+				action.Error = err.Error()
+			}
+		}
+		report.add(action)
+	}
+	return nil
+}
+
+func (m *Manager) uploadAbandoned(ctx context.Context, uploadID string, cutoff time.Time) (bool, error) {
+	var exists bool
+	err := m.db.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM uploads WHERE id::text = $1 AND updated_at >= $2)`,
+		uploadID, cutoff).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("check upload: %w", err)
+	}
+	return !exists, nil
+}
+
+func (m *Manager) deleteAll(ctx context.Context, keys []string) error {
+	if bd, ok := m.store.(batchDeleter); ok {
+		if err := bd.DeleteObjects(ctx, m.cfg.Bucket, keys); err != nil {
+			return fmt.Errorf("delete objects: %w", err)
+		}
+		return nil
+	}
+	for _, key := range keys {
+		if err := m.store.Delete(ctx, m.cfg.Bucket, key); err != nil {
+			return fmt.Errorf("delete %s: %w", key, err)
+		}
+	}
+	return nil
+}
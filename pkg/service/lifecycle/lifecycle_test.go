@@ -0,0 +1,261 @@
+package lifecycle
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/service/serviceerrors"
+	stg "github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// policyDB answers the manager's queries from fixed candidate lists and
+// records the content it marks.
+type policyDB struct {
+	mu           sync.Mutex
+	originals    [][]interface{} // id, url, size
+	unwatched    []string
+	freshUploads map[string]bool
+	alreadyCold  map[string]bool
+	marked       map[string][]string // content id -> metadata keys set
+}
+
+func newPolicyDB() *policyDB {
+	return &policyDB{freshUploads: map[string]bool{}, alreadyCold: map[string]bool{}, marked: map[string][]string{}}
+}
+
+func (d *policyDB) Query(_ context.Context, query string, _ ...interface{}) (stg.Rows, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if strings.Contains(query, "playback_events") {
+		rows := &memRows{}
+		for _, id := range d.unwatched {
+			rows.rows = append(rows.rows, []interface{}{id})
+		}
+		return rows, nil
+	}
+	return &memRows{rows: d.originals}, nil
+}
+
+func (d *policyDB) QueryRow(_ context.Context, _ string, args ...interface{}) *stg.CancelRow {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return stg.NewTestCancelRow(&memRow{vals: []interface{}{d.freshUploads[args[0].(string)]}})
+}
+
+func (d *policyDB) Exec(_ context.Context, query string, args ...interface{}) (sql.Result, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	id := args[0].(string)
+	switch {
+	case strings.Contains(query, "storage_tier"):
+		if d.alreadyCold[id] {
+			return result(0), nil
+		}
+		d.marked[id] = append(d.marked[id], "storage_tier")
+	case strings.Contains(query, "renditions_purged_at"):
+		d.marked[id] = append(d.marked[id], "renditions_purged_at")
+	}
+	return result(1), nil
+}
+
+func (d *policyDB) Begin(context.Context) (*sql.Tx, error) { return nil, errors.New("not implemented") }
+func (d *policyDB) InTransaction(context.Context, func(tx *sql.Tx) error) error {
+	return errors.New("not implemented")
+}
+func (d *policyDB) Ping(context.Context) error { return nil }
+func (d *policyDB) Close() error               { return nil }
+
+type result int64
+
+func (r result) LastInsertId() (int64, error) { return 0, nil }
+func (r result) RowsAffected() (int64, error) { return int64(r), nil }
+
+type memRow struct{ vals []interface{} }
+
+func (r *memRow) Scan(dest ...interface{}) error {
+	for i, d := range dest {
+		switch p := d.(type) {
+		case *string:
+			*p = r.vals[i].(string)
+		case *int64:
+			*p = r.vals[i].(int64)
+		case *bool:
+			*p = r.vals[i].(bool)
+		}
+	}
+	return nil
+}
+
+type memRows struct {
+	rows [][]interface{}
+	i    int
+}
+
+func (r *memRows) Next() bool { r.i++; return r.i <= len(r.rows) }
+func (r *memRows) Scan(dest ...interface{}) error {
+	return (&memRow{vals: r.rows[r.i-1]}).Scan(dest...)
+}
+func (r *memRows) Close() error { return nil }
+func (r *memRows) Err() error   { return nil }
+
+// memStore holds objects by bucket/key.
+type memStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newMemStore(keys ...string) *memStore {
+	s := &memStore{objects: make(map[string][]byte)}
+	for _, k := range keys {
+		s.objects[k] = []byte("data:" + k)
+	}
+	return s
+}
+
+func (s *memStore) DownloadStream(_ context.Context, bucket, name string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.objects[bucket+"/"+name]
+	if !ok {
+		return nil, stg.ErrObjectNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *memStore) UploadStream(_ context.Context, bucket, name string, r io.Reader, _ int64) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[bucket+"/"+name] = data
+	return nil
+}
+
+func (s *memStore) Delete(_ context.Context, bucket, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, bucket+"/"+name)
+	return nil
+}
+
+func (s *memStore) ListObjects(_ context.Context, bucket, prefix string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys []string
+	for k := range s.objects {
+		if key, ok := strings.CutPrefix(k, bucket+"/"); ok && strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s *memStore) has(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.objects[key]
+	return ok
+}
+
+func TestManager_ColdOriginals(t *testing.T) {
+	db := newPolicyDB()
+	db.originals = [][]interface{}{
+		{"c1", "/streamgate/0xowner/u1.mp4", int64(12)},
+		{"c2", "/streamgate/0xowner/u2.mp4", int64(12)},
+	}
+	db.alreadyCold["c2"] = true // moved by another replica mid-run
+	store := newMemStore("streamgate/0xowner/u1.mp4", "streamgate/0xowner/u2.mp4")
+	m := NewManager(db, store, Config{ColdBucket: "cold", ColdAfter: 24 * time.Hour}, zap.NewNop())
+
+	report, err := m.Run(context.Background(), true)
+	require.NoError(t, err)
+	require.Len(t, report.Actions, 2)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, Action{Policy: PolicyColdOriginals, ContentID: "c1", Bucket: "cold", Keys: []string{"0xowner/u1.mp4"}, Bytes: 12}, report.Actions[0])
+	assert.True(t, store.has("streamgate/0xowner/u1.mp4"), "dry run must not move anything")
+	assert.Empty(t, db.marked)
+
+	report, err = m.Run(context.Background(), false)
+	require.NoError(t, err)
+	assert.Zero(t, report.Errors)
+	assert.False(t, store.has("streamgate/0xowner/u1.mp4"))
+	assert.True(t, store.has("cold/0xowner/u1.mp4"))
+	assert.Equal(t, []string{"storage_tier"}, db.marked["c1"])
+	// The replica that recorded c2's move owns deleting its hot copy.
+	assert.True(t, store.has("streamgate/0xowner/u2.mp4"))
+}
+
+func TestManager_PurgeRenditions(t *testing.T) {
+	db := newPolicyDB()
+	db.unwatched = []string{"c1", "c2"}
+	store := newMemStore(
+		"streamgate/streams/c1/master.m3u8",
+		"streamgate/streams/c1/1280x720/seg0.ts",
+		"streamgate/streams/c1/480p/seg0.ts",
+		"streamgate/streams/c2/480p/seg0.ts",
+		"streamgate/streams/c3/720p/seg0.ts",
+	)
+	m := NewManager(db, store, Config{PurgeRenditionsAfter: 24 * time.Hour, KeepRenditions: []string{"480p"}}, zap.NewNop())
+
+	report, err := m.Run(context.Background(), false)
+	require.NoError(t, err)
+	require.Len(t, report.Actions, 1)
+	assert.Equal(t, "c1", report.Actions[0].ContentID)
+	assert.Equal(t, []string{"streams/c1/1280x720/seg0.ts", "streams/c1/master.m3u8"}, report.Actions[0].Keys)
+
+	assert.False(t, store.has("streamgate/streams/c1/1280x720/seg0.ts"))
+	assert.True(t, store.has("streamgate/streams/c1/480p/seg0.ts"))
+	assert.True(t, store.has("streamgate/streams/c3/720p/seg0.ts"))
+	// c2 only has kept renditions; it is marked so it is not listed again.
+	assert.Equal(t, []string{"renditions_purged_at"}, db.marked["c2"])
+}
+
+func TestManager_OrphanedChunks(t *testing.T) {
+	db := newPolicyDB()
+	db.freshUploads["active"] = true
+	store := newMemStore(
+		"streamgate/chunks/active/0",
+		"streamgate/chunks/abandoned/0",
+		"streamgate/chunks/abandoned/1",
+	)
+	m := NewManager(db, store, Config{OrphanChunkAge: time.Hour}, zap.NewNop())
+
+	report, err := m.Run(context.Background(), false)
+	require.NoError(t, err)
+	require.Len(t, report.Actions, 1)
+	assert.Equal(t, Action{Policy: PolicyOrphanedChunks, UploadID: "abandoned", Bucket: "streamgate",
+		Keys: []string{"chunks/abandoned/0", "chunks/abandoned/1"}}, report.Actions[0])
+	assert.True(t, store.has("streamgate/chunks/active/0"))
+	assert.False(t, store.has("streamgate/chunks/abandoned/0"))
+}
+
+func TestManager_OneRunAtATime(t *testing.T) {
+	m := NewManager(newPolicyDB(), newMemStore(), Config{}, zap.NewNop())
+	m.running.Lock()
+	_, err := m.Run(context.Background(), true)
+	assert.ErrorIs(t, err, serviceerrors.ErrAlreadyExists)
+	m.running.Unlock()
+
+	report, err := m.Run(context.Background(), true)
+	require.NoError(t, err)
+	assert.Empty(t, report.Actions)
+
+	m.Start(time.Hour, true)
+	m.Close()
+	m.Close()
+}
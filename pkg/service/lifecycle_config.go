@@ -0,0 +1,58 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/rtcdance/streamgate/pkg/storage"
+
+	"go.uber.org/zap"
+)
+
+// NewLifecycleManagerFromConfig builds the lifecycle manager described by
+// cfg over the objects in bucket, or returns nil when lifecycle management
+// is disabled. The returned interval is zero when runs are only triggered
+// through the admin API.
+func NewLifecycleManagerFromConfig(db storage.DB, store LifecycleObjectStore, cfg config.LifecycleConfig, bucket string, logger *zap.Logger) (*LifecycleManager, time.Duration, error) {
+	if !cfg.Enabled {
+		return nil, 0, nil
+	}
+	if cfg.ColdAfterDays < 0 || cfg.PurgeRenditionsAfterDays < 0 {
+		return nil, 0, fmt.Errorf("lifecycle day thresholds must not be negative")
+	}
+	if cfg.ColdAfterDays > 0 && (cfg.ColdBucket == "" || cfg.ColdBucket == bucket) {
+		return nil, 0, fmt.Errorf("lifecycle.cold_bucket must name a bucket other than %q when cold_after_days is set", bucket)
+	}
+	interval, err := parseLifecycleDuration("lifecycle.interval", cfg.Interval)
+	if err != nil {
+		return nil, 0, err
+	}
+	orphanAge, err := parseLifecycleDuration("lifecycle.orphan_chunk_age", cfg.OrphanChunkAge)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	const day = 24 * time.Hour
+	m := NewLifecycleManager(db, store, LifecycleConfig{
+		Bucket:               bucket,
+		ColdBucket:           cfg.ColdBucket,
+		ColdAfter:            time.Duration(cfg.ColdAfterDays) * day,
+		PurgeRenditionsAfter: time.Duration(cfg.PurgeRenditionsAfterDays) * day,
+		KeepRenditions:       cfg.KeepRenditions,
+		OrphanChunkAge:       orphanAge,
+		BatchSize:            cfg.BatchSize,
+	}, logger)
+	return m, interval, nil
+}
+
+func parseLifecycleDuration(key, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s %q", key, value)
+	}
+	return d, nil
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNewLifecycleManagerFromConfig(t *testing.T) {
+	enabled := config.LifecycleConfig{Enabled: true, Interval: "24h", ColdBucket: "cold", ColdAfterDays: 90, OrphanChunkAge: "72h"}
+	tests := []struct {
+		name         string
+		mutate       func(c *config.LifecycleConfig)
+		wantNil      bool
+		wantErr      bool
+		wantInterval time.Duration
+	}{
+		{name: "enabled", wantInterval: 24 * time.Hour},
+		{name: "disabled", mutate: func(c *config.LifecycleConfig) { c.Enabled = false }, wantNil: true},
+		{name: "api only", mutate: func(c *config.LifecycleConfig) { c.Interval = "" }},
+		{name: "no cold bucket", mutate: func(c *config.LifecycleConfig) { c.ColdBucket = "" }, wantErr: true},
+		{name: "cold bucket is hot bucket", mutate: func(c *config.LifecycleConfig) { c.ColdBucket = "streamgate" }, wantErr: true},
+		{name: "negative days", mutate: func(c *config.LifecycleConfig) { c.PurgeRenditionsAfterDays = -1 }, wantErr: true},
+		{name: "bad orphan age", mutate: func(c *config.LifecycleConfig) { c.OrphanChunkAge = "soon" }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := enabled
+			if tt.mutate != nil {
+				tt.mutate(&cfg)
+			}
+			m, interval, err := NewLifecycleManagerFromConfig(nil, nil, cfg, "streamgate", zap.NewNop())
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.wantNil {
+				assert.Nil(t, m)
+				return
+			}
+			assert.NotNil(t, m)
+			assert.Equal(t, tt.wantInterval, interval)
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package service
+
+import "github.com/rtcdance/streamgate/pkg/service/lifecycle"
+
+type (
+	LifecycleManager     = lifecycle.Manager
+	LifecycleConfig      = lifecycle.Config
+	LifecycleReport      = lifecycle.Report
+	LifecycleAction      = lifecycle.Action
+	LifecycleObjectStore = lifecycle.ObjectStore
+)
+
+var NewLifecycleManager = lifecycle.NewManager
@@ -0,0 +1,199 @@
+package live
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// AMF0 type markers used by RTMP commands.
+const (
+	amf0Number      = 0x00
+	amf0Boolean     = 0x01
+	amf0String      = 0x02
+	amf0Object      = 0x03
+	amf0Null        = 0x05
+	amf0Undefined   = 0x06
+	amf0ECMAArray   = 0x08
+	amf0ObjectEnd   = 0x09
+	amf0StrictArray = 0x0a
+	amf0Date        = 0x0b
+	amf0LongString  = 0x0c
+)
+
+var errAMFUnsupported = errors.New("unsupported AMF0 type")
+
+// amfObject is a decoded AMF0 object or ECMA array.
+type amfObject map[string]interface{}
+
+// decodeAMF0 decodes every value in b. Numbers decode to float64, objects
+// and ECMA arrays to amfObject, null and undefined to nil.
+func decodeAMF0(b []byte) ([]interface{}, error) {
+	r := bytes.NewReader(b)
+	var values []interface{}
+	for r.Len() > 0 {
+		v, err := readAMF0(r)
+		if err != nil {
+			return values, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func readAMF0(r *bytes.Reader) (interface{}, error) {
+	marker, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch marker {
+	case amf0Number:
+		var bits uint64
+		if err := binary.Read(r, binary.BigEndian, &bits); err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(bits), nil
+	case amf0Boolean:
+		b, err := r.ReadByte()
+		return b != 0, err
+	case amf0String:
+		return readAMF0String(r, 2)
+	case amf0LongString:
+		return readAMF0String(r, 4)
+	case amf0Object:
+		return readAMF0Properties(r)
+	case amf0ECMAArray:
+		if _, err := r.Seek(4, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+		return readAMF0Properties(r)
+	case amf0StrictArray:
+		var n uint32
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return nil, err
+		}
+		if int64(n) > int64(r.Len()) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		values := make([]interface{}, 0, n)
+		for i := uint32(0); i < n; i++ {
+			v, err := readAMF0(r)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	case amf0Date:
+		// 8-byte milliseconds plus a 2-byte time zone, unused by RTMP
+		// publishing.
+		_, err := r.Seek(10, io.SeekCurrent)
+		return nil, err
+	case amf0Null, amf0Undefined:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("%w: 0x%02x", errAMFUnsupported, marker)
+}
+
+func readAMF0String(r *bytes.Reader, lenBytes int) (string, error) {
+	var n uint32
+	if lenBytes == 2 {
+		var n16 uint16
+		if err := binary.Read(r, binary.BigEndian, &n16); err != nil {
+			return "", err
+		}
+		n = uint32(n16)
+	} else if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return "", err
+	}
+	if int64(n) > int64(r.Len()) {
+		return "", io.ErrUnexpectedEOF
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func readAMF0Properties(r *bytes.Reader) (amfObject, error) {
+	obj := amfObject{}
+	for {
+		key, err := readAMF0String(r, 2)
+		if err != nil {
+			return nil, err
+		}
+		if key == "" {
+			marker, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			if marker == amf0ObjectEnd {
+				return obj, nil
+			}
+			if err := r.UnreadByte(); err != nil {
+				return nil, err
+			}
+		}
+		v, err := readAMF0(r)
+		if err != nil {
+			return nil, err
+		}
+		obj[key] = v
+	}
+}
+
+// encodeAMF0 encodes values for an RTMP command. It supports the types
+// decodeAMF0 produces; object keys are written in sorted order.
+func encodeAMF0(values ...interface{}) []byte {
+	var b bytes.Buffer
+	for _, v := range values {
+		writeAMF0(&b, v)
+	}
+	return b.Bytes()
+}
+
+func writeAMF0(b *bytes.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		b.WriteByte(amf0Null)
+	case float64:
+		b.WriteByte(amf0Number)
+		_ = binary.Write(b, binary.BigEndian, math.Float64bits(v))
+	case int:
+		writeAMF0(b, float64(v))
+	case bool:
+		b.WriteByte(amf0Boolean)
+		if v {
+			b.WriteByte(1)
+		} else {
+			b.WriteByte(0)
+		}
+	case string:
+		b.WriteByte(amf0String)
+		writeAMF0Key(b, v)
+	case amfObject:
+		b.WriteByte(amf0Object)
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			writeAMF0Key(b, k)
+			writeAMF0(b, v[k])
+		}
+		b.Write([]byte{0, 0, amf0ObjectEnd})
+	default:
+		b.WriteByte(amf0Undefined)
+	}
+}
+
+func writeAMF0Key(b *bytes.Buffer, s string) {
+	_ = binary.Write(b, binary.BigEndian, uint16(len(s)))
+	b.WriteString(s)
+}
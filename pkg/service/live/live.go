@@ -0,0 +1,400 @@
+package live
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/monitoring"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultViewerTTL is how long a viewer counts as watching after their
+	// last playlist or segment request.
+	DefaultViewerTTL = 30 * time.Second
+	// maxStreamsPerWallet caps streams a wallet holds that have not ended.
+	maxStreamsPerWallet = 5
+	// endedStreamRetention is how long ended streams stay queryable.
+	endedStreamRetention = 24 * time.Hour
+	streamKeyBytes       = 24
+)
+
+var (
+	ErrStreamNotFound = errors.New("live stream not found")
+	ErrNotStreamOwner = errors.New("live stream belongs to another wallet")
+	ErrStreamEnded    = errors.New("live stream has ended")
+	// ErrStreamActive is returned when a second encoder publishes with a
+	// key already in use.
+	ErrStreamActive     = errors.New("live stream already has a publisher")
+	ErrInvalidStreamKey = errors.New("invalid stream key")
+	ErrTooManyStreams   = errors.New("wallet has too many open live streams")
+	ErrWalletRequired   = errors.New("wallet address required")
+)
+
+// StreamState is the lifecycle state of a live stream.
+type StreamState string
+
+const (
+	// StreamIdle streams wait for their encoder to connect; a stream whose
+	// encoder disconnects goes back to idle and can be republished.
+	StreamIdle StreamState = "idle"
+	StreamLive StreamState = "live"
+	// StreamEnded streams were stopped by their owner; the key is revoked.
+	StreamEnded StreamState = "ended"
+)
+
+// Stream is a live stream as reported by the lifecycle APIs.
+type Stream struct {
+	ID            string      `json:"id"`
+	WalletAddress string      `json:"wallet_address"`
+	Title         string      `json:"title"`
+	State         StreamState `json:"state"`
+	// Protocol is the ingest protocol of the current or last publish:
+	// "rtmp" or "srt".
+	Protocol  string     `json:"protocol,omitempty"`
+	Viewers   int        `json:"viewers"`
+	CreatedAt time.Time  `json:"created_at"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+}
+
+type liveStream struct {
+	Stream
+	keyHash   string
+	publisher io.Closer
+	sink      io.WriteCloser
+	viewers   map[string]time.Time
+}
+
+// publishSession is one connected encoder.
+type publishSession struct {
+	streamID string
+	sink     io.WriteCloser
+}
+
+// LiveService owns live streams: their wallet-bound stream keys, publish
+// lifecycle and viewer counts. Streams are held in memory; they do not
+// survive a restart.
+type LiveService struct {
+	packager  Packager
+	logger    *zap.Logger
+	viewerTTL time.Duration
+	now       func() time.Time
+
+	mu      sync.Mutex
+	streams map[string]*liveStream
+	// keys maps the SHA-256 of each stream key to its stream ID, so keys
+	// are never held in plain text.
+	keys map[string]string
+
+	hookMu     sync.Mutex
+	startHooks []func(Stream)
+}
+
+// NewLiveService creates a live service packaging ingest with packager.
+func NewLiveService(packager Packager, logger *zap.Logger) *LiveService {
+	return &LiveService{
+		packager:  packager,
+		logger:    logger,
+		viewerTTL: DefaultViewerTTL,
+		now:       time.Now,
+		streams:   make(map[string]*liveStream),
+		keys:      make(map[string]string),
+	}
+}
+
+func hashStreamKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func newStreamKey() (string, error) {
+	b := make([]byte, streamKeyBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "live_" + hex.EncodeToString(b), nil
+}
+
+// CreateStream starts a stream for wallet and returns it with its stream
+// key. The key is only returned here; encoders publish with it as the RTMP
+// stream name or the SRT stream ID.
+func (s *LiveService) CreateStream(wallet, title string) (Stream, string, error) {
+	if wallet == "" {
+		return Stream{}, "", ErrWalletRequired
+	}
+	key, err := newStreamKey()
+	if err != nil {
+		return Stream{}, "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneEndedLocked()
+	open := 0
+	for _, st := range s.streams {
+		if st.State != StreamEnded && strings.EqualFold(st.WalletAddress, wallet) {
+			open++
+		}
+	}
+	if open >= maxStreamsPerWallet {
+		return Stream{}, "", ErrTooManyStreams
+	}
+
+	st := &liveStream{
+		Stream: Stream{
+			ID:            uuid.New().String(),
+			WalletAddress: wallet,
+			Title:         title,
+			State:         StreamIdle,
+			CreatedAt:     s.now(),
+		},
+		keyHash: hashStreamKey(key),
+		viewers: make(map[string]time.Time),
+	}
+	s.streams[st.ID] = st
+	s.keys[st.keyHash] = st.ID
+	return st.Stream, key, nil
+}
+
+// GetStream returns a stream with its current viewer count.
+func (s *LiveService) GetStream(id string) (Stream, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.streams[id]
+	if !ok {
+		return Stream{}, ErrStreamNotFound
+	}
+	return s.snapshotLocked(st), nil
+}
+
+// ListStreams returns wallet's streams, newest first.
+func (s *LiveService) ListStreams(wallet string) []Stream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []Stream
+	for _, st := range s.streams {
+		if strings.EqualFold(st.WalletAddress, wallet) {
+			out = append(out, s.snapshotLocked(st))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out
+}
+
+// StopStream ends a stream on behalf of its owner: the key is revoked, the
+// encoder disconnected and the stream's HLS output removed.
+func (s *LiveService) StopStream(id, wallet string) (Stream, error) {
+	s.mu.Lock()
+	st, ok := s.streams[id]
+	if !ok {
+		s.mu.Unlock()
+		return Stream{}, ErrStreamNotFound
+	}
+	if !strings.EqualFold(st.WalletAddress, wallet) {
+		s.mu.Unlock()
+		return Stream{}, ErrNotStreamOwner
+	}
+	if st.State == StreamEnded {
+		s.mu.Unlock()
+		return Stream{}, ErrStreamEnded
+	}
+	delete(s.keys, st.keyHash)
+	publisher, sink := st.publisher, st.sink
+	s.detachLocked(st)
+	st.State = StreamEnded
+	now := s.now()
+	st.EndedAt = &now
+	st.viewers = make(map[string]time.Time)
+	snapshot := s.snapshotLocked(st)
+	s.mu.Unlock()
+
+	if publisher != nil {
+		_ = publisher.Close()
+	}
+	if sink != nil {
+		_ = sink.Close()
+	}
+	if err := s.packager.Remove(id); err != nil {
+		s.logger.Warn("Failed to remove live output", zap.String("stream_id", id), zap.Error(err))
+	}
+	s.logger.Info("Live stream stopped", zap.String("stream_id", id))
+	return snapshot, nil
+}
+
+// TouchViewer records viewer as watching a live stream.
+func (s *LiveService) TouchViewer(id, viewer string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.streams[id]
+	if !ok {
+		return ErrStreamNotFound
+	}
+	if st.State == StreamEnded {
+		return ErrStreamEnded
+	}
+	st.viewers[strings.ToLower(viewer)] = s.now()
+	return nil
+}
+
+// Playlist returns the live media playlist of a stream that has not ended.
+func (s *LiveService) Playlist(id string) ([]byte, error) {
+	if err := s.checkPlayable(id); err != nil {
+		return nil, err
+	}
+	return s.packager.Playlist(id)
+}
+
+// SegmentPath returns the local file of one live segment.
+func (s *LiveService) SegmentPath(id, name string) (string, error) {
+	if err := s.checkPlayable(id); err != nil {
+		return "", err
+	}
+	return s.packager.SegmentPath(id, name)
+}
+
+func (s *LiveService) checkPlayable(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.streams[id]
+	if !ok {
+		return ErrStreamNotFound
+	}
+	if st.State == StreamEnded {
+		return ErrStreamEnded
+	}
+	return nil
+}
+
+// Close disconnects every encoder and stops their packagers.
+func (s *LiveService) Close() {
+	s.mu.Lock()
+	var closers []io.Closer
+	for _, st := range s.streams {
+		if st.publisher != nil {
+			closers = append(closers, st.publisher)
+		}
+		if st.sink != nil {
+			closers = append(closers, st.sink)
+		}
+		s.detachLocked(st)
+		if st.State == StreamLive {
+			st.State = StreamIdle
+		}
+	}
+	s.mu.Unlock()
+	for _, c := range closers {
+		_ = c.Close()
+	}
+}
+
+// RegisterStartHook adds a hook that fires when an encoder starts
+// publishing to a stream.
+func (s *LiveService) RegisterStartHook(hook func(Stream)) {
+	s.hookMu.Lock()
+	defer s.hookMu.Unlock()
+	s.startHooks = append(s.startHooks, hook)
+}
+
+// beginPublish authenticates an encoder by stream key and starts packaging
+// its media, which arrives in format. conn is closed if the owner stops
+// the stream.
+func (s *LiveService) beginPublish(key, protocol string, format InputFormat, conn io.Closer) (*publishSession, error) {
+	session, started, err := s.attachPublisher(key, protocol, format, conn)
+	if err != nil {
+		return nil, err
+	}
+	s.hookMu.Lock()
+	hooks := append([]func(Stream){}, s.startHooks...)
+	s.hookMu.Unlock()
+	for _, hook := range hooks {
+		hook(started)
+	}
+	return session, nil
+}
+
+func (s *LiveService) attachPublisher(key, protocol string, format InputFormat, conn io.Closer) (*publishSession, Stream, error) {
+	if key == "" {
+		return nil, Stream{}, ErrInvalidStreamKey
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.keys[hashStreamKey(key)]
+	if !ok {
+		return nil, Stream{}, ErrInvalidStreamKey
+	}
+	st := s.streams[id]
+	if st.State == StreamLive {
+		return nil, Stream{}, ErrStreamActive
+	}
+	sink, err := s.packager.Start(id, format)
+	if err != nil {
+		return nil, Stream{}, err
+	}
+	st.State = StreamLive
+	st.Protocol = protocol
+	now := s.now()
+	st.StartedAt = &now
+	st.publisher = conn
+	st.sink = sink
+	monitoring.LiveStreamsActive.Inc()
+	return &publishSession{streamID: id, sink: sink}, s.snapshotLocked(st), nil
+}
+
+// endPublish runs when an encoder disconnects. The stream returns to idle
+// unless its owner stopped it.
+func (s *LiveService) endPublish(session *publishSession) {
+	s.mu.Lock()
+	st, ok := s.streams[session.streamID]
+	current := ok && st.sink == session.sink
+	if current {
+		s.detachLocked(st)
+		st.State = StreamIdle
+	}
+	s.mu.Unlock()
+	_ = session.sink.Close()
+	if current {
+		s.logger.Info("Live stream publisher disconnected", zap.String("stream_id", session.streamID))
+	}
+}
+
+// detachLocked forgets a stream's encoder and packager.
+func (s *LiveService) detachLocked(st *liveStream) {
+	if st.sink != nil {
+		monitoring.LiveStreamsActive.Dec()
+	}
+	st.publisher = nil
+	st.sink = nil
+}
+
+// snapshotLocked copies st for callers, counting viewers seen within the
+// viewer TTL and dropping the rest.
+func (s *LiveService) snapshotLocked(st *liveStream) Stream {
+	cutoff := s.now().Add(-s.viewerTTL)
+	for viewer, seen := range st.viewers {
+		if seen.Before(cutoff) {
+			delete(st.viewers, viewer)
+		}
+	}
+	out := st.Stream
+	out.Viewers = len(st.viewers)
+	return out
+}
+
+func (s *LiveService) pruneEndedLocked() {
+	cutoff := s.now().Add(-endedStreamRetention)
+	for id, st := range s.streams {
+		if st.State == StreamEnded && st.EndedAt != nil && st.EndedAt.Before(cutoff) {
+			delete(s.streams, id)
+		}
+	}
+}
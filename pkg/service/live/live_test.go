@@ -0,0 +1,240 @@
+package live
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeSink struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	closed bool
+}
+
+func (s *fakeSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0, io.ErrClosedPipe
+	}
+	return s.buf.Write(p)
+}
+
+func (s *fakeSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func (s *fakeSink) Bytes() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]byte(nil), s.buf.Bytes()...)
+}
+
+func (s *fakeSink) Closed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+type fakePackager struct {
+	mu      sync.Mutex
+	sinks   map[string]*fakeSink
+	removed []string
+}
+
+func newFakePackager() *fakePackager {
+	return &fakePackager{sinks: make(map[string]*fakeSink)}
+}
+
+func (p *fakePackager) Start(streamID string, _ InputFormat) (io.WriteCloser, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sink := &fakeSink{}
+	p.sinks[streamID] = sink
+	return sink, nil
+}
+
+func (p *fakePackager) sink(streamID string) *fakeSink {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.sinks[streamID]
+}
+
+func (p *fakePackager) Playlist(streamID string) ([]byte, error) {
+	if p.sink(streamID) == nil {
+		return nil, ErrPlaylistNotReady
+	}
+	return []byte("#EXTM3U\nseg_00000.ts\n"), nil
+}
+
+func (p *fakePackager) SegmentPath(streamID, name string) (string, error) {
+	return filepath.Join("/live", streamID, name), nil
+}
+
+func (p *fakePackager) Remove(streamID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.removed = append(p.removed, streamID)
+	return nil
+}
+
+type fakeConn struct{ closed bool }
+
+func (c *fakeConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+const testWallet = "0x742d35Cc6634C0532925a3b844Bc9e7595f2bD18"
+
+func TestLiveService_CreateStream(t *testing.T) {
+	svc := NewLiveService(newFakePackager(), zap.NewNop())
+
+	stream, key, err := svc.CreateStream(testWallet, "launch party")
+	require.NoError(t, err)
+	assert.Equal(t, StreamIdle, stream.State)
+	assert.Equal(t, "launch party", stream.Title)
+	assert.Contains(t, key, "live_")
+
+	_, _, err = svc.CreateStream("", "anonymous")
+	assert.ErrorIs(t, err, ErrWalletRequired)
+
+	for i := 1; i < maxStreamsPerWallet; i++ {
+		_, _, err = svc.CreateStream(testWallet, "")
+		require.NoError(t, err)
+	}
+	_, _, err = svc.CreateStream(testWallet, "")
+	assert.ErrorIs(t, err, ErrTooManyStreams)
+	assert.Len(t, svc.ListStreams(testWallet), maxStreamsPerWallet)
+}
+
+func TestLiveService_PublishLifecycle(t *testing.T) {
+	packager := newFakePackager()
+	svc := NewLiveService(packager, zap.NewNop())
+	var started []Stream
+	svc.RegisterStartHook(func(st Stream) { started = append(started, st) })
+	stream, key, err := svc.CreateStream(testWallet, "")
+	require.NoError(t, err)
+
+	_, err = svc.beginPublish("live_wrong", "rtmp", InputFLV, &fakeConn{})
+	assert.ErrorIs(t, err, ErrInvalidStreamKey)
+
+	conn := &fakeConn{}
+	session, err := svc.beginPublish(key, "rtmp", InputFLV, conn)
+	require.NoError(t, err)
+	got, _ := svc.GetStream(stream.ID)
+	assert.Equal(t, StreamLive, got.State)
+	assert.NotNil(t, got.StartedAt)
+	require.Len(t, started, 1)
+	assert.Equal(t, stream.ID, started[0].ID)
+	assert.Equal(t, "rtmp", started[0].Protocol)
+
+	_, err = svc.beginPublish(key, "rtmp", InputFLV, &fakeConn{})
+	assert.ErrorIs(t, err, ErrStreamActive)
+
+	// An encoder disconnect leaves the stream open for republishing.
+	svc.endPublish(session)
+	got, _ = svc.GetStream(stream.ID)
+	assert.Equal(t, StreamIdle, got.State)
+	assert.True(t, packager.sink(stream.ID).Closed())
+
+	session, err = svc.beginPublish(key, "rtmp", InputFLV, conn)
+	require.NoError(t, err)
+
+	_, err = svc.StopStream(stream.ID, "0x0000000000000000000000000000000000000001")
+	assert.ErrorIs(t, err, ErrNotStreamOwner)
+
+	stopped, err := svc.StopStream(stream.ID, testWallet)
+	require.NoError(t, err)
+	assert.Equal(t, StreamEnded, stopped.State)
+	assert.True(t, conn.closed)
+	assert.Equal(t, []string{stream.ID}, packager.removed)
+
+	// The publisher's own teardown after the stop must not reopen it.
+	svc.endPublish(session)
+	got, _ = svc.GetStream(stream.ID)
+	assert.Equal(t, StreamEnded, got.State)
+
+	_, err = svc.beginPublish(key, "rtmp", InputFLV, &fakeConn{})
+	assert.ErrorIs(t, err, ErrInvalidStreamKey)
+	_, err = svc.StopStream(stream.ID, testWallet)
+	assert.ErrorIs(t, err, ErrStreamEnded)
+	_, err = svc.Playlist(stream.ID)
+	assert.ErrorIs(t, err, ErrStreamEnded)
+}
+
+func TestLiveService_ViewerCount(t *testing.T) {
+	svc := NewLiveService(newFakePackager(), zap.NewNop())
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+	stream, _, err := svc.CreateStream(testWallet, "")
+	require.NoError(t, err)
+
+	require.NoError(t, svc.TouchViewer(stream.ID, "0xAAA"))
+	require.NoError(t, svc.TouchViewer(stream.ID, "0xaaa"))
+	now = now.Add(20 * time.Second)
+	require.NoError(t, svc.TouchViewer(stream.ID, "0xbbb"))
+	got, _ := svc.GetStream(stream.ID)
+	assert.Equal(t, 2, got.Viewers)
+
+	now = now.Add(15 * time.Second)
+	got, _ = svc.GetStream(stream.ID)
+	assert.Equal(t, 1, got.Viewers, "viewers expire after the viewer TTL")
+
+	assert.ErrorIs(t, svc.TouchViewer("missing", "0xaaa"), ErrStreamNotFound)
+}
+
+func TestFFmpegPackager(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "ffmpeg")
+	// Stand-in for FFmpeg: record stdin and write a playlist and segment
+	// into the directory of the last argument.
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+for last; do :; done
+out=$(dirname "$last")
+cat > "$out/input.flv"
+printf '#EXTM3U\n#EXTINF:2.000,\nseg_00000.ts\n' > "$last"
+: > "$out/seg_00000.ts"
+`), 0o755))
+
+	p := NewFFmpegPackager(FFmpegConfig{FFmpegPath: script, WorkDir: filepath.Join(dir, "live")}, zap.NewNop())
+	_, err := p.Playlist("s1")
+	assert.ErrorIs(t, err, ErrPlaylistNotReady)
+
+	sink, err := p.Start("s1", InputFLV)
+	require.NoError(t, err)
+	_, err = sink.Write([]byte("FLV"))
+	require.NoError(t, err)
+	require.NoError(t, sink.Close())
+
+	playlist, err := p.Playlist("s1")
+	require.NoError(t, err)
+	assert.Contains(t, string(playlist), "seg_00000.ts")
+	input, err := os.ReadFile(filepath.Join(dir, "live", "s1", "input.flv"))
+	require.NoError(t, err)
+	assert.Equal(t, "FLV", string(input))
+
+	path, err := p.SegmentPath("s1", "seg_00000.ts")
+	require.NoError(t, err)
+	assert.FileExists(t, path)
+	_, err = p.SegmentPath("s1", "../s2/seg_00000.ts")
+	assert.ErrorIs(t, err, ErrInvalidSegment)
+	_, err = p.SegmentPath("s1", "seg_00001.ts")
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	require.NoError(t, p.Remove("s1"))
+	assert.NoDirExists(t, filepath.Join(dir, "live", "s1"))
+}
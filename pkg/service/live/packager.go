@@ -0,0 +1,219 @@
+package live
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	livePlaylistName = "index.m3u8"
+	// DefaultSegmentDuration is the live HLS segment length in seconds;
+	// short segments keep glass-to-glass latency low.
+	DefaultSegmentDuration = 2
+	// DefaultWindowSegments is how many segments a live playlist lists.
+	DefaultWindowSegments = 6
+	// ffmpegStopTimeout is how long FFmpeg may take to flush the last
+	// segment after its input closes.
+	ffmpegStopTimeout = 10 * time.Second
+)
+
+var (
+	// ErrPlaylistNotReady is returned until FFmpeg has written the first
+	// segment of a stream.
+	ErrPlaylistNotReady = errors.New("live playlist not ready")
+	// ErrInvalidSegment is returned for names that are not live segments.
+	ErrInvalidSegment = errors.New("invalid live segment name")
+)
+
+// liveSegmentName matches the segments FFmpegPackager writes.
+var liveSegmentName = regexp.MustCompile(`^seg_[0-9]{5,}\.ts$`)
+
+// InputFormat is the container a publisher's media arrives in.
+type InputFormat string
+
+const (
+	// InputFLV is remuxed RTMP.
+	InputFLV InputFormat = "flv"
+	// InputMPEGTS is what SRT encoders send.
+	InputMPEGTS InputFormat = "mpegts"
+)
+
+// Packager turns a publisher's media stream into HLS.
+type Packager interface {
+	// Start begins packaging streamID from input in format; media is
+	// written to the returned writer, and closing it finishes the stream.
+	Start(streamID string, format InputFormat) (io.WriteCloser, error)
+	// Playlist returns the stream's current media playlist.
+	Playlist(streamID string) ([]byte, error)
+	// SegmentPath returns the local file of one segment of the stream.
+	SegmentPath(streamID, name string) (string, error)
+	// Remove deletes everything written for the stream.
+	Remove(streamID string) error
+}
+
+// FFmpegConfig configures FFmpegPackager.
+type FFmpegConfig struct {
+	FFmpegPath string
+	// WorkDir holds one directory of HLS output per stream.
+	WorkDir string
+	// SegmentDuration in seconds; DefaultSegmentDuration when zero.
+	SegmentDuration int
+	// WindowSegments is the live playlist length; DefaultWindowSegments
+	// when zero.
+	WindowSegments int
+}
+
+// FFmpegPackager repackages ingest with FFmpeg. Audio and video are copied,
+// not transcoded, so publishers must send H.264 and AAC.
+type FFmpegPackager struct {
+	config FFmpegConfig
+	logger *zap.Logger
+}
+
+// NewFFmpegPackager creates a packager writing under cfg.WorkDir.
+func NewFFmpegPackager(cfg FFmpegConfig, logger *zap.Logger) *FFmpegPackager {
+	if cfg.FFmpegPath == "" {
+		cfg.FFmpegPath = "ffmpeg"
+	}
+	if cfg.SegmentDuration <= 0 {
+		cfg.SegmentDuration = DefaultSegmentDuration
+	}
+	if cfg.WindowSegments <= 0 {
+		cfg.WindowSegments = DefaultWindowSegments
+	}
+	return &FFmpegPackager{config: cfg, logger: logger}
+}
+
+func (p *FFmpegPackager) streamDir(streamID string) string {
+	return filepath.Join(p.config.WorkDir, streamID)
+}
+
+// Start launches FFmpeg reading format from stdin. Output from an earlier
+// publish of the stream is discarded so the playlist starts afresh.
+func (p *FFmpegPackager) Start(streamID string, format InputFormat) (io.WriteCloser, error) {
+	dir := p.streamDir(streamID)
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("failed to clear live output: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create live output directory: %w", err)
+	}
+
+	args := []string{
+		"-hide_banner", "-loglevel", "error",
+		"-f", string(format), "-i", "pipe:0",
+		"-c", "copy",
+		"-f", "hls",
+		"-hls_time", strconv.Itoa(p.config.SegmentDuration),
+		"-hls_list_size", strconv.Itoa(p.config.WindowSegments),
+		// temp_file keeps readers from seeing half-written playlists.
+		"-hls_flags", "delete_segments+temp_file",
+		"-hls_segment_filename", filepath.Join(dir, "seg_%05d.ts"),
+		filepath.Join(dir, livePlaylistName),
+	}
+	cmd := exec.Command(p.config.FFmpegPath, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ffmpeg stdin: %w", err)
+	}
+	sink := &ffmpegSink{stdin: stdin, cmd: cmd, done: make(chan struct{})}
+	cmd.Stderr = &sink.stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+	go func() {
+		sink.err = cmd.Wait()
+		close(sink.done)
+		if sink.err != nil {
+			p.logger.Warn("Live packager exited with error",
+				zap.String("stream_id", streamID),
+				zap.Error(sink.err),
+				zap.String("stderr", sink.stderr.String()))
+		}
+	}()
+	return sink, nil
+}
+
+// Playlist reads the stream's media playlist.
+func (p *FFmpegPackager) Playlist(streamID string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(p.streamDir(streamID), livePlaylistName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrPlaylistNotReady
+	}
+	return data, err
+}
+
+// SegmentPath returns the path of a segment that still exists on disk.
+func (p *FFmpegPackager) SegmentPath(streamID, name string) (string, error) {
+	if !liveSegmentName.MatchString(name) {
+		return "", ErrInvalidSegment
+	}
+	path := filepath.Join(p.streamDir(streamID), name)
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Remove deletes the stream's output directory.
+func (p *FFmpegPackager) Remove(streamID string) error {
+	return os.RemoveAll(p.streamDir(streamID))
+}
+
+// ffmpegSink feeds one FFmpeg process. Close ends its input and waits for
+// the final segment, killing FFmpeg if it does not exit in time.
+type ffmpegSink struct {
+	stdin  io.WriteCloser
+	cmd    *exec.Cmd
+	stderr syncBuffer
+	done   chan struct{}
+	err    error
+	once   sync.Once
+}
+
+func (s *ffmpegSink) Write(b []byte) (int, error) {
+	return s.stdin.Write(b)
+}
+
+func (s *ffmpegSink) Close() error {
+	s.once.Do(func() {
+		_ = s.stdin.Close()
+		select {
+		case <-s.done:
+		case <-time.After(ffmpegStopTimeout):
+			_ = s.cmd.Process.Kill()
+			<-s.done
+		}
+	})
+	return nil
+}
+
+// syncBuffer is a bytes.Buffer safe for FFmpeg's stderr copier and the
+// goroutine logging it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,608 @@
+package live
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	rtmpVersion       = 3
+	rtmpHandshakeSize = 1536
+	// rtmpDefaultChunkSize is the chunk size both sides start with;
+	// rtmpOutChunkSize is what the server switches to after connect.
+	rtmpDefaultChunkSize = 128
+	rtmpOutChunkSize     = 4096
+	rtmpWindowAckSize    = 2500000
+	// rtmpMaxMessageSize bounds a single reassembled message; video
+	// keyframes are well below it.
+	rtmpMaxMessageSize = 8 << 20
+	// rtmpIdleTimeout closes connections that send nothing, including
+	// publishers whose encoder stalled.
+	rtmpIdleTimeout = 30 * time.Second
+)
+
+// RTMP message type IDs.
+const (
+	rtmpMsgSetChunkSize     = 1
+	rtmpMsgAbort            = 2
+	rtmpMsgAck              = 3
+	rtmpMsgUserControl      = 4
+	rtmpMsgWindowAckSize    = 5
+	rtmpMsgSetPeerBandwidth = 6
+	rtmpMsgAudio            = 8
+	rtmpMsgVideo            = 9
+	rtmpMsgDataAMF3         = 15
+	rtmpMsgCommandAMF3      = 17
+	rtmpMsgDataAMF0         = 18
+	rtmpMsgCommandAMF0      = 20
+)
+
+// Chunk stream IDs the server sends on.
+const (
+	rtmpControlChunkStream = 2
+	rtmpCommandChunkStream = 3
+	rtmpStatusChunkStream  = 5
+)
+
+// rtmpPublishStreamID is the message stream createStream hands out.
+const rtmpPublishStreamID = 1
+
+var errRTMPUnpublished = errors.New("publisher ended the stream")
+
+// RTMPServer accepts RTMP publishers. Each publish is authenticated by its
+// stream key, and its audio, video and metadata are remuxed as FLV into the
+// stream's packager. Playback is not served over RTMP.
+type RTMPServer struct {
+	addr   string
+	svc    *LiveService
+	logger *zap.Logger
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+}
+
+// NewRTMPServer creates an ingest server for svc listening on addr, e.g.
+// ":1935".
+func NewRTMPServer(addr string, svc *LiveService, logger *zap.Logger) *RTMPServer {
+	return &RTMPServer{addr: addr, svc: svc, logger: logger, conns: make(map[net.Conn]struct{})}
+}
+
+// Start binds the listener and accepts connections in the background.
+func (s *RTMPServer) Start() error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for RTMP on %s: %w", s.addr, err)
+	}
+	s.mu.Lock()
+	s.listener = ln
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.acceptLoop(ln)
+	s.logger.Info("RTMP ingest listening", zap.String("addr", ln.Addr().String()))
+	return nil
+}
+
+// Addr returns the bound address, or nil before Start.
+func (s *RTMPServer) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Close stops accepting, disconnects every client and waits for their
+// handlers to finish.
+func (s *RTMPServer) Close() error {
+	s.mu.Lock()
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+func (s *RTMPServer) acceptLoop(ln net.Listener) {
+	defer s.wg.Done()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				s.logger.Warn("RTMP accept failed", zap.Error(err))
+			}
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.conns, conn)
+				s.mu.Unlock()
+				_ = conn.Close()
+			}()
+			c := newRTMPConn(conn, s.svc, s.logger)
+			if err := c.serve(); err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) && !errors.Is(err, errRTMPUnpublished) {
+				s.logger.Debug("RTMP connection closed", zap.String("remote", conn.RemoteAddr().String()), zap.Error(err))
+			}
+		}()
+	}
+}
+
+// chunkStream is the reassembly state of one inbound chunk stream.
+type chunkStream struct {
+	timestamp uint32
+	delta     uint32
+	length    uint32
+	typeID    uint8
+	streamID  uint32
+	extended  bool
+	buf       []byte
+}
+
+type rtmpMessage struct {
+	typeID    uint8
+	streamID  uint32
+	timestamp uint32
+	payload   []byte
+}
+
+// countingReader counts bytes for acknowledgements.
+type countingReader struct {
+	r io.Reader
+	n uint32
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += uint32(n)
+	return n, err
+}
+
+type rtmpConn struct {
+	conn   net.Conn
+	in     *countingReader
+	r      *bufio.Reader
+	w      *bufio.Writer
+	svc    *LiveService
+	logger *zap.Logger
+
+	inChunkSize  uint32
+	outChunkSize uint32
+	streams      map[uint32]*chunkStream
+	ackWindow    uint32
+	lastAck      uint32
+
+	session *publishSession
+	flv     *flvWriter
+}
+
+func newRTMPConn(conn net.Conn, svc *LiveService, logger *zap.Logger) *rtmpConn {
+	in := &countingReader{r: conn}
+	return &rtmpConn{
+		conn:         conn,
+		in:           in,
+		r:            bufio.NewReader(in),
+		w:            bufio.NewWriter(conn),
+		svc:          svc,
+		logger:       logger,
+		inChunkSize:  rtmpDefaultChunkSize,
+		outChunkSize: rtmpDefaultChunkSize,
+		streams:      make(map[uint32]*chunkStream),
+	}
+}
+
+func (c *rtmpConn) serve() error {
+	defer func() {
+		if c.session != nil {
+			c.svc.endPublish(c.session)
+		}
+	}()
+	_ = c.conn.SetDeadline(time.Now().Add(rtmpIdleTimeout))
+	if err := c.handshake(); err != nil {
+		return fmt.Errorf("handshake: %w", err)
+	}
+	for {
+		_ = c.conn.SetDeadline(time.Now().Add(rtmpIdleTimeout))
+		msg, err := c.readMessage()
+		if err != nil {
+			return err
+		}
+		if err := c.handleMessage(msg); err != nil {
+			return err
+		}
+		if err := c.maybeAck(); err != nil {
+			return err
+		}
+	}
+}
+
+// handshake performs the simple (unsigned) RTMP handshake, which FFmpeg
+// and OBS both accept.
+func (c *rtmpConn) handshake() error {
+	c0c1 := make([]byte, 1+rtmpHandshakeSize)
+	if _, err := io.ReadFull(c.r, c0c1); err != nil {
+		return err
+	}
+	if c0c1[0] != rtmpVersion {
+		return fmt.Errorf("unsupported RTMP version %d", c0c1[0])
+	}
+	s0s1s2 := make([]byte, 1+2*rtmpHandshakeSize)
+	s0s1s2[0] = rtmpVersion
+	if _, err := rand.Read(s0s1s2[9 : 1+rtmpHandshakeSize]); err != nil {
+		return err
+	}
+	copy(s0s1s2[1+rtmpHandshakeSize:], c0c1[1:])
+	if _, err := c.w.Write(s0s1s2); err != nil {
+		return err
+	}
+	if err := c.w.Flush(); err != nil {
+		return err
+	}
+	c2 := make([]byte, rtmpHandshakeSize)
+	_, err := io.ReadFull(c.r, c2)
+	return err
+}
+
+// readMessage reads chunks until one message is complete.
+func (c *rtmpConn) readMessage() (*rtmpMessage, error) {
+	for {
+		b0, err := c.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		format := b0 >> 6
+		csid := uint32(b0 & 0x3f)
+		switch csid {
+		case 0:
+			b, err := c.r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			csid = 64 + uint32(b)
+		case 1:
+			var b [2]byte
+			if _, err := io.ReadFull(c.r, b[:]); err != nil {
+				return nil, err
+			}
+			csid = 64 + uint32(b[0]) + uint32(b[1])<<8
+		}
+
+		cs := c.streams[csid]
+		if cs == nil {
+			if format != 0 {
+				return nil, fmt.Errorf("chunk stream %d starts without a full header", csid)
+			}
+			cs = &chunkStream{}
+			c.streams[csid] = cs
+		}
+
+		var hdr [11]byte
+		starting := len(cs.buf) == 0
+		switch format {
+		case 0:
+			if _, err := io.ReadFull(c.r, hdr[:11]); err != nil {
+				return nil, err
+			}
+			ts := uint24(hdr[0:3])
+			cs.length = uint24(hdr[3:6])
+			cs.typeID = hdr[6]
+			cs.streamID = binary.LittleEndian.Uint32(hdr[7:11])
+			cs.extended = ts == 0xffffff
+			if cs.extended {
+				if ts, err = c.readUint32(); err != nil {
+					return nil, err
+				}
+			}
+			cs.timestamp = ts
+			cs.delta = 0
+		case 1, 2:
+			n := 7
+			if format == 2 {
+				n = 3
+			}
+			if _, err := io.ReadFull(c.r, hdr[:n]); err != nil {
+				return nil, err
+			}
+			delta := uint24(hdr[0:3])
+			if format == 1 {
+				cs.length = uint24(hdr[3:6])
+				cs.typeID = hdr[6]
+			}
+			cs.extended = delta == 0xffffff
+			if cs.extended {
+				if delta, err = c.readUint32(); err != nil {
+					return nil, err
+				}
+			}
+			cs.delta = delta
+			cs.timestamp += delta
+		case 3:
+			if cs.extended {
+				if _, err := c.readUint32(); err != nil {
+					return nil, err
+				}
+			}
+			if starting {
+				cs.timestamp += cs.delta
+			}
+		}
+
+		if cs.length > rtmpMaxMessageSize {
+			return nil, fmt.Errorf("message of %d bytes exceeds limit", cs.length)
+		}
+		n := cs.length - uint32(len(cs.buf))
+		if n > c.inChunkSize {
+			n = c.inChunkSize
+		}
+		chunk := make([]byte, n)
+		if _, err := io.ReadFull(c.r, chunk); err != nil {
+			return nil, err
+		}
+		cs.buf = append(cs.buf, chunk...)
+		if uint32(len(cs.buf)) < cs.length {
+			continue
+		}
+		msg := &rtmpMessage{typeID: cs.typeID, streamID: cs.streamID, timestamp: cs.timestamp, payload: cs.buf}
+		cs.buf = nil
+		return msg, nil
+	}
+}
+
+func (c *rtmpConn) readUint32() (uint32, error) {
+	var b [4]byte
+	if _, err := io.ReadFull(c.r, b[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(b[:]), nil
+}
+
+func (c *rtmpConn) maybeAck() error {
+	if c.ackWindow == 0 || c.in.n-c.lastAck < c.ackWindow {
+		return nil
+	}
+	c.lastAck = c.in.n
+	return c.writeMessage(rtmpControlChunkStream, rtmpMsgAck, 0, be32(c.in.n))
+}
+
+func (c *rtmpConn) handleMessage(msg *rtmpMessage) error {
+	switch msg.typeID {
+	case rtmpMsgSetChunkSize:
+		if len(msg.payload) < 4 {
+			return errors.New("short set chunk size message")
+		}
+		size := binary.BigEndian.Uint32(msg.payload) & 0x7fffffff
+		if size == 0 {
+			return errors.New("invalid chunk size 0")
+		}
+		c.inChunkSize = size
+	case rtmpMsgWindowAckSize:
+		if len(msg.payload) >= 4 {
+			c.ackWindow = binary.BigEndian.Uint32(msg.payload)
+		}
+	case rtmpMsgCommandAMF0:
+		return c.handleCommand(msg.payload)
+	case rtmpMsgCommandAMF3:
+		// AMF3 commands are AMF0 behind a one-byte format selector.
+		if len(msg.payload) > 0 {
+			return c.handleCommand(msg.payload[1:])
+		}
+	case rtmpMsgDataAMF0, rtmpMsgDataAMF3:
+		payload := msg.payload
+		if msg.typeID == rtmpMsgDataAMF3 && len(payload) > 0 {
+			payload = payload[1:]
+		}
+		if c.flv != nil {
+			return c.flv.writeTag(rtmpMsgDataAMF0, 0, stripSetDataFrame(payload))
+		}
+	case rtmpMsgAudio, rtmpMsgVideo:
+		if c.flv != nil {
+			return c.flv.writeTag(msg.typeID, msg.timestamp, msg.payload)
+		}
+	case rtmpMsgAbort, rtmpMsgAck, rtmpMsgUserControl, rtmpMsgSetPeerBandwidth:
+	}
+	return nil
+}
+
+func (c *rtmpConn) handleCommand(payload []byte) error {
+	values, err := decodeAMF0(payload)
+	if err != nil && len(values) < 2 {
+		return fmt.Errorf("malformed command: %w", err)
+	}
+	if len(values) < 2 {
+		return nil
+	}
+	name, _ := values[0].(string)
+	txn, _ := values[1].(float64)
+
+	switch name {
+	case "connect":
+		if err := c.writeMessage(rtmpControlChunkStream, rtmpMsgWindowAckSize, 0, be32(rtmpWindowAckSize)); err != nil {
+			return err
+		}
+		if err := c.writeMessage(rtmpControlChunkStream, rtmpMsgSetPeerBandwidth, 0, append(be32(rtmpWindowAckSize), 2)); err != nil {
+			return err
+		}
+		if err := c.writeMessage(rtmpControlChunkStream, rtmpMsgSetChunkSize, 0, be32(rtmpOutChunkSize)); err != nil {
+			return err
+		}
+		return c.writeCommand(0, "_result", txn,
+			amfObject{"fmsVer": "FMS/3,0,1,123", "capabilities": 31.0},
+			amfObject{"level": "status", "code": "NetConnection.Connect.Success", "description": "Connection succeeded.", "objectEncoding": 0.0})
+	case "releaseStream", "FCPublish":
+		return c.writeCommand(0, "_result", txn, nil)
+	case "createStream":
+		return c.writeCommand(0, "_result", txn, nil, float64(rtmpPublishStreamID))
+	case "publish":
+		return c.publish(values)
+	case "FCUnpublish", "deleteStream", "closeStream":
+		if c.session != nil {
+			return errRTMPUnpublished
+		}
+	case "play":
+		_ = c.writeStatus("error", "NetStream.Play.Failed", "Playback is served over HLS.")
+		return errors.New("RTMP playback is not supported")
+	}
+	return nil
+}
+
+// publish authenticates the stream key and starts remuxing into the
+// stream's packager.
+func (c *rtmpConn) publish(values []interface{}) error {
+	if c.session != nil {
+		return errors.New("connection is already publishing")
+	}
+	var key string
+	if len(values) > 3 {
+		key, _ = values[3].(string)
+	}
+	// Encoders may append parameters to the stream name.
+	key, _, _ = strings.Cut(key, "?")
+
+	session, err := c.svc.beginPublish(key, "rtmp", InputFLV, c.conn)
+	if err != nil {
+		code := "NetStream.Publish.BadName"
+		if errors.Is(err, ErrStreamActive) {
+			code = "NetStream.Publish.BadConnection"
+		}
+		_ = c.writeStatus("error", code, err.Error())
+		return fmt.Errorf("publish rejected: %w", err)
+	}
+	c.session = session
+	c.flv = newFLVWriter(session.sink)
+	if err := c.flv.writeHeader(); err != nil {
+		return err
+	}
+	c.logger.Info("RTMP publish started",
+		zap.String("stream_id", session.streamID),
+		zap.String("remote", c.conn.RemoteAddr().String()))
+	return c.writeStatus("status", "NetStream.Publish.Start", "Publishing started.")
+}
+
+func (c *rtmpConn) writeStatus(level, code, description string) error {
+	return c.writeCommandOn(rtmpStatusChunkStream, rtmpPublishStreamID, "onStatus", 0.0, nil,
+		amfObject{"level": level, "code": code, "description": description})
+}
+
+func (c *rtmpConn) writeCommand(streamID uint32, name string, txn float64, args ...interface{}) error {
+	return c.writeCommandOn(rtmpCommandChunkStream, streamID, name, txn, args...)
+}
+
+func (c *rtmpConn) writeCommandOn(csid, streamID uint32, name string, txn float64, args ...interface{}) error {
+	values := append([]interface{}{name, txn}, args...)
+	return c.writeMessage(csid, rtmpMsgCommandAMF0, streamID, encodeAMF0(values...))
+}
+
+// writeMessage writes one message with a type-0 header, split into chunks
+// of the current outbound chunk size.
+func (c *rtmpConn) writeMessage(csid uint32, typeID uint8, streamID uint32, payload []byte) error {
+	var hdr [12]byte
+	hdr[0] = byte(csid)
+	putUint24(hdr[4:7], uint32(len(payload)))
+	hdr[7] = typeID
+	binary.LittleEndian.PutUint32(hdr[8:12], streamID)
+	if _, err := c.w.Write(hdr[:]); err != nil {
+		return err
+	}
+	if typeID == rtmpMsgSetChunkSize {
+		// Applies from the next message on.
+		defer func() { c.outChunkSize = binary.BigEndian.Uint32(payload) }()
+	}
+	rest := payload
+	for len(rest) > 0 {
+		n := uint32(len(rest))
+		if n > c.outChunkSize {
+			n = c.outChunkSize
+		}
+		if _, err := c.w.Write(rest[:n]); err != nil {
+			return err
+		}
+		rest = rest[n:]
+		if len(rest) > 0 {
+			if err := c.w.WriteByte(0xc0 | byte(csid)); err != nil {
+				return err
+			}
+		}
+	}
+	return c.w.Flush()
+}
+
+// stripSetDataFrame turns an encoder's "@setDataFrame" metadata message
+// into the plain onMetaData script tag FLV files carry.
+func stripSetDataFrame(payload []byte) []byte {
+	const prefix = "\x02\x00\x0d@setDataFrame"
+	if strings.HasPrefix(string(payload), prefix) {
+		return payload[len(prefix):]
+	}
+	return payload
+}
+
+// flvWriter remuxes RTMP media messages into an FLV byte stream.
+type flvWriter struct {
+	w io.Writer
+}
+
+func newFLVWriter(w io.Writer) *flvWriter {
+	return &flvWriter{w: w}
+}
+
+func (f *flvWriter) writeHeader() error {
+	// Signature, version 1, audio+video flags, header size 9, then the
+	// zero PreviousTagSize0.
+	_, err := f.w.Write([]byte{'F', 'L', 'V', 1, 0x05, 0, 0, 0, 9, 0, 0, 0, 0})
+	return err
+}
+
+func (f *flvWriter) writeTag(typeID uint8, timestamp uint32, data []byte) error {
+	tag := make([]byte, 11+len(data)+4)
+	tag[0] = typeID
+	putUint24(tag[1:4], uint32(len(data)))
+	putUint24(tag[4:7], timestamp&0xffffff)
+	tag[7] = byte(timestamp >> 24)
+	copy(tag[11:], data)
+	binary.BigEndian.PutUint32(tag[11+len(data):], uint32(11+len(data)))
+	_, err := f.w.Write(tag)
+	return err
+}
+
+func uint24(b []byte) uint32 {
+	return uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+}
+
+func putUint24(b []byte, v uint32) {
+	b[0] = byte(v >> 16)
+	b[1] = byte(v >> 8)
+	b[2] = byte(v)
+}
+
+func be32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
@@ -0,0 +1,193 @@
+package live
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// testRTMPClient is a minimal publisher: it sends every message with a
+// type-0 header in 128-byte chunks, as encoders do before changing the
+// chunk size, and reads the server's replies with the server's own reader.
+type testRTMPClient struct {
+	t    *testing.T
+	conn net.Conn
+	in   *rtmpConn
+}
+
+func dialRTMP(t *testing.T, addr string) *testRTMPClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	c0c1 := make([]byte, 1+rtmpHandshakeSize)
+	c0c1[0] = rtmpVersion
+	_, err = conn.Write(c0c1)
+	require.NoError(t, err)
+	s0s1s2 := make([]byte, 1+2*rtmpHandshakeSize)
+	_, err = io.ReadFull(conn, s0s1s2)
+	require.NoError(t, err)
+	assert.Equal(t, byte(rtmpVersion), s0s1s2[0])
+	assert.Equal(t, c0c1[1:], s0s1s2[1+rtmpHandshakeSize:], "S2 echoes C1")
+	_, err = conn.Write(s0s1s2[1 : 1+rtmpHandshakeSize])
+	require.NoError(t, err)
+
+	in := &countingReader{r: conn}
+	return &testRTMPClient{t: t, conn: conn, in: &rtmpConn{
+		in: in, r: bufio.NewReader(in), inChunkSize: rtmpDefaultChunkSize, streams: make(map[uint32]*chunkStream),
+	}}
+}
+
+func (c *testRTMPClient) send(csid uint32, typeID uint8, streamID, timestamp uint32, payload []byte) {
+	var b bytes.Buffer
+	hdr := make([]byte, 12)
+	hdr[0] = byte(csid)
+	putUint24(hdr[1:4], timestamp)
+	putUint24(hdr[4:7], uint32(len(payload)))
+	hdr[7] = typeID
+	binary.LittleEndian.PutUint32(hdr[8:12], streamID)
+	b.Write(hdr)
+	for i := 0; i < len(payload); i += rtmpDefaultChunkSize {
+		if i > 0 {
+			b.WriteByte(0xc0 | byte(csid))
+		}
+		end := i + rtmpDefaultChunkSize
+		if end > len(payload) {
+			end = len(payload)
+		}
+		b.Write(payload[i:end])
+	}
+	_, err := c.conn.Write(b.Bytes())
+	require.NoError(c.t, err)
+}
+
+func (c *testRTMPClient) command(streamID uint32, values ...interface{}) {
+	c.send(3, rtmpMsgCommandAMF0, streamID, 0, encodeAMF0(values...))
+}
+
+// expectCommand reads until a command named name arrives, applying
+// set-chunk-size messages on the way.
+func (c *testRTMPClient) expectCommand(name string) []interface{} {
+	c.t.Helper()
+	for {
+		msg, err := c.in.readMessage()
+		require.NoError(c.t, err)
+		switch msg.typeID {
+		case rtmpMsgSetChunkSize:
+			c.in.inChunkSize = binary.BigEndian.Uint32(msg.payload)
+		case rtmpMsgCommandAMF0:
+			values, err := decodeAMF0(msg.payload)
+			require.NoError(c.t, err)
+			if values[0] == name {
+				return values
+			}
+		}
+	}
+}
+
+func startTestRTMPServer(t *testing.T, svc *LiveService) string {
+	t.Helper()
+	srv := NewRTMPServer("127.0.0.1:0", svc, zap.NewNop())
+	require.NoError(t, srv.Start())
+	t.Cleanup(func() { _ = srv.Close() })
+	return srv.Addr().String()
+}
+
+func publishTestStream(c *testRTMPClient, key string) []interface{} {
+	c.command(0, "connect", 1.0, amfObject{"app": "live", "tcUrl": "rtmp://localhost/live"})
+	result := c.expectCommand("_result")
+	assert.Equal(c.t, "NetConnection.Connect.Success", result[3].(amfObject)["code"])
+
+	c.command(0, "createStream", 2.0, nil)
+	result = c.expectCommand("_result")
+	assert.Equal(c.t, float64(rtmpPublishStreamID), result[3])
+
+	c.command(rtmpPublishStreamID, "publish", 3.0, nil, key, "live")
+	return c.expectCommand("onStatus")
+}
+
+func TestRTMPServer_Publish(t *testing.T) {
+	packager := newFakePackager()
+	svc := NewLiveService(packager, zap.NewNop())
+	stream, key, err := svc.CreateStream(testWallet, "")
+	require.NoError(t, err)
+	client := dialRTMP(t, startTestRTMPServer(t, svc))
+
+	status := publishTestStream(client, key+"?encoder=obs")
+	assert.Equal(t, "NetStream.Publish.Start", status[3].(amfObject)["code"])
+
+	metadata := encodeAMF0("@setDataFrame", "onMetaData", amfObject{"width": 1280.0})
+	client.send(4, rtmpMsgDataAMF0, rtmpPublishStreamID, 0, metadata)
+	video := bytes.Repeat([]byte{0x17}, 300) // spans three chunks
+	client.send(6, rtmpMsgVideo, rtmpPublishStreamID, 40, video)
+
+	got, _ := svc.GetStream(stream.ID)
+	assert.Equal(t, StreamLive, got.State)
+
+	wantMeta := encodeAMF0("onMetaData", amfObject{"width": 1280.0})
+	want := []byte{'F', 'L', 'V', 1, 0x05, 0, 0, 0, 9, 0, 0, 0, 0}
+	want = append(want, flvTag(rtmpMsgDataAMF0, 0, wantMeta)...)
+	want = append(want, flvTag(rtmpMsgVideo, 40, video)...)
+	require.Eventually(t, func() bool {
+		return bytes.Equal(packager.sink(stream.ID).Bytes(), want)
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// Stopping the stream disconnects the encoder.
+	_, err = svc.StopStream(stream.ID, testWallet)
+	require.NoError(t, err)
+	_, err = client.conn.Read(make([]byte, 1))
+	assert.Error(t, err)
+	assert.True(t, packager.sink(stream.ID).Closed())
+}
+
+func TestRTMPServer_RejectsUnknownKey(t *testing.T) {
+	svc := NewLiveService(newFakePackager(), zap.NewNop())
+	client := dialRTMP(t, startTestRTMPServer(t, svc))
+
+	status := publishTestStream(client, "live_unknown")
+	assert.Equal(t, "error", status[3].(amfObject)["level"])
+	assert.Equal(t, "NetStream.Publish.BadName", status[3].(amfObject)["code"])
+	_, err := io.ReadAll(client.conn)
+	assert.NoError(t, err, "server closes the connection")
+}
+
+func TestRTMPServer_DisconnectReturnsStreamToIdle(t *testing.T) {
+	svc := NewLiveService(newFakePackager(), zap.NewNop())
+	stream, key, err := svc.CreateStream(testWallet, "")
+	require.NoError(t, err)
+	client := dialRTMP(t, startTestRTMPServer(t, svc))
+	publishTestStream(client, key)
+
+	require.NoError(t, client.conn.Close())
+	require.Eventually(t, func() bool {
+		got, _ := svc.GetStream(stream.ID)
+		return got.State == StreamIdle
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestAMF0RoundTrip(t *testing.T) {
+	in := []interface{}{"connect", 1.0, amfObject{"app": "live", "nested": amfObject{"ok": true}}, nil}
+	out, err := decodeAMF0(encodeAMF0(in...))
+	require.NoError(t, err)
+	assert.Equal(t, in, out)
+
+	_, err = decodeAMF0([]byte{0x02, 0x00, 0x10, 'a'})
+	assert.Error(t, err, "truncated string")
+}
+
+func flvTag(typeID uint8, timestamp uint32, data []byte) []byte {
+	var b bytes.Buffer
+	w := newFLVWriter(&b)
+	_ = w.writeTag(typeID, timestamp, data)
+	return b.Bytes()
+}
@@ -0,0 +1,743 @@
+package live
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	srtHeaderSize    = 16
+	srtHandshakeSize = 48
+	// srtMaxPacketSize is the largest datagram read; SRT payloads fit a
+	// 1500-byte MTU.
+	srtMaxPacketSize = 1500
+	srtVersion       = 5
+	srtMagicCode     = 0x4A17
+	// srtLibVersion is the SRT version reported in HSRSP, 1.5.1.
+	srtLibVersion = 0x010501
+	// srtDefaultLatency is the receiver latency when the caller asks for
+	// less; lost packets are waited for this long before being skipped.
+	srtDefaultLatency = 120 * time.Millisecond
+	srtACKInterval    = 10 * time.Millisecond
+	srtNAKInterval    = 50 * time.Millisecond
+	srtKeepalive      = time.Second
+	// srtIdleTimeout ends sessions whose caller sends nothing, including
+	// callers that vanished without a shutdown.
+	srtIdleTimeout = 5 * time.Second
+	// srtReorderWindow bounds packets buffered behind a gap.
+	srtReorderWindow = 8192
+	srtSeqMask       = 0x7FFFFFFF
+	// srtSessionQueue is how many datagrams may wait for a session before
+	// new ones are dropped and recovered as losses.
+	srtSessionQueue = 1024
+)
+
+// SRT control packet types.
+const (
+	srtCtrlHandshake = 0x0000
+	srtCtrlKeepalive = 0x0001
+	srtCtrlACK       = 0x0002
+	srtCtrlNAK       = 0x0003
+	srtCtrlShutdown  = 0x0005
+	srtCtrlACKACK    = 0x0006
+)
+
+// SRT handshake types, extensions and flags.
+const (
+	srtHSInduction  = 1
+	srtHSConclusion = 0xFFFFFFFF
+	srtHSExtHSReq   = 0x1
+	srtHSExtKMReq   = 0x2
+
+	srtExtHSReq = 1
+	srtExtHSRsp = 2
+	srtExtKMReq = 3
+	srtExtSID   = 5
+
+	srtFlagTSBPDRcv  = 0x02
+	srtFlagTLPktDrop = 0x08
+	srtFlagNAKReport = 0x10
+	srtFlagRexmit    = 0x20
+)
+
+// SRT rejection reasons, sent as the handshake type.
+const (
+	srtRejectVersion      = 1008
+	srtRejectUnsecure     = 1011
+	srtRejectBadRequest   = 1400
+	srtRejectUnauthorized = 1401
+	srtRejectConflict     = 1409
+)
+
+// SRTServer accepts SRT callers in live mode. The stream ID carries the
+// stream key, either bare or as the "r" key of an access-control stream ID
+// ("#!::r=<key>,m=publish"), and the MPEG-TS payload is fed to the stream's
+// packager. Lost packets are requested again until the negotiated latency
+// passes and then skipped. Encrypted callers are rejected.
+type SRTServer struct {
+	addr   string
+	svc    *LiveService
+	logger *zap.Logger
+	secret []byte
+
+	mu       sync.Mutex
+	conn     *net.UDPConn
+	sessions map[string]*srtSession
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// NewSRTServer creates an ingest server for svc listening on UDP addr,
+// e.g. ":9000".
+func NewSRTServer(addr string, svc *LiveService, logger *zap.Logger) *SRTServer {
+	secret := make([]byte, 32)
+	_, _ = rand.Read(secret)
+	return &SRTServer{addr: addr, svc: svc, logger: logger, secret: secret, sessions: make(map[string]*srtSession)}
+}
+
+// Start binds the socket and serves callers in the background.
+func (s *SRTServer) Start() error {
+	udpAddr, err := net.ResolveUDPAddr("udp", s.addr)
+	if err != nil {
+		return fmt.Errorf("invalid SRT address %s: %w", s.addr, err)
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for SRT on %s: %w", s.addr, err)
+	}
+	s.mu.Lock()
+	s.conn = conn
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.readLoop(conn)
+	s.logger.Info("SRT ingest listening", zap.String("addr", conn.LocalAddr().String()))
+	return nil
+}
+
+// Addr returns the bound address, or nil before Start.
+func (s *SRTServer) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.LocalAddr()
+}
+
+// Close ends every session, sending callers a shutdown, and closes the
+// socket.
+func (s *SRTServer) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	sessions := make([]*srtSession, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	conn := s.conn
+	s.mu.Unlock()
+
+	for _, sess := range sessions {
+		_ = sess.Close()
+		<-sess.finished
+	}
+	var err error
+	if conn != nil {
+		err = conn.Close()
+	}
+	s.wg.Wait()
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+func (s *SRTServer) readLoop(conn *net.UDPConn) {
+	defer s.wg.Done()
+	buf := make([]byte, srtMaxPacketSize)
+	for {
+		n, addr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				s.logger.Warn("SRT read failed", zap.Error(err))
+			}
+			return
+		}
+		if n < srtHeaderSize {
+			continue
+		}
+		pkt := buf[:n]
+		if isSRTControl(pkt) && srtControlType(pkt) == srtCtrlHandshake {
+			s.handleHandshake(conn, pkt, addr)
+			continue
+		}
+
+		s.mu.Lock()
+		sess := s.sessions[addr.String()]
+		s.mu.Unlock()
+		if sess == nil {
+			continue
+		}
+		select {
+		case sess.in <- append([]byte(nil), pkt...):
+		default:
+		}
+	}
+}
+
+// srtHandshake is the handshake CIF.
+type srtHandshake struct {
+	version    uint32
+	encryption uint16
+	extFlags   uint16
+	initSeq    uint32
+	mtu        uint32
+	flowWindow uint32
+	hsType     uint32
+	socketID   uint32
+	cookie     uint32
+	peerIP     [16]byte
+	// exts is everything after the fixed fields.
+	exts []byte
+}
+
+func parseSRTHandshake(cif []byte) (srtHandshake, bool) {
+	if len(cif) < srtHandshakeSize {
+		return srtHandshake{}, false
+	}
+	hs := srtHandshake{
+		version:    binary.BigEndian.Uint32(cif[0:4]),
+		encryption: binary.BigEndian.Uint16(cif[4:6]),
+		extFlags:   binary.BigEndian.Uint16(cif[6:8]),
+		initSeq:    binary.BigEndian.Uint32(cif[8:12]) & srtSeqMask,
+		mtu:        binary.BigEndian.Uint32(cif[12:16]),
+		flowWindow: binary.BigEndian.Uint32(cif[16:20]),
+		hsType:     binary.BigEndian.Uint32(cif[20:24]),
+		socketID:   binary.BigEndian.Uint32(cif[24:28]),
+		cookie:     binary.BigEndian.Uint32(cif[28:32]),
+		exts:       cif[srtHandshakeSize:],
+	}
+	copy(hs.peerIP[:], cif[32:48])
+	return hs, true
+}
+
+func (hs srtHandshake) marshal() []byte {
+	b := make([]byte, srtHandshakeSize, srtHandshakeSize+len(hs.exts))
+	binary.BigEndian.PutUint32(b[0:4], hs.version)
+	binary.BigEndian.PutUint16(b[4:6], hs.encryption)
+	binary.BigEndian.PutUint16(b[6:8], hs.extFlags)
+	binary.BigEndian.PutUint32(b[8:12], hs.initSeq)
+	binary.BigEndian.PutUint32(b[12:16], hs.mtu)
+	binary.BigEndian.PutUint32(b[16:20], hs.flowWindow)
+	binary.BigEndian.PutUint32(b[20:24], hs.hsType)
+	binary.BigEndian.PutUint32(b[24:28], hs.socketID)
+	binary.BigEndian.PutUint32(b[28:32], hs.cookie)
+	copy(b[32:48], hs.peerIP[:])
+	return append(b, hs.exts...)
+}
+
+// cookie binds a caller's address to the induction response for the
+// current minute, so conclusions cannot come from spoofed addresses.
+func (s *SRTServer) cookie(addr *net.UDPAddr, minute int64) uint32 {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(addr.String()))
+	mac.Write([]byte(strconv.FormatInt(minute, 10)))
+	return binary.BigEndian.Uint32(mac.Sum(nil))
+}
+
+// validCookie accepts cookies from this minute and the last, so an
+// induction just before the minute turns still concludes.
+func (s *SRTServer) validCookie(addr *net.UDPAddr, cookie uint32) bool {
+	minute := time.Now().Unix() / 60
+	return cookie == s.cookie(addr, minute) || cookie == s.cookie(addr, minute-1)
+}
+
+func (s *SRTServer) handleHandshake(conn *net.UDPConn, pkt []byte, addr *net.UDPAddr) {
+	hs, ok := parseSRTHandshake(pkt[srtHeaderSize:])
+	if !ok {
+		return
+	}
+	reply := srtHandshake{
+		version:    srtVersion,
+		initSeq:    hs.initSeq,
+		mtu:        min(hs.mtu, srtMaxPacketSize),
+		flowWindow: hs.flowWindow,
+		peerIP:     hs.peerIP,
+	}
+
+	switch hs.hsType {
+	case srtHSInduction:
+		reply.extFlags = srtMagicCode
+		reply.hsType = srtHSInduction
+		reply.cookie = s.cookie(addr, time.Now().Unix()/60)
+		writeSRTControl(conn, addr, srtCtrlHandshake, 0, 0, hs.socketID, reply.marshal())
+		return
+	case srtHSConclusion:
+	default:
+		return
+	}
+
+	if !s.validCookie(addr, hs.cookie) {
+		return
+	}
+	s.mu.Lock()
+	existing := s.sessions[addr.String()]
+	s.mu.Unlock()
+	if existing != nil {
+		// The caller missed our conclusion response and sent its own again.
+		if existing.peerID == hs.socketID {
+			_, _ = conn.WriteToUDP(existing.response, addr)
+		}
+		return
+	}
+
+	reject := func(reason uint32, msg string) {
+		reply.hsType = reason
+		reply.cookie = hs.cookie
+		writeSRTControl(conn, addr, srtCtrlHandshake, 0, 0, hs.socketID, reply.marshal())
+		s.logger.Debug("SRT caller rejected", zap.String("remote", addr.String()), zap.String("reason", msg))
+	}
+	if hs.version < srtVersion {
+		reject(srtRejectVersion, "handshake version 4 is not supported")
+		return
+	}
+	req, err := parseSRTConclusion(hs)
+	if err != nil {
+		reject(srtRejectBadRequest, err.Error())
+		return
+	}
+	if req.encrypted {
+		reject(srtRejectUnsecure, "encryption is not supported")
+		return
+	}
+	key, err := parseSRTStreamID(req.streamID)
+	if err != nil {
+		reject(srtRejectBadRequest, err.Error())
+		return
+	}
+
+	sess := &srtSession{
+		server:   s,
+		conn:     conn,
+		addr:     addr,
+		peerID:   hs.socketID,
+		latency:  max(srtDefaultLatency, req.senderLatency),
+		start:    time.Now(),
+		nextSeq:  hs.initSeq,
+		ackedSeq: hs.initSeq,
+		buffer:   make(map[uint32][]byte),
+		in:       make(chan []byte, srtSessionQueue),
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+	id := make([]byte, 4)
+	_, _ = rand.Read(id)
+	sess.localID = binary.BigEndian.Uint32(id) & srtSeqMask
+
+	publish, err := s.svc.beginPublish(key, "srt", InputMPEGTS, sess)
+	if err != nil {
+		if errors.Is(err, ErrStreamActive) {
+			reject(srtRejectConflict, err.Error())
+		} else {
+			reject(srtRejectUnauthorized, err.Error())
+		}
+		return
+	}
+	sess.publish = publish
+
+	reply.hsType = srtHSConclusion
+	reply.socketID = sess.localID
+	reply.cookie = hs.cookie
+	if req.hasHSReq {
+		reply.extFlags = srtHSExtHSReq
+		reply.exts = srtHSRspExtension(sess.latency, req.receiverLatency)
+	}
+	sess.response = srtControlPacket(srtCtrlHandshake, 0, 0, hs.socketID, reply.marshal())
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		s.svc.endPublish(publish)
+		return
+	}
+	s.sessions[addr.String()] = sess
+	s.wg.Add(1)
+	s.mu.Unlock()
+	_, _ = conn.WriteToUDP(sess.response, addr)
+	go sess.run()
+	s.logger.Info("SRT publisher connected", zap.String("stream_id", publish.streamID), zap.String("remote", addr.String()))
+}
+
+// srtConclusion is what a caller asks for in its conclusion handshake.
+type srtConclusion struct {
+	hasHSReq        bool
+	senderLatency   time.Duration
+	receiverLatency time.Duration
+	streamID        string
+	encrypted       bool
+}
+
+func parseSRTConclusion(hs srtHandshake) (srtConclusion, error) {
+	var req srtConclusion
+	req.encrypted = hs.encryption != 0 || hs.extFlags&srtHSExtKMReq != 0
+	exts := hs.exts
+	for len(exts) >= 4 {
+		typ := binary.BigEndian.Uint16(exts[0:2])
+		size := int(binary.BigEndian.Uint16(exts[2:4])) * 4
+		if len(exts) < 4+size {
+			return req, errors.New("truncated handshake extension")
+		}
+		body := exts[4 : 4+size]
+		exts = exts[4+size:]
+
+		switch typ {
+		case srtExtHSReq:
+			if len(body) < 12 {
+				return req, errors.New("short HSREQ extension")
+			}
+			req.hasHSReq = true
+			req.receiverLatency = time.Duration(binary.BigEndian.Uint16(body[8:10])) * time.Millisecond
+			req.senderLatency = time.Duration(binary.BigEndian.Uint16(body[10:12])) * time.Millisecond
+		case srtExtKMReq:
+			req.encrypted = true
+		case srtExtSID:
+			req.streamID = decodeSRTString(body)
+		}
+	}
+	return req, nil
+}
+
+// decodeSRTString undoes the per-word byte swap SRT applies to strings in
+// handshake extensions and drops the zero padding.
+func decodeSRTString(b []byte) string {
+	return strings.TrimRight(string(swapSRTWords(b)), "\x00")
+}
+
+// swapSRTWords reverses the byte order of each 32-bit word of b.
+func swapSRTWords(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := 0; i+4 <= len(b); i += 4 {
+		out[i], out[i+1], out[i+2], out[i+3] = b[i+3], b[i+2], b[i+1], b[i]
+	}
+	return out
+}
+
+// parseSRTStreamID extracts the stream key from a bare key or an
+// access-control stream ID; only publishing is accepted.
+func parseSRTStreamID(sid string) (string, error) {
+	if !strings.HasPrefix(sid, "#!::") {
+		if sid == "" {
+			return "", errors.New("missing stream ID")
+		}
+		return sid, nil
+	}
+	var key, mode string
+	for _, kv := range strings.Split(sid[len("#!::"):], ",") {
+		k, v, _ := strings.Cut(kv, "=")
+		switch k {
+		case "r":
+			key = v
+		case "m":
+			mode = v
+		}
+	}
+	if mode != "" && mode != "publish" {
+		return "", fmt.Errorf("mode %q is not supported", mode)
+	}
+	if key == "" {
+		return "", errors.New("stream ID has no resource")
+	}
+	return key, nil
+}
+
+func srtHSRspExtension(receiverLatency, senderLatency time.Duration) []byte {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint16(b[0:2], srtExtHSRsp)
+	binary.BigEndian.PutUint16(b[2:4], 3)
+	binary.BigEndian.PutUint32(b[4:8], srtLibVersion)
+	binary.BigEndian.PutUint32(b[8:12], srtFlagTSBPDRcv|srtFlagTLPktDrop|srtFlagNAKReport|srtFlagRexmit)
+	binary.BigEndian.PutUint16(b[12:14], uint16(receiverLatency/time.Millisecond))
+	binary.BigEndian.PutUint16(b[14:16], uint16(senderLatency/time.Millisecond))
+	return b
+}
+
+// srtSession receives one caller's data packets in order and writes their
+// payload to the stream's packager.
+type srtSession struct {
+	server   *SRTServer
+	conn     *net.UDPConn
+	addr     *net.UDPAddr
+	localID  uint32
+	peerID   uint32
+	latency  time.Duration
+	start    time.Time
+	publish  *publishSession
+	response []byte
+
+	in        chan []byte
+	done      chan struct{}
+	finished  chan struct{}
+	closeOnce sync.Once
+
+	// Receiver state, owned by run.
+	nextSeq  uint32
+	highest  uint32
+	ackedSeq uint32
+	ackNo    uint32
+	buffer   map[uint32][]byte
+	gapSince time.Time
+	lastNAK  time.Time
+	lastRecv time.Time
+	lastSent time.Time
+}
+
+// Close ends the session; it is how StopStream disconnects the caller.
+func (s *srtSession) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	return nil
+}
+
+func (s *srtSession) run() {
+	defer s.server.wg.Done()
+	defer close(s.finished)
+	defer func() {
+		s.server.mu.Lock()
+		delete(s.server.sessions, s.addr.String())
+		s.server.mu.Unlock()
+		s.server.svc.endPublish(s.publish)
+	}()
+
+	s.lastRecv = time.Now()
+	s.highest = (s.nextSeq - 1) & srtSeqMask
+	ticker := time.NewTicker(srtACKInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case pkt := <-s.in:
+			s.lastRecv = time.Now()
+			if !s.handlePacket(pkt) {
+				return
+			}
+		case now := <-ticker.C:
+			if !s.tick(now) {
+				return
+			}
+		case <-s.done:
+			s.sendControl(srtCtrlShutdown, 0, nil)
+			return
+		}
+	}
+}
+
+// handlePacket processes one datagram and reports whether the session
+// continues.
+func (s *srtSession) handlePacket(pkt []byte) bool {
+	if isSRTControl(pkt) {
+		switch srtControlType(pkt) {
+		case srtCtrlShutdown:
+			s.server.logger.Info("SRT publisher disconnected", zap.String("stream_id", s.publish.streamID))
+			return false
+		case srtCtrlKeepalive:
+			s.sendControl(srtCtrlKeepalive, 0, nil)
+		}
+		// ACKACK, and ACK and NAK for data we never send, need no reply.
+		return true
+	}
+
+	seq := binary.BigEndian.Uint32(pkt[0:4]) & srtSeqMask
+	payload := pkt[srtHeaderSize:]
+	d := srtSeqDiff(seq, s.nextSeq)
+	switch {
+	case d < 0:
+		// Late retransmission or duplicate.
+		return true
+	case d == 0:
+		if !s.write(payload) {
+			return false
+		}
+		s.nextSeq = srtSeqAdd(s.nextSeq, 1)
+		if srtSeqDiff(seq, s.highest) > 0 {
+			s.highest = seq
+		}
+		return s.drain()
+	case d >= srtReorderWindow:
+		// Too far ahead to recover: skip everything missing.
+		s.buffer = make(map[uint32][]byte)
+		s.gapSince = time.Time{}
+		s.nextSeq = seq
+		s.highest = seq
+		return s.handlePacket(pkt)
+	}
+
+	if _, dup := s.buffer[seq]; dup {
+		return true
+	}
+	s.buffer[seq] = append([]byte(nil), payload...)
+	if s.gapSince.IsZero() {
+		s.gapSince = time.Now()
+	}
+	if gap := srtSeqDiff(seq, s.highest); gap > 0 {
+		if gap > 1 {
+			// Report the newly detected loss right away.
+			s.sendNAK([][2]uint32{{srtSeqAdd(s.highest, 1), srtSeqAdd(seq, -1)}})
+		}
+		s.highest = seq
+	}
+	return true
+}
+
+// drain writes buffered packets that are now in order.
+func (s *srtSession) drain() bool {
+	for {
+		payload, ok := s.buffer[s.nextSeq]
+		if !ok {
+			break
+		}
+		delete(s.buffer, s.nextSeq)
+		if !s.write(payload) {
+			return false
+		}
+		s.nextSeq = srtSeqAdd(s.nextSeq, 1)
+	}
+	if len(s.buffer) == 0 {
+		s.gapSince = time.Time{}
+	} else {
+		s.gapSince = time.Now()
+	}
+	return true
+}
+
+func (s *srtSession) write(payload []byte) bool {
+	if _, err := s.publish.sink.Write(payload); err != nil {
+		s.server.logger.Warn("SRT packager write failed", zap.String("stream_id", s.publish.streamID), zap.Error(err))
+		return false
+	}
+	return true
+}
+
+// tick sends ACKs, repeats loss reports, skips losses older than the
+// latency and keeps the connection alive.
+func (s *srtSession) tick(now time.Time) bool {
+	if now.Sub(s.lastRecv) > srtIdleTimeout {
+		s.server.logger.Info("SRT publisher timed out", zap.String("stream_id", s.publish.streamID))
+		return false
+	}
+	if !s.gapSince.IsZero() && now.Sub(s.gapSince) > s.latency {
+		// Too late to play: drop the missing packets, as TLPKTDROP does.
+		first := s.highest
+		for seq := range s.buffer {
+			if srtSeqDiff(seq, first) < 0 {
+				first = seq
+			}
+		}
+		s.nextSeq = first
+		if !s.drain() {
+			return false
+		}
+	}
+	if !s.gapSince.IsZero() && now.Sub(s.lastNAK) > srtNAKInterval {
+		s.sendNAK(s.lossRanges())
+	}
+	if s.nextSeq != s.ackedSeq {
+		s.sendACK()
+	}
+	if now.Sub(s.lastSent) > srtKeepalive {
+		s.sendControl(srtCtrlKeepalive, 0, nil)
+	}
+	return true
+}
+
+// lossRanges lists the sequence ranges missing between the next expected
+// packet and the highest received.
+func (s *srtSession) lossRanges() [][2]uint32 {
+	var ranges [][2]uint32
+	for seq := s.nextSeq; srtSeqDiff(seq, s.highest) < 0; seq = srtSeqAdd(seq, 1) {
+		if _, ok := s.buffer[seq]; ok {
+			continue
+		}
+		if n := len(ranges); n > 0 && ranges[n-1][1] == srtSeqAdd(seq, -1) {
+			ranges[n-1][1] = seq
+		} else {
+			ranges = append(ranges, [2]uint32{seq, seq})
+		}
+	}
+	return ranges
+}
+
+func (s *srtSession) sendACK() {
+	s.ackNo++
+	cif := make([]byte, 28)
+	binary.BigEndian.PutUint32(cif[0:4], s.nextSeq)
+	binary.BigEndian.PutUint32(cif[4:8], 100000) // RTT, microseconds
+	binary.BigEndian.PutUint32(cif[8:12], 50000) // RTT variance
+	binary.BigEndian.PutUint32(cif[12:16], uint32(srtReorderWindow-len(s.buffer)))
+	s.sendControl(srtCtrlACK, s.ackNo, cif)
+	s.ackedSeq = s.nextSeq
+}
+
+func (s *srtSession) sendNAK(ranges [][2]uint32) {
+	if len(ranges) == 0 {
+		return
+	}
+	var cif []byte
+	for _, r := range ranges {
+		if r[0] == r[1] {
+			cif = binary.BigEndian.AppendUint32(cif, r[0])
+		} else {
+			cif = binary.BigEndian.AppendUint32(cif, r[0]|0x80000000)
+			cif = binary.BigEndian.AppendUint32(cif, r[1])
+		}
+	}
+	s.sendControl(srtCtrlNAK, 0, cif)
+	s.lastNAK = time.Now()
+}
+
+func (s *srtSession) sendControl(typ uint16, typeSpecific uint32, cif []byte) {
+	s.send(srtControlPacket(typ, typeSpecific, uint32(time.Since(s.start)/time.Microsecond), s.peerID, cif))
+}
+
+func (s *srtSession) send(pkt []byte) {
+	_, _ = s.conn.WriteToUDP(pkt, s.addr)
+	s.lastSent = time.Now()
+}
+
+func isSRTControl(pkt []byte) bool {
+	return pkt[0]&0x80 != 0
+}
+
+func srtControlType(pkt []byte) uint16 {
+	return binary.BigEndian.Uint16(pkt[0:2]) & 0x7FFF
+}
+
+func srtControlPacket(typ uint16, typeSpecific, timestamp, dest uint32, cif []byte) []byte {
+	b := make([]byte, srtHeaderSize, srtHeaderSize+len(cif))
+	binary.BigEndian.PutUint16(b[0:2], 0x8000|typ)
+	binary.BigEndian.PutUint32(b[4:8], typeSpecific)
+	binary.BigEndian.PutUint32(b[8:12], timestamp)
+	binary.BigEndian.PutUint32(b[12:16], dest)
+	return append(b, cif...)
+}
+
+func writeSRTControl(conn *net.UDPConn, addr *net.UDPAddr, typ uint16, typeSpecific, timestamp, dest uint32, cif []byte) {
+	_, _ = conn.WriteToUDP(srtControlPacket(typ, typeSpecific, timestamp, dest, cif), addr)
+}
+
+// srtSeqDiff is a-b in 31-bit sequence space.
+func srtSeqDiff(a, b uint32) int32 {
+	return int32((a-b)<<1) >> 1
+}
+
+func srtSeqAdd(seq uint32, n int32) uint32 {
+	return (seq + uint32(n)) & srtSeqMask
+}
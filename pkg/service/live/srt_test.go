@@ -0,0 +1,192 @@
+package live
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const testSRTInitSeq = 1000
+
+// testSRTCaller is a minimal SRT caller speaking the v5 handshake.
+type testSRTCaller struct {
+	t        *testing.T
+	conn     *net.UDPConn
+	socketID uint32
+	peerID   uint32
+}
+
+func dialSRT(t *testing.T, addr string) *testSRTCaller {
+	t.Helper()
+	raddr, err := net.ResolveUDPAddr("udp", addr)
+	require.NoError(t, err)
+	conn, err := net.DialUDP("udp", nil, raddr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return &testSRTCaller{t: t, conn: conn, socketID: 0x1234}
+}
+
+func (c *testSRTCaller) write(pkt []byte) {
+	_, err := c.conn.Write(pkt)
+	require.NoError(c.t, err)
+}
+
+// read returns the next packet, or nil after timeout.
+func (c *testSRTCaller) read(timeout time.Duration) []byte {
+	buf := make([]byte, srtMaxPacketSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(timeout))
+	n, err := c.conn.Read(buf)
+	if err != nil {
+		return nil
+	}
+	return buf[:n]
+}
+
+// expectControl reads until a control packet of typ arrives.
+func (c *testSRTCaller) expectControl(typ uint16) []byte {
+	c.t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		pkt := c.read(time.Until(deadline))
+		if pkt != nil && isSRTControl(pkt) && srtControlType(pkt) == typ {
+			return pkt
+		}
+	}
+	c.t.Fatalf("no control packet of type %d", typ)
+	return nil
+}
+
+func (c *testSRTCaller) handshake(streamID string) srtHandshake {
+	c.t.Helper()
+	induction := srtHandshake{version: 4, extFlags: 2, initSeq: testSRTInitSeq, mtu: 1500, flowWindow: 8192, hsType: srtHSInduction, socketID: c.socketID}
+	c.write(srtControlPacket(srtCtrlHandshake, 0, 0, 0, induction.marshal()))
+	pkt := c.expectControl(srtCtrlHandshake)
+	resp, ok := parseSRTHandshake(pkt[srtHeaderSize:])
+	require.True(c.t, ok)
+	require.Equal(c.t, uint16(srtMagicCode), resp.extFlags)
+
+	hsreq := make([]byte, 16)
+	binary.BigEndian.PutUint16(hsreq[0:2], srtExtHSReq)
+	binary.BigEndian.PutUint16(hsreq[2:4], 3)
+	binary.BigEndian.PutUint32(hsreq[4:8], srtLibVersion)
+	binary.BigEndian.PutUint16(hsreq[14:16], 20)
+	sid := make([]byte, (len(streamID)+3)/4*4)
+	copy(sid, streamID)
+	sidExt := make([]byte, 4)
+	binary.BigEndian.PutUint16(sidExt[0:2], srtExtSID)
+	binary.BigEndian.PutUint16(sidExt[2:4], uint16(len(sid)/4))
+
+	conclusion := srtHandshake{
+		version: srtVersion, extFlags: srtHSExtHSReq | 0x4, initSeq: testSRTInitSeq, mtu: 1500, flowWindow: 8192,
+		hsType: srtHSConclusion, socketID: c.socketID, cookie: resp.cookie,
+		exts: append(append(hsreq, sidExt...), swapSRTWords(sid)...),
+	}
+	c.write(srtControlPacket(srtCtrlHandshake, 0, 0, 0, conclusion.marshal()))
+	pkt = c.expectControl(srtCtrlHandshake)
+	resp, ok = parseSRTHandshake(pkt[srtHeaderSize:])
+	require.True(c.t, ok)
+	c.peerID = resp.socketID
+	return resp
+}
+
+func (c *testSRTCaller) sendData(seq uint32, payload string) {
+	pkt := make([]byte, srtHeaderSize, srtHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(pkt[0:4], seq)
+	binary.BigEndian.PutUint32(pkt[4:8], 0xE0000000) // solo packet, in order
+	binary.BigEndian.PutUint32(pkt[12:16], c.peerID)
+	c.write(append(pkt, payload...))
+}
+
+func startTestSRTServer(t *testing.T, svc *LiveService) string {
+	t.Helper()
+	srv := NewSRTServer("127.0.0.1:0", svc, zap.NewNop())
+	require.NoError(t, srv.Start())
+	t.Cleanup(func() { _ = srv.Close() })
+	return srv.Addr().String()
+}
+
+func TestSRTServer_Publish(t *testing.T) {
+	packager := newFakePackager()
+	svc := NewLiveService(packager, zap.NewNop())
+	stream, key, err := svc.CreateStream(testWallet, "")
+	require.NoError(t, err)
+	caller := dialSRT(t, startTestSRTServer(t, svc))
+
+	resp := caller.handshake("#!::r=" + key + ",m=publish")
+	require.Equal(t, uint32(srtHSConclusion), resp.hsType)
+	require.GreaterOrEqual(t, len(resp.exts), 16)
+	assert.Equal(t, uint16(srtExtHSRsp), binary.BigEndian.Uint16(resp.exts[0:2]))
+	assert.Equal(t, uint16(srtDefaultLatency/time.Millisecond), binary.BigEndian.Uint16(resp.exts[12:14]))
+
+	got, _ := svc.GetStream(stream.ID)
+	assert.Equal(t, StreamLive, got.State)
+	assert.Equal(t, "srt", got.Protocol)
+
+	// Packet 1 arrives before 0: it is held back and 0 is reported lost.
+	caller.sendData(testSRTInitSeq+1, "B")
+	nak := caller.expectControl(srtCtrlNAK)
+	assert.Equal(t, []byte{0, 0, 0x03, 0xE8}, nak[srtHeaderSize:])
+	caller.sendData(testSRTInitSeq+3, "D")
+	nak = caller.expectControl(srtCtrlNAK)
+	assert.Equal(t, []byte{0, 0, 0x03, 0xEA}, nak[srtHeaderSize:])
+	caller.sendData(testSRTInitSeq, "A")
+	require.Eventually(t, func() bool {
+		return string(packager.sink(stream.ID).Bytes()) == "AB"
+	}, 2*time.Second, 5*time.Millisecond)
+
+	// Packet 2 never comes: it is skipped once the latency passes.
+	require.Eventually(t, func() bool {
+		return string(packager.sink(stream.ID).Bytes()) == "ABD"
+	}, 2*time.Second, 10*time.Millisecond)
+	ack := caller.expectControl(srtCtrlACK)
+	assert.GreaterOrEqual(t, binary.BigEndian.Uint32(ack[srtHeaderSize:]), uint32(testSRTInitSeq+2))
+
+	// Stopping the stream shuts the caller down.
+	_, err = svc.StopStream(stream.ID, testWallet)
+	require.NoError(t, err)
+	caller.expectControl(srtCtrlShutdown)
+	assert.True(t, packager.sink(stream.ID).Closed())
+}
+
+func TestSRTServer_Rejects(t *testing.T) {
+	svc := NewLiveService(newFakePackager(), zap.NewNop())
+	_, key, err := svc.CreateStream(testWallet, "")
+	require.NoError(t, err)
+	addr := startTestSRTServer(t, svc)
+
+	resp := dialSRT(t, addr).handshake("live_unknown")
+	assert.Equal(t, uint32(srtRejectUnauthorized), resp.hsType)
+
+	resp = dialSRT(t, addr).handshake("#!::r=" + key + ",m=request")
+	assert.Equal(t, uint32(srtRejectBadRequest), resp.hsType)
+
+	resp = dialSRT(t, addr).handshake(key)
+	require.Equal(t, uint32(srtHSConclusion), resp.hsType)
+	resp = dialSRT(t, addr).handshake(key)
+	assert.Equal(t, uint32(srtRejectConflict), resp.hsType, "the stream already has a publisher")
+}
+
+func TestSRTServer_ShutdownReturnsStreamToIdle(t *testing.T) {
+	svc := NewLiveService(newFakePackager(), zap.NewNop())
+	stream, key, err := svc.CreateStream(testWallet, "")
+	require.NoError(t, err)
+	caller := dialSRT(t, startTestSRTServer(t, svc))
+	caller.handshake(key)
+
+	caller.write(srtControlPacket(srtCtrlShutdown, 0, 0, caller.peerID, nil))
+	require.Eventually(t, func() bool {
+		got, _ := svc.GetStream(stream.ID)
+		return got.State == StreamIdle
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestSRTSeqDiff(t *testing.T) {
+	assert.Equal(t, int32(1), srtSeqDiff(0, srtSeqMask), "sequence numbers wrap at 2^31")
+	assert.Equal(t, int32(-1), srtSeqDiff(srtSeqMask, 0))
+	assert.Equal(t, uint32(0), srtSeqAdd(srtSeqMask, 1))
+}
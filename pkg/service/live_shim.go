@@ -0,0 +1,30 @@
+package service
+
+import "github.com/rtcdance/streamgate/pkg/service/live"
+
+type (
+	LiveService      = live.LiveService
+	LiveStream       = live.Stream
+	LiveStreamState  = live.StreamState
+	LivePackager     = live.Packager
+	LiveInputFormat  = live.InputFormat
+	LiveFFmpegConfig = live.FFmpegConfig
+	RTMPServer       = live.RTMPServer
+	SRTServer        = live.SRTServer
+)
+
+var (
+	NewLiveService        = live.NewLiveService
+	NewLiveFFmpegPackager = live.NewFFmpegPackager
+	NewRTMPServer         = live.NewRTMPServer
+	NewSRTServer          = live.NewSRTServer
+
+	ErrLiveStreamNotFound   = live.ErrStreamNotFound
+	ErrNotLiveStreamOwner   = live.ErrNotStreamOwner
+	ErrLiveStreamEnded      = live.ErrStreamEnded
+	ErrTooManyLiveStreams   = live.ErrTooManyStreams
+	ErrLivePlaylistNotReady = live.ErrPlaylistNotReady
+	ErrInvalidLiveSegment   = live.ErrInvalidSegment
+	ErrLiveStreamActive     = live.ErrStreamActive
+	ErrInvalidLiveStreamKey = live.ErrInvalidStreamKey
+)
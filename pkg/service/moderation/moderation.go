@@ -0,0 +1,339 @@
+package moderation
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/service/serviceerrors"
+	"github.com/rtcdance/streamgate/pkg/storage"
+
+	"go.uber.org/zap"
+)
+
+// Moderation states, kept in contents.metadata under moderation_status.
+// Content without one predates moderation or was uploaded with it off.
+const (
+	StatusPendingReview = "pending_review"
+	StatusApproved      = "approved"
+	StatusRejected      = "rejected"
+)
+
+// Decisions a moderator can make on pending content.
+const (
+	DecisionApprove = "approve"
+	DecisionReject  = "reject"
+)
+
+// autoReviewer is recorded as the reviewer of content approved without a
+// moderator.
+const autoReviewer = "auto"
+
+// Config tunes sampling and the automatic decision.
+type Config struct {
+	// FrameInterval is the time between sampled frames.
+	FrameInterval time.Duration
+	// MaxFrames caps the frames sent to the provider per upload.
+	MaxFrames int
+	// Threshold is the label score at or above which content is flagged.
+	Threshold float64
+	// AutoApprove approves content the provider does not flag. Without it
+	// every upload waits for a moderator.
+	AutoApprove bool
+	// Timeout bounds sampling and the provider call for one upload.
+	Timeout time.Duration
+}
+
+// Record is the moderation state of one content item.
+type Record struct {
+	ContentID string    `json:"content_id"`
+	Title     string    `json:"title,omitempty"`
+	OwnerID   string    `json:"owner_id,omitempty"`
+	Status    string    `json:"status"`
+	Flagged   bool      `json:"flagged"`
+	Labels    []Label   `json:"labels,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Reviewer  string    `json:"reviewer,omitempty"`
+	Note      string    `json:"note,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// metadataState is the moderation part of contents.metadata.
+type metadataState struct {
+	Status   string  `json:"moderation_status"`
+	Flagged  bool    `json:"moderation_flagged"`
+	Labels   []Label `json:"moderation_labels"`
+	Error    string  `json:"moderation_error"`
+	Reviewer string  `json:"moderation_reviewer"`
+	Note     string  `json:"moderation_note"`
+}
+
+// Service samples frames from new uploads, has a provider label them and
+// holds the content in pending_review until it is approved.
+type Service struct {
+	db       storage.DB
+	provider Provider
+	sampler  FrameSampler
+	notifier *WebhookNotifier
+	cfg      Config
+	logger   *zap.Logger
+}
+
+// NewModerationService returns a service that samples with sampler and
+// labels frames with provider.
+func NewModerationService(db storage.DB, provider Provider, sampler FrameSampler, cfg Config, logger *zap.Logger) *Service {
+	if cfg.MaxFrames <= 0 {
+		cfg.MaxFrames = 10
+	}
+	if cfg.FrameInterval <= 0 {
+		cfg.FrameInterval = 10 * time.Second
+	}
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = 0.8
+	}
+	return &Service{db: db, provider: provider, sampler: sampler, cfg: cfg, logger: logger}
+}
+
+// SetNotifier makes the service call moderator webhooks when content needs
+// review and when it is decided.
+func (s *Service) SetNotifier(n *WebhookNotifier) {
+	s.notifier = n
+}
+
+// Submit puts new content in pending_review, samples frames from inputURL
+// and has the provider label them. Unflagged content is approved when
+// AutoApprove is set; anything else stays pending and moderators are
+// notified. When sampling or the provider fails the content stays pending
+// for a moderator and the error is returned.
+func (s *Service) Submit(ctx context.Context, contentID, inputURL string) (*Record, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+	if err := s.update(ctx, contentID, metadataState{Status: StatusPendingReview}); err != nil {
+		return nil, err
+	}
+
+	modCtx := ctx
+	if s.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		modCtx, cancel = context.WithTimeout(ctx, s.cfg.Timeout)
+		defer cancel()
+	}
+	labels, modErr := s.moderate(modCtx, inputURL)
+	state := metadataState{Status: StatusPendingReview, Labels: labels}
+	if modErr != nil {
+		state.Error = modErr.Error()
+	} else {
+		state.Flagged = s.flagged(labels)
+		if !state.Flagged && s.cfg.AutoApprove {
+			state.Status = StatusApproved
+			state.Reviewer = autoReviewer
+		}
+	}
+	// A cancelled sampling run must still leave its result behind.
+	if err := s.update(context.WithoutCancel(ctx), contentID, state); err != nil {
+		return nil, err
+	}
+
+	rec := s.record(contentID, state)
+	if state.Status == StatusPendingReview {
+		s.logger.Info("Content held for review",
+			zap.String("content_id", contentID),
+			zap.Bool("flagged", state.Flagged),
+			zap.String("error", state.Error))
+		s.notify(ctx, EventReviewRequired, rec)
+	}
+	if modErr != nil {
+		return rec, fmt.Errorf("moderation of %s failed: %w", contentID, modErr)
+	}
+	return rec, nil
+}
+
+func (s *Service) moderate(ctx context.Context, inputURL string) ([]Label, error) {
+	if inputURL == "" {
+		return nil, fmt.Errorf("no input to sample")
+	}
+	frames, err := s.sampler.Sample(ctx, inputURL, s.cfg.FrameInterval, s.cfg.MaxFrames)
+	if err != nil {
+		return nil, fmt.Errorf("sample frames: %w", err)
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("sample frames: no frames extracted")
+	}
+	labels, err := s.provider.Moderate(ctx, frames)
+	if err != nil {
+		return nil, fmt.Errorf("provider: %w", err)
+	}
+	return labels, nil
+}
+
+func (s *Service) flagged(labels []Label) bool {
+	for _, l := range labels {
+		if l.Score >= s.cfg.Threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// Review records a moderator's decision on content and notifies the
+// webhooks. Decided content can be reviewed again, e.g. to take down
+// something approved automatically.
+func (s *Service) Review(ctx context.Context, contentID, decision, reviewer, note string) (*Record, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+	var status string
+	switch decision {
+	case DecisionApprove:
+		status = StatusApproved
+	case DecisionReject:
+		status = StatusRejected
+	default:
+		return nil, fmt.Errorf("decision must be %q or %q: %w", DecisionApprove, DecisionReject, serviceerrors.ErrInvalidRequest)
+	}
+
+	rec, err := s.Get(ctx, contentID)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Status == "" {
+		return nil, fmt.Errorf("content %s was not submitted for moderation: %w", contentID, serviceerrors.ErrInvalidRequest)
+	}
+	state := metadataState{
+		Status:   status,
+		Flagged:  rec.Flagged,
+		Labels:   rec.Labels,
+		Error:    rec.Error,
+		Reviewer: reviewer,
+		Note:     note,
+	}
+	if err := s.update(ctx, contentID, state); err != nil {
+		return nil, err
+	}
+
+	updated := s.record(contentID, state)
+	updated.Title, updated.OwnerID = rec.Title, rec.OwnerID
+	s.notify(ctx, EventDecided, updated)
+	return updated, nil
+}
+
+// Get returns the moderation state of content. Status is empty for
+// content that was never submitted.
+func (s *Service) Get(ctx context.Context, contentID string) (*Record, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+	row := s.db.QueryRow(ctx, `SELECT id, title, owner_id, metadata, updated_at FROM contents WHERE id = $1`, contentID)
+	rec, err := scanRecord(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("content not found %s: %w", contentID, serviceerrors.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query moderation state: %w", err)
+	}
+	return rec, nil
+}
+
+// ListPending returns content waiting for a moderator, oldest first.
+func (s *Service) ListPending(ctx context.Context, limit, offset int) ([]*Record, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+	query := `
+		SELECT id, title, owner_id, metadata, updated_at
+		FROM contents
+		WHERE metadata->>'moderation_status' = $1
+		ORDER BY updated_at ASC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := s.db.Query(ctx, query, StatusPendingReview, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending content: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var recs []*Record
+	for rows.Next() {
+		rec, err := scanRecord(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan pending content: %w", err)
+		}
+		recs = append(recs, rec)
+	}
+	return recs, rows.Err()
+}
+
+// update merges state into the content's metadata.
+func (s *Service) update(ctx context.Context, contentID string, state metadataState) error {
+	patch, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode moderation state: %w", err)
+	}
+	result, err := s.db.Exec(ctx,
+		`UPDATE contents SET metadata = COALESCE(metadata, '{}'::jsonb) || $2::jsonb, updated_at = $3 WHERE id = $1`,
+		contentID, string(patch), time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to update moderation state: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("content not found %s: %w", contentID, serviceerrors.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *Service) record(contentID string, state metadataState) *Record {
+	return &Record{
+		ContentID: contentID,
+		Status:    state.Status,
+		Flagged:   state.Flagged,
+		Labels:    state.Labels,
+		Error:     state.Error,
+		Reviewer:  state.Reviewer,
+		Note:      state.Note,
+		UpdatedAt: time.Now(),
+	}
+}
+
+func (s *Service) notify(ctx context.Context, eventName string, rec *Record) {
+	if s.notifier == nil {
+		return
+	}
+	if err := s.notifier.Notify(context.WithoutCancel(ctx), eventName, rec); err != nil {
+		s.logger.Warn("Failed to notify moderation webhook",
+			zap.String("event", eventName),
+			zap.String("content_id", rec.ContentID),
+			zap.Error(err))
+	}
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRecord(row scanner) (*Record, error) {
+	var rec Record
+	var title, ownerID sql.NullString
+	var metadataJSON []byte
+	if err := row.Scan(&rec.ContentID, &title, &ownerID, &metadataJSON, &rec.UpdatedAt); err != nil {
+		return nil, err
+	}
+	rec.Title = title.String
+	rec.OwnerID = ownerID.String
+	if len(metadataJSON) > 0 {
+		var state metadataState
+		if err := json.Unmarshal(metadataJSON, &state); err != nil {
+			return nil, fmt.Errorf("failed to parse metadata: %w", err)
+		}
+		rec.Status = state.Status
+		rec.Flagged = state.Flagged
+		rec.Labels = state.Labels
+		rec.Error = state.Error
+		rec.Reviewer = state.Reviewer
+		rec.Note = state.Note
+	}
+	return &rec, nil
+}
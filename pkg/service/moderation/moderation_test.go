@@ -0,0 +1,338 @@
+package moderation
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/service/serviceerrors"
+	stg "github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// contentDB keeps the metadata of content rows in memory and applies the
+// service's jsonb merges to it.
+type contentDB struct {
+	mu       sync.Mutex
+	metadata map[string]map[string]interface{}
+}
+
+func newContentDB(ids ...string) *contentDB {
+	db := &contentDB{metadata: make(map[string]map[string]interface{})}
+	for _, id := range ids {
+		db.metadata[id] = map[string]interface{}{"codec": "h264"}
+	}
+	return db
+}
+
+func (m *contentDB) Query(_ context.Context, _ string, args ...interface{}) (stg.Rows, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rows := &memRows{}
+	for id, md := range m.metadata {
+		if md["moderation_status"] == args[0] {
+			rows.rows = append(rows.rows, m.row(id))
+		}
+	}
+	return rows, nil
+}
+
+func (m *contentDB) QueryRow(_ context.Context, _ string, args ...interface{}) *stg.CancelRow {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := args[0].(string)
+	if _, ok := m.metadata[id]; !ok {
+		return stg.NewErrorCancelRow(sql.ErrNoRows)
+	}
+	return stg.NewTestCancelRow(&memRow{vals: m.row(id)})
+}
+
+func (m *contentDB) row(id string) []interface{} {
+	md, _ := json.Marshal(m.metadata[id])
+	return []interface{}{id, "Title " + id, "0xowner", md, time.Now()}
+}
+
+func (m *contentDB) Exec(_ context.Context, _ string, args ...interface{}) (sql.Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	md, ok := m.metadata[args[0].(string)]
+	if !ok {
+		return result(0), nil
+	}
+	var patch map[string]interface{}
+	if err := json.Unmarshal([]byte(args[1].(string)), &patch); err != nil {
+		return nil, err
+	}
+	for k, v := range patch {
+		md[k] = v
+	}
+	return result(1), nil
+}
+
+func (m *contentDB) status(id string) interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.metadata[id]["moderation_status"]
+}
+
+func (m *contentDB) Begin(context.Context) (*sql.Tx, error) {
+	return nil, errors.New("not implemented")
+}
+func (m *contentDB) InTransaction(context.Context, func(tx *sql.Tx) error) error {
+	return errors.New("not implemented")
+}
+func (m *contentDB) Ping(context.Context) error { return nil }
+func (m *contentDB) Close() error               { return nil }
+
+type result int64
+
+func (r result) LastInsertId() (int64, error) { return 0, nil }
+func (r result) RowsAffected() (int64, error) { return int64(r), nil }
+
+type memRow struct{ vals []interface{} }
+
+func (r *memRow) Scan(dest ...interface{}) error {
+	for i, d := range dest {
+		switch p := d.(type) {
+		case *string:
+			*p = r.vals[i].(string)
+		case *sql.NullString:
+			*p = sql.NullString{String: r.vals[i].(string), Valid: true}
+		case *[]byte:
+			*p = r.vals[i].([]byte)
+		case *time.Time:
+			*p = r.vals[i].(time.Time)
+		}
+	}
+	return nil
+}
+
+type memRows struct {
+	rows [][]interface{}
+	i    int
+}
+
+func (r *memRows) Next() bool { r.i++; return r.i <= len(r.rows) }
+func (r *memRows) Scan(dest ...interface{}) error {
+	return (&memRow{vals: r.rows[r.i-1]}).Scan(dest...)
+}
+func (r *memRows) Close() error { return nil }
+func (r *memRows) Err() error   { return nil }
+
+type stubSampler struct {
+	frames []Frame
+	err    error
+}
+
+func (s *stubSampler) Sample(context.Context, string, time.Duration, int) ([]Frame, error) {
+	return s.frames, s.err
+}
+
+type stubProvider struct {
+	labels []Label
+	err    error
+}
+
+func (p *stubProvider) Moderate(context.Context, []Frame) ([]Label, error) {
+	return p.labels, p.err
+}
+
+// webhookRecorder collects the events POSTed to it.
+func webhookRecorder(t *testing.T, secret string) (*httptest.Server, func() []WebhookPayload) {
+	t.Helper()
+	var mu sync.Mutex
+	var got []WebhookPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if secret != "" && r.Header.Get(SignatureHeader) != Sign([]byte(secret), body) {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		var p WebhookPayload
+		_ = json.Unmarshal(body, &p)
+		mu.Lock()
+		got = append(got, p)
+		mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []WebhookPayload {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]WebhookPayload(nil), got...)
+	}
+}
+
+func TestSubmit(t *testing.T) {
+	frames := []Frame{{Data: []byte("jpeg")}}
+	tests := []struct {
+		name        string
+		sampler     *stubSampler
+		provider    *stubProvider
+		autoApprove bool
+		wantStatus  string
+		wantFlagged bool
+		wantErr     bool
+		wantWebhook bool
+	}{
+		{name: "clean auto-approved", sampler: &stubSampler{frames: frames},
+			provider: &stubProvider{labels: []Label{{Name: "violence", Score: 0.1}}}, autoApprove: true,
+			wantStatus: StatusApproved},
+		{name: "clean held without auto-approve", sampler: &stubSampler{frames: frames},
+			provider:   &stubProvider{labels: []Label{{Name: "violence", Score: 0.1}}},
+			wantStatus: StatusPendingReview, wantWebhook: true},
+		{name: "flagged", sampler: &stubSampler{frames: frames},
+			provider: &stubProvider{labels: []Label{{Name: "nudity", Score: 0.95}}}, autoApprove: true,
+			wantStatus: StatusPendingReview, wantFlagged: true, wantWebhook: true},
+		{name: "provider down", sampler: &stubSampler{frames: frames},
+			provider: &stubProvider{err: errors.New("connection refused")}, autoApprove: true,
+			wantStatus: StatusPendingReview, wantErr: true, wantWebhook: true},
+		{name: "no frames", sampler: &stubSampler{}, provider: &stubProvider{}, autoApprove: true,
+			wantStatus: StatusPendingReview, wantErr: true, wantWebhook: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newContentDB("content-1")
+			srv, received := webhookRecorder(t, "s3cret")
+			svc := NewModerationService(db, tt.provider, tt.sampler, Config{AutoApprove: tt.autoApprove}, zap.NewNop())
+			svc.SetNotifier(NewWebhookNotifier([]string{srv.URL}, "s3cret"))
+
+			rec, err := svc.Submit(context.Background(), "content-1", "https://s3/video.mp4")
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.NotNil(t, rec)
+			assert.Equal(t, tt.wantStatus, rec.Status)
+			assert.Equal(t, tt.wantFlagged, rec.Flagged)
+			assert.Equal(t, tt.wantStatus, db.status("content-1"))
+			assert.Equal(t, "h264", db.metadata["content-1"]["codec"], "other metadata is kept")
+
+			events := received()
+			if tt.wantWebhook {
+				require.Len(t, events, 1)
+				assert.Equal(t, EventReviewRequired, events[0].Event)
+				assert.Equal(t, "content-1", events[0].Content.ContentID)
+			} else {
+				assert.Empty(t, events)
+			}
+		})
+	}
+}
+
+func TestSubmit_UnknownContent(t *testing.T) {
+	svc := NewModerationService(newContentDB(), &stubProvider{}, &stubSampler{}, Config{}, zap.NewNop())
+	_, err := svc.Submit(context.Background(), "missing", "https://s3/video.mp4")
+	assert.ErrorIs(t, err, serviceerrors.ErrNotFound)
+}
+
+func TestReview(t *testing.T) {
+	tests := []struct {
+		name       string
+		contentID  string
+		decision   string
+		wantStatus string
+		wantErr    error
+	}{
+		{name: "approve", contentID: "content-1", decision: DecisionApprove, wantStatus: StatusApproved},
+		{name: "reject", contentID: "content-1", decision: DecisionReject, wantStatus: StatusRejected},
+		{name: "bad decision", contentID: "content-1", decision: "maybe", wantErr: serviceerrors.ErrInvalidRequest},
+		{name: "never submitted", contentID: "content-2", decision: DecisionApprove, wantErr: serviceerrors.ErrInvalidRequest},
+		{name: "unknown content", contentID: "missing", decision: DecisionApprove, wantErr: serviceerrors.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newContentDB("content-1", "content-2")
+			srv, received := webhookRecorder(t, "")
+			svc := NewModerationService(db, &stubProvider{labels: []Label{{Name: "nudity", Score: 0.9}}},
+				&stubSampler{frames: []Frame{{Data: []byte("jpeg")}}}, Config{}, zap.NewNop())
+			_, err := svc.Submit(context.Background(), "content-1", "https://s3/video.mp4")
+			require.NoError(t, err)
+			svc.SetNotifier(NewWebhookNotifier([]string{srv.URL}, ""))
+
+			rec, err := svc.Review(context.Background(), tt.contentID, tt.decision, "0xadmin", "checked")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, received())
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, rec.Status)
+			assert.Equal(t, "0xadmin", rec.Reviewer)
+			assert.True(t, rec.Flagged, "provider findings are kept")
+
+			got, err := svc.Get(context.Background(), tt.contentID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, got.Status)
+			assert.Equal(t, "checked", got.Note)
+
+			events := received()
+			require.Len(t, events, 1)
+			assert.Equal(t, EventDecided, events[0].Event)
+			assert.Equal(t, tt.wantStatus, events[0].Content.Status)
+		})
+	}
+}
+
+func TestListPending(t *testing.T) {
+	db := newContentDB("content-1", "content-2", "content-3")
+	svc := NewModerationService(db, &stubProvider{labels: []Label{{Name: "nudity", Score: 0.9}}},
+		&stubSampler{frames: []Frame{{Data: []byte("jpeg")}}}, Config{}, zap.NewNop())
+	for _, id := range []string{"content-1", "content-2"} {
+		_, err := svc.Submit(context.Background(), id, "https://s3/"+id)
+		require.NoError(t, err)
+	}
+	_, err := svc.Review(context.Background(), "content-2", DecisionApprove, "0xadmin", "")
+	require.NoError(t, err)
+
+	pending, err := svc.ListPending(context.Background(), 10, 0)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "content-1", pending[0].ContentID)
+	assert.Equal(t, []Label{{Name: "nudity", Score: 0.9}}, pending[0].Labels)
+}
+
+func TestHTTPProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			Frames []providerFrame `json:"frames"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Len(t, req.Frames, 2)
+		assert.Equal(t, int64(10000), req.Frames[1].OffsetMS)
+		assert.Equal(t, []byte("frame-2"), req.Frames[1].Image)
+		_, _ = w.Write([]byte(`{"labels":[{"name":"violence","score":0.42}]}`))
+	}))
+	defer srv.Close()
+
+	frames := []Frame{{Data: []byte("frame-1")}, {Offset: 10 * time.Second, Data: []byte("frame-2")}}
+	labels, err := NewHTTPProvider(srv.URL, "key", time.Second).Moderate(context.Background(), frames)
+	require.NoError(t, err)
+	assert.Equal(t, []Label{{Name: "violence", Score: 0.42}}, labels)
+
+	_, err = NewHTTPProvider(srv.URL, "wrong", time.Second).Moderate(context.Background(), frames)
+	assert.ErrorContains(t, err, "401")
+}
+
+func TestSampleArgs(t *testing.T) {
+	args := strings.Join(sampleArgs("https://s3/video.mp4", "/tmp/frames", 2500*time.Millisecond, 8), " ")
+	assert.Contains(t, args, "-i https://s3/video.mp4")
+	assert.Contains(t, args, "fps=1/2.5,scale=640:-2")
+	assert.Contains(t, args, "-frames:v 8")
+	assert.Contains(t, args, "/tmp/frames/frame_%03d.jpg")
+}
@@ -0,0 +1,95 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Frame is one still sampled from a video.
+type Frame struct {
+	// Offset is the frame's position in the video.
+	Offset time.Duration
+	// Data is the JPEG-encoded frame.
+	Data []byte
+}
+
+// Label is a category a provider found, e.g. "nudity" or "violence", with
+// its confidence from 0 to 1.
+type Label struct {
+	Name  string  `json:"name"`
+	Score float64 `json:"score"`
+}
+
+// Provider labels sampled frames. Implementations may wrap a self-hosted
+// classifier or an external moderation API.
+type Provider interface {
+	Moderate(ctx context.Context, frames []Frame) ([]Label, error)
+}
+
+// HTTPProvider POSTs frames as JSON to a moderation endpoint:
+//
+//	{"frames": [{"offset_ms": 0, "image": "<base64 JPEG>"}, ...]}
+//
+// and expects {"labels": [{"name": "...", "score": 0.93}, ...]} back,
+// holding the highest score seen for each label. A self-hosted model only
+// needs a small adapter serving this protocol.
+type HTTPProvider struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+// NewHTTPProvider returns a provider for the endpoint at url. A non-empty
+// apiKey is sent as a bearer token.
+func NewHTTPProvider(url, apiKey string, timeout time.Duration) *HTTPProvider {
+	return &HTTPProvider{url: url, apiKey: apiKey, client: &http.Client{Timeout: timeout}}
+}
+
+type providerFrame struct {
+	OffsetMS int64  `json:"offset_ms"`
+	Image    []byte `json:"image"`
+}
+
+func (p *HTTPProvider) Moderate(ctx context.Context, frames []Frame) ([]Label, error) {
+	body := struct {
+		Frames []providerFrame `json:"frames"`
+	}{Frames: make([]providerFrame, len(frames))}
+	for i, f := range frames {
+		body.Frames[i] = providerFrame{OffsetMS: f.Offset.Milliseconds(), Image: f.Data}
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("moderation request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("moderation provider returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	var result struct {
+		Labels []Label `json:"labels"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode moderation response: %w", err)
+	}
+	return result.Labels, nil
+}
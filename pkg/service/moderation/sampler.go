@@ -0,0 +1,92 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FrameSampler extracts stills from a video.
+type FrameSampler interface {
+	Sample(ctx context.Context, inputURL string, interval time.Duration, maxFrames int) ([]Frame, error)
+}
+
+// FFmpegSampler samples frames with ffmpeg, which reads the input straight
+// from its (presigned) URL.
+type FFmpegSampler struct {
+	ffmpegPath string
+	tempDir    string
+}
+
+// NewFFmpegSampler returns a sampler running the ffmpeg at ffmpegPath,
+// "ffmpeg" when empty.
+func NewFFmpegSampler(ffmpegPath string) *FFmpegSampler {
+	if ffmpegPath == "" {
+		ffmpegPath = "ffmpeg"
+	}
+	return &FFmpegSampler{ffmpegPath: ffmpegPath, tempDir: os.TempDir()}
+}
+
+// sampleWidth is the width frames are scaled to; classifiers work on far
+// smaller images and it keeps provider requests small.
+const sampleWidth = 640
+
+func (f *FFmpegSampler) Sample(ctx context.Context, inputURL string, interval time.Duration, maxFrames int) ([]Frame, error) {
+	dir, err := os.MkdirTemp(f.tempDir, "streamgate-moderation-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	// #nosec G204 -- fixed binary, arguments are not shell-interpreted
+	output, err := exec.CommandContext(ctx, f.ffmpegPath, sampleArgs(inputURL, dir, interval, maxFrames)...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg: %w: %s", err, lastLine(output))
+	}
+	return readFrames(dir, interval)
+}
+
+// sampleArgs takes one frame at the start of every interval, up to
+// maxFrames, into dir/frame_NNN.jpg.
+func sampleArgs(inputURL, dir string, interval time.Duration, maxFrames int) []string {
+	return []string{
+		"-hide_banner", "-loglevel", "error",
+		"-i", inputURL,
+		"-vf", fmt.Sprintf("fps=1/%s,scale=%d:-2", strconv.FormatFloat(interval.Seconds(), 'f', -1, 64), sampleWidth),
+		"-frames:v", strconv.Itoa(maxFrames),
+		"-an",
+		"-q:v", "4",
+		"-start_number", "0",
+		"-y", filepath.Join(dir, "frame_%03d.jpg"),
+	}
+}
+
+// readFrames loads the frames ffmpeg wrote, in order; frame i was taken
+// at i*interval.
+func readFrames(dir string, interval time.Duration) ([]Frame, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "frame_*.jpg"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	frames := make([]Frame, 0, len(names))
+	for i, name := range names {
+		data, err := os.ReadFile(name) // #nosec G304 -- path from our temp dir
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, Frame{Offset: time.Duration(i) * interval, Data: data})
+	}
+	return frames, nil
+}
+
+func lastLine(output []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	return lines[len(lines)-1]
+}
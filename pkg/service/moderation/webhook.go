@@ -0,0 +1,91 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Webhook events sent to moderators.
+const (
+	EventReviewRequired = "moderation.review_required"
+	EventDecided        = "moderation.decided"
+)
+
+// SignatureHeader carries "sha256=<hex HMAC-SHA256 of the body>" when the
+// notifier has a secret.
+const SignatureHeader = "X-StreamGate-Signature"
+
+// WebhookPayload is the JSON body POSTed to moderator webhooks.
+type WebhookPayload struct {
+	Event     string  `json:"event"`
+	Timestamp int64   `json:"timestamp"`
+	Content   *Record `json:"content"`
+}
+
+// WebhookNotifier POSTs moderation events to a fixed set of URLs.
+type WebhookNotifier struct {
+	urls   []string
+	secret []byte
+	client *http.Client
+}
+
+// NewWebhookNotifier returns a notifier for urls. With a non-empty secret
+// every body is signed so receivers can verify it came from us.
+func NewWebhookNotifier(urls []string, secret string) *WebhookNotifier {
+	return &WebhookNotifier{
+		urls:   urls,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Notify sends the event to every URL and returns the failures joined.
+func (n *WebhookNotifier) Notify(ctx context.Context, eventName string, rec *Record) error {
+	body, err := json.Marshal(WebhookPayload{Event: eventName, Timestamp: time.Now().Unix(), Content: rec})
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, u := range n.urls {
+		if err := n.post(ctx, u, eventName, body); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", u, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (n *WebhookNotifier) post(ctx context.Context, url, eventName string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-StreamGate-Event", eventName)
+	if len(n.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(n.secret, body))
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the SignatureHeader value for body.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/rtcdance/streamgate/pkg/storage"
+
+	"go.uber.org/zap"
+)
+
+// NewModerationServiceFromConfig builds the moderation service described
+// by cfg, or returns nil when moderation is disabled.
+func NewModerationServiceFromConfig(db storage.DB, cfg config.ModerationConfig, logger *zap.Logger) (*ModerationService, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if cfg.ProviderURL == "" {
+		return nil, fmt.Errorf("moderation.provider_url is required when moderation is enabled")
+	}
+	if cfg.Threshold < 0 || cfg.Threshold > 1 {
+		return nil, fmt.Errorf("moderation.threshold must be between 0 and 1, got %v", cfg.Threshold)
+	}
+	interval, err := parseModerationDuration("moderation.frame_interval", cfg.FrameInterval)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := parseModerationDuration("moderation.timeout", cfg.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	svc := NewModerationService(db,
+		NewHTTPModerationProvider(cfg.ProviderURL, cfg.APIKey, timeout),
+		NewFFmpegFrameSampler(cfg.FFmpegPath),
+		ModerationConfig{
+			FrameInterval: interval,
+			MaxFrames:     cfg.MaxFrames,
+			Threshold:     cfg.Threshold,
+			AutoApprove:   cfg.AutoApprove,
+			Timeout:       timeout,
+		},
+		logger)
+	if len(cfg.WebhookURLs) > 0 {
+		svc.SetNotifier(NewModerationWebhookNotifier(cfg.WebhookURLs, cfg.WebhookSecret))
+	}
+	return svc, nil
+}
+
+func parseModerationDuration(key, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s %q", key, value)
+	}
+	return d, nil
+}
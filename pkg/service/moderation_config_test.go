@@ -0,0 +1,47 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNewModerationServiceFromConfig(t *testing.T) {
+	enabled := config.ModerationConfig{Enabled: true, ProviderURL: "http://moderator/v1/frames", FrameInterval: "5s", Timeout: "2m", Threshold: 0.7}
+	tests := []struct {
+		name    string
+		mutate  func(c *config.ModerationConfig)
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "enabled"},
+		{name: "disabled", mutate: func(c *config.ModerationConfig) { c.Enabled = false }, wantNil: true},
+		{name: "no provider", mutate: func(c *config.ModerationConfig) { c.ProviderURL = "" }, wantErr: true},
+		{name: "bad interval", mutate: func(c *config.ModerationConfig) { c.FrameInterval = "often" }, wantErr: true},
+		{name: "bad timeout", mutate: func(c *config.ModerationConfig) { c.Timeout = "-1m" }, wantErr: true},
+		{name: "threshold out of range", mutate: func(c *config.ModerationConfig) { c.Threshold = 80 }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := enabled
+			if tt.mutate != nil {
+				tt.mutate(&cfg)
+			}
+			svc, err := NewModerationServiceFromConfig(nil, cfg, zap.NewNop())
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.wantNil {
+				assert.Nil(t, svc)
+			} else {
+				assert.NotNil(t, svc)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package service
+
+import "github.com/rtcdance/streamgate/pkg/service/moderation"
+
+type (
+	ModerationService  = moderation.Service
+	ModerationConfig   = moderation.Config
+	ModerationRecord   = moderation.Record
+	ModerationProvider = moderation.Provider
+	ModerationLabel    = moderation.Label
+	ModerationFrame    = moderation.Frame
+	FrameSampler       = moderation.FrameSampler
+)
+
+var (
+	NewModerationService         = moderation.NewModerationService
+	NewHTTPModerationProvider    = moderation.NewHTTPProvider
+	NewFFmpegFrameSampler        = moderation.NewFFmpegSampler
+	NewModerationWebhookNotifier = moderation.NewWebhookNotifier
+)
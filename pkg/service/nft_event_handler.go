@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"math/big"
 	"strings"
 	"sync"
 	"time"
@@ -10,11 +11,21 @@ import (
 	"github.com/rtcdance/streamgate/pkg/middleware"
 	"github.com/rtcdance/streamgate/pkg/web3/event"
 
+	"github.com/ethereum/go-ethereum/common"
 	"go.uber.org/zap"
 )
 
 const defaultInvalidationBatchWindow = 500 * time.Millisecond
 
+const zeroAddress = "0x0000000000000000000000000000000000000000"
+
+// pendingInvalidation is one token movement waiting for the next flush.
+type pendingInvalidation struct {
+	contractAddress string
+	tokenID         string
+	evt             *event.IndexedEvent
+}
+
 type NFTEventHandler struct {
 	nftService      *NFTService
 	middlewareCache middleware.NFTAccessCache
@@ -22,7 +33,7 @@ type NFTEventHandler struct {
 	logger          *zap.Logger
 
 	batchWindow          time.Duration
-	pendingInvalidations sync.Map
+	pendingInvalidations sync.Map // key -> pendingInvalidation
 	flushMu              sync.Mutex
 	flushTimer           *time.Timer
 	stopOnce             sync.Once
@@ -89,9 +100,37 @@ func (h *NFTEventHandler) HandleTransferSingle(ctx context.Context, evt *event.I
 	return nil
 }
 
+// HandleTransferBatch queues an invalidation for every token ID moved by an
+// ERC-1155 TransferBatch event.
+func (h *NFTEventHandler) HandleTransferBatch(ctx context.Context, evt *event.IndexedEvent) error {
+	contractAddress := evt.ContractAddress
+
+	tokenIDs := h.extractERC1155TokenIDs(evt)
+	if len(tokenIDs) == 0 {
+		h.logger.Warn("Failed to extract token IDs from TransferBatch event",
+			zap.String("tx_hash", evt.TransactionHash))
+		return nil
+	}
+
+	h.logger.Debug("TransferBatch event detected, queued for batched invalidation",
+		zap.String("contract", contractAddress),
+		zap.Strings("token_ids", tokenIDs),
+		zap.String("tx_hash", evt.TransactionHash))
+
+	for _, tokenID := range tokenIDs {
+		h.enqueueInvalidation(contractAddress, tokenID, evt)
+	}
+
+	return nil
+}
+
+// enqueueInvalidation records a token movement. Movements of the same token
+// between different wallets are kept apart so every wallet is invalidated.
 func (h *NFTEventHandler) enqueueInvalidation(contractAddress, tokenID string, evt *event.IndexedEvent) {
-	key := contractAddress + ":" + tokenID
-	h.pendingInvalidations.Store(key, evt)
+	tokenID = normalizeTokenID(tokenID)
+	from, to := h.extractAddresses(evt)
+	key := strings.Join([]string{contractAddress, tokenID, from, to}, ":")
+	h.pendingInvalidations.Store(key, pendingInvalidation{contractAddress: contractAddress, tokenID: tokenID, evt: evt})
 	h.scheduleFlush()
 }
 
@@ -109,28 +148,11 @@ func (h *NFTEventHandler) flushAll() {
 	h.flushTimer = nil
 	h.flushMu.Unlock()
 
-	type pending struct {
-		contractAddress string
-		tokenID         string
-		evt             *event.IndexedEvent
-	}
-	var entries []pending
+	var entries []pendingInvalidation
 	h.pendingInvalidations.Range(func(k, v any) bool {
-		key := k.(string)
-		parts := strings.SplitN(key, ":", 2)
-		if len(parts) != 2 {
-			h.pendingInvalidations.Delete(k)
-			return true
+		if entry, ok := v.(pendingInvalidation); ok {
+			entries = append(entries, entry)
 		}
-		var evt *event.IndexedEvent
-		if v != nil {
-			evt, _ = v.(*event.IndexedEvent)
-		}
-		entries = append(entries, pending{
-			contractAddress: parts[0],
-			tokenID:         parts[1],
-			evt:             evt,
-		})
 		h.pendingInvalidations.Delete(k)
 		return true
 	})
@@ -162,20 +184,42 @@ func (h *NFTEventHandler) FlushNow() {
 	h.flushAll()
 }
 
+// invalidateMiddlewareCache drops every cached access result of the sending
+// and receiving wallets for the contract: the entry for the moved token and
+// the balance-only entries, whose key has an empty or collection token ID.
+// Cache keys hold lowercased addresses.
 func (h *NFTEventHandler) invalidateMiddlewareCache(ctx context.Context, contractAddress, tokenID string, evt *event.IndexedEvent) {
 	from, to := h.extractAddresses(evt)
+	contract := strings.ToLower(contractAddress)
 
-	if from != "" {
-		key := fmt.Sprintf("%d:%s:%s:%s", h.defaultChainID, from, contractAddress, tokenID)
-		h.middlewareCache.Delete(ctx, key)
+	for _, wallet := range []string{from, to} {
+		wallet = normalizeEventAddress(wallet)
+		if wallet == "" || wallet == zeroAddress {
+			continue
+		}
+		h.middlewareCache.DeleteByPrefix(ctx, fmt.Sprintf("%d:%s:%s:", h.defaultChainID, wallet, contract))
 	}
-	if to != "" {
-		key := fmt.Sprintf("%d:%s:%s:%s", h.defaultChainID, to, contractAddress, tokenID)
-		h.middlewareCache.Delete(ctx, key)
+}
+
+// normalizeEventAddress lowercases an address, accepting both 20-byte
+// addresses and 32-byte indexed topics.
+func normalizeEventAddress(addr string) string {
+	if common.IsHexAddress(addr) || (len(addr) == 66 && strings.HasPrefix(addr, "0x")) {
+		return strings.ToLower(common.HexToAddress(addr).Hex())
 	}
+	return strings.ToLower(addr)
+}
 
-	prefix := fmt.Sprintf("%d:", h.defaultChainID)
-	h.middlewareCache.DeleteByPrefix(ctx, prefix+":"+contractAddress+":"+tokenID)
+// normalizeTokenID converts a hex token ID, as found in raw indexed topics,
+// to the decimal form used in cache keys.
+func normalizeTokenID(tokenID string) string {
+	if !strings.HasPrefix(tokenID, "0x") {
+		return tokenID
+	}
+	if n, ok := new(big.Int).SetString(tokenID[2:], 16); ok {
+		return n.String()
+	}
+	return tokenID
 }
 
 func (h *NFTEventHandler) extractAddresses(evt *event.IndexedEvent) (from, to string) {
@@ -225,3 +269,20 @@ func (h *NFTEventHandler) extractERC1155TokenID(evt *event.IndexedEvent) string
 	}
 	return ""
 }
+
+func (h *NFTEventHandler) extractERC1155TokenIDs(evt *event.IndexedEvent) []string {
+	if evt.Decoded == nil {
+		return nil
+	}
+	switch ids := evt.Decoded["ids"].(type) {
+	case []string:
+		return ids
+	case []interface{}:
+		tokenIDs := make([]string, 0, len(ids))
+		for _, id := range ids {
+			tokenIDs = append(tokenIDs, fmt.Sprintf("%v", id))
+		}
+		return tokenIDs
+	}
+	return nil
+}
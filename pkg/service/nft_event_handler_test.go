@@ -122,9 +122,7 @@ func TestNFTEventHandler_HandleTransfer(t *testing.T) {
 		err := h.HandleTransfer(context.Background(), evt)
 		require.NoError(t, err)
 		h.FlushNow()
-		assert.Contains(t, cache.deletedKeys, "1:0xfrom:0xcontract:42")
-		assert.Contains(t, cache.deletedKeys, "1:0xto:0xcontract:42")
-		assert.Contains(t, cache.deletedPrefixes, "1::0xcontract:42")
+		assert.ElementsMatch(t, []string{"1:0xfrom:0xcontract:", "1:0xto:0xcontract:"}, cache.deletedPrefixes)
 	})
 }
 
@@ -143,8 +141,7 @@ func TestNFTEventHandler_HandleTransferSingle(t *testing.T) {
 		err := h.HandleTransferSingle(context.Background(), evt)
 		require.NoError(t, err)
 		h.FlushNow()
-		assert.Contains(t, cache.deletedKeys, "1:0xfrom:0xcontract:100")
-		assert.Contains(t, cache.deletedKeys, "1:0xto:0xcontract:100")
+		assert.ElementsMatch(t, []string{"1:0xfrom:0xcontract:", "1:0xto:0xcontract:"}, cache.deletedPrefixes)
 	})
 
 	t.Run("no token ID in decoded", func(t *testing.T) {
@@ -292,9 +289,34 @@ func TestNFTEventHandler_InvalidateMiddlewareCache(t *testing.T) {
 			},
 		}
 		h.invalidateMiddlewareCache(context.Background(), "0xcontract", "42", evt)
-		assert.Contains(t, cache.deletedKeys, "137:0xfrom_addr:0xcontract:42")
-		assert.Contains(t, cache.deletedKeys, "137:0xto_addr:0xcontract:42")
-		assert.Contains(t, cache.deletedPrefixes, "137::0xcontract:42")
+		assert.Equal(t, []string{"137:0xfrom_addr:0xcontract:", "137:0xto_addr:0xcontract:"}, cache.deletedPrefixes)
+	})
+
+	t.Run("checksummed addresses and topics are lowercased", func(t *testing.T) {
+		cache := &mockNFTAccessCache{}
+		h := NewNFTEventHandlerWithCache(&NFTService{}, cache, 1, zap.NewNop())
+		evt := &event.IndexedEvent{
+			Topics: []string{
+				"0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+				"0x000000000000000000000000742d35Cc6634C0532925a3b844Bc9e7595f2bD18",
+				"0x0000000000000000000000001111111111111111111111111111111111111111",
+			},
+		}
+		h.invalidateMiddlewareCache(context.Background(), "0x1234567890ABCDEF1234567890abcdef12345678", "42", evt)
+		assert.Equal(t, []string{
+			"1:0x742d35cc6634c0532925a3b844bc9e7595f2bd18:0x1234567890abcdef1234567890abcdef12345678:",
+			"1:0x1111111111111111111111111111111111111111:0x1234567890abcdef1234567890abcdef12345678:",
+		}, cache.deletedPrefixes)
+	})
+
+	t.Run("mints and burns skip the zero address", func(t *testing.T) {
+		cache := &mockNFTAccessCache{}
+		h := NewNFTEventHandlerWithCache(&NFTService{}, cache, 1, zap.NewNop())
+		evt := &event.IndexedEvent{
+			Decoded: map[string]interface{}{"from": zeroAddress, "to": "0xto"},
+		}
+		h.invalidateMiddlewareCache(context.Background(), "0xcontract", "42", evt)
+		assert.Equal(t, []string{"1:0xto:0xcontract:"}, cache.deletedPrefixes)
 	})
 
 	t.Run("empty addresses", func(t *testing.T) {
@@ -302,7 +324,54 @@ func TestNFTEventHandler_InvalidateMiddlewareCache(t *testing.T) {
 		h := NewNFTEventHandlerWithCache(&NFTService{}, cache, 1, zap.NewNop())
 		evt := &event.IndexedEvent{}
 		h.invalidateMiddlewareCache(context.Background(), "0xcontract", "42", evt)
-		assert.Empty(t, cache.deletedKeys)
-		assert.Contains(t, cache.deletedPrefixes, "1::0xcontract:42")
+		assert.Empty(t, cache.deletedPrefixes)
+	})
+}
+
+func TestNFTEventHandler_HandleTransferBatch(t *testing.T) {
+	cache := &mockNFTAccessCache{}
+	h := NewNFTEventHandlerWithCache(&NFTService{}, cache, 1, zap.NewNop())
+	evt := &event.IndexedEvent{
+		ContractAddress: "0xcontract",
+		Decoded: map[string]interface{}{
+			"ids":  []string{"1", "2"},
+			"from": "0xfrom",
+			"to":   "0xto",
+		},
+	}
+	require.NoError(t, h.HandleTransferBatch(context.Background(), evt))
+
+	pending := 0
+	h.pendingInvalidations.Range(func(_, _ any) bool {
+		pending++
+		return true
 	})
+	assert.Equal(t, 2, pending)
+
+	h.FlushNow()
+	assert.ElementsMatch(t, []string{
+		"1:0xfrom:0xcontract:", "1:0xto:0xcontract:",
+		"1:0xfrom:0xcontract:", "1:0xto:0xcontract:",
+	}, cache.deletedPrefixes)
+
+	require.NoError(t, h.HandleTransferBatch(context.Background(), &event.IndexedEvent{}))
+}
+
+func TestNFTEventHandler_SameTokenMovedTwiceInWindow(t *testing.T) {
+	cache := &mockNFTAccessCache{}
+	h := NewNFTEventHandlerWithCache(&NFTService{}, cache, 1, zap.NewNop())
+	for _, hop := range [][2]string{{"0xa", "0xb"}, {"0xb", "0xc"}} {
+		require.NoError(t, h.HandleTransfer(context.Background(), &event.IndexedEvent{
+			ContractAddress: "0xcontract",
+			Decoded:         map[string]interface{}{"tokenId": "7", "from": hop[0], "to": hop[1]},
+		}))
+	}
+	h.FlushNow()
+	assert.Subset(t, cache.deletedPrefixes, []string{"1:0xa:0xcontract:", "1:0xc:0xcontract:"})
+}
+
+func TestNormalizeTokenID(t *testing.T) {
+	assert.Equal(t, "42", normalizeTokenID("42"))
+	assert.Equal(t, "42", normalizeTokenID("0x000000000000000000000000000000000000000000000000000000000000002a"))
+	assert.Equal(t, "0xzz", normalizeTokenID("0xzz"))
 }
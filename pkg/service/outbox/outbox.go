@@ -0,0 +1,289 @@
+// Package outbox publishes events reliably from database writes. Events
+// are inserted into the event_outbox table in the same transaction as the
+// write they announce, and a relay publishes them to the event bus,
+// retrying with backoff while the broker is down. An event is published
+// at least once; its ID is stable across retries, so consumers skip
+// duplicates with an event.Deduplicator.
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/event"
+	"github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultBatchSize    = 100
+	defaultPollInterval = 2 * time.Second
+	defaultMaxBackoff   = 5 * time.Minute
+	defaultRetention    = 7 * 24 * time.Hour
+	// leaseDuration keeps claimed events from other relays while they are
+	// being published.
+	leaseDuration = time.Minute
+	// purgeInterval is how often published events past retention are
+	// deleted.
+	purgeInterval = time.Hour
+	maxErrorBytes = 512
+)
+
+// Config tunes the relay. Zero values take the defaults.
+type Config struct {
+	BatchSize    int
+	PollInterval time.Duration
+	// MaxBackoff caps the wait between attempts to publish one event.
+	MaxBackoff time.Duration
+	// Retention is how long published events are kept.
+	Retention time.Duration
+}
+
+func (c *Config) applyDefaults() {
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaultBatchSize
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = defaultPollInterval
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = defaultMaxBackoff
+	}
+	if c.Retention <= 0 {
+		c.Retention = defaultRetention
+	}
+}
+
+// Outbox stores events in the event_outbox table and relays them to a
+// bus. Any number of relays may share the table.
+type Outbox struct {
+	db     storage.DB
+	bus    event.EventBus
+	cfg    Config
+	logger *zap.Logger
+
+	wake   chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New returns an outbox over db that relays to bus. Call Start to relay.
+func New(db storage.DB, bus event.EventBus, cfg Config, logger *zap.Logger) *Outbox {
+	cfg.applyDefaults()
+	return &Outbox{db: db, bus: bus, cfg: cfg, logger: logger, wake: make(chan struct{}, 1)}
+}
+
+// Enqueue stores ev in tx so it is published if and only if tx commits.
+// ev.ID is the idempotency key consumers deduplicate on; a random one is
+// assigned when it is empty, and an event already enqueued under its ID
+// is not stored twice. Call Notify after the commit to publish promptly.
+func (o *Outbox) Enqueue(ctx context.Context, tx *sql.Tx, ev *event.Event) error {
+	if ev.ID == "" {
+		ev.ID = uuid.New().String()
+	}
+	if ev.Timestamp == 0 {
+		ev.Timestamp = time.Now().Unix()
+	}
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox event: %w", err)
+	}
+	now := time.Now().UTC()
+	if _, err := tx.ExecContext(ctx, `INSERT INTO event_outbox (id, event_type, payload, created_at, available_at)
+		VALUES ($1, $2, $3, $4, $4) ON CONFLICT (id) DO NOTHING`,
+		ev.ID, ev.Type, payload, now); err != nil {
+		return fmt.Errorf("failed to enqueue event: %w", err)
+	}
+	return nil
+}
+
+// Notify wakes the relay to publish newly committed events without
+// waiting for the next poll.
+func (o *Outbox) Notify() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start runs the relay until Close.
+func (o *Outbox) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	o.cancel = cancel
+	o.wg.Add(1)
+	go func() {
+		defer o.wg.Done()
+		ticker := time.NewTicker(o.cfg.PollInterval)
+		defer ticker.Stop()
+		var lastPurge time.Time
+		for {
+			for {
+				n, err := o.RelayOnce(ctx)
+				if err != nil && ctx.Err() == nil {
+					o.logger.Warn("Outbox relay round failed", zap.Error(err))
+				}
+				if err != nil || n < o.cfg.BatchSize {
+					break
+				}
+			}
+			if time.Since(lastPurge) >= purgeInterval {
+				lastPurge = time.Now()
+				if err := o.purge(ctx); err != nil && ctx.Err() == nil {
+					o.logger.Warn("Failed to purge published outbox events", zap.Error(err))
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-o.wake:
+			}
+		}
+	}()
+}
+
+// Close stops the relay. Unpublished events stay in the table for the
+// next relay.
+func (o *Outbox) Close() {
+	if o.cancel != nil {
+		o.cancel()
+	}
+	o.wg.Wait()
+}
+
+type pending struct {
+	id       string
+	payload  []byte
+	attempts int
+}
+
+// RelayOnce claims a batch of due events, publishes them in enqueue order
+// and records the outcome. It stops at the first failed publish, so a
+// broker outage costs one attempt per round, and returns how many events
+// were claimed.
+func (o *Outbox) RelayOnce(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	rows, err := o.db.Query(ctx, `UPDATE event_outbox SET available_at = $2
+		WHERE id IN (
+			SELECT id FROM event_outbox
+			WHERE published_at IS NULL AND available_at <= $1
+			ORDER BY created_at LIMIT $3
+			FOR UPDATE SKIP LOCKED)
+		RETURNING id, payload, attempts, created_at`,
+		now, now.Add(leaseDuration), o.cfg.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+	var batch []pending
+	var created []time.Time
+	for rows.Next() {
+		var p pending
+		var at time.Time
+		if err := rows.Scan(&p.id, &p.payload, &p.attempts, &at); err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		batch = append(batch, p)
+		created = append(created, at)
+	}
+	err = rows.Err()
+	_ = rows.Close()
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+	// RETURNING does not keep the subquery's order.
+	sortByCreated(batch, created)
+
+	// Outcomes are recorded even when ctx is cancelled mid-batch.
+	writeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	var published []string
+	for i, p := range batch {
+		var ev event.Event
+		if err := json.Unmarshal(p.payload, &ev); err != nil {
+			// Nothing can publish a corrupt payload; park it rather than
+			// retry it forever.
+			o.logger.Error("Dropping undecodable outbox event", zap.String("id", p.id), zap.Error(err))
+			published = append(published, p.id)
+			continue
+		}
+		if err := o.bus.Publish(ctx, &ev); err != nil {
+			o.recordFailure(writeCtx, batch[i:], err)
+			break
+		}
+		published = append(published, p.id)
+	}
+	if len(published) > 0 {
+		if _, err := o.db.Exec(writeCtx, `UPDATE event_outbox SET published_at = $2, last_error = NULL
+			WHERE id = ANY($1)`, pq.Array(published), time.Now().UTC()); err != nil {
+			// They are published again once the lease expires.
+			return len(batch), fmt.Errorf("failed to mark outbox events published: %w", err)
+		}
+	}
+	return len(batch), nil
+}
+
+// recordFailure schedules the next attempt for the events from the first
+// failed one on, backing off by the failed event's attempts.
+func (o *Outbox) recordFailure(ctx context.Context, rest []pending, pubErr error) {
+	attempts := rest[0].attempts + 1
+	next := time.Now().UTC().Add(o.backoff(attempts))
+	msg := pubErr.Error()
+	if len(msg) > maxErrorBytes {
+		msg = msg[:maxErrorBytes]
+	}
+	ids := make([]string, len(rest))
+	for i, p := range rest {
+		ids[i] = p.id
+	}
+	o.logger.Warn("Failed to publish outbox event, will retry",
+		zap.String("id", rest[0].id), zap.Int("attempts", attempts), zap.Time("next_attempt", next), zap.Error(pubErr))
+	if _, err := o.db.Exec(ctx, `UPDATE event_outbox SET available_at = $2,
+		attempts = attempts + CASE WHEN id = $3 THEN 1 ELSE 0 END, last_error = $4
+		WHERE id = ANY($1)`, pq.Array(ids), next, rest[0].id, msg); err != nil {
+		o.logger.Warn("Failed to record outbox publish failure", zap.Error(err))
+	}
+}
+
+// backoff is the wait after the given number of failed attempts, doubling
+// from one second up to MaxBackoff.
+func (o *Outbox) backoff(attempts int) time.Duration {
+	d := time.Second
+	for i := 1; i < attempts && d < o.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, o.cfg.MaxBackoff)
+}
+
+// purge deletes events published longer ago than the retention.
+func (o *Outbox) purge(ctx context.Context) error {
+	_, err := o.db.Exec(ctx, `DELETE FROM event_outbox WHERE published_at < $1`,
+		time.Now().UTC().Add(-o.cfg.Retention))
+	return err
+}
+
+// Pending returns how many events are waiting to be published.
+func (o *Outbox) Pending(ctx context.Context) (int, error) {
+	var n int
+	if err := o.db.QueryRow(ctx, `SELECT COUNT(*) FROM event_outbox WHERE published_at IS NULL`).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count outbox events: %w", err)
+	}
+	return n, nil
+}
+
+func sortByCreated(batch []pending, created []time.Time) {
+	for i := 1; i < len(batch); i++ {
+		for j := i; j > 0 && created[j].Before(created[j-1]); j-- {
+			batch[j], batch[j-1] = batch[j-1], batch[j]
+			created[j], created[j-1] = created[j-1], created[j]
+		}
+	}
+}
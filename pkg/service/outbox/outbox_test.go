@@ -0,0 +1,300 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/event"
+	stg "github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// recordingDriver is a database/sql driver whose statements only record
+// what they were asked to execute.
+type recordingDriver struct {
+	mu    sync.Mutex
+	execs []execCall
+}
+type recordingConn struct{ d *recordingDriver }
+type recordingStmt struct {
+	d     *recordingDriver
+	query string
+}
+type recordingTx struct{}
+
+func (d *recordingDriver) Open(string) (driver.Conn, error) { return &recordingConn{d}, nil }
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{c.d, query}, nil
+}
+func (c *recordingConn) Close() error              { return nil }
+func (c *recordingConn) Begin() (driver.Tx, error) { return recordingTx{}, nil }
+func (s *recordingStmt) Close() error              { return nil }
+func (s *recordingStmt) NumInput() int             { return -1 }
+func (s *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	call := execCall{query: s.query}
+	for _, a := range args {
+		call.args = append(call.args, a)
+	}
+	s.d.execs = append(s.d.execs, call)
+	return driver.RowsAffected(1), nil
+}
+func (s *recordingStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+func (recordingTx) Commit() error   { return nil }
+func (recordingTx) Rollback() error { return nil }
+
+var txDriver = &recordingDriver{}
+
+func init() {
+	sql.Register("outboxrec", txDriver)
+}
+
+func beginTx(t *testing.T) *sql.Tx {
+	db, err := sql.Open("outboxrec", "")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	return tx
+}
+
+type execCall struct {
+	query string
+	args  []interface{}
+}
+
+type mockDB struct {
+	queryFn func(ctx context.Context, query string, args ...interface{}) (stg.Rows, error)
+
+	mu    sync.Mutex
+	execs []execCall
+}
+
+func (m *mockDB) Query(ctx context.Context, query string, args ...interface{}) (stg.Rows, error) {
+	if m.queryFn != nil {
+		return m.queryFn(ctx, query, args...)
+	}
+	return &claimRows{}, nil
+}
+func (m *mockDB) QueryRow(context.Context, string, ...interface{}) *stg.CancelRow {
+	return stg.NewErrorCancelRow(sql.ErrNoRows)
+}
+func (m *mockDB) Exec(_ context.Context, query string, args ...interface{}) (sql.Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.execs = append(m.execs, execCall{query, args})
+	return driver.RowsAffected(1), nil
+}
+func (m *mockDB) Begin(context.Context) (*sql.Tx, error) { return nil, errors.New("not implemented") }
+func (m *mockDB) InTransaction(context.Context, func(tx *sql.Tx) error) error {
+	return errors.New("not implemented")
+}
+func (m *mockDB) Ping(context.Context) error { return nil }
+func (m *mockDB) Close() error               { return nil }
+
+func (m *mockDB) execCalls() []execCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]execCall(nil), m.execs...)
+}
+
+// claimRows answers the claim query with the given outbox rows.
+type claimRows struct {
+	rows []claimedRow
+	i    int
+}
+
+type claimedRow struct {
+	event    event.Event
+	attempts int
+	created  time.Time
+}
+
+func (r *claimRows) Next() bool { r.i++; return r.i <= len(r.rows) }
+func (r *claimRows) Scan(dest ...interface{}) error {
+	row := r.rows[r.i-1]
+	payload, err := json.Marshal(row.event)
+	if err != nil {
+		return err
+	}
+	*dest[0].(*string) = row.event.ID
+	*dest[1].(*[]byte) = payload
+	*dest[2].(*int) = row.attempts
+	*dest[3].(*time.Time) = row.created
+	return nil
+}
+func (r *claimRows) Close() error { return nil }
+func (r *claimRows) Err() error   { return nil }
+
+// downBus is an event bus whose broker is unreachable.
+type downBus struct{ event.EventBus }
+
+func (downBus) Publish(context.Context, *event.Event) error { return errors.New("broker unavailable") }
+
+func newBus(t *testing.T) *event.MemoryEventBus {
+	bus, err := event.NewMemoryEventBus()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = bus.Close() })
+	return bus
+}
+
+func collect(t *testing.T, bus event.EventBus) func() []string {
+	var mu sync.Mutex
+	var ids []string
+	_, err := bus.Subscribe(context.Background(), "upload.completed", func(_ context.Context, e *event.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		ids = append(ids, e.ID)
+		return nil
+	})
+	require.NoError(t, err)
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), ids...)
+	}
+}
+
+func TestEnqueue(t *testing.T) {
+	o := New(&mockDB{}, newBus(t), Config{}, zap.NewNop())
+	tx := beginTx(t)
+
+	keyed := &event.Event{ID: "upload-1", Type: "upload.completed", Data: map[string]interface{}{"size": 3.0}}
+	require.NoError(t, o.Enqueue(context.Background(), tx, keyed))
+	unkeyed := &event.Event{Type: "upload.completed"}
+	require.NoError(t, o.Enqueue(context.Background(), tx, unkeyed))
+	require.NoError(t, tx.Commit())
+
+	assert.NotEmpty(t, unkeyed.ID, "an idempotency key is assigned")
+	assert.NotZero(t, unkeyed.Timestamp)
+
+	txDriver.mu.Lock()
+	calls := txDriver.execs[len(txDriver.execs)-2:]
+	txDriver.mu.Unlock()
+	assert.Contains(t, calls[0].query, "INSERT INTO event_outbox")
+	assert.Contains(t, calls[0].query, "ON CONFLICT (id) DO NOTHING")
+	assert.Equal(t, "upload-1", calls[0].args[0])
+	assert.Equal(t, "upload.completed", calls[0].args[1])
+
+	var stored event.Event
+	require.NoError(t, json.Unmarshal(calls[0].args[2].([]byte), &stored))
+	assert.Equal(t, "upload-1", stored.ID)
+	assert.Equal(t, 3.0, stored.Data["size"])
+	assert.Equal(t, unkeyed.ID, calls[1].args[0])
+}
+
+func TestRelayOnce_PublishesInOrder(t *testing.T) {
+	now := time.Now()
+	db := &mockDB{queryFn: func(_ context.Context, query string, args ...interface{}) (stg.Rows, error) {
+		assert.Contains(t, query, "FOR UPDATE SKIP LOCKED")
+		return &claimRows{rows: []claimedRow{
+			{event: event.Event{ID: "e2", Type: "upload.completed"}, created: now},
+			{event: event.Event{ID: "e1", Type: "upload.completed"}, created: now.Add(-time.Second)},
+		}}, nil
+	}}
+	bus := newBus(t)
+	received := collect(t, bus)
+	o := New(db, bus, Config{}, zap.NewNop())
+
+	n, err := o.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Eventually(t, func() bool { return len(received()) == 2 }, time.Second, 5*time.Millisecond)
+
+	execs := db.execCalls()
+	require.Len(t, execs, 1)
+	assert.Contains(t, execs[0].query, "SET published_at")
+	assert.Equal(t, pq.Array([]string{"e1", "e2"}), execs[0].args[0])
+}
+
+func TestRelayOnce_BrokerDown(t *testing.T) {
+	db := &mockDB{queryFn: func(context.Context, string, ...interface{}) (stg.Rows, error) {
+		return &claimRows{rows: []claimedRow{
+			{event: event.Event{ID: "e1", Type: "upload.completed"}, attempts: 2},
+			{event: event.Event{ID: "e2", Type: "upload.completed"}},
+		}}, nil
+	}}
+	o := New(db, downBus{}, Config{MaxBackoff: time.Minute}, zap.NewNop())
+
+	before := time.Now().UTC()
+	n, err := o.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	execs := db.execCalls()
+	require.Len(t, execs, 1, "nothing is marked published")
+	assert.Contains(t, execs[0].query, "attempts = attempts +")
+	assert.Equal(t, pq.Array([]string{"e1", "e2"}), execs[0].args[0])
+	next := execs[0].args[1].(time.Time)
+	assert.WithinDuration(t, before.Add(4*time.Second), next, time.Second, "third attempt backs off 4s")
+	assert.Equal(t, "e1", execs[0].args[2])
+	assert.Equal(t, "broker unavailable", execs[0].args[3])
+}
+
+func TestRelayOnce_ClaimError(t *testing.T) {
+	db := &mockDB{queryFn: func(context.Context, string, ...interface{}) (stg.Rows, error) {
+		return nil, errors.New("connection refused")
+	}}
+	o := New(db, newBus(t), Config{}, zap.NewNop())
+
+	_, err := o.RelayOnce(context.Background())
+	assert.ErrorContains(t, err, "connection refused")
+	assert.Empty(t, db.execCalls())
+}
+
+func TestBackoff(t *testing.T) {
+	o := New(&mockDB{}, newBus(t), Config{MaxBackoff: 10 * time.Second}, zap.NewNop())
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{40, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.attempts), func(t *testing.T) {
+			assert.Equal(t, tt.want, o.backoff(tt.attempts))
+		})
+	}
+}
+
+func TestStart_RelaysOnNotify(t *testing.T) {
+	var mu sync.Mutex
+	var due []claimedRow
+	db := &mockDB{queryFn: func(context.Context, string, ...interface{}) (stg.Rows, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		rows := due
+		due = nil
+		return &claimRows{rows: rows}, nil
+	}}
+	bus := newBus(t)
+	received := collect(t, bus)
+	o := New(db, bus, Config{PollInterval: time.Hour}, zap.NewNop())
+	o.Start()
+	defer o.Close()
+
+	mu.Lock()
+	due = []claimedRow{{event: event.Event{ID: "e1", Type: "upload.completed"}}}
+	mu.Unlock()
+	o.Notify()
+
+	assert.Eventually(t, func() bool { return len(received()) == 1 }, 2*time.Second, 5*time.Millisecond)
+}
@@ -0,0 +1,10 @@
+package service
+
+import "github.com/rtcdance/streamgate/pkg/service/outbox"
+
+type (
+	EventOutbox       = outbox.Outbox
+	EventOutboxConfig = outbox.Config
+)
+
+var NewEventOutbox = outbox.New
@@ -0,0 +1,312 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultElasticsearchIndex   = "streamgate-content"
+	defaultElasticsearchTimeout = 10 * time.Second
+)
+
+// ElasticsearchConfig locates the Elasticsearch or OpenSearch cluster and
+// the index that holds content documents.
+type ElasticsearchConfig struct {
+	URL   string
+	Index string
+	// APIKey is sent as an ApiKey authorization; otherwise Username and
+	// Password are used for basic auth when set.
+	APIKey   string
+	Username string
+	Password string
+	Timeout  time.Duration
+}
+
+// ElasticsearchBackend searches an Elasticsearch or OpenSearch index over
+// their shared REST API. The index is fed by the search service.
+type ElasticsearchBackend struct {
+	base   string
+	index  string
+	cfg    ElasticsearchConfig
+	client *http.Client
+}
+
+// NewElasticsearchBackend returns a backend for the cluster at cfg.URL.
+func NewElasticsearchBackend(cfg ElasticsearchConfig) (*ElasticsearchBackend, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid elasticsearch url %q", cfg.URL)
+	}
+	if cfg.Index == "" {
+		cfg.Index = defaultElasticsearchIndex
+	}
+	if cfg.Index != strings.ToLower(cfg.Index) || strings.ContainsAny(cfg.Index, `/\*?"<>| ,#`) {
+		return nil, fmt.Errorf("invalid elasticsearch index name %q", cfg.Index)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultElasticsearchTimeout
+	}
+	return &ElasticsearchBackend{
+		base:   strings.TrimRight(cfg.URL, "/"),
+		index:  cfg.Index,
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+// indexMapping keeps tags both analyzed, for text matches, and as
+// keywords, for filters and facets.
+var indexMapping = map[string]interface{}{
+	"mappings": map[string]interface{}{
+		"properties": map[string]interface{}{
+			"content_id":       map[string]string{"type": "keyword"},
+			"title":            map[string]string{"type": "text"},
+			"description":      map[string]string{"type": "text"},
+			"type":             map[string]string{"type": "keyword"},
+			"creator":          map[string]string{"type": "keyword"},
+			"thumbnail_url":    map[string]interface{}{"type": "keyword", "index": false},
+			"duration":         map[string]string{"type": "long"},
+			"tags":             map[string]interface{}{"type": "text", "fields": map[string]interface{}{"keyword": map[string]string{"type": "keyword"}}},
+			"gating_contracts": map[string]string{"type": "keyword"},
+			"created_at":       map[string]string{"type": "date"},
+			"indexed_at":       map[string]string{"type": "date"},
+		},
+	},
+}
+
+// EnsureIndex creates the index with its mapping unless it exists.
+func (b *ElasticsearchBackend) EnsureIndex(ctx context.Context) error {
+	err := b.do(ctx, http.MethodPut, "/"+b.index, indexMapping, nil)
+	var apiErr *elasticsearchError
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusBadRequest && strings.Contains(apiErr.Body, "resource_already_exists_exception") {
+		return nil
+	}
+	return err
+}
+
+// Search implements Backend.
+func (b *ElasticsearchBackend) Search(ctx context.Context, q Query, after *Cursor, limit int, withFacets bool) ([]Hit, *Facets, error) {
+	var must interface{} = map[string]interface{}{"match_all": map[string]interface{}{}}
+	if q.Text != "" {
+		must = map[string]interface{}{"simple_query_string": map[string]interface{}{
+			"query":            q.Text,
+			"fields":           []string{"title^3", "tags^2", "description"},
+			"default_operator": "and",
+		}}
+	}
+	filter := []interface{}{}
+	term := func(field, value string) {
+		filter = append(filter, map[string]interface{}{"term": map[string]string{field: value}})
+	}
+	if q.Creator != "" {
+		term("creator", q.Creator)
+	}
+	for _, t := range q.Tags {
+		term("tags.keyword", t)
+	}
+	if q.GatingContract != "" {
+		term("gating_contracts", q.GatingContract)
+	}
+	if q.MinDuration > 0 || q.MaxDuration > 0 {
+		bounds := map[string]int64{}
+		if q.MinDuration > 0 {
+			bounds["gte"] = q.MinDuration
+		}
+		if q.MaxDuration > 0 {
+			bounds["lte"] = q.MaxDuration
+		}
+		filter = append(filter, map[string]interface{}{"range": map[string]interface{}{"duration": bounds}})
+	}
+
+	body := map[string]interface{}{
+		"query": map[string]interface{}{"bool": map[string]interface{}{"must": must, "filter": filter}},
+		"sort": []interface{}{
+			map[string]string{"_score": "desc"},
+			map[string]string{"created_at": "desc"},
+			map[string]string{"content_id": "desc"},
+		},
+		"size":             limit,
+		"track_total_hits": false,
+	}
+	if after != nil {
+		body["search_after"] = []interface{}{after.Score, after.CreatedAt.UnixMilli(), after.ContentID}
+	}
+	if withFacets {
+		body["aggs"] = map[string]interface{}{
+			"tags":     map[string]interface{}{"terms": map[string]interface{}{"field": "tags.keyword", "size": facetSize}},
+			"creators": map[string]interface{}{"terms": map[string]interface{}{"field": "creator", "size": facetSize}},
+		}
+	}
+
+	var resp struct {
+		Hits struct {
+			Hits []struct {
+				Score  *float64 `json:"_score"`
+				Source Document `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+		Aggregations map[string]struct {
+			Buckets []struct {
+				Key      string `json:"key"`
+				DocCount int64  `json:"doc_count"`
+			} `json:"buckets"`
+		} `json:"aggregations"`
+	}
+	if err := b.do(ctx, http.MethodPost, "/"+b.index+"/_search", body, &resp); err != nil {
+		return nil, nil, err
+	}
+
+	hits := make([]Hit, 0, len(resp.Hits.Hits))
+	for _, h := range resp.Hits.Hits {
+		var score float64
+		if h.Score != nil {
+			score = *h.Score
+		}
+		hits = append(hits, h.Source.hit(score))
+	}
+	var facets *Facets
+	if withFacets {
+		facets = &Facets{Tags: []Facet{}, Creators: []Facet{}}
+		for _, bucket := range resp.Aggregations["tags"].Buckets {
+			facets.Tags = append(facets.Tags, Facet{Value: bucket.Key, Count: bucket.DocCount})
+		}
+		for _, bucket := range resp.Aggregations["creators"].Buckets {
+			facets.Creators = append(facets.Creators, Facet{Value: bucket.Key, Count: bucket.DocCount})
+		}
+	}
+	return hits, facets, nil
+}
+
+// Put implements Indexer with one bulk request.
+func (b *ElasticsearchBackend) Put(ctx context.Context, docs []Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for i := range docs {
+		if docs[i].Tags == nil {
+			docs[i].Tags = []string{}
+		}
+		if docs[i].GatingContracts == nil {
+			docs[i].GatingContracts = []string{}
+		}
+		action := map[string]interface{}{"index": map[string]string{"_index": b.index, "_id": docs[i].ContentID}}
+		if err := enc.Encode(action); err != nil {
+			return err
+		}
+		if err := enc.Encode(docs[i]); err != nil {
+			return err
+		}
+	}
+
+	var resp struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			ID    string          `json:"_id"`
+			Error json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := b.send(ctx, http.MethodPost, "/_bulk", "application/x-ndjson", &buf, &resp); err != nil {
+		return err
+	}
+	if resp.Errors {
+		for _, item := range resp.Items {
+			for _, result := range item {
+				if len(result.Error) > 0 {
+					return fmt.Errorf("failed to index content %s: %s", result.ID, result.Error)
+				}
+			}
+		}
+		return errors.New("bulk indexing failed")
+	}
+	return nil
+}
+
+// Remove implements Indexer.
+func (b *ElasticsearchBackend) Remove(ctx context.Context, contentID string) error {
+	err := b.do(ctx, http.MethodDelete, "/"+b.index+"/_doc/"+url.PathEscape(contentID), nil, nil)
+	var apiErr *elasticsearchError
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// RemoveStale implements Indexer.
+func (b *ElasticsearchBackend) RemoveStale(ctx context.Context, before time.Time) error {
+	body := map[string]interface{}{
+		"query": map[string]interface{}{"range": map[string]interface{}{
+			"indexed_at": map[string]string{"lt": before.UTC().Format(time.RFC3339Nano)},
+		}},
+	}
+	return b.do(ctx, http.MethodPost, "/"+b.index+"/_delete_by_query?conflicts=proceed&refresh=true", body, nil)
+}
+
+// elasticsearchError is a non-2xx response from the cluster.
+type elasticsearchError struct {
+	Status int
+	Body   string
+}
+
+func (e *elasticsearchError) Error() string {
+	return fmt.Sprintf("elasticsearch returned %d: %s", e.Status, e.Body)
+}
+
+// do sends body as JSON and decodes the response into out, if set.
+func (b *ElasticsearchBackend) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+	return b.send(ctx, method, path, "application/json", r, out)
+}
+
+func (b *ElasticsearchBackend) send(ctx context.Context, method, path, contentType string, body io.Reader, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, b.base+path, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	switch {
+	case b.cfg.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+b.cfg.APIKey)
+	case b.cfg.Username != "":
+		req.SetBasicAuth(b.cfg.Username, b.cfg.Password)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("elasticsearch request failed: %w", err)
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &elasticsearchError{Status: resp.StatusCode, Body: strings.TrimSpace(string(msg))}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid elasticsearch response: %w", err)
+	}
+	return nil
+}
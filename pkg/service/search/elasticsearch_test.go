@@ -0,0 +1,148 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCluster records requests and answers them as Elasticsearch would.
+type fakeCluster struct {
+	mu       sync.Mutex
+	requests []string
+	bodies   map[string]string
+	auth     string
+	created  bool
+}
+
+func (f *fakeCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	body, _ := io.ReadAll(r.Body)
+	key := r.Method + " " + r.URL.Path
+	f.requests = append(f.requests, key)
+	f.bodies[key] = string(body)
+	f.auth = r.Header.Get("Authorization")
+
+	switch {
+	case r.Method == http.MethodPut:
+		if f.created {
+			http.Error(w, `{"error":{"type":"resource_already_exists_exception"}}`, http.StatusBadRequest)
+			return
+		}
+		f.created = true
+		_, _ = w.Write([]byte(`{"acknowledged":true}`))
+	case strings.HasSuffix(r.URL.Path, "/_search"):
+		_, _ = w.Write([]byte(`{
+			"hits": {"hits": [
+				{"_score": 2.5, "_source": {"content_id": "c1", "title": "Cats", "creator": "0xabc", "duration": 60,
+					"tags": ["cats"], "gating_contracts": ["0xnft"], "created_at": "2026-01-01T12:00:00.123456Z"}}
+			]},
+			"aggregations": {
+				"tags": {"buckets": [{"key": "cats", "doc_count": 3}]},
+				"creators": {"buckets": [{"key": "0xabc", "doc_count": 3}]}
+			}}`))
+	case r.URL.Path == "/_bulk":
+		if strings.Contains(string(body), `"_id":"bad"`) {
+			_, _ = w.Write([]byte(`{"errors":true,"items":[{"index":{"_id":"bad","error":{"type":"mapper_parsing_exception"}}}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+	case r.Method == http.MethodDelete:
+		http.Error(w, `{"result":"not_found"}`, http.StatusNotFound)
+	default:
+		_, _ = w.Write([]byte(`{}`))
+	}
+}
+
+func newFakeCluster(t *testing.T, cfg ElasticsearchConfig) (*ElasticsearchBackend, *fakeCluster) {
+	t.Helper()
+	fake := &fakeCluster{bodies: make(map[string]string)}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	cfg.URL = srv.URL
+	b, err := NewElasticsearchBackend(cfg)
+	require.NoError(t, err)
+	return b, fake
+}
+
+func TestElasticsearchBackend_Search(t *testing.T) {
+	b, fake := newFakeCluster(t, ElasticsearchConfig{APIKey: "secret"})
+	q := Query{Text: "cats", Creator: "0xabc", Tags: []string{"cats", "pets"}, MinDuration: 30, GatingContract: "0xnft"}
+	after := &Cursor{Score: 3, CreatedAt: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), ContentID: "c9"}
+
+	hits, facets, err := b.Search(context.Background(), q, after, 11, true)
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, "c1", hits[0].ContentID)
+	assert.Equal(t, 2.5, hits[0].Score)
+	assert.Equal(t, time.Date(2026, 1, 1, 12, 0, 0, 123456000, time.UTC), hits[0].CreatedAt)
+	assert.Equal(t, []Facet{{Value: "cats", Count: 3}}, facets.Tags)
+	assert.Equal(t, []Facet{{Value: "0xabc", Count: 3}}, facets.Creators)
+	assert.Equal(t, "ApiKey secret", fake.auth)
+
+	var body struct {
+		Query struct {
+			Bool struct {
+				Must   map[string]json.RawMessage `json:"must"`
+				Filter []json.RawMessage          `json:"filter"`
+			} `json:"bool"`
+		} `json:"query"`
+		SearchAfter []interface{}              `json:"search_after"`
+		Size        int                        `json:"size"`
+		Aggs        map[string]json.RawMessage `json:"aggs"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(fake.bodies["POST /streamgate-content/_search"]), &body))
+	assert.Contains(t, body.Query.Bool.Must, "simple_query_string")
+	assert.Len(t, body.Query.Bool.Filter, 5)
+	assert.JSONEq(t, `{"term":{"tags.keyword":"pets"}}`, string(body.Query.Bool.Filter[2]))
+	assert.JSONEq(t, `{"range":{"duration":{"gte":30}}}`, string(body.Query.Bool.Filter[4]))
+	assert.Equal(t, []interface{}{float64(3), float64(after.CreatedAt.UnixMilli()), "c9"}, body.SearchAfter)
+	assert.Equal(t, 11, body.Size)
+	assert.Contains(t, body.Aggs, "tags")
+}
+
+func TestElasticsearchBackend_Indexing(t *testing.T) {
+	b, fake := newFakeCluster(t, ElasticsearchConfig{Index: "content", Username: "elastic", Password: "pw"})
+	ctx := context.Background()
+
+	require.NoError(t, b.EnsureIndex(ctx))
+	require.NoError(t, b.EnsureIndex(ctx), "an existing index is kept")
+	assert.Contains(t, fake.bodies["PUT /content"], `"gating_contracts":{"type":"keyword"}`)
+
+	require.NoError(t, b.Put(ctx, []Document{{ContentID: "c1", Title: "Cats"}}))
+	lines := strings.Split(strings.TrimSpace(fake.bodies["POST /_bulk"]), "\n")
+	require.Len(t, lines, 2)
+	assert.JSONEq(t, `{"index":{"_index":"content","_id":"c1"}}`, lines[0])
+	assert.Contains(t, lines[1], `"tags":[]`)
+	assert.True(t, strings.HasPrefix(fake.auth, "Basic "))
+
+	err := b.Put(ctx, []Document{{ContentID: "bad"}})
+	assert.ErrorContains(t, err, "mapper_parsing_exception")
+
+	assert.NoError(t, b.Remove(ctx, "missing"), "removing an unindexed document is not an error")
+	require.NoError(t, b.RemoveStale(ctx, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)))
+	assert.JSONEq(t, `{"query":{"range":{"indexed_at":{"lt":"2026-01-01T00:00:00Z"}}}}`, fake.bodies["POST /content/_delete_by_query"])
+}
+
+func TestNewElasticsearchBackend_Validation(t *testing.T) {
+	for _, cfg := range []ElasticsearchConfig{
+		{},
+		{URL: "elasticsearch:9200"},
+		{URL: "ftp://elasticsearch"},
+		{URL: "http://elasticsearch:9200", Index: "Content"},
+		{URL: "http://elasticsearch:9200", Index: "a/b"},
+	} {
+		_, err := NewElasticsearchBackend(cfg)
+		assert.Error(t, err, "%+v", cfg)
+	}
+}
@@ -0,0 +1,176 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/models"
+	"github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/lib/pq"
+)
+
+// visibleContent restricts queries to published content that moderation
+// has not held or rejected.
+const visibleContent = models.PublicContentSQL
+
+// PostgresBackend searches the search_vector column of contents, which
+// weights titles over tags over descriptions.
+type PostgresBackend struct {
+	db storage.DB
+}
+
+// NewPostgresBackend returns a backend searching the contents table.
+func NewPostgresBackend(db storage.DB) *PostgresBackend {
+	return &PostgresBackend{db: db}
+}
+
+// sqlArgs numbers query placeholders as they are added.
+type sqlArgs []interface{}
+
+func (a *sqlArgs) add(v interface{}) string {
+	*a = append(*a, v)
+	return "$" + strconv.Itoa(len(*a))
+}
+
+// Search implements Backend.
+func (b *PostgresBackend) Search(ctx context.Context, q Query, after *Cursor, limit int, withFacets bool) ([]Hit, *Facets, error) {
+	var args sqlArgs
+	rank := "0::real"
+	where := []string{visibleContent}
+	if q.Text != "" {
+		tsq := "websearch_to_tsquery('simple', " + args.add(q.Text) + ")"
+		where = append(where, "c.search_vector @@ "+tsq)
+		rank = "ts_rank(c.search_vector, " + tsq + ")"
+	}
+	if q.Creator != "" {
+		where = append(where, "LOWER(c.owner_id) = "+args.add(q.Creator))
+	}
+	if len(q.Tags) > 0 {
+		where = append(where, "c.tags @> "+args.add(pq.Array(q.Tags))+"::text[]")
+	}
+	if q.MinDuration > 0 {
+		where = append(where, "c.duration >= "+args.add(q.MinDuration))
+	}
+	if q.MaxDuration > 0 {
+		where = append(where, "c.duration <= "+args.add(q.MaxDuration))
+	}
+	if q.GatingContract != "" {
+		where = append(where, `EXISTS (SELECT 1 FROM content_gating_rules g
+			WHERE g.content_id = c.id AND g.is_active AND LOWER(g.contract_address) = `+args.add(q.GatingContract)+`)`)
+	}
+
+	var facets *Facets
+	if withFacets {
+		var err error
+		if facets, err = b.facets(ctx, strings.Join(where, " AND "), args); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if after != nil {
+		where = append(where, fmt.Sprintf("(%s, c.created_at, c.id) < (%s::real, %s, %s::uuid)",
+			rank, args.add(after.Score), args.add(after.CreatedAt), args.add(after.ContentID)))
+	}
+	query := `SELECT c.id::text, COALESCE(c.title, ''), COALESCE(c.description, ''), COALESCE(c.type, ''),
+			LOWER(COALESCE(c.owner_id, '')), COALESCE(c.thumbnail_url, ''), COALESCE(c.duration, 0),
+			COALESCE(c.tags, '{}'), c.created_at, ` + rank + ` AS rank
+		FROM contents c
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY rank DESC, c.created_at DESC, c.id DESC
+		LIMIT ` + args.add(limit)
+
+	rows, err := b.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to search contents: %w", err)
+	}
+	defer rows.Close()
+	var hits []Hit
+	for rows.Next() {
+		var h Hit
+		if err := rows.Scan(&h.ContentID, &h.Title, &h.Description, &h.Type, &h.Creator, &h.ThumbnailURL,
+			&h.Duration, pq.Array(&h.Tags), &h.CreatedAt, &h.Score); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan search hit: %w", err)
+		}
+		hits = append(hits, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to search contents: %w", err)
+	}
+	return hits, facets, nil
+}
+
+// facets counts the tags and creators of the content matching where.
+func (b *PostgresBackend) facets(ctx context.Context, where string, args sqlArgs) (*Facets, error) {
+	tags, err := b.countFacet(ctx, `SELECT t, COUNT(*) FROM contents c CROSS JOIN LATERAL unnest(c.tags) AS t
+		WHERE `+where+` GROUP BY t ORDER BY COUNT(*) DESC, t LIMIT `+strconv.Itoa(facetSize), args)
+	if err != nil {
+		return nil, err
+	}
+	creators, err := b.countFacet(ctx, `SELECT LOWER(c.owner_id), COUNT(*) FROM contents c
+		WHERE `+where+` AND COALESCE(c.owner_id, '') <> '' GROUP BY 1 ORDER BY 2 DESC, 1 LIMIT `+strconv.Itoa(facetSize), args)
+	if err != nil {
+		return nil, err
+	}
+	return &Facets{Tags: tags, Creators: creators}, nil
+}
+
+func (b *PostgresBackend) countFacet(ctx context.Context, query string, args sqlArgs) ([]Facet, error) {
+	rows, err := b.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count facets: %w", err)
+	}
+	defer rows.Close()
+	facets := []Facet{}
+	for rows.Next() {
+		var f Facet
+		if err := rows.Scan(&f.Value, &f.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan facet: %w", err)
+		}
+		facets = append(facets, f)
+	}
+	return facets, rows.Err()
+}
+
+// loadDocuments loads the searchable content for an index: the item
+// contentID if it is set and searchable, or else up to limit items with
+// ids after afterID, in id order.
+func loadDocuments(ctx context.Context, db storage.DB, contentID, afterID string, limit int) ([]Document, error) {
+	var args sqlArgs
+	where := visibleContent
+	switch {
+	case contentID != "":
+		where += " AND c.id::text = " + args.add(contentID)
+	case afterID != "":
+		where += " AND c.id > " + args.add(afterID) + "::uuid"
+	}
+	query := `SELECT c.id::text, COALESCE(c.title, ''), COALESCE(c.description, ''), COALESCE(c.type, ''),
+			LOWER(COALESCE(c.owner_id, '')), COALESCE(c.thumbnail_url, ''), COALESCE(c.duration, 0),
+			COALESCE(c.tags, '{}'), c.created_at,
+			ARRAY(SELECT DISTINCT LOWER(g.contract_address) FROM content_gating_rules g
+				WHERE g.content_id = c.id AND g.is_active)
+		FROM contents c
+		WHERE ` + where + `
+		ORDER BY c.id
+		LIMIT ` + args.add(limit)
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load contents: %w", err)
+	}
+	defer rows.Close()
+	now := time.Now().UTC()
+	var docs []Document
+	for rows.Next() {
+		d := Document{IndexedAt: now}
+		if err := rows.Scan(&d.ContentID, &d.Title, &d.Description, &d.Type, &d.Creator, &d.ThumbnailURL,
+			&d.Duration, pq.Array(&d.Tags), &d.CreatedAt, pq.Array(&d.GatingContracts)); err != nil {
+			return nil, fmt.Errorf("failed to scan content: %w", err)
+		}
+		docs = append(docs, d)
+	}
+	return docs, rows.Err()
+}
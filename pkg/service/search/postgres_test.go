@@ -0,0 +1,68 @@
+package search
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresBackend_Search(t *testing.T) {
+	created := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	db := &queryDB{results: [][][]interface{}{
+		{{"cats", int64(4)}, {"pets", int64(2)}},
+		{{"0xabc", int64(4)}},
+		{{"c1", "Cats", "", "video", "0xabc", "", int64(60), "{pets,cats}", created, 0.5}},
+	}}
+	b := NewPostgresBackend(db)
+	q := Query{Text: "funny cats", Creator: "0xabc", Tags: []string{"cats"}, MinDuration: 30, MaxDuration: 600, GatingContract: "0xnft"}
+
+	hits, facets, err := b.Search(context.Background(), q, nil, 21, true)
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, Hit{ContentID: "c1", Title: "Cats", Type: "video", Creator: "0xabc", Duration: 60,
+		Tags: []string{"pets", "cats"}, CreatedAt: created, Score: 0.5}, hits[0])
+	assert.Equal(t, &Facets{
+		Tags:     []Facet{{Value: "cats", Count: 4}, {Value: "pets", Count: 2}},
+		Creators: []Facet{{Value: "0xabc", Count: 4}},
+	}, facets)
+
+	require.Len(t, db.queries, 3)
+	search := db.queries[2]
+	for _, clause := range []string{
+		visibleContent,
+		"c.search_vector @@ websearch_to_tsquery('simple', $1)",
+		"LOWER(c.owner_id) = $2",
+		"c.tags @> $3::text[]",
+		"c.duration >= $4",
+		"c.duration <= $5",
+		"LOWER(g.contract_address) = $6",
+		"ORDER BY rank DESC, c.created_at DESC, c.id DESC",
+		"LIMIT $7",
+	} {
+		assert.Contains(t, search, clause)
+	}
+	assert.Equal(t, []interface{}{"funny cats", "0xabc", pq.Array([]string{"cats"}), int64(30), int64(600), "0xnft", 21}, db.args[2])
+	// Facets share the filters of the search.
+	assert.Equal(t, db.args[2][:6], db.args[0])
+	assert.Contains(t, db.queries[0], "unnest(c.tags)")
+}
+
+func TestPostgresBackend_SearchAfterCursor(t *testing.T) {
+	db := &queryDB{}
+	b := NewPostgresBackend(db)
+	created := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	hits, facets, err := b.Search(context.Background(), Query{}, &Cursor{Score: 0, CreatedAt: created, ContentID: "c1"}, 11, false)
+	require.NoError(t, err)
+	assert.Empty(t, hits)
+	assert.Nil(t, facets)
+
+	require.Len(t, db.queries, 1, "no facets after the first page")
+	assert.Contains(t, db.queries[0], "(0::real, c.created_at, c.id) < ($1::real, $2, $3::uuid)")
+	assert.NotContains(t, db.queries[0], "search_vector @@")
+	assert.Equal(t, []interface{}{float64(0), created, "c1", 11}, db.args[0])
+}
@@ -0,0 +1,445 @@
+// Package search finds published content by full text, filtered by creator
+// wallet, tags, duration and gating contract. Postgres full-text search is
+// the default backend; Elasticsearch or OpenSearch can serve queries
+// instead, with their index kept in sync from Postgres.
+package search
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/service/serviceerrors"
+	"github.com/rtcdance/streamgate/pkg/storage"
+
+	"go.uber.org/zap"
+)
+
+// Page sizes.
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+const (
+	// facetSize caps the values returned per facet.
+	facetSize = 10
+	// maxTextLength caps the length of a free-text query.
+	maxTextLength = 256
+	// indexBatchSize is the number of documents loaded and indexed at once
+	// during a reindex.
+	indexBatchSize = 500
+	indexTimeout   = 30 * time.Second
+)
+
+// Query describes one search. Every filter that is set must match.
+type Query struct {
+	// Text is matched against titles, tags and descriptions. It accepts
+	// web search syntax: quoted phrases, "or" and -excluded words.
+	Text string
+	// Creator is the wallet that uploaded the content.
+	Creator string
+	// Tags must all be present on the content.
+	Tags []string
+	// MinDuration and MaxDuration bound the duration in seconds; zero
+	// leaves the bound open.
+	MinDuration int64
+	MaxDuration int64
+	// GatingContract only matches content gated by an active rule on
+	// this contract.
+	GatingContract string
+	// Limit is the page size, DefaultLimit when zero.
+	Limit int
+	// Cursor continues from the NextCursor of a previous page.
+	Cursor string
+}
+
+// Hit is one matching content item.
+type Hit struct {
+	ContentID    string    `json:"content_id"`
+	Title        string    `json:"title"`
+	Description  string    `json:"description,omitempty"`
+	Type         string    `json:"type,omitempty"`
+	Creator      string    `json:"creator,omitempty"`
+	ThumbnailURL string    `json:"thumbnail_url,omitempty"`
+	Duration     int64     `json:"duration"`
+	Tags         []string  `json:"tags"`
+	CreatedAt    time.Time `json:"created_at"`
+	Score        float64   `json:"score"`
+}
+
+// Facet is the number of matches with one value of a field.
+type Facet struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// Facets break the matches of a query down by tag and creator, most
+// frequent first.
+type Facets struct {
+	Tags     []Facet `json:"tags"`
+	Creators []Facet `json:"creators"`
+}
+
+// Result is one page of hits.
+type Result struct {
+	Hits []Hit `json:"hits"`
+	// NextCursor fetches the following page; it is empty on the last one.
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Facets cover every match of the query, so only the first page
+	// carries them.
+	Facets *Facets `json:"facets,omitempty"`
+}
+
+// Cursor is the position of the last hit of a page. Backends order hits by
+// score, creation time and content id, all descending, so pages stay
+// stable while content is added.
+type Cursor struct {
+	Score     float64   `json:"s"`
+	CreatedAt time.Time `json:"t"`
+	ContentID string    `json:"id"`
+	// Query fingerprints the query the cursor was issued for.
+	Query string `json:"q"`
+}
+
+// Backend runs searches.
+type Backend interface {
+	// Search returns up to limit hits of q that sort after the cursor, or
+	// from the start when after is nil, and the facets of q if asked.
+	Search(ctx context.Context, q Query, after *Cursor, limit int, withFacets bool) ([]Hit, *Facets, error)
+}
+
+// Indexer is implemented by backends that keep their own copy of the
+// searchable content, which the service syncs from Postgres.
+type Indexer interface {
+	// Put adds or replaces documents.
+	Put(ctx context.Context, docs []Document) error
+	// Remove deletes the document of a content item, if there is one.
+	Remove(ctx context.Context, contentID string) error
+	// RemoveStale deletes documents last indexed before the given time.
+	RemoveStale(ctx context.Context, before time.Time) error
+}
+
+// indexPreparer is implemented by indexers whose index must be created
+// before documents are added.
+type indexPreparer interface {
+	EnsureIndex(ctx context.Context) error
+}
+
+// Document is the searchable form of a content item.
+type Document struct {
+	ContentID       string    `json:"content_id"`
+	Title           string    `json:"title"`
+	Description     string    `json:"description"`
+	Type            string    `json:"type"`
+	Creator         string    `json:"creator"`
+	ThumbnailURL    string    `json:"thumbnail_url"`
+	Duration        int64     `json:"duration"`
+	Tags            []string  `json:"tags"`
+	GatingContracts []string  `json:"gating_contracts"`
+	CreatedAt       time.Time `json:"created_at"`
+	IndexedAt       time.Time `json:"indexed_at"`
+}
+
+func (d *Document) hit(score float64) Hit {
+	return Hit{
+		ContentID:    d.ContentID,
+		Title:        d.Title,
+		Description:  d.Description,
+		Type:         d.Type,
+		Creator:      d.Creator,
+		ThumbnailURL: d.ThumbnailURL,
+		Duration:     d.Duration,
+		Tags:         d.Tags,
+		CreatedAt:    d.CreatedAt,
+		Score:        score,
+	}
+}
+
+// Service validates queries, pages through backend results and keeps
+// indexing backends in sync.
+type Service struct {
+	db      storage.DB
+	backend Backend
+	indexer Indexer
+	logger  *zap.Logger
+
+	reindexing sync.Mutex
+	stop       chan struct{}
+	stopOnce   sync.Once
+	wg         sync.WaitGroup
+}
+
+// NewService returns a service searching through backend. Backends that
+// implement Indexer are fed the content in db.
+func NewService(db storage.DB, backend Backend, logger *zap.Logger) *Service {
+	s := &Service{db: db, backend: backend, logger: logger, stop: make(chan struct{})}
+	s.indexer, _ = backend.(Indexer)
+	return s
+}
+
+// Search returns one page of the content matching q. Only published
+// content that has not been held or rejected by moderation is searched.
+func (s *Service) Search(ctx context.Context, q Query) (*Result, error) {
+	q, err := normalize(q)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint := q.fingerprint()
+	var after *Cursor
+	if q.Cursor != "" {
+		if after, err = decodeCursor(q.Cursor, fingerprint); err != nil {
+			return nil, err
+		}
+	}
+
+	hits, facets, err := s.backend.Search(ctx, q, after, q.Limit+1, after == nil)
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+	res := &Result{Hits: hits, Facets: facets}
+	if len(hits) > q.Limit {
+		res.Hits = hits[:q.Limit]
+		last := res.Hits[q.Limit-1]
+		res.NextCursor = encodeCursor(Cursor{Score: last.Score, CreatedAt: last.CreatedAt, ContentID: last.ContentID, Query: fingerprint})
+	}
+	if res.Hits == nil {
+		res.Hits = []Hit{}
+	}
+	return res, nil
+}
+
+// ParseQuery reads a query from the URL parameters q, creator, tag
+// (repeatable) or comma-separated tags, min_duration, max_duration,
+// gating_contract, limit and cursor.
+func ParseQuery(v url.Values) (Query, error) {
+	q := Query{
+		Text:           v.Get("q"),
+		Creator:        v.Get("creator"),
+		Tags:           v["tag"],
+		GatingContract: v.Get("gating_contract"),
+		Cursor:         v.Get("cursor"),
+	}
+	if tags := v.Get("tags"); tags != "" {
+		q.Tags = append(q.Tags, strings.Split(tags, ",")...)
+	}
+	for _, p := range []struct {
+		name string
+		dst  *int64
+	}{{"min_duration", &q.MinDuration}, {"max_duration", &q.MaxDuration}} {
+		if s := v.Get(p.name); s != "" {
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return q, fmt.Errorf("%w: invalid %s %q", serviceerrors.ErrInvalidRequest, p.name, s)
+			}
+			*p.dst = n
+		}
+	}
+	if s := v.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return q, fmt.Errorf("%w: invalid limit %q", serviceerrors.ErrInvalidRequest, s)
+		}
+		q.Limit = n
+	}
+	return q, nil
+}
+
+// normalize trims q and applies the default limit, rejecting queries that
+// cannot match anything.
+func normalize(q Query) (Query, error) {
+	q.Text = strings.TrimSpace(q.Text)
+	if len(q.Text) > maxTextLength {
+		return q, fmt.Errorf("%w: query longer than %d characters", serviceerrors.ErrInvalidRequest, maxTextLength)
+	}
+	q.Creator = strings.ToLower(strings.TrimSpace(q.Creator))
+	q.GatingContract = strings.ToLower(strings.TrimSpace(q.GatingContract))
+
+	seen := make(map[string]bool, len(q.Tags))
+	tags := make([]string, 0, len(q.Tags))
+	for _, t := range q.Tags {
+		if t = strings.TrimSpace(t); t != "" && !seen[t] {
+			seen[t] = true
+			tags = append(tags, t)
+		}
+	}
+	sort.Strings(tags)
+	q.Tags = tags
+
+	if q.MinDuration < 0 || q.MaxDuration < 0 {
+		return q, fmt.Errorf("%w: durations must not be negative", serviceerrors.ErrInvalidRequest)
+	}
+	if q.MaxDuration > 0 && q.MinDuration > q.MaxDuration {
+		return q, fmt.Errorf("%w: min_duration exceeds max_duration", serviceerrors.ErrInvalidRequest)
+	}
+	switch {
+	case q.Limit < 0:
+		return q, fmt.Errorf("%w: limit must not be negative", serviceerrors.ErrInvalidRequest)
+	case q.Limit == 0:
+		q.Limit = DefaultLimit
+	case q.Limit > MaxLimit:
+		q.Limit = MaxLimit
+	}
+	return q, nil
+}
+
+// fingerprint identifies the filters of a normalized query, so a cursor
+// cannot be replayed against a different one.
+func (q Query) fingerprint() string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		q.Text, q.Creator, strings.Join(q.Tags, "\x1f"),
+		strconv.FormatInt(q.MinDuration, 10), strconv.FormatInt(q.MaxDuration, 10), q.GatingContract,
+	}, "\x1e")))
+	return hex.EncodeToString(sum[:8])
+}
+
+func encodeCursor(c Cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s, fingerprint string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", serviceerrors.ErrInvalidRequest)
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ContentID == "" {
+		return nil, fmt.Errorf("%w: malformed cursor", serviceerrors.ErrInvalidRequest)
+	}
+	if c.Query != fingerprint {
+		return nil, fmt.Errorf("%w: cursor belongs to a different query", serviceerrors.ErrInvalidRequest)
+	}
+	return &c, nil
+}
+
+// Indexed reports whether the backend keeps its own index.
+func (s *Service) Indexed() bool {
+	return s.indexer != nil
+}
+
+// Index refreshes the document of one content item, removing it once the
+// content is no longer searchable. It does nothing for backends without
+// an index.
+func (s *Service) Index(ctx context.Context, contentID string) error {
+	if s.indexer == nil {
+		return nil
+	}
+	docs, err := loadDocuments(ctx, s.db, contentID, "", 1)
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		return s.indexer.Remove(ctx, contentID)
+	}
+	return s.indexer.Put(ctx, docs)
+}
+
+// IndexAsync indexes a content item in the background.
+func (s *Service) IndexAsync(contentID string) {
+	if s.indexer == nil {
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), indexTimeout)
+		defer cancel()
+		if err := s.Index(ctx, contentID); err != nil {
+			s.logger.Warn("Failed to index content", zap.String("content_id", contentID), zap.Error(err))
+		}
+	}()
+}
+
+// Reindex rebuilds the backend's index from Postgres and returns the
+// number of documents indexed. Documents of content that is no longer
+// searchable are removed.
+func (s *Service) Reindex(ctx context.Context) (int, error) {
+	if s.indexer == nil {
+		return 0, fmt.Errorf("%w: the search backend has no index to rebuild", serviceerrors.ErrNotSupported)
+	}
+	if !s.reindexing.TryLock() {
+		return 0, fmt.Errorf("%w: reindex already in progress", serviceerrors.ErrAlreadyExists)
+	}
+	defer s.reindexing.Unlock()
+
+	if p, ok := s.indexer.(indexPreparer); ok {
+		if err := p.EnsureIndex(ctx); err != nil {
+			return 0, err
+		}
+	}
+	started := time.Now()
+	indexed := 0
+	after := ""
+	for {
+		docs, err := loadDocuments(ctx, s.db, "", after, indexBatchSize)
+		if err != nil {
+			return indexed, err
+		}
+		if len(docs) == 0 {
+			break
+		}
+		if err := s.indexer.Put(ctx, docs); err != nil {
+			return indexed, err
+		}
+		indexed += len(docs)
+		after = docs[len(docs)-1].ContentID
+		if len(docs) < indexBatchSize {
+			break
+		}
+	}
+	if err := s.indexer.RemoveStale(ctx, started); err != nil {
+		return indexed, err
+	}
+	s.logger.Info("Search index rebuilt", zap.Int("documents", indexed), zap.Duration("took", time.Since(started)))
+	return indexed, nil
+}
+
+// Start rebuilds the index now and then every interval until Close.
+// Moderation and gating changes reach the index this way.
+func (s *Service) Start(interval time.Duration) {
+	if s.indexer == nil || interval <= 0 {
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			select {
+			case <-s.stop:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if _, err := s.Reindex(ctx); err != nil && !errors.Is(err, serviceerrors.ErrAlreadyExists) && ctx.Err() == nil {
+				s.logger.Warn("Scheduled search reindex failed", zap.Error(err))
+			}
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Close stops scheduled reindexing and waits for indexing in progress.
+func (s *Service) Close() {
+	s.stopOnce.Do(func() { close(s.stop) })
+	s.wg.Wait()
+}
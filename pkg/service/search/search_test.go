@@ -0,0 +1,290 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/service/serviceerrors"
+	stg "github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// queryDB records queries and answers each with the next queued result
+// set. Array columns are given as Postgres array literals.
+type queryDB struct {
+	mu      sync.Mutex
+	queries []string
+	args    [][]interface{}
+	results [][][]interface{}
+}
+
+func (d *queryDB) Query(_ context.Context, query string, args ...interface{}) (stg.Rows, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queries = append(d.queries, query)
+	d.args = append(d.args, args)
+	rows := &memRows{}
+	if len(d.results) > 0 {
+		rows.rows, d.results = d.results[0], d.results[1:]
+	}
+	return rows, nil
+}
+
+func (d *queryDB) QueryRow(context.Context, string, ...interface{}) *stg.CancelRow {
+	return stg.NewErrorCancelRow(errors.New("not implemented"))
+}
+func (d *queryDB) Exec(context.Context, string, ...interface{}) (sql.Result, error) {
+	return nil, errors.New("not implemented")
+}
+func (d *queryDB) Begin(context.Context) (*sql.Tx, error) { return nil, errors.New("not implemented") }
+func (d *queryDB) InTransaction(context.Context, func(tx *sql.Tx) error) error {
+	return errors.New("not implemented")
+}
+func (d *queryDB) Ping(context.Context) error { return nil }
+func (d *queryDB) Close() error               { return nil }
+
+type memRows struct {
+	rows [][]interface{}
+	i    int
+}
+
+func (r *memRows) Next() bool { r.i++; return r.i <= len(r.rows) }
+func (r *memRows) Scan(dest ...interface{}) error {
+	row := r.rows[r.i-1]
+	for i, d := range dest {
+		switch p := d.(type) {
+		case *string:
+			*p = row[i].(string)
+		case *int64:
+			*p = row[i].(int64)
+		case *float64:
+			*p = row[i].(float64)
+		case *time.Time:
+			*p = row[i].(time.Time)
+		case sql.Scanner:
+			if err := p.Scan([]byte(row[i].(string))); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unexpected scan destination %T", d)
+		}
+	}
+	return nil
+}
+func (r *memRows) Close() error { return nil }
+func (r *memRows) Err() error   { return nil }
+
+// memBackend searches documents in memory in the backends' sort order.
+type memBackend struct {
+	mu      sync.Mutex
+	docs    map[string]Document
+	removed []string
+}
+
+func (b *memBackend) Search(_ context.Context, q Query, after *Cursor, limit int, withFacets bool) ([]Hit, *Facets, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var hits []Hit
+	for _, d := range b.docs {
+		if q.Creator != "" && d.Creator != q.Creator {
+			continue
+		}
+		var score float64
+		if q.Text != "" {
+			score = float64(strings.Count(strings.ToLower(d.Title), strings.ToLower(q.Text)))
+		}
+		hits = append(hits, d.hit(score))
+	}
+	less := func(a, b Hit) bool {
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ContentID > b.ContentID
+	}
+	sort.Slice(hits, func(i, j int) bool { return less(hits[i], hits[j]) })
+	if after != nil {
+		pos := Hit{Score: after.Score, CreatedAt: after.CreatedAt, ContentID: after.ContentID}
+		i := sort.Search(len(hits), func(i int) bool { return less(pos, hits[i]) })
+		hits = hits[i:]
+	}
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	var facets *Facets
+	if withFacets {
+		facets = &Facets{Tags: []Facet{}, Creators: []Facet{}}
+	}
+	return hits, facets, nil
+}
+
+func (b *memBackend) Put(_ context.Context, docs []Document) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, d := range docs {
+		b.docs[d.ContentID] = d
+	}
+	return nil
+}
+
+func (b *memBackend) Remove(_ context.Context, contentID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.docs, contentID)
+	b.removed = append(b.removed, contentID)
+	return nil
+}
+
+func (b *memBackend) RemoveStale(_ context.Context, before time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for id, d := range b.docs {
+		if d.IndexedAt.Before(before) {
+			delete(b.docs, id)
+		}
+	}
+	return nil
+}
+
+func TestService_PagesWithStableCursors(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	backend := &memBackend{docs: map[string]Document{}}
+	for i := 0; i < 7; i++ {
+		id := fmt.Sprintf("c%d", i)
+		// Pairs share a creation time so the id breaks ties.
+		backend.docs[id] = Document{ContentID: id, Title: "clip", Creator: "0xabc", CreatedAt: base.Add(time.Duration(i/2) * time.Hour)}
+	}
+	svc := NewService(nil, backend, zap.NewNop())
+	ctx := context.Background()
+
+	var seen []string
+	q := Query{Creator: "0xABC", Limit: 3}
+	for page := 0; ; page++ {
+		res, err := svc.Search(ctx, q)
+		require.NoError(t, err)
+		assert.Equal(t, page == 0, res.Facets != nil, "only the first page carries facets")
+		for _, h := range res.Hits {
+			seen = append(seen, h.ContentID)
+		}
+		if res.NextCursor == "" {
+			break
+		}
+		q.Cursor = res.NextCursor
+		// Content added mid-way sorts before the cursor and does not shift pages.
+		backend.docs["new"] = Document{ContentID: "new", Title: "clip", Creator: "0xabc", CreatedAt: base.Add(24 * time.Hour)}
+	}
+	assert.Equal(t, []string{"c6", "c5", "c4", "c3", "c2", "c1", "c0"}, seen)
+
+	// A cursor only continues the query it was issued for.
+	first, err := svc.Search(ctx, Query{Creator: "0xabc", Limit: 2})
+	require.NoError(t, err)
+	_, err = svc.Search(ctx, Query{Creator: "0xdef", Limit: 2, Cursor: first.NextCursor})
+	assert.ErrorIs(t, err, serviceerrors.ErrInvalidRequest)
+}
+
+func TestService_Validation(t *testing.T) {
+	svc := NewService(nil, &memBackend{docs: map[string]Document{}}, zap.NewNop())
+
+	tests := []struct {
+		name string
+		q    Query
+	}{
+		{"negative limit", Query{Limit: -1}},
+		{"negative duration", Query{MinDuration: -5}},
+		{"inverted duration range", Query{MinDuration: 60, MaxDuration: 30}},
+		{"long query", Query{Text: strings.Repeat("a", maxTextLength+1)}},
+		{"malformed cursor", Query{Cursor: "not a cursor!"}},
+		{"cursor without position", Query{Cursor: encodeCursor(Cursor{Query: Query{Limit: DefaultLimit}.fingerprint()})}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Search(context.Background(), tt.q)
+			assert.ErrorIs(t, err, serviceerrors.ErrInvalidRequest)
+		})
+	}
+
+	q, err := normalize(Query{Text: "  cats ", Tags: []string{"b", " a", "b", ""}, Limit: 1000})
+	require.NoError(t, err)
+	assert.Equal(t, Query{Text: "cats", Tags: []string{"a", "b"}, Limit: MaxLimit}, q)
+	assert.Equal(t, q.fingerprint(), Query{Text: "cats", Tags: []string{"a", "b"}, Limit: 5}.fingerprint(),
+		"the page size does not change the query")
+}
+
+func TestService_Reindex(t *testing.T) {
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	db := &queryDB{results: [][][]interface{}{{
+		{"c1", "Cats", "", "video", "0xabc", "", int64(60), "{pets,cats}", created, "{0xnft}"},
+		{"c2", "Dogs", "", "video", "0xabc", "", int64(90), "{}", created, "{}"},
+	}}}
+	backend := &memBackend{docs: map[string]Document{
+		"gone": {ContentID: "gone", IndexedAt: created},
+	}}
+	svc := NewService(db, backend, zap.NewNop())
+
+	n, err := svc.Reindex(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"pets", "cats"}, backend.docs["c1"].Tags)
+	assert.Equal(t, []string{"0xnft"}, backend.docs["c1"].GatingContracts)
+	assert.NotContains(t, backend.docs, "gone", "documents not refreshed by the rebuild are removed")
+	assert.Contains(t, db.queries[0], visibleContent)
+
+	// Content that is no longer searchable is removed from the index.
+	require.NoError(t, svc.Index(context.Background(), "c2"))
+	assert.Equal(t, []string{"c2"}, backend.removed)
+	assert.Equal(t, []interface{}{"c2", 1}, db.args[1])
+
+	svc.reindexing.Lock()
+	_, err = svc.Reindex(context.Background())
+	assert.ErrorIs(t, err, serviceerrors.ErrAlreadyExists)
+	svc.reindexing.Unlock()
+
+	pg := NewService(db, NewPostgresBackend(db), zap.NewNop())
+	_, err = pg.Reindex(context.Background())
+	assert.ErrorIs(t, err, serviceerrors.ErrNotSupported)
+	assert.NoError(t, pg.Index(context.Background(), "c1"))
+	pg.Start(time.Hour)
+	pg.Close()
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    Query
+		wantErr bool
+	}{
+		{"empty", "", Query{}, false},
+		{"all filters", "q=cats&creator=0xabc&tag=a&tag=b&tags=c,d&min_duration=30&max_duration=600&gating_contract=0xnft&limit=5&cursor=xyz",
+			Query{Text: "cats", Creator: "0xabc", Tags: []string{"a", "b", "c", "d"}, MinDuration: 30, MaxDuration: 600,
+				GatingContract: "0xnft", Limit: 5, Cursor: "xyz"}, false},
+		{"bad duration", "min_duration=long", Query{}, true},
+		{"bad limit", "limit=ten", Query{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := url.ParseQuery(tt.raw)
+			require.NoError(t, err)
+			got, err := ParseQuery(v)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, serviceerrors.ErrInvalidRequest)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
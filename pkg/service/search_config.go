@@ -0,0 +1,53 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/rtcdance/streamgate/pkg/storage"
+
+	"go.uber.org/zap"
+)
+
+// NewSearchServiceFromConfig builds the metadata search service described
+// by cfg over db, or returns nil when search is disabled. The returned
+// interval is how often the search index is rebuilt; it is zero for the
+// postgres driver, which searches the database directly.
+func NewSearchServiceFromConfig(db storage.DB, cfg config.SearchConfig, logger *zap.Logger) (*SearchService, time.Duration, error) {
+	if !cfg.Enabled {
+		return nil, 0, nil
+	}
+	switch cfg.Driver {
+	case "", "postgres":
+		return NewSearchService(db, NewPostgresSearchBackend(db), logger), 0, nil
+	case "elasticsearch", "opensearch":
+	default:
+		return nil, 0, fmt.Errorf("unknown search driver %q", cfg.Driver)
+	}
+
+	var timeout, interval time.Duration
+	var err error
+	if cfg.Elasticsearch.Timeout != "" {
+		if timeout, err = time.ParseDuration(cfg.Elasticsearch.Timeout); err != nil || timeout <= 0 {
+			return nil, 0, fmt.Errorf("invalid search.elasticsearch.timeout %q", cfg.Elasticsearch.Timeout)
+		}
+	}
+	if cfg.ReindexInterval != "" {
+		if interval, err = time.ParseDuration(cfg.ReindexInterval); err != nil || interval < 0 {
+			return nil, 0, fmt.Errorf("invalid search.reindex_interval %q", cfg.ReindexInterval)
+		}
+	}
+	backend, err := NewElasticsearchBackend(ElasticsearchSearchConfig{
+		URL:      cfg.Elasticsearch.URL,
+		Index:    cfg.Elasticsearch.Index,
+		APIKey:   cfg.Elasticsearch.APIKey,
+		Username: cfg.Elasticsearch.Username,
+		Password: cfg.Elasticsearch.Password,
+		Timeout:  timeout,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return NewSearchService(db, backend, logger), interval, nil
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNewSearchServiceFromConfig(t *testing.T) {
+	elastic := config.SearchConfig{
+		Enabled:         true,
+		Driver:          "elasticsearch",
+		Elasticsearch:   config.ElasticsearchSearchConfig{URL: "http://elasticsearch:9200", Timeout: "5s"},
+		ReindexInterval: "1h",
+	}
+	tests := []struct {
+		name         string
+		cfg          config.SearchConfig
+		wantNil      bool
+		wantIndexed  bool
+		wantInterval time.Duration
+		wantErr      bool
+	}{
+		{name: "postgres", cfg: config.SearchConfig{Enabled: true, Driver: "postgres", ReindexInterval: "1h"}},
+		{name: "default driver", cfg: config.SearchConfig{Enabled: true}},
+		{name: "disabled", cfg: config.SearchConfig{Driver: "postgres"}, wantNil: true},
+		{name: "elasticsearch", cfg: elastic, wantIndexed: true, wantInterval: time.Hour},
+		{name: "unknown driver", cfg: config.SearchConfig{Enabled: true, Driver: "solr"}, wantErr: true},
+		{name: "no elasticsearch url", cfg: func() config.SearchConfig { c := elastic; c.Elasticsearch.URL = ""; return c }(), wantErr: true},
+		{name: "bad timeout", cfg: func() config.SearchConfig { c := elastic; c.Elasticsearch.Timeout = "soon"; return c }(), wantErr: true},
+		{name: "bad interval", cfg: func() config.SearchConfig { c := elastic; c.ReindexInterval = "-1h"; return c }(), wantErr: true},
+		{name: "bad index", cfg: func() config.SearchConfig { c := elastic; c.Elasticsearch.Index = "Content"; return c }(), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, interval, err := NewSearchServiceFromConfig(nil, tt.cfg, zap.NewNop())
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.wantNil {
+				assert.Nil(t, svc)
+				return
+			}
+			require.NotNil(t, svc)
+			assert.Equal(t, tt.wantIndexed, svc.Indexed())
+			assert.Equal(t, tt.wantInterval, interval)
+		})
+	}
+}
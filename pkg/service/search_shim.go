@@ -0,0 +1,19 @@
+package service
+
+import "github.com/rtcdance/streamgate/pkg/service/search"
+
+type (
+	SearchService             = search.Service
+	SearchQuery               = search.Query
+	SearchResult              = search.Result
+	SearchHit                 = search.Hit
+	SearchBackend             = search.Backend
+	ElasticsearchSearchConfig = search.ElasticsearchConfig
+)
+
+var (
+	NewSearchService         = search.NewService
+	NewPostgresSearchBackend = search.NewPostgresBackend
+	NewElasticsearchBackend  = search.NewElasticsearchBackend
+	ParseSearchQuery         = search.ParseQuery
+)
@@ -401,7 +401,7 @@ func TestStreamingService_GenerateHLSPlaylist_Coverage(t *testing.T) {
 		"720p":  {"segment0.ts", "segment1.ts"},
 	}
 
-	playlist, err := svc.GenerateHLSPlaylist("content-1", qualitySegments, "{{PLAYBACK_TOKEN}}")
+	playlist, err := svc.GenerateHLSPlaylist("content-1", qualitySegments, "{{PLAYBACK_TOKEN}}", false)
 	require.NoError(t, err)
 	assert.Contains(t, playlist, "#EXTM3U")
 	assert.Contains(t, playlist, "#EXT-X-STREAM-INF")
@@ -11,7 +11,7 @@ import (
 
 func TestStreamingExt_GenerateHLSPlaylist_EmptySegments(t *testing.T) {
 	svc := NewStreamingService(nil, nil, newMockCache(), "http://cdn.example.com")
-	_, err := svc.GenerateHLSPlaylist("content-1", map[string][]string{}, "token")
+	_, err := svc.GenerateHLSPlaylist("content-1", map[string][]string{}, "token", false)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "no segments available")
 }
@@ -21,7 +21,7 @@ func TestStreamingExt_GenerateHLSPlaylist_SingleQuality(t *testing.T) {
 	segments := map[string][]string{
 		"720p": {"segment0.ts", "segment1.ts"},
 	}
-	playlist, err := svc.GenerateHLSPlaylist("content-1", segments, "playback-token")
+	playlist, err := svc.GenerateHLSPlaylist("content-1", segments, "playback-token", false)
 	require.NoError(t, err)
 	assert.Contains(t, playlist, "#EXTM3U")
 	assert.Contains(t, playlist, "#EXTINF")
@@ -36,7 +36,7 @@ func TestStreamingExt_GenerateHLSPlaylist_MultiQuality(t *testing.T) {
 		"720p":  {"segment0.ts"},
 		"480p":  {"segment0.ts"},
 	}
-	playlist, err := svc.GenerateHLSPlaylist("content-1", segments, "token")
+	playlist, err := svc.GenerateHLSPlaylist("content-1", segments, "token", false)
 	require.NoError(t, err)
 	assert.Contains(t, playlist, "#EXTM3U")
 	assert.Contains(t, playlist, "#EXT-X-STREAM-INF")
@@ -145,7 +145,7 @@ func TestStreamingExt_GenerateHLSPlaylist_UnknownQuality(t *testing.T) {
 		"4k":   {"segment0.ts"},
 		"720p": {"segment0.ts"},
 	}
-	playlist, err := svc.GenerateHLSPlaylist("content-1", segments, "token")
+	playlist, err := svc.GenerateHLSPlaylist("content-1", segments, "token", false)
 	require.NoError(t, err)
 	assert.Contains(t, playlist, "#EXTM3U")
 	assert.Contains(t, playlist, "#EXT-X-STREAM-INF")
@@ -41,7 +41,7 @@ func TestStreamingService_GenerateHLSPlaylist(t *testing.T) {
 		"480p":  {"seg000.ts", "seg001.ts", "seg002.ts"},
 	}
 
-	playlist, err := svc.GenerateHLSPlaylist("content-123", qualitySegments, "test-token")
+	playlist, err := svc.GenerateHLSPlaylist("content-123", qualitySegments, "test-token", false)
 	require.NoError(t, err)
 
 	assert.True(t, strings.HasPrefix(playlist, "#EXTM3U"))
@@ -0,0 +1,133 @@
+package streamingsvc
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/rtcdance/streamgate/pkg/monitoring"
+)
+
+// DefaultEgressCountryHeader is the header the country code is read from
+// when none is configured.
+const DefaultEgressCountryHeader = "CF-IPCountry"
+
+// originRegion labels manifests whose segments are served by the gateway.
+const originRegion = "origin"
+
+// EgressRegion is a regional edge that serves segments for a set of
+// countries.
+type EgressRegion struct {
+	Name string
+	// BaseURL is the scheme and host of the edge, e.g.
+	// "https://eu.cdn.example.com". Segment paths are appended unchanged.
+	BaseURL string
+	// Countries are ISO 3166-1 alpha-2 codes routed to this region.
+	Countries []string
+}
+
+// EgressRouter picks the edge a client's segments are served from.
+type EgressRouter struct {
+	countryHeader string
+	defaultRegion string
+	baseURLs      map[string]string
+	countries     map[string]string
+}
+
+// NewEgressRouter builds a router from regions. Clients whose country is
+// not mapped use defaultRegion, or origin when defaultRegion is empty.
+func NewEgressRouter(countryHeader, defaultRegion string, regions []EgressRegion) (*EgressRouter, error) {
+	if countryHeader == "" {
+		countryHeader = DefaultEgressCountryHeader
+	}
+	r := &EgressRouter{
+		countryHeader: countryHeader,
+		defaultRegion: defaultRegion,
+		baseURLs:      make(map[string]string, len(regions)),
+		countries:     make(map[string]string),
+	}
+	for _, region := range regions {
+		if region.Name == "" {
+			return nil, fmt.Errorf("egress region with base URL %q has no name", region.BaseURL)
+		}
+		if _, dup := r.baseURLs[region.Name]; dup {
+			return nil, fmt.Errorf("duplicate egress region %q", region.Name)
+		}
+		u, err := url.Parse(region.BaseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("egress region %q: base URL %q must be an absolute http(s) URL", region.Name, region.BaseURL)
+		}
+		r.baseURLs[region.Name] = strings.TrimSuffix(region.BaseURL, "/")
+		for _, cc := range region.Countries {
+			cc = strings.ToUpper(strings.TrimSpace(cc))
+			if prev, ok := r.countries[cc]; ok {
+				return nil, fmt.Errorf("country %s mapped to both %q and %q", cc, prev, region.Name)
+			}
+			r.countries[cc] = region.Name
+		}
+	}
+	if defaultRegion != "" {
+		if _, ok := r.baseURLs[defaultRegion]; !ok {
+			return nil, fmt.Errorf("default egress region %q is not defined", defaultRegion)
+		}
+	}
+	return r, nil
+}
+
+// Resolve returns the region and edge base URL for a country code. An
+// empty base URL means origin.
+func (r *EgressRouter) Resolve(country string) (region, baseURL string) {
+	if r == nil {
+		return originRegion, ""
+	}
+	region, ok := r.countries[strings.ToUpper(strings.TrimSpace(country))]
+	if !ok {
+		region = r.defaultRegion
+	}
+	if region == "" {
+		return originRegion, ""
+	}
+	return region, r.baseURLs[region]
+}
+
+// ResolveRequest resolves the region for a request from its country header.
+func (r *EgressRouter) ResolveRequest(h http.Header) (region, baseURL string) {
+	if r == nil {
+		return originRegion, ""
+	}
+	return r.Resolve(h.Get(r.countryHeader))
+}
+
+// RewriteSegmentURLs points every segment URL in an HLS manifest at
+// baseURL. Variant playlist URLs are left on origin, since they carry the
+// playback token check. An empty baseURL returns the manifest unchanged.
+func RewriteSegmentURLs(manifest, baseURL string) string {
+	if baseURL == "" {
+		return manifest
+	}
+	lines := strings.Split(manifest, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "/api/v1/streaming/") && strings.Contains(line, "/segment/") {
+			lines[i] = baseURL + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SetEgressRouter enables regional segment delivery. A nil router serves
+// every client from origin.
+func (s *StreamingService) SetEgressRouter(r *EgressRouter) {
+	s.egress = r
+}
+
+// RouteSegmentURLs rewrites a rendered manifest's segment URLs to the edge
+// for the client whose request headers are h.
+func (s *StreamingService) RouteSegmentURLs(manifest string, h http.Header) string {
+	if s.egress == nil {
+		return manifest
+	}
+	region, baseURL := s.egress.ResolveRequest(h)
+	monitoring.StreamingEgressRoutedTotal.WithLabelValues(region).Inc()
+	return RewriteSegmentURLs(manifest, baseURL)
+}
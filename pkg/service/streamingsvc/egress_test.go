@@ -0,0 +1,136 @@
+package streamingsvc
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEgressRegions() []EgressRegion {
+	return []EgressRegion{
+		{Name: "eu", BaseURL: "https://eu.cdn.example.com/", Countries: []string{"DE", "fr", " NL "}},
+		{Name: "us", BaseURL: "https://us.cdn.example.com", Countries: []string{"US", "CA"}},
+	}
+}
+
+func countryHeader(cc string) http.Header {
+	h := http.Header{}
+	if cc != "" {
+		h.Set(DefaultEgressCountryHeader, cc)
+	}
+	return h
+}
+
+func egressRoutedCount(t *testing.T, region string) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, mf := range families {
+		if mf.GetName() != "streamgate_streaming_egress_routed_total" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			if m.GetLabel()[0].GetValue() == region {
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestEgressRouter_Resolve(t *testing.T) {
+	r, err := NewEgressRouter("", "us", testEgressRegions())
+	require.NoError(t, err)
+
+	region, base := r.Resolve("de")
+	assert.Equal(t, "eu", region)
+	assert.Equal(t, "https://eu.cdn.example.com", base, "trailing slash is trimmed")
+
+	region, _ = r.Resolve("NL")
+	assert.Equal(t, "eu", region)
+
+	region, base = r.Resolve("JP")
+	assert.Equal(t, "us", region, "unmapped countries use the default region")
+	assert.Equal(t, "https://us.cdn.example.com", base)
+
+	noDefault, err := NewEgressRouter("", "", testEgressRegions())
+	require.NoError(t, err)
+	region, base = noDefault.Resolve("JP")
+	assert.Equal(t, "origin", region)
+	assert.Empty(t, base)
+}
+
+func TestNewEgressRouter_Invalid(t *testing.T) {
+	cases := map[string]struct {
+		def     string
+		regions []EgressRegion
+	}{
+		"missing name":     {regions: []EgressRegion{{BaseURL: "https://a.example.com"}}},
+		"relative URL":     {regions: []EgressRegion{{Name: "eu", BaseURL: "/eu"}}},
+		"bad scheme":       {regions: []EgressRegion{{Name: "eu", BaseURL: "ftp://eu.example.com"}}},
+		"duplicate region": {regions: []EgressRegion{{Name: "eu", BaseURL: "https://a.example.com"}, {Name: "eu", BaseURL: "https://b.example.com"}}},
+		"country in two regions": {regions: []EgressRegion{
+			{Name: "eu", BaseURL: "https://a.example.com", Countries: []string{"GB"}},
+			{Name: "uk", BaseURL: "https://b.example.com", Countries: []string{"gb"}},
+		}},
+		"unknown default": {def: "apac", regions: testEgressRegions()},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewEgressRouter("", tc.def, tc.regions)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestStreamingService_RouteSegmentURLs(t *testing.T) {
+	r, err := NewEgressRouter("", "us", testEgressRegions())
+	require.NoError(t, err)
+	s := NewStreamingService(nil, nil, nil, "")
+	s.SetEgressRouter(r)
+
+	playlist := s.GenerateMediaPlaylist("content-1", "720p", syntheticSegments(3), "tok", false)
+
+	t.Run("EU client gets EU segment URLs", func(t *testing.T) {
+		before := egressRoutedCount(t, "eu")
+		routed := s.RouteSegmentURLs(playlist, countryHeader("DE"))
+		assert.Equal(t, 3, strings.Count(routed, "\nhttps://eu.cdn.example.com/api/v1/streaming/content-1/segment/"))
+		assert.NotContains(t, routed, "\n/api/v1/streaming/")
+		assert.Contains(t, routed, "#EXT-X-ENDLIST\n")
+		assert.Equal(t, before+1, egressRoutedCount(t, "eu"))
+	})
+
+	t.Run("unmapped client gets the default region", func(t *testing.T) {
+		for _, h := range []http.Header{countryHeader("JP"), countryHeader("")} {
+			routed := s.RouteSegmentURLs(playlist, h)
+			assert.Equal(t, 3, strings.Count(routed, "\nhttps://us.cdn.example.com/api/v1/streaming/content-1/segment/"))
+		}
+	})
+
+	t.Run("master playlist variants stay on origin", func(t *testing.T) {
+		master := BuildMasterPlaylist("content-1", map[string][]string{"720p": syntheticSegments(1)}, "tok")
+		assert.Equal(t, master, s.RouteSegmentURLs(master, countryHeader("DE")))
+	})
+
+	t.Run("no router serves from origin", func(t *testing.T) {
+		plain := NewStreamingService(nil, nil, nil, "")
+		assert.Equal(t, playlist, plain.RouteSegmentURLs(playlist, countryHeader("DE")))
+	})
+}
+
+func TestEgressRouter_CustomCountryHeader(t *testing.T) {
+	r, err := NewEgressRouter("X-Geo-Country", "", testEgressRegions())
+	require.NoError(t, err)
+
+	h := http.Header{}
+	h.Set("X-Geo-Country", "FR")
+	region, _ := r.ResolveRequest(h)
+	assert.Equal(t, "eu", region)
+
+	region, _ = r.ResolveRequest(countryHeader("FR"))
+	assert.Equal(t, "origin", region, "the default header is ignored when another is configured")
+}
@@ -0,0 +1,127 @@
+package streamingsvc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rtcdance/streamgate/pkg/monitoring"
+)
+
+const (
+	// DefaultMaxManifestSegments caps a VOD media playlist at roughly 16
+	// hours of 6-second segments.
+	DefaultMaxManifestSegments = 10000
+	// DefaultManifestWarnRatio is the fraction of the cap at which manifests
+	// start being reported as near the limit.
+	DefaultManifestWarnRatio = 0.8
+	// DefaultLiveWindowSegments is how many of the newest segments a live
+	// playlist advertises.
+	DefaultLiveWindowSegments = 30
+)
+
+// ManifestLimits bounds the size of generated media playlists so a
+// pathologically long video cannot bloat manifests or the memory spent
+// rendering them.
+type ManifestLimits struct {
+	// MaxSegments caps the segments listed in a VOD playlist; later
+	// segments are dropped.
+	MaxSegments int
+	// WarnRatio of MaxSegments at which a playlist is reported as near the
+	// limit.
+	WarnRatio float64
+	// LiveWindow is the sliding window size for live playlists.
+	LiveWindow int
+}
+
+// DefaultManifestLimits returns the limits used when none are configured.
+func DefaultManifestLimits() ManifestLimits {
+	return ManifestLimits{
+		MaxSegments: DefaultMaxManifestSegments,
+		WarnRatio:   DefaultManifestWarnRatio,
+		LiveWindow:  DefaultLiveWindowSegments,
+	}
+}
+
+func (l ManifestLimits) withDefaults() ManifestLimits {
+	d := DefaultManifestLimits()
+	if l.MaxSegments <= 0 {
+		l.MaxSegments = d.MaxSegments
+	}
+	if l.WarnRatio <= 0 || l.WarnRatio > 1 {
+		l.WarnRatio = d.WarnRatio
+	}
+	if l.LiveWindow <= 0 {
+		l.LiveWindow = d.LiveWindow
+	}
+	return l
+}
+
+// SegmentWindow is the slice of a variant's segments a playlist lists.
+type SegmentWindow struct {
+	Segments []string
+	// MediaSequence is the sequence number of Segments[0].
+	MediaSequence int
+	// Live playlists omit EXT-X-ENDLIST so players keep polling.
+	Live bool
+	// Total is the number of segments before limiting.
+	Total int
+	// Truncated is set when a VOD playlist was cut at MaxSegments.
+	Truncated bool
+	// NearLimit is set when Total reached WarnRatio of MaxSegments.
+	NearLimit bool
+}
+
+// Apply selects the segments to list. Live playlists keep the newest
+// LiveWindow segments; VOD playlists keep the first MaxSegments. Outcomes
+// are recorded on streamgate_streaming_manifest_limit_total.
+func (l ManifestLimits) Apply(segments []string, live bool) SegmentWindow {
+	l = l.withDefaults()
+	w := SegmentWindow{Segments: segments, Live: live, Total: len(segments)}
+	monitoring.StreamingManifestSegments.Observe(float64(len(segments)))
+
+	if live {
+		if len(segments) > l.LiveWindow {
+			w.MediaSequence = len(segments) - l.LiveWindow
+			w.Segments = segments[w.MediaSequence:]
+			monitoring.StreamingManifestLimitTotal.WithLabelValues("windowed").Inc()
+		}
+		return w
+	}
+
+	if float64(len(segments)) >= l.WarnRatio*float64(l.MaxSegments) {
+		w.NearLimit = true
+		monitoring.StreamingManifestLimitTotal.WithLabelValues("near_limit").Inc()
+	}
+	if len(segments) > l.MaxSegments {
+		w.Segments = segments[:l.MaxSegments]
+		w.Truncated = true
+		monitoring.StreamingManifestLimitTotal.WithLabelValues("truncated").Inc()
+	}
+	return w
+}
+
+// BuildWindowedMediaPlaylist renders a media playlist for w. An empty
+// quality produces segment URLs without a quality parameter, as
+// BuildSimplePlaylist does.
+func BuildWindowedMediaPlaylist(contentID, quality string, w SegmentWindow, playbackToken string) string {
+	var b strings.Builder
+	// Each entry is ~120 bytes plus the IDs and token; sizing up front
+	// avoids repeated regrowth on long playlists.
+	b.Grow(len(w.Segments) * (160 + len(contentID) + len(playbackToken)))
+	fmt.Fprintf(&b, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:10\n#EXT-X-MEDIA-SEQUENCE:%d\n", w.MediaSequence)
+	for _, seg := range w.Segments {
+		name := seg
+		if idx := strings.LastIndex(seg, "/"); idx >= 0 {
+			name = seg[idx+1:]
+		}
+		if quality == "" {
+			fmt.Fprintf(&b, "#EXTINF:6.0,\n/api/v1/streaming/%s/segment/%s?playback_token=%s\n", contentID, name, playbackToken)
+		} else {
+			fmt.Fprintf(&b, "#EXTINF:6.0,\n/api/v1/streaming/%s/segment/%s?quality=%s&playback_token=%s\n", contentID, name, quality, playbackToken)
+		}
+	}
+	if !w.Live {
+		b.WriteString("#EXT-X-ENDLIST\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,144 @@
+package streamingsvc
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func syntheticSegments(n int) []string {
+	segs := make([]string, n)
+	for i := range segs {
+		segs[i] = fmt.Sprintf("720p/segment_%05d.ts", i)
+	}
+	return segs
+}
+
+func manifestLimitCount(t *testing.T, outcome string) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, mf := range families {
+		if mf.GetName() != "streamgate_streaming_manifest_limit_total" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			if m.GetLabel()[0].GetValue() == outcome {
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestManifestLimits_Apply(t *testing.T) {
+	limits := ManifestLimits{MaxSegments: 1000, WarnRatio: 0.8, LiveWindow: 10}
+
+	t.Run("under warn threshold is untouched", func(t *testing.T) {
+		w := limits.Apply(syntheticSegments(500), false)
+		assert.Len(t, w.Segments, 500)
+		assert.False(t, w.NearLimit)
+		assert.False(t, w.Truncated)
+	})
+
+	t.Run("near limit warns but keeps every segment", func(t *testing.T) {
+		before := manifestLimitCount(t, "near_limit")
+		w := limits.Apply(syntheticSegments(850), false)
+		assert.Len(t, w.Segments, 850)
+		assert.True(t, w.NearLimit)
+		assert.False(t, w.Truncated)
+		assert.Equal(t, before+1, manifestLimitCount(t, "near_limit"))
+	})
+
+	t.Run("over limit is truncated", func(t *testing.T) {
+		before := manifestLimitCount(t, "truncated")
+		w := limits.Apply(syntheticSegments(5000), false)
+		require.Len(t, w.Segments, 1000)
+		assert.Equal(t, "720p/segment_00999.ts", w.Segments[999])
+		assert.Equal(t, 5000, w.Total)
+		assert.True(t, w.Truncated)
+		assert.Equal(t, 0, w.MediaSequence)
+		assert.Equal(t, before+1, manifestLimitCount(t, "truncated"))
+	})
+
+	t.Run("live keeps a sliding window of the newest segments", func(t *testing.T) {
+		before := manifestLimitCount(t, "windowed")
+		w := limits.Apply(syntheticSegments(5000), true)
+		require.Len(t, w.Segments, 10)
+		assert.Equal(t, 4990, w.MediaSequence)
+		assert.Equal(t, "720p/segment_04990.ts", w.Segments[0])
+		assert.False(t, w.Truncated)
+		assert.Equal(t, before+1, manifestLimitCount(t, "windowed"))
+	})
+
+	t.Run("zero limits fall back to defaults", func(t *testing.T) {
+		w := ManifestLimits{}.Apply(syntheticSegments(DefaultMaxManifestSegments+1), false)
+		assert.Len(t, w.Segments, DefaultMaxManifestSegments)
+	})
+}
+
+func TestStreamingService_GenerateMediaPlaylist_LargePlaylist(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	s := NewStreamingService(nil, nil, nil, "", zap.New(core))
+	s.SetManifestLimits(ManifestLimits{MaxSegments: 2000})
+
+	playlist := s.GenerateMediaPlaylist("content-1", "720p", syntheticSegments(20000), "tok", false)
+
+	assert.Equal(t, 2000, strings.Count(playlist, "#EXTINF:"))
+	assert.Contains(t, playlist, "segment_01999.ts")
+	assert.NotContains(t, playlist, "segment_02000.ts")
+	assert.True(t, strings.HasSuffix(playlist, "#EXT-X-ENDLIST\n"))
+	require.Equal(t, 1, logs.FilterMessage("Media playlist exceeds segment limit, truncating").Len())
+
+	logs.TakeAll()
+	s.GenerateMediaPlaylist("content-1", "720p", syntheticSegments(1700), "tok", false)
+	assert.Equal(t, 1, logs.FilterMessage("Media playlist approaching segment limit").Len())
+}
+
+func TestStreamingService_GenerateMediaPlaylist_Live(t *testing.T) {
+	s := NewStreamingService(nil, nil, nil, "")
+	s.SetManifestLimits(ManifestLimits{LiveWindow: 5})
+
+	playlist := s.GenerateMediaPlaylist("content-1", "720p", syntheticSegments(100), "tok", true)
+
+	assert.Contains(t, playlist, "#EXT-X-MEDIA-SEQUENCE:95\n")
+	assert.Equal(t, 5, strings.Count(playlist, "#EXTINF:"))
+	assert.Contains(t, playlist, "segment_00099.ts")
+	assert.NotContains(t, playlist, "#EXT-X-ENDLIST")
+}
+
+func TestStreamingService_GenerateHLSPlaylist_SingleQualityLimited(t *testing.T) {
+	s := NewStreamingService(nil, nil, nil, "")
+	s.SetManifestLimits(ManifestLimits{MaxSegments: 100})
+
+	playlist, err := s.GenerateHLSPlaylist("content-1", map[string][]string{"default": syntheticSegments(500)}, "tok", false)
+	require.NoError(t, err)
+	assert.Equal(t, 100, strings.Count(playlist, "#EXTINF:"))
+	assert.NotContains(t, playlist, "quality=", "single-quality playlists omit the quality parameter")
+}
+
+func TestStreamingService_GenerateHLSPlaylist_SingleQualityLive(t *testing.T) {
+	s := NewStreamingService(nil, nil, nil, "")
+	s.SetManifestLimits(ManifestLimits{LiveWindow: 5})
+
+	playlist, err := s.GenerateHLSPlaylist("content-1", map[string][]string{"default": syntheticSegments(100)}, "tok", true)
+	require.NoError(t, err)
+	assert.Contains(t, playlist, "#EXT-X-MEDIA-SEQUENCE:95\n")
+	assert.Equal(t, 5, strings.Count(playlist, "#EXTINF:"))
+	assert.NotContains(t, playlist, "#EXT-X-ENDLIST")
+}
+
+func TestBuildMediaPlaylist_Unchanged(t *testing.T) {
+	got := BuildMediaPlaylist("c", "720p", []string{"720p/segment_0.ts"}, "tok")
+	want := "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:10\n#EXT-X-MEDIA-SEQUENCE:0\n" +
+		"#EXTINF:6.0,\n/api/v1/streaming/c/segment/segment_0.ts?quality=720p&playback_token=tok\n" +
+		"#EXT-X-ENDLIST\n"
+	assert.Equal(t, want, got)
+}
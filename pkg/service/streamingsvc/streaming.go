@@ -12,6 +12,7 @@ import (
 
 	"github.com/rtcdance/streamgate/pkg/cachetypes"
 	"github.com/rtcdance/streamgate/pkg/monitoring"
+	"github.com/rtcdance/streamgate/pkg/service/live"
 	"github.com/rtcdance/streamgate/pkg/service/serviceerrors"
 	"github.com/rtcdance/streamgate/pkg/storage"
 
@@ -28,6 +29,15 @@ type StreamingService struct {
 	baseURL  string
 	logger   *zap.Logger
 	sf       singleflight.Group
+	limits   ManifestLimits
+	egress   *EgressRouter
+	live     LiveStreams
+}
+
+// LiveStreams looks up the state of live streams; *live.LiveService
+// implements it.
+type LiveStreams interface {
+	GetStream(id string) (live.Stream, error)
 }
 
 // StreamingObjectStorage defines the interface for object storage
@@ -75,9 +85,56 @@ func NewStreamingService(db storage.DB, objStorage StreamingObjectStorage, cache
 		cache:    cache,
 		baseURL:  baseURL,
 		logger:   l,
+		limits:   DefaultManifestLimits(),
 	}
 }
 
+// SetManifestLimits configures the segment cap and live window applied to
+// generated media playlists. Zero fields keep their defaults.
+func (s *StreamingService) SetManifestLimits(limits ManifestLimits) {
+	s.limits = limits.withDefaults()
+}
+
+// SetLiveStreams configures where IsLive looks up whether a stream is
+// live. Without it every playlist is served as VOD.
+func (s *StreamingService) SetLiveStreams(l LiveStreams) {
+	s.live = l
+}
+
+// GenerateMediaPlaylist renders the media playlist for one variant,
+// capping VOD playlists and sliding-windowing live ones per the configured
+// ManifestLimits. An empty quality renders segment URLs without a quality
+// parameter.
+func (s *StreamingService) GenerateMediaPlaylist(contentID, quality string, segments []string, playbackToken string, live bool) string {
+	w := s.limits.Apply(segments, live)
+	switch {
+	case w.Truncated:
+		s.logger.Warn("Media playlist exceeds segment limit, truncating",
+			zap.String("content_id", contentID),
+			zap.String("quality", quality),
+			zap.Int("segments", w.Total),
+			zap.Int("limit", s.limits.MaxSegments))
+	case w.NearLimit:
+		s.logger.Warn("Media playlist approaching segment limit",
+			zap.String("content_id", contentID),
+			zap.String("quality", quality),
+			zap.Int("segments", w.Total),
+			zap.Int("limit", s.limits.MaxSegments))
+	}
+	return BuildWindowedMediaPlaylist(contentID, quality, w, playbackToken)
+}
+
+// IsLive reports whether the live service has id in the live state, whose
+// media playlists are sliding windows without an end. It reports false
+// when no live service is configured or it does not know the stream.
+func (s *StreamingService) IsLive(id string) bool {
+	if s == nil || s.live == nil {
+		return false
+	}
+	st, err := s.live.GetStream(id)
+	return err == nil && st.State == live.StreamLive
+}
+
 func (s *StreamingService) Close() {
 }
 
@@ -218,14 +275,18 @@ func (s *StreamingService) CreateStream(ctx context.Context, contentID, streamTy
 
 // GenerateHLSPlaylist generates an HLS master playlist with inline segments
 // for multi-quality streaming. The playbackToken parameter is a placeholder
-// (e.g. "{{PLAYBACK_TOKEN}}") that the handler replaces per-request.
-func (s *StreamingService) GenerateHLSPlaylist(contentID string, qualitySegments map[string][]string, playbackToken string) (string, error) {
+// (e.g. "{{PLAYBACK_TOKEN}}") that the handler replaces per-request. live
+// windows a single-variant playlist, see GenerateMediaPlaylist.
+func (s *StreamingService) GenerateHLSPlaylist(contentID string, qualitySegments map[string][]string, playbackToken string, live bool) (string, error) {
 	if len(qualitySegments) == 0 {
 		return "", fmt.Errorf("no segments available for content %s", contentID)
 	}
 	if len(qualitySegments) == 1 {
 		for _, segs := range qualitySegments {
-			return BuildSimplePlaylist(contentID, segs, playbackToken), nil
+			if s == nil {
+				return BuildSimplePlaylist(contentID, segs, playbackToken), nil
+			}
+			return s.GenerateMediaPlaylist(contentID, "", segs, playbackToken, live), nil
 		}
 	}
 	return BuildMasterPlaylist(contentID, qualitySegments, playbackToken), nil
@@ -239,17 +300,7 @@ var qualityBandwidth = map[string]int{
 }
 
 func BuildSimplePlaylist(contentID string, segments []string, playbackToken string) string {
-	var b strings.Builder
-	b.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:10\n#EXT-X-MEDIA-SEQUENCE:0\n")
-	for _, seg := range segments {
-		name := seg
-		if idx := strings.LastIndex(seg, "/"); idx >= 0 {
-			name = seg[idx+1:]
-		}
-		fmt.Fprintf(&b, "#EXTINF:6.0,\n/api/v1/streaming/%s/segment/%s?playback_token=%s\n", contentID, name, playbackToken)
-	}
-	b.WriteString("#EXT-X-ENDLIST\n")
-	return b.String()
+	return BuildWindowedMediaPlaylist(contentID, "", SegmentWindow{Segments: segments}, playbackToken)
 }
 
 func BuildMasterPlaylist(contentID string, qualitySegments map[string][]string, playbackToken string) string {
@@ -265,17 +316,7 @@ func BuildMasterPlaylist(contentID string, qualitySegments map[string][]string,
 }
 
 func BuildMediaPlaylist(contentID, quality string, segments []string, playbackToken string) string {
-	var b strings.Builder
-	b.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:10\n#EXT-X-MEDIA-SEQUENCE:0\n")
-	for _, seg := range segments {
-		name := seg
-		if idx := strings.LastIndex(seg, "/"); idx >= 0 {
-			name = seg[idx+1:]
-		}
-		fmt.Fprintf(&b, "#EXTINF:6.0,\n/api/v1/streaming/%s/segment/%s?quality=%s&playback_token=%s\n", contentID, name, quality, playbackToken)
-	}
-	b.WriteString("#EXT-X-ENDLIST\n")
-	return b.String()
+	return BuildWindowedMediaPlaylist(contentID, quality, SegmentWindow{Segments: segments}, playbackToken)
 }
 
 func qualityToResolution(quality string) string {
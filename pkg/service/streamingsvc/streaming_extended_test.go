@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/rtcdance/streamgate/pkg/service/live"
 	stg "github.com/rtcdance/streamgate/pkg/storage"
 
 	"github.com/stretchr/testify/assert"
@@ -331,7 +332,7 @@ func TestStreamingService_DeleteStream_Success(t *testing.T) {
 
 func TestStreamingService_GenerateHLSPlaylist_NoSegments(t *testing.T) {
 	svc := NewStreamingService(nil, nil, nil, "http://cdn.example.com")
-	_, err := svc.GenerateHLSPlaylist("content-1", map[string][]string{}, "token")
+	_, err := svc.GenerateHLSPlaylist("content-1", map[string][]string{}, "token", false)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "no segments available")
 }
@@ -341,7 +342,7 @@ func TestStreamingService_GenerateHLSPlaylist_SingleQuality(t *testing.T) {
 	qualitySegments := map[string][]string{
 		"720p": {"seg0.ts", "seg1.ts"},
 	}
-	playlist, err := svc.GenerateHLSPlaylist("content-1", qualitySegments, "token")
+	playlist, err := svc.GenerateHLSPlaylist("content-1", qualitySegments, "token", false)
 	require.NoError(t, err)
 	assert.Contains(t, playlist, "#EXTM3U")
 	assert.Contains(t, playlist, "#EXTINF:6.0,")
@@ -355,7 +356,7 @@ func TestStreamingService_GenerateHLSPlaylist_MultipleQualities(t *testing.T) {
 		"720p":  {"seg0.ts"},
 		"480p":  {"seg0.ts"},
 	}
-	playlist, err := svc.GenerateHLSPlaylist("content-1", qualitySegments, "token")
+	playlist, err := svc.GenerateHLSPlaylist("content-1", qualitySegments, "token", false)
 	require.NoError(t, err)
 	assert.Contains(t, playlist, "#EXT-X-STREAM-INF")
 	assert.Contains(t, playlist, "BANDWIDTH=5000000")
@@ -818,3 +819,24 @@ func TestStreamingService_GetStreamByID_NilCache_NoPanic(t *testing.T) {
 	assert.Equal(t, "s1", stream.ID)
 	assert.Len(t, stream.Qualities, 0)
 }
+
+type fakeLiveStreams map[string]live.StreamState
+
+func (f fakeLiveStreams) GetStream(id string) (live.Stream, error) {
+	state, ok := f[id]
+	if !ok {
+		return live.Stream{}, live.ErrStreamNotFound
+	}
+	return live.Stream{ID: id, State: state}, nil
+}
+
+func TestStreamingService_IsLive(t *testing.T) {
+	svc := NewStreamingService(nil, newMockObjStore(), newMockCache(), "")
+	assert.False(t, svc.IsLive("s1"), "no live service")
+
+	svc.SetLiveStreams(fakeLiveStreams{"s1": live.StreamLive, "s2": live.StreamIdle, "s3": live.StreamEnded})
+	assert.True(t, svc.IsLive("s1"))
+	assert.False(t, svc.IsLive("s2"), "idle")
+	assert.False(t, svc.IsLive("s3"), "ended")
+	assert.False(t, svc.IsLive("unknown"))
+}
@@ -316,7 +316,7 @@ func TestStreamingService_GenerateHLSPlaylist(t *testing.T) {
 	svc := NewStreamingService(nil, nil, nil, "http://cdn.example.com")
 
 	t.Run("no segments returns error", func(t *testing.T) {
-		_, err := svc.GenerateHLSPlaylist("content-1", map[string][]string{}, "token")
+		_, err := svc.GenerateHLSPlaylist("content-1", map[string][]string{}, "token", false)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "no segments available")
 	})
@@ -325,7 +325,7 @@ func TestStreamingService_GenerateHLSPlaylist(t *testing.T) {
 		qualitySegments := map[string][]string{
 			"720p": {"seg0.ts", "seg1.ts"},
 		}
-		playlist, err := svc.GenerateHLSPlaylist("content-1", qualitySegments, "token")
+		playlist, err := svc.GenerateHLSPlaylist("content-1", qualitySegments, "token", false)
 		require.NoError(t, err)
 		assert.Contains(t, playlist, "#EXTM3U")
 		assert.Contains(t, playlist, "#EXTINF:6.0,")
@@ -337,7 +337,7 @@ func TestStreamingService_GenerateHLSPlaylist(t *testing.T) {
 			"1080p": {"seg0.ts"},
 			"720p":  {"seg0.ts"},
 		}
-		playlist, err := svc.GenerateHLSPlaylist("content-1", qualitySegments, "token")
+		playlist, err := svc.GenerateHLSPlaylist("content-1", qualitySegments, "token", false)
 		require.NoError(t, err)
 		assert.Contains(t, playlist, "#EXT-X-STREAM-INF")
 		assert.Contains(t, playlist, "BANDWIDTH=5000000")
@@ -5,7 +5,11 @@ import "github.com/rtcdance/streamgate/pkg/service/streamingsvc"
 type StreamingService = streamingsvc.StreamingService
 type Quality = streamingsvc.Quality
 type StreamInfo = streamingsvc.StreamInfo
+type ManifestLimits = streamingsvc.ManifestLimits
+type EgressRegion = streamingsvc.EgressRegion
+type EgressRouter = streamingsvc.EgressRouter
 
 var NewStreamingService = streamingsvc.NewStreamingService
 var DetectStreamType = streamingsvc.DetectStreamType
 var BuildMediaPlaylist = streamingsvc.BuildMediaPlaylist
+var NewEgressRouter = streamingsvc.NewEgressRouter
@@ -0,0 +1,381 @@
+package transcoding
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/storage"
+
+	"go.uber.org/zap"
+)
+
+// ErrBudgetExceeded is returned by Transcode when a submission's estimated
+// cost would push its owner past the monthly budget.
+var ErrBudgetExceeded = errors.New("transcode budget exceeded")
+
+// DefaultSourceDuration is assumed when a source's duration is unknown, so
+// unprobed uploads are still charged for a typical clip.
+const DefaultSourceDuration = 10 * time.Minute
+
+// CostWeights prices transcode compute in abstract cost units. A rung
+// costs (PerRungSecond + PerMegapixelSecond × output megapixels) for each
+// second of source.
+type CostWeights struct {
+	PerRungSecond      float64 `json:"per_rung_second"`
+	PerMegapixelSecond float64 `json:"per_megapixel_second"`
+}
+
+// DefaultCostWeights makes a 1080p rung roughly three times the cost of a
+// 360p rung.
+var DefaultCostWeights = CostWeights{PerRungSecond: 0.5, PerMegapixelSecond: 1}
+
+// BudgetConfig configures per-wallet monthly transcode budgets.
+type BudgetConfig struct {
+	// MonthlyLimit is the cost units each wallet may spend per calendar
+	// month (UTC). Zero or negative disables enforcement; usage is still
+	// tracked.
+	MonthlyLimit float64
+	Weights      CostWeights
+	// DefaultDuration is charged when the source duration is unknown.
+	DefaultDuration time.Duration
+	// DurationLookup resolves a content's source duration. When nil the
+	// service reads contents.duration from its database.
+	DurationLookup func(ctx context.Context, contentID string) (time.Duration, error)
+}
+
+// BudgetUsage is a wallet's spend for one month.
+type BudgetUsage struct {
+	Wallet    string  `json:"wallet"`
+	Period    string  `json:"period"` // YYYY-MM, UTC
+	Limit     float64 `json:"limit"`  // 0 means unlimited
+	Consumed  float64 `json:"consumed"`
+	Reserved  float64 `json:"reserved"` // submitted but not yet completed
+	Remaining float64 `json:"remaining,omitempty"`
+}
+
+// EstimateCost returns the cost of transcoding duration of source into
+// profile. "abr" is charged for every rung of the default ladder.
+func EstimateCost(weights CostWeights, duration time.Duration, profile string) (float64, error) {
+	var rungs []TranscodingProfile
+	if profile == "abr" {
+		for _, p := range DefaultProfiles {
+			rungs = append(rungs, p)
+		}
+	} else if p, ok := DefaultProfiles[profile]; ok {
+		rungs = []TranscodingProfile{p}
+	} else {
+		return 0, fmt.Errorf("invalid profile: %s", profile)
+	}
+
+	seconds := duration.Seconds()
+	var cost float64
+	for _, p := range rungs {
+		cost += seconds * (weights.PerRungSecond + weights.PerMegapixelSecond*megapixels(p.Resolution))
+	}
+	return cost, nil
+}
+
+// megapixels parses a "WxH" resolution.
+func megapixels(resolution string) float64 {
+	w, h, ok := strings.Cut(resolution, "x")
+	if !ok {
+		return 0
+	}
+	width, err1 := strconv.Atoi(w)
+	height, err2 := strconv.Atoi(h)
+	if err1 != nil || err2 != nil {
+		return 0
+	}
+	return float64(width*height) / 1e6
+}
+
+func budgetPeriod(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+// budgetLedger tracks consumed and reserved spend per wallet and month.
+// A submission reserves its estimate; completion moves the reservation
+// into consumed spend and a final failure or cancellation releases it.
+type budgetLedger interface {
+	// reserve holds cost against wallet's current month, failing with
+	// ErrBudgetExceeded if consumed + reserved + cost would pass the limit.
+	reserve(ctx context.Context, wallet, taskID string, cost float64) error
+	// settle charges taskID's reservation to the month it was reserved in.
+	settle(ctx context.Context, taskID string) error
+	// release drops taskID's reservation without charging it.
+	release(ctx context.Context, taskID string) error
+	usage(ctx context.Context, wallet string) (BudgetUsage, error)
+}
+
+type budgetKey struct {
+	wallet string
+	period string
+}
+
+type budgetReservation struct {
+	key  budgetKey
+	cost float64
+}
+
+type budgetTotals struct {
+	consumed float64
+	reserved float64
+}
+
+// memoryBudgetLedger holds the ledger in process memory, for services
+// without a database.
+type memoryBudgetLedger struct {
+	mu           sync.Mutex
+	limit        float64
+	totals       map[budgetKey]*budgetTotals
+	reservations map[string]budgetReservation // task ID → reservation
+	now          func() time.Time
+}
+
+func newMemoryBudgetLedger(limit float64) *memoryBudgetLedger {
+	return &memoryBudgetLedger{
+		limit:        limit,
+		totals:       make(map[budgetKey]*budgetTotals),
+		reservations: make(map[string]budgetReservation),
+		now:          time.Now,
+	}
+}
+
+func (l *memoryBudgetLedger) reserve(_ context.Context, wallet, taskID string, cost float64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := budgetKey{wallet: wallet, period: budgetPeriod(l.now())}
+	t := l.totals[key]
+	if t == nil {
+		t = &budgetTotals{}
+		l.totals[key] = t
+	}
+	if l.limit > 0 && t.consumed+t.reserved+cost > l.limit {
+		return budgetExceeded(t.consumed+t.reserved, l.limit, cost)
+	}
+	t.reserved += cost
+	l.reservations[taskID] = budgetReservation{key: key, cost: cost}
+	return nil
+}
+
+func (l *memoryBudgetLedger) settle(_ context.Context, taskID string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	r, ok := l.reservations[taskID]
+	if !ok {
+		return nil
+	}
+	delete(l.reservations, taskID)
+	t := l.totals[r.key]
+	t.reserved -= r.cost
+	t.consumed += r.cost
+	return nil
+}
+
+func (l *memoryBudgetLedger) release(_ context.Context, taskID string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	r, ok := l.reservations[taskID]
+	if !ok {
+		return nil
+	}
+	delete(l.reservations, taskID)
+	l.totals[r.key].reserved -= r.cost
+	return nil
+}
+
+func (l *memoryBudgetLedger) usage(_ context.Context, wallet string) (BudgetUsage, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var t budgetTotals
+	period := budgetPeriod(l.now())
+	if cur := l.totals[budgetKey{wallet: wallet, period: period}]; cur != nil {
+		t = *cur
+	}
+	return newBudgetUsage(wallet, period, l.limit, t), nil
+}
+
+// postgresBudgetLedger keeps the ledger in the transcode_budgets and
+// transcode_budget_reservations tables, so every replica enforces the
+// same budget. Each change is a single statement, which Postgres applies
+// atomically against the wallet's row.
+type postgresBudgetLedger struct {
+	db    storage.DB
+	limit float64
+	now   func() time.Time
+}
+
+func newPostgresBudgetLedger(db storage.DB, limit float64) *postgresBudgetLedger {
+	return &postgresBudgetLedger{db: db, limit: limit, now: time.Now}
+}
+
+// reserveBudgetQuery adds $3 to the reserved spend of wallet $1 in period
+// $2 unless that would pass limit $4 (unlimited when not positive), and
+// records the reservation of task $5 only if the wallet's row changed.
+const reserveBudgetQuery = `WITH charged AS (
+	INSERT INTO transcode_budgets (wallet_address, period, reserved) VALUES ($1, $2, $3)
+	ON CONFLICT (wallet_address, period) DO UPDATE
+		SET reserved = transcode_budgets.reserved + EXCLUDED.reserved, updated_at = CURRENT_TIMESTAMP
+		WHERE $4 <= 0 OR transcode_budgets.consumed + transcode_budgets.reserved + EXCLUDED.reserved <= $4
+	RETURNING 1
+)
+INSERT INTO transcode_budget_reservations (task_id, wallet_address, period, cost)
+SELECT $5, $1, $2, $3 FROM charged`
+
+func (l *postgresBudgetLedger) reserve(ctx context.Context, wallet, taskID string, cost float64) error {
+	if l.limit > 0 && cost > l.limit {
+		return budgetExceeded(0, l.limit, cost)
+	}
+	period := budgetPeriod(l.now())
+	result, err := l.db.Exec(ctx, reserveBudgetQuery, wallet, period, cost, l.limit, taskID)
+	if err != nil {
+		return fmt.Errorf("failed to reserve transcode budget: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		u, err := l.usage(ctx, wallet)
+		if err != nil {
+			return budgetExceeded(0, l.limit, cost)
+		}
+		return budgetExceeded(u.Consumed+u.Reserved, l.limit, cost)
+	}
+	return nil
+}
+
+// settleBudgetQuery moves task $1's reservation into consumed spend.
+const settleBudgetQuery = `WITH r AS (
+	DELETE FROM transcode_budget_reservations WHERE task_id = $1
+	RETURNING wallet_address, period, cost
+)
+UPDATE transcode_budgets b
+SET reserved = b.reserved - r.cost, consumed = b.consumed + r.cost, updated_at = CURRENT_TIMESTAMP
+FROM r WHERE b.wallet_address = r.wallet_address AND b.period = r.period`
+
+func (l *postgresBudgetLedger) settle(ctx context.Context, taskID string) error {
+	if _, err := l.db.Exec(ctx, settleBudgetQuery, taskID); err != nil {
+		return fmt.Errorf("failed to settle transcode budget: %w", err)
+	}
+	return nil
+}
+
+// releaseBudgetQuery drops task $1's reservation.
+const releaseBudgetQuery = `WITH r AS (
+	DELETE FROM transcode_budget_reservations WHERE task_id = $1
+	RETURNING wallet_address, period, cost
+)
+UPDATE transcode_budgets b
+SET reserved = b.reserved - r.cost, updated_at = CURRENT_TIMESTAMP
+FROM r WHERE b.wallet_address = r.wallet_address AND b.period = r.period`
+
+func (l *postgresBudgetLedger) release(ctx context.Context, taskID string) error {
+	if _, err := l.db.Exec(ctx, releaseBudgetQuery, taskID); err != nil {
+		return fmt.Errorf("failed to release transcode budget: %w", err)
+	}
+	return nil
+}
+
+func (l *postgresBudgetLedger) usage(ctx context.Context, wallet string) (BudgetUsage, error) {
+	var t budgetTotals
+	period := budgetPeriod(l.now())
+	err := l.db.QueryRow(ctx, "SELECT consumed, reserved FROM transcode_budgets WHERE wallet_address = $1 AND period = $2", wallet, period).Scan(&t.consumed, &t.reserved)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return BudgetUsage{}, fmt.Errorf("failed to read transcode budget: %w", err)
+	}
+	return newBudgetUsage(wallet, period, l.limit, t), nil
+}
+
+func budgetExceeded(used, limit, cost float64) error {
+	return fmt.Errorf("%w: %.2f of %.2f used, job needs %.2f", ErrBudgetExceeded, used, limit, cost)
+}
+
+func newBudgetUsage(wallet, period string, limit float64, t budgetTotals) BudgetUsage {
+	u := BudgetUsage{Wallet: wallet, Period: period, Limit: limit, Consumed: t.consumed, Reserved: t.reserved}
+	if limit > 0 {
+		u.Remaining = max(limit-u.Consumed-u.Reserved, 0)
+	}
+	return u
+}
+
+// WithBudget enables cost estimation and per-wallet monthly budgets.
+// Usage is kept in the service's database, shared by every replica; a
+// service without one holds it in process memory.
+func WithBudget(cfg BudgetConfig) TranscodingOption {
+	return func(s *TranscodingService) {
+		if cfg.Weights == (CostWeights{}) {
+			cfg.Weights = DefaultCostWeights
+		}
+		if cfg.DefaultDuration <= 0 {
+			cfg.DefaultDuration = DefaultSourceDuration
+		}
+		s.budgetCfg = cfg
+		if s.db != nil {
+			s.budget = newPostgresBudgetLedger(s.db, cfg.MonthlyLimit)
+		} else {
+			s.budget = newMemoryBudgetLedger(cfg.MonthlyLimit)
+		}
+	}
+}
+
+// EstimateCost prices a submission of contentID in profile using the
+// service's weights and the source's recorded duration.
+func (s *TranscodingService) EstimateCost(ctx context.Context, contentID, profile string) (float64, error) {
+	weights := s.budgetCfg.Weights
+	if weights == (CostWeights{}) {
+		weights = DefaultCostWeights
+	}
+	return EstimateCost(weights, s.sourceDuration(ctx, contentID), profile)
+}
+
+// BudgetUsage reports wallet's spend for the current month.
+func (s *TranscodingService) BudgetUsage(ctx context.Context, wallet string) (BudgetUsage, error) {
+	if s.budget == nil {
+		return BudgetUsage{Wallet: wallet, Period: budgetPeriod(time.Now())}, nil
+	}
+	return s.budget.usage(ctx, wallet)
+}
+
+func (s *TranscodingService) sourceDuration(ctx context.Context, contentID string) time.Duration {
+	fallback := s.budgetCfg.DefaultDuration
+	if fallback <= 0 {
+		fallback = DefaultSourceDuration
+	}
+	if lookup := s.budgetCfg.DurationLookup; lookup != nil {
+		if d, err := lookup(ctx, contentID); err == nil && d > 0 {
+			return d
+		}
+		return fallback
+	}
+	if s.db == nil {
+		return fallback
+	}
+	var seconds int64
+	if err := s.db.QueryRow(ctx, "SELECT COALESCE(duration, 0) FROM contents WHERE id = $1", contentID).Scan(&seconds); err != nil || seconds <= 0 {
+		return fallback
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// releaseBudget returns taskID's reserved spend to its owner.
+func (s *TranscodingService) releaseBudget(ctx context.Context, taskID string) {
+	if s.budget == nil {
+		return
+	}
+	if err := s.budget.release(ctx, taskID); err != nil && s.log != nil {
+		s.log.Warn("Failed to release transcode budget", zap.String("task_id", taskID), zap.Error(err))
+	}
+}
+
+// settleBudget charges taskID's reserved spend to its owner.
+func (s *TranscodingService) settleBudget(ctx context.Context, taskID string) {
+	if s.budget == nil {
+		return
+	}
+	if err := s.budget.settle(ctx, taskID); err != nil && s.log != nil {
+		s.log.Warn("Failed to settle transcode budget", zap.String("task_id", taskID), zap.Error(err))
+	}
+}
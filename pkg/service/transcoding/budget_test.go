@@ -0,0 +1,228 @@
+package transcoding
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	stg "github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func budgetUsage(t *testing.T, svc *TranscodingService, wallet string) BudgetUsage {
+	t.Helper()
+	u, err := svc.BudgetUsage(context.Background(), wallet)
+	require.NoError(t, err)
+	return u
+}
+
+// fixedDuration reports every source as d long.
+func fixedDuration(d time.Duration) func(context.Context, string) (time.Duration, error) {
+	return func(context.Context, string) (time.Duration, error) { return d, nil }
+}
+
+func TestEstimateCost(t *testing.T) {
+	w := CostWeights{PerRungSecond: 1, PerMegapixelSecond: 1}
+
+	cost, err := EstimateCost(w, 10*time.Second, "360p")
+	require.NoError(t, err)
+	assert.InDelta(t, 10*(1+0.2304), cost, 1e-9)
+
+	hd, _ := EstimateCost(w, 10*time.Second, "1080p")
+	assert.Greater(t, hd, cost, "larger rungs cost more")
+
+	long, _ := EstimateCost(w, 20*time.Second, "360p")
+	assert.InDelta(t, 2*cost, long, 1e-9, "cost scales with duration")
+
+	abr, err := EstimateCost(w, 10*time.Second, "abr")
+	require.NoError(t, err)
+	var sum float64
+	for name := range DefaultProfiles {
+		c, _ := EstimateCost(w, 10*time.Second, name)
+		sum += c
+	}
+	assert.InDelta(t, sum, abr, 1e-9, "abr is charged for every rung")
+
+	_, err = EstimateCost(w, time.Second, "4k")
+	assert.Error(t, err)
+}
+
+func TestTranscode_Budget_UnderBudgetAccepted(t *testing.T) {
+	svc := NewTranscodingService(nil, nil, WithBudget(BudgetConfig{
+		MonthlyLimit:   1000,
+		Weights:        CostWeights{PerRungSecond: 1},
+		DurationLookup: fixedDuration(100 * time.Second),
+	}))
+
+	taskID, err := svc.Transcode(context.Background(), "content-1", "720p", "https://example.com/v.mp4", 0, "0xOwner")
+	require.NoError(t, err)
+	require.NotEmpty(t, taskID)
+
+	task, err := svc.GetTranscodingStatus(context.Background(), taskID)
+	require.NoError(t, err)
+	assert.InDelta(t, 100.0, task.Metadata["estimated_cost"], 1e-9)
+
+	usage := budgetUsage(t, svc, "0xOwner")
+	assert.InDelta(t, 100.0, usage.Reserved, 1e-9)
+	assert.Zero(t, usage.Consumed)
+	assert.InDelta(t, 900.0, usage.Remaining, 1e-9)
+}
+
+func TestTranscode_Budget_OverBudgetRejected(t *testing.T) {
+	svc := NewTranscodingService(nil, nil, WithBudget(BudgetConfig{
+		MonthlyLimit:   150,
+		Weights:        CostWeights{PerRungSecond: 1},
+		DurationLookup: fixedDuration(100 * time.Second),
+	}))
+
+	_, err := svc.Transcode(context.Background(), "content-1", "720p", "https://example.com/v.mp4", 0, "0xOwner")
+	require.NoError(t, err)
+
+	_, err = svc.Transcode(context.Background(), "content-2", "720p", "https://example.com/v.mp4", 0, "0xOwner")
+	assert.True(t, errors.Is(err, ErrBudgetExceeded), "pending reservations count against the budget")
+
+	tasks, _ := svc.ListTasks(context.Background(), "", "0xOwner", 10, 0)
+	assert.Len(t, tasks, 1, "rejected submissions are not queued")
+
+	_, err = svc.Transcode(context.Background(), "content-2", "720p", "https://example.com/v.mp4", 0, "0xOther")
+	assert.NoError(t, err, "budgets are per wallet")
+
+	_, err = svc.Transcode(context.Background(), "content-3", "720p", "https://example.com/v.mp4", 0, "")
+	assert.NoError(t, err, "system submissions without an owner are not charged")
+}
+
+func TestTranscode_Budget_ConsumedOnCompletion(t *testing.T) {
+	svc := NewTranscodingService(nil, nil, WithBudget(BudgetConfig{
+		MonthlyLimit:   250,
+		Weights:        CostWeights{PerRungSecond: 1},
+		DurationLookup: fixedDuration(100 * time.Second),
+	}))
+	ctx := context.Background()
+
+	done, err := svc.Transcode(ctx, "content-1", "720p", "https://example.com/v.mp4", 0, "0xOwner")
+	require.NoError(t, err)
+	cancelled, err := svc.Transcode(ctx, "content-2", "720p", "https://example.com/v.mp4", 0, "0xOwner")
+	require.NoError(t, err)
+
+	require.NoError(t, svc.CompleteTask(ctx, done, "streams/content-1/720p"))
+	require.NoError(t, svc.CancelTask(ctx, cancelled))
+
+	usage := budgetUsage(t, svc, "0xOwner")
+	assert.InDelta(t, 100.0, usage.Consumed, 1e-9, "completion charges the reservation")
+	assert.Zero(t, usage.Reserved, "cancellation releases the reservation")
+	assert.InDelta(t, 150.0, usage.Remaining, 1e-9)
+
+	require.NoError(t, svc.CompleteTask(ctx, done, "streams/content-1/720p"))
+	assert.InDelta(t, 100.0, budgetUsage(t, svc, "0xOwner").Consumed, 1e-9, "a task is charged once")
+
+	_, err = svc.Transcode(ctx, "content-3", "720p", "https://example.com/v.mp4", 0, "0xOwner")
+	require.NoError(t, err)
+	_, err = svc.Transcode(ctx, "content-4", "720p", "https://example.com/v.mp4", 0, "0xOwner")
+	assert.ErrorIs(t, err, ErrBudgetExceeded, "consumed spend counts against the budget")
+}
+
+func TestBudgetLedger_MonthlyReset(t *testing.T) {
+	ctx := context.Background()
+	l := newMemoryBudgetLedger(100)
+	now := time.Date(2026, 1, 31, 23, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	require.NoError(t, l.reserve(ctx, "0xOwner", "t1", 80))
+	require.NoError(t, l.settle(ctx, "t1"))
+	assert.ErrorIs(t, l.reserve(ctx, "0xOwner", "t2", 30), ErrBudgetExceeded)
+
+	now = now.Add(2 * time.Hour)
+	require.NoError(t, l.reserve(ctx, "0xOwner", "t2", 30), "a new month starts with a fresh budget")
+	usage, err := l.usage(ctx, "0xOwner")
+	require.NoError(t, err)
+	assert.Equal(t, "2026-02", usage.Period)
+	assert.InDelta(t, 30.0, usage.Reserved, 1e-9)
+	assert.Zero(t, usage.Consumed)
+}
+
+// budgetRow scans a transcode_budgets row.
+type budgetRow struct{ consumed, reserved float64 }
+
+func (r budgetRow) Scan(dest ...interface{}) error {
+	*dest[0].(*float64) = r.consumed
+	*dest[1].(*float64) = r.reserved
+	return nil
+}
+
+func TestPostgresBudgetLedger(t *testing.T) {
+	ctx := context.Background()
+	var queries []string
+	var lastArgs []interface{}
+	affected := int64(1)
+	db := &mockDB{
+		execFn: func(_ context.Context, query string, args ...interface{}) (sql.Result, error) {
+			queries = append(queries, query)
+			lastArgs = args
+			return &mockResult{rowsAffected: affected}, nil
+		},
+		queryRowFn: func(_ context.Context, query string, args ...interface{}) *stg.CancelRow {
+			return stg.NewTestCancelRow(budgetRow{consumed: 80, reserved: 15})
+		},
+	}
+	l := newPostgresBudgetLedger(db, 100)
+	l.now = func() time.Time { return time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC) }
+
+	require.NoError(t, l.reserve(ctx, "0xOwner", "t1", 5))
+	assert.Equal(t, reserveBudgetQuery, queries[0])
+	assert.Equal(t, []interface{}{"0xOwner", "2026-03", 5.0, 100.0, "t1"}, lastArgs)
+
+	affected = 0
+	err := l.reserve(ctx, "0xOwner", "t2", 10)
+	assert.ErrorIs(t, err, ErrBudgetExceeded, "no row changed: the limit would be passed")
+	assert.Contains(t, err.Error(), "95.00 of 100.00 used")
+
+	queries = nil
+	assert.ErrorIs(t, l.reserve(ctx, "0xOwner", "t3", 101), ErrBudgetExceeded)
+	assert.Empty(t, queries, "a job over the whole limit is rejected without a query")
+
+	require.NoError(t, l.settle(ctx, "t1"))
+	require.NoError(t, l.release(ctx, "t2"))
+	assert.Equal(t, []string{settleBudgetQuery, releaseBudgetQuery}, queries)
+	assert.Equal(t, []interface{}{"t2"}, lastArgs)
+
+	usage, err := l.usage(ctx, "0xOwner")
+	require.NoError(t, err)
+	assert.Equal(t, BudgetUsage{Wallet: "0xOwner", Period: "2026-03", Limit: 100, Consumed: 80, Reserved: 15, Remaining: 5}, usage)
+
+	db.queryRowFn = func(context.Context, string, ...interface{}) *stg.CancelRow {
+		return stg.NewErrorCancelRow(sql.ErrNoRows)
+	}
+	usage, err = l.usage(ctx, "0xNew")
+	require.NoError(t, err)
+	assert.Zero(t, usage.Consumed)
+	assert.InDelta(t, 100.0, usage.Remaining, 1e-9)
+
+	db.execFn = func(context.Context, string, ...interface{}) (sql.Result, error) {
+		return nil, errors.New("connection lost")
+	}
+	err = l.reserve(ctx, "0xOwner", "t4", 1)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrBudgetExceeded)
+}
+
+func TestWithBudget_UsesDatabaseLedger(t *testing.T) {
+	svc := NewTranscodingService(&mockDB{}, nil, WithBudget(BudgetConfig{MonthlyLimit: 10}))
+	assert.IsType(t, &postgresBudgetLedger{}, svc.budget)
+	svc = NewTranscodingService(nil, nil, WithBudget(BudgetConfig{MonthlyLimit: 10}))
+	assert.IsType(t, &memoryBudgetLedger{}, svc.budget)
+}
+
+func TestTranscode_Budget_UnknownDurationUsesDefault(t *testing.T) {
+	svc := NewTranscodingService(nil, nil, WithBudget(BudgetConfig{
+		Weights:         CostWeights{PerRungSecond: 1},
+		DefaultDuration: time.Minute,
+	}))
+
+	cost, err := svc.EstimateCost(context.Background(), "content-1", "720p")
+	require.NoError(t, err)
+	assert.InDelta(t, 60.0, cost, 1e-9)
+}
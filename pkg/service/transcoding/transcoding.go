@@ -13,13 +13,16 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/rtcdance/streamgate/pkg/core/event"
 	"github.com/rtcdance/streamgate/pkg/models"
 	"github.com/rtcdance/streamgate/pkg/monitoring"
+	"github.com/rtcdance/streamgate/pkg/resilience"
 	"github.com/rtcdance/streamgate/pkg/service/serviceerrors"
 	"github.com/rtcdance/streamgate/pkg/storage"
 
@@ -30,6 +33,10 @@ import (
 // PostTranscodeHook is called after a transcoding task completes.
 type PostTranscodeHook func(ctx context.Context, contentID, profile, outputURL string)
 
+// TranscodeFailedHook is called after a transcoding task is marked failed.
+// contentID is empty when the task is not held by this instance.
+type TranscodeFailedHook func(ctx context.Context, taskID, contentID, errorMsg string)
+
 // TranscodingService handles transcoding operations
 type TranscodingService struct {
 	db                storage.DB
@@ -45,7 +52,12 @@ type TranscodingService struct {
 	workerCount       int
 	uploadConcurrency int
 	transcodeHooks    []PostTranscodeHook
+	failedHooks       []TranscodeFailedHook
 	hookMu            sync.Mutex
+	dedup             *event.Deduplicator
+	budget            budgetLedger
+	budgetCfg         BudgetConfig
+	retryBudget       *resilience.RetryBudget
 	wg                sync.WaitGroup
 
 	minWorkers     int
@@ -56,6 +68,7 @@ type TranscodingService struct {
 	extraMu        sync.Mutex
 	serviceCtx     context.Context
 	serviceCancel  context.CancelFunc
+	checkpointing  int32
 }
 
 const defaultUploadConcurrency = 5
@@ -92,6 +105,18 @@ func WithLogger(l *zap.Logger) TranscodingOption {
 	return func(s *TranscodingService) { s.log = l }
 }
 
+// WithDeduplicator skips redelivered tasks that another worker already
+// claimed. Failed attempts release their claim so retries still run.
+func WithDeduplicator(d *event.Deduplicator) TranscodingOption {
+	return func(s *TranscodingService) { s.dedup = d }
+}
+
+// WithRetryBudget rate-limits task retries through a budget shared with
+// the process's other retrying components.
+func WithRetryBudget(b *resilience.RetryBudget) TranscodingOption {
+	return func(s *TranscodingService) { s.retryBudget = b }
+}
+
 // RegisterPostTranscodeHook adds a hook that fires after a transcode completes.
 func (s *TranscodingService) RegisterPostTranscodeHook(hook PostTranscodeHook) {
 	s.hookMu.Lock()
@@ -99,6 +124,13 @@ func (s *TranscodingService) RegisterPostTranscodeHook(hook PostTranscodeHook) {
 	s.transcodeHooks = append(s.transcodeHooks, hook)
 }
 
+// RegisterTranscodeFailedHook adds a hook that fires after a task fails.
+func (s *TranscodingService) RegisterTranscodeFailedHook(hook TranscodeFailedHook) {
+	s.hookMu.Lock()
+	defer s.hookMu.Unlock()
+	s.failedHooks = append(s.failedHooks, hook)
+}
+
 func WithUploadConcurrency(n int) TranscodingOption {
 	return func(s *TranscodingService) {
 		if n > 0 {
@@ -137,8 +169,15 @@ func WithMaxWorkers(n int) TranscodingOption {
 const (
 	defaultMaxRetries = 3
 	retryDelayBase    = 5 * time.Second
+
+	// transcodeDedupNamespace scopes the worker's seen-set keys.
+	transcodeDedupNamespace = "transcode"
 )
 
+// errTaskAttemptFailed tells the deduplicator to release a failed task's
+// claim so its retry is processed.
+var errTaskAttemptFailed = errors.New("transcoding attempt failed")
+
 // StartWorker starts the background transcoding worker.
 // It dequeues tasks and invokes the VideoTranscoder.
 // Call StopWorker() to shut down.
@@ -231,26 +270,26 @@ func (s *TranscodingService) workerLoop(ctx context.Context, workerID int, log *
 			task.Metadata = make(map[string]interface{})
 		}
 
-		func() {
-			defer func() {
-				if r := recover(); r != nil {
-					if log != nil {
-						log.Info("TranscodingService: task panic recovered, continuing loop",
-							zap.String("task_id", task.ID), zap.Any("panic", r))
-					}
-					task.Status = "failed"
-					task.Error = fmt.Sprintf("panic during processing: %v", r)
-					failCtx, failCancel := context.WithTimeout(context.Background(), 3*time.Second)
-					defer failCancel()
-					if failErr := s.FailTask(failCtx, task.ID, task.Error); failErr != nil {
-						if log != nil {
-							log.Error("Failed to mark panicked task as failed", zap.String("task_id", task.ID), zap.Error(failErr))
-						}
-					}
-				}
-			}()
-			s.processTask(ctx, task, log)
-		}()
+		ran, _ := s.dedup.ProcessOnce(ctx, task.ID, func(ctx context.Context) error {
+			s.runTask(ctx, task, log)
+			if task.Status == "failed" {
+				return errTaskAttemptFailed
+			}
+			return nil
+		})
+		if !ran {
+			monitoring.EventDuplicatesSkippedTotal.WithLabelValues(transcodeDedupNamespace).Inc()
+			if log != nil {
+				log.Info("TranscodingService: skipping duplicate task delivery", zap.String("task_id", task.ID))
+			}
+			if err := s.queue.Ack(task.ID); err != nil && log != nil {
+				log.Warn("Failed to Ack duplicate transcoding task", zap.String("task_id", task.ID), zap.Error(err))
+			}
+			continue
+		}
+		if atomic.LoadInt32(&s.checkpointing) == 1 && s.checkpointTask(task, log) {
+			continue
+		}
 
 		if task.Status == "failed" {
 			retryCount := getRetryCount(task)
@@ -262,7 +301,7 @@ func (s *TranscodingService) workerLoop(ctx context.Context, workerID int, log *
 					log.Info("TranscodingService: scheduling task for retry with exponential backoff",
 						zap.String("task_id", task.ID), zap.Int("attempt", retryCount+1), zap.Int("max", defaultMaxRetries))
 				}
-				delay := retryDelayBase * time.Duration(1<<retryCount)
+				delay := retryDelayBase*time.Duration(1<<retryCount) + s.retryBudget.Reserve("transcoding")
 				go func() {
 					time.Sleep(delay)
 					if err := s.queue.Enqueue(task); err != nil {
@@ -271,6 +310,7 @@ func (s *TranscodingService) workerLoop(ctx context.Context, workerID int, log *
 					}
 				}()
 			} else {
+				s.releaseBudget(ctx, task.ID)
 				if err := s.queue.Nak(task.ID); err != nil {
 					log.Error("Failed to Nak transcoding task, it will remain in-flight",
 						zap.String("task_id", task.ID), zap.Error(err))
@@ -285,13 +325,35 @@ func (s *TranscodingService) workerLoop(ctx context.Context, workerID int, log *
 	}
 }
 
+// runTask processes one delivery, recovering a panic as a task failure.
+func (s *TranscodingService) runTask(ctx context.Context, task *TranscodingTask, log *zap.Logger) {
+	defer func() {
+		if r := recover(); r != nil {
+			if log != nil {
+				log.Info("TranscodingService: task panic recovered, continuing loop",
+					zap.String("task_id", task.ID), zap.Any("panic", r))
+			}
+			task.Status = "failed"
+			task.Error = fmt.Sprintf("panic during processing: %v", r)
+			failCtx, failCancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer failCancel()
+			if failErr := s.FailTask(failCtx, task.ID, task.Error); failErr != nil {
+				if log != nil {
+					log.Error("Failed to mark panicked task as failed", zap.String("task_id", task.ID), zap.Error(failErr))
+				}
+			}
+		}
+	}()
+	s.processTask(ctx, task, log)
+}
+
 const stuckTaskTimeout = 10 * time.Minute
 
 func (s *TranscodingService) recoverStuckTasks(log *zap.Logger) {
 	if s.db == nil {
 		return
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(storage.WithPrimary(context.Background()), 5*time.Second)
 	defer cancel()
 
 	rows, err := s.db.Query(ctx,
@@ -336,6 +398,7 @@ func (s *TranscodingService) recoverStuckTasks(log *zap.Logger) {
 
 	if s.queue != nil {
 		for _, t := range recovered {
+			s.dedup.Forget(ctx, t.ID)
 			if qErr := s.queue.Enqueue(t); qErr != nil {
 				if log != nil {
 					log.Warn("Failed to re-enqueue recovered task", zap.String("task_id", t.ID), zap.Error(qErr))
@@ -401,6 +464,93 @@ func (s *TranscodingService) Close() {
 	}
 }
 
+// checkpointReserve is kept back from a drain deadline to checkpoint the
+// tasks still running when it comes.
+const checkpointReserve = 5 * time.Second
+
+// Drain stops the workers taking new tasks and lets the running ones
+// finish while ctx allows. Tasks still running then are checkpointed:
+// their transcode is aborted, the task is reset to pending and its
+// delivery handed back to the queue, so another instance, or this one
+// after a restart, runs it again without counting a failed attempt.
+func (s *TranscodingService) Drain(ctx context.Context) error {
+	s.cancelMu.Lock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.cancelMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	finishCtx := ctx
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		finishCtx, cancel = context.WithDeadline(ctx, deadline.Add(-checkpointReserve))
+		defer cancel()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-finishCtx.Done():
+	}
+
+	atomic.StoreInt32(&s.checkpointing, 1)
+	if s.serviceCancel != nil {
+		s.serviceCancel()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("transcoding drain: %w", ctx.Err())
+	}
+}
+
+// checkpointTask puts a task aborted by Drain back to pending and returns
+// its delivery to the queue. It reports false for a task that finished
+// before the abort reached it, which is acknowledged as usual.
+func (s *TranscodingService) checkpointTask(task *TranscodingTask, log *zap.Logger) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	checkpointed := false
+	if s.db != nil {
+		result, err := s.db.Exec(ctx, "UPDATE transcoding_tasks SET status = 'pending', started_at = NULL WHERE id = $1 AND status = 'processing'", task.ID)
+		if err != nil {
+			if log != nil {
+				log.Error("Failed to checkpoint transcoding task", zap.String("task_id", task.ID), zap.Error(err))
+			}
+			return false
+		}
+		rows, _ := result.RowsAffected()
+		checkpointed = rows > 0
+	} else {
+		_ = s.updateTask(task.ID, func(t *TranscodingTask) {
+			if t.Status == "processing" {
+				t.Status = "pending"
+				t.StartedAt = nil
+				checkpointed = true
+			}
+		})
+	}
+	if !checkpointed {
+		return false
+	}
+
+	s.dedup.Forget(ctx, task.ID)
+	if err := s.queue.Nak(task.ID); err != nil && log != nil {
+		log.Warn("Failed to return checkpointed task to the queue", zap.String("task_id", task.ID), zap.Error(err))
+	}
+	if log != nil {
+		log.Info("TranscodingService: checkpointed running task for shutdown", zap.String("task_id", task.ID))
+	}
+	return true
+}
+
 func (s *TranscodingService) startWorkerGoroutine(ctx context.Context, workerID int, log *zap.Logger) {
 	go s.workerLoop(ctx, workerID, log)
 }
@@ -629,6 +779,7 @@ func (s *TranscodingService) processTask(_ context.Context, task *TranscodingTas
 		}
 
 		s.extractAndUploadThumbnail(taskCtx, inputPath, task.ContentID)
+		s.generateAndUploadStoryboard(taskCtx, inputPath, task)
 	}
 
 	// Mark complete — output is safely in object storage, skip cleanup
@@ -696,8 +847,8 @@ func (s *TranscodingService) uploadSegments(ctx context.Context, outputDir, cont
 			return err
 		}
 		// In ABR mode, organize files by resolution extracted from filename.
-		// FFmpegTranscoder outputs files named {resolution}.m3u8 and {resolution}_{seq}.ts
-		// (e.g. 1280x720.m3u8, 1280x720_000.ts, master.m3u8).
+		// FFmpegTranscoder outputs files named {resolution}.m3u8 and {resolution}_{version}_{seq}.ts
+		// (e.g. 1280x720.m3u8, 1280x720_v17a..._000.ts, master.m3u8).
 		subdir := profile
 		if abrMode {
 			subdir = extractResolutionPrefix(relPath)
@@ -709,6 +860,10 @@ func (s *TranscodingService) uploadSegments(ctx context.Context, outputDir, cont
 			contentType = "application/vnd.apple.mpegurl"
 		} else if strings.HasSuffix(path, ".ts") {
 			contentType = "video/mp2t"
+		} else if strings.HasSuffix(path, ".mpd") {
+			contentType = "application/dash+xml"
+		} else if strings.HasSuffix(path, ".m4s") {
+			contentType = "video/iso.segment"
 		}
 
 		jobs = append(jobs, uploadJob{path: path, objectKey: objectKey, contentType: contentType})
@@ -782,14 +937,24 @@ func (s *TranscodingService) uploadSegments(ctx context.Context, outputDir, cont
 
 // extractResolutionPrefix extracts the resolution subdirectory from an ABR
 // output filename. FFmpegTranscoder outputs files like "1280x720_000.ts"
-// or "1280x720.m3u8". The resolution prefix is used as the quality subdirectory.
-// Returns the original filename unchanged for non-resolution files like "master.m3u8".
+// or "1280x720.m3u8", "1280x720-hevc_000.m4s" for HEVC and AV1 rungs, and
+// "audio-0-eng_000.ts" for alternate audio renditions. The resolution
+// prefix, codec suffix included, or the audio rendition name is used as
+// the quality subdirectory. Returns the original filename unchanged for
+// non-resolution files like "master.m3u8".
 func extractResolutionPrefix(filename string) string {
 	sep := strings.IndexAny(filename, "_.")
 	if sep <= 0 {
 		return filename
 	}
-	resolution := filename[:sep]
+	variant := filename[:sep]
+	if isAudioRendition(variant) {
+		return variant
+	}
+	resolution, codec, _ := strings.Cut(variant, "-")
+	if codec != "" && codec != "hevc" && codec != "av1" {
+		return filename
+	}
 	if idx := strings.IndexByte(resolution, 'x'); idx <= 0 || idx == len(resolution)-1 {
 		return filename
 	}
@@ -804,7 +969,26 @@ func extractResolutionPrefix(filename string) string {
 			}
 		}
 	}
-	return resolution
+	return variant
+}
+
+// isAudioRendition reports whether variant names an alternate audio
+// rendition, "audio-<position>-<language>".
+func isAudioRendition(variant string) bool {
+	rest, ok := strings.CutPrefix(variant, "audio-")
+	if !ok {
+		return false
+	}
+	position, language, ok := strings.Cut(rest, "-")
+	if !ok || position == "" || language == "" {
+		return false
+	}
+	for _, c := range position {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
 }
 
 func (s *TranscodingService) extractAndUploadThumbnail(ctx context.Context, inputPath, contentID string) {
@@ -836,6 +1020,78 @@ func (s *TranscodingService) extractAndUploadThumbnail(ctx context.Context, inpu
 	}
 }
 
+// storyboardURL returns the storage URL of a content's WebVTT storyboard.
+func storyboardURL(contentID, vttName string) string {
+	return fmt.Sprintf("/streamgate/storyboards/%s/%s", contentID, vttName)
+}
+
+// generateAndUploadStoryboard renders the scrub preview storyboard of a
+// content's source and uploads it under storyboards/{contentID}/, recording
+// the WebVTT URL in the task metadata as "storyboard_url". Storyboards are
+// per content: a content's later profiles reuse the first one's. Failures
+// are logged and leave the content without a storyboard, like thumbnails.
+func (s *TranscodingService) generateAndUploadStoryboard(ctx context.Context, inputPath string, task *TranscodingTask) {
+	gen, ok := s.transcoder.(StoryboardGenerator)
+	if !ok || task.ContentID == "" {
+		return
+	}
+	log := s.log.With(zap.String("content_id", task.ContentID))
+
+	vttKey := fmt.Sprintf("storyboards/%s/%s", task.ContentID, storyboardVTTName)
+	if exists, err := s.storage.Exists(ctx, "streamgate", vttKey); err == nil && exists {
+		s.setStoryboardURL(task, storyboardURL(task.ContentID, storyboardVTTName))
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "storyboard-")
+	if err != nil {
+		log.Warn("Storyboard directory creation failed", zap.Error(err))
+		return
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	files, err := gen.GenerateStoryboard(ctx, inputPath, dir)
+	if err != nil {
+		log.Warn("Storyboard generation failed", zap.Error(err))
+		return
+	}
+
+	var vttName string
+	// Sheets go up before the WebVTT file, whose presence marks the
+	// storyboard complete.
+	sort.SliceStable(files, func(i, j int) bool {
+		return !strings.HasSuffix(files[i], ".vtt") && strings.HasSuffix(files[j], ".vtt")
+	})
+	for _, name := range files {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			log.Warn("Storyboard read failed", zap.String("file", name), zap.Error(err))
+			return
+		}
+		contentType := "image/jpeg"
+		if strings.HasSuffix(name, ".vtt") {
+			contentType = "text/vtt"
+			vttName = name
+		}
+		key := fmt.Sprintf("storyboards/%s/%s", task.ContentID, name)
+		if err := s.storage.UploadWithContentType(ctx, "streamgate", key, data, contentType); err != nil {
+			log.Warn("Storyboard upload failed", zap.String("file", name), zap.Error(err))
+			return
+		}
+	}
+	if vttName != "" {
+		s.setStoryboardURL(task, storyboardURL(task.ContentID, vttName))
+	}
+}
+
+func (s *TranscodingService) setStoryboardURL(task *TranscodingTask, storyboard string) {
+	if task.Metadata == nil {
+		task.Metadata = make(map[string]interface{})
+	}
+	task.Metadata["storyboard_url"] = storyboard
+	s.storeTask(task)
+}
+
 // downloadInputFile downloads an HTTP URL to a local temp file and returns
 // the path. The caller is responsible for cleaning up the file.
 func (s *TranscodingService) downloadInputFile(ctx context.Context, inputURL string) (string, error) {
@@ -894,6 +1150,17 @@ type VideoTranscoder interface {
 	TranscodeHLS(ctx context.Context, inputPath, outputDir, profile string, progressFn func(variant string, progress float64)) error
 }
 
+// StoryboardGenerator is implemented by VideoTranscoders that render scrub
+// preview storyboards: JPEG sprite sheets plus a WebVTT file mapping cue
+// times to sprite regions with relative URIs. It returns the names of the
+// files written to outputDir; none when storyboards are disabled.
+type StoryboardGenerator interface {
+	GenerateStoryboard(ctx context.Context, inputPath, outputDir string) ([]string, error)
+}
+
+// storyboardVTTName is the WebVTT file name StoryboardGenerators write.
+const storyboardVTTName = "storyboard.vtt"
+
 // SegmentStorage defines the object storage operations needed by TranscodingService.
 // This is a subset of storage.ObjectStorage to avoid import cycles.
 // All methods accept a context.Context for timeout/cancellation propagation.
@@ -990,9 +1257,22 @@ func (s *TranscodingService) Transcode(ctx context.Context, contentID, profile,
 		Metadata:    make(map[string]interface{}),
 	}
 
+	// Charge the owner's monthly budget before any work is queued.
+	if s.budget != nil && ownerWallet != "" {
+		cost, err := s.EstimateCost(ctx, contentID, profile)
+		if err != nil {
+			return "", err
+		}
+		if err := s.budget.reserve(ctx, ownerWallet, taskID, cost); err != nil {
+			return "", err
+		}
+		task.Metadata["estimated_cost"] = cost
+	}
+
 	// Save to database when persistence is available.
 	if s.db != nil {
 		if err := s.saveTask(ctx, task); err != nil {
+			s.releaseBudget(ctx, taskID)
 			if strings.Contains(err.Error(), "duplicate key") || strings.Contains(err.Error(), "unique constraint") {
 				s.log.Debug("transcode task already exists for content",
 					zap.String("content_id", contentID),
@@ -1012,6 +1292,7 @@ func (s *TranscodingService) Transcode(ctx context.Context, contentID, profile,
 	// Enqueue task
 	if s.queue != nil {
 		if err := s.queue.Enqueue(task); err != nil {
+			s.releaseBudget(ctx, taskID)
 			return "", fmt.Errorf("failed to enqueue task: %w", err)
 		}
 	}
@@ -1019,6 +1300,14 @@ func (s *TranscodingService) Transcode(ctx context.Context, contentID, profile,
 	return taskID, nil
 }
 
+// QueueDepth returns how many tasks are waiting in the queue.
+func (s *TranscodingService) QueueDepth(_ context.Context) (int, error) {
+	if s.queue == nil {
+		return 0, nil
+	}
+	return s.queue.Depth()
+}
+
 // GetTranscodingStatus gets transcoding task status
 func (s *TranscodingService) GetTranscodingStatus(ctx context.Context, taskID string) (*TranscodingTask, error) {
 	if s.db == nil {
@@ -1103,6 +1392,7 @@ func (s *TranscodingService) UpdateTaskStatus(ctx context.Context, taskID, statu
 	}
 
 	var currentStatus string
+	ctx = storage.WithPrimary(ctx) // the transition guard must see the latest status
 	if err := s.db.QueryRow(ctx, "SELECT status FROM transcoding_tasks WHERE id = $1", taskID).Scan(&currentStatus); err != nil {
 		return fmt.Errorf("task not found: %s", taskID)
 	}
@@ -1162,6 +1452,14 @@ func (s *TranscodingService) StartTask(ctx context.Context, taskID string) error
 
 // CompleteTask marks a task as completed
 func (s *TranscodingService) CompleteTask(ctx context.Context, taskID, outputURL string) error {
+	if err := s.completeTask(ctx, taskID, outputURL); err != nil {
+		return err
+	}
+	s.settleBudget(ctx, taskID)
+	return nil
+}
+
+func (s *TranscodingService) completeTask(ctx context.Context, taskID, outputURL string) error {
 	if s.db == nil {
 		return s.updateTask(taskID, func(task *TranscodingTask) {
 			task.Status = "completed"
@@ -1193,7 +1491,9 @@ func (s *TranscodingService) CompleteTask(ctx context.Context, taskID, outputURL
 		}
 
 		var metadataJSON []byte
+		var storyboard string
 		if memTask, memErr := s.getTask(taskID); memErr == nil && memTask.Metadata != nil {
+			storyboard, _ = memTask.Metadata["storyboard_url"].(string)
 			finalMeta := make(map[string]interface{})
 			for k, v := range memTask.Metadata {
 				finalMeta[k] = v
@@ -1261,12 +1561,43 @@ func (s *TranscodingService) CompleteTask(ctx context.Context, taskID, outputURL
 			}
 		}
 
+		if contentID != "" && storyboard != "" {
+			if _, err := tx.ExecContext(ctx,
+				"UPDATE contents SET metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object('storyboard_url', $2::text), updated_at = $3 WHERE id = $1",
+				contentID, storyboard, time.Now()); err != nil {
+				s.log.Warn("Failed to update storyboard URL", zap.Error(err))
+			}
+		}
+
 		return nil
 	})
 }
 
 // FailTask marks a task as failed
 func (s *TranscodingService) FailTask(ctx context.Context, taskID, errorMsg string) error {
+	if atomic.LoadInt32(&s.checkpointing) == 1 {
+		// Drain aborted the task to checkpoint it; it has not failed.
+		return nil
+	}
+	if err := s.failTask(taskID, errorMsg); err != nil {
+		return err
+	}
+
+	var contentID string
+	if task, err := s.getTask(taskID); err == nil {
+		contentID = task.ContentID
+	}
+	s.hookMu.Lock()
+	hooks := make([]TranscodeFailedHook, len(s.failedHooks))
+	copy(hooks, s.failedHooks)
+	s.hookMu.Unlock()
+	for _, hook := range hooks {
+		hook(ctx, taskID, contentID, errorMsg)
+	}
+	return nil
+}
+
+func (s *TranscodingService) failTask(taskID, errorMsg string) error {
 	if s.db == nil {
 		return s.updateTask(taskID, func(task *TranscodingTask) {
 			task.Status = "failed"
@@ -1389,6 +1720,7 @@ func (s *TranscodingService) CancelTask(ctx context.Context, taskID string) erro
 				task.Status = "cancelled"
 				now := time.Now()
 				task.CompletedAt = &now
+				s.releaseBudget(ctx, taskID)
 			}
 		})
 	}
@@ -1406,6 +1738,7 @@ func (s *TranscodingService) CancelTask(ctx context.Context, taskID string) erro
 	if rowsAffected == 0 {
 		return fmt.Errorf("task cannot be cancelled: %s", taskID)
 	}
+	s.releaseBudget(ctx, taskID)
 
 	return nil
 }
@@ -1518,7 +1851,7 @@ func (s *TranscodingService) GetPendingTasks(ctx context.Context, limit int) ([]
 		LIMIT $1
 	`
 
-	rows, err := s.db.Query(ctx, query, limit)
+	rows, err := s.db.Query(storage.WithPrimary(ctx), query, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query pending tasks: %w", err)
 	}
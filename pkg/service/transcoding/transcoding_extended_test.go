@@ -769,6 +769,52 @@ func TestE2E_UploadSegmentsToStorage(t *testing.T) {
 	assert.True(t, found, "should find .m3u8 or .ts files in storage")
 }
 
+// mockStoryboardTranscoder also renders storyboards, counting the runs.
+type mockStoryboardTranscoder struct {
+	mockTranscoderWithFiles
+	storyboards int
+}
+
+func (m *mockStoryboardTranscoder) GenerateStoryboard(_ context.Context, _, outputDir string) ([]string, error) {
+	m.storyboards++
+	_ = os.WriteFile(filepath.Join(outputDir, "storyboard.vtt"), []byte("WEBVTT\n"), 0o644)
+	_ = os.WriteFile(filepath.Join(outputDir, "storyboard_000.jpg"), []byte("jpeg"), 0o644)
+	return []string{"storyboard.vtt", "storyboard_000.jpg"}, nil
+}
+
+func TestE2E_StoryboardUploaded(t *testing.T) {
+	store := newMockSegmentStorage()
+	tc := &mockStoryboardTranscoder{}
+	svc := NewTranscodingService(nil, NewMemoryTranscodingQueue(),
+		WithTranscoder(tc),
+		WithStorage(store),
+		WithLogger(zap.NewNop()),
+	)
+
+	for _, profile := range []string{"720p", "480p"} {
+		task := &models.TranscodingTask{
+			ID:        "task-storyboard-" + profile,
+			ContentID: "content-storyboard",
+			Profile:   profile,
+			Status:    "pending",
+			InputURL:  "/tmp/test-input.mp4",
+			Metadata:  make(map[string]interface{}),
+		}
+		svc.storeTask(task)
+		svc.processTask(context.Background(), task, zap.NewNop())
+
+		updated, err := svc.getTask(task.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "completed", updated.Status)
+		assert.Equal(t, "/streamgate/storyboards/content-storyboard/storyboard.vtt", updated.Metadata["storyboard_url"])
+	}
+
+	assert.Equal(t, 1, tc.storyboards, "later profiles reuse the content's storyboard")
+	data, err := store.Download(context.Background(), "streamgate", "storyboards/content-storyboard/storyboard_000.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", string(data))
+}
+
 func TestE2E_PostTranscodeHook(t *testing.T) {
 	var hookCalls []string
 	hook := func(_ context.Context, contentID, profile, outputURL string) {
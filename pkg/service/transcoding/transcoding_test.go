@@ -14,9 +14,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/rtcdance/streamgate/pkg/core/event"
 	"github.com/rtcdance/streamgate/pkg/models"
 	stg "github.com/rtcdance/streamgate/pkg/storage"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -410,6 +412,24 @@ func TestTranscodingService_FailTask(t *testing.T) {
 	require.NotNil(t, task.CompletedAt)
 }
 
+func TestTranscodingService_FailTask_FiresFailedHooks(t *testing.T) {
+	svc := NewTranscodingService(nil, NewMemoryTranscodingQueue())
+	var gotTask, gotContent, gotErr string
+	svc.RegisterTranscodeFailedHook(func(_ context.Context, taskID, contentID, errorMsg string) {
+		gotTask, gotContent, gotErr = taskID, contentID, errorMsg
+	})
+
+	taskID, _ := svc.Transcode(context.Background(), "content-h", "720p", "https://example.com/input.mp4", 1, "")
+	require.NoError(t, svc.FailTask(context.Background(), taskID, "ffmpeg exited 1"))
+	assert.Equal(t, taskID, gotTask)
+	assert.Equal(t, "content-h", gotContent)
+	assert.Equal(t, "ffmpeg exited 1", gotErr)
+
+	gotTask = ""
+	assert.Error(t, svc.FailTask(context.Background(), "nonexistent", "error"))
+	assert.Empty(t, gotTask, "hooks do not fire for unknown tasks")
+}
+
 func TestTranscodingService_UpdateTaskStatus(t *testing.T) {
 	queue := NewMemoryTranscodingQueue()
 	svc := NewTranscodingService(nil, queue)
@@ -1483,3 +1503,161 @@ func TestDB_GetTranscodingStatus_WithNullables(t *testing.T) {
 	require.NotNil(t, task.StartedAt)
 	require.NotNil(t, task.CompletedAt)
 }
+
+type countingTranscoder struct {
+	calls atomic.Int32
+}
+
+func (c *countingTranscoder) TranscodeHLS(context.Context, string, string, string, func(string, float64)) error {
+	c.calls.Add(1)
+	return nil
+}
+
+func duplicatesSkipped(t *testing.T) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, mf := range families {
+		if mf.GetName() != "streamgate_event_duplicates_skipped_total" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			if m.GetLabel()[0].GetValue() == transcodeDedupNamespace {
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestTranscodingService_Worker_SkipsDuplicateDelivery(t *testing.T) {
+	tc := &countingTranscoder{}
+	queue := NewMemoryTranscodingQueue()
+	svc := NewTranscodingService(nil, queue,
+		WithTranscoder(tc),
+		WithLogger(zap.NewNop()),
+		WithMinWorkers(1),
+		WithMaxWorkers(1),
+		WithDeduplicator(event.NewDeduplicator(event.NewMemorySeenSet(), time.Hour, zap.NewNop())),
+	)
+	var completed atomic.Int32
+	svc.RegisterPostTranscodeHook(func(context.Context, string, string, string) {
+		completed.Add(1)
+	})
+
+	before := duplicatesSkipped(t)
+	task := &models.TranscodingTask{ID: "task-dup", ContentID: "content-1", Profile: "720p", Status: "pending"}
+	require.NoError(t, queue.Enqueue(task))
+	require.NoError(t, queue.Enqueue(task), "simulated redelivery")
+
+	svc.StartWorker(zap.NewNop())
+	defer svc.StopWorker()
+
+	require.Eventually(t, func() bool {
+		return duplicatesSkipped(t) == before+1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(1), tc.calls.Load(), "the transcoder runs once per task")
+	assert.Equal(t, int32(1), completed.Load())
+}
+
+func TestExtractResolutionPrefix(t *testing.T) {
+	tests := map[string]string{
+		"1280x720.m3u8":               "1280x720",
+		"1280x720_v1a2_000.ts":        "1280x720",
+		"1920x1080-hevc.m3u8":         "1920x1080-hevc",
+		"1920x1080-av1_v1a2_init.mp4": "1920x1080-av1",
+		"1920x1080-vp9_v1a2_000.m4s":  "1920x1080-vp9_v1a2_000.m4s",
+		"master.m3u8":                 "master.m3u8",
+		"1920x_000.ts":                "1920x_000.ts",
+		"audio-0-eng.m3u8":            "audio-0-eng",
+		"audio-1-pt-br_v1a2_000.ts":   "audio-1-pt-br",
+		"audio-x-eng_000.ts":          "audio-x-eng_000.ts",
+	}
+	for name, want := range tests {
+		assert.Equal(t, want, extractResolutionPrefix(name), name)
+	}
+}
+
+type blockingTranscoder struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingTranscoder() *blockingTranscoder {
+	return &blockingTranscoder{started: make(chan struct{}, 1), release: make(chan struct{})}
+}
+
+func (b *blockingTranscoder) TranscodeHLS(ctx context.Context, _, _, _ string, _ func(string, float64)) error {
+	b.started <- struct{}{}
+	select {
+	case <-b.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type nakRecordingQueue struct {
+	*MemoryTranscodingQueue
+	mu   sync.Mutex
+	naks []string
+}
+
+func (q *nakRecordingQueue) Nak(taskID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.naks = append(q.naks, taskID)
+	return nil
+}
+
+func TestTranscodingService_Drain(t *testing.T) {
+	tests := []struct {
+		name       string
+		timeout    time.Duration
+		finish     bool
+		wantStatus string
+		wantNaks   []string
+	}{
+		{"running task finishes", 10 * time.Second, true, "completed", nil},
+		{"running task is checkpointed", checkpointReserve + 100*time.Millisecond, false, "pending", []string{"task-drain"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newBlockingTranscoder()
+			queue := &nakRecordingQueue{MemoryTranscodingQueue: NewMemoryTranscodingQueue()}
+			svc := NewTranscodingService(nil, queue,
+				WithTranscoder(tc),
+				WithLogger(zap.NewNop()),
+				WithMinWorkers(1),
+				WithMaxWorkers(1),
+			)
+			var failed atomic.Int32
+			svc.RegisterTranscodeFailedHook(func(context.Context, string, string, string) {
+				failed.Add(1)
+			})
+
+			task := &models.TranscodingTask{ID: "task-drain", ContentID: "content-1", Profile: "720p", Status: "pending"}
+			svc.storeTask(task)
+			require.NoError(t, queue.Enqueue(task))
+			svc.StartWorker(zap.NewNop())
+			defer svc.Close()
+			<-tc.started
+
+			if tt.finish {
+				close(tc.release)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			require.NoError(t, svc.Drain(ctx))
+
+			got, err := svc.GetTranscodingStatus(context.Background(), task.ID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, got.Status)
+			if tt.wantStatus == "pending" {
+				assert.Nil(t, got.StartedAt)
+			}
+			assert.Equal(t, tt.wantNaks, queue.naks)
+			assert.Zero(t, failed.Load(), "a checkpoint is not a failure")
+		})
+	}
+}
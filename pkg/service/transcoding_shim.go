@@ -1,6 +1,8 @@
 package service
 
 import (
+	"github.com/rtcdance/streamgate/pkg/core/event"
+	"github.com/rtcdance/streamgate/pkg/resilience"
 	"github.com/rtcdance/streamgate/pkg/service/transcoding"
 	"go.uber.org/zap"
 )
@@ -10,17 +12,23 @@ type (
 	TranscodingTask        = transcoding.TranscodingTask
 	TranscodingQueue       = transcoding.TranscodingQueue
 	VideoTranscoder        = transcoding.VideoTranscoder
+	StoryboardGenerator    = transcoding.StoryboardGenerator
 	SegmentStorage         = transcoding.SegmentStorage
 	PostTranscodeHook      = transcoding.PostTranscodeHook
+	TranscodeFailedHook    = transcoding.TranscodeFailedHook
 	TranscodingOption      = transcoding.TranscodingOption
 	TranscodingProfile     = transcoding.TranscodingProfile
 	MemoryTranscodingQueue = transcoding.MemoryTranscodingQueue
+	TranscodeBudgetConfig  = transcoding.BudgetConfig
+	TranscodeBudgetUsage   = transcoding.BudgetUsage
+	TranscodeCostWeights   = transcoding.CostWeights
 )
 
 var (
-	NewTranscodingService     = transcoding.NewTranscodingService
-	NewMemoryTranscodingQueue = transcoding.NewMemoryTranscodingQueue
-	DefaultProfiles           = transcoding.DefaultProfiles
+	NewTranscodingService      = transcoding.NewTranscodingService
+	NewMemoryTranscodingQueue  = transcoding.NewMemoryTranscodingQueue
+	DefaultProfiles            = transcoding.DefaultProfiles
+	ErrTranscodeBudgetExceeded = transcoding.ErrBudgetExceeded
 )
 
 func WithTranscoder(t VideoTranscoder) TranscodingOption {
@@ -34,3 +42,15 @@ func WithStorage(st SegmentStorage) TranscodingOption {
 func WithLogger(l *zap.Logger) TranscodingOption {
 	return transcoding.WithLogger(l)
 }
+
+func WithDeduplicator(d *event.Deduplicator) TranscodingOption {
+	return transcoding.WithDeduplicator(d)
+}
+
+func WithBudget(cfg TranscodeBudgetConfig) TranscodingOption {
+	return transcoding.WithBudget(cfg)
+}
+
+func WithRetryBudget(b *resilience.RetryBudget) TranscodingOption {
+	return transcoding.WithRetryBudget(b)
+}
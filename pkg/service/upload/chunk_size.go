@@ -0,0 +1,173 @@
+package upload
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rtcdance/streamgate/pkg/service/serviceerrors"
+)
+
+const (
+	// DefaultMinChunkSize is the smallest chunk the server hands out. It
+	// matches the S3 minimum part size so chunks map 1:1 onto multipart parts.
+	DefaultMinChunkSize int64 = 5 * 1024 * 1024
+	// DefaultMaxChunkSize caps a single chunk so one failed request never
+	// forces a client to resend more than this.
+	DefaultMaxChunkSize int64 = 512 * 1024 * 1024
+	// DefaultMaxChunks mirrors the S3 multipart part-count limit.
+	DefaultMaxChunks = 10000
+	// DefaultTargetChunks is the chunk count aimed for when the bounds allow
+	// it: enough for useful resume granularity without per-chunk overhead
+	// dominating.
+	DefaultTargetChunks = 64
+
+	chunkAlignment int64 = 1024 * 1024
+)
+
+// ErrChunkSizeUnsatisfiable is returned when no chunk size satisfies both the
+// server policy and the client constraints for the declared file size.
+var ErrChunkSizeUnsatisfiable = errors.New("no chunk size satisfies the requested constraints")
+
+// ChunkSizePolicy holds the server-side bounds used to negotiate chunk sizes.
+// Zero fields fall back to the Default* values.
+type ChunkSizePolicy struct {
+	MinChunkSize int64
+	MaxChunkSize int64
+	MaxChunks    int
+	TargetChunks int
+}
+
+// ChunkConstraints are optional client-side limits. Zero fields mean the
+// client has no preference.
+type ChunkConstraints struct {
+	MinChunkSize       int64 `json:"min_chunk_size,omitempty"`
+	MaxChunkSize       int64 `json:"max_chunk_size,omitempty"`
+	PreferredChunkSize int64 `json:"preferred_chunk_size,omitempty"`
+}
+
+// ChunkPlan is the result of a chunk-size negotiation.
+type ChunkPlan struct {
+	ChunkSize   int64 `json:"chunk_size"`
+	TotalChunks int   `json:"total_chunks"`
+}
+
+func (p ChunkSizePolicy) withDefaults() ChunkSizePolicy {
+	if p.MinChunkSize <= 0 {
+		p.MinChunkSize = DefaultMinChunkSize
+	}
+	if p.MaxChunkSize <= 0 {
+		p.MaxChunkSize = DefaultMaxChunkSize
+	}
+	if p.MaxChunks <= 0 {
+		p.MaxChunks = DefaultMaxChunks
+	}
+	if p.TargetChunks <= 0 {
+		p.TargetChunks = DefaultTargetChunks
+	}
+	return p
+}
+
+// Negotiate picks a chunk size for a file of totalSize bytes. Small files
+// get a single chunk; larger files aim for TargetChunks chunks, growing the
+// chunk size as needed to stay under MaxChunks. The result always lies in
+// the intersection of the server and client bounds.
+func (p ChunkSizePolicy) Negotiate(totalSize int64, client ChunkConstraints) (ChunkPlan, error) {
+	if totalSize <= 0 {
+		return ChunkPlan{}, fmt.Errorf("total size must be positive")
+	}
+	p = p.withDefaults()
+
+	lo, hi := p.MinChunkSize, p.MaxChunkSize
+	if client.MinChunkSize > lo {
+		lo = client.MinChunkSize
+	}
+	if client.MaxChunkSize > 0 && client.MaxChunkSize < hi {
+		hi = client.MaxChunkSize
+	}
+	if lo > hi {
+		return ChunkPlan{}, fmt.Errorf("%w: min %d exceeds max %d", ErrChunkSizeUnsatisfiable, lo, hi)
+	}
+
+	// Files that fit in one chunk are sent whole.
+	if totalSize <= lo {
+		return planFor(totalSize, totalSize), nil
+	}
+
+	size := client.PreferredChunkSize
+	if size <= 0 {
+		size = alignUp(ceilDiv(totalSize, int64(p.TargetChunks)))
+	}
+	if floor := alignUp(ceilDiv(totalSize, int64(p.MaxChunks))); size < floor {
+		size = floor
+	}
+	size = clamp(size, lo, hi)
+
+	if ceilDiv(totalSize, size) > int64(p.MaxChunks) {
+		return ChunkPlan{}, fmt.Errorf("%w: %d bytes needs more than %d chunks of at most %d bytes",
+			ErrChunkSizeUnsatisfiable, totalSize, p.MaxChunks, hi)
+	}
+	return planFor(totalSize, size), nil
+}
+
+// Check validates the chunking a client declares when it starts a chunked
+// upload. A chunkSize from Negotiate must lie within the server bounds and
+// split totalSize into exactly totalChunks; without one the chunk size is
+// derived from totalChunks.
+func (p ChunkSizePolicy) Check(totalSize int64, totalChunks int, chunkSize int64) (ChunkPlan, error) {
+	p = p.withDefaults()
+	if totalChunks <= 0 || totalChunks > p.MaxChunks {
+		return ChunkPlan{}, fmt.Errorf("total_chunks must be between 1 and %d: %w", p.MaxChunks, serviceerrors.ErrInvalidRequest)
+	}
+	if chunkSize <= 0 {
+		return ChunkPlan{ChunkSize: ceilDiv(totalSize, int64(totalChunks)), TotalChunks: totalChunks}, nil
+	}
+	if chunkSize > p.MaxChunkSize || (chunkSize < p.MinChunkSize && chunkSize < totalSize) {
+		return ChunkPlan{}, fmt.Errorf("chunk_size %d outside %d..%d: %w", chunkSize, p.MinChunkSize, p.MaxChunkSize, serviceerrors.ErrInvalidRequest)
+	}
+	if n := ceilDiv(totalSize, chunkSize); n != int64(totalChunks) {
+		return ChunkPlan{}, fmt.Errorf("chunk_size %d splits %d bytes into %d chunks, not %d: %w",
+			chunkSize, totalSize, n, totalChunks, serviceerrors.ErrInvalidRequest)
+	}
+	return ChunkPlan{ChunkSize: chunkSize, TotalChunks: totalChunks}, nil
+}
+
+// MaxChunks is the most chunks an upload may be split into.
+func (s *UploadService) MaxChunks() int {
+	return s.chunkPolicy.withDefaults().MaxChunks
+}
+
+// SetChunkSizePolicy sets the bounds used by NegotiateChunkSize.
+func (s *UploadService) SetChunkSizePolicy(p ChunkSizePolicy) {
+	s.chunkPolicy = p
+}
+
+// NegotiateChunkSize returns the chunk plan for a file of totalSize bytes
+// under the service's chunk-size policy and upload size limit.
+func (s *UploadService) NegotiateChunkSize(totalSize int64, client ChunkConstraints) (ChunkPlan, error) {
+	if s.maxUploadSize > 0 && totalSize > s.maxUploadSize {
+		return ChunkPlan{}, fmt.Errorf("file size %d exceeds maximum allowed size %d", totalSize, s.maxUploadSize)
+	}
+	return s.chunkPolicy.Negotiate(totalSize, client)
+}
+
+func planFor(totalSize, size int64) ChunkPlan {
+	return ChunkPlan{ChunkSize: size, TotalChunks: int(ceilDiv(totalSize, size))}
+}
+
+func ceilDiv(a, b int64) int64 {
+	return (a + b - 1) / b
+}
+
+func alignUp(n int64) int64 {
+	return ceilDiv(n, chunkAlignment) * chunkAlignment
+}
+
+func clamp(n, lo, hi int64) int64 {
+	if n < lo {
+		return lo
+	}
+	if n > hi {
+		return hi
+	}
+	return n
+}
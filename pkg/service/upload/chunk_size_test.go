@@ -0,0 +1,150 @@
+package upload
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rtcdance/streamgate/pkg/service/serviceerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	mib int64 = 1024 * 1024
+	gib       = 1024 * mib
+)
+
+func TestChunkSizePolicy_ScalesWithFileSize(t *testing.T) {
+	var p ChunkSizePolicy
+
+	tests := []struct {
+		name       string
+		size       int64
+		wantSize   int64
+		wantChunks int
+	}{
+		{name: "tiny file is one chunk", size: 512 * 1024, wantSize: 512 * 1024, wantChunks: 1},
+		{name: "exactly min is one chunk", size: DefaultMinChunkSize, wantSize: DefaultMinChunkSize, wantChunks: 1},
+		{name: "small file uses min chunk", size: 20 * mib, wantSize: DefaultMinChunkSize, wantChunks: 4},
+		{name: "medium file aims for target", size: 500 * mib, wantSize: 8 * mib, wantChunks: 63},
+		{name: "multi-GB file grows chunks", size: 10 * gib, wantSize: 160 * mib, wantChunks: 64},
+		{name: "huge file capped at max chunk", size: 4096 * gib, wantSize: DefaultMaxChunkSize, wantChunks: 8192},
+	}
+
+	prev := int64(0)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := p.Negotiate(tt.size, ChunkConstraints{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSize, plan.ChunkSize)
+			assert.Equal(t, tt.wantChunks, plan.TotalChunks)
+			assert.LessOrEqual(t, plan.ChunkSize, DefaultMaxChunkSize)
+			assert.LessOrEqual(t, plan.TotalChunks, DefaultMaxChunks)
+			assert.GreaterOrEqual(t, plan.ChunkSize, prev, "chunk size must not shrink as files grow")
+			prev = plan.ChunkSize
+		})
+	}
+}
+
+func TestChunkSizePolicy_ConfiguredBounds(t *testing.T) {
+	p := ChunkSizePolicy{MinChunkSize: 8 * mib, MaxChunkSize: 64 * mib, MaxChunks: 1000}
+
+	plan, err := p.Negotiate(20*mib, ChunkConstraints{})
+	require.NoError(t, err)
+	assert.Equal(t, 8*mib, plan.ChunkSize)
+
+	plan, err = p.Negotiate(50*gib, ChunkConstraints{})
+	require.NoError(t, err)
+	assert.Equal(t, 64*mib, plan.ChunkSize)
+	assert.Equal(t, 800, plan.TotalChunks)
+
+	_, err = p.Negotiate(100*gib, ChunkConstraints{})
+	assert.True(t, errors.Is(err, ErrChunkSizeUnsatisfiable))
+}
+
+func TestChunkSizePolicy_ClientConstraints(t *testing.T) {
+	var p ChunkSizePolicy
+
+	t.Run("client max narrows chunk size", func(t *testing.T) {
+		plan, err := p.Negotiate(10*gib, ChunkConstraints{MaxChunkSize: 32 * mib})
+		require.NoError(t, err)
+		assert.Equal(t, 32*mib, plan.ChunkSize)
+		assert.Equal(t, 320, plan.TotalChunks)
+	})
+
+	t.Run("client preference is honoured within bounds", func(t *testing.T) {
+		plan, err := p.Negotiate(1*gib, ChunkConstraints{PreferredChunkSize: 10 * mib})
+		require.NoError(t, err)
+		assert.Equal(t, 10*mib, plan.ChunkSize)
+	})
+
+	t.Run("client preference below server min is raised", func(t *testing.T) {
+		plan, err := p.Negotiate(1*gib, ChunkConstraints{PreferredChunkSize: mib})
+		require.NoError(t, err)
+		assert.Equal(t, DefaultMinChunkSize, plan.ChunkSize)
+	})
+
+	t.Run("preference that would exceed part limit is raised", func(t *testing.T) {
+		plan, err := p.Negotiate(100*gib, ChunkConstraints{PreferredChunkSize: 5 * mib})
+		require.NoError(t, err)
+		assert.LessOrEqual(t, plan.TotalChunks, DefaultMaxChunks)
+	})
+
+	t.Run("disjoint bounds are rejected", func(t *testing.T) {
+		_, err := p.Negotiate(1*gib, ChunkConstraints{MaxChunkSize: mib})
+		assert.True(t, errors.Is(err, ErrChunkSizeUnsatisfiable))
+	})
+
+	t.Run("non-positive size is rejected", func(t *testing.T) {
+		_, err := p.Negotiate(0, ChunkConstraints{})
+		assert.Error(t, err)
+	})
+}
+
+func TestUploadService_NegotiateChunkSize(t *testing.T) {
+	svc := NewUploadService(&mockDB{}, nil, "bucket")
+	svc.SetChunkSizePolicy(ChunkSizePolicy{MinChunkSize: 16 * mib})
+
+	plan, err := svc.NegotiateChunkSize(100*mib, ChunkConstraints{})
+	require.NoError(t, err)
+	assert.Equal(t, 16*mib, plan.ChunkSize)
+
+	svc.SetMaxUploadSize(50 * mib)
+	_, err = svc.NegotiateChunkSize(100*mib, ChunkConstraints{})
+	assert.Error(t, err)
+}
+
+func TestChunkSizePolicy_Check(t *testing.T) {
+	p := ChunkSizePolicy{MaxChunks: 100}
+	negotiated, err := p.Negotiate(2*gib, ChunkConstraints{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		totalSize   int64
+		totalChunks int
+		chunkSize   int64
+		want        ChunkPlan
+		wantErr     bool
+	}{
+		{"negotiated", 2 * gib, negotiated.TotalChunks, negotiated.ChunkSize, negotiated, false},
+		{"derived", 100 * mib, 10, 0, ChunkPlan{ChunkSize: 10 * mib, TotalChunks: 10}, false},
+		{"single small chunk", mib, 1, mib, ChunkPlan{ChunkSize: mib, TotalChunks: 1}, false},
+		{"too many chunks", 100 * mib, 101, 0, ChunkPlan{}, true},
+		{"no chunks", 100 * mib, 0, 0, ChunkPlan{}, true},
+		{"count mismatch", 2 * gib, negotiated.TotalChunks + 1, negotiated.ChunkSize, ChunkPlan{}, true},
+		{"below minimum", 100 * mib, 100, mib, ChunkPlan{}, true},
+		{"above maximum", 2 * gib, 2, gib, ChunkPlan{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := p.Check(tt.totalSize, tt.totalChunks, tt.chunkSize)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, serviceerrors.ErrInvalidRequest)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, plan)
+		})
+	}
+}
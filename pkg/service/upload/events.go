@@ -0,0 +1,103 @@
+package upload
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/event"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// eventPublishTimeout bounds publishing the upload.completed event.
+const eventPublishTimeout = 5 * time.Second
+
+// SetEventBus makes completed uploads publish an upload.completed event.
+// For video uploads the event carries the ID of the transcode job it asks
+// for, which the transcoder creates on receipt. Set it instead of
+// registering the auto-transcode hook, or every video is transcoded twice.
+func (s *UploadService) SetEventBus(bus event.EventBus) {
+	s.events = bus
+}
+
+// EventOutbox stores events in the transaction that makes them true and
+// publishes them after it commits. *outbox.Outbox implements it.
+type EventOutbox interface {
+	Enqueue(ctx context.Context, tx *sql.Tx, ev *event.Event) error
+	Notify()
+}
+
+// SetEventOutbox makes completed uploads enqueue their upload.completed
+// event in the transaction that creates the content, so the event is
+// published, retried if the broker is down, if and only if the content
+// exists. It takes precedence over SetEventBus. The event ID is the upload
+// ID, which consumers deduplicate on.
+func (s *UploadService) SetEventOutbox(outbox EventOutbox) {
+	s.outbox = outbox
+}
+
+// publishCompleted announces a processed upload and returns the ID of the
+// transcode job the event requests, or "" if none was requested. The
+// upload is already committed, so a failed publish is logged, not returned.
+func (s *UploadService) publishCompleted(ctx context.Context, upload *UploadInfo, contentID string) string {
+	if s.events == nil {
+		return ""
+	}
+
+	ev, jobID := s.completedEvent(ctx, upload, contentID)
+	pubCtx, cancel := context.WithTimeout(context.Background(), eventPublishTimeout)
+	defer cancel()
+	if err := s.events.Publish(pubCtx, ev); err != nil {
+		s.logger.Warn("Failed to publish upload completed event",
+			zap.String("upload_id", upload.ID),
+			zap.String("content_id", contentID),
+			zap.Error(err))
+		return ""
+	}
+	return jobID
+}
+
+// completedEvent builds the upload.completed event for a processed upload
+// and returns it with the ID of the transcode job it requests, or "".
+//
+// The event data holds upload_id, content_id, owner_id, filename,
+// content_type, size and, for videos, job_id and input_url: a presigned
+// URL when a presigner is set, the upload's storage URL otherwise.
+func (s *UploadService) completedEvent(ctx context.Context, upload *UploadInfo, contentID string) (*event.Event, string) {
+	data := map[string]interface{}{
+		"upload_id":    upload.ID,
+		"content_id":   contentID,
+		"owner_id":     upload.OwnerID,
+		"filename":     upload.Filename,
+		"content_type": upload.ContentType,
+		"size":         upload.Size,
+	}
+	jobID := ""
+	if ContentTypeToType(upload.ContentType) == "video" {
+		inputURL := upload.URL
+		if s.presigner != nil {
+			storageKey := strings.TrimPrefix(upload.URL, "/"+s.bucket+"/")
+			presigned, err := s.presigner.PresignedURL(ctx, s.bucket, storageKey, defaultPresignedURLExpiry)
+			if err != nil {
+				s.logger.Warn("Failed to presign transcode input, using the storage URL",
+					zap.String("upload_id", upload.ID), zap.Error(err))
+			} else {
+				inputURL = presigned
+			}
+		}
+		jobID = uuid.New().String()
+		data["job_id"] = jobID
+		data["input_url"] = inputURL
+	}
+
+	return &event.Event{
+		ID:        upload.ID,
+		Type:      event.EventTypeUploadCompleted,
+		Source:    "upload",
+		Timestamp: time.Now().Unix(),
+		Data:      data,
+	}, jobID
+}
@@ -0,0 +1,205 @@
+package upload
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/event"
+	stg "github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newCompletedUploadDB(contentType string) *mockDB {
+	now := time.Now()
+	return &mockDB{
+		queryRowFn: func(_ context.Context, _ string, _ ...interface{}) *stg.CancelRow {
+			return stg.NewTestCancelRow(&mockRow{vals: []interface{}{
+				"upload-1", "video.mp4", int64(1024),
+				contentType, "abc123", "completed", "/bucket/key", "owner1",
+				now, now,
+			}})
+		},
+		inTxFn: func(_ context.Context, fn func(tx *sql.Tx) error) error {
+			return fn(testTx)
+		},
+	}
+}
+
+func subscribeCompleted(t *testing.T, bus event.EventBus) <-chan *event.Event {
+	t.Helper()
+	events := make(chan *event.Event, 1)
+	_, err := bus.Subscribe(context.Background(), event.EventTypeUploadCompleted, func(_ context.Context, e *event.Event) error {
+		events <- e
+		return nil
+	})
+	require.NoError(t, err)
+	return events
+}
+
+func TestUploadService_CompleteUploadWithJob_PublishesEvent(t *testing.T) {
+	bus, err := event.NewMemoryEventBus()
+	require.NoError(t, err)
+	events := subscribeCompleted(t, bus)
+
+	svc := NewUploadService(newCompletedUploadDB("video/mp4"), newMockObjStore(), "bucket", zap.NewNop())
+	svc.SetPresigner(&mockPresigner{url: "https://storage.example.com/key?sig=1"})
+	svc.SetEventBus(bus)
+
+	contentID, jobID, err := svc.CompleteUploadWithJob(context.Background(), "upload-1")
+	require.NoError(t, err)
+	require.NotEmpty(t, jobID)
+
+	select {
+	case e := <-events:
+		assert.Equal(t, "upload-1", e.ID)
+		assert.Equal(t, jobID, e.Data["job_id"])
+		assert.Equal(t, contentID, e.Data["content_id"])
+		assert.Equal(t, "owner1", e.Data["owner_id"])
+		assert.Equal(t, "https://storage.example.com/key?sig=1", e.Data["input_url"])
+	case <-time.After(time.Second):
+		t.Fatal("no upload.completed event")
+	}
+}
+
+func TestUploadService_CompleteUploadWithJob_NonVideo(t *testing.T) {
+	bus, err := event.NewMemoryEventBus()
+	require.NoError(t, err)
+	events := subscribeCompleted(t, bus)
+
+	svc := NewUploadService(newCompletedUploadDB("image/png"), newMockObjStore(), "bucket", zap.NewNop())
+	svc.SetEventBus(bus)
+
+	_, jobID, err := svc.CompleteUploadWithJob(context.Background(), "upload-1")
+	require.NoError(t, err)
+	assert.Empty(t, jobID, "only videos are transcoded")
+
+	select {
+	case e := <-events:
+		assert.NotContains(t, e.Data, "job_id")
+	case <-time.After(time.Second):
+		t.Fatal("no upload.completed event")
+	}
+}
+
+func TestUploadService_CompleteUploadWithJob_NoEventBus(t *testing.T) {
+	svc := NewUploadService(newCompletedUploadDB("video/mp4"), newMockObjStore(), "bucket", zap.NewNop())
+
+	contentID, jobID, err := svc.CompleteUploadWithJob(context.Background(), "upload-1")
+	require.NoError(t, err)
+	assert.NotEmpty(t, contentID)
+	assert.Empty(t, jobID)
+}
+
+// fakeOutbox records the events enqueued in a transaction.
+type fakeOutbox struct {
+	err      error
+	enqueued []*event.Event
+	txs      []*sql.Tx
+	notified int
+}
+
+func (o *fakeOutbox) Enqueue(_ context.Context, tx *sql.Tx, ev *event.Event) error {
+	if o.err != nil {
+		return o.err
+	}
+	o.enqueued = append(o.enqueued, ev)
+	o.txs = append(o.txs, tx)
+	return nil
+}
+
+func (o *fakeOutbox) Notify() { o.notified++ }
+
+func TestUploadService_CompleteUploadWithJob_Outbox(t *testing.T) {
+	tests := []struct {
+		name       string
+		enqueueErr error
+		wantErr    bool
+	}{
+		{"enqueued in the content transaction", nil, false},
+		{"enqueue failure rolls back", errors.New("disk full"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus, err := event.NewMemoryEventBus()
+			require.NoError(t, err)
+			events := subscribeCompleted(t, bus)
+			outbox := &fakeOutbox{err: tt.enqueueErr}
+
+			svc := NewUploadService(newCompletedUploadDB("video/mp4"), newMockObjStore(), "bucket", zap.NewNop())
+			svc.SetEventBus(bus)
+			svc.SetEventOutbox(outbox)
+
+			contentID, jobID, err := svc.CompleteUploadWithJob(context.Background(), "upload-1")
+			if tt.wantErr {
+				assert.ErrorIs(t, err, tt.enqueueErr)
+				assert.Zero(t, outbox.notified)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, outbox.enqueued, 1)
+			e := outbox.enqueued[0]
+			assert.Same(t, testTx, outbox.txs[0])
+			assert.Equal(t, "upload-1", e.ID, "the upload ID is the idempotency key")
+			assert.Equal(t, contentID, e.Data["content_id"])
+			assert.Equal(t, jobID, e.Data["job_id"])
+			assert.NotEmpty(t, jobID)
+			assert.Equal(t, 1, outbox.notified)
+
+			select {
+			case <-events:
+				t.Fatal("the outbox relay publishes, not the service")
+			case <-time.After(50 * time.Millisecond):
+			}
+		})
+	}
+}
+
+// cidObjStore is an object store that addresses objects by content.
+type cidObjStore struct {
+	*mockObjStore
+	cid string
+	err error
+}
+
+func (s *cidObjStore) CID(_ context.Context, _, _ string) (string, error) {
+	return s.cid, s.err
+}
+
+func TestUploadService_CompleteUploadWithJob_RecordsCID(t *testing.T) {
+	tests := []struct {
+		name    string
+		store   UploadObjectStorage
+		wantCID string
+	}{
+		{"content-addressed", &cidObjStore{mockObjStore: newMockObjStore(), cid: "bafkreicid"}, "bafkreicid"},
+		{"not content-addressed", &cidObjStore{mockObjStore: newMockObjStore(), err: stg.ErrNotContentAddressed}, ""},
+		{"plain store", newMockObjStore(), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newCompletedUploadDB("video/mp4")
+			var recorded []interface{}
+			db.execFn = func(_ context.Context, _ string, args ...interface{}) (sql.Result, error) {
+				recorded = args
+				return &mockResult{}, nil
+			}
+			svc := NewUploadService(db, tt.store, "bucket", zap.NewNop())
+
+			contentID, _, err := svc.CompleteUploadWithJob(context.Background(), "upload-1")
+			require.NoError(t, err)
+			if tt.wantCID == "" {
+				assert.Nil(t, recorded)
+				return
+			}
+			require.Len(t, recorded, 3)
+			assert.Equal(t, contentID, recorded[0])
+			assert.Equal(t, tt.wantCID, recorded[1])
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package upload
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rtcdance/streamgate/pkg/service/serviceerrors"
+	"github.com/rtcdance/streamgate/pkg/storage"
+
+	"go.uber.org/zap"
+)
+
+// ErrMultipartNotConfigured is returned by the direct multipart calls when
+// the object store cannot take presigned part uploads. Callers should fall
+// back to the chunked proxy flow.
+var ErrMultipartNotConfigured = errors.New("direct multipart upload not configured")
+
+// MultipartPart is one presigned part URL. The client PUTs bytes
+// [(PartNumber-1)*PartSize, PartNumber*PartSize) of the file to URL and
+// keeps the ETag response header for completion.
+type MultipartPart struct {
+	PartNumber int    `json:"part_number"`
+	URL        string `json:"url"`
+}
+
+// MultipartPlan describes a direct-to-store multipart upload.
+type MultipartPlan struct {
+	UploadID   string          `json:"upload_id"`
+	StorageKey string          `json:"storage_key"`
+	PartSize   int64           `json:"part_size"`
+	Parts      []MultipartPart `json:"parts"`
+	ExpiresIn  int             `json:"expires_in"`
+}
+
+// SetMultipartUploader enables direct multipart uploads.
+func (s *UploadService) SetMultipartUploader(m storage.MultipartUploader) {
+	s.multipart = m
+}
+
+// InitiateMultipartUpload starts a multipart upload in the object store and
+// returns a presigned URL for every part, so the file never passes through
+// this service. Part sizes follow the chunk-size policy.
+func (s *UploadService) InitiateMultipartUpload(ctx context.Context, filename string, size int64, contentType, ownerID string) (*MultipartPlan, error) {
+	if s.multipart == nil {
+		return nil, ErrMultipartNotConfigured
+	}
+	if s.db == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+	if err := s.CheckStorageQuota(ctx, ownerID, size); err != nil {
+		return nil, err
+	}
+	// Every part but the last must meet the S3 minimum, even where the
+	// policy allows smaller chunks for the proxy flow.
+	plan, err := s.NegotiateChunkSize(size, ChunkConstraints{MinChunkSize: DefaultMinChunkSize})
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", err, serviceerrors.ErrInvalidRequest)
+	}
+
+	uploadID := uuid.New().String()
+	storageKey := fmt.Sprintf("%s/%s%s", ownerID, uploadID, filepath.Ext(filename))
+	if contentType == "" {
+		contentType = DetectContentType(filename)
+	}
+
+	multipartID, err := s.multipart.CreateMultipartUpload(ctx, s.bucket, storageKey, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	uploadInfo := &UploadInfo{
+		ID:          uploadID,
+		Filename:    filename,
+		Size:        size,
+		ContentType: contentType,
+		Status:      "uploading",
+		URL:         fmt.Sprintf("/%s/%s", s.bucket, storageKey),
+		OwnerID:     ownerID,
+		TotalChunks: plan.TotalChunks,
+		MultipartID: multipartID,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if err := s.saveUploadInfo(ctx, uploadInfo); err != nil {
+		s.abortStoreMultipart(ctx, storageKey, multipartID)
+		return nil, fmt.Errorf("failed to save upload info: %w", err)
+	}
+
+	parts := make([]MultipartPart, 0, plan.TotalChunks)
+	for n := 1; n <= plan.TotalChunks; n++ {
+		u, err := s.multipart.PresignedPartURL(ctx, s.bucket, storageKey, multipartID, n, defaultPresignedUploadExpiry)
+		if err != nil {
+			if abortErr := s.AbortMultipartUpload(ctx, uploadID); abortErr != nil {
+				s.logger.Warn("Failed to clean up multipart upload after presign failure",
+					zap.String("upload_id", uploadID), zap.Error(abortErr))
+			}
+			return nil, fmt.Errorf("failed to presign part %d: %w", n, err)
+		}
+		parts = append(parts, MultipartPart{PartNumber: n, URL: u})
+	}
+
+	return &MultipartPlan{
+		UploadID:   uploadID,
+		StorageKey: storageKey,
+		PartSize:   plan.ChunkSize,
+		Parts:      parts,
+		ExpiresIn:  int(defaultPresignedUploadExpiry.Seconds()),
+	}, nil
+}
+
+// CompleteMultipartUpload asks the store to assemble the parts the client
+// uploaded and marks the upload completed. Every planned part must be
+// listed exactly once with the ETag the store returned for it.
+func (s *UploadService) CompleteMultipartUpload(ctx context.Context, uploadID string, parts []storage.CompletedPart) error {
+	if s.multipart == nil {
+		return ErrMultipartNotConfigured
+	}
+	ctx = storage.WithPrimary(ctx)
+	info, err := s.GetUploadStatus(ctx, uploadID)
+	if err != nil {
+		return err
+	}
+	if info.Status != "uploading" {
+		return fmt.Errorf("upload %s is %s, not uploading: %w", uploadID, info.Status, serviceerrors.ErrInvalidRequest)
+	}
+	multipartID, totalParts, err := s.multipartState(ctx, uploadID)
+	if err != nil {
+		return err
+	}
+	if err := validateCompletedParts(parts, totalParts); err != nil {
+		return err
+	}
+
+	storageKey := s.storageKeyFromURL(info.URL)
+	if err := s.multipart.CompleteMultipartUpload(ctx, s.bucket, storageKey, multipartID, parts); err != nil {
+		return err
+	}
+
+	result, err := s.db.Exec(ctx,
+		"UPDATE uploads SET status = $2, updated_at = $3 WHERE id = $1 AND status = 'uploading'",
+		uploadID, "completed", time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to update upload status: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("upload already completed or status changed")
+	}
+	return nil
+}
+
+// AbortMultipartUpload discards the parts stored so far and deletes the
+// upload record.
+func (s *UploadService) AbortMultipartUpload(ctx context.Context, uploadID string) error {
+	if s.multipart == nil {
+		return ErrMultipartNotConfigured
+	}
+	ctx = storage.WithPrimary(ctx)
+	info, err := s.GetUploadStatus(ctx, uploadID)
+	if err != nil {
+		return err
+	}
+	if info.Status != "uploading" {
+		return fmt.Errorf("upload %s is %s, not uploading: %w", uploadID, info.Status, serviceerrors.ErrInvalidRequest)
+	}
+	multipartID, _, err := s.multipartState(ctx, uploadID)
+	if err != nil {
+		return err
+	}
+	s.abortStoreMultipart(ctx, s.storageKeyFromURL(info.URL), multipartID)
+
+	if _, err := s.db.Exec(ctx, "DELETE FROM uploads WHERE id = $1", uploadID); err != nil {
+		return fmt.Errorf("failed to delete upload: %w", err)
+	}
+	return nil
+}
+
+func (s *UploadService) multipartState(ctx context.Context, uploadID string) (string, int, error) {
+	var multipartID sql.NullString
+	var total sql.NullInt64
+	err := s.db.QueryRow(ctx, "SELECT multipart_id, total_chunks FROM uploads WHERE id = $1", uploadID).
+		Scan(&multipartID, &total)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read multipart state: %w", err)
+	}
+	if !multipartID.Valid || multipartID.String == "" {
+		return "", 0, fmt.Errorf("upload %s is not a multipart upload: %w", uploadID, serviceerrors.ErrInvalidRequest)
+	}
+	return multipartID.String, int(total.Int64), nil
+}
+
+func (s *UploadService) abortStoreMultipart(ctx context.Context, storageKey, multipartID string) {
+	if err := s.multipart.AbortMultipartUpload(context.WithoutCancel(ctx), s.bucket, storageKey, multipartID); err != nil {
+		s.logger.Warn("Failed to abort multipart upload", zap.String("key", storageKey), zap.Error(err))
+	}
+}
+
+func (s *UploadService) storageKeyFromURL(url string) string {
+	prefix := "/" + s.bucket + "/"
+	if len(url) < len(prefix) {
+		return url
+	}
+	return url[len(prefix):]
+}
+
+func validateCompletedParts(parts []storage.CompletedPart, totalParts int) error {
+	if totalParts > 0 && len(parts) != totalParts {
+		return fmt.Errorf("expected %d parts, got %d: %w", totalParts, len(parts), serviceerrors.ErrInvalidRequest)
+	}
+	if len(parts) == 0 {
+		return fmt.Errorf("no parts given: %w", serviceerrors.ErrInvalidRequest)
+	}
+	seen := make(map[int]bool, len(parts))
+	for _, p := range parts {
+		if p.PartNumber < 1 || p.PartNumber > len(parts) || seen[p.PartNumber] {
+			return fmt.Errorf("invalid or duplicate part number %d: %w", p.PartNumber, serviceerrors.ErrInvalidRequest)
+		}
+		if p.ETag == "" {
+			return fmt.Errorf("part %d has no etag: %w", p.PartNumber, serviceerrors.ErrInvalidRequest)
+		}
+		seen[p.PartNumber] = true
+	}
+	return nil
+}
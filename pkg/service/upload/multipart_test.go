@@ -0,0 +1,170 @@
+package upload
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/service/serviceerrors"
+	stg "github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeMultipart struct {
+	created    []string
+	presignErr error
+	completed  []stg.CompletedPart
+	aborted    []string
+}
+
+func (f *fakeMultipart) CreateMultipartUpload(_ context.Context, _, key, _ string) (string, error) {
+	f.created = append(f.created, key)
+	return "mp-1", nil
+}
+
+func (f *fakeMultipart) PresignedPartURL(_ context.Context, bucket, key, uploadID string, partNumber int, _ time.Duration) (string, error) {
+	if f.presignErr != nil {
+		return "", f.presignErr
+	}
+	return fmt.Sprintf("https://s3/%s/%s?uploadId=%s&partNumber=%d", bucket, key, uploadID, partNumber), nil
+}
+
+func (f *fakeMultipart) CompleteMultipartUpload(_ context.Context, _, _, _ string, parts []stg.CompletedPart) error {
+	f.completed = parts
+	return nil
+}
+
+func (f *fakeMultipart) AbortMultipartUpload(_ context.Context, _, key, uploadID string) error {
+	f.aborted = append(f.aborted, key+"#"+uploadID)
+	return nil
+}
+
+// multipartDB serves an upload row and its multipart state, and records
+// every Exec query.
+func multipartDB(status string, multipartID interface{}, totalParts int, execs *[]string) *mockDB {
+	now := time.Now()
+	return &mockDB{
+		queryRowFn: func(_ context.Context, query string, _ ...interface{}) *stg.CancelRow {
+			if strings.Contains(query, "SELECT multipart_id") {
+				return stg.NewTestCancelRow(&mockRow{vals: []interface{}{multipartID, totalParts}})
+			}
+			if strings.Contains(query, "FROM uploads") {
+				return stg.NewTestCancelRow(&mockRow{vals: []interface{}{
+					"upload-1", "video.mp4", int64(12 << 20),
+					"video/mp4", "", status, "/mybucket/owner1/upload-1.mp4", "owner1",
+					now, now,
+				}})
+			}
+			return stg.NewErrorCancelRow(errors.New("unexpected query"))
+		},
+		execFn: func(_ context.Context, query string, _ ...interface{}) (sql.Result, error) {
+			if execs != nil {
+				*execs = append(*execs, query)
+			}
+			return &mockResult{rowsAffected: 1}, nil
+		},
+	}
+}
+
+func TestInitiateMultipartUpload_PresignsEveryPart(t *testing.T) {
+	mp := &fakeMultipart{}
+	var execs []string
+	svc := NewUploadService(multipartDB("uploading", "mp-1", 0, &execs), newMockObjStore(), "mybucket", zap.NewNop())
+	svc.SetStorageQuota(0)
+	svc.SetMultipartUploader(mp)
+
+	size := 12*DefaultMinChunkSize + 1
+	plan, err := svc.InitiateMultipartUpload(context.Background(), "movie.mp4", size, "", "owner1")
+	require.NoError(t, err)
+
+	assert.GreaterOrEqual(t, plan.PartSize, DefaultMinChunkSize)
+	assert.Len(t, plan.Parts, int((size+plan.PartSize-1)/plan.PartSize))
+	for i, p := range plan.Parts {
+		assert.Equal(t, i+1, p.PartNumber)
+		assert.Contains(t, p.URL, fmt.Sprintf("partNumber=%d", i+1))
+	}
+	assert.Equal(t, "owner1/"+plan.UploadID+".mp4", plan.StorageKey)
+	assert.Equal(t, []string{plan.StorageKey}, mp.created)
+	require.Len(t, execs, 1)
+	assert.Contains(t, execs[0], "multipart_id")
+}
+
+func TestInitiateMultipartUpload_NotConfigured(t *testing.T) {
+	svc := NewUploadService(multipartDB("uploading", "mp-1", 0, nil), newMockObjStore(), "mybucket", zap.NewNop())
+
+	_, err := svc.InitiateMultipartUpload(context.Background(), "movie.mp4", 1024, "", "owner1")
+	assert.ErrorIs(t, err, ErrMultipartNotConfigured)
+}
+
+func TestInitiateMultipartUpload_PresignFailureAborts(t *testing.T) {
+	mp := &fakeMultipart{presignErr: errors.New("signer down")}
+	var execs []string
+	svc := NewUploadService(multipartDB("uploading", "mp-1", 1, &execs), newMockObjStore(), "mybucket", zap.NewNop())
+	svc.SetStorageQuota(0)
+	svc.SetMultipartUploader(mp)
+
+	_, err := svc.InitiateMultipartUpload(context.Background(), "movie.mp4", 1024, "", "owner1")
+	require.Error(t, err)
+	assert.Len(t, mp.aborted, 1, "the store-side upload is aborted")
+	assert.Contains(t, execs[len(execs)-1], "DELETE FROM uploads")
+}
+
+func TestCompleteMultipartUpload(t *testing.T) {
+	parts := []stg.CompletedPart{{PartNumber: 2, ETag: "b"}, {PartNumber: 1, ETag: "a"}}
+
+	tests := []struct {
+		name        string
+		status      string
+		multipartID interface{}
+		parts       []stg.CompletedPart
+		wantErr     error
+	}{
+		{name: "success", status: "uploading", multipartID: "mp-1", parts: parts},
+		{name: "already completed", status: "completed", multipartID: "mp-1", parts: parts, wantErr: serviceerrors.ErrInvalidRequest},
+		{name: "not multipart", status: "uploading", multipartID: nil, parts: parts, wantErr: serviceerrors.ErrInvalidRequest},
+		{name: "missing part", status: "uploading", multipartID: "mp-1", parts: parts[:1], wantErr: serviceerrors.ErrInvalidRequest},
+		{name: "duplicate part", status: "uploading", multipartID: "mp-1",
+			parts: []stg.CompletedPart{{PartNumber: 1, ETag: "a"}, {PartNumber: 1, ETag: "a"}}, wantErr: serviceerrors.ErrInvalidRequest},
+		{name: "missing etag", status: "uploading", multipartID: "mp-1",
+			parts: []stg.CompletedPart{{PartNumber: 1, ETag: "a"}, {PartNumber: 2}}, wantErr: serviceerrors.ErrInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mp := &fakeMultipart{}
+			var execs []string
+			svc := NewUploadService(multipartDB(tt.status, tt.multipartID, 2, &execs), newMockObjStore(), "mybucket", zap.NewNop())
+			svc.SetMultipartUploader(mp)
+
+			err := svc.CompleteMultipartUpload(context.Background(), "upload-1", tt.parts)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, mp.completed)
+				assert.Empty(t, execs)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.parts, mp.completed)
+			require.Len(t, execs, 1)
+			assert.Contains(t, execs[0], "UPDATE uploads SET status")
+		})
+	}
+}
+
+func TestAbortMultipartUpload(t *testing.T) {
+	mp := &fakeMultipart{}
+	var execs []string
+	svc := NewUploadService(multipartDB("uploading", "mp-1", 2, &execs), newMockObjStore(), "mybucket", zap.NewNop())
+	svc.SetMultipartUploader(mp)
+
+	require.NoError(t, svc.AbortMultipartUpload(context.Background(), "upload-1"))
+	assert.Equal(t, []string{"owner1/upload-1.mp4#mp-1"}, mp.aborted)
+	require.Len(t, execs, 1)
+	assert.Contains(t, execs[0], "DELETE FROM uploads")
+}
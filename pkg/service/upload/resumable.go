@@ -0,0 +1,154 @@
+package upload
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rtcdance/streamgate/pkg/service/serviceerrors"
+	"github.com/rtcdance/streamgate/pkg/storage"
+
+	"go.uber.org/zap"
+)
+
+// ErrChecksumMismatch is returned when the assembled file does not hash to
+// the SHA-256 the client declared when the upload was initiated.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ResumeStatus tells a client which chunks it still has to send before it
+// can complete an upload.
+type ResumeStatus struct {
+	UploadID       string `json:"upload_id"`
+	Status         string `json:"status"`
+	TotalChunks    int    `json:"total_chunks"`
+	UploadedChunks int    `json:"uploaded_chunks"`
+	MissingChunks  []int  `json:"missing_chunks"`
+}
+
+// InitiateChunkedUploadWithChecksum is InitiateChunkedUpload for a client
+// that knows the SHA-256 of the whole file. The checksum is kept as the
+// upload's hash and CompleteChunkedUpload rejects an assembly that does
+// not match it. A chunkSize from NegotiateChunkSize is checked against
+// totalChunks and recorded with the upload; zero derives it.
+func (s *UploadService) InitiateChunkedUploadWithChecksum(ctx context.Context, filename string, totalSize int64, totalChunks int, chunkSize int64, checksum, ownerID string) (string, error) {
+	sum, err := normalizeChecksum(checksum)
+	if err != nil {
+		return "", err
+	}
+	return s.initiateChunkedUpload(ctx, filename, totalSize, totalChunks, chunkSize, sum, ownerID)
+}
+
+// GetResumeStatus reports the chunk indices below totalChunks that have not
+// been stored yet, in ascending order. A totalChunks of zero uses the count
+// recorded when the upload was initiated, so a client that lost its own
+// state, or talks to a different replica, can still resume.
+func (s *UploadService) GetResumeStatus(ctx context.Context, uploadID string, totalChunks int) (*ResumeStatus, error) {
+	if totalChunks < 0 {
+		return nil, fmt.Errorf("total_chunks must not be negative: %w", serviceerrors.ErrInvalidRequest)
+	}
+	// A client resuming right after a chunk write must see that chunk.
+	ctx = storage.WithPrimary(ctx)
+	info, err := s.GetUploadStatus(ctx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if totalChunks == 0 {
+		if totalChunks, err = s.recordedTotalChunks(ctx, uploadID); err != nil {
+			return nil, err
+		}
+	}
+	chunks, err := s.GetChunkStatuses(ctx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := make(map[int]bool, len(chunks))
+	for _, ch := range chunks {
+		if ch.Uploaded {
+			stored[ch.ChunkIndex] = true
+		}
+	}
+	status := &ResumeStatus{
+		UploadID:      uploadID,
+		Status:        info.Status,
+		TotalChunks:   totalChunks,
+		MissingChunks: make([]int, 0),
+	}
+	if info.Status != "uploading" {
+		status.UploadedChunks = totalChunks
+		return status, nil
+	}
+	for i := 0; i < totalChunks; i++ {
+		if stored[i] {
+			status.UploadedChunks++
+		} else {
+			status.MissingChunks = append(status.MissingChunks, i)
+		}
+	}
+	return status, nil
+}
+
+func (s *UploadService) recordedTotalChunks(ctx context.Context, uploadID string) (int, error) {
+	var total sql.NullInt64
+	if err := s.db.QueryRow(ctx, "SELECT total_chunks FROM uploads WHERE id = $1", uploadID).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to read chunk count: %w", err)
+	}
+	if !total.Valid || total.Int64 <= 0 {
+		return 0, fmt.Errorf("upload %s has no recorded chunk count; pass total_chunks: %w", uploadID, serviceerrors.ErrInvalidRequest)
+	}
+	return int(total.Int64), nil
+}
+
+// checkChunkSize rejects a chunk whose size does not match the layout
+// recorded when the upload was initiated: every chunk but the last is
+// chunk_size bytes and the last one holds the remainder. Uploads created
+// before chunk sizes were recorded are not checked.
+func (s *UploadService) checkChunkSize(ctx context.Context, uploadID string, totalSize int64, chunkIndex int, size int64) error {
+	var total, chunkSize sql.NullInt64
+	if err := s.db.QueryRow(ctx, "SELECT total_chunks, chunk_size FROM uploads WHERE id = $1", uploadID).Scan(&total, &chunkSize); err != nil {
+		return fmt.Errorf("failed to read chunk layout: %w", err)
+	}
+	if !total.Valid || total.Int64 <= 0 || !chunkSize.Valid || chunkSize.Int64 <= 0 {
+		return nil
+	}
+	if int64(chunkIndex) >= total.Int64 {
+		return fmt.Errorf("chunk_index %d out of range for %d chunks: %w", chunkIndex, total.Int64, serviceerrors.ErrInvalidRequest)
+	}
+	want := chunkSize.Int64
+	if int64(chunkIndex) == total.Int64-1 {
+		want = totalSize - chunkSize.Int64*(total.Int64-1)
+	}
+	if size != want {
+		return fmt.Errorf("chunk %d is %d bytes, expected %d: %w", chunkIndex, size, want, serviceerrors.ErrInvalidRequest)
+	}
+	return nil
+}
+
+// resetChunks drops every stored chunk of an upload so that the client can
+// send the file again under the same upload ID.
+func (s *UploadService) resetChunks(ctx context.Context, uploadID string, totalChunks int) {
+	keys := make([]string, 0, totalChunks)
+	for i := 0; i < totalChunks; i++ {
+		keys = append(keys, fmt.Sprintf("chunks/%s/%d", uploadID, i))
+	}
+	if err := s.objStore.DeleteObjects(ctx, s.bucket, keys); err != nil {
+		s.logger.Warn("Failed to delete chunks after checksum mismatch", zap.String("upload_id", uploadID), zap.Error(err))
+	}
+	if _, err := s.db.Exec(ctx, "DELETE FROM upload_chunks WHERE upload_id = $1", uploadID); err != nil {
+		s.logger.Warn("Failed to reset chunk records after checksum mismatch", zap.String("upload_id", uploadID), zap.Error(err))
+	}
+}
+
+func normalizeChecksum(checksum string) (string, error) {
+	sum := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(checksum), "sha256:"))
+	if sum == "" {
+		return "", nil
+	}
+	if b, err := hex.DecodeString(sum); err != nil || len(b) != 32 {
+		return "", fmt.Errorf("checksum must be a hex-encoded SHA-256: %w", serviceerrors.ErrInvalidRequest)
+	}
+	return sum, nil
+}
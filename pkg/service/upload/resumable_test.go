@@ -0,0 +1,200 @@
+package upload
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/service/serviceerrors"
+	stg "github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func resumableDB(hash, status string, chunkRows [][]interface{}, execs *[]string) *mockDB {
+	return resumableDBWithTotal(hash, status, 0, chunkRows, execs)
+}
+
+func resumableDBWithTotal(hash, status string, recordedTotal int, chunkRows [][]interface{}, execs *[]string) *mockDB {
+	now := time.Now()
+	return &mockDB{
+		queryRowFn: func(_ context.Context, query string, _ ...interface{}) *stg.CancelRow {
+			if strings.Contains(query, "SELECT total_chunks") {
+				if recordedTotal == 0 {
+					return stg.NewTestCancelRow(&mockRow{vals: []interface{}{nil}})
+				}
+				return stg.NewTestCancelRow(&mockRow{vals: []interface{}{recordedTotal}})
+			}
+			if strings.Contains(query, "FROM uploads") {
+				return stg.NewTestCancelRow(&mockRow{vals: []interface{}{
+					"upload-1", "video.mp4", int64(18),
+					"video/mp4", hash, status, "", "owner1",
+					now, now,
+				}})
+			}
+			if strings.Contains(query, "COUNT(*)") {
+				return stg.NewTestCancelRow(&mockRow{vals: []interface{}{len(chunkRows)}})
+			}
+			return stg.NewErrorCancelRow(errors.New("unexpected query"))
+		},
+		queryFn: func(_ context.Context, _ string, _ ...interface{}) (stg.Rows, error) {
+			return &mockRows{rows: chunkRows}, nil
+		},
+		execFn: func(_ context.Context, query string, _ ...interface{}) (sql.Result, error) {
+			if execs != nil {
+				*execs = append(*execs, query)
+			}
+			return &mockResult{rowsAffected: 1}, nil
+		},
+	}
+}
+
+func storeWithChunks(n int) *mockObjStore {
+	store := newMockObjStore()
+	for i := 0; i < n; i++ {
+		store.data[fmt.Sprintf("mybucket/chunks/upload-1/%d", i)] = []byte(fmt.Sprintf("chunk%d", i))
+	}
+	return store
+}
+
+func TestCompleteChunkedUpload_ChecksumMatches(t *testing.T) {
+	sum := sha256.Sum256([]byte("chunk0chunk1chunk2"))
+	store := storeWithChunks(3)
+	rows := [][]interface{}{{0, int64(6), true}, {1, int64(6), true}, {2, int64(6), true}}
+	svc := NewUploadService(resumableDB(hex.EncodeToString(sum[:]), "uploading", rows, nil), store, "mybucket", zap.NewNop())
+
+	require.NoError(t, svc.CompleteChunkedUpload(context.Background(), "upload-1", 3))
+	assert.Equal(t, "chunk0chunk1chunk2", string(store.data["mybucket/owner1/upload-1.mp4"]))
+	for i := 0; i < 3; i++ {
+		assert.NotContains(t, store.data, fmt.Sprintf("mybucket/chunks/upload-1/%d", i), "chunks are removed once assembled")
+	}
+}
+
+func TestCompleteChunkedUpload_ChecksumMismatch(t *testing.T) {
+	sum := sha256.Sum256([]byte("something else"))
+	store := storeWithChunks(3)
+	rows := [][]interface{}{{0, int64(6), true}, {1, int64(6), true}, {2, int64(6), true}}
+	var execs []string
+	svc := NewUploadService(resumableDB(hex.EncodeToString(sum[:]), "uploading", rows, &execs), store, "mybucket", zap.NewNop())
+
+	err := svc.CompleteChunkedUpload(context.Background(), "upload-1", 3)
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.Empty(t, store.data, "neither the assembly nor the chunks are kept")
+	require.Len(t, execs, 1)
+	assert.Contains(t, execs[0], "DELETE FROM upload_chunks", "chunk records are reset and status is untouched")
+}
+
+func TestInitiateChunkedUploadWithChecksum(t *testing.T) {
+	var saved []interface{}
+	db := &mockDB{
+		execFn: func(_ context.Context, _ string, args ...interface{}) (sql.Result, error) {
+			saved = args
+			return &mockResult{}, nil
+		},
+	}
+	svc := NewUploadService(db, newMockObjStore(), "bucket", zap.NewNop())
+	svc.storageQuota = 0
+	sum := strings.Repeat("AB", 32)
+
+	_, err := svc.InitiateChunkedUploadWithChecksum(context.Background(), "video.mp4", 1024, 2, 0, "sha256:"+sum, "owner1")
+	require.NoError(t, err)
+	assert.Equal(t, strings.ToLower(sum), saved[4], "checksum is stored normalized as the upload hash")
+	assert.Equal(t, sql.NullInt64{Int64: 2, Valid: true}, saved[10], "chunk count is recorded for resume")
+	assert.Equal(t, sql.NullInt64{Int64: 512, Valid: true}, saved[12], "chunk size is derived from the count")
+
+	_, err = svc.InitiateChunkedUploadWithChecksum(context.Background(), "video.mp4", 12*1024*1024, 2, 6*1024*1024, "", "owner1")
+	require.NoError(t, err)
+	assert.Equal(t, sql.NullInt64{Int64: 6 * 1024 * 1024, Valid: true}, saved[12], "negotiated chunk size is recorded")
+	_, err = svc.InitiateChunkedUploadWithChecksum(context.Background(), "video.mp4", 12*1024*1024, 3, 6*1024*1024, "", "owner1")
+	assert.ErrorIs(t, err, serviceerrors.ErrInvalidRequest, "chunk count must match the negotiated size")
+
+	for _, bad := range []string{"abc123", strings.Repeat("zz", 32), strings.Repeat("ab", 33)} {
+		_, err := svc.InitiateChunkedUploadWithChecksum(context.Background(), "video.mp4", 1024, 2, 0, bad, "owner1")
+		assert.ErrorIs(t, err, serviceerrors.ErrInvalidRequest, bad)
+	}
+}
+
+func TestGetResumeStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      string
+		rows        [][]interface{}
+		total       int
+		wantMissing []int
+		wantDone    int
+	}{
+		{"none uploaded", "uploading", nil, 3, []int{0, 1, 2}, 0},
+		{"gaps", "uploading", [][]interface{}{{0, int64(6), true}, {2, int64(6), true}, {3, int64(6), false}}, 5, []int{1, 3, 4}, 2},
+		{"all uploaded", "uploading", [][]interface{}{{0, int64(6), true}, {1, int64(6), true}}, 2, []int{}, 2},
+		{"already assembled", "completed", nil, 4, []int{}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewUploadService(resumableDB("", tt.status, tt.rows, nil), newMockObjStore(), "mybucket", zap.NewNop())
+			st, err := svc.GetResumeStatus(context.Background(), "upload-1", tt.total)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantMissing, st.MissingChunks)
+			assert.Equal(t, tt.wantDone, st.UploadedChunks)
+			assert.Equal(t, tt.total, st.TotalChunks)
+		})
+	}
+
+	svc := NewUploadService(resumableDB("", "uploading", nil, nil), newMockObjStore(), "mybucket", zap.NewNop())
+	_, err := svc.GetResumeStatus(context.Background(), "upload-1", -1)
+	assert.ErrorIs(t, err, serviceerrors.ErrInvalidRequest)
+	_, err = svc.GetResumeStatus(context.Background(), "upload-1", 0)
+	assert.ErrorIs(t, err, serviceerrors.ErrInvalidRequest, "legacy uploads without a recorded count need total_chunks")
+}
+
+func TestGetResumeStatus_RecordedTotal(t *testing.T) {
+	rows := [][]interface{}{{1, int64(6), true}}
+	svc := NewUploadService(resumableDBWithTotal("", "uploading", 3, rows, nil), newMockObjStore(), "mybucket", zap.NewNop())
+
+	st, err := svc.GetResumeStatus(context.Background(), "upload-1", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 3, st.TotalChunks)
+	assert.Equal(t, []int{0, 2}, st.MissingChunks)
+}
+
+func TestUploadChunkStream_EnforcesRecordedChunkSize(t *testing.T) {
+	now := time.Now()
+	db := &mockDB{
+		queryRowFn: func(_ context.Context, query string, _ ...interface{}) *stg.CancelRow {
+			if strings.Contains(query, "SELECT total_chunks, chunk_size") {
+				return stg.NewTestCancelRow(&mockRow{vals: []interface{}{3, 8}})
+			}
+			return stg.NewTestCancelRow(&mockRow{vals: []interface{}{
+				"upload-1", "video.mp4", int64(18),
+				"video/mp4", "", "uploading", "", "owner1",
+				now, now,
+			}})
+		},
+		execFn: func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
+			return &mockResult{rowsAffected: 1}, nil
+		},
+	}
+	store := newMockObjStore()
+	svc := NewUploadService(db, store, "mybucket", zap.NewNop())
+	ctx := context.Background()
+
+	err := svc.UploadChunkStream(ctx, "upload-1", 0, strings.NewReader("short"), 5, "owner1")
+	assert.ErrorIs(t, err, serviceerrors.ErrInvalidRequest, "a middle chunk must be chunk_size bytes")
+	_, stored := store.data["mybucket/chunks/upload-1/0"]
+	assert.False(t, stored)
+
+	require.NoError(t, svc.UploadChunkStream(ctx, "upload-1", 0, strings.NewReader("01234567"), 8, "owner1"))
+	require.NoError(t, svc.UploadChunkStream(ctx, "upload-1", 2, strings.NewReader("ab"), 2, "owner1"), "the last chunk holds the remainder")
+
+	err = svc.UploadChunkStream(ctx, "upload-1", 2, strings.NewReader("abc"), 3, "owner1")
+	assert.ErrorIs(t, err, serviceerrors.ErrInvalidRequest)
+	err = svc.UploadChunkStream(ctx, "upload-1", 3, strings.NewReader("01234567"), 8, "owner1")
+	assert.ErrorIs(t, err, serviceerrors.ErrInvalidRequest, "chunk index past the recorded count")
+}
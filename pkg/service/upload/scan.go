@@ -0,0 +1,280 @@
+package upload
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/event"
+
+	"go.uber.org/zap"
+)
+
+// Scan statuses recorded on an upload.
+const (
+	ScanStatusClean    = "clean"
+	ScanStatusInfected = "infected"
+	ScanStatusError    = "error"
+)
+
+// quarantinePrefix is where flagged objects are moved. Nothing serves or
+// transcodes objects under it.
+const quarantinePrefix = "quarantine/"
+
+// ErrUploadQuarantined is returned by CompleteUploadWithJob when the scanner
+// flags the file. The object has been moved to quarantine and the upload
+// will not be processed.
+var ErrUploadQuarantined = errors.New("upload quarantined by malware scan")
+
+// ScanResult is a scanner's verdict on one file.
+type ScanResult struct {
+	Clean     bool   `json:"clean"`
+	Signature string `json:"signature,omitempty"` // what was found, when not clean
+}
+
+// Scanner checks a file for malware.
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (ScanResult, error)
+}
+
+// SetScanner makes CompleteUploadWithJob scan every upload before creating
+// its content and transcode job.
+func (s *UploadService) SetScanner(sc Scanner) {
+	s.scanner = sc
+}
+
+// GetScanStatus returns the recorded scan status of an upload and, for an
+// infected one, the signature found. Both are empty when scanning is off or
+// the upload has not been scanned yet.
+func (s *UploadService) GetScanStatus(ctx context.Context, uploadID string) (status, signature string, err error) {
+	if s.scanner == nil {
+		return "", "", nil
+	}
+	var st, sig sql.NullString
+	if err := s.db.QueryRow(ctx, "SELECT scan_status, scan_signature FROM uploads WHERE id = $1", uploadID).Scan(&st, &sig); err != nil {
+		return "", "", fmt.Errorf("failed to read scan status: %w", err)
+	}
+	return st.String, sig.String, nil
+}
+
+// scanUpload runs the scanner over a completed upload. A flagged file is
+// quarantined and ErrUploadQuarantined returned; a scanner failure leaves
+// the upload completed so that completion can be retried.
+func (s *UploadService) scanUpload(ctx context.Context, upload *UploadInfo) error {
+	storageKey := s.storageKeyFromURL(upload.URL)
+	rc, err := s.objStore.DownloadStream(ctx, s.bucket, storageKey)
+	if err != nil {
+		return fmt.Errorf("failed to open upload for scanning: %w", err)
+	}
+	result, err := s.scanner.Scan(ctx, rc)
+	_ = rc.Close()
+	if err != nil {
+		s.recordScan(ctx, upload.ID, ScanStatusError, "")
+		s.publishScanFailed(upload, ScanStatusError, "", err.Error())
+		return fmt.Errorf("malware scan failed: %w", err)
+	}
+	if result.Clean {
+		s.recordScan(ctx, upload.ID, ScanStatusClean, "")
+		return nil
+	}
+
+	s.logger.Warn("Upload flagged by malware scan",
+		zap.String("upload_id", upload.ID),
+		zap.String("owner_id", upload.OwnerID),
+		zap.String("signature", result.Signature))
+	if err := s.quarantine(ctx, upload, storageKey, result.Signature); err != nil {
+		return err
+	}
+	s.publishScanFailed(upload, ScanStatusInfected, result.Signature, "")
+	return fmt.Errorf("%w: %s", ErrUploadQuarantined, result.Signature)
+}
+
+// quarantine moves a flagged object under quarantinePrefix and marks the
+// upload quarantined.
+func (s *UploadService) quarantine(ctx context.Context, upload *UploadInfo, storageKey, signature string) error {
+	quarantineKey := quarantinePrefix + storageKey
+	rc, err := s.objStore.DownloadStream(ctx, s.bucket, storageKey)
+	if err != nil {
+		return fmt.Errorf("failed to read flagged upload: %w", err)
+	}
+	err = s.objStore.UploadStream(ctx, s.bucket, quarantineKey, rc, upload.Size)
+	_ = rc.Close()
+	if err != nil {
+		return fmt.Errorf("failed to quarantine upload: %w", err)
+	}
+	if err := s.objStore.Delete(ctx, s.bucket, storageKey); err != nil {
+		return fmt.Errorf("failed to remove flagged upload: %w", err)
+	}
+
+	_, err = s.db.Exec(ctx,
+		"UPDATE uploads SET status = $2, url = $3, scan_status = $4, scan_signature = $5, updated_at = $6 WHERE id = $1",
+		upload.ID, "quarantined", fmt.Sprintf("/%s/%s", s.bucket, quarantineKey), ScanStatusInfected, signature, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to mark upload quarantined: %w", err)
+	}
+	return nil
+}
+
+func (s *UploadService) recordScan(ctx context.Context, uploadID, status, signature string) {
+	_, err := s.db.Exec(ctx,
+		"UPDATE uploads SET scan_status = $2, scan_signature = $3, updated_at = $4 WHERE id = $1",
+		uploadID, status, signature, time.Now())
+	if err != nil {
+		s.logger.Warn("Failed to record scan status", zap.String("upload_id", uploadID), zap.Error(err))
+	}
+}
+
+// publishScanFailed announces an upload that was flagged or could not be
+// scanned. The event data holds upload_id, owner_id, filename, scan_status
+// and either signature or error.
+func (s *UploadService) publishScanFailed(upload *UploadInfo, status, signature, scanErr string) {
+	if s.events == nil {
+		return
+	}
+	data := map[string]interface{}{
+		"upload_id":   upload.ID,
+		"owner_id":    upload.OwnerID,
+		"filename":    upload.Filename,
+		"scan_status": status,
+	}
+	if signature != "" {
+		data["signature"] = signature
+	}
+	if scanErr != "" {
+		data["error"] = scanErr
+	}
+
+	pubCtx, cancel := context.WithTimeout(context.Background(), eventPublishTimeout)
+	defer cancel()
+	err := s.events.Publish(pubCtx, &event.Event{
+		ID:        upload.ID,
+		Type:      event.EventTypeUploadScanFailed,
+		Source:    "upload",
+		Timestamp: time.Now().Unix(),
+		Data:      data,
+	})
+	if err != nil {
+		s.logger.Warn("Failed to publish upload scan failed event",
+			zap.String("upload_id", upload.ID), zap.Error(err))
+	}
+}
+
+// clamdChunkSize is the INSTREAM chunk size sent to clamd.
+const clamdChunkSize = 64 * 1024
+
+// ClamAVScanner scans files with clamd's INSTREAM command over TCP. clamd
+// rejects streams longer than its StreamMaxLength, which must be raised to
+// the largest upload allowed.
+type ClamAVScanner struct {
+	addr    string
+	timeout time.Duration
+}
+
+// NewClamAVScanner returns a scanner for the clamd listening at addr. A
+// zero timeout means the scan is bounded only by its context.
+func NewClamAVScanner(addr string, timeout time.Duration) *ClamAVScanner {
+	return &ClamAVScanner{addr: addr, timeout: timeout}
+}
+
+func (c *ClamAVScanner) Scan(ctx context.Context, r io.Reader) (ScanResult, error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return ScanResult{}, fmt.Errorf("clamd dial: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return ScanResult{}, fmt.Errorf("clamd write: %w", err)
+	}
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		n, readErr := r.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				return ScanResult{}, fmt.Errorf("clamd write: %w", err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return ScanResult{}, fmt.Errorf("read upload: %w", readErr)
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return ScanResult{}, fmt.Errorf("clamd write: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return ScanResult{}, fmt.Errorf("clamd read: %w", err)
+	}
+	return parseClamdReply(reply)
+}
+
+// parseClamdReply interprets "stream: OK", "stream: <sig> FOUND" and
+// "<message> ERROR" replies.
+func parseClamdReply(reply string) (ScanResult, error) {
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	body := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case body == "OK":
+		return ScanResult{Clean: true}, nil
+	case strings.HasSuffix(body, " FOUND"):
+		return ScanResult{Signature: strings.TrimSuffix(body, " FOUND")}, nil
+	default:
+		return ScanResult{}, fmt.Errorf("clamd: %s", reply)
+	}
+}
+
+// HTTPScanner POSTs files to an external scanning service, which answers
+// 200 with a JSON ScanResult.
+type HTTPScanner struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPScanner returns a scanner for the service at url.
+func NewHTTPScanner(url string, timeout time.Duration) *HTTPScanner {
+	return &HTTPScanner{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+func (h *HTTPScanner) Scan(ctx context.Context, r io.Reader) (ScanResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, r)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return ScanResult{}, fmt.Errorf("scanner request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return ScanResult{}, fmt.Errorf("scanner returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	var result ScanResult
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&result); err != nil {
+		return ScanResult{}, fmt.Errorf("decode scanner response: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,219 @@
+package upload
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/event"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+type stubScanner struct {
+	result  ScanResult
+	err     error
+	scanned string
+}
+
+func (s *stubScanner) Scan(_ context.Context, r io.Reader) (ScanResult, error) {
+	data, _ := io.ReadAll(r)
+	s.scanned = string(data)
+	return s.result, s.err
+}
+
+// fakeClamd accepts one INSTREAM session and flags streams containing the
+// EICAR test string.
+func fakeClamd(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		br := bufio.NewReader(conn)
+		if cmd, err := br.ReadString(0); err != nil || cmd != "zINSTREAM\x00" {
+			return
+		}
+		var data []byte
+		for {
+			var n uint32
+			if err := binary.Read(br, binary.BigEndian, &n); err != nil {
+				return
+			}
+			if n == 0 {
+				break
+			}
+			chunk := make([]byte, n)
+			if _, err := io.ReadFull(br, chunk); err != nil {
+				return
+			}
+			data = append(data, chunk...)
+		}
+		reply := "stream: OK\x00"
+		if strings.Contains(string(data), "EICAR-STANDARD-ANTIVIRUS-TEST-FILE") {
+			reply = "stream: Eicar-Test-Signature FOUND\x00"
+		}
+		_, _ = conn.Write([]byte(reply))
+	}()
+	return ln.Addr().String()
+}
+
+func TestClamAVScanner(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantClean bool
+		wantSig   string
+	}{
+		{name: "clean", body: strings.Repeat("video", 30000), wantClean: true},
+		{name: "infected", body: eicar, wantSig: "Eicar-Test-Signature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := NewClamAVScanner(fakeClamd(t), 5*time.Second)
+			result, err := sc.Scan(context.Background(), strings.NewReader(tt.body))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantClean, result.Clean)
+			assert.Equal(t, tt.wantSig, result.Signature)
+		})
+	}
+}
+
+func TestParseClamdReply(t *testing.T) {
+	tests := []struct {
+		reply   string
+		want    ScanResult
+		wantErr bool
+	}{
+		{reply: "stream: OK\x00", want: ScanResult{Clean: true}},
+		{reply: "stream: Win.Test.EICAR_HDB-1 FOUND\x00", want: ScanResult{Signature: "Win.Test.EICAR_HDB-1"}},
+		{reply: "INSTREAM size limit exceeded. ERROR\x00", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseClamdReply(tt.reply)
+		if tt.wantErr {
+			assert.Error(t, err, tt.reply)
+			continue
+		}
+		require.NoError(t, err, tt.reply)
+		assert.Equal(t, tt.want, got)
+	}
+}
+
+func TestHTTPScanner(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path == "/broken" {
+			http.Error(w, "scanner overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		result := ScanResult{Clean: !strings.Contains(string(body), "EICAR")}
+		if !result.Clean {
+			result.Signature = "EICAR"
+		}
+		_ = json.NewEncoder(w).Encode(result)
+	}))
+	defer srv.Close()
+
+	result, err := NewHTTPScanner(srv.URL, time.Second).Scan(context.Background(), strings.NewReader(eicar))
+	require.NoError(t, err)
+	assert.Equal(t, ScanResult{Signature: "EICAR"}, result)
+
+	result, err = NewHTTPScanner(srv.URL, time.Second).Scan(context.Background(), strings.NewReader("video"))
+	require.NoError(t, err)
+	assert.True(t, result.Clean)
+
+	_, err = NewHTTPScanner(srv.URL+"/broken", time.Second).Scan(context.Background(), strings.NewReader("video"))
+	assert.ErrorContains(t, err, "503")
+}
+
+func TestCompleteUploadWithJob_Scan(t *testing.T) {
+	tests := []struct {
+		name           string
+		scanner        *stubScanner
+		wantErr        error
+		wantScanStatus string
+		wantEvent      bool
+	}{
+		{name: "clean", scanner: &stubScanner{result: ScanResult{Clean: true}}, wantScanStatus: ScanStatusClean},
+		{name: "infected", scanner: &stubScanner{result: ScanResult{Signature: "Eicar-Test-Signature"}},
+			wantErr: ErrUploadQuarantined, wantScanStatus: ScanStatusInfected, wantEvent: true},
+		{name: "scanner down", scanner: &stubScanner{err: errors.New("connection refused")},
+			wantScanStatus: ScanStatusError, wantEvent: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus, err := event.NewMemoryEventBus()
+			require.NoError(t, err)
+			events := make(chan *event.Event, 1)
+			_, err = bus.Subscribe(context.Background(), event.EventTypeUploadScanFailed, func(_ context.Context, e *event.Event) error {
+				events <- e
+				return nil
+			})
+			require.NoError(t, err)
+
+			var execArgs [][]interface{}
+			db := newCompletedUploadDB("video/mp4")
+			db.execFn = func(_ context.Context, _ string, args ...interface{}) (sql.Result, error) {
+				execArgs = append(execArgs, args)
+				return &mockResult{rowsAffected: 1}, nil
+			}
+			store := newMockObjStore()
+			store.data["bucket/key"] = []byte("video bytes")
+
+			svc := NewUploadService(db, store, "bucket", zap.NewNop())
+			svc.SetScanner(tt.scanner)
+			svc.SetEventBus(bus)
+
+			contentID, _, err := svc.CompleteUploadWithJob(context.Background(), "upload-1")
+			assert.Equal(t, "video bytes", tt.scanner.scanned)
+			require.NotEmpty(t, execArgs)
+			assert.Contains(t, execArgs[0], tt.wantScanStatus)
+
+			switch {
+			case tt.wantErr != nil:
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, contentID)
+				assert.NotContains(t, store.data, "bucket/key", "flagged object is moved")
+				assert.Equal(t, []byte("video bytes"), store.data["bucket/quarantine/key"])
+				assert.Contains(t, execArgs[0], "quarantined")
+			case tt.wantScanStatus == ScanStatusError:
+				assert.Error(t, err)
+				assert.Empty(t, contentID)
+				assert.Contains(t, store.data, "bucket/key", "object stays put so completion can be retried")
+			default:
+				require.NoError(t, err)
+				assert.NotEmpty(t, contentID)
+			}
+
+			select {
+			case e := <-events:
+				require.True(t, tt.wantEvent, "unexpected upload.scan_failed event")
+				assert.Equal(t, "upload-1", e.ID)
+				assert.Equal(t, tt.wantScanStatus, e.Data["scan_status"])
+			case <-time.After(100 * time.Millisecond):
+				assert.False(t, tt.wantEvent, "no upload.scan_failed event")
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rtcdance/streamgate/pkg/service/serviceerrors"
+
+	"go.uber.org/zap"
+)
+
+// MaxSubtitleSize caps subtitle uploads; hours of dialogue fit in far less.
+const MaxSubtitleSize int64 = 5 << 20
+
+var (
+	// ErrInvalidSubtitle is returned for files that are neither SubRip nor
+	// WebVTT, or that hold no cues.
+	ErrInvalidSubtitle = errors.New("invalid subtitle file")
+	// ErrNotContentOwner is returned when a wallet attaches subtitles to
+	// content it does not own.
+	ErrNotContentOwner = errors.New("not the content owner")
+)
+
+var (
+	subtitleLanguageRegex = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+	cueTimingRegex        = regexp.MustCompile(`^\s*((?:\d+:)?\d{1,2}:\d{2}[,.]\d{1,3})\s*-->\s*((?:\d+:)?\d{1,2}:\d{2}[,.]\d{1,3})(.*)$`)
+	// srtMarkupRegex matches SubRip markup WebVTT has no equivalent for:
+	// font tags and ASS override blocks such as {\an8}.
+	srtMarkupRegex = regexp.MustCompile(`</?font[^>]*>|\{\\[^}]*\}`)
+)
+
+// SubtitleTrack is a subtitle file attached to a content, stored as WebVTT
+// next to its renditions.
+type SubtitleTrack struct {
+	ContentID string `json:"content_id"`
+	Language  string `json:"language"`
+	Key       string `json:"key"`
+}
+
+// SubtitleKey returns where the WebVTT subtitles of a content's language
+// are stored: a rendition directory of their own, which the streaming
+// plugin lists as an HLS subtitle rendition.
+func SubtitleKey(contentID, language string) string {
+	return fmt.Sprintf("streams/%s/subtitles-%s/subtitles.vtt", contentID, language)
+}
+
+// UploadSubtitle converts a SubRip or WebVTT file to WebVTT and attaches
+// it to the content as its subtitles in language, replacing any uploaded
+// before. Only the content's owner may attach subtitles.
+func (s *UploadService) UploadSubtitle(ctx context.Context, contentID, language string, data []byte, ownerID string) (*SubtitleTrack, error) {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if !subtitleLanguageRegex.MatchString(language) {
+		return nil, fmt.Errorf("invalid subtitle language %q: %w", language, serviceerrors.ErrInvalidRequest)
+	}
+	if int64(len(data)) > MaxSubtitleSize {
+		return nil, fmt.Errorf("subtitle file exceeds %d bytes: %w", MaxSubtitleSize, ErrInvalidSubtitle)
+	}
+	if s.db == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+
+	var owner sql.NullString
+	err := s.db.QueryRow(ctx, `SELECT owner_id FROM contents WHERE id = $1`, contentID).Scan(&owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("content not found %s: %w", contentID, serviceerrors.ErrNotFound)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to query content: %w", err)
+	}
+	if !strings.EqualFold(owner.String, ownerID) {
+		return nil, ErrNotContentOwner
+	}
+
+	vtt, err := ToWebVTT(data)
+	if err != nil {
+		return nil, err
+	}
+	key := SubtitleKey(contentID, language)
+	if err := s.objStore.Upload(ctx, s.bucket, key, vtt); err != nil {
+		return nil, fmt.Errorf("failed to store subtitles: %w", err)
+	}
+
+	s.logger.Info("Subtitles attached",
+		zap.String("content_id", contentID),
+		zap.String("language", language))
+	return &SubtitleTrack{ContentID: contentID, Language: language, Key: key}, nil
+}
+
+// ToWebVTT converts SubRip subtitles to WebVTT. WebVTT input is returned
+// with its line endings normalised. Both are read as UTF-8, with or
+// without a byte order mark.
+func ToWebVTT(data []byte) ([]byte, error) {
+	text := string(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+
+	if header, _, _ := strings.Cut(text, "\n"); strings.HasPrefix(header, "WEBVTT") {
+		if !strings.Contains(text, "-->") {
+			return nil, fmt.Errorf("WebVTT file has no cues: %w", ErrInvalidSubtitle)
+		}
+		return []byte(text), nil
+	}
+
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+	cues := 0
+	for _, block := range strings.Split(text, "\n\n") {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+		// The cue number is optional in practice.
+		if len(lines) > 1 && !strings.Contains(lines[0], "-->") {
+			lines = lines[1:]
+		}
+		match := cueTimingRegex.FindStringSubmatch(lines[0])
+		if match == nil {
+			if strings.TrimSpace(block) == "" {
+				continue
+			}
+			return nil, fmt.Errorf("malformed cue %q: %w", lines[0], ErrInvalidSubtitle)
+		}
+		fmt.Fprintf(&b, "\n%s --> %s\n", vttTimestamp(match[1]), vttTimestamp(match[2]))
+		for _, line := range lines[1:] {
+			b.WriteString(srtMarkupRegex.ReplaceAllString(line, ""))
+			b.WriteString("\n")
+		}
+		cues++
+	}
+	if cues == 0 {
+		return nil, fmt.Errorf("no cues found: %w", ErrInvalidSubtitle)
+	}
+	return []byte(b.String()), nil
+}
+
+// vttTimestamp rewrites a SubRip timestamp as WebVTT's hh:mm:ss.ttt.
+func vttTimestamp(ts string) string {
+	clock, frac, _ := strings.Cut(strings.ReplaceAll(ts, ",", "."), ".")
+	parts := strings.Split(clock, ":")
+	if len(parts) == 2 {
+		parts = append([]string{"0"}, parts...)
+	}
+	for i, p := range parts {
+		if len(p) < 2 {
+			parts[i] = strings.Repeat("0", 2-len(p)) + p
+		}
+	}
+	return strings.Join(parts, ":") + "." + (frac + "00")[:3]
+}
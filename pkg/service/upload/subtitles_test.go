@@ -0,0 +1,80 @@
+package upload
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/rtcdance/streamgate/pkg/service/serviceerrors"
+	stg "github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const testSRT = "\xef\xbb\xbf1\r\n00:00:01,000 --> 00:00:03,500\r\n<font color=\"#ffff00\">Hello</font> <i>there</i>\r\n\r\n" +
+	"2\r\n00:01:02,25 --> 01:00:04,000 X1:40 X2:600\r\n{\\an8}Second\r\nline\r\n\r\n"
+
+func TestToWebVTT_SubRip(t *testing.T) {
+	vtt, err := ToWebVTT([]byte(testSRT))
+	require.NoError(t, err)
+	assert.Equal(t, "WEBVTT\n"+
+		"\n00:00:01.000 --> 00:00:03.500\nHello <i>there</i>\n"+
+		"\n00:01:02.250 --> 01:00:04.000\nSecond\nline\n", string(vtt))
+}
+
+func TestToWebVTT_WebVTT(t *testing.T) {
+	vtt, err := ToWebVTT([]byte("WEBVTT - dialogue\r\n\r\n00:01.000 --> 00:02.000\r\nHi\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "WEBVTT - dialogue\n\n00:01.000 --> 00:02.000\nHi\n", string(vtt))
+}
+
+func TestToWebVTT_Invalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"empty":         "",
+		"no cues":       "WEBVTT\n\nNOTE nothing here\n",
+		"not subtitles": "\x00\x00\x00 ftypisom",
+		"bad timing":    "1\n00:00:01 --> soon\nHello\n",
+	} {
+		_, err := ToWebVTT([]byte(data))
+		assert.ErrorIs(t, err, ErrInvalidSubtitle, name)
+	}
+}
+
+func ownerDB(owner string, err error) *mockDB {
+	return &mockDB{
+		queryRowFn: func(_ context.Context, _ string, _ ...interface{}) *stg.CancelRow {
+			if err != nil {
+				return stg.NewErrorCancelRow(err)
+			}
+			return stg.NewTestCancelRow(&mockRow{vals: []interface{}{owner}})
+		},
+	}
+}
+
+func TestUploadService_UploadSubtitle(t *testing.T) {
+	store := newMockObjStore()
+	svc := NewUploadService(ownerDB("0xABC", nil), store, "bucket", zap.NewNop())
+
+	track, err := svc.UploadSubtitle(context.Background(), "content-1", "ENG", []byte(testSRT), "0xabc")
+	require.NoError(t, err)
+	assert.Equal(t, &SubtitleTrack{ContentID: "content-1", Language: "eng", Key: "streams/content-1/subtitles-eng/subtitles.vtt"}, track)
+	assert.Contains(t, string(store.data["bucket/"+track.Key]), "00:00:01.000 --> 00:00:03.500\n")
+}
+
+func TestUploadService_UploadSubtitle_Rejected(t *testing.T) {
+	ctx := context.Background()
+
+	svc := NewUploadService(ownerDB("0xabc", nil), newMockObjStore(), "bucket", zap.NewNop())
+	_, err := svc.UploadSubtitle(ctx, "content-1", "eng", []byte(testSRT), "0xdef")
+	assert.ErrorIs(t, err, ErrNotContentOwner)
+	_, err = svc.UploadSubtitle(ctx, "content-1", "../eng", []byte(testSRT), "0xabc")
+	assert.ErrorIs(t, err, serviceerrors.ErrInvalidRequest)
+	_, err = svc.UploadSubtitle(ctx, "content-1", "eng", []byte("garbage"), "0xabc")
+	assert.ErrorIs(t, err, ErrInvalidSubtitle)
+
+	svc = NewUploadService(ownerDB("", sql.ErrNoRows), newMockObjStore(), "bucket", zap.NewNop())
+	_, err = svc.UploadSubtitle(ctx, "missing", "eng", []byte(testSRT), "0xabc")
+	assert.ErrorIs(t, err, serviceerrors.ErrNotFound)
+}
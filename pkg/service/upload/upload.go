@@ -13,6 +13,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/rtcdance/streamgate/pkg/core/event"
 	"github.com/rtcdance/streamgate/pkg/monitoring"
 	"github.com/rtcdance/streamgate/pkg/service/serviceerrors"
 	"github.com/rtcdance/streamgate/pkg/service/transcoding"
@@ -68,9 +69,14 @@ type UploadService struct {
 	logger        *zap.Logger
 	onProcessed   []PostUploadHook
 	hookMu        sync.Mutex
+	events        event.EventBus
+	outbox        EventOutbox
 	hookWg        sync.WaitGroup
 
 	chunkMergeConcurrency int // parallel chunk downloads during merge
+	chunkPolicy           ChunkSizePolicy
+	multipart             storage.MultipartUploader
+	scanner               Scanner
 }
 
 const defaultChunkMergeConcurrency = 5
@@ -172,16 +178,21 @@ type UploadPresignedURLer interface {
 
 // UploadInfo represents upload information
 type UploadInfo struct {
-	ID          string    `json:"id"`
-	Filename    string    `json:"filename"`
-	Size        int64     `json:"size"`
-	ContentType string    `json:"content_type"`
-	Hash        string    `json:"hash"`
-	Status      string    `json:"status"` // pending, uploading, completed, failed
-	URL         string    `json:"url"`
-	OwnerID     string    `json:"owner_id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID            string    `json:"id"`
+	Filename      string    `json:"filename"`
+	Size          int64     `json:"size"`
+	ContentType   string    `json:"content_type"`
+	Hash          string    `json:"hash"`
+	Status        string    `json:"status"` // pending, uploading, completed, failed, quarantined
+	URL           string    `json:"url"`
+	OwnerID       string    `json:"owner_id"`
+	TotalChunks   int       `json:"total_chunks,omitempty"`   // chunked uploads only; not loaded by GetUploadStatus
+	ChunkSize     int64     `json:"chunk_size,omitempty"`     // chunked uploads only; not loaded by GetUploadStatus
+	MultipartID   string    `json:"-"`                        // store-side ID of a direct multipart upload
+	ScanStatus    string    `json:"scan_status,omitempty"`    // not loaded by GetUploadStatus; see GetScanStatus
+	ScanSignature string    `json:"scan_signature,omitempty"` // what an infected scan found
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
 // ChunkInfo represents chunk upload information
@@ -350,6 +361,20 @@ func (r *BytesSliceReader) Read(p []byte) (n int, err error) {
 	return n, nil
 }
 
+// contextReader fails reads once ctx is done, so a copy stops at the next
+// buffer boundary after the client disconnects.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
 // GetUploadStatus gets upload status
 func (s *UploadService) GetUploadStatus(ctx context.Context, uploadID string) (*UploadInfo, error) {
 	if s.db == nil {
@@ -450,7 +475,11 @@ func (s *UploadService) GetChunkStatuses(ctx context.Context, uploadID string) (
 }
 
 // InitiateChunkedUpload initiates a chunked upload
-func (s *UploadService) InitiateChunkedUpload(ctx context.Context, filename string, totalSize int64, totalChunks int, ownerID string) (result string, err error) {
+func (s *UploadService) InitiateChunkedUpload(ctx context.Context, filename string, totalSize int64, totalChunks int, ownerID string) (string, error) {
+	return s.initiateChunkedUpload(ctx, filename, totalSize, totalChunks, 0, "", ownerID)
+}
+
+func (s *UploadService) initiateChunkedUpload(ctx context.Context, filename string, totalSize int64, totalChunks int, chunkSize int64, checksum, ownerID string) (result string, err error) {
 	start := time.Now()
 	_, span := monitoring.StartOTelSpan(ctx, "upload.initiate_chunked",
 		attribute.Int64("total_size", totalSize),
@@ -469,9 +498,16 @@ func (s *UploadService) InitiateChunkedUpload(ctx context.Context, filename stri
 	if s.db == nil {
 		return "", fmt.Errorf("database not available")
 	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
 	if s.maxUploadSize > 0 && totalSize > s.maxUploadSize {
 		return "", fmt.Errorf("upload size %d exceeds maximum allowed size %d", totalSize, s.maxUploadSize)
 	}
+	plan, err := s.chunkPolicy.Check(totalSize, totalChunks, chunkSize)
+	if err != nil {
+		return "", err
+	}
 	if err := s.CheckStorageQuota(ctx, ownerID, totalSize); err != nil {
 		return "", err
 	}
@@ -483,8 +519,11 @@ func (s *UploadService) InitiateChunkedUpload(ctx context.Context, filename stri
 		Filename:    filename,
 		Size:        totalSize,
 		ContentType: DetectContentType(filename),
+		Hash:        checksum,
 		Status:      "uploading",
 		OwnerID:     ownerID,
+		TotalChunks: plan.TotalChunks,
+		ChunkSize:   plan.ChunkSize,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
@@ -575,9 +614,15 @@ func (s *UploadService) UploadChunkStream(ctx context.Context, uploadID string,
 	if s.db == nil {
 		return fmt.Errorf("database not available")
 	}
+	// Chunk writes follow InitiateChunkedUpload closely; read the upload
+	// back from the primary so replica lag cannot hide it.
+	ctx = storage.WithPrimary(ctx)
 	if chunkIndex < 0 || chunkIndex > 100000 {
 		return fmt.Errorf("chunk_index out of range: %d", chunkIndex)
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	info, err := s.GetUploadStatus(ctx, uploadID)
 	if err != nil {
@@ -589,6 +634,9 @@ func (s *UploadService) UploadChunkStream(ctx context.Context, uploadID string,
 	if info.Status != "uploading" {
 		return fmt.Errorf("upload not in uploading state: %s", info.Status)
 	}
+	if err := s.checkChunkSize(ctx, uploadID, info.Size, chunkIndex, size); err != nil {
+		return err
+	}
 
 	storageKey := fmt.Sprintf("chunks/%s/%d", uploadID, chunkIndex)
 
@@ -600,10 +648,18 @@ func (s *UploadService) UploadChunkStream(ctx context.Context, uploadID string,
 		return fmt.Errorf("chunk %d already uploaded for upload %s", chunkIndex, uploadID)
 	}
 
-	if err := s.objStore.UploadStream(ctx, s.bucket, storageKey, reader, size); err != nil {
+	if err := s.objStore.UploadStream(ctx, s.bucket, storageKey, &contextReader{ctx: ctx, r: reader}, size); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			// Drop any partial object so the client can resend this chunk.
+			s.discardObject(ctx, storageKey)
+			return fmt.Errorf("chunk %d upload cancelled: %w", chunkIndex, ctxErr)
+		}
 		return fmt.Errorf("failed to upload chunk stream: %w", err)
 	}
 
+	// The chunk is stored; record it even if the client has gone, otherwise
+	// a resend is rejected as a duplicate but the chunk is never counted.
+	ctx = context.WithoutCancel(ctx)
 	_, err = s.db.Exec(ctx,
 		`INSERT INTO upload_chunks (upload_id, chunk_index, chunk_size, uploaded, uploaded_at)
 		 VALUES ($1, $2, $3, true, CURRENT_TIMESTAMP)
@@ -648,6 +704,11 @@ func (s *UploadService) CompleteChunkedUpload(ctx context.Context, uploadID stri
 	if s.db == nil {
 		return fmt.Errorf("database not available")
 	}
+	// The chunk count must include writes made moments ago.
+	ctx = storage.WithPrimary(ctx)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	uploadInfo, err := s.GetUploadStatus(ctx, uploadID)
 	if err != nil {
 		return err
@@ -732,7 +793,7 @@ func (s *UploadService) CompleteChunkedUpload(ctx context.Context, uploadID stri
 				if !ok {
 					break
 				}
-				if _, cErr := io.Copy(hashWriter, reader); cErr != nil {
+				if _, cErr := io.Copy(hashWriter, &contextReader{ctx: dlCtx, r: reader}); cErr != nil {
 					reader.Close()
 					cancel()
 					errCh <- fmt.Errorf("failed to stream chunk %d: %w", nextIdx, cErr)
@@ -752,18 +813,36 @@ func (s *UploadService) CompleteChunkedUpload(ctx context.Context, uploadID stri
 		errCh <- nil
 	}()
 
-	if err := s.objStore.UploadStream(ctx, s.bucket, storageKey, pr, uploadInfo.Size); err != nil {
-		pw.CloseWithError(err)
-		if writeErr := <-errCh; writeErr != nil {
+	uploadErr := s.objStore.UploadStream(ctx, s.bucket, storageKey, pr, uploadInfo.Size)
+	if uploadErr != nil {
+		pw.CloseWithError(uploadErr)
+	}
+	if writeErr := <-errCh; writeErr != nil || uploadErr != nil {
+		// Chunks and status are untouched until the merge succeeds, so the
+		// client can retry completion once the partial object is gone.
+		s.discardObject(ctx, storageKey)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("upload %s assembly cancelled: %w", uploadID, ctxErr)
+		}
+		if writeErr != nil {
 			return writeErr
 		}
-		return fmt.Errorf("failed to upload merged file: %w", err)
-	}
-	if writeErr := <-errCh; writeErr != nil {
-		return writeErr
+		return fmt.Errorf("failed to upload merged file: %w", uploadErr)
 	}
 
 	hash := hex.EncodeToString(h.Sum(nil))
+	if uploadInfo.Hash != "" && !strings.EqualFold(uploadInfo.Hash, hash) {
+		// There is no telling which chunk was corrupted, so the client has
+		// to send them all again.
+		ctx = context.WithoutCancel(ctx)
+		s.discardObject(ctx, storageKey)
+		s.resetChunks(ctx, uploadID, totalChunks)
+		return fmt.Errorf("upload %s: expected sha256 %s, assembled %s: %w", uploadID, uploadInfo.Hash, hash, ErrChecksumMismatch)
+	}
+
+	// The merged object is complete. Finish even if the client has gone,
+	// or the upload is left marked uploading with its chunks deleted.
+	ctx = context.WithoutCancel(ctx)
 
 	// Clean up chunks in parallel so large (>1000 chunk) uploads finish quickly.
 	var cleanWg sync.WaitGroup
@@ -799,6 +878,13 @@ func (s *UploadService) CompleteChunkedUpload(ctx context.Context, uploadID stri
 	return nil
 }
 
+// discardObject best-effort deletes a partially written object.
+func (s *UploadService) discardObject(ctx context.Context, key string) {
+	if err := s.objStore.Delete(context.WithoutCancel(ctx), s.bucket, key); err != nil {
+		s.logger.Debug("Failed to delete partial object", zap.String("key", key), zap.Error(err))
+	}
+}
+
 // DeleteUpload deletes an upload
 func (s *UploadService) DeleteUpload(ctx context.Context, uploadID string) error {
 	if s.db == nil {
@@ -893,10 +979,19 @@ func (s *UploadService) saveUploadInfo(ctx context.Context, info *UploadInfo) er
 		return fmt.Errorf("database not available")
 	}
 	query := `
-		INSERT INTO uploads (id, filename, size, content_type, hash, status, url, owner_id, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO uploads (id, filename, size, content_type, hash, status, url, owner_id, created_at, updated_at, total_chunks, multipart_id, chunk_size)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	`
 
+	var totalChunks, chunkSize sql.NullInt64
+	if info.TotalChunks > 0 {
+		totalChunks = sql.NullInt64{Int64: int64(info.TotalChunks), Valid: true}
+	}
+	if info.ChunkSize > 0 {
+		chunkSize = sql.NullInt64{Int64: info.ChunkSize, Valid: true}
+	}
+	multipartID := sql.NullString{String: info.MultipartID, Valid: info.MultipartID != ""}
+
 	_, err := s.db.Exec(ctx, query,
 		info.ID,
 		info.Filename,
@@ -908,6 +1003,9 @@ func (s *UploadService) saveUploadInfo(ctx context.Context, info *UploadInfo) er
 		info.OwnerID,
 		info.CreatedAt,
 		info.UpdatedAt,
+		totalChunks,
+		multipartID,
+		chunkSize,
 	)
 
 	return err
@@ -951,21 +1049,40 @@ func DetectContentType(filename string) string {
 // defer-tx.Rollback() pattern for safe cleanup.
 // Returns the content ID of the newly created content record.
 func (s *UploadService) CompleteUploadWithTx(ctx context.Context, uploadID string) (string, error) {
+	contentID, _, err := s.CompleteUploadWithJob(ctx, uploadID)
+	return contentID, err
+}
+
+// CompleteUploadWithJob is CompleteUploadWithTx that also returns the ID
+// of the transcode job requested for the content, or "" if none was; see
+// SetEventBus. With a scanner set, the file is scanned first; see
+// SetScanner.
+func (s *UploadService) CompleteUploadWithJob(ctx context.Context, uploadID string) (string, string, error) {
 	if s.db == nil {
-		return "", fmt.Errorf("database not available")
+		return "", "", fmt.Errorf("database not available")
 	}
 
 	// Get current upload info
 	upload, err := s.GetUploadStatus(ctx, uploadID)
 	if err != nil {
-		return "", fmt.Errorf("get upload status: %w", err)
+		return "", "", fmt.Errorf("get upload status: %w", err)
 	}
 
 	if upload.Status != "completed" {
-		return "", fmt.Errorf("upload not completed: %s", upload.Status)
+		return "", "", fmt.Errorf("upload not completed: %s", upload.Status)
+	}
+	if s.scanner != nil {
+		if err := s.scanUpload(ctx, upload); err != nil {
+			return "", "", err
+		}
 	}
 
-	var contentID string
+	contentID := uuid.New().String()
+	var jobID string
+	var completed *event.Event
+	if s.outbox != nil {
+		completed, jobID = s.completedEvent(ctx, upload, contentID)
+	}
 	err = s.db.InTransaction(ctx, func(tx *sql.Tx) error {
 		// Update upload status to "processed"
 		res, err := tx.ExecContext(ctx, `
@@ -980,7 +1097,6 @@ func (s *UploadService) CompleteUploadWithTx(ctx context.Context, uploadID strin
 		}
 
 		// Create content record from upload
-		contentID = uuid.New().String()
 		thumbnailURL := fmt.Sprintf("https://via.placeholder.com/320x180?text=%s", upload.Filename)
 		if _, err := tx.ExecContext(ctx, `
 			INSERT INTO contents (id, title, type, size, status, owner_id, url, thumbnail_url, created_at, updated_at)
@@ -990,15 +1106,26 @@ func (s *UploadService) CompleteUploadWithTx(ctx context.Context, uploadID strin
 			return fmt.Errorf("insert content: %w", err)
 		}
 
+		if completed != nil {
+			if err := s.outbox.Enqueue(ctx, tx, completed); err != nil {
+				return fmt.Errorf("enqueue upload completed event: %w", err)
+			}
+		}
 		return nil
 	})
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	s.logger.Info("Upload completed with content record",
 		zap.String("upload_id", uploadID),
 		zap.String("content_id", contentID))
+	s.recordContentCID(ctx, upload, contentID)
+	if completed != nil {
+		s.outbox.Notify()
+	} else {
+		jobID = s.publishCompleted(ctx, upload, contentID)
+	}
 
 	s.hookMu.Lock()
 	hooks := make([]PostUploadHook, len(s.onProcessed))
@@ -1025,7 +1152,34 @@ func (s *UploadService) CompleteUploadWithTx(ctx context.Context, uploadID strin
 		}(hook)
 	}
 
-	return contentID, nil
+	return contentID, jobID, nil
+}
+
+// recordContentCID stores the CID of the uploaded file in the content's
+// metadata as ipfs_cid when the object store is content-addressed, so the
+// file can be referenced as ipfs://<cid>, e.g. from NFT metadata. Failing
+// to record it does not fail the upload.
+func (s *UploadService) recordContentCID(ctx context.Context, upload *UploadInfo, contentID string) {
+	ca, ok := s.objStore.(storage.ContentAddresser)
+	if !ok {
+		return
+	}
+	cid, err := ca.CID(ctx, s.bucket, s.storageKeyFromURL(upload.URL))
+	if errors.Is(err, storage.ErrNotContentAddressed) {
+		return
+	}
+	if err != nil {
+		s.logger.Warn("Failed to resolve upload CID",
+			zap.String("content_id", contentID), zap.Error(err))
+		return
+	}
+	if _, err := s.db.Exec(ctx, `
+		UPDATE contents SET metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object('ipfs_cid', $2::text), updated_at = $3
+		WHERE id = $1
+	`, contentID, cid, time.Now()); err != nil {
+		s.logger.Warn("Failed to record content CID",
+			zap.String("content_id", contentID), zap.String("cid", cid), zap.Error(err))
+	}
 }
 
 // contentTypeToType maps a MIME content type to a content type string.
@@ -484,7 +484,7 @@ func TestUploadService_CompleteChunkedUpload_Success(t *testing.T) {
 			if strings.Contains(query, "FROM uploads") {
 				return stg.NewTestCancelRow(&mockRow{vals: []interface{}{
 					"upload-1", "video.mp4", int64(1024),
-					"video/mp4", "abc123", "uploading", "/mybucket/owner1/upload-1.mp4", "owner1",
+					"video/mp4", "", "uploading", "/mybucket/owner1/upload-1.mp4", "owner1",
 					now, now,
 				}})
 			}
@@ -509,6 +509,129 @@ func TestUploadService_CompleteChunkedUpload_Success(t *testing.T) {
 	assert.True(t, ok, "merged file should exist in object store")
 }
 
+// cancelOnReadStore cancels the request context the first time the chunk
+// at cancelKey is read, simulating a client disconnect mid-assembly.
+type cancelOnReadStore struct {
+	*mockObjStore
+	cancelKey string
+	cancel    context.CancelFunc
+}
+
+func (m *cancelOnReadStore) DownloadStream(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	rc, err := m.mockObjStore.DownloadStream(ctx, bucket, key)
+	if err != nil || key != m.cancelKey {
+		return rc, err
+	}
+	return io.NopCloser(&cancelReader{r: rc, cancel: m.cancel}), nil
+}
+
+type cancelReader struct {
+	r      io.Reader
+	cancel context.CancelFunc
+}
+
+func (r *cancelReader) Read(p []byte) (int, error) {
+	r.cancel()
+	return r.r.Read(p)
+}
+
+func TestUploadService_CompleteChunkedUpload_CancelledMidAssembly(t *testing.T) {
+	now := time.Now()
+	store := newMockObjStore()
+	for i := 0; i < 3; i++ {
+		store.data[fmt.Sprintf("mybucket/chunks/upload-1/%d", i)] = []byte(strings.Repeat(fmt.Sprint(i), 64*1024))
+	}
+
+	var statusUpdates int32
+	db := &mockDB{
+		queryRowFn: func(_ context.Context, query string, _ ...interface{}) *stg.CancelRow {
+			if strings.Contains(query, "FROM uploads") {
+				return stg.NewTestCancelRow(&mockRow{vals: []interface{}{
+					"upload-1", "video.mp4", int64(3 * 64 * 1024),
+					"video/mp4", "", "uploading", "", "owner1",
+					now, now,
+				}})
+			}
+			if strings.Contains(query, "COUNT(*)") {
+				return stg.NewTestCancelRow(&mockRow{vals: []interface{}{3}})
+			}
+			return stg.NewErrorCancelRow(errors.New("unexpected query"))
+		},
+		execFn: func(_ context.Context, query string, _ ...interface{}) (sql.Result, error) {
+			if strings.Contains(query, "UPDATE uploads") {
+				atomic.AddInt32(&statusUpdates, 1)
+			}
+			return &mockResult{rowsAffected: 1}, nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	svc := NewUploadService(db, &cancelOnReadStore{mockObjStore: store, cancelKey: "chunks/upload-1/1", cancel: cancel}, "mybucket", zap.NewNop())
+	svc.SetChunkMergeConcurrency(1)
+
+	err := svc.CompleteChunkedUpload(ctx, "upload-1", 3)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	store.mu.RLock()
+	_, merged := store.data["mybucket/owner1/upload-1.mp4"]
+	chunks := 0
+	for i := 0; i < 3; i++ {
+		if _, ok := store.data[fmt.Sprintf("mybucket/chunks/upload-1/%d", i)]; ok {
+			chunks++
+		}
+	}
+	store.mu.RUnlock()
+	assert.False(t, merged, "partial merged object is discarded")
+	assert.Equal(t, 3, chunks, "chunks are kept for the retry")
+	assert.Zero(t, atomic.LoadInt32(&statusUpdates), "upload stays in uploading state")
+
+	// Retrying with a live context completes the upload.
+	svc = NewUploadService(db, store, "mybucket", zap.NewNop())
+	require.NoError(t, svc.CompleteChunkedUpload(context.Background(), "upload-1", 3))
+	assert.Len(t, store.data["mybucket/owner1/upload-1.mp4"], 3*64*1024)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&statusUpdates))
+}
+
+func TestUploadService_UploadChunkStream_CancelledMidWrite(t *testing.T) {
+	now := time.Now()
+	store := newMockObjStore()
+	var recorded int32
+	db := &mockDB{
+		queryRowFn: func(_ context.Context, _ string, _ ...interface{}) *stg.CancelRow {
+			return stg.NewTestCancelRow(&mockRow{vals: []interface{}{
+				"upload-1", "video.mp4", int64(1024),
+				"video/mp4", "", "uploading", "", "owner1",
+				now, now,
+			}})
+		},
+		execFn: func(_ context.Context, query string, _ ...interface{}) (sql.Result, error) {
+			if strings.Contains(query, "upload_chunks") {
+				atomic.AddInt32(&recorded, 1)
+			}
+			return &mockResult{rowsAffected: 1}, nil
+		},
+	}
+	svc := NewUploadService(db, store, "mybucket", zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	body := io.MultiReader(strings.NewReader("first half "), &cancelReader{r: strings.NewReader("second half"), cancel: cancel})
+	err := svc.UploadChunkStream(ctx, "upload-1", 0, body, 22, "owner1")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, stored := store.data["mybucket/chunks/upload-1/0"]
+	assert.False(t, stored, "partial chunk is discarded")
+	assert.Zero(t, atomic.LoadInt32(&recorded))
+
+	require.NoError(t, svc.UploadChunkStream(context.Background(), "upload-1", 0, strings.NewReader("the whole chunk"), 15, "owner1"),
+		"the chunk can be resent")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&recorded))
+
+	err = svc.UploadChunkStream(ctx, "upload-1", 1, strings.NewReader("x"), 1, "owner1")
+	assert.ErrorIs(t, err, context.Canceled, "an already-cancelled request does no work")
+}
+
 func TestUploadService_CompleteChunkedUpload_NotUploadingState(t *testing.T) {
 	now := time.Now()
 	db := &mockDB{
@@ -637,7 +760,7 @@ func TestUploadService_CompleteChunkedUpload_AlreadyCompleted(t *testing.T) {
 			if strings.Contains(query, "FROM uploads") {
 				return stg.NewTestCancelRow(&mockRow{vals: []interface{}{
 					"upload-1", "video.mp4", int64(1024),
-					"video/mp4", "abc123", "uploading", "/mybucket/owner1/upload-1.mp4", "owner1",
+					"video/mp4", "", "uploading", "/mybucket/owner1/upload-1.mp4", "owner1",
 					now, now,
 				}})
 			}
@@ -1060,7 +1183,7 @@ func TestUploadService_CompleteChunkedUpload_UpdateFails(t *testing.T) {
 			if strings.Contains(query, "FROM uploads") {
 				return stg.NewTestCancelRow(&mockRow{vals: []interface{}{
 					"upload-1", "video.mp4", int64(6),
-					"video/mp4", "abc123", "uploading", "/mybucket/owner1/upload-1.mp4", "owner1",
+					"video/mp4", "", "uploading", "/mybucket/owner1/upload-1.mp4", "owner1",
 					now, now,
 				}})
 			}
@@ -1092,7 +1215,7 @@ func TestUploadService_CompleteChunkedUpload_HashVerification(t *testing.T) {
 			if strings.Contains(query, "FROM uploads") {
 				return stg.NewTestCancelRow(&mockRow{vals: []interface{}{
 					"upload-1", "video.mp4", int64(6),
-					"video/mp4", "abc123", "uploading", "/mybucket/owner1/upload-1.mp4", "owner1",
+					"video/mp4", "", "uploading", "/mybucket/owner1/upload-1.mp4", "owner1",
 					now, now,
 				}})
 			}
@@ -1139,6 +1262,9 @@ func (r *mockRow) Scan(dest ...interface{}) error {
 				*p = int64(v)
 			case *float64:
 				*p = float64(v)
+			case *sql.NullInt64:
+				p.Int64 = int64(v)
+				p.Valid = true
 			}
 		case int64:
 			switch p := d.(type) {
@@ -1209,6 +1335,10 @@ func (m *mockRows) Scan(dest ...interface{}) error {
 	}
 	for i, d := range dest {
 		switch v := row[i].(type) {
+		case int:
+			if p, ok := d.(*int); ok {
+				*p = v
+			}
 		case int64:
 			if p, ok := d.(*int64); ok {
 				*p = v
@@ -112,7 +112,10 @@ func (m *mockObjStore) UploadStream(_ context.Context, bucket, key string, reade
 	if m.uploadErr != nil {
 		return m.uploadErr
 	}
-	data, _ := io.ReadAll(reader)
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
 	m.mu.Lock()
 	m.data[bucket+"/"+key] = data
 	m.mu.Unlock()
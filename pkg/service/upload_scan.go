@@ -0,0 +1,38 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+)
+
+// NewUploadScanner builds the malware scanner selected by cfg, or returns
+// nil when scanning is disabled.
+func NewUploadScanner(cfg config.UploadScanConfig) (Scanner, error) {
+	var timeout time.Duration
+	if cfg.Timeout != "" {
+		d, err := time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid upload.scan.timeout %q: %w", cfg.Timeout, err)
+		}
+		timeout = d
+	}
+
+	switch cfg.Backend {
+	case "":
+		return nil, nil
+	case "clamav":
+		if cfg.ClamAVAddr == "" {
+			return nil, fmt.Errorf("upload.scan.clamav_addr is required for the clamav backend")
+		}
+		return NewClamAVScanner(cfg.ClamAVAddr, timeout), nil
+	case "http":
+		if cfg.HTTPURL == "" {
+			return nil, fmt.Errorf("upload.scan.http_url is required for the http backend")
+		}
+		return NewHTTPScanner(cfg.HTTPURL, timeout), nil
+	default:
+		return nil, fmt.Errorf("unknown upload.scan.backend %q", cfg.Backend)
+	}
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUploadScanner(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.UploadScanConfig
+		want    Scanner
+		wantErr bool
+	}{
+		{name: "disabled", cfg: config.UploadScanConfig{}},
+		{name: "clamav", cfg: config.UploadScanConfig{Backend: "clamav", ClamAVAddr: "clamd:3310", Timeout: "1m"}, want: &ClamAVScanner{}},
+		{name: "http", cfg: config.UploadScanConfig{Backend: "http", HTTPURL: "http://scanner/scan"}, want: &HTTPScanner{}},
+		{name: "clamav without addr", cfg: config.UploadScanConfig{Backend: "clamav"}, wantErr: true},
+		{name: "http without url", cfg: config.UploadScanConfig{Backend: "http"}, wantErr: true},
+		{name: "unknown backend", cfg: config.UploadScanConfig{Backend: "sophos"}, wantErr: true},
+		{name: "bad timeout", cfg: config.UploadScanConfig{Backend: "clamav", ClamAVAddr: "clamd:3310", Timeout: "soon"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, err := NewUploadScanner(tt.cfg)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.want == nil {
+				assert.Nil(t, sc)
+				return
+			}
+			assert.IsType(t, tt.want, sc)
+		})
+	}
+}
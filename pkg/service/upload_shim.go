@@ -2,6 +2,8 @@ package service
 
 import "github.com/rtcdance/streamgate/pkg/service/upload"
 
+const MaxSubtitleSize = upload.MaxSubtitleSize
+
 type (
 	UploadService         = upload.UploadService
 	UploadInfo            = upload.UploadInfo
@@ -12,6 +14,17 @@ type (
 	AutoTranscodeHookDeps = upload.AutoTranscodeHookDeps
 	PostUploadHook        = upload.PostUploadHook
 	BytesSliceReader      = upload.BytesSliceReader
+	ChunkSizePolicy       = upload.ChunkSizePolicy
+	ChunkConstraints      = upload.ChunkConstraints
+	ChunkPlan             = upload.ChunkPlan
+	SubtitleTrack         = upload.SubtitleTrack
+	ResumeStatus          = upload.ResumeStatus
+	MultipartPlan         = upload.MultipartPlan
+	MultipartPart         = upload.MultipartPart
+	Scanner               = upload.Scanner
+	ScanResult            = upload.ScanResult
+	ClamAVScanner         = upload.ClamAVScanner
+	HTTPScanner           = upload.HTTPScanner
 )
 
 var (
@@ -19,4 +32,15 @@ var (
 	ContentTypeToType = upload.ContentTypeToType
 	DetectContentType = upload.DetectContentType
 	BytesReader       = upload.BytesReader
+	ToWebVTT          = upload.ToWebVTT
+	SubtitleKey       = upload.SubtitleKey
+	NewClamAVScanner  = upload.NewClamAVScanner
+	NewHTTPScanner    = upload.NewHTTPScanner
+
+	ErrInvalidSubtitle  = upload.ErrInvalidSubtitle
+	ErrNotContentOwner  = upload.ErrNotContentOwner
+	ErrChecksumMismatch = upload.ErrChecksumMismatch
+
+	ErrMultipartNotConfigured = upload.ErrMultipartNotConfigured
+	ErrUploadQuarantined      = upload.ErrUploadQuarantined
 )
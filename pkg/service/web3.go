@@ -28,8 +28,8 @@ type Web3Deps struct {
 // DefaultWeb3Deps creates default real dependencies for production use.
 func DefaultWeb3Deps(cfg *config.Config, logger *zap.Logger) Web3Deps {
 	mcm := web3.NewMultiChainManager(logger)
-	if len(cfg.Web3.Chains) > 0 {
-		web3.ApplyChainConfigs(cfg.Web3.Chains)
+	if entries := cfg.Web3.ChainEntries(); len(entries) > 0 {
+		web3.ApplyChainConfigs(entries)
 	}
 	return Web3Deps{
 		ChainManager: mcm,
@@ -81,27 +81,68 @@ func NewWeb3Service(deps Web3Deps, cfg *config.Config, logger *zap.Logger) (*Web
 		nonceManagers:     make(map[int64]web3.NonceProvider),
 	}
 
-	// Initialize primary chain (Ethereum)
-	if err := service.multiChainManager.AddChain(11155111); err != nil {
-		logger.Warn("Failed to add Ethereum Sepolia", zap.Error(err))
+	// Extra RPC endpoints for the configured chain enable failover and
+	// periodic endpoint health checks.
+	if len(cfg.Web3.EthereumRPCs) > 0 {
+		rpcURLs := cfg.Web3.EthereumRPCs
+		if cfg.Web3.EthereumRPC != "" && !strings.Contains(cfg.Web3.EthereumRPC, "YOUR_KEY") {
+			rpcURLs = append([]string{cfg.Web3.EthereumRPC}, rpcURLs...)
+		}
+		if err := web3.SetChainRPCs(cfg.Web3.ChainID, rpcURLs); err != nil {
+			logger.Warn("Ignoring configured RPC endpoints", zap.Error(err))
+		}
 	}
-
-	// Initialize Polygon testnet
-	if err := service.multiChainManager.AddChain(80002); err != nil {
-		logger.Warn("Failed to add Polygon Amoy", zap.Error(err))
+	if cfg.Web3.RPCHealthInterval != "" {
+		if interval, err := time.ParseDuration(cfg.Web3.RPCHealthInterval); err != nil {
+			logger.Warn("Invalid web3.rpc_health_interval, endpoint health checks disabled", zap.String("value", cfg.Web3.RPCHealthInterval), zap.Error(err))
+		} else {
+			service.multiChainManager.SetHealthCheckInterval(interval)
+		}
 	}
 
-	// Initialize Anvil local (dev only)
-	if err := service.multiChainManager.AddChain(31337); err != nil {
-		logger.Warn("Failed to add Anvil local chain", zap.Error(err))
+	// Chains marked disabled in config are never registered, built-in or not.
+	disabled := make(map[int64]bool)
+	for _, entry := range cfg.Web3.ChainEntries() {
+		if entry.Disabled {
+			disabled[entry.ID] = true
+		}
 	}
 
-	// Initialize Solana chains
-	if err := service.multiChainManager.AddChain(-1); err != nil {
-		logger.Warn("Failed to add Solana Mainnet", zap.Error(err))
+	// Initialize built-in chains: Ethereum Sepolia (primary), Polygon Amoy,
+	// Anvil local (dev only) and the Solana chains.
+	for _, builtin := range []struct {
+		id   int64
+		name string
+	}{
+		{11155111, "Ethereum Sepolia"},
+		{80002, "Polygon Amoy"},
+		{31337, "Anvil local chain"},
+		{-1, "Solana Mainnet"},
+		{-2, "Solana Devnet"},
+	} {
+		if disabled[builtin.id] {
+			logger.Info("Skipping disabled chain", zap.Int64("chain_id", builtin.id), zap.String("name", builtin.name))
+			continue
+		}
+		if err := service.multiChainManager.AddChain(builtin.id); err != nil {
+			logger.Warn("Failed to add "+builtin.name, zap.Error(err))
+		}
 	}
-	if err := service.multiChainManager.AddChain(-2); err != nil {
-		logger.Warn("Failed to add Solana Devnet", zap.Error(err))
+
+	// Initialize configured chains that are not built in above
+	for _, entry := range cfg.Web3.ChainEntries() {
+		if entry.Disabled {
+			continue
+		}
+		if _, err := service.multiChainManager.GetClient(entry.ID); err == nil {
+			continue
+		}
+		if _, err := service.multiChainManager.GetSolanaClient(entry.ID); err == nil {
+			continue
+		}
+		if err := service.multiChainManager.AddChain(entry.ID); err != nil {
+			logger.Warn("Failed to add configured chain", zap.Int64("chain_id", entry.ID), zap.String("name", entry.Name), zap.Error(err))
+		}
 	}
 
 	// Apply RPC rate limiter if configured
@@ -178,10 +219,11 @@ func NewWeb3Service(deps Web3Deps, cfg *config.Config, logger *zap.Logger) (*Web
 		// EventIndexer polls for ERC-721/ERC-1155 Transfer events.
 		transferSig := "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
 		transferSingleSig := "0xc3d58168c5ae7397731d063d5bbf3d657854427343f4c083240f7aacaa2d0f62"
+		transferBatchSig := "0x4a39dc06d4c0dbc64b70af90fd698a233a518aa5d07e595d983b8c0526c8f7fb"
 		indexer, err := web3.NewEventIndexerWithConfig(
 			ethClient,
 			web3.EventIndexerConfig{
-				EventSignatures:    []string{transferSig, transferSingleSig},
+				EventSignatures:    []string{transferSig, transferSingleSig, transferBatchSig},
 				ConfirmationBlocks: 12,
 				UpdateInterval:     4 * time.Second,
 			},
@@ -196,6 +238,8 @@ func NewWeb3Service(deps Web3Deps, cfg *config.Config, logger *zap.Logger) (*Web
 				indexer.SetSubscriber(wsSub)
 			}
 			indexer.SetReorgDetector(reorgDetector)
+			// The parser names events, which is what EventListener dispatches on.
+			indexer.SetEventParser(web3.NewEventParser(logger))
 			indexerCtx, indexerCancel := context.WithCancel(context.Background())
 			if err := indexer.Start(indexerCtx); err != nil {
 				indexerCancel()
@@ -215,7 +259,7 @@ func NewWeb3Service(deps Web3Deps, cfg *config.Config, logger *zap.Logger) (*Web
 					service.nftService = nftSvc
 
 					listener := web3.NewEventListener(indexer, logger)
-					nftSvc.RegisterEventHandlerWithCache(listener, service.nftAccessCache, 11155111)
+					nftSvc.RegisterEventHandlerWithCache(listener, service.nftAccessCache, indexerChainID)
 					service.eventListener = listener
 
 					indexer.SetOnEvent(func(ctx context.Context, event *web3.IndexedEvent) error {
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
 
@@ -66,7 +67,7 @@ func (ws *Web3Service) VerifyNFTOwnership(ctx context.Context, chainID int64, co
 	standard := web3.DetectTokenStandard(ctx, ethCaller, contractAddress, ws.logger)
 	switch standard {
 	case web3.TokenStandardERC1155:
-		verifier := web3.NewERC1155Verifier(ethCaller, ws.logger, nil)
+		verifier := web3.NewERC1155Verifier(ethCaller, ws.logger, nil).WithChainID(chainID)
 		return verifier.VerifyNFTOwnership(ctx, contractAddress, tokenID, ownerAddress)
 	default:
 		// ERC-721 or unknown — use the standard NFTVerifier
@@ -167,19 +168,18 @@ func (ws *Web3Service) GetNFT(ctx context.Context, chainID int64, contractAddres
 }
 
 // DetectContractType detects whether a contract is ERC-721 or ERC-1155.
-// Returns "ERC-721", "ERC-1155", or "unknown".
+// Returns "ERC-721", "ERC-1155", or "unknown". ERC-165 is authoritative;
+// contracts without it are probed with balanceOf calls.
 func (ws *Web3Service) DetectContractType(ctx context.Context, chainID int64, contractAddress string) string {
 	client, err := ws.multiChainManager.GetClient(chainID)
 	if err != nil {
 		return "unknown"
 	}
-	switch web3.DetectTokenStandard(ctx, client.GetEthClient(), contractAddress, ws.logger) {
-	case web3.TokenStandardERC721:
-		return "ERC-721"
-	case web3.TokenStandardERC1155:
-		return "ERC-1155"
+	standard, err := web3.ClassifyTokenStandard(ctx, client.GetEthClient(), contractAddress)
+	if errors.Is(err, web3.ErrERC165NotSupported) {
+		standard = web3.DetectTokenStandard(ctx, client.GetEthClient(), contractAddress, ws.logger)
 	}
-	return "unknown"
+	return web3.TokenStandardName(standard)
 }
 
 // ListNFTs lists NFTs
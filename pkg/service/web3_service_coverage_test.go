@@ -28,6 +28,7 @@ type svcCovChainManager struct {
 	getMainnetFn      func() []*web3.ChainConfig
 	getRPCStatusesFn  func() map[int64][]web3.RPCStatus
 	closeFn           func()
+	added             []int64
 }
 
 func (m *svcCovChainManager) GetClient(chainID int64) (*web3.ChainClient, error) {
@@ -44,7 +45,12 @@ func (m *svcCovChainManager) GetSolanaClient(chainID int64) (*web3.SolanaVerifie
 	return nil, errors.New("solana client not found")
 }
 
-func (m *svcCovChainManager) AddChain(chainID int64) error { return nil }
+func (m *svcCovChainManager) AddChain(chainID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.added = append(m.added, chainID)
+	return nil
+}
 func (m *svcCovChainManager) GetSupportedChains() []*web3.ChainConfig {
 	if m.getSupportedFn != nil {
 		return m.getSupportedFn()
@@ -70,6 +76,7 @@ func (m *svcCovChainManager) GetRPCStatuses() map[int64][]web3.RPCStatus {
 	return nil
 }
 func (m *svcCovChainManager) SetRateLimiter(rl *web3.RPCRateLimiter) {}
+func (m *svcCovChainManager) SetHealthCheckInterval(interval time.Duration) {}
 func (m *svcCovChainManager) Close() {
 	if m.closeFn != nil {
 		m.closeFn()
@@ -547,6 +554,24 @@ func TestSvcCov_NewWeb3Service_InvalidKey(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestSvcCov_NewWeb3Service_SkipsDisabledChains(t *testing.T) {
+	mcm := newSvcCovMCM()
+	cfg := &config.Config{
+		Web3: config.Web3Config{
+			Chains: []config.ChainConfigEntry{
+				{ID: 80002, Name: "Polygon Amoy", Disabled: true},
+				{ID: 137, Name: "Polygon", Disabled: true},
+				{ID: 8453, Name: "Base"},
+			},
+		},
+	}
+	svc, err := NewWeb3Service(Web3Deps{ChainManager: mcm}, cfg, zap.NewNop())
+	require.NoError(t, err)
+	defer svc.Close()
+
+	assert.Equal(t, []int64{11155111, 31337, -1, -2, 8453}, mcm.added)
+}
+
 func TestSvcCov_Web3Service_RegisterContent_WithKeyNoChain(t *testing.T) {
 	svc := &Web3Service{logger: zap.NewNop(), secureKey: &web3.SecurePrivateKey{}, multiChainManager: newSvcCovMCM(), config: &config.Config{}}
 	_, err := svc.RegisterContent(context.Background(), 1, "0xContract", "hash", "uri")
@@ -0,0 +1,342 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/service/serviceerrors"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Delivery request headers. SignatureHeader carries "sha256=<hex>", the
+// HMAC-SHA256 with the endpoint secret of the TimestampHeader value, a
+// dot and the body; receivers should reject stale timestamps.
+const (
+	EventHeader     = "X-StreamGate-Event"
+	DeliveryHeader  = "X-StreamGate-Delivery"
+	TimestampHeader = "X-StreamGate-Timestamp"
+	SignatureHeader = "X-StreamGate-Signature"
+)
+
+// Delivery statuses. Dead deliveries exhausted their attempts.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusDead      = "dead"
+)
+
+const (
+	// claimBatch caps the deliveries claimed per poll.
+	claimBatch = 50
+	// deliveryConcurrency caps the requests in flight per instance.
+	deliveryConcurrency = 8
+	// leaseMargin is added to the request timeout while a delivery is
+	// claimed, so no other instance sends it in the meantime.
+	leaseMargin   = 30 * time.Second
+	maxErrorBytes = 512
+)
+
+// Delivery is one event queued for one endpoint.
+type Delivery struct {
+	ID             string          `json:"id"`
+	EndpointID     string          `json:"endpoint_id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// DeliveryFilter selects deliveries to list; empty fields match all.
+type DeliveryFilter struct {
+	EndpointID string
+	Status     string
+}
+
+// Sign returns the SignatureHeader value for body sent at timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// sender POSTs signed payloads.
+type sender struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+func newSender(timeout time.Duration) *sender {
+	return &sender{client: &http.Client{Timeout: timeout}, now: time.Now}
+}
+
+// send returns the response status, or 0 when no response arrived, and
+// an error unless the endpoint answered 2xx.
+func (s *sender) send(ctx context.Context, target, secret, deliveryID, eventType string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	ts := s.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "StreamGate-Webhooks/1.0")
+	req.Header.Set(EventHeader, eventType)
+	req.Header.Set(DeliveryHeader, deliveryID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(ts, 10))
+	req.Header.Set(SignatureHeader, Sign(secret, ts, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBytes))
+		return resp.StatusCode, fmt.Errorf("endpoint returned %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff is the wait after the given number of failed attempts: the
+// initial backoff doubled per further attempt, capped, with up to 20%
+// jitter so retries of one outage spread out.
+func (s *Service) backoff(attempts int) time.Duration {
+	d := s.cfg.InitialBackoff
+	for i := 1; i < attempts && d < s.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > s.cfg.MaxBackoff {
+		d = s.cfg.MaxBackoff
+	}
+	return d + time.Duration(rand.Int64N(int64(d)/5+1))
+}
+
+// Start runs the delivery worker until Close.
+func (s *Service) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			for {
+				n, err := s.DeliverDue(ctx)
+				if err != nil && ctx.Err() == nil {
+					s.logger.Warn("Webhook delivery round failed", zap.Error(err))
+				}
+				if err != nil || n < claimBatch {
+					break
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-s.wake:
+			}
+		}
+	}()
+}
+
+// Close stops the worker and waits for in-flight deliveries and emits.
+func (s *Service) Close() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+type claimedDelivery struct {
+	id, eventType, url, secret string
+	payload                    []byte
+	attempts                   int
+}
+
+// DeliverDue claims the pending deliveries that are due, sends them and
+// records the outcome. It returns how many were claimed.
+func (s *Service) DeliverDue(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	rows, err := s.db.Query(ctx, `UPDATE webhook_deliveries d SET next_attempt_at = $2
+		FROM webhook_endpoints e
+		WHERE e.id = d.endpoint_id AND d.id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at LIMIT $3
+			FOR UPDATE SKIP LOCKED)
+		RETURNING d.id::text, d.event_type, d.payload, d.attempts, e.url, e.secret`,
+		now, now.Add(s.cfg.Timeout+leaseMargin), claimBatch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	var claimed []claimedDelivery
+	for rows.Next() {
+		var d claimedDelivery
+		if err := rows.Scan(&d.id, &d.eventType, &d.payload, &d.attempts, &d.url, &d.secret); err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		claimed = append(claimed, d)
+	}
+	err = rows.Err()
+	_ = rows.Close()
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	sem := make(chan struct{}, deliveryConcurrency)
+	var wg sync.WaitGroup
+	for _, d := range claimed {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(d claimedDelivery) {
+			defer func() { <-sem; wg.Done() }()
+			s.attempt(ctx, d)
+		}(d)
+	}
+	wg.Wait()
+	return len(claimed), nil
+}
+
+// attempt sends one delivery and records the result. Results are written
+// even when ctx is cancelled so a shutdown does not lose them.
+func (s *Service) attempt(ctx context.Context, d claimedDelivery) {
+	status, sendErr := s.sender.send(ctx, d.url, d.secret, d.id, d.eventType, d.payload)
+	writeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+	attempts := d.attempts + 1
+	var err error
+	if sendErr == nil {
+		_, err = s.db.Exec(writeCtx, `UPDATE webhook_deliveries SET status = $2, attempts = $3,
+			last_status_code = $4, last_error = NULL, delivered_at = $5 WHERE id = $1`,
+			d.id, StatusDelivered, attempts, status, now)
+	} else {
+		next, state := now.Add(s.backoff(attempts)), StatusPending
+		if attempts >= s.cfg.MaxAttempts {
+			state = StatusDead
+			s.logger.Warn("Webhook delivery dead-lettered",
+				zap.String("delivery_id", d.id), zap.String("event", d.eventType), zap.Int("attempts", attempts), zap.Error(sendErr))
+		}
+		msg := sendErr.Error()
+		if len(msg) > maxErrorBytes {
+			msg = msg[:maxErrorBytes]
+		}
+		_, err = s.db.Exec(writeCtx, `UPDATE webhook_deliveries SET status = $2, attempts = $3,
+			last_status_code = $4, last_error = $5, next_attempt_at = $6 WHERE id = $1`,
+			d.id, state, attempts, nullStatus(status), msg, next)
+	}
+	if err != nil {
+		s.logger.Warn("Failed to record webhook delivery attempt", zap.String("delivery_id", d.id), zap.Error(err))
+	}
+}
+
+func nullStatus(code int) interface{} {
+	if code == 0 {
+		return nil
+	}
+	return code
+}
+
+// ListDeliveries lists deliveries matching f, newest first.
+func (s *Service) ListDeliveries(ctx context.Context, f DeliveryFilter, limit, offset int) ([]Delivery, error) {
+	if f.EndpointID != "" {
+		if _, err := uuid.Parse(f.EndpointID); err != nil {
+			return nil, fmt.Errorf("invalid endpoint id %q: %w", f.EndpointID, serviceerrors.ErrInvalidRequest)
+		}
+	}
+	switch f.Status {
+	case "", StatusPending, StatusDelivered, StatusDead:
+	default:
+		return nil, fmt.Errorf("unknown delivery status %q: %w", f.Status, serviceerrors.ErrInvalidRequest)
+	}
+	rows, err := s.db.Query(ctx, `SELECT id::text, endpoint_id::text, event_id::text, event_type, payload, status, attempts,
+			next_attempt_at, COALESCE(last_status_code, 0), COALESCE(last_error, ''), created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE ($1 = '' OR endpoint_id::text = $1) AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC, id
+		LIMIT $3 OFFSET $4`, f.EndpointID, f.Status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := []Delivery{}
+	for rows.Next() {
+		var d Delivery
+		var payload []byte
+		var delivered sql.NullTime
+		if err := rows.Scan(&d.ID, &d.EndpointID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &delivered); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		d.Payload = json.RawMessage(payload)
+		if delivered.Valid {
+			d.DeliveredAt = &delivered.Time
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// Redeliver queues the delivery id to be sent again now with a fresh
+// attempt budget, whatever its status.
+func (s *Service) Redeliver(ctx context.Context, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return fmt.Errorf("webhook delivery %s: %w", id, serviceerrors.ErrNotFound)
+	}
+	result, err := s.db.Exec(ctx, `UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = $2
+		WHERE id = $1`, id, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to redeliver webhook delivery: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("webhook delivery %s: %w", id, serviceerrors.ErrNotFound)
+	}
+	s.notify()
+	return nil
+}
+
+// RedeliverDead requeues every dead delivery of the endpoint id, e.g.
+// after the receiver recovers from an outage, and returns how many.
+func (s *Service) RedeliverDead(ctx context.Context, endpointID string) (int, error) {
+	if _, err := s.GetEndpoint(ctx, endpointID); err != nil {
+		return 0, err
+	}
+	result, err := s.db.Exec(ctx, `UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = $2
+		WHERE endpoint_id = $1 AND status = 'dead'`, endpointID, time.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to redeliver webhook deliveries: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	if n > 0 {
+		s.notify()
+	}
+	return int(n), nil
+}
@@ -0,0 +1,377 @@
+// Package webhook delivers platform events to endpoints registered by
+// operators. Every event is stored as one delivery per subscribed endpoint
+// and POSTed with an HMAC signature; failed deliveries are retried with
+// exponential backoff and end up as dead letters that can be redelivered.
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/service/serviceerrors"
+	"github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// Platform events delivered to webhooks.
+const (
+	EventUploadCompleted       = "upload.completed"
+	EventTranscodeCompleted    = "transcode.completed"
+	EventTranscodeFailed       = "transcode.failed"
+	EventNFTVerificationFailed = "nft.verification.failed"
+	EventStreamStarted         = "stream.started"
+)
+
+// Events lists the events endpoints may subscribe to.
+var Events = []string{
+	EventUploadCompleted,
+	EventTranscodeCompleted,
+	EventTranscodeFailed,
+	EventNFTVerificationFailed,
+	EventStreamStarted,
+}
+
+const (
+	defaultMaxAttempts    = 8
+	defaultInitialBackoff = 30 * time.Second
+	defaultMaxBackoff     = 6 * time.Hour
+	defaultTimeout        = 10 * time.Second
+	defaultPollInterval   = 5 * time.Second
+	// emitTimeout bounds storing an event emitted with EmitAsync.
+	emitTimeout = 5 * time.Second
+	secretBytes = 32
+)
+
+// Config tunes delivery. Zero values take the defaults.
+type Config struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Timeout        time.Duration
+	PollInterval   time.Duration
+}
+
+func (c *Config) applyDefaults() {
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = defaultMaxAttempts
+	}
+	if c.InitialBackoff <= 0 {
+		c.InitialBackoff = defaultInitialBackoff
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = defaultMaxBackoff
+	}
+	if c.MaxBackoff < c.InitialBackoff {
+		c.MaxBackoff = c.InitialBackoff
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTimeout
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = defaultPollInterval
+	}
+}
+
+// Endpoint is a registered webhook receiver. Events lists the events it
+// receives; empty means all of them.
+type Endpoint struct {
+	ID          string   `json:"id"`
+	URL         string   `json:"url"`
+	Events      []string `json:"events"`
+	Description string   `json:"description"`
+	Active      bool     `json:"active"`
+	// Secret signs deliveries. It is only returned when the endpoint is
+	// created or its secret is replaced.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// EndpointUpdate changes an endpoint. Nil fields are left unchanged.
+type EndpointUpdate struct {
+	URL         *string
+	Events      *[]string
+	Description *string
+	Active      *bool
+	// RotateSecret replaces the signing secret with a new random one.
+	RotateSecret bool
+}
+
+// Payload is the JSON body POSTed to endpoints.
+type Payload struct {
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	CreatedAt time.Time              `json:"created_at"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+// Service stores endpoints and deliveries in Postgres and runs the
+// delivery worker. Any number of instances may share the tables.
+type Service struct {
+	db     storage.DB
+	cfg    Config
+	sender *sender
+	logger *zap.Logger
+
+	wake   chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewService returns a webhook service over db. Call Start to deliver.
+func NewService(db storage.DB, cfg Config, logger *zap.Logger) *Service {
+	cfg.applyDefaults()
+	return &Service{
+		db:     db,
+		cfg:    cfg,
+		sender: newSender(cfg.Timeout),
+		logger: logger,
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+func validateEndpoint(rawURL string, events []string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid webhook url %q: %w", rawURL, serviceerrors.ErrInvalidRequest)
+	}
+	for _, e := range events {
+		if !isEvent(e) {
+			return fmt.Errorf("unknown webhook event %q: %w", e, serviceerrors.ErrInvalidRequest)
+		}
+	}
+	return nil
+}
+
+func isEvent(name string) bool {
+	for _, e := range Events {
+		if e == name {
+			return true
+		}
+	}
+	return false
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, secretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// CreateEndpoint registers an active endpoint. A random secret is
+// generated when secret is empty; either way it is returned once, on the
+// endpoint.
+func (s *Service) CreateEndpoint(ctx context.Context, rawURL, secret string, events []string, description string) (*Endpoint, error) {
+	events = normalizeEvents(events)
+	if err := validateEndpoint(rawURL, events); err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		var err error
+		if secret, err = newSecret(); err != nil {
+			return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+	}
+	now := time.Now().UTC()
+	ep := &Endpoint{
+		ID:          uuid.New().String(),
+		URL:         rawURL,
+		Events:      events,
+		Description: description,
+		Active:      true,
+		Secret:      secret,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	_, err := s.db.Exec(ctx, `INSERT INTO webhook_endpoints (id, url, secret, events, description, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, TRUE, $6, $6)`,
+		ep.ID, ep.URL, ep.Secret, pq.Array(ep.Events), ep.Description, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook endpoint: %w", err)
+	}
+	return ep, nil
+}
+
+const endpointColumns = `id::text, url, events, COALESCE(description, ''), active, created_at, updated_at`
+
+func scanEndpoint(row storage.RowScanner) (*Endpoint, error) {
+	var ep Endpoint
+	if err := row.Scan(&ep.ID, &ep.URL, pq.Array(&ep.Events), &ep.Description, &ep.Active, &ep.CreatedAt, &ep.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if ep.Events == nil {
+		ep.Events = []string{}
+	}
+	return &ep, nil
+}
+
+// GetEndpoint returns the endpoint id, without its secret.
+func (s *Service) GetEndpoint(ctx context.Context, id string) (*Endpoint, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("webhook endpoint %s: %w", id, serviceerrors.ErrNotFound)
+	}
+	ep, err := scanEndpoint(s.db.QueryRow(ctx, `SELECT `+endpointColumns+` FROM webhook_endpoints WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("webhook endpoint %s: %w", id, serviceerrors.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook endpoint: %w", err)
+	}
+	return ep, nil
+}
+
+// ListEndpoints returns all endpoints, oldest first, without secrets.
+func (s *Service) ListEndpoints(ctx context.Context) ([]*Endpoint, error) {
+	rows, err := s.db.Query(ctx, `SELECT `+endpointColumns+` FROM webhook_endpoints ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook endpoints: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	eps := []*Endpoint{}
+	for rows.Next() {
+		ep, err := scanEndpoint(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook endpoint: %w", err)
+		}
+		eps = append(eps, ep)
+	}
+	return eps, rows.Err()
+}
+
+// UpdateEndpoint applies u to the endpoint id and returns it, with the new
+// secret when u rotates it.
+func (s *Service) UpdateEndpoint(ctx context.Context, id string, u EndpointUpdate) (*Endpoint, error) {
+	ep, err := s.GetEndpoint(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if u.URL != nil {
+		ep.URL = *u.URL
+	}
+	if u.Events != nil {
+		ep.Events = normalizeEvents(*u.Events)
+	}
+	if u.Description != nil {
+		ep.Description = *u.Description
+	}
+	if u.Active != nil {
+		ep.Active = *u.Active
+	}
+	if err := validateEndpoint(ep.URL, ep.Events); err != nil {
+		return nil, err
+	}
+	ep.UpdatedAt = time.Now().UTC()
+
+	query := `UPDATE webhook_endpoints SET url = $2, events = $3, description = $4, active = $5, updated_at = $6`
+	args := []interface{}{id, ep.URL, pq.Array(ep.Events), ep.Description, ep.Active, ep.UpdatedAt}
+	if u.RotateSecret {
+		if ep.Secret, err = newSecret(); err != nil {
+			return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+		query += `, secret = $7`
+		args = append(args, ep.Secret)
+	}
+	result, err := s.db.Exec(ctx, query+` WHERE id = $1`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update webhook endpoint: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("webhook endpoint %s: %w", id, serviceerrors.ErrNotFound)
+	}
+	return ep, nil
+}
+
+// DeleteEndpoint removes the endpoint id and its deliveries.
+func (s *Service) DeleteEndpoint(ctx context.Context, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return fmt.Errorf("webhook endpoint %s: %w", id, serviceerrors.ErrNotFound)
+	}
+	result, err := s.db.Exec(ctx, `DELETE FROM webhook_endpoints WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook endpoint: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("webhook endpoint %s: %w", id, serviceerrors.ErrNotFound)
+	}
+	return nil
+}
+
+// Emit queues eventType for every active endpoint subscribed to it and
+// returns the number of deliveries queued.
+func (s *Service) Emit(ctx context.Context, eventType string, data map[string]interface{}) (int, error) {
+	if !isEvent(eventType) {
+		return 0, fmt.Errorf("unknown webhook event %q: %w", eventType, serviceerrors.ErrInvalidRequest)
+	}
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	now := time.Now().UTC()
+	p := Payload{ID: uuid.New().String(), Type: eventType, CreatedAt: now, Data: data}
+	body, err := json.Marshal(p)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	result, err := s.db.Exec(ctx, `INSERT INTO webhook_deliveries (id, endpoint_id, event_id, event_type, payload, next_attempt_at, created_at)
+		SELECT uuid_generate_v4(), e.id, $1, $2, $3, $4, $4 FROM webhook_endpoints e
+		WHERE e.active AND (cardinality(e.events) = 0 OR $2 = ANY(e.events))`,
+		p.ID, eventType, body, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to queue webhook deliveries: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	if n > 0 {
+		s.notify()
+	}
+	return int(n), nil
+}
+
+// EmitAsync emits the event without blocking the caller, for use from
+// request paths and hooks. Failures are logged.
+func (s *Service) EmitAsync(eventType string, data map[string]interface{}) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), emitTimeout)
+		defer cancel()
+		if _, err := s.Emit(ctx, eventType, data); err != nil {
+			s.logger.Warn("Failed to emit webhook event", zap.String("event", eventType), zap.Error(err))
+		}
+	}()
+}
+
+// notify wakes the worker to deliver new events without waiting for the
+// next poll.
+func (s *Service) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// normalizeEvents trims and deduplicates event names.
+func normalizeEvents(events []string) []string {
+	out := make([]string, 0, len(events))
+	seen := make(map[string]bool, len(events))
+	for _, e := range events {
+		e = strings.TrimSpace(e)
+		if e != "" && !seen[e] {
+			seen[e] = true
+			out = append(out, e)
+		}
+	}
+	return out
+}
@@ -0,0 +1,244 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/service/serviceerrors"
+	stg "github.com/rtcdance/streamgate/pkg/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const (
+	endpointID = "7f1d9a8e-2b7c-4c39-9d1e-2f3a4b5c6d7e"
+	deliveryID = "11111111-1111-4111-8111-111111111111"
+)
+
+type execCall struct {
+	query string
+	args  []interface{}
+}
+
+type mockDB struct {
+	queryFn    func(ctx context.Context, query string, args ...interface{}) (stg.Rows, error)
+	queryRowFn func(ctx context.Context, query string, args ...interface{}) *stg.CancelRow
+	affected   int64
+
+	mu    sync.Mutex
+	execs []execCall
+}
+
+func (m *mockDB) Query(ctx context.Context, query string, args ...interface{}) (stg.Rows, error) {
+	if m.queryFn != nil {
+		return m.queryFn(ctx, query, args...)
+	}
+	return nil, errors.New("not implemented")
+}
+func (m *mockDB) QueryRow(ctx context.Context, query string, args ...interface{}) *stg.CancelRow {
+	if m.queryRowFn != nil {
+		return m.queryRowFn(ctx, query, args...)
+	}
+	return stg.NewErrorCancelRow(sql.ErrNoRows)
+}
+func (m *mockDB) Exec(_ context.Context, query string, args ...interface{}) (sql.Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.execs = append(m.execs, execCall{query, args})
+	return mockResult{m.affected}, nil
+}
+func (m *mockDB) Begin(context.Context) (*sql.Tx, error) { return nil, errors.New("not implemented") }
+func (m *mockDB) InTransaction(context.Context, func(tx *sql.Tx) error) error {
+	return errors.New("not implemented")
+}
+func (m *mockDB) Ping(context.Context) error { return nil }
+func (m *mockDB) Close() error               { return nil }
+
+type mockResult struct{ rowsAffected int64 }
+
+func (m mockResult) LastInsertId() (int64, error) { return 0, nil }
+func (m mockResult) RowsAffected() (int64, error) { return m.rowsAffected, nil }
+
+// claimRows answers the claim query with one delivery per target url.
+type claimRows struct {
+	urls     []string
+	attempts int
+	i        int
+}
+
+func (r *claimRows) Next() bool { r.i++; return r.i <= len(r.urls) }
+func (r *claimRows) Scan(dest ...interface{}) error {
+	*dest[0].(*string) = deliveryID
+	*dest[1].(*string) = EventUploadCompleted
+	*dest[2].(*[]byte) = []byte(`{"type":"upload.completed"}`)
+	*dest[3].(*int) = r.attempts
+	*dest[4].(*string) = r.urls[r.i-1]
+	*dest[5].(*string) = "whsec_test"
+	return nil
+}
+func (r *claimRows) Close() error { return nil }
+func (r *claimRows) Err() error   { return nil }
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":"e1"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000." + string(body)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	assert.Equal(t, want, Sign("secret", 1700000000, body))
+	assert.NotEqual(t, want, Sign("other", 1700000000, body))
+	assert.NotEqual(t, want, Sign("secret", 1700000001, body))
+}
+
+func TestBackoff(t *testing.T) {
+	s := NewService(&mockDB{}, Config{InitialBackoff: time.Second, MaxBackoff: 10 * time.Second}, zap.NewNop())
+	tests := []struct {
+		attempts int
+		base     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{30, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.attempts), func(t *testing.T) {
+			d := s.backoff(tt.attempts)
+			assert.GreaterOrEqual(t, d, tt.base)
+			assert.LessOrEqual(t, d, tt.base+tt.base/5)
+		})
+	}
+}
+
+func TestCreateEndpoint(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		events  []string
+		wantErr bool
+	}{
+		{"all events", "https://example.com/hook", nil, false},
+		{"some events", "http://hooks.local/x", []string{" upload.completed", "stream.started", "upload.completed"}, false},
+		{"unknown event", "https://example.com/hook", []string{"upload.started"}, true},
+		{"not http", "ftp://example.com/hook", nil, true},
+		{"no host", "https:///hook", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &mockDB{affected: 1}
+			ep, err := NewService(db, Config{}, zap.NewNop()).CreateEndpoint(context.Background(), tt.url, "", tt.events, "")
+			if tt.wantErr {
+				assert.ErrorIs(t, err, serviceerrors.ErrInvalidRequest)
+				assert.Empty(t, db.execs)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(ep.Secret, "whsec_"))
+			assert.True(t, ep.Active)
+			assert.Len(t, ep.Events, len(normalizeEvents(tt.events)))
+			assert.Len(t, db.execs, 1)
+		})
+	}
+}
+
+func TestEmit(t *testing.T) {
+	db := &mockDB{affected: 2}
+	s := NewService(db, Config{}, zap.NewNop())
+
+	n, err := s.Emit(context.Background(), EventStreamStarted, map[string]interface{}{"stream_id": "s1"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	require.Len(t, db.execs, 1)
+	assert.Equal(t, EventStreamStarted, db.execs[0].args[1])
+	assert.Contains(t, string(db.execs[0].args[2].([]byte)), `"stream_id":"s1"`)
+	assert.Len(t, s.wake, 1, "emitting wakes the worker")
+
+	_, err = s.Emit(context.Background(), "stream.paused", nil)
+	assert.ErrorIs(t, err, serviceerrors.ErrInvalidRequest)
+}
+
+func TestDeliverDue(t *testing.T) {
+	var gotSig, gotTS, gotBody string
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotSig, gotTS, gotBody = r.Header.Get(SignatureHeader), r.Header.Get(TimestampHeader), string(body)
+		assert.Equal(t, EventUploadCompleted, r.Header.Get(EventHeader))
+		assert.Equal(t, deliveryID, r.Header.Get(DeliveryHeader))
+	}))
+	defer ok.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "boom", http.StatusBadGateway)
+	}))
+	defer failing.Close()
+
+	tests := []struct {
+		name       string
+		url        string
+		attempts   int
+		wantStatus string
+		wantCode   interface{}
+	}{
+		{"delivered", ok.URL, 0, StatusDelivered, http.StatusOK},
+		{"retried", failing.URL, 0, StatusPending, http.StatusBadGateway},
+		{"dead-lettered", failing.URL, 2, StatusDead, http.StatusBadGateway},
+		{"unreachable", "http://127.0.0.1:1", 0, StatusPending, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &mockDB{queryFn: func(context.Context, string, ...interface{}) (stg.Rows, error) {
+				return &claimRows{urls: []string{tt.url}, attempts: tt.attempts}, nil
+			}}
+			s := NewService(db, Config{MaxAttempts: 3, Timeout: time.Second}, zap.NewNop())
+
+			n, err := s.DeliverDue(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, 1, n)
+			require.Len(t, db.execs, 1)
+			args := db.execs[0].args
+			assert.Equal(t, tt.wantStatus, args[1])
+			assert.Equal(t, tt.attempts+1, args[2])
+			assert.Equal(t, tt.wantCode, args[3])
+		})
+	}
+
+	ts, err := strconv.ParseInt(gotTS, 10, 64)
+	require.NoError(t, err)
+	assert.Equal(t, Sign("whsec_test", ts, []byte(gotBody)), gotSig)
+}
+
+func TestRedeliver(t *testing.T) {
+	s := NewService(&mockDB{affected: 0}, Config{}, zap.NewNop())
+	assert.ErrorIs(t, s.Redeliver(context.Background(), deliveryID), serviceerrors.ErrNotFound)
+	assert.ErrorIs(t, s.Redeliver(context.Background(), "nope"), serviceerrors.ErrNotFound)
+
+	db := &mockDB{affected: 1}
+	s = NewService(db, Config{}, zap.NewNop())
+	require.NoError(t, s.Redeliver(context.Background(), deliveryID))
+	assert.Contains(t, db.execs[0].query, "attempts = 0")
+
+	_, err := s.RedeliverDead(context.Background(), endpointID)
+	assert.ErrorIs(t, err, serviceerrors.ErrNotFound, "unknown endpoint")
+}
+
+func TestListDeliveries_Validation(t *testing.T) {
+	s := NewService(&mockDB{}, Config{}, zap.NewNop())
+	_, err := s.ListDeliveries(context.Background(), DeliveryFilter{Status: "lost"}, 10, 0)
+	assert.ErrorIs(t, err, serviceerrors.ErrInvalidRequest)
+	_, err = s.ListDeliveries(context.Background(), DeliveryFilter{EndpointID: "x"}, 10, 0)
+	assert.ErrorIs(t, err, serviceerrors.ErrInvalidRequest)
+}
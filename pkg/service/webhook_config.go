@@ -0,0 +1,43 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/rtcdance/streamgate/pkg/storage"
+
+	"go.uber.org/zap"
+)
+
+// NewWebhookServiceFromConfig builds the webhook service described by cfg,
+// or returns nil when webhooks are disabled. The caller starts it.
+func NewWebhookServiceFromConfig(db storage.DB, cfg config.WebhooksConfig, logger *zap.Logger) (*WebhookService, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if cfg.MaxAttempts < 0 {
+		return nil, fmt.Errorf("webhooks.max_attempts must not be negative")
+	}
+	wc := WebhookConfig{MaxAttempts: cfg.MaxAttempts}
+	for _, d := range []struct {
+		key   string
+		value string
+		dst   *time.Duration
+	}{
+		{"webhooks.initial_backoff", cfg.InitialBackoff, &wc.InitialBackoff},
+		{"webhooks.max_backoff", cfg.MaxBackoff, &wc.MaxBackoff},
+		{"webhooks.timeout", cfg.Timeout, &wc.Timeout},
+		{"webhooks.poll_interval", cfg.PollInterval, &wc.PollInterval},
+	} {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("invalid %s %q", d.key, d.value)
+		}
+		*d.dst = v
+	}
+	return NewWebhookService(db, wc, logger), nil
+}
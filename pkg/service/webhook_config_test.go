@@ -0,0 +1,43 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNewWebhookServiceFromConfig(t *testing.T) {
+	enabled := config.WebhooksConfig{Enabled: true, MaxAttempts: 8, InitialBackoff: "30s", MaxBackoff: "6h", Timeout: "10s", PollInterval: "5s"}
+	tests := []struct {
+		name    string
+		mutate  func(c *config.WebhooksConfig)
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "enabled"},
+		{name: "defaults", mutate: func(c *config.WebhooksConfig) { *c = config.WebhooksConfig{Enabled: true} }},
+		{name: "disabled", mutate: func(c *config.WebhooksConfig) { c.Enabled = false }, wantNil: true},
+		{name: "negative attempts", mutate: func(c *config.WebhooksConfig) { c.MaxAttempts = -1 }, wantErr: true},
+		{name: "bad backoff", mutate: func(c *config.WebhooksConfig) { c.InitialBackoff = "soon" }, wantErr: true},
+		{name: "zero timeout", mutate: func(c *config.WebhooksConfig) { c.Timeout = "0s" }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := enabled
+			if tt.mutate != nil {
+				tt.mutate(&cfg)
+			}
+			svc, err := NewWebhookServiceFromConfig(nil, cfg, zap.NewNop())
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantNil, svc == nil)
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package service
+
+import "github.com/rtcdance/streamgate/pkg/service/webhook"
+
+type (
+	WebhookService        = webhook.Service
+	WebhookConfig         = webhook.Config
+	WebhookEndpoint       = webhook.Endpoint
+	WebhookEndpointUpdate = webhook.EndpointUpdate
+	WebhookDelivery       = webhook.Delivery
+	WebhookDeliveryFilter = webhook.DeliveryFilter
+)
+
+var NewWebhookService = webhook.NewService
+
+// Platform events delivered to webhooks.
+const (
+	WebhookEventUploadCompleted       = webhook.EventUploadCompleted
+	WebhookEventTranscodeCompleted    = webhook.EventTranscodeCompleted
+	WebhookEventTranscodeFailed       = webhook.EventTranscodeFailed
+	WebhookEventNFTVerificationFailed = webhook.EventNFTVerificationFailed
+	WebhookEventStreamStarted         = webhook.EventStreamStarted
+)
@@ -0,0 +1,319 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultArweaveBundlerURL = "https://upload.ardrive.io/v1/tx"
+	defaultArweaveGatewayURL = "https://arweave.net"
+
+	// arweaveSignatureType is the ANS-104 signature type of Arweave (RSA-PSS)
+	// wallets; their signatures and owners are both 512 bytes.
+	arweaveSignatureType = 1
+	arweaveKeySize       = 512
+)
+
+// arweaveIDPattern matches a transaction or data item ID: 32 bytes,
+// base64url without padding.
+var arweaveIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{43}$`)
+
+// ArweaveTag is a name/value tag attached to archived data, indexed by
+// gateways for lookup.
+type ArweaveTag struct {
+	Name  string
+	Value string
+}
+
+// ArweaveConfig configures an ArweaveUploader.
+type ArweaveConfig struct {
+	// BundlerURL is the ANS-104 upload endpoint signed data items are
+	// posted to; the bundler pays for and settles them on Arweave.
+	BundlerURL string
+	// GatewayURL serves archived data by ID.
+	GatewayURL string
+	// Wallet is the JWK of the RSA-4096 wallet that signs uploads.
+	Wallet []byte
+}
+
+// ArweaveUploader archives data permanently on Arweave. Data is signed as
+// an ANS-104 data item with the configured wallet and posted to a bundler,
+// so uploads are accepted immediately rather than waiting for a block, and
+// is read back through a gateway. Anything archived is public and can never
+// be deleted.
+type ArweaveUploader struct {
+	key     *rsa.PrivateKey
+	owner   []byte
+	bundler string
+	gateway string
+	client  *http.Client
+}
+
+// NewArweaveUploader creates an uploader signing with cfg.Wallet.
+func NewArweaveUploader(cfg ArweaveConfig) (*ArweaveUploader, error) {
+	key, err := parseArweaveJWK(cfg.Wallet)
+	if err != nil {
+		return nil, fmt.Errorf("invalid arweave wallet: %w", err)
+	}
+	bundler := strings.TrimSuffix(cfg.BundlerURL, "/")
+	if bundler == "" {
+		bundler = defaultArweaveBundlerURL
+	}
+	gateway := strings.TrimSuffix(cfg.GatewayURL, "/")
+	if gateway == "" {
+		gateway = defaultArweaveGatewayURL
+	}
+	return &ArweaveUploader{
+		key:     key,
+		owner:   key.N.FillBytes(make([]byte, arweaveKeySize)),
+		bundler: bundler,
+		gateway: gateway,
+		// Archived originals can be many gigabytes; callers bound uploads
+		// with their context.
+		client: &http.Client{},
+	}, nil
+}
+
+// Address returns the wallet address uploads are signed by.
+func (u *ArweaveUploader) Address() string {
+	sum := sha256.Sum256(u.owner)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Upload archives size bytes and returns the data item ID they can be
+// retrieved by. The data is read twice, once to sign it and once to send
+// it, so open is called once for each pass and must return the same bytes
+// both times.
+func (u *ArweaveUploader) Upload(ctx context.Context, open func() (io.ReadCloser, error), size int64, tags []ArweaveTag) (string, error) {
+	if size < 0 {
+		return "", errors.New("arweave upload requires the data size")
+	}
+	rawTags, err := encodeArweaveTags(tags)
+	if err != nil {
+		return "", err
+	}
+
+	dataHash, err := u.hashData(ctx, open, size)
+	if err != nil {
+		return "", err
+	}
+	message := deepHashList(
+		deepHashBlob([]byte("dataitem")),
+		deepHashBlob([]byte("1")),
+		deepHashBlob([]byte(strconv.Itoa(arweaveSignatureType))),
+		deepHashBlob(u.owner),
+		deepHashBlob(nil), // target
+		deepHashBlob(nil), // anchor
+		deepHashBlob(rawTags),
+		dataHash,
+	)
+	digest := sha256.Sum256(message)
+	signature, err := rsa.SignPSS(rand.Reader, u.key, crypto.SHA256, digest[:],
+		&rsa.PSSOptions{SaltLength: 32, Hash: crypto.SHA256})
+	if err != nil {
+		return "", fmt.Errorf("failed to sign data item: %w", err)
+	}
+	idSum := sha256.Sum256(signature)
+	id := base64.RawURLEncoding.EncodeToString(idSum[:])
+
+	header := new(bytes.Buffer)
+	_ = binary.Write(header, binary.LittleEndian, uint16(arweaveSignatureType))
+	header.Write(signature)
+	header.Write(u.owner)
+	header.Write([]byte{0, 0}) // no target, no anchor
+	_ = binary.Write(header, binary.LittleEndian, uint64(len(tags)))
+	_ = binary.Write(header, binary.LittleEndian, uint64(len(rawTags)))
+	header.Write(rawTags)
+
+	rc, err := open()
+	if err != nil {
+		return "", fmt.Errorf("failed to reopen data: %w", err)
+	}
+	defer func() { _ = rc.Close() }()
+	body := io.MultiReader(header, &ctxReader{ctx: ctx, r: io.LimitReader(rc, size)})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.bundler, body)
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = int64(header.Len()) + size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to post data item: %w", err)
+	}
+	defer drainClose(resp)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusAccepted {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("bundler rejected data item: status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	var receipt struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&receipt); err == nil && receipt.ID != "" && receipt.ID != id {
+		return "", fmt.Errorf("bundler acknowledged data item %s, expected %s", receipt.ID, id)
+	}
+	return id, nil
+}
+
+// hashData returns the deep hash of the data, checking it is size bytes.
+func (u *ArweaveUploader) hashData(ctx context.Context, open func() (io.ReadCloser, error), size int64) ([]byte, error) {
+	rc, err := open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open data: %w", err)
+	}
+	defer func() { _ = rc.Close() }()
+	h := sha512.New384()
+	n, err := io.Copy(h, &ctxReader{ctx: ctx, r: io.LimitReader(rc, size+1)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data: %w", err)
+	}
+	if n != size {
+		return nil, fmt.Errorf("data is %d bytes, expected %d", n, size)
+	}
+	return deepHashBlobSum(size, h), nil
+}
+
+// Download opens archived data by ID through the gateway. The caller must
+// close it.
+func (u *ArweaveUploader) Download(ctx context.Context, id string) (io.ReadCloser, error) {
+	if !arweaveIDPattern.MatchString(id) {
+		return nil, fmt.Errorf("invalid arweave id %q", id)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.gateway+"/"+id, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", id, err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		drainClose(resp)
+		return nil, ErrObjectNotFound
+	default:
+		drainClose(resp)
+		return nil, fmt.Errorf("gateway returned %d for %s", resp.StatusCode, id)
+	}
+}
+
+// encodeArweaveTags serializes tags as the Avro array ANS-104 specifies.
+func encodeArweaveTags(tags []ArweaveTag) ([]byte, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	buf := new(bytes.Buffer)
+	writeAvroLong(buf, int64(len(tags)))
+	for _, t := range tags {
+		if t.Name == "" {
+			return nil, errors.New("arweave tag name must not be empty")
+		}
+		writeAvroLong(buf, int64(len(t.Name)))
+		buf.WriteString(t.Name)
+		writeAvroLong(buf, int64(len(t.Value)))
+		buf.WriteString(t.Value)
+	}
+	writeAvroLong(buf, 0)
+	return buf.Bytes(), nil
+}
+
+func writeAvroLong(buf *bytes.Buffer, v int64) {
+	var tmp [binary.MaxVarintLen64]byte
+	buf.Write(tmp[:binary.PutVarint(tmp[:], v)])
+}
+
+// deepHashBlob is the Arweave deep hash of a byte string.
+func deepHashBlob(data []byte) []byte {
+	h := sha512.New384()
+	h.Write(data)
+	return deepHashBlobSum(int64(len(data)), h)
+}
+
+// deepHashBlobSum finishes the deep hash of a byte string of the given
+// size whose contents were written to h.
+func deepHashBlobSum(size int64, h hash.Hash) []byte {
+	tag := sha512.Sum384([]byte("blob" + strconv.FormatInt(size, 10)))
+	return sha384(tag[:], h.Sum(nil))
+}
+
+// deepHashList is the Arweave deep hash of a list whose elements hash to
+// items.
+func deepHashList(items ...[]byte) []byte {
+	tag := sha512.Sum384([]byte("list" + strconv.Itoa(len(items))))
+	acc := tag[:]
+	for _, item := range items {
+		acc = sha384(acc, item)
+	}
+	return acc
+}
+
+func sha384(parts ...[]byte) []byte {
+	h := sha512.New384()
+	for _, p := range parts {
+		h.Write(p)
+	}
+	return h.Sum(nil)
+}
+
+// parseArweaveJWK reads an Arweave wallet keyfile.
+func parseArweaveJWK(data []byte) (*rsa.PrivateKey, error) {
+	var jwk struct {
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+		D   string `json:"d"`
+		P   string `json:"p"`
+		Q   string `json:"q"`
+	}
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return nil, err
+	}
+	if jwk.Kty != "RSA" {
+		return nil, fmt.Errorf("key type %q is not RSA", jwk.Kty)
+	}
+	var ints [5]*big.Int
+	for i, s := range []string{jwk.N, jwk.E, jwk.D, jwk.P, jwk.Q} {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil || len(b) == 0 {
+			return nil, errors.New("missing or malformed key parameter")
+		}
+		ints[i] = new(big.Int).SetBytes(b)
+	}
+	if !ints[1].IsInt64() || ints[1].Int64() > 1<<31-1 {
+		return nil, errors.New("public exponent out of range")
+	}
+	key := &rsa.PrivateKey{
+		PublicKey: rsa.PublicKey{N: ints[0], E: int(ints[1].Int64())},
+		D:         ints[2],
+		Primes:    []*big.Int{ints[3], ints[4]},
+	}
+	if key.N.BitLen() != arweaveKeySize*8 {
+		return nil, fmt.Errorf("key is %d bits, arweave wallets are %d", key.N.BitLen(), arweaveKeySize*8)
+	}
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+	key.Precompute()
+	return key, nil
+}
@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	arweaveTestKeyOnce sync.Once
+	arweaveTestKey     *rsa.PrivateKey
+)
+
+// testArweaveWallet returns a JWK for a 4096-bit key, generated once since
+// key generation is slow.
+func testArweaveWallet(t *testing.T) []byte {
+	t.Helper()
+	arweaveTestKeyOnce.Do(func() {
+		key, err := rsa.GenerateKey(rand.Reader, 4096)
+		if err != nil {
+			panic(err)
+		}
+		arweaveTestKey = key
+	})
+	enc := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+	wallet, err := json.Marshal(map[string]string{
+		"kty": "RSA",
+		"n":   enc(arweaveTestKey.N),
+		"e":   enc(big.NewInt(int64(arweaveTestKey.E))),
+		"d":   enc(arweaveTestKey.D),
+		"p":   enc(arweaveTestKey.Primes[0]),
+		"q":   enc(arweaveTestKey.Primes[1]),
+	})
+	require.NoError(t, err)
+	return wallet
+}
+
+// fakeBundler parses posted data items, verifies their signatures and
+// serves their data back as a gateway would.
+type fakeBundler struct {
+	mu    sync.Mutex
+	items map[string][]byte
+	tags  map[string][]byte
+}
+
+func (f *fakeBundler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Method == http.MethodGet {
+		data, ok := f.items[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	if len(body) < 2+2*arweaveKeySize+2+16 || binary.LittleEndian.Uint16(body) != arweaveSignatureType {
+		http.Error(w, "malformed data item", http.StatusBadRequest)
+		return
+	}
+	sig := body[2 : 2+arweaveKeySize]
+	owner := body[2+arweaveKeySize : 2+2*arweaveKeySize]
+	rest := body[2+2*arweaveKeySize+2:]
+	tagBytes := binary.LittleEndian.Uint64(rest[8:16])
+	rawTags, data := rest[16:16+tagBytes], rest[16+tagBytes:]
+
+	message := deepHashList(
+		deepHashBlob([]byte("dataitem")), deepHashBlob([]byte("1")), deepHashBlob([]byte("1")),
+		deepHashBlob(owner), deepHashBlob(nil), deepHashBlob(nil), deepHashBlob(rawTags), deepHashBlob(data))
+	digest := sha256.Sum256(message)
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(owner), E: 65537}
+	if err := rsa.VerifyPSS(pub, crypto.SHA256, digest[:], sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto}); err != nil {
+		http.Error(w, "invalid signature", http.StatusBadRequest)
+		return
+	}
+	idSum := sha256.Sum256(sig)
+	id := base64.RawURLEncoding.EncodeToString(idSum[:])
+	f.items[id] = data
+	f.tags[id] = rawTags
+	_ = json.NewEncoder(w).Encode(map[string]string{"id": id})
+}
+
+func newFakeArweave(t *testing.T) (*ArweaveUploader, *fakeBundler) {
+	t.Helper()
+	fake := &fakeBundler{items: make(map[string][]byte), tags: make(map[string][]byte)}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	u, err := NewArweaveUploader(ArweaveConfig{BundlerURL: srv.URL + "/tx", GatewayURL: srv.URL, Wallet: testArweaveWallet(t)})
+	require.NoError(t, err)
+	return u, fake
+}
+
+func opener(data []byte) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(data)), nil }
+}
+
+func TestArweaveUploader_RoundTrip(t *testing.T) {
+	u, fake := newFakeArweave(t)
+	ctx := context.Background()
+	data := bytes.Repeat([]byte("master"), 10000)
+
+	id, err := u.Upload(ctx, opener(data), int64(len(data)), []ArweaveTag{
+		{Name: "Content-Type", Value: "video/mp4"},
+		{Name: "Content-Id", Value: "c1"},
+	})
+	require.NoError(t, err)
+	assert.Regexp(t, arweaveIDPattern, id)
+	assert.Equal(t, []byte("\x04\x18Content-Type\x12video/mp4\x14Content-Id\x04c1\x00"), fake.tags[id])
+
+	rc, err := u.Download(ctx, id)
+	require.NoError(t, err)
+	got, err := io.ReadAll(rc)
+	_ = rc.Close()
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+
+	_, err = u.Download(ctx, strings.Repeat("A", 43))
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+	_, err = u.Download(ctx, "../admin")
+	assert.Error(t, err)
+}
+
+func TestArweaveUploader_RejectsWrongSize(t *testing.T) {
+	u, fake := newFakeArweave(t)
+
+	_, err := u.Upload(context.Background(), opener([]byte("short")), 10, nil)
+	assert.ErrorContains(t, err, "expected 10")
+	_, err = u.Upload(context.Background(), opener([]byte("data")), -1, nil)
+	assert.Error(t, err)
+	assert.Empty(t, fake.items)
+}
+
+func TestDeepHash(t *testing.T) {
+	blob := func(b string) []byte {
+		tag := sha512.Sum384([]byte("blob" + string(rune('0'+len(b)))))
+		data := sha512.Sum384([]byte(b))
+		return sha384(tag[:], data[:])
+	}
+	assert.Equal(t, blob("abc"), deepHashBlob([]byte("abc")))
+
+	listTag := sha512.Sum384([]byte("list2"))
+	want := sha384(sha384(listTag[:], blob("a")), blob("bc"))
+	assert.Equal(t, want, deepHashList(deepHashBlob([]byte("a")), deepHashBlob([]byte("bc"))))
+}
+
+func TestNewArweaveUploader_Validation(t *testing.T) {
+	small, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	enc := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+	smallJWK, _ := json.Marshal(map[string]string{
+		"kty": "RSA", "n": enc(small.N), "e": enc(big.NewInt(int64(small.E))),
+		"d": enc(small.D), "p": enc(small.Primes[0]), "q": enc(small.Primes[1]),
+	})
+
+	tests := []struct {
+		name   string
+		wallet []byte
+		errMsg string
+	}{
+		{"not json", []byte("nope"), "invalid arweave wallet"},
+		{"not rsa", []byte(`{"kty":"EC"}`), "not RSA"},
+		{"missing params", []byte(`{"kty":"RSA","n":"AQAB"}`), "missing or malformed"},
+		{"small key", smallJWK, "2048 bits"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewArweaveUploader(ArweaveConfig{Wallet: tt.wallet})
+			assert.ErrorContains(t, err, tt.errMsg)
+		})
+	}
+
+	u, err := NewArweaveUploader(ArweaveConfig{Wallet: testArweaveWallet(t)})
+	require.NoError(t, err)
+	assert.Len(t, u.Address(), 43)
+	assert.Equal(t, defaultArweaveBundlerURL, u.bundler)
+}
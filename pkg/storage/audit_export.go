@@ -0,0 +1,416 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// AuditFormat is the wire format of exported audit batches.
+type AuditFormat string
+
+const (
+	// AuditFormatJSONLines writes one JSON object per line.
+	AuditFormatJSONLines AuditFormat = "jsonl"
+	// AuditFormatCEF writes one ArcSight Common Event Format record per line.
+	AuditFormatCEF AuditFormat = "cef"
+)
+
+const (
+	defaultAuditBatchSize     = 100
+	defaultAuditFlushInterval = 5 * time.Second
+	defaultAuditMaxRetries    = 3
+	defaultAuditRetryBackoff  = 500 * time.Millisecond
+	defaultAuditMaxPending    = 100
+	auditShipTimeout          = 30 * time.Second
+)
+
+// ErrAuditSinkRejected marks a sink error that retrying cannot fix, such as
+// an HTTP 400 from a SIEM collector. Sinks wrap it with %w.
+var ErrAuditSinkRejected = errors.New("audit batch rejected by sink")
+
+// AuditEvent is an exported audit record. Seq increases by one per event
+// an exporter accepts, so receivers can de-duplicate redelivered batches.
+type AuditEvent struct {
+	Seq        uint64    `json:"seq"`
+	Timestamp  time.Time `json:"timestamp"`
+	Action     string    `json:"action"`
+	Actor      string    `json:"actor"`
+	Resource   string    `json:"resource"`
+	ResourceID string    `json:"resource_id"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	Details    string    `json:"details,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	TraceID    string    `json:"trace_id,omitempty"`
+}
+
+// AuditBatch is an encoded run of consecutive events handed to a sink.
+type AuditBatch struct {
+	FirstSeq uint64
+	LastSeq  uint64
+	Count    int
+	Format   AuditFormat
+	Payload  []byte
+}
+
+// AuditSink ships encoded audit batches to an external system. Ship may be
+// called again with the same batch after an error, so sinks should write
+// idempotently where they can.
+type AuditSink interface {
+	Ship(ctx context.Context, batch AuditBatch) error
+	Close() error
+}
+
+// AuditExporterConfig tunes batching and retry. Zero values use defaults.
+type AuditExporterConfig struct {
+	Format AuditFormat
+	// BatchSize flushes a batch once this many events are buffered.
+	BatchSize int
+	// FlushInterval flushes a partial batch after this long.
+	FlushInterval time.Duration
+	// MaxRetries is the number of extra Ship attempts per flush for
+	// transient errors; negative disables retries. The backoff doubles
+	// after each attempt.
+	MaxRetries   int
+	RetryBackoff time.Duration
+	// MaxPendingBatches bounds undelivered batches kept for redelivery;
+	// the oldest are dropped beyond it.
+	MaxPendingBatches int
+}
+
+func (c AuditExporterConfig) withDefaults() AuditExporterConfig {
+	if c.Format == "" {
+		c.Format = AuditFormatJSONLines
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaultAuditBatchSize
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = defaultAuditFlushInterval
+	}
+	if c.MaxRetries < 0 {
+		c.MaxRetries = 0
+	} else if c.MaxRetries == 0 {
+		c.MaxRetries = defaultAuditMaxRetries
+	}
+	if c.RetryBackoff <= 0 {
+		c.RetryBackoff = defaultAuditRetryBackoff
+	}
+	if c.MaxPendingBatches <= 0 {
+		c.MaxPendingBatches = defaultAuditMaxPending
+	}
+	return c
+}
+
+// AuditExportStats reports delivery progress.
+type AuditExportStats struct {
+	Logged    uint64
+	Delivered uint64
+	// Dropped counts events lost to a full buffer, a rejected batch, or
+	// pending-batch overflow.
+	Dropped uint64
+	// PendingBatches are encoded but not yet acknowledged by the sink.
+	PendingBatches int
+	// LastDeliveredSeq is the Seq of the newest event the sink accepted.
+	LastDeliveredSeq uint64
+}
+
+// AuditExporter batches audit events and ships them to an AuditSink with
+// at-least-once delivery: a batch stays pending until the sink accepts it
+// and is retried on later flushes, in order. It satisfies AuditLogger.
+type AuditExporter struct {
+	sink   AuditSink
+	cfg    AuditExporterConfig
+	logger *zap.Logger
+	ch     chan AuditEvent
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// seq, buf and pending are owned by the worker goroutine.
+	seq     uint64
+	buf     []AuditEvent
+	pending []AuditBatch
+
+	mu    sync.Mutex
+	stats AuditExportStats
+}
+
+// NewAuditExporter creates an exporter for sink. Call Start to begin
+// shipping and Close to flush and stop.
+func NewAuditExporter(sink AuditSink, cfg AuditExporterConfig, logger *zap.Logger) (*AuditExporter, error) {
+	if sink == nil {
+		return nil, fmt.Errorf("audit exporter requires a sink")
+	}
+	cfg = cfg.withDefaults()
+	if cfg.Format != AuditFormatJSONLines && cfg.Format != AuditFormatCEF {
+		return nil, fmt.Errorf("unsupported audit format %q", cfg.Format)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &AuditExporter{
+		sink:   sink,
+		cfg:    cfg,
+		logger: logger,
+		ch:     make(chan AuditEvent, auditBufferSize),
+		ctx:    ctx,
+		cancel: cancel,
+	}, nil
+}
+
+func (e *AuditExporter) Start() {
+	e.wg.Add(1)
+	go e.worker()
+}
+
+func (e *AuditExporter) Log(ctx context.Context, action, actor, resource, resourceID string, success bool, errMsg, details string) {
+	event := AuditEvent{
+		Timestamp:  time.Now().UTC(),
+		Action:     action,
+		Actor:      actor,
+		Resource:   resource,
+		ResourceID: resourceID,
+		Success:    success,
+		Error:      errMsg,
+		Details:    details,
+		IP:         auditClientIP(ctx),
+		TraceID:    auditTraceID(ctx),
+	}
+
+	select {
+	case e.ch <- event:
+		e.mu.Lock()
+		e.stats.Logged++
+		e.mu.Unlock()
+	default:
+		e.mu.Lock()
+		e.stats.Dropped++
+		e.mu.Unlock()
+		e.logger.Warn("audit export buffer full, dropping entry",
+			zap.String("action", action),
+			zap.String("actor", actor))
+	}
+}
+
+// Stats returns a snapshot of delivery progress.
+func (e *AuditExporter) Stats() AuditExportStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.stats
+}
+
+func (e *AuditExporter) worker() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.ctx.Done():
+			e.drain()
+			e.flush()
+			return
+		case event := <-e.ch:
+			e.buffer(event)
+			if len(e.buf) >= e.cfg.BatchSize {
+				e.flush()
+			}
+		case <-ticker.C:
+			e.flush()
+		}
+	}
+}
+
+func (e *AuditExporter) drain() {
+	for {
+		select {
+		case event := <-e.ch:
+			e.buffer(event)
+			if len(e.buf) >= e.cfg.BatchSize {
+				e.enqueueBatch()
+			}
+		default:
+			return
+		}
+	}
+}
+
+// buffer numbers event in arrival order and adds it to the open batch.
+func (e *AuditExporter) buffer(event AuditEvent) {
+	e.seq++
+	event.Seq = e.seq
+	e.buf = append(e.buf, event)
+}
+
+// flush encodes buffered events into a batch, then delivers pending
+// batches oldest first, stopping at the first that cannot be delivered.
+func (e *AuditExporter) flush() {
+	e.enqueueBatch()
+	for len(e.pending) > 0 {
+		batch := e.pending[0]
+		err := e.ship(batch)
+		if err != nil && !errors.Is(err, ErrAuditSinkRejected) {
+			e.logger.Warn("audit batch delivery failed, will retry on next flush",
+				zap.Uint64("first_seq", batch.FirstSeq),
+				zap.Uint64("last_seq", batch.LastSeq),
+				zap.Error(err))
+			break
+		}
+		e.pending = e.pending[1:]
+
+		e.mu.Lock()
+		if err != nil {
+			e.stats.Dropped += uint64(batch.Count)
+		} else {
+			e.stats.Delivered += uint64(batch.Count)
+			e.stats.LastDeliveredSeq = batch.LastSeq
+		}
+		e.stats.PendingBatches = len(e.pending)
+		e.mu.Unlock()
+
+		if err != nil {
+			e.logger.Error("audit batch rejected by sink, dropping",
+				zap.Uint64("first_seq", batch.FirstSeq),
+				zap.Uint64("last_seq", batch.LastSeq),
+				zap.Error(err))
+		}
+	}
+}
+
+func (e *AuditExporter) enqueueBatch() {
+	if len(e.buf) == 0 {
+		return
+	}
+	events := e.buf
+	e.buf = nil
+
+	payload, err := EncodeAuditEvents(e.cfg.Format, events)
+	if err != nil {
+		e.logger.Error("failed to encode audit batch", zap.Error(err))
+		e.mu.Lock()
+		e.stats.Dropped += uint64(len(events))
+		e.mu.Unlock()
+		return
+	}
+	e.pending = append(e.pending, AuditBatch{
+		FirstSeq: events[0].Seq,
+		LastSeq:  events[len(events)-1].Seq,
+		Count:    len(events),
+		Format:   e.cfg.Format,
+		Payload:  payload,
+	})
+
+	var overflow int
+	for len(e.pending) > e.cfg.MaxPendingBatches {
+		overflow += e.pending[0].Count
+		e.pending = e.pending[1:]
+	}
+	e.mu.Lock()
+	e.stats.Dropped += uint64(overflow)
+	e.stats.PendingBatches = len(e.pending)
+	e.mu.Unlock()
+	if overflow > 0 {
+		e.logger.Error("audit export backlog full, dropped oldest events", zap.Int("events", overflow))
+	}
+}
+
+// ship sends one batch, retrying transient errors with exponential backoff.
+func (e *AuditExporter) ship(batch AuditBatch) error {
+	backoff := e.cfg.RetryBackoff
+	var err error
+	for attempt := 0; attempt <= e.cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), auditShipTimeout)
+		err = e.sink.Ship(ctx, batch)
+		cancel()
+		if err == nil || errors.Is(err, ErrAuditSinkRejected) {
+			return err
+		}
+	}
+	return err
+}
+
+// Close flushes buffered events, makes a final delivery attempt for
+// pending batches, and closes the sink.
+func (e *AuditExporter) Close() error {
+	e.cancel()
+	e.wg.Wait()
+	return e.sink.Close()
+}
+
+// EncodeAuditEvents renders events in format, one record per line.
+func EncodeAuditEvents(format AuditFormat, events []AuditEvent) ([]byte, error) {
+	var b bytes.Buffer
+	switch format {
+	case AuditFormatJSONLines:
+		enc := json.NewEncoder(&b)
+		for _, ev := range events {
+			if err := enc.Encode(ev); err != nil {
+				return nil, fmt.Errorf("encode audit event %d: %w", ev.Seq, err)
+			}
+		}
+	case AuditFormatCEF:
+		for _, ev := range events {
+			b.WriteString(formatCEF(ev))
+			b.WriteByte('\n')
+		}
+	default:
+		return nil, fmt.Errorf("unsupported audit format %q", format)
+	}
+	return b.Bytes(), nil
+}
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`)
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r", `\r`, "\n", `\n`)
+)
+
+// formatCEF renders ev as a CEF:0 record. Failed actions are severity 7,
+// successful ones 3.
+func formatCEF(ev AuditEvent) string {
+	severity, outcome := 3, "success"
+	if !ev.Success {
+		severity, outcome = 7, "failure"
+	}
+	ext := []string{
+		"rt=" + strconv.FormatInt(ev.Timestamp.UnixMilli(), 10),
+		"suser=" + cefExtensionEscaper.Replace(ev.Actor),
+		"act=" + cefExtensionEscaper.Replace(ev.Action),
+		"outcome=" + outcome,
+		"cs1Label=resource",
+		"cs1=" + cefExtensionEscaper.Replace(ev.Resource),
+		"cs2Label=resourceId",
+		"cs2=" + cefExtensionEscaper.Replace(ev.ResourceID),
+		"cn1Label=seq",
+		"cn1=" + strconv.FormatUint(ev.Seq, 10),
+	}
+	if ev.IP != "" {
+		ext = append(ext, "src="+cefExtensionEscaper.Replace(ev.IP))
+	}
+	if ev.TraceID != "" {
+		ext = append(ext, "cs3Label=traceId", "cs3="+ev.TraceID)
+	}
+	if ev.Error != "" {
+		ext = append(ext, "reason="+cefExtensionEscaper.Replace(ev.Error))
+	}
+	if ev.Details != "" {
+		ext = append(ext, "msg="+cefExtensionEscaper.Replace(ev.Details))
+	}
+	return fmt.Sprintf("CEF:0|StreamGate|StreamGate|1.0|%s|%s|%d|%s",
+		cefHeaderEscaper.Replace(ev.Action),
+		cefHeaderEscaper.Replace(ev.Action),
+		severity,
+		strings.Join(ext, " "))
+}
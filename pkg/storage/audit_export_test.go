@@ -0,0 +1,392 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var _ AuditLogger = (*AuditExporter)(nil)
+
+// fakeAuditSink records accepted batches. fail, when set, is consulted on
+// every Ship call and its error returned instead of accepting the batch.
+type fakeAuditSink struct {
+	mu       sync.Mutex
+	batches  []AuditBatch
+	attempts int
+	fail     func(attempt int) error
+	closed   bool
+}
+
+func (s *fakeAuditSink) Ship(_ context.Context, batch AuditBatch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	if s.fail != nil {
+		if err := s.fail(s.attempts); err != nil {
+			return err
+		}
+	}
+	s.batches = append(s.batches, batch)
+	return nil
+}
+
+func (s *fakeAuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func (s *fakeAuditSink) snapshot() ([]AuditBatch, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]AuditBatch(nil), s.batches...), s.attempts
+}
+
+func decodeJSONLines(t *testing.T, payload []byte) []AuditEvent {
+	t.Helper()
+	var events []AuditEvent
+	sc := bufio.NewScanner(bytes.NewReader(payload))
+	for sc.Scan() {
+		var ev AuditEvent
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &ev))
+		events = append(events, ev)
+	}
+	require.NoError(t, sc.Err())
+	return events
+}
+
+func logAuditEvents(e *AuditExporter, n int) {
+	for i := 0; i < n; i++ {
+		e.Log(context.Background(), "content.create", "0xabc", "content", fmt.Sprintf("c%d", i), true, "", "")
+	}
+}
+
+func TestNewAuditExporter_Validation(t *testing.T) {
+	_, err := NewAuditExporter(nil, AuditExporterConfig{}, zap.NewNop())
+	assert.Error(t, err)
+
+	_, err = NewAuditExporter(&fakeAuditSink{}, AuditExporterConfig{Format: "xml"}, zap.NewNop())
+	assert.Error(t, err)
+
+	e, err := NewAuditExporter(&fakeAuditSink{}, AuditExporterConfig{MaxRetries: -1}, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, AuditFormatJSONLines, e.cfg.Format)
+	assert.Equal(t, defaultAuditBatchSize, e.cfg.BatchSize)
+	assert.Zero(t, e.cfg.MaxRetries)
+}
+
+func TestAuditExporter_Batching(t *testing.T) {
+	sink := &fakeAuditSink{}
+	e, err := NewAuditExporter(sink, AuditExporterConfig{BatchSize: 3, FlushInterval: time.Hour}, zap.NewNop())
+	require.NoError(t, err)
+	e.Start()
+
+	logAuditEvents(e, 7)
+	require.Eventually(t, func() bool {
+		batches, _ := sink.snapshot()
+		return len(batches) == 2
+	}, 2*time.Second, 5*time.Millisecond, "full batches ship without waiting for the interval")
+
+	require.NoError(t, e.Close())
+	assert.True(t, sink.closed)
+
+	batches, _ := sink.snapshot()
+	require.Len(t, batches, 3, "Close flushes the partial batch")
+	var seq uint64
+	for i, want := range []int{3, 3, 1} {
+		b := batches[i]
+		assert.Equal(t, want, b.Count)
+		assert.Equal(t, AuditFormatJSONLines, b.Format)
+		assert.Equal(t, seq+1, b.FirstSeq)
+
+		events := decodeJSONLines(t, b.Payload)
+		require.Len(t, events, want)
+		for _, ev := range events {
+			seq++
+			assert.Equal(t, seq, ev.Seq)
+			assert.Equal(t, "content.create", ev.Action)
+			assert.Equal(t, "0xabc", ev.Actor)
+		}
+		assert.Equal(t, seq, b.LastSeq)
+	}
+
+	stats := e.Stats()
+	assert.Equal(t, uint64(7), stats.Logged)
+	assert.Equal(t, uint64(7), stats.Delivered)
+	assert.Equal(t, uint64(7), stats.LastDeliveredSeq)
+	assert.Zero(t, stats.Dropped)
+	assert.Zero(t, stats.PendingBatches)
+}
+
+func TestAuditExporter_FlushInterval(t *testing.T) {
+	sink := &fakeAuditSink{}
+	e, err := NewAuditExporter(sink, AuditExporterConfig{BatchSize: 100, FlushInterval: 20 * time.Millisecond}, zap.NewNop())
+	require.NoError(t, err)
+	e.Start()
+	defer e.Close()
+
+	logAuditEvents(e, 2)
+	require.Eventually(t, func() bool {
+		batches, _ := sink.snapshot()
+		return len(batches) == 1 && batches[0].Count == 2
+	}, 2*time.Second, 5*time.Millisecond)
+}
+
+func TestAuditExporter_RetriesTransientFailure(t *testing.T) {
+	sink := &fakeAuditSink{fail: func(attempt int) error {
+		if attempt <= 2 {
+			return errors.New("connection reset by peer")
+		}
+		return nil
+	}}
+	e, err := NewAuditExporter(sink, AuditExporterConfig{
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+		MaxRetries:    3,
+		RetryBackoff:  time.Millisecond,
+	}, zap.NewNop())
+	require.NoError(t, err)
+	e.Start()
+
+	logAuditEvents(e, 2)
+	require.NoError(t, e.Close())
+
+	batches, attempts := sink.snapshot()
+	assert.Equal(t, 3, attempts)
+	require.Len(t, batches, 1, "the batch is delivered exactly once after retries")
+	assert.Equal(t, uint64(1), batches[0].FirstSeq)
+	assert.Equal(t, uint64(2), batches[0].LastSeq)
+	assert.Equal(t, uint64(2), e.Stats().Delivered)
+}
+
+func TestAuditExporter_PendingBatchRedeliveredInOrder(t *testing.T) {
+	var down sync.Mutex
+	outage := true
+	sink := &fakeAuditSink{fail: func(int) error {
+		down.Lock()
+		defer down.Unlock()
+		if outage {
+			return errors.New("503 service unavailable")
+		}
+		return nil
+	}}
+	e, err := NewAuditExporter(sink, AuditExporterConfig{
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+		MaxRetries:    -1,
+	}, zap.NewNop())
+	require.NoError(t, err)
+	e.Start()
+
+	logAuditEvents(e, 4)
+	require.Eventually(t, func() bool {
+		return e.Stats().PendingBatches == 2
+	}, 2*time.Second, 5*time.Millisecond)
+	assert.Zero(t, e.Stats().Delivered)
+
+	down.Lock()
+	outage = false
+	down.Unlock()
+	require.NoError(t, e.Close())
+
+	batches, _ := sink.snapshot()
+	require.Len(t, batches, 2)
+	assert.Equal(t, uint64(1), batches[0].FirstSeq)
+	assert.Equal(t, uint64(3), batches[1].FirstSeq)
+
+	stats := e.Stats()
+	assert.Equal(t, uint64(4), stats.Delivered)
+	assert.Equal(t, uint64(4), stats.LastDeliveredSeq)
+	assert.Zero(t, stats.PendingBatches)
+}
+
+func TestAuditExporter_RejectedBatchDropped(t *testing.T) {
+	sink := &fakeAuditSink{fail: func(attempt int) error {
+		if attempt == 1 {
+			return ErrAuditSinkRejected
+		}
+		return nil
+	}}
+	e, err := NewAuditExporter(sink, AuditExporterConfig{
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+		RetryBackoff:  time.Millisecond,
+	}, zap.NewNop())
+	require.NoError(t, err)
+	e.Start()
+
+	logAuditEvents(e, 3)
+	require.NoError(t, e.Close())
+
+	batches, attempts := sink.snapshot()
+	assert.Equal(t, 2, attempts, "rejected batches are not retried")
+	require.Len(t, batches, 1)
+	assert.Equal(t, uint64(3), batches[0].FirstSeq)
+
+	stats := e.Stats()
+	assert.Equal(t, uint64(2), stats.Dropped)
+	assert.Equal(t, uint64(1), stats.Delivered)
+}
+
+func TestAuditExporter_PendingOverflowDropsOldest(t *testing.T) {
+	sink := &fakeAuditSink{fail: func(int) error { return errors.New("timeout") }}
+	e, err := NewAuditExporter(sink, AuditExporterConfig{
+		BatchSize:         1,
+		FlushInterval:     time.Hour,
+		MaxRetries:        -1,
+		MaxPendingBatches: 2,
+	}, zap.NewNop())
+	require.NoError(t, err)
+	e.Start()
+
+	logAuditEvents(e, 5)
+	require.NoError(t, e.Close())
+
+	stats := e.Stats()
+	assert.Equal(t, uint64(3), stats.Dropped)
+	assert.Equal(t, 2, stats.PendingBatches)
+	assert.Zero(t, stats.Delivered)
+}
+
+func TestEncodeAuditEvents_CEF(t *testing.T) {
+	events := []AuditEvent{
+		{
+			Seq:        7,
+			Timestamp:  time.UnixMilli(1700000000123).UTC(),
+			Action:     "content|delete",
+			Actor:      "0xabc",
+			Resource:   "content",
+			ResourceID: "a=b",
+			Success:    false,
+			Error:      "not owner\nretry",
+			Details:    `path C:\tmp`,
+		},
+		{Seq: 8, Action: "auth.login", Actor: "0xdef", Resource: "session", ResourceID: "s1", Success: true},
+	}
+
+	payload, err := EncodeAuditEvents(AuditFormatCEF, events)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(payload), "\n"), "\n")
+	require.Len(t, lines, 2)
+
+	assert.Equal(t, `CEF:0|StreamGate|StreamGate|1.0|content\|delete|content\|delete|7|`+
+		`rt=1700000000123 suser=0xabc act=content|delete outcome=failure `+
+		`cs1Label=resource cs1=content cs2Label=resourceId cs2=a\=b cn1Label=seq cn1=7 `+
+		`reason=not owner\nretry msg=path C:\\tmp`, lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "CEF:0|StreamGate|StreamGate|1.0|auth.login|auth.login|3|"))
+	assert.Contains(t, lines[1], "outcome=success")
+	assert.NotContains(t, lines[1], "reason=")
+
+	_, err = EncodeAuditEvents("xml", events)
+	assert.Error(t, err)
+}
+
+func TestAuditExporter_CEFFormat(t *testing.T) {
+	sink := &fakeAuditSink{}
+	e, err := NewAuditExporter(sink, AuditExporterConfig{Format: AuditFormatCEF, BatchSize: 10}, zap.NewNop())
+	require.NoError(t, err)
+	e.Start()
+
+	logAuditEvents(e, 2)
+	require.NoError(t, e.Close())
+
+	batches, _ := sink.snapshot()
+	require.Len(t, batches, 1)
+	assert.Equal(t, AuditFormatCEF, batches[0].Format)
+	assert.Equal(t, 2, strings.Count(string(batches[0].Payload), "CEF:0|StreamGate|"))
+}
+
+func TestFileAuditSink_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := NewFileAuditSink(path, 10)
+	require.NoError(t, err)
+	sink.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	require.NoError(t, sink.Ship(context.Background(), AuditBatch{Payload: []byte("first\n")}))
+	require.NoError(t, sink.Ship(context.Background(), AuditBatch{Payload: []byte("second\n")}))
+	require.NoError(t, sink.Close())
+
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "second\n", string(current))
+
+	rotated, err := os.ReadFile(path + ".20260102T030405.000000000")
+	require.NoError(t, err)
+	assert.Equal(t, "first\n", string(rotated))
+}
+
+type fakeAuditUploader struct {
+	bucket, name, contentType string
+	data                      []byte
+	err                       error
+}
+
+func (u *fakeAuditUploader) UploadWithContentType(_ context.Context, bucket, objectName string, data []byte, contentType string) error {
+	u.bucket, u.name, u.data, u.contentType = bucket, objectName, data, contentType
+	return u.err
+}
+
+func TestObjectAuditSink_Ship(t *testing.T) {
+	up := &fakeAuditUploader{}
+	sink := NewObjectAuditSink(up, "compliance", "audit/streamgate")
+	sink.now = func() time.Time { return time.Date(2026, 3, 9, 12, 0, 0, 0, time.UTC) }
+
+	batch := AuditBatch{FirstSeq: 1, LastSeq: 100, Count: 100, Format: AuditFormatJSONLines, Payload: []byte("{}\n")}
+	require.NoError(t, sink.Ship(context.Background(), batch))
+	assert.Equal(t, "compliance", up.bucket)
+	assert.Equal(t, "audit/streamgate/2026/03/09/audit-00000000000000000001-00000000000000000100.jsonl", up.name)
+	assert.Equal(t, "application/x-ndjson", up.contentType)
+	assert.Equal(t, batch.Payload, up.data)
+
+	up.err = errors.New("connection refused")
+	assert.Error(t, sink.Ship(context.Background(), batch))
+	assert.NoError(t, sink.Close())
+}
+
+func TestHTTPAuditSink_StatusClassification(t *testing.T) {
+	var status int
+	var gotHeader http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Clone()
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	sink := NewHTTPAuditSink(srv.URL, map[string]string{"Authorization": "Splunk token"}, srv.Client())
+	defer sink.Close()
+	batch := AuditBatch{FirstSeq: 5, LastSeq: 9, Format: AuditFormatCEF, Payload: []byte("CEF:0|...\n")}
+
+	status = http.StatusOK
+	require.NoError(t, sink.Ship(context.Background(), batch))
+	assert.Equal(t, "5", gotHeader.Get("X-Audit-First-Seq"))
+	assert.Equal(t, "9", gotHeader.Get("X-Audit-Last-Seq"))
+	assert.Equal(t, "Splunk token", gotHeader.Get("Authorization"))
+	assert.Equal(t, "text/plain; charset=utf-8", gotHeader.Get("Content-Type"))
+
+	for _, code := range []int{http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusRequestTimeout} {
+		status = code
+		err := sink.Ship(context.Background(), batch)
+		require.Error(t, err)
+		assert.False(t, errors.Is(err, ErrAuditSinkRejected), code)
+	}
+
+	status = http.StatusBadRequest
+	assert.ErrorIs(t, sink.Ship(context.Background(), batch), ErrAuditSinkRejected)
+}
@@ -2,17 +2,52 @@ package storage
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/rtcdance/streamgate/pkg/core/config"
+	"github.com/rtcdance/streamgate/pkg/models"
+
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
 const (
 	auditBufferSize  = 1024
-	auditInsertQuery = `INSERT INTO audit_logs (action, actor, resource, resource_id, success, error_msg, details, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	auditInsertQuery = `INSERT INTO audit_logs (action, actor, resource, resource_id, success, error_msg, details, ip, trace_id, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	auditSelectQuery = `SELECT id, action, actor, resource, resource_id, success, error_msg, details, ip, trace_id, created_at FROM audit_logs`
+	// audit_logs rejects deletes outside a transaction that sets this.
+	auditPurgeGuard = `SET LOCAL streamgate.audit_purge = 'on'`
+
+	auditPurgeInterval   = time.Hour
+	defaultAuditLimit    = 100
+	maxAuditQueryResults = 1000
 )
 
+type auditClientIPKey struct{}
+
+// WithAuditClientIP returns ctx carrying the client IP that audit entries
+// logged with it record.
+func WithAuditClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, auditClientIPKey{}, ip)
+}
+
+func auditClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(auditClientIPKey{}).(string)
+	return ip
+}
+
+// auditTraceID returns the trace ID of the span in ctx, if any.
+func auditTraceID(ctx context.Context) string {
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+	return ""
+}
+
 type auditEntry struct {
 	action     string
 	actor      string
@@ -21,16 +56,37 @@ type auditEntry struct {
 	success    bool
 	errMsg     string
 	details    string
+	ip         string
+	traceID    string
 	timestamp  time.Time
 }
 
+// AuditQuery filters audit log queries. Empty fields match every entry.
+type AuditQuery struct {
+	Actor      string
+	Action     string
+	Resource   string
+	ResourceID string
+	Success    *bool
+	Since      time.Time
+	Until      time.Time
+	Limit      int
+	Offset     int
+}
+
+// PostgresAuditLogger appends audit entries to the audit_logs table from a
+// buffered background worker, so logging never blocks a request. The
+// table is append-only; entries older than the retention set with
+// SetRetention are purged hourly.
 type PostgresAuditLogger struct {
-	db     *PostgresDB
-	logger *zap.Logger
-	ch     chan auditEntry
-	wg     sync.WaitGroup
-	ctx    context.Context
-	cancel context.CancelFunc
+	db        *PostgresDB
+	logger    *zap.Logger
+	ch        chan auditEntry
+	retention time.Duration
+	exporter  *AuditExporter
+	wg        sync.WaitGroup
+	ctx       context.Context
+	cancel    context.CancelFunc
 }
 
 func NewPostgresAuditLogger(db *PostgresDB, logger *zap.Logger) *PostgresAuditLogger {
@@ -44,11 +100,25 @@ func NewPostgresAuditLogger(db *PostgresDB, logger *zap.Logger) *PostgresAuditLo
 	}
 }
 
+// SetRetention makes the worker purge entries older than retention. Zero
+// keeps entries forever. Call it before Start.
+func (al *PostgresAuditLogger) SetRetention(retention time.Duration) {
+	al.retention = retention
+}
+
+// SetExporter makes Log also hand every entry to e, which ships it to an
+// external sink. Call it before Start; Close does not close e.
+func (al *PostgresAuditLogger) SetExporter(e *AuditExporter) {
+	al.exporter = e
+}
+
 func (al *PostgresAuditLogger) Start() {
 	al.wg.Add(1)
 	go al.worker()
 }
 
+// Log queues an entry. The client IP set with WithAuditClientIP and the
+// trace ID of the span in ctx are recorded with it.
 func (al *PostgresAuditLogger) Log(ctx context.Context, action, actor, resource, resourceID string, success bool, errMsg, details string) {
 	entry := auditEntry{
 		action:     action,
@@ -58,8 +128,13 @@ func (al *PostgresAuditLogger) Log(ctx context.Context, action, actor, resource,
 		success:    success,
 		errMsg:     errMsg,
 		details:    details,
+		ip:         auditClientIP(ctx),
+		traceID:    auditTraceID(ctx),
 		timestamp:  time.Now(),
 	}
+	if al.exporter != nil {
+		al.exporter.Log(ctx, action, actor, resource, resourceID, success, errMsg, details)
+	}
 
 	select {
 	case al.ch <- entry:
@@ -73,6 +148,14 @@ func (al *PostgresAuditLogger) Log(ctx context.Context, action, actor, resource,
 func (al *PostgresAuditLogger) worker() {
 	defer al.wg.Done()
 
+	var purge <-chan time.Time
+	if al.retention > 0 {
+		ticker := time.NewTicker(auditPurgeInterval)
+		defer ticker.Stop()
+		purge = ticker.C
+		al.purgeExpired()
+	}
+
 	for {
 		select {
 		case <-al.ctx.Done():
@@ -80,6 +163,8 @@ func (al *PostgresAuditLogger) worker() {
 			return
 		case entry := <-al.ch:
 			al.persist(entry)
+		case <-purge:
+			al.purgeExpired()
 		}
 	}
 }
@@ -100,6 +185,8 @@ func (al *PostgresAuditLogger) persist(entry auditEntry) {
 		entry.success,
 		entry.errMsg,
 		entry.details,
+		entry.ip,
+		entry.traceID,
 		entry.timestamp,
 	)
 	if err != nil {
@@ -109,6 +196,130 @@ func (al *PostgresAuditLogger) persist(entry auditEntry) {
 	}
 }
 
+func (al *PostgresAuditLogger) purgeExpired() {
+	ctx, cancel := context.WithTimeout(al.ctx, 30*time.Second)
+	defer cancel()
+
+	n, err := al.Purge(ctx, time.Now().Add(-al.retention))
+	if err != nil {
+		al.logger.Warn("failed to purge expired audit logs", zap.Error(err))
+		return
+	}
+	if n > 0 {
+		al.logger.Info("purged expired audit logs",
+			zap.Int64("deleted", n),
+			zap.Duration("retention", al.retention))
+	}
+}
+
+// Purge deletes the entries created before cutoff and returns how many
+// were deleted. It is the only way entries leave the table.
+func (al *PostgresAuditLogger) Purge(ctx context.Context, cutoff time.Time) (int64, error) {
+	if al.db == nil || al.db.db == nil {
+		return 0, nil
+	}
+	var deleted int64
+	err := al.db.InTransaction(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, auditPurgeGuard); err != nil {
+			return fmt.Errorf("enable audit purge: %w", err)
+		}
+		res, err := tx.ExecContext(ctx, `DELETE FROM audit_logs WHERE created_at < $1`, cutoff)
+		if err != nil {
+			return fmt.Errorf("delete expired audit logs: %w", err)
+		}
+		deleted, err = res.RowsAffected()
+		return err
+	})
+	return deleted, err
+}
+
+// Query returns the entries matching q, newest first. Limit defaults to
+// 100 and is capped at 1000.
+func (al *PostgresAuditLogger) Query(ctx context.Context, q AuditQuery) ([]models.AuditLog, error) {
+	if al.db == nil || al.db.db == nil {
+		return nil, fmt.Errorf("audit log: database not configured")
+	}
+	query, args := buildAuditQuery(q)
+	rows, err := al.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query audit logs: %w", err)
+	}
+	defer rows.Close()
+
+	logs := make([]models.AuditLog, 0)
+	for rows.Next() {
+		var l models.AuditLog
+		if err := rows.Scan(&l.ID, &l.Action, &l.Actor, &l.Resource, &l.ResourceID,
+			&l.Success, &l.ErrorMsg, &l.Details, &l.IP, &l.TraceID, &l.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan audit log: %w", err)
+		}
+		logs = append(logs, l)
+	}
+	return logs, rows.Err()
+}
+
+func buildAuditQuery(q AuditQuery) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if q.Actor != "" {
+		add("actor = $%d", q.Actor)
+	}
+	if q.Action != "" {
+		add("action = $%d", q.Action)
+	}
+	if q.Resource != "" {
+		add("resource = $%d", q.Resource)
+	}
+	if q.ResourceID != "" {
+		add("resource_id = $%d", q.ResourceID)
+	}
+	if q.Success != nil {
+		add("success = $%d", *q.Success)
+	}
+	if !q.Since.IsZero() {
+		add("created_at >= $%d", q.Since)
+	}
+	if !q.Until.IsZero() {
+		add("created_at < $%d", q.Until)
+	}
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	if limit > maxAuditQueryResults {
+		limit = maxAuditQueryResults
+	}
+	offset := q.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	query := auditSelectQuery
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	args = append(args, limit, offset)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	return query, args
+}
+
+// AuditConfigChanges returns a ConfigManager change handler that records
+// which configuration sections changed. Values are left out, as they
+// include secrets.
+func AuditConfigChanges(al AuditLogger) config.ConfigChangeHandler {
+	return func(oldCfg, newCfg *config.Config) error {
+		if changed := config.ChangedSections(oldCfg, newCfg); len(changed) > 0 {
+			al.Log(context.Background(), "config.change", "system", "config", "", true, "", strings.Join(changed, ","))
+		}
+		return nil
+	}
+}
+
 func (al *PostgresAuditLogger) drain() {
 	for {
 		select {
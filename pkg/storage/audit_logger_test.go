@@ -3,11 +3,15 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/rtcdance/streamgate/pkg/core/config"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -149,3 +153,96 @@ func TestPostgresAuditLogger_Constants(t *testing.T) {
 	assert.Equal(t, 1024, auditBufferSize)
 	assert.NotEmpty(t, auditInsertQuery)
 }
+
+func TestPostgresAuditLogger_LogRecordsRequestContext(t *testing.T) {
+	al := NewPostgresAuditLogger(nil, zap.NewNop())
+	tid, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	ctx := trace.ContextWithSpanContext(context.Background(),
+		trace.NewSpanContext(trace.SpanContextConfig{TraceID: tid, SpanID: trace.SpanID{1}}))
+	ctx = WithAuditClientIP(ctx, "203.0.113.7")
+
+	al.Log(ctx, "auth.wallet_login", "0xabc", "auth", "ch1", true, "", "")
+
+	entry := <-al.ch
+	assert.Equal(t, "203.0.113.7", entry.ip)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", entry.traceID)
+}
+
+func TestBuildAuditQuery(t *testing.T) {
+	failed := false
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		q         AuditQuery
+		wantWhere string
+		wantArgs  []interface{}
+	}{
+		{
+			name:     "no filters",
+			wantArgs: []interface{}{100, 0},
+		},
+		{
+			name:      "filters",
+			q:         AuditQuery{Actor: "0xabc", Action: "auth.wallet_login", Success: &failed, Since: since, Limit: 10, Offset: 20},
+			wantWhere: " WHERE actor = $1 AND action = $2 AND success = $3 AND created_at >= $4",
+			wantArgs:  []interface{}{"0xabc", "auth.wallet_login", false, since, 10, 20},
+		},
+		{
+			name:     "limit capped",
+			q:        AuditQuery{Limit: 5000, Offset: -1},
+			wantArgs: []interface{}{1000, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args := buildAuditQuery(tt.q)
+			n := len(tt.wantArgs)
+			assert.Equal(t, auditSelectQuery+tt.wantWhere+
+				fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", n-1, n), query)
+			assert.Equal(t, tt.wantArgs, args)
+		})
+	}
+}
+
+// auditRecorder keeps the details of the entries it is given.
+type auditRecorder struct{ details []string }
+
+func (a *auditRecorder) Log(_ context.Context, action, _, _, _ string, _ bool, _, details string) {
+	a.details = append(a.details, action+" "+details)
+}
+
+func (a *auditRecorder) Close() error { return nil }
+
+func TestAuditConfigChanges(t *testing.T) {
+	rec := &auditRecorder{}
+	handler := AuditConfigChanges(rec)
+	oldCfg := &config.Config{}
+	newCfg := &config.Config{}
+
+	require.NoError(t, handler(oldCfg, newCfg))
+	assert.Empty(t, rec.details, "unchanged configs are not audited")
+
+	newCfg.Auth.JWTSecret = "rotated"
+	newCfg.Audit.Retention = "24h"
+	require.NoError(t, handler(oldCfg, newCfg))
+	assert.Equal(t, []string{"config.change Audit,Auth"}, rec.details)
+}
+
+func TestPostgresAuditLogger_Exporter(t *testing.T) {
+	sink := &fakeAuditSink{}
+	exporter, err := NewAuditExporter(sink, AuditExporterConfig{}, zap.NewNop())
+	require.NoError(t, err)
+	exporter.Start()
+
+	al := NewPostgresAuditLogger(nil, zap.NewNop())
+	al.SetExporter(exporter)
+	al.Start()
+	al.Log(WithAuditClientIP(context.Background(), "10.0.0.1"), "content.delete", "0xabc", "content", "c1", true, "", "")
+	require.NoError(t, al.Close())
+	require.NoError(t, exporter.Close())
+
+	batches, _ := sink.snapshot()
+	require.Len(t, batches, 1)
+	assert.Contains(t, string(batches[0].Payload), `"action":"content.delete"`)
+	assert.Contains(t, string(batches[0].Payload), `"ip":"10.0.0.1"`)
+}
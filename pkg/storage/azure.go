@@ -0,0 +1,489 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// azureAPIVersion is the Blob service REST version requests are made
+	// against; SAS tokens are signed with the same version.
+	azureAPIVersion = "2021-08-06"
+	// azureBlockSize is the size of the blocks large streams are staged in.
+	azureBlockSize = 8 << 20
+)
+
+// AzureBlobConfig holds Azure Blob Storage configuration
+type AzureBlobConfig struct {
+	AccountName string
+	AccountKey  string // Base64 account key, as shown in the portal
+	Endpoint    string // Optional: defaults to https://<account>.blob.core.windows.net; set for Azurite
+	UseSSL      bool   // Scheme for an Endpoint given without one
+}
+
+// AzureBlobStorage handles Azure Blob Storage through the Blob service REST
+// API with Shared Key authorization. Buckets map to containers. Presigned
+// download URLs are service SAS tokens; presigned and multipart uploads are
+// not supported because Put Blob needs headers a bare presigned PUT lacks.
+type AzureBlobStorage struct {
+	account string
+	key     []byte
+	baseURL *url.URL
+	client  *http.Client
+}
+
+// NewAzureBlobStorage creates a new Azure Blob Storage instance
+func NewAzureBlobStorage(config AzureBlobConfig) (*AzureBlobStorage, error) {
+	if config.AccountName == "" {
+		return nil, errors.New("azure storage requires an account name")
+	}
+	key, err := base64.StdEncoding.DecodeString(config.AccountKey)
+	if err != nil || len(key) == 0 {
+		return nil, errors.New("azure storage requires a base64 account key")
+	}
+
+	endpoint := config.Endpoint
+	switch {
+	case endpoint == "":
+		endpoint = "https://" + config.AccountName + ".blob.core.windows.net"
+	case !strings.Contains(endpoint, "://"):
+		if config.UseSSL {
+			endpoint = "https://" + endpoint
+		} else {
+			endpoint = "http://" + endpoint
+		}
+	}
+	base, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Azure endpoint: %w", err)
+	}
+
+	return &AzureBlobStorage{
+		account: config.AccountName,
+		key:     key,
+		baseURL: base,
+		client:  &http.Client{},
+	}, nil
+}
+
+// Close releases idle connections.
+func (az *AzureBlobStorage) Close() error {
+	az.client.CloseIdleConnections()
+	return nil
+}
+
+// Upload uploads to Azure
+func (az *AzureBlobStorage) Upload(ctx context.Context, container, blob string, data []byte) error {
+	return az.UploadWithContentType(ctx, container, blob, data, detectContentTypeByExt(blob))
+}
+
+func (az *AzureBlobStorage) UploadStream(ctx context.Context, container, blob string, reader io.Reader, size int64) error {
+	return az.UploadStreamWithContentType(ctx, container, blob, reader, size, detectContentTypeByExt(blob))
+}
+
+// UploadWithContentType uploads to Azure with specific content type
+func (az *AzureBlobStorage) UploadWithContentType(ctx context.Context, container, blob string, data []byte, contentType string) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return az.putBlob(ctx, container, blob, bytes.NewReader(data), int64(len(data)), contentType)
+}
+
+// UploadStreamWithContentType sends streams up to one block with a single
+// Put Blob; larger or unsized streams are staged as blocks and committed
+// with Put Block List.
+func (az *AzureBlobStorage) UploadStreamWithContentType(ctx context.Context, container, blob string, reader io.Reader, size int64, contentType string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	if size >= 0 && size <= azureBlockSize {
+		return az.putBlob(ctx, container, blob, reader, size, contentType)
+	}
+
+	buf := make([]byte, azureBlockSize)
+	var ids []string
+	for {
+		n, err := io.ReadFull(reader, buf)
+		if n > 0 {
+			id := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%08d", len(ids))))
+			if err := az.putBlock(ctx, container, blob, id, buf[:n]); err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read upload stream: %w", err)
+		}
+	}
+	return az.putBlockList(ctx, container, blob, ids, contentType)
+}
+
+func (az *AzureBlobStorage) putBlob(ctx context.Context, container, blob string, body io.Reader, size int64, contentType string) error {
+	resp, err := az.do(ctx, http.MethodPut, az.blobURL(container, blob, nil), body, size, map[string]string{
+		"Content-Type":   contentType,
+		"x-ms-blob-type": "BlockBlob",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload to Azure: %w", err)
+	}
+	defer drainClose(resp)
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("failed to upload to Azure: %w", azureError(resp))
+	}
+	return nil
+}
+
+func (az *AzureBlobStorage) putBlock(ctx context.Context, container, blob, id string, data []byte) error {
+	q := url.Values{"comp": {"block"}, "blockid": {id}}
+	resp, err := az.do(ctx, http.MethodPut, az.blobURL(container, blob, q), bytes.NewReader(data), int64(len(data)), nil)
+	if err != nil {
+		return fmt.Errorf("failed to upload block to Azure: %w", err)
+	}
+	defer drainClose(resp)
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("failed to upload block to Azure: %w", azureError(resp))
+	}
+	return nil
+}
+
+func (az *AzureBlobStorage) putBlockList(ctx context.Context, container, blob string, ids []string, contentType string) error {
+	var body bytes.Buffer
+	body.WriteString(`<?xml version="1.0" encoding="utf-8"?><BlockList>`)
+	for _, id := range ids {
+		body.WriteString("<Latest>" + id + "</Latest>")
+	}
+	body.WriteString("</BlockList>")
+
+	q := url.Values{"comp": {"blocklist"}}
+	resp, err := az.do(ctx, http.MethodPut, az.blobURL(container, blob, q), &body, int64(body.Len()), map[string]string{
+		"x-ms-blob-content-type": contentType,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to commit Azure blocks: %w", err)
+	}
+	defer drainClose(resp)
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("failed to commit Azure blocks: %w", azureError(resp))
+	}
+	return nil
+}
+
+// Download downloads from Azure and returns the entire content as a byte
+// slice. Only safe for objects smaller than maxDownloadSize (1 GB).
+func (az *AzureBlobStorage) Download(ctx context.Context, container, blob string) ([]byte, error) {
+	rc, err := az.DownloadStream(ctx, container, blob)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rc.Close() }()
+
+	buf := new(bytes.Buffer)
+	n, err := io.Copy(buf, io.LimitReader(rc, maxDownloadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Azure blob: %w", err)
+	}
+	if n > maxDownloadSize {
+		return nil, errors.New("Azure blob exceeds maximum download size (1 GB)")
+	}
+	return buf.Bytes(), nil
+}
+
+// DownloadStream returns an io.ReadCloser for streaming a blob from Azure.
+// The caller must close the reader when done.
+func (az *AzureBlobStorage) DownloadStream(ctx context.Context, container, blob string) (io.ReadCloser, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+
+	resp, err := az.do(ctx, http.MethodGet, az.blobURL(container, blob, nil), nil, 0, nil)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to get blob from Azure: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer cancel()
+		defer drainClose(resp)
+		return nil, fmt.Errorf("failed to get blob from Azure: %w", azureError(resp))
+	}
+	return &readCloserWithCancel{ReadCloser: resp.Body, cancel: cancel}, nil
+}
+
+// Delete deletes from Azure. Deleting a missing blob is not an error.
+func (az *AzureBlobStorage) Delete(ctx context.Context, container, blob string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	resp, err := az.do(ctx, http.MethodDelete, az.blobURL(container, blob, nil), nil, 0, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete from Azure: %w", err)
+	}
+	defer drainClose(resp)
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("failed to delete from Azure: %w", azureError(resp))
+	}
+	return nil
+}
+
+// DeleteObjects deletes blobs one at a time; the batch API needs multipart
+// request bodies that are not worth it for the handful of objects deleted
+// together here.
+func (az *AzureBlobStorage) DeleteObjects(ctx context.Context, container string, blobs []string) error {
+	for _, blob := range blobs {
+		if err := az.Delete(ctx, container, blob); err != nil {
+			return fmt.Errorf("failed to delete object %s from Azure: %w", blob, err)
+		}
+	}
+	return nil
+}
+
+// Exists checks if a blob exists in Azure
+func (az *AzureBlobStorage) Exists(ctx context.Context, container, blob string) (bool, error) {
+	_, err := az.Stat(ctx, container, blob)
+	if errors.Is(err, ErrObjectNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (az *AzureBlobStorage) Stat(ctx context.Context, container, blob string) (ObjectInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	resp, err := az.do(ctx, http.MethodHead, az.blobURL(container, blob, nil), nil, 0, nil)
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("failed to stat Azure blob: %w", err)
+	}
+	defer drainClose(resp)
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return ObjectInfo{}, ErrObjectNotFound
+	default:
+		return ObjectInfo{}, fmt.Errorf("failed to stat Azure blob: %w", azureError(resp))
+	}
+
+	info := ObjectInfo{
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+		ETag:        strings.Trim(resp.Header.Get("ETag"), `"`),
+	}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.LastModified = t
+	}
+	return info, nil
+}
+
+// ListObjects lists blobs in a container with a prefix
+func (az *AzureBlobStorage) ListObjects(ctx context.Context, container, prefix string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	keys := make([]string, 0)
+	marker := ""
+	for {
+		q := url.Values{"restype": {"container"}, "comp": {"list"}}
+		if prefix != "" {
+			q.Set("prefix", prefix)
+		}
+		if marker != "" {
+			q.Set("marker", marker)
+		}
+		page, err := az.listPage(ctx, container, q)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range page.Blobs {
+			keys = append(keys, b.Name)
+		}
+		if page.NextMarker == "" {
+			return keys, nil
+		}
+		marker = page.NextMarker
+	}
+}
+
+type azureBlobList struct {
+	Blobs []struct {
+		Name string `xml:"Name"`
+	} `xml:"Blobs>Blob"`
+	NextMarker string `xml:"NextMarker"`
+}
+
+func (az *AzureBlobStorage) listPage(ctx context.Context, container string, q url.Values) (*azureBlobList, error) {
+	resp, err := az.do(ctx, http.MethodGet, az.blobURL(container, "", q), nil, 0, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+	defer drainClose(resp)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list objects: %w", azureError(resp))
+	}
+	var page azureBlobList
+	if err := xml.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("failed to decode Azure blob list: %w", err)
+	}
+	return &page, nil
+}
+
+// CreateBucket creates a container if it does not exist
+func (az *AzureBlobStorage) CreateBucket(ctx context.Context, container string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	q := url.Values{"restype": {"container"}}
+	resp, err := az.do(ctx, http.MethodPut, az.blobURL(container, "", q), nil, 0, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create container: %w", err)
+	}
+	defer drainClose(resp)
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusConflict {
+		return fmt.Errorf("failed to create container: %w", azureError(resp))
+	}
+	return nil
+}
+
+// PresignedURL generates a read-only service SAS URL for a blob
+func (az *AzureBlobStorage) PresignedURL(_ context.Context, container, blob string, expiration time.Duration) (string, error) {
+	expiry := time.Now().UTC().Add(expiration).Format("2006-01-02T15:04:05Z")
+	resource := "/blob/" + az.account + "/" + container + "/" + blob
+	// Fields in the order the service SAS string-to-sign lists them; the
+	// empty ones are optional parameters this URL does not set.
+	toSign := strings.Join([]string{
+		"r", "", expiry, resource, "", "", "", azureAPIVersion, "b",
+		"", "", "", "", "", "", "",
+	}, "\n")
+
+	q := url.Values{
+		"sv":  {azureAPIVersion},
+		"se":  {expiry},
+		"sr":  {"b"},
+		"sp":  {"r"},
+		"sig": {az.hmac(toSign)},
+	}
+	return az.blobURL(container, blob, q).String(), nil
+}
+
+func (az *AzureBlobStorage) blobURL(container, blob string, query url.Values) *url.URL {
+	u := *az.baseURL
+	u.Path += "/" + container
+	if blob != "" {
+		u.Path += "/" + blob
+	}
+	u.RawPath = ""
+	u.RawQuery = query.Encode()
+	return &u
+}
+
+func (az *AzureBlobStorage) do(ctx context.Context, method string, u *url.URL, body io.Reader, size int64, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	for k, v := range headers {
+		if v != "" {
+			req.Header.Set(k, v)
+		}
+	}
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("x-ms-version", azureAPIVersion)
+	az.sign(req)
+	return az.client.Do(req)
+}
+
+// sign sets the Shared Key Authorization header.
+func (az *AzureBlobStorage) sign(req *http.Request) {
+	length := ""
+	if req.ContentLength > 0 {
+		length = strconv.FormatInt(req.ContentLength, 10)
+	}
+
+	var msHeaders []string
+	for k := range req.Header {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-ms-") {
+			msHeaders = append(msHeaders, lk)
+		}
+	}
+	sort.Strings(msHeaders)
+	var canonical strings.Builder
+	for _, k := range msHeaders {
+		canonical.WriteString(k + ":" + strings.TrimSpace(req.Header.Get(k)) + "\n")
+	}
+
+	canonical.WriteString("/" + az.account + req.URL.EscapedPath())
+	query := req.URL.Query()
+	params := make([]string, 0, len(query))
+	for k := range query {
+		params = append(params, k)
+	}
+	sort.Strings(params)
+	for _, k := range params {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		canonical.WriteString("\n" + strings.ToLower(k) + ":" + strings.Join(values, ","))
+	}
+
+	h := req.Header
+	toSign := strings.Join([]string{
+		req.Method,
+		h.Get("Content-Encoding"),
+		h.Get("Content-Language"),
+		length,
+		h.Get("Content-MD5"),
+		h.Get("Content-Type"),
+		"", // Date: x-ms-date is used instead
+		h.Get("If-Modified-Since"),
+		h.Get("If-Match"),
+		h.Get("If-None-Match"),
+		h.Get("If-Unmodified-Since"),
+		h.Get("Range"),
+		canonical.String(),
+	}, "\n")
+	req.Header.Set("Authorization", "SharedKey "+az.account+":"+az.hmac(toSign))
+}
+
+func (az *AzureBlobStorage) hmac(s string) string {
+	mac := hmac.New(sha256.New, az.key)
+	mac.Write([]byte(s))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// azureError describes a failed response from its error body.
+func azureError(resp *http.Response) error {
+	var body struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	_ = xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body)
+	if body.Code == "" {
+		body.Code = resp.Header.Get("x-ms-error-code")
+	}
+	if body.Code == "" {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return fmt.Errorf("status %d: %s: %s", resp.StatusCode, body.Code, strings.TrimSpace(body.Message))
+}
+
+func drainClose(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	_ = resp.Body.Close()
+}
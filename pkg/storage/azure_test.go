@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var azureTestKey = base64.StdEncoding.EncodeToString([]byte("azure-test-account-key"))
+
+// fakeBlobService is an in-memory Blob service for a path-style account,
+// as Azurite serves it.
+type fakeBlobService struct {
+	mu         sync.Mutex
+	blobs      map[string][]byte
+	types      map[string]string
+	blocks     map[string][]byte
+	containers map[string]bool
+	authFailed bool
+}
+
+func newFakeBlobService() *fakeBlobService {
+	return &fakeBlobService{
+		blobs:      map[string][]byte{},
+		types:      map[string]string{},
+		blocks:     map[string][]byte{},
+		containers: map[string]bool{},
+	}
+}
+
+func (f *fakeBlobService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "SharedKey acct:") || r.Header.Get("x-ms-version") != azureAPIVersion {
+		f.authFailed = true
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	// Path is /acct/<container>[/<blob>].
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/acct/"), "/", 2)
+	container, blob := parts[0], ""
+	if len(parts) == 2 {
+		blob = parts[1]
+	}
+	key := container + "/" + blob
+	q := r.URL.Query()
+
+	switch {
+	case r.Method == http.MethodPut && q.Get("restype") == "container":
+		if f.containers[container] {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		f.containers[container] = true
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodGet && q.Get("comp") == "list":
+		var out struct {
+			XMLName    xml.Name `xml:"EnumerationResults"`
+			Blobs      []string `xml:"Blobs>Blob>Name"`
+			NextMarker string   `xml:"NextMarker"`
+		}
+		for k := range f.blobs {
+			name := strings.TrimPrefix(k, container+"/")
+			if strings.HasPrefix(k, container+"/") && strings.HasPrefix(name, q.Get("prefix")) {
+				out.Blobs = append(out.Blobs, name)
+			}
+		}
+		_ = xml.NewEncoder(w).Encode(out)
+	case r.Method == http.MethodPut && q.Get("comp") == "block":
+		data, _ := io.ReadAll(r.Body)
+		f.blocks[key+"#"+q.Get("blockid")] = data
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && q.Get("comp") == "blocklist":
+		var list struct {
+			Latest []string `xml:"Latest"`
+		}
+		_ = xml.NewDecoder(r.Body).Decode(&list)
+		var data []byte
+		for _, id := range list.Latest {
+			data = append(data, f.blocks[key+"#"+id]...)
+		}
+		f.blobs[key] = data
+		f.types[key] = r.Header.Get("x-ms-blob-content-type")
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut:
+		if r.Header.Get("x-ms-blob-type") != "BlockBlob" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		f.blobs[key] = data
+		f.types[key] = r.Header.Get("Content-Type")
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		data, ok := f.blobs[key]
+		if !ok {
+			w.Header().Set("x-ms-error-code", "BlobNotFound")
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", f.types[key])
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		w.Header().Set("ETag", `"0x8D"`)
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(data)
+		}
+	case r.Method == http.MethodDelete:
+		if _, ok := f.blobs[key]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.blobs, key)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newTestAzureStorage(t *testing.T) (*AzureBlobStorage, *fakeBlobService) {
+	t.Helper()
+	fake := newFakeBlobService()
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	az, err := NewAzureBlobStorage(AzureBlobConfig{
+		AccountName: "acct",
+		AccountKey:  azureTestKey,
+		Endpoint:    srv.URL + "/acct",
+	})
+	require.NoError(t, err)
+	return az, fake
+}
+
+func TestAzureBlobStorage_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	az, fake := newTestAzureStorage(t)
+
+	require.NoError(t, az.CreateBucket(ctx, "media"))
+	require.NoError(t, az.CreateBucket(ctx, "media"), "existing container is not an error")
+
+	require.NoError(t, az.Upload(ctx, "media", "content/c1/master.m3u8", []byte("#EXTM3U")))
+	data, err := az.Download(ctx, "media", "content/c1/master.m3u8")
+	require.NoError(t, err)
+	assert.Equal(t, "#EXTM3U", string(data))
+
+	info, err := az.Stat(ctx, "media", "content/c1/master.m3u8")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), info.Size)
+	assert.Equal(t, "application/vnd.apple.mpegurl", info.ContentType)
+	assert.Equal(t, "0x8D", info.ETag)
+
+	keys, err := az.ListObjects(ctx, "media", "content/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"content/c1/master.m3u8"}, keys)
+
+	require.NoError(t, az.Delete(ctx, "media", "content/c1/master.m3u8"))
+	require.NoError(t, az.Delete(ctx, "media", "content/c1/master.m3u8"), "missing blob is not an error")
+	ok, err := az.Exists(ctx, "media", "content/c1/master.m3u8")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = az.Download(ctx, "media", "missing")
+	assert.ErrorContains(t, err, "BlobNotFound")
+	assert.False(t, fake.authFailed)
+}
+
+func TestAzureBlobStorage_UploadStreamInBlocks(t *testing.T) {
+	ctx := context.Background()
+	az, fake := newTestAzureStorage(t)
+
+	payload := strings.Repeat("x", azureBlockSize+10)
+	require.NoError(t, az.UploadStreamWithContentType(ctx, "media", "uploads/u1", strings.NewReader(payload), -1, "video/mp4"))
+
+	assert.Len(t, fake.blocks, 2)
+	assert.Equal(t, payload, string(fake.blobs["media/uploads/u1"]))
+	assert.Equal(t, "video/mp4", fake.types["media/uploads/u1"])
+}
+
+func TestAzureBlobStorage_SharedKeySignature(t *testing.T) {
+	az, err := NewAzureBlobStorage(AzureBlobConfig{AccountName: "acct", AccountKey: azureTestKey})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodGet, "https://acct.blob.core.windows.net/media?restype=container&comp=list&prefix=a", nil)
+	require.NoError(t, err)
+	req.Header.Set("x-ms-date", "Mon, 01 Jan 2024 00:00:00 GMT")
+	req.Header.Set("x-ms-version", azureAPIVersion)
+	az.sign(req)
+
+	toSign := "GET\n\n\n\n\n\n\n\n\n\n\n\n" +
+		"x-ms-date:Mon, 01 Jan 2024 00:00:00 GMT\nx-ms-version:" + azureAPIVersion + "\n" +
+		"/acct/media\ncomp:list\nprefix:a\nrestype:container"
+	key, _ := base64.StdEncoding.DecodeString(azureTestKey)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(toSign))
+	assert.Equal(t, "SharedKey acct:"+base64.StdEncoding.EncodeToString(mac.Sum(nil)), req.Header.Get("Authorization"))
+}
+
+func TestAzureBlobStorage_PresignedURL(t *testing.T) {
+	az, err := NewAzureBlobStorage(AzureBlobConfig{AccountName: "acct", AccountKey: azureTestKey})
+	require.NoError(t, err)
+
+	raw, err := az.PresignedURL(context.Background(), "media", "content/c1/master.m3u8", time.Hour)
+	require.NoError(t, err)
+	u, err := url.Parse(raw)
+	require.NoError(t, err)
+
+	assert.Equal(t, "acct.blob.core.windows.net", u.Host)
+	assert.Equal(t, "/media/content/c1/master.m3u8", u.Path)
+	q := u.Query()
+	assert.Equal(t, "r", q.Get("sp"))
+	assert.Equal(t, "b", q.Get("sr"))
+	assert.Equal(t, azureAPIVersion, q.Get("sv"))
+	assert.Regexp(t, regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z$`), q.Get("se"))
+	assert.NotEmpty(t, q.Get("sig"))
+}
+
+func TestNewAzureBlobStorage_Validation(t *testing.T) {
+	_, err := NewAzureBlobStorage(AzureBlobConfig{AccountKey: azureTestKey})
+	assert.Error(t, err)
+	_, err = NewAzureBlobStorage(AzureBlobConfig{AccountName: "acct", AccountKey: "not base64!"})
+	assert.Error(t, err)
+
+	az, err := NewAzureBlobStorage(AzureBlobConfig{AccountName: "devstoreaccount1", AccountKey: azureTestKey, Endpoint: "127.0.0.1:10000/devstoreaccount1"})
+	require.NoError(t, err)
+	assert.Equal(t, "http://127.0.0.1:10000/devstoreaccount1", az.baseURL.String())
+}
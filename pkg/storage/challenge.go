@@ -13,4 +13,6 @@ type WalletChallenge struct {
 	IssuedAt      time.Time `json:"issued_at"`
 	ExpiresAt     time.Time `json:"expires_at"`
 	UsedAt        time.Time `json:"used_at,omitempty"`
+	Attempts      int       `json:"attempts,omitempty"`
+	MaxAttempts   int       `json:"max_attempts,omitempty"` // 0 means no limit
 }
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+)
+
+// NewObjectStorageFromConfig connects to the object storage driver selected
+// by cfg.Type, wrapped with metrics and tracing. An empty type is MinIO.
+func NewObjectStorageFromConfig(cfg config.StorageConfig) (*InstrumentedObjectStorage, error) {
+	inner, err := newObjectStorage(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewInstrumentedObjectStorage(inner), nil
+}
+
+func newObjectStorage(cfg config.StorageConfig) (ObjectStorage, error) {
+	switch cfg.Type {
+	case "", "minio":
+		return NewMinIOStorage(MinIOConfig{
+			Endpoint:        cfg.Endpoint,
+			AccessKeyID:     cfg.AccessKey,
+			SecretAccessKey: cfg.SecretKey,
+			UseSSL:          cfg.UseSSL,
+		})
+	case "s3":
+		return NewS3Storage(S3Config{
+			Region:          cfg.Region,
+			AccessKeyID:     cfg.AccessKey,
+			SecretAccessKey: cfg.SecretKey,
+			Endpoint:        cfg.Endpoint,
+		})
+	case "gcs":
+		return NewGCSStorage(GCSConfig{
+			Endpoint:        cfg.Endpoint,
+			AccessKeyID:     cfg.AccessKey,
+			SecretAccessKey: cfg.SecretKey,
+		})
+	case "azure":
+		return NewAzureBlobStorage(AzureBlobConfig{
+			AccountName: cfg.AccessKey,
+			AccountKey:  cfg.SecretKey,
+			Endpoint:    cfg.Endpoint,
+			UseSSL:      cfg.UseSSL,
+		})
+	case "local":
+		return NewLocalStorage(cfg.Path)
+	case "ipfs":
+		var gateway string
+		if len(cfg.IPFS.Gateways) > 0 {
+			gateway = cfg.IPFS.Gateways[0]
+		}
+		return NewIPFSStorage(IPFSConfig{
+			APIURL:            cfg.IPFS.APIURL,
+			Root:              cfg.IPFS.Root,
+			GatewayURL:        gateway,
+			PinningServiceURL: cfg.IPFS.PinningServiceURL,
+			PinningToken:      cfg.IPFS.PinningToken,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported storage type: %s", cfg.Type)
+	}
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/rtcdance/streamgate/pkg/core/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewObjectStorageFromConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.StorageConfig
+		want    interface{}
+		wantErr bool
+	}{
+		{"default is minio", config.StorageConfig{Endpoint: "localhost:9000"}, &MinIOStorage{}, false},
+		{"minio", config.StorageConfig{Type: "minio", Endpoint: "localhost:9000"}, &MinIOStorage{}, false},
+		{"s3", config.StorageConfig{Type: "s3", Region: "us-east-1"}, &S3Storage{}, false},
+		{"gcs", config.StorageConfig{Type: "gcs", AccessKey: "GOOG1E", SecretKey: "secret"}, &MinIOStorage{}, false},
+		{"gcs without HMAC key", config.StorageConfig{Type: "gcs"}, nil, true},
+		{"azure", config.StorageConfig{Type: "azure", AccessKey: "acct", SecretKey: azureTestKey}, &AzureBlobStorage{}, false},
+		{"azure without account", config.StorageConfig{Type: "azure"}, nil, true},
+		{"local", config.StorageConfig{Type: "local", Path: t.TempDir()}, &LocalStorage{}, false},
+		{"local without path", config.StorageConfig{Type: "local"}, nil, true},
+		{"ipfs", config.StorageConfig{Type: "ipfs", IPFS: config.StorageIPFSConfig{Gateways: []string{"http://localhost:8080"}}}, &IPFSStorage{}, false},
+		{"ipfs pinning without token", config.StorageConfig{Type: "ipfs", IPFS: config.StorageIPFSConfig{PinningServiceURL: "https://api.pinata.cloud/psa"}}, nil, true},
+		{"unknown", config.StorageConfig{Type: "ftp"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewObjectStorageFromConfig(tt.cfg)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.IsType(t, tt.want, store.Unwrap())
+			var _ ObjectStater = store
+		})
+	}
+}
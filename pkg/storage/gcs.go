@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+)
+
+// gcsEndpoint is Cloud Storage's S3-compatible XML API.
+const gcsEndpoint = "storage.googleapis.com"
+
+// GCSConfig holds Google Cloud Storage configuration. Access goes through
+// the XML API's S3 interoperability mode, so the credentials are an HMAC
+// key pair created for a service account, not a JSON key file.
+type GCSConfig struct {
+	Endpoint        string // Optional: defaults to storage.googleapis.com
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// NewGCSStorage creates a Google Cloud Storage instance. It shares the
+// MinIO driver, which speaks the interoperable XML API, including presigned
+// and multipart uploads.
+func NewGCSStorage(config GCSConfig) (*MinIOStorage, error) {
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, errors.New("GCS storage requires an HMAC access key and secret")
+	}
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = gcsEndpoint
+	}
+	useSSL := !strings.HasPrefix(endpoint, "http://")
+	endpoint = strings.TrimPrefix(strings.TrimPrefix(endpoint, "https://"), "http://")
+	return NewMinIOStorage(MinIOConfig{
+		Endpoint:        endpoint,
+		AccessKeyID:     config.AccessKeyID,
+		SecretAccessKey: config.SecretAccessKey,
+		UseSSL:          useSSL,
+	})
+}
@@ -0,0 +1,216 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/monitoring"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+var (
+	storageOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "streamgate_storage_operation_duration_seconds",
+		Help:    "Duration of object storage operations",
+		Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"op"})
+	storageErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "streamgate_storage_errors_total",
+		Help: "Total failed object storage operations",
+	}, []string{"op"})
+)
+
+func init() {
+	for _, c := range []prometheus.Collector{storageOperationDuration, storageErrorsTotal} {
+		if err := prometheus.Register(c); err != nil {
+			if _, ok := err.(prometheus.AlreadyRegisteredError); !ok {
+				panic(err)
+			}
+		}
+	}
+}
+
+// errPresignedUploadUnsupported is returned by InstrumentedObjectStorage when
+// the wrapped store cannot issue presigned upload URLs.
+var errPresignedUploadUnsupported = errors.New("presigned upload URLs not supported by storage backend")
+
+// InstrumentedObjectStorage decorates an ObjectStorage with per-operation
+// latency histograms, error counters and a tracing span carrying the bucket,
+// object key and size.
+type InstrumentedObjectStorage struct {
+	inner ObjectStorage
+}
+
+// NewInstrumentedObjectStorage wraps inner with metrics and tracing.
+func NewInstrumentedObjectStorage(inner ObjectStorage) *InstrumentedObjectStorage {
+	return &InstrumentedObjectStorage{inner: inner}
+}
+
+// Unwrap returns the decorated storage.
+func (s *InstrumentedObjectStorage) Unwrap() ObjectStorage {
+	return s.inner
+}
+
+// observe starts a span for op and returns a func that records the outcome.
+func (s *InstrumentedObjectStorage) observe(ctx context.Context, op, bucket, key string, size int64) (context.Context, func(error)) {
+	attrs := []attribute.KeyValue{
+		attribute.String("storage.op", op),
+		attribute.String("storage.bucket", bucket),
+	}
+	if key != "" {
+		attrs = append(attrs, attribute.String("storage.key", key))
+	}
+	if size >= 0 {
+		attrs = append(attrs, attribute.Int64("storage.size", size))
+	}
+	ctx, span := monitoring.StartOTelSpan(ctx, "storage."+op, attrs...)
+	start := time.Now()
+
+	return ctx, func(err error) {
+		storageOperationDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+		if err != nil {
+			storageErrorsTotal.WithLabelValues(op).Inc()
+			monitoring.RecordSpanError(span, err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+func (s *InstrumentedObjectStorage) Upload(ctx context.Context, bucket, objectName string, data []byte) error {
+	ctx, done := s.observe(ctx, "put", bucket, objectName, int64(len(data)))
+	err := s.inner.Upload(ctx, bucket, objectName, data)
+	done(err)
+	return err
+}
+
+func (s *InstrumentedObjectStorage) UploadStream(ctx context.Context, bucket, objectName string, reader io.Reader, size int64) error {
+	ctx, done := s.observe(ctx, "put", bucket, objectName, size)
+	err := s.inner.UploadStream(ctx, bucket, objectName, reader, size)
+	done(err)
+	return err
+}
+
+func (s *InstrumentedObjectStorage) UploadWithContentType(ctx context.Context, bucket, objectName string, data []byte, contentType string) error {
+	ctx, done := s.observe(ctx, "put", bucket, objectName, int64(len(data)))
+	err := s.inner.UploadWithContentType(ctx, bucket, objectName, data, contentType)
+	done(err)
+	return err
+}
+
+func (s *InstrumentedObjectStorage) UploadStreamWithContentType(ctx context.Context, bucket, objectName string, reader io.Reader, size int64, contentType string) error {
+	ctx, done := s.observe(ctx, "put", bucket, objectName, size)
+	err := s.inner.UploadStreamWithContentType(ctx, bucket, objectName, reader, size, contentType)
+	done(err)
+	return err
+}
+
+func (s *InstrumentedObjectStorage) Download(ctx context.Context, bucket, objectName string) ([]byte, error) {
+	ctx, done := s.observe(ctx, "get", bucket, objectName, -1)
+	data, err := s.inner.Download(ctx, bucket, objectName)
+	done(err)
+	return data, err
+}
+
+// DownloadStream records the time to open the stream, not to drain it.
+func (s *InstrumentedObjectStorage) DownloadStream(ctx context.Context, bucket, objectName string) (io.ReadCloser, error) {
+	ctx, done := s.observe(ctx, "get", bucket, objectName, -1)
+	rc, err := s.inner.DownloadStream(ctx, bucket, objectName)
+	done(err)
+	return rc, err
+}
+
+func (s *InstrumentedObjectStorage) Delete(ctx context.Context, bucket, objectName string) error {
+	ctx, done := s.observe(ctx, "delete", bucket, objectName, -1)
+	err := s.inner.Delete(ctx, bucket, objectName)
+	done(err)
+	return err
+}
+
+func (s *InstrumentedObjectStorage) DeleteObjects(ctx context.Context, bucket string, objectNames []string) error {
+	ctx, done := s.observe(ctx, "delete_batch", bucket, "", int64(len(objectNames)))
+	err := s.inner.DeleteObjects(ctx, bucket, objectNames)
+	done(err)
+	return err
+}
+
+func (s *InstrumentedObjectStorage) ListObjects(ctx context.Context, bucket, prefix string) ([]string, error) {
+	ctx, done := s.observe(ctx, "list", bucket, prefix, -1)
+	names, err := s.inner.ListObjects(ctx, bucket, prefix)
+	done(err)
+	return names, err
+}
+
+func (s *InstrumentedObjectStorage) Exists(ctx context.Context, bucket, objectName string) (bool, error) {
+	ctx, done := s.observe(ctx, "exists", bucket, objectName, -1)
+	ok, err := s.inner.Exists(ctx, bucket, objectName)
+	done(err)
+	return ok, err
+}
+
+func (s *InstrumentedObjectStorage) CreateBucket(ctx context.Context, bucket string) error {
+	ctx, done := s.observe(ctx, "create_bucket", bucket, "", -1)
+	err := s.inner.CreateBucket(ctx, bucket)
+	done(err)
+	return err
+}
+
+func (s *InstrumentedObjectStorage) PresignedURL(ctx context.Context, bucket, objectName string, expiry time.Duration) (string, error) {
+	ctx, done := s.observe(ctx, "presign", bucket, objectName, -1)
+	url, err := s.inner.PresignedURL(ctx, bucket, objectName, expiry)
+	done(err)
+	return url, err
+}
+
+// PresignedUploader issues URLs a client can PUT an object to directly.
+// MinIO and S3 both implement it.
+type PresignedUploader interface {
+	PresignedUploadURL(ctx context.Context, bucket, objectName string, expiry time.Duration) (string, error)
+}
+
+// AsPresignedUploader returns store as a PresignedUploader if it can issue
+// presigned upload URLs. Use it rather than a type assertion: the
+// instrumented wrapper has the method whatever the backend is.
+func AsPresignedUploader(store interface{}) (PresignedUploader, bool) {
+	if s, ok := store.(*InstrumentedObjectStorage); ok {
+		if !s.SupportsPresignedUpload() {
+			return nil, false
+		}
+		return s, true
+	}
+	ups, ok := store.(PresignedUploader)
+	return ups, ok
+}
+
+// SupportsPresignedUpload reports whether the wrapped store issues
+// presigned upload URLs.
+func (s *InstrumentedObjectStorage) SupportsPresignedUpload() bool {
+	_, ok := AsPresignedUploader(s.inner)
+	return ok
+}
+
+// PresignedUploadURL forwards to the wrapped store when it supports
+// presigned uploads, and returns errPresignedUploadUnsupported otherwise.
+func (s *InstrumentedObjectStorage) PresignedUploadURL(ctx context.Context, bucket, objectName string, expiry time.Duration) (string, error) {
+	ups, ok := s.inner.(PresignedUploader)
+	if !ok {
+		return "", errPresignedUploadUnsupported
+	}
+	ctx, done := s.observe(ctx, "presign_upload", bucket, objectName, -1)
+	url, err := ups.PresignedUploadURL(ctx, bucket, objectName, expiry)
+	done(err)
+	return url, err
+}
+
+// Close closes the wrapped store if it holds resources.
+func (s *InstrumentedObjectStorage) Close() error {
+	if c, ok := s.inner.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type fakeObjectStorage struct {
+	err     error
+	objects map[string][]byte
+}
+
+func (f *fakeObjectStorage) Upload(_ context.Context, bucket, objectName string, data []byte) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.objects[bucket+"/"+objectName] = data
+	return nil
+}
+
+func (f *fakeObjectStorage) UploadStream(ctx context.Context, bucket, objectName string, reader io.Reader, _ int64) error {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	return f.Upload(ctx, bucket, objectName, data)
+}
+
+func (f *fakeObjectStorage) UploadWithContentType(ctx context.Context, bucket, objectName string, data []byte, _ string) error {
+	return f.Upload(ctx, bucket, objectName, data)
+}
+
+func (f *fakeObjectStorage) UploadStreamWithContentType(ctx context.Context, bucket, objectName string, reader io.Reader, size int64, _ string) error {
+	return f.UploadStream(ctx, bucket, objectName, reader, size)
+}
+
+func (f *fakeObjectStorage) Download(_ context.Context, bucket, objectName string) ([]byte, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	data, ok := f.objects[bucket+"/"+objectName]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return data, nil
+}
+
+func (f *fakeObjectStorage) DownloadStream(ctx context.Context, bucket, objectName string) (io.ReadCloser, error) {
+	data, err := f.Download(ctx, bucket, objectName)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (f *fakeObjectStorage) Delete(_ context.Context, bucket, objectName string) error {
+	if f.err != nil {
+		return f.err
+	}
+	delete(f.objects, bucket+"/"+objectName)
+	return nil
+}
+
+func (f *fakeObjectStorage) DeleteObjects(ctx context.Context, bucket string, objectNames []string) error {
+	for _, n := range objectNames {
+		if err := f.Delete(ctx, bucket, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeObjectStorage) ListObjects(_ context.Context, bucket, prefix string) ([]string, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	var names []string
+	for k := range f.objects {
+		if strings.HasPrefix(k, bucket+"/"+prefix) {
+			names = append(names, strings.TrimPrefix(k, bucket+"/"))
+		}
+	}
+	return names, nil
+}
+
+func (f *fakeObjectStorage) Exists(_ context.Context, bucket, objectName string) (bool, error) {
+	if f.err != nil {
+		return false, f.err
+	}
+	_, ok := f.objects[bucket+"/"+objectName]
+	return ok, nil
+}
+
+func (f *fakeObjectStorage) CreateBucket(context.Context, string) error { return f.err }
+
+func (f *fakeObjectStorage) PresignedURL(_ context.Context, bucket, objectName string, _ time.Duration) (string, error) {
+	return "https://example.test/" + bucket + "/" + objectName, f.err
+}
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return rec
+}
+
+// storageMetric returns the error count and duration sample count for op.
+func storageMetric(t *testing.T, op string) (errs float64, samples uint64) {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			if len(m.GetLabel()) != 1 || m.GetLabel()[0].GetValue() != op {
+				continue
+			}
+			switch mf.GetName() {
+			case "streamgate_storage_errors_total":
+				errs = m.GetCounter().GetValue()
+			case "streamgate_storage_operation_duration_seconds":
+				samples = m.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+	return errs, samples
+}
+
+func spanAttr(span sdktrace.ReadOnlySpan, key string) (attribute.Value, bool) {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestInstrumentedObjectStorage_Success(t *testing.T) {
+	rec := recordSpans(t)
+	store := NewInstrumentedObjectStorage(&fakeObjectStorage{objects: map[string][]byte{}})
+	ctx := context.Background()
+
+	putErrs, putSamples := storageMetric(t, "put")
+	_, getSamples := storageMetric(t, "get")
+	_, delSamples := storageMetric(t, "delete")
+
+	require.NoError(t, store.Upload(ctx, "bucket", "videos/a.mp4", []byte("hello")))
+	data, err := store.Download(ctx, "bucket", "videos/a.mp4")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hello"), data)
+	require.NoError(t, store.Delete(ctx, "bucket", "videos/a.mp4"))
+
+	errs, samples := storageMetric(t, "put")
+	assert.Equal(t, putErrs, errs)
+	assert.Equal(t, putSamples+1, samples)
+	_, samples = storageMetric(t, "get")
+	assert.Equal(t, getSamples+1, samples)
+	_, samples = storageMetric(t, "delete")
+	assert.Equal(t, delSamples+1, samples)
+
+	spans := rec.Ended()
+	require.Len(t, spans, 3)
+	assert.Equal(t, "storage.put", spans[0].Name())
+	assert.Equal(t, "storage.get", spans[1].Name())
+	assert.Equal(t, "storage.delete", spans[2].Name())
+
+	key, ok := spanAttr(spans[0], "storage.key")
+	require.True(t, ok)
+	assert.Equal(t, "videos/a.mp4", key.AsString())
+	size, ok := spanAttr(spans[0], "storage.size")
+	require.True(t, ok)
+	assert.Equal(t, int64(5), size.AsInt64())
+	assert.NotEqual(t, codes.Error, spans[0].Status().Code)
+}
+
+func TestInstrumentedObjectStorage_Error(t *testing.T) {
+	rec := recordSpans(t)
+	boom := errors.New("backend down")
+	store := NewInstrumentedObjectStorage(&fakeObjectStorage{err: boom, objects: map[string][]byte{}})
+	ctx := context.Background()
+
+	putErrs, _ := storageMetric(t, "put")
+	getErrs, _ := storageMetric(t, "get")
+	delErrs, _ := storageMetric(t, "delete")
+
+	assert.ErrorIs(t, store.UploadStream(ctx, "bucket", "k", strings.NewReader("x"), 1), boom)
+	_, err := store.Download(ctx, "bucket", "k")
+	assert.ErrorIs(t, err, boom)
+	assert.ErrorIs(t, store.Delete(ctx, "bucket", "k"), boom)
+
+	errs, _ := storageMetric(t, "put")
+	assert.Equal(t, putErrs+1, errs)
+	errs, _ = storageMetric(t, "get")
+	assert.Equal(t, getErrs+1, errs)
+	errs, _ = storageMetric(t, "delete")
+	assert.Equal(t, delErrs+1, errs)
+
+	spans := rec.Ended()
+	require.Len(t, spans, 3)
+	for _, s := range spans {
+		assert.Equal(t, codes.Error, s.Status().Code, s.Name())
+		assert.Equal(t, "backend down", s.Status().Description)
+	}
+}
+
+func TestInstrumentedObjectStorage_PresignedUploadUnsupported(t *testing.T) {
+	store := NewInstrumentedObjectStorage(&fakeObjectStorage{objects: map[string][]byte{}})
+	_, err := store.PresignedUploadURL(context.Background(), "bucket", "k", time.Minute)
+	assert.ErrorIs(t, err, errPresignedUploadUnsupported)
+	assert.False(t, store.SupportsPresignedUpload())
+	_, ok := AsPresignedUploader(store)
+	assert.False(t, ok)
+	assert.NoError(t, store.Close())
+}
+
+type fakePresignedStorage struct {
+	fakeObjectStorage
+}
+
+func (f *fakePresignedStorage) PresignedUploadURL(_ context.Context, bucket, objectName string, _ time.Duration) (string, error) {
+	return "https://store/" + bucket + "/" + objectName, nil
+}
+
+func TestAsPresignedUploader(t *testing.T) {
+	store := NewInstrumentedObjectStorage(&fakePresignedStorage{fakeObjectStorage{objects: map[string][]byte{}}})
+	ups, ok := AsPresignedUploader(store)
+	require.True(t, ok)
+	url, err := ups.PresignedUploadURL(context.Background(), "bucket", "k", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "https://store/bucket/k", url)
+}
+
+type fakeMultipartStorage struct {
+	fakeObjectStorage
+}
+
+func (f *fakeMultipartStorage) CreateMultipartUpload(context.Context, string, string, string) (string, error) {
+	return "upload-1", nil
+}
+
+func (f *fakeMultipartStorage) PresignedPartURL(context.Context, string, string, string, int, time.Duration) (string, error) {
+	return "", nil
+}
+
+func (f *fakeMultipartStorage) CompleteMultipartUpload(context.Context, string, string, string, []CompletedPart) error {
+	return nil
+}
+
+func (f *fakeMultipartStorage) AbortMultipartUpload(context.Context, string, string, string) error {
+	return nil
+}
+
+func TestAsMultipartUploader(t *testing.T) {
+	local, err := NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	store := NewInstrumentedObjectStorage(local)
+	assert.False(t, store.SupportsMultipart())
+	_, ok := AsMultipartUploader(store)
+	assert.False(t, ok, "local storage behind the wrapper")
+	_, err = store.CreateMultipartUpload(context.Background(), "bucket", "k", "video/mp4")
+	assert.ErrorIs(t, err, errMultipartUnsupported)
+
+	store = NewInstrumentedObjectStorage(&fakeMultipartStorage{fakeObjectStorage{objects: map[string][]byte{}}})
+	mu, ok := AsMultipartUploader(store)
+	require.True(t, ok)
+	uploadID, err := mu.CreateMultipartUpload(context.Background(), "bucket", "k", "video/mp4")
+	require.NoError(t, err)
+	assert.Equal(t, "upload-1", uploadID)
+}
@@ -13,6 +13,10 @@ var (
 	ErrChallengeUsed = errors.New("challenge already used")
 	// ErrChallengeNotFound is returned when a challenge ID does not exist.
 	ErrChallengeNotFound = errors.New("challenge not found")
+	// ErrChallengeExpired is returned when a challenge outlived its ExpiresAt.
+	ErrChallengeExpired = errors.New("challenge expired")
+	// ErrChallengeExhausted is returned when a challenge ran out of attempts.
+	ErrChallengeExhausted = errors.New("max attempts exceeded")
 )
 
 // UserRepository abstracts user data access.
@@ -42,6 +46,16 @@ type ChallengeStore interface {
 	MarkChallengeUsed(ctx context.Context, id string, usedAt time.Time) error
 }
 
+// AttemptChallengeStore is a ChallengeStore that also limits how many
+// times a challenge may be answered.
+type AttemptChallengeStore interface {
+	ChallengeStore
+	// AttemptChallenge atomically checks that a challenge is unused,
+	// unexpired and under its MaxAttempts, counts one attempt and returns
+	// the updated challenge. Expired and exhausted challenges are removed.
+	AttemptChallenge(ctx context.Context, id string, now time.Time) (*WalletChallenge, error)
+}
+
 // AuditLogger records security-relevant operations for compliance and forensics.
 type AuditLogger interface {
 	Log(ctx context.Context, action, actor, resource, resourceID string, success bool, errMsg, details string)
@@ -0,0 +1,378 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	shell "github.com/ipfs/go-ipfs-api"
+)
+
+const (
+	defaultIPFSAPIURL  = "localhost:5001"
+	defaultIPFSRoot    = "/streamgate"
+	defaultIPFSGateway = "https://ipfs.io"
+)
+
+// ErrNotContentAddressed is returned by CID when the storage backend does
+// not address objects by content.
+var ErrNotContentAddressed = errors.New("storage backend is not content-addressed")
+
+// ContentAddresser is implemented by stores that can report the content
+// identifier of an object, so it can be recorded and served by CID.
+type ContentAddresser interface {
+	CID(ctx context.Context, bucket, objectName string) (string, error)
+}
+
+// IPFSConfig holds IPFS storage configuration
+type IPFSConfig struct {
+	APIURL     string // Kubo RPC API; defaults to localhost:5001
+	Root       string // MFS directory buckets live under; defaults to /streamgate
+	GatewayURL string // Gateway presigned URLs point at; defaults to https://ipfs.io
+	// PinningServiceURL, when set, is an IPFS Pinning Service API endpoint
+	// (e.g. https://api.pinata.cloud/psa) every upload is also pinned to,
+	// so content outlives the local node.
+	PinningServiceURL string
+	PinningToken      string
+}
+
+// IPFSStorage stores objects in the mutable file system (MFS) of an IPFS
+// node, under <root>/<bucket>/<key>. Files in MFS are kept by the node's
+// garbage collector, which pins them locally. Presigned URLs are public
+// gateway URLs: anything stored here can be fetched by anyone who learns
+// its CID, so it suits content that is gated on playback, not secret.
+// Presigned and multipart uploads are not supported.
+type IPFSStorage struct {
+	shell   *shell.Shell
+	root    string
+	gateway string
+	pinURL  string
+	token   string
+	client  *http.Client
+}
+
+// NewIPFSStorage creates a new IPFS storage instance. It does not contact
+// the node; the first operation does.
+func NewIPFSStorage(config IPFSConfig) (*IPFSStorage, error) {
+	apiURL := config.APIURL
+	if apiURL == "" {
+		apiURL = defaultIPFSAPIURL
+	}
+	root := config.Root
+	if root == "" {
+		root = defaultIPFSRoot
+	}
+	root = path.Clean("/" + root)
+	if root == "/" {
+		return nil, errors.New("IPFS storage root must not be the MFS root")
+	}
+	gateway := config.GatewayURL
+	if gateway == "" {
+		gateway = defaultIPFSGateway
+	}
+	if config.PinningServiceURL != "" && config.PinningToken == "" {
+		return nil, errors.New("IPFS pinning service requires a token")
+	}
+	return &IPFSStorage{
+		shell:   shell.NewShell(apiURL),
+		root:    root,
+		gateway: strings.TrimSuffix(gateway, "/"),
+		pinURL:  strings.TrimSuffix(config.PinningServiceURL, "/"),
+		token:   config.PinningToken,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// path maps bucket and key to an MFS path, rejecting names that would
+// escape their bucket.
+func (is *IPFSStorage) path(bucket, key string) (string, error) {
+	if bucket == "" || bucket == "." || bucket == ".." || strings.Contains(bucket, "/") {
+		return "", fmt.Errorf("invalid bucket name %q", bucket)
+	}
+	dir := is.root + "/" + bucket
+	if key == "" {
+		return dir, nil
+	}
+	p := path.Join(dir, key)
+	if !strings.HasPrefix(p, dir+"/") {
+		return "", fmt.Errorf("invalid object name %q", key)
+	}
+	return p, nil
+}
+
+// Upload uploads to IPFS
+func (is *IPFSStorage) Upload(ctx context.Context, bucket, objectName string, data []byte) error {
+	return is.UploadStream(ctx, bucket, objectName, bytes.NewReader(data), int64(len(data)))
+}
+
+// UploadStream writes the object into MFS with CIDv1 raw leaves, then pins
+// it to the pinning service if one is configured.
+func (is *IPFSStorage) UploadStream(ctx context.Context, bucket, objectName string, reader io.Reader, size int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	p, err := is.path(bucket, objectName)
+	if err != nil {
+		return err
+	}
+	if size >= 0 {
+		reader = io.LimitReader(reader, size)
+	}
+	err = is.shell.FilesWrite(ctx, p, reader,
+		shell.FilesWrite.Create(true),
+		shell.FilesWrite.Parents(true),
+		shell.FilesWrite.Truncate(true),
+		shell.FilesWrite.RawLeaves(true),
+		shell.FilesWrite.CidVersion(1))
+	if err != nil {
+		return fmt.Errorf("failed to upload to IPFS: %w", err)
+	}
+	if is.pinURL == "" {
+		return nil
+	}
+
+	cid, err := is.CID(ctx, bucket, objectName)
+	if err != nil {
+		return err
+	}
+	return is.pinRemote(ctx, cid, bucket+"/"+objectName)
+}
+
+// UploadWithContentType uploads to IPFS. IPFS keeps no content type;
+// gateways sniff it and Stat derives it from the object name.
+func (is *IPFSStorage) UploadWithContentType(ctx context.Context, bucket, objectName string, data []byte, _ string) error {
+	return is.Upload(ctx, bucket, objectName, data)
+}
+
+func (is *IPFSStorage) UploadStreamWithContentType(ctx context.Context, bucket, objectName string, reader io.Reader, size int64, _ string) error {
+	return is.UploadStream(ctx, bucket, objectName, reader, size)
+}
+
+// pinRemote asks the pinning service to pin cid. The service fetches the
+// content from the network asynchronously; the request is only queued.
+func (is *IPFSStorage) pinRemote(ctx context.Context, cid, name string) error {
+	body, err := json.Marshal(map[string]string{"cid": cid, "name": name})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, is.pinURL+"/pins", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to pin %s: %w", cid, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+is.token)
+	resp, err := is.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to pin %s: %w", cid, err)
+	}
+	defer drainClose(resp)
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return fmt.Errorf("failed to pin %s: pinning service returned %d: %s", cid, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// Download downloads from IPFS and returns the entire content as a byte
+// slice. Only safe for objects smaller than maxDownloadSize (1 GB).
+func (is *IPFSStorage) Download(ctx context.Context, bucket, objectName string) ([]byte, error) {
+	rc, err := is.DownloadStream(ctx, bucket, objectName)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rc.Close() }()
+
+	buf := new(bytes.Buffer)
+	n, err := io.Copy(buf, io.LimitReader(rc, maxDownloadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IPFS object: %w", err)
+	}
+	if n > maxDownloadSize {
+		return nil, errors.New("IPFS object exceeds maximum download size (1 GB)")
+	}
+	return buf.Bytes(), nil
+}
+
+// DownloadStream returns an io.ReadCloser for streaming an object from
+// IPFS. The caller must close the reader when done.
+func (is *IPFSStorage) DownloadStream(ctx context.Context, bucket, objectName string) (io.ReadCloser, error) {
+	p, err := is.path(bucket, objectName)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	rc, err := is.shell.FilesRead(ctx, p)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to get object from IPFS: %w", err)
+	}
+	return &readCloserWithCancel{ReadCloser: rc, cancel: cancel}, nil
+}
+
+// Delete removes an object from MFS, leaving it to the node's garbage
+// collector. Remote pins are kept. Deleting a missing object is not an
+// error.
+func (is *IPFSStorage) Delete(ctx context.Context, bucket, objectName string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	p, err := is.path(bucket, objectName)
+	if err != nil {
+		return err
+	}
+	if err := is.shell.FilesRm(ctx, p, true); err != nil && !isIPFSNotExist(err) {
+		return fmt.Errorf("failed to delete from IPFS: %w", err)
+	}
+	return nil
+}
+
+func (is *IPFSStorage) DeleteObjects(ctx context.Context, bucket string, objectNames []string) error {
+	for _, name := range objectNames {
+		if err := is.Delete(ctx, bucket, name); err != nil {
+			return fmt.Errorf("failed to delete object %s from IPFS: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ListObjects lists objects in a bucket with a prefix
+func (is *IPFSStorage) ListObjects(ctx context.Context, bucket, prefix string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	dir, err := is.path(bucket, "")
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0)
+	if err := is.list(ctx, dir, "", prefix, &keys); err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+	return keys, nil
+}
+
+// list walks the MFS directory dir, whose keys start with rel, skipping
+// subdirectories that cannot hold keys with prefix.
+func (is *IPFSStorage) list(ctx context.Context, dir, rel, prefix string, keys *[]string) error {
+	entries, err := is.shell.FilesLs(ctx, dir, shell.FilesLs.Stat(true))
+	if err != nil {
+		if rel == "" && isIPFSNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, e := range entries {
+		key := rel + e.Name
+		if e.Type == 1 {
+			sub := key + "/"
+			if strings.HasPrefix(sub, prefix) || strings.HasPrefix(prefix, sub) {
+				if err := is.list(ctx, dir+"/"+e.Name, sub, prefix, keys); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(key, prefix) {
+			*keys = append(*keys, key)
+		}
+	}
+	return nil
+}
+
+// Exists checks if an object exists in IPFS
+func (is *IPFSStorage) Exists(ctx context.Context, bucket, objectName string) (bool, error) {
+	_, err := is.Stat(ctx, bucket, objectName)
+	if errors.Is(err, ErrObjectNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Stat reports an object's size and CID, as its ETag.
+func (is *IPFSStorage) Stat(ctx context.Context, bucket, objectName string) (ObjectInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	p, err := is.path(bucket, objectName)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	st, err := is.shell.FilesStat(ctx, p)
+	if err != nil {
+		if isIPFSNotExist(err) {
+			return ObjectInfo{}, ErrObjectNotFound
+		}
+		return ObjectInfo{}, fmt.Errorf("failed to stat IPFS object: %w", err)
+	}
+	if st.Type == "directory" {
+		return ObjectInfo{}, ErrObjectNotFound
+	}
+	return ObjectInfo{
+		Size:        int64(st.Size),
+		ContentType: detectContentTypeByExt(objectName),
+		ETag:        st.Hash,
+	}, nil
+}
+
+// CID returns the content identifier of an object.
+func (is *IPFSStorage) CID(ctx context.Context, bucket, objectName string) (string, error) {
+	info, err := is.Stat(ctx, bucket, objectName)
+	if err != nil {
+		return "", err
+	}
+	return info.ETag, nil
+}
+
+// CreateBucket creates the bucket directory in MFS
+func (is *IPFSStorage) CreateBucket(ctx context.Context, bucket string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	dir, err := is.path(bucket, "")
+	if err != nil {
+		return err
+	}
+	if err := is.shell.FilesMkdir(ctx, dir, shell.FilesMkdir.Parents(true)); err != nil {
+		return fmt.Errorf("failed to create bucket: %w", err)
+	}
+	return nil
+}
+
+// PresignedURL returns the gateway URL of the object's CID. It does not
+// expire: the CID always names the same bytes.
+func (is *IPFSStorage) PresignedURL(ctx context.Context, bucket, objectName string, _ time.Duration) (string, error) {
+	cid, err := is.CID(ctx, bucket, objectName)
+	if err != nil {
+		return "", err
+	}
+	return is.gateway + "/ipfs/" + cid, nil
+}
+
+// CID forwards to the wrapped store when it is content-addressed.
+func (s *InstrumentedObjectStorage) CID(ctx context.Context, bucket, objectName string) (string, error) {
+	ca, ok := s.inner.(ContentAddresser)
+	if !ok {
+		return "", ErrNotContentAddressed
+	}
+	ctx, done := s.observe(ctx, "cid", bucket, objectName, -1)
+	cid, err := ca.CID(ctx, bucket, objectName)
+	done(err)
+	return cid, err
+}
+
+// isIPFSNotExist reports whether err is the node's answer for a missing
+// MFS path.
+func isIPFSNotExist(err error) bool {
+	var serr *shell.Error
+	return errors.As(err, &serr) && strings.Contains(serr.Message, "does not exist")
+}
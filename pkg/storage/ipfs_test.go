@@ -0,0 +1,205 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKubo serves the MFS subset of the Kubo RPC API from memory.
+type fakeKubo struct {
+	mu    sync.Mutex
+	files map[string][]byte
+	dirs  map[string]bool
+}
+
+func (k *fakeKubo) cid(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "bafk" + hex.EncodeToString(sum[:8])
+}
+
+func (k *fakeKubo) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	p := r.URL.Query().Get("arg")
+	notExist := func() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"Message":"file does not exist","Code":0,"Type":"error"}`))
+	}
+	switch strings.TrimPrefix(r.URL.Path, "/api/v0/") {
+	case "version":
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"Version":"0.24.0"}`))
+	case "files/write":
+		mr, err := r.MultipartReader()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		part, err := mr.NextPart()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(part)
+		k.files[p] = data
+		for d := path.Dir(p); d != "/"; d = path.Dir(d) {
+			k.dirs[d] = true
+		}
+		w.WriteHeader(http.StatusOK)
+	case "files/read":
+		data, ok := k.files[p]
+		if !ok {
+			notExist()
+			return
+		}
+		_, _ = w.Write(data)
+	case "files/stat":
+		w.Header().Set("Content-Type", "application/json")
+		if data, ok := k.files[p]; ok {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"Hash": k.cid(data), "Size": len(data), "Type": "file"})
+			return
+		}
+		if k.dirs[p] {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"Hash": "bafydir", "Type": "directory"})
+			return
+		}
+		notExist()
+	case "files/ls":
+		if !k.dirs[p] {
+			notExist()
+			return
+		}
+		type entry struct {
+			Name string
+			Type int
+		}
+		seen := map[string]bool{}
+		var entries []entry
+		add := func(child string, typ int) {
+			if path.Dir(child) == p && !seen[child] {
+				seen[child] = true
+				entries = append(entries, entry{Name: path.Base(child), Type: typ})
+			}
+		}
+		for f := range k.files {
+			add(f, 0)
+		}
+		for d := range k.dirs {
+			add(d, 1)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"Entries": entries})
+	case "files/rm":
+		if _, ok := k.files[p]; !ok {
+			notExist()
+			return
+		}
+		delete(k.files, p)
+	case "files/mkdir":
+		k.dirs[p] = true
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestIPFSStorage(t *testing.T, cfg IPFSConfig) (*IPFSStorage, *fakeKubo) {
+	t.Helper()
+	kubo := &fakeKubo{files: map[string][]byte{}, dirs: map[string]bool{}}
+	srv := httptest.NewServer(kubo)
+	t.Cleanup(srv.Close)
+	cfg.APIURL = srv.URL
+	is, err := NewIPFSStorage(cfg)
+	require.NoError(t, err)
+	return is, kubo
+}
+
+func TestIPFSStorage_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	is, kubo := newTestIPFSStorage(t, IPFSConfig{GatewayURL: "http://gw.example/"})
+
+	require.NoError(t, is.CreateBucket(ctx, "media"))
+	require.NoError(t, is.Upload(ctx, "media", "streams/c1/720p/seg_000.ts", []byte("segment")))
+	require.NoError(t, is.UploadStream(ctx, "media", "streams/c1/720p/index.m3u8", strings.NewReader("#EXTM3U"), -1))
+	require.NoError(t, is.Upload(ctx, "media", "uploads/u1.mp4", []byte("video")))
+	assert.Contains(t, kubo.files, "/streamgate/media/streams/c1/720p/seg_000.ts")
+
+	data, err := is.Download(ctx, "media", "streams/c1/720p/seg_000.ts")
+	require.NoError(t, err)
+	assert.Equal(t, "segment", string(data))
+
+	info, err := is.Stat(ctx, "media", "streams/c1/720p/index.m3u8")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), info.Size)
+	assert.Equal(t, "application/vnd.apple.mpegurl", info.ContentType)
+
+	cid, err := is.CID(ctx, "media", "streams/c1/720p/seg_000.ts")
+	require.NoError(t, err)
+	assert.Equal(t, kubo.cid([]byte("segment")), cid)
+
+	u, err := is.PresignedURL(ctx, "media", "streams/c1/720p/seg_000.ts", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "http://gw.example/ipfs/"+cid, u)
+
+	keys, err := is.ListObjects(ctx, "media", "streams/c1/")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"streams/c1/720p/seg_000.ts", "streams/c1/720p/index.m3u8"}, keys)
+
+	keys, err = is.ListObjects(ctx, "other", "")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	require.NoError(t, is.DeleteObjects(ctx, "media", []string{"uploads/u1.mp4", "uploads/missing.mp4"}))
+	ok, err := is.Exists(ctx, "media", "uploads/u1.mp4")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	_, err = is.Stat(ctx, "media", "streams")
+	assert.ErrorIs(t, err, ErrObjectNotFound, "directories are not objects")
+
+	assert.Error(t, is.Upload(ctx, "media", "../escape", []byte("x")))
+}
+
+func TestIPFSStorage_PinsRemotely(t *testing.T) {
+	var pinned map[string]string
+	var auth string
+	psa := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/psa/pins" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		auth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&pinned)
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"requestid":"r1","status":"queued"}`))
+	}))
+	defer psa.Close()
+
+	is, kubo := newTestIPFSStorage(t, IPFSConfig{PinningServiceURL: psa.URL + "/psa", PinningToken: "tok"})
+	require.NoError(t, is.Upload(context.Background(), "media", "uploads/u1.mp4", []byte("video")))
+
+	assert.Equal(t, "Bearer tok", auth)
+	assert.Equal(t, kubo.cid([]byte("video")), pinned["cid"])
+	assert.Equal(t, "media/uploads/u1.mp4", pinned["name"])
+
+	_, err := NewIPFSStorage(IPFSConfig{PinningServiceURL: psa.URL})
+	assert.Error(t, err, "a pinning service needs a token")
+}
+
+func TestInstrumentedObjectStorage_CID(t *testing.T) {
+	_, err := NewInstrumentedObjectStorage(&fakeObjectStorage{objects: map[string][]byte{}}).CID(context.Background(), "b", "k")
+	assert.ErrorIs(t, err, ErrNotContentAddressed)
+}
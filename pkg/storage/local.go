@@ -0,0 +1,262 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// localTempPrefix marks files still being written; ListObjects skips them.
+const localTempPrefix = ".upload-"
+
+// LocalStorage stores objects as files under a root directory, one
+// subdirectory per bucket. It is meant for development and single-node
+// deployments: presigned URLs are file:// paths only this host can open,
+// and presigned or multipart uploads are not supported.
+type LocalStorage struct {
+	root string
+}
+
+// NewLocalStorage creates a local-disk storage rooted at dir, creating the
+// directory if needed.
+func NewLocalStorage(dir string) (*LocalStorage, error) {
+	if dir == "" {
+		return nil, errors.New("local storage requires a path")
+	}
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid local storage path: %w", err)
+	}
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create local storage directory: %w", err)
+	}
+	return &LocalStorage{root: root}, nil
+}
+
+// path maps bucket and key to a file under the root, rejecting names that
+// would escape their bucket.
+func (ls *LocalStorage) path(bucket, key string) (string, error) {
+	if bucket == "" || bucket == "." || bucket == ".." || strings.ContainsAny(bucket, `/\`) {
+		return "", fmt.Errorf("invalid bucket name %q", bucket)
+	}
+	dir := filepath.Join(ls.root, bucket)
+	if key == "" {
+		return dir, nil
+	}
+	p := filepath.Join(dir, filepath.FromSlash(key))
+	if rel, err := filepath.Rel(dir, p); err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("invalid object name %q", key)
+	}
+	return p, nil
+}
+
+// Upload writes an object to disk
+func (ls *LocalStorage) Upload(ctx context.Context, bucket, objectName string, data []byte) error {
+	return ls.UploadStream(ctx, bucket, objectName, bytes.NewReader(data), int64(len(data)))
+}
+
+// UploadStream writes to a temporary file renamed into place once complete,
+// so readers never see a partial object.
+func (ls *LocalStorage) UploadStream(ctx context.Context, bucket, objectName string, reader io.Reader, size int64) error {
+	p, err := ls.path(bucket, objectName)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o750); err != nil {
+		return fmt.Errorf("failed to create object directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), localTempPrefix+"*")
+	if err != nil {
+		return fmt.Errorf("failed to create object file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if size >= 0 {
+		reader = io.LimitReader(reader, size)
+	}
+	if _, err := io.Copy(tmp, &ctxReader{ctx: ctx, r: reader}); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return fmt.Errorf("failed to store object: %w", err)
+	}
+	return nil
+}
+
+// UploadWithContentType writes an object to disk. The content type is not
+// stored; Stat derives it from the object name.
+func (ls *LocalStorage) UploadWithContentType(ctx context.Context, bucket, objectName string, data []byte, _ string) error {
+	return ls.Upload(ctx, bucket, objectName, data)
+}
+
+func (ls *LocalStorage) UploadStreamWithContentType(ctx context.Context, bucket, objectName string, reader io.Reader, size int64, _ string) error {
+	return ls.UploadStream(ctx, bucket, objectName, reader, size)
+}
+
+// Download reads an object. Only safe for objects smaller than
+// maxDownloadSize (1 GB).
+func (ls *LocalStorage) Download(ctx context.Context, bucket, objectName string) ([]byte, error) {
+	rc, err := ls.DownloadStream(ctx, bucket, objectName)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rc.Close() }()
+
+	buf := new(bytes.Buffer)
+	n, err := io.Copy(buf, io.LimitReader(rc, maxDownloadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	if n > maxDownloadSize {
+		return nil, errors.New("object exceeds maximum download size (1 GB)")
+	}
+	return buf.Bytes(), nil
+}
+
+// DownloadStream opens an object for reading. The caller must close it.
+func (ls *LocalStorage) DownloadStream(_ context.Context, bucket, objectName string) (io.ReadCloser, error) {
+	p, err := ls.path(bucket, objectName)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p) // #nosec G304 -- path is confined to the storage root
+	if err != nil {
+		return nil, fmt.Errorf("failed to open object: %w", err)
+	}
+	return f, nil
+}
+
+// Delete removes an object. Deleting a missing object is not an error.
+func (ls *LocalStorage) Delete(_ context.Context, bucket, objectName string) error {
+	p, err := ls.path(bucket, objectName)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+	return nil
+}
+
+func (ls *LocalStorage) DeleteObjects(ctx context.Context, bucket string, objectNames []string) error {
+	for _, name := range objectNames {
+		if err := ls.Delete(ctx, bucket, name); err != nil {
+			return fmt.Errorf("failed to delete object %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ListObjects lists the objects in a bucket whose names start with prefix.
+func (ls *LocalStorage) ListObjects(ctx context.Context, bucket, prefix string) ([]string, error) {
+	dir, err := ls.path(bucket, "")
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0)
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && p == dir {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), localTempPrefix) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+	return keys, nil
+}
+
+// Exists checks if an object exists
+func (ls *LocalStorage) Exists(ctx context.Context, bucket, objectName string) (bool, error) {
+	_, err := ls.Stat(ctx, bucket, objectName)
+	if errors.Is(err, ErrObjectNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (ls *LocalStorage) Stat(_ context.Context, bucket, objectName string) (ObjectInfo, error) {
+	p, err := ls.path(bucket, objectName)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	fi, err := os.Stat(p)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && fi.IsDir()) {
+		return ObjectInfo{}, ErrObjectNotFound
+	}
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("failed to stat object: %w", err)
+	}
+	return ObjectInfo{
+		Size:         fi.Size(),
+		ContentType:  detectContentTypeByExt(objectName),
+		ETag:         fmt.Sprintf("%x-%x", fi.ModTime().UnixNano(), fi.Size()),
+		LastModified: fi.ModTime(),
+	}, nil
+}
+
+// CreateBucket creates the bucket directory
+func (ls *LocalStorage) CreateBucket(_ context.Context, bucket string) error {
+	dir, err := ls.path(bucket, "")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("failed to create bucket: %w", err)
+	}
+	return nil
+}
+
+// PresignedURL returns a file:// URL for the object. It does not expire and
+// is only usable on this host, e.g. as an FFmpeg input.
+func (ls *LocalStorage) PresignedURL(_ context.Context, bucket, objectName string, _ time.Duration) (string, error) {
+	p, err := ls.path(bucket, objectName)
+	if err != nil {
+		return "", err
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(p)}).String(), nil
+}
+
+// ctxReader stops a copy once its context is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
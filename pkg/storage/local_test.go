@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStorage_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	ls, err := NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, ls.CreateBucket(ctx, "media"))
+
+	require.NoError(t, ls.Upload(ctx, "media", "content/c1/master.m3u8", []byte("#EXTM3U")))
+	require.NoError(t, ls.UploadStream(ctx, "media", "content/c1/seg_000.ts", strings.NewReader("segment-bytes"), -1))
+	require.NoError(t, ls.UploadStreamWithContentType(ctx, "media", "uploads/u1", bytes.NewReader([]byte("0123456789")), 4, "video/mp4"))
+
+	data, err := ls.Download(ctx, "media", "content/c1/master.m3u8")
+	require.NoError(t, err)
+	assert.Equal(t, "#EXTM3U", string(data))
+
+	rc, err := ls.DownloadStream(ctx, "media", "uploads/u1")
+	require.NoError(t, err)
+	got, _ := io.ReadAll(rc)
+	_ = rc.Close()
+	assert.Equal(t, "0123", string(got), "stream is cut at the declared size")
+
+	info, err := ls.Stat(ctx, "media", "content/c1/master.m3u8")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), info.Size)
+	assert.Equal(t, "application/vnd.apple.mpegurl", info.ContentType)
+	assert.NotEmpty(t, info.ETag)
+
+	keys, err := ls.ListObjects(ctx, "media", "content/")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"content/c1/master.m3u8", "content/c1/seg_000.ts"}, keys)
+
+	require.NoError(t, ls.DeleteObjects(ctx, "media", []string{"content/c1/seg_000.ts", "content/c1/missing.ts"}))
+	ok, err := ls.Exists(ctx, "media", "content/c1/seg_000.ts")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	_, err = ls.Stat(ctx, "media", "content/c1/seg_000.ts")
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+
+	keys, err = ls.ListObjects(ctx, "empty", "")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestLocalStorage_RejectsEscapingNames(t *testing.T) {
+	ctx := context.Background()
+	ls, err := NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		bucket string
+		key    string
+	}{
+		{"parent key", "media", "../other/secret"},
+		{"nested parent key", "media", "a/../../secret"},
+		{"parent bucket", "..", "secret"},
+		{"bucket with separator", "media/sub", "x"},
+		{"empty bucket", "", "x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, ls.Upload(ctx, tt.bucket, tt.key, []byte("x")))
+			_, err := ls.Download(ctx, tt.bucket, tt.key)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLocalStorage_PresignedURL(t *testing.T) {
+	dir := t.TempDir()
+	ls, err := NewLocalStorage(dir)
+	require.NoError(t, err)
+
+	u, err := ls.PresignedURL(context.Background(), "media", "uploads/u1", time.Hour)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(u, "file://"), u)
+	assert.True(t, strings.HasSuffix(u, "/media/uploads/u1"), u)
+}
@@ -1,25 +1,94 @@
 package storage
 
 import (
+	"container/list"
 	"context"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
+
+	"github.com/rtcdance/streamgate/pkg/monitoring"
 )
 
+// DefaultMaxMemoryChallenges caps the in-memory challenge store when no
+// limit is configured.
+const DefaultMaxMemoryChallenges = 100000
+
+// ErrChallengeStoreFull is returned by SaveChallenge when the store is at
+// capacity and its policy is OverflowReject.
+var ErrChallengeStoreFull = errors.New("challenge store is full")
+
+// OverflowPolicy decides what a bounded in-memory store does with a new
+// entry once it is full.
+type OverflowPolicy string
+
+const (
+	// OverflowEvictOldest drops the oldest entry to make room.
+	OverflowEvictOldest OverflowPolicy = "evict_oldest"
+	// OverflowReject refuses the new entry.
+	OverflowReject OverflowPolicy = "reject"
+)
+
+// ParseOverflowPolicy parses a configured policy. Empty means
+// OverflowEvictOldest.
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch p := OverflowPolicy(s); p {
+	case "":
+		return OverflowEvictOldest, nil
+	case OverflowEvictOldest, OverflowReject:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown overflow policy %q (want %q or %q)", s, OverflowEvictOldest, OverflowReject)
+	}
+}
+
 // MemoryChallengeStore stores challenges in-memory for local development and tests.
+// It holds at most maxEntries challenges; see OverflowPolicy.
 type MemoryChallengeStore struct {
 	mu         sync.RWMutex
-	challenges map[string]*WalletChallenge
+	challenges map[string]*list.Element
+	order      *list.List // *WalletChallenge, newest at front
+	maxEntries int
+	policy     OverflowPolicy
 	stopCh     chan struct{}
 	wg         sync.WaitGroup
 }
 
+// MemoryChallengeStoreOption configures a MemoryChallengeStore.
+type MemoryChallengeStoreOption func(*MemoryChallengeStore)
+
+// WithMaxChallenges caps the number of stored challenges. n <= 0 keeps
+// DefaultMaxMemoryChallenges.
+func WithMaxChallenges(n int) MemoryChallengeStoreOption {
+	return func(m *MemoryChallengeStore) {
+		if n > 0 {
+			m.maxEntries = n
+		}
+	}
+}
+
+// WithChallengeOverflowPolicy sets what happens when the store is full.
+func WithChallengeOverflowPolicy(p OverflowPolicy) MemoryChallengeStoreOption {
+	return func(m *MemoryChallengeStore) {
+		if p != "" {
+			m.policy = p
+		}
+	}
+}
+
 // NewMemoryChallengeStore creates a new in-memory challenge store.
-func NewMemoryChallengeStore() *MemoryChallengeStore {
+func NewMemoryChallengeStore(opts ...MemoryChallengeStoreOption) *MemoryChallengeStore {
 	m := &MemoryChallengeStore{
-		challenges: make(map[string]*WalletChallenge),
+		challenges: make(map[string]*list.Element),
+		order:      list.New(),
+		maxEntries: DefaultMaxMemoryChallenges,
+		policy:     OverflowEvictOldest,
 		stopCh:     make(chan struct{}),
 	}
+	for _, opt := range opts {
+		opt(m)
+	}
 	m.wg.Add(1)
 	go m.cleanupLoop()
 	return m
@@ -45,42 +114,84 @@ func (m *MemoryChallengeStore) cleanupLoop() {
 		case <-m.stopCh:
 			return
 		case <-ticker.C:
-			m.evictExpired()
+			m.EvictExpired(time.Now())
 		}
 	}
 }
 
-func (m *MemoryChallengeStore) evictExpired() {
-	now := time.Now()
+// EvictExpired removes the challenges that expired before now. The store
+// does so every five minutes on its own.
+func (m *MemoryChallengeStore) EvictExpired(now time.Time) {
 	m.mu.Lock()
-	for id, ch := range m.challenges {
-		if now.After(ch.ExpiresAt) {
-			delete(m.challenges, id)
+	for id, elem := range m.challenges {
+		if now.After(elem.Value.(*WalletChallenge).ExpiresAt) {
+			m.remove(id, elem)
 		}
 	}
 	m.mu.Unlock()
 }
 
+func (m *MemoryChallengeStore) remove(id string, elem *list.Element) {
+	m.order.Remove(elem)
+	delete(m.challenges, id)
+}
+
+// makeRoom frees a slot for a new challenge. Expired and used challenges
+// at the tail are dropped first; the caller must hold m.mu.
+func (m *MemoryChallengeStore) makeRoom() error {
+	now := time.Now()
+	for len(m.challenges) >= m.maxEntries {
+		oldest := m.order.Back()
+		ch := oldest.Value.(*WalletChallenge)
+		if now.After(ch.ExpiresAt) || !ch.UsedAt.IsZero() {
+			m.remove(ch.ID, oldest)
+			continue
+		}
+		monitoring.MemoryStoreEvictionsTotal.WithLabelValues("challenge", string(m.policy)).Inc()
+		if m.policy == OverflowReject {
+			return ErrChallengeStoreFull
+		}
+		m.remove(ch.ID, oldest)
+	}
+	return nil
+}
+
 // SaveChallenge stores a challenge.
 func (m *MemoryChallengeStore) SaveChallenge(ctx context.Context, challenge *WalletChallenge) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	challengeCopy := *challenge
-	m.challenges[challenge.ID] = &challengeCopy
+	if elem, ok := m.challenges[challenge.ID]; ok {
+		elem.Value = &challengeCopy
+		m.order.MoveToFront(elem)
+		return nil
+	}
+	if err := m.makeRoom(); err != nil {
+		return err
+	}
+	m.challenges[challenge.ID] = m.order.PushFront(&challengeCopy)
 	return nil
 }
 
+// Len returns the number of stored challenges, including expired ones not
+// yet cleaned up.
+func (m *MemoryChallengeStore) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.challenges)
+}
+
 // GetChallenge retrieves a challenge by ID.
 func (m *MemoryChallengeStore) GetChallenge(ctx context.Context, id string) (*WalletChallenge, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	challenge, ok := m.challenges[id]
+	elem, ok := m.challenges[id]
 	if !ok {
 		return nil, ErrChallengeNotFound
 	}
-	copyData := *challenge
+	copyData := *elem.Value.(*WalletChallenge)
 	return &copyData, nil
 }
 
@@ -89,13 +200,40 @@ func (m *MemoryChallengeStore) MarkChallengeUsed(ctx context.Context, id string,
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	challenge, ok := m.challenges[id]
+	elem, ok := m.challenges[id]
 	if !ok {
 		return ErrChallengeNotFound
 	}
+	challenge := elem.Value.(*WalletChallenge)
 	if !challenge.UsedAt.IsZero() {
 		return ErrChallengeUsed
 	}
 	challenge.UsedAt = usedAt
 	return nil
 }
+
+// AttemptChallenge counts an attempt against a challenge.
+func (m *MemoryChallengeStore) AttemptChallenge(ctx context.Context, id string, now time.Time) (*WalletChallenge, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elem, ok := m.challenges[id]
+	if !ok {
+		return nil, ErrChallengeNotFound
+	}
+	challenge := elem.Value.(*WalletChallenge)
+	if !challenge.UsedAt.IsZero() {
+		return nil, ErrChallengeUsed
+	}
+	if now.After(challenge.ExpiresAt) {
+		m.remove(id, elem)
+		return nil, ErrChallengeExpired
+	}
+	if challenge.MaxAttempts > 0 && challenge.Attempts >= challenge.MaxAttempts {
+		m.remove(id, elem)
+		return nil, ErrChallengeExhausted
+	}
+	challenge.Attempts++
+	copyData := *challenge
+	return &copyData, nil
+}
@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/minio/minio-go/v7"
+)
+
+var errMultipartUnsupported = errors.New("multipart upload not supported by object storage")
+
+// CompletedPart is a part a client has PUT to a presigned part URL, with
+// the ETag the store returned for it.
+type CompletedPart struct {
+	PartNumber int    `json:"part_number"`
+	ETag       string `json:"etag"`
+}
+
+// MultipartUploader lets clients send large objects straight to the store
+// as presigned part uploads. MinIO and S3 both implement it.
+type MultipartUploader interface {
+	CreateMultipartUpload(ctx context.Context, bucket, key, contentType string) (string, error)
+	PresignedPartURL(ctx context.Context, bucket, key, uploadID string, partNumber int, expiry time.Duration) (string, error)
+	CompleteMultipartUpload(ctx context.Context, bucket, key, uploadID string, parts []CompletedPart) error
+	AbortMultipartUpload(ctx context.Context, bucket, key, uploadID string) error
+}
+
+// AsMultipartUploader returns store as a MultipartUploader if it can take
+// multipart uploads. Use it rather than a type assertion: the instrumented
+// wrapper has the methods whatever the backend is.
+func AsMultipartUploader(store interface{}) (MultipartUploader, bool) {
+	if s, ok := store.(*InstrumentedObjectStorage); ok {
+		if !s.SupportsMultipart() {
+			return nil, false
+		}
+		return s, true
+	}
+	mu, ok := store.(MultipartUploader)
+	return mu, ok
+}
+
+// sortedParts returns parts in ascending part-number order, which both
+// stores require on completion.
+func sortedParts(parts []CompletedPart) []CompletedPart {
+	out := append([]CompletedPart(nil), parts...)
+	sort.Slice(out, func(i, j int) bool { return out[i].PartNumber < out[j].PartNumber })
+	return out
+}
+
+func (s3s *S3Storage) CreateMultipartUpload(ctx context.Context, bucket, key, contentType string) (string, error) {
+	input := &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	out, err := s3s.client.CreateMultipartUploadWithContext(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("failed to create multipart upload: %w", err)
+	}
+	return aws.StringValue(out.UploadId), nil
+}
+
+func (s3s *S3Storage) PresignedPartURL(ctx context.Context, bucket, key, uploadID string, partNumber int, expiry time.Duration) (string, error) {
+	req, _ := s3s.client.UploadPartRequest(&s3.UploadPartInput{
+		Bucket:     aws.String(bucket),
+		Key:        aws.String(key),
+		UploadId:   aws.String(uploadID),
+		PartNumber: aws.Int64(int64(partNumber)),
+	})
+	presignedURL, err := req.Presign(expiry)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign part %d: %w", partNumber, err)
+	}
+	return presignedURL, nil
+}
+
+func (s3s *S3Storage) CompleteMultipartUpload(ctx context.Context, bucket, key, uploadID string, parts []CompletedPart) error {
+	completed := make([]*s3.CompletedPart, 0, len(parts))
+	for _, p := range sortedParts(parts) {
+		completed = append(completed, &s3.CompletedPart{
+			ETag:       aws.String(p.ETag),
+			PartNumber: aws.Int64(int64(p.PartNumber)),
+		})
+	}
+	_, err := s3s.client.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(bucket),
+		Key:             aws.String(key),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+	return nil
+}
+
+func (s3s *S3Storage) AbortMultipartUpload(ctx context.Context, bucket, key, uploadID string) error {
+	_, err := s3s.client.AbortMultipartUploadWithContext(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to abort multipart upload: %w", err)
+	}
+	return nil
+}
+
+func (ms *MinIOStorage) CreateMultipartUpload(ctx context.Context, bucket, objectName, contentType string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	uploadID, err := minio.Core{Client: ms.client}.NewMultipartUpload(ctx, bucket, objectName, minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		return "", fmt.Errorf("failed to create multipart upload: %w", err)
+	}
+	return uploadID, nil
+}
+
+func (ms *MinIOStorage) PresignedPartURL(ctx context.Context, bucket, objectName, uploadID string, partNumber int, expiry time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	params := url.Values{}
+	params.Set("partNumber", strconv.Itoa(partNumber))
+	params.Set("uploadId", uploadID)
+	u, err := ms.client.Presign(ctx, http.MethodPut, bucket, objectName, expiry, params)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign part %d: %w", partNumber, err)
+	}
+	return u.String(), nil
+}
+
+func (ms *MinIOStorage) CompleteMultipartUpload(ctx context.Context, bucket, objectName, uploadID string, parts []CompletedPart) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	completed := make([]minio.CompletePart, 0, len(parts))
+	for _, p := range sortedParts(parts) {
+		completed = append(completed, minio.CompletePart{PartNumber: p.PartNumber, ETag: p.ETag})
+	}
+	if _, err := (minio.Core{Client: ms.client}).CompleteMultipartUpload(ctx, bucket, objectName, uploadID, completed, minio.PutObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+	return nil
+}
+
+func (ms *MinIOStorage) AbortMultipartUpload(ctx context.Context, bucket, objectName, uploadID string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if err := (minio.Core{Client: ms.client}).AbortMultipartUpload(ctx, bucket, objectName, uploadID); err != nil {
+		return fmt.Errorf("failed to abort multipart upload: %w", err)
+	}
+	return nil
+}
+
+// SupportsMultipart reports whether the wrapped store takes multipart
+// uploads.
+func (s *InstrumentedObjectStorage) SupportsMultipart() bool {
+	_, ok := AsMultipartUploader(s.inner)
+	return ok
+}
+
+func (s *InstrumentedObjectStorage) multipart() (MultipartUploader, error) {
+	mu, ok := s.inner.(MultipartUploader)
+	if !ok {
+		return nil, errMultipartUnsupported
+	}
+	return mu, nil
+}
+
+func (s *InstrumentedObjectStorage) CreateMultipartUpload(ctx context.Context, bucket, objectName, contentType string) (string, error) {
+	mu, err := s.multipart()
+	if err != nil {
+		return "", err
+	}
+	ctx, done := s.observe(ctx, "multipart_create", bucket, objectName, -1)
+	uploadID, err := mu.CreateMultipartUpload(ctx, bucket, objectName, contentType)
+	done(err)
+	return uploadID, err
+}
+
+func (s *InstrumentedObjectStorage) PresignedPartURL(ctx context.Context, bucket, objectName, uploadID string, partNumber int, expiry time.Duration) (string, error) {
+	mu, err := s.multipart()
+	if err != nil {
+		return "", err
+	}
+	ctx, done := s.observe(ctx, "presign_part", bucket, objectName, -1)
+	u, err := mu.PresignedPartURL(ctx, bucket, objectName, uploadID, partNumber, expiry)
+	done(err)
+	return u, err
+}
+
+func (s *InstrumentedObjectStorage) CompleteMultipartUpload(ctx context.Context, bucket, objectName, uploadID string, parts []CompletedPart) error {
+	mu, err := s.multipart()
+	if err != nil {
+		return err
+	}
+	ctx, done := s.observe(ctx, "multipart_complete", bucket, objectName, -1)
+	err = mu.CompleteMultipartUpload(ctx, bucket, objectName, uploadID, parts)
+	done(err)
+	return err
+}
+
+func (s *InstrumentedObjectStorage) AbortMultipartUpload(ctx context.Context, bucket, objectName, uploadID string) error {
+	mu, err := s.multipart()
+	if err != nil {
+		return err
+	}
+	ctx, done := s.observe(ctx, "multipart_abort", bucket, objectName, -1)
+	err = mu.AbortMultipartUpload(ctx, bucket, objectName, uploadID)
+	done(err)
+	return err
+}
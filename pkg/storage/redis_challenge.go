@@ -107,7 +107,7 @@ func (r *RedisChallengeStore) GetChallenge(ctx context.Context, id string) (*Wal
 	data, err := r.client.Get(ctx, id).Bytes()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
-			return nil, fmt.Errorf("challenge not found: %s", id)
+			return nil, fmt.Errorf("%w: %s", ErrChallengeNotFound, id)
 		}
 		return nil, fmt.Errorf("failed to get challenge: %w", err)
 	}
@@ -184,6 +184,74 @@ func (r *RedisChallengeStore) MarkChallengeUsed(ctx context.Context, id string,
 	return nil
 }
 
+// attemptChallengeLua atomically checks that a challenge is unused and
+// under its max_attempts and counts one attempt, keeping the key's TTL.
+// It returns the updated challenge JSON or one of NOT_FOUND, ALREADY_USED,
+// EXHAUSTED. Exhausted challenges are deleted.
+var attemptChallengeLua = redis.NewScript(`
+local data = redis.call('GET', KEYS[1])
+if not data then
+  return 'NOT_FOUND'
+end
+local decoded = cjson.decode(data)
+if decoded.used_at and decoded.used_at ~= '' and decoded.used_at ~= '0001-01-01T00:00:00Z' then
+  return 'ALREADY_USED'
+end
+local attempts = tonumber(decoded.attempts) or 0
+local maxAttempts = tonumber(decoded.max_attempts) or 0
+if maxAttempts > 0 and attempts >= maxAttempts then
+  redis.call('DEL', KEYS[1])
+  return 'EXHAUSTED'
+end
+decoded.attempts = attempts + 1
+local encoded = cjson.encode(decoded)
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl > 0 then
+  redis.call('SET', KEYS[1], encoded, 'PX', ttl)
+else
+  redis.call('SET', KEYS[1], encoded)
+end
+return encoded
+`)
+
+// AttemptChallenge counts an attempt against a challenge. ExpiresAt never
+// changes once saved, so it is checked before the script runs.
+func (r *RedisChallengeStore) AttemptChallenge(ctx context.Context, id string, now time.Time) (*WalletChallenge, error) {
+	challenge, err := r.GetChallenge(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if now.After(challenge.ExpiresAt) {
+		ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+		defer cancel()
+		if err := r.client.Del(ctx, id).Err(); err != nil {
+			return nil, fmt.Errorf("failed to delete expired challenge: %w", err)
+		}
+		return nil, ErrChallengeExpired
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	result, err := attemptChallengeLua.Run(ctx, r.client, []string{id}).Text()
+	if err != nil {
+		return nil, fmt.Errorf("failed to record challenge attempt: %w", err)
+	}
+	switch result {
+	case "NOT_FOUND":
+		return nil, ErrChallengeNotFound
+	case "ALREADY_USED":
+		return nil, ErrChallengeUsed
+	case "EXHAUSTED":
+		return nil, ErrChallengeExhausted
+	}
+
+	var attempted WalletChallenge
+	if err := json.Unmarshal([]byte(result), &attempted); err != nil {
+		return nil, fmt.Errorf("failed to decode challenge: %w", err)
+	}
+	return &attempted, nil
+}
+
 // Close closes the Redis connection.
 func (r *RedisChallengeStore) Close() error {
 	if r.client != nil {
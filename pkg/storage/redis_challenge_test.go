@@ -7,6 +7,7 @@ import (
 
 	"github.com/alicebob/miniredis/v2"
 	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -172,6 +173,35 @@ func TestRedisChallengeStore_TTLExpiration(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestRedisChallengeStore_AttemptChallenge(t *testing.T) {
+	store, mr := setupRedisChallengeStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	require.NoError(t, store.SaveChallenge(ctx, &WalletChallenge{ID: "a", ExpiresAt: now.Add(time.Minute), MaxAttempts: 1}))
+	got, err := store.AttemptChallenge(ctx, "a", now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.Attempts)
+	assert.InDelta(t, store.ttl.Seconds(), mr.TTL("a").Seconds(), 1, "an attempt keeps the key's TTL")
+	_, err = store.AttemptChallenge(ctx, "a", now)
+	assert.ErrorIs(t, err, ErrChallengeExhausted)
+	assert.False(t, mr.Exists("a"), "exhausted challenges are removed")
+
+	require.NoError(t, store.SaveChallenge(ctx, &WalletChallenge{ID: "b", ExpiresAt: now.Add(time.Minute)}))
+	_, err = store.AttemptChallenge(ctx, "b", now.Add(2*time.Minute))
+	assert.ErrorIs(t, err, ErrChallengeExpired)
+	assert.False(t, mr.Exists("b"), "expired challenges are removed")
+
+	require.NoError(t, store.SaveChallenge(ctx, &WalletChallenge{ID: "c", ExpiresAt: now.Add(time.Minute)}))
+	require.NoError(t, store.MarkChallengeUsed(ctx, "c", now))
+	_, err = store.AttemptChallenge(ctx, "c", now)
+	assert.ErrorIs(t, err, ErrChallengeUsed)
+
+	_, err = store.AttemptChallenge(ctx, "missing", now)
+	assert.ErrorIs(t, err, ErrChallengeNotFound)
+	assert.ErrorIs(t, store.MarkChallengeUsed(ctx, "missing", now), ErrChallengeNotFound)
+}
+
 func TestMemoryChallengeStore_SaveAndGet(t *testing.T) {
 	store := NewMemoryChallengeStore()
 	ctx := context.Background()
@@ -384,18 +414,16 @@ func TestMemoryChallengeStore_EvictExpired(t *testing.T) {
 	store := NewMemoryChallengeStore()
 	defer store.Close()
 
-	store.mu.Lock()
-	store.challenges["expired-1"] = &WalletChallenge{
+	require.NoError(t, store.SaveChallenge(context.Background(), &WalletChallenge{
 		ID:        "expired-1",
 		ExpiresAt: time.Now().Add(-time.Hour),
-	}
-	store.challenges["valid-1"] = &WalletChallenge{
+	}))
+	require.NoError(t, store.SaveChallenge(context.Background(), &WalletChallenge{
 		ID:        "valid-1",
 		ExpiresAt: time.Now().Add(time.Hour),
-	}
-	store.mu.Unlock()
+	}))
 
-	store.evictExpired()
+	store.EvictExpired(time.Now())
 
 	store.mu.RLock()
 	_, hasExpired := store.challenges["expired-1"]
@@ -410,14 +438,12 @@ func TestMemoryChallengeStore_EvictExpired_AllValid(t *testing.T) {
 	store := NewMemoryChallengeStore()
 	defer store.Close()
 
-	store.mu.Lock()
-	store.challenges["valid-1"] = &WalletChallenge{
+	require.NoError(t, store.SaveChallenge(context.Background(), &WalletChallenge{
 		ID:        "valid-1",
 		ExpiresAt: time.Now().Add(time.Hour),
-	}
-	store.mu.Unlock()
+	}))
 
-	store.evictExpired()
+	store.EvictExpired(time.Now())
 
 	store.mu.RLock()
 	count := len(store.challenges)
@@ -430,7 +456,7 @@ func TestMemoryChallengeStore_EvictExpired_Empty(t *testing.T) {
 	store := NewMemoryChallengeStore()
 	defer store.Close()
 
-	assert.NotPanics(t, store.evictExpired)
+	assert.NotPanics(t, func() { store.EvictExpired(time.Now()) })
 }
 
 func TestMemoryChallengeStore_SaveChallenge_ReturnsCopy(t *testing.T) {
@@ -454,3 +480,155 @@ func TestMemoryChallengeStore_SaveChallenge_ReturnsCopy(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "original", original.Nonce)
 }
+
+func memoryStoreEvictions(t *testing.T, store, policy string) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, mf := range families {
+		if mf.GetName() != "streamgate_memory_store_evictions_total" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["store"] == store && labels["policy"] == policy {
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func saveTestChallenges(t *testing.T, store *MemoryChallengeStore, ids ...string) []error {
+	t.Helper()
+	errs := make([]error, len(ids))
+	for i, id := range ids {
+		errs[i] = store.SaveChallenge(context.Background(), &WalletChallenge{
+			ID:        id,
+			ExpiresAt: time.Now().Add(time.Hour),
+		})
+	}
+	return errs
+}
+
+func TestMemoryChallengeStore_CapEvictsOldest(t *testing.T) {
+	store := NewMemoryChallengeStore(WithMaxChallenges(3))
+	defer store.Close()
+	before := memoryStoreEvictions(t, "challenge", "evict_oldest")
+
+	for _, err := range saveTestChallenges(t, store, "c1", "c2", "c3", "c4", "c5") {
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 3, store.Len())
+	for _, id := range []string{"c1", "c2"} {
+		_, err := store.GetChallenge(context.Background(), id)
+		assert.ErrorIs(t, err, ErrChallengeNotFound, id)
+	}
+	for _, id := range []string{"c3", "c4", "c5"} {
+		_, err := store.GetChallenge(context.Background(), id)
+		assert.NoError(t, err, id)
+	}
+	assert.Equal(t, before+2, memoryStoreEvictions(t, "challenge", "evict_oldest"))
+}
+
+func TestMemoryChallengeStore_CapRejectsNew(t *testing.T) {
+	store := NewMemoryChallengeStore(WithMaxChallenges(2), WithChallengeOverflowPolicy(OverflowReject))
+	defer store.Close()
+	before := memoryStoreEvictions(t, "challenge", "reject")
+
+	errs := saveTestChallenges(t, store, "c1", "c2", "c3")
+	require.NoError(t, errs[0])
+	require.NoError(t, errs[1])
+	assert.ErrorIs(t, errs[2], ErrChallengeStoreFull)
+
+	assert.Equal(t, 2, store.Len())
+	_, err := store.GetChallenge(context.Background(), "c1")
+	assert.NoError(t, err, "existing challenges are kept")
+	assert.Equal(t, before+1, memoryStoreEvictions(t, "challenge", "reject"))
+
+	require.NoError(t, store.SaveChallenge(context.Background(), &WalletChallenge{ID: "c2", ExpiresAt: time.Now().Add(time.Hour)}),
+		"updating an existing challenge does not need a free slot")
+}
+
+func TestMemoryChallengeStore_CapReclaimsExpiredFirst(t *testing.T) {
+	store := NewMemoryChallengeStore(WithMaxChallenges(2), WithChallengeOverflowPolicy(OverflowReject))
+	defer store.Close()
+
+	require.NoError(t, store.SaveChallenge(context.Background(), &WalletChallenge{ID: "stale", ExpiresAt: time.Now().Add(-time.Minute)}))
+	for _, err := range saveTestChallenges(t, store, "c1", "c2") {
+		require.NoError(t, err, "an expired challenge frees its slot")
+	}
+	_, err := store.GetChallenge(context.Background(), "stale")
+	assert.ErrorIs(t, err, ErrChallengeNotFound)
+}
+
+func TestMemoryChallengeStore_CapReclaimsUsed(t *testing.T) {
+	store := NewMemoryChallengeStore(WithMaxChallenges(2), WithChallengeOverflowPolicy(OverflowReject))
+	defer store.Close()
+	ctx := context.Background()
+
+	errs := saveTestChallenges(t, store, "c1", "c2")
+	require.NoError(t, errs[0])
+	require.NoError(t, errs[1])
+	require.NoError(t, store.MarkChallengeUsed(ctx, "c1", time.Now()))
+
+	require.NoError(t, store.SaveChallenge(ctx, &WalletChallenge{ID: "c3", ExpiresAt: time.Now().Add(time.Hour)}),
+		"a used challenge frees its slot")
+	_, err := store.GetChallenge(ctx, "c1")
+	assert.ErrorIs(t, err, ErrChallengeNotFound)
+}
+
+func TestMemoryChallengeStore_AttemptChallenge(t *testing.T) {
+	store := NewMemoryChallengeStore()
+	defer store.Close()
+	ctx := context.Background()
+	now := time.Now()
+
+	require.NoError(t, store.SaveChallenge(ctx, &WalletChallenge{ID: "limited", ExpiresAt: now.Add(time.Minute), MaxAttempts: 2}))
+	for i := 1; i <= 2; i++ {
+		got, err := store.AttemptChallenge(ctx, "limited", now)
+		require.NoError(t, err)
+		assert.Equal(t, i, got.Attempts)
+	}
+	_, err := store.AttemptChallenge(ctx, "limited", now)
+	assert.ErrorIs(t, err, ErrChallengeExhausted)
+	_, err = store.GetChallenge(ctx, "limited")
+	assert.ErrorIs(t, err, ErrChallengeNotFound, "an exhausted challenge is removed")
+
+	require.NoError(t, store.SaveChallenge(ctx, &WalletChallenge{ID: "expired", ExpiresAt: now.Add(-time.Second)}))
+	_, err = store.AttemptChallenge(ctx, "expired", now)
+	assert.ErrorIs(t, err, ErrChallengeExpired)
+	_, err = store.GetChallenge(ctx, "expired")
+	assert.ErrorIs(t, err, ErrChallengeNotFound, "an expired challenge is removed")
+
+	require.NoError(t, store.SaveChallenge(ctx, &WalletChallenge{ID: "used", ExpiresAt: now.Add(time.Minute)}))
+	require.NoError(t, store.MarkChallengeUsed(ctx, "used", now))
+	_, err = store.AttemptChallenge(ctx, "used", now)
+	assert.ErrorIs(t, err, ErrChallengeUsed)
+
+	require.NoError(t, store.SaveChallenge(ctx, &WalletChallenge{ID: "unlimited", ExpiresAt: now.Add(time.Minute)}))
+	for i := 0; i < 5; i++ {
+		_, err = store.AttemptChallenge(ctx, "unlimited", now)
+		require.NoError(t, err, "MaxAttempts 0 means no limit")
+	}
+
+	_, err = store.AttemptChallenge(ctx, "missing", now)
+	assert.ErrorIs(t, err, ErrChallengeNotFound)
+}
+
+func TestParseOverflowPolicy(t *testing.T) {
+	p, err := ParseOverflowPolicy("")
+	require.NoError(t, err)
+	assert.Equal(t, OverflowEvictOldest, p)
+
+	p, err = ParseOverflowPolicy("reject")
+	require.NoError(t, err)
+	assert.Equal(t, OverflowReject, p)
+
+	_, err = ParseOverflowPolicy("lifo")
+	assert.Error(t, err)
+}
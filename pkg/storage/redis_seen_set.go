@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const seenSetKeyPrefix = "event_seen:"
+
+// RedisSeenSet records processed event IDs in Redis so duplicate deliveries
+// are skipped across every replica. Each ID is a key set with SET NX, so
+// exactly one consumer claims it, and Redis evicts it after its TTL.
+type RedisSeenSet struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisSeenSet creates a seen-set on a shared client. namespace keeps
+// subscribers from claiming each other's IDs, e.g. "transcode".
+func NewRedisSeenSet(client *redis.Client, namespace string) *RedisSeenSet {
+	return &RedisSeenSet{client: client, prefix: seenSetKeyPrefix + namespace + ":"}
+}
+
+// MarkSeen claims id for ttl, returning false if it is already claimed.
+func (s *RedisSeenSet) MarkSeen(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	ok, err := s.client.SetNX(ctx, s.prefix+id, time.Now().UTC().Format(time.RFC3339), ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to mark event seen: %w", err)
+	}
+	return ok, nil
+}
+
+// Forget releases a claim so the next delivery of id is processed.
+func (s *RedisSeenSet) Forget(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	if err := s.client.Del(ctx, s.prefix+id).Err(); err != nil {
+		return fmt.Errorf("failed to forget event: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisSeenSet_MarkSeen(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	s := NewRedisSeenSet(client, "transcode")
+	ctx := context.Background()
+
+	first, err := s.MarkSeen(ctx, "task-1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, first)
+	assert.True(t, mr.Exists("event_seen:transcode:task-1"))
+
+	first, err = s.MarkSeen(ctx, "task-1", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, first, "a second replica sees the claim")
+
+	other := NewRedisSeenSet(client, "notify")
+	first, err = other.MarkSeen(ctx, "task-1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, first, "namespaces are independent")
+
+	mr.FastForward(time.Minute)
+	first, err = s.MarkSeen(ctx, "task-1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, first, "claims expire with their TTL")
+
+	require.NoError(t, s.Forget(ctx, "task-1"))
+	assert.False(t, mr.Exists("event_seen:transcode:task-1"))
+}
+
+func TestRedisSeenSet_Unavailable(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "localhost:0"})
+	defer client.Close()
+
+	s := NewRedisSeenSet(client, "transcode")
+	_, err := s.MarkSeen(context.Background(), "task-1", time.Minute)
+	assert.Error(t, err)
+	assert.Error(t, s.Forget(context.Background(), "task-1"))
+}
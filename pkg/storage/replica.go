@@ -0,0 +1,259 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultReplicaRetryAfter is how long a failed replica is skipped before
+// reads are routed to it again.
+const DefaultReplicaRetryAfter = 30 * time.Second
+
+// ReplicaConfig tunes read-replica routing.
+type ReplicaConfig struct {
+	// RetryAfter is how long a replica is skipped after a failed read.
+	// Zero uses DefaultReplicaRetryAfter.
+	RetryAfter time.Duration
+}
+
+type primaryKey struct{}
+
+// WithPrimary marks ctx so reads made with it go to the primary, for
+// callers that must observe their own just-committed writes.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+func primaryRequested(ctx context.Context) bool {
+	v, _ := ctx.Value(primaryKey{}).(bool)
+	return v
+}
+
+type replica struct {
+	db DB
+	// downUntil is the UnixNano time before which the replica is skipped.
+	downUntil atomic.Int64
+}
+
+// ReplicaDB routes plain reads to a pool of read replicas and everything
+// else — writes, transactions, locking reads and INSERT ... RETURNING —
+// to the primary. Replicas are used round-robin; one that fails a read is
+// skipped for RetryAfter and the read is retried on the primary, so a
+// replica outage degrades to primary-only rather than to errors.
+type ReplicaDB struct {
+	primary    DB
+	replicas   []*replica
+	next       atomic.Uint64
+	retryAfter time.Duration
+	now        func() time.Time
+	closeOnce  sync.Once
+}
+
+// NewReplicaDB wraps primary with read routing across replicas. With no
+// replicas every call goes to the primary.
+func NewReplicaDB(primary DB, replicas []DB, cfg ReplicaConfig) *ReplicaDB {
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = DefaultReplicaRetryAfter
+	}
+	r := &ReplicaDB{primary: primary, retryAfter: cfg.RetryAfter, now: time.Now}
+	for _, db := range replicas {
+		if db != nil {
+			r.replicas = append(r.replicas, &replica{db: db})
+		}
+	}
+	return r
+}
+
+// Primary returns the primary DB.
+func (r *ReplicaDB) Primary() DB {
+	return r.primary
+}
+
+// HealthyReplicas reports how many replicas are currently eligible for reads.
+func (r *ReplicaDB) HealthyReplicas() int {
+	now := r.now().UnixNano()
+	n := 0
+	for _, rep := range r.replicas {
+		if rep.downUntil.Load() <= now {
+			n++
+		}
+	}
+	return n
+}
+
+// pick returns the next healthy replica, or nil when the read must go to
+// the primary.
+func (r *ReplicaDB) pick(ctx context.Context, query string) *replica {
+	if len(r.replicas) == 0 || primaryRequested(ctx) || !isReadOnlyQuery(query) {
+		return nil
+	}
+	now := r.now().UnixNano()
+	start := r.next.Add(1)
+	for i := range r.replicas {
+		rep := r.replicas[(start+uint64(i))%uint64(len(r.replicas))]
+		if rep.downUntil.Load() <= now {
+			return rep
+		}
+	}
+	return nil
+}
+
+// markDown takes rep out of rotation unless the failure was the caller's
+// own cancellation.
+func (r *ReplicaDB) markDown(ctx context.Context, rep *replica) {
+	if ctx.Err() != nil {
+		return
+	}
+	rep.downUntil.Store(r.now().Add(r.retryAfter).UnixNano())
+}
+
+// Query runs read-only queries on a replica, falling back to the primary
+// if the replica fails.
+func (r *ReplicaDB) Query(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	if rep := r.pick(ctx, query); rep != nil {
+		rows, err := rep.db.Query(ctx, query, args...)
+		if err == nil {
+			return rows, nil
+		}
+		r.markDown(ctx, rep)
+		if ctx.Err() != nil {
+			return nil, err
+		}
+	}
+	return r.primary.Query(ctx, query, args...)
+}
+
+// QueryRow runs read-only queries on a replica. Row errors surface at
+// Scan, so the fallback to the primary happens there; sql.ErrNoRows is a
+// result, not a failure, and is returned as is.
+func (r *ReplicaDB) QueryRow(ctx context.Context, query string, args ...interface{}) *CancelRow {
+	rep := r.pick(ctx, query)
+	if rep == nil {
+		return r.primary.QueryRow(ctx, query, args...)
+	}
+	return &CancelRow{
+		row: &fallbackRow{
+			ctx:     ctx,
+			replica: rep.db.QueryRow(ctx, query, args...),
+			fallback: func() *CancelRow {
+				r.markDown(ctx, rep)
+				return r.primary.QueryRow(ctx, query, args...)
+			},
+		},
+		cancel: func() {},
+	}
+}
+
+// fallbackRow scans a replica row and retries on the primary if the
+// replica could not answer.
+type fallbackRow struct {
+	ctx      context.Context
+	replica  *CancelRow
+	fallback func() *CancelRow
+}
+
+func (f *fallbackRow) Scan(dest ...interface{}) error {
+	err := f.replica.Scan(dest...)
+	if err == nil || errors.Is(err, sql.ErrNoRows) || f.ctx.Err() != nil {
+		return err
+	}
+	return f.fallback().Scan(dest...)
+}
+
+// Exec always runs on the primary.
+func (r *ReplicaDB) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return r.primary.Exec(ctx, query, args...)
+}
+
+// Begin always runs on the primary.
+func (r *ReplicaDB) Begin(ctx context.Context) (*sql.Tx, error) {
+	return r.primary.Begin(ctx)
+}
+
+// InTransaction always runs on the primary.
+func (r *ReplicaDB) InTransaction(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return r.primary.InTransaction(ctx, fn)
+}
+
+// Ping checks the primary. Replica health does not affect readiness since
+// reads fall back to the primary.
+func (r *ReplicaDB) Ping(ctx context.Context) error {
+	return r.primary.Ping(ctx)
+}
+
+// Close closes the replicas and the primary.
+func (r *ReplicaDB) Close() error {
+	var errs []error
+	r.closeOnce.Do(func() {
+		for _, rep := range r.replicas {
+			if err := rep.db.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if err := r.primary.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// isReadOnlyQuery reports whether query can be served by a replica: a
+// SELECT or WITH statement that neither modifies data nor takes row locks.
+func isReadOnlyQuery(query string) bool {
+	q := strings.ToUpper(stripLeadingSQLComments(query))
+	if !strings.HasPrefix(q, "SELECT") && !strings.HasPrefix(q, "WITH") {
+		return false
+	}
+	for _, kw := range []string{"INSERT", "UPDATE", "DELETE", "FOR SHARE", "FOR KEY SHARE", "NEXTVAL", "SETVAL", "PG_ADVISORY_LOCK", "PG_ADVISORY_XACT_LOCK"} {
+		if containsSQLKeyword(q, kw) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsSQLKeyword reports whether kw appears in q as a whole word, so
+// a column such as last_update does not count as UPDATE.
+func containsSQLKeyword(q, kw string) bool {
+	for i := 0; ; {
+		j := strings.Index(q[i:], kw)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(kw)
+		if (start == 0 || !isSQLIdentByte(q[start-1])) && (end == len(q) || !isSQLIdentByte(q[end])) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func isSQLIdentByte(b byte) bool {
+	return b == '_' || b >= 'A' && b <= 'Z' || b >= 'a' && b <= 'z' || b >= '0' && b <= '9'
+}
+
+func stripLeadingSQLComments(query string) string {
+	q := strings.TrimSpace(query)
+	for {
+		switch {
+		case strings.HasPrefix(q, "--"):
+			if i := strings.IndexByte(q, '\n'); i >= 0 {
+				q = strings.TrimSpace(q[i+1:])
+				continue
+			}
+			return ""
+		case strings.HasPrefix(q, "/*"):
+			if i := strings.Index(q, "*/"); i >= 0 {
+				q = strings.TrimSpace(q[i+2:])
+				continue
+			}
+			return ""
+		}
+		return q
+	}
+}
@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedScanner writes v into the first destination.
+type fixedScanner struct{ v string }
+
+func (s fixedScanner) Scan(dest ...interface{}) error {
+	*dest[0].(*string) = s.v
+	return nil
+}
+
+// routedDB records each call under name; err, when set, fails reads.
+func routedDB(name string, calls *[]string, err *error) *mockDB {
+	fail := func() error {
+		if err != nil {
+			return *err
+		}
+		return nil
+	}
+	return &mockDB{
+		queryFn: func(context.Context, string, ...interface{}) (Rows, error) {
+			*calls = append(*calls, name+":query")
+			if e := fail(); e != nil {
+				return nil, e
+			}
+			return nil, nil
+		},
+		queryRowFn: func(context.Context, string, ...interface{}) *CancelRow {
+			*calls = append(*calls, name+":queryrow")
+			if e := fail(); e != nil {
+				return NewErrorCancelRow(e)
+			}
+			return NewTestCancelRow(fixedScanner{v: name})
+		},
+		execFn: func(context.Context, string, ...interface{}) (sql.Result, error) {
+			*calls = append(*calls, name+":exec")
+			return nil, nil
+		},
+		inTxFn: func(context.Context, func(tx *sql.Tx) error) error {
+			*calls = append(*calls, name+":tx")
+			return nil
+		},
+	}
+}
+
+func TestReplicaDB_ReadsGoToReplicasWritesToPrimary(t *testing.T) {
+	var calls []string
+	db := NewReplicaDB(routedDB("primary", &calls, nil),
+		[]DB{routedDB("r1", &calls, nil), routedDB("r2", &calls, nil)}, ReplicaConfig{})
+	ctx := context.Background()
+
+	_, err := db.Query(ctx, "SELECT id FROM contents ORDER BY created_at DESC LIMIT $1", 10)
+	require.NoError(t, err)
+	_, err = db.Query(ctx, "  -- search\n  select id from contents where title ilike $1", "%a%")
+	require.NoError(t, err)
+	var got string
+	require.NoError(t, db.QueryRow(ctx, "SELECT title FROM contents WHERE id = $1", "c1").Scan(&got))
+	assert.NotEqual(t, "primary", got)
+
+	_, err = db.Exec(ctx, "UPDATE contents SET title = $1 WHERE id = $2", "t", "c1")
+	require.NoError(t, err)
+	require.NoError(t, db.QueryRow(ctx, "INSERT INTO contents (id) VALUES ($1) RETURNING id", "c2").Scan(&got))
+	assert.Equal(t, "primary", got, "INSERT ... RETURNING is a write")
+	require.NoError(t, db.QueryRow(ctx, "SELECT status FROM tasks WHERE id = $1 FOR UPDATE", "t1").Scan(&got))
+	assert.Equal(t, "primary", got, "locking reads need the primary")
+	require.NoError(t, db.QueryRow(WithPrimary(ctx), "SELECT title FROM contents WHERE id = $1", "c1").Scan(&got))
+	assert.Equal(t, "primary", got, "WithPrimary pins reads to the primary")
+	require.NoError(t, db.InTransaction(ctx, func(*sql.Tx) error { return nil }))
+
+	assert.Equal(t, []string{
+		"r2:query", "r1:query", "r2:queryrow",
+		"primary:exec", "primary:queryrow", "primary:queryrow", "primary:queryrow", "primary:tx",
+	}, calls, "reads rotate across replicas; writes stay on the primary")
+}
+
+func TestReplicaDB_ReplicaFailureFallsBackToPrimary(t *testing.T) {
+	var calls []string
+	replicaErr := errors.New("connection refused")
+	db := NewReplicaDB(routedDB("primary", &calls, nil),
+		[]DB{routedDB("r1", &calls, &replicaErr)}, ReplicaConfig{RetryAfter: time.Minute})
+	now := time.Now()
+	db.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, err := db.Query(ctx, "SELECT id FROM contents")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"r1:query", "primary:query"}, calls)
+	assert.Zero(t, db.HealthyReplicas())
+
+	calls = nil
+	var got string
+	require.NoError(t, db.QueryRow(ctx, "SELECT title FROM contents WHERE id = $1", "c1").Scan(&got))
+	assert.Equal(t, "primary", got)
+	assert.Equal(t, []string{"primary:queryrow"}, calls, "an unhealthy replica is skipped")
+
+	replicaErr = nil
+	now = now.Add(2 * time.Minute)
+	calls = nil
+	require.NoError(t, db.QueryRow(ctx, "SELECT title FROM contents WHERE id = $1", "c1").Scan(&got))
+	assert.Equal(t, "r1", got, "the replica rejoins after RetryAfter")
+	assert.Equal(t, 1, db.HealthyReplicas())
+}
+
+func TestReplicaDB_QueryRowFallbackAtScan(t *testing.T) {
+	var calls []string
+	replicaErr := errors.New("connection reset")
+	db := NewReplicaDB(routedDB("primary", &calls, nil),
+		[]DB{routedDB("r1", &calls, &replicaErr)}, ReplicaConfig{})
+
+	var got string
+	require.NoError(t, db.QueryRow(context.Background(), "SELECT title FROM contents WHERE id = $1", "c1").Scan(&got))
+	assert.Equal(t, "primary", got)
+	assert.Equal(t, []string{"r1:queryrow", "primary:queryrow"}, calls)
+	assert.Zero(t, db.HealthyReplicas())
+}
+
+func TestReplicaDB_NoRowsIsNotAFailure(t *testing.T) {
+	var calls []string
+	noRows := sql.ErrNoRows
+	db := NewReplicaDB(routedDB("primary", &calls, nil),
+		[]DB{routedDB("r1", &calls, &noRows)}, ReplicaConfig{})
+
+	var got string
+	err := db.QueryRow(context.Background(), "SELECT title FROM contents WHERE id = $1", "missing").Scan(&got)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.Equal(t, []string{"r1:queryrow"}, calls)
+	assert.Equal(t, 1, db.HealthyReplicas())
+}
+
+func TestIsReadOnlyQuery(t *testing.T) {
+	for query, want := range map[string]bool{
+		"SELECT * FROM contents":                                     true,
+		"select last_update, updated_at from contents":               true,
+		"/* list */ SELECT id FROM contents":                         true,
+		"WITH c AS (SELECT id FROM contents) SELECT * FROM c":        true,
+		"WITH d AS (DELETE FROM tasks RETURNING id) SELECT * FROM d": false,
+		"SELECT id FROM tasks WHERE status = 'pending' FOR UPDATE":   false,
+		"SELECT id FROM tasks FOR SHARE":                             false,
+		"SELECT nextval('seq')":                                      false,
+		"INSERT INTO contents (id) VALUES ($1) RETURNING id":         false,
+		"UPDATE contents SET title = $1":                             false,
+		"":                                                           false,
+	} {
+		assert.Equal(t, want, isReadOnlyQuery(query), query)
+	}
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/minio/minio-go/v7"
+)
+
+// ErrObjectNotFound is returned by Stat when the object does not exist.
+var ErrObjectNotFound = errors.New("object not found")
+
+var errStatUnsupported = errors.New("stat not supported by object storage")
+
+// ObjectInfo describes a stored object.
+type ObjectInfo struct {
+	Size         int64
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+// ObjectStater reports an object's metadata without downloading it. Every
+// driver in this package implements it.
+type ObjectStater interface {
+	Stat(ctx context.Context, bucket, objectName string) (ObjectInfo, error)
+}
+
+func (ms *MinIOStorage) Stat(ctx context.Context, bucket, objectName string) (ObjectInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	info, err := ms.client.StatObject(ctx, bucket, objectName, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return ObjectInfo{}, ErrObjectNotFound
+		}
+		return ObjectInfo{}, fmt.Errorf("failed to stat object: %w", err)
+	}
+	return ObjectInfo{
+		Size:         info.Size,
+		ContentType:  info.ContentType,
+		ETag:         info.ETag,
+		LastModified: info.LastModified,
+	}, nil
+}
+
+func (s3s *S3Storage) Stat(ctx context.Context, bucket, key string) (ObjectInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	out, err := s3s.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if aerr, ok := err.(interface{ Code() string }); ok && aerr.Code() == "NotFound" {
+			return ObjectInfo{}, ErrObjectNotFound
+		}
+		return ObjectInfo{}, fmt.Errorf("failed to stat object: %w", err)
+	}
+	return ObjectInfo{
+		Size:         aws.Int64Value(out.ContentLength),
+		ContentType:  aws.StringValue(out.ContentType),
+		ETag:         aws.StringValue(out.ETag),
+		LastModified: aws.TimeValue(out.LastModified),
+	}, nil
+}
+
+// Stat forwards to the wrapped store.
+func (s *InstrumentedObjectStorage) Stat(ctx context.Context, bucket, objectName string) (ObjectInfo, error) {
+	st, ok := s.inner.(ObjectStater)
+	if !ok {
+		return ObjectInfo{}, errStatUnsupported
+	}
+	ctx, done := s.observe(ctx, "stat", bucket, objectName, -1)
+	info, err := st.Stat(ctx, bucket, objectName)
+	if errors.Is(err, ErrObjectNotFound) {
+		// A missing object is an answer, not a storage failure.
+		done(nil)
+	} else {
+		done(err)
+	}
+	return info, err
+}
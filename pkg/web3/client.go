@@ -32,6 +32,7 @@ type ChainClient struct {
 	finality    FinalityStrategy
 	wg          sync.WaitGroup
 	closed      atomic.Bool
+	healthStop  chan struct{}
 
 	nftVerifier   atomic.Pointer[NFTVerifier]
 	nftVerifierMu sync.Mutex
@@ -76,6 +77,7 @@ type rpcEndpointState struct {
 	CooldownUntil time.Time
 	Score         float64
 	LastLatency   time.Duration
+	LastBlock     uint64
 }
 
 // RPCStatus describes the current runtime status of an RPC endpoint.
@@ -87,6 +89,7 @@ type RPCStatus struct {
 	CooldownUntil time.Time `json:"cooldown_until,omitempty"`
 	Score         float64   `json:"score"`
 	LastLatencyMs int64     `json:"last_latency_ms,omitempty"`
+	LastBlock     uint64    `json:"last_block,omitempty"`
 }
 
 const (
@@ -183,6 +186,7 @@ func (cc *ChainClient) SubscribeNewHead(ctx context.Context, ch chan<- *types.He
 // Close closes the client connection
 func (cc *ChainClient) Close() {
 	cc.closed.Store(true)
+	cc.stopHealthChecks()
 
 	cc.wg.Wait()
 
@@ -209,6 +213,7 @@ func (cc *ChainClient) GetRPCStatuses() []RPCStatus {
 			CooldownUntil: state.CooldownUntil,
 			Score:         state.Score,
 			LastLatencyMs: state.LastLatency.Milliseconds(),
+			LastBlock:     state.LastBlock,
 		})
 	}
 	return statuses
@@ -488,6 +493,9 @@ func withChainClient[T any](ctx context.Context, cc *ChainClient, op string, fn
 	cc.updateRPCScores(cc.getActiveRPCIndex(), latency, err == nil)
 
 	if err == nil || total <= 1 {
+		if err != nil && isRateLimitRPCError(err) {
+			cc.countEndpointError(cc.getActiveRPCIndex(), "rate_limited")
+		}
 		if err != nil && isPermanentRPCError(err) {
 			return zero, NewPermanentError(fmt.Sprintf("%s failed", op), err)
 		}
@@ -498,7 +506,7 @@ func withChainClient[T any](ctx context.Context, cc *ChainClient, op string, fn
 		zap.String("operation", op),
 		zap.String("rpc_url", cc.rpcURL),
 		zap.Error(err))
-	cc.recordEndpointFailure(cc.getActiveRPCIndex())
+	cc.recordCallError(cc.getActiveRPCIndex(), err)
 
 	lastErr := err
 	for attempts := 1; attempts < total; attempts++ {
@@ -523,6 +531,7 @@ func withChainClient[T any](ctx context.Context, cc *ChainClient, op string, fn
 			return result, nil
 		}
 		lastErr = err
+		cc.recordCallError(cc.getActiveRPCIndex(), err)
 		cc.logger.Warn("RPC operation failed on fallback endpoint",
 			zap.String("operation", op),
 			zap.String("rpc_url", cc.rpcURL),
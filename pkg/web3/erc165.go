@@ -0,0 +1,97 @@
+package web3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rtcdance/streamgate/pkg/web3/internal/abiutil"
+)
+
+// ERC-165 interface IDs of the token standards StreamGate recognises.
+var (
+	InterfaceIDERC165           = [4]byte{0x01, 0xff, 0xc9, 0xa7}
+	InterfaceIDERC721           = [4]byte{0x80, 0xac, 0x58, 0xcd}
+	InterfaceIDERC721Metadata   = [4]byte{0x5b, 0x5e, 0x13, 0x9f}
+	InterfaceIDERC721Enumerable = [4]byte{0x78, 0x0e, 0x9d, 0x63}
+	InterfaceIDERC1155          = [4]byte{0xd9, 0xb6, 0x7a, 0x26}
+
+	// interfaceIDInvalid must never be supported; contracts that claim it
+	// answer true to everything and cannot be classified.
+	interfaceIDInvalid = [4]byte{0xff, 0xff, 0xff, 0xff}
+)
+
+// ErrERC165NotSupported is returned by ClassifyTokenStandard for contracts
+// that do not implement ERC-165, so callers can fall back to probing.
+var ErrERC165NotSupported = errors.New("contract does not implement ERC-165")
+
+var erc165ABI = abiutil.MustParseABI("ERC-165", `[{"constant":true,"inputs":[{"name":"interfaceID","type":"bytes4"}],"name":"supportsInterface","outputs":[{"name":"","type":"bool"}],"payable":false,"stateMutability":"view","type":"function"}]`)
+
+// SupportsInterface calls supportsInterface(interfaceID) on the contract. It
+// does not check that the contract implements ERC-165 itself; a revert or
+// empty result is returned as an error.
+func SupportsInterface(ctx context.Context, caller EthCaller, contractAddress string, interfaceID [4]byte) (bool, error) {
+	if !common.IsHexAddress(contractAddress) {
+		return false, fmt.Errorf("invalid contract address: %s", contractAddress)
+	}
+	contract := common.HexToAddress(contractAddress)
+
+	data, err := erc165ABI.Pack("supportsInterface", interfaceID)
+	if err != nil {
+		return false, fmt.Errorf("failed to pack supportsInterface call: %w", err)
+	}
+	result, err := caller.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: data}, nil)
+	if err != nil {
+		return false, fmt.Errorf("supportsInterface call failed: %w", err)
+	}
+	if len(result) < 32 {
+		return false, fmt.Errorf("supportsInterface returned insufficient data (len=%d)", len(result))
+	}
+
+	var supported bool
+	if err := erc165ABI.UnpackIntoInterface(&supported, "supportsInterface", result); err != nil {
+		return false, fmt.Errorf("failed to unpack supportsInterface result: %w", err)
+	}
+	return supported, nil
+}
+
+// ClassifyTokenStandard classifies a contract as ERC-721 or ERC-1155 using
+// only ERC-165, following the detection procedure in the EIP: the contract
+// must support 0x01ffc9a7 and must not support 0xffffffff. It returns
+// ErrERC165NotSupported when that check fails, and TokenStandardUnknown for
+// ERC-165 contracts that are neither standard.
+func ClassifyTokenStandard(ctx context.Context, caller EthCaller, contractAddress string) (TokenStandard, error) {
+	if !common.IsHexAddress(contractAddress) {
+		return TokenStandardUnknown, fmt.Errorf("invalid contract address: %s", contractAddress)
+	}
+
+	supports165, err := SupportsInterface(ctx, caller, contractAddress, InterfaceIDERC165)
+	if err != nil || !supports165 {
+		return TokenStandardUnknown, ErrERC165NotSupported
+	}
+	if supportsAll, err := SupportsInterface(ctx, caller, contractAddress, interfaceIDInvalid); err != nil || supportsAll {
+		return TokenStandardUnknown, ErrERC165NotSupported
+	}
+
+	// ERC-1155 first: some contracts implement both and are multi-token.
+	if ok, err := SupportsInterface(ctx, caller, contractAddress, InterfaceIDERC1155); err == nil && ok {
+		return TokenStandardERC1155, nil
+	}
+	if ok, err := SupportsInterface(ctx, caller, contractAddress, InterfaceIDERC721); err == nil && ok {
+		return TokenStandardERC721, nil
+	}
+	return TokenStandardUnknown, nil
+}
+
+// TokenStandardName returns "ERC-721", "ERC-1155" or "unknown".
+func TokenStandardName(standard TokenStandard) string {
+	switch standard {
+	case TokenStandardERC721:
+		return "ERC-721"
+	case TokenStandardERC1155:
+		return "ERC-1155"
+	}
+	return "unknown"
+}
@@ -0,0 +1,103 @@
+package web3
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// erc165Caller answers supportsInterface from a fixed set of interface IDs.
+type erc165Caller struct {
+	supported map[[4]byte]bool
+	err       error
+}
+
+func (c *erc165Caller) CallContract(_ context.Context, call ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	var id [4]byte
+	copy(id[:], call.Data[4:8])
+	result := make([]byte, 32)
+	if c.supported[id] {
+		result[31] = 1
+	}
+	return result, nil
+}
+
+func (c *erc165Caller) CodeAt(_ context.Context, _ common.Address, _ *big.Int) ([]byte, error) {
+	return []byte{0x60}, nil
+}
+
+const erc165TestContract = "0x1234567890123456789012345678901234567890"
+
+func TestClassifyTokenStandard(t *testing.T) {
+	ctx := context.Background()
+	for name, tc := range map[string]struct {
+		caller   *erc165Caller
+		standard TokenStandard
+		err      error
+	}{
+		"erc721": {
+			caller:   &erc165Caller{supported: map[[4]byte]bool{InterfaceIDERC165: true, InterfaceIDERC721: true}},
+			standard: TokenStandardERC721,
+		},
+		"erc1155": {
+			caller:   &erc165Caller{supported: map[[4]byte]bool{InterfaceIDERC165: true, InterfaceIDERC1155: true}},
+			standard: TokenStandardERC1155,
+		},
+		"neither": {
+			caller:   &erc165Caller{supported: map[[4]byte]bool{InterfaceIDERC165: true}},
+			standard: TokenStandardUnknown,
+		},
+		"no erc165": {
+			caller:   &erc165Caller{supported: map[[4]byte]bool{InterfaceIDERC721: true}},
+			standard: TokenStandardUnknown,
+			err:      ErrERC165NotSupported,
+		},
+		"claims everything": {
+			caller:   &erc165Caller{supported: map[[4]byte]bool{InterfaceIDERC165: true, interfaceIDInvalid: true, InterfaceIDERC721: true}},
+			standard: TokenStandardUnknown,
+			err:      ErrERC165NotSupported,
+		},
+		"reverts": {
+			caller:   &erc165Caller{err: errors.New("execution reverted")},
+			standard: TokenStandardUnknown,
+			err:      ErrERC165NotSupported,
+		},
+	} {
+		standard, err := ClassifyTokenStandard(ctx, tc.caller, erc165TestContract)
+		assert.Equal(t, tc.standard, standard, name)
+		if tc.err != nil {
+			assert.ErrorIs(t, err, tc.err, name)
+		} else {
+			assert.NoError(t, err, name)
+		}
+	}
+
+	_, err := ClassifyTokenStandard(ctx, &erc165Caller{}, "not-an-address")
+	assert.Error(t, err)
+}
+
+func TestSupportsInterface(t *testing.T) {
+	caller := &erc165Caller{supported: map[[4]byte]bool{InterfaceIDERC721Enumerable: true}}
+	ok, err := SupportsInterface(context.Background(), caller, erc165TestContract, InterfaceIDERC721Enumerable)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = SupportsInterface(context.Background(), caller, erc165TestContract, InterfaceIDERC721Metadata)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestTokenStandardName(t *testing.T) {
+	assert.Equal(t, "ERC-721", TokenStandardName(TokenStandardERC721))
+	assert.Equal(t, "ERC-1155", TokenStandardName(TokenStandardERC1155))
+	assert.Equal(t, "unknown", TokenStandardName(TokenStandardUnknown))
+}
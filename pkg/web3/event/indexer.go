@@ -610,6 +610,7 @@ func (ei *EventIndexer) logToEvent(log *types.Log) *IndexedEvent {
 	}
 
 	decoded := make(map[string]interface{})
+	eventType := "ContractEvent"
 
 	ei.mu.RLock()
 	parser := ei.eventParser
@@ -619,12 +620,13 @@ func (ei *EventIndexer) logToEvent(log *types.Log) *IndexedEvent {
 		parsed := parser.ParseLogs([]*types.Log{log})
 		if len(parsed) > 0 && parsed[0].Name != "Unknown" {
 			decoded = parsed[0].Args
+			eventType = parsed[0].Name
 		}
 	}
 
 	event := &IndexedEvent{
 		ID:              fmt.Sprintf("%s-%d", log.TxHash.Hex(), log.Index),
-		EventType:       "ContractEvent",
+		EventType:       eventType,
 		ContractAddress: log.Address.Hex(),
 		TransactionHash: log.TxHash.Hex(),
 		BlockNumber:     log.BlockNumber,
@@ -775,6 +775,24 @@ func TestLogToEvent_WithParser(t *testing.T) {
 	assert.Equal(t, uint64(5), event.BlockNumber)
 }
 
+func TestLogToEvent_NamesParsedEvents(t *testing.T) {
+	indexer, err := newTestEventIndexer(&mockEventReader{blockNum: 100})
+	require.NoError(t, err)
+
+	log := makeTransferLog(5, common.HexToHash("0x01"), 0)
+	log.Topics = append(log.Topics,
+		common.BytesToHash(common.HexToAddress("0x01").Bytes()),
+		common.BytesToHash(common.HexToAddress("0x02").Bytes()),
+		common.BigToHash(big.NewInt(42)))
+	log.Data = nil
+	assert.Equal(t, "ContractEvent", indexer.logToEvent(&log).EventType, "without a parser events are generic")
+
+	indexer.SetEventParser(NewEventParser(zap.NewNop()))
+	event := indexer.logToEvent(&log)
+	assert.Equal(t, "Transfer", event.EventType)
+	assert.Equal(t, common.HexToAddress("0x02").Hex(), event.Decoded["to"])
+}
+
 func TestIndexRange_ContextCancelled(t *testing.T) {
 	reader := &mockEventReader{blockNum: 20}
 	indexer, err := newTestEventIndexer(reader)
@@ -150,6 +150,12 @@ func formatValue(v interface{}) interface{} {
 	switch val := v.(type) {
 	case *big.Int:
 		return val.String()
+	case []*big.Int:
+		nums := make([]string, len(val))
+		for i, n := range val {
+			nums[i] = n.String()
+		}
+		return nums
 	case common.Address:
 		return val.Hex()
 	case []common.Address:
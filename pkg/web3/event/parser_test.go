@@ -2,8 +2,10 @@ package event
 
 import (
 	"math/big"
+	"strings"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"go.uber.org/zap"
@@ -161,3 +163,39 @@ func TestEventParser_ERC1155TransferSingle(t *testing.T) {
 		t.Errorf("expected TransferSingle, got %s", events[0].Name)
 	}
 }
+
+func TestEventParser_ERC1155TransferBatch(t *testing.T) {
+	parser := NewEventParser(zap.NewNop())
+	parsedABI, err := abi.JSON(strings.NewReader(ERC1155EventABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch := parsedABI.Events["TransferBatch"]
+	data, err := batch.Inputs.NonIndexed().Pack(
+		[]*big.Int{big.NewInt(1), big.NewInt(2)},
+		[]*big.Int{big.NewInt(5), big.NewInt(6)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log := &types.Log{
+		Address: common.HexToAddress("0x0000000000000000000000000000000000000004"),
+		Topics: []common.Hash{
+			batch.ID,
+			common.BytesToHash(common.HexToAddress("0x01").Bytes()),
+			common.BytesToHash(common.HexToAddress("0x02").Bytes()),
+			common.BytesToHash(common.HexToAddress("0x03").Bytes()),
+		},
+		Data: data,
+	}
+
+	events := parser.ParseLogs([]*types.Log{log})
+	if len(events) != 1 || events[0].Name != "TransferBatch" {
+		t.Fatalf("expected one TransferBatch event, got %+v", events)
+	}
+	ids, ok := events[0].Args["ids"].([]string)
+	if !ok || len(ids) != 2 || ids[0] != "1" || ids[1] != "2" {
+		t.Errorf("expected ids [1 2], got %#v", events[0].Args["ids"])
+	}
+}
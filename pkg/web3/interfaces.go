@@ -18,15 +18,21 @@ import (
 
 type (
 	SolanaVerifier          = solana.SolanaVerifier
+	Ed25519Verifier         = solana.Ed25519SignatureVerifier
 	MetaplexMetadata        = solana.MetaplexMetadata
 	EIP712TypedData         = signature.EIP712TypedData
 	EIP712Domain            = signature.EIP712Domain
 	EIP712Types             = signature.EIP712Types
+	TypedDataError          = signature.TypedDataError
 	SignatureVerifier       = signature.SignatureVerifier
 	WalletManager           = signature.WalletManager
 	SecurePrivateKey        = signature.SecurePrivateKey
 	SIWEMessage             = signature.SIWEMessage
 	SIWEMessageOption       = signature.SIWEMessageOption
+	SIWEExpectations        = signature.SIWEExpectations
+	SIWEVerifier            = signature.SIWEVerifier
+	LoginMessageTemplate    = signature.LoginMessageTemplate
+	LoginMessageFields      = signature.LoginMessageFields
 	EIP712Verifier          = signature.EIP712Verifier
 	EthCaller               = nft.EthCaller
 	BlockTagCaller          = nft.BlockTagCaller
@@ -35,6 +41,7 @@ type (
 	ApprovalInfo            = nft.ApprovalInfo
 	TokenStandard           = nft.TokenStandard
 	ERC1155Verifier         = nft.ERC1155Verifier
+	ERC721Verifier          = nft.ERC721Verifier
 	ERC20Reader             = nft.ERC20Reader
 	ERC20TokenInfo          = nft.ERC20TokenInfo
 	BlockTag                = nft.BlockTag
@@ -88,6 +95,15 @@ var (
 	BalanceOfABIJSON = contract.BalanceOfABIJSON
 )
 
+var (
+	ErrMissingPrimaryType = signature.ErrMissingPrimaryType
+	ErrUndefinedType      = signature.ErrUndefinedType
+	ErrInvalidTypeField   = signature.ErrInvalidTypeField
+	ErrDomainMismatch     = signature.ErrDomainMismatch
+
+	ErrSIWESignatureMismatch = signature.ErrSIWESignatureMismatch
+)
+
 const PermitABI = nft.PermitABI
 
 func NewNFTVerifier(client EthCaller, logger *zap.Logger) *NFTVerifier {
@@ -98,6 +114,10 @@ func NewERC1155Verifier(ethClient EthCaller, logger *zap.Logger, cache cachetype
 	return nft.NewERC1155Verifier(ethClient, logger, cache)
 }
 
+func NewERC721Verifier(ethClient EthCaller, logger *zap.Logger, cache cachetypes.CacheBackend) *ERC721Verifier {
+	return nft.NewERC721Verifier(ethClient, logger, cache)
+}
+
 func NewERC20Reader(caller EthCaller, logger *zap.Logger) *ERC20Reader {
 	return nft.NewERC20Reader(caller, logger)
 }
@@ -118,6 +138,14 @@ func WithSIWEExpirationTime(t time.Time) SIWEMessageOption {
 	return signature.WithSIWEExpirationTime(t)
 }
 
+func WithSIWEStatement(statement string) SIWEMessageOption {
+	return signature.WithSIWEStatement(statement)
+}
+
+func ParseLoginMessageTemplate(text string) (*LoginMessageTemplate, error) {
+	return signature.ParseLoginMessageTemplate(text)
+}
+
 func BuildSIWEMessage(msg *SIWEMessage) string {
 	return signature.BuildSIWEMessage(msg)
 }
@@ -130,6 +158,14 @@ func ValidateSIWEMessage(msg *SIWEMessage, expectedDomain, expectedAddress, expe
 	return signature.ValidateSIWEMessage(msg, expectedDomain, expectedAddress, expectedNonce, expectedChainID)
 }
 
+func ValidateSIWEFields(msg *SIWEMessage, exp SIWEExpectations) error {
+	return signature.ValidateSIWEFields(msg, exp)
+}
+
+func NewSIWEVerifier(signatures *SignatureVerifier) *SIWEVerifier {
+	return signature.NewSIWEVerifier(signatures)
+}
+
 func NewSignatureVerifier(logger *zap.Logger) *SignatureVerifier {
 	return signature.NewSignatureVerifier(logger)
 }
@@ -150,6 +186,10 @@ func NewSolanaVerifier(logger *zap.Logger, rpcEndpoint ...string) *SolanaVerifie
 	return solana.NewSolanaVerifier(logger, rpcEndpoint...)
 }
 
+func NewEd25519Verifier(logger *zap.Logger) *Ed25519Verifier {
+	return solana.NewEd25519SignatureVerifier(logger)
+}
+
 type ChainReader interface {
 	GetClient(chainID int64) (*ChainClient, error)
 	GetSolanaClient(chainID int64) (*SolanaVerifier, error)
@@ -162,6 +202,7 @@ type ChainReader interface {
 type ChainAdmin interface {
 	AddChain(chainID int64) error
 	SetRateLimiter(rl *RPCRateLimiter)
+	SetHealthCheckInterval(interval time.Duration)
 }
 
 type ChainLifecycle interface {
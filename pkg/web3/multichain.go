@@ -3,7 +3,10 @@ package web3
 import (
 	"fmt"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/rtcdance/streamgate/pkg/core/config"
 	"github.com/rtcdance/streamgate/pkg/web3/solana"
@@ -29,6 +32,10 @@ func init() {
 	// entries at runtime from configuration.
 }
 
+// chainsMu guards supportedChains and chainAliases, which ApplyChainConfigs
+// and SetChainRPCs change while requests read them.
+var chainsMu sync.RWMutex
+
 var supportedChains = map[int64]*ChainConfig{
 	// Anvil (local dev)
 	31337: {
@@ -149,6 +156,27 @@ var supportedChains = map[int64]*ChainConfig{
 		IsTestnet: true,
 	},
 
+	// Base
+	8453: {
+		ID:        8453,
+		Name:      "Base",
+		RPC:       "https://mainnet.base.org",
+		RPCs:      []string{"https://mainnet.base.org", "https://base-rpc.publicnode.com"},
+		Explorer:  "https://basescan.org",
+		Currency:  "ETH",
+		IsTestnet: false,
+		Finality:  L2Finality,
+	},
+	84532: {
+		ID:        84532,
+		Name:      "Base Sepolia",
+		RPC:       "https://sepolia.base.org",
+		RPCs:      []string{"https://sepolia.base.org"},
+		Explorer:  "https://sepolia.basescan.org",
+		Currency:  "ETH",
+		IsTestnet: true,
+	},
+
 	// Solana
 	-1: {
 		ID:        -1,
@@ -172,11 +200,16 @@ var supportedChains = map[int64]*ChainConfig{
 
 // ApplyChainConfigs merges external chain configurations into supportedChains.
 // Config entries matching existing chain IDs override the built-in defaults
-// (RPC endpoints, explorer URL, etc.). Unknown chain IDs are added.
+// (RPC endpoints, explorer URL, etc.); fields left empty keep the built-in
+// value, so a named network only needs an ID and its RPCs. Unknown chain IDs
+// are added. Entries with a slug become resolvable by ResolveChainID.
 // Existing Finality factory is preserved unless the config entry explicitly
 // provides one (e.g. via a finality field), to avoid replacing chain-specific
 // finality defaults (like BlockTagLatest for anvil) with a nil finality.
+// A non-zero Confirmations replaces the finality depth but keeps its block tag.
 func ApplyChainConfigs(entries []config.ChainConfigEntry) {
+	chainsMu.Lock()
+	defer chainsMu.Unlock()
 	for _, entry := range entries {
 		existing, hasExisting := supportedChains[entry.ID]
 		cfg := &ChainConfig{
@@ -188,6 +221,25 @@ func ApplyChainConfigs(entries []config.ChainConfigEntry) {
 			Currency:  entry.Currency,
 			IsTestnet: entry.IsTestnet,
 		}
+		if hasExisting {
+			if cfg.Name == "" {
+				cfg.Name = existing.Name
+			}
+			if cfg.RPC == "" && len(cfg.RPCs) == 0 {
+				cfg.RPC = existing.RPC
+				cfg.RPCs = existing.RPCs
+			}
+			if cfg.Explorer == "" {
+				cfg.Explorer = existing.Explorer
+			}
+			if cfg.Currency == "" {
+				cfg.Currency = existing.Currency
+			}
+			cfg.IsTestnet = cfg.IsTestnet || existing.IsTestnet
+		}
+		if cfg.RPC == "" && len(cfg.RPCs) > 0 {
+			cfg.RPC = cfg.RPCs[0]
+		}
 		if len(cfg.RPCs) == 0 && cfg.RPC != "" {
 			cfg.RPCs = []string{cfg.RPC}
 		}
@@ -197,8 +249,71 @@ func ApplyChainConfigs(entries []config.ChainConfigEntry) {
 		if hasExisting && existing.Finality != nil {
 			cfg.Finality = existing.Finality
 		}
+		if entry.Confirmations > 0 {
+			cfg.Finality = confirmationsFinality(cfg.Finality, entry.Confirmations)
+		}
 		supportedChains[entry.ID] = cfg
+		if entry.Slug != "" {
+			chainAliases[strings.ToLower(entry.Slug)] = entry.ID
+		}
+	}
+}
+
+// confirmationsFinality wraps base with a different confirmation depth.
+// Chains without a finality factory read at BlockTagSafe.
+func confirmationsFinality(base FinalityFactory, confirmations uint64) FinalityFactory {
+	return func(reader HeaderReader, logger *zap.Logger) FinalityStrategy {
+		blockTag := BlockTagSafe
+		if base != nil {
+			blockTag = base(reader, logger).BlockTag()
+		}
+		return newFinalityDefault(reader, confirmations, blockTag, logger)
+	}
+}
+
+// chainAliases maps the chain names clients may use instead of numeric IDs.
+// ApplyChainConfigs adds configured network slugs.
+var chainAliases = map[string]int64{
+	"anvil":            31337,
+	"ethereum":         1,
+	"eth":              1,
+	"mainnet":          1,
+	"sepolia":          11155111,
+	"polygon":          137,
+	"matic":            137,
+	"amoy":             80002,
+	"polygon-amoy":     80002,
+	"bsc":              56,
+	"bnb":              56,
+	"bsc-testnet":      97,
+	"arbitrum":         42161,
+	"arbitrum-sepolia": 421614,
+	"optimism":         10,
+	"optimism-sepolia": 11155420,
+	"base":             8453,
+	"base-sepolia":     84532,
+	"solana":           -1,
+	"solana-devnet":    -2,
+}
+
+// ResolveChainID resolves a chain name ("polygon", case-insensitive) or a
+// decimal chain ID to a supported chain ID.
+func ResolveChainID(chain string) (int64, error) {
+	chain = strings.ToLower(strings.TrimSpace(chain))
+	chainsMu.RLock()
+	defer chainsMu.RUnlock()
+	id, ok := chainAliases[chain]
+	if !ok {
+		parsed, err := strconv.ParseInt(chain, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("unknown chain: %q", chain)
+		}
+		id = parsed
+	}
+	if _, supported := supportedChains[id]; !supported {
+		return 0, fmt.Errorf("chain not supported: %q", chain)
 	}
+	return id, nil
 }
 
 // MultiChainManager manages multiple blockchain connections
@@ -207,6 +322,7 @@ type MultiChainManager struct {
 	clients       map[int64]*ChainClient
 	solanaClients map[int64]*solana.SolanaVerifier
 	rateLimiter   *RPCRateLimiter
+	healthCheck   time.Duration
 	logger        *zap.Logger
 }
 
@@ -224,7 +340,7 @@ func (mcm *MultiChainManager) AddChain(chainID int64) error {
 	mcm.logger.Info("Adding chain",
 		zap.Int64("chain_id", chainID))
 
-	config, exists := supportedChains[chainID]
+	config, exists := GetChainConfig(chainID)
 	if !exists {
 		mcm.logger.Error("Chain not supported",
 			zap.Int64("chain_id", chainID))
@@ -257,6 +373,12 @@ func (mcm *MultiChainManager) AddChain(chainID int64) error {
 	if config.Finality != nil {
 		client.SetFinality(config.Finality(client, mcm.logger))
 	}
+	mcm.mu.RLock()
+	healthCheck := mcm.healthCheck
+	mcm.mu.RUnlock()
+	if len(rpcURLs) > 1 {
+		client.StartHealthChecks(healthCheck)
+	}
 
 	mcm.mu.Lock()
 	mcm.clients[chainID] = client
@@ -334,9 +456,37 @@ func (mcm *MultiChainManager) SetRateLimiter(rl *RPCRateLimiter) {
 	mcm.rateLimiter = rl
 }
 
+// SetHealthCheckInterval enables periodic endpoint health checks, at the
+// given interval, on EVM chains added afterwards that have more than one
+// RPC endpoint. Zero disables them.
+func (mcm *MultiChainManager) SetHealthCheckInterval(interval time.Duration) {
+	mcm.mu.Lock()
+	defer mcm.mu.Unlock()
+	mcm.healthCheck = interval
+}
+
+// SetChainRPCs replaces the RPC endpoints of a supported chain. The first
+// endpoint becomes the primary. It must be called before AddChain.
+func SetChainRPCs(chainID int64, rpcURLs []string) error {
+	chainsMu.Lock()
+	defer chainsMu.Unlock()
+	cfg, ok := supportedChains[chainID]
+	if !ok {
+		return fmt.Errorf("chain not supported: %d", chainID)
+	}
+	if len(rpcURLs) == 0 {
+		return fmt.Errorf("no rpc urls given for chain %d", chainID)
+	}
+	updated := *cfg
+	updated.RPC = rpcURLs[0]
+	updated.RPCs = append([]string(nil), rpcURLs...)
+	supportedChains[chainID] = &updated
+	return nil
+}
+
 // GetChainConfig gets the configuration for a chain
 func (mcm *MultiChainManager) GetChainConfig(chainID int64) (*ChainConfig, error) {
-	config, exists := supportedChains[chainID]
+	config, exists := GetChainConfig(chainID)
 	if !exists {
 		mcm.logger.Error("Chain not supported",
 			zap.Int64("chain_id", chainID))
@@ -347,6 +497,8 @@ func (mcm *MultiChainManager) GetChainConfig(chainID int64) (*ChainConfig, error
 }
 
 func GetSupportedChains() []*ChainConfig {
+	chainsMu.RLock()
+	defer chainsMu.RUnlock()
 	chains := make([]*ChainConfig, 0, len(supportedChains))
 	for _, config := range supportedChains {
 		chains = append(chains, config)
@@ -355,17 +507,15 @@ func GetSupportedChains() []*ChainConfig {
 }
 
 func GetChainConfig(chainID int64) (*ChainConfig, bool) {
+	chainsMu.RLock()
+	defer chainsMu.RUnlock()
 	cfg, ok := supportedChains[chainID]
 	return cfg, ok
 }
 
 // GetSupportedChains gets all supported chains
 func (mcm *MultiChainManager) GetSupportedChains() []*ChainConfig {
-	chains := make([]*ChainConfig, 0, len(supportedChains))
-	for _, config := range supportedChains {
-		chains = append(chains, config)
-	}
-	return chains
+	return GetSupportedChains()
 }
 
 // GetRPCStatuses returns the runtime RPC status for each configured chain.
@@ -383,7 +533,7 @@ func (mcm *MultiChainManager) GetRPCStatuses() map[int64][]RPCStatus {
 // GetTestnetChains gets all testnet chains
 func (mcm *MultiChainManager) GetTestnetChains() []*ChainConfig {
 	chains := make([]*ChainConfig, 0)
-	for _, config := range supportedChains {
+	for _, config := range GetSupportedChains() {
 		if config.IsTestnet {
 			chains = append(chains, config)
 		}
@@ -395,7 +545,7 @@ func (mcm *MultiChainManager) GetTestnetChains() []*ChainConfig {
 // GetMainnetChains gets all mainnet chains
 func (mcm *MultiChainManager) GetMainnetChains() []*ChainConfig {
 	chains := make([]*ChainConfig, 0)
-	for _, config := range supportedChains {
+	for _, config := range GetSupportedChains() {
 		if !config.IsTestnet {
 			chains = append(chains, config)
 		}
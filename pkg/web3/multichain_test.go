@@ -1,6 +1,7 @@
 package web3
 
 import (
+	"sync"
 	"testing"
 
 	"github.com/rtcdance/streamgate/pkg/core/config"
@@ -232,3 +233,72 @@ func TestChainConfig_Fields(t *testing.T) {
 	assert.NotEmpty(t, cfg.RPC)
 	assert.NotEmpty(t, cfg.RPCs)
 }
+
+func TestResolveChainID(t *testing.T) {
+	for chain, want := range map[string]int64{
+		"polygon":  137,
+		"Base":     8453,
+		" bsc ":    56,
+		"arbitrum": 42161,
+		"137":      137,
+	} {
+		got, err := ResolveChainID(chain)
+		require.NoError(t, err, chain)
+		assert.Equal(t, want, got, chain)
+	}
+	_, err := ResolveChainID("dogechain")
+	assert.Error(t, err)
+	_, err = ResolveChainID("424242")
+	assert.Error(t, err, "numeric IDs must be supported chains")
+}
+
+func TestApplyChainConfigs_NamedNetwork(t *testing.T) {
+	original := supportedChains[137]
+	t.Cleanup(func() {
+		supportedChains[137] = original
+		delete(chainAliases, "pos")
+	})
+
+	ApplyChainConfigs([]config.ChainConfigEntry{{
+		ID:            137,
+		Slug:          "pos",
+		RPCs:          []string{"https://polygon.example.com"},
+		Confirmations: 256,
+	}})
+
+	cfg, ok := GetChainConfig(137)
+	require.True(t, ok)
+	assert.Equal(t, "Polygon", cfg.Name, "empty fields keep built-in values")
+	assert.Equal(t, "MATIC", cfg.Currency)
+	assert.Equal(t, "https://polygon.example.com", cfg.RPC)
+	finality := cfg.Finality(nil, zap.NewNop())
+	assert.Equal(t, uint64(256), finality.RequiredConfirmations())
+	assert.Equal(t, BlockTagSafe, finality.BlockTag())
+
+	id, err := ResolveChainID("pos")
+	require.NoError(t, err)
+	assert.Equal(t, int64(137), id)
+}
+
+func TestApplyChainConfigs_ConcurrentReads(t *testing.T) {
+	original := supportedChains[137]
+	t.Cleanup(func() {
+		supportedChains[137] = original
+		delete(chainAliases, "pos")
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			ApplyChainConfigs([]config.ChainConfigEntry{{ID: 137, Slug: "pos"}})
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		_, err := ResolveChainID("polygon")
+		require.NoError(t, err)
+		assert.NotEmpty(t, GetSupportedChains())
+	}
+	wg.Wait()
+}
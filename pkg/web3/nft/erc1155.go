@@ -21,6 +21,7 @@ type ERC1155Verifier struct {
 	logger           *zap.Logger
 	cache            cachetypes.CacheBackend
 	cacheTTL         time.Duration
+	chainID          int64
 	parsedERC1155ABI abi.ABI
 }
 
@@ -34,6 +35,14 @@ func NewERC1155Verifier(ethClient EthCaller, logger *zap.Logger, cache cachetype
 	}
 }
 
+// WithChainID records which chain the client is connected to. Cached
+// balances are keyed by it, so verifiers for different chains can share
+// one cache without a contract address on one chain answering for another.
+func (ev *ERC1155Verifier) WithChainID(chainID int64) *ERC1155Verifier {
+	ev.chainID = chainID
+	return ev
+}
+
 const erc1155FullABI = `[{"constant":true,"inputs":[{"name":"account","type":"address"},{"name":"id","type":"uint256"}],"name":"balanceOf","outputs":[{"name":"","type":"uint256"}],"payable":false,"stateMutability":"view","type":"function"},{"constant":true,"inputs":[{"name":"accounts","type":"address[]"},{"name":"ids","type":"uint256[]"}],"name":"balanceOfBatch","outputs":[{"name":"","type":"uint256[]"}],"payable":false,"stateMutability":"view","type":"function"},{"constant":true,"inputs":[{"name":"account","type":"address"},{"name":"operator","type":"address"}],"name":"isApprovedForAll","outputs":[{"name":"","type":"bool"}],"payable":false,"stateMutability":"view","type":"function"},{"constant":true,"inputs":[{"name":"id","type":"uint256"}],"name":"uri","outputs":[{"name":"","type":"string"}],"payable":false,"stateMutability":"view","type":"function"}]`
 
 func (ev *ERC1155Verifier) VerifyNFTOwnership(ctx context.Context, contractAddress, tokenID, ownerAddress string) (bool, error) {
@@ -49,7 +58,7 @@ func (ev *ERC1155Verifier) VerifyNFTOwnership(ctx context.Context, contractAddre
 		return false, fmt.Errorf("invalid owner address: %s", ownerAddress)
 	}
 
-	cacheKey := fmt.Sprintf("erc1155:balance:%s:%s:%s", contractAddress, tokenID, ownerAddress)
+	cacheKey := fmt.Sprintf("erc1155:balance:%d:%s:%s:%s", ev.chainID, contractAddress, tokenID, ownerAddress)
 	if ev.cache != nil {
 		if cached, err := ev.cache.Get(cacheKey); err == nil {
 			if balance, ok := cached.(*big.Int); ok {
@@ -0,0 +1,277 @@
+package nft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/cachetypes"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rtcdance/streamgate/pkg/web3/internal/abiutil"
+	"go.uber.org/zap"
+)
+
+// erc721EnumerableInterfaceID is the ERC-165 ID of the optional ERC-721
+// enumeration extension (totalSupply, tokenByIndex, tokenOfOwnerByIndex).
+var erc721EnumerableInterfaceID = [4]byte{0x78, 0x0e, 0x9d, 0x63}
+
+// DefaultMaxEnumeratedTokens caps how many token IDs ListOwnedTokenIDs reads
+// for one owner, since each ID costs an RPC call.
+const DefaultMaxEnumeratedTokens = 1000
+
+// ErrNotEnumerable is returned by ListOwnedTokenIDs when the contract does
+// not implement the ERC-721 enumeration extension.
+var ErrNotEnumerable = errors.New("contract does not support ERC-721 enumeration")
+
+const erc721FullABI = `[{"constant":true,"inputs":[{"name":"owner","type":"address"}],"name":"balanceOf","outputs":[{"name":"","type":"uint256"}],"payable":false,"stateMutability":"view","type":"function"},{"constant":true,"inputs":[{"name":"tokenId","type":"uint256"}],"name":"ownerOf","outputs":[{"name":"","type":"address"}],"payable":false,"stateMutability":"view","type":"function"},{"constant":true,"inputs":[{"name":"owner","type":"address"},{"name":"index","type":"uint256"}],"name":"tokenOfOwnerByIndex","outputs":[{"name":"","type":"uint256"}],"payable":false,"stateMutability":"view","type":"function"}]`
+
+// ERC721Verifier checks ERC-721 ownership. Unlike NFTVerifier it checks
+// balanceOf before ownerOf, caches results and can enumerate an owner's
+// tokens on contracts that implement ERC721Enumerable.
+type ERC721Verifier struct {
+	ethClient       EthCaller
+	logger          *zap.Logger
+	cache           cachetypes.CacheBackend
+	cacheTTL        time.Duration
+	maxEnumerated   int
+	chainID         int64
+	parsedERC721ABI abi.ABI
+}
+
+func NewERC721Verifier(ethClient EthCaller, logger *zap.Logger, cache cachetypes.CacheBackend) *ERC721Verifier {
+	return &ERC721Verifier{
+		ethClient:       ethClient,
+		logger:          logger,
+		cache:           cache,
+		cacheTTL:        5 * time.Minute,
+		maxEnumerated:   DefaultMaxEnumeratedTokens,
+		parsedERC721ABI: abiutil.MustParseABI("ERC-721", erc721FullABI),
+	}
+}
+
+// WithChainID records which chain the client is connected to; cached
+// ownership results are keyed by it.
+func (ev *ERC721Verifier) WithChainID(chainID int64) *ERC721Verifier {
+	ev.chainID = chainID
+	return ev
+}
+
+// WithMaxEnumeratedTokens sets the ListOwnedTokenIDs cap.
+func (ev *ERC721Verifier) WithMaxEnumeratedTokens(max int) *ERC721Verifier {
+	ev.maxEnumerated = max
+	return ev
+}
+
+// VerifyNFTOwnership reports whether ownerAddress owns tokenID. An owner
+// with a zero balance is rejected without an ownerOf call.
+func (ev *ERC721Verifier) VerifyNFTOwnership(ctx context.Context, contractAddress, tokenID, ownerAddress string) (bool, error) {
+	ev.logger.Debug("Verifying ERC-721 NFT ownership",
+		zap.String("contract", contractAddress),
+		zap.String("token_id", tokenID),
+		zap.String("owner", ownerAddress))
+
+	if !common.IsHexAddress(contractAddress) {
+		return false, fmt.Errorf("invalid contract address: %s", contractAddress)
+	}
+	if !common.IsHexAddress(ownerAddress) {
+		return false, fmt.Errorf("invalid owner address: %s", ownerAddress)
+	}
+
+	tokenIDInt := new(big.Int)
+	if _, ok := tokenIDInt.SetString(tokenID, 10); !ok {
+		return false, fmt.Errorf("invalid token ID: %s", tokenID)
+	}
+
+	cacheKey := fmt.Sprintf("erc721:owner:%d:%s:%s:%s", ev.chainID, contractAddress, tokenID, ownerAddress)
+	if ev.cache != nil {
+		if cached, err := ev.cache.Get(cacheKey); err == nil {
+			if owned, ok := cached.(bool); ok {
+				return owned, nil
+			}
+		}
+	}
+
+	contractAddr := common.HexToAddress(contractAddress)
+	ownerAddr := common.HexToAddress(ownerAddress)
+
+	balance, err := ev.getBalance(ctx, contractAddr, ownerAddr)
+	if err != nil {
+		return false, fmt.Errorf("failed to get balance: %w", err)
+	}
+
+	owned := false
+	if balance.Sign() > 0 {
+		tokenOwner, err := ev.getOwner(ctx, contractAddr, tokenIDInt)
+		if err != nil {
+			return false, fmt.Errorf("failed to get owner: %w", err)
+		}
+		owned = tokenOwner == ownerAddr
+	}
+
+	if ev.cache != nil {
+		_ = ev.cache.SetWithExpiration(cacheKey, owned, ev.cacheTTL)
+	}
+
+	ev.logger.Debug("ERC-721 ownership verified",
+		zap.String("contract", contractAddress),
+		zap.String("token_id", tokenID),
+		zap.String("owner", ownerAddress),
+		zap.Bool("owned", owned),
+		zap.String("balance", balance.String()))
+
+	return owned, nil
+}
+
+// IsEnumerable reports whether the contract advertises ERC721Enumerable
+// through ERC-165.
+func (ev *ERC721Verifier) IsEnumerable(ctx context.Context, contractAddress string) (bool, error) {
+	if !common.IsHexAddress(contractAddress) {
+		return false, fmt.Errorf("invalid contract address: %s", contractAddress)
+	}
+	supported, err := callSupportsInterface(ctx, ev.ethClient, erc165ParsedABI, common.HexToAddress(contractAddress), erc721EnumerableInterfaceID)
+	if err != nil {
+		return false, nil
+	}
+	return supported, nil
+}
+
+// ListOwnedTokenIDs returns the token IDs ownerAddress holds, read with
+// tokenOfOwnerByIndex. It returns ErrNotEnumerable for contracts without
+// the enumeration extension, and an error if the balance exceeds the
+// configured cap.
+func (ev *ERC721Verifier) ListOwnedTokenIDs(ctx context.Context, contractAddress, ownerAddress string) ([]string, error) {
+	ev.logger.Debug("Listing ERC-721 tokens",
+		zap.String("contract", contractAddress),
+		zap.String("owner", ownerAddress))
+
+	if !common.IsHexAddress(contractAddress) {
+		return nil, fmt.Errorf("invalid contract address: %s", contractAddress)
+	}
+	if !common.IsHexAddress(ownerAddress) {
+		return nil, fmt.Errorf("invalid owner address: %s", ownerAddress)
+	}
+
+	enumerable, err := ev.IsEnumerable(ctx, contractAddress)
+	if err != nil {
+		return nil, err
+	}
+	if !enumerable {
+		return nil, fmt.Errorf("%w: %s", ErrNotEnumerable, contractAddress)
+	}
+
+	contractAddr := common.HexToAddress(contractAddress)
+	ownerAddr := common.HexToAddress(ownerAddress)
+
+	balance, err := ev.getBalance(ctx, contractAddr, ownerAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance: %w", err)
+	}
+	if !balance.IsInt64() || balance.Int64() > int64(ev.maxEnumerated) {
+		return nil, fmt.Errorf("owner holds %s tokens, more than the enumeration limit of %d", balance, ev.maxEnumerated)
+	}
+
+	count := int(balance.Int64())
+	tokenIDs := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		tokenID, err := ev.getTokenOfOwnerByIndex(ctx, contractAddr, ownerAddr, big.NewInt(int64(i)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get token at index %d: %w", i, err)
+		}
+		tokenIDs = append(tokenIDs, tokenID.String())
+	}
+
+	ev.logger.Debug("ERC-721 tokens listed",
+		zap.String("contract", contractAddress),
+		zap.String("owner", ownerAddress),
+		zap.Int("count", len(tokenIDs)))
+
+	return tokenIDs, nil
+}
+
+// IsERC721Contract reports whether the contract advertises ERC-721 through
+// ERC-165. Contracts that predate ERC-165 are reported as false.
+func (ev *ERC721Verifier) IsERC721Contract(ctx context.Context, contractAddress string) (bool, error) {
+	ev.logger.Debug("Checking if contract is ERC-721 compliant",
+		zap.String("contract", contractAddress))
+
+	if !common.IsHexAddress(contractAddress) {
+		return false, fmt.Errorf("invalid contract address: %s", contractAddress)
+	}
+
+	contractAddr := common.HexToAddress(contractAddress)
+
+	supports165, err := callSupportsInterface(ctx, ev.ethClient, erc165ParsedABI, contractAddr, erc165InterfaceID)
+	if err != nil || !supports165 {
+		return false, nil
+	}
+	supports721, err := callSupportsInterface(ctx, ev.ethClient, erc165ParsedABI, contractAddr, erc721InterfaceID)
+	if err != nil {
+		return false, nil
+	}
+
+	return supports721, nil
+}
+
+func (ev *ERC721Verifier) getBalance(ctx context.Context, contractAddress, owner common.Address) (*big.Int, error) {
+	result, err := ev.call(ctx, contractAddress, "balanceOf", owner)
+	if err != nil {
+		return nil, err
+	}
+
+	var balance *big.Int
+	if err := ev.parsedERC721ABI.UnpackIntoInterface(&balance, "balanceOf", result); err != nil {
+		return nil, fmt.Errorf("failed to unpack result: %w", err)
+	}
+	return balance, nil
+}
+
+func (ev *ERC721Verifier) getOwner(ctx context.Context, contractAddress common.Address, tokenID *big.Int) (common.Address, error) {
+	result, err := ev.call(ctx, contractAddress, "ownerOf", tokenID)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	var owner common.Address
+	if err := ev.parsedERC721ABI.UnpackIntoInterface(&owner, "ownerOf", result); err != nil {
+		return common.Address{}, fmt.Errorf("failed to unpack result: %w", err)
+	}
+	return owner, nil
+}
+
+func (ev *ERC721Verifier) getTokenOfOwnerByIndex(ctx context.Context, contractAddress, owner common.Address, index *big.Int) (*big.Int, error) {
+	result, err := ev.call(ctx, contractAddress, "tokenOfOwnerByIndex", owner, index)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokenID *big.Int
+	if err := ev.parsedERC721ABI.UnpackIntoInterface(&tokenID, "tokenOfOwnerByIndex", result); err != nil {
+		return nil, fmt.Errorf("failed to unpack result: %w", err)
+	}
+	return tokenID, nil
+}
+
+func (ev *ERC721Verifier) call(ctx context.Context, contractAddress common.Address, method string, args ...interface{}) ([]byte, error) {
+	data, err := ev.parsedERC721ABI.Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack function call: %w", err)
+	}
+
+	result, err := ev.ethClient.CallContract(ctx, ethereum.CallMsg{
+		To:   &contractAddress,
+		Data: data,
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("contract call failed: %w", err)
+	}
+
+	if len(result) < 32 {
+		return nil, fmt.Errorf("%s returned insufficient data (len=%d): contract may not exist or is not a valid ERC-721 contract", method, len(result))
+	}
+	return result, nil
+}
@@ -0,0 +1,159 @@
+package nft
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const (
+	erc721TestContract = "0x1234567890123456789012345678901234567890"
+	erc721TestOwner    = "0x742d35Cc6634C0532925a3b844Bc9e7595f2bD18"
+)
+
+// erc721Contract is a fake ERC-721 contract answering by method selector.
+type erc721Contract struct {
+	interfaces map[[4]byte]bool
+	balances   map[common.Address]*big.Int
+	owners     map[string]common.Address
+	owned      map[common.Address][]*big.Int
+	calls      map[string]int
+}
+
+func (c *erc721Contract) caller(t *testing.T) *erc1155MockCaller {
+	parsed := NewERC721Verifier(nil, zap.NewNop(), nil).parsedERC721ABI
+	c.calls = make(map[string]int)
+	return &erc1155MockCaller{
+		callContractFn: func(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+			if bytes.Equal(call.Data[:4], erc165ParsedABI.Methods["supportsInterface"].ID) {
+				c.calls["supportsInterface"]++
+				var id [4]byte
+				copy(id[:], call.Data[4:8])
+				return encodeBool(c.interfaces[id]), nil
+			}
+			method, err := parsed.MethodById(call.Data[:4])
+			require.NoError(t, err)
+			c.calls[method.Name]++
+			args, err := method.Inputs.Unpack(call.Data[4:])
+			require.NoError(t, err)
+			switch method.Name {
+			case "balanceOf":
+				if b, ok := c.balances[args[0].(common.Address)]; ok {
+					return encodeUint256(b), nil
+				}
+				return encodeUint256(big.NewInt(0)), nil
+			case "ownerOf":
+				owner, ok := c.owners[args[0].(*big.Int).String()]
+				if !ok {
+					return nil, fmt.Errorf("execution reverted: nonexistent token")
+				}
+				return common.LeftPadBytes(owner.Bytes(), 32), nil
+			case "tokenOfOwnerByIndex":
+				return encodeUint256(c.owned[args[0].(common.Address)][args[1].(*big.Int).Int64()]), nil
+			}
+			return nil, fmt.Errorf("unexpected method %s", method.Name)
+		},
+	}
+}
+
+func TestERC721Verifier_VerifyNFTOwnership(t *testing.T) {
+	owner := common.HexToAddress(erc721TestOwner)
+	other := common.HexToAddress("0x000000000000000000000000000000000000dEaD")
+	contract := &erc721Contract{
+		balances: map[common.Address]*big.Int{owner: big.NewInt(1), other: big.NewInt(1)},
+		owners:   map[string]common.Address{"7": owner, "8": other},
+	}
+	verifier := NewERC721Verifier(contract.caller(t), zap.NewNop(), nil)
+	ctx := context.Background()
+
+	owned, err := verifier.VerifyNFTOwnership(ctx, erc721TestContract, "7", erc721TestOwner)
+	require.NoError(t, err)
+	assert.True(t, owned)
+
+	owned, err = verifier.VerifyNFTOwnership(ctx, erc721TestContract, "8", erc721TestOwner)
+	require.NoError(t, err)
+	assert.False(t, owned)
+
+	_, err = verifier.VerifyNFTOwnership(ctx, erc721TestContract, "9", erc721TestOwner)
+	assert.Error(t, err, "ownerOf reverts for nonexistent tokens")
+}
+
+func TestERC721Verifier_VerifyNFTOwnership_ZeroBalanceSkipsOwnerOf(t *testing.T) {
+	contract := &erc721Contract{}
+	verifier := NewERC721Verifier(contract.caller(t), zap.NewNop(), nil)
+
+	owned, err := verifier.VerifyNFTOwnership(context.Background(), erc721TestContract, "7", erc721TestOwner)
+	require.NoError(t, err)
+	assert.False(t, owned)
+	assert.Equal(t, 1, contract.calls["balanceOf"])
+	assert.Zero(t, contract.calls["ownerOf"])
+}
+
+func TestERC721Verifier_VerifyNFTOwnership_InvalidInput(t *testing.T) {
+	verifier := NewERC721Verifier(&erc1155MockCaller{}, zap.NewNop(), nil)
+	ctx := context.Background()
+
+	_, err := verifier.VerifyNFTOwnership(ctx, "not-an-address", "1", erc721TestOwner)
+	assert.Error(t, err)
+	_, err = verifier.VerifyNFTOwnership(ctx, erc721TestContract, "1", "not-an-address")
+	assert.Error(t, err)
+	_, err = verifier.VerifyNFTOwnership(ctx, erc721TestContract, "abc", erc721TestOwner)
+	assert.Error(t, err)
+}
+
+func TestERC721Verifier_ListOwnedTokenIDs(t *testing.T) {
+	owner := common.HexToAddress(erc721TestOwner)
+	contract := &erc721Contract{
+		interfaces: map[[4]byte]bool{erc721EnumerableInterfaceID: true},
+		balances:   map[common.Address]*big.Int{owner: big.NewInt(3)},
+		owned:      map[common.Address][]*big.Int{owner: {big.NewInt(4), big.NewInt(10), big.NewInt(42)}},
+	}
+	verifier := NewERC721Verifier(contract.caller(t), zap.NewNop(), nil)
+
+	ids, err := verifier.ListOwnedTokenIDs(context.Background(), erc721TestContract, erc721TestOwner)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"4", "10", "42"}, ids)
+
+	verifier.WithMaxEnumeratedTokens(2)
+	_, err = verifier.ListOwnedTokenIDs(context.Background(), erc721TestContract, erc721TestOwner)
+	assert.ErrorContains(t, err, "enumeration limit")
+}
+
+func TestERC721Verifier_ListOwnedTokenIDs_NotEnumerable(t *testing.T) {
+	contract := &erc721Contract{}
+	verifier := NewERC721Verifier(contract.caller(t), zap.NewNop(), nil)
+
+	_, err := verifier.ListOwnedTokenIDs(context.Background(), erc721TestContract, erc721TestOwner)
+	assert.ErrorIs(t, err, ErrNotEnumerable)
+	assert.Zero(t, contract.calls["tokenOfOwnerByIndex"])
+}
+
+func TestERC721Verifier_IsERC721Contract(t *testing.T) {
+	ctx := context.Background()
+
+	erc721 := &erc721Contract{interfaces: map[[4]byte]bool{erc165InterfaceID: true, erc721InterfaceID: true}}
+	ok, err := NewERC721Verifier(erc721.caller(t), zap.NewNop(), nil).IsERC721Contract(ctx, erc721TestContract)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	erc1155 := &erc721Contract{interfaces: map[[4]byte]bool{erc165InterfaceID: true, erc1155InterfaceID: true}}
+	ok, err = NewERC721Verifier(erc1155.caller(t), zap.NewNop(), nil).IsERC721Contract(ctx, erc721TestContract)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	failing := &erc1155MockCaller{}
+	ok, err = NewERC721Verifier(failing, zap.NewNop(), nil).IsERC721Contract(ctx, erc721TestContract)
+	require.NoError(t, err)
+	assert.False(t, ok, "contracts without ERC-165 are not detected")
+
+	_, err = NewERC721Verifier(failing, zap.NewNop(), nil).IsERC721Contract(ctx, "bad")
+	assert.Error(t, err)
+}
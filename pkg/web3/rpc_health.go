@@ -0,0 +1,263 @@
+package web3
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rtcdance/streamgate/pkg/monitoring"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"go.uber.org/zap"
+)
+
+const (
+	// rpcRateLimitCooldown keeps a rate-limited endpoint out of rotation
+	// longer than an ordinary failure: providers typically reset quotas on
+	// minute boundaries.
+	rpcRateLimitCooldown = 60 * time.Second
+	// rpcProbeTimeout bounds a single endpoint health probe.
+	rpcProbeTimeout = 5 * time.Second
+	// rpcMaxBlockLag is how many blocks an endpoint may trail the highest
+	// head seen in the same probe round before it is treated as unhealthy.
+	rpcMaxBlockLag = 5
+	// rpcRebalanceMargin is the score lead a healthy endpoint needs over the
+	// active one before calls are moved to it, to avoid flapping.
+	rpcRebalanceMargin = 0.2
+)
+
+// isRateLimitRPCError reports whether err is a provider quota or rate-limit
+// rejection (HTTP 429, JSON-RPC -32005 or the usual provider messages).
+func isRateLimitRPCError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, pattern := range []string{
+		"429",
+		"too many requests",
+		"rate limit",
+		"rate-limit",
+		"request limit",
+		"exceeded the quota",
+		"-32005",
+	} {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordCallError puts the endpoint at idx into cooldown after a failed
+// call, using the longer cooldown for rate limits, and counts the error.
+func (cc *ChainClient) recordCallError(idx int, err error) {
+	reason := "error"
+	if isRateLimitRPCError(err) {
+		reason = "rate_limited"
+		cc.recordEndpointCooldown(idx, rpcRateLimitCooldown)
+	} else {
+		cc.recordEndpointFailure(idx)
+	}
+	cc.countEndpointError(idx, reason)
+}
+
+func (cc *ChainClient) recordEndpointCooldown(idx int, cooldown time.Duration) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if idx < 0 || idx >= len(cc.rpcStates) {
+		return
+	}
+	state := cc.rpcStates[idx]
+	state.Failures++
+	state.LastFailureAt = time.Now()
+	state.CooldownUntil = state.LastFailureAt.Add(cooldown)
+	cc.rpcStates[idx] = state
+}
+
+func (cc *ChainClient) countEndpointError(idx int, reason string) {
+	if idx < 0 || idx >= len(cc.rpcURLs) {
+		return
+	}
+	monitoring.RPCEndpointErrorsTotal.WithLabelValues(strconv.FormatInt(cc.chainID, 10), monitoring.RPCProviderFromURL(cc.rpcURLs[idx]), reason).Inc()
+}
+
+// StartHealthChecks probes every configured endpoint with eth_blockNumber
+// each interval and moves calls to the best healthy endpoint. It is a no-op
+// for a non-positive interval or when already started; Close stops it.
+func (cc *ChainClient) StartHealthChecks(interval time.Duration) {
+	if interval <= 0 || cc.closed.Load() {
+		return
+	}
+	cc.mu.Lock()
+	if cc.healthStop != nil {
+		cc.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	cc.healthStop = stop
+	cc.mu.Unlock()
+
+	cc.wg.Add(1)
+	go func() {
+		defer cc.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				cc.ProbeEndpoints(ctx)
+				cancel()
+			}
+		}
+	}()
+	cc.logger.Info("RPC endpoint health checks started",
+		zap.Int64("chain_id", cc.chainID),
+		zap.Int("endpoints", len(cc.rpcURLs)),
+		zap.Duration("interval", interval))
+}
+
+func (cc *ChainClient) stopHealthChecks() {
+	cc.mu.Lock()
+	stop := cc.healthStop
+	cc.healthStop = nil
+	cc.mu.Unlock()
+	if stop != nil {
+		close(stop)
+	}
+}
+
+type rpcProbeResult struct {
+	latency time.Duration
+	head    uint64
+	err     error
+}
+
+// ProbeEndpoints checks every endpoint's eth_blockNumber latency in
+// parallel, updates scores, cooldowns and per-endpoint metrics, and then
+// rebalances. Endpoints trailing the highest head by more than
+// rpcMaxBlockLag blocks count as failed. It returns the resulting statuses.
+func (cc *ChainClient) ProbeEndpoints(ctx context.Context) []RPCStatus {
+	results := make([]rpcProbeResult, len(cc.rpcURLs))
+	var wg sync.WaitGroup
+	for idx := range cc.rpcURLs {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			results[idx] = cc.probeEndpoint(ctx, idx)
+		}(idx)
+	}
+	wg.Wait()
+
+	var maxHead uint64
+	for _, r := range results {
+		if r.err == nil && r.head > maxHead {
+			maxHead = r.head
+		}
+	}
+
+	chainLabel := strconv.FormatInt(cc.chainID, 10)
+	for idx, r := range results {
+		provider := monitoring.RPCProviderFromURL(cc.rpcURLs[idx])
+		healthy := r.err == nil && maxHead-r.head <= rpcMaxBlockLag
+		switch {
+		case r.err != nil:
+			cc.recordCallError(idx, r.err)
+			cc.updateRPCScores(idx, r.latency, false)
+			cc.logger.Warn("RPC endpoint health probe failed",
+				zap.Int64("chain_id", cc.chainID),
+				zap.String("rpc_url", cc.rpcURLs[idx]),
+				zap.Error(r.err))
+		case !healthy:
+			cc.recordEndpointFailure(idx)
+			cc.countEndpointError(idx, "lagging")
+			cc.updateRPCScores(idx, r.latency, false)
+			cc.logger.Warn("RPC endpoint is lagging",
+				zap.Int64("chain_id", cc.chainID),
+				zap.String("rpc_url", cc.rpcURLs[idx]),
+				zap.Uint64("head", r.head),
+				zap.Uint64("max_head", maxHead))
+		default:
+			cc.updateRPCScores(idx, r.latency, true)
+			monitoring.RPCEndpointProbeLatencySeconds.WithLabelValues(chainLabel, provider).Set(r.latency.Seconds())
+		}
+		if r.err == nil {
+			cc.mu.Lock()
+			cc.rpcStates[idx].LastBlock = r.head
+			cc.mu.Unlock()
+		}
+		up := 0.0
+		if healthy {
+			up = 1
+		}
+		monitoring.RPCEndpointUp.WithLabelValues(chainLabel, provider).Set(up)
+	}
+
+	cc.rebalance(ctx)
+	return cc.GetRPCStatuses()
+}
+
+// probeEndpoint measures eth_blockNumber on one endpoint. The active
+// endpoint is probed over the live connection; others get a short-lived one.
+func (cc *ChainClient) probeEndpoint(ctx context.Context, idx int) rpcProbeResult {
+	probeCtx, cancel := context.WithTimeout(ctx, rpcProbeTimeout)
+	defer cancel()
+
+	client := cc.client.Load()
+	if idx != cc.getActiveRPCIndex() || client == nil {
+		dialed, err := ethclient.DialContext(probeCtx, cc.rpcURLs[idx])
+		if err != nil {
+			return rpcProbeResult{err: err}
+		}
+		defer dialed.Close()
+		client = dialed
+	}
+
+	start := time.Now()
+	head, err := client.BlockNumber(probeCtx)
+	return rpcProbeResult{latency: time.Since(start), head: head, err: err}
+}
+
+// rebalance moves calls to the best-scored endpoint that is not cooling
+// down, if the active endpoint is cooling down or the best one leads it by
+// rpcRebalanceMargin.
+func (cc *ChainClient) rebalance(ctx context.Context) {
+	active := cc.getActiveRPCIndex()
+	best := -1
+	for _, idx := range cc.sortedRPCScores() {
+		if cc.endpointReady(idx, false) {
+			best = idx
+			break
+		}
+	}
+	if best < 0 || best == active {
+		return
+	}
+
+	cc.mu.RLock()
+	activeScore := cc.rpcStates[active].Score
+	bestScore := cc.rpcStates[best].Score
+	cc.mu.RUnlock()
+	if cc.endpointReady(active, false) && bestScore < activeScore+rpcRebalanceMargin {
+		return
+	}
+
+	client, _, err := cc.connectAt(ctx, best)
+	if err != nil {
+		cc.recordCallError(best, err)
+		return
+	}
+	from := monitoring.RPCProviderFromURL(cc.rpcURLs[active])
+	cc.setActiveClient(best, client, false)
+	monitoring.RPCFailoverTotal.WithLabelValues("rebalance", from, monitoring.RPCProviderFromURL(cc.rpcURLs[best])).Inc()
+	cc.logger.Info("Rebalanced blockchain RPC endpoint",
+		zap.Int64("chain_id", cc.chainID),
+		zap.String("rpc_url", cc.rpcURLs[best]),
+		zap.Float64("score", bestScore),
+		zap.Float64("previous_score", activeScore))
+}
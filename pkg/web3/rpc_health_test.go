@@ -0,0 +1,136 @@
+package web3
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func blockNumberHandler(head string) func(req rpcRequest) rpcResponse {
+	return func(req rpcRequest) rpcResponse {
+		return rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: head}
+	}
+}
+
+func rpcErrorHandler(code int, message string) func(req rpcRequest) rpcResponse {
+	return func(req rpcRequest) rpcResponse {
+		return rpcResponse{JSONRPC: "2.0", ID: req.ID, Error: map[string]interface{}{"code": code, "message": message}}
+	}
+}
+
+func TestIsRateLimitRPCError(t *testing.T) {
+	for _, msg := range []string{
+		"429 Too Many Requests",
+		"daily request limit reached",
+		"Your app has exceeded the quota",
+		"rate limit exceeded",
+	} {
+		assert.True(t, isRateLimitRPCError(errors.New(msg)), msg)
+	}
+	assert.False(t, isRateLimitRPCError(errors.New("execution reverted")))
+	assert.False(t, isRateLimitRPCError(nil))
+}
+
+func TestChainClient_RateLimitedEndpointCoolsDownLonger(t *testing.T) {
+	limited := newRPCServer(t, map[string]func(req rpcRequest) rpcResponse{
+		"eth_chainId":     chainIDHandler(11155111),
+		"eth_blockNumber": rpcErrorHandler(-32005, "rate limit exceeded"),
+	})
+	defer limited.Close()
+	healthy := newRPCServer(t, map[string]func(req rpcRequest) rpcResponse{
+		"eth_chainId":     chainIDHandler(11155111),
+		"eth_blockNumber": blockNumberHandler("0x2a"),
+	})
+	defer healthy.Close()
+
+	client, err := NewChainClientWithFallback([]string{limited.URL, healthy.URL}, 11155111, zap.NewNop())
+	require.NoError(t, err)
+	defer client.Close()
+
+	head, err := client.GetBlockNumber(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), head)
+
+	status := client.GetRPCStatuses()[0]
+	assert.False(t, status.IsActive)
+	assert.True(t, status.CooldownUntil.After(time.Now().Add(rpcFailureCooldown)), "rate limits cool down longer than failures")
+}
+
+func TestChainClient_ProbeEndpoints(t *testing.T) {
+	primary := newRPCServer(t, map[string]func(req rpcRequest) rpcResponse{
+		"eth_chainId":     chainIDHandler(11155111),
+		"eth_blockNumber": rpcErrorHandler(-32000, "upstream failure"),
+	})
+	defer primary.Close()
+	lagging := newRPCServer(t, map[string]func(req rpcRequest) rpcResponse{
+		"eth_chainId":     chainIDHandler(11155111),
+		"eth_blockNumber": blockNumberHandler("0x1"),
+	})
+	defer lagging.Close()
+	healthy := newRPCServer(t, map[string]func(req rpcRequest) rpcResponse{
+		"eth_chainId":     chainIDHandler(11155111),
+		"eth_blockNumber": blockNumberHandler("0x64"),
+	})
+	defer healthy.Close()
+
+	client, err := NewChainClientWithFallback([]string{primary.URL, lagging.URL, healthy.URL}, 11155111, zap.NewNop())
+	require.NoError(t, err)
+	defer client.Close()
+	require.Equal(t, primary.URL, client.rpcURL)
+
+	statuses := client.ProbeEndpoints(context.Background())
+	require.Len(t, statuses, 3)
+
+	assert.Equal(t, 1, statuses[0].Failures, "failed probe")
+	assert.Equal(t, 1, statuses[1].Failures, "lagging endpoint")
+	assert.Equal(t, uint64(1), statuses[1].LastBlock)
+	assert.Zero(t, statuses[2].Failures)
+	assert.Equal(t, uint64(100), statuses[2].LastBlock)
+	assert.True(t, statuses[2].IsActive, "calls move off the failing endpoint")
+	assert.Equal(t, healthy.URL, client.rpcURL)
+}
+
+func TestChainClient_RebalanceKeepsHealthyActive(t *testing.T) {
+	first := newRPCServer(t, map[string]func(req rpcRequest) rpcResponse{
+		"eth_chainId":     chainIDHandler(11155111),
+		"eth_blockNumber": blockNumberHandler("0x64"),
+	})
+	defer first.Close()
+	second := newRPCServer(t, map[string]func(req rpcRequest) rpcResponse{
+		"eth_chainId":     chainIDHandler(11155111),
+		"eth_blockNumber": blockNumberHandler("0x64"),
+	})
+	defer second.Close()
+
+	client, err := NewChainClientWithFallback([]string{first.URL, second.URL}, 11155111, zap.NewNop())
+	require.NoError(t, err)
+	defer client.Close()
+
+	client.ProbeEndpoints(context.Background())
+	assert.Equal(t, first.URL, client.rpcURL, "similar scores do not flap")
+}
+
+func TestChainClient_StartHealthChecks(t *testing.T) {
+	server := newRPCServer(t, map[string]func(req rpcRequest) rpcResponse{
+		"eth_chainId":     chainIDHandler(11155111),
+		"eth_blockNumber": blockNumberHandler("0x7"),
+	})
+	defer server.Close()
+
+	client, err := NewChainClientWithFallback([]string{server.URL, server.URL + "/"}, 11155111, zap.NewNop())
+	require.NoError(t, err)
+
+	client.StartHealthChecks(10 * time.Millisecond)
+	client.StartHealthChecks(10 * time.Millisecond) // no-op when running
+	assert.Eventually(t, func() bool {
+		return client.GetRPCStatuses()[1].LastBlock == 7
+	}, time.Second, 10*time.Millisecond)
+
+	client.Close()
+	client.StartHealthChecks(10 * time.Millisecond) // no-op once closed
+}
@@ -70,6 +70,10 @@ func (ev *EIP712Verifier) VerifyTypedData(address string, typedData *EIP712Typed
 		sig[64] -= 27
 	}
 
+	if err := typedData.Validate(); err != nil {
+		return false, err
+	}
+
 	apiTypedData := ev.convertToAPITypes(typedData)
 
 	hash, err := hashTypedData(apiTypedData)
@@ -100,6 +104,10 @@ func (ev *EIP712Verifier) SignTypedData(typedData *EIP712TypedData, privateKey *
 	ev.logger.Debug("Signing EIP-712 typed data",
 		zap.String("primary_type", typedData.PrimaryType))
 
+	if err := typedData.Validate(); err != nil {
+		return "", err
+	}
+
 	apiTypedData := ev.convertToAPITypes(typedData)
 
 	hash, err := hashTypedData(apiTypedData)
@@ -223,11 +231,16 @@ func CreateDelegationTypedData(domain EIP712Domain, delegatee, authority string,
 	}
 }
 
+// ParseTypedDataFromJSON decodes and validates an EIP-712 document;
+// structural problems are reported as a *TypedDataError.
 func ParseTypedDataFromJSON(jsonData []byte) (*EIP712TypedData, error) {
 	var typedData EIP712TypedData
 	if err := json.Unmarshal(jsonData, &typedData); err != nil {
 		return nil, fmt.Errorf("failed to parse typed data JSON: %w", err)
 	}
+	if err := typedData.Validate(); err != nil {
+		return nil, err
+	}
 	return &typedData, nil
 }
 
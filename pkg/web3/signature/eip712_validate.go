@@ -0,0 +1,182 @@
+package signature
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Typed-data validation failures. Validate wraps them in a
+// *TypedDataError, so callers can match with errors.Is.
+var (
+	ErrMissingPrimaryType = errors.New("primary type is not set")
+	ErrUndefinedType      = errors.New("type is not defined")
+	ErrInvalidTypeField   = errors.New("invalid type field")
+	ErrDomainMismatch     = errors.New("domain does not match EIP712Domain type")
+)
+
+// TypedDataError reports which type and field of an EIP-712 document
+// failed validation.
+type TypedDataError struct {
+	Type   string // struct type being checked, e.g. "Permit"
+	Field  string // field within Type, empty when the type itself is at fault
+	Err    error  // one of the Err* sentinels above
+	Detail string // extra context, such as the undefined type's name
+}
+
+func (e *TypedDataError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("invalid typed data: ")
+	if e.Type != "" {
+		sb.WriteString(e.Type)
+		if e.Field != "" {
+			sb.WriteString(".")
+			sb.WriteString(e.Field)
+		}
+		sb.WriteString(": ")
+	}
+	sb.WriteString(e.Err.Error())
+	if e.Detail != "" {
+		sb.WriteString(": ")
+		sb.WriteString(e.Detail)
+	}
+	return sb.String()
+}
+
+func (e *TypedDataError) Unwrap() error { return e.Err }
+
+// eip712DomainFields are the domain fields EIP-712 defines, with their
+// required types.
+var eip712DomainFields = map[string]string{
+	"name":              "string",
+	"version":           "string",
+	"chainId":           "uint256",
+	"verifyingContract": "address",
+	"salt":              "bytes32",
+}
+
+// Validate checks that the document is internally consistent before it is
+// hashed: the primary type is defined, every field refers to an atomic
+// type or a defined struct, and the domain values match the declared
+// EIP712Domain type in both directions.
+func (td *EIP712TypedData) Validate() error {
+	if td.PrimaryType == "" {
+		return &TypedDataError{Err: ErrMissingPrimaryType}
+	}
+	if _, ok := td.Types[td.PrimaryType]; !ok {
+		return &TypedDataError{Type: td.PrimaryType, Err: ErrUndefinedType, Detail: "primary type"}
+	}
+	if _, ok := td.Types["EIP712Domain"]; !ok {
+		return &TypedDataError{Type: "EIP712Domain", Err: ErrUndefinedType}
+	}
+
+	for _, typeName := range slices.Sorted(maps.Keys(td.Types)) {
+		fields := td.Types[typeName]
+		seen := make(map[string]bool, len(fields))
+		for _, f := range fields {
+			if f.Name == "" {
+				return &TypedDataError{Type: typeName, Err: ErrInvalidTypeField, Detail: "field name is empty"}
+			}
+			if seen[f.Name] {
+				return &TypedDataError{Type: typeName, Field: f.Name, Err: ErrInvalidTypeField, Detail: "duplicate field"}
+			}
+			seen[f.Name] = true
+			base := baseTypeName(f.Type)
+			if base == "" {
+				return &TypedDataError{Type: typeName, Field: f.Name, Err: ErrInvalidTypeField, Detail: fmt.Sprintf("malformed type %q", f.Type)}
+			}
+			if isAtomicType(base) {
+				continue
+			}
+			if _, ok := td.Types[base]; !ok {
+				return &TypedDataError{Type: typeName, Field: f.Name, Err: ErrUndefinedType, Detail: base}
+			}
+		}
+	}
+
+	return td.validateDomain()
+}
+
+func (td *EIP712TypedData) validateDomain() error {
+	declared := make(map[string]bool)
+	for _, f := range td.Types["EIP712Domain"] {
+		want, known := eip712DomainFields[f.Name]
+		if !known {
+			return &TypedDataError{Type: "EIP712Domain", Field: f.Name, Err: ErrDomainMismatch, Detail: "not an EIP-712 domain field"}
+		}
+		if f.Type != want {
+			return &TypedDataError{Type: "EIP712Domain", Field: f.Name, Err: ErrDomainMismatch, Detail: fmt.Sprintf("type %s, want %s", f.Type, want)}
+		}
+		declared[f.Name] = true
+	}
+
+	d := td.Domain
+	for _, f := range []struct {
+		name  string
+		isSet bool
+	}{
+		{"name", d.Name != ""},
+		{"version", d.Version != ""},
+		{"chainId", d.ChainId != nil},
+		{"verifyingContract", d.VerifyingContract != ""},
+		{"salt", d.Salt != ""},
+	} {
+		switch {
+		case declared[f.name] && !f.isSet:
+			return &TypedDataError{Type: "EIP712Domain", Field: f.name, Err: ErrDomainMismatch, Detail: "declared but not set"}
+		case f.isSet && !declared[f.name]:
+			return &TypedDataError{Type: "EIP712Domain", Field: f.name, Err: ErrDomainMismatch, Detail: "set but not declared"}
+		}
+	}
+	if d.VerifyingContract != "" && !common.IsHexAddress(d.VerifyingContract) {
+		return &TypedDataError{Type: "EIP712Domain", Field: "verifyingContract", Err: ErrDomainMismatch, Detail: "not an address"}
+	}
+	return nil
+}
+
+// baseTypeName strips array suffixes ("Person[]", "uint256[3][]") and
+// returns "" for malformed brackets.
+func baseTypeName(t string) string {
+	for strings.HasSuffix(t, "]") {
+		open := strings.LastIndexByte(t, '[')
+		if open < 0 {
+			return ""
+		}
+		if n := t[open+1 : len(t)-1]; n != "" {
+			if _, err := strconv.ParseUint(n, 10, 32); err != nil {
+				return ""
+			}
+		}
+		t = t[:open]
+	}
+	return t
+}
+
+// isAtomicType reports whether t is an EIP-712 atomic or dynamic type
+// rather than a reference to a struct.
+func isAtomicType(t string) bool {
+	switch t {
+	case "address", "bool", "string", "bytes":
+		return true
+	}
+	for prefix, limit := range map[string]int{"bytes": 32, "uint": 256, "int": 256} {
+		rest, ok := strings.CutPrefix(t, prefix)
+		if !ok || rest == "" {
+			continue
+		}
+		n, err := strconv.Atoi(rest)
+		if err != nil || n <= 0 || n > limit {
+			return false
+		}
+		if prefix != "bytes" && n%8 != 0 {
+			return false
+		}
+		return true
+	}
+	return false
+}
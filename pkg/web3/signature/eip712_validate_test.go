@@ -0,0 +1,90 @@
+package signature
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validPermit() *EIP712TypedData {
+	domain := EIP712Domain{
+		Name:              "StreamGate",
+		Version:           "1",
+		ChainId:           big.NewInt(1),
+		VerifyingContract: "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC",
+	}
+	return CreatePermitTypedData(domain, "0x1", "0x2", big.NewInt(100), big.NewInt(0), big.NewInt(1716000000))
+}
+
+func TestEIP712TypedData_Validate_Valid(t *testing.T) {
+	assert.NoError(t, validPermit().Validate())
+
+	td := validPermit()
+	td.Types["Order"] = []EIP712Type{{Name: "permits", Type: "Permit[]"}, {Name: "amounts", Type: "uint256[2]"}}
+	td.PrimaryType = "Order"
+	assert.NoError(t, td.Validate(), "arrays of defined structs and atomic types are allowed")
+}
+
+func TestEIP712TypedData_Validate_MissingPrimaryType(t *testing.T) {
+	td := validPermit()
+	td.PrimaryType = ""
+	assert.ErrorIs(t, td.Validate(), ErrMissingPrimaryType)
+
+	td.PrimaryType = "Transfer"
+	err := td.Validate()
+	assert.ErrorIs(t, err, ErrUndefinedType)
+	var tdErr *TypedDataError
+	require.True(t, errors.As(err, &tdErr))
+	assert.Equal(t, "Transfer", tdErr.Type)
+}
+
+func TestEIP712TypedData_Validate_UndefinedReferencedType(t *testing.T) {
+	td := validPermit()
+	td.Types["Permit"] = append(td.Types["Permit"], EIP712Type{Name: "holder", Type: "Person"})
+
+	err := td.Validate()
+	assert.ErrorIs(t, err, ErrUndefinedType)
+	var tdErr *TypedDataError
+	require.True(t, errors.As(err, &tdErr))
+	assert.Equal(t, "Permit", tdErr.Type)
+	assert.Equal(t, "holder", tdErr.Field)
+	assert.Contains(t, err.Error(), "Person")
+
+	td = validPermit()
+	td.Types["Permit"][0].Type = "uint7"
+	assert.ErrorIs(t, td.Validate(), ErrUndefinedType, "a malformed atomic type is not silently accepted")
+}
+
+func TestEIP712TypedData_Validate_Domain(t *testing.T) {
+	td := validPermit()
+	td.Domain.VerifyingContract = ""
+	err := td.Validate()
+	assert.ErrorIs(t, err, ErrDomainMismatch)
+	assert.Contains(t, err.Error(), "verifyingContract")
+
+	td = validPermit()
+	td.Domain.Salt = "0x01"
+	assert.ErrorIs(t, td.Validate(), ErrDomainMismatch, "domain values must be declared")
+
+	td = validPermit()
+	td.Types["EIP712Domain"][2].Type = "string"
+	assert.ErrorIs(t, td.Validate(), ErrDomainMismatch, "chainId must be uint256")
+
+	td = validPermit()
+	delete(td.Types, "EIP712Domain")
+	assert.ErrorIs(t, td.Validate(), ErrUndefinedType)
+}
+
+func TestParseTypedDataFromJSON_Validates(t *testing.T) {
+	td := validPermit()
+	td.PrimaryType = "Missing"
+	jsonData, err := json.Marshal(td)
+	require.NoError(t, err)
+
+	_, err = ParseTypedDataFromJSON(jsonData)
+	assert.ErrorIs(t, err, ErrUndefinedType)
+}
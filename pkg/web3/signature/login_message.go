@@ -0,0 +1,78 @@
+package signature
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// DefaultLoginMessageTemplate is the personal_sign login message used when
+// no template is configured.
+const DefaultLoginMessageTemplate = "Sign this message to authenticate with StreamGate.\n" +
+	"Address: {{.Address}}\n" +
+	"Chain ID: {{.ChainID}}\n" +
+	"Nonce: {{.Nonce}}\n" +
+	"Issued At: {{.IssuedAt}}\n" +
+	"Expires At: {{.ExpiresAt}}"
+
+// LoginMessageFields are the values a login message template can reference,
+// e.g. {{.Domain}} or {{.Nonce}}. Times are RFC 3339 in UTC.
+type LoginMessageFields struct {
+	Domain    string
+	Address   string
+	ChainID   int64
+	Nonce     string
+	IssuedAt  string
+	ExpiresAt string
+	Statement string
+}
+
+// LoginMessageTemplate renders branded personal_sign login messages.
+type LoginMessageTemplate struct {
+	tmpl *template.Template
+}
+
+// ParseLoginMessageTemplate compiles text as a Go text/template over
+// LoginMessageFields. The template must render the nonce and issue time so
+// every signed message is single-use and dated; an empty text uses
+// DefaultLoginMessageTemplate.
+func ParseLoginMessageTemplate(text string) (*LoginMessageTemplate, error) {
+	if strings.TrimSpace(text) == "" {
+		text = DefaultLoginMessageTemplate
+	}
+	tmpl, err := template.New("login_message").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid login message template: %w", err)
+	}
+	t := &LoginMessageTemplate{tmpl: tmpl}
+
+	probe := LoginMessageFields{
+		Domain:    "probe.example",
+		Address:   "0x0000000000000000000000000000000000000001",
+		ChainID:   1,
+		Nonce:     "probe-nonce-8c1f",
+		IssuedAt:  "2000-01-02T03:04:05Z",
+		ExpiresAt: "2000-01-02T03:09:05Z",
+		Statement: "probe statement",
+	}
+	rendered, err := t.Render(probe)
+	if err != nil {
+		return nil, fmt.Errorf("invalid login message template: %w", err)
+	}
+	if !strings.Contains(rendered, probe.Nonce) {
+		return nil, fmt.Errorf("invalid login message template: must include {{.Nonce}}")
+	}
+	if !strings.Contains(rendered, probe.IssuedAt) {
+		return nil, fmt.Errorf("invalid login message template: must include {{.IssuedAt}}")
+	}
+	return t, nil
+}
+
+// Render executes the template with f.
+func (t *LoginMessageTemplate) Render(f LoginMessageFields) (string, error) {
+	var sb strings.Builder
+	if err := t.tmpl.Execute(&sb, f); err != nil {
+		return "", fmt.Errorf("failed to render login message: %w", err)
+	}
+	return sb.String(), nil
+}
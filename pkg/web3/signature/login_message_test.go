@@ -0,0 +1,49 @@
+package signature
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLoginMessageTemplate_Render(t *testing.T) {
+	tmpl, err := ParseLoginMessageTemplate("{{.Statement}}\n{{.Domain}} wants {{.Address}} on chain {{.ChainID}}\nNonce: {{.Nonce}}\nAt: {{.IssuedAt}} until {{.ExpiresAt}}")
+	require.NoError(t, err)
+
+	msg, err := tmpl.Render(LoginMessageFields{
+		Domain:    "acme.example",
+		Address:   "0x71C7656EC7ab88b098defB751B7401B5f6d8976F",
+		ChainID:   137,
+		Nonce:     "n0nce",
+		IssuedAt:  "2026-05-07T12:00:00Z",
+		ExpiresAt: "2026-05-07T12:05:00Z",
+		Statement: "Welcome back",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Welcome back\nacme.example wants 0x71C7656EC7ab88b098defB751B7401B5f6d8976F on chain 137\nNonce: n0nce\nAt: 2026-05-07T12:00:00Z until 2026-05-07T12:05:00Z", msg)
+}
+
+func TestParseLoginMessageTemplate_Default(t *testing.T) {
+	tmpl, err := ParseLoginMessageTemplate("  ")
+	require.NoError(t, err)
+	msg, err := tmpl.Render(LoginMessageFields{Address: "0xabc", ChainID: 1, Nonce: "n0nce", IssuedAt: "t0", ExpiresAt: "t1"})
+	require.NoError(t, err)
+	assert.Equal(t, "Sign this message to authenticate with StreamGate.\nAddress: 0xabc\nChain ID: 1\nNonce: n0nce\nIssued At: t0\nExpires At: t1", msg)
+}
+
+func TestParseLoginMessageTemplate_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"syntax error":    "Nonce: {{.Nonce}",
+		"unknown field":   "{{.Nonce}} {{.IssuedAt}} {{.Tenant}}",
+		"missing nonce":   "Sign in at {{.IssuedAt}}",
+		"missing issued":  "Nonce: {{.Nonce}}",
+		"constant output": "Sign in to Acme",
+	}
+	for name, text := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseLoginMessageTemplate(text)
+			assert.Error(t, err)
+		})
+	}
+}
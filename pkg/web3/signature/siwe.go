@@ -2,6 +2,7 @@ package signature
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,6 +18,7 @@ type SIWEMessage struct {
 	Version        string
 	ChainID        int64
 	Nonce          string
+	Statement      string
 	IssuedAt       string
 	ExpirationTime string
 	NotBefore      string
@@ -29,7 +31,7 @@ type SIWEMessage struct {
 // EIP-4361 compliance: it prevents phishing (domain binding), replay (nonce),
 // and cross-chain attacks (chain ID binding).
 //
-// A zero-value expectedDomain skips domain validation (for backwards compatibility).
+// Zero-value expected values skip their check (for backwards compatibility).
 func ValidateSIWEMessage(msg *SIWEMessage, expectedDomain, expectedAddress, expectedNonce string, expectedChainID int64) error {
 	if msg.Version != "1" {
 		return fmt.Errorf("invalid SIWE version: got %q, want \"1\"", msg.Version)
@@ -42,6 +44,9 @@ func ValidateSIWEMessage(msg *SIWEMessage, expectedDomain, expectedAddress, expe
 		return fmt.Errorf("SIWE address is not EIP-55 checksummed: got %q, want %q", msg.Address, checksummed)
 	}
 
+	if expectedAddress != "" && common.HexToAddress(expectedAddress) != common.HexToAddress(msg.Address) {
+		return fmt.Errorf("SIWE address mismatch: got %q, want %q", msg.Address, expectedAddress)
+	}
 	if expectedDomain != "" && !strings.EqualFold(msg.Domain, expectedDomain) {
 		return fmt.Errorf("SIWE domain mismatch: got %q, want %q", msg.Domain, expectedDomain)
 	}
@@ -54,6 +59,10 @@ func ValidateSIWEMessage(msg *SIWEMessage, expectedDomain, expectedAddress, expe
 	return nil
 }
 
+// DefaultSIWEStatement is the statement line of SIWE messages that do not
+// set one.
+const DefaultSIWEStatement = "Sign in to StreamGate"
+
 // BuildSIWEMessage constructs an EIP-4361 formatted message string.
 func BuildSIWEMessage(msg *SIWEMessage) string {
 	var sb strings.Builder
@@ -61,7 +70,11 @@ func BuildSIWEMessage(msg *SIWEMessage) string {
 	fmt.Fprintf(&sb, "%s wants you to sign in with your Ethereum account:\n", msg.Domain)
 	fmt.Fprintf(&sb, "%s\n\n", msg.Address)
 
-	sb.WriteString("Sign in to StreamGate\n\n")
+	statement := msg.Statement
+	if statement == "" {
+		statement = DefaultSIWEStatement
+	}
+	fmt.Fprintf(&sb, "%s\n\n", statement)
 
 	fmt.Fprintf(&sb, "URI: %s\n", msg.URI)
 	fmt.Fprintf(&sb, "Version: %s\n", msg.Version)
@@ -110,6 +123,9 @@ func ParseSIWEMessage(message string) (*SIWEMessage, error) {
 	if !common.IsHexAddress(msg.Address) {
 		return nil, fmt.Errorf("invalid SIWE message: invalid Ethereum address format")
 	}
+	if lines[2] == "" && lines[4] == "" {
+		msg.Statement = lines[3]
+	}
 
 	for _, line := range lines[5:] {
 		line = strings.TrimSpace(line)
@@ -122,8 +138,10 @@ func ParseSIWEMessage(message string) (*SIWEMessage, error) {
 		} else if strings.HasPrefix(line, "Version: ") {
 			msg.Version = strings.TrimPrefix(line, "Version: ")
 		} else if strings.HasPrefix(line, "Chain ID: ") {
-			var chainID int64
-			_, _ = fmt.Sscanf(strings.TrimPrefix(line, "Chain ID: "), "%d", &chainID)
+			chainID, err := strconv.ParseInt(strings.TrimPrefix(line, "Chain ID: "), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid SIWE message: invalid Chain ID: %w", err)
+			}
 			msg.ChainID = chainID
 		} else if strings.HasPrefix(line, "Nonce: ") {
 			msg.Nonce = strings.TrimPrefix(line, "Nonce: ")
@@ -157,6 +175,12 @@ func WithSIWEExpirationTime(t time.Time) SIWEMessageOption {
 	return func(m *SIWEMessage) { m.ExpirationTime = t.UTC().Format(time.RFC3339) }
 }
 
+// WithSIWEStatement sets the human-readable statement shown above the
+// message fields.
+func WithSIWEStatement(statement string) SIWEMessageOption {
+	return func(m *SIWEMessage) { m.Statement = statement }
+}
+
 func WithSIWENotBefore(t time.Time) SIWEMessageOption {
 	return func(m *SIWEMessage) { m.NotBefore = t.UTC().Format(time.RFC3339) }
 }
@@ -134,6 +134,22 @@ func TestBuildAndParseRoundTrip(t *testing.T) {
 	assert.Equal(t, original.Resources, parsed.Resources)
 }
 
+func TestBuildAndParseRoundTrip_Statement(t *testing.T) {
+	now := time.Date(2026, 5, 7, 12, 0, 0, 0, time.UTC)
+	msg := NewSIWEMessage("acme.example", "0x71C7656EC7ab88b098defB751B7401B5f6d8976F", "https://acme.example/login", 1, "abc123", now, WithSIWEStatement("Sign in to Acme Video"))
+
+	built := BuildSIWEMessage(msg)
+	assert.Contains(t, built, "\n\nSign in to Acme Video\n\n")
+	parsed, err := ParseSIWEMessage(built)
+	require.NoError(t, err)
+	assert.Equal(t, "Sign in to Acme Video", parsed.Statement)
+
+	msg.Statement = ""
+	parsed, err = ParseSIWEMessage(BuildSIWEMessage(msg))
+	require.NoError(t, err)
+	assert.Equal(t, DefaultSIWEStatement, parsed.Statement)
+}
+
 func TestNewSIWEMessage_Defaults(t *testing.T) {
 	now := time.Now()
 	expires := now.Add(5 * time.Minute)
@@ -0,0 +1,121 @@
+package signature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultSIWEClockSkew is the clock skew tolerated on SIWE time fields.
+const DefaultSIWEClockSkew = 30 * time.Second
+
+// ErrSIWESignatureMismatch is returned when a SIWE signature was not made
+// by the message's address.
+var ErrSIWESignatureMismatch = errors.New("SIWE signature does not match message address")
+
+// SIWEExpectations are the values a SIWE message must match. Empty fields
+// are not checked, except that the time fields are always validated.
+type SIWEExpectations struct {
+	Domain  string
+	Address string
+	URI     string
+	Nonce   string
+	ChainID int64
+	// Now is the verification time; zero uses time.Now.
+	Now time.Time
+	// ClockSkew is tolerated on Issued At, Not Before and Expiration Time;
+	// zero uses DefaultSIWEClockSkew.
+	ClockSkew time.Duration
+}
+
+// ValidateSIWEFields checks every field of msg: version, address, domain,
+// URI, nonce and chain ID against exp, and that Issued At is not in the
+// future, Not Before has passed and Expiration Time has not.
+func ValidateSIWEFields(msg *SIWEMessage, exp SIWEExpectations) error {
+	if err := ValidateSIWEMessage(msg, exp.Domain, exp.Address, exp.Nonce, exp.ChainID); err != nil {
+		return err
+	}
+	if exp.URI != "" && msg.URI != exp.URI {
+		return fmt.Errorf("SIWE URI mismatch: got %q, want %q", msg.URI, exp.URI)
+	}
+
+	now := exp.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	skew := exp.ClockSkew
+	if skew <= 0 {
+		skew = DefaultSIWEClockSkew
+	}
+
+	issuedAt, err := parseSIWETime("Issued At", msg.IssuedAt)
+	if err != nil {
+		return err
+	}
+	if issuedAt.IsZero() {
+		return fmt.Errorf("invalid SIWE message: missing Issued At field")
+	}
+	if issuedAt.After(now.Add(skew)) {
+		return fmt.Errorf("SIWE message issued in the future: %s", msg.IssuedAt)
+	}
+	notBefore, err := parseSIWETime("Not Before", msg.NotBefore)
+	if err != nil {
+		return err
+	}
+	if !notBefore.IsZero() && notBefore.After(now.Add(skew)) {
+		return fmt.Errorf("SIWE message not yet valid: not before %s", msg.NotBefore)
+	}
+	expiresAt, err := parseSIWETime("Expiration Time", msg.ExpirationTime)
+	if err != nil {
+		return err
+	}
+	if !expiresAt.IsZero() && now.After(expiresAt.Add(skew)) {
+		return fmt.Errorf("SIWE message expired at %s", msg.ExpirationTime)
+	}
+	return nil
+}
+
+// parseSIWETime parses an RFC 3339 SIWE time field; empty yields zero.
+func parseSIWETime(field, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid SIWE message: invalid %s %q", field, value)
+	}
+	return t, nil
+}
+
+// SIWEVerifier verifies signed Sign-In with Ethereum messages.
+type SIWEVerifier struct {
+	signatures *SignatureVerifier
+}
+
+// NewSIWEVerifier creates a SIWE verifier. Signatures are checked with
+// signatures, so its EIP-1271 checker, if any, covers contract wallets.
+func NewSIWEVerifier(signatures *SignatureVerifier) *SIWEVerifier {
+	return &SIWEVerifier{signatures: signatures}
+}
+
+// Verify parses message, validates all of its fields against exp and only
+// then checks that signature was made by the message's address. It returns
+// the parsed message.
+func (v *SIWEVerifier) Verify(ctx context.Context, message, signature string, exp SIWEExpectations) (*SIWEMessage, error) {
+	msg, err := ParseSIWEMessage(message)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateSIWEFields(msg, exp); err != nil {
+		return nil, err
+	}
+	valid, err := v.signatures.VerifySignature(ctx, msg.Address, message, signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify SIWE signature: %w", err)
+	}
+	if !valid {
+		return nil, ErrSIWESignatureMismatch
+	}
+	return msg, nil
+}
@@ -0,0 +1,101 @@
+package signature
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSIWEVerifier_Verify(t *testing.T) {
+	sv := NewSignatureVerifier(zap.NewNop())
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	other, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	address := sv.GetAddressFromPrivateKey(key)
+
+	issued := time.Date(2026, 5, 7, 12, 0, 0, 0, time.UTC)
+	message := BuildSIWEMessage(NewSIWEMessage("streamgate.io", address, "https://streamgate.io/login", 1, "abc123", issued,
+		WithSIWEExpirationTime(issued.Add(5*time.Minute))))
+	signature, err := sv.SignMessage(message, key)
+	require.NoError(t, err)
+	forged, err := sv.SignMessage(message, other)
+	require.NoError(t, err)
+
+	exp := SIWEExpectations{
+		Domain:  "streamgate.io",
+		Address: address,
+		URI:     "https://streamgate.io/login",
+		Nonce:   "abc123",
+		ChainID: 1,
+		Now:     issued.Add(time.Minute),
+	}
+	v := NewSIWEVerifier(sv)
+	ctx := context.Background()
+
+	msg, err := v.Verify(ctx, message, signature, exp)
+	require.NoError(t, err)
+	assert.Equal(t, address, msg.Address)
+
+	_, err = v.Verify(ctx, message, forged, exp)
+	assert.ErrorIs(t, err, ErrSIWESignatureMismatch)
+
+	for name, tc := range map[string]struct {
+		mutate      func(*SIWEExpectations)
+		errContains string
+	}{
+		"domain":      {func(e *SIWEExpectations) { e.Domain = "evil.io" }, "domain mismatch"},
+		"uri":         {func(e *SIWEExpectations) { e.URI = "https://evil.io/login" }, "URI mismatch"},
+		"address":     {func(e *SIWEExpectations) { e.Address = sv.GetAddressFromPrivateKey(other) }, "address mismatch"},
+		"nonce":       {func(e *SIWEExpectations) { e.Nonce = "other" }, "nonce mismatch"},
+		"chain":       {func(e *SIWEExpectations) { e.ChainID = 137 }, "chain ID mismatch"},
+		"expired":     {func(e *SIWEExpectations) { e.Now = issued.Add(10 * time.Minute) }, "expired"},
+		"from future": {func(e *SIWEExpectations) { e.Now = issued.Add(-time.Hour) }, "issued in the future"},
+	} {
+		e := exp
+		tc.mutate(&e)
+		_, err := v.Verify(ctx, message, signature, e)
+		require.Error(t, err, name)
+		assert.Contains(t, err.Error(), tc.errContains, name)
+		assert.False(t, errors.Is(err, ErrSIWESignatureMismatch), "%s: fields are rejected before the signature is checked", name)
+	}
+}
+
+func TestValidateSIWEFields_TimeFields(t *testing.T) {
+	addr := "0x71C7656EC7ab88b098defB751B7401B5f6d8976F"
+	now := time.Date(2026, 5, 7, 12, 0, 0, 0, time.UTC)
+	base := SIWEMessage{Version: "1", Address: addr, IssuedAt: "2026-05-07T11:59:00Z"}
+
+	msg := base
+	assert.NoError(t, ValidateSIWEFields(&msg, SIWEExpectations{Now: now}))
+
+	msg = base
+	msg.IssuedAt = ""
+	assert.ErrorContains(t, ValidateSIWEFields(&msg, SIWEExpectations{Now: now}), "missing Issued At")
+
+	msg = base
+	msg.IssuedAt = "yesterday"
+	assert.ErrorContains(t, ValidateSIWEFields(&msg, SIWEExpectations{Now: now}), "invalid Issued At")
+
+	msg = base
+	msg.NotBefore = "2026-05-07T12:10:00Z"
+	assert.ErrorContains(t, ValidateSIWEFields(&msg, SIWEExpectations{Now: now}), "not yet valid")
+
+	msg = base
+	msg.ExpirationTime = "2026-05-07T11:59:50Z"
+	assert.NoError(t, ValidateSIWEFields(&msg, SIWEExpectations{Now: now}), "within clock skew")
+	assert.ErrorContains(t, ValidateSIWEFields(&msg, SIWEExpectations{Now: now, ClockSkew: time.Second}), "expired")
+}
+
+func TestParseSIWEMessage_InvalidChainID(t *testing.T) {
+	msg := BuildSIWEMessage(NewSIWEMessage("streamgate.io", "0x71C7656EC7ab88b098defB751B7401B5f6d8976F", "https://streamgate.io", 1, "n", time.Now()))
+	_, err := ParseSIWEMessage(strings.Replace(msg, "Chain ID: 1", "Chain ID: one", 1))
+	assert.ErrorContains(t, err, "invalid Chain ID")
+}
@@ -0,0 +1,57 @@
+package solana
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"go.uber.org/zap"
+)
+
+// Ed25519SignatureVerifier verifies wallet signatures the way Phantom and
+// other Solana wallets produce them with signMessage: an ed25519 signature
+// over the raw UTF-8 message bytes, base58-encoded, checked against the
+// base58 public key that is the wallet address.
+//
+// It has the same VerifySignature signature as the EVM SignatureVerifier so
+// callers can route by chain type. Unlike SolanaVerifier it needs no RPC
+// endpoint.
+type Ed25519SignatureVerifier struct {
+	logger *zap.Logger
+}
+
+// NewEd25519SignatureVerifier creates an ed25519 signature verifier.
+func NewEd25519SignatureVerifier(logger *zap.Logger) *Ed25519SignatureVerifier {
+	return &Ed25519SignatureVerifier{logger: logger}
+}
+
+// VerifySignature reports whether signature is address's signature of
+// message. Malformed addresses or signatures are errors; a well-formed
+// signature by another key is (false, nil).
+func (v *Ed25519SignatureVerifier) VerifySignature(ctx context.Context, address, message, signature string) (bool, error) {
+	v.logger.Debug("Verifying ed25519 signature",
+		zap.String("address", address),
+		zap.Int("message_length", len(message)))
+
+	pubKey, err := solana.PublicKeyFromBase58(address)
+	if err != nil {
+		return false, fmt.Errorf("invalid Solana address: %w", err)
+	}
+	sig, err := solana.SignatureFromBase58(signature)
+	if err != nil {
+		return false, fmt.Errorf("invalid base58 signature: %w", err)
+	}
+
+	if !ed25519.Verify(pubKey[:], []byte(message), sig[:]) {
+		v.logger.Warn("ed25519 signature verification failed", zap.String("address", address))
+		return false, nil
+	}
+	return true, nil
+}
+
+// SignMessage signs message with privateKey and returns the base58
+// signature VerifySignature accepts (for testing).
+func (v *Ed25519SignatureVerifier) SignMessage(message string, privateKey ed25519.PrivateKey) string {
+	return solana.SignatureFromBytes(ed25519.Sign(privateKey, []byte(message))).String()
+}
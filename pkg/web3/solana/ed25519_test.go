@@ -0,0 +1,41 @@
+package solana
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestEd25519SignatureVerifier_VerifySignature(t *testing.T) {
+	v := NewEd25519SignatureVerifier(zap.NewNop())
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	address := solana.PublicKeyFromBytes(pub).String()
+	ctx := context.Background()
+	message := "Sign in to StreamGate\nNonce: abc123"
+
+	valid, err := v.VerifySignature(ctx, address, message, v.SignMessage(message, priv))
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	valid, err = v.VerifySignature(ctx, address, message+"!", v.SignMessage(message, priv))
+	require.NoError(t, err)
+	assert.False(t, valid, "a different message")
+
+	valid, err = v.VerifySignature(ctx, address, message, v.SignMessage(message, otherPriv))
+	require.NoError(t, err)
+	assert.False(t, valid, "a different key")
+
+	_, err = v.VerifySignature(ctx, "0x71C7656EC7ab88b098defB751B7401B5f6d8976F", message, v.SignMessage(message, priv))
+	assert.ErrorContains(t, err, "invalid Solana address")
+	_, err = v.VerifySignature(ctx, address, message, "not-base58-0OIl")
+	assert.ErrorContains(t, err, "invalid base58 signature")
+}
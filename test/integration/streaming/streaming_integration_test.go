@@ -103,7 +103,7 @@ func TestStreamingService_HLSFormat(t *testing.T) {
 		"1080p": {"seg000.ts", "seg001.ts"},
 		"720p":  {"seg000.ts", "seg001.ts"},
 	}
-	playlist, err := streamingService.GenerateHLSPlaylist("content-123", qualitySegments, "test-token")
+	playlist, err := streamingService.GenerateHLSPlaylist("content-123", qualitySegments, "test-token", false)
 	require.NoError(t, err)
 	require.NotEmpty(t, playlist)
 	require.Contains(t, playlist, ".m3u8")